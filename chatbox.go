@@ -4,39 +4,78 @@
 package chatbox
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/real-rm/chatbox/internal/alerts"
+	"github.com/real-rm/chatbox/internal/apierror"
+	"github.com/real-rm/chatbox/internal/apikey"
 	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/chatbox/internal/backup"
+	"github.com/real-rm/chatbox/internal/businesshours"
+	"github.com/real-rm/chatbox/internal/cluster"
 	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/cost"
+	"github.com/real-rm/chatbox/internal/email"
+	"github.com/real-rm/chatbox/internal/embed"
 	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/events"
+	"github.com/real-rm/chatbox/internal/experiment"
+	"github.com/real-rm/chatbox/internal/export"
+	"github.com/real-rm/chatbox/internal/health"
 	"github.com/real-rm/chatbox/internal/httperrors"
+	"github.com/real-rm/chatbox/internal/injection"
+	"github.com/real-rm/chatbox/internal/jobs"
 	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/maintenance"
+	"github.com/real-rm/chatbox/internal/memory"
 	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/moderation"
 	"github.com/real-rm/chatbox/internal/notification"
+	"github.com/real-rm/chatbox/internal/priority"
+	"github.com/real-rm/chatbox/internal/prompt"
 	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/readonly"
+	"github.com/real-rm/chatbox/internal/remotewrite"
+	"github.com/real-rm/chatbox/internal/requestid"
+	"github.com/real-rm/chatbox/internal/retrieval"
 	"github.com/real-rm/chatbox/internal/router"
+	"github.com/real-rm/chatbox/internal/sandbox"
+	"github.com/real-rm/chatbox/internal/sentiment"
 	"github.com/real-rm/chatbox/internal/session"
 	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/chatbox/internal/tracing"
+	"github.com/real-rm/chatbox/internal/transcribe"
 	"github.com/real-rm/chatbox/internal/upload"
 	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/watchlist"
+	"github.com/real-rm/chatbox/internal/webhook"
 	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/real-rm/chatbox/internal/widget"
+	"github.com/real-rm/chatbox/internal/wsticket"
 	"github.com/real-rm/goconfig"
 	"github.com/real-rm/gohelper"
 	levelStore "github.com/real-rm/golevelstore"
 	"github.com/real-rm/golog"
 	"github.com/real-rm/gomongo"
 	"github.com/real-rm/goupload"
+	"github.com/redis/go-redis/v9"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func init() {
@@ -52,8 +91,25 @@ var (
 	globalWSHandler     *websocket.Handler
 	globalSessionMgr    *session.SessionManager
 	globalMessageRouter *router.MessageRouter
-	globalAdminLimiter  *ratelimit.MessageLimiter
-	globalPublicLimiter *ratelimit.MessageLimiter
+	globalAdminLimiter  ratelimit.Limiter
+	globalPublicLimiter ratelimit.Limiter
+	globalIPLimiter     *ratelimit.IPLimiter
+	globalRatePolicies  *ratelimit.Policies
+	globalRedis         *redis.Client   // nil unless the Redis rate limit backend is configured
+	globalCluster       *cluster.Bridge // nil unless chatbox.cluster.enabled is set
+	globalClusterRedis  *redis.Client   // the cluster's own client; nil when it shares globalRedis
+	globalWebhooks      *webhook.Dispatcher
+	globalMailer        *email.Mailer       // nil unless transcript emails are enabled
+	globalEvents        *events.Bus         // nil unless event sinks are configured
+	globalSentiment     *sentiment.Pipeline // nil unless sentiment analysis is enabled
+	globalBackups       *backup.Runner      // nil unless session backups are enabled
+	globalScheduler     *jobs.Scheduler
+	globalStorage       *storage.StorageService
+	globalUploads       *upload.UploadService
+	globalMetricsPusher *remotewrite.Pusher      // nil unless metrics remote write is configured
+	globalAlerts        *alerts.Monitor          // nil unless alert rules are configured
+	globalTracer        *sdktrace.TracerProvider // nil unless tracing is enabled
+	globalReloader      *configReloader
 	globalLogger        *golog.Logger
 	shutdownMu          sync.Mutex
 )
@@ -150,35 +206,17 @@ func Register(r *gin.Engine, config *goconfig.ConfigAccessor, logger *golog.Logg
 	if err != nil {
 		return fmt.Errorf("failed to create upload service: %w", err)
 	}
-
-	// Load encryption key for message content at rest
-	// Priority: Environment variable > Config file
-	// The key must be exactly 32 bytes for AES-256 encryption
-	var encryptionKey []byte
-	encryptionKeyStr := os.Getenv("ENCRYPTION_KEY")
-	if encryptionKeyStr == "" {
-		// Fall back to config file
-		encryptionKeyStr, err = config.ConfigStringWithDefault("chatbox.encryption_key", "")
-		// No else needed: early return pattern (guard clause)
-		if err != nil {
-			return fmt.Errorf("failed to get encryption key: %w", err)
-		}
-		if encryptionKeyStr != "" && containsPlaceholder(encryptionKeyStr) {
-			return fmt.Errorf("ENCRYPTION_KEY contains placeholder value — set a real key before deploying")
-		}
-	}
-	// No else needed: optional operation (logging based on configuration state)
-	if encryptionKeyStr != "" {
-		// Convert string to bytes
-		encryptionKey = []byte(encryptionKeyStr)
-		chatboxLogger.Info("Message encryption enabled", "key_length", len(encryptionKey))
-	} else {
-		chatboxLogger.Error("No encryption key configured — messages will be stored unencrypted. Set ENCRYPTION_KEY to enable AES-256-GCM encryption at rest.")
+	uploadIndexCtx, uploadIndexCancel := util.NewTimeoutContext(constants.MongoIndexTimeout)
+	defer uploadIndexCancel()
+	// No else needed: optional operation (non-critical index creation)
+	if err := uploadService.EnsureIndexes(uploadIndexCtx); err != nil {
+		chatboxLogger.Warn("Failed to create file_stats indexes", "error", err)
 	}
 
-	// Validate encryption key length before any encryption operations
+	// Load encryption key for message content at rest
+	encryptionKey, err := loadEncryptionKey(config, chatboxLogger)
 	// No else needed: early return pattern (guard clause)
-	if err := validateEncryptionKey(encryptionKey); err != nil {
+	if err != nil {
 		return err
 	}
 
@@ -218,8 +256,172 @@ func Register(r *gin.Engine, config *goconfig.ConfigAccessor, logger *golog.Logg
 		}
 	}
 
-	// Create storage service with encryption key
+	// Create the session store with encryption key. storageService also backs the
+	// session history, admin and audit endpoints.
 	storageService := storage.NewStorageService(mongo, "chat", "sessions", chatboxLogger, encryptionKey)
+	var sessionStore storage.SessionStore = storageService
+
+	// Load anonymized analytics mode for privacy-first deployments
+	// Priority: Environment variable > Config file
+	anonymized, err := config.ConfigBoolWithDefault("chatbox.anonymized_analytics", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get anonymized analytics setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envAnonymized := os.Getenv("CHATBOX_ANONYMIZED_ANALYTICS"); envAnonymized != "" {
+		anonymized = envAnonymized == "true"
+	}
+	anonymizationSalt, err := loadAnonymizationSalt(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (dedicated salt instead of the encryption key)
+	if len(anonymizationSalt) > 0 {
+		storageService.SetAnonymizationSalt(anonymizationSalt)
+	}
+	// No else needed: optional operation (only enable if configured)
+	if anonymized {
+		// No else needed: early return pattern (guard clause)
+		if err := storageService.SetAnonymizedMode(true); err != nil {
+			return fmt.Errorf("chatbox.anonymized_analytics requires chatbox.anonymization_salt or chatbox.encryption_key to key the user ID hashes: %w", err)
+		}
+		chatboxLogger.Info("Anonymized analytics mode enabled: storing hashed user IDs and aggregate counters only")
+	}
+	anonymizedTenants := loadAnonymizedTenants(config)
+	// No else needed: optional operation (only for the listed tenants)
+	if len(anonymizedTenants) > 0 && !anonymized {
+		// No else needed: early return pattern (guard clause)
+		if err := storageService.SetAnonymizedTenants(anonymizedTenants); err != nil {
+			return fmt.Errorf("invalid chatbox.anonymized_tenants: %w", err)
+		}
+		chatboxLogger.Info("Anonymized analytics mode enabled for tenants", "tenants", anonymizedTenants)
+	}
+
+	// Load searchable-hash index setting for admin search over encrypted messages
+	// Priority: Environment variable > Config file
+	searchHashIndex, err := config.ConfigBoolWithDefault("chatbox.search_hash_index", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get search hash index setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envSearchHashIndex := os.Getenv("CHATBOX_SEARCH_HASH_INDEX"); envSearchHashIndex != "" {
+		searchHashIndex = envSearchHashIndex == "true"
+	}
+	// No else needed: optional operation (only enable if configured)
+	if searchHashIndex {
+		storageService.SetSearchHashIndex(true)
+		// No else needed: optional operation (warn about ineffective setting)
+		if len(encryptionKey) == 0 {
+			chatboxLogger.Warn("chatbox.search_hash_index has no effect without an encryption key; plaintext messages use the text index")
+		}
+	}
+
+	// Load session retention policy (0 days keeps sessions forever)
+	retentionDays, err := config.ConfigIntWithDefault("chatbox.session_retention_days", 0)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get session retention days: %w", err)
+	}
+	restoreGraceDays, err := config.ConfigIntWithDefault("chatbox.session_restore_grace_days", constants.DefaultSessionRestoreGraceDays)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get session restore grace days: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if retentionDays < 0 || restoreGraceDays < 0 {
+		return fmt.Errorf("session retention and restore grace days must not be negative")
+	}
+
+	// Load metrics rollup setting (admin metrics served from precomputed rollups)
+	metricsRollup, err := config.ConfigBoolWithDefault("chatbox.metrics_rollup", true)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get metrics rollup setting: %w", err)
+	}
+
+	// Schedule the retention purge and metrics rollups as background jobs
+	day := 24 * time.Hour
+	scheduler, err := newJobScheduler(config, storageService, time.Duration(retentionDays)*day, time.Duration(restoreGraceDays)*day, metricsRollup, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+
+	// Load message write batching settings (opt-in: buffered messages are lost on a crash)
+	// Priority: Environment variable > Config file
+	messageBatching, err := config.ConfigBoolWithDefault("chatbox.message_batching", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get message batching setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envBatching := os.Getenv("CHATBOX_MESSAGE_BATCHING"); envBatching != "" {
+		messageBatching = envBatching == "true"
+	}
+	messageBatchIntervalStr, err := config.ConfigStringWithDefault("chatbox.message_batch_interval", constants.DefaultMessageBatchInterval.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get message batch interval: %w", err)
+	}
+	messageBatchInterval, err := time.ParseDuration(messageBatchIntervalStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("invalid message batch interval format: %w", err)
+	}
+	messageBatchSize, err := config.ConfigIntWithDefault("chatbox.message_batch_size", constants.DefaultMessageBatchSize)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get message batch size: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if messageBatchInterval <= 0 || messageBatchInterval > constants.MaxMessageBatchInterval {
+		return fmt.Errorf("chatbox.message_batch_interval must be positive and at most %s", constants.MaxMessageBatchInterval)
+	}
+	// No else needed: early return pattern (guard clause)
+	if messageBatchSize <= 0 || messageBatchSize > constants.MaxMessageBatchSize {
+		return fmt.Errorf("chatbox.message_batch_size must be between 1 and %d", constants.MaxMessageBatchSize)
+	}
+
+	// Load whether session changes made elsewhere are followed (requires a replica set)
+	// Priority: Environment variable > Config file
+	changeStream, err := config.ConfigBoolWithDefault("chatbox.change_stream", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get change stream setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envChangeStream := os.Getenv("CHATBOX_CHANGE_STREAM"); envChangeStream != "" {
+		changeStream = envChangeStream == "true"
+	}
+
+	// Create the session backup runner (nil when disabled)
+	backupRunner, err := newBackupRunner(config, mongo, storageService, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+
+	// Load whether pending session schema migrations are applied at startup
+	// Priority: Environment variable > Config file
+	migrateOnStartup, err := config.ConfigBoolWithDefault("chatbox.migrate_on_startup", true)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get migrate on startup setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envMigrate := os.Getenv("CHATBOX_MIGRATE_ON_STARTUP"); envMigrate != "" {
+		migrateOnStartup = envMigrate == "true"
+	}
+
+	// Pin tenants to the datastores their data must stay in (data residency)
+	residency, err := loadResidency(config, storageService, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
 
 	// Ensure MongoDB indexes are created for optimal query performance
 	indexCtx, indexCancel := util.NewTimeoutContext(constants.MongoIndexTimeout)
@@ -229,13 +431,26 @@ func Register(r *gin.Engine, config *goconfig.ConfigAccessor, logger *golog.Logg
 		chatboxLogger.Warn("Failed to create MongoDB indexes", "error", err)
 		// Don't fail startup - indexes can be created manually if needed
 	}
+	migrateSessionDocuments(storageService, migrateOnStartup, chatboxLogger)
 
 	// Create session manager
 	sessionManager := session.NewSessionManager(reconnectTimeout, chatboxLogger)
 
-	// Rehydrate active sessions from MongoDB into the in-memory map.
+	// No else needed: optional operation (route sessions to their tenant's datastore)
+	if residency {
+		sessionStore = storage.NewResidentStore(storageService, func(sessionID string) (string, bool) {
+			sess, err := sessionManager.GetSession(sessionID)
+			// No else needed: early return pattern (session not in memory)
+			if err != nil {
+				return "", false
+			}
+			return sess.TenantID, true
+		})
+	}
+
+	// Rehydrate active sessions from the session store into the in-memory map.
 	// This restores sessions that survived a pod restart (see C2: horizontal scaling).
-	if err := sessionManager.RehydrateFromStorage(storageService); err != nil {
+	if err := sessionManager.RehydrateFromStorage(sessionStore); err != nil {
 		chatboxLogger.Warn("Failed to rehydrate sessions from storage", "error", err)
 		// Non-fatal: sessions will be recreated when users reconnect
 	}
@@ -243,11 +458,32 @@ func Register(r *gin.Engine, config *goconfig.ConfigAccessor, logger *golog.Logg
 	// NOTE: sessionManager.StartCleanup() is deferred until after all validation
 	// to avoid leaking goroutines if Register() returns an error.
 
-	// Create LLM service
-	llmService, err := llm.NewLLMService(config, chatboxLogger)
+	// Load LLM feature toggle; when disabled, chat is human-only
+	// Priority: Environment variable > Config file
+	llmEnabled, err := config.ConfigBoolWithDefault("chatbox.llm_enabled", true)
 	// No else needed: early return pattern (guard clause)
 	if err != nil {
-		return fmt.Errorf("failed to create LLM service: %w", err)
+		return fmt.Errorf("failed to get LLM enabled setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envLLMEnabled := os.Getenv("CHATBOX_LLM_ENABLED"); envLLMEnabled != "" {
+		llmEnabled = envLLMEnabled == "true"
+	}
+
+	// Create LLM service (skipped in human-only mode, so no providers are required)
+	// routerLLM stays an untyped nil interface when LLM is disabled.
+	var llmService *llm.LLMService
+	var routerLLM router.LLMService
+	// No else needed: optional operation (LLM only created when enabled)
+	if llmEnabled {
+		llmService, err = llm.NewLLMService(config, chatboxLogger)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return fmt.Errorf("failed to create LLM service: %w", err)
+		}
+		routerLLM = llmService
+	} else {
+		chatboxLogger.Info("LLM features disabled: sessions route directly to the admin help queue")
 	}
 
 	// Create notification service
@@ -270,1083 +506,8071 @@ func Register(r *gin.Engine, config *goconfig.ConfigAccessor, logger *golog.Logg
 	}
 
 	// Create message router
-	messageRouter := router.NewMessageRouter(sessionManager, llmService, uploadService, notificationService, storageService, llmStreamTimeout, chatboxLogger)
+	messageRouter := router.NewMessageRouter(sessionManager, routerLLM, uploadService, notificationService, sessionStore, llmStreamTimeout, chatboxLogger)
+	messageRouter.SetHumanOnlyMode(!llmEnabled)
 
-	// Create admin rate limiter
-	adminRateLimit, err := config.ConfigIntWithDefault("chatbox.admin_rate_limit", constants.DefaultAdminRateLimit)
+	// Load session auto-title setting (opt-out)
+	// Priority: Environment variable > Config file
+	autoTitle, err := config.ConfigBoolWithDefault("chatbox.auto_title", true)
 	// No else needed: early return pattern (guard clause)
 	if err != nil {
-		return fmt.Errorf("failed to get admin rate limit: %w", err)
+		return fmt.Errorf("failed to get auto title setting: %w", err)
 	}
-	adminRateWindowStr, err := config.ConfigStringWithDefault("chatbox.admin_rate_window", constants.DefaultRateWindow.String())
+	// No else needed: optional operation (environment override)
+	if envAutoTitle := os.Getenv("CHATBOX_AUTO_TITLE"); envAutoTitle != "" {
+		autoTitle = envAutoTitle == "true"
+	}
+	messageRouter.SetAutoTitle(autoTitle)
+
+	// Load multi-device setting (opt-out)
+	// Priority: Environment variable > Config file
+	multiDevice, err := config.ConfigBoolWithDefault("chatbox.multi_device", true)
 	// No else needed: early return pattern (guard clause)
 	if err != nil {
-		return fmt.Errorf("failed to get admin rate window: %w", err)
+		return fmt.Errorf("failed to get multi device setting: %w", err)
 	}
-	adminRateWindow, err := time.ParseDuration(adminRateWindowStr)
+	// No else needed: optional operation (environment override)
+	if envMultiDevice := os.Getenv("CHATBOX_MULTI_DEVICE"); envMultiDevice != "" {
+		multiDevice = envMultiDevice == "true"
+	}
+	messageRouter.SetMultiDevice(multiDevice)
+
+	// Load read receipt setting (opt-in: tracks when users read admin replies)
+	// Priority: Environment variable > Config file
+	readReceipts, err := config.ConfigBoolWithDefault("chatbox.read_receipts", false)
 	// No else needed: early return pattern (guard clause)
 	if err != nil {
-		return fmt.Errorf("invalid admin rate window format: %w", err)
+		return fmt.Errorf("failed to get read receipts setting: %w", err)
 	}
+	// No else needed: optional operation (environment override)
+	if envReadReceipts := os.Getenv("CHATBOX_READ_RECEIPTS"); envReadReceipts != "" {
+		readReceipts = envReadReceipts == "true"
+	}
+	messageRouter.SetReadReceipts(readReceipts)
 
-	adminLimiter := ratelimit.NewMessageLimiter(adminRateWindow, adminRateLimit)
-
-	chatboxLogger.Info("Admin rate limiter configured",
-		"rate_limit", adminRateLimit,
-		"window", adminRateWindow)
+	// Load Swagger UI setting (opt-in: the page loads its assets from a public CDN)
+	// Priority: Environment variable > Config file
+	openAPIUI, err := config.ConfigBoolWithDefault("chatbox.openapi_ui", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get OpenAPI UI setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envOpenAPIUI := os.Getenv("CHATBOX_OPENAPI_UI"); envOpenAPIUI != "" {
+		openAPIUI = envOpenAPIUI == "true"
+	}
 
-	// Create JWT validator
-	validator := auth.NewJWTValidator(jwtSecret)
+	// Load embeddable widget setting (opt-in: serves the chat widget scripts)
+	// Priority: Environment variable > Config file
+	widgetEnabled, err := config.ConfigBoolWithDefault("chatbox.widget.enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get widget setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envWidget := os.Getenv("CHATBOX_WIDGET_ENABLED"); envWidget != "" {
+		widgetEnabled = envWidget == "true"
+	}
+	var widgetBundle *widget.Bundle
+	// No else needed: optional operation (widget enabled)
+	if widgetEnabled {
+		widgetBundle, err = widget.Load()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return fmt.Errorf("failed to load chat widget: %w", err)
+		}
+		chatboxLogger.Info("Chat widget enabled", "version", widgetBundle.Version())
+	}
 
-	// Create WebSocket handler with router
-	wsHandler := websocket.NewHandler(validator, messageRouter, chatboxLogger, maxMessageSize)
+	// Scheduled maintenance windows: new sessions refused, banners and readiness
+	maintenanceSchedule, err := loadMaintenanceSchedule(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	messageRouter.SetMaintenance(maintenanceSchedule)
 
-	// Create public endpoint rate limiter (per-IP, prevents abuse of healthz/readyz/metrics)
-	publicLimiter := ratelimit.NewMessageLimiter(1*time.Minute, constants.PublicEndpointRate)
+	// Business hours: help requests after hours get an automatic reply and a tag
+	businessHours, err := loadBusinessHours(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	var sessionTagger router.SessionTagger
+	// No else needed: optional operation (session stores that keep tags)
+	if tagger, ok := sessionStore.(router.SessionTagger); ok {
+		sessionTagger = tagger
+	}
+	messageRouter.SetBusinessHours(businessHours, sessionTagger)
+
+	// Admin read-only mode for storage incidents, stored in MongoDB. A mode that
+	// cannot be loaded (storage down) starts writable and is reloaded later.
+	readOnlyMode := readonly.New(readonly.State{})
+	var loadReadOnly func() (readonly.State, error)
+	loadReadOnly = storageService.GetReadOnlyMode
+	state, err := loadReadOnly()
+	// No else needed: optional operation (log only)
+	if err != nil {
+		chatboxLogger.Warn("Failed to load read-only mode, starting writable", "error", err)
+	}
+	readOnlyMode.Sync(state)
+	messageRouter.SetReadOnly(readOnlyMode, loadReadOnly)
 
-	// Configure allowed origins for WebSocket connections
-	// SECURITY: When no origins are configured, ALL origins are accepted.
-	// This is acceptable only in development. In production, always configure
-	// allowed_origins to prevent cross-site WebSocket hijacking.
-	allowedOriginsStr, err := config.ConfigStringWithDefault("chatbox.allowed_origins", "")
-	// No else needed: optional operation (configuration with fallback logging)
-	if err == nil && allowedOriginsStr != "" {
-		if containsPlaceholder(allowedOriginsStr) {
-			return fmt.Errorf("chatbox.allowed_origins contains placeholder value %q — set actual origins before deploying", allowedOriginsStr)
-		}
-		origins := strings.Split(allowedOriginsStr, ",")
-		for i, origin := range origins {
-			origins[i] = strings.TrimSpace(origin)
+	// Fallback chains, per-attempt timeouts and retries for LLM calls
+	// No else needed: optional operation (LLM only created when enabled)
+	if llmService != nil {
+		fallback, err := loadFallbackConfig(config, llmService)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return err
 		}
-		wsHandler.SetAllowedOrigins(origins)
-	} else {
-		chatboxLogger.Warn("No allowed origins configured, allowing all origins (development mode)")
+		messageRouter.SetFallback(fallback)
 	}
 
-	// Start background cleanup goroutines only after all validation is complete,
-	// so we don't leak goroutines if Register() returns an error.
-	sessionManager.StartCleanup()
-	adminLimiter.StartCleanup()
-	publicLimiter.StartCleanup()
+	// Per-provider send queues: requests a provider rate limits wait for capacity
+	queueDepth, err := config.ConfigIntWithDefault("chatbox.llm_queue.max_depth", constants.DefaultProviderQueueDepth)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get LLM queue depth: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if queueDepth < 0 {
+		return fmt.Errorf("chatbox.llm_queue.max_depth must not be negative")
+	}
+	messageRouter.SetProviderQueue(queueDepth)
 
-	// Store global references for graceful shutdown.
-	// Stop any previously-registered instances to prevent goroutine leaks
-	// when Register() is called multiple times (tests, hot-reload).
-	shutdownMu.Lock()
-	if globalSessionMgr != nil {
-		globalSessionMgr.StopCleanup()
+	// Per-session and per-message LLM timeout overrides (disabled without a maximum)
+	minLLMTimeout, maxLLMTimeout, err := loadLLMTimeoutBounds(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
 	}
-	if globalMessageRouter != nil {
-		globalMessageRouter.Shutdown()
+	messageRouter.SetLLMTimeoutBounds(minLLMTimeout, maxLLMTimeout)
+	// No else needed: optional operation (log only when enabled)
+	if maxLLMTimeout > 0 {
+		chatboxLogger.Info("LLM timeout overrides enabled", "min", minLLMTimeout, "max", maxLLMTimeout)
 	}
-	if globalAdminLimiter != nil {
-		globalAdminLimiter.StopCleanup()
+
+	// Idle timeout: sessions without activity are warned, then ended and persisted
+	idleTimeoutStr, err := config.ConfigStringWithDefault("chatbox.idle_timeout", constants.DefaultIdleTimeout.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get idle timeout: %w", err)
 	}
-	if globalPublicLimiter != nil {
-		globalPublicLimiter.StopCleanup()
+	idleTimeout, err := time.ParseDuration(idleTimeoutStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("invalid idle timeout format: %w", err)
 	}
-	if globalWSHandler != nil {
-		_ = globalWSHandler.ShutdownWithContext(context.Background())
+	idleWarningStr, err := config.ConfigStringWithDefault("chatbox.idle_warning", constants.DefaultIdleWarning.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get idle warning: %w", err)
+	}
+	idleWarning, err := time.ParseDuration(idleWarningStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("invalid idle warning format: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := sessionManager.SetIdleTimeout(idleTimeout, idleWarning, messageRouter); err != nil {
+		return fmt.Errorf("invalid idle timeout configuration: %w", err)
 	}
-	globalWSHandler = wsHandler
-	globalSessionMgr = sessionManager
-	globalMessageRouter = messageRouter
-	globalAdminLimiter = adminLimiter
-	globalPublicLimiter = publicLimiter
-	globalLogger = chatboxLogger
-	shutdownMu.Unlock()
 
-	// Configure CORS middleware
-	// Load CORS configuration from config file or environment
-	corsOriginsStr, err := config.ConfigStringWithDefault("chatbox.cors_allowed_origins", "")
-	// No else needed: optional operation (CORS configuration with fallback logging)
-	if err == nil && corsOriginsStr != "" {
-		if containsPlaceholder(corsOriginsStr) {
-			return fmt.Errorf("chatbox.cors_allowed_origins contains placeholder value %q — set actual origins before deploying", corsOriginsStr)
-		}
-		// Parse allowed origins from comma-separated string
-		allowedOrigins := strings.Split(corsOriginsStr, ",")
-		for i, origin := range allowedOrigins {
-			allowedOrigins[i] = strings.TrimSpace(origin)
-		}
+	// Create webhook dispatcher for help request, takeover, and session end events
+	// webhookPublisher stays an untyped nil interface when no URLs are configured.
+	webhookDispatcher, err := newWebhookDispatcher(config, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	var webhookPublisher router.WebhookPublisher
+	// No else needed: optional operation (webhooks only when configured)
+	if webhookDispatcher != nil {
+		webhookPublisher = webhookDispatcher
+		messageRouter.SetWebhookPublisher(webhookPublisher)
+	}
 
-		// Configure CORS middleware
-		corsConfig := cors.Config{
-			AllowOrigins:     allowedOrigins,
-			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-			ExposeHeaders:    []string{"Content-Length"},
-			AllowCredentials: true,
-			MaxAge:           12 * time.Hour,
-		}
+	// Create the session lifecycle event bus for integrations
+	// eventPublisher stays an untyped nil interface when no sinks are configured.
+	eventBus, err := newEventBus(config, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	var eventPublisher router.EventPublisher
+	// No else needed: optional operation (event bus only when configured)
+	if eventBus != nil {
+		eventPublisher = eventBus
+		messageRouter.SetEventPublisher(eventPublisher)
+	}
+	// Sessions ended over HTTP are reported to webhooks and the event bus
+	sessionEndPublisher := events.Tee(webhookPublisher, eventPublisher)
 
-		// Apply CORS middleware to the router
-		r.Use(cors.New(corsConfig))
+	// Create the content moderation pipeline (nil when disabled)
+	moderationPipeline, err := newModerationPipeline(config, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (moderation only when configured)
+	if moderationPipeline != nil {
+		messageRouter.SetModerator(moderationPipeline)
+		chatboxLogger.Info("Content moderation enabled", "filters", moderationPipeline.Len())
+	}
 
-		chatboxLogger.Info("CORS middleware configured",
-			"allowed_origins", allowedOrigins,
-			"allow_credentials", true)
-	} else {
-		chatboxLogger.Warn("No CORS origins configured, CORS middleware not enabled")
+	// Create the prompt injection scanner (nil when disabled)
+	injectionScanner, err := newInjectionScanner(config, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (detection only when enabled)
+	if injectionScanner != nil {
+		messageRouter.SetInjectionScanner(injectionScanner)
+		chatboxLogger.Info("Prompt injection detection enabled", "detectors", injectionScanner.Len())
 	}
 
-	// Configure trusted proxies to prevent X-Forwarded-For spoofing.
-	// c.ClientIP() will only trust X-Forwarded-For from these networks.
-	trustedProxiesStr, _ := config.ConfigStringWithDefault("chatbox.trusted_proxies", constants.DefaultTrustedProxies)
-	if trustedProxiesStr != "" {
-		proxies := strings.Split(trustedProxiesStr, ",")
-		for i, p := range proxies {
-			proxies[i] = strings.TrimSpace(p)
-		}
-		if err := r.SetTrustedProxies(proxies); err != nil {
-			chatboxLogger.Warn("Failed to set trusted proxies", "error", err)
-		} else {
-			chatboxLogger.Info("Trusted proxies configured", "proxies", proxies)
-		}
+	// Create the sentiment analysis pipeline (nil when disabled)
+	sentimentPipeline, err := newSentimentPipeline(config, sessionManager, sessionStore, webhookPublisher, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (sentiment analysis only when enabled)
+	if sentimentPipeline != nil {
+		messageRouter.SetSentimentScorer(sentimentPipeline)
 	}
 
-	// Apply security headers middleware
-	r.Use(securityHeadersMiddleware())
+	// Create the retriever for retrieval-augmented generation (nil when disabled)
+	retriever, err := newRetriever(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (retrieval only when configured)
+	if retriever != nil {
+		messageRouter.SetRetriever(retriever)
+		chatboxLogger.Info("Retrieval-augmented generation enabled")
+	}
 
-	// Apply metrics middleware to record HTTP request duration
-	r.Use(metricsMiddleware())
+	// Create the code execution sandbox runner (nil when disabled)
+	codeRunner, sandboxTenants, err := newCodeRunner(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (sandbox only when configured)
+	if codeRunner != nil {
+		messageRouter.SetCodeRunner(codeRunner, sandboxTenants)
+		chatboxLogger.Info("Code execution sandbox enabled", "tenants", sandboxTenants, "languages", codeRunner.Languages())
+	}
 
-	chatboxLogger.Info("Using HTTP path prefix", "prefix", pathPrefix)
+	// Create the speech-to-text provider for streamed voice messages (nil when disabled)
+	transcriber, err := newTranscriber(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (streaming transcription only when configured)
+	if transcriber != nil {
+		messageRouter.SetTranscriber(transcriber, uploadService)
+		chatboxLogger.Info("Streaming voice transcription enabled")
+	}
 
-	// Register routes
-	chatGroup := r.Group(pathPrefix)
-	{
-		// WebSocket endpoint - use Gin context adapter
-		chatGroup.GET("/ws", func(c *gin.Context) {
-			// M8: If JWT is in query param, move it to Authorization header and redact
-			// from URL to prevent it from appearing in Gin access logs.
-			if token := c.Query("token"); token != "" {
-				if c.Request.Header.Get("Authorization") == "" {
-					c.Request.Header.Set("Authorization", "Bearer "+token)
-				}
-				q := c.Request.URL.Query()
-				q.Del("token")
-				c.Request.URL.RawQuery = q.Encode()
-			}
-			wsHandler.HandleWebSocket(c.Writer, c.Request)
-		})
+	// Create the mailer of session transcripts requested on session end (nil when disabled)
+	transcriptMailer, err := newTranscriptMailer(config, uploadService, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
 
-		// User session endpoints (authenticated but not admin-only)
-		chatGroup.GET("/sessions", userAuthMiddleware(validator, chatboxLogger), handleUserSessions(storageService, chatboxLogger))
-		chatGroup.GET("/sessions/:sessionID", userAuthMiddleware(validator, chatboxLogger), handleGetSessionMessages(storageService, chatboxLogger))
-		chatGroup.POST("/sessions/:sessionID/end", userAuthMiddleware(validator, chatboxLogger), handleEndSession(storageService, sessionManager, chatboxLogger))
-		chatGroup.POST("/sessions/:sessionID/share", userAuthMiddleware(validator, chatboxLogger), handleShareSession(storageService, chatboxLogger))
+	// Create the pusher of session metrics to a Prometheus remote write endpoint (nil when disabled)
+	metricsPusher, err := newMetricsPusher(config, storageService, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
 
-		// Public shared session endpoint (no auth, rate-limited)
-		chatGroup.GET("/shared/:shareToken", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleGetSharedSession(storageService, chatboxLogger))
+	// Create the monitor of usage alert rules (nil when no rules are configured)
+	alertMonitor, err := newAlertMonitor(config, storageService, sessionManager, events.Tee(webhookPublisher, eventPublisher), chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
 
-		// Admin HTTP endpoints
-		adminGroup := chatGroup.Group("/admin")
-		adminGroup.Use(authMiddleware(validator, chatboxLogger))
-		adminGroup.Use(adminRateLimitMiddleware(adminLimiter, chatboxLogger))
-		{
-			adminGroup.GET("/sessions", handleListSessions(storageService, sessionManager, chatboxLogger))
-			adminGroup.GET("/metrics", handleGetMetrics(storageService, chatboxLogger))
-			adminGroup.POST("/takeover/:sessionID", handleAdminTakeover(messageRouter, chatboxLogger))
-		}
+	// Create the system prompt template service (config templates plus admin-managed ones)
+	promptService, err := newPromptService(config, storageService)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	messageRouter.SetPromptRenderer(promptService)
 
-		// Health check endpoints (rate limited to prevent abuse)
-		chatGroup.GET("/healthz", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleHealthCheck)
-		chatGroup.GET("/readyz", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleReadyCheck(mongo, llmService, chatboxLogger))
+	// Create the A/B experiment service (config experiments plus admin-managed ones)
+	experimentService, err := newExperimentService(config, storageService)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
 	}
+	messageRouter.SetExperiments(experimentService)
 
-	// Prometheus metrics endpoint — under prefix, restricted to configured networks
-	metricsAllowedStr, _ := config.ConfigStringWithDefault("chatbox.metrics_allowed_networks", constants.DefaultMetricsAllowedNetworks)
-	metricsNets := parseNetworks(metricsAllowedStr, chatboxLogger)
-	chatGroup.GET("/metrics/prometheus",
-		metricsNetworkMiddleware(metricsNets, chatboxLogger),
-		publicRateLimitMiddleware(publicLimiter, chatboxLogger),
-		gin.WrapH(promhttp.Handler()),
-	)
+	// Load the role permissions for admin endpoints and admin WebSocket actions
+	policy, err := newAuthzPolicy(config, storageService)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	messageRouter.SetPolicy(policy)
 
-	// Warn if MongoDB URI appears to have no authentication (L4)
-	mongoURI, _ := config.ConfigStringWithDefault("database.uri", "")
-	if mongoURI == "" {
-		mongoURI, _ = config.ConfigStringWithDefault("MONGO_URI", "")
+	// Load the API keys for server-to-server admin calls (nil when none are configured)
+	apiKeys, err := newAPIKeyRegistry(config, storageService)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
 	}
-	if mongoURI != "" && !strings.Contains(mongoURI, "@") {
-		chatboxLogger.Warn("MongoDB URI does not contain authentication credentials — ensure auth is configured for production")
+	// No else needed: optional operation (log only when enabled)
+	if apiKeys != nil {
+		chatboxLogger.Info("API key authentication enabled", "keys", apiKeys.Len())
 	}
 
-	chatboxLogger.Info("Chatbox service registered successfully",
-		"websocket_endpoint", pathPrefix+"/ws",
-		"admin_endpoints", pathPrefix+"/admin/*",
-		"health_endpoints", pathPrefix+"/healthz, "+pathPrefix+"/readyz",
-		"metrics_endpoint", pathPrefix+"/metrics/prometheus",
-	)
-
-	return nil
-}
-
-// metricsMiddleware records HTTP request duration for Prometheus monitoring
-// securityHeadersMiddleware adds standard HTTP security headers to all responses.
-func securityHeadersMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("X-Content-Type-Options", "nosniff")
-		c.Header("X-Frame-Options", "DENY")
-		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
-		c.Header("X-XSS-Protection", "1; mode=block")
-		c.Next()
+	// Load per-user daily LLM token budget (0 disables enforcement)
+	// Priority: Environment variable > Config file
+	dailyTokenBudget, err := config.ConfigIntWithDefault("chatbox.daily_token_budget", 0)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get daily token budget: %w", err)
 	}
-}
-
-func metricsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-		c.Next()
-		metrics.HTTPRequestDuration.With(prometheus.Labels{
-			"endpoint": c.FullPath(),
-			"method":   c.Request.Method,
-		}).Observe(time.Since(start).Seconds())
+	// No else needed: optional operation (environment override)
+	if envBudget := os.Getenv("CHATBOX_DAILY_TOKEN_BUDGET"); envBudget != "" {
+		// No else needed: optional operation (logging based on parse result)
+		if _, err := fmt.Sscanf(envBudget, "%d", &dailyTokenBudget); err != nil {
+			chatboxLogger.Warn("Invalid CHATBOX_DAILY_TOKEN_BUDGET environment variable, using config value", "value", envBudget)
+		}
+	}
+	// No else needed: optional operation (budget only enforced when configured)
+	if dailyTokenBudget > 0 {
+		messageRouter.SetTokenBudget(ratelimit.NewTokenBudgetLimiter(dailyTokenBudget))
+		chatboxLogger.Info("Daily token budget enabled", "tokens_per_user", dailyTokenBudget)
+	}
+	// Count token usage with each model's tokenizer (see [chatbox.models.<id>] tokenizer)
+	// No else needed: optional operation (no models to count for in human-only mode)
+	if llmService != nil {
+		messageRouter.SetTokenCounter(llmService)
+	}
+	// Record LLM usage and cost per call for finance reports (see [chatbox.costs])
+	var costs *cost.Calculator
+	costs, err = loadCostCalculator(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	messageRouter.SetUsageRecorder(storageService, costs)
+	messageRouter.SetImpersonationAuditor(storageService)
+	// Rank sessions waiting for an admin (see [chatbox.help_queue])
+	helpQueueScorer, err := loadHelpQueueScorer(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
 	}
-}
-
-// publicRateLimitMiddleware creates a Gin middleware for rate limiting public endpoints
-// (healthz, readyz, metrics) by client IP to prevent abuse.
-func publicRateLimitMiddleware(limiter *ratelimit.MessageLimiter, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Use Gin's ClientIP() which respects trusted proxies to prevent X-Forwarded-For spoofing
-		clientIP := c.ClientIP()
 
-		if !limiter.Allow(clientIP) {
-			retryAfter := limiter.GetRetryAfter(clientIP)
-			retryAfterSeconds := (retryAfter + constants.MillisecondsPerSecond - 1) / constants.MillisecondsPerSecond
-			if retryAfterSeconds < constants.MinRetryAfterSeconds {
-				retryAfterSeconds = constants.MinRetryAfterSeconds
-			}
-			c.Header(constants.HeaderRetryAfter, fmt.Sprintf("%d", retryAfterSeconds))
+	// Create admin rate limiter
+	adminRateWindow, adminRateLimit, err := loadAdminRateLimit(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
 
-			c.JSON(constants.StatusTooManyRequests, gin.H{
-				"error":   "rate_limit_exceeded",
-				"message": constants.ErrMsgRateLimitExceeded,
-			})
-			c.Abort()
-			return
-		}
+	// Connect to Redis when rate limits are shared across replicas (nil for the memory backend)
+	redisClient, err := newRateLimitRedisClient(config, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
 
-		c.Next()
+	var adminLimiter ratelimit.Limiter = ratelimit.NewMessageLimiter(adminRateWindow, adminRateLimit)
+	// No else needed: optional operation (distributed limits only when configured)
+	if redisClient != nil {
+		adminLimiter = ratelimit.NewRedisLimiter(redisClient, "admin", adminRateWindow, adminRateLimit)
+		messageRouter.SetMessageLimiter(ratelimit.NewRedisLimiter(redisClient, "message", constants.DefaultRateWindow, constants.DefaultRateLimit))
 	}
-}
 
-// validateEncryptionKey checks if the encryption key is exactly 32 bytes
-// Returns error if key is provided but not 32 bytes
-// Returns nil if key is empty (encryption disabled) or exactly 32 bytes
-func validateEncryptionKey(key []byte) error {
-	keyLen := len(key)
+	chatboxLogger.Info("Admin rate limiter configured",
+		"rate_limit", adminRateLimit,
+		"window", adminRateWindow)
 
-	// Empty key is valid (encryption disabled)
-	if keyLen == 0 {
-		return nil
+	// Per-endpoint admin rate limit policies, on top of the global admin limit
+	ratePolicies, err := loadRatePolicies(config, redisClient, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
 	}
 
-	// 32 bytes is valid for AES-256
-	if keyLen == constants.EncryptionKeyLength {
-		return nil
+	// Route WebSocket traffic for connections held by other replicas (started below)
+	clusterBridge, clusterRedis, err := loadClusterBridge(config, redisClient, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (cluster routing only when enabled)
+	if clusterBridge != nil {
+		messageRouter.SetClusterBridge(clusterBridge)
 	}
 
-	// Any other length is invalid
-	return fmt.Errorf("encryption key must be exactly %d bytes for AES-256, got %d bytes. Please provide a valid %d-byte key or remove the key to disable encryption", constants.EncryptionKeyLength, keyLen, constants.EncryptionKeyLength)
-}
+	// Create JWT validator
+	validator := auth.NewJWTValidator(jwtSecret)
 
-// authMiddleware creates a Gin middleware for JWT authentication
-func authMiddleware(validator *auth.JWTValidator, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Extract token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		token, err := util.ExtractBearerToken(authHeader)
-		// No else needed: early return pattern (guard clause)
-		if err != nil {
-			httperrors.RespondUnauthorized(c, httperrors.MsgInvalidAuthHeader)
-			c.Abort()
-			return
-		}
+	// Create WebSocket handler with router
+	wsHandler := websocket.NewHandler(validator, messageRouter, chatboxLogger, maxMessageSize)
+	wsHandler.SetPolicy(policy)
+	wsHandler.SetUserBlocks(storageService)
+
+	// Keyword alerts: user messages matching the tenant's watchlist are tagged
+	// and pushed to the admin dashboard streams
+	var keywordAlerts *watchlist.Service
+	keywordAlerts = watchlist.NewService(func(tenantID string) ([]*watchlist.Keyword, error) {
+		return storageService.ForTenant(tenantID).ListKeywords()
+	})
+	messageRouter.SetKeywordAlerts(keywordAlerts, wsHandler)
 
-		// Validate token
-		claims, err := validator.ValidateToken(token)
+	// Load user memory setting: facts users state about themselves are kept
+	// across sessions and offered to prompt templates as {{user_memory}}
+	// Priority: Environment variable > Config file
+	memoryEnabled, err := config.ConfigBoolWithDefault("chatbox.memory.enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get user memory setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envMemory := os.Getenv("CHATBOX_MEMORY_ENABLED"); envMemory != "" {
+		memoryEnabled = envMemory == "true"
+	}
+	// No else needed: optional operation (only enable if configured)
+	if memoryEnabled {
 		// No else needed: early return pattern (guard clause)
-		if err != nil {
-			// Log detailed error server-side
-			logger.Warn("Token validation failed",
-				"error", err,
-				"component", "auth")
-			// Send generic error to client
-			httperrors.RespondInvalidToken(c)
-			c.Abort()
-			return
+		if anonymized {
+			return fmt.Errorf("chatbox.memory.enabled cannot be combined with chatbox.anonymized_analytics")
 		}
+		messageRouter.SetMemory(storageService)
+		chatboxLogger.Info("User memory enabled", "max_facts", constants.MaxMemoryFacts)
+	}
 
-		// Check for admin role
-		hasAdminRole := false
-		for _, role := range claims.Roles {
-			// No else needed: optional operation (role checking loop)
-			if role == constants.RoleAdmin || role == constants.RoleChatAdmin {
-				hasAdminRole = true
-				break
-			}
-		}
+	// Load permessage-deflate compression setting for WebSocket connections
+	// Priority: Environment variable > Config file
+	wsCompression, err := config.ConfigBoolWithDefault("chatbox.ws_compression", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get ws compression setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envWSCompression := os.Getenv("CHATBOX_WS_COMPRESSION"); envWSCompression != "" {
+		wsCompression = envWSCompression == "true"
+	}
+	// No else needed: optional operation (only enable if configured)
+	if wsCompression {
+		wsHandler.SetCompression(true)
+		chatboxLogger.Info("WebSocket permessage-deflate compression enabled")
+	}
 
+	// Load the assembled size limit of messages sent in chunks (0 disables them)
+	// Priority: Environment variable > Config file
+	maxChunkedSize, err := config.ConfigIntWithDefault("chatbox.max_chunked_message_size", constants.DefaultMaxChunkedMessageSize)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get max chunked message size: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envMaxChunkedSize := os.Getenv("MAX_CHUNKED_MESSAGE_SIZE"); envMaxChunkedSize != "" {
+		parsed, err := strconv.Atoi(envMaxChunkedSize)
 		// No else needed: early return pattern (guard clause)
-		if !hasAdminRole {
-			logger.Warn("Insufficient permissions for admin endpoint",
-				"user_id", claims.UserID,
-				"roles", claims.Roles,
-				"component", "auth")
-			httperrors.RespondForbidden(c)
-			c.Abort()
-			return
+		if err != nil {
+			return fmt.Errorf("invalid MAX_CHUNKED_MESSAGE_SIZE %q: %w", envMaxChunkedSize, err)
 		}
-
-		// Store claims in context
-		c.Set("claims", claims)
-		c.Next()
+		maxChunkedSize = parsed
 	}
-}
+	// No else needed: early return pattern (guard clause)
+	if maxChunkedSize < 0 {
+		return fmt.Errorf("chatbox.max_chunked_message_size must not be negative")
+	}
+	wsHandler.SetChunkedMessageLimit(int64(maxChunkedSize))
+	chatboxLogger.Info("Chunked message limit", "size_bytes", maxChunkedSize)
 
-// adminRateLimitMiddleware creates a Gin middleware for admin endpoint rate limiting
-func adminRateLimitMiddleware(limiter *ratelimit.MessageLimiter, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Get claims from context (set by authMiddleware)
-		claimsInterface, exists := c.Get("claims")
-		// No else needed: early return pattern (guard clause - let authMiddleware handle missing claims)
-		if !exists {
-			// If no claims, let authMiddleware handle it
-			c.Next()
-			return
-		}
-
-		claims, ok := claimsInterface.(*auth.Claims)
-		// No else needed: early return pattern (guard clause)
-		if !ok {
-			util.LogError(logger, "admin_rate_limit", "validate claims type", fmt.Errorf("invalid claims type in context"))
-			httperrors.RespondInternalError(c)
-			c.Abort()
-			return
-		}
-
-		// Check rate limit
-		// No else needed: early return pattern (guard clause)
-		if !limiter.Allow(claims.UserID) {
-			retryAfter := limiter.GetRetryAfter(claims.UserID)
-
-			logger.Warn("Admin rate limit exceeded",
-				"user_id", claims.UserID,
-				"endpoint", c.Request.URL.Path,
-				"retry_after_ms", retryAfter,
-				"component", "admin_rate_limit")
-
-			// CRITICAL FIX M3: Convert milliseconds to seconds properly with ceiling to avoid 0
-			retryAfterSeconds := (retryAfter + constants.MillisecondsPerSecond - 1) / constants.MillisecondsPerSecond // Round up to nearest second
-			// No else needed: optional operation (minimum retry after enforcement)
-			if retryAfterSeconds < constants.MinRetryAfterSeconds {
-				retryAfterSeconds = constants.MinRetryAfterSeconds
-			}
-			c.Header(constants.HeaderRetryAfter, fmt.Sprintf("%d", retryAfterSeconds))
-
-			// Return 429 Too Many Requests
-			c.JSON(constants.StatusTooManyRequests, gin.H{
-				"error":          "rate_limit_exceeded",
-				"message":        constants.ErrMsgRateLimitExceeded,
-				"retry_after_ms": retryAfter,
-			})
-			c.Abort()
-			return
-		}
-
-		c.Next()
+	// Load strict message schema: client messages with fields their type does not
+	// declare are rejected instead of ignored
+	// Priority: Environment variable > Config file
+	strictSchema, err := config.ConfigBoolWithDefault("chatbox.strict_message_schema", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get strict message schema setting: %w", err)
 	}
-}
-
-// userAuthMiddleware creates a Gin middleware for JWT authentication (without admin check)
-func userAuthMiddleware(validator *auth.JWTValidator, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Extract token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		token, err := util.ExtractBearerToken(authHeader)
-		// No else needed: early return pattern (guard clause)
-		if err != nil {
-			httperrors.RespondUnauthorized(c, httperrors.MsgInvalidAuthHeader)
-			c.Abort()
-			return
-		}
-
-		// Validate token
-		claims, err := validator.ValidateToken(token)
-		// No else needed: early return pattern (guard clause)
-		if err != nil {
-			// Log detailed error server-side
-			logger.Warn("Token validation failed",
-				"error", err,
-				"component", "auth")
-			// Send generic error to client
-			httperrors.RespondInvalidToken(c)
-			c.Abort()
-			return
-		}
+	// No else needed: optional operation (environment override)
+	if envStrictSchema := os.Getenv("CHATBOX_STRICT_MESSAGE_SCHEMA"); envStrictSchema != "" {
+		strictSchema = envStrictSchema == "true"
+	}
+	wsHandler.SetStrictSchema(strictSchema)
 
-		// Store claims in context
-		c.Set("claims", claims)
-		c.Next()
+	// Reconnect backoff guidance, raised while connection attempts storm in
+	reconnectConfig, err := loadReconnectConfig(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
 	}
-}
+	// No else needed: early return pattern (guard clause)
+	if err := wsHandler.SetReconnectPolicy(reconnectConfig); err != nil {
+		return fmt.Errorf("invalid chatbox.reconnect: %w", err)
+	}
+	chatboxLogger.Info("Reconnect policy",
+		"base_delay", reconnectConfig.BaseDelay,
+		"max_delay", reconnectConfig.MaxDelay,
+		"storm_limit", reconnectConfig.StormLimit)
 
-// handleUserSessions returns a handler for listing the authenticated user's sessions
-func handleUserSessions(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Get claims from context
-		claimsInterface, exists := c.Get("claims")
+	// Load guest mode: /ws admits clients without a token as anonymous guests
+	// Priority: Environment variable > Config file
+	guestMode, err := config.ConfigBoolWithDefault("chatbox.guest_mode", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get guest mode setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envGuestMode := os.Getenv("CHATBOX_GUEST_MODE"); envGuestMode != "" {
+		guestMode = envGuestMode == "true"
+	}
+	var guestLimiter ratelimit.Limiter // nil unless guest mode is enabled
+	// No else needed: optional operation (guests only when enabled)
+	if guestMode {
+		guestTokenTTLStr, err := config.ConfigStringWithDefault("chatbox.guest_token_ttl", constants.DefaultGuestTokenTTL.String())
 		// No else needed: early return pattern (guard clause)
-		if !exists {
-			httperrors.RespondUnauthorized(c, "")
-			return
+		if err != nil {
+			return fmt.Errorf("failed to get guest token TTL: %w", err)
 		}
-
-		claims, ok := claimsInterface.(*auth.Claims)
+		guestTokenTTL, err := time.ParseDuration(guestTokenTTLStr)
 		// No else needed: early return pattern (guard clause)
-		if !ok {
-			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
-			httperrors.RespondInternalError(c)
-			return
+		if err != nil || guestTokenTTL <= 0 {
+			return fmt.Errorf("invalid guest token TTL %q: must be a positive duration", guestTokenTTLStr)
 		}
-
-		// Get user's sessions (capped at DefaultSessionLimit)
-		sessions, err := storageService.ListUserSessions(claims.UserID, constants.DefaultSessionLimit)
+		guestRateLimit, err := loadGuestRateLimit(config)
 		// No else needed: early return pattern (guard clause)
 		if err != nil {
-			// Log detailed error server-side
-			util.LogError(logger, "http", "list user sessions", err, "user_id", claims.UserID)
-			// Send generic error to client
-			httperrors.RespondInternalError(c)
-			return
+			return err
 		}
 
-		c.JSON(constants.StatusOK, gin.H{
-			"sessions":  sessions,
-			"user_id":   claims.UserID,
-			"count":     len(sessions),
-			"limit":     constants.DefaultSessionLimit,
-			"truncated": len(sessions) == constants.DefaultSessionLimit,
-		})
-	}
-}
-
-// handleGetSessionMessages returns a handler for fetching a single session's messages.
-// SECURITY: Enforces session ownership — users can only access their own sessions.
-func handleGetSessionMessages(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		claimsInterface, exists := c.Get("claims")
-		if !exists {
-			httperrors.RespondUnauthorized(c, "")
-			return
-		}
-		claims, ok := claimsInterface.(*auth.Claims)
-		if !ok {
-			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
-			httperrors.RespondInternalError(c)
-			return
+		guestLimiter = ratelimit.NewMessageLimiter(constants.DefaultRateWindow, guestRateLimit)
+		// No else needed: optional operation (distributed limits only when configured)
+		if redisClient != nil {
+			guestLimiter = ratelimit.NewRedisLimiter(redisClient, "guest_message", constants.DefaultRateWindow, guestRateLimit)
 		}
+		messageRouter.SetGuestMessageLimiter(guestLimiter)
+		wsHandler.SetGuestMode(guestTokenTTL)
+		chatboxLogger.Info("Guest mode enabled",
+			"token_ttl", guestTokenTTL,
+			"rate_limit", guestRateLimit)
+	}
 
-		sessionID := c.Param("sessionID")
-		if sessionID == "" {
-			httperrors.RespondBadRequest(c, "session ID is required")
-			return
-		}
+	// Load the lifetime of one-time WebSocket connection tickets
+	// Priority: Environment variable > Config file
+	wsTicketTTLStr, err := config.ConfigStringWithDefault("chatbox.ws_ticket_ttl", constants.DefaultWSTicketTTL.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get ws ticket TTL: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envTTL := os.Getenv("CHATBOX_WS_TICKET_TTL"); envTTL != "" {
+		wsTicketTTLStr = envTTL
+	}
+	wsTicketTTL, err := time.ParseDuration(wsTicketTTLStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || wsTicketTTL <= 0 || wsTicketTTL > constants.MaxWSTicketTTL {
+		return fmt.Errorf("invalid ws ticket TTL %q: must be a positive duration of at most %s", wsTicketTTLStr, constants.MaxWSTicketTTL)
+	}
+	var ticketStore wsticket.Store = wsticket.NewMemoryStore(wsTicketTTL)
+	// No else needed: optional operation (tickets redeemable on any replica only with Redis)
+	if redisClient != nil {
+		ticketStore = wsticket.NewRedisStore(redisClient, wsTicketTTL)
+	}
+	wsHandler.SetTickets(ticketStore)
 
-		sess, err := storageService.GetSession(sessionID)
-		if err != nil {
-			util.LogError(logger, "http", "get session", err, "session_id", sessionID, "user_id", claims.UserID)
-			httperrors.RespondNotFound(c, "Session not found")
-			return
-		}
+	// Load the partner origins allowed to embed the chat widget (nil when none are configured)
+	embedRegistry, err := newEmbedRegistry(config, redisClient)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
 
-		// Verify ownership
-		if sess.UserID != claims.UserID {
-			logger.Warn("Session ownership violation",
-				"session_id", sessionID,
-				"session_owner", sess.UserID,
-				"requesting_user", claims.UserID)
-			httperrors.RespondNotFound(c, "Session not found")
-			return
-		}
+	// Create public endpoint rate limiter (per-IP, prevents abuse of healthz/readyz/metrics)
+	publicLimiter := ratelimit.NewMessageLimiter(1*time.Minute, constants.PublicEndpointRate)
 
-		c.JSON(constants.StatusOK, gin.H{
-			"session_id": sess.ID,
-			"name":       sess.Name,
-			"model_id":   sess.ModelID,
-			"messages":   sess.Messages,
-		})
+	// Create connection throttle for /ws and /sse (per-IP, checked before authentication)
+	ipConnectLimit, err := config.ConfigIntWithDefault("chatbox.ws_ip_connect_limit", constants.DefaultIPConnectLimit)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to get ws ip connect limit: %w", err)
 	}
-}
+	// No else needed: early return pattern (guard clause)
+	if ipConnectLimit < 0 {
+		return fmt.Errorf("chatbox.ws_ip_connect_limit must not be negative")
+	}
+	ipLimiter := ratelimit.NewIPLimiter(constants.DefaultRateWindow, ipConnectLimit)
 
-// handleEndSession ends an active session for the authenticated user.
-func handleEndSession(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		claimsInterface, exists := c.Get("claims")
-		if !exists {
-			httperrors.RespondUnauthorized(c, "")
-			return
-		}
-		claims, ok := claimsInterface.(*auth.Claims)
-		if !ok {
-			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
-			httperrors.RespondInternalError(c)
-			return
+	// Configure allowed origins for WebSocket connections
+	// SECURITY: When no origins are configured, ALL origins are accepted.
+	// This is acceptable only in development. In production, always configure
+	// allowed_origins to prevent cross-site WebSocket hijacking.
+	origins, err := loadAllowedOrigins(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (configuration with fallback logging)
+	if len(origins) > 0 {
+		// Sites embedding the widget connect from their own origin
+		// No else needed: optional operation (only when embedding is configured)
+		if embedRegistry != nil {
+			origins = append(origins, embedRegistry.Origins()...)
 		}
+		wsHandler.SetAllowedOrigins(origins)
+	} else {
+		chatboxLogger.Warn("No allowed origins configured, allowing all origins (development mode)")
+	}
 
-		sessionID := c.Param("sessionID")
-		if sessionID == "" {
-			httperrors.RespondBadRequest(c, "session ID is required")
-			return
-		}
+	// Build the CORS middleware, applied once the routes are registered
+	corsOrigins, err := loadCORSOrigins(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	corsHandler, err := newCORSHandler(corsOrigins)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("invalid chatbox.cors_allowed_origins: %w", err)
+	}
+	corsMiddleware := &reloadableCORS{handler: corsHandler}
+
+	// Apply the safe-to-change config sections on SIGHUP or POST /admin/reload
+	reloader := &configReloader{
+		load:          loadConfiguration,
+		wsHandler:     wsHandler,
+		cors:          corsMiddleware,
+		embedRegistry: embedRegistry,
+		adminLimiter:  adminLimiter,
+		guestLimiter:  guestLimiter,
+		ratePolicies:  ratePolicies,
+		llmService:    llmService,
+		logger:        chatboxLogger,
+	}
 
-		// Verify ownership via storage
-		sess, err := storageService.GetSession(sessionID)
-		if err != nil {
-			httperrors.RespondNotFound(c, "Session not found")
-			return
-		}
-		if sess.UserID != claims.UserID {
-			httperrors.RespondNotFound(c, "Session not found")
-			return
+	// Subscribe to this replica's cluster channel
+	// No else needed: optional operation (cluster routing only when enabled)
+	if clusterBridge != nil {
+		// No else needed: early return pattern (guard clause)
+		if err := clusterBridge.Start(messageRouter.HandleClusterEnvelope); err != nil {
+			return fmt.Errorf("failed to start cluster routing: %w", err)
 		}
+	}
 
-		// End in-memory session (ignore not-found — may already be expired from memory)
-		_ = sessionManager.EndSession(sessionID)
-
-		// Persist to storage
-		if err := storageService.EndSession(sessionID, time.Now()); err != nil {
-			util.LogError(logger, "http", "end session", err, "session_id", sessionID)
-			httperrors.RespondInternalError(c)
-			return
+	// Set up OpenTelemetry tracing last, so a failed Register() leaves no exporter running
+	tracerProvider, err := newTracerProvider(config, chatboxLogger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: optional operation (cluster routing only when enabled)
+		if clusterBridge != nil {
+			clusterBridge.Close()
 		}
-
-		c.JSON(constants.StatusOK, gin.H{"status": "ended"})
+		return err
 	}
-}
 
-// handleShareSession generates or retrieves a share token for a session.
-// SECURITY: Enforces session ownership — users can only share their own sessions.
-func handleShareSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		claimsInterface, exists := c.Get("claims")
-		if !exists {
-			httperrors.RespondUnauthorized(c, "")
-			return
-		}
-		claims, ok := claimsInterface.(*auth.Claims)
-		if !ok {
-			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
-			httperrors.RespondInternalError(c)
-			return
-		}
+	// Start background cleanup goroutines only after all validation is complete,
+	// so we don't leak goroutines if Register() returns an error.
+	sessionManager.StartCleanup()
+	adminLimiter.StartCleanup()
+	publicLimiter.StartCleanup()
+	ipLimiter.StartCleanup()
+	ratePolicies.StartCleanup()
+	uploadService.StartGarbageCollector(constants.DefaultBlobGCInterval, constants.DefaultBlobGCGracePeriod, chatboxLogger)
+	scheduler.Start()
+	// No else needed: optional operation (retention only when configured)
+	if retentionDays > 0 {
+		chatboxLogger.Info("Session retention enabled",
+			"retention_days", retentionDays,
+			"restore_grace_days", restoreGraceDays)
+	}
+	// No else needed: optional operation (messages written one by one unless enabled)
+	if messageBatching {
+		storageService.StartMessageBatching(messageBatchInterval, messageBatchSize)
+		chatboxLogger.Info("Message write batching enabled",
+			"interval", messageBatchInterval,
+			"size", messageBatchSize)
+	}
+	// No else needed: optional operation (remote write only when configured)
+	if metricsPusher != nil {
+		metricsPusher.Start()
+	}
+	// No else needed: optional operation (alerts only when rules are configured)
+	if alertMonitor != nil {
+		alertMonitor.Start()
+	}
+	// No else needed: optional operation (sessions are only refreshed when enabled)
+	if changeStream {
+		storageService.StartSessionWatch(func(change storage.SessionChange) {
+			messageRouter.ApplySessionChange(change.SessionID, change.Session)
+		})
+		chatboxLogger.Info("Session change stream enabled")
+	}
 
-		sessionID := c.Param("sessionID")
-		if sessionID == "" {
-			httperrors.RespondBadRequest(c, "session ID is required")
-			return
-		}
+	// Store global references for graceful shutdown.
+	// Stop any previously-registered instances to prevent goroutine leaks
+	// when Register() is called multiple times (tests, hot-reload).
+	shutdownMu.Lock()
+	if globalSessionMgr != nil {
+		globalSessionMgr.StopCleanup()
+	}
+	if globalMessageRouter != nil {
+		globalMessageRouter.Shutdown()
+	}
+	if globalAdminLimiter != nil {
+		globalAdminLimiter.StopCleanup()
+	}
+	if globalPublicLimiter != nil {
+		globalPublicLimiter.StopCleanup()
+	}
+	if globalIPLimiter != nil {
+		globalIPLimiter.StopCleanup()
+	}
+	globalRatePolicies.StopCleanup()
+	if globalCluster != nil {
+		globalCluster.Close()
+	}
+	if globalClusterRedis != nil {
+		_ = globalClusterRedis.Close()
+	}
+	if globalRedis != nil {
+		_ = globalRedis.Close()
+	}
+	if globalWSHandler != nil {
+		_ = globalWSHandler.ShutdownWithContext(context.Background())
+	}
+	if globalWebhooks != nil {
+		_ = globalWebhooks.Shutdown(context.Background())
+	}
+	if globalMailer != nil {
+		_ = globalMailer.Shutdown(context.Background())
+	}
+	if globalEvents != nil {
+		_ = globalEvents.Shutdown(context.Background())
+	}
+	if globalSentiment != nil {
+		_ = globalSentiment.Shutdown(context.Background())
+	}
+	if globalBackups != nil {
+		_ = globalBackups.Shutdown(context.Background())
+	}
+	if globalScheduler != nil {
+		globalScheduler.Stop()
+	}
+	if globalStorage != nil {
+		globalStorage.StopMessageBatching()
+		globalStorage.StopSessionWatch()
+	}
+	if globalUploads != nil {
+		globalUploads.StopGarbageCollector()
+	}
+	if globalMetricsPusher != nil {
+		globalMetricsPusher.Stop()
+	}
+	if globalAlerts != nil {
+		globalAlerts.Stop()
+	}
+	if globalTracer != nil {
+		_ = globalTracer.Shutdown(context.Background())
+	}
+	globalWSHandler = wsHandler
+	globalSessionMgr = sessionManager
+	globalMessageRouter = messageRouter
+	globalAdminLimiter = adminLimiter
+	globalPublicLimiter = publicLimiter
+	globalIPLimiter = ipLimiter
+	globalRatePolicies = ratePolicies
+	globalRedis = redisClient
+	globalCluster = clusterBridge
+	globalClusterRedis = clusterRedis
+	globalWebhooks = webhookDispatcher
+	globalMailer = transcriptMailer
+	globalEvents = eventBus
+	globalSentiment = sentimentPipeline
+	globalBackups = backupRunner
+	globalScheduler = scheduler
+	globalStorage = storageService
+	globalUploads = uploadService
+	globalMetricsPusher = metricsPusher
+	globalAlerts = alertMonitor
+	globalTracer = tracerProvider
+	globalReloader = reloader
+	globalLogger = chatboxLogger
+	shutdownMu.Unlock()
 
-		// Verify ownership
-		sess, err := storageService.GetSession(sessionID)
-		if err != nil {
-			httperrors.RespondNotFound(c, "Session not found")
-			return
+	// Assign every request an ID, continuing the caller's X-Request-ID, and log
+	// an access line once it completes. Registered first so requests rejected by
+	// later middleware are logged too.
+	r.Use(requestid.Middleware())
+	r.Use(accessLogMiddleware(chatboxLogger))
+
+	// Configure CORS middleware; its allowed origins are reloadable
+	r.Use(corsMiddleware.handle)
+	// No else needed: optional operation (CORS configuration with fallback logging)
+	if len(corsOrigins) > 0 {
+		chatboxLogger.Info("CORS middleware configured",
+			"allowed_origins", corsOrigins,
+			"allow_credentials", true)
+	} else {
+		chatboxLogger.Warn("No CORS origins configured, CORS middleware not enabled")
+	}
+
+	// Configure trusted proxies to prevent X-Forwarded-For spoofing.
+	// c.ClientIP() will only trust X-Forwarded-For from these networks.
+	trustedProxiesStr, _ := config.ConfigStringWithDefault("chatbox.trusted_proxies", constants.DefaultTrustedProxies)
+	if trustedProxiesStr != "" {
+		proxies := strings.Split(trustedProxiesStr, ",")
+		for i, p := range proxies {
+			proxies[i] = strings.TrimSpace(p)
 		}
-		if sess.UserID != claims.UserID {
-			httperrors.RespondNotFound(c, "Session not found")
-			return
+		if err := r.SetTrustedProxies(proxies); err != nil {
+			chatboxLogger.Warn("Failed to set trusted proxies", "error", err)
+		} else {
+			chatboxLogger.Info("Trusted proxies configured", "proxies", proxies)
 		}
+	}
 
-		// Check if already shared — return existing token
-		existingToken, err := storageService.GetShareToken(sessionID)
-		if err != nil {
-			util.LogError(logger, "http", "get share token", err, "session_id", sessionID)
-			httperrors.RespondInternalError(c)
-			return
+	// Apply security headers middleware
+	r.Use(securityHeadersMiddleware())
+
+	// Apply metrics middleware to record HTTP request duration
+	r.Use(metricsMiddleware())
+
+	// Continue incoming traces and wrap each request in a server span.
+	// Without tracing enabled the global provider is a no-op.
+	r.Use(tracing.Middleware())
+
+	chatboxLogger.Info("Using HTTP path prefix", "prefix", pathPrefix)
+
+	// OpenAPI spec of the REST routes below, encoded once
+	openAPISpec, err := buildOpenAPISpec(pathPrefix)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+
+	// Register routes
+	chatGroup := r.Group(pathPrefix)
+	// Every request made with an impersonation token is audited under the admin's ID
+	chatGroup.Use(impersonationAuditMiddleware(storageService, chatboxLogger))
+	{
+		// WebSocket endpoint - use Gin context adapter
+		chatGroup.GET("/ws", ipThrottleMiddleware(ipLimiter, chatboxLogger), func(c *gin.Context) {
+			moveQueryTokenToHeader(c)
+			recordClientIP(c)
+			wsHandler.HandleWebSocket(c.Writer, c.Request)
+		})
+
+		// Server-Sent Events transport for networks that block WebSockets.
+		// Shares the WebSocket handler's authentication, limits, and router pipeline.
+		chatGroup.GET("/sse", ipThrottleMiddleware(ipLimiter, chatboxLogger), func(c *gin.Context) {
+			moveQueryTokenToHeader(c)
+			recordClientIP(c)
+			wsHandler.HandleSSE(c.Writer, c.Request)
+		})
+		chatGroup.POST("/sse/messages", func(c *gin.Context) {
+			wsHandler.HandleSSEMessage(c.Writer, c.Request)
+		})
+		// One-time connection tickets, so browsers need not put their JWT in the URL
+		chatGroup.POST("/ws/ticket", chatAuthMiddleware(validator, chatboxLogger), handleIssueWSTicket(ticketStore, chatboxLogger))
+
+		// Read-only admin spectating of a live session. Registered outside adminGroup
+		// because browsers cannot set headers on WebSocket upgrades; the handler
+		// authenticates the ?token= parameter and checks the admin role itself.
+		chatGroup.GET("/admin/sessions/:sessionID/watch", func(c *gin.Context) {
+			moveQueryTokenToHeader(c)
+			// No else needed: optional operation (only started watches are audited)
+			if claims := wsHandler.HandleWatch(c.Writer, c.Request, c.Param("sessionID")); claims != nil {
+				recordAdminAudit(c, storageService, claims, constants.AuditActionWatch, constants.StatusSwitchingProtocols, chatboxLogger)
+			}
+		})
+
+		// Live dashboard counters of this replica, pushed over a WebSocket. Registered
+		// outside adminGroup for the same reason as the watch endpoint.
+		chatGroup.GET("/admin/metrics/stream", func(c *gin.Context) {
+			moveQueryTokenToHeader(c)
+			// No else needed: optional operation (only started streams are audited)
+			if claims := wsHandler.HandleMetricsStream(c.Writer, c.Request); claims != nil {
+				recordAdminAudit(c, storageService, claims, constants.AuditActionStreamMetrics, constants.StatusSwitchingProtocols, chatboxLogger)
+			}
+		})
+
+		// User session endpoints (authenticated but not admin-only)
+		chatGroup.GET("/models", userAuthMiddleware(validator, chatboxLogger), handleListModels(llmService))
+		// Session history endpoints read and write MongoDB directly; writes are
+		// refused while read-only
+		writes := readOnlyMiddleware(readOnlyMode)
+		chatGroup.GET("/sessions", userAuthMiddleware(validator, chatboxLogger), handleUserSessions(storageService, chatboxLogger))
+		chatGroup.POST("/sessions/claim", userAuthMiddleware(validator, chatboxLogger), writes, handleClaimGuestSessions(validator, storageService, sessionManager, chatboxLogger))
+		chatGroup.GET("/sessions/shared", userAuthMiddleware(validator, chatboxLogger), handleSharedSessions(storageService, chatboxLogger))
+		chatGroup.GET("/sessions/:sessionID", userAuthMiddleware(validator, chatboxLogger), handleGetSessionMessages(storageService, chatboxLogger))
+		chatGroup.PATCH("/sessions/:sessionID", userAuthMiddleware(validator, chatboxLogger), writes, handleRenameSession(storageService, sessionManager, chatboxLogger))
+		chatGroup.DELETE("/sessions/:sessionID", userAuthMiddleware(validator, chatboxLogger), writes, handleDeleteSession(storageService, sessionManager, sessionEndPublisher, chatboxLogger))
+		chatGroup.POST("/sessions/:sessionID/end", userAuthMiddleware(validator, chatboxLogger), writes, handleEndSession(storageService, sessionManager, sessionEndPublisher, transcriptMailer, chatboxLogger))
+		chatGroup.POST("/sessions/:sessionID/share", userAuthMiddleware(validator, chatboxLogger), writes, handleShareSession(storageService, chatboxLogger))
+		chatGroup.DELETE("/sessions/:sessionID/share", userAuthMiddleware(validator, chatboxLogger), writes, handleRevokeShare(storageService, chatboxLogger))
+		chatGroup.GET("/sessions/:sessionID/grants", userAuthMiddleware(validator, chatboxLogger), handleListSessionGrants(storageService, chatboxLogger))
+		chatGroup.PUT("/sessions/:sessionID/grants/:userID", userAuthMiddleware(validator, chatboxLogger), writes, handleGrantSessionAccess(storageService, chatboxLogger))
+		chatGroup.DELETE("/sessions/:sessionID/grants/:userID", userAuthMiddleware(validator, chatboxLogger), writes, handleRevokeSessionAccess(storageService, chatboxLogger))
+		chatGroup.POST("/sessions/:sessionID/fork", userAuthMiddleware(validator, chatboxLogger), writes, handleForkSession(storageService, sessionManager, sessionEndPublisher, eventPublisher, chatboxLogger))
+		chatGroup.POST("/sessions/:sessionID/merge/:sourceID", userAuthMiddleware(validator, chatboxLogger), writes, handleMergeSessions(storageService, sessionManager, sessionEndPublisher, chatboxLogger))
+		chatGroup.POST("/sessions/:sessionID/snapshot", userAuthMiddleware(validator, chatboxLogger), writes, handleCreateSnapshot(storageService, chatboxLogger))
+		chatGroup.GET("/sessions/:sessionID/snapshots", userAuthMiddleware(validator, chatboxLogger), handleListSnapshots(storageService, chatboxLogger))
+		chatGroup.POST("/snapshots/:snapshotID/restore", userAuthMiddleware(validator, chatboxLogger), writes, handleRestoreSnapshot(storageService, sessionManager, sessionEndPublisher, eventPublisher, chatboxLogger))
+		chatGroup.POST("/sessions/:sessionID/tags", userAuthMiddleware(validator, chatboxLogger), writes, handleTagSession(storageService, chatboxLogger))
+		chatGroup.DELETE("/sessions/:sessionID/tags/:tag", userAuthMiddleware(validator, chatboxLogger), writes, handleUntagSession(storageService, chatboxLogger))
+		chatGroup.PUT("/sessions/:sessionID/messages/:index/feedback", userAuthMiddleware(validator, chatboxLogger), writes, handleMessageFeedback(storageService, sessionManager, chatboxLogger))
+		chatGroup.POST("/sessions/:sessionID/messages/:index/pin", userAuthMiddleware(validator, chatboxLogger), writes, handlePinMessage(storageService, sessionManager, chatboxLogger))
+		chatGroup.DELETE("/sessions/:sessionID/messages/:index/pin", userAuthMiddleware(validator, chatboxLogger), writes, handleUnpinMessage(storageService, sessionManager, chatboxLogger))
+		chatGroup.POST("/report", userAuthMiddleware(validator, chatboxLogger), writes, handleReportMessage(storageService, chatboxLogger))
+		chatGroup.GET("/memory", userAuthMiddleware(validator, chatboxLogger), handleListMemory(storageService, chatboxLogger))
+		chatGroup.DELETE("/memory", userAuthMiddleware(validator, chatboxLogger), writes, handleClearMemory(storageService, chatboxLogger))
+		chatGroup.DELETE("/memory/:factID", userAuthMiddleware(validator, chatboxLogger), writes, handleDeleteMemoryFact(storageService, chatboxLogger))
+
+		// Embed token endpoint for partner sites (partner API key, rate-limited)
+		// No else needed: optional operation (only when embedding is configured)
+		if embedRegistry != nil {
+			chatGroup.POST("/embed/token", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleEmbedToken(embedRegistry, validator, chatboxLogger))
 		}
-		if existingToken != "" {
-			c.JSON(constants.StatusOK, gin.H{
-				"share_token": existingToken,
-			})
-			return
+
+		// Public shared session endpoint (no auth, rate-limited)
+		chatGroup.GET("/shared/:shareToken", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleGetSharedSession(storageService, chatboxLogger))
+
+		// Admin HTTP endpoints
+		adminGroup := chatGroup.Group("/admin")
+		adminGroup.Use(apiKeyMiddleware(apiKeys, chatboxLogger))
+		adminGroup.Use(authMiddleware(validator, policy, chatboxLogger))
+		adminGroup.Use(adminRateLimitMiddleware(adminLimiter, chatboxLogger))
+		{
+			audit := func(action string) gin.HandlerFunc {
+				return auditMiddleware(storageService, action, chatboxLogger)
+			}
+			can := func(perm authz.Permission) gin.HandlerFunc {
+				return permissionMiddleware(policy, perm, chatboxLogger)
+			}
+			limit := func(name string) gin.HandlerFunc {
+				return ratePolicyMiddleware(ratePolicies, name, chatboxLogger)
+			}
+			adminGroup.GET("/permissions", handleGetPermissions(policy))
+			adminGroup.POST("/takeover/:sessionID", audit(constants.AuditActionTakeover), can(authz.PermTakeover), handleAdminTakeover(messageRouter, chatboxLogger))
+			adminGroup.POST("/handback/:sessionID", audit(constants.AuditActionHandback), can(authz.PermTakeover), handleAdminHandback(messageRouter, chatboxLogger))
+			adminGroup.POST("/sessions/:sessionID/messages", audit(constants.AuditActionSendMessage), can(authz.PermBroadcast), handleAdminSendMessage(messageRouter, chatboxLogger))
+			adminGroup.POST("/drain", limit(constants.RatePolicyBulk), audit(constants.AuditActionDrain), can(authz.PermBroadcast), handleDrain(wsHandler, chatboxLogger))
+			adminGroup.GET("/ip-stats", audit(constants.AuditActionViewIPStats), can(authz.PermViewSessions), handleIPStats(ipLimiter))
+			adminGroup.GET("/ip-bans", audit(constants.AuditActionViewIPStats), can(authz.PermViewSessions), handleListIPBans(ipLimiter))
+			adminGroup.PUT("/ip-bans/:ip", audit(constants.AuditActionBanIP), can(authz.PermManage), handleBanIP(ipLimiter, chatboxLogger))
+			adminGroup.DELETE("/ip-bans/:ip", audit(constants.AuditActionUnbanIP), can(authz.PermManage), handleUnbanIP(ipLimiter, chatboxLogger))
+			adminGroup.GET("/connections", audit(constants.AuditActionViewConnections), can(authz.PermViewSessions), handleListConnections(wsHandler))
+			adminGroup.GET("/jobs", audit(constants.AuditActionViewJobs), can(authz.PermViewSessions), handleListJobs(scheduler, chatboxLogger))
+			adminGroup.GET("/alerts", audit(constants.AuditActionViewAlerts), can(authz.PermViewSessions), handleListAlerts(alertMonitor))
+			adminGroup.GET("/help-queue", audit(constants.AuditActionViewHelpQueue), can(authz.PermViewSessions), handleHelpQueue(sessionManager, helpQueueScorer))
+			adminGroup.DELETE("/connections/:connectionID", audit(constants.AuditActionCloseConnection), can(authz.PermManage), handleCloseConnection(wsHandler, chatboxLogger))
+			adminGroup.POST("/impersonate/:userID", audit(constants.AuditActionImpersonate), can(authz.PermImpersonate), handleImpersonate(validator, chatboxLogger))
+			adminGroup.GET("/readonly", can(authz.PermViewSessions), handleGetReadOnly(readOnlyMode))
+			adminGroup.POST("/readonly", audit(constants.AuditActionSetReadOnly), can(authz.PermManage), handleSetReadOnly(readOnlyMode, storageService, chatboxLogger))
+			adminGroup.POST("/reload", audit(constants.AuditActionReloadConfig), can(authz.PermManage), handleReloadConfig(reloader, chatboxLogger))
+			// Session analytics, audit, prompt and canned response endpoints query MongoDB
+			adminGroup.GET("/sessions", limit(constants.RatePolicyList), audit(constants.AuditActionListSessions), can(authz.PermViewSessions), handleListSessions(storageService, sessionManager, chatboxLogger))
+			adminGroup.GET("/sessions/search", limit(constants.RatePolicyList), audit(constants.AuditActionSearch), can(authz.PermViewSessions), handleSearchSessions(storageService, chatboxLogger))
+			adminGroup.GET("/metrics", limit(constants.RatePolicyList), audit(constants.AuditActionViewMetrics), can(authz.PermViewSessions), handleGetMetrics(storageService, chatboxLogger))
+			adminGroup.GET("/costs", limit(constants.RatePolicyList), audit(constants.AuditActionViewCosts), can(authz.PermViewSessions), handleGetCosts(storageService, costs, chatboxLogger))
+			adminGroup.GET("/sessions/:sessionID/export", limit(constants.RatePolicyExport), audit(constants.AuditActionExport), can(authz.PermExport), handleExportSession(storageService, chatboxLogger))
+			adminGroup.GET("/export/finetune", limit(constants.RatePolicyExport), audit(constants.AuditActionExportFinetune), can(authz.PermExport), handleFinetuneExport(storageService, chatboxLogger))
+			adminGroup.POST("/sessions/:sessionID/restore", audit(constants.AuditActionRestore), can(authz.PermPurge), handleRestoreSession(storageService, chatboxLogger))
+			adminGroup.POST("/sessions/:sessionID/tags", audit(constants.AuditActionTagSession), can(authz.PermManage), handleAdminTagSession(storageService, chatboxLogger))
+			adminGroup.DELETE("/sessions/:sessionID/tags/:tag", audit(constants.AuditActionTagSession), can(authz.PermManage), handleAdminUntagSession(storageService, chatboxLogger))
+			adminGroup.POST("/sessions/:sessionID/end", audit(constants.AuditActionForceEnd), can(authz.PermManage), handleForceEndSession(storageService, messageRouter, wsHandler, chatboxLogger))
+			adminGroup.POST("/sessions/:sessionID/messages/:index/pin", audit(constants.AuditActionPinMessage), can(authz.PermManage), handleAdminPinMessage(storageService, sessionManager, chatboxLogger))
+			adminGroup.DELETE("/sessions/:sessionID/messages/:index/pin", audit(constants.AuditActionPinMessage), can(authz.PermManage), handleAdminUnpinMessage(storageService, sessionManager, chatboxLogger))
+			adminGroup.GET("/migrations", audit(constants.AuditActionViewMigrations), can(authz.PermViewSessions), handleMigrationReport(storageService, chatboxLogger))
+			adminGroup.POST("/migrations", limit(constants.RatePolicyBulk), audit(constants.AuditActionRunMigrations), can(authz.PermManage), handleRunMigrations(storageService, chatboxLogger))
+			// No else needed: optional operation (backups only when enabled)
+			if backupRunner != nil {
+				adminGroup.GET("/backups", audit(constants.AuditActionViewBackups), can(authz.PermExport), handleListBackups(backupRunner))
+				adminGroup.POST("/backups", limit(constants.RatePolicyBulk), audit(constants.AuditActionStartBackup), can(authz.PermExport), handleStartBackup(backupRunner, chatboxLogger))
+			}
+			adminGroup.GET("/audit", limit(constants.RatePolicyList), audit(constants.AuditActionViewAudit), can(authz.PermViewSessions), handleListAudit(storageService, chatboxLogger))
+			adminGroup.GET("/reports", limit(constants.RatePolicyList), audit(constants.AuditActionListReports), can(authz.PermViewSessions), handleListReports(storageService, chatboxLogger))
+			adminGroup.GET("/user-blocks", audit(constants.AuditActionListBlocks), can(authz.PermViewSessions), handleListUserBlocks(storageService, chatboxLogger))
+			adminGroup.PUT("/user-blocks/:userID", audit(constants.AuditActionBlockUser), can(authz.PermManage), handleBlockUser(storageService, wsHandler, chatboxLogger))
+			adminGroup.DELETE("/user-blocks/:userID", audit(constants.AuditActionUnblockUser), can(authz.PermManage), handleUnblockUser(storageService, chatboxLogger))
+			adminGroup.GET("/prompts", audit(constants.AuditActionListPrompts), can(authz.PermViewSessions), handleListPrompts(promptService, storageService, chatboxLogger))
+			adminGroup.POST("/prompts", audit(constants.AuditActionCreatePrompt), can(authz.PermManage), handleCreatePrompt(storageService, chatboxLogger))
+			adminGroup.GET("/prompts/:templateID", audit(constants.AuditActionListPrompts), can(authz.PermViewSessions), handleGetPrompt(promptService, storageService, chatboxLogger))
+			adminGroup.PUT("/prompts/:templateID", audit(constants.AuditActionUpdatePrompt), can(authz.PermManage), handleUpdatePrompt(promptService, storageService, chatboxLogger))
+			adminGroup.DELETE("/prompts/:templateID", audit(constants.AuditActionDeletePrompt), can(authz.PermManage), handleDeletePrompt(promptService, storageService, chatboxLogger))
+			adminGroup.GET("/experiments", audit(constants.AuditActionListExperiments), can(authz.PermViewSessions), handleListExperiments(experimentService, storageService, chatboxLogger))
+			adminGroup.POST("/experiments", audit(constants.AuditActionCreateExperiment), can(authz.PermManage), handleCreateExperiment(experimentService, storageService, routerLLM, promptService, chatboxLogger))
+			adminGroup.DELETE("/experiments/:experimentID", audit(constants.AuditActionDeleteExperiment), can(authz.PermManage), handleDeleteExperiment(experimentService, storageService, chatboxLogger))
+			adminGroup.GET("/experiments/:experimentID/metrics", audit(constants.AuditActionViewMetrics), can(authz.PermViewSessions), handleGetExperimentMetrics(experimentService, storageService, chatboxLogger))
+			adminGroup.GET("/canned", audit(constants.AuditActionListCanned), can(authz.PermViewSessions), handleListCanned(storageService, chatboxLogger))
+			adminGroup.POST("/canned", audit(constants.AuditActionCreateCanned), can(authz.PermManage), handleCreateCanned(storageService, chatboxLogger))
+			adminGroup.GET("/canned/:cannedID", audit(constants.AuditActionListCanned), can(authz.PermViewSessions), handleGetCanned(storageService, chatboxLogger))
+			adminGroup.PUT("/canned/:cannedID", audit(constants.AuditActionUpdateCanned), can(authz.PermManage), handleUpdateCanned(storageService, chatboxLogger))
+			adminGroup.DELETE("/canned/:cannedID", audit(constants.AuditActionDeleteCanned), can(authz.PermManage), handleDeleteCanned(storageService, chatboxLogger))
+			adminGroup.GET("/keywords", audit(constants.AuditActionListKeywords), can(authz.PermViewSessions), handleListKeywords(storageService, chatboxLogger))
+			adminGroup.POST("/keywords", audit(constants.AuditActionCreateKeyword), can(authz.PermManage), handleCreateKeyword(keywordAlerts, storageService, chatboxLogger))
+			adminGroup.GET("/keywords/:keywordID", audit(constants.AuditActionListKeywords), can(authz.PermViewSessions), handleGetKeyword(storageService, chatboxLogger))
+			adminGroup.PUT("/keywords/:keywordID", audit(constants.AuditActionUpdateKeyword), can(authz.PermManage), handleUpdateKeyword(keywordAlerts, storageService, chatboxLogger))
+			adminGroup.DELETE("/keywords/:keywordID", audit(constants.AuditActionDeleteKeyword), can(authz.PermManage), handleDeleteKeyword(keywordAlerts, storageService, chatboxLogger))
+			adminGroup.POST("/sessions/:sessionID/canned/:cannedID", audit(constants.AuditActionSendCanned), can(authz.PermBroadcast), handleSendCanned(storageService, sessionManager, messageRouter, chatboxLogger))
 		}
 
-		// Generate new share token
-		token, err := gohelper.GenUUID(constants.ShareTokenLength)
-		if err != nil {
-			util.LogError(logger, "http", "generate share token", err, "session_id", sessionID)
-			httperrors.RespondInternalError(c)
-			return
+		// Data subject requests (GDPR export and erasure), admin only
+		usersGroup := chatGroup.Group("/users")
+		usersGroup.Use(authMiddleware(validator, policy, chatboxLogger))
+		usersGroup.Use(adminRateLimitMiddleware(adminLimiter, chatboxLogger))
+		{
+			audit := func(action string) gin.HandlerFunc {
+				return auditMiddleware(storageService, action, chatboxLogger)
+			}
+			can := func(perm authz.Permission) gin.HandlerFunc {
+				return permissionMiddleware(policy, perm, chatboxLogger)
+			}
+			limit := func(name string) gin.HandlerFunc {
+				return ratePolicyMiddleware(ratePolicies, name, chatboxLogger)
+			}
+			usersGroup.GET("/:userID/export", limit(constants.RatePolicyExport), audit(constants.AuditActionExportUser), can(authz.PermExport), handleExportUserData(storageService, chatboxLogger))
+			usersGroup.DELETE("/:userID/data", limit(constants.RatePolicyBulk), audit(constants.AuditActionEraseUser), can(authz.PermPurge), handleDeleteUserData(storageService, sessionManager, chatboxLogger))
 		}
 
-		// Persist token
-		if err := storageService.SetShareToken(sessionID, token); err != nil {
-			util.LogError(logger, "http", "set share token", err, "session_id", sessionID)
-			httperrors.RespondInternalError(c)
-			return
+		// Health check endpoints (rate limited to prevent abuse)
+		chatGroup.GET("/healthz", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleHealthCheck)
+		readyChecks := readinessChecks(mongo, llmService, redisClient, uploadTempDir(config))
+		// No else needed: optional operation (maintenance windows configured)
+		if maintenanceSchedule != nil {
+			readyChecks.Register("maintenance", false, maintenanceReadinessCheck(maintenanceSchedule))
+		}
+		readyChecks.Register("read_only", false, readOnlyReadinessCheck(readOnlyMode))
+		chatGroup.GET("/readyz", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleReadyCheck(readyChecks, chatboxLogger))
+
+		// API documentation: the OpenAPI spec, and optionally a Swagger UI page rendering it
+		chatGroup.GET("/openapi.json", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleOpenAPISpec(openAPISpec))
+		// No else needed: optional operation (Swagger UI enabled)
+		if openAPIUI {
+			chatGroup.GET("/docs", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleOpenAPIUI(pathPrefix))
 		}
 
-		logger.Info("Session shared", "session_id", sessionID, "user_id", claims.UserID)
-		c.JSON(constants.StatusOK, gin.H{
-			"share_token": token,
-		})
+		// Embeddable chat widget: a loader at a fixed path and the widget under its version
+		// No else needed: optional operation (widget enabled)
+		if widgetBundle != nil {
+			chatGroup.GET("/widget/loader.js", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleWidgetLoader(widgetBundle))
+			chatGroup.GET("/widget/version", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleWidgetVersion(widgetBundle, pathPrefix))
+			chatGroup.GET("/widget/:version/:file", publicRateLimitMiddleware(publicLimiter, chatboxLogger), handleWidgetAsset(widgetBundle))
+		}
+	}
+
+	// Prometheus metrics endpoint — under prefix, restricted to configured networks
+	metricsAllowedStr, _ := config.ConfigStringWithDefault("chatbox.metrics_allowed_networks", constants.DefaultMetricsAllowedNetworks)
+	metricsNets := parseNetworks(metricsAllowedStr, chatboxLogger)
+	chatGroup.GET("/metrics/prometheus",
+		metricsNetworkMiddleware(metricsNets, chatboxLogger),
+		publicRateLimitMiddleware(publicLimiter, chatboxLogger),
+		gin.WrapH(promhttp.Handler()),
+	)
+
+	// Warn if MongoDB URI appears to have no authentication (L4)
+	mongoURI, _ := config.ConfigStringWithDefault("database.uri", "")
+	if mongoURI == "" {
+		mongoURI, _ = config.ConfigStringWithDefault("MONGO_URI", "")
+	}
+	if mongoURI != "" && !strings.Contains(mongoURI, "@") {
+		chatboxLogger.Warn("MongoDB URI does not contain authentication credentials — ensure auth is configured for production")
+	}
+
+	chatboxLogger.Info("Chatbox service registered successfully",
+		"websocket_endpoint", pathPrefix+"/ws",
+		"sse_endpoint", pathPrefix+"/sse",
+		"admin_endpoints", pathPrefix+"/admin/*",
+		"health_endpoints", pathPrefix+"/healthz, "+pathPrefix+"/readyz",
+		"metrics_endpoint", pathPrefix+"/metrics/prometheus",
+	)
+
+	return nil
+}
+
+// metricsMiddleware records HTTP request duration for Prometheus monitoring
+// securityHeadersMiddleware adds standard HTTP security headers to all responses.
+func securityHeadersMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("X-Frame-Options", "DENY")
+		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
+		c.Header("X-XSS-Protection", "1; mode=block")
+		c.Next()
 	}
 }
 
-// handleGetSharedSession returns session data for a public share link.
-// No authentication required — anyone with the share token can view.
-func handleGetSharedSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+// accessLogMiddleware logs one structured line per REST call, with the request
+// ID assigned by requestid.Middleware, for the log pipeline. WebSocket and SSE
+// connections get a line for the request that opened them; the WebSocket
+// handler logs their frames in batches.
+func accessLogMiddleware(logger *golog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		shareToken := c.Param("shareToken")
-		if shareToken == "" {
-			httperrors.RespondBadRequest(c, "share token is required")
-			return
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		// No else needed: optional operation (unmatched routes keep a bounded route label)
+		if route == "" {
+			route = "unmatched"
+		}
+		var userID string
+		value, _ := c.Get("claims")
+		// No else needed: optional operation (only authenticated routes carry claims)
+		if claims, ok := value.(*auth.Claims); ok {
+			userID = claims.UserID
 		}
+		bytesOut := c.Writer.Size()
+		// No else needed: conditional assignment (no body written)
+		if bytesOut < 0 {
+			bytesOut = 0
+		}
+		logger.Info("HTTP access",
+			"request_id", c.GetString(requestid.ContextKey),
+			"method", c.Request.Method,
+			"route", route,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency_ms", time.Since(start).Milliseconds(),
+			"bytes_in", c.Request.ContentLength,
+			"bytes_out", bytesOut,
+			"user_id", userID,
+			"client_ip", c.ClientIP(),
+			"component", "access")
+	}
+}
 
-		sess, err := storageService.GetSessionByShareToken(shareToken)
-		if err != nil {
-			if errors.Is(err, storage.ErrSessionNotFound) {
-				httperrors.RespondNotFound(c, constants.ErrMsgSharedSessionNotFound)
-				return
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.HTTPRequestDuration.With(prometheus.Labels{
+			"endpoint": c.FullPath(),
+			"method":   c.Request.Method,
+		}).Observe(time.Since(start).Seconds())
+	}
+}
+
+// publicRateLimitMiddleware creates a Gin middleware for rate limiting public endpoints
+// (healthz, readyz, metrics) by client IP to prevent abuse.
+func publicRateLimitMiddleware(limiter ratelimit.Limiter, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Use Gin's ClientIP() which respects trusted proxies to prevent X-Forwarded-For spoofing
+		clientIP := c.ClientIP()
+
+		if !limiter.Allow(clientIP) {
+			retryAfter := limiter.GetRetryAfter(clientIP)
+			retryAfterSeconds := (retryAfter + constants.MillisecondsPerSecond - 1) / constants.MillisecondsPerSecond
+			if retryAfterSeconds < constants.MinRetryAfterSeconds {
+				retryAfterSeconds = constants.MinRetryAfterSeconds
 			}
-			util.LogError(logger, "http", "get shared session", err)
-			httperrors.RespondInternalError(c)
+			c.Header(constants.HeaderRetryAfter, fmt.Sprintf("%d", retryAfterSeconds))
+
+			httperrors.RespondRateLimited(c, constants.ErrMsgRateLimitExceeded, retryAfter)
+			c.Abort()
 			return
 		}
 
-		c.JSON(constants.StatusOK, gin.H{
-			"session_id": sess.ID,
-			"name":       sess.Name,
-			"messages":   sess.Messages,
-		})
+		c.Next()
 	}
 }
 
-// handleListSessions returns a handler for listing sessions with pagination, filtering, and sorting
-func handleListSessions(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+// ipThrottleMiddleware limits connection attempts per client IP and refuses
+// banned IPs. It runs before the WebSocket and SSE handlers authenticate, so
+// reconnect storms are refused without validating tokens.
+func ipThrottleMiddleware(limiter *ratelimit.IPLimiter, logger *golog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Parse query parameters
-		userID := c.Query("user_id")
-		if len(userID) > 255 {
-			httperrors.RespondBadRequest(c, "user_id exceeds maximum length of 255 characters")
+		// Use Gin's ClientIP() which respects trusted proxies to prevent X-Forwarded-For spoofing
+		clientIP := c.ClientIP()
+
+		// No else needed: early return pattern (allowed)
+		if limiter.Allow(clientIP) {
+			c.Next()
 			return
 		}
-		status := c.Query("status")                       // "active" or "ended"
-		adminAssistedStr := c.Query("admin_assisted")     // "true" or "false"
-		sortBy := c.DefaultQuery("sort_by", "start_time") // "start_time", "end_time", "message_count", "total_tokens", "user_id"
-		sortOrder := c.DefaultQuery("sort_order", "desc") // "asc" or "desc"
-		limitStr := c.DefaultQuery("limit", "100")
-		offsetStr := c.DefaultQuery("offset", "0")
-		startTimeFromStr := c.Query("start_time_from") // RFC3339 format
-		startTimeToStr := c.Query("start_time_to")     // RFC3339 format
 
-		// Validate sort parameters against whitelist
-		if !constants.ValidSortFields[sortBy] {
-			httperrors.RespondBadRequest(c, fmt.Sprintf("invalid sort_by field %q; allowed: start_time, end_time, message_count, total_tokens, user_id", sortBy))
-			return
+		retryAfter := limiter.GetRetryAfter(clientIP)
+		retryAfterSeconds := (retryAfter + constants.MillisecondsPerSecond - 1) / constants.MillisecondsPerSecond
+		// No else needed: optional operation (minimum retry after enforcement)
+		if retryAfterSeconds < constants.MinRetryAfterSeconds {
+			retryAfterSeconds = constants.MinRetryAfterSeconds
 		}
-		if !constants.ValidSortOrders[sortOrder] {
-			httperrors.RespondBadRequest(c, fmt.Sprintf("invalid sort_order %q; allowed: asc, desc", sortOrder))
+		c.Header(constants.HeaderRetryAfter, fmt.Sprintf("%d", retryAfterSeconds))
+
+		// No else needed: early return pattern (banned IP)
+		if _, banned := limiter.Banned(clientIP); banned {
+			logger.Warn("Connection from banned IP refused",
+				"ip", clientIP,
+				"component", "connection_throttle")
+			httperrors.Respond(c, apierror.CodeIPBanned, "Connections from this address are temporarily blocked")
+			c.Abort()
 			return
 		}
 
-		// Parse limit
-		limit := constants.DefaultSessionLimit
-		// No else needed: optional operation (limit parsing with validation)
-		if l, err := fmt.Sscanf(limitStr, "%d", &limit); err == nil && l == 1 {
-			// No else needed: optional operation (limit range validation)
-			if limit <= 0 || limit > constants.MaxSessionLimit {
-				limit = constants.DefaultSessionLimit
-			}
-		}
+		logger.Warn("Connection rate limit exceeded",
+			"ip", clientIP,
+			"retry_after_ms", retryAfter,
+			"component", "connection_throttle")
+		httperrors.RespondRateLimited(c, constants.ErrMsgRateLimitExceeded, retryAfter)
+		c.Abort()
+	}
+}
 
-		// Parse offset
-		offset := 0
-		// No else needed: optional operation (offset parsing with validation)
-		if o, err := fmt.Sscanf(offsetStr, "%d", &offset); err == nil && o == 1 {
-			// No else needed: optional operation (offset range validation)
-			if offset < 0 {
-				offset = 0
-			}
+// loadEncryptionKey loads the key encrypting message content at rest. It is
+// nil when encryption is disabled; otherwise it must be exactly 32 bytes for
+// AES-256.
+// Priority: Environment variable > Config file
+func loadEncryptionKey(config *goconfig.ConfigAccessor, logger *golog.Logger) ([]byte, error) {
+	var encryptionKey []byte
+	encryptionKeyStr := os.Getenv("ENCRYPTION_KEY")
+	if encryptionKeyStr == "" {
+		// Fall back to config file
+		var err error
+		encryptionKeyStr, err = config.ConfigStringWithDefault("chatbox.encryption_key", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get encryption key: %w", err)
 		}
-
-		// Parse admin_assisted filter
-		var adminAssisted *bool
-		// No else needed: optional operation (filter parsing)
-		if adminAssistedStr != "" {
-			val := adminAssistedStr == "true"
-			adminAssisted = &val
+		if encryptionKeyStr != "" && containsPlaceholder(encryptionKeyStr) {
+			return nil, fmt.Errorf("ENCRYPTION_KEY contains placeholder value — set a real key before deploying")
 		}
+	}
+	// No else needed: optional operation (logging based on configuration state)
+	if encryptionKeyStr != "" {
+		// Convert string to bytes
+		encryptionKey = []byte(encryptionKeyStr)
+		logger.Info("Message encryption enabled", "key_length", len(encryptionKey))
+	} else {
+		logger.Error("No encryption key configured — messages will be stored unencrypted. Set ENCRYPTION_KEY to enable AES-256-GCM encryption at rest.")
+	}
 
-		// Parse active status filter
-		var active *bool
-		// No else needed: optional operation (filter parsing)
-		if status != "" {
-			// No else needed: optional operation (status value parsing)
-			if status == "active" {
-				val := true
-				active = &val
-			} else if status == "ended" {
-				val := false
-				active = &val
-			}
-		}
+	// Validate encryption key length before any encryption operations
+	// No else needed: early return pattern (guard clause)
+	if err := validateEncryptionKey(encryptionKey); err != nil {
+		return nil, err
+	}
+	return encryptionKey, nil
+}
 
-		// Parse time range filters
-		var startTimeFrom, startTimeTo *time.Time
-		// No else needed: optional operation (time filter parsing)
-		if startTimeFromStr != "" {
-			t, err := time.Parse(time.RFC3339, startTimeFromStr)
+// validateEncryptionKey checks if the encryption key is exactly 32 bytes
+// Returns error if key is provided but not 32 bytes
+// Returns nil if key is empty (encryption disabled) or exactly 32 bytes
+func validateEncryptionKey(key []byte) error {
+	keyLen := len(key)
+
+	// Empty key is valid (encryption disabled)
+	if keyLen == 0 {
+		return nil
+	}
+
+	// 32 bytes is valid for AES-256
+	if keyLen == constants.EncryptionKeyLength {
+		return nil
+	}
+
+	// Any other length is invalid
+	return fmt.Errorf("encryption key must be exactly %d bytes for AES-256, got %d bytes. Please provide a valid %d-byte key or remove the key to disable encryption", constants.EncryptionKeyLength, keyLen, constants.EncryptionKeyLength)
+}
+
+// authMiddleware creates a Gin middleware for JWT authentication of admin
+// endpoints. Tokens must carry a role with at least one permission in policy;
+// permissionMiddleware checks the permission each endpoint needs. Requests
+// already authenticated by apiKeyMiddleware skip the JWT.
+func authMiddleware(validator *auth.JWTValidator, policy *authz.Policy, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("claims")
+		claims, ok := value.(*auth.Claims)
+		// No else needed: optional operation (JWT only when no API key was presented)
+		if !ok {
+			// Extract token from Authorization header
+			authHeader := c.GetHeader("Authorization")
+			token, err := util.ExtractBearerToken(authHeader)
 			// No else needed: early return pattern (guard clause)
 			if err != nil {
-				logger.Warn("Invalid start_time_from parameter",
-					"value", startTimeFromStr,
-					"error", err,
-					"component", "http")
-				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				httperrors.RespondUnauthorized(c, httperrors.MsgInvalidAuthHeader)
+				c.Abort()
 				return
 			}
-			startTimeFrom = &t
-		}
-		// No else needed: optional operation (time filter parsing)
-		if startTimeToStr != "" {
-			t, err := time.Parse(time.RFC3339, startTimeToStr)
+
+			// Validate token
+			claims, err = validator.ValidateToken(token)
 			// No else needed: early return pattern (guard clause)
 			if err != nil {
-				logger.Warn("Invalid start_time_to parameter",
-					"value", startTimeToStr,
+				// Log detailed error server-side
+				logger.Warn("Token validation failed",
 					"error", err,
-					"component", "http")
-				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+					"component", "auth")
+				// Send generic error to client
+				httperrors.RespondInvalidToken(c)
+				c.Abort()
 				return
 			}
-			startTimeTo = &t
 		}
 
-		// Translate API sort field name to internal BSON field name
-		internalSortBy := constants.APISortFieldMap[sortBy]
+		// Check for a role with admin permissions
+		// No else needed: early return pattern (guard clause)
+		if !policy.IsAdmin(claims.Roles) {
+			logger.Warn("Insufficient permissions for admin endpoint",
+				"user_id", claims.UserID,
+				"roles", claims.Roles,
+				"component", "auth")
+			httperrors.RespondForbidden(c)
+			c.Abort()
+			return
+		}
 
-		// Build options for ListAllSessionsWithOptions
-		opts := &storage.SessionListOptions{
-			Limit:         limit,
-			Offset:        offset,
-			UserID:        userID,
-			StartTimeFrom: startTimeFrom,
-			StartTimeTo:   startTimeTo,
-			AdminAssisted: adminAssisted,
-			Active:        active,
-			SortBy:        internalSortBy,
-			SortOrder:     sortOrder,
+		// Store claims in context
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// apiKeyMiddleware creates a Gin middleware authenticating server-to-server
+// admin calls by the API key in the X-API-Key header. Authenticated requests
+// carry the key's claims, with the role of its scope, on to authMiddleware;
+// requests without the header go on to JWT authentication. apiKeys is nil when
+// no API keys are configured, and the header is then ignored.
+func apiKeyMiddleware(apiKeys *apikey.Registry, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(constants.HeaderAPIKey)
+		// No else needed: early return pattern (JWT authentication)
+		if key == "" || apiKeys == nil {
+			c.Next()
+			return
 		}
 
-		// List sessions with options
-		sessions, err := storageService.ListAllSessionsWithOptions(opts)
+		config, err := apiKeys.Authenticate(key)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			logger.Warn("API key authentication failed",
+				"ip", c.ClientIP(),
+				"component", "auth")
+			httperrors.RespondUnauthorized(c, httperrors.MsgUnauthorized)
+			c.Abort()
+			return
+		}
+
+		c.Set("claims", config.Claims())
+		c.Next()
+	}
+}
+
+// permissionMiddleware creates a Gin middleware that requires perm for an admin
+// endpoint. It runs after authMiddleware, which stores the claims.
+func permissionMiddleware(policy *authz.Policy, perm authz.Permission, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := c.Get("claims")
+		adminClaims, ok := claims.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok || !policy.Allows(adminClaims.Roles, perm) {
+			// No else needed: optional operation (log only authenticated callers)
+			if ok {
+				logger.Warn("Missing permission for admin endpoint",
+					"user_id", adminClaims.UserID,
+					"roles", adminClaims.Roles,
+					"permission", perm,
+					"component", "auth")
+			}
+			httperrors.RespondForbidden(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleGetPermissions returns the caller's roles and the admin permissions they
+// grant, so the admin dashboard can hide actions the caller cannot perform
+func handleGetPermissions(policy *authz.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := c.Get("claims")
+		adminClaims, ok := claims.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			httperrors.RespondUnauthorized(c, httperrors.MsgUnauthorized)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"roles":       adminClaims.Roles,
+			"permissions": policy.Permissions(adminClaims.Roles),
+		})
+	}
+}
+
+// adminRateLimitMiddleware creates a Gin middleware for admin endpoint rate limiting
+func adminRateLimitMiddleware(limiter ratelimit.Limiter, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get claims from context (set by authMiddleware)
+		claimsInterface, exists := c.Get("claims")
+		// No else needed: early return pattern (guard clause - let authMiddleware handle missing claims)
+		if !exists {
+			// If no claims, let authMiddleware handle it
+			c.Next()
+			return
+		}
+
+		claims, ok := claimsInterface.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			util.LogError(logger, "admin_rate_limit", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			c.Abort()
+			return
+		}
+
+		// Check rate limit
+		// No else needed: early return pattern (guard clause)
+		if !limiter.Allow(claims.UserID) {
+			retryAfter := limiter.GetRetryAfter(claims.UserID)
+
+			logger.Warn("Admin rate limit exceeded",
+				"user_id", claims.UserID,
+				"endpoint", c.Request.URL.Path,
+				"retry_after_ms", retryAfter,
+				"component", "admin_rate_limit")
+
+			// CRITICAL FIX M3: Convert milliseconds to seconds properly with ceiling to avoid 0
+			retryAfterSeconds := (retryAfter + constants.MillisecondsPerSecond - 1) / constants.MillisecondsPerSecond // Round up to nearest second
+			// No else needed: optional operation (minimum retry after enforcement)
+			if retryAfterSeconds < constants.MinRetryAfterSeconds {
+				retryAfterSeconds = constants.MinRetryAfterSeconds
+			}
+			c.Header(constants.HeaderRetryAfter, fmt.Sprintf("%d", retryAfterSeconds))
+
+			// Return 429 Too Many Requests
+			httperrors.RespondRateLimited(c, constants.ErrMsgRateLimitExceeded, retryAfter)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ratePolicyMiddleware creates a Gin middleware enforcing the named admin rate
+// limit policy per caller. Endpoints whose policy is not configured are only
+// subject to the global admin rate limit.
+func ratePolicyMiddleware(policies *ratelimit.Policies, name string, logger *golog.Logger) gin.HandlerFunc {
+	limiter, ok := policies.Limiter(name)
+	// No else needed: early return pattern (policy not configured)
+	if !ok {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	return adminRateLimitMiddleware(limiter, logger)
+}
+
+// userAuthMiddleware creates a Gin middleware for JWT authentication (without admin check).
+// Impersonation tokens may only read: an admin acting as a user must not delete,
+// share or email their data.
+func userAuthMiddleware(validator *auth.JWTValidator, logger *golog.Logger) gin.HandlerFunc {
+	return authenticateUser(validator, logger, false)
+}
+
+// chatAuthMiddleware is userAuthMiddleware for endpoints that open a chat, which
+// impersonation tokens may use whatever the method
+func chatAuthMiddleware(validator *auth.JWTValidator, logger *golog.Logger) gin.HandlerFunc {
+	return authenticateUser(validator, logger, true)
+}
+
+// authenticateUser validates the request's JWT and stores its claims in the
+// context. Impersonation tokens are refused on anything but reads unless
+// impersonatedWrites is set.
+func authenticateUser(validator *auth.JWTValidator, logger *golog.Logger, impersonatedWrites bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Extract token from Authorization header
+		authHeader := c.GetHeader("Authorization")
+		token, err := util.ExtractBearerToken(authHeader)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			httperrors.RespondUnauthorized(c, httperrors.MsgInvalidAuthHeader)
+			c.Abort()
+			return
+		}
+
+		// Validate token
+		claims, err := validator.ValidateToken(token)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			// Log detailed error server-side
+			logger.Warn("Token validation failed",
+				"error", err,
+				"component", "auth")
+			// Send generic error to client
+			httperrors.RespondInvalidToken(c)
+			c.Abort()
+			return
+		}
+
+		// Embed tokens are bound to the partner site they were minted for
+		// No else needed: early return pattern (guard clause)
+		if !claims.AllowsOrigin(c.GetHeader("Origin")) {
+			logger.Warn("Token used from another origin",
+				"user_id", claims.UserID,
+				"origin", c.GetHeader("Origin"),
+				"component", "auth")
+			httperrors.RespondInvalidToken(c)
+			c.Abort()
+			return
+		}
+
+		// Store claims in context
+		c.Set("claims", claims)
+
+		// No else needed: optional operation (impersonated requests are logged for audit)
+		if claims.ImpersonatorID != "" {
+			logger.Warn("Admin impersonating user",
+				"admin_id", claims.ImpersonatorID,
+				"user_id", claims.UserID,
+				"method", c.Request.Method,
+				"path", c.FullPath(),
+				"component", "auth")
+			// No else needed: early return pattern (impersonation tokens only read and chat)
+			if !impersonatedWrites && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				httperrors.RespondForbidden(c)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// moveQueryTokenToHeader moves a JWT passed as ?token= into the Authorization header
+// and redacts it from the URL so it does not appear in Gin access logs (M8).
+// Browser WebSocket and EventSource APIs cannot set headers, so clients fall back to the query.
+func moveQueryTokenToHeader(c *gin.Context) {
+	// No else needed: optional operation (only when token is in the query)
+	if token := c.Query("token"); token != "" {
+		if c.Request.Header.Get("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		q := c.Request.URL.Query()
+		q.Del("token")
+		c.Request.URL.RawQuery = q.Encode()
+	}
+}
+
+// recordClientIP passes the client IP resolved by Gin, which only trusts
+// X-Forwarded-For from trusted proxies, to the WebSocket handler for connection diagnostics
+func recordClientIP(c *gin.Context) {
+	c.Request = c.Request.WithContext(websocket.WithClientIP(c.Request.Context(), c.ClientIP()))
+}
+
+// adminTenantScope returns the tenant whose data the admin making the request may
+// see, and whether the admin may see every tenant (super admins)
+func adminTenantScope(c *gin.Context) (string, bool) {
+	claims, _ := c.Get("claims")
+	adminClaims, ok := claims.(*auth.Claims)
+	// No else needed: early return pattern (no claims, restrict to the default tenant)
+	if !ok {
+		return "", false
+	}
+	return adminClaims.TenantID, util.HasRole(adminClaims.Roles, constants.RoleSuperAdmin)
+}
+
+// adminStorage returns the storage view for the admin making the request: sessions
+// of the admin's tenant, or of every tenant for super admins. Super admins may
+// pass ?tenant_id= to see a single tenant, which is how they reach the sessions
+// of tenants pinned to another datastore (see loadResidency).
+func adminStorage(c *gin.Context, storageService *storage.StorageService) *storage.StorageService {
+	claims, _ := c.Get("claims")
+	adminClaims, ok := claims.(*auth.Claims)
+	// No else needed: early return pattern (no claims, restrict to the default tenant)
+	if !ok {
+		return storageService.ForTenant("")
+	}
+	// No else needed: early return pattern (super admins see all tenants)
+	if util.HasRole(adminClaims.Roles, constants.RoleSuperAdmin) {
+		// No else needed: early return pattern (super admin chose a tenant)
+		if tenantID := c.Query("tenant_id"); tenantID != "" {
+			return storageService.ForTenant(tenantID)
+		}
+		return storageService
+	}
+	return storageService.ForTenant(adminClaims.TenantID)
+}
+
+// auditMiddleware records the admin action handled by the rest of the chain in the
+// audit log, including the response status so that denied and failed attempts are
+// kept too. Must run after authMiddleware; requests without admin claims are not recorded.
+func auditMiddleware(storageService *storage.StorageService, action string, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		claims, _ := c.Get("claims")
+		adminClaims, ok := claims.(*auth.Claims)
+		// No else needed: early return pattern (authMiddleware rejected the request)
+		if !ok {
+			return
+		}
+		recordAdminAudit(c, storageService, adminClaims, action, c.Writer.Status(), logger)
+	}
+}
+
+// recordAdminAudit writes an admin action to the audit log. Failures are logged but
+// do not affect the response, which has already been written. Actions of super
+// admins on a chosen tenant (?tenant_id=) are kept in that tenant's datastore,
+// since they name its sessions and users.
+func recordAdminAudit(c *gin.Context, storageService *storage.StorageService, claims *auth.Claims, action string, status int, logger *golog.Logger) {
+	entry := &storage.AuditEntry{
+		Action:    action,
+		ActorID:   claims.UserID,
+		ActorName: claims.Name,
+		TenantID:  claims.TenantID,
+		SessionID: c.Param("sessionID"),
+		UserID:    c.Param("userID"),
+		IP:        c.ClientIP(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Status:    status,
+	}
+	target := claims.TenantID
+	// No else needed: optional operation (super admin working on another tenant)
+	if tenantID := c.Query("tenant_id"); tenantID != "" && util.HasRole(claims.Roles, constants.RoleSuperAdmin) {
+		target = tenantID
+	}
+	// No else needed: optional operation (error logging)
+	if err := storageService.DatastoreFor(target).RecordAudit(entry); err != nil {
+		util.LogError(logger, "audit", "record admin action", err,
+			"action", action,
+			"admin_id", claims.UserID,
+			"session_id", entry.SessionID)
+	}
+}
+
+// impersonationAuditMiddleware records every request authenticated with an
+// impersonation token in the audit log once it is answered, including refused
+// ones, with the admin as the actor and the impersonated user as the subject
+func impersonationAuditMiddleware(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		value, _ := c.Get("claims")
+		claims, ok := value.(*auth.Claims)
+		// No else needed: early return pattern (not an impersonated request)
+		if !ok || claims.ImpersonatorID == "" {
+			return
+		}
+		entry := &storage.AuditEntry{
+			Action:    constants.AuditActionImpersonatedRequest,
+			ActorID:   claims.ImpersonatorID,
+			TenantID:  claims.TenantID,
+			SessionID: c.Param("sessionID"),
+			UserID:    claims.UserID,
+			IP:        c.ClientIP(),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Status:    c.Writer.Status(),
+		}
+		// No else needed: optional operation (error logging)
+		if err := storageService.RecordAudit(entry); err != nil {
+			util.LogError(logger, "audit", "record impersonated request", err,
+				"admin_id", claims.ImpersonatorID,
+				"user_id", claims.UserID)
+		}
+	}
+}
+
+// modelCatalogEntry is a model as offered to clients by handleListModels.
+// System prompts and provider endpoints are deliberately not exposed.
+type modelCatalogEntry struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Provider    string   `json:"provider"`
+	Type        string   `json:"type"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	// Bounds for session_config messages (server defaults apply when unset)
+	MaxTokensLimit int     `json:"max_tokens_limit,omitempty"`
+	MaxTemperature float64 `json:"max_temperature,omitempty"`
+}
+
+// handleListModels returns the model catalog so clients can offer a model picker.
+// llmService is nil in human-only mode, in which case the catalog is empty.
+func handleListModels(llmService *llm.LLMService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		models := []modelCatalogEntry{}
+		// No else needed: optional operation (no models in human-only mode)
+		if llmService != nil {
+			for _, m := range llmService.GetAvailableModels() {
+				models = append(models, modelCatalogEntry{
+					ID:          m.ID,
+					Name:        m.Name,
+					Provider:    m.Provider,
+					Type:        m.Type,
+					MaxTokens:   m.MaxTokens,
+					Temperature: m.Temperature,
+
+					MaxTokensLimit: m.MaxTokensLimit,
+					MaxTemperature: m.MaxTemperature,
+				})
+			}
+		}
+		c.JSON(constants.StatusOK, gin.H{"models": models})
+	}
+}
+
+// handleUserSessions returns a handler for listing the authenticated user's sessions
+func handleUserSessions(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get claims from context
+		claimsInterface, exists := c.Get("claims")
+		// No else needed: early return pattern (guard clause)
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+
+		claims, ok := claimsInterface.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Anonymized mode keeps no per-user history; degrade to an empty list
+		// No else needed: early return pattern (guard clause)
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			c.JSON(constants.StatusOK, gin.H{
+				"sessions":   []*storage.SessionMetadata{},
+				"user_id":    claims.UserID,
+				"count":      0,
+				"limit":      constants.DefaultSessionLimit,
+				"truncated":  false,
+				"anonymized": true,
+			})
+			return
+		}
+
+		// Get user's sessions within their tenant (capped at DefaultSessionLimit)
+		sessions, err := storageService.ForTenant(claims.TenantID).ListUserSessions(claims.UserID, constants.DefaultSessionLimit)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			// Log detailed error server-side
+			util.LogError(logger, "http", "list user sessions", err, "user_id", claims.UserID)
+			// Send generic error to client
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"sessions":  sessions,
+			"user_id":   claims.UserID,
+			"count":     len(sessions),
+			"limit":     constants.DefaultSessionLimit,
+			"truncated": len(sessions) == constants.DefaultSessionLimit,
+		})
+	}
+}
+
+// handleGetSessionMessages returns a handler for fetching a single session's messages.
+// SECURITY: Enforces session access — users can only read their own sessions and
+// sessions shared with them.
+func handleGetSessionMessages(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		// Message content is never stored in anonymized mode
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		// Only sessions of the caller's tenant they own or were granted access to
+		// match (user IDs are only unique within a tenant)
+		sess, err := storageService.ForTenant(claims.TenantID).GetUserSession(sessionID, claims.UserID)
+		if err != nil {
+			util.LogError(logger, "http", "get session", err, "session_id", sessionID, "user_id", claims.UserID)
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"session_id": sess.ID,
+			"name":       sess.Name,
+			"model_id":   sess.ModelID,
+			"pinned":     sess.PinnedMessages(),
+			"messages":   sess.Messages,
+		})
+	}
+}
+
+// claimGuestSessionsRequest is the request body for handleClaimGuestSessions
+type claimGuestSessionsRequest struct {
+	GuestToken string `json:"guest_token"`
+}
+
+// handleClaimGuestSessions moves the sessions a user had as a guest, before
+// logging in, to their account. The guest token issued by the WebSocket endpoint
+// proves the caller was that guest, so it must not have expired yet.
+func handleClaimGuestSessions(validator *auth.JWTValidator, storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Sessions are claimed by logged-in users, not by another guest
+		if util.HasRole(claims.Roles, constants.RoleGuest) {
+			httperrors.RespondForbidden(c)
+			return
+		}
+
+		var req claimGuestSessionsRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.GuestToken == "" {
+			httperrors.RespondBadRequest(c, "guest_token is required")
+			return
+		}
+		guest, err := validator.ValidateToken(req.GuestToken)
+		if err != nil || !util.HasRole(guest.Roles, constants.RoleGuest) {
+			logger.Warn("Guest token rejected", "user_id", claims.UserID, "error", err)
+			httperrors.RespondBadRequest(c, "invalid or expired guest token")
+			return
+		}
+
+		sessionIDs, err := storageService.ForTenant(claims.TenantID).ClaimGuestSessions(guest.UserID, claims.UserID, time.Now())
+		if err != nil {
+			util.LogError(logger, "http", "claim guest sessions", err, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		// In-memory copies still belong to the guest; storage now has the claimed sessions
+		sessionManager.RemoveSessions(sessionIDs)
+		// No else needed: optional operation (JSON array instead of null)
+		if sessionIDs == nil {
+			sessionIDs = []string{}
+		}
+
+		logger.Info("Guest sessions claimed",
+			"user_id", claims.UserID,
+			"guest_id", guest.UserID,
+			"count", len(sessionIDs))
+		c.JSON(constants.StatusOK, gin.H{"claimed": len(sessionIDs), "session_ids": sessionIDs})
+	}
+}
+
+// endSessionRequest is the optional body of handleEndSession
+type endSessionRequest struct {
+	// SendTranscript emails the session's transcript to the verified address of the account
+	SendTranscript bool `json:"send_transcript"`
+}
+
+// handleEndSession ends an active session for the authenticated user, and
+// queues its transcript for email when the user asks for it.
+// SECURITY: Transcripts only go to the verified email address of the token,
+// at most once per session, and never for guests or impersonation tokens.
+// webhooks may be nil when no webhook endpoints are configured, and mailer
+// when transcript emails are disabled.
+func handleEndSession(storageService *storage.StorageService, sessionManager *session.SessionManager, webhooks router.WebhookPublisher, mailer *email.Mailer, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		// An empty body just ends the session
+		var req endSessionRequest
+		// No else needed: optional operation (body is optional)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+				httperrors.RespondBadRequest(c, "Invalid request body")
+				return
+			}
+		}
+		recipient := ""
+		// No else needed: optional operation (transcript requested)
+		if req.SendTranscript {
+			// No else needed: early return pattern (guard clause)
+			if mailer == nil {
+				httperrors.RespondFeatureDisabled(c, "Transcript emails are not enabled")
+				return
+			}
+			// Transcripts need the message content, which is not stored in anonymized mode
+			// No else needed: early return pattern (guard clause)
+			if storageService.IsAnonymizedTenant(claims.TenantID) {
+				httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+				return
+			}
+			// Guests have no account, and admins impersonating a user must not mail the user's transcript
+			// No else needed: early return pattern (guard clause)
+			if util.HasRole(claims.Roles, constants.RoleGuest) || claims.ImpersonatorID != "" {
+				httperrors.Respond(c, apierror.CodeForbidden, "Transcript emails are only available to signed-in users")
+				return
+			}
+			var err error
+			recipient, err = email.ParseAddress(claims.Email)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.Respond(c, apierror.CodeForbidden, "Transcript emails require a verified email address")
+				return
+			}
+		}
+
+		// Verify ownership via storage
+		sess, err := storageService.DatastoreFor(claims.TenantID).GetSession(sessionID)
+		if err != nil {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+		if sess.UserID != storageService.StoredUserIDFor(claims.TenantID, claims.UserID) || sess.TenantID != claims.TenantID {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+
+		// End in-memory session (ignore not-found — may already be expired from memory)
+		_ = sessionManager.EndSession(sessionID)
+
+		// Persist to storage
+		endTime := time.Now()
+		if err := storageService.DatastoreFor(claims.TenantID).EndSession(sessionID, endTime); err != nil {
+			util.LogError(logger, "http", "end session", err, "session_id", sessionID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// No else needed: optional operation (only when webhooks are configured)
+		if webhooks != nil {
+			webhooks.Publish(webhook.Event{
+				Type:      webhook.EventSessionEnded,
+				SessionID: sessionID,
+				UserID:    claims.UserID,
+				TenantID:  claims.TenantID,
+			})
+		}
+
+		// No else needed: early return pattern (no transcript requested)
+		if recipient == "" {
+			c.JSON(constants.StatusOK, gin.H{"status": "ended"})
+			return
+		}
+		// No else needed: optional operation (keep the end time of sessions ended earlier)
+		if sess.EndTime == nil {
+			sess.EndTime = &endTime
+		}
+		// Claimed before queueing, so concurrent requests cannot both send the transcript
+		if err := storageService.DatastoreFor(claims.TenantID).MarkTranscriptSent(sessionID, endTime); err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrTranscriptAlreadySent) {
+				c.JSON(constants.StatusOK, gin.H{"status": "ended", "transcript_email": "already_sent"})
+				return
+			}
+			util.LogError(logger, "http", "mark transcript sent", err, "session_id", sessionID)
+			c.JSON(constants.StatusOK, gin.H{"status": "ended", "transcript_email": "unavailable"})
+			return
+		}
+		transcript := "queued"
+		// No else needed: optional operation (the session is ended even when the email cannot be queued)
+		if err := mailer.Queue(email.Job{To: recipient, Session: sess}); err != nil {
+			util.LogError(logger, "http", "queue transcript email", err, "session_id", sessionID)
+			transcript = "unavailable"
+		}
+		c.JSON(constants.StatusOK, gin.H{"status": "ended", "transcript_email": transcript})
+	}
+}
+
+// renameSessionRequest is the request body for handleRenameSession
+type renameSessionRequest struct {
+	Name string `json:"name"`
+}
+
+// handleRenameSession renames a session for the authenticated user.
+func handleRenameSession(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		// Session names are not stored in anonymized mode
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		var req renameSessionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > constants.MaxSessionNameLength {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("name must be 1-%d bytes", constants.MaxSessionNameLength))
+			return
+		}
+
+		if err := storageService.ForTenant(claims.TenantID).RenameUserSession(sessionID, claims.UserID, name); err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrSessionNotFound) {
+				httperrors.RespondSessionNotFound(c)
+				return
+			}
+			util.LogError(logger, "http", "rename session", err, "session_id", sessionID, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Keep the in-memory copy in sync so later session updates do not restore the old name
+		// (ignore not-found — the session may not be loaded)
+		_ = sessionManager.SetSessionName(sessionID, name)
+
+		c.JSON(constants.StatusOK, gin.H{"session_id": sessionID, "name": name})
+	}
+}
+
+// messageFeedbackRequest is the request body for handleMessageFeedback
+type messageFeedbackRequest struct {
+	Rating  string `json:"rating"` // constants.FeedbackUp or constants.FeedbackDown
+	Comment string `json:"comment"`
+}
+
+// handleMessageFeedback records the authenticated user's thumbs up/down and
+// optional comment on the AI message at index of a session, replacing any
+// earlier feedback on it.
+func handleMessageFeedback(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+		index, err := strconv.Atoi(c.Param("index"))
+		if err != nil || index < 0 {
+			httperrors.RespondBadRequest(c, "message index must be a non-negative integer")
+			return
+		}
+
+		// Messages are not stored in anonymized mode
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		var req messageFeedbackRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+		feedback := &session.Feedback{
+			Rating:    req.Rating,
+			Comment:   strings.TrimSpace(req.Comment),
+			UpdatedAt: time.Now(),
+		}
+		if err := feedback.Validate(); err != nil {
+			httperrors.RespondBadRequest(c, err.Error())
+			return
+		}
+
+		if err := storageService.ForTenant(claims.TenantID).SetUserMessageFeedback(sessionID, claims.UserID, index, feedback); err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrMessageNotFound) {
+				httperrors.RespondNotFound(c, "AI message not found")
+				return
+			}
+			util.LogError(logger, "http", "set message feedback", err, "session_id", sessionID, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Keep the in-memory copy in sync (ignore errors — the session may not be loaded)
+		_ = sessionManager.SetMessageFeedback(sessionID, index, feedback)
+
+		c.JSON(constants.StatusOK, gin.H{"session_id": sessionID, "message_index": index, "feedback": feedback})
+	}
+}
+
+// sessionTagsRequest is the request body for the tag endpoints
+type sessionTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// respondSessionTags sends the session's tags after a tag update, or the error
+// response for a failed update
+func respondSessionTags(c *gin.Context, sessionID string, tags []string, err error, logger *golog.Logger) {
+	switch {
+	case err == nil:
+		c.JSON(constants.StatusOK, gin.H{"session_id": sessionID, "tags": tags})
+	case errors.Is(err, storage.ErrSessionNotFound):
+		httperrors.RespondSessionNotFound(c)
+	case errors.Is(err, storage.ErrInvalidTag), errors.Is(err, storage.ErrTooManyTags):
+		httperrors.RespondBadRequest(c, err.Error())
+	case errors.Is(err, storage.ErrAnonymizedMode):
+		httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+	default:
+		util.LogError(logger, "http", "update session tags", err, "session_id", sessionID)
+		httperrors.RespondInternalError(c)
+	}
+}
+
+// handleTagSession adds tags to a session of the authenticated user.
+func handleTagSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		var req sessionTagsRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		tags, err := storageService.ForTenant(claims.TenantID).AddUserSessionTags(sessionID, claims.UserID, req.Tags)
+		respondSessionTags(c, sessionID, tags, err, logger)
+	}
+}
+
+// handleUntagSession removes a tag from a session of the authenticated user.
+func handleUntagSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		tags, err := storageService.ForTenant(claims.TenantID).RemoveUserSessionTag(sessionID, claims.UserID, c.Param("tag"))
+		respondSessionTags(c, sessionID, tags, err, logger)
+	}
+}
+
+// handleAdminTagSession adds tags to any session of the admin's tenant
+func handleAdminTagSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req sessionTagsRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		tags, err := adminStorage(c, storageService).AddSessionTags(sessionID, req.Tags)
+		respondSessionTags(c, sessionID, tags, err, logger)
+	}
+}
+
+// handleAdminUntagSession removes a tag from any session of the admin's tenant
+func handleAdminUntagSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionID")
+		tags, err := adminStorage(c, storageService).RemoveSessionTag(sessionID, c.Param("tag"))
+		respondSessionTags(c, sessionID, tags, err, logger)
+	}
+}
+
+// forceEndSessionRequest is the body of handleForceEndSession
+type forceEndSessionRequest struct {
+	// Reason is one of constants.EndReasons
+	Reason string `json:"reason"`
+}
+
+// handleForceEndSession returns a handler that ends any active session of the
+// admin's tenant with a reason code: the client is told, its connections on
+// this replica are closed with a websocket.CloseSessionEnded close frame, and
+// the end and its reason are persisted for the session list and metrics
+func handleForceEndSession(storageService *storage.StorageService, messageRouter *router.MessageRouter, wsHandler *websocket.Handler, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req forceEndSessionRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if !router.ValidEndReason(req.Reason) {
+			httperrors.RespondBadRequest(c, "reason must be one of: "+strings.Join(constants.EndReasons, ", "))
+			return
+		}
+		adminID := ""
+		// No else needed: optional operation (record the ending admin)
+		if claims, ok := c.Get("claims"); ok {
+			if adminClaims, ok := claims.(*auth.Claims); ok {
+				adminID = adminClaims.UserID
+			}
+		}
+
+		sessionID := c.Param("sessionID")
+		view := adminStorage(c, storageService)
+		sess, err := view.GetSession(sessionID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if sess.EndTime != nil {
+			httperrors.Respond(c, apierror.CodeConflict, "Session already ended")
+			return
+		}
+
+		messageRouter.ForceEndSession(sess, adminID, req.Reason)
+		// No else needed: early return pattern (guard clause)
+		if err := view.EndSession(sessionID, time.Now()); err != nil {
+			util.LogError(logger, "http", "force end session", err, "session_id", sessionID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := view.RecordEndReason(sessionID, req.Reason, adminID); err != nil {
+			util.LogError(logger, "http", "record session end reason", err, "session_id", sessionID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		closed := wsHandler.CloseSessionConnections(sessionID, websocket.CloseSessionEnded, req.Reason)
+
+		c.JSON(constants.StatusOK, gin.H{
+			"status":             "ended",
+			"session_id":         sessionID,
+			"reason":             req.Reason,
+			"closed_connections": closed,
+		})
+	}
+}
+
+// pinIndex returns the message index of a pin request, or responds with an
+// error and returns false
+func pinIndex(c *gin.Context) (int, bool) {
+	index, err := strconv.Atoi(c.Param("index"))
+	// No else needed: early return pattern (guard clause)
+	if err != nil || index < 0 {
+		httperrors.RespondBadRequest(c, "message index must be a non-negative integer")
+		return 0, false
+	}
+	return index, true
+}
+
+// respondSessionPins sends the session's pins after a pin update, keeping the
+// in-memory session in sync, or the error response for a failed update
+func respondSessionPins(c *gin.Context, sessionManager *session.SessionManager, sessionID string, pins []session.Pin, err error, logger *golog.Logger) {
+	switch {
+	case err == nil:
+		// Ignore errors — the session may not be loaded
+		_ = sessionManager.SetPins(sessionID, pins)
+		c.JSON(constants.StatusOK, gin.H{"session_id": sessionID, "pins": pins})
+	case errors.Is(err, storage.ErrSessionNotFound):
+		httperrors.RespondSessionNotFound(c)
+	case errors.Is(err, storage.ErrPinnedMessageNotFound):
+		httperrors.RespondNotFound(c, "Message not found")
+	case errors.Is(err, storage.ErrTooManyPins):
+		httperrors.RespondBadRequest(c, err.Error())
+	case errors.Is(err, storage.ErrAnonymizedMode):
+		httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+	default:
+		util.LogError(logger, "http", "update pinned messages", err, "session_id", sessionID)
+		httperrors.RespondInternalError(c)
+	}
+}
+
+// handlePinMessage pins the message at index of a session of the authenticated user.
+func handlePinMessage(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+		index, ok := pinIndex(c)
+		if !ok {
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		pins, err := storageService.ForTenant(claims.TenantID).PinUserMessage(sessionID, claims.UserID, index)
+		respondSessionPins(c, sessionManager, sessionID, pins, err, logger)
+	}
+}
+
+// handleUnpinMessage removes the pin of the message at index of a session of
+// the authenticated user.
+func handleUnpinMessage(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+		index, ok := pinIndex(c)
+		if !ok {
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		pins, err := storageService.ForTenant(claims.TenantID).UnpinUserMessage(sessionID, claims.UserID, index)
+		respondSessionPins(c, sessionManager, sessionID, pins, err, logger)
+	}
+}
+
+// reportMessageRequest is the request body for reporting an abusive message
+type reportMessageRequest struct {
+	SessionID    string `json:"session_id"`
+	MessageIndex *int   `json:"message_index"`
+	Category     string `json:"category"` // one of storage.ReportCategories
+	Reason       string `json:"reason"`   // optional explanation
+}
+
+// handleReportMessage records the authenticated user's report of an abusive AI
+// or admin message in one of their sessions, for admins to review.
+func handleReportMessage(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Messages are not stored in anonymized mode
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		var req reportMessageRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+		if req.SessionID == "" {
+			httperrors.RespondBadRequest(c, "session_id is required")
+			return
+		}
+		if req.MessageIndex == nil || *req.MessageIndex < 0 {
+			httperrors.RespondBadRequest(c, "message_index must be a non-negative integer")
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if err := storage.ValidateReport(req.Category, req.Reason); err != nil {
+			httperrors.RespondBadRequest(c, err.Error())
+			return
+		}
+
+		report, err := storageService.ForTenant(claims.TenantID).ReportMessage(req.SessionID, claims.UserID, *req.MessageIndex, req.Category, req.Reason)
+		switch {
+		case err == nil:
+		case errors.Is(err, storage.ErrSessionNotFound):
+			httperrors.RespondSessionNotFound(c)
+			return
+		case errors.Is(err, storage.ErrReportedMessageNotFound):
+			httperrors.RespondNotFound(c, "AI or admin message not found")
+			return
+		case errors.Is(err, storage.ErrAlreadyReported):
+			httperrors.Respond(c, apierror.CodeConflict, "This message was already reported")
+			return
+		default:
+			util.LogError(logger, "http", "report message", err, "session_id", req.SessionID, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Warn("Message reported as abusive",
+			"report_id", report.ID,
+			"session_id", report.SessionID,
+			"message_index", report.MessageIndex,
+			"sender", report.Sender,
+			"category", report.Category,
+			"user_id", claims.UserID)
+		c.JSON(constants.StatusCreated, gin.H{
+			"id":            report.ID,
+			"session_id":    report.SessionID,
+			"message_index": report.MessageIndex,
+			"category":      report.Category,
+			"created_at":    report.CreatedAt,
+		})
+	}
+}
+
+// handleListMemory returns the facts remembered about the authenticated user,
+// oldest first, so users can see what the assistant knows about them
+func handleListMemory(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		facts, err := storageService.ForTenant(claims.TenantID).ListUserMemory(claims.UserID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list user memory", err, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{
+			"facts": facts,
+			"count": len(facts),
+		})
+	}
+}
+
+// handleDeleteMemoryFact forgets one fact remembered about the authenticated user.
+func handleDeleteMemoryFact(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		factID := c.Param("factID")
+		err := storageService.ForTenant(claims.TenantID).DeleteUserMemoryFact(claims.UserID, factID)
+		switch {
+		case err == nil:
+		case errors.Is(err, memory.ErrFactNotFound):
+			httperrors.RespondNotFound(c, "Memory fact not found")
+			return
+		default:
+			util.LogError(logger, "http", "delete memory fact", err, "fact_id", factID, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Info("Memory fact deleted", "fact_id", factID, "user_id", claims.UserID)
+		c.JSON(constants.StatusOK, gin.H{
+			"id":     factID,
+			"status": "deleted",
+		})
+	}
+}
+
+// handleClearMemory forgets everything remembered about the authenticated user
+func handleClearMemory(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		deleted, err := storageService.ForTenant(claims.TenantID).ClearUserMemory(claims.UserID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "clear user memory", err, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Info("User memory cleared", "user_id", claims.UserID, "facts_deleted", deleted)
+		c.JSON(constants.StatusOK, gin.H{
+			"status":        "cleared",
+			"facts_deleted": deleted,
+		})
+	}
+}
+
+// handleAdminPinMessage pins the message at index of any session of the admin's tenant
+func handleAdminPinMessage(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		index, ok := pinIndex(c)
+		if !ok {
+			return
+		}
+		var adminID string
+		claims, _ := c.Get("claims")
+		// No else needed: optional operation (record who pinned the message)
+		if adminClaims, ok := claims.(*auth.Claims); ok {
+			adminID = adminClaims.UserID
+		}
+
+		sessionID := c.Param("sessionID")
+		pins, err := adminStorage(c, storageService).PinMessage(sessionID, index, adminID)
+		respondSessionPins(c, sessionManager, sessionID, pins, err, logger)
+	}
+}
+
+// handleAdminUnpinMessage removes the pin of the message at index of any
+// session of the admin's tenant
+func handleAdminUnpinMessage(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		index, ok := pinIndex(c)
+		if !ok {
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		pins, err := adminStorage(c, storageService).UnpinMessage(sessionID, index)
+		respondSessionPins(c, sessionManager, sessionID, pins, err, logger)
+	}
+}
+
+// handleDeleteSession ends and soft-deletes a session for the authenticated user.
+// Deleted sessions disappear from the user's history; admins can restore them
+// until the retention grace period has passed. webhooks may be nil when no
+// webhook endpoints are configured.
+func handleDeleteSession(storageService *storage.StorageService, sessionManager *session.SessionManager, webhooks router.WebhookPublisher, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		wasActive, err := storageService.ForTenant(claims.TenantID).DeleteUserSession(sessionID, claims.UserID, time.Now())
+		if err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrSessionNotFound) {
+				httperrors.RespondSessionNotFound(c)
+				return
+			}
+			util.LogError(logger, "http", "delete session", err, "session_id", sessionID, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// End in-memory session (ignore not-found — may already be expired from memory)
+		_ = sessionManager.EndSession(sessionID)
+
+		// No else needed: optional operation (only sessions ended by this delete, when webhooks are configured)
+		if wasActive && webhooks != nil {
+			webhooks.Publish(webhook.Event{
+				Type:      webhook.EventSessionEnded,
+				SessionID: sessionID,
+				UserID:    claims.UserID,
+				TenantID:  claims.TenantID,
+			})
+		}
+
+		logger.Info("Session deleted", "session_id", sessionID, "user_id", claims.UserID)
+		c.JSON(constants.StatusOK, gin.H{"session_id": sessionID, "status": "deleted"})
+	}
+}
+
+// shareSessionRequest is the optional body of handleShareSession
+type shareSessionRequest struct {
+	// ExpiresInHours is the lifetime of the link; 0 keeps an existing link's
+	// expiry, or gives a new link constants.DefaultShareLinkTTL
+	ExpiresInHours int `json:"expires_in_hours"`
+}
+
+// handleShareSession generates or retrieves the read-only share link of a session.
+// An active link is returned as is unless a new lifetime is requested; an expired
+// link is replaced with a new token.
+// SECURITY: Enforces session ownership — users can only share their own sessions.
+func handleShareSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		// Sharing exposes message content, which is not stored in anonymized mode
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		// An empty body keeps the current link or uses the default lifetime
+		var req shareSessionRequest
+		// No else needed: optional operation (body is optional)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+				httperrors.RespondBadRequest(c, "Invalid request body")
+				return
+			}
+		}
+		ttl := time.Duration(req.ExpiresInHours) * time.Hour
+		if req.ExpiresInHours < 0 || ttl > constants.MaxShareLinkTTL {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("expires_in_hours must be between 1 and %d, or 0 for the default", int(constants.MaxShareLinkTTL.Hours())))
+			return
+		}
+
+		// Verify ownership
+		sess, err := storageService.DatastoreFor(claims.TenantID).GetSession(sessionID)
+		if err != nil {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+		if sess.UserID != storageService.StoredUserIDFor(claims.TenantID, claims.UserID) || sess.TenantID != claims.TenantID {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+
+		// Check if already shared — return the active link
+		link, err := storageService.DatastoreFor(claims.TenantID).GetShareLink(sessionID)
+		if err != nil {
+			util.LogError(logger, "http", "get share link", err, "session_id", sessionID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		now := time.Now()
+		if link != nil && !link.Expired(now) && ttl == 0 {
+			c.JSON(constants.StatusOK, link)
+			return
+		}
+		// No else needed: optional operation (default lifetime)
+		if ttl == 0 {
+			ttl = constants.DefaultShareLinkTTL
+		}
+
+		token := ""
+		// No else needed: optional operation (a new lifetime keeps an active link's token)
+		if link != nil && !link.Expired(now) {
+			token = link.Token
+		} else {
+			token, err = gohelper.GenUUID(constants.ShareTokenLength)
+			if err != nil {
+				util.LogError(logger, "http", "generate share token", err, "session_id", sessionID)
+				httperrors.RespondInternalError(c)
+				return
+			}
+		}
+
+		// Persist token
+		expiresAt := now.Add(ttl).UTC()
+		if err := storageService.DatastoreFor(claims.TenantID).SetShareToken(sessionID, token, expiresAt); err != nil {
+			util.LogError(logger, "http", "set share token", err, "session_id", sessionID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Info("Session shared", "session_id", sessionID, "user_id", claims.UserID, "expires_at", expiresAt)
+		c.JSON(constants.StatusOK, &storage.ShareLink{Token: token, ExpiresAt: &expiresAt})
+	}
+}
+
+// handleRevokeShare revokes the share link of a session, so its token stops working.
+// SECURITY: Enforces session ownership — users can only revoke their own links.
+func handleRevokeShare(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		// Verify ownership
+		sess, err := storageService.DatastoreFor(claims.TenantID).GetSession(sessionID)
+		if err != nil {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+		if sess.UserID != storageService.StoredUserIDFor(claims.TenantID, claims.UserID) || sess.TenantID != claims.TenantID {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+
+		if err := storageService.DatastoreFor(claims.TenantID).RevokeShareToken(sessionID); err != nil {
+			util.LogError(logger, "http", "revoke share token", err, "session_id", sessionID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Info("Session share revoked", "session_id", sessionID, "user_id", claims.UserID)
+		c.JSON(constants.StatusOK, gin.H{
+			"session_id": sessionID,
+			"status":     "revoked",
+		})
+	}
+}
+
+// grantSessionAccessRequest is the request body for handleGrantSessionAccess
+type grantSessionAccessRequest struct {
+	Access string `json:"access"` // constants.GrantAccessRead or constants.GrantAccessWrite
+}
+
+// respondSessionGrants writes the grants of a session after a grant operation, or its error
+func respondSessionGrants(c *gin.Context, sessionID string, grants []storage.SessionGrant, err error, logger *golog.Logger) {
+	switch {
+	case err == nil:
+		c.JSON(constants.StatusOK, gin.H{"session_id": sessionID, "grants": grants})
+	case errors.Is(err, storage.ErrSessionNotFound):
+		httperrors.RespondSessionNotFound(c)
+	case errors.Is(err, storage.ErrInvalidGrant), errors.Is(err, storage.ErrTooManyGrants):
+		httperrors.RespondBadRequest(c, err.Error())
+	case errors.Is(err, storage.ErrAnonymizedMode):
+		httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+	default:
+		util.LogError(logger, "http", "update session grants", err, "session_id", sessionID)
+		httperrors.RespondInternalError(c)
+	}
+}
+
+// handleListSessionGrants lists the users a session of the authenticated user is shared with.
+func handleListSessionGrants(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		grants, err := storageService.ForTenant(claims.TenantID).ListSessionGrants(sessionID, claims.UserID)
+		respondSessionGrants(c, sessionID, grants, err, logger)
+	}
+}
+
+// handleGrantSessionAccess shares a session of the authenticated user with
+// another user of their tenant, for reading or also writing, replacing the
+// access of an earlier grant to that user.
+func handleGrantSessionAccess(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Guest sessions are claimed by logging in, not shared
+		if util.HasRole(claims.Roles, constants.RoleGuest) {
+			httperrors.RespondForbidden(c)
+			return
+		}
+
+		var req grantSessionAccessRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		granteeID := c.Param("userID")
+		grants, err := storageService.ForTenant(claims.TenantID).GrantUserSessionAccess(sessionID, claims.UserID, granteeID, req.Access)
+		// No else needed: optional operation (log successful grants)
+		if err == nil {
+			logger.Info("Session access granted",
+				"session_id", sessionID,
+				"user_id", claims.UserID,
+				"grantee_id", granteeID,
+				"access", req.Access)
+		}
+		respondSessionGrants(c, sessionID, grants, err, logger)
+	}
+}
+
+// handleRevokeSessionAccess revokes the access of a user to a session of the
+// authenticated user. Revoking access that was never granted changes nothing.
+func handleRevokeSessionAccess(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		granteeID := c.Param("userID")
+		grants, err := storageService.ForTenant(claims.TenantID).RevokeUserSessionAccess(sessionID, claims.UserID, granteeID)
+		// No else needed: optional operation (log successful revocations)
+		if err == nil {
+			logger.Info("Session access revoked",
+				"session_id", sessionID,
+				"user_id", claims.UserID,
+				"grantee_id", granteeID)
+		}
+		respondSessionGrants(c, sessionID, grants, err, logger)
+	}
+}
+
+// handleSharedSessions lists the sessions other users of the caller's tenant
+// shared with them, most recent first
+func handleSharedSessions(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Message content is never stored in anonymized mode, so nothing is shared
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		sessions, err := storageService.ForTenant(claims.TenantID).ListSharedSessions(claims.UserID, constants.DefaultSessionLimit)
+		if err != nil {
+			util.LogError(logger, "http", "list shared sessions", err, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"sessions":  sessions,
+			"count":     len(sessions),
+			"limit":     constants.DefaultSessionLimit,
+			"truncated": len(sessions) == constants.DefaultSessionLimit,
+		})
+	}
+}
+
+// forkSessionRequest is the request body for handleForkSession
+type forkSessionRequest struct {
+	// MessageIndex is the index of the last message copied into the fork; nil copies every message
+	MessageIndex *int `json:"message_index"`
+}
+
+// handleForkSession clones a session's messages up to a message index into a new
+// session, so users can explore an alternate conversation path without losing the
+// original. The fork becomes the user's active session; a previously active session
+// is ended. webhooks and bus may be nil when no webhook endpoints or event sinks
+// are configured.
+// SECURITY: Enforces session ownership — users can only fork their own sessions.
+func handleForkSession(storageService *storage.StorageService, sessionManager *session.SessionManager, webhooks router.WebhookPublisher, bus router.EventPublisher, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		// Forking copies message content, which is not stored in anonymized mode
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		// An empty body forks the whole session
+		var req forkSessionRequest
+		// No else needed: optional operation (body is optional)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+				httperrors.RespondBadRequest(c, "Invalid request body")
+				return
+			}
+		}
+
+		// Verify ownership
+		source, err := storageService.DatastoreFor(claims.TenantID).GetSession(sessionID)
+		if err != nil {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+		if source.UserID != storageService.StoredUserIDFor(claims.TenantID, claims.UserID) || source.TenantID != claims.TenantID {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+
+		messageCount := len(source.Messages)
+		// No else needed: optional operation (fork point defaults to the last message)
+		if req.MessageIndex != nil {
+			if *req.MessageIndex < 0 || *req.MessageIndex >= len(source.Messages) {
+				httperrors.RespondBadRequest(c, "message_index is out of range")
+				return
+			}
+			messageCount = *req.MessageIndex + 1
+		}
+
+		fork, ok := branchSession(c, storageService, sessionManager, webhooks, bus, claims, source, messageCount, logger)
+		// No else needed: early return pattern (error response already sent)
+		if !ok {
+			return
+		}
+
+		logger.Info("Session forked",
+			"session_id", fork.ID,
+			"source_session_id", sessionID,
+			"user_id", claims.UserID,
+			"message_count", messageCount)
+		c.JSON(constants.StatusCreated, gin.H{
+			"session_id":    fork.ID,
+			"forked_from":   sessionID,
+			"message_count": messageCount,
+		})
+	}
+}
+
+// branchSession copies the first messageCount messages of source into a new
+// session that becomes the user's active session; a previously active session is
+// ended. Sends the error response and returns false on failure.
+func branchSession(c *gin.Context, storageService *storage.StorageService, sessionManager *session.SessionManager, webhooks router.WebhookPublisher, bus router.EventPublisher, claims *auth.Claims, source *session.Session, messageCount int, logger *golog.Logger) (*session.Session, bool) {
+	// No else needed: optional operation (user may have no active session)
+	if active, err := sessionManager.GetActiveSessionForTenantUser(claims.TenantID, claims.UserID); err == nil {
+		_ = sessionManager.EndSession(active.ID)
+		if err := storageService.DatastoreFor(claims.TenantID).EndSession(active.ID, time.Now()); err != nil {
+			util.LogError(logger, "http", "end session before branch", err, "session_id", active.ID)
+			httperrors.RespondInternalError(c)
+			return nil, false
+		}
+		// No else needed: optional operation (only when webhooks are configured)
+		if webhooks != nil {
+			webhooks.Publish(webhook.Event{
+				Type:      webhook.EventSessionEnded,
+				SessionID: active.ID,
+				UserID:    claims.UserID,
+				TenantID:  claims.TenantID,
+			})
+		}
+	}
+
+	branch, err := sessionManager.ForkSession(source, messageCount)
+	if err != nil {
+		util.LogError(logger, "http", "branch session", err, "session_id", source.ID)
+		httperrors.RespondInternalError(c)
+		return nil, false
+	}
+
+	// Persist the branch; roll back the in-memory session on failure
+	if err := storageService.DatastoreFor(claims.TenantID).CreateSession(branch); err != nil {
+		_ = sessionManager.EndSession(branch.ID)
+		util.LogError(logger, "http", "create branched session", err, "session_id", branch.ID)
+		httperrors.RespondInternalError(c)
+		return nil, false
+	}
+
+	// No else needed: optional operation (only when the event bus is configured)
+	if bus != nil {
+		bus.Publish(events.Event{
+			Type:      events.TypeSessionCreated,
+			SessionID: branch.ID,
+			UserID:    claims.UserID,
+			TenantID:  claims.TenantID,
+			Data:      map[string]string{"branched_from": source.ID},
+		})
+	}
+	return branch, true
+}
+
+// handleMergeSessions merges a duplicate session (sourceID) into an earlier,
+// ended session: messages are interleaved chronologically, token totals are
+// added up, the target records the merge, and the source is ended if still
+// active and soft-deleted. webhooks may be nil when no webhook endpoints are
+// configured.
+// SECURITY: Enforces session ownership — users can only merge their own sessions.
+func handleMergeSessions(storageService *storage.StorageService, sessionManager *session.SessionManager, webhooks router.WebhookPublisher, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		targetID := c.Param("sessionID")
+		sourceID := c.Param("sourceID")
+		if targetID == "" || sourceID == "" {
+			httperrors.RespondBadRequest(c, "session IDs are required")
+			return
+		}
+		if targetID == sourceID {
+			httperrors.RespondBadRequest(c, "cannot merge a session into itself")
+			return
+		}
+
+		// Merging rewrites message content, which is not stored in anonymized mode
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		result, err := storageService.ForTenant(claims.TenantID).MergeUserSessions(targetID, sourceID, claims.UserID, time.Now())
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrSessionNotFound):
+				httperrors.RespondSessionNotFound(c)
+			case errors.Is(err, storage.ErrSessionActive):
+				httperrors.Respond(c, apierror.CodeConflict, "End the session before merging another session into it")
+			case errors.Is(err, storage.ErrMergeConflict):
+				httperrors.Respond(c, apierror.CodeConflict, "The session changed during the merge, please try again")
+			default:
+				util.LogError(logger, "http", "merge sessions", err, "session_id", targetID, "source_session_id", sourceID, "user_id", claims.UserID)
+				httperrors.RespondInternalError(c)
+			}
+			return
+		}
+
+		// End in-memory session (ignore not-found — may already be expired from memory)
+		_ = sessionManager.EndSession(sourceID)
+
+		// No else needed: optional operation (only sources ended by this merge, when webhooks are configured)
+		if result.SourceEnded && webhooks != nil {
+			webhooks.Publish(webhook.Event{
+				Type:      webhook.EventSessionEnded,
+				SessionID: sourceID,
+				UserID:    claims.UserID,
+				TenantID:  claims.TenantID,
+				Data:      map[string]string{"merged_into": targetID},
+			})
+		}
+
+		logger.Info("Sessions merged",
+			"session_id", targetID,
+			"source_session_id", sourceID,
+			"user_id", claims.UserID,
+			"message_count", result.MessageCount)
+		c.JSON(constants.StatusOK, result)
+	}
+}
+
+// snapshotRequest is the request body for handleCreateSnapshot
+type snapshotRequest struct {
+	Label string `json:"label"`
+}
+
+// handleCreateSnapshot stores a restore point of a session of the authenticated user.
+func handleCreateSnapshot(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		// Snapshots copy message content, which is not stored in anonymized mode
+		if storageService.IsAnonymizedTenant(claims.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		// An empty body creates an unlabelled snapshot
+		var req snapshotRequest
+		// No else needed: optional operation (body is optional)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+				httperrors.RespondBadRequest(c, "Invalid request body")
+				return
+			}
+		}
+		label := strings.TrimSpace(req.Label)
+		if len(label) > constants.MaxSnapshotLabelLength {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("label must be at most %d bytes", constants.MaxSnapshotLabelLength))
+			return
+		}
+
+		snapshot, err := storageService.ForTenant(claims.TenantID).CreateUserSnapshot(sessionID, claims.UserID, label)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrSessionNotFound):
+				httperrors.RespondSessionNotFound(c)
+			case errors.Is(err, storage.ErrTooManySnapshots):
+				httperrors.RespondBadRequest(c, err.Error())
+			default:
+				util.LogError(logger, "http", "create snapshot", err, "session_id", sessionID, "user_id", claims.UserID)
+				httperrors.RespondInternalError(c)
+			}
+			return
+		}
+
+		logger.Info("Session snapshot created",
+			"snapshot_id", snapshot.ID,
+			"session_id", sessionID,
+			"user_id", claims.UserID,
+			"message_count", snapshot.MessageCount)
+		c.JSON(constants.StatusCreated, snapshot)
+	}
+}
+
+// handleListSnapshots lists the snapshots of a session of the authenticated user, newest first.
+func handleListSnapshots(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		sessionID := c.Param("sessionID")
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, "session ID is required")
+			return
+		}
+
+		snapshots, err := storageService.ForTenant(claims.TenantID).ListUserSnapshots(sessionID, claims.UserID)
+		if err != nil {
+			util.LogError(logger, "http", "list snapshots", err, "session_id", sessionID, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{"session_id": sessionID, "snapshots": snapshots})
+	}
+}
+
+// handleRestoreSnapshot branches a new session from a snapshot of the authenticated
+// user. The snapshot and the session it was taken from are left untouched; the new
+// session becomes the user's active session. webhooks and bus may be nil when no
+// webhook endpoints or event sinks are configured.
+func handleRestoreSnapshot(storageService *storage.StorageService, sessionManager *session.SessionManager, webhooks router.WebhookPublisher, bus router.EventPublisher, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsInterface, exists := c.Get("claims")
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		snapshotID := c.Param("snapshotID")
+		if snapshotID == "" {
+			httperrors.RespondBadRequest(c, "snapshot ID is required")
+			return
+		}
+
+		snapshot, source, err := storageService.ForTenant(claims.TenantID).GetUserSnapshot(snapshotID, claims.UserID)
+		if err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrSnapshotNotFound) {
+				httperrors.RespondNotFound(c, "Snapshot not found")
+				return
+			}
+			util.LogError(logger, "http", "get snapshot", err, "snapshot_id", snapshotID, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		restored, ok := branchSession(c, storageService, sessionManager, webhooks, bus, claims, source, len(source.Messages), logger)
+		// No else needed: early return pattern (error response already sent)
+		if !ok {
+			return
+		}
+
+		logger.Info("Session snapshot restored",
+			"session_id", restored.ID,
+			"snapshot_id", snapshot.ID,
+			"source_session_id", snapshot.SessionID,
+			"user_id", claims.UserID)
+		c.JSON(constants.StatusCreated, gin.H{
+			"session_id":    restored.ID,
+			"restored_from": snapshot.ID,
+			"message_count": len(source.Messages),
+		})
+	}
+}
+
+// wsTicketResponse is the response body of handleIssueWSTicket
+type wsTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handleIssueWSTicket exchanges the caller's JWT for a one-time ticket to open
+// a WebSocket or SSE connection with (?ticket=), so that the JWT itself stays
+// out of URLs and the logs that record them
+func handleIssueWSTicket(tickets wsticket.Store, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get claims from context
+		claimsInterface, exists := c.Get("claims")
+		// No else needed: early return pattern (guard clause)
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+
+		claims, ok := claimsInterface.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		ticket, expiresAt, err := tickets.Issue(c.Request.Context(), claims)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "issue ws ticket", err, "user_id", claims.UserID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(constants.StatusOK, wsTicketResponse{Ticket: ticket, ExpiresAt: expiresAt.UTC()})
+	}
+}
+
+// embedTokenRequest is the request body for handleEmbedToken
+type embedTokenRequest struct {
+	Origin string `json:"origin"`
+	// UserID identifies the visitor on the partner's side; empty for anonymous visitors
+	UserID string `json:"user_id"`
+}
+
+// handleEmbedToken mints a short-lived token for the chat widget embedded on a
+// partner site. The partner's backend authenticates with the API key configured
+// for its origin; the token is only accepted from that origin and counts against
+// the origin's hourly quota.
+func handleEmbedToken(registry *embed.Registry, validator *auth.JWTValidator, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey, err := util.ExtractBearerToken(c.GetHeader("Authorization"))
+		if err != nil {
+			httperrors.RespondUnauthorized(c, httperrors.MsgInvalidAuthHeader)
+			return
+		}
+
+		var req embedTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Origin == "" {
+			httperrors.RespondBadRequest(c, "origin is required")
+			return
+		}
+		if len(req.UserID) > constants.MaxEmbedUserIDLength {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("user_id must be at most %d bytes", constants.MaxEmbedUserIDLength))
+			return
+		}
+
+		origin, retryAfter, err := registry.Authorize(req.Origin, apiKey)
+		if err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, embed.ErrQuotaExceeded) {
+				logger.Warn("Embed token quota exceeded", "origin", req.Origin, "retry_after_ms", retryAfter)
+				retryAfterSeconds := (retryAfter + constants.MillisecondsPerSecond - 1) / constants.MillisecondsPerSecond
+				// No else needed: optional operation (minimum retry after enforcement)
+				if retryAfterSeconds < constants.MinRetryAfterSeconds {
+					retryAfterSeconds = constants.MinRetryAfterSeconds
+				}
+				c.Header(constants.HeaderRetryAfter, fmt.Sprintf("%d", retryAfterSeconds))
+				httperrors.RespondRateLimited(c, err.Error(), retryAfter)
+				return
+			}
+			logger.Warn("Embed token request rejected", "origin", req.Origin, "error", err)
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+
+		// Partner user IDs are namespaced by origin so partners cannot impersonate each other's users
+		subject := ""
+		// No else needed: optional operation (anonymous visitors get a random ID)
+		if req.UserID != "" {
+			subject = origin.Name + ":" + req.UserID
+		}
+		token, claims, err := validator.IssueEmbedToken(origin.Origin, origin.TenantID, subject, origin.TokenTTL)
+		if err != nil {
+			util.LogError(logger, "http", "issue embed token", err, "origin", origin.Origin)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Info("Embed token issued", "origin", origin.Origin, "user_id", claims.UserID)
+		c.JSON(constants.StatusOK, gin.H{
+			"token":      token,
+			"user_id":    claims.UserID,
+			"expires_at": time.Now().Add(origin.TokenTTL).UTC(),
+		})
+	}
+}
+
+// handleGetSharedSession returns session data for a public share link, or
+// renders the transcript as a page for browsers (requests accepting text/html).
+// No authentication required — anyone with an unexpired share token can view.
+func handleGetSharedSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shareToken := c.Param("shareToken")
+		if shareToken == "" {
+			httperrors.RespondBadRequest(c, "share token is required")
+			return
+		}
+
+		sess, link, err := storageService.GetSessionByShareToken(shareToken)
+		if err != nil {
+			if errors.Is(err, storage.ErrSessionNotFound) {
+				httperrors.Respond(c, apierror.CodeSessionNotFound, constants.ErrMsgSharedSessionNotFound)
+				return
+			}
+			util.LogError(logger, "http", "get shared session", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Revoked links must stop working at once, so nothing may be cached
+		c.Header("Cache-Control", "no-store")
+		c.Header("X-Robots-Tag", "noindex")
+		// No else needed: early return pattern (browsers get the rendered transcript)
+		if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+			page, err := renderSharePage(sess, link)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				util.LogError(logger, "http", "render shared session", err, "session_id", sess.ID)
+				httperrors.RespondInternalError(c)
+				return
+			}
+			c.Data(constants.StatusOK, "text/html; charset=utf-8", page)
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"session_id": sess.ID,
+			"name":       sess.Name,
+			"messages":   sess.Messages,
+			"expires_at": link.ExpiresAt,
+		})
+	}
+}
+
+// handleListSessions returns a handler for listing sessions with pagination, filtering, and sorting
+func handleListSessions(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Parse query parameters
+		userID := c.Query("user_id")
+		if len(userID) > 255 {
+			httperrors.RespondBadRequest(c, "user_id exceeds maximum length of 255 characters")
+			return
+		}
+		status := c.Query("status")                       // "active" or "ended"
+		adminAssistedStr := c.Query("admin_assisted")     // "true" or "false"
+		sortBy := c.DefaultQuery("sort_by", "start_time") // "start_time", "end_time", "message_count", "total_tokens", "user_id"
+		sortOrder := c.DefaultQuery("sort_order", "desc") // "asc" or "desc"
+		limitStr := c.DefaultQuery("limit", "100")
+		offsetStr := c.DefaultQuery("offset", "0")
+		startTimeFromStr := c.Query("start_time_from") // RFC3339 format
+		startTimeToStr := c.Query("start_time_to")     // RFC3339 format
+		after := c.Query("after")                      // next_cursor of the previous page
+		appVersion := c.Query("app_version")           // client SDK app version
+		platform := c.Query("platform")                // client platform
+		if len(appVersion) > constants.MaxClientMetadataLength || len(platform) > constants.MaxClientMetadataLength {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("app_version and platform must be at most %d characters", constants.MaxClientMetadataLength))
+			return
+		}
+
+		// Validate sort parameters against whitelist
+		if !constants.ValidSortFields[sortBy] {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("invalid sort_by field %q; allowed: start_time, end_time, message_count, total_tokens, user_id", sortBy))
+			return
+		}
+		if !constants.ValidSortOrders[sortOrder] {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("invalid sort_order %q; allowed: asc, desc", sortOrder))
+			return
+		}
+
+		// Parse limit
+		limit := constants.DefaultSessionLimit
+		// No else needed: optional operation (limit parsing with validation)
+		if l, err := fmt.Sscanf(limitStr, "%d", &limit); err == nil && l == 1 {
+			// No else needed: optional operation (limit range validation)
+			if limit <= 0 || limit > constants.MaxSessionLimit {
+				limit = constants.DefaultSessionLimit
+			}
+		}
+
+		// Parse offset
+		offset := 0
+		// No else needed: optional operation (offset parsing with validation)
+		if o, err := fmt.Sscanf(offsetStr, "%d", &offset); err == nil && o == 1 {
+			// No else needed: optional operation (offset range validation)
+			if offset < 0 {
+				offset = 0
+			}
+		}
+
+		// Cursor pagination follows the start time order and replaces the offset
+		// No else needed: optional operation (cursor validation)
+		if after != "" {
+			if sortBy != "start_time" {
+				httperrors.RespondBadRequest(c, "after requires sort_by=start_time")
+				return
+			}
+			if offset != 0 {
+				httperrors.RespondBadRequest(c, "after cannot be combined with offset")
+				return
+			}
+		}
+
+		// Parse admin_assisted filter
+		var adminAssisted *bool
+		// No else needed: optional operation (filter parsing)
+		if adminAssistedStr != "" {
+			val := adminAssistedStr == "true"
+			adminAssisted = &val
+		}
+
+		// Parse active status filter
+		var active *bool
+		// No else needed: optional operation (filter parsing)
+		if status != "" {
+			// No else needed: optional operation (status value parsing)
+			if status == "active" {
+				val := true
+				active = &val
+			} else if status == "ended" {
+				val := false
+				active = &val
+			}
+		}
+
+		// Parse time range filters
+		var startTimeFrom, startTimeTo *time.Time
+		// No else needed: optional operation (time filter parsing)
+		if startTimeFromStr != "" {
+			t, err := time.Parse(time.RFC3339, startTimeFromStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				logger.Warn("Invalid start_time_from parameter",
+					"value", startTimeFromStr,
+					"error", err,
+					"component", "http")
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			startTimeFrom = &t
+		}
+		// No else needed: optional operation (time filter parsing)
+		if startTimeToStr != "" {
+			t, err := time.Parse(time.RFC3339, startTimeToStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				logger.Warn("Invalid start_time_to parameter",
+					"value", startTimeToStr,
+					"error", err,
+					"component", "http")
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			startTimeTo = &t
+		}
+
+		// Parse tag filter (comma-separated, sessions must carry every tag)
+		var tags []string
+		// No else needed: optional operation (filter parsing)
+		if tagsStr := c.Query("tags"); tagsStr != "" {
+			parsed, err := storage.NormalizeTags(strings.Split(tagsStr, ","))
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, err.Error())
+				return
+			}
+			tags = parsed
+		}
+
+		// Translate API sort field name to internal BSON field name
+		internalSortBy := constants.APISortFieldMap[sortBy]
+
+		// Build options for ListAllSessionsWithOptions
+		opts := &storage.SessionListOptions{
+			Limit:         limit,
+			Offset:        offset,
+			After:         after,
+			UserID:        userID,
+			StartTimeFrom: startTimeFrom,
+			StartTimeTo:   startTimeTo,
+			AdminAssisted: adminAssisted,
+			Active:        active,
+			Tags:          tags,
+			AppVersion:    appVersion,
+			Platform:      platform,
+			SortBy:        internalSortBy,
+			SortOrder:     sortOrder,
+		}
+
+		// List sessions with options
+		sessions, err := adminStorage(c, storageService).ListAllSessionsWithOptions(opts)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrInvalidCursor) {
+				httperrors.RespondBadRequest(c, err.Error())
+				return
+			}
+			// Log detailed error server-side
+			util.LogError(logger, "http", "list sessions", err)
+			// Send generic error to client
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// A full page sorted by start time may have more sessions after it
+		nextCursor := ""
+		// No else needed: optional operation (only when another page may follow)
+		if sortBy == "start_time" && len(sessions) == limit {
+			nextCursor = storage.SessionCursor(sessions[len(sessions)-1])
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"sessions":    sessions,
+			"count":       len(sessions),
+			"limit":       limit,
+			"offset":      offset,
+			"next_cursor": nextCursor,
+		})
+	}
+}
+
+// handleGetMetrics returns a handler for getting session metrics
+func handleGetMetrics(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", string(export.FormatJSON))
+		// No else needed: early return pattern (guard clause)
+		if format != string(export.FormatJSON) && format != string(export.FormatCSV) {
+			httperrors.RespondBadRequest(c, "format must be json or csv")
+			return
+		}
+
+		// Get query parameters for time range
+		startTimeStr := c.Query("start_time")
+		endTimeStr := c.Query("end_time")
+
+		// Parse time range
+		var startTime, endTime time.Time
+		var err error
+
+		// No else needed: optional operation (time range parsing with default)
+		if startTimeStr != "" {
+			startTime, err = time.Parse(time.RFC3339, startTimeStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				logger.Warn("Invalid start_time parameter",
+					"value", startTimeStr,
+					"error", err,
+					"component", "http")
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+		} else {
+			// Default to last 24 hours
+			startTime = time.Now().Add(-24 * time.Hour)
+		}
+
+		// No else needed: optional operation (time range parsing with default)
+		if endTimeStr != "" {
+			endTime, err = time.Parse(time.RFC3339, endTimeStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				logger.Warn("Invalid end_time parameter",
+					"value", endTimeStr,
+					"error", err,
+					"component", "http")
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+		} else {
+			// Default to now
+			endTime = time.Now()
+		}
+
+		// Get metrics from storage
+		metrics, err := adminStorage(c, storageService).GetRollupMetrics(startTime, endTime)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			// Log detailed error server-side
+			util.LogError(logger, "http", "get session metrics", err)
+			// Send generic error to client
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// TotalTokens is already computed by the metrics aggregation.
+		// No separate GetTokenUsage call needed.
+
+		// Per-stage latency of the AI replies, to find the slow pipeline stage
+		latency, err := adminStorage(c, storageService).GetLatencyBreakdown(startTime, endTime)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "get latency breakdown", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// No else needed: early return pattern (spreadsheet download)
+		if format == string(export.FormatCSV) {
+			var buf bytes.Buffer
+			values := append(sessionMetricValues(metrics), latencyMetricValues(latency)...)
+			// No else needed: early return pattern (guard clause)
+			if err := export.WriteMetricsCSV(&buf, startTime, endTime, values); err != nil {
+				util.LogError(logger, "http", "render session metrics", err)
+				httperrors.RespondInternalError(c)
+				return
+			}
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"metrics-%s.csv\"", endTime.UTC().Format("20060102T150405Z")))
+			c.Data(constants.StatusOK, export.FormatCSV.ContentType(), buf.Bytes())
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"metrics": metrics,
+			"latency": latency,
+			"time_range": gin.H{
+				"start": startTime.Format(time.RFC3339),
+				"end":   endTime.Format(time.RFC3339),
+			},
+		})
+	}
+}
+
+// sessionMetricValues lists the session metrics m as named values, for the CSV
+// export and the remote write pusher. Tag counts come last, by tag, followed by
+// the force-ended session counts by reason code.
+func sessionMetricValues(m *storage.Metrics) []export.MetricValue {
+	values := []export.MetricValue{
+		{Name: "total_sessions", Value: float64(m.TotalSessions)},
+		{Name: "active_sessions", Value: float64(m.ActiveSessions)},
+		{Name: "total_tokens", Value: float64(m.TotalTokens)},
+		{Name: "avg_response_time_ms", Value: float64(m.AvgResponseTime)},
+		{Name: "max_response_time_ms", Value: float64(m.MaxResponseTime)},
+		{Name: "admin_assisted_sessions", Value: float64(m.AdminAssistedCount)},
+		{Name: "feedback_up", Value: float64(m.FeedbackUp)},
+		{Name: "feedback_down", Value: float64(m.FeedbackDown)},
+		{Name: "feedback_positive_rate", Value: m.FeedbackPositiveRate},
+	}
+	tags := make([]string, 0, len(m.TagCounts))
+	for tag := range m.TagCounts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		values = append(values, export.MetricValue{Name: "tag_sessions", Tag: tag, Value: float64(m.TagCounts[tag])})
+	}
+	reasons := make([]string, 0, len(m.EndReasonCounts))
+	for reason := range m.EndReasonCounts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		values = append(values, export.MetricValue{Name: "force_ended_sessions", Tag: reason, Value: float64(m.EndReasonCounts[reason])})
+	}
+	return values
+}
+
+// latencyMetricValues converts a latency breakdown into metric values tagged
+// with the pipeline stage
+func latencyMetricValues(l *storage.LatencyBreakdown) []export.MetricValue {
+	values := []export.MetricValue{{Name: "latency_samples", Value: float64(l.Samples)}}
+	for _, stage := range storage.LatencyStages {
+		values = append(values,
+			export.MetricValue{Name: "latency_p50_ms", Tag: stage, Value: float64(l.Stages[stage].P50)},
+			export.MetricValue{Name: "latency_p95_ms", Tag: stage, Value: float64(l.Stages[stage].P95)})
+	}
+	return values
+}
+
+// handleGetCosts returns a handler reporting LLM usage and cost from start_time
+// to end_time (default: the last 30 days), grouped by group_by: tenant (default),
+// model or day
+func handleGetCosts(storageService *storage.StorageService, costs *cost.Calculator, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupBy := c.DefaultQuery("group_by", constants.CostGroupByTenant)
+		endTime := time.Now()
+		startTime := endTime.Add(-constants.DefaultCostReportWindow)
+
+		// No else needed: optional operation (time range parsing with default)
+		if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+			t, err := time.Parse(time.RFC3339, startTimeStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			startTime = t
+		}
+		// No else needed: optional operation (time range parsing with default)
+		if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+			t, err := time.Parse(time.RFC3339, endTimeStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			endTime = t
+		}
+
+		groups, err := adminStorage(c, storageService).GetCosts(startTime, endTime, groupBy)
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, storage.ErrInvalidCostGrouping) {
+			httperrors.RespondBadRequest(c, "group_by must be tenant, model or day")
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "get costs", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		var totalTokens int64
+		var totalCost float64
+		for _, group := range groups {
+			totalTokens += group.Tokens
+			totalCost += group.Cost
+		}
+		c.JSON(constants.StatusOK, gin.H{
+			"group_by":     groupBy,
+			"currency":     costs.Currency(),
+			"groups":       groups,
+			"total_tokens": totalTokens,
+			"total_cost":   totalCost,
+			"time_range": gin.H{
+				"start": startTime.Format(time.RFC3339),
+				"end":   endTime.Format(time.RFC3339),
+			},
+		})
+	}
+}
+
+// handleSearchSessions returns a handler that searches message content across
+// sessions. The q query parameter must match every word; results are the most
+// recent matching sessions with snippets of the matching messages.
+func handleSearchSessions(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := strings.TrimSpace(c.Query("q"))
+
+		// No else needed: early return pattern (guard clause)
+		if query == "" {
+			httperrors.RespondBadRequest(c, "q query parameter is required")
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if len(query) > constants.MaxSearchQueryLength {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("q must not exceed %d characters", constants.MaxSearchQueryLength))
+			return
+		}
+
+		// Message content is never stored in anonymized mode
+		store := adminStorage(c, storageService)
+		// No else needed: early return pattern (guard clause)
+		if store.IsAnonymized() {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		limit := constants.DefaultSearchLimit
+		// No else needed: optional operation (limit parsing with validation)
+		if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= constants.MaxSearchLimit {
+			limit = l
+		}
+
+		results, err := store.SearchSessions(query, limit)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrEmptySearchQuery) {
+				httperrors.RespondBadRequest(c, err.Error())
+				return
+			}
+			util.LogError(logger, "http", "search sessions", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"query":   query,
+			"results": results,
+			"count":   len(results),
+		})
+	}
+}
+
+// handleExportSession returns a handler that downloads a session's full decrypted
+// transcript, including system events, admin interventions, and token counts.
+// The format query parameter selects json (default), csv, or md.
+func handleExportSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionID")
+
+		// No else needed: early return pattern (guard clause)
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgSessionIDRequired)
+			return
+		}
+
+		format, err := export.ParseFormat(c.Query("format"))
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			httperrors.RespondBadRequest(c, err.Error())
+			return
+		}
+
+		sess, err := adminStorage(c, storageService).GetSession(sessionID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrSessionNotFound) {
+				httperrors.RespondSessionNotFound(c)
+				return
+			}
+			util.LogError(logger, "http", "get session for export", err, "session_id", sessionID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Transcripts are never stored in anonymized mode
+		// No else needed: early return pattern (guard clause)
+		if storageService.IsAnonymizedTenant(sess.TenantID) {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		var buf bytes.Buffer
+		// Render fully before responding so a failure never sends a truncated file
+		// No else needed: early return pattern (guard clause)
+		if err := export.NewTranscript(sess, time.Now()).Write(&buf, format); err != nil {
+			util.LogError(logger, "http", "render session export", err,
+				"session_id", sessionID,
+				"format", string(format))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// No else needed: optional operation (audit log of who exported what)
+		if claims, ok := c.Get("claims"); ok {
+			if adminClaims, ok := claims.(*auth.Claims); ok {
+				logger.Info("Session transcript exported",
+					"session_id", sessionID,
+					"admin_id", adminClaims.UserID,
+					"format", string(format))
+			}
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"session-%s.%s\"", sessionID, format.Extension()))
+		c.Data(constants.StatusOK, format.ContentType(), buf.Bytes())
+	}
+}
+
+// handleExportUserData returns a handler that exports all stored sessions of a
+// user as a ZIP archive of JSON transcripts, with the facts remembered about
+// the user, for data subject access requests.
+func handleExportUserData(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userID")
+
+		// No else needed: early return pattern (guard clause)
+		if userID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgUserIDRequired)
+			return
+		}
+
+		sessions, err := adminStorage(c, storageService).ExportUserData(userID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "export user data", err, "user_id", userID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		facts, err := adminStorage(c, storageService).ExportUserMemory(userID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "export user memory", err, "user_id", userID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		var buf bytes.Buffer
+		// Render fully before responding so a failure never sends a truncated file
+		// No else needed: early return pattern (guard clause)
+		if err := export.WriteUserArchive(&buf, userID, sessions, facts, time.Now()); err != nil {
+			util.LogError(logger, "http", "render user data export", err, "user_id", userID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Info("User data exported", "user_id", userID, "sessions", len(sessions), "memory_facts", len(facts))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"user-%s.zip\"", userID))
+		c.Data(constants.StatusOK, export.ArchiveContentType, buf.Bytes())
+	}
+}
+
+// handleFinetuneExport returns a handler that exports stored conversations as
+// a fine-tuning dataset: a ZIP archive of the dataset and a manifest of the
+// included session IDs, with PII redacted. The format query parameter selects
+// openai-jsonl (default) or sharegpt; tags (comma-separated, all required),
+// start_time_from and start_time_to (RFC3339), min_feedback_score and limit
+// select the sessions.
+func handleFinetuneExport(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, err := export.ParseFinetuneFormat(c.Query("format"))
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			httperrors.RespondBadRequest(c, err.Error())
+			return
+		}
+
+		// Transcripts are never stored in anonymized mode
+		store := adminStorage(c, storageService)
+		// No else needed: early return pattern (guard clause)
+		if store.IsAnonymized() {
+			httperrors.RespondFeatureDisabled(c, constants.ErrMsgAnonymizedMode)
+			return
+		}
+
+		var opts storage.FinetuneOptions
+		filters := make(map[string]string)
+		// No else needed: optional operation (filter parsing)
+		if tagsStr := c.Query("tags"); tagsStr != "" {
+			tags, err := storage.NormalizeTags(strings.Split(tagsStr, ","))
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, err.Error())
+				return
+			}
+			opts.Tags = tags
+			filters["tags"] = strings.Join(tags, ",")
+		}
+		for _, param := range []string{"start_time_from", "start_time_to"} {
+			value := c.Query(param)
+			// No else needed: optional operation (filter parsing)
+			if value == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, value)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			// No else needed: conditional assignment (lower or upper bound)
+			if param == "start_time_from" {
+				opts.StartTimeFrom = &t
+			} else {
+				opts.StartTimeTo = &t
+			}
+			filters[param] = value
+		}
+		// No else needed: optional operation (filter parsing)
+		if scoreStr := c.Query("min_feedback_score"); scoreStr != "" {
+			score, err := strconv.Atoi(scoreStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, "min_feedback_score must be an integer")
+				return
+			}
+			opts.MinFeedbackScore = &score
+			filters["min_feedback_score"] = scoreStr
+		}
+		// No else needed: optional operation (limit parsing)
+		if limitStr := c.Query("limit"); limitStr != "" {
+			limit, err := strconv.Atoi(limitStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil || limit <= 0 || limit > constants.MaxFinetuneSessions {
+				httperrors.RespondBadRequest(c, fmt.Sprintf("limit must be between 1 and %d", constants.MaxFinetuneSessions))
+				return
+			}
+			opts.Limit = limit
+			filters["limit"] = limitStr
+		}
+
+		sessions, err := store.FinetuneSessions(opts)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "find sessions for fine-tuning export", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		var buf bytes.Buffer
+		exportedAt := time.Now()
+		// Render fully before responding so a failure never sends a truncated file
+		manifest, err := export.WriteFinetuneArchive(&buf, sessions, format, filters, exportedAt)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "render fine-tuning export", err, "format", string(format))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Info("Fine-tuning dataset exported",
+			"format", string(format),
+			"sessions", manifest.SessionCount,
+			"skipped", len(manifest.Skipped),
+			"redactions", manifest.Redactions)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"finetune-%s-%s.zip\"", format, exportedAt.UTC().Format("20060102-150405")))
+		c.Data(constants.StatusOK, export.ArchiveContentType, buf.Bytes())
+	}
+}
+
+// handleDeleteUserData returns a handler that permanently erases all stored
+// sessions of a user, for data subject erasure requests. The erasure is kept in
+// the audit log (actor, time and subject user ID) by auditMiddleware.
+func handleDeleteUserData(storageService *storage.StorageService, sessionManager *session.SessionManager, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userID")
+
+		// No else needed: early return pattern (guard clause)
+		if userID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgUserIDRequired)
+			return
+		}
+
+		sessionIDs, err := adminStorage(c, storageService).DeleteUserData(userID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "delete user data", err, "user_id", userID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Live copies in memory would otherwise be written back on the next message
+		removed := sessionManager.RemoveSessions(sessionIDs)
+
+		logger.Info("User data erased",
+			"user_id", userID,
+			"sessions_deleted", len(sessionIDs),
+			"sessions_in_memory", removed)
+		c.JSON(constants.StatusOK, gin.H{
+			"user_id":          userID,
+			"status":           "erased",
+			"sessions_deleted": len(sessionIDs),
+		})
+	}
+}
+
+// handleRestoreSession returns a handler that restores a session soft-deleted by
+// the retention purger, as long as it is still within the restore grace window.
+func handleRestoreSession(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionID")
+
+		// No else needed: early return pattern (guard clause)
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgSessionIDRequired)
+			return
+		}
+
+		// No else needed: early return pattern (guard clause)
+		if err := adminStorage(c, storageService).RestoreSession(sessionID); err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, storage.ErrSessionNotRestorable) {
+				httperrors.Respond(c, apierror.CodeSessionNotFound, "No restorable session found")
+				return
+			}
+			util.LogError(logger, "http", "restore session", err, "session_id", sessionID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// No else needed: optional operation (audit log of who restored what)
+		if claims, ok := c.Get("claims"); ok {
+			if adminClaims, ok := claims.(*auth.Claims); ok {
+				logger.Info("Session restored by admin",
+					"session_id", sessionID,
+					"admin_id", adminClaims.UserID)
+			}
+		}
+
+		c.JSON(constants.StatusOK, gin.H{"session_id": sessionID, "status": "restored"})
+	}
+}
+
+// handleDrain returns a handler that puts the service into drain mode ahead of a
+// shutdown: new connections and messages are refused and connected clients get a
+// server_draining event. The optional countdown query parameter (seconds) tells
+// clients how long they have to reconnect elsewhere.
+func handleDrain(wsHandler *websocket.Handler, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		countdown := constants.DefaultDrainCountdown
+		// No else needed: optional operation (countdown parsing with validation)
+		if countdownStr := c.Query("countdown"); countdownStr != "" {
+			seconds, err := strconv.Atoi(countdownStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > constants.MaxDrainCountdown {
+				httperrors.RespondBadRequest(c, fmt.Sprintf("countdown must be between 0 and %d seconds", int(constants.MaxDrainCountdown.Seconds())))
+				return
+			}
+			countdown = time.Duration(seconds) * time.Second
+		}
+
+		notified := wsHandler.StartDrain(countdown)
+
+		// No else needed: optional operation (audit log of who started the drain)
+		if claims, ok := c.Get("claims"); ok {
+			if adminClaims, ok := claims.(*auth.Claims); ok {
+				logger.Info("Drain mode started by admin",
+					"admin_id", adminClaims.UserID,
+					"countdown", countdown)
+			}
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"status":               "draining",
+			"countdown_seconds":    int(countdown.Seconds()),
+			"deadline":             time.Now().Add(countdown).UTC().Format(time.RFC3339),
+			"connections_notified": notified,
+		})
+	}
+}
+
+// handleIPStats returns a handler listing the client IPs with the most connection
+// attempts in the current window (?limit=, default 20), with their refused
+// attempts and active bans. Counts are per replica.
+func handleIPStats(ipLimiter *ratelimit.IPLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := constants.DefaultTopTalkers
+		// No else needed: optional operation (limit parsing with validation)
+		if limitStr := c.Query("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil || parsed <= 0 || parsed > constants.MaxTopTalkers {
+				httperrors.RespondBadRequest(c, fmt.Sprintf("limit must be between 1 and %d", constants.MaxTopTalkers))
+				return
+			}
+			limit = parsed
+		}
+
+		ips := ipLimiter.TopTalkers(limit)
+		c.JSON(constants.StatusOK, gin.H{
+			"ips":   ips,
+			"count": len(ips),
+		})
+	}
+}
+
+// handleListIPBans returns a handler listing the active IP bans, soonest expiry first
+func handleListIPBans(ipLimiter *ratelimit.IPLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bans := ipLimiter.Bans()
+		c.JSON(constants.StatusOK, gin.H{
+			"bans":  bans,
+			"count": len(bans),
+		})
+	}
+}
+
+// banIPRequest is the request body for banning a client IP
+type banIPRequest struct {
+	DurationMinutes int    `json:"duration_minutes"` // 0 selects constants.DefaultIPBanDuration
+	Reason          string `json:"reason"`
+}
+
+// handleBanIP returns a handler that bans a client IP from opening WebSocket and
+// SSE connections for a while. Existing connections are not closed.
+func handleBanIP(ipLimiter *ratelimit.IPLimiter, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := net.ParseIP(c.Param("ip"))
+		// No else needed: early return pattern (guard clause)
+		if ip == nil {
+			httperrors.RespondBadRequest(c, "ip must be an IPv4 or IPv6 address")
+			return
+		}
+
+		var req banIPRequest
+		// No else needed: optional operation (the body is optional)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				httperrors.RespondBadRequest(c, "Invalid request body")
+				return
+			}
+		}
+
+		duration := constants.DefaultIPBanDuration
+		// No else needed: optional operation (requested duration)
+		if req.DurationMinutes != 0 {
+			duration = time.Duration(req.DurationMinutes) * time.Minute
+		}
+		// No else needed: early return pattern (guard clause)
+		if duration <= 0 || duration > constants.MaxIPBanDuration {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("duration_minutes must be between 1 and %d", int(constants.MaxIPBanDuration.Minutes())))
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if len(req.Reason) > constants.MaxBanReasonLength {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("reason exceeds maximum length of %d bytes", constants.MaxBanReasonLength))
+			return
+		}
+
+		var bannedBy string
+		// No else needed: optional operation (record the banning admin)
+		if claims, ok := c.Get("claims"); ok {
+			if adminClaims, ok := claims.(*auth.Claims); ok {
+				bannedBy = adminClaims.UserID
+			}
+		}
+
+		ban := ipLimiter.Ban(ip.String(), duration, req.Reason, bannedBy)
+		logger.Info("Client IP banned by admin",
+			"ip", ban.IP,
+			"admin_id", bannedBy,
+			"until", ban.Until,
+			"reason", ban.Reason)
+
+		c.JSON(constants.StatusOK, ban)
+	}
+}
+
+// handleUnbanIP returns a handler that lifts the ban of a client IP
+func handleUnbanIP(ipLimiter *ratelimit.IPLimiter, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := net.ParseIP(c.Param("ip"))
+		// No else needed: early return pattern (guard clause)
+		if ip == nil {
+			httperrors.RespondBadRequest(c, "ip must be an IPv4 or IPv6 address")
+			return
+		}
+
+		// No else needed: early return pattern (guard clause)
+		if !ipLimiter.Unban(ip.String()) {
+			httperrors.RespondNotFound(c, "IP is not banned")
+			return
+		}
+
+		logger.Info("Client IP ban lifted by admin", "ip", ip.String())
+		c.JSON(constants.StatusOK, gin.H{"ip": ip.String(), "status": "unbanned"})
+	}
+}
+
+// handleListUserBlocks returns a handler listing the active user blocks of the
+// admin's tenant, or of every tenant for super admins, newest first
+func handleListUserBlocks(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		blocks, err := adminStorage(c, storageService).ListUserBlocks()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list user blocks", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{
+			"blocks": blocks,
+			"count":  len(blocks),
+		})
+	}
+}
+
+// blockUserRequest is the optional request body for blocking a user
+type blockUserRequest struct {
+	DurationMinutes int    `json:"duration_minutes"` // 0 blocks until lifted
+	Reason          string `json:"reason"`
+	// TenantID is the user's tenant; only super admins may name a tenant other than their own
+	TenantID string `json:"tenant_id"`
+}
+
+// blockTenant returns the tenant of the user an admin blocks or unblocks: the
+// admin's own, or the requested one for super admins. Responds 403 and returns
+// false when any other admin names another tenant.
+func blockTenant(c *gin.Context, requested string) (string, bool) {
+	tenantID, allTenants := adminTenantScope(c)
+	// No else needed: early return pattern (the admin's own tenant)
+	if requested == "" || requested == tenantID {
+		return tenantID, true
+	}
+	// No else needed: early return pattern (guard clause)
+	if !allTenants {
+		httperrors.RespondForbidden(c)
+		return "", false
+	}
+	return requested, true
+}
+
+// handleBlockUser returns a handler that blocks a user from opening WebSocket
+// and SSE connections, until the block expires or is lifted. The user's open
+// connections to this replica are closed; connections to other replicas are
+// refused when they reconnect.
+func handleBlockUser(storageService *storage.StorageService, wsHandler *websocket.Handler, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userID")
+		// No else needed: early return pattern (guard clause)
+		if userID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgUserIDRequired)
+			return
+		}
+
+		var req blockUserRequest
+		// No else needed: optional operation (the body is optional)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				httperrors.RespondBadRequest(c, "Invalid request body")
+				return
+			}
+		}
+		// No else needed: early return pattern (guard clause)
+		if req.DurationMinutes < 0 || time.Duration(req.DurationMinutes)*time.Minute > constants.MaxUserBlockDuration {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("duration_minutes must be between 0 (until lifted) and %d", int(constants.MaxUserBlockDuration.Minutes())))
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if len(req.Reason) > constants.MaxBanReasonLength {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("reason exceeds maximum length of %d bytes", constants.MaxBanReasonLength))
+			return
+		}
+		tenantID, ok := blockTenant(c, req.TenantID)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return
+		}
+
+		block := &storage.UserBlock{
+			UserID:   userID,
+			TenantID: tenantID,
+			Reason:   req.Reason,
+		}
+		// No else needed: optional operation (record the blocking admin)
+		if claims, ok := c.Get("claims"); ok {
+			if adminClaims, ok := claims.(*auth.Claims); ok {
+				block.BlockedBy = adminClaims.UserID
+			}
+		}
+		// No else needed: optional operation (blocks without a duration last until lifted)
+		if req.DurationMinutes > 0 {
+			expiresAt := time.Now().UTC().Add(time.Duration(req.DurationMinutes) * time.Minute)
+			block.ExpiresAt = &expiresAt
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := storageService.BlockUser(block); err != nil {
+			util.LogError(logger, "http", "block user", err, "user_id", userID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		closed := 0
+		for _, info := range wsHandler.ListConnections() {
+			// No else needed: optional operation (only the blocked user's connections)
+			if info.UserID != userID || info.TenantID != tenantID {
+				continue
+			}
+			// No else needed: optional operation (may have closed meanwhile)
+			if _, ok := wsHandler.CloseConnection(info.ConnectionID); ok {
+				closed++
+			}
+		}
+
+		logger.Info("User blocked by admin",
+			"user_id", userID,
+			"tenant_id", tenantID,
+			"admin_id", block.BlockedBy,
+			"expires_at", block.ExpiresAt,
+			"reason", block.Reason,
+			"closed_connections", closed)
+		c.JSON(constants.StatusOK, gin.H{
+			"block":              block,
+			"closed_connections": closed,
+		})
+	}
+}
+
+// handleUnblockUser returns a handler that lifts the block of a user. Super
+// admins name the user's tenant with the tenant_id query parameter.
+func handleUnblockUser(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userID")
+		// No else needed: early return pattern (guard clause)
+		if userID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgUserIDRequired)
+			return
+		}
+		tenantID, ok := blockTenant(c, c.Query("tenant_id"))
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return
+		}
+
+		err := storageService.UnblockUser(tenantID, userID)
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, storage.ErrUserBlockNotFound) {
+			httperrors.RespondNotFound(c, "User is not blocked")
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "unblock user", err, "user_id", userID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Info("User block lifted by admin", "user_id", userID, "tenant_id", tenantID)
+		c.JSON(constants.StatusOK, gin.H{"user_id": userID, "status": "unblocked"})
+	}
+}
+
+// handleListConnections returns a handler listing the active WebSocket and SSE
+// connections of this replica with their traffic and heartbeat round trip,
+// oldest first. Admins see their own tenant's connections; super admins see all.
+func handleListConnections(wsHandler *websocket.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, allTenants := adminTenantScope(c)
+		conns := make([]websocket.ConnectionInfo, 0)
+		for _, info := range wsHandler.ListConnections() {
+			// No else needed: optional operation (tenant isolation)
+			if allTenants || info.TenantID == tenantID {
+				conns = append(conns, info)
+			}
+		}
+		c.JSON(constants.StatusOK, gin.H{
+			"connections": conns,
+			"count":       len(conns),
+		})
+	}
+}
+
+// handleListJobs returns a handler listing the background jobs with their
+// schedules and recent runs. Jobs run for every tenant, so it requires super_admin.
+func handleListJobs(scheduler *jobs.Scheduler, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// No else needed: early return pattern (guard clause)
+		if _, allTenants := adminTenantScope(c); !allTenants {
+			httperrors.RespondForbidden(c)
+			return
+		}
+
+		statuses, err := scheduler.Status()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list jobs", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{"jobs": statuses})
+	}
+}
+
+// handleListAlerts returns a handler that lists the state of the usage alert
+// rules evaluated by this replica. Alerts cover every tenant, so only super
+// admins may list them.
+func handleListAlerts(monitor *alerts.Monitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// No else needed: early return pattern (guard clause)
+		if _, allTenants := adminTenantScope(c); !allTenants {
+			httperrors.RespondForbidden(c)
+			return
+		}
+		list := []alerts.Alert{}
+		firing := 0
+		// No else needed: optional operation (no rules configured lists none)
+		if monitor != nil {
+			list = monitor.Alerts()
+		}
+		for _, alert := range list {
+			// No else needed: optional operation (count firing rules)
+			if alert.State == alerts.StateFiring {
+				firing++
+			}
+		}
+		c.JSON(constants.StatusOK, gin.H{"alerts": list, "firing": firing})
+	}
+}
+
+// helpQueueEntry is a session waiting for an admin as listed by handleHelpQueue
+type helpQueueEntry struct {
+	SessionID   string         `json:"session_id"`
+	UserID      string         `json:"user_id"`
+	TenantID    string         `json:"tenant_id,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	ModelID     string         `json:"model_id,omitempty"`
+	Tier        string         `json:"tier,omitempty"`
+	RequestedAt time.Time      `json:"requested_at"`
+	WaitSeconds int64          `json:"wait_seconds"`
+	Score       priority.Score `json:"score"`
+}
+
+// handleHelpQueue returns a handler listing the sessions of this replica that
+// requested help and no admin has taken over, highest priority first (see
+// internal/priority). Admins see their own tenant's sessions; super admins see all.
+func handleHelpQueue(sessionManager *session.SessionManager, scorer *priority.Scorer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, allTenants := adminTenantScope(c)
+		now := time.Now()
+		queue := make([]helpQueueEntry, 0)
+		for _, req := range sessionManager.HelpQueue(constants.HelpQueueRecentMessages) {
+			// No else needed: optional operation (tenant isolation)
+			if !allTenants && req.TenantID != tenantID {
+				continue
+			}
+			wait := now.Sub(req.RequestedAt)
+			queue = append(queue, helpQueueEntry{
+				SessionID:   req.SessionID,
+				UserID:      req.UserID,
+				TenantID:    req.TenantID,
+				Name:        req.Name,
+				ModelID:     req.ModelID,
+				Tier:        req.CustomerTier,
+				RequestedAt: req.RequestedAt,
+				WaitSeconds: int64(wait.Seconds()),
+				Score: scorer.Score(priority.Input{
+					Wait:     wait,
+					Messages: req.UserMessages,
+					Tier:     req.CustomerTier,
+				}),
+			})
+		}
+		// Highest score first; equal scores in the order help was requested
+		sort.SliceStable(queue, func(i, j int) bool {
+			// No else needed: tie-breaker below
+			if queue[i].Score.Total != queue[j].Score.Total {
+				return queue[i].Score.Total > queue[j].Score.Total
+			}
+			return queue[i].RequestedAt.Before(queue[j].RequestedAt)
+		})
+		c.JSON(constants.StatusOK, gin.H{
+			"queue": queue,
+			"count": len(queue),
+		})
+	}
+}
+
+// handleCloseConnection returns a handler that force-closes an active connection
+// of this replica. The client may reconnect; ban its IP to keep it out.
+func handleCloseConnection(wsHandler *websocket.Handler, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		connectionID := c.Param("connectionID")
+		tenantID, allTenants := adminTenantScope(c)
+		found := false
+		for _, info := range wsHandler.ListConnections() {
+			// No else needed: optional operation (only the admin's tenant's connections)
+			if info.ConnectionID == connectionID && (allTenants || info.TenantID == tenantID) {
+				found = true
+				break
+			}
+		}
+		// No else needed: early return pattern (guard clause)
+		if !found {
+			httperrors.RespondNotFound(c, "Connection not found")
+			return
+		}
+
+		info, ok := wsHandler.CloseConnection(connectionID)
+		// No else needed: early return pattern (closed meanwhile)
+		if !ok {
+			httperrors.RespondNotFound(c, "Connection not found")
+			return
+		}
+
+		logger.Info("Connection force-closed by admin",
+			"connection_id", connectionID,
+			"user_id", info.UserID,
+			"session_id", info.SessionID)
+		c.JSON(constants.StatusOK, gin.H{
+			"connection": info,
+			"status":     "closed",
+		})
+	}
+}
+
+// impersonateRequest is the optional body of POST /admin/impersonate/:userID
+type impersonateRequest struct {
+	// TTL is how long the token is valid, as a Go duration (e.g. "10m")
+	TTL string `json:"ttl"`
+	// TenantID is the user's tenant; only super admins may name a tenant other than their own
+	TenantID string `json:"tenant_id"`
+}
+
+// handleImpersonate mints a short-lived token letting the admin open the
+// user-facing chat as the user, to reproduce user-reported issues. The token only
+// carries the impersonation role (never admin access) and names the admin, so
+// impersonated connections and messages are marked in logs and transcripts.
+func handleImpersonate(validator *auth.JWTValidator, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userID")
+		// No else needed: early return pattern (guard clause)
+		if userID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgUserIDRequired)
+			return
+		}
+
+		claimsInterface, exists := c.Get("claims")
+		// No else needed: early return pattern (guard clause)
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+		claims, ok := claimsInterface.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		var req impersonateRequest
+		// No else needed: optional operation (body is optional)
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+				httperrors.RespondBadRequest(c, "Invalid request body")
+				return
+			}
+		}
+
+		ttl := constants.DefaultImpersonationTTL
+		// No else needed: optional operation (default TTL when not given)
+		if req.TTL != "" {
+			parsed, err := time.ParseDuration(req.TTL)
+			// No else needed: early return pattern (guard clause)
+			if err != nil || parsed <= 0 || parsed > constants.MaxImpersonationTTL {
+				httperrors.RespondBadRequest(c, fmt.Sprintf("ttl must be a positive duration of at most %s", constants.MaxImpersonationTTL))
+				return
+			}
+			ttl = parsed
+		}
+
+		tenantID, allTenants := adminTenantScope(c)
+		// No else needed: optional operation (super admins may impersonate users of any tenant)
+		if req.TenantID != "" && req.TenantID != tenantID {
+			// No else needed: early return pattern (guard clause)
+			if !allTenants {
+				httperrors.RespondForbidden(c)
+				return
+			}
+			tenantID = req.TenantID
+		}
+
+		token, _, err := validator.IssueImpersonationToken(userID, tenantID, claims.UserID, ttl)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "issue impersonation token", err, "user_id", userID)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		logger.Warn("Admin impersonation token issued",
+			"admin_id", claims.UserID,
+			"user_id", userID,
+			"tenant_id", tenantID,
+			"ttl", ttl)
+		c.JSON(constants.StatusOK, gin.H{
+			"token":           token,
+			"user_id":         userID,
+			"impersonator_id": claims.UserID,
+			"expires_at":      time.Now().Add(ttl).UTC(),
+		})
+	}
+}
+
+// handleMigrationReport returns a handler reporting, without changing anything,
+// which session documents each pending schema migration would change. Session
+// documents of every tenant are migrated together, so super_admin is required.
+func handleMigrationReport(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// No else needed: early return pattern (guard clause)
+		if _, allTenants := adminTenantScope(c); !allTenants {
+			httperrors.RespondForbidden(c)
+			return
+		}
+
+		ctx, cancel := util.NewTimeoutContext(constants.SchemaMigrationTimeout)
+		defer cancel()
+		report, err := storageService.MigrateSessions(ctx, true)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "report session migrations", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		c.JSON(constants.StatusOK, report)
+	}
+}
+
+// handleRunMigrations returns a handler applying the pending session schema
+// migrations. Like the report, it requires super_admin.
+func handleRunMigrations(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// No else needed: early return pattern (guard clause)
+		if _, allTenants := adminTenantScope(c); !allTenants {
+			httperrors.RespondForbidden(c)
+			return
+		}
+
+		ctx, cancel := util.NewTimeoutContext(constants.SchemaMigrationTimeout)
+		defer cancel()
+		report, err := storageService.MigrateSessions(ctx, false)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "run session migrations", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		logger.Info("Session migrations run by admin", "schema_version", report.SchemaVersion)
+		c.JSON(constants.StatusOK, report)
+	}
+}
+
+// handleStartBackup returns a handler starting a backup of every session in the
+// background; poll GET /admin/backups for its outcome. Backups cover every
+// tenant, so super_admin is required.
+func handleStartBackup(runner *backup.Runner, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// No else needed: early return pattern (guard clause)
+		if _, allTenants := adminTenantScope(c); !allTenants {
+			httperrors.RespondForbidden(c)
+			return
+		}
+		var requestedBy string
+		claims, _ := c.Get("claims")
+		// No else needed: optional operation (record who started the backup)
+		if adminClaims, ok := claims.(*auth.Claims); ok {
+			requestedBy = adminClaims.UserID
+		}
+
+		job, err := runner.Start(requestedBy, time.Now())
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			switch {
+			case errors.Is(err, backup.ErrJobRunning):
+				httperrors.Respond(c, apierror.CodeConflict, "A backup is already running")
+			case errors.Is(err, backup.ErrShutdown):
+				httperrors.RespondServiceUnavailable(c)
+			default:
+				util.LogError(logger, "http", "start backup", err)
+				httperrors.RespondInternalError(c)
+			}
+			return
+		}
+		logger.Info("Session backup started by admin", "backup_id", job.ID, "admin_id", requestedBy)
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// handleListBackups returns a handler listing the latest backup jobs of this
+// replica, newest first. Like starting a backup, it requires super_admin.
+func handleListBackups(runner *backup.Runner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// No else needed: early return pattern (guard clause)
+		if _, allTenants := adminTenantScope(c); !allTenants {
+			httperrors.RespondForbidden(c)
+			return
+		}
+		jobs := runner.Jobs()
+		c.JSON(constants.StatusOK, gin.H{
+			"backups": jobs,
+			"count":   len(jobs),
+		})
+	}
+}
+
+// handleListAudit returns a handler listing the admin audit log, most recent first.
+// Filters: actor_id, session_id, action, from and to (RFC3339), plus limit and offset.
+// Admins see their own tenant's entries; super admins see every tenant.
+func handleListAudit(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := &storage.AuditListOptions{
+			ActorID:   c.Query("actor_id"),
+			SessionID: c.Query("session_id"),
+			Action:    c.Query("action"),
+		}
+
+		// No else needed: early return pattern (guard clause)
+		if len(opts.ActorID) > 255 || len(opts.SessionID) > 255 || len(opts.Action) > 255 {
+			httperrors.RespondBadRequest(c, "filter values must not exceed 255 characters")
+			return
+		}
+
+		// Parse limit and offset; invalid values fall back to the defaults
+		opts.Limit = constants.DefaultSessionLimit
+		// No else needed: optional operation (limit parsing with validation)
+		if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 && limit <= constants.MaxSessionLimit {
+			opts.Limit = limit
+		}
+		// No else needed: optional operation (offset parsing with validation)
+		if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+			opts.Offset = offset
+		}
+
+		// Parse time range filters
+		// No else needed: optional operation (time filter parsing)
+		if fromStr := c.Query("from"); fromStr != "" {
+			t, err := time.Parse(time.RFC3339, fromStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			opts.From = &t
+		}
+		// No else needed: optional operation (time filter parsing)
+		if toStr := c.Query("to"); toStr != "" {
+			t, err := time.Parse(time.RFC3339, toStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			opts.To = &t
+		}
+
+		entries, err := adminStorage(c, storageService).ListAuditEntries(opts)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list audit entries", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"entries": entries,
+			"count":   len(entries),
+			"limit":   opts.Limit,
+			"offset":  opts.Offset,
+		})
+	}
+}
+
+// handleListReports returns a handler listing the abuse reports of the admin's
+// tenant, or of every tenant for super admins, newest first. The session_id
+// query parameter restricts the list to one session.
+func handleListReports(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Query("session_id")
+		// No else needed: early return pattern (guard clause)
+		if len(sessionID) > 255 {
+			httperrors.RespondBadRequest(c, "filter values must not exceed 255 characters")
+			return
+		}
+		limit := constants.DefaultReportListLimit
+		// No else needed: optional operation (limit parsing with validation)
+		if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 && parsed <= constants.MaxReportListLimit {
+			limit = parsed
+		}
+
+		reports, err := adminStorage(c, storageService).ListAbuseReports(sessionID, limit)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list abuse reports", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{
+			"reports": reports,
+			"count":   len(reports),
+			"limit":   limit,
+		})
+	}
+}
+
+// takeoverDeniedResponse is the 409 body of a denied takeover: the error
+// envelope plus who holds the session and the admin's place in line
+type takeoverDeniedResponse struct {
+	apierror.Envelope
+	SessionID     string `json:"session_id"`
+	HolderID      string `json:"holder_id"`
+	HolderName    string `json:"holder_name"`
+	Reserved      bool   `json:"reserved"`
+	QueuePosition int    `json:"queue_position"`
+}
+
+// handleAdminTakeover returns a handler for admin session takeover
+func handleAdminTakeover(messageRouter *router.MessageRouter, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionID")
+
+		// No else needed: early return pattern (guard clause)
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgSessionIDRequired)
+			return
+		}
+
+		// Get admin claims from context (set by authMiddleware)
+		claimsInterface, exists := c.Get("claims")
+		// No else needed: early return pattern (guard clause)
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+
+		claims, ok := claimsInterface.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// Create an admin connection for the takeover.
+		// NOTE: This connection has no writePump consuming its send channel.
+		// It serves as a session marker for admin assistance tracking.
+		// Messages sent to this connection via BroadcastToSession will buffer
+		// (capacity 256) and be silently dropped when full. For full bidirectional
+		// admin messaging, use WebSocket-based admin takeover instead.
+		adminConn := websocket.NewConnection(claims.UserID, claims.Roles)
+		adminConn.Name = claims.Name
+		adminConn.TenantID = claims.TenantID
+		adminConn.ConnectionID = fmt.Sprintf("admin-%s-%d", claims.UserID, time.Now().UnixNano())
+
+		// Handle admin takeover
+		if err := messageRouter.HandleAdminTakeover(adminConn, sessionID); err != nil {
+			// Another admin holds the session: the admin joined its waiting list
+			var denied *router.TakeoverDeniedError
+			// No else needed: early return pattern (guard clause)
+			if errors.As(err, &denied) {
+				c.JSON(constants.StatusConflict, takeoverDeniedResponse{
+					Envelope:      apierror.New(apierror.CodeTakeoverDenied, denied.Error()),
+					SessionID:     sessionID,
+					HolderID:      denied.HolderID,
+					HolderName:    denied.HolderName,
+					Reserved:      denied.Reserved,
+					QueuePosition: denied.Position,
+				})
+				return
+			}
+
+			util.LogError(logger, "http", "initiate admin takeover", err,
+				"session_id", sessionID,
+				"admin_id", claims.UserID)
+
+			// Map error to appropriate HTTP status
+			var chatErr *chaterrors.ChatError
+			if errors.As(err, &chatErr) {
+				switch chatErr.Code {
+				case chaterrors.ErrCodeNotFound, chaterrors.ErrCodeSessionNotFound:
+					httperrors.RespondSessionNotFound(c)
+				case chaterrors.ErrCodeUnauthorized:
+					httperrors.RespondForbidden(c)
+				case chaterrors.ErrCodeInvalidFormat:
+					httperrors.RespondBadRequest(c, chatErr.Message)
+				default:
+					httperrors.RespondInternalError(c)
+				}
+			} else {
+				httperrors.RespondInternalError(c)
+			}
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"message":    "Takeover initiated successfully",
+			"session_id": sessionID,
+			"admin_id":   claims.UserID,
+		})
+	}
+}
+
+// handleAdminHandback returns a handler that ends an admin takeover and routes
+// the session back to the LLM. The intervention window is recorded in the
+// session document and the user is notified.
+func handleAdminHandback(messageRouter *router.MessageRouter, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionID")
+
+		// No else needed: early return pattern (guard clause)
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgSessionIDRequired)
+			return
+		}
+
+		claimsInterface, exists := c.Get("claims")
+		// No else needed: early return pattern (guard clause)
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+
+		claims, ok := claimsInterface.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		// The connection only identifies the admin; nothing is sent to it
+		adminConn := websocket.NewConnection(claims.UserID, claims.Roles)
+		adminConn.Name = claims.Name
+		adminConn.TenantID = claims.TenantID
+
+		if err := messageRouter.HandleHandback(adminConn, sessionID); err != nil {
+			util.LogError(logger, "http", "hand back session", err,
+				"session_id", sessionID,
+				"admin_id", claims.UserID)
+
+			// Map error to appropriate HTTP status
+			var chatErr *chaterrors.ChatError
+			if errors.As(err, &chatErr) {
+				switch chatErr.Code {
+				case chaterrors.ErrCodeNotFound, chaterrors.ErrCodeSessionNotFound:
+					httperrors.RespondSessionNotFound(c)
+				case chaterrors.ErrCodeUnauthorized:
+					httperrors.RespondForbidden(c)
+				case chaterrors.ErrCodeInvalidFormat:
+					httperrors.RespondBadRequest(c, chatErr.Message)
+				default:
+					httperrors.RespondInternalError(c)
+				}
+			} else {
+				httperrors.RespondInternalError(c)
+			}
+			return
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"message":    "Session handed back to the AI",
+			"session_id": sessionID,
+			"admin_id":   claims.UserID,
+		})
+	}
+}
+
+// promptTemplateRequest is the body of prompt template create and update requests
+type promptTemplateRequest struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// handleListPrompts returns a handler listing the prompt templates available to
+// the admin's tenant: the read-only config templates followed by stored templates
+func handleListPrompts(prompts *prompt.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stored, err := adminStorage(c, storageService).ListPromptTemplates()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list prompt templates", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		templates := append(prompts.ConfigTemplates(), stored...)
+		c.JSON(constants.StatusOK, gin.H{
+			"templates": templates,
+			"count":     len(templates),
+		})
+	}
+}
+
+// handleGetPrompt returns a handler returning one prompt template
+func handleGetPrompt(prompts *prompt.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("templateID")
+		// No else needed: early return pattern (config templates are visible to every tenant)
+		if prompts.IsConfigTemplate(id) {
+			t, _ := prompts.Get("", id)
+			c.JSON(constants.StatusOK, t)
+			return
+		}
+
+		t, err := adminStorage(c, storageService).GetPromptTemplate(id)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondPromptError(c, logger, "get prompt template", id, err)
+			return
+		}
+		c.JSON(constants.StatusOK, t)
+	}
+}
+
+// handleCreatePrompt returns a handler storing a new prompt template for the
+// admin's tenant
+func handleCreatePrompt(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req promptTemplateRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		t := &prompt.Template{Name: strings.TrimSpace(req.Name), Content: req.Content}
+		// No else needed: early return pattern (guard clause)
+		if err := adminStorage(c, storageService).CreatePromptTemplate(t); err != nil {
+			respondPromptError(c, logger, "create prompt template", "", err)
+			return
+		}
+		c.JSON(constants.StatusCreated, t)
+	}
+}
+
+// handleUpdatePrompt returns a handler replacing the name and content of a stored
+// prompt template. Sessions using it pick up the change once the cached copy expires.
+func handleUpdatePrompt(prompts *prompt.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("templateID")
+		// No else needed: early return pattern (guard clause)
+		if prompts.IsConfigTemplate(id) {
+			httperrors.RespondBadRequest(c, "Templates defined in config are read-only")
+			return
+		}
+
+		var req promptTemplateRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		t, err := adminStorage(c, storageService).UpdatePromptTemplate(id, strings.TrimSpace(req.Name), req.Content)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondPromptError(c, logger, "update prompt template", id, err)
+			return
+		}
+		prompts.Invalidate(t.TenantID, id)
+		c.JSON(constants.StatusOK, t)
+	}
+}
+
+// handleDeletePrompt returns a handler deleting a stored prompt template. Sessions
+// using it continue without a system prompt.
+func handleDeletePrompt(prompts *prompt.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("templateID")
+		// No else needed: early return pattern (guard clause)
+		if prompts.IsConfigTemplate(id) {
+			httperrors.RespondBadRequest(c, "Templates defined in config are read-only")
+			return
+		}
+
+		// Look the template up first: super admins may delete templates of any
+		// tenant, and the cached copy is keyed by the owning tenant
+		scoped := adminStorage(c, storageService)
+		t, err := scoped.GetPromptTemplate(id)
+		// No else needed: early return pattern (guard clause)
+		if err == nil {
+			err = scoped.DeletePromptTemplate(id)
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondPromptError(c, logger, "delete prompt template", id, err)
+			return
+		}
+		prompts.Invalidate(t.TenantID, id)
+		c.JSON(constants.StatusOK, gin.H{"id": id, "status": "deleted"})
+	}
+}
+
+// respondPromptError maps prompt template storage errors to HTTP responses
+func respondPromptError(c *gin.Context, logger *golog.Logger, op, id string, err error) {
+	switch {
+	case errors.Is(err, prompt.ErrTemplateNotFound):
+		httperrors.RespondNotFound(c, "Prompt template not found")
+	case errors.Is(err, prompt.ErrInvalidTemplate):
+		httperrors.RespondBadRequest(c, err.Error())
+	default:
+		util.LogError(logger, "http", op, err, "template_id", id)
+		httperrors.RespondInternalError(c)
+	}
+}
+
+// experimentRequest is the body of experiment create requests
+type experimentRequest struct {
+	Name     string               `json:"name"`
+	Kind     string               `json:"kind"`
+	Variants []experiment.Variant `json:"variants"`
+	Active   *bool                `json:"active"` // default true
+}
+
+// handleListExperiments returns a handler listing the A/B experiments running
+// for the admin's tenant: the read-only config experiments followed by stored ones
+func handleListExperiments(experiments *experiment.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stored, err := adminStorage(c, storageService).ListExperiments()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list experiments", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		list := append(experiments.ConfigExperiments(), stored...)
+		c.JSON(constants.StatusOK, gin.H{
+			"experiments": list,
+			"count":       len(list),
+		})
+	}
+}
+
+// handleCreateExperiment returns a handler storing a new experiment for the
+// admin's tenant. Variant values must be models of the catalog or prompt
+// templates visible to the tenant; new sessions are assigned once the
+// tenant's cached experiments expire on other instances.
+func handleCreateExperiment(experiments *experiment.Service, storageService *storage.StorageService, llmService router.LLMService, prompts *prompt.Service, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req experimentRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		e := &experiment.Experiment{Name: strings.TrimSpace(req.Name), Kind: req.Kind, Variants: req.Variants, Active: true}
+		// No else needed: optional operation (experiments start active unless asked otherwise)
+		if req.Active != nil {
+			e.Active = *req.Active
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := e.Validate(); err != nil {
+			httperrors.RespondBadRequest(c, err.Error())
+			return
+		}
+
+		// The experiment belongs to the tenant of the storage view it is created on
+		tenantID, allTenants := adminTenantScope(c)
+		// No else needed: conditional assignment (super admins create for the tenant they chose)
+		if allTenants {
+			tenantID = c.Query("tenant_id")
+		}
+		for _, v := range e.Variants {
+			// No else needed: early return pattern (guard clause)
+			if err := validateVariantValue(e.Kind, v.Value, tenantID, llmService, prompts); err != nil {
+				httperrors.RespondBadRequest(c, fmt.Sprintf("variant %s: %v", v.Name, err))
+				return
+			}
+		}
+
+		// No else needed: early return pattern (guard clause)
+		if err := adminStorage(c, storageService).CreateExperiment(e); err != nil {
+			respondExperimentError(c, logger, "create experiment", "", err)
+			return
+		}
+		experiments.Invalidate(e.TenantID)
+		c.JSON(constants.StatusCreated, e)
+	}
+}
+
+// validateVariantValue checks that a variant's value names a model of the
+// catalog or a prompt template visible to the tenant
+func validateVariantValue(kind, value, tenantID string, llmService router.LLMService, prompts *prompt.Service) error {
+	// No else needed: early return pattern (prompt template variants)
+	if kind == experiment.KindPrompt {
+		// No else needed: early return pattern (guard clause)
+		if _, err := prompts.Get(tenantID, value); err != nil {
+			return fmt.Errorf("unknown prompt template %q", value)
+		}
+		return nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if llmService == nil {
+		return fmt.Errorf("model experiments require the LLM to be enabled")
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := llmService.ValidateModel(value); err != nil {
+		return fmt.Errorf("unknown model %q", value)
+	}
+	return nil
+}
+
+// handleDeleteExperiment returns a handler deleting a stored experiment. New
+// sessions are no longer assigned to it; the variants recorded on existing
+// sessions are kept, so its metrics remain available.
+func handleDeleteExperiment(experiments *experiment.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("experimentID")
+		// No else needed: early return pattern (guard clause)
+		if experiments.ConfigExperiment(id) != nil {
+			httperrors.RespondBadRequest(c, "Experiments defined in config are read-only")
+			return
+		}
+
+		// Look the experiment up first: super admins may delete experiments of any
+		// tenant, and the cached list is keyed by the owning tenant
+		scoped := adminStorage(c, storageService)
+		e, err := scoped.GetExperiment(id)
+		// No else needed: early return pattern (guard clause)
+		if err == nil {
+			err = scoped.DeleteExperiment(id)
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondExperimentError(c, logger, "delete experiment", id, err)
+			return
+		}
+		experiments.Invalidate(e.TenantID)
+		c.JSON(constants.StatusOK, gin.H{"id": id, "status": "deleted"})
+	}
+}
+
+// handleGetExperimentMetrics returns a handler reporting the session metrics of
+// each variant of an experiment, for sessions started from start_time to
+// end_time (default: the last 30 days)
+func handleGetExperimentMetrics(experiments *experiment.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("experimentID")
+		scoped := adminStorage(c, storageService)
+		e := experiments.ConfigExperiment(id)
+		// No else needed: optional operation (stored experiments must be visible to the admin)
+		if e == nil {
+			var err error
+			e, err = scoped.GetExperiment(id)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				respondExperimentError(c, logger, "get experiment", id, err)
+				return
+			}
+		}
+
+		endTime := time.Now()
+		startTime := endTime.Add(-constants.DefaultExperimentWindow)
+		// No else needed: optional operation (time range parsing with default)
+		if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+			t, err := time.Parse(time.RFC3339, startTimeStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			startTime = t
+		}
+		// No else needed: optional operation (time range parsing with default)
+		if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+			t, err := time.Parse(time.RFC3339, endTimeStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
+				return
+			}
+			endTime = t
+		}
+
+		variants, err := scoped.GetExperimentMetrics(id, startTime, endTime)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "get experiment metrics", err, "experiment_id", id)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{
+			"experiment": e,
+			"variants":   variants,
+			"time_range": gin.H{
+				"start": startTime.Format(time.RFC3339),
+				"end":   endTime.Format(time.RFC3339),
+			},
+		})
+	}
+}
+
+// respondExperimentError maps experiment storage errors to HTTP responses
+func respondExperimentError(c *gin.Context, logger *golog.Logger, op, id string, err error) {
+	switch {
+	case errors.Is(err, experiment.ErrExperimentNotFound):
+		httperrors.RespondNotFound(c, "Experiment not found")
+	case errors.Is(err, experiment.ErrInvalidExperiment):
+		httperrors.RespondBadRequest(c, err.Error())
+	default:
+		util.LogError(logger, "http", op, err, "experiment_id", id)
+		httperrors.RespondInternalError(c)
+	}
+}
+
+// cannedRequest is the body of canned response create and update requests
+type cannedRequest struct {
+	Title string   `json:"title"`
+	Steps []string `json:"steps"`
+}
+
+// handleListCanned returns a handler listing the canned responses and flows of
+// the admin's tenant, most used first
+func handleListCanned(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := adminStorage(c, storageService).ListCanned()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list canned responses", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{
+			"canned": list,
+			"count":  len(list),
+		})
+	}
+}
+
+// handleGetCanned returns a handler returning one canned response
+func handleGetCanned(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("cannedID")
+		canned, err := adminStorage(c, storageService).GetCanned(id)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondCannedError(c, logger, "get canned response", id, err)
+			return
+		}
+		c.JSON(constants.StatusOK, canned)
+	}
+}
+
+// handleCreateCanned returns a handler storing a new canned response for the
+// admin's tenant. A response with several steps is a flow.
+func handleCreateCanned(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req cannedRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		canned := &storage.CannedResponse{Title: strings.TrimSpace(req.Title), Steps: req.Steps}
+		claims, _ := c.Get("claims")
+		// No else needed: optional operation (record the author when known)
+		if adminClaims, ok := claims.(*auth.Claims); ok {
+			canned.CreatedBy = adminClaims.UserID
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := adminStorage(c, storageService).CreateCanned(canned); err != nil {
+			respondCannedError(c, logger, "create canned response", "", err)
+			return
+		}
+		c.JSON(constants.StatusCreated, canned)
+	}
+}
+
+// handleUpdateCanned returns a handler replacing the title and steps of a
+// canned response. Its usage counter is kept.
+func handleUpdateCanned(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("cannedID")
+		var req cannedRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		canned, err := adminStorage(c, storageService).UpdateCanned(id, strings.TrimSpace(req.Title), req.Steps)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondCannedError(c, logger, "update canned response", id, err)
+			return
+		}
+		c.JSON(constants.StatusOK, canned)
+	}
+}
+
+// handleDeleteCanned returns a handler deleting a canned response
+func handleDeleteCanned(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("cannedID")
+		// No else needed: early return pattern (guard clause)
+		if err := adminStorage(c, storageService).DeleteCanned(id); err != nil {
+			respondCannedError(c, logger, "delete canned response", id, err)
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{"id": id, "status": "deleted"})
+	}
+}
+
+// sendCannedRequest is the optional body of handleSendCanned. Step selects one
+// step (0-based) of a flow; without it every step is sent in order.
+type sendCannedRequest struct {
+	Step *int `json:"step"`
+}
+
+// handleSendCanned returns a handler inserting a canned response into a session
+// the admin has taken over. Each step is sent as an admin message, and the
+// canned response's usage counter is incremented once per insertion.
+func handleSendCanned(storageService *storage.StorageService, sessionManager *session.SessionManager, messageRouter *router.MessageRouter, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionID")
+		cannedID := c.Param("cannedID")
+
+		// No else needed: early return pattern (guard clause)
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgSessionIDRequired)
+			return
+		}
+
+		claimsInterface, exists := c.Get("claims")
+		// No else needed: early return pattern (guard clause)
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+
+		claims, ok := claimsInterface.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		var req sendCannedRequest
+		// No else needed: optional operation (the body is optional)
+		if c.Request.ContentLength != 0 {
+			// No else needed: early return pattern (guard clause)
+			if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+				httperrors.RespondBadRequest(c, "Invalid request body")
+				return
+			}
+		}
+
+		// Admins may only message sessions of their own tenant
+		// No else needed: early return pattern (guard clause)
+		if err := messageRouter.CheckTenantAccess(sessionID, claims.TenantID, claims.Roles); err != nil {
+			var chatErr *chaterrors.ChatError
+			// No else needed: early return pattern (cross-tenant access)
+			if errors.As(err, &chatErr) && chatErr.Code == chaterrors.ErrCodeUnauthorized {
+				httperrors.RespondForbidden(c)
+				return
+			}
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+
+		sess, err := sessionManager.GetSession(sessionID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+		// No else needed: early return pattern (canned responses are for taken-over sessions)
+		if sess.GetAssistingAdminID() != claims.UserID {
+			httperrors.Respond(c, apierror.CodeTakeoverDenied, "Take over the session before sending canned responses")
+			return
+		}
+
+		scoped := adminStorage(c, storageService)
+		canned, err := scoped.GetCanned(cannedID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondCannedError(c, logger, "get canned response", cannedID, err)
+			return
+		}
+
+		steps := canned.Steps
+		// No else needed: optional operation (send a single step of a flow)
+		if req.Step != nil {
+			// No else needed: early return pattern (guard clause)
+			if *req.Step < 0 || *req.Step >= len(canned.Steps) {
+				httperrors.RespondBadRequest(c, fmt.Sprintf("step must be between 0 and %d", len(canned.Steps)-1))
+				return
+			}
+			steps = canned.Steps[*req.Step : *req.Step+1]
+		}
+
+		seqs := make([]uint64, 0, len(steps))
+		for _, step := range steps {
+			msg, err := messageRouter.SendAdminMessage(claims.UserID, claims.Name, sessionID, step)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				util.LogError(logger, "http", "send canned response", err,
+					"session_id", sessionID,
+					"canned_id", cannedID,
+					"admin_id", claims.UserID,
+					"sent_steps", len(seqs))
+				var chatErr *chaterrors.ChatError
+				// No else needed: early return pattern (refused while read-only)
+				if errors.As(err, &chatErr) && chatErr.Code == chaterrors.ErrCodeReadOnly {
+					httperrors.Respond(c, apierror.CodeReadOnly, chatErr.Message)
+					return
+				}
+				httperrors.RespondInternalError(c)
+				return
+			}
+			seqs = append(seqs, msg.Seq)
+		}
+
+		// No else needed: optional operation (a lost count does not undo the messages)
+		if err := scoped.RecordCannedUse(cannedID, time.Now()); err != nil {
+			util.LogError(logger, "http", "record canned response use", err, "canned_id", cannedID)
+		}
+
+		c.JSON(constants.StatusCreated, gin.H{
+			"session_id": sessionID,
+			"canned_id":  cannedID,
+			"admin_id":   claims.UserID,
+			"sent":       len(seqs),
+			"seqs":       seqs,
+		})
+	}
+}
+
+// respondCannedError maps canned response storage errors to HTTP responses
+func respondCannedError(c *gin.Context, logger *golog.Logger, op, id string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrCannedNotFound):
+		httperrors.RespondNotFound(c, "Canned response not found")
+	case errors.Is(err, storage.ErrInvalidCanned):
+		httperrors.RespondBadRequest(c, err.Error())
+	default:
+		util.LogError(logger, "http", op, err, "canned_id", id)
+		httperrors.RespondInternalError(c)
+	}
+}
+
+// keywordRequest is the body of watchlist keyword create and update requests
+type keywordRequest struct {
+	Term  string `json:"term"`
+	Regex bool   `json:"regex"`
+	Tag   string `json:"tag"`
+}
+
+// handleListKeywords returns a handler listing the keyword watchlist of the
+// admin's tenant, oldest first
+func handleListKeywords(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keywords, err := adminStorage(c, storageService).ListKeywords()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "list keywords", err)
+			httperrors.RespondInternalError(c)
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{
+			"keywords": keywords,
+			"count":    len(keywords),
+		})
+	}
+}
+
+// handleGetKeyword returns a handler returning one watchlist keyword
+func handleGetKeyword(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("keywordID")
+		k, err := adminStorage(c, storageService).GetKeyword(id)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondKeywordError(c, logger, "get keyword", id, err)
+			return
+		}
+		c.JSON(constants.StatusOK, k)
+	}
+}
+
+// handleCreateKeyword returns a handler adding a keyword to the watchlist of
+// the admin's tenant. Matching starts on this instance right away and on
+// others once their cached watchlist expires.
+func handleCreateKeyword(keywords *watchlist.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req keywordRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		k := &watchlist.Keyword{Term: strings.TrimSpace(req.Term), Regex: req.Regex, Tag: req.Tag}
+		claims, _ := c.Get("claims")
+		// No else needed: optional operation (record the author when known)
+		if adminClaims, ok := claims.(*auth.Claims); ok {
+			k.CreatedBy = adminClaims.UserID
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := adminStorage(c, storageService).CreateKeyword(k); err != nil {
+			respondKeywordError(c, logger, "create keyword", "", err)
+			return
+		}
+		keywords.Invalidate(k.TenantID)
+		c.JSON(constants.StatusCreated, k)
+	}
+}
+
+// handleUpdateKeyword returns a handler replacing the term, kind and tag of a
+// watchlist keyword
+func handleUpdateKeyword(keywords *watchlist.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("keywordID")
+		var req keywordRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		k, err := adminStorage(c, storageService).UpdateKeyword(id, strings.TrimSpace(req.Term), req.Regex, req.Tag)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondKeywordError(c, logger, "update keyword", id, err)
+			return
+		}
+		keywords.Invalidate(k.TenantID)
+		c.JSON(constants.StatusOK, k)
+	}
+}
+
+// handleDeleteKeyword returns a handler removing a keyword from its watchlist
+func handleDeleteKeyword(keywords *watchlist.Service, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("keywordID")
+		// Look the keyword up first: super admins may delete keywords of any
+		// tenant, and the cached watchlist is keyed by the owning tenant
+		scoped := adminStorage(c, storageService)
+		k, err := scoped.GetKeyword(id)
+		// No else needed: early return pattern (guard clause)
+		if err == nil {
+			err = scoped.DeleteKeyword(id)
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			respondKeywordError(c, logger, "delete keyword", id, err)
+			return
+		}
+		keywords.Invalidate(k.TenantID)
+		c.JSON(constants.StatusOK, gin.H{"id": id, "status": "deleted"})
+	}
+}
+
+// respondKeywordError maps watchlist storage errors to HTTP responses
+func respondKeywordError(c *gin.Context, logger *golog.Logger, op, id string, err error) {
+	switch {
+	case errors.Is(err, watchlist.ErrKeywordNotFound):
+		httperrors.RespondNotFound(c, "Keyword not found")
+	case errors.Is(err, watchlist.ErrInvalidKeyword):
+		httperrors.RespondBadRequest(c, err.Error())
+	default:
+		util.LogError(logger, "http", op, err, "keyword_id", id)
+		httperrors.RespondInternalError(c)
+	}
+}
+
+// adminMessageRequest is the request body for handleAdminSendMessage
+type adminMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// handleAdminSendMessage returns a handler that injects an admin message into a
+// session over HTTP, for admins who are not holding a WebSocket connection.
+// The message is delivered to the user's connection and persisted to the transcript.
+func handleAdminSendMessage(messageRouter *router.MessageRouter, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionID")
+
+		// No else needed: early return pattern (guard clause)
+		if sessionID == "" {
+			httperrors.RespondBadRequest(c, constants.ErrMsgSessionIDRequired)
+			return
+		}
+
+		// Get admin claims from context (set by authMiddleware)
+		claimsInterface, exists := c.Get("claims")
+		// No else needed: early return pattern (guard clause)
+		if !exists {
+			httperrors.RespondUnauthorized(c, "")
+			return
+		}
+
+		claims, ok := claimsInterface.(*auth.Claims)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
+			httperrors.RespondInternalError(c)
+			return
+		}
+
+		var req adminMessageRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+
+		// Admins may only message sessions of their own tenant
+		// No else needed: early return pattern (guard clause)
+		if err := messageRouter.CheckTenantAccess(sessionID, claims.TenantID, claims.Roles); err != nil {
+			var chatErr *chaterrors.ChatError
+			// No else needed: early return pattern (cross-tenant access)
+			if errors.As(err, &chatErr) && chatErr.Code == chaterrors.ErrCodeUnauthorized {
+				httperrors.RespondForbidden(c)
+				return
+			}
+			httperrors.RespondSessionNotFound(c)
+			return
+		}
+
+		msg, err := messageRouter.SendAdminMessage(claims.UserID, claims.Name, sessionID, req.Content)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(logger, "http", "send admin message", err,
+				"session_id", sessionID,
+				"admin_id", claims.UserID)
+
+			// Map error to appropriate HTTP status
+			var chatErr *chaterrors.ChatError
+			if errors.As(err, &chatErr) {
+				switch chatErr.Code {
+				case chaterrors.ErrCodeNotFound, chaterrors.ErrCodeSessionNotFound:
+					httperrors.RespondSessionNotFound(c)
+				case chaterrors.ErrCodeInvalidFormat, chaterrors.ErrCodeMissingField:
+					httperrors.RespondBadRequest(c, chatErr.Message)
+				case chaterrors.ErrCodeReadOnly:
+					httperrors.Respond(c, apierror.CodeReadOnly, chatErr.Message)
+				default:
+					httperrors.RespondInternalError(c)
+				}
+			} else {
+				httperrors.RespondInternalError(c)
+			}
+			return
+		}
+
+		c.JSON(constants.StatusCreated, gin.H{
+			"session_id": sessionID,
+			"admin_id":   claims.UserID,
+			"content":    msg.Content,
+			"seq":        msg.Seq,
+			"timestamp":  msg.Timestamp.Format(time.RFC3339),
+		})
+	}
+}
+
+// handleHealthCheck returns a handler for liveness probe endpoint.
+// This endpoint checks if the application is alive and should be restarted if it fails.
+// It performs minimal checks to determine if the process is running correctly.
+func handleHealthCheck(c *gin.Context) {
+	// Basic liveness check - if we can respond, we're alive
+	c.JSON(constants.StatusOK, gin.H{
+		"status":    "healthy",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// handleReadyCheck returns a handler for readiness probe endpoint.
+// This endpoint checks if the application is ready to serve traffic.
+// It runs every registered dependency check and reports each one with its
+// latency. The status is "ready", "degraded" (an optional dependency failed,
+// still 200 so the pod keeps receiving traffic), "maintenance" (a scheduled
+// window is in progress, still 200 so existing sessions keep working), or
+// "not ready" with 503.
+func handleReadyCheck(checks *health.Registry, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checks.Run(c.Request.Context())
+
+		for name, result := range report.Checks {
+			// No else needed: optional operation (log failing checks server-side)
+			if result.Status != health.StatusReady {
+				logger.Warn("Readiness check failed",
+					"check", name,
+					"status", result.Status,
+					"reason", result.Reason,
+					"error", result.Err,
+					"component", "health")
+			}
+		}
+
+		statusCode := constants.StatusOK
+		// No else needed: optional operation (status code adjustment based on health)
+		if report.Status == health.StatusNotReady {
+			statusCode = constants.StatusServiceUnavailable
+		}
+
+		c.JSON(statusCode, gin.H{
+			"status":    report.Status,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"checks":    report.Checks,
+		})
+	}
+}
+
+// readinessChecks builds the readiness checks. MongoDB and the LLM provider
+// catalog are critical; LLM provider reachability, Redis and the upload
+// directory are optional and only degrade the service. llmService and
+// redisClient may be nil and uploadDir empty when not in use.
+func readinessChecks(mongo *gomongo.Mongo, llmService *llm.LLMService, redisClient *redis.Client, uploadDir string) *health.Registry {
+	checks := health.NewRegistry(constants.HealthCheckTimeout)
+
+	checks.Register("mongodb", true, func(ctx context.Context) health.Result {
+		// No else needed: early return pattern (guard clause)
+		if mongo == nil {
+			return health.Fail("MongoDB not initialized", nil)
+		}
+		// Use Ping() to check MongoDB connectivity
+		// No else needed: early return pattern (guard clause)
+		if err := mongo.Coll("chat", "sessions").Ping(ctx); err != nil {
+			return health.Fail("Database connectivity check failed", err)
+		}
+		return health.OK(nil)
+	})
+
+	// No else needed: optional operation (nil means LLM not configured)
+	if llmService != nil {
+		checks.Register("llm", true, llmReadinessCheck(llmService))
+	}
+
+	// No else needed: optional operation (Redis only backs distributed rate limits)
+	if redisClient != nil {
+		checks.Register("redis", false, func(ctx context.Context) health.Result {
+			// No else needed: early return pattern (guard clause)
+			if err := redisClient.Ping(ctx).Err(); err != nil {
+				return health.Fail("Redis connectivity check failed", err)
+			}
+			return health.OK(nil)
+		})
+	}
+
+	// No else needed: optional operation (only when uploads use a local directory)
+	if uploadDir != "" {
+		checks.Register("disk", false, func(ctx context.Context) health.Result {
+			// No else needed: early return pattern (guard clause)
+			if err := health.WritableDir(uploadDir); err != nil {
+				return health.Fail("Upload directory is not writable", err)
+			}
+			return health.OK(nil)
+		})
+	}
+
+	return checks
+}
+
+// llmReadinessCheck checks that LLM providers are configured and probes each
+// distinct provider endpoint. Unreachable providers degrade the service rather
+// than failing readiness: restarting the pod would not bring them back.
+func llmReadinessCheck(llmService *llm.LLMService) health.CheckFunc {
+	client := &http.Client{Timeout: constants.HealthCheckTimeout}
+	return func(ctx context.Context) health.Result {
+		models := llmService.GetAvailableModels()
+		// No else needed: early return pattern (guard clause)
+		if len(models) == 0 {
+			return health.Fail("No LLM providers configured", nil)
+		}
+
+		endpoints := make(map[string]bool)
+		for _, model := range models {
+			// No else needed: optional operation (providers without an endpoint use their SDK default)
+			if model.Endpoint != "" {
+				endpoints[model.Endpoint] = true
+			}
+		}
+
+		var (
+			mu          sync.Mutex
+			wg          sync.WaitGroup
+			unreachable []string
+			lastErr     error
+		)
+		for endpoint := range endpoints {
+			wg.Add(1)
+			go func(endpoint string) {
+				defer wg.Done()
+				// No else needed: optional operation (record unreachable endpoints)
+				if err := health.ProbeURL(ctx, client, endpoint); err != nil {
+					mu.Lock()
+					unreachable = append(unreachable, endpoint)
+					lastErr = err
+					mu.Unlock()
+				}
+			}(endpoint)
+		}
+		wg.Wait()
+
+		details := map[string]interface{}{
+			"providers_count":       len(models),
+			"unreachable_endpoints": len(unreachable),
+		}
+		// No else needed: early return pattern (some providers unreachable)
+		if len(unreachable) > 0 {
+			result := health.Degraded(fmt.Sprintf("%d of %d LLM endpoints unreachable", len(unreachable), len(endpoints)), lastErr)
+			result.Details = details
+			return result
+		}
+		return health.OK(details)
+	}
+}
+
+// uploadTempDir returns the local temporary directory of the chat upload entry
+// ([[userupload.types]] with entryName "uploads"), or "" when none is configured
+func uploadTempDir(config *goconfig.ConfigAccessor) string {
+	raw, err := config.Config("userupload.types")
+	// No else needed: early return pattern (uploads not configured)
+	if err != nil || raw == nil {
+		return ""
+	}
+	types, _ := raw.([]interface{})
+	for _, item := range types {
+		entry, _ := item.(map[string]interface{})
+		// No else needed: optional operation (only the chat upload entry)
+		if entryName, _ := entry["entryName"].(string); entryName == "uploads" {
+			tmpPath, _ := entry["tmpPath"].(string)
+			return tmpPath
+		}
+	}
+	return ""
+}
+
+// Shutdown gracefully shuts down the chatbox service.
+// It closes all active WebSocket connections and flushes logs.
+// This function should be called when the application receives a SIGTERM or SIGINT signal.
+// It respects the context deadline and will force shutdown if the deadline is exceeded.
+func Shutdown(ctx context.Context) error {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+
+	// No else needed: optional operation (logging during shutdown)
+	if globalLogger != nil {
+		globalLogger.Info("Starting graceful shutdown of chatbox service")
+	}
+
+	// In drain mode, let active LLM streams finish before the router cancels them
+	// No else needed: optional operation (only after POST /admin/drain)
+	if globalWSHandler != nil && globalWSHandler.IsDraining() {
+		// No else needed: optional operation (error logging)
+		if err := globalWSHandler.WaitForInflight(ctx); err != nil && globalLogger != nil {
+			globalLogger.Warn("In-flight messages did not finish before shutdown deadline", "error", err)
+		}
+	}
+
+	// Stop session cleanup goroutine
+	// No else needed: optional operation (cleanup stop)
+	if globalSessionMgr != nil {
+		globalSessionMgr.StopCleanup()
+	}
+
+	// Stop message router cleanup goroutines
+	// No else needed: optional operation (cleanup stop)
+	if globalMessageRouter != nil {
+		globalMessageRouter.Shutdown()
+	}
+
+	// Stop the background jobs (retention purge, metrics rollups), waiting
+	// for runs in progress
+	// No else needed: optional operation (cleanup stop)
+	if globalScheduler != nil {
+		globalScheduler.Stop()
+	}
+
+	// Stop the session change stream and write the messages still buffered
+	// for batching
+	// No else needed: optional operation (cleanup stop)
+	if globalStorage != nil {
+		globalStorage.StopMessageBatching()
+		globalStorage.StopSessionWatch()
+	}
+
+	// Stop the unreferenced upload garbage collector
+	// No else needed: optional operation (cleanup stop)
+	if globalUploads != nil {
+		globalUploads.StopGarbageCollector()
+	}
+
+	// Stop the metrics remote write pusher
+	// No else needed: optional operation (remote write only when configured)
+	if globalMetricsPusher != nil {
+		globalMetricsPusher.Stop()
+	}
+
+	// Stop the usage alert monitor
+	// No else needed: optional operation (alerts only when rules are configured)
+	if globalAlerts != nil {
+		globalAlerts.Stop()
+	}
+
+	// Stop admin rate limiter cleanup
+	// No else needed: optional operation (cleanup stop)
+	if globalAdminLimiter != nil {
+		globalAdminLimiter.StopCleanup()
+	}
+
+	// Stop public rate limiter cleanup
+	if globalPublicLimiter != nil {
+		globalPublicLimiter.StopCleanup()
+	}
+
+	// Stop connection throttle cleanup
+	// No else needed: optional operation (cleanup stop)
+	if globalIPLimiter != nil {
+		globalIPLimiter.StopCleanup()
+	}
+
+	// Stop per-endpoint rate limit policy cleanup (nil-safe)
+	globalRatePolicies.StopCleanup()
+
+	// Release this replica's connections to other replicas and stop cluster routing
+	// No else needed: optional operation (cluster routing only when enabled)
+	if globalCluster != nil {
+		globalCluster.Close()
+	}
+	// No else needed: optional operation (cluster routing only when enabled)
+	if globalClusterRedis != nil {
+		// No else needed: optional operation (error logging)
+		if err := globalClusterRedis.Close(); err != nil && globalLogger != nil {
+			globalLogger.Warn("Cluster Redis client close error", "error", err)
+		}
+	}
+
+	// Close the Redis rate limit client; later checks fail open until the server stops
+	// No else needed: optional operation (Redis only when configured)
+	if globalRedis != nil {
+		// No else needed: optional operation (error logging)
+		if err := globalRedis.Close(); err != nil && globalLogger != nil {
+			globalLogger.Warn("Redis client close error", "error", err)
+		}
+	}
+
+	// Deliver queued webhook events; on timeout pending events are dropped
+	// No else needed: optional operation (webhooks only when configured)
+	if globalWebhooks != nil {
+		// No else needed: optional operation (error logging)
+		if err := globalWebhooks.Shutdown(ctx); err != nil && globalLogger != nil {
+			globalLogger.Warn("Webhook dispatcher shutdown error", "error", err)
+		}
+	}
+
+	// Send queued transcript emails; on timeout pending emails are dropped
+	// No else needed: optional operation (transcript emails only when enabled)
+	if globalMailer != nil {
+		// No else needed: optional operation (error logging)
+		if err := globalMailer.Shutdown(ctx); err != nil && globalLogger != nil {
+			globalLogger.Warn("Transcript mailer shutdown error", "error", err)
+		}
+	}
+
+	// Send queued lifecycle events; on timeout pending events are dropped
+	// No else needed: optional operation (event bus only when configured)
+	if globalEvents != nil {
+		// No else needed: optional operation (error logging)
+		if err := globalEvents.Shutdown(ctx); err != nil && globalLogger != nil {
+			globalLogger.Warn("Event bus shutdown error", "error", err)
+		}
+	}
+
+	// Score queued messages; on timeout the remaining ones are not scored
+	// No else needed: optional operation (sentiment analysis only when enabled)
+	if globalSentiment != nil {
+		// No else needed: optional operation (error logging)
+		if err := globalSentiment.Shutdown(ctx); err != nil && globalLogger != nil {
+			globalLogger.Warn("Sentiment pipeline shutdown error", "error", err)
+		}
+	}
+
+	// Cancel a running backup; its partial archive is discarded
+	// No else needed: optional operation (backups only when enabled)
+	if globalBackups != nil {
+		// No else needed: optional operation (error logging)
+		if err := globalBackups.Shutdown(ctx); err != nil && globalLogger != nil {
+			globalLogger.Warn("Backup runner shutdown error", "error", err)
+		}
+	}
+
+	// Flush buffered spans
+	// No else needed: optional operation (tracing only when enabled)
+	if globalTracer != nil {
+		tracerCtx, tracerCancel := context.WithTimeout(ctx, constants.TracingShutdownTimeout)
+		// No else needed: optional operation (error logging)
+		if err := globalTracer.Shutdown(tracerCtx); err != nil && globalLogger != nil {
+			globalLogger.Warn("Tracer provider shutdown error", "error", err)
+		}
+		tracerCancel()
+	}
+
+	// Close all WebSocket connections with context deadline
+	// No else needed: optional operation (WebSocket shutdown with error handling)
+	if globalWSHandler != nil {
+		// No else needed: early return pattern (guard clause)
+		if err := globalWSHandler.ShutdownWithContext(ctx); err != nil {
+			// No else needed: optional operation (error logging)
+			if globalLogger != nil {
+				globalLogger.Warn("WebSocket handler shutdown error", "error", err)
+			}
+			return err
+		}
+	}
+
+	// Flush logs
+	// No else needed: optional operation (final logging)
+	if globalLogger != nil {
+		globalLogger.Info("Chatbox service shutdown complete")
+		// Note: Logger.Close() should be called by gomain, not here
+	}
+
+	return nil
+}
+
+// newWebhookDispatcher creates the webhook dispatcher from configuration.
+// Returns nil when no webhook URLs are configured.
+// Priority: Environment variables > Config file
+func newWebhookDispatcher(config *goconfig.ConfigAccessor, logger *golog.Logger) (*webhook.Dispatcher, error) {
+	urlsStr, err := config.ConfigStringWithDefault("chatbox.webhooks.urls", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook URLs: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envURLs := os.Getenv("CHATBOX_WEBHOOK_URLS"); envURLs != "" {
+		urlsStr = envURLs
+	}
+
+	var urls []string
+	for _, u := range strings.Split(urlsStr, ",") {
+		// No else needed: optional operation (skip empty entries)
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	// No else needed: early return pattern (webhooks disabled)
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	secret := os.Getenv("CHATBOX_WEBHOOK_SECRET")
+	// No else needed: optional operation (config fallback)
+	if secret == "" {
+		secret, err = config.ConfigStringWithDefault("chatbox.webhooks.secret", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get webhook secret: %w", err)
+		}
+	}
+	// No else needed: optional operation (warn about unsigned deliveries)
+	if secret == "" {
+		logger.Warn("Webhook secret not configured - deliveries will not be signed")
+	}
+
+	maxRetries, err := config.ConfigIntWithDefault("chatbox.webhooks.max_retries", constants.DefaultWebhookMaxRetries)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook max retries: %w", err)
+	}
+	timeoutStr, err := config.ConfigStringWithDefault("chatbox.webhooks.timeout", constants.DefaultWebhookTimeout.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook timeout: %w", err)
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook timeout format: %w", err)
+	}
+
+	dispatcher, err := webhook.NewDispatcher(webhook.Config{
+		URLs:       urls,
+		Secret:     secret,
+		MaxRetries: maxRetries,
+		Timeout:    timeout,
+	}, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook dispatcher: %w", err)
+	}
+	logger.Info("Webhooks enabled", "endpoints", len(urls))
+	return dispatcher, nil
+}
+
+// migrateSessionDocuments upgrades stored session documents to the current
+// schema version at startup. Without apply, it only reports pending documents.
+// Failures are logged: the remaining documents are migrated on the next start
+// or with POST /admin/migrations.
+func migrateSessionDocuments(storageService *storage.StorageService, apply bool, logger *golog.Logger) {
+	ctx, cancel := util.NewTimeoutContext(constants.SchemaMigrationTimeout)
+	defer cancel()
+
+	report, err := storageService.MigrateSessions(ctx, !apply)
+	// No else needed: early return pattern (non-critical, retried on next start)
+	if err != nil {
+		logger.Warn("Failed to migrate session documents", "error", err)
+		return
+	}
+	// No else needed: optional operation (warn only about documents left behind)
+	if !apply && report.PendingDocuments() > 0 {
+		logger.Warn("Session documents need schema migration; run POST /admin/migrations or enable chatbox.migrate_on_startup",
+			"pending", report.PendingDocuments(),
+			"schema_version", report.SchemaVersion)
+	}
+}
+
+// newEventBus creates the session lifecycle event bus from the [chatbox.events]
+// configuration. Returns nil when no sinks are configured.
+// Priority: Environment variables > Config file
+func newEventBus(config *goconfig.ConfigAccessor, logger *golog.Logger) (*events.Bus, error) {
+	sinksStr, err := config.ConfigStringWithDefault("chatbox.events.sinks", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event sinks: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envSinks := os.Getenv("CHATBOX_EVENT_SINKS"); envSinks != "" {
+		sinksStr = envSinks
+	}
+
+	var sinks []events.Sink
+	for _, name := range strings.Split(sinksStr, ",") {
+		var sink events.Sink
+		switch name = strings.TrimSpace(name); name {
+		case "":
+			continue
+		case constants.EventSinkLog:
+			sink = events.NewLogSink(logger)
+		case constants.EventSinkWebhook:
+			sink, err = newEventWebhookSink(config, logger)
+		case constants.EventSinkNATS:
+			sink, err = newEventNATSSink(config, logger)
+		case constants.EventSinkKafka:
+			sink, err = newEventKafkaSink(config)
+		default:
+			err = fmt.Errorf("unknown event sink %q", name)
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	// No else needed: early return pattern (event bus disabled)
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+
+	bus, err := events.NewBus(sinks, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event bus: %w", err)
+	}
+	logger.Info("Session event bus enabled", "sinks", sinksStr)
+	return bus, nil
+}
+
+// newEventWebhookSink creates the event bus webhook sink, which posts every
+// lifecycle event to chatbox.events.webhook_urls
+func newEventWebhookSink(config *goconfig.ConfigAccessor, logger *golog.Logger) (events.Sink, error) {
+	urlsStr, err := config.ConfigStringWithDefault("chatbox.events.webhook_urls", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event webhook URLs: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envURLs := os.Getenv("CHATBOX_EVENT_WEBHOOK_URLS"); envURLs != "" {
+		urlsStr = envURLs
+	}
+	var urls []string
+	for _, u := range strings.Split(urlsStr, ",") {
+		// No else needed: optional operation (skip empty entries)
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+
+	secret := os.Getenv("CHATBOX_EVENT_WEBHOOK_SECRET")
+	// No else needed: optional operation (config fallback)
+	if secret == "" {
+		secret, err = config.ConfigStringWithDefault("chatbox.events.webhook_secret", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get event webhook secret: %w", err)
+		}
+	}
+
+	dispatcher, err := webhook.NewDispatcher(webhook.Config{URLs: urls, Secret: secret}, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event webhook sink: %w", err)
+	}
+	return events.NewWebhookSink(dispatcher), nil
+}
+
+// newEventNATSSink creates the event bus NATS sink
+func newEventNATSSink(config *goconfig.ConfigAccessor, logger *golog.Logger) (events.Sink, error) {
+	natsURL, err := config.ConfigStringWithDefault("chatbox.events.nats_url", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NATS URL: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envURL := os.Getenv("CHATBOX_EVENT_NATS_URL"); envURL != "" {
+		natsURL = envURL
+	}
+	subject, err := config.ConfigStringWithDefault("chatbox.events.nats_subject", constants.DefaultEventSubject)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NATS subject: %w", err)
+	}
+	token := os.Getenv("CHATBOX_EVENT_NATS_TOKEN")
+	// No else needed: optional operation (config fallback)
+	if token == "" {
+		token, err = config.ConfigStringWithDefault("chatbox.events.nats_token", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get NATS token: %w", err)
+		}
+	}
+
+	sink, err := events.NewNATSSink(events.NATSConfig{URL: natsURL, Subject: subject, Token: token}, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event NATS sink: %w", err)
+	}
+	return sink, nil
+}
+
+// newEventKafkaSink creates the event bus Kafka sink
+func newEventKafkaSink(config *goconfig.ConfigAccessor) (events.Sink, error) {
+	restURL, err := config.ConfigStringWithDefault("chatbox.events.kafka_rest_url", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kafka REST URL: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envURL := os.Getenv("CHATBOX_EVENT_KAFKA_REST_URL"); envURL != "" {
+		restURL = envURL
+	}
+	topic, err := config.ConfigStringWithDefault("chatbox.events.kafka_topic", constants.DefaultEventSubject)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kafka topic: %w", err)
+	}
+
+	sink, err := events.NewKafkaSink(events.KafkaConfig{RESTURL: restURL, Topic: topic})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event Kafka sink: %w", err)
+	}
+	return sink, nil
+}
+
+// newPromptService creates the system prompt template service from the optional
+// [chatbox.prompts.<id>] config tables and the templates stored by admins
+func newPromptService(config *goconfig.ConfigAccessor, storageService *storage.StorageService) (*prompt.Service, error) {
+	var configTemplates []*prompt.Template
+	raw, err := config.Config("chatbox.prompts")
+	// No else needed: optional operation (config templates only when configured)
+	if err == nil && raw != nil {
+		configTemplates, err = prompt.ParseConfigTemplates(raw)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt templates: %w", err)
+		}
+	}
+
+	// Admin-managed templates are stored in MongoDB
+	return prompt.NewService(configTemplates, func(tenantID, id string) (*prompt.Template, error) {
+		return storageService.ForTenant(tenantID).GetPromptTemplate(id)
+	}), nil
+}
+
+// newExperimentService creates the A/B experiment service from the optional
+// [chatbox.experiments.<id>] config tables and the experiments stored by admins
+func newExperimentService(config *goconfig.ConfigAccessor, storageService *storage.StorageService) (*experiment.Service, error) {
+	var configExperiments []*experiment.Experiment
+	raw, err := config.Config("chatbox.experiments")
+	// No else needed: optional operation (config experiments only when configured)
+	if err == nil && raw != nil {
+		configExperiments, err = experiment.ParseConfigExperiments(raw)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid experiments: %w", err)
+		}
+	}
+
+	// Admin-managed experiments are stored in MongoDB
+	return experiment.NewService(configExperiments, storageService.TenantExperiments), nil
+}
+
+// newAuthzPolicy builds the role permissions for admin endpoints: the defaults
+// (see authz.DefaultRoles), overridden per role by the optional [chatbox.roles]
+// config table, overridden in turn by the chat_roles MongoDB collection. Stored
+// roles are read once at startup.
+func newAuthzPolicy(config *goconfig.ConfigAccessor, storageService *storage.StorageService) (*authz.Policy, error) {
+	roles := authz.DefaultRoles()
+	raw, err := config.Config("chatbox.roles")
+	// No else needed: optional operation (config roles only when configured)
+	if err == nil && raw != nil {
+		configRoles, err := authz.ParseConfigRoles(raw)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chatbox.roles: %w", err)
+		}
+		roles = authz.Merge(roles, configRoles)
+	}
+
+	// Stored roles live in MongoDB
+	storedRoles, err := storageService.ListRolePermissions()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role permissions: %w", err)
+	}
+	return authz.NewPolicy(authz.Merge(roles, storedRoles))
+}
+
+// loadMaintenanceSchedule reads the optional [chatbox.maintenance] table of
+// scheduled maintenance windows. Returns nil when none is configured.
+func loadMaintenanceSchedule(config *goconfig.ConfigAccessor) (*maintenance.Schedule, error) {
+	raw, err := config.Config("chatbox.maintenance")
+	// No else needed: early return pattern (maintenance windows not configured)
+	if err != nil || raw == nil {
+		return nil, nil
+	}
+	schedule, err := maintenance.ParseConfig(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.maintenance: %w", err)
+	}
+	return schedule, nil
+}
+
+// loadBusinessHours reads the optional [chatbox.business_hours] table of weekly
+// hours in which help requests are answered. Returns nil when none is configured.
+func loadBusinessHours(config *goconfig.ConfigAccessor) (*businesshours.Schedule, error) {
+	raw, err := config.Config("chatbox.business_hours")
+	// No else needed: early return pattern (business hours not configured)
+	if err != nil || raw == nil {
+		return nil, nil
+	}
+	hours, err := businesshours.ParseConfig(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.business_hours: %w", err)
+	}
+	return hours, nil
+}
+
+// maintenanceReadinessCheck reports "maintenance" while a scheduled window is in
+// progress, and the next window otherwise
+func maintenanceReadinessCheck(schedule *maintenance.Schedule) health.CheckFunc {
+	return func(ctx context.Context) health.Result {
+		now := time.Now()
+		// No else needed: early return pattern (window in progress)
+		if window, active := schedule.Active(now); active {
+			return health.Maintenance("Scheduled maintenance in progress", map[string]interface{}{
+				"ends_at": window.End.UTC().Format(time.RFC3339),
+			})
+		}
+		window, ok := schedule.Next(now)
+		// No else needed: early return pattern (no window ahead)
+		if !ok {
+			return health.OK(nil)
+		}
+		return health.OK(map[string]interface{}{
+			"next_starts_at": window.Start.UTC().Format(time.RFC3339),
+			"next_ends_at":   window.End.UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// readOnlyMessage returns the message shown to users while mode is read-only
+func readOnlyMessage(mode *readonly.Mode) (string, bool) {
+	msg, enabled := mode.Enabled()
+	// No else needed: conditional assignment (default message)
+	if msg == "" {
+		msg = constants.DefaultReadOnlyMessage
+	}
+	return msg, enabled
+}
+
+// readOnlyMiddleware refuses a user request that writes session history with a
+// READ_ONLY error while the admin read-only mode is on
+func readOnlyMiddleware(mode *readonly.Mode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// No else needed: early return pattern (refused while read-only)
+		if msg, enabled := readOnlyMessage(mode); enabled {
+			httperrors.Respond(c, apierror.CodeReadOnly, msg)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// readOnlyReadinessCheck reports "maintenance" while the admin read-only mode
+// is on, so dashboards show it without taking pods out of rotation
+func readOnlyReadinessCheck(mode *readonly.Mode) health.CheckFunc {
+	return func(ctx context.Context) health.Result {
+		state := mode.State()
+		// No else needed: early return pattern (not read-only)
+		if !state.Enabled {
+			return health.OK(nil)
+		}
+		msg, _ := readOnlyMessage(mode)
+		return health.Maintenance("Read-only mode", map[string]interface{}{
+			"message":    msg,
+			"updated_by": state.UpdatedBy,
+			"updated_at": state.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// setReadOnlyRequest is the body of POST /admin/readonly
+type setReadOnlyRequest struct {
+	Enabled *bool  `json:"enabled"`
+	Message string `json:"message"`
+}
+
+// handleGetReadOnly returns a handler that reports the admin read-only mode
+func handleGetReadOnly(mode *readonly.Mode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(constants.StatusOK, mode.State())
+	}
+}
+
+// handleSetReadOnly returns a handler that turns the admin read-only mode on or
+// off. The mode applies to this replica at once, and is stored in MongoDB so it
+// survives restarts and reaches the other replicas within
+// constants.ReadOnlySyncInterval. When it cannot be stored, e.g. during the
+// storage incident it is meant for, it still applies here and the response
+// reports persisted=false.
+func handleSetReadOnly(mode *readonly.Mode, storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req setReadOnlyRequest
+		// No else needed: early return pattern (guard clause)
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httperrors.RespondBadRequest(c, "Invalid request body")
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if req.Enabled == nil {
+			httperrors.RespondBadRequest(c, "enabled is required")
+			return
+		}
+		req.Message = strings.TrimSpace(req.Message)
+		// No else needed: early return pattern (guard clause)
+		if len(req.Message) > constants.MaxReadOnlyMessageLength {
+			httperrors.RespondBadRequest(c, fmt.Sprintf("message exceeds maximum length of %d bytes", constants.MaxReadOnlyMessageLength))
+			return
+		}
+
+		state := readonly.State{
+			Enabled:   *req.Enabled,
+			Message:   req.Message,
+			UpdatedAt: time.Now().UTC().Truncate(time.Millisecond), // MongoDB stores milliseconds
+		}
+		// No else needed: optional operation (record the admin)
+		if claims, ok := c.Get("claims"); ok {
+			if adminClaims, ok := claims.(*auth.Claims); ok {
+				state.UpdatedBy = adminClaims.UserID
+			}
+		}
+		mode.Set(state)
+		logger.Warn("Read-only mode set by admin",
+			"enabled", state.Enabled,
+			"admin_id", state.UpdatedBy,
+			"message", state.Message)
+
+		persisted := false
+		// No else needed: optional operation (the mode is only kept in memory without storage)
+		if storageService != nil {
+			err := storageService.SetReadOnlyMode(state)
+			// No else needed: optional operation (log only; the mode applies on this replica)
+			if err != nil {
+				util.LogError(logger, "http", "store read-only mode", err,
+					"enabled", state.Enabled,
+					"admin_id", state.UpdatedBy)
+			}
+			persisted = err == nil
+		}
+
+		c.JSON(constants.StatusOK, gin.H{
+			"enabled":    state.Enabled,
+			"message":    state.Message,
+			"updated_by": state.UpdatedBy,
+			"updated_at": state.UpdatedAt,
+			"persisted":  persisted,
+		})
+	}
+}
+
+// newAPIKeyRegistry creates the registry of API keys for server-to-server admin
+// calls from the optional [chatbox.api_keys.<name>] config tables and the
+// chat_api_keys MongoDB collection, whose keys replace configured keys of the
+// same name. Returns nil when no keys are configured.
+func newAPIKeyRegistry(config *goconfig.ConfigAccessor, storageService *storage.StorageService) (*apikey.Registry, error) {
+	var keys []*apikey.Key
+	raw, err := config.Config("chatbox.api_keys")
+	// No else needed: optional operation (config keys only when configured)
+	if err == nil && raw != nil {
+		keys, err = apikey.ParseConfigKeys(raw)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chatbox.api_keys: %w", err)
+		}
+	}
+
+	// Stored keys live in MongoDB
+	storedKeys, err := storageService.ListAPIKeys()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api keys: %w", err)
+	}
+	keys = apikey.Merge(keys, storedKeys)
+
+	// No else needed: early return pattern (API key authentication disabled)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return apikey.NewRegistry(keys)
+}
+
+// loadFallbackConfig reads [chatbox.llm_fallback]: the per-attempt timeout, the
+// retries per model and the fallback chains, whose models must all be in the
+// model catalog
+func loadFallbackConfig(config *goconfig.ConfigAccessor, llmService *llm.LLMService) (router.FallbackConfig, error) {
+	var fallback router.FallbackConfig
+
+	attemptTimeoutStr, err := config.ConfigStringWithDefault("chatbox.llm_fallback.attempt_timeout", "0s")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fallback, fmt.Errorf("failed to get LLM attempt timeout: %w", err)
+	}
+	fallback.AttemptTimeout, err = time.ParseDuration(attemptTimeoutStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || fallback.AttemptTimeout < 0 {
+		return fallback, fmt.Errorf("invalid LLM attempt timeout %q", attemptTimeoutStr)
+	}
+
+	fallback.Retries, err = config.ConfigIntWithDefault("chatbox.llm_fallback.retries", 0)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fallback, fmt.Errorf("failed to get LLM retries: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if fallback.Retries < 0 || fallback.Retries > constants.MaxFallbackRetries {
+		return fallback, fmt.Errorf("chatbox.llm_fallback.retries must be between 0 and %d", constants.MaxFallbackRetries)
+	}
+
+	raw, err := config.Config("chatbox.llm_fallback.chains")
+	// No else needed: early return pattern (no chains configured)
+	if err != nil || raw == nil {
+		return fallback, nil
+	}
+	chains, err := router.ParseFallbackChains(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid chatbox.llm_fallback.chains: %w", err)
+	}
+	for modelID, chain := range chains {
+		for _, id := range append([]string{modelID}, chain...) {
+			// No else needed: early return pattern (guard clause)
+			if err := llmService.ValidateModel(id); err != nil {
+				return fallback, fmt.Errorf("invalid chatbox.llm_fallback.chains: %w", err)
+			}
+		}
+	}
+	fallback.Chains = chains
+	return fallback, nil
+}
+
+// loadResidency pins the tenants of the optional [chatbox.residency.tenants]
+// table to the [dbs.<name>] datastores their data is kept in. Returns false
+// when no tenant is pinned to another datastore than the default one.
+func loadResidency(config *goconfig.ConfigAccessor, storageService *storage.StorageService, logger *golog.Logger) (bool, error) {
+	raw, err := config.Config("chatbox.residency.tenants")
+	// No else needed: early return pattern (no tenants pinned)
+	if err != nil || raw == nil {
+		return false, nil
+	}
+	tenants, err := storage.ParseResidency(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return false, fmt.Errorf("invalid chatbox.residency.tenants: %w", err)
+	}
+
+	datastores := make(map[string]bool)
+	for tenantID, datastore := range tenants {
+		dbConfig, err := config.Config("dbs." + datastore)
+		// No else needed: early return pattern (guard clause)
+		if err != nil || dbConfig == nil {
+			return false, fmt.Errorf("invalid chatbox.residency.tenants: tenant %s: datastore %q has no [dbs.%s] entry", tenantID, datastore, datastore)
+		}
+		// No else needed: optional operation (tenants kept in the default datastore)
+		if datastore != constants.DefaultDatabase {
+			datastores[datastore] = true
+		}
+	}
+	// No else needed: early return pattern (every tenant stays in the default datastore)
+	if len(datastores) == 0 {
+		return false, nil
+	}
+
+	storageService.SetResidency(constants.DefaultDatabase, tenants)
+	logger.Info("Tenant data residency enabled",
+		"tenants", len(tenants),
+		"datastores", len(datastores))
+	return true, nil
+}
+
+// loadLLMTimeoutBounds reads the bounds of the LLM timeouts sessions and
+// messages may ask for from [chatbox.llm_timeouts]. An empty max disables
+// overrides.
+func loadLLMTimeoutBounds(config *goconfig.ConfigAccessor) (time.Duration, time.Duration, error) {
+	minStr, err := config.ConfigStringWithDefault("chatbox.llm_timeouts.min", constants.DefaultMinLLMTimeout.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get LLM timeout minimum: %w", err)
+	}
+	minTimeout, err := time.ParseDuration(minStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || minTimeout <= 0 {
+		return 0, 0, fmt.Errorf("invalid chatbox.llm_timeouts.min %q: must be a positive duration", minStr)
+	}
+
+	maxStr, err := config.ConfigStringWithDefault("chatbox.llm_timeouts.max", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get LLM timeout maximum: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envMax := os.Getenv("CHATBOX_LLM_TIMEOUT_MAX"); envMax != "" {
+		maxStr = envMax
+	}
+	// No else needed: early return pattern (overrides disabled)
+	if maxStr == "" {
+		return minTimeout, 0, nil
+	}
+	maxTimeout, err := time.ParseDuration(maxStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || maxTimeout < minTimeout {
+		return 0, 0, fmt.Errorf("invalid chatbox.llm_timeouts.max %q: must be a duration of at least the minimum %s", maxStr, minTimeout)
+	}
+	return minTimeout, maxTimeout, nil
+}
+
+// loadReconnectConfig reads [chatbox.reconnect]: the reconnect backoff suggested
+// to clients and the connection attempts that start a reconnect storm
+// Priority: Environment variable > Config file
+func loadReconnectConfig(config *goconfig.ConfigAccessor) (websocket.ReconnectConfig, error) {
+	var cfg websocket.ReconnectConfig
+	baseStr, err := config.ConfigStringWithDefault("chatbox.reconnect.base_delay", constants.DefaultReconnectBaseDelay.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to get reconnect base delay: %w", err)
+	}
+	cfg.BaseDelay, err = time.ParseDuration(baseStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid chatbox.reconnect.base_delay %q: %w", baseStr, err)
+	}
+	maxStr, err := config.ConfigStringWithDefault("chatbox.reconnect.max_delay", constants.DefaultReconnectMaxDelay.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to get reconnect max delay: %w", err)
+	}
+	cfg.MaxDelay, err = time.ParseDuration(maxStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid chatbox.reconnect.max_delay %q: %w", maxStr, err)
+	}
+	cfg.Jitter, err = configFloat(config, "chatbox.reconnect.jitter", constants.DefaultReconnectJitter)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.MaxAttempts, err = config.ConfigIntWithDefault("chatbox.reconnect.max_attempts", constants.DefaultReconnectMaxAttempts)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to get reconnect max attempts: %w", err)
+	}
+	cfg.StormLimit, err = config.ConfigIntWithDefault("chatbox.reconnect.storm_limit", constants.DefaultReconnectStormLimit)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to get reconnect storm limit: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envLimit := os.Getenv("CHATBOX_RECONNECT_STORM_LIMIT"); envLimit != "" {
+		cfg.StormLimit, err = strconv.Atoi(envLimit)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid CHATBOX_RECONNECT_STORM_LIMIT %q: %w", envLimit, err)
+		}
+	}
+	return cfg, nil
+}
+
+// loadCostCalculator reads [chatbox.costs]: the currency and the per-model
+// prices, per million tokens, of recorded LLM usage
+func loadCostCalculator(config *goconfig.ConfigAccessor) (*cost.Calculator, error) {
+	currency, err := config.ConfigStringWithDefault("chatbox.costs.currency", constants.DefaultCostCurrency)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost currency: %w", err)
+	}
+
+	raw, err := config.Config("chatbox.costs.prices")
+	// No else needed: early return pattern (no prices configured)
+	if err != nil || raw == nil {
+		return cost.NewCalculator(currency, nil), nil
+	}
+	prices, err := cost.ParsePrices(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.costs.prices: %w", err)
+	}
+	return cost.NewCalculator(currency, prices), nil
+}
+
+// loadHelpQueueScorer reads [chatbox.help_queue]: the weights of the wait time,
+// frustration and customer tier components of help queue priorities
+func loadHelpQueueScorer(config *goconfig.ConfigAccessor) (*priority.Scorer, error) {
+	raw, err := config.Config("chatbox.help_queue")
+	// No else needed: early return pattern (nothing configured)
+	if err != nil || raw == nil {
+		return priority.NewScorer(priority.DefaultWeights()), nil
+	}
+	weights, err := priority.ParseWeights(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.help_queue: %w", err)
+	}
+	return priority.NewScorer(weights), nil
+}
+
+// newModerationPipeline creates the content moderation pipeline from configuration.
+// Returns nil when moderation is disabled or no filters are configured.
+// Priority: Environment variables > Config file
+func newModerationPipeline(config *goconfig.ConfigAccessor, logger *golog.Logger) (*moderation.Pipeline, error) {
+	enabled, err := config.ConfigBoolWithDefault("chatbox.moderation.enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation enabled flag: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEnabled := os.Getenv("CHATBOX_MODERATION_ENABLED"); envEnabled != "" {
+		enabled = envEnabled == "true"
+	}
+	// No else needed: early return pattern (moderation disabled)
+	if !enabled {
+		return nil, nil
+	}
+
+	var stages []moderation.Stage
+
+	// Denylist: comma-separated words or phrases
+	denylistStr, err := config.ConfigStringWithDefault("chatbox.moderation.denylist", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation denylist: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envDenylist := os.Getenv("CHATBOX_MODERATION_DENYLIST"); envDenylist != "" {
+		denylistStr = envDenylist
+	}
+	// No else needed: optional operation (denylist only when configured)
+	if strings.TrimSpace(denylistStr) != "" {
+		filter, err := moderation.NewDenylistFilter(strings.Split(denylistStr, ","))
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation denylist: %w", err)
+		}
+		stage, err := moderationStage(config, "chatbox.moderation.denylist_action", "block", filter)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, stage)
+	}
+
+	// Patterns: array of regular expressions (an array because patterns may contain commas)
+	patterns, err := configStringList(config, "chatbox.moderation.patterns")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: optional operation (patterns only when configured)
+	if len(patterns) > 0 {
+		filter, err := moderation.NewRegexFilter("pattern", patterns)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		stage, err := moderationStage(config, "chatbox.moderation.patterns_action", "redact", filter)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, stage)
+	}
+
+	// OpenAI moderation API
+	apiKey := os.Getenv("CHATBOX_MODERATION_OPENAI_API_KEY")
+	// No else needed: optional operation (config fallback)
+	if apiKey == "" {
+		apiKey, err = config.ConfigStringWithDefault("chatbox.moderation.openai_api_key", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get moderation OpenAI API key: %w", err)
+		}
+	}
+	// No else needed: optional operation (OpenAI filter only when a key is configured)
+	if apiKey != "" {
+		endpoint, err := config.ConfigStringWithDefault("chatbox.moderation.openai_endpoint", constants.DefaultOpenAIModerationEndpoint)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get moderation OpenAI endpoint: %w", err)
+		}
+		model, err := config.ConfigStringWithDefault("chatbox.moderation.openai_model", constants.DefaultOpenAIModerationModel)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get moderation OpenAI model: %w", err)
+		}
+		filter, err := moderation.NewOpenAIFilter(moderation.OpenAIConfig{
+			APIKey:   apiKey,
+			Endpoint: endpoint,
+			Model:    model,
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		stage, err := moderationStage(config, "chatbox.moderation.openai_action", "flag", filter)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, stage)
+	}
+
+	// No else needed: early return pattern (nothing to moderate with)
+	if len(stages) == 0 {
+		logger.Warn("Moderation enabled but no filters configured - moderation disabled")
+		return nil, nil
+	}
+	return moderation.NewPipeline(logger, stages...), nil
+}
+
+// moderationStage pairs a filter with the action configured under key
+func moderationStage(config *goconfig.ConfigAccessor, key, defaultAction string, filter moderation.Filter) (moderation.Stage, error) {
+	actionStr, err := config.ConfigStringWithDefault(key, defaultAction)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return moderation.Stage{}, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	action, err := moderation.ParseAction(actionStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return moderation.Stage{}, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return moderation.Stage{Filter: filter, Action: action}, nil
+}
+
+// newInjectionScanner creates the prompt injection scanner from the
+// [chatbox.injection] settings. Returns nil when detection is disabled.
+// Priority: Environment variables > Config file
+func newInjectionScanner(config *goconfig.ConfigAccessor, logger *golog.Logger) (*injection.Scanner, error) {
+	enabled, err := config.ConfigBoolWithDefault("chatbox.injection.enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get injection enabled flag: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEnabled := os.Getenv("CHATBOX_INJECTION_ENABLED"); envEnabled != "" {
+		enabled = envEnabled == "true"
+	}
+	// No else needed: early return pattern (detection disabled)
+	if !enabled {
+		return nil, nil
+	}
+
+	actionStr, err := config.ConfigStringWithDefault("chatbox.injection.action", "warn")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get injection action: %w", err)
+	}
+	userAction, err := injection.ParseAction(actionStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.injection.action: %w", err)
+	}
+	documentActionStr, err := config.ConfigStringWithDefault("chatbox.injection.document_action", actionStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get injection document action: %w", err)
+	}
+	documentAction, err := injection.ParseAction(documentActionStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.injection.document_action: %w", err)
+	}
+
+	// Patterns: array of regular expressions added to the built-in heuristics
+	patterns, err := configStringList(config, "chatbox.injection.patterns")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	heuristic, err := injection.NewHeuristicDetector(patterns)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	detectors := []injection.Detector{heuristic}
+
+	// Optional external classifier
+	endpoint, err := config.ConfigStringWithDefault("chatbox.injection.classifier_endpoint", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get injection classifier endpoint: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEndpoint := os.Getenv("CHATBOX_INJECTION_CLASSIFIER_ENDPOINT"); envEndpoint != "" {
+		endpoint = envEndpoint
+	}
+	// No else needed: optional operation (classifier only when configured)
+	if endpoint != "" {
+		apiKey := os.Getenv("CHATBOX_INJECTION_CLASSIFIER_API_KEY")
+		// No else needed: optional operation (config fallback)
+		if apiKey == "" {
+			apiKey, err = config.ConfigStringWithDefault("chatbox.injection.classifier_api_key", "")
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get injection classifier API key: %w", err)
+			}
+		}
+		threshold, err := configFloat(config, "chatbox.injection.classifier_threshold", constants.DefaultInjectionThreshold)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		classifier, err := injection.NewClassifierDetector(injection.ClassifierConfig{
+			Endpoint:  endpoint,
+			APIKey:    apiKey,
+			Threshold: threshold,
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		detectors = append(detectors, classifier)
+	}
+
+	return injection.NewScanner(logger, userAction, documentAction, detectors...), nil
+}
+
+// newRetriever creates the HTTP retriever for retrieval-augmented generation
+// from the [chatbox.retrieval] settings. Returns nil when no endpoint is configured.
+func newRetriever(config *goconfig.ConfigAccessor) (*retrieval.HTTPRetriever, error) {
+	endpoint, err := config.ConfigStringWithDefault("chatbox.retrieval.endpoint", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retrieval endpoint: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEndpoint := os.Getenv("CHATBOX_RETRIEVAL_ENDPOINT"); envEndpoint != "" {
+		endpoint = envEndpoint
+	}
+	// No else needed: early return pattern (retrieval disabled)
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	apiKey := os.Getenv("CHATBOX_RETRIEVAL_API_KEY")
+	// No else needed: optional operation (config fallback)
+	if apiKey == "" {
+		apiKey, err = config.ConfigStringWithDefault("chatbox.retrieval.api_key", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get retrieval API key: %w", err)
+		}
+	}
+	topK, err := config.ConfigIntWithDefault("chatbox.retrieval.top_k", constants.DefaultRetrievalTopK)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retrieval top_k: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if topK <= 0 {
+		return nil, fmt.Errorf("chatbox.retrieval.top_k must be positive, got %d", topK)
+	}
+	timeoutStr, err := config.ConfigStringWithDefault("chatbox.retrieval.timeout", constants.DefaultRetrievalTimeout.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retrieval timeout: %w", err)
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || timeout <= 0 {
+		return nil, fmt.Errorf("invalid chatbox.retrieval.timeout %q: must be a positive duration", timeoutStr)
+	}
+
+	return retrieval.NewHTTPRetriever(retrieval.HTTPConfig{
+		Endpoint: endpoint,
+		APIKey:   apiKey,
+		TopK:     topK,
+		Timeout:  timeout,
+	})
+}
+
+// newCodeRunner creates the HTTP runner of the code execution sandbox from the
+// [chatbox.sandbox] settings, and returns the tenants it is enabled for.
+// Returns nil when no endpoint is configured.
+func newCodeRunner(config *goconfig.ConfigAccessor) (*sandbox.HTTPRunner, []string, error) {
+	endpoint, err := config.ConfigStringWithDefault("chatbox.sandbox.endpoint", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get sandbox endpoint: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEndpoint := os.Getenv("CHATBOX_SANDBOX_ENDPOINT"); envEndpoint != "" {
+		endpoint = envEndpoint
+	}
+	// No else needed: early return pattern (sandbox disabled)
+	if endpoint == "" {
+		return nil, nil, nil
+	}
+
+	apiKey := os.Getenv("CHATBOX_SANDBOX_API_KEY")
+	// No else needed: optional operation (config fallback)
+	if apiKey == "" {
+		apiKey, err = config.ConfigStringWithDefault("chatbox.sandbox.api_key", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get sandbox API key: %w", err)
+		}
+	}
+	tenantsStr, err := config.ConfigStringWithDefault("chatbox.sandbox.tenants", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get sandbox tenants: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envTenants := os.Getenv("CHATBOX_SANDBOX_TENANTS"); envTenants != "" {
+		tenantsStr = envTenants
+	}
+	var tenants []string
+	for _, tenant := range strings.Split(tenantsStr, ",") {
+		// No else needed: optional operation (skip blank entries)
+		if tenant = strings.TrimSpace(tenant); tenant != "" {
+			tenants = append(tenants, tenant)
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(tenants) == 0 {
+		return nil, nil, fmt.Errorf("chatbox.sandbox.tenants is required when the sandbox endpoint is set (use %q for every tenant)", constants.SandboxAllTenants)
+	}
+	languagesStr, err := config.ConfigStringWithDefault("chatbox.sandbox.languages", strings.Join(constants.DefaultSandboxLanguages, ","))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get sandbox languages: %w", err)
+	}
+	timeoutStr, err := config.ConfigStringWithDefault("chatbox.sandbox.timeout", constants.DefaultSandboxTimeout.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get sandbox timeout: %w", err)
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || timeout <= 0 {
+		return nil, nil, fmt.Errorf("invalid chatbox.sandbox.timeout %q: must be a positive duration", timeoutStr)
+	}
+
+	runner, err := sandbox.NewHTTPRunner(sandbox.HTTPConfig{
+		Endpoint:  endpoint,
+		APIKey:    apiKey,
+		Languages: strings.Split(languagesStr, ","),
+		Timeout:   timeout,
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, nil, err
+	}
+	return runner, tenants, nil
+}
+
+// newTranscriber creates the HTTP speech-to-text provider for streamed voice
+// messages from the [chatbox.transcription] settings. Returns nil when no
+// endpoint is configured.
+func newTranscriber(config *goconfig.ConfigAccessor) (*transcribe.HTTPProvider, error) {
+	endpoint, err := config.ConfigStringWithDefault("chatbox.transcription.endpoint", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcription endpoint: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEndpoint := os.Getenv("CHATBOX_TRANSCRIPTION_ENDPOINT"); envEndpoint != "" {
+		endpoint = envEndpoint
+	}
+	// No else needed: early return pattern (transcription disabled)
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	apiKey := os.Getenv("CHATBOX_TRANSCRIPTION_API_KEY")
+	// No else needed: optional operation (config fallback)
+	if apiKey == "" {
+		apiKey, err = config.ConfigStringWithDefault("chatbox.transcription.api_key", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transcription API key: %w", err)
+		}
+	}
+	timeoutStr, err := config.ConfigStringWithDefault("chatbox.transcription.timeout", constants.DefaultTranscriptionTimeout.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcription timeout: %w", err)
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || timeout <= 0 {
+		return nil, fmt.Errorf("invalid chatbox.transcription.timeout %q: must be a positive duration", timeoutStr)
+	}
+
+	return transcribe.NewHTTPProvider(transcribe.HTTPConfig{
+		Endpoint: endpoint,
+		APIKey:   apiKey,
+		Timeout:  timeout,
+	})
+}
+
+// newTranscriptMailer creates the mailer of session transcripts from the
+// [chatbox.email] settings. Returns nil when it is disabled. Files are attached
+// from uploadService, which may be nil when uploads are unavailable.
+// Priority: Environment variable > Config file
+func newTranscriptMailer(config *goconfig.ConfigAccessor, uploadService *upload.UploadService, logger *golog.Logger) (*email.Mailer, error) {
+	enabled, err := config.ConfigBoolWithDefault("chatbox.email.enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email enabled flag: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEnabled := os.Getenv("CHATBOX_EMAIL_ENABLED"); envEnabled != "" {
+		enabled = envEnabled == "true"
+	}
+	// No else needed: early return pattern (transcript emails disabled)
+	if !enabled {
+		return nil, nil
+	}
+
+	cfg := email.Config{}
+	for _, setting := range []struct {
+		key, env string
+		value    *string
+	}{
+		{"host", "CHATBOX_SMTP_HOST", &cfg.Host},
+		{"username", "CHATBOX_SMTP_USERNAME", &cfg.Username},
+		{"password", "CHATBOX_SMTP_PASSWORD", &cfg.Password},
+		{"from", "CHATBOX_EMAIL_FROM", &cfg.From},
+		{"subject", "", &cfg.Subject},
+	} {
+		*setting.value, err = config.ConfigStringWithDefault("chatbox.email."+setting.key, "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get email %s: %w", setting.key, err)
+		}
+		// No else needed: optional operation (environment override)
+		if env := os.Getenv(setting.env); setting.env != "" && env != "" {
+			*setting.value = env
+		}
+	}
+	cfg.Port, err = config.ConfigIntWithDefault("chatbox.email.port", constants.DefaultSMTPPort)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email port: %w", err)
+	}
+	cfg.MaxRetries, err = config.ConfigIntWithDefault("chatbox.email.max_retries", constants.DefaultEmailMaxRetries)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email max retries: %w", err)
+	}
+
+	templatePath, err := config.ConfigStringWithDefault("chatbox.email.template", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email template: %w", err)
+	}
+	// No else needed: optional operation (custom template)
+	if templatePath != "" {
+		body, err := os.ReadFile(templatePath)
 		// No else needed: early return pattern (guard clause)
 		if err != nil {
-			// Log detailed error server-side
-			util.LogError(logger, "http", "list sessions", err)
-			// Send generic error to client
-			httperrors.RespondInternalError(c)
-			return
+			return nil, fmt.Errorf("failed to read email template: %w", err)
+		}
+		// No else needed: early return pattern (guard clause)
+		if len(body) > constants.MaxEmailTemplateBytes {
+			return nil, fmt.Errorf("email template %s is larger than %d bytes", templatePath, constants.MaxEmailTemplateBytes)
 		}
+		cfg.Template = string(body)
+	}
 
-		c.JSON(constants.StatusOK, gin.H{
-			"sessions": sessions,
-			"count":    len(sessions),
-			"limit":    limit,
-			"offset":   offset,
-		})
+	var fetch email.FileFetcher
+	// No else needed: optional operation (attachments only with uploads)
+	if uploadService != nil {
+		fetch = uploadService.DownloadFile
 	}
+	mailer, err := email.NewMailer(cfg, fetch, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.email configuration: %w", err)
+	}
+	logger.Info("Transcript emails enabled", "smtp_host", cfg.Host, "smtp_port", cfg.Port)
+	return mailer, nil
 }
 
-// handleGetMetrics returns a handler for getting session metrics
-func handleGetMetrics(storageService *storage.StorageService, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Get query parameters for time range
-		startTimeStr := c.Query("start_time")
-		endTimeStr := c.Query("end_time")
+// newSentimentPipeline creates the sentiment analysis pipeline from the optional
+// [chatbox.sentiment] config table. Returns nil when it is disabled. Rolling
+// sentiments are persisted when the session store keeps them, and alerts are
+// posted to the webhook endpoints when any are configured; webhooks may be nil.
+// Priority: Environment variable > Config file
+func newSentimentPipeline(config *goconfig.ConfigAccessor, sessionManager *session.SessionManager, sessionStore storage.SessionStore, webhooks router.WebhookPublisher, logger *golog.Logger) (*sentiment.Pipeline, error) {
+	enabled, err := config.ConfigBoolWithDefault("chatbox.sentiment.enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sentiment enabled flag: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEnabled := os.Getenv("CHATBOX_SENTIMENT_ENABLED"); envEnabled != "" {
+		enabled = envEnabled == "true"
+	}
+	// No else needed: early return pattern (sentiment analysis disabled)
+	if !enabled {
+		return nil, nil
+	}
 
-		// Parse time range
-		var startTime, endTime time.Time
-		var err error
+	timeoutStr, err := config.ConfigStringWithDefault("chatbox.sentiment.timeout", constants.DefaultSentimentTimeout.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sentiment timeout: %w", err)
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || timeout <= 0 {
+		return nil, fmt.Errorf("invalid chatbox.sentiment.timeout %q: must be a positive duration", timeoutStr)
+	}
 
-		// No else needed: optional operation (time range parsing with default)
-		if startTimeStr != "" {
-			startTime, err = time.Parse(time.RFC3339, startTimeStr)
-			// No else needed: early return pattern (guard clause)
-			if err != nil {
-				logger.Warn("Invalid start_time parameter",
-					"value", startTimeStr,
-					"error", err,
-					"component", "http")
-				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
-				return
-			}
-		} else {
-			// Default to last 24 hours
-			startTime = time.Now().Add(-24 * time.Hour)
+	analyzerName, err := config.ConfigStringWithDefault("chatbox.sentiment.analyzer", sentiment.AnalyzerLexicon)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sentiment analyzer: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envAnalyzer := os.Getenv("CHATBOX_SENTIMENT_ANALYZER"); envAnalyzer != "" {
+		analyzerName = envAnalyzer
+	}
+	var analyzer sentiment.Analyzer
+	switch analyzerName {
+	case sentiment.AnalyzerLexicon:
+		analyzer = sentiment.LexiconAnalyzer{}
+	case sentiment.AnalyzerHTTP:
+		endpoint, err := config.ConfigStringWithDefault("chatbox.sentiment.endpoint", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sentiment endpoint: %w", err)
 		}
-
-		// No else needed: optional operation (time range parsing with default)
-		if endTimeStr != "" {
-			endTime, err = time.Parse(time.RFC3339, endTimeStr)
+		// No else needed: optional operation (environment override)
+		if envEndpoint := os.Getenv("CHATBOX_SENTIMENT_ENDPOINT"); envEndpoint != "" {
+			endpoint = envEndpoint
+		}
+		apiKey := os.Getenv("CHATBOX_SENTIMENT_API_KEY")
+		// No else needed: optional operation (config fallback)
+		if apiKey == "" {
+			apiKey, err = config.ConfigStringWithDefault("chatbox.sentiment.api_key", "")
 			// No else needed: early return pattern (guard clause)
 			if err != nil {
-				logger.Warn("Invalid end_time parameter",
-					"value", endTimeStr,
-					"error", err,
-					"component", "http")
-				httperrors.RespondBadRequest(c, httperrors.MsgInvalidTimeFormat)
-				return
+				return nil, fmt.Errorf("failed to get sentiment API key: %w", err)
 			}
-		} else {
-			// Default to now
-			endTime = time.Now()
 		}
-
-		// Get metrics from storage
-		metrics, err := storageService.GetSessionMetrics(startTime, endTime)
+		analyzer, err = sentiment.NewHTTPAnalyzer(sentiment.HTTPConfig{
+			Endpoint: endpoint,
+			APIKey:   apiKey,
+			Timeout:  timeout,
+		})
 		// No else needed: early return pattern (guard clause)
 		if err != nil {
-			// Log detailed error server-side
-			util.LogError(logger, "http", "get session metrics", err)
-			// Send generic error to client
-			httperrors.RespondInternalError(c)
-			return
+			return nil, err
 		}
+	default:
+		return nil, fmt.Errorf("invalid chatbox.sentiment.analyzer %q: expected %s or %s", analyzerName, sentiment.AnalyzerLexicon, sentiment.AnalyzerHTTP)
+	}
 
-		// TotalTokens is already computed by GetSessionMetrics aggregation pipeline.
-		// No separate GetTokenUsage call needed.
+	smoothing, err := configFloat(config, "chatbox.sentiment.smoothing", constants.DefaultSentimentSmoothing)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := configFloat(config, "chatbox.sentiment.alert_threshold", constants.DefaultSentimentThreshold)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
 
-		c.JSON(constants.StatusOK, gin.H{
-			"metrics": metrics,
-			"time_range": gin.H{
-				"start": startTime.Format(time.RFC3339),
-				"end":   endTime.Format(time.RFC3339),
-			},
-		})
+	// Keep the optional dependencies untyped nil interfaces when absent
+	var store sentiment.Store
+	// No else needed: optional operation (session stores that keep sentiments)
+	if sentimentStore, ok := sessionStore.(sentiment.Store); ok {
+		store = sentimentStore
 	}
+	var alerts sentiment.Publisher
+	// No else needed: optional operation (webhooks only when configured)
+	if webhooks != nil {
+		alerts = webhooks
+	}
+
+	pipeline, err := sentiment.NewPipeline(analyzer, sentiment.Config{
+		Smoothing: smoothing,
+		Threshold: threshold,
+		Timeout:   timeout,
+	}, sessionManager, store, alerts, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.sentiment: %w", err)
+	}
+	logger.Info("Sentiment analysis enabled",
+		"analyzer", analyzer.Name(),
+		"smoothing", smoothing,
+		"alert_threshold", threshold)
+	return pipeline, nil
 }
 
-// handleAdminTakeover returns a handler for admin session takeover
-func handleAdminTakeover(messageRouter *router.MessageRouter, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		sessionID := c.Param("sessionID")
+// configFloat reads a number given as a string, like chatbox.tracing_sample_ratio;
+// def applies when it is unset
+func configFloat(config *goconfig.ConfigAccessor, key string, def float64) (float64, error) {
+	str, err := config.ConfigStringWithDefault(key, "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	// No else needed: early return pattern (unset)
+	if str == "" {
+		return def, nil
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", key, str, err)
+	}
+	return value, nil
+}
 
-		// No else needed: early return pattern (guard clause)
-		if sessionID == "" {
-			httperrors.RespondBadRequest(c, constants.ErrMsgSessionIDRequired)
-			return
-		}
+// newBackupRunner creates the session backup runner from the optional
+// [chatbox.backup] config table. Returns nil when backups are disabled.
+// Priority: Environment variable > Config file
+func newBackupRunner(config *goconfig.ConfigAccessor, mongo *gomongo.Mongo, storageService *storage.StorageService, logger *golog.Logger) (*backup.Runner, error) {
+	enabled, err := config.ConfigBoolWithDefault("chatbox.backup.enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup enabled flag: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEnabled := os.Getenv("CHATBOX_BACKUP_ENABLED"); envEnabled != "" {
+		enabled = envEnabled == "true"
+	}
+	// No else needed: early return pattern (backups disabled)
+	if !enabled {
+		return nil, nil
+	}
 
-		// Get admin claims from context (set by authMiddleware)
-		claimsInterface, exists := c.Get("claims")
-		// No else needed: early return pattern (guard clause)
-		if !exists {
-			httperrors.RespondUnauthorized(c, "")
-			return
-		}
+	key, err := loadBackupKey(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	target, err := newBackupTarget(config, mongo)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	runner, err := backup.NewRunner(storageService, target, key, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.backup: %w", err)
+	}
+	logger.Info("Session backups enabled", "target", target.Name())
+	return runner, nil
+}
 
-		claims, ok := claimsInterface.(*auth.Claims)
+// loadBackupKey loads the key sealing backup archives. It is separate from the
+// message encryption key, which never leaves the service, and must be exactly
+// 32 bytes.
+// Priority: Environment variable > Config file
+func loadBackupKey(config *goconfig.ConfigAccessor) ([]byte, error) {
+	keyStr := os.Getenv("CHATBOX_BACKUP_KEY")
+	// No else needed: optional operation (config fallback)
+	if keyStr == "" {
+		var err error
+		keyStr, err = config.ConfigStringWithDefault("chatbox.backup.key", "")
 		// No else needed: early return pattern (guard clause)
-		if !ok {
-			util.LogError(logger, "http", "validate claims type", fmt.Errorf("invalid claims type in context"))
-			httperrors.RespondInternalError(c)
-			return
+		if err != nil {
+			return nil, fmt.Errorf("failed to get backup key: %w", err)
 		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if keyStr == "" || containsPlaceholder(keyStr) {
+		return nil, fmt.Errorf("chatbox.backup.key is required: set CHATBOX_BACKUP_KEY to a %d-byte key", backup.KeySize)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(keyStr) != backup.KeySize {
+		return nil, fmt.Errorf("backup key must be exactly %d bytes, got %d bytes", backup.KeySize, len(keyStr))
+	}
+	return []byte(keyStr), nil
+}
 
-		// Create an admin connection for the takeover.
-		// NOTE: This connection has no writePump consuming its send channel.
-		// It serves as a session marker for admin assistance tracking.
-		// Messages sent to this connection via BroadcastToSession will buffer
-		// (capacity 256) and be silently dropped when full. For full bidirectional
-		// admin messaging, use WebSocket-based admin takeover instead.
-		adminConn := websocket.NewConnection(claims.UserID, claims.Roles)
-		adminConn.Name = claims.Name
-		adminConn.ConnectionID = fmt.Sprintf("admin-%s-%d", claims.UserID, time.Now().UnixNano())
-
-		// Handle admin takeover
-		if err := messageRouter.HandleAdminTakeover(adminConn, sessionID); err != nil {
-			util.LogError(logger, "http", "initiate admin takeover", err,
-				"session_id", sessionID,
-				"admin_id", claims.UserID)
+// newBackupTarget creates where backup archives are stored: a local directory
+// or the goupload "backups" entry of the CHAT site, e.g. an S3 bucket. goupload
+// must be initialized for the s3 target.
+// Priority: Environment variable > Config file
+func newBackupTarget(config *goconfig.ConfigAccessor, mongo *gomongo.Mongo) (backup.Target, error) {
+	targetName, err := config.ConfigStringWithDefault("chatbox.backup.target", backup.TargetLocal)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup target: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envTarget := os.Getenv("CHATBOX_BACKUP_TARGET"); envTarget != "" {
+		targetName = envTarget
+	}
 
-			// Map error to appropriate HTTP status
-			var chatErr *chaterrors.ChatError
-			if errors.As(err, &chatErr) {
-				switch chatErr.Code {
-				case chaterrors.ErrCodeNotFound:
-					httperrors.RespondNotFound(c, "Session not found")
-				case chaterrors.ErrCodeInvalidFormat:
-					httperrors.RespondBadRequest(c, chatErr.Message)
-				default:
-					httperrors.RespondInternalError(c)
-				}
-			} else {
-				httperrors.RespondInternalError(c)
-			}
-			return
+	switch targetName {
+	case backup.TargetLocal:
+		dir, err := config.ConfigStringWithDefault("chatbox.backup.path", constants.DefaultBackupDir)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get backup path: %w", err)
 		}
-
-		c.JSON(constants.StatusOK, gin.H{
-			"message":    "Takeover initiated successfully",
-			"session_id": sessionID,
-			"admin_id":   claims.UserID,
-		})
+		// No else needed: optional operation (environment override)
+		if envDir := os.Getenv("CHATBOX_BACKUP_PATH"); envDir != "" {
+			dir = envDir
+		}
+		return backup.NewDirTarget(dir)
+	case backup.TargetS3:
+		return backup.NewUploadTarget("CHAT", constants.BackupUploadEntry, mongo.Coll("chat", "file_stats"))
+	default:
+		return nil, fmt.Errorf("invalid chatbox.backup.target %q: expected %s or %s", targetName, backup.TargetLocal, backup.TargetS3)
 	}
 }
 
-// handleHealthCheck returns a handler for liveness probe endpoint.
-// This endpoint checks if the application is alive and should be restarted if it fails.
-// It performs minimal checks to determine if the process is running correctly.
-func handleHealthCheck(c *gin.Context) {
-	// Basic liveness check - if we can respond, we're alive
-	c.JSON(constants.StatusOK, gin.H{
-		"status":    "healthy",
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-	})
-}
+// RestoreBackup restores the sessions of a backup archive into the MongoDB
+// session collection, resolving sessions that already exist with strategy
+// ("skip", "overwrite" or "merge"). The archive is read from file when it is
+// set, otherwise from location in the configured [chatbox.backup] target.
+// Message content is encrypted with the configured encryption key.
+//
+// It backs the restore command (cmd/restore) and must not run inside a
+// registered service.
+func RestoreBackup(ctx context.Context, config *goconfig.ConfigAccessor, logger *golog.Logger, mongo *gomongo.Mongo, file, location, strategy string) (*backup.RestoreResult, error) {
+	conflict, err := storage.ParseConflictStrategy(strategy)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadBackupKey(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	encryptionKey, err := loadEncryptionKey(config, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
 
-// handleReadyCheck returns a handler for readiness probe endpoint.
-// This endpoint checks if the application is ready to serve traffic.
-// It performs comprehensive checks on all critical dependencies.
-func handleReadyCheck(mongo *gomongo.Mongo, llmService *llm.LLMService, logger *golog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		checks := make(map[string]interface{})
-		allReady := true
+	var archive io.ReadCloser
+	// No else needed: conditional assignment (local file or configured target)
+	if file != "" {
+		archive, err = os.Open(file)
+	} else {
+		// No else needed: early return pattern (guard clause)
+		if err := goupload.Init(goupload.InitOptions{Logger: logger, Config: config}); err != nil {
+			return nil, fmt.Errorf("failed to initialize goupload: %w", err)
+		}
+		var target backup.Target
+		target, err = newBackupTarget(config, mongo)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		archive, err = target.Open(ctx, location)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer archive.Close()
 
-		// Check MongoDB connection
-		// No else needed: optional operation (MongoDB health check)
-		if mongo == nil {
-			checks["mongodb"] = map[string]interface{}{
-				"status": "not ready",
-				"reason": "MongoDB not initialized",
-			}
-			allReady = false
-		} else {
-			// Verify MongoDB connection by pinging the server
-			ctx, cancel := util.NewTimeoutContext(constants.HealthCheckTimeout)
-			defer cancel()
-
-			// Use Ping() to check MongoDB connectivity
-			// This is the recommended way to verify database health
-			testColl := mongo.Coll("chat", "sessions")
-			err := testColl.Ping(ctx)
-			// No else needed: optional operation (health check result recording)
-			if err != nil {
-				// Log detailed error server-side
-				logger.Warn("MongoDB health check failed",
-					"error", err,
-					"component", "health")
+	storageService := storage.NewStorageService(mongo, "chat", "sessions", logger, encryptionKey)
+	return backup.Restore(ctx, archive, key, storageService, conflict, logger)
+}
 
-				// Send generic error to client
-				checks["mongodb"] = map[string]interface{}{
-					"status": "not ready",
-					"reason": "Database connectivity check failed",
-				}
-				allReady = false
-			} else {
-				checks["mongodb"] = map[string]interface{}{
-					"status": "ready",
-				}
-			}
-		}
+// newEmbedRegistry creates the registry of partner origins allowed to embed the
+// chat widget from the optional [chatbox.embed.origins.<name>] config tables.
+// Returns nil when no origins are configured.
+func newEmbedRegistry(config *goconfig.ConfigAccessor, redisClient *redis.Client) (*embed.Registry, error) {
+	raw, err := config.Config("chatbox.embed.origins")
+	// No else needed: early return pattern (embedding disabled)
+	if err != nil || raw == nil {
+		return nil, nil
+	}
+	origins, err := embed.ParseConfigOrigins(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embed origins: %w", err)
+	}
+	// No else needed: early return pattern (empty table)
+	if len(origins) == 0 {
+		return nil, nil
+	}
 
-		// Check LLM provider availability (optional — nil means LLM not configured)
-		if llmService != nil {
-			models := llmService.GetAvailableModels()
-			if len(models) == 0 {
-				checks["llm"] = map[string]interface{}{
-					"status": "not ready",
-					"reason": "No LLM providers configured",
-				}
-				allReady = false
-			} else {
-				checks["llm"] = map[string]interface{}{
-					"status":          "ready",
-					"providers_count": len(models),
-				}
-			}
+	var newLimiter embed.LimiterFunc
+	// No else needed: optional operation (distributed quotas only when configured)
+	if redisClient != nil {
+		newLimiter = func(origin *embed.Origin) ratelimit.Limiter {
+			return ratelimit.NewRedisLimiter(redisClient, "embed_token", time.Hour, origin.TokensPerHour)
 		}
+	}
+	return embed.NewRegistry(origins, newLimiter), nil
+}
 
-		// Determine overall status
-		status := "ready"
-		statusCode := constants.StatusOK
-		// No else needed: optional operation (status code adjustment based on health)
-		if !allReady {
-			status = "not ready"
-			statusCode = constants.StatusServiceUnavailable
+// loadRatePolicies reads the per-endpoint admin rate limit policies from the
+// optional [chatbox.rate_limits] table. Returns nil when none are configured.
+func loadRatePolicies(config *goconfig.ConfigAccessor, redisClient *redis.Client, logger *golog.Logger) (*ratelimit.Policies, error) {
+	policies, err := parseRatePolicies(config)
+	// No else needed: early return pattern (guard clause or global admin limit only)
+	if err != nil || policies == nil {
+		return nil, err
+	}
+
+	var newLimiter ratelimit.PolicyLimiterFunc
+	// No else needed: optional operation (distributed limits only when configured)
+	if redisClient != nil {
+		newLimiter = func(policy ratelimit.Policy) ratelimit.Limiter {
+			return ratelimit.NewRedisLimiter(redisClient, "admin_"+policy.Name, policy.Window, policy.Limit)
 		}
+	}
+	for _, policy := range policies {
+		logger.Info("Admin rate limit policy configured",
+			"policy", policy.Name,
+			"rate_limit", policy.Limit,
+			"window", policy.Window)
+	}
+	return ratelimit.NewPolicies(policies, newLimiter), nil
+}
 
-		c.JSON(statusCode, gin.H{
-			"status":    status,
-			"timestamp": time.Now().UTC().Format(time.RFC3339),
-			"checks":    checks,
-		})
+// parseRatePolicies reads the admin rate limit policies of the optional
+// [chatbox.rate_limits] table. Returns nil when none are configured.
+func parseRatePolicies(config *goconfig.ConfigAccessor) ([]ratelimit.Policy, error) {
+	raw, err := config.Config("chatbox.rate_limits")
+	// No else needed: early return pattern (global admin limit only)
+	if err != nil || raw == nil {
+		return nil, nil
 	}
+	policies, err := ratelimit.ParsePolicies(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.rate_limits: %w", err)
+	}
+	return policies, nil
 }
 
-// Shutdown gracefully shuts down the chatbox service.
-// It closes all active WebSocket connections and flushes logs.
-// This function should be called when the application receives a SIGTERM or SIGINT signal.
-// It respects the context deadline and will force shutdown if the deadline is exceeded.
-func Shutdown(ctx context.Context) error {
-	shutdownMu.Lock()
-	defer shutdownMu.Unlock()
+// newMetricsPusher creates the pusher of aggregated session metrics to the
+// Prometheus remote write endpoint of the optional [chatbox.metrics_remote_write]
+// table. Every interval it pushes the metrics of the trailing window as
+// chatbox_report_<metric> gauges labeled with job and window. Returns nil when
+// no endpoint is configured.
+func newMetricsPusher(config *goconfig.ConfigAccessor, storageService *storage.StorageService, logger *golog.Logger) (*remotewrite.Pusher, error) {
+	endpoint, err := config.ConfigStringWithDefault("chatbox.metrics_remote_write.url", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics remote write url: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEndpoint := os.Getenv("CHATBOX_METRICS_REMOTE_WRITE_URL"); envEndpoint != "" {
+		endpoint = envEndpoint
+	}
+	// No else needed: early return pattern (remote write disabled)
+	if endpoint == "" {
+		return nil, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := llm.ValidateEndpoint(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid chatbox.metrics_remote_write.url %q: %w", endpoint, err)
+	}
 
-	// No else needed: optional operation (logging during shutdown)
-	if globalLogger != nil {
-		globalLogger.Info("Starting graceful shutdown of chatbox service")
+	token := os.Getenv("CHATBOX_METRICS_REMOTE_WRITE_TOKEN")
+	// No else needed: optional operation (config fallback)
+	if token == "" {
+		token, err = config.ConfigStringWithDefault("chatbox.metrics_remote_write.bearer_token", "")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metrics remote write bearer token: %w", err)
+		}
+	}
+	intervalStr, err := config.ConfigStringWithDefault("chatbox.metrics_remote_write.interval", constants.DefaultMetricsPushInterval.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics remote write interval: %w", err)
+	}
+	interval, err := time.ParseDuration(intervalStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid chatbox.metrics_remote_write.interval %q: must be a positive duration", intervalStr)
+	}
+	windowStr, err := config.ConfigStringWithDefault("chatbox.metrics_remote_write.window", "24h")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics remote write window: %w", err)
+	}
+	window, err := time.ParseDuration(windowStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("invalid chatbox.metrics_remote_write.window %q: must be a positive duration", windowStr)
+	}
+	job, err := config.ConfigStringWithDefault("chatbox.metrics_remote_write.job", constants.DefaultMetricsPushJob)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics remote write job: %w", err)
 	}
 
-	// Stop session cleanup goroutine
-	// No else needed: optional operation (cleanup stop)
-	if globalSessionMgr != nil {
-		globalSessionMgr.StopCleanup()
+	client, err := remotewrite.NewClient(remotewrite.Config{Endpoint: endpoint, BearerToken: token})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	collect := func(ctx context.Context) ([]remotewrite.Sample, error) {
+		now := time.Now()
+		m, err := storageService.GetRollupMetrics(now.Add(-window), now)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		return sessionMetricSamples(m, job, windowStr), nil
+	}
+	onError := func(err error) {
+		util.LogError(logger, "metrics_remote_write", "push session metrics", err)
 	}
 
-	// Stop message router cleanup goroutines
-	// No else needed: optional operation (cleanup stop)
-	if globalMessageRouter != nil {
-		globalMessageRouter.Shutdown()
+	logger.Info("Metrics remote write enabled",
+		"interval", interval,
+		"window", window,
+		"job", job)
+	return remotewrite.NewPusher(client, interval, collect, onError), nil
+}
+
+// newAlertMonitor creates the monitor of the usage alert rules of the optional
+// [chatbox.alerts] table, publishing alert events to publisher (may be nil).
+// Returns nil when no rules are configured.
+func newAlertMonitor(config *goconfig.ConfigAccessor, storageService *storage.StorageService, sessionManager *session.SessionManager, publisher alerts.Publisher, logger *golog.Logger) (*alerts.Monitor, error) {
+	raw, err := config.Config("chatbox.alerts")
+	// No else needed: early return pattern (alerts not configured)
+	if err != nil || raw == nil {
+		return nil, nil
+	}
+	cfg, err := alerts.ParseConfig(raw)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.alerts: %w", err)
+	}
+	// No else needed: early return pattern (no rules)
+	if len(cfg.Rules) == 0 {
+		return nil, nil
 	}
 
-	// Stop admin rate limiter cleanup
-	// No else needed: optional operation (cleanup stop)
-	if globalAdminLimiter != nil {
-		globalAdminLimiter.StopCleanup()
+	sources := map[string]alerts.Source{
+		alerts.MetricErrorRate: alerts.ErrorRate(metrics.LLMTotals),
+		alerts.MetricQueueLength: func(ctx context.Context) (float64, error) {
+			return float64(len(sessionManager.HelpQueue(0))), nil
+		},
+		alerts.MetricTokensPerDay: func(ctx context.Context) (float64, error) {
+			now := time.Now()
+			groups, err := storageService.GetCosts(now.Add(-constants.AlertTokensWindow), now, constants.CostGroupByTenant)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				return 0, err
+			}
+			var tokens int64
+			for _, group := range groups {
+				tokens += group.Tokens
+			}
+			return float64(tokens), nil
+		},
 	}
 
-	// Stop public rate limiter cleanup
-	if globalPublicLimiter != nil {
-		globalPublicLimiter.StopCleanup()
+	monitor, err := alerts.NewMonitor(cfg, sources, publisher, logger)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.alerts: %w", err)
 	}
+	logger.Info("Usage alerts enabled",
+		"rules", len(cfg.Rules),
+		"interval", cfg.Interval)
+	return monitor, nil
+}
 
-	// Close all WebSocket connections with context deadline
-	// No else needed: optional operation (WebSocket shutdown with error handling)
-	if globalWSHandler != nil {
-		// No else needed: early return pattern (guard clause)
-		if err := globalWSHandler.ShutdownWithContext(ctx); err != nil {
-			// No else needed: optional operation (error logging)
-			if globalLogger != nil {
-				globalLogger.Warn("WebSocket handler shutdown error", "error", err)
-			}
-			return err
+// sessionMetricSamples converts session metrics into remote write samples
+// named chatbox_report_<metric>, labeled with job, window and, for tag counts, tag
+func sessionMetricSamples(m *storage.Metrics, job, window string) []remotewrite.Sample {
+	values := sessionMetricValues(m)
+	samples := make([]remotewrite.Sample, 0, len(values))
+	for _, v := range values {
+		labels := map[string]string{"job": job, "window": window}
+		// No else needed: optional operation (per-tag counts)
+		if v.Tag != "" {
+			labels["tag"] = v.Tag
 		}
+		samples = append(samples, remotewrite.Sample{Name: "chatbox_report_" + v.Name, Labels: labels, Value: v.Value})
 	}
+	return samples
+}
 
-	// Flush logs
-	// No else needed: optional operation (final logging)
-	if globalLogger != nil {
-		globalLogger.Info("Chatbox service shutdown complete")
-		// Note: Logger.Close() should be called by gomain, not here
+// configStringList reads an optional array of strings from configuration.
+// Returns nil when the key is not set.
+func configStringList(config *goconfig.ConfigAccessor, key string) ([]string, error) {
+	raw, err := config.Config(key)
+	// No else needed: early return pattern (key not set)
+	if err != nil || raw == nil {
+		return nil, nil
 	}
-
-	return nil
+	items, ok := raw.([]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an array", key)
+	}
+	values := make([]string, 0, len(items))
+	for i, item := range items {
+		value, ok := item.(string)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] is not a string", key, i)
+		}
+		values = append(values, value)
+	}
+	return values, nil
 }
 
 // validateJWTSecret validates the JWT secret strength
@@ -1428,6 +8652,127 @@ func metricsNetworkMiddleware(allowedNets []*net.IPNet, logger *golog.Logger) gi
 	}
 }
 
+// loadAdminRateLimit reads the window and limit of the global admin rate limit
+func loadAdminRateLimit(config *goconfig.ConfigAccessor) (time.Duration, int, error) {
+	adminRateLimit, err := config.ConfigIntWithDefault("chatbox.admin_rate_limit", constants.DefaultAdminRateLimit)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get admin rate limit: %w", err)
+	}
+	adminRateWindowStr, err := config.ConfigStringWithDefault("chatbox.admin_rate_window", constants.DefaultRateWindow.String())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get admin rate window: %w", err)
+	}
+	adminRateWindow, err := time.ParseDuration(adminRateWindowStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid admin rate window format: %w", err)
+	}
+	return adminRateWindow, adminRateLimit, nil
+}
+
+// loadGuestRateLimit reads the messages per minute allowed to each guest
+func loadGuestRateLimit(config *goconfig.ConfigAccessor) (int, error) {
+	guestRateLimit, err := config.ConfigIntWithDefault("chatbox.guest_rate_limit", constants.DefaultGuestRateLimit)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get guest rate limit: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if guestRateLimit <= 0 {
+		return 0, fmt.Errorf("chatbox.guest_rate_limit must be positive")
+	}
+	return guestRateLimit, nil
+}
+
+// loadCORSOrigins reads the comma-separated origins allowed to make
+// cross-origin HTTP requests. Returns nil when none are configured, which
+// disables CORS.
+func loadCORSOrigins(config *goconfig.ConfigAccessor) ([]string, error) {
+	corsOriginsStr, err := config.ConfigStringWithDefault("chatbox.cors_allowed_origins", "")
+	// No else needed: early return pattern (not configured)
+	if err != nil || corsOriginsStr == "" {
+		return nil, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if containsPlaceholder(corsOriginsStr) {
+		return nil, fmt.Errorf("chatbox.cors_allowed_origins contains placeholder value %q — set actual origins before deploying", corsOriginsStr)
+	}
+	origins := strings.Split(corsOriginsStr, ",")
+	for i, origin := range origins {
+		origins[i] = strings.TrimSpace(origin)
+	}
+	return origins, nil
+}
+
+// loadAllowedOrigins reads the comma-separated origins allowed to open WebSocket
+// connections. Returns nil when none are configured, which allows all origins.
+func loadAllowedOrigins(config *goconfig.ConfigAccessor) ([]string, error) {
+	allowedOriginsStr, err := config.ConfigStringWithDefault("chatbox.allowed_origins", "")
+	// No else needed: early return pattern (not configured)
+	if err != nil || allowedOriginsStr == "" {
+		return nil, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if containsPlaceholder(allowedOriginsStr) {
+		return nil, fmt.Errorf("chatbox.allowed_origins contains placeholder value %q — set actual origins before deploying", allowedOriginsStr)
+	}
+	origins := strings.Split(allowedOriginsStr, ",")
+	for i, origin := range origins {
+		origins[i] = strings.TrimSpace(origin)
+	}
+	return origins, nil
+}
+
+// loadAnonymizationSalt reads the optional secret keying the user ID hashes
+// of anonymized mode (see storage.SetAnonymizationSalt)
+// Priority: Environment variable > Config file
+func loadAnonymizationSalt(config *goconfig.ConfigAccessor) ([]byte, error) {
+	salt := os.Getenv("CHATBOX_ANONYMIZATION_SALT")
+	// No else needed: optional operation (fall back to the config file)
+	if salt == "" {
+		salt, _ = config.ConfigStringWithDefault("chatbox.anonymization_salt", "")
+	}
+	// No else needed: early return pattern (not configured)
+	if salt == "" {
+		return nil, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if containsPlaceholder(salt) {
+		return nil, fmt.Errorf("chatbox.anonymization_salt contains a placeholder value — set a random secret before deploying")
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(salt) < constants.MinAnonymizationSaltLength {
+		return nil, fmt.Errorf("chatbox.anonymization_salt must be at least %d bytes", constants.MinAnonymizationSaltLength)
+	}
+	return []byte(salt), nil
+}
+
+// loadAnonymizedTenants reads the tenants whose data is anonymized (see
+// storage.SetAnonymizedTenants) from chatbox.anonymized_tenants, a comma-separated
+// list of tenant IDs
+// Priority: Environment variable > Config file
+func loadAnonymizedTenants(config *goconfig.ConfigAccessor) []string {
+	tenantsStr, err := config.ConfigStringWithDefault("chatbox.anonymized_tenants", "")
+	// No else needed: optional operation (environment override)
+	if envTenants := os.Getenv("CHATBOX_ANONYMIZED_TENANTS"); envTenants != "" {
+		tenantsStr, err = envTenants, nil
+	}
+	// No else needed: early return pattern (not configured)
+	if err != nil || strings.TrimSpace(tenantsStr) == "" {
+		return nil
+	}
+	var tenants []string
+	for _, tenantID := range strings.Split(tenantsStr, ",") {
+		// No else needed: optional operation (skip empty entries)
+		if tenantID = strings.TrimSpace(tenantID); tenantID != "" {
+			tenants = append(tenants, tenantID)
+		}
+	}
+	return tenants
+}
+
 // containsPlaceholder checks if a configuration value still contains
 // a deployment placeholder that should have been replaced.
 func containsPlaceholder(value string) bool {
@@ -1438,3 +8783,253 @@ func containsPlaceholder(value string) bool {
 		strings.Contains(upper, "CHANGE_ME") ||
 		strings.Contains(upper, "YOUR-")
 }
+
+// newRateLimitRedisClient connects to Redis when the "redis" rate limit backend is
+// configured, so per-user message and admin limits are shared across replicas.
+// Returns nil for the default "memory" backend.
+// Priority: Environment variables > Config file
+func newRateLimitRedisClient(config *goconfig.ConfigAccessor, logger *golog.Logger) (*redis.Client, error) {
+	backend, err := config.ConfigStringWithDefault("chatbox.rate_limit_backend", constants.RateLimitBackendMemory)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limit backend: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envBackend := os.Getenv("CHATBOX_RATE_LIMIT_BACKEND"); envBackend != "" {
+		backend = envBackend
+	}
+
+	// No else needed: early return pattern (default in-memory limiters)
+	if backend == constants.RateLimitBackendMemory {
+		return nil, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if backend != constants.RateLimitBackendRedis {
+		return nil, fmt.Errorf("invalid chatbox.rate_limit_backend %q: must be %q or %q", backend, constants.RateLimitBackendMemory, constants.RateLimitBackendRedis)
+	}
+
+	// Fail fast on a misconfigured Redis; at runtime the limiters fail open instead
+	client, err := dialRedis(config, fmt.Sprintf("rate_limit_backend is %q", constants.RateLimitBackendRedis))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := client.Options()
+	logger.Info("Redis rate limit backend configured", "addr", opts.Addr, "db", opts.DB)
+	return client, nil
+}
+
+// dialRedis connects to the Redis at chatbox.redis_url and checks it responds.
+// requiredBy names the setting that needs Redis, for the missing URL error.
+func dialRedis(config *goconfig.ConfigAccessor, requiredBy string) (*redis.Client, error) {
+	redisURL, err := config.ConfigStringWithDefault("chatbox.redis_url", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redis URL: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envURL := os.Getenv("CHATBOX_REDIS_URL"); envURL != "" {
+		redisURL = envURL
+	}
+	// No else needed: early return pattern (guard clause)
+	if redisURL == "" {
+		return nil, fmt.Errorf("chatbox.redis_url is required when %s", requiredBy)
+	}
+
+	opts, err := redis.ParseURL(redisURL)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatbox.redis_url: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := util.NewTimeoutContext(constants.ShortTimeout)
+	defer cancel()
+	// No else needed: early return pattern (guard clause)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return client, nil
+}
+
+// newJobScheduler creates the scheduler of the background jobs: the session
+// retention purge when retention is set, and the metrics rollups when enabled.
+// Schedules are read from chatbox.jobs. Replicas share locks so each run
+// happens on one replica, and runs are recorded for GET /admin/jobs.
+func newJobScheduler(config *goconfig.ConfigAccessor, storageService *storage.StorageService, retention, grace time.Duration, metricsRollup bool, logger *golog.Logger) (*jobs.Scheduler, error) {
+	// Priority: Environment variable > Config file
+	retentionSchedule, err := config.ConfigStringWithDefault("chatbox.jobs.retention_purge", constants.DefaultRetentionPurgeSchedule)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention purge schedule: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envSchedule := os.Getenv("CHATBOX_JOBS_RETENTION_PURGE"); envSchedule != "" {
+		retentionSchedule = envSchedule
+	}
+	rollupSchedule, err := config.ConfigStringWithDefault("chatbox.jobs.metrics_rollup", constants.DefaultMetricsRollupSchedule)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics rollup schedule: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envSchedule := os.Getenv("CHATBOX_JOBS_METRICS_ROLLUP"); envSchedule != "" {
+		rollupSchedule = envSchedule
+	}
+
+	// The replica name in locks and run history: the pod name when set
+	owner := os.Getenv("CHATBOX_POD_ID")
+	// No else needed: conditional assignment (fall back to the host name)
+	if owner == "" {
+		owner, _ = os.Hostname()
+	}
+	// No else needed: conditional assignment (no host name)
+	if owner == "" {
+		owner = cluster.RandomPodID()
+	}
+
+	scheduler := jobs.NewScheduler(storageService, owner, logger)
+
+	// No else needed: optional operation (retention only when configured)
+	if retention > 0 {
+		storageService.SetRestoreGrace(grace)
+		err := scheduler.Add(jobs.Job{
+			Name:     constants.JobRetentionPurge,
+			Schedule: retentionSchedule,
+			Run: func(context.Context) error {
+				_, _, err := storageService.PurgeExpiredSessions(time.Now(), retention, grace)
+				return err
+			},
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chatbox.jobs.retention_purge: %w", err)
+		}
+	}
+	// No else needed: optional operation (rollups only when enabled)
+	if metricsRollup {
+		err := scheduler.Add(jobs.Job{
+			Name:     constants.JobMetricsRollup,
+			Schedule: rollupSchedule,
+			Run: func(context.Context) error {
+				return storageService.RollupMetrics(time.Now())
+			},
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chatbox.jobs.metrics_rollup: %w", err)
+		}
+	}
+	return scheduler, nil
+}
+
+// loadClusterBridge creates the bridge that routes WebSocket traffic between
+// replicas when chatbox.cluster.enabled is set, sharing the rate limit Redis
+// client when there is one. Returns the Redis client it dialed itself, if any,
+// for the caller to close. Returns a nil bridge when disabled.
+func loadClusterBridge(config *goconfig.ConfigAccessor, redisClient *redis.Client, logger *golog.Logger) (*cluster.Bridge, *redis.Client, error) {
+	enabled, err := config.ConfigBoolWithDefault("chatbox.cluster.enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get cluster enabled: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEnabled := os.Getenv("CHATBOX_CLUSTER_ENABLED"); envEnabled != "" {
+		enabled = envEnabled == "true"
+	}
+	// No else needed: early return pattern (single replica)
+	if !enabled {
+		return nil, nil, nil
+	}
+
+	podID, err := config.ConfigStringWithDefault("chatbox.cluster.pod_id", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get cluster pod ID: %w", err)
+	}
+	// No else needed: optional operation (environment override, e.g. the Kubernetes pod name)
+	if envPodID := os.Getenv("CHATBOX_POD_ID"); envPodID != "" {
+		podID = envPodID
+	}
+	// No else needed: conditional assignment (a restarted replica gets a new ID)
+	if podID == "" {
+		podID = cluster.RandomPodID()
+	}
+
+	var dialed *redis.Client
+	// No else needed: conditional assignment (reuse the rate limit client when configured)
+	if redisClient == nil {
+		dialed, err = dialRedis(config, "chatbox.cluster.enabled is set")
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, nil, err
+		}
+		redisClient = dialed
+	}
+
+	logger.Info("Cluster routing enabled", "pod_id", podID, "ownership_ttl", constants.ClusterOwnershipTTL)
+	return cluster.NewBridge(redisClient, podID, constants.ClusterOwnershipTTL, logger), dialed, nil
+}
+
+// newTracerProvider sets up OpenTelemetry tracing from configuration.
+// Returns nil when tracing is disabled.
+// Priority: Environment variables > Config file
+func newTracerProvider(config *goconfig.ConfigAccessor, logger *golog.Logger) (*sdktrace.TracerProvider, error) {
+	enabled, err := config.ConfigBoolWithDefault("chatbox.tracing_enabled", false)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracing enabled setting: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEnabled := os.Getenv("CHATBOX_TRACING_ENABLED"); envEnabled != "" {
+		enabled = envEnabled == "true"
+	}
+	// No else needed: early return pattern (tracing disabled)
+	if !enabled {
+		return nil, nil
+	}
+
+	endpoint, err := config.ConfigStringWithDefault("chatbox.tracing_endpoint", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracing endpoint: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envEndpoint := os.Getenv("CHATBOX_TRACING_ENDPOINT"); envEndpoint != "" {
+		endpoint = envEndpoint
+	}
+
+	ratioStr, err := config.ConfigStringWithDefault("chatbox.tracing_sample_ratio", "")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracing sample ratio: %w", err)
+	}
+	// No else needed: optional operation (environment override)
+	if envRatio := os.Getenv("CHATBOX_TRACING_SAMPLE_RATIO"); envRatio != "" {
+		ratioStr = envRatio
+	}
+	sampleRatio := constants.DefaultTracingSampleRatio
+	// No else needed: optional operation (default ratio when unset)
+	if ratioStr != "" {
+		sampleRatio, err = strconv.ParseFloat(ratioStr, 64)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chatbox.tracing_sample_ratio %q: %w", ratioStr, err)
+		}
+	}
+
+	provider, err := tracing.Init(context.Background(), tracing.Config{
+		Endpoint:    endpoint,
+		ServiceName: constants.DefaultTracingServiceName,
+		SampleRatio: sampleRatio,
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("OpenTelemetry tracing enabled", "endpoint", endpoint, "sample_ratio", sampleRatio)
+	return provider, nil
+}
@@ -0,0 +1,47 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/requestid"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogMiddleware_PropagatesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	router := gin.New()
+	router.Use(requestid.Middleware())
+	router.Use(accessLogMiddleware(logger))
+	router.GET("/sessions", func(c *gin.Context) {
+		c.Set("claims", createMockJWTClaims("user-1", "User", []string{"user"}))
+		c.String(http.StatusOK, requestid.FromContext(c.Request.Context()))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+	req.Header.Set(constants.HeaderRequestID, "edge-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "edge-123", w.Header().Get(constants.HeaderRequestID))
+	assert.Equal(t, "edge-123", w.Body.String())
+
+	// Unmatched routes are logged too and still get an ID
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotEmpty(t, w.Header().Get(constants.HeaderRequestID))
+}
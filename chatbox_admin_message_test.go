@@ -0,0 +1,79 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/router"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/require"
+)
+
+// newAdminMessageContext builds a gin context for an admin message request with a JSON body
+func newAdminMessageContext(sessionID, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	claims := createMockJWTClaims("admin1", "Admin User", []string{"admin"})
+	c, w := createTestHTTPRequest("POST", "/admin/sessions/"+sessionID+"/messages", claims)
+	c.Request, _ = http.NewRequest("POST", "/admin/sessions/"+sessionID+"/messages", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{gin.Param{Key: "sessionID", Value: sessionID}}
+	return c, w
+}
+
+func TestHandleAdminSendMessage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger, err := golog.InitLog(golog.LogConfig{
+		Level:          "error",
+		StandardOutput: false,
+		Dir:            "/tmp",
+	})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	sessionManager := session.NewSessionManager(30*time.Second, logger)
+	testSession, err := sessionManager.CreateSession("user123")
+	require.NoError(t, err)
+
+	messageRouter := router.NewMessageRouter(sessionManager, nil, nil, nil, nil, 30*time.Second, logger)
+	defer messageRouter.Shutdown()
+	handler := handleAdminSendMessage(messageRouter, logger)
+
+	t.Run("success", func(t *testing.T) {
+		c, w := newAdminMessageContext(testSession.ID, `{"content":"Hello from support"}`)
+		handler(c)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Contains(t, w.Body.String(), `"session_id":"`+testSession.ID+`"`)
+		require.Contains(t, w.Body.String(), `"content":"Hello from support"`)
+	})
+
+	t.Run("empty content", func(t *testing.T) {
+		c, w := newAdminMessageContext(testSession.ID, `{"content":""}`)
+		handler(c)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		c, w := newAdminMessageContext(testSession.ID, `not json`)
+		handler(c)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		c, w := newAdminMessageContext("no-such-session", `{"content":"hi"}`)
+		handler(c)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("missing claims", func(t *testing.T) {
+		c, w := createTestHTTPRequest("POST", "/admin/sessions/"+testSession.ID+"/messages", nil)
+		c.Params = gin.Params{gin.Param{Key: "sessionID", Value: testSession.ID}}
+		handler(c)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,70 @@
+package chatbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/alerts"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleListAlerts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	monitor, err := alerts.NewMonitor(alerts.Config{Rules: []alerts.Rule{
+		{Name: "queue", Metric: alerts.MetricQueueLength, Threshold: 2},
+		{Name: "errors", Metric: alerts.MetricErrorRate, Threshold: 10},
+	}}, map[string]alerts.Source{
+		alerts.MetricQueueLength: func(ctx context.Context) (float64, error) { return 3, nil },
+		alerts.MetricErrorRate:   func(ctx context.Context) (float64, error) { return 0, nil },
+	}, nil, logger)
+	require.NoError(t, err)
+	monitor.Evaluate(context.Background(), time.Now())
+
+	get := func(monitor *alerts.Monitor, claims *auth.Claims) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/admin/alerts", func(c *gin.Context) {
+			c.Set("claims", claims)
+		}, handleListAlerts(monitor))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/alerts", nil))
+		return w
+	}
+	root := createMockJWTClaims("root", "Root", []string{constants.RoleSuperAdmin})
+
+	assert.Equal(t, http.StatusForbidden, get(monitor, createMockJWTClaims("admin-1", "Admin", []string{constants.RoleAdmin})).Code)
+
+	w := get(monitor, root)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Alerts []alerts.Alert `json:"alerts"`
+		Firing int            `json:"firing"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Firing)
+	require.Len(t, resp.Alerts, 2)
+	assert.Equal(t, "queue", resp.Alerts[0].Rule)
+	assert.Equal(t, alerts.StateFiring, resp.Alerts[0].State)
+	assert.Equal(t, alerts.StateOK, resp.Alerts[1].State)
+
+	// Without rules the list is empty
+	w = get(nil, root)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"alerts": [], "firing": 0}`, w.Body.String())
+}
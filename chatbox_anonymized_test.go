@@ -0,0 +1,101 @@
+package chatbox
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/goconfig"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAnonymizationSettings(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	t.Setenv("RMBASE_FILE_CFG", configPath)
+	t.Cleanup(func() { goconfig.ResetConfig() })
+	load := func(content string) *goconfig.ConfigAccessor {
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+		config, err := loadConfiguration()
+		require.NoError(t, err)
+		return config
+	}
+
+	config := load(`
+[chatbox]
+anonymized_tenants = " acme, ,globex "
+anonymization_salt = "a-long-enough-secret"
+`)
+	assert.Equal(t, []string{"acme", "globex"}, loadAnonymizedTenants(config))
+	salt, err := loadAnonymizationSalt(config)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a-long-enough-secret"), salt)
+
+	t.Setenv("CHATBOX_ANONYMIZED_TENANTS", "initech")
+	assert.Equal(t, []string{"initech"}, loadAnonymizedTenants(config), "the environment overrides the config file")
+
+	config = load(`
+[chatbox]
+anonymization_salt = "short"
+`)
+	_, err = loadAnonymizationSalt(config)
+	assert.Error(t, err)
+
+	config = load(`
+[chatbox]
+anonymization_salt = "REPLACE_WITH_RANDOM_SECRET"
+`)
+	_, err = loadAnonymizationSalt(config)
+	assert.Error(t, err)
+
+	config = load(`
+[chatbox]
+`)
+	salt, err = loadAnonymizationSalt(config)
+	require.NoError(t, err)
+	assert.Nil(t, salt)
+}
+
+func TestOwnerHandlers_AnonymizedTenant(t *testing.T) {
+	storageService, cleanup := setupTestStorage(t)
+	defer cleanup()
+	storageService.SetAnonymizationSalt([]byte("test-anonymization-salt"))
+	require.NoError(t, storageService.SetAnonymizedTenants([]string{"acme"}))
+	require.NoError(t, storageService.CreateSession(&session.Session{
+		ID:        "anonymized-session-1",
+		UserID:    "user-1",
+		TenantID:  "acme",
+		StartTime: time.Now(),
+	}))
+	stored, err := storageService.GetSession("anonymized-session-1")
+	require.NoError(t, err)
+	require.NotEqual(t, "user-1", stored.UserID, "the tenant's user IDs are stored hashed")
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: t.TempDir()})
+	require.NoError(t, err)
+	defer logger.Close()
+	sessionManager := session.NewSessionManager(15*time.Minute, logger)
+
+	call := func(handler gin.HandlerFunc, userID string) int {
+		claims := createMockJWTClaims(userID, "User", []string{"user"})
+		claims.TenantID = "acme"
+		c, w := createTestHTTPRequest("POST", "/sessions/anonymized-session-1", claims)
+		c.Params = gin.Params{gin.Param{Key: "sessionID", Value: "anonymized-session-1"}}
+		handler(c)
+		return w.Code
+	}
+
+	// The owner is matched by the tenant's hash, other users are not
+	revoke := handleRevokeShare(storageService, logger)
+	assert.Equal(t, http.StatusNotFound, call(revoke, "user-2"))
+	assert.Equal(t, http.StatusOK, call(revoke, "user-1"))
+
+	end := handleEndSession(storageService, sessionManager, nil, nil, logger)
+	assert.Equal(t, http.StatusNotFound, call(end, "user-2"))
+	assert.Equal(t, http.StatusOK, call(end, "user-1"))
+}
@@ -0,0 +1,90 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/apikey"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	secret := "V4l1d-JWT-K3y-F0r-T3st1ng-Purp0ses-1!"
+	validator := auth.NewJWTValidator(secret)
+	policy := authz.Default()
+	registry, err := apikey.NewRegistry([]*apikey.Key{
+		{Name: "reporting", Hash: apikey.Hash("read-key"), Scope: apikey.ScopeRead, TenantID: "acme"},
+		{Name: "ops", Hash: apikey.Hash("admin-key"), Scope: apikey.ScopeAdmin},
+	})
+	require.NoError(t, err)
+
+	var caller *auth.Claims
+	router := gin.New()
+	admin := router.Group("/admin")
+	admin.Use(apiKeyMiddleware(registry, logger))
+	admin.Use(authMiddleware(validator, policy, logger))
+	ok := func(c *gin.Context) {
+		value, _ := c.Get("claims")
+		caller, _ = value.(*auth.Claims)
+		c.Status(http.StatusOK)
+	}
+	admin.GET("/sessions", permissionMiddleware(policy, authz.PermViewSessions, logger), ok)
+	admin.POST("/drain", permissionMiddleware(policy, authz.PermBroadcast, logger), ok)
+
+	call := func(method, path, apiKey, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if apiKey != "" {
+			req.Header.Set(constants.HeaderAPIKey, apiKey)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Read-only keys can only read, scoped to their tenant
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/admin/sessions", "read-key", ""))
+	require.NotNil(t, caller)
+	assert.Equal(t, apikey.UserIDPrefix+"reporting", caller.UserID)
+	assert.Equal(t, "acme", caller.TenantID)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/admin/drain", "read-key", ""))
+
+	// Admin keys can act
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/admin/drain", "admin-key", ""))
+
+	// Unknown keys are rejected even with a valid JWT
+	token := createTestJWT(t, secret, "admin-user", []string{constants.RoleAdmin})
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/admin/sessions", "wrong-key", token))
+
+	// Without a key, JWTs work as before
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/admin/sessions", "", token))
+	assert.Equal(t, "admin-user", caller.UserID)
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/admin/sessions", "", ""))
+
+	// Without configured keys the header is ignored
+	plain := gin.New()
+	plain.GET("/admin/sessions", apiKeyMiddleware(nil, logger), authMiddleware(validator, policy, logger), ok)
+	req := httptest.NewRequest(http.MethodGet, "/admin/sessions", nil)
+	req.Header.Set(constants.HeaderAPIKey, "read-key")
+	w := httptest.NewRecorder()
+	plain.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,87 @@
+package chatbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/backup"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingSource exports nothing once release is closed
+type blockingSource struct {
+	release chan struct{}
+}
+
+func (b *blockingSource) ExportSessions(ctx context.Context, fn func(doc *storage.SessionDocument) error) error {
+	select {
+	case <-b.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestHandleBackups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	target, err := backup.NewDirTarget(t.TempDir())
+	require.NoError(t, err)
+	source := &blockingSource{release: make(chan struct{})}
+	runner, err := backup.NewRunner(source, target, []byte("0123456789abcdef0123456789abcdef"), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		close(source.release)
+		_ = runner.Shutdown(context.Background())
+	})
+
+	serve := func(method string, claims *auth.Claims) *httptest.ResponseRecorder {
+		router := gin.New()
+		setClaims := func(c *gin.Context) { c.Set("claims", claims) }
+		router.GET("/admin/backups", setClaims, handleListBackups(runner))
+		router.POST("/admin/backups", setClaims, handleStartBackup(runner, logger))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/admin/backups", nil))
+		return w
+	}
+
+	admin := createMockJWTClaims("admin-1", "Admin", []string{constants.RoleAdmin})
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, admin).Code, "backups cover every tenant")
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, admin).Code)
+
+	root := createMockJWTClaims("root", "Root", []string{constants.RoleSuperAdmin})
+	w := serve(http.MethodPost, root)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var job backup.Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, backup.JobRunning, job.State)
+	assert.Equal(t, "root", job.RequestedBy)
+
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, root).Code, "one backup at a time")
+
+	w = serve(http.MethodGet, root)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Backups []backup.Job `json:"backups"`
+		Count   int          `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, job.ID, resp.Backups[0].ID)
+}
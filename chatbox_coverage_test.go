@@ -14,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
 	"github.com/real-rm/chatbox/internal/constants"
 	"github.com/real-rm/chatbox/internal/ratelimit"
 	"github.com/real-rm/chatbox/internal/router"
@@ -418,7 +419,7 @@ func TestAuthMiddleware_MissingToken(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	// Add middleware to test endpoint
-	router.GET("/test", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/test", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
 
@@ -442,7 +443,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	// Add middleware to test endpoint
-	router.GET("/test", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/test", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
 
@@ -467,7 +468,7 @@ func TestAuthMiddleware_ExpiredToken_Coverage(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	// Add middleware to test endpoint
-	router.GET("/test", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/test", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
 
@@ -506,7 +507,7 @@ func TestAuthMiddleware_ValidTokenNoAdminRole(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	// Add middleware to test endpoint
-	router.GET("/test", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/test", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
 
@@ -534,7 +535,7 @@ func TestAuthMiddleware_ValidTokenWithAdminRole(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	// Add middleware to test endpoint
-	router.GET("/test", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/test", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
 
@@ -562,7 +563,7 @@ func TestAuthMiddleware_ValidTokenWithChatAdminRole(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	// Add middleware to test endpoint
-	router.GET("/test", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/test", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
 
@@ -690,7 +691,7 @@ func TestProperty_AuthenticationMiddlewareEnforcesAccessControl(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			router := gin.New()
-			router.GET("/test", authMiddleware(validator, logger), func(c *gin.Context) {
+			router.GET("/test", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "success"})
 			})
 
@@ -724,7 +725,7 @@ func TestRateLimitMiddleware_Enforcement(t *testing.T) {
 
 	router := gin.New()
 	router.GET("/test",
-		authMiddleware(validator, logger),
+		authMiddleware(validator, authz.Default(), logger),
 		adminRateLimitMiddleware(limiter, logger),
 		func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -777,7 +778,7 @@ func TestRateLimitMiddleware_ResetAfterWindow(t *testing.T) {
 
 	router := gin.New()
 	router.GET("/test",
-		authMiddleware(validator, logger),
+		authMiddleware(validator, authz.Default(), logger),
 		adminRateLimitMiddleware(limiter, logger),
 		func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -864,7 +865,7 @@ func TestRateLimitMiddleware_DifferentUsers(t *testing.T) {
 
 	router := gin.New()
 	router.GET("/test",
-		authMiddleware(validator, logger),
+		authMiddleware(validator, authz.Default(), logger),
 		adminRateLimitMiddleware(limiter, logger),
 		func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -956,7 +957,7 @@ func TestProperty_RateLimitingMiddlewareEnforcesLimits(t *testing.T) {
 
 			router := gin.New()
 			router.GET("/test",
-				authMiddleware(validator, logger),
+				authMiddleware(validator, authz.Default(), logger),
 				adminRateLimitMiddleware(limiter, logger),
 				func(c *gin.Context) {
 					c.JSON(http.StatusOK, gin.H{"message": "success"})
@@ -1010,7 +1011,7 @@ func TestAuthorizationMiddleware_AdminRole(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	router := gin.New()
-	router.GET("/admin", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/admin", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "admin access granted"})
 	})
 
@@ -1035,7 +1036,7 @@ func TestAuthorizationMiddleware_ChatAdminRole(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	router := gin.New()
-	router.GET("/admin", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/admin", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "admin access granted"})
 	})
 
@@ -1060,7 +1061,7 @@ func TestAuthorizationMiddleware_NonAdminRole(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	router := gin.New()
-	router.GET("/admin", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/admin", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "admin access granted"})
 	})
 
@@ -1085,7 +1086,7 @@ func TestAuthorizationMiddleware_EmptyRoles(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	router := gin.New()
-	router.GET("/admin", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/admin", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "admin access granted"})
 	})
 
@@ -1110,7 +1111,7 @@ func TestAuthorizationMiddleware_MultipleRolesWithAdmin(t *testing.T) {
 	validator := auth.NewJWTValidator(secret)
 
 	router := gin.New()
-	router.GET("/admin", authMiddleware(validator, logger), func(c *gin.Context) {
+	router.GET("/admin", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "admin access granted"})
 	})
 
@@ -1197,7 +1198,7 @@ func TestProperty_AuthorizationMiddlewareEnforcesRoles(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			router := gin.New()
-			router.GET("/admin", authMiddleware(validator, logger), func(c *gin.Context) {
+			router.GET("/admin", authMiddleware(validator, authz.Default(), logger), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "admin access granted"})
 			})
 
@@ -1250,7 +1251,7 @@ func TestHandleReadyCheck_Success(t *testing.T) {
 	mongo := setupTestMongo(t)
 
 	router := gin.New()
-	router.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+	router.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 
 	req := httptest.NewRequest("GET", "/readyz", nil)
 	w := httptest.NewRecorder()
@@ -1276,7 +1277,7 @@ func TestHandleReadyCheck_MongoNil(t *testing.T) {
 	logger := setupTestLogger(t)
 
 	router := gin.New()
-	router.GET("/readyz", handleReadyCheck(nil, nil, logger))
+	router.GET("/readyz", handleReadyCheck(readinessChecks(nil, nil, nil, ""), logger))
 
 	req := httptest.NewRequest("GET", "/readyz", nil)
 	w := httptest.NewRecorder()
@@ -1376,7 +1377,7 @@ func TestHandleListSessions_WithFilters(t *testing.T) {
 	sessionManager := session.NewSessionManager(30*time.Second, logger)
 
 	router := gin.New()
-	router.GET("/admin/sessions", authMiddleware(validator, logger), handleListSessions(storageService, sessionManager, logger))
+	router.GET("/admin/sessions", authMiddleware(validator, authz.Default(), logger), handleListSessions(storageService, sessionManager, logger))
 
 	// Create test sessions in storage
 	testSession := &session.Session{
@@ -1425,7 +1426,7 @@ func TestHandleListSessions_InvalidTimeFormat(t *testing.T) {
 	sessionManager := session.NewSessionManager(30*time.Second, logger)
 
 	router := gin.New()
-	router.GET("/admin/sessions", authMiddleware(validator, logger), handleListSessions(storageService, sessionManager, logger))
+	router.GET("/admin/sessions", authMiddleware(validator, authz.Default(), logger), handleListSessions(storageService, sessionManager, logger))
 
 	// Create admin token
 	token := createTestJWT(t, secret, "admin-user", []string{constants.RoleAdmin})
@@ -1452,7 +1453,7 @@ func TestHandleGetMetrics_Success(t *testing.T) {
 	storageService := storage.NewStorageService(mongo, "chat", "sessions", logger, nil)
 
 	router := gin.New()
-	router.GET("/admin/metrics", authMiddleware(validator, logger), handleGetMetrics(storageService, logger))
+	router.GET("/admin/metrics", authMiddleware(validator, authz.Default(), logger), handleGetMetrics(storageService, logger))
 
 	// Create admin token
 	token := createTestJWT(t, secret, "admin-user", []string{constants.RoleAdmin})
@@ -1491,7 +1492,7 @@ func TestHandleGetMetrics_InvalidTimeFormat(t *testing.T) {
 	storageService := storage.NewStorageService(mongo, "chat", "sessions", logger, nil)
 
 	router := gin.New()
-	router.GET("/admin/metrics", authMiddleware(validator, logger), handleGetMetrics(storageService, logger))
+	router.GET("/admin/metrics", authMiddleware(validator, authz.Default(), logger), handleGetMetrics(storageService, logger))
 
 	// Create admin token
 	token := createTestJWT(t, secret, "admin-user", []string{constants.RoleAdmin})
@@ -1507,6 +1508,75 @@ func TestHandleGetMetrics_InvalidTimeFormat(t *testing.T) {
 	}
 }
 
+// TestHandleGetMetrics_CSV tests the spreadsheet download of the metrics endpoint
+func TestHandleGetMetrics_CSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := setupTestLogger(t)
+	mongo := setupTestMongo(t)
+	secret := "V4l1d-JWT-K3y-F0r-T3st1ng-Purp0ses-1!"
+	validator := auth.NewJWTValidator(secret)
+
+	storageService := storage.NewStorageService(mongo, "chat", "sessions", logger, nil)
+
+	router := gin.New()
+	router.GET("/admin/metrics", authMiddleware(validator, authz.Default(), logger), handleGetMetrics(storageService, logger))
+
+	token := createTestJWT(t, secret, "admin-user", []string{constants.RoleAdmin})
+
+	req := httptest.NewRequest("GET", "/admin/metrics?format=csv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+		t.Errorf("Expected CSV content type, got %q", got)
+	}
+	if !strings.HasPrefix(w.Body.String(), "start_time,end_time,metric,tag,value\n") {
+		t.Errorf("Expected CSV header, got %q", w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), ",total_sessions,,") {
+		t.Errorf("Expected total_sessions row, got %q", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/admin/metrics?format=xml", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown format, got %d", w.Code)
+	}
+}
+
+// TestSessionMetricSamples tests the remote write series of session metrics
+func TestSessionMetricSamples(t *testing.T) {
+	samples := sessionMetricSamples(&storage.Metrics{
+		TotalSessions:   7,
+		TagCounts:       map[string]int{"billing": 2},
+		EndReasonCounts: map[string]int{"abuse": 1},
+	}, "chatbox", "24h")
+
+	byName := make(map[string]float64)
+	for _, s := range samples {
+		if s.Labels["job"] != "chatbox" || s.Labels["window"] != "24h" {
+			t.Errorf("Expected job and window labels on %s, got %v", s.Name, s.Labels)
+		}
+		byName[s.Name+"/"+s.Labels["tag"]] = s.Value
+	}
+	if byName["chatbox_report_total_sessions/"] != 7 {
+		t.Errorf("Expected chatbox_report_total_sessions 7, got %v", byName)
+	}
+	if byName["chatbox_report_tag_sessions/billing"] != 2 {
+		t.Errorf("Expected billing tag count 2, got %v", byName)
+	}
+	if byName["chatbox_report_force_ended_sessions/abuse"] != 1 {
+		t.Errorf("Expected abuse force-end count 1, got %v", byName)
+	}
+}
+
 // TestHandleAdminTakeover_Success tests admin takeover endpoint
 func TestHandleAdminTakeover_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -1521,7 +1591,7 @@ func TestHandleAdminTakeover_Success(t *testing.T) {
 	messageRouter := router.NewMessageRouter(sessionManager, nil, nil, nil, storageService, 30*time.Second, logger)
 
 	router := gin.New()
-	router.POST("/admin/takeover/:sessionID", authMiddleware(validator, logger), handleAdminTakeover(messageRouter, logger))
+	router.POST("/admin/takeover/:sessionID", authMiddleware(validator, authz.Default(), logger), handleAdminTakeover(messageRouter, logger))
 
 	// Create test session in storage and session manager
 	testSession := &session.Session{
@@ -1578,7 +1648,7 @@ func TestHandleAdminTakeover_MissingSessionID(t *testing.T) {
 	messageRouter := router.NewMessageRouter(sessionManager, nil, nil, nil, storageService, 30*time.Second, logger)
 
 	router := gin.New()
-	router.POST("/admin/takeover/:sessionID", authMiddleware(validator, logger), handleAdminTakeover(messageRouter, logger))
+	router.POST("/admin/takeover/:sessionID", authMiddleware(validator, authz.Default(), logger), handleAdminTakeover(messageRouter, logger))
 
 	// Create admin token
 	token := createTestJWT(t, secret, "admin-user", []string{constants.RoleAdmin})
@@ -1699,11 +1769,11 @@ func TestProperty_HTTPHandlersProcessValidRequests(t *testing.T) {
 
 			// Register endpoints
 			router.GET("/healthz", handleHealthCheck)
-			router.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+			router.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 			router.GET("/sessions", userAuthMiddleware(validator, logger), handleUserSessions(storageService, logger))
 
 			adminGroup := router.Group("/admin")
-			adminGroup.Use(authMiddleware(validator, logger))
+			adminGroup.Use(authMiddleware(validator, authz.Default(), logger))
 			{
 				adminGroup.GET("/sessions", handleListSessions(storageService, sessionManager, logger))
 				adminGroup.GET("/metrics", handleGetMetrics(storageService, logger))
@@ -2110,11 +2180,11 @@ func TestConcurrentHTTPRequests_DifferentEndpoints(t *testing.T) {
 
 	router := gin.New()
 	router.GET("/healthz", handleHealthCheck)
-	router.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+	router.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 	router.GET("/sessions", userAuthMiddleware(validator, logger), handleUserSessions(storageService, logger))
 
 	adminGroup := router.Group("/admin")
-	adminGroup.Use(authMiddleware(validator, logger))
+	adminGroup.Use(authMiddleware(validator, authz.Default(), logger))
 	{
 		adminGroup.GET("/sessions", handleListSessions(storageService, sessionManager, logger))
 		adminGroup.GET("/metrics", handleGetMetrics(storageService, logger))
@@ -2184,7 +2254,7 @@ func TestConcurrentHTTPRequests_WithRateLimiting(t *testing.T) {
 
 	router := gin.New()
 	adminGroup := router.Group("/admin")
-	adminGroup.Use(authMiddleware(validator, logger))
+	adminGroup.Use(authMiddleware(validator, authz.Default(), logger))
 	adminGroup.Use(adminRateLimitMiddleware(limiter, logger))
 	{
 		adminGroup.GET("/sessions", handleListSessions(storageService, sessionManager, logger))
@@ -2300,11 +2370,11 @@ func TestProperty_ConcurrentHTTPRequestsAreThreadSafe(t *testing.T) {
 	// Set up router with all endpoints
 	r := gin.New()
 	r.GET("/healthz", handleHealthCheck)
-	r.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+	r.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 	r.GET("/sessions", userAuthMiddleware(validator, logger), handleUserSessions(storageService, logger))
 
 	adminGroup := r.Group("/admin")
-	adminGroup.Use(authMiddleware(validator, logger))
+	adminGroup.Use(authMiddleware(validator, authz.Default(), logger))
 	adminGroup.Use(adminRateLimitMiddleware(limiter, logger))
 	{
 		adminGroup.GET("/sessions", handleListSessions(storageService, sessionManager, logger))
@@ -2777,7 +2847,7 @@ connectTimeout = "100ms"
 		// If initialization fails, we still test the nil path
 		t.Logf("MongoDB initialization failed: %v", err)
 		router := gin.New()
-		router.GET("/readyz", handleReadyCheck(nil, nil, logger))
+		router.GET("/readyz", handleReadyCheck(readinessChecks(nil, nil, nil, ""), logger))
 
 		req := httptest.NewRequest("GET", "/readyz", nil)
 		w := httptest.NewRecorder()
@@ -2792,7 +2862,7 @@ connectTimeout = "100ms"
 	// If initialization succeeded (lazy connection), test the ping failure
 	t.Log("MongoDB initialized, testing ping failure")
 	router := gin.New()
-	router.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+	router.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 
 	req := httptest.NewRequest("GET", "/readyz", nil)
 	w := httptest.NewRecorder()
@@ -0,0 +1,92 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/embed"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleEmbedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	validator := auth.NewJWTValidator("test-secret")
+	registry := embed.NewRegistry([]*embed.Origin{{
+		Name:          "shop",
+		Origin:        "https://shop.example.com",
+		APIKey:        "shop-key",
+		TenantID:      "tenant-shop",
+		TokenTTL:      5 * time.Minute,
+		TokensPerHour: 2,
+	}}, nil)
+
+	router := gin.New()
+	router.POST("/embed/token", handleEmbedToken(registry, validator, logger))
+	router.GET("/sessions", userAuthMiddleware(validator, logger), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	mint := func(apiKey, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/embed/token", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, mint("", `{"origin": "https://shop.example.com"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, mint("wrong-key", `{"origin": "https://shop.example.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, mint("shop-key", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, mint("shop-key", `{"origin": "https://shop.example.com", "user_id": "`+strings.Repeat("x", 129)+`"}`).Code)
+
+	w := mint("shop-key", `{"origin": "https://shop.example.com", "user_id": "42"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Token  string `json:"token"`
+		UserID string `json:"user_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, auth.EmbedIDPrefix+"shop:42", resp.UserID)
+
+	claims, err := validator.ValidateToken(resp.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-shop", claims.TenantID)
+	assert.Equal(t, []string{constants.RoleEmbed}, claims.Roles)
+
+	// The token is only accepted from the partner's origin
+	call := func(origin string) int {
+		req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+resp.Token)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, call("https://shop.example.com"))
+	assert.Equal(t, http.StatusUnauthorized, call("https://evil.example.com"))
+
+	// The origin's hourly quota is 2 tokens
+	require.Equal(t, http.StatusOK, mint("shop-key", `{"origin": "https://shop.example.com"}`).Code)
+	w = mint("shop-key", `{"origin": "https://shop.example.com"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get(constants.HeaderRetryAfter))
+}
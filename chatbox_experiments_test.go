@@ -0,0 +1,85 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/experiment"
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleExperiments(t *testing.T) {
+	storageService, cleanup := setupTestStorageWithData(t, nil)
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	prompts := prompt.NewService([]*prompt.Template{
+		{ID: "support", Name: "Support", Content: "Help", ReadOnly: true},
+		{ID: "brief", Name: "Brief", Content: "Be brief", ReadOnly: true},
+	}, nil)
+	experiments := experiment.NewService([]*experiment.Experiment{
+		{ID: "models", Name: "Models", Kind: experiment.KindModel, Active: true, ReadOnly: true, Variants: []experiment.Variant{
+			{Name: "a", Value: "gpt-4", Weight: 1},
+			{Name: "b", Value: "claude-3", Weight: 1},
+		}},
+	}, storageService.TenantExperiments)
+	claims := createMockJWTClaims("admin-1", "Admin", []string{"admin"})
+	call := func(handler gin.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		c, w := createTestHTTPRequest(method, "/admin/experiments/"+id, claims)
+		c.Request = httptest.NewRequest(method, "/admin/experiments/"+id, strings.NewReader(body))
+		c.Params = gin.Params{gin.Param{Key: "experimentID", Value: id}}
+		handler(c)
+		return w
+	}
+	create := handleCreateExperiment(experiments, storageService, nil, prompts, logger)
+
+	// Invalid experiments and unknown variant values are rejected
+	assert.Equal(t, http.StatusBadRequest, call(create, "POST", "", `{"name": "One", "kind": "prompt", "variants": [{"name": "a", "value": "support", "weight": 1}]}`).Code)
+	w := call(create, "POST", "", `{"name": "Missing", "kind": "prompt", "variants": [{"name": "a", "value": "support", "weight": 1}, {"name": "b", "value": "missing", "weight": 1}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "missing")
+	w = call(create, "POST", "", `{"name": "Models", "kind": "model", "variants": [{"name": "a", "value": "gpt-4", "weight": 1}, {"name": "b", "value": "claude-3", "weight": 1}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "model experiments need the LLM")
+
+	w = call(create, "POST", "", `{"name": "Prompts", "kind": "prompt", "variants": [{"name": "a", "value": "support", "weight": 3}, {"name": "b", "value": "brief", "weight": 1}]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created experiment.Experiment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, created.Active)
+	defer func() { _ = storageService.DeleteExperiment(created.ID) }()
+
+	// New sessions are assigned the stored experiment right away on this instance
+	assignments, err := experiments.Assign("", "user-1")
+	require.NoError(t, err)
+	require.Len(t, assignments, 2)
+	assert.Equal(t, created.ID, assignments[1].ExperimentID)
+
+	w = call(handleListExperiments(experiments, storageService, logger), "GET", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"models"`)
+	assert.Contains(t, w.Body.String(), created.ID)
+
+	w = call(handleGetExperimentMetrics(experiments, storageService, logger), "GET", created.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"variants":[]`)
+	assert.Equal(t, http.StatusNotFound, call(handleGetExperimentMetrics(experiments, storageService, logger), "GET", "missing", "").Code)
+
+	// Config experiments are read-only
+	assert.Equal(t, http.StatusBadRequest, call(handleDeleteExperiment(experiments, storageService, logger), "DELETE", "models", "").Code)
+
+	require.Equal(t, http.StatusOK, call(handleDeleteExperiment(experiments, storageService, logger), "DELETE", created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, call(handleDeleteExperiment(experiments, storageService, logger), "DELETE", created.ID, "").Code)
+	assignments, err = experiments.Assign("", "user-1")
+	require.NoError(t, err)
+	assert.Len(t, assignments, 1)
+}
@@ -0,0 +1,69 @@
+package chatbox
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleExportSession_InvalidFormat(t *testing.T) {
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	claims := createMockJWTClaims("admin1", "Admin User", []string{"admin"})
+	c, w := createTestHTTPRequest("GET", "/admin/sessions/s1/export?format=pdf", claims)
+	c.Params = gin.Params{gin.Param{Key: "sessionID", Value: "s1"}}
+
+	handleExportSession(nil, logger)(c)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleExportSession_Formats(t *testing.T) {
+	now := time.Now()
+	sess := &session.Session{
+		ID:        "export-session-1",
+		UserID:    "user-1",
+		Name:      "Export me",
+		StartTime: now,
+		Messages: []*session.Message{
+			{Content: "hello", Timestamp: now, Sender: "user"},
+		},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{sess})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	handler := handleExportSession(storageService, logger)
+	claims := createMockJWTClaims("admin1", "Admin User", []string{"admin"})
+
+	for format, contentType := range map[string]string{
+		"json": "application/json",
+		"csv":  "text/csv",
+		"md":   "text/markdown",
+	} {
+		c, w := createTestHTTPRequest("GET", "/admin/sessions/"+sess.ID+"/export?format="+format, claims)
+		c.Params = gin.Params{gin.Param{Key: "sessionID", Value: sess.ID}}
+		handler(c)
+
+		require.Equal(t, http.StatusOK, w.Code, format)
+		require.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), contentType), format)
+		require.Contains(t, w.Header().Get("Content-Disposition"), "session-"+sess.ID+"."+format)
+		require.Contains(t, w.Body.String(), "hello", format)
+	}
+
+	c, w := createTestHTTPRequest("GET", "/admin/sessions/missing/export", claims)
+	c.Params = gin.Params{gin.Param{Key: "sessionID", Value: "missing"}}
+	handler(c)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,75 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/router"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleForceEndSession(t *testing.T) {
+	active := createTestSession("force-end-user", "active", true)
+	ended := createTestSession("force-end-user", "ended", false)
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{active, ended})
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: t.TempDir()})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	sessionManager := session.NewSessionManager(30*time.Second, logger)
+	messageRouter := router.NewMessageRouter(sessionManager, nil, nil, nil, storageService, 30*time.Second, logger)
+	defer messageRouter.Shutdown()
+	wsHandler := websocket.NewHandler(nil, messageRouter, logger, 1048576)
+	conn := websocket.NewConnection("force-end-user", []string{"user"})
+	conn.ConnectionID = "conn-1"
+	conn.SetSessionID(active.ID)
+	wsHandler.RegisterConnectionForTest(conn)
+
+	engine := gin.New()
+	engine.POST("/admin/sessions/:sessionID/end", func(c *gin.Context) {
+		c.Set("claims", createMockJWTClaims("admin-1", "Admin", []string{constants.RoleAdmin}))
+	}, handleForceEndSession(storageService, messageRouter, wsHandler, logger))
+	post := func(sessionID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/sessions/"+sessionID+"/end", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, post(active.ID, `{`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(active.ID, `{"reason": "rude"}`).Code)
+	assert.Equal(t, http.StatusNotFound, post("no-such-session", `{"reason": "abuse"}`).Code)
+	assert.Equal(t, http.StatusConflict, post(ended.ID, `{"reason": "abuse"}`).Code)
+
+	w := post(active.ID, `{"reason": "abuse"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"closed_connections":1`)
+	assert.Empty(t, wsHandler.ListConnections())
+
+	stored, err := storageService.GetSession(active.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored.EndTime)
+	sessions, err := storageService.ListUserSessions("force-end-user", 10)
+	require.NoError(t, err)
+	for _, s := range sessions {
+		if s.ID == active.ID {
+			assert.Equal(t, constants.EndReasonAbuse, s.EndReason)
+			assert.Equal(t, "admin-1", s.EndedBy)
+		}
+	}
+
+	assert.Equal(t, http.StatusConflict, post(active.ID, `{"reason": "abuse"}`).Code, "already ended")
+}
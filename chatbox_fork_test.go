@@ -0,0 +1,81 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleForkSession(t *testing.T) {
+	now := time.Now()
+	endTime := now.Add(time.Minute)
+	source := &session.Session{
+		ID:        "fork-source-1",
+		UserID:    "user-1",
+		Name:      "Fork me",
+		ModelID:   "gpt-4",
+		StartTime: now,
+		EndTime:   &endTime,
+		Messages: []*session.Message{
+			{Content: "question", Timestamp: now, Sender: "user"},
+			{Content: "answer", Timestamp: now, Sender: "ai"},
+			{Content: "follow-up", Timestamp: now, Sender: "user"},
+		},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{source})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	sessionManager := session.NewSessionManager(15*time.Minute, logger)
+	handler := handleForkSession(storageService, sessionManager, nil, nil, logger)
+
+	fork := func(userID, body string) *httptest.ResponseRecorder {
+		c, w := createTestHTTPRequest("POST", "/sessions/"+source.ID+"/fork", createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Request = httptest.NewRequest("POST", "/sessions/"+source.ID+"/fork", strings.NewReader(body))
+		c.Params = gin.Params{gin.Param{Key: "sessionID", Value: source.ID}}
+		handler(c)
+		return w
+	}
+
+	// Only the owner may fork
+	assert.Equal(t, http.StatusNotFound, fork("user-2", "").Code)
+	assert.Equal(t, http.StatusBadRequest, fork("user-1", `{"message_index": 3}`).Code)
+
+	w := fork("user-1", `{"message_index": 1}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, source.ID, resp["forked_from"])
+	assert.Equal(t, float64(2), resp["message_count"])
+
+	forkID := resp["session_id"].(string)
+	stored, err := storageService.GetSession(forkID)
+	require.NoError(t, err)
+	require.Len(t, stored.Messages, 2)
+	assert.Equal(t, "answer", stored.Messages[1].Content)
+	assert.Equal(t, "Fork me", stored.Name)
+
+	// The original is left untouched
+	original, err := storageService.GetSession(source.ID)
+	require.NoError(t, err)
+	assert.Len(t, original.Messages, 3)
+
+	// Forking again replaces the previous fork as the active session
+	w = fork("user-1", "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	previous, err := storageService.GetSession(forkID)
+	require.NoError(t, err)
+	assert.NotNil(t, previous.EndTime)
+}
@@ -0,0 +1,101 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionGrantHandlers(t *testing.T) {
+	now := time.Now()
+	shared := &session.Session{
+		ID:        "grant-session-1",
+		UserID:    "owner-1",
+		Name:      "Lease review",
+		StartTime: now,
+		Messages:  []*session.Message{{Content: "Is clause 4 standard?", Timestamp: now, Sender: "user"}},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{shared})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	call := func(handler gin.HandlerFunc, method, userID string, roles []string, body string, params gin.Params) *httptest.ResponseRecorder {
+		path := "/sessions/" + shared.ID
+		c, w := createTestHTTPRequest(method, path, createMockJWTClaims(userID, "User", roles))
+		c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+		c.Params = append(gin.Params{gin.Param{Key: "sessionID", Value: shared.ID}}, params...)
+		handler(c)
+		return w
+	}
+	grant := func(userID, granteeID, access string) *httptest.ResponseRecorder {
+		params := gin.Params{gin.Param{Key: "userID", Value: granteeID}}
+		return call(handleGrantSessionAccess(storageService, logger), "PUT", userID, []string{"user"}, `{"access": "`+access+`"}`, params)
+	}
+	read := func(userID string) *httptest.ResponseRecorder {
+		return call(handleGetSessionMessages(storageService, logger), "GET", userID, []string{"user"}, "", nil)
+	}
+	rename := func(userID string) *httptest.ResponseRecorder {
+		handler := handleRenameSession(storageService, session.NewSessionManager(15*time.Minute, logger), logger)
+		return call(handler, "PATCH", userID, []string{"user"}, `{"name": "Renamed"}`, nil)
+	}
+
+	// Only the owner shares, and guests cannot share at all
+	assert.Equal(t, http.StatusNotFound, read("colleague-1").Code)
+	assert.Equal(t, http.StatusNotFound, grant("colleague-1", "colleague-2", constants.GrantAccessRead).Code)
+	assert.Equal(t, http.StatusBadRequest, grant("owner-1", "colleague-1", "admin").Code)
+	params := gin.Params{gin.Param{Key: "userID", Value: "colleague-1"}}
+	w := call(handleGrantSessionAccess(storageService, logger), "PUT", "owner-1", []string{constants.RoleGuest}, `{"access": "read"}`, params)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Read access lets the colleague read but not rename the session
+	w = grant("owner-1", "colleague-1", constants.GrantAccessRead)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"user_id":"colleague-1"`)
+	assert.Equal(t, http.StatusOK, read("colleague-1").Code)
+	assert.Equal(t, http.StatusNotFound, rename("colleague-1").Code)
+
+	// Write access also lets them rename it
+	require.Equal(t, http.StatusOK, grant("owner-1", "colleague-1", constants.GrantAccessWrite).Code)
+	assert.Equal(t, http.StatusOK, rename("colleague-1").Code)
+
+	w = call(handleSharedSessions(storageService, logger), "GET", "colleague-1", []string{"user"}, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Sessions []struct {
+			ID     string `json:"id"`
+			Access string `json:"access"`
+		} `json:"sessions"`
+		Count int `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, 1, list.Count)
+	assert.Equal(t, shared.ID, list.Sessions[0].ID)
+	assert.Equal(t, constants.GrantAccessWrite, list.Sessions[0].Access)
+
+	// Grants are managed by the owner only
+	w = call(handleListSessionGrants(storageService, logger), "GET", "colleague-1", []string{"user"}, "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = call(handleListSessionGrants(storageService, logger), "GET", "owner-1", []string{"user"}, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"access":"write"`)
+
+	// Revoking ends the colleague's access at once
+	w = call(handleRevokeSessionAccess(storageService, logger), "DELETE", "owner-1", []string{"user"}, "", params)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"grants":[]`)
+	assert.Equal(t, http.StatusNotFound, read("colleague-1").Code)
+	assert.Equal(t, http.StatusOK, read("owner-1").Code)
+}
@@ -0,0 +1,57 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimGuestSessions_Validation covers the requests rejected before any
+// session is claimed
+func TestClaimGuestSessions_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	validator := auth.NewJWTValidator("test-secret")
+	guestToken, _, err := validator.IssueGuestToken(time.Hour)
+	require.NoError(t, err)
+	expiredToken, _, err := validator.IssueGuestToken(-time.Minute)
+	require.NoError(t, err)
+	userToken := createTestJWT(t, "test-secret", "user-2", []string{"user"})
+
+	claim := func(caller *auth.Claims, body string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.POST("/sessions/claim", func(c *gin.Context) {
+			c.Set("claims", caller)
+		}, handleClaimGuestSessions(validator, nil, nil, logger))
+		req := httptest.NewRequest(http.MethodPost, "/sessions/claim", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	user := createMockJWTClaims("user-1", "User", []string{"user"})
+	guest := createMockJWTClaims("guest-1", "Guest", []string{constants.RoleGuest})
+
+	assert.Equal(t, http.StatusForbidden, claim(guest, `{"guest_token": "`+guestToken+`"}`).Code, "guests cannot claim")
+	assert.Equal(t, http.StatusBadRequest, claim(user, `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, claim(user, `{"guest_token": "bogus"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, claim(user, `{"guest_token": "`+expiredToken+`"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, claim(user, `{"guest_token": "`+userToken+`"}`).Code, "only guest tokens can be claimed")
+}
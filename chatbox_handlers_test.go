@@ -1442,9 +1442,9 @@ func TestHandleAdminTakeover_RouterError(t *testing.T) {
 	// Call handler
 	handler(c)
 
-	// Verify not-found response (router returns ErrCodeNotFound for non-existent session)
+	// Verify not-found response (router returns ErrCodeSessionNotFound for non-existent session)
 	require.Equal(t, 404, w.Code)
-	require.Contains(t, w.Body.String(), "error")
+	require.Contains(t, w.Body.String(), "SESSION_NOT_FOUND")
 }
 
 // TestHandleAdminTakeover_ResponseFormat tests the response format for successful takeover
@@ -1587,7 +1587,7 @@ func TestHandleAdminTakeover_CoverageImprovement(t *testing.T) {
 			sessionID:    testSession.ID,
 			claims:       createMockJWTClaims("admin4", "Admin Four", []string{"admin"}),
 			setClaims:    true,
-			expectedCode: 400, // Fails because session is already being assisted by admin1
+			expectedCode: 409, // Denied because session is already being assisted by admin1
 		},
 	}
 
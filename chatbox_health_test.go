@@ -2,6 +2,7 @@ package chatbox
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/health"
 	"github.com/real-rm/goconfig"
 	"github.com/real-rm/golog"
 	"github.com/real-rm/gomongo"
@@ -72,7 +74,7 @@ func TestMongoDBHealthCheck_Integration(t *testing.T) {
 
 		// Create router and register handler
 		router := gin.New()
-		router.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+		router.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 
 		// Make request
 		req, _ := http.NewRequest("GET", "/readyz", nil)
@@ -105,7 +107,7 @@ func TestMongoDBHealthCheck_Down(t *testing.T) {
 
 		// Create router with nil MongoDB
 		router := gin.New()
-		router.GET("/readyz", handleReadyCheck(nil, nil, testLogger))
+		router.GET("/readyz", handleReadyCheck(readinessChecks(nil, nil, nil, ""), testLogger))
 
 		// Make request
 		req, _ := http.NewRequest("GET", "/readyz", nil)
@@ -164,7 +166,7 @@ func TestMongoDBHealthCheck_Down(t *testing.T) {
 			t.Log("MongoDB initialization failed as expected:", err)
 
 			router := gin.New()
-			router.GET("/readyz", handleReadyCheck(nil, nil, logger))
+			router.GET("/readyz", handleReadyCheck(readinessChecks(nil, nil, nil, ""), logger))
 
 			req, _ := http.NewRequest("GET", "/readyz", nil)
 			w := httptest.NewRecorder()
@@ -177,7 +179,7 @@ func TestMongoDBHealthCheck_Down(t *testing.T) {
 
 		// If initialization succeeded (lazy connection), the Ping should fail
 		router := gin.New()
-		router.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+		router.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 
 		req, _ := http.NewRequest("GET", "/readyz", nil)
 		w := httptest.NewRecorder()
@@ -208,7 +210,7 @@ func TestMongoDBHealthCheck_Timeout(t *testing.T) {
 
 		// Create router with nil MongoDB (fast path)
 		router := gin.New()
-		router.GET("/readyz", handleReadyCheck(nil, nil, testLogger))
+		router.GET("/readyz", handleReadyCheck(readinessChecks(nil, nil, nil, ""), testLogger))
 
 		// Make request and measure time
 		start := time.Now()
@@ -224,3 +226,45 @@ func TestMongoDBHealthCheck_Timeout(t *testing.T) {
 		t.Logf("Health check completed in %v", elapsed)
 	})
 }
+
+// TestReadyCheck_Degraded tests that failing optional dependencies degrade the
+// service without failing readiness
+func TestReadyCheck_Degraded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := CreateTestLogger(t)
+	defer logger.Close()
+
+	checks := health.NewRegistry(time.Second)
+	checks.Register("mongodb", true, func(ctx context.Context) health.Result { return health.OK(nil) })
+	checks.Register("disk", false, func(ctx context.Context) health.Result {
+		return health.Fail("Upload directory is not writable", os.ErrPermission)
+	})
+
+	router := gin.New()
+	router.GET("/readyz", handleReadyCheck(checks, logger))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Status string                   `json:"status"`
+		Checks map[string]health.Result `json:"checks"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "degraded", response.Status)
+	assert.Equal(t, health.StatusReady, response.Checks["mongodb"].Status)
+	assert.Equal(t, health.StatusDegraded, response.Checks["disk"].Status)
+	assert.Equal(t, "Upload directory is not writable", response.Checks["disk"].Reason)
+	assert.NotContains(t, w.Body.String(), "permission denied", "errors are only logged")
+}
+
+// TestReadinessChecks_OptionalDependencies tests that optional checks are only
+// registered when their dependency is in use
+func TestReadinessChecks_OptionalDependencies(t *testing.T) {
+	report := readinessChecks(nil, nil, nil, "").Run(context.Background())
+	assert.Len(t, report.Checks, 1)
+
+	report = readinessChecks(nil, nil, nil, t.TempDir()).Run(context.Background())
+	assert.Equal(t, health.StatusReady, report.Checks["disk"].Status)
+	assert.Equal(t, health.StatusNotReady, report.Status, "MongoDB is still required")
+}
@@ -0,0 +1,79 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/priority"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleHelpQueue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+	sm := session.NewSessionManager(15*time.Minute, logger)
+
+	requestHelp := func(userID, tenantID, tier, content string) string {
+		sess, err := sm.CreateSessionForTenant(userID, tenantID)
+		require.NoError(t, err)
+		require.NoError(t, sm.SetCustomerTier(sess.ID, tier))
+		require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: content, Sender: constants.SenderUser}))
+		require.NoError(t, sm.MarkHelpRequested(sess.ID))
+		return sess.ID
+	}
+	calm := requestHelp("user-1", "tenant-a", "", "how do I change my email address")
+	angry := requestHelp("user-2", "tenant-a", "", "this is broken and USELESS, I am furious!!")
+	gold := requestHelp("user-3", "tenant-a", "gold", "how do I change my email address")
+	requestHelp("user-4", "tenant-b", "gold", "this is terrible")
+
+	scorer := priority.NewScorer(priority.Weights{PerMinute: 1, Frustration: 30, Tiers: map[string]float64{"gold": 100}})
+	list := func(claims *auth.Claims) []helpQueueEntry {
+		router := gin.New()
+		router.GET("/admin/help-queue", func(c *gin.Context) {
+			c.Set("claims", claims)
+		}, handleHelpQueue(sm, scorer))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/help-queue", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Queue []helpQueueEntry `json:"queue"`
+			Count int              `json:"count"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, len(resp.Queue), resp.Count)
+		return resp.Queue
+	}
+
+	admin := createMockJWTClaims("admin-1", "Admin", []string{constants.RoleAdmin})
+	admin.TenantID = "tenant-a"
+	queue := list(admin)
+	require.Len(t, queue, 3, "other tenants' sessions are hidden")
+	assert.Equal(t, []string{gold, angry, calm}, []string{queue[0].SessionID, queue[1].SessionID, queue[2].SessionID})
+	assert.Equal(t, "gold", queue[0].Tier)
+	assert.Equal(t, 100.0, queue[0].Score.Tier)
+	assert.Greater(t, queue[1].Score.Frustration, 0.0)
+	assert.Less(t, queue[1].Score.Sentiment, 0.0)
+	assert.Zero(t, queue[2].Score.Frustration)
+
+	assert.Len(t, list(createMockJWTClaims("root", "Root", []string{constants.RoleSuperAdmin})), 4)
+
+	// Taken over sessions leave the queue
+	require.NoError(t, sm.MarkAdminAssisted(angry, "admin-1", "Admin"))
+	assert.Len(t, list(admin), 2)
+}
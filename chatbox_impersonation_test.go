@@ -0,0 +1,61 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationTokens_ReadAndChatOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	storageService, cleanup := setupTestStorage(t)
+	defer cleanup()
+	logger := setupTestLogger(t)
+
+	validator := auth.NewJWTValidator("V4l1d-JWT-K3y-F0r-T3st1ng-Purp0ses-1!")
+	adminID := "admin-" + time.Now().Format("150405.000000000")
+	token, _, err := validator.IssueImpersonationToken("user-1", "", adminID, time.Minute)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	engine.Use(impersonationAuditMiddleware(storageService, logger))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	engine.GET("/sessions/:sessionID", userAuthMiddleware(validator, logger), ok)
+	engine.DELETE("/sessions/:sessionID", userAuthMiddleware(validator, logger), ok)
+	engine.POST("/sessions/:sessionID/share", userAuthMiddleware(validator, logger), ok)
+	engine.POST("/ws/ticket", chatAuthMiddleware(validator, logger), ok)
+
+	call := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/sessions/s-1"), "impersonation tokens read")
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/ws/ticket"), "impersonation tokens chat")
+	assert.Equal(t, http.StatusForbidden, call(http.MethodDelete, "/sessions/s-1"))
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/sessions/s-1/share"))
+
+	// Every request, refused or not, is audited under the admin's ID
+	entries, err := storageService.ListAuditEntries(&storage.AuditListOptions{ActorID: adminID})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	statuses := map[string]int{}
+	for _, entry := range entries {
+		assert.Equal(t, constants.AuditActionImpersonatedRequest, entry.Action)
+		assert.Equal(t, "user-1", entry.UserID)
+		statuses[entry.Method+" "+entry.Path] = entry.Status
+	}
+	assert.Equal(t, http.StatusForbidden, statuses["DELETE /sessions/s-1"])
+	assert.Equal(t, http.StatusOK, statuses["GET /sessions/s-1"])
+}
@@ -0,0 +1,139 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIPThrottleTestRouter serves /ws behind the connection throttle and the
+// IP admin endpoints, authenticated as an admin
+func newIPThrottleTestRouter(t *testing.T, limiter *ratelimit.IPLimiter) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	router := gin.New()
+	router.GET("/ws", ipThrottleMiddleware(limiter, logger), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	admin := router.Group("/admin", func(c *gin.Context) {
+		c.Set("claims", createMockJWTClaims("admin-1", "Admin", []string{constants.RoleAdmin}))
+	})
+	admin.GET("/ip-stats", handleIPStats(limiter))
+	admin.GET("/ip-bans", handleListIPBans(limiter))
+	admin.PUT("/ip-bans/:ip", handleBanIP(limiter, logger))
+	admin.DELETE("/ip-bans/:ip", handleUnbanIP(limiter, logger))
+	return router
+}
+
+// connectFrom sends a /ws request from ip
+func connectFrom(router *gin.Engine, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIPThrottleMiddleware_LimitsConnectionAttempts(t *testing.T) {
+	router := newIPThrottleTestRouter(t, ratelimit.NewIPLimiter(time.Minute, 2))
+
+	assert.Equal(t, http.StatusOK, connectFrom(router, "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, connectFrom(router, "10.0.0.1").Code)
+
+	w := connectFrom(router, "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+	assert.NotEmpty(t, w.Header().Get(constants.HeaderRetryAfter))
+
+	assert.Equal(t, http.StatusOK, connectFrom(router, "10.0.0.2").Code)
+}
+
+func TestIPBanEndpoints(t *testing.T) {
+	router := newIPThrottleTestRouter(t, ratelimit.NewIPLimiter(time.Minute, 100))
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/ip-bans/10.0.0.1", strings.NewReader(`{"duration_minutes": 30, "reason": "reconnect storm"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var ban ratelimit.IPBan
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ban))
+	assert.Equal(t, "10.0.0.1", ban.IP)
+	assert.Equal(t, "admin-1", ban.BannedBy)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), ban.Until, time.Minute)
+
+	w = connectFrom(router, "10.0.0.1")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "IP_BANNED")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/ip-stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats struct {
+		IPs []ratelimit.IPStats `json:"ips"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	require.Len(t, stats.IPs, 1)
+	assert.Equal(t, 1, stats.IPs[0].Rejected)
+	assert.NotNil(t, stats.IPs[0].BannedUntil)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/ip-bans", nil))
+	assert.Contains(t, w.Body.String(), "reconnect storm")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/ip-bans/10.0.0.1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, connectFrom(router, "10.0.0.1").Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/ip-bans/10.0.0.1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBanIP_Validation(t *testing.T) {
+	router := newIPThrottleTestRouter(t, ratelimit.NewIPLimiter(time.Minute, 100))
+
+	for name, tc := range map[string]struct {
+		path string
+		body string
+	}{
+		"invalid ip":       {"/admin/ip-bans/not-an-ip", ""},
+		"negative minutes": {"/admin/ip-bans/10.0.0.1", `{"duration_minutes": -5}`},
+		"too long":         {"/admin/ip-bans/10.0.0.1", `{"duration_minutes": 20000}`},
+		"malformed body":   {"/admin/ip-bans/10.0.0.1", `{`},
+	} {
+		req := httptest.NewRequest(http.MethodPut, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	// Without a body the default duration applies
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/ip-bans/2001:db8::1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var ban ratelimit.IPBan
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ban))
+	assert.WithinDuration(t, time.Now().Add(constants.DefaultIPBanDuration), ban.Until, time.Minute)
+}
@@ -0,0 +1,65 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/memory"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleUserMemory(t *testing.T) {
+	storageService, cleanup := setupTestStorageWithData(t, nil)
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	const userID = "memory-handler-user"
+	_, err = storageService.RememberUserFacts("", userID, "sess-1", []memory.Extracted{
+		{Kind: memory.KindName, Text: "Anna"},
+		{Kind: memory.KindPreference, Text: "short answers"},
+	})
+	require.NoError(t, err)
+	defer storageService.ClearUserMemory(userID)
+
+	call := func(handler gin.HandlerFunc, method, userID, factID string) *httptest.ResponseRecorder {
+		c, w := createTestHTTPRequest(method, "/chatbox/memory/"+factID, createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Params = gin.Params{gin.Param{Key: "factID", Value: factID}}
+		handler(c)
+		return w
+	}
+	list := func(userID string) []*memory.Fact {
+		w := call(handleListMemory(storageService, logger), "GET", userID, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Facts []*memory.Fact `json:"facts"`
+			Count int            `json:"count"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, len(resp.Facts), resp.Count)
+		return resp.Facts
+	}
+
+	facts := list(userID)
+	require.Len(t, facts, 2)
+	assert.Equal(t, "Anna", facts[0].Text)
+	assert.Empty(t, list("someone-else"))
+
+	// Other users cannot delete the user's facts
+	assert.Equal(t, http.StatusNotFound, call(handleDeleteMemoryFact(storageService, logger), "DELETE", "someone-else", facts[0].ID).Code)
+	assert.Equal(t, http.StatusOK, call(handleDeleteMemoryFact(storageService, logger), "DELETE", userID, facts[0].ID).Code)
+	assert.Equal(t, http.StatusNotFound, call(handleDeleteMemoryFact(storageService, logger), "DELETE", userID, facts[0].ID).Code)
+	assert.Len(t, list(userID), 1)
+
+	w := call(handleClearMemory(storageService, logger), "DELETE", userID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"facts_deleted":1`)
+	assert.Empty(t, list(userID))
+}
@@ -0,0 +1,64 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMergeSessions(t *testing.T) {
+	now := time.Now()
+	endTime := now.Add(-time.Hour)
+	target := &session.Session{
+		ID:        "merge-target-1",
+		UserID:    "user-1",
+		StartTime: now.Add(-2 * time.Hour),
+		EndTime:   &endTime,
+		Messages:  []*session.Message{{Content: "question", Timestamp: now.Add(-2 * time.Hour), Sender: "user"}},
+	}
+	source := &session.Session{
+		ID:        "merge-source-1",
+		UserID:    "user-1",
+		StartTime: now.Add(-time.Minute),
+		IsActive:  true,
+		Messages:  []*session.Message{{Content: "question again", Timestamp: now.Add(-time.Minute), Sender: "user"}},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{target, source})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	sessionManager := session.NewSessionManager(15*time.Minute, logger)
+	handler := handleMergeSessions(storageService, sessionManager, nil, logger)
+
+	merge := func(userID, targetID, sourceID string) *httptest.ResponseRecorder {
+		c, w := createTestHTTPRequest("POST", "/sessions/"+targetID+"/merge/"+sourceID, createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Params = gin.Params{{Key: "sessionID", Value: targetID}, {Key: "sourceID", Value: sourceID}}
+		handler(c)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, merge("user-1", target.ID, target.ID).Code)
+	assert.Equal(t, http.StatusNotFound, merge("user-2", target.ID, source.ID).Code, "only the owner may merge")
+	assert.Equal(t, http.StatusConflict, merge("user-1", source.ID, target.ID).Code, "the target must be ended")
+
+	w := merge("user-1", target.ID, source.ID)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, source.ID, resp["merged_from"])
+	assert.Equal(t, float64(2), resp["message_count"])
+
+	// The source is gone once merged
+	assert.Equal(t, http.StatusNotFound, merge("user-1", target.ID, source.ID).Code)
+}
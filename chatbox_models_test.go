@@ -0,0 +1,24 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleListModels_HumanOnlyMode(t *testing.T) {
+	c, w := createTestHTTPRequest("GET", "/models", createMockJWTClaims("user-1", "User", []string{"user"}))
+
+	handleListModels(nil)(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Models []modelCatalogEntry `json:"models"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotNil(t, resp.Models)
+	assert.Empty(t, resp.Models)
+}
@@ -0,0 +1,150 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registeredRoutes returns the routes Register adds, as "METHOD /path" relative
+// to the path prefix, read from chatbox.go so the check needs no MongoDB
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "chatbox.go", nil, 0)
+	require.NoError(t, err)
+
+	groups := map[string]string{"chatGroup": ""}
+	// Subgroups are assigned as xGroup := chatGroup.Group("/x")
+	ast.Inspect(file, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			return true
+		}
+		name, _ := assign.Lhs[0].(*ast.Ident)
+		call, _ := assign.Rhs[0].(*ast.CallExpr)
+		if name == nil || call == nil || len(call.Args) == 0 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Group" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if ok && lit.Kind == token.STRING {
+			prefix, _ := strconv.Unquote(lit.Value)
+			groups[name.Name] = prefix
+		}
+		return true
+	})
+	var routes []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		group, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		prefix, known := groups[group.Name]
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !known || !ok || lit.Kind != token.STRING {
+			return true
+		}
+		path, err := strconv.Unquote(lit.Value)
+		require.NoError(t, err)
+		switch sel.Sel.Name {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			routes = append(routes, sel.Sel.Name+" "+prefix+path)
+		}
+		return true
+	})
+	return routes
+}
+
+func TestOpenAPI_DocumentsRegisteredRoutes(t *testing.T) {
+	routes := registeredRoutes(t)
+	require.NotEmpty(t, routes)
+
+	doc, err := openapi.Build(openapi.Info{}, "", nil, apiRoutes())
+	require.NoError(t, err)
+	documented := doc.Operations()
+
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		assert.Contains(t, documented, openapi.OperationFor(method, path), "route %s is not in apiRoutes", route)
+	}
+	assert.Contains(t, routes, "GET /admin/readonly")
+	assert.Contains(t, routes, "DELETE /users/:userID/data")
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	spec, err := buildOpenAPISpec(constants.DefaultPathPrefix)
+	require.NoError(t, err)
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(spec, &doc))
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+	assert.Equal(t, constants.OpenAPITitle, doc.Info.Title)
+	assert.Equal(t, []openapi.Server{{URL: constants.DefaultPathPrefix}}, doc.Servers)
+
+	// Sessions, admin, health and file endpoints are documented
+	get := doc.Paths["/sessions/{sessionID}"]["get"]
+	require.NotNil(t, get)
+	assert.Equal(t, "sessionID", get.Parameters[0].Name)
+	assert.Contains(t, get.Responses, "401")
+
+	takeover := doc.Paths["/admin/takeover/{sessionID}"]["post"]
+	require.NotNil(t, takeover)
+	assert.Len(t, takeover.Security, 2, "admin routes take a JWT or an API key")
+	assert.Contains(t, takeover.Description, "`takeover`")
+
+	export := doc.Paths["/users/{userID}/export"]["get"]
+	require.NotNil(t, export)
+	assert.Contains(t, export.Responses["200"].Content, "application/zip")
+	assert.Contains(t, doc.Paths, "/readyz")
+
+	// Typed bodies become component schemas, and errors share the envelope
+	assert.Contains(t, doc.Components.Schemas, "SessionMetadata")
+	assert.Contains(t, doc.Components.Schemas["renameSessionRequest"].Properties, "name")
+	assert.Equal(t, "#/components/schemas/Envelope", get.Responses["404"].Content["application/json"].Schema.Ref)
+	assert.Contains(t, doc.Components.Schemas["Envelope"].Properties, "code")
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spec, err := buildOpenAPISpec("/api/chat")
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/api/chat/openapi.json", handleOpenAPISpec(spec))
+	router.GET("/api/chat/docs", handleOpenAPIUI("/api/chat"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/chat/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.JSONEq(t, string(spec), w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/chat/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "/api/chat/openapi.json"`)
+}
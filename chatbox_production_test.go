@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
 	"github.com/real-rm/chatbox/internal/message"
 	"github.com/real-rm/chatbox/internal/ratelimit"
 	"github.com/real-rm/chatbox/internal/websocket"
@@ -213,7 +214,7 @@ func TestProductionIssue18_AdminRateLimiting(t *testing.T) {
 
 	// Register admin endpoint with rate limiting
 	adminGroup := router.Group("/chat/admin")
-	adminGroup.Use(authMiddleware(validator, logger))
+	adminGroup.Use(authMiddleware(validator, authz.Default(), logger))
 	adminGroup.Use(adminRateLimitMiddleware(limiter, logger))
 	{
 		adminGroup.GET("/sessions", func(c *gin.Context) {
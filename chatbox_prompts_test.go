@@ -0,0 +1,79 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePrompts_CRUD(t *testing.T) {
+	storageService, cleanup := setupTestStorageWithData(t, nil)
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	prompts := prompt.NewService([]*prompt.Template{
+		{ID: "support", Name: "Support", Content: "Help {{user_name}}", ReadOnly: true},
+	}, func(tenantID, id string) (*prompt.Template, error) {
+		return storageService.ForTenant(tenantID).GetPromptTemplate(id)
+	})
+	claims := createMockJWTClaims("admin-1", "Admin", []string{"admin"})
+	call := func(handler gin.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		c, w := createTestHTTPRequest(method, "/admin/prompts/"+id, claims)
+		c.Request = httptest.NewRequest(method, "/admin/prompts/"+id, strings.NewReader(body))
+		c.Params = gin.Params{gin.Param{Key: "templateID", Value: id}}
+		handler(c)
+		return w
+	}
+
+	// Invalid templates are rejected
+	w := call(handleCreatePrompt(storageService, logger), "POST", "", `{"name": "Bad", "content": "Hi {{email}}"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = call(handleCreatePrompt(storageService, logger), "POST", "", `{"name": " Sales ", "content": "Sell to {{user_name}}"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created prompt.Template
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "Sales", created.Name)
+	defer func() { _ = storageService.DeletePromptTemplate(created.ID) }()
+
+	// Stored templates render once created
+	rendered, err := prompts.Render("", created.ID, map[string]string{prompt.VarUserName: "Bob"})
+	require.NoError(t, err)
+	assert.Equal(t, "Sell to Bob", rendered)
+
+	w = call(handleListPrompts(prompts, storageService, logger), "GET", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"support"`)
+	assert.Contains(t, w.Body.String(), created.ID)
+
+	w = call(handleGetPrompt(prompts, storageService, logger), "GET", "support", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, call(handleGetPrompt(prompts, storageService, logger), "GET", "missing", "").Code)
+
+	// Config templates are read-only
+	assert.Equal(t, http.StatusBadRequest, call(handleUpdatePrompt(prompts, storageService, logger), "PUT", "support", `{"name": "x", "content": "y"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(handleDeletePrompt(prompts, storageService, logger), "DELETE", "support", "").Code)
+
+	// Updates are visible immediately on this instance
+	w = call(handleUpdatePrompt(prompts, storageService, logger), "PUT", created.ID, `{"name": "Sales", "content": "Upsell {{user_name}}"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	rendered, err = prompts.Render("", created.ID, map[string]string{prompt.VarUserName: "Bob"})
+	require.NoError(t, err)
+	assert.Equal(t, "Upsell Bob", rendered)
+
+	require.Equal(t, http.StatusOK, call(handleDeletePrompt(prompts, storageService, logger), "DELETE", created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, call(handleDeletePrompt(prompts, storageService, logger), "DELETE", created.ID, "").Code)
+	_, err = prompts.Render("", created.ID, nil)
+	assert.ErrorIs(t, err, prompt.ErrTemplateNotFound)
+}
@@ -71,7 +71,7 @@ func TestPublicRateLimitMiddleware(t *testing.T) {
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 		assert.Equal(t, constants.StatusTooManyRequests, w.Code)
-		assert.Contains(t, w.Body.String(), "rate_limit_exceeded")
+		assert.Contains(t, w.Body.String(), "RATE_LIMITED")
 	})
 
 	t.Run("DifferentIPsHaveSeparateLimits", func(t *testing.T) {
@@ -0,0 +1,99 @@
+package chatbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/health"
+	"github.com/real-rm/chatbox/internal/readonly"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReadOnlyTestRouter serves the read-only admin endpoints, authenticated as
+// an admin, and a user write endpoint behind the read-only middleware. Nothing
+// is stored, so the mode is kept in memory only.
+func newReadOnlyTestRouter(t *testing.T, mode *readonly.Mode) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	router := gin.New()
+	router.GET("/sessions/:sessionID", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.PATCH("/sessions/:sessionID", readOnlyMiddleware(mode), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	admin := router.Group("/admin", func(c *gin.Context) {
+		c.Set("claims", createMockJWTClaims("admin-1", "Admin", []string{constants.RoleAdmin}))
+	})
+	admin.GET("/readonly", handleGetReadOnly(mode))
+	admin.POST("/readonly", handleSetReadOnly(mode, nil, logger))
+	return router
+}
+
+func TestReadOnlyEndpoints(t *testing.T) {
+	mode := readonly.New(readonly.State{})
+	router := newReadOnlyTestRouter(t, mode)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/sessions/s1", `{}`).Code)
+
+	// Turning it on refuses writes, while reads keep working
+	w := send(http.MethodPost, "/admin/readonly", `{"enabled": true, "message": "Storage incident"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, true, resp["enabled"])
+	assert.Equal(t, "admin-1", resp["updated_by"])
+	assert.Equal(t, false, resp["persisted"], "nothing is stored without MongoDB")
+
+	w = send(http.MethodPatch, "/sessions/s1", `{}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "READ_ONLY")
+	assert.Contains(t, w.Body.String(), "Storage incident")
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/sessions/s1", "").Code)
+
+	w = send(http.MethodGet, "/admin/readonly", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var state readonly.State
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.True(t, state.Enabled)
+	assert.Equal(t, "Storage incident", state.Message)
+
+	result := readOnlyReadinessCheck(mode)(context.Background())
+	assert.Equal(t, health.StatusMaintenance, result.Status)
+	assert.Equal(t, "Storage incident", result.Details["message"])
+
+	// Turning it off accepts writes again
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/admin/readonly", `{"enabled": false}`).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/sessions/s1", `{}`).Code)
+	assert.Equal(t, health.StatusReady, readOnlyReadinessCheck(mode)(context.Background()).Status)
+
+	// enabled is required, and the message is bounded
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/admin/readonly", `{"message": "x"}`).Code)
+	long := `{"enabled": true, "message": "` + strings.Repeat("x", constants.MaxReadOnlyMessageLength+1) + `"}`
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/admin/readonly", long).Code)
+	assert.False(t, mode.State().Enabled)
+}
@@ -0,0 +1,56 @@
+package chatbox
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRateLimitRedisClient_MemoryBackendByDefault(t *testing.T) {
+	config := setupTestConfig(t)
+	logger := setupTestLogger(t)
+
+	client, err := newRateLimitRedisClient(config, logger)
+	require.NoError(t, err)
+	assert.Nil(t, client)
+}
+
+func TestNewRateLimitRedisClient_RedisBackend(t *testing.T) {
+	mr := miniredis.RunT(t)
+	config := setupTestConfig(t)
+	logger := setupTestLogger(t)
+	t.Setenv("CHATBOX_RATE_LIMIT_BACKEND", "redis")
+	t.Setenv("CHATBOX_REDIS_URL", "redis://"+mr.Addr()+"/0")
+
+	client, err := newRateLimitRedisClient(config, logger)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+	defer client.Close()
+}
+
+func TestNewRateLimitRedisClient_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		url     string
+	}{
+		{"unknown backend", "memcached", ""},
+		{"missing url", "redis", ""},
+		{"invalid url", "redis", "http://localhost:6379"},
+		{"unreachable", "redis", "redis://127.0.0.1:1/0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := setupTestConfig(t)
+			logger := setupTestLogger(t)
+			t.Setenv("CHATBOX_RATE_LIMIT_BACKEND", tt.backend)
+			t.Setenv("CHATBOX_REDIS_URL", tt.url)
+
+			client, err := newRateLimitRedisClient(config, logger)
+			assert.Error(t, err)
+			assert.Nil(t, client)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/real-rm/goconfig"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigReloader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: t.TempDir()})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+	}
+	t.Setenv("RMBASE_FILE_CFG", configPath)
+	t.Cleanup(func() { goconfig.ResetConfig() })
+
+	wsHandler := websocket.NewHandler(nil, nil, logger, 1048576)
+	adminLimiter := ratelimit.NewMessageLimiter(time.Minute, 100)
+	guestLimiter := ratelimit.NewMessageLimiter(time.Minute, 100)
+	policies := ratelimit.NewPolicies([]ratelimit.Policy{{Name: constants.RatePolicyList, Limit: 100, Window: time.Minute}}, nil)
+	corsHandler, err := newCORSHandler(nil)
+	require.NoError(t, err)
+	corsMiddleware := &reloadableCORS{handler: corsHandler}
+	reloader := &configReloader{
+		load:         loadConfiguration,
+		wsHandler:    wsHandler,
+		cors:         corsMiddleware,
+		adminLimiter: adminLimiter,
+		guestLimiter: guestLimiter,
+		ratePolicies: policies,
+		logger:       logger,
+	}
+	require.True(t, wsHandler.IsOpenOrigin())
+
+	api := gin.New()
+	api.Use(corsMiddleware.handle)
+	api.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	corsAllowOrigin := func() string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set("Origin", "https://admin.example.com")
+		api.ServeHTTP(w, req)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+	require.Empty(t, corsAllowOrigin())
+
+	writeConfig(`
+[chatbox]
+allowed_origins = "https://app.example.com"
+cors_allowed_origins = "https://admin.example.com"
+admin_rate_limit = 1
+guest_rate_limit = 2
+
+[chatbox.rate_limits.list]
+limit = 1
+`)
+	sections, err := reloader.Reload()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{reloadSectionPolicies, reloadSectionOrigins, reloadSectionAdminLimit, reloadSectionGuestLimit, reloadSectionCORS}, sections)
+	assert.False(t, wsHandler.IsOpenOrigin())
+	assert.Equal(t, "https://admin.example.com", corsAllowOrigin(), "the reloaded CORS origins apply")
+	assert.True(t, adminLimiter.Allow("admin-1"))
+	assert.False(t, adminLimiter.Allow("admin-1"), "the reloaded admin limit applies")
+	assert.True(t, guestLimiter.Allow("guest-1"))
+	assert.True(t, guestLimiter.Allow("guest-1"))
+	assert.False(t, guestLimiter.Allow("guest-1"), "the reloaded guest limit applies")
+	listLimiter, ok := policies.Limiter(constants.RatePolicyList)
+	require.True(t, ok)
+	assert.True(t, listLimiter.Allow("admin-1"))
+	assert.False(t, listLimiter.Allow("admin-1"), "the reloaded policy limit applies")
+
+	// An invalid configuration changes nothing
+	engine := gin.New()
+	engine.POST("/admin/reload", handleReloadConfig(reloader, logger))
+	reload := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+		return w
+	}
+	writeConfig(`
+[chatbox]
+admin_rate_limit = 50
+
+[chatbox.rate_limits.list]
+limit = 50
+
+[chatbox.rate_limits.export]
+limit = 1
+`)
+	w := reload()
+	assert.Equal(t, http.StatusBadRequest, w.Code, "policies cannot be added without a restart")
+	assert.Contains(t, w.Body.String(), "restart")
+	assert.False(t, wsHandler.IsOpenOrigin())
+	assert.False(t, adminLimiter.Allow("admin-1"))
+	assert.False(t, listLimiter.Allow("admin-1"))
+	assert.Equal(t, "https://admin.example.com", corsAllowOrigin())
+
+	// A section rejected last still leaves the earlier ones unapplied
+	writeConfig(`
+[chatbox]
+admin_rate_limit = 50
+cors_allowed_origins = "admin.example.com"
+
+[chatbox.rate_limits.list]
+limit = 50
+`)
+	assert.Equal(t, http.StatusBadRequest, reload().Code)
+	assert.False(t, wsHandler.IsOpenOrigin())
+	assert.False(t, adminLimiter.Allow("admin-1"))
+	assert.False(t, listLimiter.Allow("admin-1"))
+	assert.Equal(t, "https://admin.example.com", corsAllowOrigin())
+
+	writeConfig(`
+[chatbox]
+allowed_origins = "https://app.example.com"
+admin_rate_window = "soon"
+`)
+	assert.Equal(t, http.StatusBadRequest, reload().Code)
+	assert.False(t, adminLimiter.Allow("admin-1"))
+
+	// Without allowed origins, all origins are accepted again
+	writeConfig(`
+[chatbox]
+admin_rate_limit = 50
+
+[chatbox.rate_limits.list]
+limit = 50
+`)
+	w = reload()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"reloaded":[`)
+	assert.True(t, wsHandler.IsOpenOrigin())
+	assert.True(t, adminLimiter.Allow("admin-1"))
+	assert.True(t, listLimiter.Allow("admin-1"))
+	assert.Empty(t, corsAllowOrigin(), "without CORS origins, CORS is disabled again")
+}
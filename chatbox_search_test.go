@@ -0,0 +1,85 @@
+package chatbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSearchSessions_InvalidQuery(t *testing.T) {
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	claims := createMockJWTClaims("admin1", "Admin User", []string{"admin"})
+	for _, path := range []string{
+		"/admin/sessions/search",
+		"/admin/sessions/search?q=%20%20",
+		"/admin/sessions/search?q=" + strings.Repeat("a", 300),
+	} {
+		c, w := createTestHTTPRequest("GET", path, claims)
+		handleSearchSessions(nil, logger)(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestHandleSearchSessions_Anonymized(t *testing.T) {
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	storageService := &storage.StorageService{}
+	storageService.SetAnonymizationSalt([]byte("test-anonymization-salt"))
+	require.NoError(t, storageService.SetAnonymizedMode(true))
+
+	claims := createMockJWTClaims("admin1", "Admin User", []string{"admin"})
+	c, w := createTestHTTPRequest("GET", "/admin/sessions/search?q=refund", claims)
+	handleSearchSessions(storageService, logger)(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHandleSearchSessions(t *testing.T) {
+	now := time.Now()
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{{
+		ID:        "search-session-1",
+		UserID:    "user-1",
+		StartTime: now,
+		Messages: []*session.Message{
+			{Content: "Where is my refund?", Timestamp: now, Sender: "user"},
+		},
+	}})
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, storageService.EnsureIndexes(ctx))
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	claims := createMockJWTClaims("admin1", "Admin User", []string{"admin"})
+	c, w := createTestHTTPRequest("GET", "/admin/sessions/search?q=refund", claims)
+	handleSearchSessions(storageService, logger)(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Count   int                     `json:"count"`
+		Results []*storage.SearchResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, "search-session-1", resp.Results[0].Session.ID)
+	require.Len(t, resp.Results[0].Snippets, 1)
+	assert.Equal(t, "Where is my refund?", resp.Results[0].Snippets[0].Text)
+}
@@ -0,0 +1,130 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareLinks(t *testing.T) {
+	now := time.Now()
+	source := &session.Session{
+		ID:        "share-source-1",
+		UserID:    "user-1",
+		Name:      "Shipping question",
+		StartTime: now,
+		Messages: []*session.Message{
+			{Content: "Where is my <b>order</b>?", Timestamp: now, Sender: "user"},
+			{Content: "It ships tomorrow.", Timestamp: now, Sender: "ai"},
+		},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{source})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	call := func(handler gin.HandlerFunc, method, userID, body string) *httptest.ResponseRecorder {
+		path := "/sessions/" + source.ID + "/share"
+		c, w := createTestHTTPRequest(method, path, createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+		c.Params = gin.Params{gin.Param{Key: "sessionID", Value: source.ID}}
+		handler(c)
+		return w
+	}
+	share := func(userID, body string) *httptest.ResponseRecorder {
+		return call(handleShareSession(storageService, logger), "POST", userID, body)
+	}
+	view := func(token, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/shared/"+token, nil)
+		c.Request.Header.Set("Accept", accept)
+		c.Params = gin.Params{gin.Param{Key: "shareToken", Value: token}}
+		handleGetSharedSession(storageService, logger)(c)
+		return w
+	}
+
+	// Only the owner may share, for at most the maximum lifetime
+	assert.Equal(t, http.StatusNotFound, share("user-2", "").Code)
+	assert.Equal(t, http.StatusBadRequest, share("user-1", `{"expires_in_hours": 100000}`).Code)
+	assert.Equal(t, http.StatusBadRequest, share("user-1", `{"expires_in_hours": -1}`).Code)
+
+	w := share("user-1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var link storage.ShareLink
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	require.NotEmpty(t, link.Token)
+	require.NotNil(t, link.ExpiresAt)
+	assert.WithinDuration(t, now.Add(7*24*time.Hour), *link.ExpiresAt, time.Minute)
+
+	// Sharing again returns the same link; a new lifetime keeps the token
+	w = share("user-1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var again storage.ShareLink
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &again))
+	assert.Equal(t, link.Token, again.Token)
+	w = share("user-1", `{"expires_in_hours": 2}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &again))
+	assert.Equal(t, link.Token, again.Token)
+	assert.WithinDuration(t, now.Add(2*time.Hour), *again.ExpiresAt, time.Minute)
+
+	// API clients get JSON, browsers the escaped transcript
+	w = view(link.Token, "application/json")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var shared map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+	assert.Equal(t, source.ID, shared["session_id"])
+
+	w = view(link.Token, "text/html,application/xhtml+xml")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "Shipping question")
+	assert.Contains(t, w.Body.String(), "Where is my &lt;b&gt;order&lt;/b&gt;?")
+	assert.Contains(t, w.Body.String(), "It ships tomorrow.")
+
+	// Only the owner may revoke; a revoked link stops working
+	assert.Equal(t, http.StatusNotFound, call(handleRevokeShare(storageService, logger), "DELETE", "user-2", "").Code)
+	assert.Equal(t, http.StatusOK, call(handleRevokeShare(storageService, logger), "DELETE", "user-1", "").Code)
+	assert.Equal(t, http.StatusNotFound, view(link.Token, "application/json").Code)
+
+	// Sharing after revocation issues a new token
+	w = share("user-1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &again))
+	assert.NotEqual(t, link.Token, again.Token)
+}
+
+func TestRenderSharePage(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sess := &session.Session{
+		Messages: []*session.Message{
+			{Content: "<script>alert(1)</script>", Timestamp: now, Sender: "user"},
+			{Content: "", Timestamp: now, Sender: "user", FileURL: "https://files.example.com/a.png"},
+			{Content: "Agent joined", Timestamp: now, Sender: "bot"},
+		},
+	}
+
+	page, err := renderSharePage(sess, &storage.ShareLink{Token: "t"})
+	require.NoError(t, err)
+	html := string(page)
+	assert.Contains(t, html, "<title>Shared conversation</title>")
+	assert.NotContains(t, html, "<script>")
+	assert.Contains(t, html, "&lt;script&gt;")
+	assert.Contains(t, html, `<div class="msg system"><div class="meta">System`)
+	assert.Equal(t, 2, strings.Count(html, `<div class="msg `), "messages without content are left out")
+	assert.NotContains(t, html, "This link expires")
+}
@@ -375,7 +375,7 @@ func TestShutdown_MultipleComponentCombinations(t *testing.T) {
 			// Initialize components based on test case
 			var sessionMgr *session.SessionManager
 			var messageRouter *router.MessageRouter
-			var adminLimiter *ratelimit.MessageLimiter
+			var adminLimiter ratelimit.Limiter
 			var wsHandler *websocket.Handler
 
 			if tc.initSessionMgr {
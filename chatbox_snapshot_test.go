@@ -0,0 +1,95 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotHandlers_CreateListRestore(t *testing.T) {
+	now := time.Now()
+	source := &session.Session{
+		// Snapshots share one collection across tests, so the session ID is unique
+		ID:        fmt.Sprintf("snapshot-source-%d", now.UnixNano()),
+		UserID:    "user-1",
+		Name:      "Snapshot me",
+		StartTime: now,
+		Messages: []*session.Message{
+			{Content: "question", Timestamp: now, Sender: "user"},
+			{Content: "answer", Timestamp: now, Sender: "ai"},
+		},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{source})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	sessionManager := session.NewSessionManager(15*time.Minute, logger)
+
+	serve := func(handler gin.HandlerFunc, userID, path, body string, params gin.Params) *httptest.ResponseRecorder {
+		c, w := createTestHTTPRequest("POST", path, createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Request = httptest.NewRequest("POST", path, strings.NewReader(body))
+		c.Params = params
+		handler(c)
+		return w
+	}
+	sessionParams := gin.Params{gin.Param{Key: "sessionID", Value: source.ID}}
+	create := handleCreateSnapshot(storageService, logger)
+
+	assert.Equal(t, http.StatusNotFound, serve(create, "user-2", "/snapshot", "", sessionParams).Code, "only the owner may snapshot")
+	assert.Equal(t, http.StatusBadRequest, serve(create, "user-1", "/snapshot", `{"label": "`+strings.Repeat("x", 101)+`"}`, sessionParams).Code)
+
+	w := serve(create, "user-1", "/snapshot", `{"label": " before edits "}`, sessionParams)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var snapshot map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &snapshot))
+	assert.Equal(t, "before edits", snapshot["label"])
+	assert.Equal(t, float64(2), snapshot["message_count"])
+	snapshotID := snapshot["id"].(string)
+
+	w = serve(handleListSnapshots(storageService, logger), "user-1", "/snapshots", "", sessionParams)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Snapshots []map[string]interface{} `json:"snapshots"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Snapshots, 1)
+	assert.Equal(t, snapshotID, list.Snapshots[0]["id"])
+
+	// The session moves on after the snapshot
+	require.NoError(t, storageService.AddMessage(source.ID, &session.Message{Content: "follow-up", Timestamp: now, Sender: "user"}))
+
+	restore := handleRestoreSnapshot(storageService, sessionManager, nil, nil, logger)
+	snapshotParams := gin.Params{gin.Param{Key: "snapshotID", Value: snapshotID}}
+	assert.Equal(t, http.StatusNotFound, serve(restore, "user-2", "/restore", "", snapshotParams).Code, "only the owner may restore")
+
+	w = serve(restore, "user-1", "/restore", "", snapshotParams)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, snapshotID, resp["restored_from"])
+	assert.Equal(t, float64(2), resp["message_count"])
+
+	restored, err := storageService.GetSession(resp["session_id"].(string))
+	require.NoError(t, err)
+	require.Len(t, restored.Messages, 2)
+	assert.Equal(t, "answer", restored.Messages[1].Content)
+	assert.Equal(t, "Snapshot me", restored.Name)
+
+	// The original keeps its later messages
+	original, err := storageService.GetSession(source.ID)
+	require.NoError(t, err)
+	assert.Len(t, original.Messages, 3)
+}
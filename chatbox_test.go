@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/chatbox/internal/constants"
 	"github.com/real-rm/chatbox/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +23,7 @@ import (
 	"github.com/real-rm/golog"
 	"github.com/real-rm/gomongo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // performRequest is a helper function to perform HTTP requests in tests
@@ -203,7 +206,7 @@ func TestReadyCheckWithMongoDB(t *testing.T) {
 		router := gin.New()
 
 		// Register the ready check handler with nil MongoDB
-		router.GET("/readyz", handleReadyCheck(nil, nil, testLogger))
+		router.GET("/readyz", handleReadyCheck(readinessChecks(nil, nil, nil, ""), testLogger))
 
 		// Create a test request
 		w := performRequest(router, "GET", "/readyz", nil)
@@ -262,7 +265,7 @@ func TestReadyCheckWithMongoDB(t *testing.T) {
 
 			// Test with nil mongo
 			router := gin.New()
-			router.GET("/readyz", handleReadyCheck(nil, nil, logger))
+			router.GET("/readyz", handleReadyCheck(readinessChecks(nil, nil, nil, ""), logger))
 			w := performRequest(router, "GET", "/readyz", nil)
 			assert.Equal(t, 503, w.Code)
 			return
@@ -271,7 +274,7 @@ func TestReadyCheckWithMongoDB(t *testing.T) {
 		// If initialization succeeded (connection pool created but not tested yet),
 		// the Ping() call in the handler should fail
 		router := gin.New()
-		router.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+		router.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 
 		w := performRequest(router, "GET", "/readyz", nil)
 
@@ -323,7 +326,7 @@ func TestReadyCheckWithMongoDB(t *testing.T) {
 
 		// Create a test router
 		router := gin.New()
-		router.GET("/readyz", handleReadyCheck(mongo, nil, logger))
+		router.GET("/readyz", handleReadyCheck(readinessChecks(mongo, nil, nil, ""), logger))
 
 		w := performRequest(router, "GET", "/readyz", nil)
 
@@ -683,7 +686,7 @@ func TestHandleReadyCheck(t *testing.T) {
 		logger := CreateTestLogger(t)
 		defer logger.Close()
 
-		handler := handleReadyCheck(nil, nil, logger)
+		handler := handleReadyCheck(readinessChecks(nil, nil, nil, ""), logger)
 
 		router := gin.New()
 		router.GET("/readyz", handler)
@@ -700,7 +703,7 @@ func TestHandleReadyCheck(t *testing.T) {
 		logger := CreateTestLogger(t)
 		defer logger.Close()
 
-		handler := handleReadyCheck(nil, nil, logger)
+		handler := handleReadyCheck(readinessChecks(nil, nil, nil, ""), logger)
 
 		router := gin.New()
 		router.GET("/readyz", handler)
@@ -726,7 +729,7 @@ func TestHandleReadyCheck(t *testing.T) {
 		logger := CreateTestLogger(t)
 		defer logger.Close()
 
-		handler := handleReadyCheck(nil, nil, logger)
+		handler := handleReadyCheck(readinessChecks(nil, nil, nil, ""), logger)
 
 		router := gin.New()
 		router.GET("/readyz", handler)
@@ -784,7 +787,7 @@ func TestAuthMiddleware_ValidAdminToken(t *testing.T) {
 
 	// Create test router with middleware
 	router := gin.New()
-	router.Use(authMiddleware(validator, logger))
+	router.Use(authMiddleware(validator, authz.Default(), logger))
 	router.GET("/test", func(c *gin.Context) {
 		// Verify claims are set in context
 		claimsInterface, exists := c.Get("claims")
@@ -829,7 +832,7 @@ func TestAuthMiddleware_ValidChatAdminToken(t *testing.T) {
 
 	// Create test router with middleware
 	router := gin.New()
-	router.Use(authMiddleware(validator, logger))
+	router.Use(authMiddleware(validator, authz.Default(), logger))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
@@ -853,7 +856,7 @@ func TestAuthMiddleware_MissingAuthHeader(t *testing.T) {
 	defer logger.Close()
 
 	router := gin.New()
-	router.Use(authMiddleware(validator, logger))
+	router.Use(authMiddleware(validator, authz.Default(), logger))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
@@ -877,7 +880,7 @@ func TestAuthMiddleware_InvalidTokenFormat(t *testing.T) {
 	defer logger.Close()
 
 	router := gin.New()
-	router.Use(authMiddleware(validator, logger))
+	router.Use(authMiddleware(validator, authz.Default(), logger))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
@@ -911,7 +914,7 @@ func TestAuthMiddleware_ExpiredToken(t *testing.T) {
 	tokenString, _ := token.SignedString([]byte(testSecret))
 
 	router := gin.New()
-	router.Use(authMiddleware(validator, logger))
+	router.Use(authMiddleware(validator, authz.Default(), logger))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
@@ -945,7 +948,7 @@ func TestAuthMiddleware_NonAdminRole(t *testing.T) {
 	tokenString, _ := token.SignedString([]byte(testSecret))
 
 	router := gin.New()
-	router.Use(authMiddleware(validator, logger))
+	router.Use(authMiddleware(validator, authz.Default(), logger))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
@@ -960,6 +963,55 @@ func TestAuthMiddleware_NonAdminRole(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Insufficient permissions")
 }
 
+// TestPermissionMiddleware tests that admin endpoints require the permission
+// configured for the caller's roles
+func TestPermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testSecret := "test-secret-key-for-jwt-validation"
+	validator := auth.NewJWTValidator(testSecret)
+	logger := CreateTestLogger(t)
+	defer logger.Close()
+
+	policy, err := authz.NewPolicy(map[string][]authz.Permission{
+		"support": {authz.PermViewSessions, authz.PermTakeover},
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(authMiddleware(validator, policy, logger))
+	router.GET("/permissions", handleGetPermissions(policy))
+	router.GET("/sessions", permissionMiddleware(policy, authz.PermViewSessions, logger), func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+	router.DELETE("/users/u1/data", permissionMiddleware(policy, authz.PermPurge, logger), func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+
+	request := func(method, path string, roles []string) *httptest.ResponseRecorder {
+		claims := jwt.MapClaims{
+			"user_id": "admin-123",
+			"roles":   roles,
+			"exp":     time.Now().Add(time.Hour).Unix(),
+			"iat":     time.Now().Unix(),
+		}
+		tokenString, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenString)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, 200, request("GET", "/sessions", []string{"support"}).Code)
+	assert.Equal(t, 403, request("DELETE", "/users/u1/data", []string{"support"}).Code)
+	assert.Equal(t, 403, request("GET", "/sessions", []string{constants.RoleAdmin}).Code, "roles missing from the policy are not admins")
+
+	w := request("GET", "/permissions", []string{"support"})
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"roles": ["support"], "permissions": ["takeover", "view_sessions"]}`, w.Body.String())
+}
+
 // TestAuthMiddleware_InvalidSignature tests authMiddleware with wrong signature
 func TestAuthMiddleware_InvalidSignature(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -980,7 +1032,7 @@ func TestAuthMiddleware_InvalidSignature(t *testing.T) {
 	tokenString, _ := token.SignedString([]byte("wrong-secret"))
 
 	router := gin.New()
-	router.Use(authMiddleware(validator, logger))
+	router.Use(authMiddleware(validator, authz.Default(), logger))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
@@ -1198,8 +1250,43 @@ func TestAdminRateLimitMiddleware_BlocksWhenExceeded(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	assert.Equal(t, 429, w.Code, "Request should be rate limited")
-	assert.Contains(t, w.Body.String(), "rate_limit_exceeded")
-	assert.Contains(t, w.Body.String(), "retry_after_ms")
+	assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+	assert.Contains(t, w.Body.String(), "retry_after")
+}
+
+// TestRatePolicyMiddleware tests per-endpoint policies limit only their endpoints
+func TestRatePolicyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := CreateTestLogger(t)
+	defer logger.Close()
+
+	policies := ratelimit.NewPolicies([]ratelimit.Policy{{Name: constants.RatePolicyExport, Limit: 1, Window: time.Minute}}, nil)
+	defer policies.StopCleanup()
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: "admin-export", Roles: []string{"admin"}})
+		c.Next()
+	})
+	ok := func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	}
+	router.GET("/export", ratePolicyMiddleware(policies, constants.RatePolicyExport, logger), ok)
+	router.GET("/sessions", ratePolicyMiddleware(policies, constants.RatePolicyList, logger), ok)
+
+	codes := func(path string, n int) []int {
+		var got []int
+		for i := 0; i < n; i++ {
+			req, _ := http.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			got = append(got, w.Code)
+		}
+		return got
+	}
+	assert.Equal(t, []int{200, 429}, codes("/export", 2), "export is limited by its policy")
+	assert.Equal(t, []int{200, 200, 200}, codes("/sessions", 3), "unconfigured policies do not limit")
 }
 
 // TestAdminRateLimitMiddleware_ReturnsRetryAfterHeader tests Retry-After header is set
@@ -0,0 +1,70 @@
+package chatbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/email"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleEndSession_TranscriptEmail(t *testing.T) {
+	sess := createTestSession("transcript-user", "active", true)
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{sess})
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	logger := setupTestLogger(t)
+	mailer, err := email.NewMailer(email.Config{Host: "smtp.example.invalid", From: "support@example.com"}, nil, logger)
+	require.NoError(t, err)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_ = mailer.Shutdown(ctx)
+	}()
+	sessionManager := session.NewSessionManager(30*time.Second, logger)
+
+	post := func(claims *auth.Claims, body string) *httptest.ResponseRecorder {
+		engine := gin.New()
+		engine.POST("/sessions/:sessionID/end", func(c *gin.Context) {
+			c.Set("claims", claims)
+		}, handleEndSession(storageService, sessionManager, nil, mailer, logger))
+		req := httptest.NewRequest(http.MethodPost, "/sessions/"+sess.ID+"/end", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	user := createMockJWTClaims("transcript-user", "Jane", []string{"user"})
+	guest := createMockJWTClaims("transcript-user", "Guest", []string{constants.RoleGuest})
+	guest.Email = "jane@example.com"
+	impersonated := createMockJWTClaims("transcript-user", "Jane", []string{constants.RoleImpersonation})
+	impersonated.Email = "jane@example.com"
+	impersonated.ImpersonatorID = "admin-1"
+
+	// The recipient is never taken from the request
+	assert.Equal(t, http.StatusForbidden, post(user, `{"send_transcript": true}`).Code, "no verified address")
+	assert.Equal(t, http.StatusForbidden, post(guest, `{"send_transcript": true}`).Code)
+	assert.Equal(t, http.StatusForbidden, post(impersonated, `{"send_transcript": true}`).Code)
+
+	verified := createMockJWTClaims("transcript-user", "Jane", []string{"user"})
+	verified.Email = "jane@example.com"
+	w := post(verified, `{"send_transcript": true}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"transcript_email":"queued"`)
+
+	// Each session's transcript is emailed at most once
+	w = post(verified, `{"send_transcript": true}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"transcript_email":"already_sent"`)
+}
@@ -0,0 +1,100 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUserBlockTestRouter serves the user block and report endpoints, as an
+// admin of the default tenant and as user-1. Requests rejected before storage
+// is used do not need MongoDB.
+func newUserBlockTestRouter(t *testing.T, storageService *storage.StorageService) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	router := gin.New()
+	admin := router.Group("/admin", func(c *gin.Context) {
+		c.Set("claims", createMockJWTClaims("admin-1", "Admin", []string{constants.RoleAdmin}))
+	})
+	wsHandler := websocket.NewHandler(nil, nil, logger, 1048576)
+	admin.PUT("/user-blocks/:userID", handleBlockUser(storageService, wsHandler, logger))
+	admin.DELETE("/user-blocks/:userID", handleUnblockUser(storageService, logger))
+	router.POST("/report", func(c *gin.Context) {
+		c.Set("claims", createMockJWTClaims("user-1", "User", []string{"user"}))
+	}, handleReportMessage(storageService, logger))
+	return router
+}
+
+func TestBlockUser_Validation(t *testing.T) {
+	router := newUserBlockTestRouter(t, &storage.StorageService{})
+
+	for name, tc := range map[string]struct {
+		body   string
+		status int
+	}{
+		"negative minutes": {`{"duration_minutes": -5}`, http.StatusBadRequest},
+		"too long":         {`{"duration_minutes": 600000}`, http.StatusBadRequest},
+		"long reason":      {`{"reason": "` + strings.Repeat("r", constants.MaxBanReasonLength+1) + `"}`, http.StatusBadRequest},
+		"malformed body":   {`{`, http.StatusBadRequest},
+		"other tenant":     {`{"tenant_id": "acme"}`, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/admin/user-blocks/user-1", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Code, name)
+	}
+
+	// Only super admins may lift blocks of another tenant
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/user-blocks/user-1?tenant_id=acme", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestReportMessage_Validation(t *testing.T) {
+	router := newUserBlockTestRouter(t, &storage.StorageService{})
+
+	for name, body := range map[string]string{
+		"malformed body":   `{`,
+		"no session":       `{"message_index": 1, "category": "spam"}`,
+		"no index":         `{"session_id": "session-1", "category": "spam"}`,
+		"negative index":   `{"session_id": "session-1", "message_index": -1, "category": "spam"}`,
+		"unknown category": `{"session_id": "session-1", "message_index": 1, "category": "rude"}`,
+		"reason too long":  `{"session_id": "session-1", "message_index": 1, "category": "spam", "reason": "` + strings.Repeat("r", constants.MaxReportReasonLength+1) + `"}`,
+		"missing category": `{"session_id": "session-1", "message_index": 1}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	// Nothing to report when messages are not stored
+	anonymized := &storage.StorageService{}
+	anonymized.SetAnonymizationSalt([]byte("test-anonymization-salt"))
+	require.NoError(t, anonymized.SetAnonymizedMode(true))
+	router = newUserBlockTestRouter(t, anonymized)
+	req := httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(`{"session_id": "session-1", "message_index": 1, "category": "spam"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "FEATURE_DISABLED")
+}
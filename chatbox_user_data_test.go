@@ -0,0 +1,69 @@
+package chatbox
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleUserData_ExportAndErase(t *testing.T) {
+	subject := createTestSession("gdpr-user", "First", false)
+	other := createTestSession("other-user", "Other", true)
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{subject, other})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	sessionManager := session.NewSessionManager(15*time.Minute, logger)
+	live, err := sessionManager.CreateSession("gdpr-user")
+	require.NoError(t, err)
+	require.NoError(t, storageService.CreateSession(live))
+
+	claims := createMockJWTClaims("admin-1", "Admin", []string{"admin"})
+	call := func(handler gin.HandlerFunc, method, userID string) *bytes.Buffer {
+		c, w := createTestHTTPRequest(method, "/chatbox/users/"+userID, claims)
+		c.Params = gin.Params{gin.Param{Key: "userID", Value: userID}}
+		handler(c)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w.Body
+	}
+
+	body := call(handleExportUserData(storageService, logger), "GET", "gdpr-user")
+	zr, err := zip.NewReader(bytes.NewReader(body.Bytes()), int64(body.Len()))
+	require.NoError(t, err)
+	names := make([]string, 0, len(zr.File))
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"manifest.json",
+		"sessions/" + subject.ID + ".json",
+		"sessions/" + live.ID + ".json",
+	}, names)
+
+	body = call(handleDeleteUserData(storageService, sessionManager, logger), "DELETE", "gdpr-user")
+	assert.Contains(t, body.String(), `"sessions_deleted":2`)
+
+	// Erased sessions are gone from storage and memory; other users keep theirs
+	_, err = storageService.GetSession(subject.ID)
+	assert.Error(t, err)
+	_, err = sessionManager.GetSession(live.ID)
+	assert.Error(t, err)
+	_, err = storageService.GetSession(other.ID)
+	assert.NoError(t, err)
+
+	body = call(handleExportUserData(storageService, logger), "GET", "gdpr-user")
+	zr, err = zip.NewReader(bytes.NewReader(body.Bytes()), int64(body.Len()))
+	require.NoError(t, err)
+	assert.Len(t, zr.File, 1, "only the manifest remains")
+}
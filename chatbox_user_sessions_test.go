@@ -0,0 +1,170 @@
+package chatbox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRenameSession(t *testing.T) {
+	sess := &session.Session{
+		ID:        "rename-session-1",
+		UserID:    "user-1",
+		Name:      "Old name",
+		StartTime: time.Now(),
+		Messages:  []*session.Message{},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{sess})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	handler := handleRenameSession(storageService, session.NewSessionManager(15*time.Minute, logger), logger)
+	rename := func(userID, body string) *httptest.ResponseRecorder {
+		c, w := createTestHTTPRequest("PATCH", "/sessions/"+sess.ID, createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Request = httptest.NewRequest("PATCH", "/sessions/"+sess.ID, strings.NewReader(body))
+		c.Params = gin.Params{gin.Param{Key: "sessionID", Value: sess.ID}}
+		handler(c)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, rename("user-1", `{"name": "   "}`).Code)
+	assert.Equal(t, http.StatusBadRequest, rename("user-1", `{"name": "`+strings.Repeat("a", 101)+`"}`).Code)
+	assert.Equal(t, http.StatusNotFound, rename("user-2", `{"name": "Stolen"}`).Code)
+
+	require.Equal(t, http.StatusOK, rename("user-1", `{"name": " Trip planning "}`).Code)
+	stored, err := storageService.GetSession(sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Trip planning", stored.Name)
+}
+
+func TestHandleDeleteSession(t *testing.T) {
+	sess := &session.Session{
+		ID:        "delete-session-1",
+		UserID:    "user-1",
+		StartTime: time.Now(),
+		Messages:  []*session.Message{},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{sess})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	handler := handleDeleteSession(storageService, session.NewSessionManager(15*time.Minute, logger), nil, logger)
+	del := func(userID string) *httptest.ResponseRecorder {
+		c, w := createTestHTTPRequest("DELETE", "/sessions/"+sess.ID, createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Params = gin.Params{gin.Param{Key: "sessionID", Value: sess.ID}}
+		handler(c)
+		return w
+	}
+
+	// Only the owner may delete
+	assert.Equal(t, http.StatusNotFound, del("user-2").Code)
+	_, err = storageService.GetSession(sess.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, del("user-1").Code)
+	_, err = storageService.GetSession(sess.ID)
+	assert.Error(t, err, "deleted sessions are hidden")
+	assert.Equal(t, http.StatusNotFound, del("user-1").Code)
+}
+
+func TestHandleMessageFeedback(t *testing.T) {
+	sess := &session.Session{
+		ID:        "feedback-session-1",
+		UserID:    "user-1",
+		StartTime: time.Now(),
+		Messages: []*session.Message{
+			{Content: "Hi", Sender: constants.SenderUser, Timestamp: time.Now()},
+			{Content: "Hello!", Sender: constants.SenderAI, Timestamp: time.Now()},
+		},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{sess})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	handler := handleMessageFeedback(storageService, session.NewSessionManager(15*time.Minute, logger), logger)
+	rate := func(userID, index, body string) *httptest.ResponseRecorder {
+		path := "/sessions/" + sess.ID + "/messages/" + index + "/feedback"
+		c, w := createTestHTTPRequest("PUT", path, createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Request = httptest.NewRequest("PUT", path, strings.NewReader(body))
+		c.Params = gin.Params{{Key: "sessionID", Value: sess.ID}, {Key: "index", Value: index}}
+		handler(c)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, rate("user-1", "1", `{"rating": "meh"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, rate("user-1", "-1", `{"rating": "up"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, rate("user-1", "1", `{"rating": "up", "comment": "`+strings.Repeat("a", 1001)+`"}`).Code)
+	assert.Equal(t, http.StatusNotFound, rate("user-1", "0", `{"rating": "up"}`).Code, "user messages cannot be rated")
+	assert.Equal(t, http.StatusNotFound, rate("user-2", "1", `{"rating": "up"}`).Code)
+
+	require.Equal(t, http.StatusOK, rate("user-1", "1", `{"rating": "down", "comment": " Missed the point "}`).Code)
+	stored, err := storageService.GetSession(sess.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.Messages[1].Feedback)
+	assert.Equal(t, constants.FeedbackDown, stored.Messages[1].Feedback.Rating)
+	assert.Equal(t, "Missed the point", stored.Messages[1].Feedback.Comment)
+}
+
+func TestHandlePinMessage(t *testing.T) {
+	sess := &session.Session{
+		ID:        "pin-session-1",
+		UserID:    "user-1",
+		StartTime: time.Now(),
+		Messages: []*session.Message{
+			{Content: "Hi", Sender: constants.SenderUser, Timestamp: time.Now()},
+			{Content: "Hello!", Sender: constants.SenderAI, Timestamp: time.Now()},
+		},
+	}
+	storageService, cleanup := setupTestStorageWithData(t, []*session.Session{sess})
+	defer cleanup()
+
+	logger, err := golog.InitLog(golog.LogConfig{Level: "error", StandardOutput: false, Dir: "/tmp"})
+	require.NoError(t, err)
+	defer logger.Close()
+
+	sessionManager := session.NewSessionManager(15*time.Minute, logger)
+	pin := handlePinMessage(storageService, sessionManager, logger)
+	unpin := handleUnpinMessage(storageService, sessionManager, logger)
+	call := func(handler gin.HandlerFunc, method, userID, index string) *httptest.ResponseRecorder {
+		path := "/sessions/" + sess.ID + "/messages/" + index + "/pin"
+		c, w := createTestHTTPRequest(method, path, createMockJWTClaims(userID, "User", []string{"user"}))
+		c.Params = gin.Params{{Key: "sessionID", Value: sess.ID}, {Key: "index", Value: index}}
+		handler(c)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, call(pin, "POST", "user-1", "first").Code)
+	assert.Equal(t, http.StatusNotFound, call(pin, "POST", "user-1", "2").Code)
+	assert.Equal(t, http.StatusNotFound, call(pin, "POST", "user-2", "1").Code)
+
+	w := call(pin, "POST", "user-1", "1")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"message_index":1`)
+	stored, err := storageService.GetSession(sess.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Pins, 1)
+	assert.Equal(t, "user-1", stored.Pins[0].PinnedBy)
+
+	require.Equal(t, http.StatusOK, call(unpin, "DELETE", "user-1", "1").Code)
+	stored, err = storageService.GetSession(sess.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Pins)
+}
@@ -0,0 +1,65 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/widget"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWidgetEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bundle, err := widget.Load()
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/api/chat/widget/loader.js", handleWidgetLoader(bundle))
+	router.GET("/api/chat/widget/version", handleWidgetVersion(bundle, "/api/chat"))
+	router.GET("/api/chat/widget/:version/:file", handleWidgetAsset(bundle))
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/chat/widget/version", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	var version struct {
+		Version   string `json:"version"`
+		WidgetURL string `json:"widget_url"`
+		LoaderURL string `json:"loader_url"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &version))
+	assert.Equal(t, bundle.Version(), version.Version)
+	assert.Equal(t, "/api/chat/widget/loader.js", version.LoaderURL)
+
+	w = get(version.LoaderURL, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, widget.ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), bundle.Version()+"/widget.js")
+
+	w = get(version.WidgetURL, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, widget.ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Cache-Control"), "immutable")
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = get(version.WidgetURL, etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/api/chat/widget/0123456789ab/widget.js", "").Code, "old version")
+	assert.Equal(t, http.StatusNotFound, get("/api/chat/widget/"+bundle.Version()+"/other.js", "").Code)
+}
@@ -0,0 +1,88 @@
+// Command restore restores the sessions of a backup archive, written by
+// POST /admin/backups, into the MongoDB session collection.
+//
+//	restore -file ./backups/chat-sessions-20260101T000000Z.bak -strategy merge
+//	restore -location <goupload path> -strategy skip
+//
+// It reads the same configuration as the server (CHATBOX config file and
+// environment), including the backup key and the message encryption key that
+// restored content is encrypted with.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	chatbox "github.com/real-rm/chatbox"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/goconfig"
+	"github.com/real-rm/golog"
+	"github.com/real-rm/gomongo"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Restore failed: %v", err)
+	}
+}
+
+func run() error {
+	file := flag.String("file", "", "local archive file to restore")
+	location := flag.String("location", "", "archive location in the configured chatbox.backup target")
+	strategy := flag.String("strategy", string(storage.ConflictSkip), "what to do with sessions that already exist: skip, overwrite or merge")
+	flag.Parse()
+
+	// No else needed: early return pattern (guard clause)
+	if (*file == "") == (*location == "") {
+		return errors.New("exactly one of -file and -location is required")
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := goconfig.LoadConfig(); err != nil {
+		return err
+	}
+	cfg, err := goconfig.Default()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	logDir, _ := cfg.ConfigStringWithDefault("log.dir", constants.DefaultLogDir)
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            logDir,
+		Level:          "info",
+		StandardOutput: true,
+		InfoFile:       "restore.log",
+		ErrorFile:      "restore-error.log",
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+
+	mongo, err := gomongo.InitMongoDB(logger, cfg)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to initialize MongoDB: %w", err)
+	}
+
+	// Stop between sessions on Ctrl-C; restored sessions are kept
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	result, err := chatbox.RestoreBackup(ctx, cfg, logger, mongo, *file, *location, *strategy)
+	// No else needed: optional operation (report the sessions restored before a failure)
+	if result != nil {
+		encoded, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(os.Stdout, string(encoded))
+	}
+	return err
+}
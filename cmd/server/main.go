@@ -66,6 +66,21 @@ func setupSignalHandler() chan os.Signal {
 	return sigChan
 }
 
+// reloadOnSignal reloads the chatbox configuration on every signal received
+// on reloadChan until done is closed
+func reloadOnSignal(reloadChan <-chan os.Signal, done <-chan struct{}, logger *golog.Logger) {
+	for {
+		select {
+		case <-reloadChan:
+			if _, err := chatbox.Reload(); err != nil {
+				logger.Warn("Configuration reload failed", "error", err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
 // runWithSignalChannel is a testable version of run that accepts a signal channel
 func runWithSignalChannel(sigChan chan os.Signal) error {
 	// Load configuration
@@ -109,6 +124,14 @@ func runWithSignalChannel(sigChan chan os.Signal) error {
 
 	logger.Info("Server started", "addr", addr)
 
+	// Reload the safe-to-change configuration on SIGHUP without dropping connections
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	defer signal.Stop(reloadChan)
+	reloadDone := make(chan struct{})
+	defer close(reloadDone)
+	go reloadOnSignal(reloadChan, reloadDone, logger)
+
 	// Wait for shutdown signal
 	<-sigChan
 	logger.Info("Shutting down gracefully")
@@ -287,6 +287,40 @@ func TestSetupSignalHandler(t *testing.T) {
 	})
 }
 
+// TestReloadOnSignal tests that reloadOnSignal keeps handling reload signals,
+// including failed reloads, until done is closed
+func TestReloadOnSignal(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	setupConfigFile()
+	defer cleanupConfigFile()
+
+	cfg, err := loadConfiguration()
+	require.NoError(t, err)
+	logger, err := initializeLogger(cfg)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	reloadChan := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		reloadOnSignal(reloadChan, done, logger)
+		close(stopped)
+	}()
+
+	// Reloads that fail, e.g. before the chatbox is registered, do not stop the loop
+	reloadChan <- syscall.SIGHUP
+	reloadChan <- syscall.SIGHUP
+
+	close(done)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("reloadOnSignal did not stop")
+	}
+}
+
 // TestRunWithSignalChannel tests the runWithSignalChannel function
 //
 // **Validates: Requirements 6.5**
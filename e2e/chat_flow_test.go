@@ -0,0 +1,147 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestE2E_ChatHelpTakeoverShutdown walks one session through the full lifecycle:
+// connect → chat with the LLM → help request → admin takeover → admin message →
+// transcript check → graceful shutdown.
+func TestE2E_ChatHelpTakeoverShutdown(t *testing.T) {
+	llm := fakeLLM(t, "Hello from the model")
+	svc := startService(t, llm.URL)
+
+	userID := fmt.Sprintf("e2e-user-%d", time.Now().UnixNano())
+	userToken := token(t, userID, "E2E User", "user")
+	adminToken := token(t, "e2e-admin", "E2E Admin", "admin")
+
+	// Connect: the server announces available models right away
+	conn := svc.dial(t, userToken)
+	status := readUntil(t, conn, message.TypeConnectionStatus)
+	require.NotEmpty(t, status.Models)
+
+	// Chat: the client proposes a session ID and the server answers with the authoritative one
+	require.NoError(t, conn.WriteJSON(&message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: fmt.Sprintf("client-%d", time.Now().UnixNano()),
+		Content:   "Hi there",
+		Sender:    message.SenderUser,
+	}))
+	reply := readUntil(t, conn, message.TypeAIResponse)
+	sessionID := reply.SessionID
+	require.NotEmpty(t, sessionID)
+	content := reply.Content
+	// Streamed replies end with a chunk marked done
+	for reply.Metadata["done"] != "true" {
+		reply = readUntil(t, conn, message.TypeAIResponse)
+		content += reply.Content
+	}
+	assert.Equal(t, "Hello from the model", content)
+
+	// Help request
+	require.NoError(t, conn.WriteJSON(&message.Message{
+		Type:      message.TypeHelpRequest,
+		SessionID: sessionID,
+		Sender:    message.SenderUser,
+	}))
+	ack := readUntil(t, conn, message.TypeConnectionStatus)
+	assert.Contains(t, ack.Content, "Help request sent")
+
+	// Takeover over REST
+	require.Equal(t, http.StatusOK, svc.post(t, "/chatbox/admin/takeover/"+sessionID, adminToken, ""))
+	join := readUntil(t, conn, message.TypeAdminJoin)
+	assert.Equal(t, "e2e-admin", join.Metadata["admin_id"])
+
+	// Admin message over REST
+	require.Equal(t, http.StatusCreated,
+		svc.post(t, "/chatbox/admin/sessions/"+sessionID+"/messages", adminToken, `{"content":"An admin is here"}`))
+	adminMsg := readUntil(t, conn, message.TypeAdminMessage)
+	assert.Equal(t, "An admin is here", adminMsg.Content)
+
+	// Transcript: persisted in MongoDB with the system events in order
+	transcript := fetchTranscript(t, svc, sessionID, userToken)
+	var senders, events []string
+	for _, msg := range transcript {
+		senders = append(senders, msg.Sender)
+		// No else needed: optional operation (only collect system events)
+		if msg.Event != "" {
+			events = append(events, msg.Event)
+		}
+	}
+	assert.Contains(t, senders, constants.SenderUser)
+	assert.Contains(t, senders, constants.SenderAI)
+	assert.Contains(t, senders, constants.SenderAdmin)
+	assert.Equal(t, []string{constants.SystemEventHandoff, constants.SystemEventAdminTakeover}, events)
+
+	// Shutdown: open connections are closed by the server
+	require.NoError(t, svc.shutdown())
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(readTimeout)))
+	for {
+		_, _, err := conn.ReadMessage()
+		// No else needed: optional operation (drain messages queued before the close)
+		if err == nil {
+			continue
+		}
+		// Either a close frame or an abrupt EOF is acceptable; a timeout is not
+		var netErr net.Error
+		assert.False(t, errors.As(err, &netErr) && netErr.Timeout(), "connection was not closed on shutdown: %v", err)
+		break
+	}
+}
+
+// TestE2E_TicketConnect connects with a one-time ticket, which the service keeps
+// in Redis so any replica can redeem it, and checks a ticket opens one connection.
+func TestE2E_TicketConnect(t *testing.T) {
+	llm := fakeLLM(t, "unused")
+	svc := startService(t, llm.URL)
+
+	userToken := token(t, fmt.Sprintf("e2e-ticket-%d", time.Now().UnixNano()), "E2E Ticket", "user")
+	ticket := svc.issueTicket(t, userToken)
+	require.NotEmpty(t, ticket)
+
+	conn, status := svc.dialTicket(t, ticket)
+	require.Equal(t, http.StatusSwitchingProtocols, status)
+	readUntil(t, conn, message.TypeConnectionStatus)
+
+	_, status = svc.dialTicket(t, ticket)
+	assert.Equal(t, http.StatusUnauthorized, status, "a ticket opens one connection")
+}
+
+// transcriptMessage is the subset of a stored message the tests inspect
+type transcriptMessage struct {
+	Content string `json:"content"`
+	Sender  string `json:"sender"`
+	Event   string `json:"event"`
+}
+
+// fetchTranscript loads a session's stored messages through the user API
+func fetchTranscript(t *testing.T, svc *service, sessionID, userToken string) []transcriptMessage {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, svc.baseURL+"/chatbox/sessions/"+sessionID, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+userToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Messages []transcriptMessage `json:"messages"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body.Messages
+}
@@ -0,0 +1,417 @@
+//go:build e2e
+
+// Package e2e contains end-to-end tests that boot the chatbox service with
+// chatbox.Register against real infrastructure started in Docker.
+//
+// MongoDB and Redis containers are started with dockertest. Redis backs the
+// rate limiter, cluster pub/sub routing and WebSocket connection tickets, so the
+// suite runs the service the way a multi-replica deployment does. Run with:
+//
+//	make test-e2e-go
+//
+// Set E2E_MONGO_URI or E2E_REDIS_URL to reuse an existing MongoDB or Redis
+// instead of starting a container.
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/real-rm/chatbox"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/goconfig"
+	"github.com/real-rm/golog"
+	"github.com/real-rm/gomongo"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	jwtSecret     = "E2e-Int3gr4t10n-S1gn1ng-K3y-F0r-Ch4tb0x!"
+	allowedOrigin = "http://localhost:3000"
+	mongoImage    = "mongo"
+	mongoTag      = "7.0"
+	redisImage    = "redis"
+	redisTag      = "7-alpine"
+	startTimeout  = 60 * time.Second
+	readTimeout   = 10 * time.Second
+)
+
+var (
+	// mongoURI and redisURL are set by TestMain to the MongoDB and Redis used by
+	// every test in the package
+	mongoURI string
+	redisURL string
+
+	mongoOnce   sync.Once
+	sharedMongo *gomongo.Mongo
+	mongoErr    error
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(runSuite(m))
+}
+
+// runSuite starts shared infrastructure, runs the tests, and tears everything down
+func runSuite(m *testing.M) int {
+	mongoURI = os.Getenv("E2E_MONGO_URI")
+	redisURL = os.Getenv("E2E_REDIS_URL")
+
+	// No else needed: optional operation (both services provided externally)
+	if mongoURI == "" || redisURL == "" {
+		pool, err := dockertest.NewPool("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "e2e: failed to connect to Docker: %v\n", err)
+			return 1
+		}
+		pool.MaxWait = startTimeout
+
+		// No else needed: optional operation (reuse an externally provided MongoDB)
+		if mongoURI == "" {
+			resource, err := startContainer(pool, mongoImage, mongoTag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "e2e: failed to start MongoDB: %v\n", err)
+				return 1
+			}
+			defer purge(pool, resource)
+
+			mongoURI = fmt.Sprintf("mongodb://%s/chat", resource.GetHostPort("27017/tcp"))
+			if err := pool.Retry(pingMongo); err != nil {
+				fmt.Fprintf(os.Stderr, "e2e: MongoDB did not become ready: %v\n", err)
+				return 1
+			}
+		}
+
+		// No else needed: optional operation (reuse an externally provided Redis)
+		if redisURL == "" {
+			resource, err := startContainer(pool, redisImage, redisTag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "e2e: failed to start Redis: %v\n", err)
+				return 1
+			}
+			defer purge(pool, resource)
+
+			redisURL = fmt.Sprintf("redis://%s/0", resource.GetHostPort("6379/tcp"))
+			if err := pool.Retry(pingRedis); err != nil {
+				fmt.Fprintf(os.Stderr, "e2e: Redis did not become ready: %v\n", err)
+				return 1
+			}
+		}
+	}
+
+	return m.Run()
+}
+
+// startContainer runs image:tag with its exposed ports published on random host ports
+func startContainer(pool *dockertest.Pool, image, tag string) (*dockertest.Resource, error) {
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{Repository: image, Tag: tag}, func(config *docker.HostConfig) {
+		// Remove the container even if the suite is killed before purge runs
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s:%s: %w", image, tag, err)
+	}
+	// Stop the container if the suite hangs
+	_ = resource.Expire(uint(5 * time.Minute / time.Second))
+	return resource, nil
+}
+
+// purge removes a container started for the suite
+func purge(pool *dockertest.Pool, resource *dockertest.Resource) {
+	// No else needed: optional operation (report leftovers, the suite result stands)
+	if err := pool.Purge(resource); err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: failed to remove container: %v\n", err)
+	}
+}
+
+// pingMongo checks that the MongoDB at mongoURI answers
+func pingMongo() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(ctx)
+	return client.Ping(ctx, nil)
+}
+
+// pingRedis checks that the Redis at redisURL answers
+func pingRedis() error {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return err
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return client.Ping(ctx).Err()
+}
+
+// fakeLLM serves an OpenAI-compatible streaming endpoint that always answers reply
+func fakeLLM(t *testing.T, reply string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", reply)
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"total_tokens\":12}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// service is a chatbox instance listening on a random port
+type service struct {
+	baseURL string // http://host:port
+	server  *http.Server
+	stopped bool
+}
+
+// startService writes a config, connects to MongoDB, runs chatbox.Register, and
+// serves the engine on a random loopback port.
+func startService(t *testing.T, llmEndpoint string) *service {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	configContent := fmt.Sprintf(`
+[dbs]
+verbose = 0
+
+[dbs.chat]
+uri = %q
+
+[chatbox]
+jwt_secret = %q
+reconnect_timeout = "30s"
+encryption_key = ""
+llm_stream_timeout = "10s"
+allowed_origins = %q
+cors_allowed_origins = %q
+path_prefix = "/chatbox"
+rate_limit_backend = "redis"
+redis_url = %q
+
+[chatbox.cluster]
+enabled = true
+
+[[llm.providers]]
+id = "gpt-4"
+name = "Fake LLM"
+type = "openai"
+endpoint = %q
+apiKey = "e2e-key"
+model = "gpt-4"
+
+[mail.engines]
+ses = []
+smtp = []
+
+[[mail.engines.mock]]
+name = "mock"
+verbose = false
+`, mongoURI, jwtSecret, allowedOrigin, allowedOrigin, redisURL, llmEndpoint)
+
+	configFile, err := os.CreateTemp(t.TempDir(), "chatbox-e2e-*.toml")
+	if err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+	if _, err := configFile.WriteString(configContent); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	configFile.Close()
+
+	t.Setenv("RMBASE_FILE_CFG", configFile.Name())
+	t.Setenv("JWT_SECRET", jwtSecret)
+	goconfig.ResetConfig()
+	if err := goconfig.LoadConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	config, err := goconfig.Default()
+	if err != nil {
+		t.Fatalf("config accessor: %v", err)
+	}
+
+	logger, err := golog.InitLog(golog.LogConfig{Dir: t.TempDir(), Level: "error"})
+	if err != nil {
+		t.Fatalf("init logger: %v", err)
+	}
+	t.Cleanup(func() { logger.Close() })
+
+	// gomongo keeps a process-wide client, so connect once for the whole suite
+	mongoOnce.Do(func() {
+		sharedMongo, mongoErr = gomongo.InitMongoDB(logger, config)
+	})
+	if mongoErr != nil {
+		t.Fatalf("connect MongoDB: %v", mongoErr)
+	}
+
+	engine := gin.New()
+	if err := chatbox.Register(engine, config, logger, sharedMongo); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	svc := &service{
+		baseURL: "http://" + listener.Addr().String(),
+		server:  &http.Server{Handler: engine, ReadHeaderTimeout: 5 * time.Second},
+	}
+	go svc.server.Serve(listener)
+
+	t.Cleanup(func() {
+		// No else needed: optional operation (the test may have shut down already)
+		if !svc.stopped {
+			_ = svc.shutdown()
+		}
+	})
+	return svc
+}
+
+// shutdown runs chatbox.Shutdown, as gomain does on SIGTERM, then stops the HTTP server
+func (s *service) shutdown() error {
+	s.stopped = true
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := chatbox.Shutdown(ctx)
+	_ = s.server.Shutdown(ctx)
+	return err
+}
+
+// token signs a JWT for the given user and roles
+func token(t *testing.T, userID, name string, roles ...string) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"iat":     time.Now().Unix(),
+		"user_id": userID,
+		"name":    name,
+		"roles":   roles,
+	}).SignedString([]byte(jwtSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+// dial opens a WebSocket to the service as the token's user
+func (s *service) dial(t *testing.T, jwtToken string) *websocket.Conn {
+	t.Helper()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+jwtToken)
+	header.Set("Origin", allowedOrigin)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(strings.Replace(s.baseURL, "http", "ws", 1)+"/chatbox/ws", header)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial WebSocket (status %d): %v", status, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// issueTicket exchanges the token for a one-time WebSocket connection ticket
+func (s *service) issueTicket(t *testing.T, jwtToken string) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, s.baseURL+"/chatbox/ws/ticket", nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwtToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST ws/ticket: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST ws/ticket: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Ticket string `json:"ticket"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode ticket: %v", err)
+	}
+	return body.Ticket
+}
+
+// dialTicket opens a WebSocket with a connection ticket and returns the handshake status
+func (s *service) dialTicket(t *testing.T, ticket string) (*websocket.Conn, int) {
+	t.Helper()
+	header := http.Header{}
+	header.Set("Origin", allowedOrigin)
+
+	url := strings.Replace(s.baseURL, "http", "ws", 1) + "/chatbox/ws?ticket=" + ticket
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	status := 0
+	// No else needed: optional operation (resp is nil when the connection itself failed)
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	// No else needed: optional operation (rejected handshakes return no connection)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, status
+}
+
+// readUntil reads messages until one of type want arrives, failing after readTimeout
+func readUntil(t *testing.T, conn *websocket.Conn, want message.MessageType) *message.Message {
+	t.Helper()
+	deadline := time.Now().Add(readTimeout)
+	for {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			t.Fatalf("set read deadline: %v", err)
+		}
+		var msg message.Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s: %v", want, err)
+		}
+		if msg.Type == want {
+			return &msg
+		}
+	}
+}
+
+// post sends an authenticated JSON POST to the service and returns the status code
+func (s *service) post(t *testing.T, path, jwtToken, body string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, s.baseURL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwtToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
@@ -0,0 +1,368 @@
+// Package alerts evaluates usage alert rules, such as LLM tokens per day, the
+// LLM error rate or the help queue length, against configured thresholds.
+//
+// A Monitor reads each watched metric every interval from a Source and compares
+// it with the rules' thresholds. When a rule starts or stops firing, an
+// alert_firing or alert_resolved webhook event is published and logged. The
+// current state of every rule is listed by GET /admin/alerts. Each replica
+// evaluates the rules on its own, so per-replica metrics like the help queue
+// reflect that replica only.
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/webhook"
+	"github.com/real-rm/golog"
+)
+
+// Metrics rules may watch
+const (
+	MetricTokensPerDay = "tokens_per_day" // LLM tokens used over the last 24 hours
+	MetricErrorRate    = "error_rate"     // Percentage of LLM requests that failed since the previous evaluation
+	MetricQueueLength  = "queue_length"   // Sessions waiting in the help queue
+)
+
+// Metrics lists the metrics rules may watch
+var Metrics = []string{MetricTokensPerDay, MetricErrorRate, MetricQueueLength}
+
+// Alert states
+const (
+	StateUnknown = "unknown" // Not evaluated yet
+	StateOK      = "ok"
+	StateFiring  = "firing"
+)
+
+// ErrNoRules is returned when a monitor is created without any rules
+var ErrNoRules = errors.New("at least one alert rule is required")
+
+// Rule fires while its metric is above the threshold
+type Rule struct {
+	Name      string
+	Metric    string // One of Metrics
+	Threshold float64
+}
+
+// Config holds the rules and how often they are evaluated
+type Config struct {
+	Interval time.Duration
+	Rules    []Rule
+}
+
+// Alert is the state of a rule
+type Alert struct {
+	Rule      string  `json:"rule"`
+	Metric    string  `json:"metric"`
+	Threshold float64 `json:"threshold"`
+	State     string  `json:"state"` // StateUnknown, StateOK or StateFiring
+	Value     float64 `json:"value"` // Metric value at the latest successful evaluation
+	// Since is when the rule entered its state, nil before the first evaluation
+	Since       *time.Time `json:"since,omitempty"`
+	EvaluatedAt *time.Time `json:"evaluated_at,omitempty"`
+	// Error is set when the metric could not be read at the latest evaluation;
+	// the state is then kept
+	Error string `json:"error,omitempty"`
+}
+
+// Source reads the current value of a metric
+type Source func(ctx context.Context) (float64, error)
+
+// Publisher posts alert events (implemented by webhook.Dispatcher and events.Bus)
+type Publisher interface {
+	Publish(event webhook.Event)
+}
+
+// ParseConfig converts the raw [chatbox.alerts] config value, a table with an
+// optional interval and a rules array of { name, metric, threshold } tables
+func ParseConfig(raw interface{}) (Config, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return Config{}, fmt.Errorf("chatbox.alerts is not a table")
+	}
+
+	cfg := Config{Interval: constants.DefaultAlertInterval}
+	// No else needed: optional operation (interval defaults to DefaultAlertInterval)
+	if intervalStr, ok := table["interval"].(string); ok {
+		interval, err := time.ParseDuration(intervalStr)
+		// No else needed: early return pattern (guard clause)
+		if err != nil || interval < constants.MinAlertInterval {
+			return Config{}, fmt.Errorf("interval must be a duration of at least %s, got %q", constants.MinAlertInterval, intervalStr)
+		}
+		cfg.Interval = interval
+	}
+
+	var items []map[string]interface{}
+	switch list := table["rules"].(type) {
+	case nil:
+	case []map[string]interface{}:
+		items = list
+	case []interface{}:
+		for _, item := range list {
+			rule, ok := item.(map[string]interface{})
+			// No else needed: early return pattern (guard clause)
+			if !ok {
+				return Config{}, fmt.Errorf("rules must be an array of tables")
+			}
+			items = append(items, rule)
+		}
+	default:
+		return Config{}, fmt.Errorf("rules must be an array of tables")
+	}
+
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		name, _ := item["name"].(string)
+		// No else needed: early return pattern (guard clause)
+		if name == "" || seen[name] {
+			return Config{}, fmt.Errorf("rule %d: name must be set and unique", i+1)
+		}
+		seen[name] = true
+		metric, _ := item["metric"].(string)
+		// No else needed: early return pattern (guard clause)
+		if !slices.Contains(Metrics, metric) {
+			return Config{}, fmt.Errorf("rule %s: metric must be one of %v, got %q", name, Metrics, metric)
+		}
+		threshold, err := parseNumber(item["threshold"])
+		// No else needed: early return pattern (guard clause)
+		if err != nil || threshold < 0 {
+			return Config{}, fmt.Errorf("rule %s: threshold must be a non-negative number", name)
+		}
+		cfg.Rules = append(cfg.Rules, Rule{Name: name, Metric: metric, Threshold: threshold})
+	}
+	return cfg, nil
+}
+
+// parseNumber reads a threshold, written as a TOML integer, float or string
+func parseNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("not a number")
+	}
+}
+
+// ErrorRate returns a Source of the percentage of requests that failed since
+// its previous read, from the cumulative totals returned by read. The first
+// read only takes the baseline and reports 0.
+func ErrorRate(read func() (requests, failures float64)) Source {
+	var mu sync.Mutex
+	var baseRequests, baseFailures float64
+	started := false
+	return func(ctx context.Context) (float64, error) {
+		requests, failures := read()
+		mu.Lock()
+		defer mu.Unlock()
+		deltaRequests, deltaFailures := requests-baseRequests, failures-baseFailures
+		baseRequests, baseFailures = requests, failures
+		// No else needed: early return pattern (baseline read)
+		if !started {
+			started = true
+			return 0, nil
+		}
+		// No else needed: early return pattern (no failures in the period)
+		if deltaFailures <= 0 {
+			return 0, nil
+		}
+		// Streams can fail after their request was counted, so cap at 100%
+		// No else needed: early return pattern (failures without new requests)
+		if deltaRequests <= deltaFailures {
+			return 100, nil
+		}
+		return deltaFailures / deltaRequests * 100, nil
+	}
+}
+
+// Monitor evaluates alert rules every interval until stopped
+type Monitor struct {
+	rules    []Rule
+	sources  map[string]Source
+	interval time.Duration
+	alerts   Publisher // nil when neither webhooks nor the event bus are configured
+	logger   *golog.Logger
+
+	mu     sync.RWMutex
+	states []Alert // In the order of rules
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewMonitor creates a monitor of cfg's rules. Every watched metric needs a
+// source. alerts may be nil.
+func NewMonitor(cfg Config, sources map[string]Source, alerts Publisher, logger *golog.Logger) (*Monitor, error) {
+	// No else needed: early return pattern (guard clause)
+	if len(cfg.Rules) == 0 {
+		return nil, ErrNoRules
+	}
+	states := make([]Alert, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		// No else needed: early return pattern (guard clause)
+		if sources[rule.Metric] == nil {
+			return nil, fmt.Errorf("rule %s: metric %s is not available", rule.Name, rule.Metric)
+		}
+		states = append(states, Alert{Rule: rule.Name, Metric: rule.Metric, Threshold: rule.Threshold, State: StateUnknown})
+	}
+	interval := cfg.Interval
+	// No else needed: optional operation (apply default)
+	if interval <= 0 {
+		interval = constants.DefaultAlertInterval
+	}
+	return &Monitor{
+		rules:    cfg.Rules,
+		sources:  sources,
+		interval: interval,
+		alerts:   alerts,
+		logger:   logger.WithGroup("alerts"),
+		states:   states,
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Alerts returns the state of every rule, in configuration order
+func (m *Monitor) Alerts() []Alert {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.states)
+}
+
+// Evaluate reads the watched metrics once and updates the state of every
+// rule, publishing an event for each rule that starts or stops firing
+func (m *Monitor) Evaluate(ctx context.Context, now time.Time) {
+	// Read each metric once: sources like ErrorRate measure since their previous read
+	type reading struct {
+		value float64
+		err   error
+	}
+	readings := make(map[string]reading, len(m.sources))
+	for _, rule := range m.rules {
+		// No else needed: optional operation (metric already read)
+		if _, ok := readings[rule.Metric]; !ok {
+			value, err := m.sources[rule.Metric](ctx)
+			readings[rule.Metric] = reading{value: value, err: err}
+		}
+	}
+
+	var changed []Alert
+	m.mu.Lock()
+	for i, rule := range m.rules {
+		alert := &m.states[i]
+		evaluatedAt := now
+		alert.EvaluatedAt = &evaluatedAt
+		r := readings[rule.Metric]
+		// No else needed: optional operation (state kept until the metric can be read)
+		if r.err != nil {
+			alert.Error = r.err.Error()
+			continue
+		}
+		alert.Error = ""
+		alert.Value = r.value
+		state := StateOK
+		// No else needed: conditional assignment (above threshold)
+		if r.value > rule.Threshold {
+			state = StateFiring
+		}
+		// No else needed: optional operation (state unchanged)
+		if state == alert.State {
+			continue
+		}
+		notify := state == StateFiring || alert.State == StateFiring
+		alert.State = state
+		alert.Since = &evaluatedAt
+		firing := 0.0
+		// No else needed: conditional assignment (gauge is 1 while firing)
+		if state == StateFiring {
+			firing = 1
+		}
+		metrics.AlertsFiring.WithLabelValues(rule.Name).Set(firing)
+		// No else needed: optional operation (the first evaluation finding a rule ok is not news)
+		if notify {
+			changed = append(changed, *alert)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, alert := range changed {
+		m.publish(alert)
+	}
+	for metric, r := range readings {
+		// No else needed: optional operation (log metrics that could not be read)
+		if r.err != nil {
+			m.logger.Warn("Failed to read alert metric", "metric", metric, "error", r.err)
+		}
+	}
+}
+
+// publish logs a rule that started or stopped firing and posts its event
+func (m *Monitor) publish(alert Alert) {
+	eventType, message := webhook.EventAlertResolved, "Alert resolved"
+	// No else needed: conditional assignment (rule started firing)
+	if alert.State == StateFiring {
+		eventType, message = webhook.EventAlertFiring, "Alert firing"
+	}
+	m.logger.Warn(message,
+		"rule", alert.Rule,
+		"metric", alert.Metric,
+		"value", alert.Value,
+		"threshold", alert.Threshold)
+	// No else needed: optional operation (only when webhooks or the event bus are configured)
+	if m.alerts != nil {
+		m.alerts.Publish(webhook.Event{
+			Type:      eventType,
+			Timestamp: *alert.Since,
+			Data: map[string]string{
+				"rule":      alert.Rule,
+				"metric":    alert.Metric,
+				"value":     strconv.FormatFloat(alert.Value, 'f', -1, 64),
+				"threshold": strconv.FormatFloat(alert.Threshold, 'f', -1, 64),
+			},
+		})
+	}
+}
+
+// Start evaluates the rules every interval until Stop is called
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+			m.Evaluate(ctx, time.Now())
+			cancel()
+		}
+	}()
+}
+
+// Stop stops the monitor and waits for an evaluation in progress.
+// Safe to call multiple times and when the monitor was never started.
+func (m *Monitor) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+	m.wg.Wait()
+}
@@ -0,0 +1,183 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/webhook"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(t *testing.T) *golog.Logger {
+	t.Helper()
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+	return logger
+}
+
+// recordingPublisher keeps the published events
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []webhook.Event
+}
+
+func (p *recordingPublisher) Publish(event webhook.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+func (p *recordingPublisher) Events() []webhook.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]webhook.Event(nil), p.events...)
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(map[string]interface{}{
+		"interval": "30s",
+		"rules": []interface{}{
+			map[string]interface{}{"name": "tokens", "metric": MetricTokensPerDay, "threshold": int64(1000000)},
+			map[string]interface{}{"name": "errors", "metric": MetricErrorRate, "threshold": 5.5},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.Interval)
+	assert.Equal(t, []Rule{
+		{Name: "tokens", Metric: MetricTokensPerDay, Threshold: 1000000},
+		{Name: "errors", Metric: MetricErrorRate, Threshold: 5.5},
+	}, cfg.Rules)
+
+	cfg, err = ParseConfig(map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultAlertInterval, cfg.Interval)
+	assert.Empty(t, cfg.Rules)
+
+	invalid := []map[string]interface{}{
+		{"interval": "1s"},
+		{"rules": "tokens"},
+		{"rules": []interface{}{map[string]interface{}{"metric": MetricQueueLength, "threshold": int64(1)}}},
+		{"rules": []interface{}{map[string]interface{}{"name": "q", "metric": "latency", "threshold": int64(1)}}},
+		{"rules": []interface{}{map[string]interface{}{"name": "q", "metric": MetricQueueLength}}},
+		{"rules": []interface{}{map[string]interface{}{"name": "q", "metric": MetricQueueLength, "threshold": int64(-1)}}},
+		{"rules": []interface{}{
+			map[string]interface{}{"name": "q", "metric": MetricQueueLength, "threshold": int64(1)},
+			map[string]interface{}{"name": "q", "metric": MetricErrorRate, "threshold": int64(1)},
+		}},
+	}
+	for _, raw := range invalid {
+		_, err := ParseConfig(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+	_, err = ParseConfig("rules")
+	assert.Error(t, err)
+}
+
+func TestErrorRate(t *testing.T) {
+	var requests, failures float64
+	source := ErrorRate(func() (float64, float64) { return requests, failures })
+	ctx := context.Background()
+
+	requests, failures = 100, 10
+	rate, err := source(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, rate, "the first read takes the baseline")
+
+	requests, failures = 120, 15
+	rate, _ = source(ctx)
+	assert.InDelta(t, 25, rate, 0.001)
+
+	rate, _ = source(ctx)
+	assert.Zero(t, rate, "no requests since the previous read")
+
+	failures = 17
+	rate, _ = source(ctx)
+	assert.Equal(t, 100.0, rate, "failures without new requests")
+}
+
+func TestMonitor_Evaluate(t *testing.T) {
+	queue := 0.0
+	var tokensErr error
+	sources := map[string]Source{
+		MetricQueueLength:  func(ctx context.Context) (float64, error) { return queue, nil },
+		MetricTokensPerDay: func(ctx context.Context) (float64, error) { return 500, tokensErr },
+	}
+	publisher := &recordingPublisher{}
+	monitor, err := NewMonitor(Config{Rules: []Rule{
+		{Name: "queue", Metric: MetricQueueLength, Threshold: 3},
+		{Name: "tokens", Metric: MetricTokensPerDay, Threshold: 1000},
+	}}, sources, publisher, newTestLogger(t))
+	require.NoError(t, err)
+
+	for _, alert := range monitor.Alerts() {
+		assert.Equal(t, StateUnknown, alert.State)
+		assert.Nil(t, alert.Since)
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	monitor.Evaluate(ctx, start)
+	list := monitor.Alerts()
+	assert.Equal(t, StateOK, list[0].State)
+	assert.Equal(t, StateOK, list[1].State)
+	assert.Equal(t, 500.0, list[1].Value)
+	assert.Empty(t, publisher.Events(), "rules found ok are not announced")
+
+	queue = 4
+	monitor.Evaluate(ctx, start.Add(time.Minute))
+	list = monitor.Alerts()
+	assert.Equal(t, StateFiring, list[0].State)
+	assert.Equal(t, 4.0, list[0].Value)
+	require.NotNil(t, list[0].Since)
+	assert.True(t, list[0].Since.Equal(start.Add(time.Minute)))
+	events := publisher.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, webhook.EventAlertFiring, events[0].Type)
+	assert.Equal(t, map[string]string{"rule": "queue", "metric": MetricQueueLength, "value": "4", "threshold": "3"}, events[0].Data)
+
+	// Still firing: no new event, Since kept
+	queue = 5
+	monitor.Evaluate(ctx, start.Add(2*time.Minute))
+	assert.Len(t, publisher.Events(), 1)
+	assert.True(t, monitor.Alerts()[0].Since.Equal(start.Add(time.Minute)))
+
+	// A metric that cannot be read keeps its state and reports the error
+	tokensErr = errors.New("database unavailable")
+	queue = 1
+	monitor.Evaluate(ctx, start.Add(3*time.Minute))
+	list = monitor.Alerts()
+	assert.Equal(t, StateOK, list[0].State)
+	assert.Equal(t, StateOK, list[1].State)
+	assert.Equal(t, "database unavailable", list[1].Error)
+	events = publisher.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, webhook.EventAlertResolved, events[1].Type)
+}
+
+func TestNewMonitor(t *testing.T) {
+	logger := newTestLogger(t)
+	_, err := NewMonitor(Config{}, nil, nil, logger)
+	assert.ErrorIs(t, err, ErrNoRules)
+
+	_, err = NewMonitor(Config{Rules: []Rule{{Name: "tokens", Metric: MetricTokensPerDay, Threshold: 1}}}, map[string]Source{}, nil, logger)
+	assert.Error(t, err, "metric without a source")
+
+	monitor, err := NewMonitor(Config{Rules: []Rule{{Name: "queue", Metric: MetricQueueLength, Threshold: 1}}}, map[string]Source{
+		MetricQueueLength: func(ctx context.Context) (float64, error) { return 2, nil },
+	}, nil, logger)
+	require.NoError(t, err)
+	monitor.Evaluate(context.Background(), time.Now())
+	assert.Equal(t, StateFiring, monitor.Alerts()[0].State, "firing without a publisher")
+	monitor.Stop()
+}
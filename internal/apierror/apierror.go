@@ -0,0 +1,148 @@
+// Package apierror defines the machine-readable error codes shared by the
+// WebSocket protocol and the HTTP API, and the JSON envelope of HTTP error
+// responses.
+//
+// Codes are stable: clients branch on them, so a code is never renamed or
+// reused for a different condition. Messages are human-readable and may change.
+//
+// HTTP error responses have the envelope
+//
+//	{"error": "Session not found", "code": "SESSION_NOT_FOUND"}
+//
+// with optional "details" and, for rate limits, "retry_after" (milliseconds).
+// WebSocket errors are messages of type "error" whose "error" field carries the
+// same code:
+//
+//	{"type": "error", "error": {"code": "RATE_LIMITED", "message": "...", "recoverable": true, "retry_after": 1000}}
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Code is a stable machine-readable error code
+type Code string
+
+// Authentication and authorization errors
+const (
+	CodeUnauthorized      Code = "UNAUTHORIZED"
+	CodeInvalidToken      Code = "INVALID_TOKEN"
+	CodeExpiredToken      Code = "EXPIRED_TOKEN"
+	CodeForbidden         Code = "FORBIDDEN"
+	CodeInsufficientPerms Code = "INSUFFICIENT_PERMISSIONS"
+	CodeFeatureDisabled   Code = "FEATURE_DISABLED"
+	CodeIPBanned          Code = "IP_BANNED"
+	CodeUserBlocked       Code = "USER_BLOCKED"
+)
+
+// Request validation errors
+const (
+	CodeBadRequest      Code = "BAD_REQUEST"
+	CodeInvalidRequest  Code = "INVALID_REQUEST"
+	CodeInvalidFormat   Code = "INVALID_FORMAT"
+	CodeMissingField    Code = "MISSING_FIELD"
+	CodeInvalidFileType Code = "INVALID_FILE_TYPE"
+	CodeInvalidFileSize Code = "INVALID_FILE_SIZE"
+	CodeContentBlocked  Code = "CONTENT_BLOCKED"
+	CodePayloadTooLarge Code = "PAYLOAD_TOO_LARGE"
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeSessionNotFound Code = "SESSION_NOT_FOUND"
+	CodeTakeoverDenied  Code = "TAKEOVER_DENIED"
+	CodeConflict        Code = "CONFLICT"
+)
+
+// Service errors
+const (
+	CodeInternalError      Code = "INTERNAL_ERROR"
+	CodeServiceError       Code = "SERVICE_ERROR"
+	CodeServiceUnavailable Code = "SERVICE_UNAVAILABLE"
+	CodeNotImplemented     Code = "NOT_IMPLEMENTED"
+	CodeLLMUnavailable     Code = "LLM_UNAVAILABLE"
+	CodeLLMTimeout         Code = "LLM_TIMEOUT"
+	CodeDatabaseError      Code = "DATABASE_ERROR"
+	CodeStorageError       Code = "STORAGE_ERROR"
+	CodeMaintenance        Code = "MAINTENANCE"
+	CodeReadOnly           Code = "READ_ONLY"
+)
+
+// Rate limiting errors
+const (
+	CodeRateLimited     Code = "RATE_LIMITED"
+	CodeTooManyRequests Code = "TOO_MANY_REQUESTS"
+	CodeConnectionLimit Code = "CONNECTION_LIMIT_EXCEEDED"
+	CodeQuotaExceeded   Code = "QUOTA_EXCEEDED"
+)
+
+// statuses maps each code to the HTTP status it is served with
+var statuses = map[Code]int{
+	CodeUnauthorized:      http.StatusUnauthorized,
+	CodeInvalidToken:      http.StatusUnauthorized,
+	CodeExpiredToken:      http.StatusUnauthorized,
+	CodeForbidden:         http.StatusForbidden,
+	CodeInsufficientPerms: http.StatusForbidden,
+	CodeFeatureDisabled:   http.StatusForbidden,
+	CodeIPBanned:          http.StatusForbidden,
+	CodeUserBlocked:       http.StatusForbidden,
+
+	CodeBadRequest:      http.StatusBadRequest,
+	CodeInvalidRequest:  http.StatusBadRequest,
+	CodeInvalidFormat:   http.StatusBadRequest,
+	CodeMissingField:    http.StatusBadRequest,
+	CodeInvalidFileType: http.StatusBadRequest,
+	CodeInvalidFileSize: http.StatusRequestEntityTooLarge,
+	CodeContentBlocked:  http.StatusUnprocessableEntity,
+	CodePayloadTooLarge: http.StatusRequestEntityTooLarge,
+	CodeNotFound:        http.StatusNotFound,
+	CodeSessionNotFound: http.StatusNotFound,
+	CodeTakeoverDenied:  http.StatusConflict,
+	CodeConflict:        http.StatusConflict,
+
+	CodeInternalError:      http.StatusInternalServerError,
+	CodeServiceError:       http.StatusInternalServerError,
+	CodeServiceUnavailable: http.StatusServiceUnavailable,
+	CodeNotImplemented:     http.StatusNotImplemented,
+	CodeLLMUnavailable:     http.StatusServiceUnavailable,
+	CodeLLMTimeout:         http.StatusGatewayTimeout,
+	CodeDatabaseError:      http.StatusInternalServerError,
+	CodeStorageError:       http.StatusInternalServerError,
+	CodeMaintenance:        http.StatusServiceUnavailable,
+	CodeReadOnly:           http.StatusServiceUnavailable,
+
+	CodeRateLimited:     http.StatusTooManyRequests,
+	CodeTooManyRequests: http.StatusTooManyRequests,
+	CodeConnectionLimit: http.StatusTooManyRequests,
+	CodeQuotaExceeded:   http.StatusTooManyRequests,
+}
+
+// Status returns the HTTP status for a code; unknown codes are internal errors
+func (c Code) Status() int {
+	// No else needed: early return pattern (known code)
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Envelope is the JSON body of every HTTP error response
+type Envelope struct {
+	Error      string `json:"error"` // human-readable message
+	Code       Code   `json:"code"`
+	Details    string `json:"details,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"` // milliseconds, only for rate limits
+}
+
+// New returns the envelope for a code and message
+func New(code Code, message string) Envelope {
+	return Envelope{Error: message, Code: code}
+}
+
+// Write writes the envelope as a JSON response with the code's HTTP status.
+// It is for plain net/http handlers such as the WebSocket upgrade; Gin
+// handlers use the httperrors package.
+func Write(w http.ResponseWriter, code Code, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code.Status())
+	_ = json.NewEncoder(w).Encode(New(code, message))
+}
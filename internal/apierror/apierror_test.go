@@ -0,0 +1,57 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeStatus(t *testing.T) {
+	tests := []struct {
+		code Code
+		want int
+	}{
+		{CodeUnauthorized, http.StatusUnauthorized},
+		{CodeForbidden, http.StatusForbidden},
+		{CodeInvalidFormat, http.StatusBadRequest},
+		{CodeSessionNotFound, http.StatusNotFound},
+		{CodeTakeoverDenied, http.StatusConflict},
+		{CodeConflict, http.StatusConflict},
+		{CodePayloadTooLarge, http.StatusRequestEntityTooLarge},
+		{CodeRateLimited, http.StatusTooManyRequests},
+		{CodeLLMTimeout, http.StatusGatewayTimeout},
+		{CodeLLMUnavailable, http.StatusServiceUnavailable},
+		{CodeMaintenance, http.StatusServiceUnavailable},
+		{CodeReadOnly, http.StatusServiceUnavailable},
+		{Code("SOMETHING_NEW"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.code.Status(), tt.code)
+	}
+}
+
+func TestCodeStatus_EveryCodeIsMapped(t *testing.T) {
+	for code, status := range statuses {
+		assert.GreaterOrEqual(t, status, 400, code)
+		assert.Regexp(t, `^[A-Z_]+$`, string(code))
+	}
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	Write(w, CodeSessionNotFound, "Session not found")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]interface{}{
+		"error": "Session not found",
+		"code":  "SESSION_NOT_FOUND",
+	}, body, "optional fields are omitted")
+}
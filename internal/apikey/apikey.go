@@ -0,0 +1,189 @@
+// Package apikey authenticates server-to-server calls to the admin API.
+//
+// Internal services that cannot obtain JWTs present an API key in the
+// X-API-Key header instead. Keys are configured under
+// [chatbox.api_keys.<name>] or stored in the chat_api_keys MongoDB collection,
+// only as the hex SHA-256 hash of the key, so the configuration never holds a
+// usable secret. Each key has a scope, read-only or admin, mapped to a role of
+// the authorization policy, and is limited to one tenant.
+package apikey
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// UserIDPrefix starts the caller ID of every request authenticated by an API key
+const UserIDPrefix = "apikey:"
+
+// Scope is the access an API key grants
+type Scope string
+
+// Scopes
+const (
+	// ScopeRead lists, searches and reads sessions and metrics (constants.RoleAPIKeyRead)
+	ScopeRead Scope = "read"
+	// ScopeAdmin performs admin actions as well (constants.RoleAPIKeyAdmin)
+	ScopeAdmin Scope = "admin"
+)
+
+var (
+	// ErrUnauthorized is returned when no configured key matches
+	ErrUnauthorized = errors.New("invalid API key")
+	// ErrInvalidKey is returned when a key configuration is invalid
+	ErrInvalidKey = errors.New("invalid API key configuration")
+)
+
+// Key is an API key allowed to call the admin API
+type Key struct {
+	Name     string // Identifies the caller in logs and the audit log
+	Hash     string // Hex SHA-256 of the key
+	Scope    Scope  // Access the key grants
+	TenantID string // Tenant the key is limited to; empty for the default tenant
+}
+
+// Hash returns the hex SHA-256 of key, as configured for it
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Role returns the authorization role of the key's scope
+func (k *Key) Role() string {
+	// No else needed: early return pattern (admin scope)
+	if k.Scope == ScopeAdmin {
+		return constants.RoleAPIKeyAdmin
+	}
+	return constants.RoleAPIKeyRead
+}
+
+// Claims returns the identity of requests authenticated by the key
+func (k *Key) Claims() *auth.Claims {
+	return &auth.Claims{
+		UserID:   UserIDPrefix + k.Name,
+		Name:     k.Name,
+		Roles:    []string{k.Role()},
+		TenantID: k.TenantID,
+	}
+}
+
+// Validate checks the key's name, hash and scope
+func (k *Key) Validate() error {
+	// No else needed: early return pattern (guard clause)
+	if k.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidKey)
+	}
+	decoded, err := hex.DecodeString(k.Hash)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("%w: hash must be the hex SHA-256 of the key", ErrInvalidKey)
+	}
+	// No else needed: early return pattern (guard clause)
+	if k.Scope != ScopeRead && k.Scope != ScopeAdmin {
+		return fmt.Errorf("%w: scope must be %q or %q, got %q", ErrInvalidKey, ScopeRead, ScopeAdmin, k.Scope)
+	}
+	return nil
+}
+
+// ParseConfigKeys converts the raw [chatbox.api_keys] config value, a table of
+// <name> = { hash, scope, tenant_id } tables, into keys sorted by name
+func ParseConfigKeys(raw interface{}) ([]*Key, error) {
+	tables, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.api_keys is not a table")
+	}
+
+	keys := make([]*Key, 0, len(tables))
+	for name, value := range tables {
+		table, ok := value.(map[string]interface{})
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("api key %s: not a table", name)
+		}
+		hash, _ := table["hash"].(string)
+		scope, _ := table["scope"].(string)
+		tenantID, _ := table["tenant_id"].(string)
+		key := &Key{Name: name, Hash: hash, Scope: Scope(scope), TenantID: tenantID}
+		// No else needed: early return pattern (guard clause)
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("api key %s: %w", name, err)
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys, nil
+}
+
+// Merge returns base with each key of overrides replacing the key of the same
+// name in base, sorted by name
+func Merge(base []*Key, overrides []*Key) []*Key {
+	byName := make(map[string]*Key, len(base)+len(overrides))
+	for _, key := range base {
+		byName[key.Name] = key
+	}
+	for _, key := range overrides {
+		byName[key.Name] = key
+	}
+	merged := make([]*Key, 0, len(byName))
+	for _, key := range byName {
+		merged = append(merged, key)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return merged
+}
+
+// Registry authenticates API keys. It is immutable after creation and safe for
+// concurrent use.
+type Registry struct {
+	keys map[string]*Key // hash -> key
+}
+
+// NewRegistry creates a registry for keys. Returns ErrInvalidKey for an invalid
+// key or two keys with the same hash.
+func NewRegistry(keys []*Key) (*Registry, error) {
+	r := &Registry{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		// No else needed: early return pattern (guard clause)
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("api key %s: %w", key.Name, err)
+		}
+		hash := hex.EncodeToString(mustDecode(key.Hash))
+		// No else needed: early return pattern (guard clause)
+		if other, exists := r.keys[hash]; exists {
+			return nil, fmt.Errorf("%w: api keys %s and %s have the same hash", ErrInvalidKey, other.Name, key.Name)
+		}
+		r.keys[hash] = key
+	}
+	return r, nil
+}
+
+// mustDecode decodes a hash checked by Validate
+func mustDecode(hash string) []byte {
+	decoded, _ := hex.DecodeString(hash)
+	return decoded
+}
+
+// Len returns the number of keys
+func (r *Registry) Len() int {
+	return len(r.keys)
+}
+
+// Authenticate returns the key matching key. Returns ErrUnauthorized when no
+// key matches.
+func (r *Registry) Authenticate(key string) (*Key, error) {
+	// Keys are looked up by hash, so the lookup time says nothing about the key
+	config, ok := r.keys[Hash(key)]
+	// No else needed: early return pattern (guard clause)
+	if key == "" || !ok {
+		return nil, ErrUnauthorized
+	}
+	return config, nil
+}
@@ -0,0 +1,77 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigKeys(t *testing.T) {
+	keys, err := ParseConfigKeys(map[string]interface{}{
+		"reporting": map[string]interface{}{"hash": Hash("secret-1"), "scope": "read"},
+		"billing":   map[string]interface{}{"hash": Hash("secret-2"), "scope": "admin", "tenant_id": "acme"},
+	})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "billing", keys[0].Name, "sorted by name")
+	assert.Equal(t, ScopeAdmin, keys[0].Scope)
+	assert.Equal(t, "acme", keys[0].TenantID)
+	assert.Equal(t, ScopeRead, keys[1].Scope)
+
+	invalid := []interface{}{
+		"not a table",
+		map[string]interface{}{"reporting": "secret"},
+		map[string]interface{}{"reporting": map[string]interface{}{"hash": "secret-1", "scope": "read"}},
+		map[string]interface{}{"reporting": map[string]interface{}{"hash": Hash("secret-1"), "scope": "write"}},
+		map[string]interface{}{"reporting": map[string]interface{}{"hash": Hash("secret-1")[:10], "scope": "read"}},
+	}
+	for _, raw := range invalid {
+		_, err := ParseConfigKeys(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+}
+
+func TestRegistry_Authenticate(t *testing.T) {
+	registry, err := NewRegistry([]*Key{
+		{Name: "reporting", Hash: Hash("secret-1"), Scope: ScopeRead},
+		{Name: "billing", Hash: strings.ToUpper(Hash("secret-2")), Scope: ScopeAdmin, TenantID: "acme"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, registry.Len())
+
+	key, err := registry.Authenticate("secret-1")
+	require.NoError(t, err)
+	claims := key.Claims()
+	assert.Equal(t, UserIDPrefix+"reporting", claims.UserID)
+	assert.Equal(t, []string{constants.RoleAPIKeyRead}, claims.Roles)
+	assert.Empty(t, claims.TenantID)
+
+	key, err = registry.Authenticate("secret-2")
+	require.NoError(t, err, "hashes are case-insensitive")
+	assert.Equal(t, []string{constants.RoleAPIKeyAdmin}, key.Claims().Roles)
+	assert.Equal(t, "acme", key.Claims().TenantID)
+
+	_, err = registry.Authenticate("secret-3")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = registry.Authenticate("")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = NewRegistry([]*Key{
+		{Name: "a", Hash: Hash("same"), Scope: ScopeRead},
+		{Name: "b", Hash: Hash("same"), Scope: ScopeAdmin},
+	})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestMerge(t *testing.T) {
+	merged := Merge(
+		[]*Key{{Name: "reporting", Scope: ScopeRead}, {Name: "billing", Scope: ScopeRead}},
+		[]*Key{{Name: "billing", Scope: ScopeAdmin}},
+	)
+	require.Len(t, merged, 2)
+	assert.Equal(t, "billing", merged[0].Name)
+	assert.Equal(t, ScopeAdmin, merged[0].Scope, "stored keys replace configured keys")
+}
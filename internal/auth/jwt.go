@@ -1,12 +1,22 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/real-rm/chatbox/internal/constants"
 )
 
+// GuestIDPrefix starts the user ID of every guest token
+const GuestIDPrefix = "guest-"
+
+// EmbedIDPrefix starts the user ID of every embed token
+const EmbedIDPrefix = "embed-"
+
 var (
 	// ErrInvalidToken is returned when the token is malformed or invalid
 	ErrInvalidToken = errors.New("invalid token")
@@ -20,9 +30,20 @@ var (
 
 // Claims represents the JWT claims extracted from a token
 type Claims struct {
-	UserID string
-	Name   string
-	Roles  []string
+	UserID         string
+	Name           string
+	Roles          []string
+	TenantID       string // Empty for single-tenant deployments
+	Origin         string // Set on embed tokens, which are only accepted from this origin
+	ImpersonatorID string // Set on impersonation tokens: the admin acting as the user
+	Tier           string // Customer tier, weighting the user's place in the help queue
+	Email          string // Verified email address; empty unless the token has email_verified set
+}
+
+// AllowsOrigin reports whether the token may be used by a request from origin.
+// Tokens without an origin restriction are accepted from anywhere.
+func (c *Claims) AllowsOrigin(origin string) bool {
+	return c.Origin == "" || c.Origin == origin
 }
 
 // JWTValidator handles JWT token validation
@@ -98,6 +119,26 @@ func (v *JWTValidator) ValidateToken(tokenString string) (*Claims, error) {
 		name = userID
 	}
 
+	// Extract tenant_id (optional field, absent in single-tenant deployments)
+	tenantID, _ := mapClaims["tenant_id"].(string)
+
+	// Extract origin (optional field, only on embed tokens)
+	origin, _ := mapClaims["origin"].(string)
+
+	// Extract impersonator (optional field, only on impersonation tokens)
+	impersonator, _ := mapClaims["impersonator"].(string)
+
+	// Extract tier (optional field, set by deployments with customer tiers)
+	tier, _ := mapClaims["tier"].(string)
+
+	// Extract email (optional field, only trusted when the issuer verified it)
+	email, _ := mapClaims["email"].(string)
+	verified, _ := mapClaims["email_verified"].(bool)
+	// No else needed: optional operation (unverified addresses are ignored)
+	if !verified {
+		email = ""
+	}
+
 	// Extract roles
 	rolesInterface, ok := mapClaims["roles"]
 	// No else needed: early return pattern (guard clause)
@@ -113,12 +154,129 @@ func (v *JWTValidator) ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	return &Claims{
-		UserID: userID,
-		Name:   name,
-		Roles:  roles,
+		UserID:         userID,
+		Name:           name,
+		Roles:          roles,
+		TenantID:       tenantID,
+		Origin:         origin,
+		ImpersonatorID: impersonator,
+		Tier:           tier,
+		Email:          email,
 	}, nil
 }
 
+// IssueGuestToken signs a token for an anonymous guest, valid for ttl. The guest
+// gets a random user ID and only the guest role. Returns the token and its claims.
+func (v *JWTValidator) IssueGuestToken(ttl time.Duration) (string, *Claims, error) {
+	randomBytes := make([]byte, 16)
+	// No else needed: early return pattern (guard clause)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", nil, fmt.Errorf("failed to generate guest ID: %w", err)
+	}
+
+	claims := &Claims{
+		UserID: GuestIDPrefix + hex.EncodeToString(randomBytes),
+		Name:   "Guest",
+		Roles:  []string{constants.RoleGuest},
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": claims.UserID,
+		"name":    claims.Name,
+		"roles":   claims.Roles,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	})
+	signed, err := token.SignedString(v.secret)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign guest token: %w", err)
+	}
+	return signed, claims, nil
+}
+
+// IssueEmbedToken signs a token for a user of the chat widget embedded on a
+// partner site, valid for ttl and only from origin. subject identifies the user
+// on the partner's side; an empty subject gets a random user ID. The user ID is
+// always prefixed with EmbedIDPrefix so it cannot collide with a regular user.
+// Returns the token and its claims.
+func (v *JWTValidator) IssueEmbedToken(origin, tenantID, subject string, ttl time.Duration) (string, *Claims, error) {
+	// No else needed: optional operation (anonymous widget users get a random ID)
+	if subject == "" {
+		randomBytes := make([]byte, 16)
+		// No else needed: early return pattern (guard clause)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return "", nil, fmt.Errorf("failed to generate embed user ID: %w", err)
+		}
+		subject = hex.EncodeToString(randomBytes)
+	}
+
+	claims := &Claims{
+		UserID:   EmbedIDPrefix + subject,
+		Name:     "Visitor",
+		Roles:    []string{constants.RoleEmbed},
+		TenantID: tenantID,
+		Origin:   origin,
+	}
+	now := time.Now()
+	mapClaims := jwt.MapClaims{
+		"user_id": claims.UserID,
+		"name":    claims.Name,
+		"roles":   claims.Roles,
+		"origin":  claims.Origin,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	}
+	// No else needed: optional operation (default tenant has no tenant_id claim)
+	if tenantID != "" {
+		mapClaims["tenant_id"] = tenantID
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims).SignedString(v.secret)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign embed token: %w", err)
+	}
+	return signed, claims, nil
+}
+
+// IssueImpersonationToken signs a token letting the admin adminID use the chat as
+// userID of tenantID, valid for ttl. The token only has the impersonation role, so
+// it reaches the user-facing endpoints but none of the admin's own permissions.
+// Returns the token and its claims.
+func (v *JWTValidator) IssueImpersonationToken(userID, tenantID, adminID string, ttl time.Duration) (string, *Claims, error) {
+	// No else needed: early return pattern (guard clause)
+	if userID == "" || adminID == "" {
+		return "", nil, fmt.Errorf("%w: impersonation requires a user and an admin", ErrMissingClaims)
+	}
+
+	claims := &Claims{
+		UserID:         userID,
+		Name:           userID,
+		Roles:          []string{constants.RoleImpersonation},
+		TenantID:       tenantID,
+		ImpersonatorID: adminID,
+	}
+	now := time.Now()
+	mapClaims := jwt.MapClaims{
+		"user_id":      claims.UserID,
+		"name":         claims.Name,
+		"roles":        claims.Roles,
+		"impersonator": claims.ImpersonatorID,
+		"iat":          now.Unix(),
+		"exp":          now.Add(ttl).Unix(),
+	}
+	// No else needed: optional operation (default tenant has no tenant_id claim)
+	if tenantID != "" {
+		mapClaims["tenant_id"] = tenantID
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims).SignedString(v.secret)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign impersonation token: %w", err)
+	}
+	return signed, claims, nil
+}
+
 // extractRoles converts the roles claim to a string slice
 func extractRoles(rolesInterface interface{}) ([]string, error) {
 	// Handle []interface{} (common JWT claim format)
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -309,3 +310,158 @@ func TestValidateToken_WithEmptyName(t *testing.T) {
 	assert.Equal(t, "user-789", extractedClaims.Name) // Should default to user_id when empty
 	assert.Equal(t, []string{"user"}, extractedClaims.Roles)
 }
+
+func TestValidateToken_TenantID(t *testing.T) {
+	validator := NewJWTValidator(testSecret)
+
+	claims := jwt.MapClaims{
+		"user_id":   "user-123",
+		"roles":     []string{"user"},
+		"tenant_id": "tenant-a",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"iat":       time.Now().Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, _ := token.SignedString([]byte(testSecret))
+
+	extractedClaims, err := validator.ValidateToken(tokenString)
+
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", extractedClaims.TenantID)
+
+	// Tokens without tenant_id belong to the default tenant
+	extractedClaims, err = validator.ValidateToken(createTestToken("user-123", []string{"user"}, time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "", extractedClaims.TenantID)
+}
+
+func TestValidateToken_Tier(t *testing.T) {
+	validator := NewJWTValidator(testSecret)
+
+	claims := jwt.MapClaims{
+		"user_id": "user-123",
+		"roles":   []string{"user"},
+		"tier":    "enterprise",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, _ := token.SignedString([]byte(testSecret))
+
+	extractedClaims, err := validator.ValidateToken(tokenString)
+	require.NoError(t, err)
+	assert.Equal(t, "enterprise", extractedClaims.Tier)
+
+	// The tier claim is optional
+	extractedClaims, err = validator.ValidateToken(createTestToken("user-123", []string{"user"}, time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "", extractedClaims.Tier)
+}
+
+func TestValidateToken_Email(t *testing.T) {
+	validator := NewJWTValidator(testSecret)
+
+	sign := func(extra jwt.MapClaims) string {
+		claims := jwt.MapClaims{
+			"user_id": "user-123",
+			"roles":   []string{"user"},
+			"exp":     time.Now().Add(time.Hour).Unix(),
+			"iat":     time.Now().Unix(),
+		}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		tokenString, _ := token.SignedString([]byte(testSecret))
+		return tokenString
+	}
+
+	extractedClaims, err := validator.ValidateToken(sign(jwt.MapClaims{"email": "jane@example.com", "email_verified": true}))
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", extractedClaims.Email)
+
+	// Unverified addresses are not trusted
+	extractedClaims, err = validator.ValidateToken(sign(jwt.MapClaims{"email": "jane@example.com"}))
+	require.NoError(t, err)
+	assert.Equal(t, "", extractedClaims.Email)
+	extractedClaims, err = validator.ValidateToken(sign(jwt.MapClaims{"email": "jane@example.com", "email_verified": "true"}))
+	require.NoError(t, err)
+	assert.Equal(t, "", extractedClaims.Email)
+}
+
+func TestIssueGuestToken(t *testing.T) {
+	validator := NewJWTValidator(testSecret)
+
+	token, claims, err := validator.IssueGuestToken(time.Hour)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(claims.UserID, GuestIDPrefix))
+	assert.Equal(t, []string{"guest"}, claims.Roles)
+
+	validated, err := validator.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, claims, validated)
+
+	// Every guest gets its own identity
+	_, other, err := validator.IssueGuestToken(time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, claims.UserID, other.UserID)
+
+	expired, _, err := validator.IssueGuestToken(-time.Minute)
+	require.NoError(t, err)
+	_, err = validator.ValidateToken(expired)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestIssueEmbedToken(t *testing.T) {
+	validator := NewJWTValidator(testSecret)
+
+	token, claims, err := validator.IssueEmbedToken("https://partner.example.com", "tenant-a", "shop:42", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, EmbedIDPrefix+"shop:42", claims.UserID)
+	assert.Equal(t, []string{"embed"}, claims.Roles)
+
+	validated, err := validator.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, claims, validated)
+	assert.Equal(t, "tenant-a", validated.TenantID)
+
+	// The token only works from its origin
+	assert.True(t, validated.AllowsOrigin("https://partner.example.com"))
+	assert.False(t, validated.AllowsOrigin("https://evil.example.com"))
+	assert.False(t, validated.AllowsOrigin(""))
+
+	// Anonymous widget users get a random identity
+	_, anonymous, err := validator.IssueEmbedToken("https://partner.example.com", "", "", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(anonymous.UserID, EmbedIDPrefix))
+	assert.Greater(t, len(anonymous.UserID), len(EmbedIDPrefix))
+}
+
+func TestIssueImpersonationToken(t *testing.T) {
+	validator := NewJWTValidator(testSecret)
+
+	token, claims, err := validator.IssueImpersonationToken("user-42", "tenant-a", "admin-1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", claims.UserID)
+	assert.Equal(t, []string{"impersonation"}, claims.Roles, "no admin role is carried over")
+
+	validated, err := validator.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, claims, validated)
+	assert.Equal(t, "admin-1", validated.ImpersonatorID)
+	assert.Equal(t, "tenant-a", validated.TenantID)
+
+	expired, _, err := validator.IssueImpersonationToken("user-42", "", "admin-1", -time.Minute)
+	require.NoError(t, err)
+	_, err = validator.ValidateToken(expired)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+
+	_, _, err = validator.IssueImpersonationToken("user-42", "", "", time.Hour)
+	assert.ErrorIs(t, err, ErrMissingClaims)
+}
+
+func TestClaimsAllowsOrigin_Unrestricted(t *testing.T) {
+	claims := &Claims{UserID: "user-1", Roles: []string{"user"}}
+	assert.True(t, claims.AllowsOrigin("https://any.example.com"))
+	assert.True(t, claims.AllowsOrigin(""))
+}
@@ -0,0 +1,208 @@
+// Package authz maps roles to the admin capabilities they grant.
+//
+// Every admin endpoint and admin WebSocket action requires one permission. By
+// default the admin, chat_admin and super_admin roles hold all of them, as before
+// permissions existed. Deployments can narrow or extend this per role in config
+// under [chatbox.roles] or in the chat_roles MongoDB collection; a role listed
+// there gets exactly the permissions listed for it.
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// Permission is an admin capability granted to roles
+type Permission string
+
+// Permissions
+const (
+	// PermViewSessions lists, searches and watches sessions, and reads metrics,
+	// the audit log, prompt templates and IP stats
+	PermViewSessions Permission = "view_sessions"
+	// PermTakeover takes over sessions and hands them back
+	PermTakeover Permission = "takeover"
+	// PermExport downloads session transcripts and user data exports
+	PermExport Permission = "export"
+	// PermBroadcast sends admin messages into sessions and drains the server
+	PermBroadcast Permission = "broadcast"
+	// PermPurge erases user data and restores retention-deleted sessions
+	PermPurge Permission = "purge"
+	// PermManage manages tags, prompt templates and IP bans
+	PermManage Permission = "manage"
+	// PermImpersonate mints tokens to use the chat as a user, to reproduce their issues
+	PermImpersonate Permission = "impersonate"
+)
+
+// AllPermissions lists every permission
+var AllPermissions = []Permission{
+	PermViewSessions,
+	PermTakeover,
+	PermExport,
+	PermBroadcast,
+	PermPurge,
+	PermManage,
+	PermImpersonate,
+}
+
+// ErrUnknownPermission is returned when a role is granted a permission that does not exist
+var ErrUnknownPermission = errors.New("unknown permission")
+
+// valid reports whether perm is in AllPermissions
+func (perm Permission) valid() bool {
+	for _, p := range AllPermissions {
+		// No else needed: early return pattern (permission found)
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy maps roles to their permissions. It is immutable after creation and
+// safe for concurrent use.
+type Policy struct {
+	roles map[string]map[Permission]bool
+}
+
+// DefaultRoles returns the built-in role permissions: every admin role holds
+// every permission. Read-only API keys may only view sessions, and admin API
+// keys hold every permission but impersonation, which issues user tokens.
+func DefaultRoles() map[string][]Permission {
+	return map[string][]Permission{
+		constants.RoleAdmin:       AllPermissions,
+		constants.RoleChatAdmin:   AllPermissions,
+		constants.RoleSuperAdmin:  AllPermissions,
+		constants.RoleAPIKeyRead:  {PermViewSessions},
+		constants.RoleAPIKeyAdmin: {PermViewSessions, PermTakeover, PermExport, PermBroadcast, PermPurge, PermManage},
+	}
+}
+
+// Default returns the policy with DefaultRoles
+func Default() *Policy {
+	policy, _ := NewPolicy(DefaultRoles())
+	return policy
+}
+
+// NewPolicy creates a policy granting each role the listed permissions.
+// It returns ErrUnknownPermission for permissions not in AllPermissions.
+func NewPolicy(roles map[string][]Permission) (*Policy, error) {
+	p := &Policy{roles: make(map[string]map[Permission]bool, len(roles))}
+	for role, perms := range roles {
+		granted := make(map[Permission]bool, len(perms))
+		for _, perm := range perms {
+			// No else needed: early return pattern (guard clause)
+			if !perm.valid() {
+				return nil, fmt.Errorf("role %s: %w %q", role, ErrUnknownPermission, perm)
+			}
+			granted[perm] = true
+		}
+		p.roles[role] = granted
+	}
+	return p, nil
+}
+
+// Allows reports whether any of roles grants perm
+func (p *Policy) Allows(roles []string, perm Permission) bool {
+	for _, role := range roles {
+		// No else needed: early return pattern (permission found)
+		if p.roles[role][perm] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether roles grant any permission, i.e. whether the caller
+// may use the admin API at all
+func (p *Policy) IsAdmin(roles []string) bool {
+	for _, role := range roles {
+		// No else needed: early return pattern (admin role found)
+		if len(p.roles[role]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the permissions granted by roles, sorted
+func (p *Policy) Permissions(roles []string) []Permission {
+	granted := make(map[Permission]bool)
+	for _, role := range roles {
+		for perm := range p.roles[role] {
+			granted[perm] = true
+		}
+	}
+	perms := make([]Permission, 0, len(granted))
+	for perm := range granted {
+		perms = append(perms, perm)
+	}
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+// ParseConfigRoles converts the raw [chatbox.roles] config value, a table of
+// role = ["permission", ...] entries, into role permissions
+func ParseConfigRoles(raw interface{}) (map[string][]Permission, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.roles is not a table")
+	}
+
+	roles := make(map[string][]Permission, len(table))
+	for role, value := range table {
+		// No else needed: optional operation (string lists decoded as []string)
+		if names, ok := value.([]string); ok {
+			value = toInterfaces(names)
+		}
+		list, ok := value.([]interface{})
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("role %s: permissions must be a list of strings", role)
+		}
+		perms := make([]Permission, 0, len(list))
+		for _, item := range list {
+			name, ok := item.(string)
+			// No else needed: early return pattern (guard clause)
+			if !ok {
+				return nil, fmt.Errorf("role %s: permissions must be a list of strings", role)
+			}
+			perms = append(perms, Permission(name))
+		}
+		roles[role] = perms
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if _, err := NewPolicy(roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// toInterfaces converts a string slice to the generic list type of decoded config
+func toInterfaces(names []string) []interface{} {
+	list := make([]interface{}, len(names))
+	for i, name := range names {
+		list[i] = name
+	}
+	return list
+}
+
+// Merge returns base with the roles of each override replacing the same roles
+// in base; later overrides win
+func Merge(base map[string][]Permission, overrides ...map[string][]Permission) map[string][]Permission {
+	merged := make(map[string][]Permission, len(base))
+	for role, perms := range base {
+		merged[role] = perms
+	}
+	for _, override := range overrides {
+		for role, perms := range override {
+			merged[role] = perms
+		}
+	}
+	return merged
+}
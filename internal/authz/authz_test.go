@@ -0,0 +1,81 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := Default()
+
+	for _, role := range []string{constants.RoleAdmin, constants.RoleChatAdmin, constants.RoleSuperAdmin} {
+		assert.True(t, policy.IsAdmin([]string{role}), role)
+		for _, perm := range AllPermissions {
+			assert.True(t, policy.Allows([]string{role}, perm), "%s %s", role, perm)
+		}
+	}
+
+	assert.True(t, policy.Allows([]string{constants.RoleAPIKeyRead}, PermViewSessions))
+	assert.False(t, policy.Allows([]string{constants.RoleAPIKeyRead}, PermManage), "read-only keys")
+	assert.True(t, policy.Allows([]string{constants.RoleAPIKeyAdmin}, PermManage))
+	assert.False(t, policy.Allows([]string{constants.RoleAPIKeyAdmin}, PermImpersonate), "keys never mint user tokens by default")
+
+	assert.False(t, policy.IsAdmin([]string{"user"}))
+	assert.False(t, policy.IsAdmin(nil))
+	assert.False(t, policy.Allows([]string{"user", constants.RoleGuest}, PermViewSessions))
+}
+
+func TestNewPolicy(t *testing.T) {
+	policy, err := NewPolicy(map[string][]Permission{
+		"support": {PermViewSessions, PermTakeover},
+		"auditor": {PermViewSessions, PermExport},
+		"none":    {},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, policy.Allows([]string{"support"}, PermTakeover))
+	assert.False(t, policy.Allows([]string{"support"}, PermExport))
+	assert.True(t, policy.Allows([]string{"support", "auditor"}, PermExport), "roles combine")
+	assert.False(t, policy.IsAdmin([]string{"none"}), "a role without permissions is not an admin role")
+	assert.Equal(t, []Permission{PermExport, PermTakeover, PermViewSessions}, policy.Permissions([]string{"support", "auditor"}))
+
+	_, err = NewPolicy(map[string][]Permission{"support": {"delete_everything"}})
+	assert.ErrorIs(t, err, ErrUnknownPermission)
+}
+
+func TestParseConfigRoles(t *testing.T) {
+	roles, err := ParseConfigRoles(map[string]interface{}{
+		"support":       []interface{}{"view_sessions", "takeover"},
+		"chat_admin":    []string{"view_sessions"},
+		"former_admins": []interface{}{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Permission{PermViewSessions, PermTakeover}, roles["support"])
+	assert.Equal(t, []Permission{PermViewSessions}, roles["chat_admin"])
+	assert.Empty(t, roles["former_admins"])
+
+	_, err = ParseConfigRoles(map[string]interface{}{"support": []interface{}{"fly"}})
+	assert.ErrorIs(t, err, ErrUnknownPermission)
+	_, err = ParseConfigRoles(map[string]interface{}{"support": "takeover"})
+	assert.Error(t, err)
+	_, err = ParseConfigRoles(map[string]interface{}{"support": []interface{}{1}})
+	assert.Error(t, err)
+	_, err = ParseConfigRoles("takeover")
+	assert.Error(t, err)
+}
+
+func TestMerge(t *testing.T) {
+	merged := Merge(DefaultRoles(),
+		map[string][]Permission{constants.RoleChatAdmin: {PermViewSessions}, "support": {PermTakeover}},
+		map[string][]Permission{"support": {PermViewSessions}},
+	)
+	policy, err := NewPolicy(merged)
+	require.NoError(t, err)
+
+	assert.True(t, policy.Allows([]string{constants.RoleAdmin}, PermPurge), "roles not overridden keep their permissions")
+	assert.False(t, policy.Allows([]string{constants.RoleChatAdmin}, PermPurge), "overridden roles get exactly the listed permissions")
+	assert.Equal(t, []Permission{PermViewSessions}, merged["support"], "later overrides win")
+}
@@ -0,0 +1,321 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// KeySize is the length of backup keys in bytes (AES-256)
+const KeySize = 32
+
+// Archive layout: the magic header, then sealed chunks of at most chunkSize
+// plaintext bytes, each written as its ciphertext length, nonce and ciphertext.
+// The plaintext is a gzip stream of concatenated BSON documents, the layout of
+// mongodump's .bson files. Every chunk is authenticated together with its
+// position and whether it is the last one, so reordered or truncated archives
+// are rejected.
+const (
+	magic     = "CHATBAK\x01"
+	chunkSize = 64 * 1024
+	nonceSize = 12
+	// maxDocumentSize is MongoDB's maximum BSON document size
+	maxDocumentSize = 16 * 1024 * 1024
+)
+
+var (
+	// ErrInvalidKey is returned for keys that are not KeySize bytes
+	ErrInvalidKey = fmt.Errorf("backup key must be %d bytes", KeySize)
+	// ErrNotArchive is returned when the data does not start with the archive header
+	ErrNotArchive = errors.New("not a chatbox backup archive")
+	// ErrCorrupt is returned when a chunk does not authenticate: the archive was
+	// modified or the key is wrong
+	ErrCorrupt = errors.New("backup archive is corrupt or the key is wrong")
+	// ErrTruncated is returned when the archive ends before its last chunk
+	ErrTruncated = errors.New("backup archive is truncated")
+)
+
+// newAEAD creates the AES-256-GCM cipher of key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	// No else needed: early return pattern (guard clause)
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkAAD binds a chunk to its position and to whether it ends the archive
+func chunkAAD(index uint64, final bool) []byte {
+	aad := make([]byte, len(magic)+9)
+	copy(aad, magic)
+	binary.BigEndian.PutUint64(aad[len(magic):], index)
+	// No else needed: optional operation (flag the last chunk)
+	if final {
+		aad[len(aad)-1] = 1
+	}
+	return aad
+}
+
+// sealWriter encrypts what is written to it in chunks. The last chunk is only
+// sealed by Close, so it always holds the end of the data.
+type sealWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	buf   []byte
+	index uint64
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	for len(s.buf) > chunkSize {
+		// No else needed: early return pattern (guard clause)
+		if err := s.seal(s.buf[:chunkSize], false); err != nil {
+			return 0, err
+		}
+		s.buf = append(s.buf[:0], s.buf[chunkSize:]...)
+	}
+	return len(p), nil
+}
+
+// Close seals the remaining data as the last chunk
+func (s *sealWriter) Close() error {
+	return s.seal(s.buf, true)
+}
+
+func (s *sealWriter) seal(plaintext []byte, final bool) error {
+	nonce := make([]byte, nonceSize)
+	// No else needed: early return pattern (guard clause)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	ciphertext := s.aead.Seal(nil, nonce, plaintext, chunkAAD(s.index, final))
+	s.index++
+
+	header := make([]byte, 4, 4+nonceSize)
+	binary.BigEndian.PutUint32(header, uint32(len(ciphertext)))
+	header = append(header, nonce...)
+	// No else needed: early return pattern (guard clause)
+	if _, err := s.w.Write(header); err != nil {
+		return err
+	}
+	_, err := s.w.Write(ciphertext)
+	return err
+}
+
+// openReader decrypts the chunks written by sealWriter
+type openReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	buf   []byte
+	index uint64
+	final bool
+}
+
+func (o *openReader) Read(p []byte) (int, error) {
+	for len(o.buf) == 0 {
+		// No else needed: early return pattern (guard clause)
+		if o.final {
+			return 0, o.checkEnd()
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk
+func (o *openReader) open() error {
+	header := make([]byte, 4+nonceSize)
+	// No else needed: early return pattern (guard clause)
+	if _, err := io.ReadFull(o.r, header); err != nil {
+		return truncated(err)
+	}
+	length := binary.BigEndian.Uint32(header)
+	// No else needed: early return pattern (guard clause)
+	if length < uint32(o.aead.Overhead()) || length > chunkSize+uint32(o.aead.Overhead()) {
+		return ErrCorrupt
+	}
+	ciphertext := make([]byte, length)
+	// No else needed: early return pattern (guard clause)
+	if _, err := io.ReadFull(o.r, ciphertext); err != nil {
+		return truncated(err)
+	}
+
+	nonce := header[4:]
+	// The last chunk is flagged, so try both: only one authenticates
+	plaintext, err := o.aead.Open(nil, nonce, ciphertext, chunkAAD(o.index, false))
+	// No else needed: optional operation (retry as the last chunk)
+	if err != nil {
+		plaintext, err = o.aead.Open(nil, nonce, ciphertext, chunkAAD(o.index, true))
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return ErrCorrupt
+		}
+		o.final = true
+	}
+	o.index++
+	o.buf = plaintext
+	return nil
+}
+
+// checkEnd reports io.EOF when nothing follows the last chunk
+func (o *openReader) checkEnd() error {
+	var extra [1]byte
+	n, err := o.r.Read(extra[:])
+	// No else needed: early return pattern (guard clause)
+	if n > 0 {
+		return ErrCorrupt
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return io.EOF
+}
+
+// truncated maps short reads of a chunk to ErrTruncated
+func truncated(err error) error {
+	// No else needed: early return pattern (guard clause)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
+
+// Writer writes documents to an encrypted, compressed archive
+type Writer struct {
+	seal  *sealWriter
+	gz    *gzip.Writer
+	count int
+}
+
+// NewWriter writes the archive header to w and returns a writer sealing with key.
+// Close must be called to complete the archive.
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	aead, err := newAEAD(key)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := io.WriteString(w, magic); err != nil {
+		return nil, err
+	}
+	seal := &sealWriter{w: w, aead: aead}
+	return &Writer{seal: seal, gz: gzip.NewWriter(seal)}, nil
+}
+
+// Add appends doc, which must marshal to a BSON document
+func (w *Writer) Add(doc interface{}) error {
+	data, err := bson.Marshal(doc)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := w.gz.Write(data); err != nil {
+		return err
+	}
+	w.count++
+	return nil
+}
+
+// Count returns the number of documents added
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Close completes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	// No else needed: early return pattern (guard clause)
+	if err := w.gz.Close(); err != nil {
+		return err
+	}
+	return w.seal.Close()
+}
+
+// Reader reads the documents of an archive written by Writer
+type Reader struct {
+	gz *gzip.Reader
+}
+
+// NewReader checks the archive header of r and returns a reader opening it with key
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	aead, err := newAEAD(key)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(magic))
+	// No else needed: early return pattern (guard clause)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header, []byte(magic)) {
+		return nil, ErrNotArchive
+	}
+	gz, err := gzip.NewReader(&openReader{r: r, aead: aead})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, archiveError(err)
+	}
+	return &Reader{gz: gz}, nil
+}
+
+// Next decodes the next document into out. It returns io.EOF after the last
+// document, once the whole archive has been authenticated.
+func (r *Reader) Next(out interface{}) error {
+	var length [4]byte
+	_, err := io.ReadFull(r.gz, length[:])
+	// No else needed: early return pattern (end of archive)
+	if err == io.EOF {
+		return io.EOF
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return archiveError(err)
+	}
+	size := binary.LittleEndian.Uint32(length[:])
+	// No else needed: early return pattern (guard clause)
+	if size < 5 || size > maxDocumentSize {
+		return ErrCorrupt
+	}
+	data := make([]byte, size)
+	copy(data, length[:])
+	// No else needed: early return pattern (guard clause)
+	if _, err := io.ReadFull(r.gz, data[4:]); err != nil {
+		return archiveError(err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := bson.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode document: %w", err)
+	}
+	return nil
+}
+
+// archiveError maps decompression failures to archive errors
+func archiveError(err error) error {
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(err, ErrCorrupt) || errors.Is(err, ErrTruncated) {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return fmt.Errorf("%w: %v", ErrCorrupt, err)
+}
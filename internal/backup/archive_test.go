@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+type testDoc struct {
+	ID   string `bson:"_id"`
+	Body string `bson:"body"`
+}
+
+// writeArchive returns an archive of docs sealed with testKey
+func writeArchive(t *testing.T, docs ...testDoc) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testKey)
+	require.NoError(t, err)
+	for _, doc := range docs {
+		require.NoError(t, w.Add(doc))
+	}
+	assert.Equal(t, len(docs), w.Count())
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// readArchive returns the documents of data and the error that ended the read
+func readArchive(data, key []byte) ([]testDoc, error) {
+	r, err := NewReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+	var docs []testDoc
+	for {
+		var doc testDoc
+		err := r.Next(&doc)
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+func TestArchive_RoundTrip(t *testing.T) {
+	// Documents spanning several chunks
+	var docs []testDoc
+	for i := 0; i < 50; i++ {
+		docs = append(docs, testDoc{ID: string(rune('a' + i%26)), Body: strings.Repeat("x", 8*1024) + string(rune(i))})
+	}
+	data := writeArchive(t, docs...)
+	assert.NotContains(t, string(data), "xxxx", "content is encrypted")
+
+	read, err := readArchive(data, testKey)
+	require.NoError(t, err)
+	assert.Equal(t, docs, read)
+
+	empty, err := readArchive(writeArchive(t), testKey)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+// randomBody returns n bytes of text that does not compress well, so archives
+// of it span several chunks
+func randomBody(n int) string {
+	rng := rand.New(rand.NewSource(1))
+	buf := make([]byte, n/2)
+	rng.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func TestArchive_Rejected(t *testing.T) {
+	data := writeArchive(t, testDoc{ID: "1", Body: randomBody(300 * 1024)})
+
+	_, err := NewWriter(io.Discard, []byte("short"))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = readArchive([]byte("not an archive"), testKey)
+	assert.ErrorIs(t, err, ErrNotArchive)
+
+	_, err = readArchive(data, []byte("fedcba9876543210fedcba9876543210"))
+	assert.ErrorIs(t, err, ErrCorrupt, "wrong key")
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-5] ^= 1
+	_, err = readArchive(tampered, testKey)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = readArchive(data[:len(data)-10], testKey)
+	assert.ErrorIs(t, err, ErrTruncated)
+
+	_, err = readArchive(append(append([]byte(nil), data...), 0), testKey)
+	assert.ErrorIs(t, err, ErrCorrupt, "data after the last chunk")
+}
+
+func TestArchive_TruncatedAtChunkBoundary(t *testing.T) {
+	data := writeArchive(t, testDoc{ID: "1", Body: randomBody(300 * 1024)})
+
+	// Drop the last chunk: the remaining chunks all authenticate
+	first := len(magic) + 4 + nonceSize + int(bytesToLength(data[len(magic):]))
+	_, err := readArchive(data[:first], testKey)
+	assert.ErrorIs(t, err, ErrTruncated)
+}
+
+func TestArchive_DocumentsAreBSON(t *testing.T) {
+	// Documents keep their BSON field names, like mongodump output
+	raw, err := bson.Marshal(testDoc{ID: "1", Body: "hi"})
+	require.NoError(t, err)
+	r, err := NewReader(bytes.NewReader(writeArchive(t, testDoc{ID: "1", Body: "hi"})), testKey)
+	require.NoError(t, err)
+	var doc bson.Raw
+	require.NoError(t, r.Next(&doc))
+	assert.Equal(t, bson.Raw(raw), doc)
+}
+
+// bytesToLength reads a chunk's ciphertext length
+func bytesToLength(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
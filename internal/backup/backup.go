@@ -0,0 +1,349 @@
+// Package backup exports stored sessions to encrypted, compressed archives and
+// restores them.
+//
+// Archives are independent of mongodump: message content is decrypted on export
+// and re-encrypted with the restoring service's key, so backups survive
+// rotations of the message encryption key and never contain it. Instead each
+// archive is sealed with a dedicated backup key (AES-256-GCM over a gzip stream
+// of BSON session documents, see archive.go). Archives are kept by a Target: a
+// local directory or goupload storage such as S3.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+)
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// uploadOwner is the goupload owner of uploaded archives
+const uploadOwner = "backup"
+
+var (
+	// ErrJobRunning is returned when a backup is started while another one runs
+	ErrJobRunning = errors.New("a backup is already running")
+	// ErrShutdown is returned when a backup is started after Shutdown
+	ErrShutdown = errors.New("backup runner is shut down")
+)
+
+// Source lists the sessions to back up (implemented by storage.StorageService)
+type Source interface {
+	ExportSessions(ctx context.Context, fn func(doc *storage.SessionDocument) error) error
+}
+
+// Sink stores restored sessions (implemented by storage.StorageService)
+type Sink interface {
+	ImportSession(doc *storage.SessionDocument, strategy storage.ConflictStrategy) (storage.RestoreOutcome, error)
+}
+
+// Job reports a backup run
+type Job struct {
+	ID          string     `json:"id"` // archive name
+	State       string     `json:"state"`
+	Target      string     `json:"target"`
+	Location    string     `json:"location,omitempty"` // where the archive is stored, once completed
+	Sessions    int        `json:"sessions"`
+	Bytes       int64      `json:"bytes"`
+	RequestedBy string     `json:"requested_by,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// Runner runs backup jobs in the background, one at a time, and keeps the
+// latest jobs of this replica
+type Runner struct {
+	source Source
+	target Target
+	key    []byte
+	logger *golog.Logger
+
+	mu      sync.Mutex
+	jobs    []Job // oldest first
+	running bool
+	closed  bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewRunner creates a runner exporting source to target, sealed with key
+func NewRunner(source Source, target Target, key []byte, logger *golog.Logger) (*Runner, error) {
+	// No else needed: early return pattern (guard clause)
+	if source == nil || target == nil {
+		return nil, errors.New("backup source and target are required")
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	return &Runner{source: source, target: target, key: key, logger: logger}, nil
+}
+
+// Start begins a backup in the background and returns its job. Returns
+// ErrJobRunning while another backup runs.
+func (r *Runner) Start(requestedBy string, now time.Time) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// No else needed: early return pattern (guard clause)
+	if r.closed {
+		return Job{}, ErrShutdown
+	}
+	// No else needed: early return pattern (guard clause)
+	if r.running {
+		return Job{}, ErrJobRunning
+	}
+
+	job := Job{
+		ID:          ArchiveName(now),
+		State:       JobRunning,
+		Target:      r.target.Name(),
+		RequestedBy: requestedBy,
+		StartedAt:   now.UTC(),
+	}
+	r.jobs = append(r.jobs, job)
+	// No else needed: optional operation (keep the latest jobs only)
+	if len(r.jobs) > constants.MaxBackupJobs {
+		r.jobs = r.jobs[len(r.jobs)-constants.MaxBackupJobs:]
+	}
+	r.running = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.BackupTimeout)
+	r.cancel = cancel
+	r.wg.Add(1)
+	util.SafeGo(r.logger, "backup", func() {
+		defer r.wg.Done()
+		defer cancel()
+		r.finish(r.run(ctx, job))
+	})
+	return job, nil
+}
+
+// Jobs returns the latest jobs, newest first
+func (r *Runner) Jobs() []Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	jobs := make([]Job, len(r.jobs))
+	for i, job := range r.jobs {
+		jobs[len(jobs)-1-i] = job
+	}
+	return jobs
+}
+
+// Shutdown cancels the running backup, if any, and waits for it to stop. If
+// ctx expires first, ctx.Err() is returned.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	// No else needed: optional operation (only while a backup runs)
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish records the outcome of the running job
+func (r *Runner) finish(job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.jobs {
+		// No else needed: optional operation (the job may have been evicted)
+		if r.jobs[i].ID == job.ID {
+			r.jobs[i] = job
+		}
+	}
+	r.running = false
+	r.cancel = nil
+}
+
+// run writes the archive to a temporary file, then stores it in the target
+func (r *Runner) run(ctx context.Context, job Job) Job {
+	err := r.backup(ctx, &job)
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		job.State = JobFailed
+		job.Error = err.Error()
+		metrics.Backups.WithLabelValues(job.Target, JobFailed).Inc()
+		util.LogError(r.logger, "backup", "back up sessions", err, "backup_id", job.ID)
+		return job
+	}
+	job.State = JobCompleted
+	metrics.Backups.WithLabelValues(job.Target, JobCompleted).Inc()
+	r.logger.Info("Session backup completed",
+		"backup_id", job.ID,
+		"target", job.Target,
+		"location", job.Location,
+		"sessions", job.Sessions,
+		"bytes", job.Bytes)
+	return job
+}
+
+// backup exports the sessions and stores the archive, filling in job
+func (r *Runner) backup(ctx context.Context, job *Job) error {
+	tmp, err := os.CreateTemp("", "chatbox-backup-*")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	sessions, err := Export(ctx, r.source, tmp, r.key)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to read temporary archive: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read temporary archive: %w", err)
+	}
+
+	location, err := r.target.Store(ctx, job.ID, tmp, size)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	job.Location = location
+	job.Sessions = sessions
+	job.Bytes = size
+	return nil
+}
+
+// ArchiveName returns the name of an archive started at now
+func ArchiveName(now time.Time) string {
+	return constants.BackupArchivePrefix + now.UTC().Format("20060102T150405Z") + constants.BackupArchiveSuffix
+}
+
+// Export writes every session of source to w as an archive sealed with key and
+// returns the number of sessions
+func Export(ctx context.Context, source Source, w io.Writer, key []byte) (int, error) {
+	archive, err := NewWriter(w, key)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, err
+	}
+	err = source.ExportSessions(ctx, func(doc *storage.SessionDocument) error {
+		return archive.Add(doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, fmt.Errorf("failed to export sessions: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := archive.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return archive.Count(), nil
+}
+
+// RestoreResult counts restored sessions by outcome
+type RestoreResult struct {
+	Created     int      `json:"created"`
+	Overwritten int      `json:"overwritten"`
+	Merged      int      `json:"merged"`
+	Skipped     int      `json:"skipped"`
+	Failed      int      `json:"failed"`
+	Errors      []string `json:"errors,omitempty"` // the first constants.MaxRestoreErrors session errors
+}
+
+// Restore stores the sessions of the archive read from r in sink, resolving
+// conflicts with strategy. Sessions that fail are counted and the restore goes
+// on; a corrupt or truncated archive stops it, and the result so far is
+// returned with the error.
+func Restore(ctx context.Context, r io.Reader, key []byte, sink Sink, strategy storage.ConflictStrategy, logger *golog.Logger) (*RestoreResult, error) {
+	result := &RestoreResult{}
+	// No else needed: early return pattern (guard clause)
+	if _, err := storage.ParseConflictStrategy(string(strategy)); err != nil {
+		return result, err
+	}
+	archive, err := NewReader(r, key)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return result, err
+	}
+
+	for {
+		// No else needed: early return pattern (guard clause)
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		var doc storage.SessionDocument
+		err := archive.Next(&doc)
+		// No else needed: early return pattern (end of archive)
+		if err == io.EOF {
+			break
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return result, err
+		}
+
+		outcome, err := sink.ImportSession(&doc, strategy)
+		// No else needed: early return pattern (continue with the next session)
+		if err != nil {
+			result.Failed++
+			metrics.RestoredSessions.WithLabelValues("failed").Inc()
+			// No else needed: optional operation (cap the listed errors)
+			if len(result.Errors) < constants.MaxRestoreErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", doc.ID, err))
+			}
+			util.LogError(logger, "backup", "restore session", err, "session_id", doc.ID)
+			continue
+		}
+		metrics.RestoredSessions.WithLabelValues(string(outcome)).Inc()
+		switch outcome {
+		case storage.RestoreCreated:
+			result.Created++
+		case storage.RestoreOverwritten:
+			result.Overwritten++
+		case storage.RestoreMerged:
+			result.Merged++
+		default:
+			result.Skipped++
+		}
+	}
+
+	logger.Info("Session restore completed",
+		"strategy", strategy,
+		"created", result.Created,
+		"overwritten", result.Overwritten,
+		"merged", result.Merged,
+		"skipped", result.Skipped,
+		"failed", result.Failed)
+	return result, nil
+}
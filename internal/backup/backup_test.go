@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(t *testing.T) *golog.Logger {
+	t.Helper()
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+	return logger
+}
+
+// fakeSource exports fixed sessions, blocking until release is closed when set
+type fakeSource struct {
+	docs    []*storage.SessionDocument
+	release chan struct{}
+}
+
+func (f *fakeSource) ExportSessions(ctx context.Context, fn func(doc *storage.SessionDocument) error) error {
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for _, doc := range f.docs {
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fakeSink keeps imported sessions by ID, resolving conflicts like storage
+type fakeSink struct {
+	sessions map[string]*storage.SessionDocument
+	fail     string
+}
+
+func (f *fakeSink) ImportSession(doc *storage.SessionDocument, strategy storage.ConflictStrategy) (storage.RestoreOutcome, error) {
+	if doc.ID == f.fail {
+		return "", errors.New("write failed")
+	}
+	_, exists := f.sessions[doc.ID]
+	switch {
+	case !exists:
+		f.sessions[doc.ID] = doc
+		return storage.RestoreCreated, nil
+	case strategy == storage.ConflictOverwrite:
+		f.sessions[doc.ID] = doc
+		return storage.RestoreOverwritten, nil
+	default:
+		return storage.RestoreSkipped, nil
+	}
+}
+
+func testSessions() []*storage.SessionDocument {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []*storage.SessionDocument{
+		{ID: "s1", UserID: "u1", StartTime: start, Messages: []storage.MessageDocument{{Content: "hello", Sender: "user", Timestamp: start}}},
+		{ID: "s2", UserID: "u2", StartTime: start, Tags: []string{"vip"}},
+	}
+}
+
+func TestExportAndRestore(t *testing.T) {
+	logger := newTestLogger(t)
+	var archive bytes.Buffer
+	n, err := Export(context.Background(), &fakeSource{docs: testSessions()}, &archive, testKey)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	sink := &fakeSink{sessions: map[string]*storage.SessionDocument{"s2": {ID: "s2"}}}
+	result, err := Restore(context.Background(), bytes.NewReader(archive.Bytes()), testKey, sink, storage.ConflictSkip, logger)
+	require.NoError(t, err)
+	assert.Equal(t, &RestoreResult{Created: 1, Skipped: 1}, result)
+	assert.Equal(t, "hello", sink.sessions["s1"].Messages[0].Content)
+	assert.Empty(t, sink.sessions["s2"].Tags, "skip keeps the stored session")
+
+	result, err = Restore(context.Background(), bytes.NewReader(archive.Bytes()), testKey, sink, storage.ConflictOverwrite, logger)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Overwritten)
+	assert.Equal(t, []string{"vip"}, sink.sessions["s2"].Tags)
+
+	// Failed sessions are reported and the restore goes on
+	sink = &fakeSink{sessions: map[string]*storage.SessionDocument{}, fail: "s1"}
+	result, err = Restore(context.Background(), bytes.NewReader(archive.Bytes()), testKey, sink, storage.ConflictMerge, logger)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 1, result.Created)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "s1")
+
+	_, err = Restore(context.Background(), bytes.NewReader(archive.Bytes()), testKey, sink, "replace", logger)
+	assert.ErrorIs(t, err, storage.ErrInvalidConflictStrategy)
+	_, err = Restore(context.Background(), bytes.NewReader(archive.Bytes()[:archive.Len()-4]), testKey, sink, storage.ConflictSkip, logger)
+	assert.ErrorIs(t, err, ErrTruncated)
+}
+
+func TestRunner(t *testing.T) {
+	logger := newTestLogger(t)
+	target, err := NewDirTarget(t.TempDir())
+	require.NoError(t, err)
+	source := &fakeSource{docs: testSessions(), release: make(chan struct{})}
+
+	_, err = NewRunner(source, target, []byte("short"), logger)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	runner, err := NewRunner(source, target, testKey, logger)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	job, err := runner.Start("admin-1", now)
+	require.NoError(t, err)
+	assert.Equal(t, "chat-sessions-20260304T050607Z.bak", job.ID)
+	assert.Equal(t, JobRunning, job.State)
+	assert.Equal(t, TargetLocal, job.Target)
+
+	_, err = runner.Start("admin-1", now)
+	assert.ErrorIs(t, err, ErrJobRunning, "one backup at a time")
+
+	close(source.release)
+	require.Eventually(t, func() bool { return runner.Jobs()[0].State != JobRunning }, 5*time.Second, 10*time.Millisecond)
+	done := runner.Jobs()[0]
+	assert.Equal(t, JobCompleted, done.State, done.Error)
+	assert.Equal(t, 2, done.Sessions)
+	assert.Equal(t, "admin-1", done.RequestedBy)
+	require.NotNil(t, done.FinishedAt)
+
+	// The stored archive restores
+	r, err := target.Open(context.Background(), done.Location)
+	require.NoError(t, err)
+	defer r.Close()
+	sink := &fakeSink{sessions: map[string]*storage.SessionDocument{}}
+	result, err := Restore(context.Background(), r, testKey, sink, storage.ConflictSkip, logger)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Created)
+
+	require.NoError(t, runner.Shutdown(context.Background()))
+	_, err = runner.Start("admin-1", now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrShutdown)
+}
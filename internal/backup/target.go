@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/real-rm/gomongo"
+	"github.com/real-rm/goupload"
+)
+
+// Target names used in configuration
+const (
+	TargetLocal = "local"
+	TargetS3    = "s3"
+)
+
+// ErrInvalidLocation is returned for archive locations a target does not own
+var ErrInvalidLocation = errors.New("invalid backup location")
+
+// Target stores archives. Implementations must be safe for concurrent use.
+type Target interface {
+	// Name identifies the target in job reports
+	Name() string
+	// Store saves the archive read from r as name and returns where it is stored
+	Store(ctx context.Context, name string, r io.Reader, size int64) (location string, err error)
+	// Open returns the archive stored at location
+	Open(ctx context.Context, location string) (io.ReadCloser, error)
+}
+
+// DirTarget stores archives as files in a local directory. Locations are file
+// names in the directory.
+type DirTarget struct {
+	dir string
+}
+
+// NewDirTarget creates dir if needed; archives are only readable by the owner
+func NewDirTarget(dir string) (*DirTarget, error) {
+	// No else needed: early return pattern (guard clause)
+	if dir == "" {
+		return nil, errors.New("backup directory is required")
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return &DirTarget{dir: dir}, nil
+}
+
+// Name returns the target name
+func (t *DirTarget) Name() string {
+	return TargetLocal
+}
+
+// Store writes the archive to a temporary file renamed once complete, so
+// interrupted backups never leave partial archives under their final name
+func (t *DirTarget) Store(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	path, err := t.path(name)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(t.dir, "."+name+".*")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	// No else needed: optional operation (close even after a failed copy)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", fmt.Errorf("failed to write backup file: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return name, nil
+}
+
+// Open opens the archive file named location
+func (t *DirTarget) Open(ctx context.Context, location string) (io.ReadCloser, error) {
+	path, err := t.path(location)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// path returns the path of the archive file name, which must not leave the directory
+func (t *DirTarget) path(name string) (string, error) {
+	// No else needed: early return pattern (guard clause)
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", ErrInvalidLocation
+	}
+	return filepath.Join(t.dir, name), nil
+}
+
+// UploadTarget stores archives with goupload, e.g. in S3, under an upload entry
+// of its own. Locations are goupload file paths.
+type UploadTarget struct {
+	statsUpdater goupload.StatsUpdater
+	site         string
+	entryName    string
+}
+
+// NewUploadTarget creates a target storing archives in the goupload entry
+// entryName of site
+func NewUploadTarget(site, entryName string, statsColl *gomongo.MongoCollection) (*UploadTarget, error) {
+	// No else needed: early return pattern (guard clause)
+	if site == "" || entryName == "" {
+		return nil, errors.New("backup upload site and entry are required")
+	}
+	statsUpdater, err := goupload.NewStatsUpdater(site, entryName, statsColl)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stats updater: %w", err)
+	}
+	return &UploadTarget{statsUpdater: statsUpdater, site: site, entryName: entryName}, nil
+}
+
+// Name returns the target name
+func (t *UploadTarget) Name() string {
+	return TargetS3
+}
+
+// Store uploads the archive
+func (t *UploadTarget) Store(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	result, err := goupload.Upload(ctx, t.statsUpdater, t.site, t.entryName, uploadOwner, r, name, size)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload backup: %w", err)
+	}
+	return result.Filename, nil
+}
+
+// Open downloads the archive at location
+func (t *UploadTarget) Open(ctx context.Context, location string) (io.ReadCloser, error) {
+	// No else needed: early return pattern (guard clause)
+	if location == "" {
+		return nil, ErrInvalidLocation
+	}
+	info, err := goupload.Download(ctx, t.site, t.entryName, location)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download backup: %w", err)
+	}
+	return io.NopCloser(bytes.NewReader(info.Content)), nil
+}
@@ -0,0 +1,43 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirTarget(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	target, err := NewDirTarget(dir)
+	require.NoError(t, err)
+	assert.Equal(t, TargetLocal, target.Name())
+
+	location, err := target.Store(context.Background(), "archive.bak", strings.NewReader("sealed"), 6)
+	require.NoError(t, err)
+	assert.Equal(t, "archive.bak", location)
+	info, err := os.Stat(filepath.Join(dir, "archive.bak"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "archives are only readable by the owner")
+
+	r, err := target.Open(context.Background(), location)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, r.Close())
+	require.NoError(t, err)
+	assert.Equal(t, "sealed", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+
+	for _, location := range []string{"", ".", "..", "../archive.bak", "sub/archive.bak"} {
+		_, err := target.Open(context.Background(), location)
+		assert.ErrorIs(t, err, ErrInvalidLocation, location)
+	}
+}
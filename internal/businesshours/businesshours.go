@@ -0,0 +1,238 @@
+// Package businesshours holds the weekly hours in which administrators answer
+// help requests.
+//
+// Hours are configured under [chatbox.business_hours] in an IANA time zone.
+// Outside them, help requests get an automatic reply saying when the team is
+// back, and their sessions are tagged for next-day triage. LLM chat is not
+// affected.
+package businesshours
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// ErrInvalidHours is returned when business hours are misconfigured
+var ErrInvalidHours = errors.New("invalid business hours")
+
+// minutesPerDay is the close time of a period lasting until midnight
+const minutesPerDay = 24 * 60
+
+// Period is a daily opening period in minutes after midnight, closing at
+// Close (exclusive). Close is at most 24:00.
+type Period struct {
+	Open  int
+	Close int
+}
+
+// dayNames maps the keys of the days table to weekdays
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule is the weekly business hours. A nil schedule is always open.
+// It is immutable after creation and safe for concurrent use.
+type Schedule struct {
+	location *time.Location
+	days     [7][]Period // by weekday, sorted by open
+	message  string      // Start of the automatic reply outside business hours
+}
+
+// NewSchedule creates business hours in location from each weekday's opening
+// periods; days without periods are closed. Returns ErrInvalidHours for a
+// period that does not close after it opens, periods that overlap, or a week
+// without any period.
+func NewSchedule(location *time.Location, days map[time.Weekday][]Period, message string) (*Schedule, error) {
+	// No else needed: early return pattern (guard clause)
+	if location == nil {
+		return nil, fmt.Errorf("%w: a time zone is required", ErrInvalidHours)
+	}
+	s := &Schedule{location: location, message: message}
+	// No else needed: conditional assignment (default message)
+	if s.message == "" {
+		s.message = constants.DefaultAfterHoursMessage
+	}
+
+	open := false
+	for day, periods := range days {
+		sorted := make([]Period, len(periods))
+		copy(sorted, periods)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Open < sorted[j].Open })
+		for i, p := range sorted {
+			// No else needed: early return pattern (guard clause)
+			if p.Open < 0 || p.Close > minutesPerDay || p.Close <= p.Open {
+				return nil, fmt.Errorf("%w: %s %s must close after it opens, within the day", ErrInvalidHours, day, p)
+			}
+			// No else needed: early return pattern (guard clause)
+			if i > 0 && p.Open < sorted[i-1].Close {
+				return nil, fmt.Errorf("%w: %s %s overlaps %s", ErrInvalidHours, day, p, sorted[i-1])
+			}
+		}
+		s.days[day] = sorted
+		open = open || len(sorted) > 0
+	}
+	// No else needed: early return pattern (guard clause)
+	if !open {
+		return nil, fmt.Errorf("%w: at least one day must have opening hours", ErrInvalidHours)
+	}
+	return s, nil
+}
+
+// String formats p as "HH:MM-HH:MM"
+func (p Period) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", p.Open/60, p.Open%60, p.Close/60, p.Close%60)
+}
+
+// ParseConfig converts the raw [chatbox.business_hours] config value, a table
+// with an optional timezone and message and a days table mapping mon ... sun
+// to comma-separated "HH:MM-HH:MM" periods ("" or "closed" for closed days,
+// like days that are left out), into a schedule
+func ParseConfig(raw interface{}) (*Schedule, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.business_hours is not a table")
+	}
+
+	timezone := constants.DefaultBusinessHoursTimezone
+	// No else needed: optional operation (timezone defaults to DefaultBusinessHoursTimezone)
+	if tz, ok := table["timezone"].(string); ok && tz != "" {
+		timezone = tz
+	}
+	location, err := time.LoadLocation(timezone)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidHours, timezone)
+	}
+
+	message, _ := table["message"].(string)
+
+	rawDays, ok := table["days"].(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("%w: days must be a table of mon ... sun = \"HH:MM-HH:MM\"", ErrInvalidHours)
+	}
+	days := make(map[time.Weekday][]Period, len(rawDays))
+	for name, value := range rawDays {
+		day, ok := dayNames[strings.ToLower(name)]
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown day %q, use mon, tue, wed, thu, fri, sat or sun", ErrInvalidHours, name)
+		}
+		spec, ok := value.(string)
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a string of \"HH:MM-HH:MM\" periods", ErrInvalidHours, name)
+		}
+		periods, err := parsePeriods(spec)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidHours, name, err)
+		}
+		days[day] = periods
+	}
+	return NewSchedule(location, days, message)
+}
+
+// parsePeriods parses comma-separated "HH:MM-HH:MM" periods; "" and "closed"
+// are no periods
+func parsePeriods(spec string) ([]Period, error) {
+	spec = strings.TrimSpace(spec)
+	// No else needed: early return pattern (closed day)
+	if spec == "" || strings.EqualFold(spec, "closed") {
+		return nil, nil
+	}
+
+	var periods []Period
+	for _, part := range strings.Split(spec, ",") {
+		opening, closing, ok := strings.Cut(strings.TrimSpace(part), "-")
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("period %q must be HH:MM-HH:MM", part)
+		}
+		openMin, err := parseClock(opening)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		closeMin, err := parseClock(closing)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, Period{Open: openMin, Close: closeMin})
+	}
+	return periods, nil
+}
+
+// parseClock parses "HH:MM", 00:00 to 24:00, into minutes after midnight
+func parseClock(clock string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(clock), ":")
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	// No else needed: early return pattern (guard clause)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h*60+m > minutesPerDay {
+		return 0, fmt.Errorf("time %q must be HH:MM between 00:00 and 24:00", clock)
+	}
+	return h*60 + m, nil
+}
+
+// Open reports whether now is within business hours
+func (s *Schedule) Open(now time.Time) bool {
+	// No else needed: early return pattern (no schedule)
+	if s == nil {
+		return true
+	}
+	local := now.In(s.location)
+	minute := local.Hour()*60 + local.Minute()
+	for _, p := range s.days[local.Weekday()] {
+		// No else needed: early return pattern (within a period)
+		if minute >= p.Open && minute < p.Close {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the start of the first opening period after now, or now
+// itself within business hours
+func (s *Schedule) NextOpen(now time.Time) time.Time {
+	// No else needed: early return pattern (already open)
+	if s.Open(now) {
+		return now
+	}
+	local := now.In(s.location)
+	// Every week has a period, so one starts within the next 7 days
+	for offset := 0; offset <= 7; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, s.location)
+		for _, p := range s.days[day.Weekday()] {
+			start := time.Date(day.Year(), day.Month(), day.Day(), p.Open/60, p.Open%60, 0, 0, s.location)
+			// No else needed: early return pattern (first opening after now)
+			if start.After(now) {
+				return start
+			}
+		}
+	}
+	return now
+}
+
+// AutoResponse returns the automatic reply to a help request at now, outside
+// business hours, and when the team is back
+func (s *Schedule) AutoResponse(now time.Time) (string, time.Time) {
+	opensAt := s.NextOpen(now)
+	content := fmt.Sprintf("%s An administrator will reply after we reopen on %s.",
+		s.message, opensAt.In(s.location).Format("Mon, Jan 2 at 15:04 MST"))
+	return content, opensAt
+}
@@ -0,0 +1,96 @@
+package businesshours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func weekdays(t *testing.T) *Schedule {
+	t.Helper()
+	schedule, err := ParseConfig(map[string]interface{}{
+		"timezone": "America/Toronto",
+		"message":  "We are closed.",
+		"days": map[string]interface{}{
+			"mon": "09:00-12:00, 13:00-17:00",
+			"tue": "09:00-17:00",
+			"wed": "09:00-17:00",
+			"thu": "09:00-17:00",
+			"fri": "09:00-17:00",
+			"sat": "closed",
+		},
+	})
+	require.NoError(t, err)
+	return schedule
+}
+
+func TestSchedule_Open(t *testing.T) {
+	schedule := weekdays(t)
+	toronto, err := time.LoadLocation("America/Toronto")
+	require.NoError(t, err)
+
+	// Monday 2026-03-02
+	assert.True(t, schedule.Open(time.Date(2026, 3, 2, 9, 0, 0, 0, toronto)))
+	assert.False(t, schedule.Open(time.Date(2026, 3, 2, 12, 30, 0, 0, toronto)), "lunch break")
+	assert.False(t, schedule.Open(time.Date(2026, 3, 2, 17, 0, 0, 0, toronto)), "periods close at their end")
+	assert.False(t, schedule.Open(time.Date(2026, 3, 7, 10, 0, 0, 0, toronto)), "closed on Saturday")
+	assert.False(t, schedule.Open(time.Date(2026, 3, 8, 10, 0, 0, 0, toronto)), "days left out are closed")
+	// 14:30 UTC is 09:30 in Toronto
+	assert.True(t, schedule.Open(time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)), "hours are in the schedule's time zone")
+
+	var none *Schedule
+	assert.True(t, none.Open(time.Now()), "no business hours is always open")
+}
+
+func TestSchedule_NextOpen(t *testing.T) {
+	schedule := weekdays(t)
+	toronto, err := time.LoadLocation("America/Toronto")
+	require.NoError(t, err)
+
+	open := time.Date(2026, 3, 3, 10, 0, 0, 0, toronto)
+	assert.Equal(t, open, schedule.NextOpen(open))
+
+	assert.Equal(t, time.Date(2026, 3, 2, 13, 0, 0, 0, toronto), schedule.NextOpen(time.Date(2026, 3, 2, 12, 30, 0, 0, toronto)))
+	assert.Equal(t, time.Date(2026, 3, 3, 9, 0, 0, 0, toronto), schedule.NextOpen(time.Date(2026, 3, 2, 18, 0, 0, 0, toronto)))
+	// Friday evening to Monday morning, across the switch to daylight saving time
+	next := schedule.NextOpen(time.Date(2026, 3, 6, 18, 0, 0, 0, toronto))
+	assert.Equal(t, time.Date(2026, 3, 9, 9, 0, 0, 0, toronto), next)
+	assert.Equal(t, 9, next.In(toronto).Hour())
+
+	content, opensAt := schedule.AutoResponse(time.Date(2026, 3, 6, 18, 0, 0, 0, toronto))
+	assert.Equal(t, next, opensAt)
+	assert.Equal(t, "We are closed. An administrator will reply after we reopen on Mon, Mar 9 at 09:00 EDT.", content)
+}
+
+func TestParseConfig_Defaults(t *testing.T) {
+	schedule, err := ParseConfig(map[string]interface{}{
+		"days": map[string]interface{}{"sun": "00:00-24:00"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, schedule.location)
+	assert.Equal(t, constants.DefaultAfterHoursMessage, schedule.message)
+	assert.True(t, schedule.Open(time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)), "open until midnight")
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	invalid := []interface{}{
+		"not a table",
+		map[string]interface{}{},
+		map[string]interface{}{"timezone": "Mars/Olympus", "days": map[string]interface{}{"mon": "09:00-17:00"}},
+		map[string]interface{}{"days": map[string]interface{}{"someday": "09:00-17:00"}},
+		map[string]interface{}{"days": map[string]interface{}{"mon": 9}},
+		map[string]interface{}{"days": map[string]interface{}{"mon": "09:00"}},
+		map[string]interface{}{"days": map[string]interface{}{"mon": "9am-5pm"}},
+		map[string]interface{}{"days": map[string]interface{}{"mon": "17:00-09:00"}},
+		map[string]interface{}{"days": map[string]interface{}{"mon": "09:00-24:30"}},
+		map[string]interface{}{"days": map[string]interface{}{"mon": "09:00-13:00,12:00-17:00"}},
+		map[string]interface{}{"days": map[string]interface{}{"mon": "closed", "tue": ""}},
+	}
+	for _, raw := range invalid {
+		_, err := ParseConfig(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+}
@@ -0,0 +1,419 @@
+// Package cluster routes WebSocket traffic between chatbox replicas over Redis
+// pub/sub, so that admin messages and broadcasts reach a user connected to a
+// different pod than the admin.
+//
+// Every replica subscribes to its own channel. A replica holding a connection
+// claims its ownership key (the session, or the admin and session of an admin
+// connection) with a TTL it refreshes while the connection stays up. Traffic
+// for a connection held elsewhere is published to the owner's channel. When a
+// pod dies its keys expire, and a publish no replica receives clears the stale
+// owner at once, so a client reconnecting to another pod takes over.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+	"github.com/redis/go-redis/v9"
+)
+
+// Envelope kinds
+const (
+	KindUser         = "user"          // Data is a frame for the session's user connection
+	KindAdmin        = "admin"         // Data is a frame for the admin connection of AdminID
+	KindAdminMessage = "admin_message" // Request: send Content as an admin message
+	KindTenantCheck  = "tenant_check"  // Request: check that TenantID and Roles may access the session
+	KindReply        = "reply"         // Reply to the request with the same ID
+)
+
+var (
+	// ErrNotForwarded is returned by Request when no other replica holds the connection
+	ErrNotForwarded = errors.New("connection not held by another replica")
+	// ErrRequestTimeout is returned by Request when the owner does not reply in time
+	ErrRequestTimeout = errors.New("replica did not reply in time")
+)
+
+// Envelope is a message between replicas
+type Envelope struct {
+	Kind      string          `json:"kind"`
+	ID        string          `json:"id,omitempty"` // request ID, echoed by the reply
+	From      string          `json:"from"`         // pod that sent the envelope
+	SessionID string          `json:"session_id,omitempty"`
+	AdminID   string          `json:"admin_id,omitempty"`
+	AdminName string          `json:"admin_name,omitempty"`
+	TenantID  string          `json:"tenant_id,omitempty"`
+	Roles     []string        `json:"roles,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"` // pre-marshaled frame, or the reply payload
+	Error     *ReplyError     `json:"error,omitempty"`
+}
+
+// ReplyError is a failed request, as the error code and message of a ChatError
+type ReplyError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handler handles the envelopes sent to this replica. For requests it returns
+// the reply; its return value is ignored for other kinds.
+type Handler func(env Envelope) Envelope
+
+// releaseScript deletes an ownership key only while it still names the pod.
+// KEYS[1] key; ARGV[1] pod ID.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// refreshScript extends an ownership key only while it still names the pod.
+// KEYS[1] key; ARGV: pod ID, TTL (ms).
+var refreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Bridge connects this replica to the others
+type Bridge struct {
+	client redis.UniversalClient
+	podID  string
+	ttl    time.Duration
+	logger *golog.Logger
+
+	mu      sync.Mutex
+	owned   map[string]struct{}      // ownership keys held by this pod
+	pending map[string]chan Envelope // request ID -> waiting requester
+
+	pubsub *redis.PubSub
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewBridge creates a bridge for the replica podID. Ownership keys expire
+// after ttl unless refreshed. An empty podID is replaced by a random one.
+func NewBridge(client redis.UniversalClient, podID string, ttl time.Duration, logger *golog.Logger) *Bridge {
+	// No else needed: optional operation (apply default)
+	if podID == "" {
+		podID = RandomPodID()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bridge{
+		client:  client,
+		podID:   podID,
+		ttl:     ttl,
+		logger:  logger.WithGroup("cluster"),
+		owned:   make(map[string]struct{}),
+		pending: make(map[string]chan Envelope),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// RandomPodID returns a random replica ID
+func RandomPodID() string {
+	return newID()
+}
+
+// newID returns a random hex ID
+func newID() string {
+	id := make([]byte, 8)
+	// crypto/rand.Read never returns an error on supported platforms
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// SessionKey is the ownership key of a session's user connection
+func SessionKey(sessionID string) string {
+	return constants.ClusterKeyPrefix + "session:" + sessionID
+}
+
+// AdminKey is the ownership key of an admin's connection to a session
+func AdminKey(adminID, sessionID string) string {
+	return constants.ClusterKeyPrefix + "admin:" + adminID + ":" + sessionID
+}
+
+// channel is the pub/sub channel of a replica
+func channel(podID string) string {
+	return constants.ClusterKeyPrefix + "pod:" + podID
+}
+
+// PodID returns this replica's ID
+func (b *Bridge) PodID() string {
+	return b.podID
+}
+
+// Start subscribes to this replica's channel, hands every envelope to handler
+// and refreshes the keys this replica owns until Close
+func (b *Bridge) Start(handler Handler) error {
+	b.pubsub = b.client.Subscribe(b.ctx, channel(b.podID))
+	// Wait for the subscription so that nothing published after Start is missed
+	ctx, cancel := context.WithTimeout(b.ctx, constants.ShortTimeout)
+	defer cancel()
+	// No else needed: early return pattern (guard clause)
+	if _, err := b.pubsub.Receive(ctx); err != nil {
+		_ = b.pubsub.Close()
+		return fmt.Errorf("failed to subscribe to cluster channel: %w", err)
+	}
+
+	b.wg.Add(2)
+	util.SafeGo(b.logger, "clusterReceive", func() {
+		defer b.wg.Done()
+		b.receive(handler)
+	})
+	util.SafeGo(b.logger, "clusterHeartbeat", func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(b.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-ticker.C:
+				b.refresh()
+			}
+		}
+	})
+	return nil
+}
+
+// receive dispatches the envelopes published to this replica's channel
+func (b *Bridge) receive(handler Handler) {
+	for msg := range b.pubsub.Channel() {
+		var env Envelope
+		// No else needed: optional operation (skip malformed envelopes)
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+			b.logger.Warn("Invalid cluster envelope", "error", err)
+			continue
+		}
+		metrics.ClusterMessagesReceived.With(prometheus.Labels{"kind": env.Kind}).Inc()
+
+		// No else needed: early return pattern (replies go to the waiting request)
+		if env.Kind == KindReply {
+			b.mu.Lock()
+			waiting, ok := b.pending[env.ID]
+			delete(b.pending, env.ID)
+			b.mu.Unlock()
+			// No else needed: optional operation (the request may have timed out)
+			if ok {
+				waiting <- env
+			}
+			continue
+		}
+
+		// Requests are handled concurrently so a slow one does not hold up delivery
+		// No else needed: optional operation (only requests expect a reply)
+		if env.ID != "" {
+			b.wg.Add(1)
+			util.SafeGo(b.logger, "clusterRequest", func() {
+				defer b.wg.Done()
+				reply := handler(env)
+				reply.Kind = KindReply
+				reply.ID = env.ID
+				// No else needed: optional operation (the requester times out)
+				if _, err := b.publish(env.From, reply); err != nil {
+					b.logger.Warn("Failed to reply to cluster request", "pod_id", env.From, "error", err)
+				}
+			})
+			continue
+		}
+		handler(env)
+	}
+}
+
+// refresh extends the keys this replica owns, and forgets the ones another
+// replica has claimed since
+func (b *Bridge) refresh() {
+	b.mu.Lock()
+	keys := make([]string, 0, len(b.owned))
+	for key := range b.owned {
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+
+	for _, key := range keys {
+		ctx, cancel := context.WithTimeout(b.ctx, constants.RedisOperationTimeout)
+		extended, err := refreshScript.Run(ctx, b.client, []string{key}, b.podID, b.ttl.Milliseconds()).Int()
+		cancel()
+		// No else needed: optional operation (retried on the next heartbeat)
+		if err != nil {
+			b.logger.Warn("Failed to refresh cluster ownership", "key", key, "error", err)
+			continue
+		}
+		// No else needed: optional operation (claimed by another replica)
+		if extended == 0 {
+			b.mu.Lock()
+			delete(b.owned, key)
+			b.mu.Unlock()
+		}
+	}
+}
+
+// Claim makes this replica the owner of key
+func (b *Bridge) Claim(key string) error {
+	ctx, cancel := context.WithTimeout(b.ctx, constants.RedisOperationTimeout)
+	defer cancel()
+	// No else needed: early return pattern (guard clause)
+	if err := b.client.Set(ctx, key, b.podID, b.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to claim %s: %w", key, err)
+	}
+	b.mu.Lock()
+	b.owned[key] = struct{}{}
+	b.mu.Unlock()
+	return nil
+}
+
+// Release gives up ownership of key, unless another replica has claimed it since
+func (b *Bridge) Release(key string) {
+	b.mu.Lock()
+	delete(b.owned, key)
+	b.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.RedisOperationTimeout)
+	defer cancel()
+	// No else needed: optional operation (the key expires on its own)
+	if err := releaseScript.Run(ctx, b.client, []string{key}, b.podID).Err(); err != nil {
+		b.logger.Warn("Failed to release cluster ownership", "key", key, "error", err)
+	}
+}
+
+// owner returns the replica owning key, or "" when none does
+func (b *Bridge) owner(ctx context.Context, key string) (string, error) {
+	podID, err := b.client.Get(ctx, key).Result()
+	// No else needed: early return pattern (no owner)
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return podID, err
+}
+
+// publish sends env to the replica podID and reports whether it received it
+func (b *Bridge) publish(podID string, env Envelope) (bool, error) {
+	env.From = b.podID
+	payload, err := json.Marshal(env)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal cluster envelope: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, constants.RedisOperationTimeout)
+	defer cancel()
+	receivers, err := b.client.Publish(ctx, channel(podID), payload).Result()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return false, fmt.Errorf("failed to publish cluster envelope: %w", err)
+	}
+	return receivers > 0, nil
+}
+
+// route publishes env to the replica owning key. Returns false when no other
+// replica owns it. An owner that is no longer subscribed has died: its stale
+// ownership is cleared so the connection can be claimed again.
+func (b *Bridge) route(key string, env Envelope) (bool, error) {
+	ctx, cancel := context.WithTimeout(b.ctx, constants.RedisOperationTimeout)
+	podID, err := b.owner(ctx, key)
+	cancel()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up owner of %s: %w", key, err)
+	}
+	// No else needed: early return pattern (held here or nowhere)
+	if podID == "" || podID == b.podID {
+		return false, nil
+	}
+
+	received, err := b.publish(podID, env)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		metrics.ClusterForwardFailures.Inc()
+		return false, err
+	}
+	// No else needed: early return pattern (owner is gone)
+	if !received {
+		b.logger.Warn("Cluster owner is gone, clearing its ownership", "key", key, "pod_id", podID)
+		metrics.ClusterForwardFailures.Inc()
+		rctx, rcancel := context.WithTimeout(b.ctx, constants.RedisOperationTimeout)
+		defer rcancel()
+		_ = releaseScript.Run(rctx, b.client, []string{key}, podID).Err()
+		return false, nil
+	}
+	metrics.ClusterMessagesForwarded.With(prometheus.Labels{"kind": env.Kind}).Inc()
+	return true, nil
+}
+
+// Forward delivers env to the replica owning key. Returns false when no other
+// replica holds the connection.
+func (b *Bridge) Forward(key string, env Envelope) (bool, error) {
+	env.ID = ""
+	return b.route(key, env)
+}
+
+// Request sends env to the replica owning key and waits for its reply.
+// Returns ErrNotForwarded when no other replica holds the connection.
+func (b *Bridge) Request(key string, env Envelope) (Envelope, error) {
+	env.ID = newID()
+	waiting := make(chan Envelope, 1)
+	b.mu.Lock()
+	b.pending[env.ID] = waiting
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, env.ID)
+		b.mu.Unlock()
+	}()
+
+	forwarded, err := b.route(key, env)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return Envelope{}, err
+	}
+	// No else needed: early return pattern (not held elsewhere)
+	if !forwarded {
+		return Envelope{}, ErrNotForwarded
+	}
+
+	timer := time.NewTimer(constants.ClusterRequestTimeout)
+	defer timer.Stop()
+	select {
+	case reply := <-waiting:
+		return reply, nil
+	case <-timer.C:
+		return Envelope{}, ErrRequestTimeout
+	case <-b.ctx.Done():
+		return Envelope{}, ErrRequestTimeout
+	}
+}
+
+// Close stops receiving and releases every key this replica owns, so clients
+// reconnecting to other replicas are reachable at once
+func (b *Bridge) Close() {
+	b.mu.Lock()
+	keys := make([]string, 0, len(b.owned))
+	for key := range b.owned {
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+	for _, key := range keys {
+		b.Release(key)
+	}
+
+	b.cancel()
+	// No else needed: optional operation (only after Start)
+	if b.pubsub != nil {
+		_ = b.pubsub.Close()
+	}
+	b.wg.Wait()
+}
@@ -0,0 +1,143 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/real-rm/golog"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestLogger() *golog.Logger {
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            "/tmp/chatbox-cluster-test-logs",
+		Level:          "error",
+		StandardOutput: false,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize test logger: %v", err))
+	}
+	return logger
+}
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+// newTestBridge returns a started bridge for podID that hands its envelopes to handler
+func newTestBridge(t *testing.T, client *redis.Client, podID string, handler Handler) *Bridge {
+	t.Helper()
+	bridge := NewBridge(client, podID, time.Minute, createTestLogger())
+	require.NoError(t, bridge.Start(handler))
+	t.Cleanup(bridge.Close)
+	return bridge
+}
+
+func TestBridge_ForwardToOwner(t *testing.T) {
+	_, client := newTestRedis(t)
+	received := make(chan Envelope, 1)
+	podA := newTestBridge(t, client, "pod-a", func(env Envelope) Envelope { return Envelope{} })
+	podB := newTestBridge(t, client, "pod-b", func(env Envelope) Envelope {
+		received <- env
+		return Envelope{}
+	})
+
+	require.NoError(t, podB.Claim(SessionKey("session-1")))
+
+	forwarded, err := podA.Forward(SessionKey("session-1"), Envelope{Kind: KindUser, SessionID: "session-1", Data: []byte(`{"type":"ai_response"}`)})
+	require.NoError(t, err)
+	assert.True(t, forwarded)
+	select {
+	case env := <-received:
+		assert.Equal(t, KindUser, env.Kind)
+		assert.Equal(t, "pod-a", env.From)
+		assert.JSONEq(t, `{"type":"ai_response"}`, string(env.Data))
+	case <-time.After(time.Second):
+		t.Fatal("envelope not delivered to the owner")
+	}
+
+	forwarded, err = podA.Forward(SessionKey("session-2"), Envelope{Kind: KindUser})
+	require.NoError(t, err)
+	assert.False(t, forwarded, "nobody owns the session")
+
+	forwarded, err = podB.Forward(SessionKey("session-1"), Envelope{Kind: KindUser})
+	require.NoError(t, err)
+	assert.False(t, forwarded, "the owner delivers locally")
+}
+
+func TestBridge_Request(t *testing.T) {
+	_, client := newTestRedis(t)
+	podA := newTestBridge(t, client, "pod-a", func(env Envelope) Envelope { return Envelope{} })
+	podB := newTestBridge(t, client, "pod-b", func(env Envelope) Envelope {
+		return Envelope{Content: "handled " + env.Kind + " for " + env.SessionID}
+	})
+	require.NoError(t, podB.Claim(SessionKey("session-1")))
+
+	reply, err := podA.Request(SessionKey("session-1"), Envelope{Kind: KindTenantCheck, SessionID: "session-1"})
+	require.NoError(t, err)
+	assert.Equal(t, KindReply, reply.Kind)
+	assert.Equal(t, "handled tenant_check for session-1", reply.Content)
+
+	_, err = podA.Request(SessionKey("session-2"), Envelope{Kind: KindTenantCheck})
+	assert.ErrorIs(t, err, ErrNotForwarded)
+}
+
+func TestBridge_DeadOwnerIsCleared(t *testing.T) {
+	mr, client := newTestRedis(t)
+	podA := newTestBridge(t, client, "pod-a", func(env Envelope) Envelope { return Envelope{} })
+
+	// pod-b claimed the session and died without releasing it
+	dead := NewBridge(client, "pod-b", time.Minute, createTestLogger())
+	require.NoError(t, dead.Claim(SessionKey("session-1")))
+
+	forwarded, err := podA.Forward(SessionKey("session-1"), Envelope{Kind: KindUser})
+	require.NoError(t, err)
+	assert.False(t, forwarded)
+	assert.False(t, mr.Exists(SessionKey("session-1")), "stale ownership cleared")
+}
+
+func TestBridge_OwnershipExpires(t *testing.T) {
+	mr, client := newTestRedis(t)
+	bridge := NewBridge(client, "pod-a", time.Minute, createTestLogger())
+	require.NoError(t, bridge.Claim(SessionKey("session-1")))
+
+	bridge.refresh()
+	mr.FastForward(50 * time.Second)
+	bridge.refresh()
+	mr.FastForward(50 * time.Second)
+	assert.True(t, mr.Exists(SessionKey("session-1")), "refreshed while held")
+
+	mr.FastForward(time.Minute)
+	assert.False(t, mr.Exists(SessionKey("session-1")), "expires once no longer refreshed")
+}
+
+func TestBridge_ReleaseKeepsNewOwner(t *testing.T) {
+	mr, client := newTestRedis(t)
+	podA := NewBridge(client, "pod-a", time.Minute, createTestLogger())
+	podB := NewBridge(client, "pod-b", time.Minute, createTestLogger())
+
+	require.NoError(t, podA.Claim(SessionKey("session-1")))
+	require.NoError(t, podB.Claim(SessionKey("session-1")))
+	podA.Release(SessionKey("session-1"))
+	owner, err := mr.Get(SessionKey("session-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "pod-b", owner)
+
+	// A refresh notices the key was taken over
+	require.NoError(t, podA.Claim(AdminKey("admin-1", "session-1")))
+	require.NoError(t, podB.Claim(AdminKey("admin-1", "session-1")))
+	podA.refresh()
+	assert.Empty(t, podA.owned)
+
+	podB.Close()
+	assert.False(t, mr.Exists(SessionKey("session-1")), "Close releases owned keys")
+	assert.False(t, mr.Exists(AdminKey("admin-1", "session-1")))
+}
@@ -6,31 +6,38 @@ import "time"
 
 // HTTP Status Codes
 const (
+	StatusSwitchingProtocols = 101
 	StatusOK                 = 200
+	StatusCreated            = 201
+	StatusNotModified        = 304
+	StatusConflict           = 409
 	StatusTooManyRequests    = 429
 	StatusServiceUnavailable = 503
 )
 
 // Timeouts for various operations
 const (
-	DefaultContextTimeout   = 10 * time.Second  // Standard database operations
-	LongContextTimeout      = 30 * time.Second  // Complex queries and index creation
-	DefaultLLMStreamTimeout = 120 * time.Second // LLM streaming requests
-	LLMClientTimeout        = 60 * time.Second  // HTTP client timeout for LLM API calls
-	MongoIndexTimeout       = 30 * time.Second  // MongoDB index creation
-	ShortTimeout            = 2 * time.Second   // Quick operations like health checks
-	MessageAddTimeout       = 5 * time.Second   // Adding messages to sessions
-	SessionEndTimeout       = 5 * time.Second   // Ending sessions
-	GracefulShutdownTimeout = 30 * time.Second  // Graceful shutdown deadline
-	HealthCheckTimeout      = 2 * time.Second   // Health check operations
-	MetricsTimeout          = 30 * time.Second  // Metrics aggregation
-	VoiceProcessTimeout     = 60 * time.Second  // Voice message processing
+	DefaultContextTimeout   = 10 * time.Second       // Standard database operations
+	LongContextTimeout      = 30 * time.Second       // Complex queries and index creation
+	DefaultLLMStreamTimeout = 120 * time.Second      // LLM streaming requests
+	LLMClientTimeout        = 60 * time.Second       // HTTP client timeout for LLM API calls
+	MongoIndexTimeout       = 30 * time.Second       // MongoDB index creation
+	ShortTimeout            = 2 * time.Second        // Quick operations like health checks
+	MessageAddTimeout       = 5 * time.Second        // Adding messages to sessions
+	SessionEndTimeout       = 5 * time.Second        // Ending sessions
+	GracefulShutdownTimeout = 30 * time.Second       // Graceful shutdown deadline
+	HealthCheckTimeout      = 2 * time.Second        // Health check operations
+	RedisOperationTimeout   = 500 * time.Millisecond // Per-check timeout for the Redis rate limiter
+	MetricsTimeout          = 30 * time.Second       // Metrics aggregation
+	VoiceProcessTimeout     = 60 * time.Second       // Voice message processing
+	SchemaMigrationTimeout  = 10 * time.Minute       // Session document schema migrations
 )
 
 // Sizes and Limits
 const (
 	DefaultMaxMessageSize        = 1048576 // 1MB in bytes for WebSocket messages
 	EncryptionKeyLength          = 32      // AES-256 requires exactly 32 bytes
+	MinAnonymizationSaltLength   = 16      // Shortest secret keying the user ID hashes of anonymized mode
 	ShareTokenLength             = 32      // Hex chars for share token
 	DefaultSessionLimit          = 100     // Default number of sessions to return
 	MaxSessionLimit              = 1000    // Maximum sessions per query (performance cap)
@@ -41,7 +48,31 @@ const (
 	MaxUsersTracked              = 100000  // Maximum distinct users in rate limiter map
 	PublicEndpointRate           = 60      // Requests per minute for public endpoints (healthz, readyz, metrics)
 	MaxLLMErrorBodySize          = 1024    // Max bytes to read from LLM provider error responses
+	MaxErrorBodyBytes            = 512     // Max bytes of an external service error response included in errors
 	MaxConcurrentMessagesPerConn = 3       // Max concurrent RouteMessage goroutines per WebSocket connection
+	ReplayBufferSize             = 256     // Max outbound messages buffered per session for reconnect replay
+	DefaultSearchLimit           = 20      // Default number of sessions returned by message search
+	MaxSearchLimit               = 100     // Maximum sessions per search query
+	MaxSearchQueryLength         = 256     // Maximum search query length in bytes
+	MaxSessionNameLength         = 100     // Maximum length in bytes of a user-chosen session name
+	MaxSearchScanSessions        = 500     // Most recent sessions decrypted per search when encrypted without a hash index
+	MaxSearchSnippets            = 3       // Maximum matching snippets returned per session
+	SearchSnippetRadius          = 60      // Characters of context kept on each side of a match
+	SearchTermHashLength         = 16      // Hex characters kept of each searchable term hash
+	MaxSessionTags               = 20      // Maximum tags per session
+	MaxTagLength                 = 32      // Maximum length in bytes of a session tag
+	MaxPinnedMessages            = 20      // Maximum pinned messages per session
+	MaxSessionGrants             = 20      // Maximum users a session is shared with
+	MaxGrantUserIDLength         = 128     // Maximum length in bytes of the user ID a session is shared with
+	MaxTagCounts                 = 50      // Most used tags reported by the admin metrics endpoint
+	MaxLatencySamples            = 10000   // Most recent AI replies sampled for the latency percentiles of the admin metrics endpoint
+	DefaultIPConnectLimit        = 30      // Connection attempts per minute per client IP on /ws and /sse
+	DefaultTopTalkers            = 20      // Default number of IPs returned by the admin IP stats endpoint
+	MaxTopTalkers                = 500     // Maximum IPs per IP stats query
+	MaxBanReasonLength           = 256     // Maximum length in bytes of an IP ban reason
+	DefaultGuestRateLimit        = 10      // Default messages per minute per guest
+	MaxSnapshotsPerSession       = 50      // Maximum stored snapshots per session
+	MaxSnapshotLabelLength       = 100     // Maximum length in bytes of a snapshot label
 )
 
 // HTTP Server Timeouts (for standalone server mode)
@@ -57,22 +88,65 @@ const (
 	DefaultRateWindow       = 1 * time.Minute  // Rate limiting window
 	DefaultCleanupInterval  = 5 * time.Minute  // Cleanup goroutine interval
 	DefaultSessionTTL       = 15 * time.Minute // Session time-to-live after inactivity
+	DefaultIdleTimeout      = 30 * time.Minute // Active sessions without activity are ended
+	DefaultIdleWarning      = 1 * time.Minute  // session_expiring warning lead before an idle end
+	DefaultGuestTokenTTL    = 2 * time.Hour    // Lifetime of guest tokens issued by the WebSocket endpoint
+	RetentionPurgeInterval  = 1 * time.Hour    // Session retention purger interval
+	MetricsRollupInterval   = 15 * time.Minute // Metrics rollup aggregator interval
+	MetricsRollupLookback   = 48 * time.Hour   // Recent rollups recomputed on every run
 	InitialRetryDelay       = 100 * time.Millisecond
 	MaxRetryDelay           = 2 * time.Second
 	RetryMultiplier         = 2.0
 )
 
+// Metrics rollup granularities (bucket sizes of the metrics_rollup collection)
+const (
+	RollupHourly = "hour"
+	RollupDaily  = "day"
+)
+
+// Rate limiter backends
+const (
+	RateLimitBackendMemory = "memory"             // Per-process sliding window (default)
+	RateLimitBackendRedis  = "redis"              // Sliding window shared across replicas
+	RedisRateLimitPrefix   = "chatbox:ratelimit:" // Key prefix for Redis rate limit windows
+)
+
 // Role Names for authorization
 const (
-	RoleAdmin     = "admin"
-	RoleChatAdmin = "chat_admin"
+	RoleAdmin      = "admin"
+	RoleChatAdmin  = "chat_admin"
+	RoleSuperAdmin = "super_admin" // Admin access across all tenants
+	RoleGuest      = "guest"       // Anonymous user with a token issued by the WebSocket endpoint
+	RoleEmbed      = "embed"       // Chat widget user with a token minted for a partner origin
+	// RoleImpersonation is the only role of a token minted for an admin acting as a user
+	RoleImpersonation = "impersonation"
+	// RoleAPIKeyRead and RoleAPIKeyAdmin are the roles of callers authenticated by an API key
+	RoleAPIKeyRead  = "api_key_read"
+	RoleAPIKeyAdmin = "api_key_admin"
 )
 
 // Sender Types for messages
 const (
-	SenderUser  = "user"
-	SenderAI    = "ai"
-	SenderAdmin = "admin"
+	SenderUser   = "user"
+	SenderAI     = "ai"
+	SenderAdmin  = "admin"
+	SenderSystem = "system"
+)
+
+// System event types recorded in session transcripts.
+// System events use SenderSystem and are never sent to the LLM.
+const (
+	SystemEventAdminTakeover = "admin_takeover"
+	SystemEventAdminLeave    = "admin_leave"
+	SystemEventAdminHandback = "admin_handback"
+	SystemEventHandoff       = "handoff"   // session queued for a human agent
+	SystemEventRedaction     = "redaction" // message content removed by moderation
+	SystemEventReconnect     = "reconnect"
+	SystemEventIdleTimeout   = "idle_timeout"   // session ended after inactivity
+	SystemEventAfterHours    = "after_hours"    // help requested outside business hours
+	SystemEventCodeExecution = "code_execution" // output of code from an AI reply run in the sandbox
+	SystemEventForceEnded    = "force_ended"    // session ended by an admin with a reason code
 )
 
 // Default Configuration Values
@@ -80,17 +154,39 @@ const (
 	DefaultMongoURI   = "mongodb://localhost:27017"
 	DefaultDatabase   = "chat"
 	DefaultCollection = "sessions"
+	AuditCollection   = "audit_log" // Admin action audit trail (never purged by session retention)
+	PromptCollection  = "prompt_templates"
+	RollupCollection  = "metrics_rollup" // Hourly and daily session metrics (see storage/metrics_rollup.go)
 	DefaultModel      = "gpt-4"
 	DefaultPort       = 8080
 	DefaultLogLevel   = "info"
 	DefaultLogDir     = "logs"
 	DefaultPathPrefix = "/chatbox" // Default HTTP path prefix for all routes
+
+	DefaultSessionRestoreGraceDays = 7 // Days a soft-deleted session can be restored before it is hard-deleted
+
+	SnapshotCollection = "session_snapshots" // Session snapshots for debugging and restore points (see storage/snapshots.go)
+	RoleCollection     = "chat_roles"        // Role permissions overriding config (see internal/authz)
+	UsageCollection    = "llm_usage"         // LLM token usage and cost per call (see storage/usage.go)
+	CannedCollection   = "canned_responses"  // Admin canned responses and flows (see storage/canned.go)
+	APIKeyCollection   = "chat_api_keys"     // Hashed API keys for server-to-server admin calls (see internal/apikey)
+	SettingsCollection = "chat_settings"     // Service settings toggled by admins at runtime (see storage/settings.go)
+	BlockCollection    = "user_blocks"       // Users blocked from connecting (see storage/blocks.go)
+	ReportCollection   = "abuse_reports"     // Messages reported as abusive by users (see storage/reports.go)
+	KeywordCollection  = "keyword_watchlist" // Keywords admins are alerted about (see internal/watchlist)
+	JobLockCollection  = "job_locks"         // Which replica runs each scheduled job (see storage/jobs.go)
+	JobRunCollection   = "job_runs"          // Scheduled job run history (see storage/jobs.go)
+	MemoryCollection   = "user_memory"       // Facts users stated about themselves (see internal/memory)
+
+	ExperimentCollection = "chat_experiments" // Admin-managed A/B experiments (see internal/experiment)
 )
 
 // HTTP Headers
 const (
 	HeaderAuthorization = "Authorization"
 	HeaderRetryAfter    = "Retry-After"
+	HeaderAPIKey        = "X-API-Key"    // API key of server-to-server admin calls
+	HeaderRequestID     = "X-Request-ID" // ID correlating a request's logs, assigned when the caller sends none
 	BearerPrefix        = "Bearer "
 	BearerPrefixLength  = 7
 )
@@ -104,7 +200,9 @@ const (
 	ErrMsgRateLimitExceeded     = "Too many requests. Please try again later."
 	ErrMsgInvalidTimeFormat     = "Invalid time format. Use RFC3339 format."
 	ErrMsgSessionIDRequired     = "Session ID is required"
+	ErrMsgUserIDRequired        = "User ID is required"
 	ErrMsgSharedSessionNotFound = "Shared session not found"
+	ErrMsgAnonymizedMode        = "This feature is disabled in anonymized analytics mode"
 )
 
 // MongoDB Field Names (BSON tags)
@@ -119,20 +217,158 @@ const (
 	MongoFieldTotalTokens   = "totalTokens"
 	MongoFieldLastActivity  = "lastActivity"
 	MongoFieldShareToken    = "shareToken"
+	MongoFieldShareExpires  = "shareExpTs"
+	MongoFieldMessageCount  = "msgCount"
+	MongoFieldDeletedAt     = "delTs"
+	MongoFieldTenantID      = "tid"
+	MongoFieldAction        = "act"
+	MongoFieldActorID       = "actor"
+	MongoFieldSessionID     = "sid"
+	MongoFieldSearchTerms   = "srch"
+	MongoFieldMsgContent    = "msgs.content"
+	MongoFieldMsgTimestamp  = "msgs.ts"
+	MongoFieldMsgTiming     = "msgs.timing"
+	MongoFieldInterventions = "interventions"
+	MongoFieldPromptID      = "promptId"
+	MongoFieldLLMParams     = "llmParams"
+	MongoFieldThread        = "providerThread"
+	MongoFieldTags          = "tags"
+	MongoFieldPins          = "pins"
+	MongoFieldGrants        = "grants"
+	MongoFieldGrantUserID   = "grants.uid"
+	MongoFieldGranularity   = "gran"
+	MongoFieldComputedAt    = "computedAt"
+	MongoFieldCost          = "cost"
+	MongoFieldModel         = "model"
+	MongoFieldSchemaVersion = "schemaVersion"
+	MongoFieldMergedFrom    = "mergedFrom"
+	MongoFieldMergedInto    = "mergedInto"
+	MongoFieldSentiment     = "sentiment"
+	MongoFieldEndReason     = "endReason"
+	MongoFieldEndedBy       = "endedBy"
+	MongoFieldTranscriptTs  = "transcriptTs"
+	MongoFieldExperiments   = "experiments"
+	MongoFieldExperimentID  = "experiments.id"
+
+	MongoFieldClientAppVersion = "client.appVer"
+	MongoFieldClientPlatform   = "client.platform"
+
+	MongoFieldBlobPath = "path" // Stored copy of a deduplicated upload (file_stats)
+	MongoFieldBlobRefs = "refs" // References to a deduplicated upload (file_stats)
+	MongoFieldModified = "mt"
 )
 
 // MongoDB Index Names
 const (
-	IndexUserID        = "idx_user_id"
-	IndexStartTime     = "idx_start_time"
-	IndexAdminAssisted = "idx_admin_assisted"
-	IndexUserStartTime = "idx_user_start_time"
-	IndexShareToken    = "idx_share_token"
+	IndexUserID           = "idx_user_id"
+	IndexStartTime        = "idx_start_time"
+	IndexAdminAssisted    = "idx_admin_assisted"
+	IndexUserStartTime    = "idx_user_start_time"
+	IndexShareToken       = "idx_share_token"
+	IndexDeletedAt        = "idx_deleted_at"
+	IndexTenantStart      = "idx_tenant_start_time"
+	IndexAuditTime        = "idx_audit_ts"
+	IndexAuditActor       = "idx_audit_actor_ts"
+	IndexAuditSession     = "idx_audit_session_ts"
+	IndexMessageText      = "idx_msgs_text"
+	IndexSearchTerms      = "idx_search_terms"
+	IndexPromptTenant     = "idx_prompt_tenant"
+	IndexTags             = "idx_tags"
+	IndexGrants           = "idx_grants_uid"
+	IndexRollupBucket     = "idx_rollup_gran_tenant_ts"
+	IndexSnapshots        = "idx_snapshot_session_ts"
+	IndexUsageTime        = "idx_usage_ts"
+	IndexUsageTenant      = "idx_usage_tenant_ts"
+	IndexCannedTenant     = "idx_canned_tenant_uses"
+	IndexBlobPath         = "idx_blob_path"
+	IndexBlobRefs         = "idx_blob_refs_mt"
+	IndexBlockTenant      = "idx_block_tenant_ts"
+	IndexBlockExpiry      = "idx_block_expiry"
+	IndexReportTenant     = "idx_report_tenant_ts"
+	IndexReportSession    = "idx_report_session_ts"
+	IndexKeywordTenant    = "idx_keyword_tenant_ts"
+	IndexJobRuns          = "idx_job_runs_job_ts"
+	IndexJobRunExpiry     = "idx_job_runs_expiry"
+	IndexMemoryUser       = "idx_memory_tenant_user_ts"
+	IndexExperiments      = "idx_experiments_ts"
+	IndexExperimentTenant = "idx_experiment_tenant"
+)
+
+// Admin audit log actions
+const (
+	AuditActionListSessions = "list_sessions"
+	AuditActionViewMetrics  = "view_metrics"
+	AuditActionTakeover     = "takeover"
+	AuditActionHandback     = "handback"
+	AuditActionSendMessage  = "send_message"
+	AuditActionExport       = "export"
+	AuditActionRestore      = "restore"
+	AuditActionDrain        = "drain"
+	AuditActionWatch        = "watch"
+	AuditActionViewAudit    = "view_audit"
+	AuditActionSearch       = "search"
+	AuditActionListPrompts  = "list_prompts"
+	AuditActionCreatePrompt = "create_prompt"
+	AuditActionUpdatePrompt = "update_prompt"
+	AuditActionDeletePrompt = "delete_prompt"
+	AuditActionExportUser   = "export_user_data"
+	AuditActionEraseUser    = "erase_user_data"
+	AuditActionTagSession   = "tag_session"
+	AuditActionViewIPStats  = "view_ip_stats"
+	AuditActionBanIP        = "ban_ip"
+	AuditActionUnbanIP      = "unban_ip"
+
+	AuditActionViewConnections  = "view_connections"
+	AuditActionCloseConnection  = "close_connection"
+	AuditActionViewCosts        = "view_costs"
+	AuditActionImpersonate      = "impersonate"
+	AuditActionViewMigrations   = "view_migrations"
+	AuditActionRunMigrations    = "run_migrations"
+	AuditActionListCanned       = "list_canned"
+	AuditActionCreateCanned     = "create_canned"
+	AuditActionUpdateCanned     = "update_canned"
+	AuditActionDeleteCanned     = "delete_canned"
+	AuditActionSendCanned       = "send_canned"
+	AuditActionStreamMetrics    = "stream_metrics"
+	AuditActionSetReadOnly      = "set_read_only"
+	AuditActionViewHelpQueue    = "view_help_queue"
+	AuditActionStartBackup      = "start_backup"
+	AuditActionViewBackups      = "view_backups"
+	AuditActionPinMessage       = "pin_message"
+	AuditActionExportFinetune   = "export_finetune"
+	AuditActionListBlocks       = "list_user_blocks"
+	AuditActionBlockUser        = "block_user"
+	AuditActionUnblockUser      = "unblock_user"
+	AuditActionListReports      = "list_reports"
+	AuditActionListKeywords     = "list_keywords"
+	AuditActionCreateKeyword    = "create_keyword"
+	AuditActionUpdateKeyword    = "update_keyword"
+	AuditActionDeleteKeyword    = "delete_keyword"
+	AuditActionViewJobs         = "view_jobs"
+	AuditActionViewAlerts       = "view_alerts"
+	AuditActionForceEnd         = "force_end_session"
+	AuditActionListExperiments  = "list_experiments"
+	AuditActionCreateExperiment = "create_experiment"
+	AuditActionDeleteExperiment = "delete_experiment"
+	AuditActionReloadConfig     = "reload_config"
+
+	// Actions an admin took as a user with an impersonation token; the actor is
+	// the admin and the user ID the impersonated user
+	AuditActionImpersonatedRequest = "impersonated_request"
+	AuditActionImpersonatedMessage = "impersonated_message"
 )
 
 // Token Estimation
 const (
 	CharsPerToken = 4 // Rough estimate: 4 characters per token for LLM usage
+
+	// MetadataKeyTokens is the message metadata key holding the estimated token count of an LLM reply
+	MetadataKeyTokens = "tokens"
+	// MetadataKeyTruncated marks an LLM reply cut short because the client cancelled the generation
+	MetadataKeyTruncated = "truncated"
+	// MetadataKeyConsolidated marks the final ai_response of a reply streamed as
+	// ai_delta frames, whose content is the whole reply
+	MetadataKeyConsolidated = "consolidated"
 )
 
 // Weak Secrets for validation (security check)
@@ -207,9 +443,518 @@ var ValidSortOrders = map[string]bool{
 // Default Anthropic max tokens
 const DefaultAnthropicMaxTokens = 4096
 
+// MaxModelTemperature is the highest sampling temperature accepted in the model catalog
+const MaxModelTemperature = 2.0
+
+// DefaultMaxTokensLimit is the highest max_tokens a client may request for a session
+// when the model catalog sets no max_tokens_limit for the model
+const DefaultMaxTokensLimit = 4096
+
 // LLM retry configuration
 const (
 	LLMInitialRetryDelay   = 1 * time.Second  // Base delay for LLM retry exponential backoff
 	LLMMaxRetryDelay       = 30 * time.Second // Cap for exponential backoff in LLM retries
 	LLMStreamHeaderTimeout = 30 * time.Second // Max wait for first response byte on streaming requests
 )
+
+// Webhook delivery configuration
+const (
+	DefaultWebhookTimeout    = 10 * time.Second // HTTP timeout for a single webhook delivery attempt
+	WebhookInitialRetryDelay = 1 * time.Second  // Base delay for webhook retry exponential backoff
+	WebhookMaxRetryDelay     = 30 * time.Second // Cap for exponential backoff in webhook retries
+	DefaultWebhookMaxRetries = 3                // Delivery attempts per endpoint before an event is dropped
+	WebhookQueueSize         = 1000             // Max events waiting for delivery; newer events are dropped when full
+)
+
+// Drain mode configuration
+const (
+	DefaultDrainCountdown = 30 * time.Second // Time clients are given to reconnect elsewhere before shutdown
+	MaxDrainCountdown     = 10 * time.Minute // Upper bound for a requested drain countdown
+)
+
+// Client IP bans
+const (
+	DefaultIPBanDuration = 1 * time.Hour      // Ban duration when the admin gives none
+	MaxIPBanDuration     = 7 * 24 * time.Hour // Upper bound for a requested ban duration
+)
+
+// Admin takeover waiting list
+const (
+	TakeoverWaitTimeout = 10 * time.Minute // How long a denied admin stays in a session's waiting list
+	TakeoverClaimWindow = 30 * time.Second // How long a released session is reserved for the next waiting admin
+)
+
+// Webhook HTTP headers
+const (
+	HeaderWebhookEvent     = "X-Chatbox-Event"     // Event type, e.g. help_requested
+	HeaderWebhookDelivery  = "X-Chatbox-Delivery"  // Unique event ID, stable across retries
+	HeaderWebhookTimestamp = "X-Chatbox-Timestamp" // Unix seconds included in the signature
+	HeaderWebhookSignature = "X-Chatbox-Signature" // "sha256=" + hex HMAC of "<timestamp>.<body>"
+)
+
+// Message moderation configuration
+const (
+	DefaultModerationTimeout        = 5 * time.Second                         // Max time a message may spend in the moderation pipeline
+	DefaultOpenAIModerationEndpoint = "https://api.openai.com/v1/moderations" // OpenAI moderation API endpoint
+	DefaultOpenAIModerationModel    = "omni-moderation-latest"                // OpenAI moderation model
+	ModerationRedactionMask         = "[redacted]"                            // Replaces content matched by a redacting filter
+	ModerationRemovedContent        = "[removed by moderation]"               // Replaces blocked content, or redacted content that cannot be localized
+
+	// MetadataKeyModeration is the message metadata key holding the moderation action taken (flag, redact, block)
+	MetadataKeyModeration = "moderation"
+	// MetadataKeyModerationCategories is the message metadata key holding comma-separated moderation categories
+	MetadataKeyModerationCategories = "moderation_categories"
+)
+
+// OpenTelemetry tracing configuration
+const (
+	TracerName                = "github.com/real-rm/chatbox" // Instrumentation scope of chatbox spans
+	DefaultTracingServiceName = "chatbox"                    // service.name resource attribute
+	DefaultTracingSampleRatio = 1.0                          // Fraction of new traces sampled; incoming sampled traces are always kept
+	TracingShutdownTimeout    = 5 * time.Second              // Max time spent flushing buffered spans on shutdown
+)
+
+// System prompt templates
+const (
+	MaxPromptNameLength    = 100              // Maximum template name length in bytes
+	MaxPromptContentLength = 8192             // Maximum template content length in bytes
+	PromptCacheTTL         = 30 * time.Second // How long a stored template is cached before it is looked up again
+
+	// MetadataKeyPromptTemplate is the metadata key on the message that creates a session selecting its prompt template
+	MetadataKeyPromptTemplate = "prompt_template"
+)
+
+// A/B experiments
+const (
+	MaxExperimentNameLength = 100                 // Maximum experiment name length in bytes
+	MaxExperimentVariants   = 10                  // Maximum variants of one experiment
+	ExperimentCacheTTL      = 30 * time.Second    // How long a tenant's stored experiments are cached before they are listed again
+	DefaultExperimentWindow = 30 * 24 * time.Hour // Period of GET /admin/experiments/:experimentID/metrics without start_time
+)
+
+// Retrieval-augmented generation
+const (
+	DefaultRetrievalTimeout  = 5 * time.Second // Max time the router waits for retrieved documents
+	DefaultRetrievalTopK     = 3               // Documents requested per user message
+	MaxRetrievalSnippetBytes = 200             // Length of each document snippet kept in message metadata
+
+	// MetadataKeyRetrieval is the message metadata key holding the JSON list of documents an AI reply was grounded in
+	MetadataKeyRetrieval = "retrieval"
+)
+
+// Chat widget embedding
+const (
+	DefaultEmbedTokenTTL      = 15 * time.Minute // Lifetime of embed tokens unless an origin sets token_ttl
+	MaxEmbedTokenTTL          = time.Hour        // Longest token_ttl an origin may configure
+	DefaultEmbedTokensPerHour = 1000             // Embed tokens an origin may mint per hour unless it sets tokens_per_hour
+	MaxEmbedUserIDLength      = 128              // Maximum partner user ID length in bytes
+)
+
+// Message feedback
+const (
+	FeedbackUp               = "up"   // Thumbs up on an AI message
+	FeedbackDown             = "down" // Thumbs down on an AI message
+	MaxFeedbackCommentLength = 1000   // Maximum free-text feedback length in bytes
+)
+
+// Session access grants
+const (
+	GrantAccessRead  = "read"  // The user may read the session's messages
+	GrantAccessWrite = "write" // The user may also rename, tag and pin the session and rate its messages
+)
+
+// Session auto-titles
+const (
+	AutoTitleAfterExchanges = 2                // AI replies after which a session is titled by the LLM
+	AutoTitleTimeout        = 15 * time.Second // Max time spent generating a title
+	AutoTitleMaxLength      = 50               // Maximum title length in bytes, as for names taken from the first message
+	AutoTitleMaxContext     = 2000             // Maximum characters of each message sent to the LLM for titling
+)
+
+// LLM fallback chains (see router/fallback.go)
+const (
+	FallbackRetryBaseDelay = 500 * time.Millisecond // Base delay before retrying a model after a transient error
+	FallbackRetryMaxDelay  = 5 * time.Second        // Cap for the exponential retry backoff, before jitter
+	MaxFallbackRetries     = 5                      // Highest retries setting accepted per model
+
+	// MetadataKeyModel is the message metadata key holding the model that generated an LLM reply
+	MetadataKeyModel = "model"
+	// MetadataKeyProvider is the message metadata key holding the provider that served an LLM reply
+	MetadataKeyProvider = "provider"
+	// MetadataKeyFallbackFrom holds the session's model when a fallback model served the reply instead
+	MetadataKeyFallbackFrom = "fallback_from"
+)
+
+// Message integrity (see storage/integrity.go)
+const (
+	MessageSealAttempts = 5 // Attempts at appending an encrypted message while other messages are appended concurrently
+
+	// MetadataKeyIntegrity is set on messages read back from storage that fail the integrity check
+	MetadataKeyIntegrity = "integrity"
+	// MessageIntegrityTampered marks a message whose ciphertext was modified or moved from another message or session
+	MessageIntegrityTampered = "tampered"
+)
+
+// LLM cost tracking (see internal/cost and storage/usage.go)
+const (
+	DefaultCostCurrency     = "USD"               // Currency of [chatbox.costs.prices] unless chatbox.costs.currency is set
+	DefaultCostReportWindow = 30 * 24 * time.Hour // Period of GET /admin/costs without start_time
+
+	// Groupings of GET /admin/costs (group_by query parameter)
+	CostGroupByTenant = "tenant"
+	CostGroupByModel  = "model"
+	CostGroupByDay    = "day" // UTC days
+)
+
+// Multi-device sessions (see router/multidevice.go)
+const (
+	// MetadataKeyConnectionID is the metadata key identifying the device a relayed typing indicator came from
+	MetadataKeyConnectionID = "connection_id"
+)
+
+// Session lifecycle event bus (see internal/events)
+const (
+	EventQueueSize   = 1000            // Max events waiting for the sinks; newer events are dropped when full
+	EventSinkTimeout = 5 * time.Second // Time a sink has to accept one event
+
+	// Sinks selectable with chatbox.events.sinks
+	EventSinkLog     = "log"
+	EventSinkWebhook = "webhook"
+	EventSinkNATS    = "nats"
+	EventSinkKafka   = "kafka"
+
+	DefaultEventSubject = "chatbox.events" // NATS subject prefix and Kafka topic unless configured
+	DefaultNATSPort     = "4222"
+	NATSClientName      = "chatbox" // Client name sent in NATS CONNECT
+)
+
+// Admin impersonation (POST /admin/impersonate/:userID)
+const (
+	DefaultImpersonationTTL = 15 * time.Minute // Lifetime of an impersonation token unless the request sets ttl
+	MaxImpersonationTTL     = 1 * time.Hour    // Longest impersonation token an admin can request
+
+	// MetadataKeyImpersonatedBy marks user messages sent by an admin impersonating the user
+	MetadataKeyImpersonatedBy = "impersonated_by"
+)
+
+// Chunked messages (see websocket/chunked.go)
+const (
+	DefaultMaxChunkedMessageSize = 262144          // 256KB: max assembled size of a message sent in chunks
+	MaxPendingChunkedMessages    = 2               // Max chunked messages a connection can be assembling at once
+	ChunkedMessageTimeout        = 2 * time.Minute // Time to commit a chunked message after message_begin
+	MaxUploadIDLength            = 64              // Maximum upload_id length in bytes
+)
+
+// Canned responses and flows (see storage/canned.go)
+const (
+	MaxCannedTitleLength = 100   // Maximum canned response title length in bytes
+	MaxCannedSteps       = 10    // Maximum messages of a multi-step flow
+	MaxCannedStepLength  = 10000 // Maximum length in bytes of one step, as for any admin message
+)
+
+// Live admin metrics stream (see websocket/metrics_stream.go)
+const (
+	LiveMetricsInterval = 5 * time.Second // Time between snapshots pushed to a dashboard
+	LiveMetricsWindow   = time.Minute     // Window of the per-minute rates and the LLM latency p95
+)
+
+// Message write batching (see storage/batching.go)
+const (
+	DefaultMessageBatchInterval = 500 * time.Millisecond // Time between writes of buffered messages
+	DefaultMessageBatchSize     = 20                     // Buffered messages of a session that trigger a write
+	MaxMessageBatchInterval     = 10 * time.Second       // Longest time a message may stay buffered
+	MaxMessageBatchSize         = 500                    // Most messages written in one update
+)
+
+// Scheduled maintenance (see internal/maintenance)
+const (
+	DefaultMaintenanceNotice    = 30 * time.Minute // Time before a window that banners start
+	MaintenanceAnnounceInterval = time.Minute      // Time between maintenance banners sent to connected clients
+
+	// DefaultMaintenanceMessage is shown to users when a window has no message
+	DefaultMaintenanceMessage = "Chat is undergoing scheduled maintenance. Please come back soon."
+)
+
+// Client SDK metadata (see websocket/client.go)
+const (
+	MaxClientMetadataLength = 256 // Characters kept of each client metadata value
+)
+
+// Admin rate limit policies (see [chatbox.rate_limits] and ratelimit/policy.go)
+const (
+	RatePolicyList   = "list"   // Session listing, search, metrics and audit queries
+	RatePolicyExport = "export" // Session and user data exports
+	RatePolicyBulk   = "bulk"   // Migrations, user data erasure and drains
+)
+
+// Upload deduplication (see upload/dedup.go)
+const (
+	BlobIDPrefix             = "sha256:"      // file_stats ID prefix of deduplicated uploads
+	DefaultBlobGCInterval    = 1 * time.Hour  // Time between collections of unreferenced uploads
+	DefaultBlobGCGracePeriod = 24 * time.Hour // Time an upload stays unreferenced before it is deleted
+)
+
+// Metrics remote write (see internal/remotewrite)
+const (
+	DefaultMetricsPushInterval = 1 * time.Minute  // Time between pushes of session metrics
+	DefaultMetricsPushWindow   = 24 * time.Hour   // Trailing period the pushed session metrics cover
+	DefaultMetricsPushTimeout  = 10 * time.Second // HTTP timeout per push
+	DefaultMetricsPushJob      = "chatbox"        // job label of pushed series
+)
+
+// LLM provider threads (see router/thread.go)
+const (
+	MaxThreadMigrationMessages = 50 // Messages of the conversation so far sent to a model that takes over a session
+)
+
+// Read-only mode (see internal/readonly)
+const (
+	ReadOnlySettingID        = "readonly"       // chat_settings document of the read-only mode
+	ReadOnlySyncInterval     = 30 * time.Second // Time between reloads of the stored read-only mode
+	MaxReadOnlyMessageLength = 500              // Maximum length in bytes of the message shown while read-only
+	DefaultReadOnlyMessage   = "Chat is temporarily read-only. Your conversation history is still available."
+)
+
+// OpenAPI document (see openapi.go)
+const (
+	OpenAPITitle   = "Chatbox API" // info.title of the served spec and the Swagger UI page title
+	OpenAPIVersion = "1.0.0"       // info.version of the served spec, bumped with breaking REST changes
+)
+
+// Usage alerts (see internal/alerts)
+const (
+	DefaultAlertInterval = time.Minute      // Time between evaluations of the alert rules
+	MinAlertInterval     = 10 * time.Second // Shortest configurable evaluation interval
+	AlertTokensWindow    = 24 * time.Hour   // Period the tokens_per_day metric covers
+)
+
+// Embeddable chat widget (see internal/widget)
+const (
+	WidgetLoaderMaxAge = 5 * time.Minute      // Cache lifetime of widget/loader.js, which points to the current widget version
+	WidgetAssetMaxAge  = 365 * 24 * time.Hour // Cache lifetime of a versioned widget file, whose content never changes
+)
+
+// Help queue (GET /admin/help-queue, see internal/priority)
+const (
+	HelpQueueRecentMessages = 5 // User messages per session the sentiment classifier reads
+)
+
+// Sentiment analysis (see internal/sentiment)
+const (
+	DefaultSentimentSmoothing = 0.3             // Weight of the newest message in a session's rolling sentiment
+	DefaultSentimentThreshold = -0.5            // Rolling sentiment below which the sentiment_dropped webhook is sent
+	DefaultSentimentTimeout   = 5 * time.Second // Max time to score one message
+	SentimentQueueSize        = 1000            // Max messages waiting to be scored; newer ones are dropped when full
+)
+
+// Session backups (see internal/backup)
+const (
+	BackupTimeout       = 2 * time.Hour    // Max time of one backup job, including the upload
+	MaxBackupJobs       = 20               // Finished backup jobs kept for GET /admin/backups
+	MaxRestoreErrors    = 20               // Session errors listed in a restore report
+	BackupUploadEntry   = "backups"        // goupload entry of the s3 backup target
+	DefaultBackupDir    = "./backups"      // Directory of the local backup target
+	BackupArchivePrefix = "chat-sessions-" // Archive names: prefix, UTC start time, BackupArchiveSuffix
+	BackupArchiveSuffix = ".bak"           // Archive file extension
+)
+
+// LLM provider send queues (see router/send_queue.go)
+const (
+	DefaultProviderQueueDepth   = 100             // Requests queued per rate limited provider before new ones fail
+	ProviderQueueInitialBackoff = 2 * time.Second // Wait after a provider's 429 before it is tried again
+	ProviderQueueMaxBackoff     = time.Minute     // Cap for the wait, doubled after each consecutive 429
+	ProviderQueueSendEstimate   = time.Second     // Time each request ahead in a queue is estimated to take, for ETAs
+)
+
+// Business hours (see internal/businesshours)
+const (
+	DefaultBusinessHoursTimezone = "UTC"         // Time zone of business hours without a timezone setting
+	AfterHoursTag                = "after_hours" // Tag of sessions that asked for help outside business hours
+
+	// DefaultAfterHoursMessage starts the automatic reply to help requests outside business hours
+	DefaultAfterHoursMessage = "Our support team is currently offline."
+)
+
+// Horizontal scaling (see internal/cluster)
+const (
+	ClusterKeyPrefix      = "chatbox:cluster:" // Key and channel prefix of the cross-replica bridge
+	ClusterOwnershipTTL   = 30 * time.Second   // Expiry of a replica's connection ownership, refreshed every third of it
+	ClusterRequestTimeout = 2 * time.Second    // Wait for the reply of the replica holding a connection
+)
+
+// Streaming voice transcription (see internal/transcribe)
+const (
+	DefaultTranscriptionTimeout = 10 * time.Second // HTTP timeout per audio chunk sent to the speech-to-text service
+	MaxVoiceStreamBytes         = 10 << 20         // Audio a streamed voice message may carry (10 MiB)
+	MaxVoiceStreamsPerSession   = 2                // Voice messages a session may stream at once
+	VoiceStreamIdleTimeout      = 2 * time.Minute  // A voice stream without chunks for this long is dropped
+	DefaultVoiceStreamFormat    = "audio/webm"     // Audio format of streams that do not name one
+
+	// MetadataKeyTranscriptFinal marks a transcript message as the final transcript ("true") or an interim one
+	MetadataKeyTranscriptFinal = "final"
+	// MetadataKeyTranscribed marks a voice message whose content was transcribed from a streamed recording
+	MetadataKeyTranscribed = "transcribed"
+)
+
+// Request IDs and access logs (see internal/requestid)
+const (
+	MaxRequestIDLength          = 128              // Longer X-Request-ID headers are replaced by a new ID
+	AccessLogFrameBatchSize     = 100              // WebSocket frames summarized by one access log line
+	AccessLogFrameBatchInterval = 30 * time.Second // A batch this old is logged with the next frame, or when the connection closes
+)
+
+// Per-session LLM timeouts (see router/llm_timeout.go)
+const (
+	DefaultMinLLMTimeout = 10 * time.Second // Shortest LLM reply timeout a session or message may ask for
+	LLMKeepaliveInterval = 15 * time.Second // Silence after which a long-running reply sends a keepalive
+
+	// MetadataKeyKeepalive marks a loading message sent to keep the connection of a long-running reply busy
+	MetadataKeyKeepalive = "keepalive"
+	// MetadataKeyTimeoutSeconds is the timeout of a long-running reply, on its loading message
+	MetadataKeyTimeoutSeconds = "timeout_seconds"
+	// MetadataKeyElapsedSeconds is how long a long-running reply has been running, on its keepalives
+	MetadataKeyElapsedSeconds = "elapsed_seconds"
+)
+
+// Session change stream (see storage/change_stream.go)
+const (
+	ChangeStreamRetryDelay    = 1 * time.Second // Wait before reopening a failed change stream, doubled per failure
+	ChangeStreamMaxRetryDelay = 1 * time.Minute // Longest wait between attempts to reopen the change stream
+
+	// MetadataKeyExternal marks messages and session updates that came from another instance or tooling
+	MetadataKeyExternal = "external"
+	// MetadataKeySessionName is the new name of a session on a session_update message
+	MetadataKeySessionName = "name"
+	// MetadataKeyEnded marks a session_update for a session ended elsewhere
+	MetadataKeyEnded = "ended"
+	// MetadataKeyDeleted marks a session_update for a session deleted elsewhere
+	MetadataKeyDeleted = "deleted"
+)
+
+// Fine-tuning dataset export (see export/finetune.go)
+const (
+	MaxFinetuneSessions = 1000 // Sessions one fine-tuning export may include
+)
+
+// User blocks and abuse reports (see storage/blocks.go and storage/reports.go)
+const (
+	MaxUserBlockDuration   = 365 * 24 * time.Hour // Upper bound for a requested block duration; blocks without one last until lifted
+	MaxReportReasonLength  = 1000                 // Maximum length in bytes of the explanation given with a report
+	DefaultReportListLimit = 50                   // Reports returned by the admin report list when no limit is given
+	MaxReportListLimit     = 500                  // Maximum reports per admin report list query
+
+	// ErrMsgUserBlocked is the message of connections refused for a blocked user
+	ErrMsgUserBlocked = "This account is blocked from starting conversations"
+)
+
+// Code execution sandbox (see router/sandbox.go)
+const (
+	DefaultSandboxTimeout  = 30 * time.Second // Max time the router waits for one snippet to run
+	MaxSandboxRunsPerReply = 3                // Code blocks of one AI reply sent to the sandbox
+	MaxSandboxOutputBytes  = 4096             // Length of stdout and stderr each kept in the output message
+
+	SandboxAllTenants    = "*"       // Tenant list entry enabling the sandbox for every tenant
+	SandboxDefaultTenant = "default" // Tenant list entry naming the default tenant (sessions without a tenant)
+
+	// MetadataKeyLanguage is the language of the code whose output a code_execution event holds
+	MetadataKeyLanguage = "language"
+	// MetadataKeyExitCode is the exit code of the code whose output a code_execution event holds
+	MetadataKeyExitCode = "exit_code"
+)
+
+// DefaultSandboxLanguages are the code fence languages run when the sandbox
+// configuration names none
+var DefaultSandboxLanguages = []string{"python", "javascript", "bash"}
+
+// Keyword alerts (see internal/watchlist and router/keywords.go)
+const (
+	MaxKeywordTermLength   = 200              // Maximum length in bytes of a watchlist term or pattern
+	MaxKeywordsPerTenant   = 100              // Watchlist keywords one tenant may define
+	KeywordCacheTTL        = 30 * time.Second // How long a tenant's watchlist is cached before it is looked up again
+	KeywordAlertTag        = "keyword_alert"  // Tag of sessions matching a keyword without a tag of its own
+	MaxKeywordAlertExcerpt = 200              // Length in bytes of the message excerpt in a keyword alert
+)
+
+// User memory (see internal/memory and router/memory.go)
+const (
+	MaxMemoryFacts      = 50  // Facts remembered per user; the oldest are forgotten first
+	MaxMemoryFactLength = 200 // Maximum length in bytes of a remembered fact
+)
+
+// Reason codes of sessions an admin force-ended (see router/force_end.go)
+const (
+	EndReasonAbuse     = "abuse"     // the user abused the service
+	EndReasonStale     = "stale"     // the session was left open and no longer needed
+	EndReasonDuplicate = "duplicate" // the user has another session for the same matter
+
+	// MetadataKeyEndReason is the reason code on the session_update of a force-ended session
+	MetadataKeyEndReason = "end_reason"
+)
+
+// EndReasons lists the reason codes an admin can force-end a session with
+var EndReasons = []string{EndReasonAbuse, EndReasonStale, EndReasonDuplicate}
+
+// Reconnect backoff guidance (see websocket/reconnect.go)
+const (
+	DefaultReconnectBaseDelay   = time.Second      // First suggested reconnect delay
+	DefaultReconnectMaxDelay    = 30 * time.Second // Largest suggested reconnect delay
+	DefaultReconnectJitter      = 0.5              // Suggested randomization, as a fraction of the delay
+	DefaultReconnectMaxAttempts = 10               // Suggested reconnect attempts before giving up
+	DefaultReconnectStormLimit  = 500              // Connection attempts per ReconnectStormWindow that start a storm (0 never does)
+	ReconnectStormWindow        = 10 * time.Second // Window connection attempts are counted in
+	ReconnectStormCooldown      = time.Minute      // Time after the last busy window before delays go back to normal
+	ReconnectStormMultiplier    = 8                // Factor delays are raised by during a storm
+)
+
+// Session share links (see handleShareSession)
+const (
+	DefaultShareLinkTTL = 7 * 24 * time.Hour  // Lifetime of share links created without expires_in_hours
+	MaxShareLinkTTL     = 90 * 24 * time.Hour // Longest lifetime a share link may be given
+)
+
+// Transcript emails (see internal/email)
+const (
+	DefaultSMTPPort         = 587                            // SMTP submission port; STARTTLS is used when the server offers it
+	DefaultEmailSubject     = "Your conversation transcript" // Subject of transcript emails
+	DefaultEmailMaxRetries  = 5                              // Send attempts before a transcript email is dropped
+	EmailInitialRetryDelay  = 5 * time.Second                // Base delay for transcript email retry exponential backoff
+	EmailMaxRetryDelay      = 5 * time.Minute                // Cap for exponential backoff in transcript email retries
+	EmailQueueSize          = 500                            // Max transcript emails waiting to be sent; newer ones are refused when full
+	EmailRecipientLimit     = 5                              // Max transcript emails to one address per EmailRecipientWindow
+	EmailRecipientWindow    = time.Hour                      // Window of EmailRecipientLimit
+	EmailSendTimeout        = 30 * time.Second               // Max time for one SMTP send attempt
+	MaxEmailAttachmentBytes = 10 * 1024 * 1024               // Total size of the files attached to one transcript email
+	MaxEmailTemplateBytes   = 256 * 1024                     // Largest transcript email template file
+)
+
+// Prompt injection detection (see internal/injection)
+const (
+	DefaultInjectionTimeout   = 3 * time.Second // Max time content may spend in the injection detectors
+	DefaultInjectionThreshold = 0.8             // Classifier score (0-1) from which content counts as an injection
+
+	// MetadataKeyInjection is the message metadata key holding the injection action taken (warn, strip)
+	MetadataKeyInjection = "injection"
+	// MetadataKeyInjectionRules is the message metadata key holding the comma-separated rules that detected an injection
+	MetadataKeyInjectionRules = "injection_rules"
+)
+
+// WebSocket connection tickets (see internal/wsticket)
+const (
+	DefaultWSTicketTTL = 30 * time.Second  // Lifetime of one-time tickets issued by POST /ws/ticket
+	MaxWSTicketTTL     = 5 * time.Minute   // Longest configurable ticket lifetime
+	WSTicketBytes      = 32                // Random bytes in a ticket
+	WSTicketKeyPrefix  = "chatbox:ticket:" // Key prefix of tickets in Redis
+)
+
+// Scheduled background jobs (see internal/jobs)
+const (
+	JobRetentionPurge = "retention_purge" // Session retention policy (see storage/retention.go)
+	JobMetricsRollup  = "metrics_rollup"  // Hourly and daily metrics rollups (see storage/metrics_rollup.go)
+
+	DefaultRetentionPurgeSchedule = "@every 1h"         // Default schedule of the retention purge
+	DefaultMetricsRollupSchedule  = "@every 15m"        // Default schedule of the metrics rollup
+	MinJobInterval                = time.Minute         // Shortest interval between runs of a job
+	DefaultJobTimeout             = 30 * time.Minute    // Longest a run may take; another replica may start the job after that
+	JobRunRetention               = 30 * 24 * time.Hour // How long job run history is kept
+	JobStatusRuns                 = 10                  // Recent runs listed per job by GET /admin/jobs
+)
@@ -0,0 +1,84 @@
+// Package cost converts LLM token usage into currency for finance reporting.
+//
+// Prices are configured per model in [chatbox.costs.prices] as the cost of one
+// million tokens. Like session token usage, a call's tokens include the prompt
+// as well as the reply, so a model's price should blend its provider's input
+// and output rates. Models without a price cost nothing; their tokens are still
+// recorded.
+package cost
+
+import (
+	"fmt"
+	"math"
+)
+
+// tokensPerUnit is the number of tokens a configured price applies to
+const tokensPerUnit = 1_000_000
+
+// Calculator converts token usage into cost with per-model prices
+type Calculator struct {
+	currency string
+	prices   map[string]float64 // model ID -> price per million tokens
+}
+
+// NewCalculator creates a calculator charging prices, in currency per million
+// tokens, for each model ID
+func NewCalculator(currency string, prices map[string]float64) *Calculator {
+	copied := make(map[string]float64, len(prices))
+	for modelID, price := range prices {
+		copied[modelID] = price
+	}
+	return &Calculator{currency: currency, prices: copied}
+}
+
+// Currency returns the currency code costs are expressed in
+func (c *Calculator) Currency() string {
+	return c.currency
+}
+
+// Priced reports whether modelID has a configured price
+func (c *Calculator) Priced(modelID string) bool {
+	_, ok := c.prices[modelID]
+	return ok
+}
+
+// Cost returns the cost of tokens used with modelID, 0 when the model has no
+// price. A nil calculator prices nothing.
+func (c *Calculator) Cost(modelID string, tokens int) float64 {
+	// No else needed: early return pattern (cost tracking not configured)
+	if c == nil || tokens <= 0 {
+		return 0
+	}
+	return c.prices[modelID] * float64(tokens) / tokensPerUnit
+}
+
+// ParsePrices converts the raw [chatbox.costs.prices] config value, a table of
+// model = price entries, into per-model prices. Prices must be non-negative numbers.
+func ParsePrices(raw interface{}) (map[string]float64, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.costs.prices is not a table")
+	}
+
+	prices := make(map[string]float64, len(table))
+	for modelID, value := range table {
+		var price float64
+		switch v := value.(type) {
+		case float64:
+			price = v
+		case int64:
+			price = float64(v)
+		case int:
+			price = float64(v)
+		default:
+			return nil, fmt.Errorf("model %s: price must be a number", modelID)
+		}
+		// No else needed: early return pattern (guard clause)
+		if price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+			return nil, fmt.Errorf("model %s: price must be a non-negative number", modelID)
+		}
+		prices[modelID] = price
+	}
+	return prices, nil
+}
@@ -0,0 +1,49 @@
+package cost
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculator_Cost(t *testing.T) {
+	prices := map[string]float64{"gpt-4": 30, "claude-3": 15}
+	calc := NewCalculator("USD", prices)
+	prices["gpt-4"] = 0 // the calculator keeps its own copy
+
+	assert.Equal(t, "USD", calc.Currency())
+	assert.InDelta(t, 0.03, calc.Cost("gpt-4", 1000), 1e-12)
+	assert.InDelta(t, 15.0, calc.Cost("claude-3", 1_000_000), 1e-12)
+	assert.Zero(t, calc.Cost("gpt-4", 0))
+	assert.Zero(t, calc.Cost("gpt-4", -5))
+
+	assert.True(t, calc.Priced("gpt-4"))
+	assert.False(t, calc.Priced("unpriced"))
+	assert.Zero(t, calc.Cost("unpriced", 1000), "models without a price cost nothing")
+
+	var none *Calculator
+	assert.Zero(t, none.Cost("gpt-4", 1000))
+}
+
+func TestParsePrices(t *testing.T) {
+	prices, err := ParsePrices(map[string]interface{}{
+		"gpt-4":    30.0,
+		"claude-3": int64(15),
+		"free":     0,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"gpt-4": 30, "claude-3": 15, "free": 0}, prices)
+
+	invalid := []interface{}{
+		"not a table",
+		map[string]interface{}{"gpt-4": "30"},
+		map[string]interface{}{"gpt-4": -1.0},
+		map[string]interface{}{"gpt-4": math.Inf(1)},
+	}
+	for _, raw := range invalid {
+		_, err := ParsePrices(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+}
@@ -0,0 +1,634 @@
+// Package email sends session transcripts to users by SMTP.
+//
+// Users ask for a transcript when they end a session. Transcripts are queued
+// and sent in the background by a single worker, so the request never waits
+// on the mail server, and retried with exponential backoff while the server
+// fails temporarily (4xx replies and network errors). The HTML body is
+// rendered from a configurable template; files shared in the session are
+// attached up to constants.MaxEmailAttachmentBytes in total and the rest are
+// listed by name. Each recipient gets at most constants.EmailRecipientLimit
+// transcripts per constants.EmailRecipientWindow; more are refused when queued.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+)
+
+// implicitTLSPort is the SMTP port that is connected to over TLS rather than upgraded with STARTTLS
+const implicitTLSPort = 465
+
+var (
+	// ErrInvalidAddress is returned for a recipient that is not a single email address
+	ErrInvalidAddress = errors.New("invalid email address")
+	// ErrQueueFull is returned when too many transcript emails wait to be sent
+	ErrQueueFull = errors.New("transcript email queue is full")
+	// ErrRateLimited is returned when a recipient was sent too many transcript emails recently
+	ErrRateLimited = errors.New("too many transcript emails to this address")
+	// ErrClosed is returned when the mailer has shut down
+	ErrClosed = errors.New("transcript mailer has shut down")
+)
+
+// DefaultTemplate is the HTML template of transcript emails. Custom templates
+// are executed with a Transcript.
+const DefaultTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #1f2328;">
+<h2>{{.Title}}</h2>
+<p style="color: #59636e;">Started {{.Started}}{{if .Ended}}, ended {{.Ended}}{{end}}</p>
+{{range .Messages}}<p><strong>{{.Sender}}</strong> <span style="color: #59636e;">{{.Time}}</span><br>
+{{if .Content}}<span style="white-space: pre-wrap;">{{.Content}}</span>{{end}}{{if .File}}<em>File: {{.File}}</em>{{end}}</p>
+{{end}}{{if .Omitted}}<p style="color: #59636e;">Files not attached: {{range $i, $f := .Omitted}}{{if $i}}, {{end}}{{$f}}{{end}}</p>
+{{end}}</body>
+</html>
+`
+
+// textTemplate renders the plain text alternative of transcript emails
+var textTemplate = texttemplate.Must(texttemplate.New("text").Parse(`{{.Title}}
+Started {{.Started}}{{if .Ended}}, ended {{.Ended}}{{end}}
+{{range .Messages}}
+{{.Sender}} ({{.Time}}):
+{{if .Content}}{{.Content}}
+{{end}}{{if .File}}[File: {{.File}}]
+{{end}}{{end}}{{if .Omitted}}
+Files not attached: {{range $i, $f := .Omitted}}{{if $i}}, {{end}}{{$f}}{{end}}
+{{end}}`))
+
+// senderLabels names the senders of a transcript
+var senderLabels = map[string]string{
+	constants.SenderUser:   "You",
+	constants.SenderAI:     "Assistant",
+	constants.SenderAdmin:  "Support",
+	constants.SenderSystem: "System",
+}
+
+// Config holds the SMTP and transcript settings
+type Config struct {
+	Host       string // SMTP server
+	Port       int    // Defaults to constants.DefaultSMTPPort; 465 connects over TLS
+	Username   string // PLAIN authentication when set, only sent over TLS or to localhost
+	Password   string
+	From       string // Sender address, e.g. "Chat Support <support@example.com>"
+	Subject    string // Defaults to constants.DefaultEmailSubject
+	Template   string // HTML body template; DefaultTemplate when empty
+	MaxRetries int    // Send attempts per email (defaults to constants.DefaultEmailMaxRetries)
+}
+
+// FileFetcher returns the content and file name of an uploaded file
+type FileFetcher func(ctx context.Context, fileID string) ([]byte, string, error)
+
+// Job is a transcript email waiting to be sent
+type Job struct {
+	To      string // Recipient, as returned by ParseAddress
+	Session *session.Session
+}
+
+// Transcript is the data transcript email templates are executed with
+type Transcript struct {
+	Title     string
+	SessionID string
+	Started   string
+	Ended     string // Empty when the session has no end time
+	Messages  []TranscriptMessage
+	Omitted   []string // Names of the files too large to attach, or that could not be fetched
+}
+
+// TranscriptMessage is a message of a transcript
+type TranscriptMessage struct {
+	Sender  string // You, Assistant, Support or System
+	Time    string
+	Content string
+	File    string // Name of the file sent with the message
+}
+
+// attachment is a file attached to a transcript email
+type attachment struct {
+	name    string
+	content []byte
+}
+
+// sendFunc sends msg from one address to another
+type sendFunc func(ctx context.Context, from, to string, msg []byte) error
+
+// Mailer queues transcript emails and sends them over SMTP
+type Mailer struct {
+	host       string
+	addr       string
+	port       int
+	auth       smtp.Auth
+	from       *mail.Address
+	subject    string
+	html       *template.Template
+	maxRetries int
+	baseDelay  time.Duration
+	fetch      FileFetcher
+	send       sendFunc
+	logger     *golog.Logger
+
+	recipients *ratelimit.MessageLimiter // transcript emails per recipient
+
+	queue  chan Job
+	ctx    context.Context // cancelled when shutdown gives up on pending emails
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	closed bool
+	mu     sync.RWMutex
+}
+
+// NewMailer validates the configuration and starts the sending worker.
+// fetch may be nil when file uploads are disabled; files are then only listed.
+func NewMailer(cfg Config, fetch FileFetcher, logger *golog.Logger) (*Mailer, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.Host == "" {
+		return nil, errors.New("SMTP host is required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", cfg.From, err)
+	}
+
+	port := cfg.Port
+	// No else needed: optional operation (apply default)
+	if port == 0 {
+		port = constants.DefaultSMTPPort
+	}
+	// No else needed: early return pattern (guard clause)
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid SMTP port %d", port)
+	}
+	subject := cfg.Subject
+	// No else needed: optional operation (apply default)
+	if subject == "" {
+		subject = constants.DefaultEmailSubject
+	}
+	body := cfg.Template
+	// No else needed: optional operation (apply default)
+	if body == "" {
+		body = DefaultTemplate
+	}
+	html, err := template.New("transcript").Parse(body)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transcript email template: %w", err)
+	}
+	maxRetries := cfg.MaxRetries
+	// No else needed: optional operation (apply default)
+	if maxRetries <= 0 {
+		maxRetries = constants.DefaultEmailMaxRetries
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Mailer{
+		host:       cfg.Host,
+		addr:       net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		port:       port,
+		from:       from,
+		subject:    subject,
+		html:       html,
+		maxRetries: maxRetries,
+		baseDelay:  constants.EmailInitialRetryDelay,
+		fetch:      fetch,
+		logger:     logger,
+		recipients: ratelimit.NewMessageLimiter(constants.EmailRecipientWindow, constants.EmailRecipientLimit),
+		queue:      make(chan Job, constants.EmailQueueSize),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	// No else needed: optional operation (authentication only when configured)
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	m.send = m.sendSMTP
+	m.recipients.StartCleanup()
+
+	m.wg.Add(1)
+	util.SafeGo(logger, "email", func() {
+		defer m.wg.Done()
+		m.run()
+	})
+	return m, nil
+}
+
+// ParseAddress checks that addr is a single email address and returns it
+// without any display name
+func ParseAddress(addr string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(addr))
+	// No else needed: early return pattern (guard clause)
+	if err != nil || len(parsed.Address) > 254 {
+		return "", ErrInvalidAddress
+	}
+	return parsed.Address, nil
+}
+
+// Queue queues a transcript email. It never blocks: ErrQueueFull, ErrRateLimited
+// or ErrClosed is returned when the email cannot be queued.
+func (m *Mailer) Queue(job Job) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if m.closed {
+		return ErrClosed
+	}
+	// No else needed: early return pattern (guard clause)
+	if !m.recipients.Allow(strings.ToLower(job.To)) {
+		metrics.TranscriptEmails.WithLabelValues("limited").Inc()
+		return ErrRateLimited
+	}
+
+	select {
+	case m.queue <- job:
+		return nil
+	default:
+		metrics.TranscriptEmails.WithLabelValues("dropped").Inc()
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting emails and waits for queued emails to be sent.
+// If ctx expires first, pending emails are abandoned and ctx.Err() is returned.
+func (m *Mailer) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	// No else needed: optional operation (close once)
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+	m.recipients.StopCleanup()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		return ctx.Err()
+	}
+}
+
+// run sends queued emails until the queue is closed
+func (m *Mailer) run() {
+	for job := range m.queue {
+		msg, err := m.build(job)
+		// No else needed: early return pattern (skip unrenderable email)
+		if err != nil {
+			metrics.TranscriptEmails.WithLabelValues("failed").Inc()
+			util.LogError(m.logger, "email", "build transcript email", err, "session_id", job.Session.ID)
+			continue
+		}
+		// No else needed: early return pattern (failures are logged and counted)
+		if err := m.deliver(job.To, msg); err != nil {
+			metrics.TranscriptEmails.WithLabelValues("failed").Inc()
+			util.LogError(m.logger, "email", "send transcript email", err, "session_id", job.Session.ID)
+			continue
+		}
+		metrics.TranscriptEmails.WithLabelValues("sent").Inc()
+		m.logger.Info("Transcript email sent", "session_id", job.Session.ID)
+	}
+}
+
+// deliver sends an email with retry logic and exponential backoff.
+// Permanent SMTP failures (5xx replies) are not retried.
+func (m *Mailer) deliver(to string, msg []byte) error {
+	var lastErr error
+	for attempt := 0; attempt < m.maxRetries; attempt++ {
+		if attempt > 0 {
+			// Calculate exponential backoff delay
+			delay := m.baseDelay * time.Duration(1<<uint(attempt-1))
+			if delay > constants.EmailMaxRetryDelay {
+				delay = constants.EmailMaxRetryDelay
+			}
+
+			select {
+			case <-m.ctx.Done():
+				return fmt.Errorf("sending abandoned: %w", lastErr)
+			case <-time.After(delay):
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(m.ctx, constants.EmailSendTimeout)
+		err := m.send(ctx, m.from.Address, to, msg)
+		cancel()
+		// No else needed: early return pattern (success)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		// No else needed: early return pattern (permanent failure)
+		if permanent(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed after %d attempts: %w", m.maxRetries, lastErr)
+}
+
+// permanent reports whether err is an SMTP reply that retrying will not change
+func permanent(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code >= 500
+}
+
+// sendSMTP makes a single send attempt over SMTP, upgrading the connection
+// with STARTTLS when the server offers it
+func (m *Mailer) sendSMTP(ctx context.Context, from, to string, msg []byte) error {
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if m.port == implicitTLSPort {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: m.host}}).DialContext(ctx, "tcp", m.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", m.addr)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.host)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	// No else needed: optional operation (upgrade when offered)
+	if ok, _ := client.Extension("STARTTLS"); ok && m.port != implicitTLSPort {
+		// No else needed: early return pattern (guard clause)
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	// No else needed: optional operation (authentication only when configured)
+	if m.auth != nil {
+		// No else needed: early return pattern (guard clause)
+		if err := client.Auth(m.auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// build renders a transcript email and attaches the session's files
+func (m *Mailer) build(job Job) ([]byte, error) {
+	transcript, attachments := m.transcript(job.Session)
+
+	var html, text bytes.Buffer
+	// No else needed: early return pattern (guard clause)
+	if err := m.html.Execute(&html, transcript); err != nil {
+		return nil, fmt.Errorf("failed to render transcript: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := textTemplate.Execute(&text, transcript); err != nil {
+		return nil, fmt.Errorf("failed to render transcript: %w", err)
+	}
+	return compose(m.from, job.To, m.subject, text.Bytes(), html.Bytes(), attachments)
+}
+
+// transcript returns the template data of a session and the files to attach.
+// Files that cannot be fetched or do not fit are listed in Omitted.
+func (m *Mailer) transcript(sess *session.Session) (*Transcript, []attachment) {
+	t := &Transcript{
+		Title:     sess.Name,
+		SessionID: sess.ID,
+		Started:   sess.StartTime.UTC().Format(time.RFC1123),
+	}
+	// No else needed: optional operation (unnamed sessions)
+	if t.Title == "" {
+		t.Title = "Conversation transcript"
+	}
+	// No else needed: optional operation (ended sessions)
+	if sess.EndTime != nil {
+		t.Ended = sess.EndTime.UTC().Format(time.RFC1123)
+	}
+
+	var attachments []attachment
+	size := 0
+	for _, msg := range sess.Messages {
+		label, ok := senderLabels[msg.Sender]
+		// No else needed: optional operation (unknown senders shown as system)
+		if !ok {
+			label = senderLabels[constants.SenderSystem]
+		}
+		tm := TranscriptMessage{
+			Sender:  label,
+			Time:    msg.Timestamp.UTC().Format(time.RFC1123),
+			Content: msg.Content,
+		}
+		// No else needed: optional operation (messages with a file)
+		if msg.FileID != "" {
+			file, ok := m.fetchFile(msg.FileID, constants.MaxEmailAttachmentBytes-size)
+			tm.File = file.name
+			if ok {
+				attachments = append(attachments, file)
+				size += len(file.content)
+			} else {
+				t.Omitted = append(t.Omitted, file.name)
+			}
+		}
+		// No else needed: optional operation (skip empty messages)
+		if tm.Content != "" || tm.File != "" {
+			t.Messages = append(t.Messages, tm)
+		}
+	}
+	return t, attachments
+}
+
+// fetchFile downloads an uploaded file and reports whether it fits in limit
+// bytes. The file ID's base name is used when the file cannot be fetched.
+func (m *Mailer) fetchFile(fileID string, limit int) (attachment, bool) {
+	file := attachment{name: filepath.Base(fileID)}
+	// No else needed: early return pattern (uploads disabled)
+	if m.fetch == nil {
+		return file, false
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, constants.EmailSendTimeout)
+	defer cancel()
+	content, name, err := m.fetch(ctx, fileID)
+	// No else needed: early return pattern (listed instead of attached)
+	if err != nil {
+		util.LogError(m.logger, "email", "fetch transcript attachment", err, "file_id", fileID)
+		return file, false
+	}
+	// No else needed: optional operation (stored file name)
+	if name != "" {
+		file.name = filepath.Base(name)
+	}
+	file.content = content
+	return file, len(content) <= limit
+}
+
+// compose builds a MIME email with a text and an HTML alternative and the attachments
+func compose(from *mail.Address, to, subject string, text, html []byte, attachments []attachment) ([]byte, error) {
+	var buf bytes.Buffer
+	mixed := multipart.NewWriter(&buf)
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "From: %s\r\n", from.String())
+	fmt.Fprintf(&head, "To: %s\r\n", to)
+	fmt.Fprintf(&head, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&head, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&head, "Message-ID: %s\r\n", messageID(from.Address))
+	fmt.Fprintf(&head, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&head, "Content-Type: multipart/mixed; boundary=%s\r\n", mixed.Boundary())
+	head.WriteString("\r\n")
+
+	var alt bytes.Buffer
+	alternative := multipart.NewWriter(&alt)
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		// No else needed: early return pattern (guard clause)
+		if _, err := qp.Write(part.body); err != nil {
+			return nil, err
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := alternative.Close(); err != nil {
+		return nil, err
+	}
+
+	w, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := w.Write(alt.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, a := range attachments {
+		contentType := mime.TypeByExtension(filepath.Ext(a.name))
+		// No else needed: optional operation (unknown file types)
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := writeBase64(w, a.content); err != nil {
+			return nil, err
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
+
+// writeBase64 writes content base64-encoded in lines of 76 characters
+func writeBase64(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 0 {
+		n := 76
+		// No else needed: optional operation (last line)
+		if len(encoded) < n {
+			n = len(encoded)
+		}
+		// No else needed: early return pattern (guard clause)
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+// messageID returns a random Message-ID in the sender's domain
+func messageID(from string) string {
+	domain := "chatbox.local"
+	// No else needed: optional operation (sender's domain)
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
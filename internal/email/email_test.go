@@ -0,0 +1,248 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestLogger() *golog.Logger {
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            "/tmp/chatbox-email-test-logs",
+		Level:          "error",
+		StandardOutput: false,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize test logger: %v", err))
+	}
+	return logger
+}
+
+// sentEmail is an email captured by recordingSender
+type sentEmail struct {
+	from, to string
+	msg      []byte
+}
+
+// recordingSender fails the first failures attempts with err and records the emails sent
+type recordingSender struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	attempts int
+	sent     []sentEmail
+}
+
+func (r *recordingSender) send(_ context.Context, from, to string, msg []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.attempts <= r.failures {
+		return r.err
+	}
+	r.sent = append(r.sent, sentEmail{from: from, to: to, msg: msg})
+	return nil
+}
+
+// newTestMailer creates a mailer sending through sender with a short retry delay
+func newTestMailer(t *testing.T, sender *recordingSender, fetch FileFetcher) *Mailer {
+	t.Helper()
+	m, err := NewMailer(Config{Host: "smtp.example.com", From: "Chat Support <support@example.com>"}, fetch, createTestLogger())
+	require.NoError(t, err)
+	m.send = sender.send
+	m.baseDelay = time.Millisecond
+	return m
+}
+
+func testSession() *session.Session {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Minute)
+	return &session.Session{
+		ID:        "session-1",
+		Name:      "Billing question",
+		StartTime: start,
+		EndTime:   &end,
+		Messages: []*session.Message{
+			{Content: "Why was I charged <twice>?", Timestamp: start, Sender: constants.SenderUser},
+			{Content: "Here is the receipt.", Timestamp: start.Add(time.Minute), Sender: constants.SenderAdmin, FileID: "files/receipt.pdf"},
+			{Content: "", Timestamp: start.Add(2 * time.Minute), Sender: constants.SenderUser, FileID: "files/scan.png"},
+		},
+	}
+}
+
+// parts returns the decoded text, HTML and attachment parts of an email
+func parts(t *testing.T, raw []byte) (text, html string, attachments map[string][]byte) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	attachments = make(map[string][]byte)
+	mixed := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mixed.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		mediaType, partParams, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		if mediaType == "multipart/alternative" {
+			alternative := multipart.NewReader(part, partParams["boundary"])
+			for {
+				alt, err := alternative.NextRawPart()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				body, err := io.ReadAll(quotedprintable.NewReader(alt))
+				require.NoError(t, err)
+				if strings.HasPrefix(alt.Header.Get("Content-Type"), "text/html") {
+					html = string(body)
+				} else {
+					text = string(body)
+				}
+			}
+			continue
+		}
+		encoded, err := io.ReadAll(part)
+		require.NoError(t, err)
+		content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+		require.NoError(t, err)
+		attachments[part.FileName()] = content
+	}
+	return text, html, attachments
+}
+
+func TestParseAddress(t *testing.T) {
+	addr, err := ParseAddress(" Jane <jane@example.com> ")
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", addr)
+
+	for _, invalid := range []string{"", "jane", "a@b.com, c@d.com", "jane@example.com\r\nBcc: x@y.com"} {
+		_, err := ParseAddress(invalid)
+		assert.ErrorIs(t, err, ErrInvalidAddress, invalid)
+	}
+}
+
+func TestNewMailer_Validation(t *testing.T) {
+	logger := createTestLogger()
+	_, err := NewMailer(Config{From: "support@example.com"}, nil, logger)
+	assert.Error(t, err, "host is required")
+	_, err = NewMailer(Config{Host: "smtp.example.com", From: "not an address"}, nil, logger)
+	assert.Error(t, err)
+	_, err = NewMailer(Config{Host: "smtp.example.com", From: "support@example.com", Template: "{{.Title"}, nil, logger)
+	assert.Error(t, err)
+}
+
+func TestMailer_SendsTranscriptWithAttachments(t *testing.T) {
+	sender := &recordingSender{}
+	fetch := func(_ context.Context, fileID string) ([]byte, string, error) {
+		if fileID == "files/receipt.pdf" {
+			return []byte("%PDF-receipt"), "receipt.pdf", nil
+		}
+		return nil, "", errors.New("file not found")
+	}
+	m := newTestMailer(t, sender, fetch)
+
+	require.NoError(t, m.Queue(Job{To: "jane@example.com", Session: testSession()}))
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	require.Len(t, sender.sent, 1)
+	sent := sender.sent[0]
+	assert.Equal(t, "support@example.com", sent.from)
+	assert.Equal(t, "jane@example.com", sent.to)
+
+	header, err := mail.ReadMessage(bytes.NewReader(sent.msg))
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultEmailSubject, header.Header.Get("Subject"))
+	assert.Equal(t, "jane@example.com", header.Header.Get("To"))
+
+	text, html, attachments := parts(t, sent.msg)
+	assert.Contains(t, text, "You (Sun, 01 Mar 2026 12:00:00 UTC):\r\nWhy was I charged <twice>?")
+	assert.Contains(t, text, "[File: receipt.pdf]")
+	assert.Contains(t, html, "Billing question")
+	assert.Contains(t, html, "Why was I charged &lt;twice&gt;?")
+	assert.Contains(t, html, "<strong>Support</strong>")
+	// The file that could not be fetched is listed instead of attached
+	assert.Contains(t, html, "Files not attached: scan.png")
+	assert.Equal(t, map[string][]byte{"receipt.pdf": []byte("%PDF-receipt")}, attachments)
+}
+
+func TestMailer_OversizedFilesAreListed(t *testing.T) {
+	sender := &recordingSender{}
+	fetch := func(_ context.Context, fileID string) ([]byte, string, error) {
+		return make([]byte, constants.MaxEmailAttachmentBytes/2+1), "", nil
+	}
+	m := newTestMailer(t, sender, fetch)
+
+	require.NoError(t, m.Queue(Job{To: "jane@example.com", Session: testSession()}))
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	require.Len(t, sender.sent, 1)
+	text, _, attachments := parts(t, sender.sent[0].msg)
+	assert.Len(t, attachments, 1, "only the first file fits")
+	assert.Contains(t, attachments, "receipt.pdf")
+	assert.Contains(t, text, "Files not attached: scan.png")
+}
+
+func TestMailer_RetriesTemporaryFailures(t *testing.T) {
+	sender := &recordingSender{failures: 2, err: &textproto.Error{Code: 451, Msg: "try again later"}}
+	m := newTestMailer(t, sender, nil)
+
+	require.NoError(t, m.Queue(Job{To: "jane@example.com", Session: testSession()}))
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	assert.Equal(t, 3, sender.attempts)
+	assert.Len(t, sender.sent, 1)
+}
+
+func TestMailer_PermanentFailureIsNotRetried(t *testing.T) {
+	sender := &recordingSender{failures: 1, err: &textproto.Error{Code: 550, Msg: "mailbox unavailable"}}
+	m := newTestMailer(t, sender, nil)
+
+	require.NoError(t, m.Queue(Job{To: "jane@example.com", Session: testSession()}))
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	assert.Equal(t, 1, sender.attempts)
+	assert.Empty(t, sender.sent)
+}
+
+func TestMailer_QueueAfterShutdown(t *testing.T) {
+	m := newTestMailer(t, &recordingSender{}, nil)
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.ErrorIs(t, m.Queue(Job{To: "jane@example.com", Session: testSession()}), ErrClosed)
+}
+
+func TestMailer_RateLimitsRecipient(t *testing.T) {
+	sender := &recordingSender{}
+	m := newTestMailer(t, sender, nil)
+
+	for i := 0; i < constants.EmailRecipientLimit; i++ {
+		require.NoError(t, m.Queue(Job{To: "jane@example.com", Session: testSession()}))
+	}
+	assert.ErrorIs(t, m.Queue(Job{To: "Jane@Example.com", Session: testSession()}), ErrRateLimited)
+	require.NoError(t, m.Queue(Job{To: "john@example.com", Session: testSession()}), "other recipients are not limited")
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	assert.Len(t, sender.sent, constants.EmailRecipientLimit+1)
+}
@@ -0,0 +1,179 @@
+// Package embed authorizes the partner sites that embed the chat widget.
+//
+// Each partner origin is configured under [chatbox.embed.origins.<name>] with
+// its own API key, tenant, token lifetime and hourly quota. Partners exchange
+// their API key for short-lived tokens bound to their origin, so they never need
+// the main JWT secret and a leaked widget token cannot be used from another site.
+package embed
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+)
+
+var (
+	// ErrUnauthorized is returned when the origin is not configured or the API key does not match it
+	ErrUnauthorized = errors.New("unknown origin or invalid API key")
+	// ErrQuotaExceeded is returned when an origin has minted its hourly quota of tokens
+	ErrQuotaExceeded = errors.New("embed token quota exceeded")
+)
+
+// Origin is a partner site allowed to embed the chat widget
+type Origin struct {
+	Name          string        // Config table name; namespaces the partner's user IDs
+	Origin        string        // Scheme and host the widget runs on, e.g. https://partner.example.com
+	APIKey        string        // Secret the partner's backend presents to mint tokens
+	TenantID      string        // Tenant the widget's sessions belong to; empty for the default tenant
+	TokenTTL      time.Duration // Lifetime of minted tokens
+	TokensPerHour int           // Tokens the partner may mint per hour
+}
+
+// ParseConfigOrigins converts the raw [chatbox.embed.origins] config value into
+// origins sorted by name
+func ParseConfigOrigins(raw interface{}) ([]*Origin, error) {
+	tables, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.embed.origins is not a table")
+	}
+
+	origins := make([]*Origin, 0, len(tables))
+	seen := make(map[string]string, len(tables))
+	for name, value := range tables {
+		table, ok := value.(map[string]interface{})
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("embed origin %s: not a table", name)
+		}
+
+		origin, err := parseOrigin(name, table)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("embed origin %s: %w", name, err)
+		}
+		// No else needed: early return pattern (guard clause)
+		if other, exists := seen[origin.Origin]; exists {
+			return nil, fmt.Errorf("embed origin %s: origin %s is already configured by %s", name, origin.Origin, other)
+		}
+		seen[origin.Origin] = name
+		origins = append(origins, origin)
+	}
+
+	sort.Slice(origins, func(i, j int) bool { return origins[i].Name < origins[j].Name })
+	return origins, nil
+}
+
+// parseOrigin validates one [chatbox.embed.origins.<name>] table
+func parseOrigin(name string, table map[string]interface{}) (*Origin, error) {
+	originStr, _ := table["origin"].(string)
+	u, err := url.Parse(originStr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return nil, fmt.Errorf("origin must be a scheme and host such as https://partner.example.com, got %q", originStr)
+	}
+
+	apiKey, _ := table["api_key"].(string)
+	// No else needed: early return pattern (guard clause)
+	if apiKey == "" {
+		return nil, fmt.Errorf("api_key is required")
+	}
+
+	tenantID, _ := table["tenant_id"].(string)
+
+	ttl := constants.DefaultEmbedTokenTTL
+	// No else needed: optional operation (lifetime defaults to DefaultEmbedTokenTTL)
+	if ttlStr, ok := table["token_ttl"].(string); ok {
+		ttl, err = time.ParseDuration(ttlStr)
+		// No else needed: early return pattern (guard clause)
+		if err != nil || ttl <= 0 || ttl > constants.MaxEmbedTokenTTL {
+			return nil, fmt.Errorf("token_ttl must be a positive duration of at most %s, got %q", constants.MaxEmbedTokenTTL, ttlStr)
+		}
+	}
+
+	perHour := constants.DefaultEmbedTokensPerHour
+	// No else needed: optional operation (quota defaults to DefaultEmbedTokensPerHour)
+	if value, exists := table["tokens_per_hour"]; exists {
+		n, ok := value.(int64)
+		// No else needed: early return pattern (guard clause)
+		if !ok || n <= 0 {
+			return nil, fmt.Errorf("tokens_per_hour must be a positive integer")
+		}
+		perHour = int(n)
+	}
+
+	return &Origin{
+		Name:          name,
+		Origin:        u.Scheme + "://" + u.Host,
+		APIKey:        apiKey,
+		TenantID:      tenantID,
+		TokenTTL:      ttl,
+		TokensPerHour: perHour,
+	}, nil
+}
+
+// LimiterFunc creates the limiter enforcing an origin's hourly token quota.
+// The limiter is keyed by the origin name.
+type LimiterFunc func(origin *Origin) ratelimit.Limiter
+
+// Registry authorizes token requests against the configured origins
+type Registry struct {
+	origins  map[string]*Origin // origin -> configuration
+	limiters map[string]ratelimit.Limiter
+}
+
+// NewRegistry creates a registry for origins. newLimiter may be nil, in which
+// case quotas are tracked in process memory.
+func NewRegistry(origins []*Origin, newLimiter LimiterFunc) *Registry {
+	// No else needed: optional operation (in-memory quotas by default)
+	if newLimiter == nil {
+		newLimiter = func(origin *Origin) ratelimit.Limiter {
+			return ratelimit.NewMessageLimiter(time.Hour, origin.TokensPerHour)
+		}
+	}
+
+	r := &Registry{
+		origins:  make(map[string]*Origin, len(origins)),
+		limiters: make(map[string]ratelimit.Limiter, len(origins)),
+	}
+	for _, origin := range origins {
+		r.origins[origin.Origin] = origin
+		r.limiters[origin.Origin] = newLimiter(origin)
+	}
+	return r
+}
+
+// Origins returns the configured origins
+func (r *Registry) Origins() []string {
+	origins := make([]string, 0, len(r.origins))
+	for origin := range r.origins {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	return origins
+}
+
+// Authorize checks apiKey against the configuration of origin and counts a
+// minted token against its quota. Returns ErrUnauthorized for an unknown origin
+// or wrong key, and ErrQuotaExceeded with the milliseconds until the next token
+// is allowed when the quota is used up.
+func (r *Registry) Authorize(origin, apiKey string) (*Origin, int, error) {
+	config, ok := r.origins[origin]
+	// No else needed: early return pattern (guard clause)
+	if !ok || subtle.ConstantTimeCompare([]byte(config.APIKey), []byte(apiKey)) != 1 {
+		return nil, 0, ErrUnauthorized
+	}
+
+	limiter := r.limiters[origin]
+	// No else needed: early return pattern (guard clause)
+	if !limiter.Allow(config.Name) {
+		return nil, limiter.GetRetryAfter(config.Name), ErrQuotaExceeded
+	}
+	return config, 0, nil
+}
@@ -0,0 +1,101 @@
+package embed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigOrigins(t *testing.T) {
+	origins, err := ParseConfigOrigins(map[string]interface{}{
+		"shop": map[string]interface{}{
+			"origin":          "https://shop.example.com/",
+			"api_key":         "shop-key",
+			"tenant_id":       "tenant-shop",
+			"token_ttl":       "5m",
+			"tokens_per_hour": int64(20),
+		},
+		"blog": map[string]interface{}{
+			"origin":  "https://blog.example.com",
+			"api_key": "blog-key",
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, origins, 2)
+
+	assert.Equal(t, &Origin{
+		Name:          "blog",
+		Origin:        "https://blog.example.com",
+		APIKey:        "blog-key",
+		TokenTTL:      constants.DefaultEmbedTokenTTL,
+		TokensPerHour: constants.DefaultEmbedTokensPerHour,
+	}, origins[0])
+	assert.Equal(t, &Origin{
+		Name:          "shop",
+		Origin:        "https://shop.example.com",
+		APIKey:        "shop-key",
+		TenantID:      "tenant-shop",
+		TokenTTL:      5 * time.Minute,
+		TokensPerHour: 20,
+	}, origins[1])
+}
+
+func TestParseConfigOrigins_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		table map[string]interface{}
+	}{
+		{"missing origin", map[string]interface{}{"api_key": "k"}},
+		{"origin with path", map[string]interface{}{"origin": "https://shop.example.com/widget", "api_key": "k"}},
+		{"not http", map[string]interface{}{"origin": "ftp://shop.example.com", "api_key": "k"}},
+		{"missing api key", map[string]interface{}{"origin": "https://shop.example.com"}},
+		{"ttl too long", map[string]interface{}{"origin": "https://shop.example.com", "api_key": "k", "token_ttl": "2h"}},
+		{"bad ttl", map[string]interface{}{"origin": "https://shop.example.com", "api_key": "k", "token_ttl": "soon"}},
+		{"zero quota", map[string]interface{}{"origin": "https://shop.example.com", "api_key": "k", "tokens_per_hour": int64(0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfigOrigins(map[string]interface{}{"shop": tt.table})
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := ParseConfigOrigins("not a table")
+	assert.Error(t, err)
+
+	_, err = ParseConfigOrigins(map[string]interface{}{
+		"a": map[string]interface{}{"origin": "https://shop.example.com", "api_key": "a"},
+		"b": map[string]interface{}{"origin": "https://shop.example.com", "api_key": "b"},
+	})
+	assert.Error(t, err, "an origin can only be configured once")
+}
+
+func TestRegistry_Authorize(t *testing.T) {
+	registry := NewRegistry([]*Origin{
+		{Name: "shop", Origin: "https://shop.example.com", APIKey: "shop-key", TokensPerHour: 2},
+		{Name: "blog", Origin: "https://blog.example.com", APIKey: "blog-key", TokensPerHour: 2},
+	}, nil)
+	assert.Equal(t, []string{"https://blog.example.com", "https://shop.example.com"}, registry.Origins())
+
+	origin, _, err := registry.Authorize("https://shop.example.com", "shop-key")
+	require.NoError(t, err)
+	assert.Equal(t, "shop", origin.Name)
+
+	// Keys only work for their own origin
+	_, _, err = registry.Authorize("https://shop.example.com", "blog-key")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, _, err = registry.Authorize("https://other.example.com", "shop-key")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	// Quotas are per origin
+	_, _, err = registry.Authorize("https://shop.example.com", "shop-key")
+	require.NoError(t, err)
+	_, retryAfter, err := registry.Authorize("https://shop.example.com", "shop-key")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Greater(t, retryAfter, 0)
+	_, _, err = registry.Authorize("https://blog.example.com", "blog-key")
+	assert.NoError(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/real-rm/chatbox/internal/apierror"
 	"github.com/real-rm/chatbox/internal/message"
 )
 
@@ -23,33 +24,39 @@ const (
 	CategoryRateLimit ErrorCategory = "rate_limit"
 )
 
-// ErrorCode represents specific error codes
-type ErrorCode string
+// ErrorCode represents specific error codes. Codes are shared with the HTTP API
+// and defined in the apierror package.
+type ErrorCode = apierror.Code
 
 const (
 	// Authentication errors
-	ErrCodeInvalidToken      ErrorCode = "INVALID_TOKEN"
-	ErrCodeExpiredToken      ErrorCode = "EXPIRED_TOKEN"
-	ErrCodeInsufficientPerms ErrorCode = "INSUFFICIENT_PERMISSIONS"
-	ErrCodeUnauthorized      ErrorCode = "UNAUTHORIZED" // CRITICAL FIX M5: Add proper error code
+	ErrCodeInvalidToken      = apierror.CodeInvalidToken
+	ErrCodeExpiredToken      = apierror.CodeExpiredToken
+	ErrCodeInsufficientPerms = apierror.CodeInsufficientPerms
+	ErrCodeUnauthorized      = apierror.CodeUnauthorized // CRITICAL FIX M5: Add proper error code
 
 	// Validation errors
-	ErrCodeInvalidFormat   ErrorCode = "INVALID_FORMAT"
-	ErrCodeMissingField    ErrorCode = "MISSING_FIELD"
-	ErrCodeInvalidFileType ErrorCode = "INVALID_FILE_TYPE"
-	ErrCodeInvalidFileSize ErrorCode = "INVALID_FILE_SIZE"
-	ErrCodeNotFound        ErrorCode = "NOT_FOUND" // CRITICAL FIX M5: Add proper error code
+	ErrCodeInvalidFormat   = apierror.CodeInvalidFormat
+	ErrCodeMissingField    = apierror.CodeMissingField
+	ErrCodeInvalidFileType = apierror.CodeInvalidFileType
+	ErrCodeInvalidFileSize = apierror.CodeInvalidFileSize
+	ErrCodeNotFound        = apierror.CodeNotFound // CRITICAL FIX M5: Add proper error code
+	ErrCodeContentBlocked  = apierror.CodeContentBlocked
+	ErrCodeSessionNotFound = apierror.CodeSessionNotFound
 
 	// Service errors
-	ErrCodeLLMUnavailable ErrorCode = "LLM_UNAVAILABLE"
-	ErrCodeLLMTimeout     ErrorCode = "LLM_TIMEOUT"
-	ErrCodeDatabaseError  ErrorCode = "DATABASE_ERROR"
-	ErrCodeStorageError   ErrorCode = "STORAGE_ERROR"
-	ErrCodeServiceError   ErrorCode = "SERVICE_ERROR"
+	ErrCodeLLMUnavailable = apierror.CodeLLMUnavailable
+	ErrCodeLLMTimeout     = apierror.CodeLLMTimeout
+	ErrCodeDatabaseError  = apierror.CodeDatabaseError
+	ErrCodeStorageError   = apierror.CodeStorageError
+	ErrCodeServiceError   = apierror.CodeServiceError
+	ErrCodeMaintenance    = apierror.CodeMaintenance
+	ErrCodeReadOnly       = apierror.CodeReadOnly
 
 	// Rate limiting errors
-	ErrCodeTooManyRequests ErrorCode = "TOO_MANY_REQUESTS"
-	ErrCodeConnectionLimit ErrorCode = "CONNECTION_LIMIT_EXCEEDED"
+	ErrCodeTooManyRequests = apierror.CodeTooManyRequests
+	ErrCodeConnectionLimit = apierror.CodeConnectionLimit
+	ErrCodeQuotaExceeded   = apierror.CodeQuotaExceeded
 )
 
 // ChatError represents an application error with category and recoverability information
@@ -194,6 +201,20 @@ func ErrStorageError(cause error) *ChatError {
 	return NewServiceError(ErrCodeStorageError, "File storage operation failed", cause)
 }
 
+// ErrMaintenance creates an error for a new session refused during a scheduled
+// maintenance window, retryable after retryAfter milliseconds when it ends
+func ErrMaintenance(message string, retryAfter int) *ChatError {
+	err := NewServiceError(ErrCodeMaintenance, message, nil)
+	err.RetryAfter = retryAfter
+	return err
+}
+
+// ErrReadOnly creates an error for a new session or message refused while an
+// admin has put the service in read-only mode
+func ErrReadOnly(message string) *ChatError {
+	return NewServiceError(ErrCodeReadOnly, message, nil)
+}
+
 // ErrTooManyRequests creates a too many requests error
 func ErrTooManyRequests(retryAfter int) *ChatError {
 	return NewRateLimitError(ErrCodeTooManyRequests,
@@ -206,12 +227,36 @@ func ErrConnectionLimitExceeded(retryAfter int) *ChatError {
 		"Connection limit exceeded, please try again later", retryAfter, nil)
 }
 
+// ErrQuotaExceeded creates a daily token budget exceeded error
+func ErrQuotaExceeded(retryAfter int) *ChatError {
+	return NewRateLimitError(ErrCodeQuotaExceeded,
+		"Daily token budget exceeded, please try again tomorrow", retryAfter, nil)
+}
+
+// ErrContentBlocked creates an error for a message rejected by content moderation
+func ErrContentBlocked() *ChatError {
+	return NewValidationError(ErrCodeContentBlocked,
+		"Message was blocked by content moderation", nil)
+}
+
+// ErrInjectionBlocked creates an error for a message rejected as a prompt injection
+func ErrInjectionBlocked() *ChatError {
+	return NewValidationError(ErrCodeContentBlocked,
+		"Message was blocked as a possible prompt injection", nil)
+}
+
 // ErrNotFound creates a not found error (CRITICAL FIX M5)
 func ErrNotFound(resourceType string) *ChatError {
 	return NewValidationError(ErrCodeNotFound,
 		fmt.Sprintf("%s not found", resourceType), nil)
 }
 
+// ErrSessionNotFound creates an error for a session that does not exist or is
+// not visible to the caller
+func ErrSessionNotFound(cause error) *ChatError {
+	return NewValidationError(ErrCodeSessionNotFound, "Session not found", cause)
+}
+
 // ErrUnauthorized creates an unauthorized access error (CRITICAL FIX M5)
 func ErrUnauthorized(message string) *ChatError {
 	return NewAuthError(ErrCodeUnauthorized, message, nil)
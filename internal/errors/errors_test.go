@@ -317,6 +317,44 @@ func TestErrStorageError(t *testing.T) {
 	}
 }
 
+func TestErrMaintenance(t *testing.T) {
+	retryAfter := 60000
+	err := ErrMaintenance("Back soon", retryAfter)
+
+	if err.Category != CategoryService {
+		t.Errorf("Expected category %s, got %s", CategoryService, err.Category)
+	}
+	if err.Code != ErrCodeMaintenance {
+		t.Errorf("Expected code %s, got %s", ErrCodeMaintenance, err.Code)
+	}
+	if err.Message != "Back soon" {
+		t.Errorf("Expected message 'Back soon', got '%s'", err.Message)
+	}
+	if !err.Recoverable {
+		t.Error("Expected recoverable error")
+	}
+	if err.RetryAfter != retryAfter {
+		t.Errorf("Expected retry after %d, got %d", retryAfter, err.RetryAfter)
+	}
+}
+
+func TestErrReadOnly(t *testing.T) {
+	err := ErrReadOnly("Storage incident")
+
+	if err.Category != CategoryService {
+		t.Errorf("Expected category %s, got %s", CategoryService, err.Category)
+	}
+	if err.Code != ErrCodeReadOnly {
+		t.Errorf("Expected code %s, got %s", ErrCodeReadOnly, err.Code)
+	}
+	if err.Message != "Storage incident" {
+		t.Errorf("Expected message 'Storage incident', got '%s'", err.Message)
+	}
+	if !err.Recoverable {
+		t.Error("Expected recoverable error")
+	}
+}
+
 func TestErrTooManyRequests(t *testing.T) {
 	retryAfter := 10000
 	err := ErrTooManyRequests(retryAfter)
@@ -0,0 +1,202 @@
+// Package events publishes session lifecycle events (created sessions, added
+// messages, ended sessions, admin takeovers) to pluggable sinks, so downstream
+// analytics can consume them instead of polling the database.
+//
+// Events share the JSON envelope of webhook deliveries. They are queued and sent
+// to every sink in order by a single background worker, so callers on the message
+// path never block on I/O. Delivery is at most once: an event a sink fails to
+// accept is logged and counted, not retried (the webhook sink retries on its own).
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/webhook"
+	"github.com/real-rm/golog"
+)
+
+// Event is a session lifecycle event. It has the same JSON form as a webhook event.
+type Event = webhook.Event
+
+// Event types published on the bus
+const (
+	TypeSessionCreated = "session_created"
+	TypeMessageAdded   = "message_added" // Data holds the sender, and the event of system messages; never the content
+	TypeSessionEnded   = webhook.EventSessionEnded
+	TypeHelpRequested  = webhook.EventHelpRequested
+	TypeAdminTakeover  = webhook.EventAdminTakeover
+	TypeAdminHandback  = webhook.EventAdminHandback
+)
+
+// ErrNoSinks is returned when a bus is created without any sinks
+var ErrNoSinks = errors.New("at least one event sink is required")
+
+// Sink delivers events to one downstream system
+type Sink interface {
+	// Name identifies the sink in logs and metrics
+	Name() string
+	// Send delivers one event; it must give up when ctx is done
+	Send(ctx context.Context, event Event) error
+	// Close flushes and releases the sink's resources
+	Close(ctx context.Context) error
+}
+
+// Publisher accepts lifecycle events (implemented by Bus and webhook.Dispatcher)
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Bus queues events and sends them to its sinks
+type Bus struct {
+	sinks  []Sink
+	logger *golog.Logger
+
+	queue  chan Event
+	wg     sync.WaitGroup
+	closed bool
+	mu     sync.RWMutex
+}
+
+// NewBus starts a bus delivering every event to each of sinks, in order
+func NewBus(sinks []Sink, logger *golog.Logger) (*Bus, error) {
+	// No else needed: early return pattern (guard clause)
+	if len(sinks) == 0 {
+		return nil, ErrNoSinks
+	}
+
+	b := &Bus{
+		sinks:  sinks,
+		logger: logger,
+		queue:  make(chan Event, constants.EventQueueSize),
+	}
+
+	b.wg.Add(1)
+	util.SafeGo(logger, "events", func() {
+		defer b.wg.Done()
+		b.run()
+	})
+	return b, nil
+}
+
+// Publish queues an event for the sinks. It never blocks: when the queue is full
+// or the bus has shut down, the event is dropped and logged.
+// ID and Timestamp are filled in when empty.
+func (b *Bus) Publish(event Event) {
+	// No else needed: optional operation (fill defaults)
+	if event.ID == "" {
+		event.ID = webhook.NewEventID()
+	}
+	// No else needed: optional operation (fill defaults)
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if b.closed {
+		b.logger.Warn("Event bus stopped, dropping event", "event", event.Type, "session_id", event.SessionID)
+		return
+	}
+
+	select {
+	case b.queue <- event:
+	default:
+		metrics.EventDeliveries.WithLabelValues(event.Type, "bus", "dropped").Inc()
+		b.logger.Warn("Event queue full, dropping event", "event", event.Type, "session_id", event.SessionID)
+	}
+}
+
+// Shutdown stops accepting events, waits for queued events to be sent and closes
+// the sinks. If ctx expires first, pending events are abandoned and ctx.Err() is
+// returned.
+func (b *Bus) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	// No else needed: optional operation (close once)
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	var errs []error
+	for _, sink := range b.sinks {
+		// No else needed: optional operation (collect close errors)
+		if err := sink.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run sends queued events to every sink until the queue is closed
+func (b *Bus) run() {
+	for event := range b.queue {
+		for _, sink := range b.sinks {
+			ctx, cancel := context.WithTimeout(context.Background(), constants.EventSinkTimeout)
+			err := sink.Send(ctx, event)
+			cancel()
+			// No else needed: optional operation (failures are logged and counted)
+			if err != nil {
+				metrics.EventDeliveries.WithLabelValues(event.Type, sink.Name(), "failed").Inc()
+				util.LogError(b.logger, "events", "send event", err,
+					"event", event.Type,
+					"event_id", event.ID,
+					"session_id", event.SessionID,
+					"sink", sink.Name())
+				continue
+			}
+			metrics.EventDeliveries.WithLabelValues(event.Type, sink.Name(), "delivered").Inc()
+		}
+	}
+}
+
+// Tee returns a publisher forwarding every event to each of publishers. Nil
+// publishers are skipped; Tee returns nil when none remain.
+func Tee(publishers ...Publisher) Publisher {
+	var active tee
+	for _, p := range publishers {
+		// No else needed: optional operation (skip disabled publishers)
+		if p != nil {
+			active = append(active, p)
+		}
+	}
+	// No else needed: early return pattern (nothing to publish to)
+	if len(active) == 0 {
+		return nil
+	}
+	return active
+}
+
+// tee forwards events to several publishers
+type tee []Publisher
+
+// Publish forwards event to every publisher
+func (t tee) Publish(event Event) {
+	// Share the ID so consumers can correlate deliveries of the same event
+	// No else needed: optional operation (fill defaults)
+	if event.ID == "" {
+		event.ID = webhook.NewEventID()
+	}
+	for _, p := range t {
+		p.Publish(event)
+	}
+}
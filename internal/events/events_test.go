@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestLogger() *golog.Logger {
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            "/tmp/chatbox-events-test-logs",
+		Level:          "error",
+		StandardOutput: false,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize test logger: %v", err))
+	}
+	return logger
+}
+
+// recordingSink records the events it is sent
+type recordingSink struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+	closed bool
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(_ context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return s.err
+}
+
+func (s *recordingSink) Close(context.Context) error {
+	s.closed = true
+	return nil
+}
+
+func TestBus_SendsEventsToEverySinkInOrder(t *testing.T) {
+	failing := &recordingSink{err: errors.New("unavailable")}
+	ok := &recordingSink{}
+	bus, err := NewBus([]Sink{failing, ok}, createTestLogger())
+	require.NoError(t, err)
+
+	bus.Publish(Event{Type: TypeSessionCreated, SessionID: "session-1"})
+	bus.Publish(Event{Type: TypeMessageAdded, SessionID: "session-1", Data: map[string]string{"sender": "user"}})
+	require.NoError(t, bus.Shutdown(context.Background()))
+
+	for _, sink := range []*recordingSink{failing, ok} {
+		require.Len(t, sink.events, 2, "a failing sink does not stop delivery to the others")
+		assert.Equal(t, TypeSessionCreated, sink.events[0].Type)
+		assert.Equal(t, TypeMessageAdded, sink.events[1].Type)
+		assert.NotEmpty(t, sink.events[0].ID)
+		assert.False(t, sink.events[0].Timestamp.IsZero())
+		assert.True(t, sink.closed)
+	}
+
+	bus.Publish(Event{Type: TypeSessionEnded, SessionID: "session-1"}) // dropped after shutdown
+	assert.Len(t, ok.events, 2)
+}
+
+func TestNewBus_RequiresSinks(t *testing.T) {
+	_, err := NewBus(nil, createTestLogger())
+	assert.ErrorIs(t, err, ErrNoSinks)
+}
+
+// publisherFunc adapts a function to Publisher
+type publisherFunc func(Event)
+
+func (f publisherFunc) Publish(event Event) { f(event) }
+
+func TestTee(t *testing.T) {
+	assert.Nil(t, Tee(nil, nil), "a tee of disabled publishers is disabled")
+
+	var got []Event
+	record := publisherFunc(func(e Event) { got = append(got, e) })
+	Tee(record, nil, record).Publish(Event{Type: TypeSessionEnded, SessionID: "session-1"})
+
+	require.Len(t, got, 2)
+	assert.NotEmpty(t, got[0].ID)
+	assert.Equal(t, got[0].ID, got[1].ID, "every publisher gets the same event ID")
+}
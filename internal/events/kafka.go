@@ -0,0 +1,135 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// Content types of the Kafka REST Proxy v2 API
+const (
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+	kafkaAccept      = "application/vnd.kafka.v2+json"
+)
+
+// KafkaConfig holds Kafka sink settings
+type KafkaConfig struct {
+	RESTURL string // Kafka REST Proxy base URL
+	Topic   string // Topic events are produced to
+}
+
+// KafkaSink produces events to a Kafka topic through a Kafka REST Proxy (v2 API).
+// Records are keyed by session ID, so the events of a session keep their order.
+type KafkaSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+// kafkaRecord is one record of a produce request
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+// kafkaProduceResponse is the part of a produce response reporting record errors
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+// NewKafkaSink validates the configuration and creates the sink.
+// The proxy must use https, except internal hosts which may use http.
+func NewKafkaSink(cfg KafkaConfig) (*KafkaSink, error) {
+	// No else needed: early return pattern (guard clause)
+	if err := llm.ValidateEndpoint(cfg.RESTURL); err != nil {
+		return nil, fmt.Errorf("invalid Kafka REST URL %q: %w", cfg.RESTURL, err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka topic is required")
+	}
+	return &KafkaSink{
+		endpoint: strings.TrimRight(cfg.RESTURL, "/") + "/topics/" + url.PathEscape(cfg.Topic),
+		client:   &http.Client{Timeout: constants.EventSinkTimeout},
+	}, nil
+}
+
+// Name implements Sink
+func (s *KafkaSink) Name() string {
+	return constants.EventSinkKafka
+}
+
+// Send implements Sink: it produces event as one record keyed by its session ID
+func (s *KafkaSink) Send(ctx context.Context, event Event) error {
+	body, err := util.MarshalJSON(map[string][]kafkaRecord{
+		"records": {{Key: event.SessionID, Value: event}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaAccept)
+
+	resp, err := s.client.Do(req)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to produce to Kafka: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, constants.MaxLLMErrorBodySize))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to read Kafka response: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka REST proxy returned status %d", resp.StatusCode)
+	}
+
+	// The proxy reports records it could not produce with a 200 status
+	var produced kafkaProduceResponse
+	// No else needed: optional operation (responses without offsets carry no record errors)
+	if err := json.Unmarshal(respBody, &produced); err == nil {
+		for _, offset := range produced.Offsets {
+			// No else needed: early return pattern (record rejected)
+			if offset.ErrorCode != nil || offset.Error != nil {
+				return fmt.Errorf("kafka rejected record: %s", derefString(offset.Error))
+			}
+		}
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *KafkaSink) Close(context.Context) error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// derefString returns the value of s, or "unknown error" when nil
+func derefString(s *string) string {
+	// No else needed: early return pattern (guard clause)
+	if s == nil {
+		return "unknown error"
+	}
+	return *s
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaSink_ProducesRecordKeyedBySession(t *testing.T) {
+	var body []byte
+	var path, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":7,"error_code":null,"error":null}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewKafkaSink(KafkaConfig{RESTURL: server.URL + "/", Topic: "chatbox.events"})
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), Event{ID: "e1", Type: TypeSessionCreated, SessionID: "session-1"}))
+
+	assert.Equal(t, "/topics/chatbox.events", path)
+	assert.Equal(t, kafkaContentType, contentType)
+	var produced struct {
+		Records []struct {
+			Key   string `json:"key"`
+			Value Event  `json:"value"`
+		} `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(body, &produced))
+	require.Len(t, produced.Records, 1)
+	assert.Equal(t, "session-1", produced.Records[0].Key)
+	assert.Equal(t, TypeSessionCreated, produced.Records[0].Value.Type)
+}
+
+func TestKafkaSink_ReportsRejectedRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"offsets":[{"error_code":40403,"error":"topic not found"}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewKafkaSink(KafkaConfig{RESTURL: server.URL, Topic: "missing"})
+	require.NoError(t, err)
+	err = sink.Send(context.Background(), Event{Type: TypeSessionCreated, SessionID: "session-1"})
+	assert.ErrorContains(t, err, "topic not found")
+
+	_, err = NewKafkaSink(KafkaConfig{RESTURL: "http://kafka.example.com", Topic: "t"})
+	assert.Error(t, err, "plain http is only allowed for internal hosts")
+}
@@ -0,0 +1,199 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+)
+
+// NATSConfig holds NATS sink settings
+type NATSConfig struct {
+	URL     string // Server address, nats://host[:port] (port defaults to 4222)
+	Subject string // Subject prefix; events are published to "<subject>.<event type>"
+	Token   string // Authentication token; connects without authentication when empty
+}
+
+// NATSSink publishes events to a NATS server with the core NATS text protocol.
+// The connection is opened on the first event and re-opened after a failure.
+type NATSSink struct {
+	addr    string
+	subject string
+	token   string
+	logger  *golog.Logger
+
+	mu   sync.Mutex
+	conn net.Conn // nil until connected, and after a failure
+}
+
+// NewNATSSink validates the configuration and creates the sink
+func NewNATSSink(cfg NATSConfig, logger *golog.Logger) (*NATSSink, error) {
+	u, err := url.Parse(cfg.URL)
+	// No else needed: early return pattern (guard clause)
+	if err != nil || u.Scheme != "nats" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q: must be nats://host[:port]", cfg.URL)
+	}
+	// No else needed: early return pattern (guard clause)
+	if cfg.Subject == "" || strings.ContainsAny(cfg.Subject, " \t\r\n*>") {
+		return nil, fmt.Errorf("invalid NATS subject %q", cfg.Subject)
+	}
+
+	port := u.Port()
+	// No else needed: optional operation (apply default)
+	if port == "" {
+		port = constants.DefaultNATSPort
+	}
+	return &NATSSink{
+		addr:    net.JoinHostPort(u.Hostname(), port),
+		subject: cfg.Subject,
+		token:   cfg.Token,
+		logger:  logger,
+	}, nil
+}
+
+// Name implements Sink
+func (s *NATSSink) Name() string {
+	return constants.EventSinkNATS
+}
+
+// Send implements Sink: it publishes event to "<subject>.<event type>"
+func (s *NATSSink) Send(ctx context.Context, event Event) error {
+	body, err := util.MarshalJSON(event)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// No else needed: optional operation (connect on first use or after a failure)
+	if s.conn == nil {
+		// No else needed: early return pattern (guard clause)
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline, ok := ctx.Deadline()
+	// No else needed: optional operation (apply default)
+	if !ok {
+		deadline = time.Now().Add(constants.EventSinkTimeout)
+	}
+	_ = s.conn.SetWriteDeadline(deadline)
+
+	frame := fmt.Sprintf("PUB %s.%s %d\r\n%s\r\n", s.subject, event.Type, len(body), body)
+	// No else needed: early return pattern (drop the connection; the next event reconnects)
+	if _, err := s.conn.Write([]byte(frame)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+	return nil
+}
+
+// connect dials the server, reads its INFO greeting and sends CONNECT.
+// Caller must hold s.mu.
+func (s *NATSSink) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	// No else needed: optional operation (apply default)
+	if !ok {
+		deadline = time.Now().Add(constants.EventSinkTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	reader := bufio.NewReader(conn)
+	info, err := reader.ReadString('\n')
+	// No else needed: early return pattern (guard clause)
+	if err != nil || !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting: %q", strings.TrimSpace(info))
+	}
+
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     constants.NATSClientName,
+		"lang":     "go",
+	}
+	// No else needed: optional operation (authenticate only when configured)
+	if s.token != "" {
+		options["auth_token"] = s.token
+	}
+	connectJSON, err := util.MarshalJSON(options)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to marshal NATS CONNECT: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := conn.Write([]byte("CONNECT " + string(connectJSON) + "\r\n")); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send NATS CONNECT: %w", err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	s.conn = conn
+	util.SafeGo(s.logger, "events-nats", func() {
+		s.readLoop(conn, reader)
+	})
+	return nil
+}
+
+// readLoop answers server PINGs (the server drops clients that stop answering)
+// and logs server errors until conn fails
+func (s *NATSSink) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		// No else needed: early return pattern (connection closed or failed)
+		if err != nil {
+			s.mu.Lock()
+			// No else needed: optional operation (the next event reconnects)
+			if s.conn == conn {
+				s.conn.Close()
+				s.conn = nil
+			}
+			s.mu.Unlock()
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			s.mu.Lock()
+			_ = conn.SetWriteDeadline(time.Now().Add(constants.EventSinkTimeout))
+			_, _ = conn.Write([]byte("PONG\r\n"))
+			s.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			s.logger.Warn("NATS server error", "error", strings.TrimSpace(line))
+		}
+	}
+}
+
+// Close implements Sink
+func (s *NATSSink) Close(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// No else needed: early return pattern (never connected)
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
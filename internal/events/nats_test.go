@@ -0,0 +1,74 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSSink_PublishesToEventSubject(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	lines := make(chan string, 8)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- strings.TrimSpace(line)
+		}
+	}()
+
+	sink, err := NewNATSSink(NATSConfig{
+		URL:     "nats://" + listener.Addr().String(),
+		Subject: "chatbox.events",
+		Token:   "secret",
+	}, createTestLogger())
+	require.NoError(t, err)
+	defer sink.Close(context.Background())
+
+	require.NoError(t, sink.Send(context.Background(), Event{ID: "e1", Type: TypeSessionEnded, SessionID: "session-1"}))
+
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("no line received")
+			return ""
+		}
+	}
+	connect := next()
+	assert.True(t, strings.HasPrefix(connect, "CONNECT "))
+	assert.Contains(t, connect, `"auth_token":"secret"`)
+	assert.True(t, strings.HasPrefix(next(), "PUB chatbox.events.session_ended "))
+	assert.Contains(t, next(), `"session_id":"session-1"`)
+}
+
+func TestNewNATSSink_ValidatesConfig(t *testing.T) {
+	logger := createTestLogger()
+	_, err := NewNATSSink(NATSConfig{URL: "http://localhost:4222", Subject: "s"}, logger)
+	assert.Error(t, err)
+	_, err = NewNATSSink(NATSConfig{URL: "nats://localhost", Subject: "bad subject"}, logger)
+	assert.Error(t, err)
+
+	sink, err := NewNATSSink(NATSConfig{URL: "nats://localhost", Subject: "chatbox.events"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:4222", sink.addr)
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/webhook"
+	"github.com/real-rm/golog"
+)
+
+// LogSink writes events to the service log
+type LogSink struct {
+	logger *golog.Logger
+}
+
+// NewLogSink creates a sink logging every event at info level
+func NewLogSink(logger *golog.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+// Name implements Sink
+func (s *LogSink) Name() string {
+	return constants.EventSinkLog
+}
+
+// Send implements Sink
+func (s *LogSink) Send(_ context.Context, event Event) error {
+	s.logger.Info("Session event",
+		"event", event.Type,
+		"event_id", event.ID,
+		"session_id", event.SessionID,
+		"user_id", event.UserID,
+		"tenant_id", event.TenantID,
+		"admin_id", event.AdminID,
+		"data", event.Data)
+	return nil
+}
+
+// Close implements Sink
+func (s *LogSink) Close(context.Context) error {
+	return nil
+}
+
+// WebhookSink posts events to HTTP endpoints through a webhook dispatcher, with
+// its signing and retries
+type WebhookSink struct {
+	dispatcher *webhook.Dispatcher
+}
+
+// NewWebhookSink creates a sink posting every event with dispatcher
+func NewWebhookSink(dispatcher *webhook.Dispatcher) *WebhookSink {
+	return &WebhookSink{dispatcher: dispatcher}
+}
+
+// Name implements Sink
+func (s *WebhookSink) Name() string {
+	return constants.EventSinkWebhook
+}
+
+// Send implements Sink. The event is queued on the dispatcher, which delivers it
+// in the background.
+func (s *WebhookSink) Send(_ context.Context, event Event) error {
+	s.dispatcher.Publish(event)
+	return nil
+}
+
+// Close implements Sink: it waits for queued deliveries until ctx expires
+func (s *WebhookSink) Close(ctx context.Context) error {
+	return s.dispatcher.Shutdown(ctx)
+}
@@ -0,0 +1,327 @@
+// Package experiment assigns chat sessions to the variants of A/B experiments
+// comparing LLM models or system prompt templates.
+//
+// Experiments come from two places: read-only experiments defined in config
+// under [chatbox.experiments.<id>], running for every tenant, and experiments
+// managed by admins through the API, stored in MongoDB per tenant. Each
+// experiment splits traffic between two or more weighted variants. A user is
+// always assigned the same variant of an experiment: the variant is picked from
+// a hash of the experiment ID and user ID, so assignments are stable across
+// sessions and replicas without being stored anywhere but on the session.
+package experiment
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// Experiment kinds: what a variant's value selects
+const (
+	KindModel  = "model"  // Value is a model ID from the model catalog
+	KindPrompt = "prompt" // Value is a system prompt template ID
+)
+
+var (
+	// ErrExperimentNotFound is returned when no experiment with the ID is visible to the tenant
+	ErrExperimentNotFound = errors.New("experiment not found")
+	// ErrInvalidExperiment is returned when an experiment fails validation
+	ErrInvalidExperiment = errors.New("invalid experiment")
+)
+
+// Variant is one arm of an experiment
+type Variant struct {
+	Name   string `bson:"nm" json:"name"`
+	Value  string `bson:"value" json:"value"`   // Model ID or prompt template ID, depending on the experiment kind
+	Weight int    `bson:"weight" json:"weight"` // Share of users relative to the other variants' weights
+}
+
+// Experiment splits new sessions between variants of a model or prompt template
+type Experiment struct {
+	ID        string    `bson:"_id" json:"id"`
+	TenantID  string    `bson:"tid,omitempty" json:"tenant_id,omitempty"` // empty for the default tenant
+	Name      string    `bson:"nm" json:"name"`
+	Kind      string    `bson:"kind" json:"kind"` // KindModel or KindPrompt
+	Variants  []Variant `bson:"variants" json:"variants"`
+	Active    bool      `bson:"active" json:"active"` // Inactive experiments assign no new sessions
+	ReadOnly  bool      `bson:"-" json:"read_only"`   // defined in config; cannot be changed through the API
+	CreatedAt time.Time `bson:"ts,omitempty" json:"created_at,omitempty"`
+	UpdatedAt time.Time `bson:"mt,omitempty" json:"updated_at,omitempty"`
+}
+
+// Assignment is the variant of an experiment a user is assigned
+type Assignment struct {
+	ExperimentID string
+	Kind         string
+	Variant      string // Variant name
+	Value        string // Model ID or prompt template ID to use
+}
+
+// Validate checks that an experiment has a name, a known kind and 2 to
+// constants.MaxExperimentVariants uniquely named variants with a value and a
+// positive weight
+func (e *Experiment) Validate() error {
+	// No else needed: early return pattern (guard clause)
+	if e.Name == "" || len(e.Name) > constants.MaxExperimentNameLength {
+		return fmt.Errorf("%w: name must be 1-%d bytes", ErrInvalidExperiment, constants.MaxExperimentNameLength)
+	}
+	// No else needed: early return pattern (guard clause)
+	if e.Kind != KindModel && e.Kind != KindPrompt {
+		return fmt.Errorf("%w: kind must be %s or %s", ErrInvalidExperiment, KindModel, KindPrompt)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(e.Variants) < 2 || len(e.Variants) > constants.MaxExperimentVariants {
+		return fmt.Errorf("%w: 2-%d variants are required", ErrInvalidExperiment, constants.MaxExperimentVariants)
+	}
+	seen := make(map[string]bool, len(e.Variants))
+	for i, v := range e.Variants {
+		// No else needed: early return pattern (guard clause)
+		if v.Name == "" || seen[v.Name] {
+			return fmt.Errorf("%w: variant %d: name must be set and unique", ErrInvalidExperiment, i+1)
+		}
+		seen[v.Name] = true
+		// No else needed: early return pattern (guard clause)
+		if v.Value == "" {
+			return fmt.Errorf("%w: variant %s: value is required", ErrInvalidExperiment, v.Name)
+		}
+		// No else needed: early return pattern (guard clause)
+		if v.Weight <= 0 {
+			return fmt.Errorf("%w: variant %s: weight must be positive", ErrInvalidExperiment, v.Name)
+		}
+	}
+	return nil
+}
+
+// Assign returns the variant of an experiment a user is assigned: the same user
+// always gets the same variant for as long as the variants are unchanged. The
+// variants must be valid (see Validate).
+func Assign(experimentID, userID string, variants []Variant) Variant {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	sum := sha256.Sum256([]byte(experimentID + "\x00" + userID))
+	point := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, v := range variants {
+		// No else needed: early return pattern (point falls in this variant's share)
+		if point < v.Weight {
+			return v
+		}
+		point -= v.Weight
+	}
+	return variants[len(variants)-1]
+}
+
+// ParseConfigExperiments converts the raw [chatbox.experiments] config value
+// into read-only experiments sorted by ID
+func ParseConfigExperiments(raw interface{}) ([]*Experiment, error) {
+	tables, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.experiments is not a table")
+	}
+
+	experiments := make([]*Experiment, 0, len(tables))
+	for id, value := range tables {
+		table, ok := value.(map[string]interface{})
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("experiment %s: not a table", id)
+		}
+
+		e := &Experiment{ID: id, Active: true, ReadOnly: true}
+		e.Name, _ = table["name"].(string)
+		e.Kind, _ = table["kind"].(string)
+		// No else needed: optional operation (name defaults to the ID)
+		if e.Name == "" {
+			e.Name = id
+		}
+		// No else needed: optional operation (experiments are active unless disabled)
+		if active, ok := table["active"].(bool); ok {
+			e.Active = active
+		}
+		variants, err := parseVariants(table["variants"])
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("experiment %s: %w", id, err)
+		}
+		e.Variants = variants
+		// No else needed: early return pattern (guard clause)
+		if err := e.Validate(); err != nil {
+			return nil, fmt.Errorf("experiment %s: %w", id, err)
+		}
+		experiments = append(experiments, e)
+	}
+
+	sort.Slice(experiments, func(i, j int) bool { return experiments[i].ID < experiments[j].ID })
+	return experiments, nil
+}
+
+// parseVariants reads an array of { name, value, weight } tables
+func parseVariants(raw interface{}) ([]Variant, error) {
+	var items []map[string]interface{}
+	switch list := raw.(type) {
+	case []map[string]interface{}:
+		items = list
+	case []interface{}:
+		for _, item := range list {
+			table, ok := item.(map[string]interface{})
+			// No else needed: early return pattern (guard clause)
+			if !ok {
+				return nil, fmt.Errorf("%w: variants must be an array of tables", ErrInvalidExperiment)
+			}
+			items = append(items, table)
+		}
+	default:
+		return nil, fmt.Errorf("%w: variants must be an array of tables", ErrInvalidExperiment)
+	}
+
+	variants := make([]Variant, 0, len(items))
+	for i, item := range items {
+		v := Variant{}
+		v.Name, _ = item["name"].(string)
+		v.Value, _ = item["value"].(string)
+		weight, err := parseWeight(item["weight"])
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("%w: variant %d: weight must be an integer", ErrInvalidExperiment, i+1)
+		}
+		v.Weight = weight
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+// parseWeight reads a variant weight, written as a TOML integer or string;
+// variants without one weigh 1
+func parseWeight(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 1, nil
+	case int64:
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("not an integer")
+	}
+}
+
+// ListFunc lists the admin-managed experiments of a tenant
+type ListFunc func(tenantID string) ([]*Experiment, error)
+
+// cacheEntry is a tenant's stored experiments and when they were listed
+type cacheEntry struct {
+	experiments []*Experiment
+	loadedAt    time.Time
+}
+
+// Service assigns users to the running experiments of their tenant: config
+// experiments first, then stored ones, in ID order. Stored experiments are
+// cached per tenant for constants.ExperimentCacheTTL so creating a session does
+// not hit MongoDB; changes made on another instance become visible once the
+// cached list expires.
+type Service struct {
+	config []*Experiment
+	store  ListFunc // nil when only config experiments are available
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry // tenantID -> stored experiments
+}
+
+// NewService creates an experiment service. store may be nil.
+func NewService(configExperiments []*Experiment, store ListFunc) *Service {
+	return &Service{
+		config: configExperiments,
+		store:  store,
+		cache:  make(map[string]cacheEntry),
+	}
+}
+
+// ConfigExperiments returns the read-only config experiments sorted by ID
+func (s *Service) ConfigExperiments() []*Experiment {
+	return append([]*Experiment(nil), s.config...)
+}
+
+// ConfigExperiment returns the config experiment with the ID, or nil when there is none
+func (s *Service) ConfigExperiment(id string) *Experiment {
+	for _, e := range s.config {
+		// No else needed: early return pattern (found)
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// stored returns the tenant's stored experiments, from the cache when fresh
+func (s *Service) stored(tenantID string) ([]*Experiment, error) {
+	// No else needed: early return pattern (config experiments only)
+	if s.store == nil {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	entry, cached := s.cache[tenantID]
+	s.mu.Unlock()
+	// No else needed: early return pattern (fresh cache hit)
+	if cached && time.Since(entry.loadedAt) < constants.ExperimentCacheTTL {
+		return entry.experiments, nil
+	}
+
+	experiments, err := s.store(tenantID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(experiments, func(i, j int) bool { return experiments[i].ID < experiments[j].ID })
+
+	s.mu.Lock()
+	s.cache[tenantID] = cacheEntry{experiments: experiments, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return experiments, nil
+}
+
+// Assign returns the variants a user of the tenant is assigned in the active
+// experiments. A session takes part in at most one experiment per kind, the
+// first in order, since two model experiments could not both pick its model.
+// Config experiments are still assigned when stored ones cannot be listed; the
+// error is returned alongside.
+func (s *Service) Assign(tenantID, userID string) ([]Assignment, error) {
+	stored, err := s.stored(tenantID)
+	experiments := append(s.ConfigExperiments(), stored...)
+
+	var assignments []Assignment
+	kinds := make(map[string]bool, 2)
+	for _, e := range experiments {
+		// No else needed: optional operation (skip inactive experiments and kinds already assigned)
+		if !e.Active || kinds[e.Kind] {
+			continue
+		}
+		kinds[e.Kind] = true
+		v := Assign(e.ID, userID, e.Variants)
+		assignments = append(assignments, Assignment{
+			ExperimentID: e.ID,
+			Kind:         e.Kind,
+			Variant:      v.Name,
+			Value:        v.Value,
+		})
+	}
+	return assignments, err
+}
+
+// Invalidate drops the cached experiments of a tenant after one was created or deleted
+func (s *Service) Invalidate(tenantID string) {
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+}
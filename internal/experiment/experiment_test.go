@@ -0,0 +1,131 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() *Experiment {
+		return &Experiment{Name: "Models", Kind: KindModel, Variants: []Variant{
+			{Name: "control", Value: "gpt-4", Weight: 1},
+			{Name: "treatment", Value: "claude-3", Weight: 1},
+		}}
+	}
+	assert.NoError(t, valid().Validate())
+
+	invalid := []func(e *Experiment){
+		func(e *Experiment) { e.Name = "" },
+		func(e *Experiment) { e.Kind = "temperature" },
+		func(e *Experiment) { e.Variants = e.Variants[:1] },
+		func(e *Experiment) { e.Variants[1].Name = "control" },
+		func(e *Experiment) { e.Variants[1].Value = "" },
+		func(e *Experiment) { e.Variants[1].Weight = 0 },
+	}
+	for i, change := range invalid {
+		e := valid()
+		change(e)
+		assert.ErrorIs(t, e.Validate(), ErrInvalidExperiment, "case %d", i)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	variants := []Variant{
+		{Name: "control", Value: "gpt-4", Weight: 3},
+		{Name: "treatment", Value: "claude-3", Weight: 1},
+	}
+
+	// The same user always gets the same variant
+	first := Assign("exp", "user-1", variants)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first, Assign("exp", "user-1", variants))
+	}
+
+	// Traffic is split by weight
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[Assign("exp", fmt.Sprintf("user-%d", i), variants).Name]++
+	}
+	assert.InDelta(t, 3000, counts["control"], 200)
+	assert.InDelta(t, 1000, counts["treatment"], 200)
+}
+
+func TestParseConfigExperiments(t *testing.T) {
+	experiments, err := ParseConfigExperiments(map[string]interface{}{
+		"models": map[string]interface{}{
+			"name": "Models",
+			"kind": KindModel,
+			"variants": []interface{}{
+				map[string]interface{}{"name": "control", "value": "gpt-4", "weight": int64(90)},
+				map[string]interface{}{"name": "claude", "value": "claude-3", "weight": int64(10)},
+			},
+		},
+		"brief": map[string]interface{}{
+			"kind":   KindPrompt,
+			"active": false,
+			"variants": []map[string]interface{}{
+				{"name": "a", "value": "support"},
+				{"name": "b", "value": "brief"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, experiments, 2)
+	assert.Equal(t, &Experiment{ID: "brief", Name: "brief", Kind: KindPrompt, ReadOnly: true, Variants: []Variant{
+		{Name: "a", Value: "support", Weight: 1},
+		{Name: "b", Value: "brief", Weight: 1},
+	}}, experiments[0])
+	assert.True(t, experiments[1].Active)
+	assert.Equal(t, 90, experiments[1].Variants[0].Weight)
+
+	_, err = ParseConfigExperiments(map[string]interface{}{
+		"bad": map[string]interface{}{"kind": KindModel, "variants": "gpt-4"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidExperiment)
+	_, err = ParseConfigExperiments("not a table")
+	assert.Error(t, err)
+}
+
+func TestService_Assign(t *testing.T) {
+	variants := []Variant{{Name: "a", Value: "x", Weight: 1}, {Name: "b", Value: "y", Weight: 1}}
+	lists := 0
+	var storeErr error
+	store := ListFunc(func(tenantID string) ([]*Experiment, error) {
+		lists++
+		if storeErr != nil {
+			return nil, storeErr
+		}
+		return []*Experiment{
+			{ID: "stored-model", TenantID: tenantID, Kind: KindModel, Active: true, Variants: variants},
+			{ID: "stored-prompt", TenantID: tenantID, Kind: KindPrompt, Active: true, Variants: variants},
+		}, nil
+	})
+	svc := NewService([]*Experiment{
+		{ID: "config-model", Kind: KindModel, Active: true, ReadOnly: true, Variants: variants},
+		{ID: "config-off", Kind: KindPrompt, Active: false, ReadOnly: true, Variants: variants},
+	}, store)
+
+	assignments, err := svc.Assign("acme", "user-1")
+	require.NoError(t, err)
+	require.Len(t, assignments, 2, "one experiment per kind")
+	assert.Equal(t, "config-model", assignments[0].ExperimentID)
+	assert.Equal(t, "stored-prompt", assignments[1].ExperimentID, "inactive experiments are skipped")
+	assert.Equal(t, Assign("stored-prompt", "user-1", variants).Value, assignments[1].Value)
+
+	// Stored experiments are cached per tenant until invalidated
+	_, _ = svc.Assign("acme", "user-2")
+	assert.Equal(t, 1, lists)
+	svc.Invalidate("acme")
+	storeErr = errors.New("database unavailable")
+	assignments, err = svc.Assign("acme", "user-1")
+	assert.Error(t, err)
+	require.Len(t, assignments, 1, "config experiments are still assigned")
+	assert.Equal(t, "config-model", assignments[0].ExperimentID)
+
+	assert.NotNil(t, svc.ConfigExperiment("config-off"))
+	assert.Nil(t, svc.ConfigExperiment("stored-model"))
+}
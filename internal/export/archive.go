@@ -0,0 +1,83 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/memory"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// ArchiveContentType is the HTTP Content-Type of a user data archive
+const ArchiveContentType = "application/zip"
+
+// ArchiveManifest describes the contents of a user data archive
+type ArchiveManifest struct {
+	UserID       string    `json:"user_id"`
+	ExportedAt   time.Time `json:"exported_at"`
+	SessionCount int       `json:"session_count"`
+	Sessions     []string  `json:"sessions"`     // file names of the session transcripts
+	MemoryFacts  int       `json:"memory_facts"` // facts remembered about the user, in memory.json
+}
+
+// WriteUserArchive writes all sessions of a user as a ZIP archive for a data
+// subject access request: a manifest.json plus one JSON transcript per session
+// under sessions/, and the facts remembered about the user in memory.json when
+// there are any.
+func WriteUserArchive(w io.Writer, userID string, sessions []*session.Session, facts []*memory.Fact, exportedAt time.Time) error {
+	zw := zip.NewWriter(w)
+
+	manifest := ArchiveManifest{
+		UserID:       userID,
+		ExportedAt:   exportedAt,
+		SessionCount: len(sessions),
+		Sessions:     make([]string, 0, len(sessions)),
+		MemoryFacts:  len(facts),
+	}
+	for _, sess := range sessions {
+		name := fmt.Sprintf("sessions/%s.json", sess.ID)
+		manifest.Sessions = append(manifest.Sessions, name)
+
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: exportedAt})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := NewTranscript(sess, exportedAt).Write(f, FormatJSON); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	// No else needed: optional operation (only users with remembered facts)
+	if len(facts) > 0 {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: "memory.json", Method: zip.Deflate, Modified: exportedAt})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return fmt.Errorf("failed to add memory.json: %w", err)
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		// No else needed: early return pattern (guard clause)
+		if err := enc.Encode(facts); err != nil {
+			return fmt.Errorf("failed to write memory.json: %w", err)
+		}
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "manifest.json", Method: zip.Deflate, Modified: exportedAt})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to add manifest: %w", err)
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	// No else needed: early return pattern (guard clause)
+	if err := enc.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/memory"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteUserArchive(t *testing.T) {
+	exportedAt := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	second := &session.Session{ID: "sess-2", UserID: "user-1", StartTime: exportedAt}
+
+	var buf bytes.Buffer
+	facts := []*memory.Fact{{ID: "f1", UserID: "user-1", Kind: memory.KindName, Text: "Anna", CreatedAt: exportedAt}}
+	require.NoError(t, WriteUserArchive(&buf, "user-1", []*session.Session{testSession(), second}, facts, exportedAt))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = data
+	}
+	require.Len(t, files, 4)
+
+	var manifest ArchiveManifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Equal(t, "user-1", manifest.UserID)
+	assert.Equal(t, 2, manifest.SessionCount)
+	assert.Equal(t, []string{"sessions/sess-1.json", "sessions/sess-2.json"}, manifest.Sessions)
+	assert.Equal(t, 1, manifest.MemoryFacts)
+
+	var remembered []*memory.Fact
+	require.NoError(t, json.Unmarshal(files["memory.json"], &remembered))
+	require.Len(t, remembered, 1)
+	assert.Equal(t, "Anna", remembered[0].Text)
+
+	var transcript Transcript
+	require.NoError(t, json.Unmarshal(files["sessions/sess-1.json"], &transcript))
+	assert.Equal(t, "sess-1", transcript.SessionID)
+	assert.NotEmpty(t, transcript.Messages)
+}
+
+func TestWriteUserArchive_NoSessions(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteUserArchive(&buf, "user-1", nil, nil, time.Now()))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 1)
+	assert.Equal(t, "manifest.json", zr.File[0].Name)
+}
@@ -0,0 +1,352 @@
+// Package export renders session transcripts as downloadable JSON, CSV, or Markdown.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// Format is a transcript export format
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "md"
+)
+
+// ErrUnsupportedFormat is returned for unknown export formats
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// ParseFormat parses a format query value. An empty value selects JSON.
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(value))) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatMarkdown, "markdown":
+		return FormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("%w: %q (use json, csv, or md)", ErrUnsupportedFormat, value)
+	}
+}
+
+// ContentType returns the HTTP Content-Type for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension for the format, without a dot
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Transcript is the complete export of one session
+type Transcript struct {
+	SessionID          string        `json:"session_id"`
+	UserID             string        `json:"user_id"`
+	Name               string        `json:"name"`
+	ModelID            string        `json:"model_id,omitempty"`
+	StartTime          time.Time     `json:"start_time"`
+	EndTime            *time.Time    `json:"end_time,omitempty"`
+	HelpRequested      bool          `json:"help_requested"`
+	AdminAssisted      bool          `json:"admin_assisted"`
+	AssistingAdminID   string        `json:"assisting_admin_id,omitempty"`
+	AssistingAdminName string        `json:"assisting_admin_name,omitempty"`
+	TotalTokens        int           `json:"total_tokens"`
+	MessageCount       int           `json:"message_count"`
+	AdminInterventions int           `json:"admin_interventions"`
+	ExportedAt         time.Time     `json:"exported_at"`
+	Pinned             []PinnedEntry `json:"pinned,omitempty"` // pinned messages in pin order
+	Messages           []Entry       `json:"messages"`
+}
+
+// Entry is one transcript line: a chat message or a system event
+type Entry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Sender    string            `json:"sender"`
+	Event     string            `json:"event,omitempty"`
+	Content   string            `json:"content"`
+	FileID    string            `json:"file_id,omitempty"`
+	FileURL   string            `json:"file_url,omitempty"`
+	Tokens    int               `json:"tokens,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Pinned    bool              `json:"pinned,omitempty"`
+}
+
+// PinnedEntry is a pinned transcript entry with its pin
+type PinnedEntry struct {
+	MessageIndex int       `json:"message_index"`
+	PinnedBy     string    `json:"pinned_by"`
+	PinnedAt     time.Time `json:"pinned_at"`
+	Entry
+}
+
+// IsAdminIntervention reports whether the entry was written by an admin or
+// records an admin joining or leaving the session.
+func (e Entry) IsAdminIntervention() bool {
+	return e.Sender == constants.SenderAdmin ||
+		e.Event == constants.SystemEventAdminTakeover ||
+		e.Event == constants.SystemEventAdminLeave
+}
+
+// NewTranscript builds a transcript from a session. The session is read under
+// its read lock, so live sessions can be exported safely.
+func NewTranscript(sess *session.Session, exportedAt time.Time) *Transcript {
+	sess.RLock()
+	defer sess.RUnlock()
+
+	t := &Transcript{
+		SessionID:          sess.ID,
+		UserID:             sess.UserID,
+		Name:               sess.Name,
+		ModelID:            sess.ModelID,
+		StartTime:          sess.StartTime,
+		EndTime:            sess.EndTime,
+		HelpRequested:      sess.HelpRequested,
+		AdminAssisted:      sess.AdminAssisted,
+		AssistingAdminID:   sess.AssistingAdminID,
+		AssistingAdminName: sess.AssistingAdminName,
+		TotalTokens:        sess.TotalTokens,
+		ExportedAt:         exportedAt,
+		Messages:           make([]Entry, 0, len(sess.Messages)),
+	}
+
+	pinned := make(map[int]bool, len(sess.Pins))
+	for _, pin := range sess.Pins {
+		pinned[pin.MessageIndex] = true
+	}
+	for i, msg := range sess.Messages {
+		entry := Entry{
+			Timestamp: msg.Timestamp,
+			Sender:    msg.Sender,
+			Event:     msg.Event,
+			Content:   msg.Content,
+			FileID:    msg.FileID,
+			FileURL:   msg.FileURL,
+			Metadata:  msg.Metadata,
+			Pinned:    pinned[i],
+		}
+		// No else needed: optional operation (token counts are recorded on LLM replies only)
+		if tokens, err := strconv.Atoi(msg.Metadata[constants.MetadataKeyTokens]); err == nil {
+			entry.Tokens = tokens
+		}
+		// No else needed: optional operation (system events are not counted as messages)
+		if !msg.IsSystemEvent() {
+			t.MessageCount++
+		}
+		// No else needed: optional operation (count admin activity)
+		if entry.IsAdminIntervention() {
+			t.AdminInterventions++
+		}
+		t.Messages = append(t.Messages, entry)
+	}
+	for _, pin := range sess.Pins {
+		// No else needed: optional operation (skip pins of missing messages)
+		if pin.MessageIndex >= 0 && pin.MessageIndex < len(t.Messages) {
+			t.Pinned = append(t.Pinned, PinnedEntry{
+				MessageIndex: pin.MessageIndex,
+				PinnedBy:     pin.PinnedBy,
+				PinnedAt:     pin.PinnedAt,
+				Entry:        t.Messages[pin.MessageIndex],
+			})
+		}
+	}
+
+	return t
+}
+
+// Write renders the transcript in the given format
+func (t *Transcript) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		return t.writeJSON(w)
+	case FormatCSV:
+		return t.writeCSV(w)
+	case FormatMarkdown:
+		return t.writeMarkdown(w)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// writeJSON writes the transcript as indented JSON
+func (t *Transcript) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	// No else needed: early return pattern (guard clause)
+	if err := enc.Encode(t); err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	return nil
+}
+
+// csvHeader lists the CSV columns. Session-level fields repeat on every row so
+// that each row stands alone when filtered in a spreadsheet.
+var csvHeader = []string{
+	"session_id", "user_id", "timestamp", "sender", "event", "content",
+	"file_id", "file_url", "tokens", "admin_id", "admin_name", "pinned",
+}
+
+// writeCSV writes one row per transcript entry
+func (t *Transcript) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	// No else needed: early return pattern (guard clause)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, e := range t.Messages {
+		// No else needed: conditional assignment, value already set if condition is false
+		tokens := ""
+		if e.Tokens > 0 {
+			tokens = strconv.Itoa(e.Tokens)
+		}
+		// No else needed: conditional assignment, value already set if condition is false
+		pinned := ""
+		if e.Pinned {
+			pinned = "true"
+		}
+		row := []string{
+			t.SessionID, t.UserID, e.Timestamp.UTC().Format(time.RFC3339), e.Sender, e.Event,
+			csvSafe(e.Content), e.FileID, csvSafe(e.FileURL), tokens,
+			e.Metadata["admin_id"], csvSafe(e.Metadata["admin_name"]), pinned,
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvSafe neutralizes values that spreadsheet applications would evaluate as
+// formulas (CSV injection) by prefixing them with a single quote.
+func csvSafe(value string) string {
+	// No else needed: early return pattern (guard clause)
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	default:
+		return value
+	}
+}
+
+// writeMarkdown writes a human-readable transcript
+func (t *Transcript) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	// No else needed: conditional assignment, value already set if condition is false
+	title := t.Name
+	if title == "" {
+		title = t.SessionID
+	}
+	fmt.Fprintf(&b, "# Transcript: %s\n\n", title)
+	fmt.Fprintf(&b, "- **Session ID:** %s\n", t.SessionID)
+	fmt.Fprintf(&b, "- **User ID:** %s\n", t.UserID)
+	// No else needed: optional operation (model may be unset)
+	if t.ModelID != "" {
+		fmt.Fprintf(&b, "- **Model:** %s\n", t.ModelID)
+	}
+	fmt.Fprintf(&b, "- **Started:** %s\n", t.StartTime.UTC().Format(time.RFC3339))
+	// No else needed: optional operation (active sessions have no end time)
+	if t.EndTime != nil {
+		fmt.Fprintf(&b, "- **Ended:** %s\n", t.EndTime.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "- **Messages:** %d\n", t.MessageCount)
+	fmt.Fprintf(&b, "- **Total tokens:** %d\n", t.TotalTokens)
+	fmt.Fprintf(&b, "- **Help requested:** %t\n", t.HelpRequested)
+	// No else needed: optional operation (only for admin-assisted sessions)
+	if t.AdminAssisted {
+		fmt.Fprintf(&b, "- **Assisting admin:** %s (%s)\n", t.AssistingAdminName, t.AssistingAdminID)
+	}
+	fmt.Fprintf(&b, "- **Admin interventions:** %d\n", t.AdminInterventions)
+	fmt.Fprintf(&b, "- **Exported:** %s\n\n", t.ExportedAt.UTC().Format(time.RFC3339))
+
+	// No else needed: optional operation (only sessions with pinned messages)
+	if len(t.Pinned) > 0 {
+		b.WriteString("## Pinned\n\n")
+		for _, p := range t.Pinned {
+			writeMarkdownEntry(&b, p.Entry, fmt.Sprintf(" · pinned by %s", p.PinnedBy))
+		}
+	}
+
+	b.WriteString("## Messages\n\n")
+	for _, e := range t.Messages {
+		// No else needed: conditional assignment, value already set if condition is false
+		suffix := ""
+		if e.Pinned {
+			suffix = " · pinned"
+		}
+		writeMarkdownEntry(&b, e, suffix)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write Markdown transcript: %w", err)
+	}
+	return nil
+}
+
+// writeMarkdownEntry writes one transcript entry, with suffix after its heading
+func writeMarkdownEntry(b *strings.Builder, e Entry, suffix string) {
+	ts := e.Timestamp.UTC().Format(time.RFC3339)
+	// No else needed: early return pattern (system events render as a single italic line)
+	if e.Event != "" {
+		fmt.Fprintf(b, "_%s · %s: %s_%s\n\n", ts, e.Event, e.Content, suffix)
+		return
+	}
+
+	fmt.Fprintf(b, "**%s** · %s", senderLabel(e), ts)
+	// No else needed: optional operation (token counts are recorded on LLM replies only)
+	if e.Tokens > 0 {
+		fmt.Fprintf(b, " · %d tokens", e.Tokens)
+	}
+	b.WriteString(suffix + "\n\n")
+	// No else needed: optional operation (attachments)
+	if e.FileURL != "" {
+		fmt.Fprintf(b, "Attachment: %s\n\n", e.FileURL)
+	}
+	// Quote every content line so multi-line messages stay inside the block
+	fmt.Fprintf(b, "> %s\n\n", strings.ReplaceAll(e.Content, "\n", "\n> "))
+}
+
+// senderLabel returns the display label for a message sender
+func senderLabel(e Entry) string {
+	switch e.Sender {
+	case constants.SenderUser:
+		return "User"
+	case constants.SenderAI:
+		return "Assistant"
+	case constants.SenderAdmin:
+		// No else needed: early return pattern (name available)
+		if name := e.Metadata["admin_name"]; name != "" {
+			return "Admin (" + name + ")"
+		}
+		return "Admin"
+	default:
+		return e.Sender
+	}
+}
@@ -0,0 +1,162 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSession returns a session with user, AI, admin, and system entries
+func testSession() *session.Session {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	return &session.Session{
+		ID:                 "sess-1",
+		UserID:             "user-1",
+		Name:               "Billing question",
+		ModelID:            "gpt-4",
+		StartTime:          start,
+		HelpRequested:      true,
+		AdminAssisted:      true,
+		AssistingAdminID:   "admin-1",
+		AssistingAdminName: "Alice",
+		TotalTokens:        42,
+		Messages: []*session.Message{
+			{Content: "=SUM(A1:A2)", Timestamp: start, Sender: constants.SenderUser},
+			{
+				Content:   "Here is an answer\nover two lines",
+				Timestamp: start.Add(time.Second),
+				Sender:    constants.SenderAI,
+				Metadata:  map[string]string{constants.MetadataKeyTokens: "42"},
+			},
+			{
+				Content:   "Administrator Alice took over the session",
+				Timestamp: start.Add(2 * time.Second),
+				Sender:    constants.SenderSystem,
+				Event:     constants.SystemEventAdminTakeover,
+				Metadata:  map[string]string{"admin_id": "admin-1", "admin_name": "Alice"},
+			},
+			{
+				Content:   "I can help with that",
+				Timestamp: start.Add(3 * time.Second),
+				Sender:    constants.SenderAdmin,
+				Metadata:  map[string]string{"admin_id": "admin-1", "admin_name": "Alice"},
+			},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for input, want := range map[string]Format{
+		"":         FormatJSON,
+		"json":     FormatJSON,
+		"CSV":      FormatCSV,
+		"md":       FormatMarkdown,
+		"markdown": FormatMarkdown,
+	} {
+		got, err := ParseFormat(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := ParseFormat("pdf")
+	assert.True(t, errors.Is(err, ErrUnsupportedFormat))
+}
+
+func TestNewTranscript_Summary(t *testing.T) {
+	tr := NewTranscript(testSession(), time.Now())
+
+	assert.Equal(t, 3, tr.MessageCount, "system events are not counted as messages")
+	assert.Equal(t, 2, tr.AdminInterventions, "takeover event and admin message")
+	assert.Equal(t, 42, tr.TotalTokens)
+	require.Len(t, tr.Messages, 4)
+	assert.Equal(t, 42, tr.Messages[1].Tokens)
+}
+
+func TestWrite_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewTranscript(testSession(), time.Now()).Write(&buf, FormatJSON))
+
+	var decoded Transcript
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "sess-1", decoded.SessionID)
+	assert.Equal(t, "Alice", decoded.AssistingAdminName)
+	require.Len(t, decoded.Messages, 4)
+	assert.Equal(t, constants.SystemEventAdminTakeover, decoded.Messages[2].Event)
+}
+
+func TestWrite_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewTranscript(testSession(), time.Now()).Write(&buf, FormatCSV))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 5, "header plus one row per entry")
+	assert.Equal(t, csvHeader, rows[0])
+
+	assert.Equal(t, "'=SUM(A1:A2)", rows[1][5], "formula-like content must be neutralized")
+	assert.Equal(t, "Here is an answer\nover two lines", rows[2][5])
+	assert.Equal(t, "42", rows[2][8])
+	assert.Equal(t, constants.SystemEventAdminTakeover, rows[3][4])
+	assert.Equal(t, "admin-1", rows[4][9])
+}
+
+func TestWrite_Markdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewTranscript(testSession(), time.Now()).Write(&buf, FormatMarkdown))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "# Transcript: Billing question\n"))
+	assert.Contains(t, out, "- **Total tokens:** 42")
+	assert.Contains(t, out, "- **Assisting admin:** Alice (admin-1)")
+	assert.Contains(t, out, "> Here is an answer\n> over two lines")
+	assert.Contains(t, out, "**Assistant** · 2026-03-01T10:00:01Z · 42 tokens")
+	assert.Contains(t, out, "_2026-03-01T10:00:02Z · admin_takeover: Administrator Alice took over the session_")
+	assert.Contains(t, out, "**Admin (Alice)**")
+}
+
+func TestWrite_UnsupportedFormat(t *testing.T) {
+	err := NewTranscript(testSession(), time.Now()).Write(&bytes.Buffer{}, Format("xml"))
+	assert.True(t, errors.Is(err, ErrUnsupportedFormat))
+}
+
+func TestNewTranscript_Pinned(t *testing.T) {
+	sess := testSession()
+	pinnedAt := sess.StartTime.Add(time.Minute)
+	sess.Pins = []session.Pin{
+		{MessageIndex: 3, PinnedBy: "admin-1", PinnedAt: pinnedAt},
+		{MessageIndex: 1, PinnedBy: "user-1", PinnedAt: pinnedAt},
+		{MessageIndex: 9, PinnedBy: "user-1", PinnedAt: pinnedAt},
+	}
+	tr := NewTranscript(sess, time.Now())
+
+	require.Len(t, tr.Pinned, 2, "pins of missing messages are skipped")
+	assert.Equal(t, "I can help with that", tr.Pinned[0].Content)
+	assert.Equal(t, "admin-1", tr.Pinned[0].PinnedBy)
+	assert.Equal(t, 1, tr.Pinned[1].MessageIndex)
+	assert.True(t, tr.Messages[1].Pinned)
+	assert.False(t, tr.Messages[0].Pinned)
+
+	var buf bytes.Buffer
+	require.NoError(t, tr.Write(&buf, FormatMarkdown))
+	out := buf.String()
+	pinnedSection := strings.Index(out, "## Pinned\n\n**Admin (Alice)** · 2026-03-01T10:00:03Z · pinned by admin-1\n\n> I can help with that")
+	assert.GreaterOrEqual(t, pinnedSection, 0)
+	assert.Less(t, pinnedSection, strings.Index(out, "## Messages"), "pins come before the messages")
+	assert.Contains(t, out, "**Assistant** · 2026-03-01T10:00:01Z · 42 tokens · pinned\n")
+
+	buf.Reset()
+	require.NoError(t, tr.Write(&buf, FormatCSV))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "true", rows[2][11])
+	assert.Equal(t, "", rows[1][11])
+}
@@ -0,0 +1,252 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// FinetuneFormat is a fine-tuning dataset format
+type FinetuneFormat string
+
+const (
+	// FinetuneOpenAI is OpenAI's chat fine-tuning format: one JSON object per
+	// line, {"messages": [{"role": "user"|"assistant", "content": ...}]}
+	FinetuneOpenAI FinetuneFormat = "openai-jsonl"
+	// FinetuneShareGPT is the ShareGPT format: a JSON array of
+	// {"id", "conversations": [{"from": "human"|"gpt", "value": ...}]}
+	FinetuneShareGPT FinetuneFormat = "sharegpt"
+)
+
+// ParseFinetuneFormat parses a format query value. An empty value selects openai-jsonl.
+func ParseFinetuneFormat(value string) (FinetuneFormat, error) {
+	switch FinetuneFormat(strings.ToLower(strings.TrimSpace(value))) {
+	case "", FinetuneOpenAI:
+		return FinetuneOpenAI, nil
+	case FinetuneShareGPT:
+		return FinetuneShareGPT, nil
+	default:
+		return "", fmt.Errorf("%w: %q (use openai-jsonl or sharegpt)", ErrUnsupportedFormat, value)
+	}
+}
+
+// datasetName returns the archive file name of the dataset
+func (f FinetuneFormat) datasetName() string {
+	// No else needed: early return pattern (JSON Lines)
+	if f == FinetuneOpenAI {
+		return "dataset.jsonl"
+	}
+	return "dataset.json"
+}
+
+// piiPatterns are the kinds of personal data redacted from fine-tuning
+// datasets, each replaced by its placeholder. IP addresses and card numbers
+// come before phone numbers, which would match them too.
+var piiPatterns = []struct {
+	re          *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[EMAIL]"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`), "[IP]"},
+	{regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), "[CARD]"},
+	{regexp.MustCompile(`\+?\(?\d[\d\s().-]{6,}\d`), "[PHONE]"},
+}
+
+// RedactPII replaces email addresses, IP addresses, card numbers and phone
+// numbers in text with placeholders such as [EMAIL]. Returns the redacted text
+// and the number of replacements.
+func RedactPII(text string) (string, int) {
+	count := 0
+	for _, p := range piiPatterns {
+		text = p.re.ReplaceAllStringFunc(text, func(string) string {
+			count++
+			return p.placeholder
+		})
+	}
+	return text, count
+}
+
+// FinetuneManifest describes a fine-tuning dataset archive
+type FinetuneManifest struct {
+	Format       FinetuneFormat    `json:"format"`
+	ExportedAt   time.Time         `json:"exported_at"`
+	Filters      map[string]string `json:"filters,omitempty"` // query filters the sessions were selected with
+	SessionCount int               `json:"session_count"`
+	Sessions     []string          `json:"sessions"`   // IDs of the sessions in the dataset, in dataset order
+	Skipped      []string          `json:"skipped"`    // IDs of selected sessions without a complete exchange
+	Redactions   int               `json:"redactions"` // PII matches replaced by placeholders
+	Dataset      string            `json:"dataset"`    // file name of the dataset
+}
+
+// turn is a dataset message: consecutive messages of one side joined
+type turn struct {
+	assistant bool
+	content   string
+}
+
+// finetuneTurns returns the conversation of a session as alternating user and
+// assistant turns, starting with the user and ending with the assistant, with
+// PII redacted, and the number of redactions. Only user messages and AI
+// replies are used: system events, admin messages, and messages that were
+// blocked by moderation, cut off or fail their integrity check are left out.
+func finetuneTurns(sess *session.Session) ([]turn, int) {
+	sess.RLock()
+	defer sess.RUnlock()
+
+	var turns []turn
+	redactions := 0
+	for _, msg := range sess.Messages {
+		// No else needed: optional operation (conversation content only)
+		if msg.IsSystemEvent() || (msg.Sender != constants.SenderUser && msg.Sender != constants.SenderAI) {
+			continue
+		}
+		// No else needed: optional operation (skip content not fit for training)
+		if msg.Metadata[constants.MetadataKeyModeration] == "block" ||
+			msg.Metadata[constants.MetadataKeyTruncated] == "true" ||
+			msg.Metadata[constants.MetadataKeyIntegrity] == constants.MessageIntegrityTampered {
+			continue
+		}
+		content := strings.TrimSpace(msg.Content)
+		// No else needed: optional operation (skip empty messages, e.g. bare uploads)
+		if content == "" {
+			continue
+		}
+		content, n := RedactPII(content)
+		redactions += n
+
+		assistant := msg.Sender == constants.SenderAI
+		switch {
+		case len(turns) == 0 && assistant:
+			// The dataset starts with the user; a greeting before it is dropped
+			continue
+		case len(turns) > 0 && turns[len(turns)-1].assistant == assistant:
+			turns[len(turns)-1].content += "\n\n" + content
+		default:
+			turns = append(turns, turn{assistant: assistant, content: content})
+		}
+	}
+	// No else needed: optional operation (a trailing question has no answer to learn from)
+	if len(turns) > 0 && !turns[len(turns)-1].assistant {
+		turns = turns[:len(turns)-1]
+	}
+	return turns, redactions
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIExample struct {
+	Messages []openAIMessage `json:"messages"`
+}
+
+type shareGPTTurn struct {
+	From  string `json:"from"`
+	Value string `json:"value"`
+}
+
+type shareGPTExample struct {
+	ID            string         `json:"id"`
+	Conversations []shareGPTTurn `json:"conversations"`
+}
+
+// WriteFinetuneArchive writes sessions as a fine-tuning dataset in a ZIP
+// archive: the dataset (dataset.jsonl or dataset.json) plus a manifest.json
+// listing the sessions included and skipped. filters are recorded in the
+// manifest as given. Sessions without a complete exchange are skipped.
+func WriteFinetuneArchive(w io.Writer, sessions []*session.Session, format FinetuneFormat, filters map[string]string, exportedAt time.Time) (*FinetuneManifest, error) {
+	manifest := &FinetuneManifest{
+		Format:     format,
+		ExportedAt: exportedAt,
+		Filters:    filters,
+		Sessions:   make([]string, 0, len(sessions)),
+		Skipped:    make([]string, 0),
+		Dataset:    format.datasetName(),
+	}
+
+	zw := zip.NewWriter(w)
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: manifest.Dataset, Method: zip.Deflate, Modified: exportedAt})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s: %w", manifest.Dataset, err)
+	}
+
+	enc := json.NewEncoder(f)
+	var shareGPT []shareGPTExample
+	for _, sess := range sessions {
+		turns, redactions := finetuneTurns(sess)
+		// No else needed: optional operation (nothing to learn from)
+		if len(turns) == 0 {
+			manifest.Skipped = append(manifest.Skipped, sess.ID)
+			continue
+		}
+		manifest.Sessions = append(manifest.Sessions, sess.ID)
+		manifest.Redactions += redactions
+
+		switch format {
+		case FinetuneOpenAI:
+			example := openAIExample{Messages: make([]openAIMessage, len(turns))}
+			for i, t := range turns {
+				example.Messages[i] = openAIMessage{Role: "user", Content: t.content}
+				// No else needed: conditional assignment (user by default)
+				if t.assistant {
+					example.Messages[i].Role = "assistant"
+				}
+			}
+			// No else needed: early return pattern (guard clause)
+			if err := enc.Encode(example); err != nil {
+				return nil, fmt.Errorf("failed to write session %s: %w", sess.ID, err)
+			}
+		case FinetuneShareGPT:
+			example := shareGPTExample{ID: sess.ID, Conversations: make([]shareGPTTurn, len(turns))}
+			for i, t := range turns {
+				example.Conversations[i] = shareGPTTurn{From: "human", Value: t.content}
+				// No else needed: conditional assignment (human by default)
+				if t.assistant {
+					example.Conversations[i].From = "gpt"
+				}
+			}
+			shareGPT = append(shareGPT, example)
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+		}
+	}
+	// No else needed: optional operation (ShareGPT is one JSON array)
+	if format == FinetuneShareGPT {
+		// No else needed: optional operation (an empty dataset is an empty array)
+		if shareGPT == nil {
+			shareGPT = []shareGPTExample{}
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := enc.Encode(shareGPT); err != nil {
+			return nil, fmt.Errorf("failed to write dataset: %w", err)
+		}
+	}
+	manifest.SessionCount = len(manifest.Sessions)
+
+	f, err = zw.CreateHeader(&zip.FileHeader{Name: "manifest.json", Method: zip.Deflate, Modified: exportedAt})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add manifest: %w", err)
+	}
+	enc = json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	// No else needed: early return pattern (guard clause)
+	if err := enc.Encode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readArchive returns the files of a ZIP archive by name
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = content
+	}
+	return files
+}
+
+func TestParseFinetuneFormat(t *testing.T) {
+	for input, want := range map[string]FinetuneFormat{
+		"":             FinetuneOpenAI,
+		"openai-jsonl": FinetuneOpenAI,
+		" ShareGPT ":   FinetuneShareGPT,
+	} {
+		got, err := ParseFinetuneFormat(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+	_, err := ParseFinetuneFormat("alpaca")
+	assert.True(t, errors.Is(err, ErrUnsupportedFormat))
+}
+
+func TestRedactPII(t *testing.T) {
+	redacted, n := RedactPII("Mail jane.doe@example.com or call +1 (415) 555-0134 from 192.168.1.20, card 4111 1111 1111 1111. Order 12345 ships in 3 days.")
+	assert.Equal(t, "Mail [EMAIL] or call [PHONE] from [IP], card [CARD]. Order 12345 ships in 3 days.", redacted)
+	assert.Equal(t, 4, n)
+
+	redacted, n = RedactPII("Nothing personal here")
+	assert.Equal(t, "Nothing personal here", redacted)
+	assert.Zero(t, n)
+}
+
+func TestFinetuneTurns(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	sess := &session.Session{
+		ID: "sess-1",
+		Messages: []*session.Message{
+			{Content: "Welcome! How can I help?", Sender: constants.SenderAI, Timestamp: start},
+			{Content: "My invoice is wrong.", Sender: constants.SenderUser, Timestamp: start},
+			{Content: "It's for bob@example.com", Sender: constants.SenderUser, Timestamp: start},
+			{Content: "Let me check.", Sender: constants.SenderAI, Timestamp: start},
+			{Content: "Administrator Alice took over the session", Sender: constants.SenderSystem, Event: constants.SystemEventAdminTakeover, Timestamp: start},
+			{Content: "Fixed it for you", Sender: constants.SenderAdmin, Timestamp: start},
+			{Content: "[blocked]", Sender: constants.SenderUser, Timestamp: start, Metadata: map[string]string{constants.MetadataKeyModeration: "block"}},
+			{Content: "Thanks!", Sender: constants.SenderUser, Timestamp: start},
+			{Content: "You're welc", Sender: constants.SenderAI, Timestamp: start, Metadata: map[string]string{constants.MetadataKeyTruncated: "true"}},
+		},
+	}
+
+	turns, redactions := finetuneTurns(sess)
+	assert.Equal(t, []turn{
+		{assistant: false, content: "My invoice is wrong.\n\nIt's for [EMAIL]"},
+		{assistant: true, content: "Let me check."},
+	}, turns, "greetings, admin messages, blocked and truncated messages and the unanswered question are left out")
+	assert.Equal(t, 1, redactions)
+}
+
+func TestWriteFinetuneArchive_OpenAI(t *testing.T) {
+	exportedAt := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	unanswered := &session.Session{ID: "sess-2", Messages: []*session.Message{
+		{Content: "Hello?", Sender: constants.SenderUser},
+	}}
+
+	var buf bytes.Buffer
+	manifest, err := WriteFinetuneArchive(&buf, []*session.Session{testSession(), unanswered}, FinetuneOpenAI,
+		map[string]string{"tag": "billing"}, exportedAt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sess-1"}, manifest.Sessions)
+	assert.Equal(t, []string{"sess-2"}, manifest.Skipped)
+
+	files := readArchive(t, buf.Bytes())
+	require.Len(t, files, 2)
+	lines := strings.Split(strings.TrimSpace(string(files["dataset.jsonl"])), "\n")
+	require.Len(t, lines, 1)
+	var example openAIExample
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &example))
+	assert.Equal(t, []openAIMessage{
+		{Role: "user", Content: "=SUM(A1:A2)"},
+		{Role: "assistant", Content: "Here is an answer\nover two lines"},
+	}, example.Messages)
+
+	var stored FinetuneManifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &stored))
+	assert.Equal(t, FinetuneOpenAI, stored.Format)
+	assert.Equal(t, 1, stored.SessionCount)
+	assert.Equal(t, "billing", stored.Filters["tag"])
+	assert.Equal(t, "dataset.jsonl", stored.Dataset)
+}
+
+func TestWriteFinetuneArchive_ShareGPT(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := WriteFinetuneArchive(&buf, []*session.Session{testSession()}, FinetuneShareGPT, nil, time.Now())
+	require.NoError(t, err)
+
+	var examples []shareGPTExample
+	require.NoError(t, json.Unmarshal(readArchive(t, buf.Bytes())["dataset.json"], &examples))
+	require.Len(t, examples, 1)
+	assert.Equal(t, "sess-1", examples[0].ID)
+	assert.Equal(t, []shareGPTTurn{
+		{From: "human", Value: "=SUM(A1:A2)"},
+		{From: "gpt", Value: "Here is an answer\nover two lines"},
+	}, examples[0].Conversations)
+
+	buf.Reset()
+	_, err = WriteFinetuneArchive(&buf, nil, FinetuneShareGPT, nil, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", string(readArchive(t, buf.Bytes())["dataset.json"]), "an empty dataset is an empty array")
+}
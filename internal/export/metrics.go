@@ -0,0 +1,43 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// MetricValue is one aggregated session metric, such as total_sessions. Per-tag
+// counts carry their tag.
+type MetricValue struct {
+	Name  string
+	Tag   string
+	Value float64
+}
+
+// metricsCSVHeader lists the metrics CSV columns. The time range repeats on
+// every row so that exports of several ranges can be pasted into one sheet.
+var metricsCSVHeader = []string{"start_time", "end_time", "metric", "tag", "value"}
+
+// WriteMetricsCSV writes one row per metric value of the range from start to end
+func WriteMetricsCSV(w io.Writer, start, end time.Time, values []MetricValue) error {
+	cw := csv.NewWriter(w)
+	// No else needed: early return pattern (guard clause)
+	if err := cw.Write(metricsCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	startStr := start.UTC().Format(time.RFC3339)
+	endStr := end.UTC().Format(time.RFC3339)
+	for _, v := range values {
+		row := []string{startStr, endStr, v.Name, csvSafe(v.Tag), strconv.FormatFloat(v.Value, 'f', -1, 64)}
+		// No else needed: early return pattern (guard clause)
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
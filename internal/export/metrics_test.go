@@ -0,0 +1,31 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetricsCSV(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMetricsCSV(&buf, start, end, []MetricValue{
+		{Name: "total_sessions", Value: 12},
+		{Name: "feedback_positive_rate", Value: 0.75},
+		{Name: "tag_sessions", Tag: "=HYPERLINK(\"x\")", Value: 3},
+	}))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, metricsCSVHeader, rows[0])
+	assert.Equal(t, []string{"2026-10-01T00:00:00Z", "2026-10-02T00:00:00Z", "total_sessions", "", "12"}, rows[1])
+	assert.Equal(t, "0.75", rows[2][4])
+	assert.Equal(t, "'=HYPERLINK(\"x\")", rows[3][3], "tags are neutralized against formula injection")
+}
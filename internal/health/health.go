@@ -0,0 +1,191 @@
+// Package health runs the readiness checks of the service's dependencies.
+//
+// Checks are registered in a Registry as critical or optional. A failing critical
+// check (e.g. MongoDB) makes the service "not ready" so Kubernetes stops routing
+// traffic to it; a failing optional check (e.g. Redis, whose rate limiter fails
+// open) only makes it "degraded": the service keeps serving with reduced
+// functionality, and dashboards can alert on it. During a scheduled maintenance
+// window the service reports "maintenance": it stays routable for existing
+// sessions while refusing new ones.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Status is the state of a single check or of the service as a whole
+type Status string
+
+const (
+	StatusReady       Status = "ready"
+	StatusDegraded    Status = "degraded"
+	StatusMaintenance Status = "maintenance"
+	StatusNotReady    Status = "not ready"
+)
+
+// severity orders statuses from best to worst
+func (s Status) severity() int {
+	switch s {
+	case StatusReady:
+		return 0
+	case StatusDegraded:
+		return 1
+	case StatusMaintenance:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Status    Status                 `json:"status"`
+	Reason    string                 `json:"reason,omitempty"` // safe to show to clients
+	LatencyMs float64                `json:"latency_ms"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Err       error                  `json:"-"` // underlying error, for server-side logs only
+}
+
+// OK returns a passing result with optional details
+func OK(details map[string]interface{}) Result {
+	return Result{Status: StatusReady, Details: details}
+}
+
+// Fail returns a failing result. reason is shown to clients; err is only logged.
+func Fail(reason string, err error) Result {
+	return Result{Status: StatusNotReady, Reason: reason, Err: err}
+}
+
+// Degraded returns a result for a dependency that works only partially
+func Degraded(reason string, err error) Result {
+	return Result{Status: StatusDegraded, Reason: reason, Err: err}
+}
+
+// Maintenance returns a result for a scheduled maintenance window in progress
+func Maintenance(reason string, details map[string]interface{}) Result {
+	return Result{Status: StatusMaintenance, Reason: reason, Details: details}
+}
+
+// CheckFunc checks one dependency. It must return when ctx is done.
+type CheckFunc func(ctx context.Context) Result
+
+// check is a registered CheckFunc
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// Report is the outcome of running every registered check
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Registry holds the readiness checks of the service
+type Registry struct {
+	timeout time.Duration // Per-check deadline
+	checks  []check
+}
+
+// NewRegistry creates an empty registry whose checks each get timeout to complete
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check. A failing critical check makes the service not ready;
+// a failing optional check only makes it degraded.
+// Must be called before Run.
+func (r *Registry) Register(name string, critical bool, fn CheckFunc) {
+	r.checks = append(r.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// Run runs all checks concurrently and reports each result with its latency.
+// The overall status is the worst status of any check.
+func (r *Registry) Run(ctx context.Context) Report {
+	results := make([]Result, len(r.checks))
+
+	var wg sync.WaitGroup
+	for i, c := range r.checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: make(map[string]Result, len(r.checks))}
+	for i, c := range r.checks {
+		report.Checks[c.name] = results[i]
+		// No else needed: optional operation (keep the worst status)
+		if results[i].Status.severity() > report.Status.severity() {
+			report.Status = results[i].Status
+		}
+	}
+	return report
+}
+
+// run runs one check with its deadline
+func (r *Registry) run(ctx context.Context, c check) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := c.fn(ctx)
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	// No else needed: optional operation (optional dependencies never fail readiness)
+	if !c.critical && result.Status == StatusNotReady {
+		result.Status = StatusDegraded
+	}
+	return result
+}
+
+// ProbeURL reports whether an HTTP endpoint is reachable. Any HTTP response,
+// including errors such as 401 or 404, counts as reachable: the check is about
+// the network path, not about credentials.
+func ProbeURL(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// WritableDir reports whether files can be created in dir
+func WritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	f, err := os.CreateTemp(dir, ".health-*")
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	// No else needed: early return pattern (guard clause)
+	if err := f.Close(); err != nil {
+		_ = os.Remove(name)
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	ok := func(ctx context.Context) Result { return OK(map[string]interface{}{"count": 2}) }
+	failing := func(ctx context.Context) Result { return Fail("down", errors.New("connection refused")) }
+
+	t.Run("all checks pass", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register("mongodb", true, ok)
+		r.Register("redis", false, ok)
+
+		report := r.Run(context.Background())
+		assert.Equal(t, StatusReady, report.Status)
+		assert.Len(t, report.Checks, 2)
+		assert.Equal(t, 2, report.Checks["mongodb"].Details["count"])
+	})
+
+	t.Run("optional failure degrades", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register("mongodb", true, ok)
+		r.Register("redis", false, failing)
+
+		report := r.Run(context.Background())
+		assert.Equal(t, StatusDegraded, report.Status)
+		assert.Equal(t, StatusDegraded, report.Checks["redis"].Status)
+		assert.Equal(t, "down", report.Checks["redis"].Reason)
+	})
+
+	t.Run("critical failure is not ready", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register("mongodb", true, failing)
+		r.Register("llm", true, func(ctx context.Context) Result { return Degraded("partial", nil) })
+
+		report := r.Run(context.Background())
+		assert.Equal(t, StatusNotReady, report.Status)
+		assert.Equal(t, StatusDegraded, report.Checks["llm"].Status)
+	})
+
+	t.Run("maintenance outranks degraded", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register("redis", false, failing)
+		r.Register("maintenance", false, func(ctx context.Context) Result { return Maintenance("scheduled", nil) })
+
+		report := r.Run(context.Background())
+		assert.Equal(t, StatusMaintenance, report.Status)
+		assert.Equal(t, "scheduled", report.Checks["maintenance"].Reason)
+
+		r.Register("mongodb", true, failing)
+		assert.Equal(t, StatusNotReady, r.Run(context.Background()).Status)
+	})
+
+	t.Run("checks run concurrently with a deadline", func(t *testing.T) {
+		r := NewRegistry(50 * time.Millisecond)
+		slow := func(ctx context.Context) Result {
+			<-ctx.Done()
+			return Fail("timed out", ctx.Err())
+		}
+		r.Register("a", true, slow)
+		r.Register("b", true, slow)
+
+		start := time.Now()
+		report := r.Run(context.Background())
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, StatusNotReady, report.Status)
+		assert.GreaterOrEqual(t, report.Checks["a"].LatencyMs, 40.0)
+	})
+}
+
+func TestProbeURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	client := server.Client()
+	assert.NoError(t, ProbeURL(context.Background(), client, server.URL), "any HTTP response counts as reachable")
+
+	server.Close()
+	assert.Error(t, ProbeURL(context.Background(), client, server.URL))
+	assert.Error(t, ProbeURL(context.Background(), client, "://bad"))
+}
+
+func TestWritableDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, WritableDir(dir))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the probe file is removed")
+
+	assert.Error(t, WritableDir(filepath.Join(dir, "missing")))
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	assert.Error(t, WritableDir(file))
+}
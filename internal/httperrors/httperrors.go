@@ -1,17 +1,15 @@
 // Package httperrors provides generic error responses for HTTP endpoints.
 // It ensures that internal implementation details are not leaked to clients.
+// Responses use the apierror envelope and codes.
 package httperrors
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/apierror"
 )
 
 // ErrorResponse represents a generic error response for clients
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    string `json:"code,omitempty"`
-	Details string `json:"details,omitempty"`
-}
+type ErrorResponse = apierror.Envelope
 
 // Generic error messages that don't expose internal details
 const (
@@ -27,20 +25,29 @@ const (
 	MsgInvalidTimeFormat  = "Invalid time format, expected RFC3339"
 	MsgSessionNotFound    = "Session not found"
 	MsgOperationFailed    = "Operation failed"
+	MsgRateLimited        = "Rate limit exceeded, please try again later"
 )
 
 // Error codes for client-side handling
 const (
-	CodeUnauthorized       = "UNAUTHORIZED"
-	CodeInvalidToken       = "INVALID_TOKEN"
-	CodeForbidden          = "FORBIDDEN"
-	CodeInvalidRequest     = "INVALID_REQUEST"
-	CodeInternalError      = "INTERNAL_ERROR"
-	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
-	CodeNotFound           = "NOT_FOUND"
-	CodeBadRequest         = "BAD_REQUEST"
+	CodeUnauthorized       = apierror.CodeUnauthorized
+	CodeInvalidToken       = apierror.CodeInvalidToken
+	CodeForbidden          = apierror.CodeForbidden
+	CodeInvalidRequest     = apierror.CodeInvalidRequest
+	CodeInternalError      = apierror.CodeInternalError
+	CodeServiceUnavailable = apierror.CodeServiceUnavailable
+	CodeNotFound           = apierror.CodeNotFound
+	CodeBadRequest         = apierror.CodeBadRequest
+	CodeFeatureDisabled    = apierror.CodeFeatureDisabled
+	CodeSessionNotFound    = apierror.CodeSessionNotFound
+	CodeRateLimited        = apierror.CodeRateLimited
 )
 
+// Respond sends an error response with the code's HTTP status
+func Respond(c *gin.Context, code apierror.Code, message string) {
+	c.JSON(code.Status(), apierror.New(code, message))
+}
+
 // RespondUnauthorized sends a 401 response with a generic message
 func RespondUnauthorized(c *gin.Context, message string) {
 	if message == "" {
@@ -105,3 +112,34 @@ func RespondNotFound(c *gin.Context, message string) {
 		Code:  CodeNotFound,
 	})
 }
+
+// RespondFeatureDisabled sends a 403 response for features turned off by configuration
+func RespondFeatureDisabled(c *gin.Context, message string) {
+	if message == "" {
+		message = MsgForbidden
+	}
+	c.JSON(403, ErrorResponse{
+		Error: message,
+		Code:  CodeFeatureDisabled,
+	})
+}
+
+// RespondSessionNotFound sends a 404 response for a missing session
+func RespondSessionNotFound(c *gin.Context) {
+	c.JSON(404, ErrorResponse{
+		Error: MsgSessionNotFound,
+		Code:  CodeSessionNotFound,
+	})
+}
+
+// RespondRateLimited sends a 429 response; retryAfter is in milliseconds
+func RespondRateLimited(c *gin.Context, message string, retryAfter int) {
+	if message == "" {
+		message = MsgRateLimited
+	}
+	c.JSON(429, ErrorResponse{
+		Error:      message,
+		Code:       CodeRateLimited,
+		RetryAfter: retryAfter,
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/apierror"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -170,3 +171,47 @@ func TestErrorResponseDoesNotLeakInternalDetails(t *testing.T) {
 		})
 	}
 }
+
+func TestRespondSessionNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	RespondSessionNotFound(c)
+
+	assert.Equal(t, 404, w.Code)
+
+	var response ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, MsgSessionNotFound, response.Error)
+	assert.Equal(t, CodeSessionNotFound, response.Code)
+}
+
+func TestRespondRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	RespondRateLimited(c, "", 1500)
+
+	assert.Equal(t, 429, w.Code)
+
+	var response ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, MsgRateLimited, response.Error)
+	assert.Equal(t, CodeRateLimited, response.Code)
+	assert.Equal(t, 1500, response.RetryAfter)
+}
+
+func TestRespond_UsesCodeStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	Respond(c, apierror.CodeTakeoverDenied, "Session is already assisted")
+
+	assert.Equal(t, 409, w.Code)
+	assert.JSONEq(t, `{"error":"Session is already assisted","code":"TAKEOVER_DENIED"}`, w.Body.String())
+}
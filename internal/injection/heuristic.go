@@ -0,0 +1,76 @@
+package injection
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// DetectorHeuristic is the name of the built-in heuristic detector
+const DetectorHeuristic = "heuristic"
+
+// rule is a named injection pattern
+type rule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// builtinRules are the phrasings common to injection and jailbreak attempts.
+// They aim at instructions addressed to the model, not at topics, so that
+// users asking about e.g. system prompts in general are not caught.
+var builtinRules = []rule{
+	{"ignore_instructions", regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override|bypass)\b[^.\n]{0,40}\b(previous|prior|above|earlier|preceding|all|any|your|the|system)\b[^.\n]{0,20}\b(instructions?|rules|prompts?|directions|guidelines|constraints)\b`)},
+	{"prompt_leak", regexp.MustCompile(`(?i)\b(reveal|show|print|repeat|output|display|leak)\b[^.\n]{0,40}\b(system|hidden|initial|original|secret)\s+(prompt|instructions?)\b`)},
+	{"role_override", regexp.MustCompile(`(?i)\b(you are now|from now on,? you (are|will)|pretend (to be|you are) an? (unrestricted|unfiltered|jailbroken)|act as an? (unrestricted|unfiltered|jailbroken)|developer mode|do anything now|jailbreak)\b`)},
+	{"new_instructions", regexp.MustCompile(`(?i)\b(new|updated|real|actual|additional)\s+(system\s+)?(instructions|system prompt)\s*:`)},
+	{"fake_delimiter", regexp.MustCompile(`(?im)(<\|im_(start|end)\|>|\[/?INST\]|<</?SYS>>|<\s*/?\s*system\s*>|^\s*#{2,}\s*(system|instructions?)\s*:?\s*$|\bBEGIN (SYSTEM|ADMIN) (PROMPT|INSTRUCTIONS)\b)`)},
+}
+
+// HeuristicDetector detects injections with the built-in rules and any
+// configured patterns, reported as rule "custom"
+type HeuristicDetector struct {
+	rules []rule
+}
+
+// NewHeuristicDetector creates the heuristic detector, adding the given regular
+// expressions to the built-in rules
+func NewHeuristicDetector(patterns []string) (*HeuristicDetector, error) {
+	rules := append([]rule(nil), builtinRules...)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid injection pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, rule{name: "custom", re: re})
+	}
+	return &HeuristicDetector{rules: rules}, nil
+}
+
+// Name returns the detector name
+func (d *HeuristicDetector) Name() string {
+	return DetectorHeuristic
+}
+
+// Detect reports the rules matching content and where they match
+func (d *HeuristicDetector) Detect(_ context.Context, content string) (Verdict, error) {
+	var verdict Verdict
+	seen := make(map[string]bool)
+	for _, r := range d.rules {
+		matches := r.re.FindAllStringIndex(content, -1)
+		// No else needed: early return pattern (rule did not match)
+		if len(matches) == 0 {
+			continue
+		}
+		verdict.Detected = true
+		for _, m := range matches {
+			verdict.Spans = append(verdict.Spans, Span{Start: m[0], End: m[1]})
+		}
+		// No else needed: optional operation (report each rule once)
+		if !seen[r.name] {
+			seen[r.name] = true
+			verdict.Rules = append(verdict.Rules, r.name)
+		}
+	}
+	return verdict, nil
+}
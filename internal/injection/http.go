@@ -0,0 +1,98 @@
+package injection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/jsonhttp"
+)
+
+// DetectorClassifier is the name of the external classifier detector
+const DetectorClassifier = "classifier"
+
+// ErrNoEndpoint is returned when the classifier is created without an endpoint
+var ErrNoEndpoint = errors.New("injection classifier endpoint is required")
+
+// ClassifierConfig holds external injection classifier settings
+type ClassifierConfig struct {
+	Endpoint  string        // URL the text is POSTed to
+	APIKey    string        // Sent as a bearer token when set
+	Threshold float64       // Score from which content is an injection (defaults to constants.DefaultInjectionThreshold)
+	Timeout   time.Duration // HTTP timeout (defaults to constants.DefaultInjectionTimeout)
+}
+
+// ClassifierDetector detects injections with an external classifier. It POSTs
+// {"text"} as JSON and expects {"score"} in return, the probability in [0, 1]
+// that the text is an injection. The classifier cannot say where the
+// injection is, so stripping content it detects blocks it.
+type ClassifierDetector struct {
+	threshold float64
+	client    *jsonhttp.Client
+}
+
+type classifyRequest struct {
+	Text string `json:"text"`
+}
+
+type classifyResponse struct {
+	Score *float64 `json:"score"`
+}
+
+// NewClassifierDetector validates the configuration and creates the detector.
+// The endpoint must use https, except internal hosts which may use http.
+func NewClassifierDetector(cfg ClassifierConfig) (*ClassifierDetector, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.Endpoint == "" {
+		return nil, ErrNoEndpoint
+	}
+	threshold := cfg.Threshold
+	// No else needed: optional operation (apply default)
+	if threshold == 0 {
+		threshold = constants.DefaultInjectionThreshold
+	}
+	// No else needed: early return pattern (guard clause)
+	if threshold < 0 || threshold > 1 {
+		return nil, fmt.Errorf("injection classifier threshold %v must be between 0 and 1", threshold)
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultInjectionTimeout
+	}
+
+	client, err := jsonhttp.New("injection classifier", cfg.Endpoint, cfg.APIKey, timeout)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClassifierDetector{threshold: threshold, client: client}, nil
+}
+
+// Name returns the detector name
+func (d *ClassifierDetector) Name() string {
+	return DetectorClassifier
+}
+
+// Detect sends content to the classifier and reports an injection when its
+// score reaches the threshold
+func (d *ClassifierDetector) Detect(ctx context.Context, content string) (Verdict, error) {
+	var parsed classifyResponse
+	// No else needed: early return pattern (guard clause)
+	if err := d.client.Post(ctx, classifyRequest{Text: content}, &parsed); err != nil {
+		return Verdict{}, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if parsed.Score == nil || *parsed.Score < 0 || *parsed.Score > 1 || math.IsNaN(*parsed.Score) {
+		return Verdict{}, errors.New("classifier response has no score in [0, 1]")
+	}
+	// No else needed: early return pattern (below the threshold)
+	if *parsed.Score < d.threshold {
+		return Verdict{}, nil
+	}
+	return Verdict{Detected: true, Rules: []string{DetectorClassifier}}, nil
+}
@@ -0,0 +1,194 @@
+// Package injection detects prompt injection and jailbreak attempts in content
+// before it reaches the LLM: user messages, and documents returned by the
+// retriever, which may carry instructions planted by whoever wrote them.
+//
+// A Scanner runs every detector over the content: the built-in heuristics,
+// and optionally an external classifier. When one of them detects an
+// injection, the scanner takes the action configured for the content's
+// source: warn (keep the content, annotate and count it), strip (remove the
+// offending lines) or block (reject a user message, drop a document).
+// Detectors that fail are logged and skipped so that detection problems never
+// take the chat down.
+package injection
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+)
+
+// Action is what the scanner does with content an injection was detected in.
+// Actions are ordered by severity.
+type Action int
+
+const (
+	ActionNone  Action = iota // No injection detected
+	ActionWarn                // Content passes unchanged but is annotated and counted
+	ActionStrip               // Lines holding the injection are removed
+	ActionBlock               // User messages are rejected and documents dropped
+)
+
+// String returns the action name used in configuration, metadata and metrics
+func (a Action) String() string {
+	switch a {
+	case ActionWarn:
+		return "warn"
+	case ActionStrip:
+		return "strip"
+	case ActionBlock:
+		return "block"
+	default:
+		return "none"
+	}
+}
+
+// ParseAction parses a configured action name (warn, strip or block)
+func ParseAction(name string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "warn":
+		return ActionWarn, nil
+	case "strip":
+		return ActionStrip, nil
+	case "block":
+		return ActionBlock, nil
+	default:
+		return ActionNone, fmt.Errorf("unknown injection action %q (expected warn, strip or block)", name)
+	}
+}
+
+// Source identifies where scanned content comes from
+type Source string
+
+const (
+	SourceUser     Source = "user"     // User message, scanned before it is stored and sent to the LLM
+	SourceDocument Source = "document" // Retrieved document, scanned before it is given to the LLM
+)
+
+// Span is the byte range [Start, End) of detected content
+type Span struct {
+	Start, End int
+}
+
+// Verdict is a detector's assessment of a piece of content
+type Verdict struct {
+	Detected bool
+	Rules    []string // Why it detected an injection, e.g. matched rule names
+	Spans    []Span   // Where; empty when the detector cannot localize the injection
+}
+
+// Detector looks for injections in content. Implementations must be safe for concurrent use.
+type Detector interface {
+	// Name identifies the detector in logs and results
+	Name() string
+	// Detect assesses content; an error means the detector could not decide
+	Detect(ctx context.Context, content string) (Verdict, error)
+}
+
+// Result is the outcome of scanning content
+type Result struct {
+	Action  Action   // Action taken; ActionNone when nothing was detected
+	Content string   // Content to use from here on; empty when blocked
+	Rules   []string // Rules reported by every detector that triggered
+}
+
+// Scanner runs content through its detectors and applies the configured actions
+type Scanner struct {
+	detectors []Detector
+	actions   map[Source]Action
+	logger    *golog.Logger
+}
+
+// NewScanner creates a scanner that takes userAction on injections in user
+// messages and documentAction on injections in retrieved documents
+func NewScanner(logger *golog.Logger, userAction, documentAction Action, detectors ...Detector) *Scanner {
+	return &Scanner{
+		detectors: detectors,
+		actions:   map[Source]Action{SourceUser: userAction, SourceDocument: documentAction},
+		logger:    logger,
+	}
+}
+
+// Len returns the number of detectors of the scanner
+func (s *Scanner) Len() int {
+	return len(s.detectors)
+}
+
+// Scan runs content through every detector. Stripping removes each line that
+// overlaps a detected span; when a detector cannot localize the injection, or
+// nothing is left, the content is blocked instead. Detectors that return an
+// error are logged and skipped (fail open).
+func (s *Scanner) Scan(ctx context.Context, source Source, content string) *Result {
+	result := &Result{Action: ActionNone, Content: content}
+
+	var spans []Span
+	localized := true
+	for _, detector := range s.detectors {
+		verdict, err := detector.Detect(ctx, content)
+		// No else needed: early return pattern (skip failed detector)
+		if err != nil {
+			util.LogError(s.logger, "injection", "detect injection", err,
+				"detector", detector.Name(),
+				"source", string(source))
+			continue
+		}
+		// No else needed: early return pattern (detector did not trigger)
+		if !verdict.Detected {
+			continue
+		}
+		result.Rules = append(result.Rules, verdict.Rules...)
+		spans = append(spans, verdict.Spans...)
+		localized = localized && len(verdict.Spans) > 0
+	}
+	// No else needed: early return pattern (nothing detected)
+	if len(result.Rules) == 0 {
+		return result
+	}
+
+	result.Action = s.actions[source]
+	// No else needed: optional operation (only strip changes the content here)
+	if result.Action == ActionStrip {
+		result.Content = stripLines(content, spans)
+		// No else needed: optional operation (nothing usable is left)
+		if !localized || strings.TrimSpace(result.Content) == "" {
+			result.Action = ActionBlock
+		}
+	}
+	// No else needed: optional operation (blocked content is not used)
+	if result.Action == ActionBlock {
+		result.Content = ""
+	}
+
+	metrics.InjectionDetections.WithLabelValues(string(source), result.Action.String()).Inc()
+	s.logger.Warn("Prompt injection detected",
+		"source", string(source),
+		"action", result.Action.String(),
+		"rules", result.Rules)
+	return result
+}
+
+// stripLines removes every line of content that overlaps one of spans
+func stripLines(content string, spans []Span) string {
+	var kept []string
+	start := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		end := start + len(line)
+		hit := false
+		for _, span := range spans {
+			// No else needed: optional operation (span overlaps the line)
+			if span.Start < end && span.End > start {
+				hit = true
+				break
+			}
+		}
+		// No else needed: optional operation (keep clean lines)
+		if !hit {
+			kept = append(kept, line)
+		}
+		start = end
+	}
+	return strings.TrimSpace(strings.Join(kept, ""))
+}
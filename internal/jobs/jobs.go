@@ -0,0 +1,310 @@
+// Package jobs runs the service's periodic background tasks, such as the
+// session retention purge and the metrics rollups, on cron-like schedules.
+//
+// Every replica runs a scheduler, and each computes the same run times (slots)
+// from a job's schedule. Before running a slot, a replica claims it with a
+// lock in the shared Store; replicas that lose the race skip the slot, so it
+// runs once across the deployment. Runs are recorded in the store and listed
+// by GET /admin/jobs. Without a store, jobs run on every replica.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+)
+
+// ErrStarted is returned when adding a job to a started scheduler
+var ErrStarted = errors.New("scheduler already started")
+
+// Run statuses
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// Run is one run of a job, as recorded in the run history
+type Run struct {
+	Job        string    `bson:"job" json:"job"`
+	Owner      string    `bson:"owner" json:"owner"`       // Replica that ran the job
+	Slot       time.Time `bson:"slot" json:"scheduled_at"` // Scheduled time the run was for
+	StartedAt  time.Time `bson:"ts" json:"started_at"`
+	FinishedAt time.Time `bson:"endTs" json:"finished_at"`
+	DurationMs int64     `bson:"dur" json:"duration_ms"`
+	Status     string    `bson:"status" json:"status"` // RunSucceeded or RunFailed
+	Error      string    `bson:"err,omitempty" json:"error,omitempty"`
+}
+
+// Job is a named background task run on a schedule
+type Job struct {
+	Name     string
+	Schedule string                          // See ParseSchedule
+	Timeout  time.Duration                   // Longest a run may take (defaults to constants.DefaultJobTimeout)
+	Run      func(ctx context.Context) error // Must return when ctx is done
+}
+
+// Store coordinates replicas and keeps the run history
+// (implemented by storage.StorageService)
+type Store interface {
+	AcquireJobLock(job, owner string, slot, until time.Time) (bool, error)
+	ReleaseJobLock(job, owner string, slot time.Time) error
+	RecordJobRun(run *Run) error
+	ListJobRuns(job string, limit int) ([]*Run, error)
+}
+
+// Status describes a job for the admin status endpoint
+type Status struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"next_run"`
+	// Running reports a run in progress on this replica
+	Running bool `json:"running"`
+	// RecentRuns are the latest runs on any replica, newest first
+	RecentRuns []*Run `json:"recent_runs"`
+}
+
+// scheduled is a job added to the scheduler
+type scheduled struct {
+	job      Job
+	schedule Schedule
+
+	mu      sync.Mutex
+	next    time.Time
+	running bool
+	last    *Run // Latest run on this replica, listed when there is no store
+}
+
+// Scheduler runs jobs on their schedules until stopped
+type Scheduler struct {
+	store  Store  // nil runs every slot locally
+	owner  string // Identifies this replica in locks and run history
+	logger *golog.Logger
+
+	jobs    []*scheduled
+	started bool
+	mu      sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler for the replica owner. store may be nil.
+func NewScheduler(store Store, owner string, logger *golog.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		store:  store,
+		owner:  owner,
+		logger: logger.WithGroup("jobs"),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Add registers a job. Jobs must be added before Start.
+func (s *Scheduler) Add(job Job) error {
+	schedule, err := ParseSchedule(job.Schedule)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	// No else needed: optional operation (apply default)
+	if job.Timeout <= 0 {
+		job.Timeout = constants.DefaultJobTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// No else needed: early return pattern (guard clause)
+	if s.started {
+		return ErrStarted
+	}
+	for _, existing := range s.jobs {
+		// No else needed: early return pattern (guard clause)
+		if existing.job.Name == job.Name {
+			return fmt.Errorf("job %s is already scheduled", job.Name)
+		}
+	}
+	s.jobs = append(s.jobs, &scheduled{job: job, schedule: schedule, next: schedule.Next(time.Now())})
+	return nil
+}
+
+// Len returns the number of scheduled jobs
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
+
+// Start starts running the jobs. Safe to call once; later calls do nothing.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// No else needed: early return pattern (already started)
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		util.SafeGo(s.logger, "job-"+j.job.Name, func() {
+			defer s.wg.Done()
+			s.loop(j)
+		})
+	}
+}
+
+// Stop cancels runs in progress and waits for the jobs to return.
+// Safe to call multiple times and when the scheduler was never started.
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// loop waits for each slot of a job and runs it
+func (s *Scheduler) loop(j *scheduled) {
+	for {
+		slot := j.schedule.Next(time.Now())
+		j.mu.Lock()
+		j.next = slot
+		j.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(slot))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.runSlot(j, slot)
+	}
+}
+
+// runSlot runs a job for slot, unless another replica claimed the slot
+func (s *Scheduler) runSlot(j *scheduled, slot time.Time) {
+	name := j.job.Name
+	// No else needed: optional operation (coordinate replicas when shared)
+	if s.store != nil {
+		acquired, err := s.store.AcquireJobLock(name, s.owner, slot, time.Now().Add(j.job.Timeout))
+		// No else needed: early return pattern (skip the slot, the next one retries)
+		if err != nil {
+			util.LogError(s.logger, "jobs", "acquire job lock", err, "job", name)
+			metrics.JobRuns.WithLabelValues(name, "skipped").Inc()
+			return
+		}
+		// No else needed: early return pattern (another replica runs the slot)
+		if !acquired {
+			s.logger.Debug("Job slot claimed by another replica", "job", name, "slot", slot)
+			metrics.JobRuns.WithLabelValues(name, "skipped").Inc()
+			return
+		}
+	}
+
+	j.mu.Lock()
+	j.running = true
+	j.mu.Unlock()
+
+	run := s.run(j, slot)
+
+	j.mu.Lock()
+	j.running = false
+	j.last = run
+	j.mu.Unlock()
+
+	// No else needed: early return pattern (no shared store)
+	if s.store == nil {
+		return
+	}
+	// No else needed: optional operation (history is best effort)
+	if err := s.store.RecordJobRun(run); err != nil {
+		util.LogError(s.logger, "jobs", "record job run", err, "job", name)
+	}
+	// No else needed: optional operation (the lock expires at the timeout anyway)
+	if err := s.store.ReleaseJobLock(name, s.owner, slot); err != nil {
+		util.LogError(s.logger, "jobs", "release job lock", err, "job", name)
+	}
+}
+
+// run calls the job with its timeout, turning a panic into a failed run
+func (s *Scheduler) run(j *scheduled, slot time.Time) *Run {
+	ctx, cancel := context.WithTimeout(s.ctx, j.job.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	err := func() (err error) {
+		defer func() {
+			// No else needed: optional operation (only on panic)
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return j.job.Run(ctx)
+	}()
+	finished := time.Now()
+
+	run := &Run{
+		Job:        j.job.Name,
+		Owner:      s.owner,
+		Slot:       slot.UTC(),
+		StartedAt:  started.UTC(),
+		FinishedAt: finished.UTC(),
+		DurationMs: finished.Sub(started).Milliseconds(),
+		Status:     RunSucceeded,
+	}
+	metrics.JobDuration.WithLabelValues(j.job.Name).Observe(finished.Sub(started).Seconds())
+	// No else needed: early return pattern (successful run)
+	if err == nil {
+		metrics.JobRuns.WithLabelValues(j.job.Name, RunSucceeded).Inc()
+		return run
+	}
+	run.Status = RunFailed
+	run.Error = err.Error()
+	metrics.JobRuns.WithLabelValues(j.job.Name, RunFailed).Inc()
+	util.LogError(s.logger, "jobs", "run job", err, "job", j.job.Name)
+	return run
+}
+
+// Status returns the status of every job, with its recent runs on any replica
+// when there is a store, or its latest run on this replica otherwise
+func (s *Scheduler) Status() ([]Status, error) {
+	s.mu.Lock()
+	jobs := append([]*scheduled(nil), s.jobs...)
+	s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(jobs))
+	for _, j := range jobs {
+		j.mu.Lock()
+		status := Status{
+			Name:       j.job.Name,
+			Schedule:   j.job.Schedule,
+			NextRun:    j.next.UTC(),
+			Running:    j.running,
+			RecentRuns: []*Run{},
+		}
+		// No else needed: optional operation (local history without a store)
+		if j.last != nil {
+			status.RecentRuns = append(status.RecentRuns, j.last)
+		}
+		j.mu.Unlock()
+
+		// No else needed: optional operation (shared history)
+		if s.store != nil {
+			runs, err := s.store.ListJobRuns(j.job.Name, constants.JobStatusRuns)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				return nil, err
+			}
+			status.RecentRuns = runs
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,199 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestLogger() *golog.Logger {
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            "/tmp/chatbox-jobs-test-logs",
+		Level:          "error",
+		StandardOutput: false,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize test logger: %v", err))
+	}
+	return logger
+}
+
+// memoryStore implements Store with the lock rules of storage.AcquireJobLock
+type memoryStore struct {
+	mu    sync.Mutex
+	slots map[string]time.Time
+	until map[string]time.Time
+	runs  []*Run
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{slots: make(map[string]time.Time), until: make(map[string]time.Time)}
+}
+
+func (m *memoryStore) AcquireJobLock(job, _ string, slot, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if held, ok := m.slots[job]; ok && (!held.Before(slot) || !m.until[job].Before(time.Now())) {
+		return false, nil
+	}
+	m.slots[job] = slot
+	m.until[job] = until
+	return true, nil
+}
+
+func (m *memoryStore) ReleaseJobLock(job, _ string, _ time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.until[job] = time.Now().Add(-time.Nanosecond)
+	return nil
+}
+
+func (m *memoryStore) RecordJobRun(run *Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+func (m *memoryStore) ListJobRuns(job string, limit int) ([]*Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	runs := []*Run{}
+	for i := len(m.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		if m.runs[i].Job == job {
+			runs = append(runs, m.runs[i])
+		}
+	}
+	return runs, nil
+}
+
+func TestScheduler_SlotRunsOnceAcrossReplicas(t *testing.T) {
+	store := newMemoryStore()
+	calls := 0
+	job := Job{Name: "purge", Schedule: "@every 1h", Run: func(context.Context) error {
+		calls++
+		return nil
+	}}
+
+	a := NewScheduler(store, "replica-a", createTestLogger())
+	b := NewScheduler(store, "replica-b", createTestLogger())
+	require.NoError(t, a.Add(job))
+	require.NoError(t, b.Add(job))
+
+	slot := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	a.runSlot(a.jobs[0], slot)
+	b.runSlot(b.jobs[0], slot)
+	assert.Equal(t, 1, calls)
+
+	// The next slot runs again, on whichever replica claims it first
+	b.runSlot(b.jobs[0], slot.Add(time.Hour))
+	assert.Equal(t, 2, calls)
+
+	require.Len(t, store.runs, 2)
+	assert.Equal(t, "replica-a", store.runs[0].Owner)
+	assert.Equal(t, "replica-b", store.runs[1].Owner)
+	assert.Equal(t, RunSucceeded, store.runs[1].Status)
+	assert.Equal(t, slot.Add(time.Hour), store.runs[1].Slot)
+}
+
+func TestScheduler_FailuresAndPanicsAreRecorded(t *testing.T) {
+	store := newMemoryStore()
+	s := NewScheduler(store, "replica-a", createTestLogger())
+	require.NoError(t, s.Add(Job{Name: "fails", Schedule: "@hourly", Run: func(context.Context) error {
+		return errors.New("mongo unavailable")
+	}}))
+	require.NoError(t, s.Add(Job{Name: "panics", Schedule: "@hourly", Run: func(context.Context) error {
+		panic("boom")
+	}}))
+
+	slot := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	s.runSlot(s.jobs[0], slot)
+	s.runSlot(s.jobs[1], slot)
+
+	require.Len(t, store.runs, 2)
+	assert.Equal(t, RunFailed, store.runs[0].Status)
+	assert.Equal(t, "mongo unavailable", store.runs[0].Error)
+	assert.Equal(t, RunFailed, store.runs[1].Status)
+	assert.Equal(t, "panic: boom", store.runs[1].Error)
+}
+
+func TestScheduler_Status(t *testing.T) {
+	store := newMemoryStore()
+	s := NewScheduler(store, "replica-a", createTestLogger())
+	require.NoError(t, s.Add(Job{Name: "rollup", Schedule: "@every 15m", Run: func(context.Context) error { return nil }}))
+
+	statuses, err := s.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "rollup", statuses[0].Name)
+	assert.Equal(t, "@every 15m", statuses[0].Schedule)
+	assert.True(t, statuses[0].NextRun.After(time.Now()))
+	assert.Empty(t, statuses[0].RecentRuns)
+
+	s.runSlot(s.jobs[0], time.Now().Truncate(15*time.Minute))
+	statuses, err = s.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses[0].RecentRuns, 1)
+	assert.Equal(t, RunSucceeded, statuses[0].RecentRuns[0].Status)
+}
+
+func TestScheduler_WithoutStoreRunsLocally(t *testing.T) {
+	s := NewScheduler(nil, "replica-a", createTestLogger())
+	calls := 0
+	require.NoError(t, s.Add(Job{Name: "local", Schedule: "@hourly", Run: func(context.Context) error {
+		calls++
+		return nil
+	}}))
+
+	slot := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	s.runSlot(s.jobs[0], slot)
+	s.runSlot(s.jobs[0], slot)
+	assert.Equal(t, 2, calls)
+
+	statuses, err := s.Status()
+	require.NoError(t, err)
+	assert.Len(t, statuses[0].RecentRuns, 1, "the latest local run")
+}
+
+func TestScheduler_Add(t *testing.T) {
+	s := NewScheduler(nil, "replica-a", createTestLogger())
+	run := func(context.Context) error { return nil }
+	require.NoError(t, s.Add(Job{Name: "a", Schedule: "@hourly", Run: run}))
+	assert.Error(t, s.Add(Job{Name: "a", Schedule: "@daily", Run: run}), "duplicate name")
+	assert.Error(t, s.Add(Job{Name: "b", Schedule: "@every 1s", Run: run}), "too frequent")
+
+	s.Start()
+	defer s.Stop()
+	assert.ErrorIs(t, s.Add(Job{Name: "c", Schedule: "@hourly", Run: run}), ErrStarted)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestScheduler_StopCancelsRuns(t *testing.T) {
+	s := NewScheduler(nil, "replica-a", createTestLogger())
+	started := make(chan struct{})
+	require.NoError(t, s.Add(Job{Name: "slow", Schedule: "@hourly", Run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}}))
+
+	done := make(chan struct{})
+	go func() {
+		s.runSlot(s.jobs[0], time.Now())
+		close(done)
+	}()
+	<-started
+	s.Stop()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("run was not cancelled")
+	}
+}
@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// scheduleHorizon is how far ahead Next looks for a matching time
+const scheduleHorizon = 5 * 366 * 24 * time.Hour
+
+// Schedule computes when a job runs. Times are in UTC.
+type Schedule interface {
+	// Next returns the first run time strictly after t
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a job schedule:
+//   - "@every <duration>", e.g. "@every 15m": every interval, at multiples of
+//     the interval so that every replica computes the same run times
+//   - "@hourly", "@daily", "@weekly": shorthands for "0 * * * *", "0 0 * * *"
+//     and "0 0 * * 0"
+//   - a cron expression "minute hour day-of-month month day-of-week", each
+//     field "*", a value, a range "a-b", a step "*/n" or "a-b/n", or a comma
+//     separated list of those. Day of week 0 and 7 are Sunday.
+//
+// Runs must be at least constants.MinJobInterval apart.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	}
+
+	// No else needed: early return pattern (fixed interval)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		// No else needed: early return pattern (guard clause)
+		if interval < constants.MinJobInterval {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be at least %s", spec, constants.MinJobInterval)
+		}
+		return every(interval), nil
+	}
+
+	fields := strings.Fields(spec)
+	// No else needed: early return pattern (guard clause)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected @every <duration> or 5 cron fields", spec)
+	}
+	var c cron
+	var err error
+	bounds := []struct {
+		set      *uint64
+		min, max int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dom, 1, 31},
+		{&c.month, 1, 12},
+		{&c.dow, 0, 7},
+	}
+	for i, b := range bounds {
+		*b.set, err = parseField(fields[i], b.min, b.max)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	// No else needed: optional operation (7 is another name for Sunday)
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+
+	// No else needed: early return pattern (guard clause)
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid schedule %q: never runs", spec)
+	}
+	return &c, nil
+}
+
+// every runs at the multiples of an interval
+type every time.Duration
+
+// Next returns the first multiple of the interval after t
+func (e every) Next(t time.Time) time.Time {
+	interval := time.Duration(e)
+	return t.UTC().Truncate(interval).Add(interval)
+}
+
+// cron matches times against the bit sets of a cron expression
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// Next returns the first matching minute after t, or the zero time when there
+// is none within the next five years (e.g. "0 0 30 2 *")
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(scheduleHorizon)
+	for t.Before(limit) {
+		// No else needed: early return pattern (skip to the next month)
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		// No else needed: early return pattern (skip to the next day)
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		// No else needed: early return pattern (skip to the next hour)
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		// No else needed: early return pattern (skip to the next minute)
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the cron rule for days: when both the day of month and
+// the day of week are restricted, a day matching either runs
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	// No else needed: early return pattern (both restricted)
+	if !c.domAny && !c.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// parseField parses a cron field into a bit set of the values in [min, max]
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		// No else needed: optional operation (stepped range)
+		if base, stepStr, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(stepStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = base, n
+		}
+
+		lo, hi := min, max
+		// No else needed: optional operation ("*" is the full range)
+		if rangePart != "*" {
+			var err error
+			loStr, hiStr, isRange := strings.Cut(rangePart, "-")
+			lo, err = strconv.Atoi(loStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			// No else needed: optional operation (range)
+			if isRange {
+				hi, err = strconv.Atoi(hiStr)
+				// No else needed: early return pattern (guard clause)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %q", part)
+				}
+			}
+			// No else needed: optional operation ("a/n" runs from a to the end of the range)
+			if !isRange && step > 1 {
+				hi = max
+			}
+		}
+		// No else needed: early return pattern (guard clause)
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,93 @@
+// Package jsonhttp is the client for the external JSON services the chatbox
+// calls: moderation, retrieval, sentiment analysis, transcription, the code
+// sandbox and the injection classifier. Each call POSTs a JSON body and expects
+// a JSON answer with status 200.
+package jsonhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+)
+
+// Client POSTs JSON to one service endpoint
+type Client struct {
+	name     string // Names the service in errors, e.g. "retrieval"
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// New validates the endpoint and creates a client for the service called name.
+// The endpoint must use https, except internal hosts which may use http. The
+// API key is sent as a bearer token when set.
+func New(name, endpoint, apiKey string, timeout time.Duration) (*Client, error) {
+	// No else needed: early return pattern (guard clause)
+	if err := llm.ValidateEndpoint(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid %s endpoint %q: %w", name, endpoint, err)
+	}
+
+	return &Client{
+		name:     name,
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Endpoint returns the URL requests are sent to
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// Timeout returns the HTTP timeout of each request
+func (c *Client) Timeout() time.Duration {
+	return c.client.Timeout
+}
+
+// Post sends in as JSON and decodes the response into out. A status other
+// than 200 is an error carrying the start of the response body.
+func (c *Client) Post(ctx context.Context, in, out any) error {
+	body, err := json.Marshal(in)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", c.name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", c.name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// No else needed: optional operation (unauthenticated services)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+
+	// No else needed: early return pattern (guard clause)
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, constants.MaxErrorBodyBytes))
+		return fmt.Errorf("%s service returned status %d: %s", c.name, resp.StatusCode, errBody)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", c.name, err)
+	}
+	return nil
+}
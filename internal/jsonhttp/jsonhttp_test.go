@@ -0,0 +1,76 @@
+package jsonhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_ValidatesEndpoint(t *testing.T) {
+	_, err := New("retrieval", "http://search.example.com/query", "", time.Second)
+	assert.ErrorContains(t, err, "invalid retrieval endpoint", "public endpoints must use https")
+
+	c, err := New("retrieval", "https://search.example.com/query", "", 3*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "https://search.example.com/query", c.Endpoint())
+	assert.Equal(t, 3*time.Second, c.Timeout())
+}
+
+func TestClient_Post(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var in map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		_, _ = w.Write([]byte(`{"echo":"` + in["text"] + `"}`))
+	}))
+	defer server.Close()
+
+	c, err := New("sentiment", server.URL, "test-key", time.Second)
+	require.NoError(t, err)
+
+	var out struct {
+		Echo string `json:"echo"`
+	}
+	require.NoError(t, c.Post(context.Background(), map[string]string{"text": "hello"}, &out))
+	assert.Equal(t, "hello", out.Echo)
+}
+
+func TestClient_PostErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"), "no API key configured")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("overloaded" + strings.Repeat("x", 2*constants.MaxErrorBodyBytes)))
+	}))
+	defer server.Close()
+
+	c, err := New("sandbox", server.URL, "", time.Second)
+	require.NoError(t, err)
+
+	err = c.Post(context.Background(), struct{}{}, &struct{}{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sandbox service returned status 503: overloaded")
+	assert.Less(t, len(err.Error()), 2*constants.MaxErrorBodyBytes, "the error body is truncated")
+}
+
+func TestClient_PostInvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	c, err := New("transcription", server.URL, "", time.Second)
+	require.NoError(t, err)
+
+	err = c.Post(context.Background(), struct{}{}, &struct{}{})
+	assert.ErrorContains(t, err, "failed to decode transcription response")
+}
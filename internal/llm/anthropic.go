@@ -13,6 +13,7 @@ import (
 
 	"github.com/real-rm/chatbox/internal/constants"
 	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/tokenizer"
 	"github.com/real-rm/golog"
 )
 
@@ -45,10 +46,12 @@ func NewAnthropicProvider(apiKey, endpoint, model string, logger *golog.Logger)
 
 // anthropicRequest represents the request format for Anthropic API
 type anthropicRequest struct {
-	Model     string             `json:"model"`
-	Messages  []anthropicMessage `json:"messages"`
-	MaxTokens int                `json:"max_tokens"`
-	Stream    bool               `json:"stream"`
+	Model       string             `json:"model"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Stream      bool               `json:"stream"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        *float64           `json:"top_p,omitempty"`
 }
 
 type anthropicMessage struct {
@@ -91,6 +94,15 @@ type anthropicDelta struct {
 	Text string `json:"text"`
 }
 
+// maxTokensOrDefault returns the requested max tokens, or the Anthropic default
+// when unset (the Anthropic API requires max_tokens on every request)
+func maxTokensOrDefault(maxTokens int) int {
+	if maxTokens > 0 {
+		return maxTokens
+	}
+	return constants.DefaultAnthropicMaxTokens
+}
+
 // SendMessage sends a message to Anthropic and returns the complete response
 func (p *AnthropicProvider) SendMessage(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
 	startTime := time.Now()
@@ -113,10 +125,12 @@ func (p *AnthropicProvider) SendMessage(ctx context.Context, req *LLMRequest) (*
 
 	// Create request body
 	reqBody := anthropicRequest{
-		Model:     p.model,
-		Messages:  messages,
-		MaxTokens: constants.DefaultAnthropicMaxTokens,
-		Stream:    false,
+		Model:       p.model,
+		Messages:    messages,
+		MaxTokens:   maxTokensOrDefault(req.MaxTokens),
+		Stream:      false,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -183,10 +197,12 @@ func (p *AnthropicProvider) StreamMessage(ctx context.Context, req *LLMRequest)
 
 	// Create request body
 	reqBody := anthropicRequest{
-		Model:     p.model,
-		Messages:  messages,
-		MaxTokens: constants.DefaultAnthropicMaxTokens,
-		Stream:    true,
+		Model:       p.model,
+		Messages:    messages,
+		MaxTokens:   maxTokensOrDefault(req.MaxTokens),
+		Stream:      true,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -288,9 +304,8 @@ func (p *AnthropicProvider) StreamMessage(ctx context.Context, req *LLMRequest)
 	return chunkChan, nil
 }
 
-// GetTokenCount estimates the token count for the given text
-// This is a simple approximation: ~4 characters per token for English text
+// GetTokenCount estimates the token count for the given text with the
+// default counter of the Anthropic model family (see tokenizer.ForProvider)
 func (p *AnthropicProvider) GetTokenCount(text string) int {
-	// Simple approximation: 1 token ≈ 4 characters
-	return len(text) / 4
+	return tokenizer.ForProvider("anthropic").Count(text)
 }
@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/tokenizer"
+	"github.com/real-rm/goconfig"
+)
+
+// ModelCatalog is a parsed model catalog ready to replace the service's, see
+// LLMService.ParseCatalog
+type ModelCatalog struct {
+	models    []ModelInfo
+	providers map[string]LLMProvider       // Map of model ID to the instance of its provider
+	counters  map[string]tokenizer.Counter // Map of model ID to the token counter overriding its provider's
+}
+
+// Models returns the models of the catalog sorted by ID
+func (c *ModelCatalog) Models() []ModelInfo {
+	return c.models
+}
+
+// ParseCatalog loads the model catalog from the [chatbox.models.<id>] tables of
+// cfg without applying it. The models must be served by the providers the
+// service was created with; providers are not reloaded.
+func (s *LLMService) ParseCatalog(cfg *goconfig.ConfigAccessor) (*ModelCatalog, error) {
+	models, err := loadModelCatalog(cfg, s.providerConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load model catalog: %w", err)
+	}
+	if len(models) == 0 {
+		for _, providerCfg := range s.providerConfigs {
+			models = append(models, ModelInfo{
+				ID:       providerCfg.ID,
+				Name:     providerCfg.Name,
+				Type:     providerCfg.Type,
+				Endpoint: providerCfg.Endpoint,
+				Provider: providerCfg.ID,
+			})
+		}
+	}
+
+	catalog := &ModelCatalog{
+		models:    models,
+		providers: make(map[string]LLMProvider, len(models)),
+		counters:  make(map[string]tokenizer.Counter),
+	}
+	for _, model := range models {
+		catalog.providers[model.ID] = s.instances[model.Provider]
+		if model.Tokenizer != "" || model.CharsPerToken > 0 {
+			counter, err := tokenizer.New(model.Tokenizer, model.Type, model.CharsPerToken)
+			if err != nil {
+				return nil, fmt.Errorf("model %s: %w", model.ID, err)
+			}
+			catalog.counters[model.ID] = counter
+		}
+	}
+	return catalog, nil
+}
+
+// SetCatalog replaces the model catalog at once. Requests already sent to a
+// model are not affected; sessions whose model was removed use the default
+// model from their next message.
+func (s *LLMService) SetCatalog(catalog *ModelCatalog) {
+	models := make(map[string]ModelInfo, len(catalog.models))
+	for _, model := range catalog.models {
+		models[model.ID] = model
+		s.logger.Info("Registered model", "model_id", model.ID, "provider_id", model.Provider)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = models
+	s.providers = catalog.providers
+	s.counters = catalog.counters
+}
+
+// loadModelCatalog loads the model catalog from the [chatbox.models.<id>] config tables.
+// Returns an empty catalog when none are configured; every provider is then
+// offered as a model with the provider's defaults.
+func loadModelCatalog(cfg *goconfig.ConfigAccessor, providers []LLMProviderConfig) ([]ModelInfo, error) {
+	raw, err := cfg.Config("chatbox.models")
+	if err != nil || raw == nil {
+		return []ModelInfo{}, nil
+	}
+	return parseModelCatalog(raw, providers)
+}
+
+// parseModelCatalog converts the raw [chatbox.models] config value into catalog entries
+// sorted by model ID. Each entry must reference a configured provider by ID.
+func parseModelCatalog(raw interface{}, providers []LLMProviderConfig) ([]ModelInfo, error) {
+	tables, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("chatbox.models is not a table")
+	}
+
+	byID := make(map[string]LLMProviderConfig, len(providers))
+	for _, p := range providers {
+		byID[p.ID] = p
+	}
+
+	models := make([]ModelInfo, 0, len(tables))
+	for id, value := range tables {
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("model %s: not a table", id)
+		}
+
+		providerID := getStringFromMap(table, "provider")
+		provider, exists := byID[providerID]
+		if !exists {
+			return nil, fmt.Errorf("model %s: unknown provider %q", id, providerID)
+		}
+
+		model := ModelInfo{
+			ID:           id,
+			Name:         getStringFromMap(table, "name"),
+			Type:         provider.Type,
+			Endpoint:     provider.Endpoint,
+			Provider:     providerID,
+			SystemPrompt: getStringFromMap(table, "system_prompt"),
+		}
+		if model.Name == "" {
+			model.Name = id
+		}
+
+		if v, exists := table["max_tokens"]; exists {
+			maxTokens, ok := v.(int64)
+			if !ok || maxTokens <= 0 {
+				return nil, fmt.Errorf("model %s: max_tokens must be a positive integer", id)
+			}
+			model.MaxTokens = int(maxTokens)
+		}
+
+		if v, exists := table["max_tokens_limit"]; exists {
+			limit, ok := v.(int64)
+			if !ok || limit <= 0 {
+				return nil, fmt.Errorf("model %s: max_tokens_limit must be a positive integer", id)
+			}
+			model.MaxTokensLimit = int(limit)
+		}
+
+		if v, exists := table["max_temperature"]; exists {
+			maxTemperature, ok := toFloat(v)
+			if !ok || maxTemperature <= 0 || maxTemperature > constants.MaxModelTemperature {
+				return nil, fmt.Errorf("model %s: max_temperature must be greater than 0 and at most %g", id, constants.MaxModelTemperature)
+			}
+			model.MaxTemperature = maxTemperature
+		}
+
+		if v, exists := table["temperature"]; exists {
+			var temperature float64
+			switch t := v.(type) {
+			case float64:
+				temperature = t
+			case int64:
+				temperature = float64(t)
+			default:
+				return nil, fmt.Errorf("model %s: temperature must be a number", id)
+			}
+			if temperature < 0 || temperature > constants.MaxModelTemperature {
+				return nil, fmt.Errorf("model %s: temperature must be between 0 and %g", id, constants.MaxModelTemperature)
+			}
+			model.Temperature = &temperature
+		}
+
+		model.Tokenizer = getStringFromMap(table, "tokenizer")
+		if v, exists := table["chars_per_token"]; exists {
+			charsPerToken, ok := toFloat(v)
+			if !ok || charsPerToken <= 0 {
+				return nil, fmt.Errorf("model %s: chars_per_token must be a positive number", id)
+			}
+			model.CharsPerToken = charsPerToken
+		}
+		if _, err := tokenizer.New(model.Tokenizer, model.Type, model.CharsPerToken); err != nil {
+			return nil, fmt.Errorf("model %s: %w", id, err)
+		}
+
+		models = append(models, model)
+	}
+
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// newRequest builds a provider request for modelID, applying the model's catalog
+// settings: the system prompt is prepended to the conversation, and max tokens and
+// temperature override the provider defaults. Session parameters set on ctx with
+// WithParams override the catalog settings. A request continuing the thread set
+// on ctx with WithThread leaves out the system prompt, which the thread already
+// holds.
+func (s *LLMService) newRequest(ctx context.Context, modelID string, messages []ChatMessage, stream bool) *LLMRequest {
+	s.mu.RLock()
+	model := s.models[modelID]
+	s.mu.RUnlock()
+
+	threadID := threadFromContext(ctx, modelID)
+	if model.SystemPrompt != "" && threadID == "" {
+		withPrompt := make([]ChatMessage, 0, len(messages)+1)
+		withPrompt = append(withPrompt, ChatMessage{Role: constants.SenderSystem, Content: model.SystemPrompt})
+		messages = append(withPrompt, messages...)
+	}
+
+	req := &LLMRequest{
+		ModelID:     modelID,
+		Messages:    messages,
+		Stream:      stream,
+		MaxTokens:   model.MaxTokens,
+		Temperature: model.Temperature,
+		ThreadID:    threadID,
+	}
+	applyParams(req, model, paramsFromContext(ctx))
+	return req
+}
+
+// toFloat converts a TOML number, which may be decoded as an integer, to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
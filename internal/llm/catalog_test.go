@@ -0,0 +1,196 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/tokenizer"
+	"github.com/real-rm/goconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var catalogTestProviders = []LLMProviderConfig{
+	{ID: "openai-main", Name: "OpenAI", Type: "openai", Endpoint: "https://api.openai.com/v1"},
+	{ID: "claude", Name: "Claude", Type: "anthropic", Endpoint: "https://api.anthropic.com/v1"},
+}
+
+func TestParseModelCatalog(t *testing.T) {
+	raw := map[string]interface{}{
+		"gpt-creative": map[string]interface{}{
+			"name":             "GPT (creative)",
+			"provider":         "openai-main",
+			"max_tokens":       int64(2048),
+			"temperature":      0.9,
+			"system_prompt":    "Be imaginative.",
+			"max_tokens_limit": int64(8192),
+			"max_temperature":  int64(1),
+		},
+		"claude-default": map[string]interface{}{
+			"provider":    "claude",
+			"temperature": int64(1),
+		},
+	}
+
+	models, err := parseModelCatalog(raw, catalogTestProviders)
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+
+	// Sorted by ID
+	assert.Equal(t, "claude-default", models[0].ID)
+	assert.Equal(t, "claude-default", models[0].Name, "name defaults to the ID")
+	assert.Equal(t, "anthropic", models[0].Type)
+	assert.Zero(t, models[0].MaxTokens)
+	require.NotNil(t, models[0].Temperature)
+	assert.Equal(t, 1.0, *models[0].Temperature)
+
+	assert.Equal(t, "gpt-creative", models[1].ID)
+	assert.Equal(t, "GPT (creative)", models[1].Name)
+	assert.Equal(t, "openai-main", models[1].Provider)
+	assert.Equal(t, "openai", models[1].Type)
+	assert.Equal(t, 2048, models[1].MaxTokens)
+	assert.Equal(t, 0.9, *models[1].Temperature)
+	assert.Equal(t, "Be imaginative.", models[1].SystemPrompt)
+	assert.Equal(t, 8192, models[1].MaxTokensLimit)
+	assert.Equal(t, 1.0, models[1].MaxTemperature)
+}
+
+func TestParseModelCatalog_Errors(t *testing.T) {
+	tests := map[string]interface{}{
+		"not a table":      "gpt-4",
+		"entry not table":  map[string]interface{}{"m": "x"},
+		"unknown provider": map[string]interface{}{"m": map[string]interface{}{"provider": "missing"}},
+		"bad max tokens":   map[string]interface{}{"m": map[string]interface{}{"provider": "claude", "max_tokens": int64(0)}},
+		"bad temperature":  map[string]interface{}{"m": map[string]interface{}{"provider": "claude", "temperature": 2.5}},
+		"temperature text": map[string]interface{}{"m": map[string]interface{}{"provider": "claude", "temperature": "hot"}},
+		"bad token limit":  map[string]interface{}{"m": map[string]interface{}{"provider": "claude", "max_tokens_limit": int64(-1)}},
+		"bad max temp":     map[string]interface{}{"m": map[string]interface{}{"provider": "claude", "max_temperature": 3.0}},
+		"bad tokenizer":    map[string]interface{}{"m": map[string]interface{}{"provider": "claude", "tokenizer": "sentencepiece"}},
+		"bad chars/token":  map[string]interface{}{"m": map[string]interface{}{"provider": "claude", "chars_per_token": int64(0)}},
+	}
+	for name, raw := range tests {
+		_, err := parseModelCatalog(raw, catalogTestProviders)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLLMService_AppliesModelSettings(t *testing.T) {
+	var got openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_ = json.NewEncoder(w).Encode(openAIResponse{
+			Choices: []openAIChoice{{Message: openAIMessage{Role: "assistant", Content: "ok"}}},
+		})
+	}))
+	defer server.Close()
+
+	temperature := 0.2
+	svc := newTestService(t)
+	svc.models["precise"] = ModelInfo{
+		ID:           "precise",
+		Type:         "openai",
+		Provider:     "openai-main",
+		MaxTokens:    256,
+		Temperature:  &temperature,
+		SystemPrompt: "Answer briefly.",
+	}
+	svc.providers["precise"] = NewOpenAIProvider("test-key", server.URL, "gpt-4o", createTestLogger())
+
+	_, err := svc.SendMessage(context.Background(), "precise", []ChatMessage{{Role: "user", Content: "Hi"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, 256, got.MaxTokens)
+	require.NotNil(t, got.Temperature)
+	assert.Equal(t, 0.2, *got.Temperature)
+	require.Len(t, got.Messages, 2)
+	assert.Equal(t, "system", got.Messages[0].Role)
+	assert.Equal(t, "Answer briefly.", got.Messages[0].Content)
+	assert.Equal(t, "Hi", got.Messages[1].Content)
+}
+
+func TestParseModelCatalog_Tokenizer(t *testing.T) {
+	raw := map[string]interface{}{
+		"claude-tiktoken": map[string]interface{}{"provider": "claude", "tokenizer": "tiktoken"},
+		"claude-dense":    map[string]interface{}{"provider": "claude", "chars_per_token": int64(3)},
+	}
+
+	models, err := parseModelCatalog(raw, catalogTestProviders)
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, 3.0, models[0].CharsPerToken)
+	assert.Empty(t, models[0].Tokenizer)
+	assert.Equal(t, "tiktoken", models[1].Tokenizer)
+}
+
+func TestLLMService_GetTokenCount_ModelTokenizer(t *testing.T) {
+	svc := newTestServiceWithModel(t, "claude", "anthropic", &MockLLMProvider{
+		getTokenCountFunc: func(text string) int { return 100 },
+	})
+
+	// Without an override the provider counts
+	count, err := svc.GetTokenCount("claude", "Hello, world!")
+	require.NoError(t, err)
+	assert.Equal(t, 100, count)
+
+	// A configured counter takes precedence
+	svc.counters = map[string]tokenizer.Counter{"claude": tokenizer.Heuristic{CharsPerToken: 2}}
+	count, err = svc.GetTokenCount("claude", "Hello, world!")
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+}
+
+// loadCatalogConfig loads a configuration holding only content
+func loadCatalogConfig(t *testing.T, content string) *goconfig.ConfigAccessor {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	goconfig.ResetConfig()
+	t.Setenv("RMBASE_FILE_CFG", path)
+	t.Cleanup(func() { goconfig.ResetConfig() })
+	require.NoError(t, goconfig.LoadConfig())
+	cfg, err := goconfig.Default()
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestLLMService_ReplaceCatalog(t *testing.T) {
+	svc := newTestService(t)
+	openai := &MockLLMProvider{}
+	svc.providerConfigs = catalogTestProviders
+	svc.instances = map[string]LLMProvider{"openai-main": openai, "claude": &MockLLMProvider{}}
+
+	catalog, err := svc.ParseCatalog(loadCatalogConfig(t, `
+[chatbox.models.precise]
+provider = "openai-main"
+tokenizer = "heuristic"
+`))
+	require.NoError(t, err)
+	require.Len(t, catalog.Models(), 1)
+	assert.Error(t, svc.ValidateModel("precise"), "parsing does not apply the catalog")
+
+	svc.SetCatalog(catalog)
+	require.NoError(t, svc.ValidateModel("precise"))
+	provider, err := svc.getProvider("precise")
+	require.NoError(t, err)
+	assert.Same(t, openai, provider, "models are served by the existing provider instances")
+	assert.Contains(t, svc.counters, "precise")
+
+	// Without models, every provider is offered as a model
+	catalog, err = svc.ParseCatalog(loadCatalogConfig(t, "[app]\nname = \"test\"\n"))
+	require.NoError(t, err)
+	svc.SetCatalog(catalog)
+	assert.Error(t, svc.ValidateModel("precise"))
+	assert.NoError(t, svc.ValidateModel("claude"))
+
+	// Models of unknown providers are rejected
+	_, err = svc.ParseCatalog(loadCatalogConfig(t, `
+[chatbox.models.new]
+provider = "gemini"
+`))
+	assert.Error(t, err)
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/real-rm/chatbox/internal/constants"
 	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/tokenizer"
 	"github.com/real-rm/gohelper"
 	"github.com/real-rm/golog"
 )
@@ -84,12 +85,13 @@ func (p *DifyProvider) SendMessage(ctx context.Context, req *LLMRequest) (*LLMRe
 	// Dify expects a single query string, so we'll concatenate the messages
 	query := p.formatMessages(req.Messages)
 
-	// Create request body
+	// Create request body; a request with a thread continues that Dify conversation
 	reqBody := difyRequest{
-		Inputs:       make(map[string]string),
-		Query:        query,
-		ResponseMode: "blocking",
-		User:         "user-" + fmt.Sprintf("%d", gohelper.TimeToDateInt(time.Now())),
+		Inputs:         make(map[string]string),
+		Query:          query,
+		ResponseMode:   "blocking",
+		ConversationID: req.ThreadID,
+		User:           "user-" + fmt.Sprintf("%d", gohelper.TimeToDateInt(time.Now())),
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -113,6 +115,10 @@ func (p *DifyProvider) SendMessage(ctx context.Context, req *LLMRequest) (*LLMRe
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound && req.ThreadID != "" {
+		return p.SendMessage(ctx, p.restartThread(req))
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, constants.MaxLLMErrorBodySize))
 		return nil, fmt.Errorf("Dify API error (status %d): %s", resp.StatusCode, string(body))
@@ -134,6 +140,7 @@ func (p *DifyProvider) SendMessage(ctx context.Context, req *LLMRequest) (*LLMRe
 		Content:    difyResp.Answer,
 		TokensUsed: difyResp.Metadata.Usage.TotalTokens,
 		Duration:   duration,
+		ThreadID:   difyResp.ConversationID,
 	}, nil
 }
 
@@ -142,12 +149,13 @@ func (p *DifyProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-ch
 	// Dify expects a single query string
 	query := p.formatMessages(req.Messages)
 
-	// Create request body
+	// Create request body; a request with a thread continues that Dify conversation
 	reqBody := difyRequest{
-		Inputs:       make(map[string]string),
-		Query:        query,
-		ResponseMode: "streaming",
-		User:         "user-" + fmt.Sprintf("%d", gohelper.TimeToDateInt(time.Now())),
+		Inputs:         make(map[string]string),
+		Query:          query,
+		ResponseMode:   "streaming",
+		ConversationID: req.ThreadID,
+		User:           "user-" + fmt.Sprintf("%d", gohelper.TimeToDateInt(time.Now())),
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -171,6 +179,11 @@ func (p *DifyProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-ch
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusNotFound && req.ThreadID != "" {
+		resp.Body.Close()
+		return p.StreamMessage(ctx, p.restartThread(req))
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, constants.MaxLLMErrorBodySize))
 		resp.Body.Close()
@@ -185,6 +198,10 @@ func (p *DifyProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-ch
 		defer recoverStreamPanic(chunkChan, "dify", p.logger)
 		defer resp.Body.Close()
 
+		// Every event carries the conversation the reply belongs to; the final
+		// chunk reports it so the caller can continue the conversation
+		var conversationID string
+
 		scanner := bufio.NewScanner(resp.Body)
 		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024) // 1MB max to handle large SSE events
 		for scanner.Scan() {
@@ -208,6 +225,9 @@ func (p *DifyProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-ch
 				// Skip malformed events
 				continue
 			}
+			if event.ConversationID != "" {
+				conversationID = event.ConversationID
+			}
 
 			// Handle different event types
 			// Dify uses "message" for basic chat apps and "agent_message" for agent/workflow apps.
@@ -224,7 +244,7 @@ func (p *DifyProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-ch
 			case "message_end":
 				// End of stream
 				select {
-				case chunkChan <- &LLMChunk{Content: "", Done: true}:
+				case chunkChan <- &LLMChunk{Content: "", Done: true, ThreadID: conversationID}:
 				case <-ctx.Done():
 				}
 				return
@@ -245,7 +265,7 @@ func (p *DifyProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-ch
 
 		// Send final chunk if not already sent
 		select {
-		case chunkChan <- &LLMChunk{Content: "", Done: true}:
+		case chunkChan <- &LLMChunk{Content: "", Done: true, ThreadID: conversationID}:
 		case <-ctx.Done():
 		}
 	}()
@@ -253,11 +273,20 @@ func (p *DifyProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-ch
 	return chunkChan, nil
 }
 
-// GetTokenCount estimates the token count for the given text
-// This is a simple approximation: ~4 characters per token for English text
+// GetTokenCount estimates the token count for the given text with the
+// default counter of the Dify model family (see tokenizer.ForProvider)
 func (p *DifyProvider) GetTokenCount(text string) int {
-	// Simple approximation: 1 token ≈ 4 characters
-	return len(text) / 4
+	return tokenizer.ForProvider("dify").Count(text)
+}
+
+// restartThread returns req without its thread, for a conversation Dify no
+// longer knows: deleted, or started under another user (the request user
+// changes daily). The reply then starts a new conversation.
+func (p *DifyProvider) restartThread(req *LLMRequest) *LLMRequest {
+	p.logger.Warn("Dify conversation not found, starting a new one", "conversation_id", req.ThreadID)
+	restart := *req
+	restart.ThreadID = ""
+	return &restart
 }
 
 // formatMessages converts ChatMessage array to a single query string
@@ -9,14 +9,18 @@ import (
 	"net/url"
 	"os"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/real-rm/chatbox/internal/constants"
 	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/tokenizer"
+	"github.com/real-rm/chatbox/internal/tracing"
 	"github.com/real-rm/goconfig"
 	"github.com/real-rm/golog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // newStreamTransport returns an HTTP transport cloned from http.DefaultTransport
@@ -61,9 +65,13 @@ type LLMProvider interface {
 
 // LLMRequest represents a request to an LLM provider
 type LLMRequest struct {
-	ModelID  string        // The model identifier
-	Messages []ChatMessage // The conversation history
-	Stream   bool          // Whether to stream the response
+	ModelID     string        // The model identifier
+	Messages    []ChatMessage // The conversation history
+	Stream      bool          // Whether to stream the response
+	MaxTokens   int           // Max tokens to generate (0 uses the provider default)
+	Temperature *float64      // Sampling temperature (nil uses the provider default)
+	TopP        *float64      // Nucleus sampling probability mass (nil uses the provider default)
+	ThreadID    string        // Provider thread to continue (empty starts a new one, see WithThread)
 }
 
 // ChatMessage represents a single message in the conversation
@@ -77,29 +85,46 @@ type LLMResponse struct {
 	Content    string        // The generated response text
 	TokensUsed int           // Number of tokens consumed
 	Duration   time.Duration // Time taken to generate the response
+	ThreadID   string        // Provider thread holding the conversation (empty when the provider keeps none)
 }
 
 // LLMChunk represents a chunk of a streaming response
 type LLMChunk struct {
-	Content string // The chunk content
-	Done    bool   // Whether this is the final chunk
+	Content  string // The chunk content
+	Done     bool   // Whether this is the final chunk
+	ThreadID string // Provider thread holding the conversation, set on the final chunk (empty when the provider keeps none)
 }
 
 // ModelInfo contains information about an available LLM model
 type ModelInfo struct {
-	ID       string // Unique identifier
-	Name     string // Display name
-	Type     string // Provider type (openai, anthropic, dify)
-	Endpoint string // API endpoint
+	ID           string   // Unique identifier
+	Name         string   // Display name
+	Type         string   // Provider type (openai, anthropic, dify)
+	Endpoint     string   // API endpoint
+	Provider     string   // ID of the provider serving the model
+	MaxTokens    int      // Max tokens to generate (0 uses the provider default)
+	Temperature  *float64 // Sampling temperature (nil uses the provider default)
+	SystemPrompt string   // Prepended to every conversation; never sent to clients
+
+	// Bounds for per-session parameters requested by clients (see params.go)
+	MaxTokensLimit int     // Highest max_tokens a client may request (0 uses constants.DefaultMaxTokensLimit)
+	MaxTemperature float64 // Highest temperature a client may request (0 uses constants.MaxModelTemperature)
+
+	// Token counting overrides (see internal/tokenizer); empty uses the provider's counter
+	Tokenizer     string  // Tokenizer name (tiktoken or heuristic)
+	CharsPerToken float64 // Characters per token for the heuristic tokenizer (0 uses its default)
 }
 
 // LLMService manages multiple LLM providers and routes requests to them
 type LLMService struct {
-	providers map[string]LLMProvider   // Map of provider ID to provider instance
-	models    map[string]ModelInfo     // Map of model ID to model info
-	config    *goconfig.ConfigAccessor // Configuration accessor
-	logger    *golog.Logger            // Logger for LLM operations
-	mu        sync.RWMutex             // Protects concurrent access
+	providers       map[string]LLMProvider       // Map of model ID to the instance of its provider
+	models          map[string]ModelInfo         // Map of model ID to model info (the model catalog)
+	counters        map[string]tokenizer.Counter // Map of model ID to the token counter overriding its provider's
+	providerConfigs []LLMProviderConfig          // Configured providers, which the catalog's models reference
+	instances       map[string]LLMProvider       // Map of provider ID to its instance
+	config          *goconfig.ConfigAccessor     // Configuration accessor
+	logger          *golog.Logger                // Logger for LLM operations
+	mu              sync.RWMutex                 // Protects concurrent access
 }
 
 // NewLLMService creates a new LLM service with the given configuration accessor
@@ -126,30 +151,34 @@ func NewLLMService(cfg *goconfig.ConfigAccessor, logger *golog.Logger) (*LLMServ
 	service := &LLMService{
 		providers: make(map[string]LLMProvider),
 		models:    make(map[string]ModelInfo),
+		counters:  make(map[string]tokenizer.Counter),
 		config:    cfg,
 		logger:    llmLogger,
 	}
 
-	// Register all configured providers
+	// Create all configured providers
+	instances := make(map[string]LLMProvider, len(providers))
 	for _, providerCfg := range providers {
-		modelInfo := ModelInfo{
-			ID:       providerCfg.ID,
-			Name:     providerCfg.Name,
-			Type:     providerCfg.Type,
-			Endpoint: providerCfg.Endpoint,
-		}
-		service.models[providerCfg.ID] = modelInfo
-
 		// Create provider instance based on type
 		provider, err := createProvider(providerCfg, llmLogger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create provider %s: %w", providerCfg.ID, err)
 		}
 
-		service.providers[providerCfg.ID] = provider
+		instances[providerCfg.ID] = provider
 		llmLogger.Info("Registered LLM provider", "provider_id", providerCfg.ID, "type", providerCfg.Type)
 	}
 
+	service.providerConfigs = providers
+	service.instances = instances
+
+	// Load the model catalog; without one, every provider is offered as a model
+	catalog, err := service.ParseCatalog(cfg)
+	if err != nil {
+		return nil, err
+	}
+	service.SetCatalog(catalog)
+
 	return service, nil
 }
 
@@ -298,6 +327,14 @@ func (s *LLMService) registerProviderUnsafe(modelID string, provider LLMProvider
 
 // SendMessage sends a message to the specified LLM model with retry logic and response time tracking
 func (s *LLMService) SendMessage(ctx context.Context, modelID string, messages []ChatMessage) (*LLMResponse, error) {
+	ctx, span := tracing.Start(ctx, "llm.SendMessage", tracing.AttrModelID.String(modelID))
+	resp, err := s.sendMessage(ctx, modelID, messages)
+	tracing.End(span, err)
+	return resp, err
+}
+
+// sendMessage implements SendMessage within the span in ctx
+func (s *LLMService) sendMessage(ctx context.Context, modelID string, messages []ChatMessage) (*LLMResponse, error) {
 	if modelID == "" {
 		return nil, ErrInvalidModelID
 	}
@@ -309,12 +346,9 @@ func (s *LLMService) SendMessage(ctx context.Context, modelID string, messages [
 
 	// Get provider name for metrics
 	providerName := s.getProviderName(modelID)
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrProvider.String(providerName))
 
-	req := &LLMRequest{
-		ModelID:  modelID,
-		Messages: messages,
-		Stream:   false,
-	}
+	req := s.newRequest(ctx, modelID, messages, false)
 
 	// Implement retry logic with exponential backoff
 	var lastErr error
@@ -384,6 +418,19 @@ func (s *LLMService) SendMessage(ctx context.Context, modelID string, messages [
 
 // StreamMessage sends a message to the specified LLM model and returns a streaming channel with retry logic
 func (s *LLMService) StreamMessage(ctx context.Context, modelID string, messages []ChatMessage) (<-chan *LLMChunk, error) {
+	// The span stays open until the stream ends, so it covers the full response time
+	ctx, span := tracing.Start(ctx, "llm.StreamMessage", tracing.AttrModelID.String(modelID))
+	chunks, err := s.streamMessage(ctx, modelID, messages)
+	// No else needed: on success the stream goroutine ends the span
+	if err != nil {
+		tracing.End(span, err)
+	}
+	return chunks, err
+}
+
+// streamMessage implements StreamMessage within the span in ctx, ending the span
+// when an established stream finishes
+func (s *LLMService) streamMessage(ctx context.Context, modelID string, messages []ChatMessage) (<-chan *LLMChunk, error) {
 	if modelID == "" {
 		return nil, ErrInvalidModelID
 	}
@@ -393,14 +440,13 @@ func (s *LLMService) StreamMessage(ctx context.Context, modelID string, messages
 		return nil, err
 	}
 
+	span := trace.SpanFromContext(ctx)
+
 	// Get provider name for metrics
 	providerName := s.getProviderName(modelID)
+	span.SetAttributes(tracing.AttrProvider.String(providerName))
 
-	req := &LLMRequest{
-		ModelID:  modelID,
-		Messages: messages,
-		Stream:   true,
-	}
+	req := s.newRequest(ctx, modelID, messages, true)
 
 	// Implement retry logic with exponential backoff for stream establishment
 	var lastErr error
@@ -437,6 +483,7 @@ func (s *LLMService) StreamMessage(ctx context.Context, modelID string, messages
 			s.logger.Info("LLM stream established", "model_id", modelID)
 			wrappedChan := make(chan *LLMChunk)
 			go func() {
+				defer span.End()
 				defer close(wrappedChan)
 				defer recoverStreamPanic(wrappedChan, providerName, s.logger)
 				firstChunk := true
@@ -445,11 +492,13 @@ func (s *LLMService) StreamMessage(ctx context.Context, modelID string, messages
 					if firstChunk {
 						duration := time.Since(startTime)
 						metrics.LLMLatency.WithLabelValues(providerName).Observe(duration.Seconds())
+						span.AddEvent("first_token")
 						firstChunk = false
 					}
 					select {
 					case wrappedChan <- chunk:
 					case <-ctx.Done():
+						tracing.RecordError(span, ctx.Err())
 						return
 					}
 				}
@@ -474,7 +523,7 @@ func (s *LLMService) StreamMessage(ctx context.Context, modelID string, messages
 	return nil, fmt.Errorf("failed to establish stream after %d attempts: %w", maxRetries, lastErr)
 }
 
-// GetAvailableModels returns a list of all configured models
+// GetAvailableModels returns the model catalog sorted by model ID
 func (s *LLMService) GetAvailableModels() []ModelInfo {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -483,6 +532,7 @@ func (s *LLMService) GetAvailableModels() []ModelInfo {
 	for _, model := range s.models {
 		models = append(models, model)
 	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
 	return models
 }
 
@@ -502,12 +552,20 @@ func (s *LLMService) ValidateModel(modelID string) error {
 	return nil
 }
 
-// GetTokenCount estimates the token count for the given text using the specified model
+// GetTokenCount estimates the token count for the given text using the specified model.
+// The model's configured tokenizer is used when it has one, otherwise its provider's.
 func (s *LLMService) GetTokenCount(modelID string, text string) (int, error) {
 	if modelID == "" {
 		return 0, ErrInvalidModelID
 	}
 
+	s.mu.RLock()
+	counter, exists := s.counters[modelID]
+	s.mu.RUnlock()
+	if exists {
+		return counter.Count(text), nil
+	}
+
 	provider, err := s.getProvider(modelID)
 	if err != nil {
 		return 0, err
@@ -599,6 +657,23 @@ func recoverStreamPanic(chunkChan chan<- *LLMChunk, component string, logger *go
 	}
 }
 
+// IsRetryable reports whether an error returned by SendMessage or StreamMessage
+// is a transient provider failure (network error, timeout, 5xx, rate limit)
+func IsRetryable(err error) bool {
+	return isRetryableError(err)
+}
+
+// IsRateLimited reports whether an error returned by SendMessage or StreamMessage
+// is the provider rejecting the request for exceeding its rate limits (HTTP 429)
+func IsRateLimited(err error) bool {
+	// No else needed: early return pattern (guard clause)
+	if err == nil {
+		return false
+	}
+	errStr := err.Error()
+	return strings.Contains(errStr, "status 429") || strings.Contains(errStr, "rate limit")
+}
+
 // isRetryableError determines if an error should trigger a retry
 func isRetryableError(err error) bool {
 	if err == nil {
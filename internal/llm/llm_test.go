@@ -3,6 +3,7 @@ package llm
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -1007,3 +1008,10 @@ func TestIsRetryableError(t *testing.T) {
 		})
 	}
 }
+
+func TestIsRateLimited(t *testing.T) {
+	assert.False(t, IsRateLimited(nil))
+	assert.True(t, IsRateLimited(fmt.Errorf("failed to establish stream after 3 attempts: %w", errors.New("OpenAI API error (status 429): slow down"))))
+	assert.True(t, IsRateLimited(errors.New("rate limit exceeded")))
+	assert.False(t, IsRateLimited(errors.New("Anthropic API error (status 503): overloaded")), "other transient errors are retried, not queued")
+}
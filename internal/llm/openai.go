@@ -13,6 +13,7 @@ import (
 
 	"github.com/real-rm/chatbox/internal/constants"
 	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/tokenizer"
 	"github.com/real-rm/golog"
 )
 
@@ -45,9 +46,12 @@ func NewOpenAIProvider(apiKey, endpoint, model string, logger *golog.Logger) *Op
 
 // openAIRequest represents the request format for OpenAI API
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
-	Stream   bool            `json:"stream"`
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Stream      bool            `json:"stream"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
 }
 
 type openAIMessage struct {
@@ -93,9 +97,12 @@ func (p *OpenAIProvider) SendMessage(ctx context.Context, req *LLMRequest) (*LLM
 
 	// Create request body
 	reqBody := openAIRequest{
-		Model:    p.model,
-		Messages: messages,
-		Stream:   false,
+		Model:       p.model,
+		Messages:    messages,
+		Stream:      false,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -156,9 +163,12 @@ func (p *OpenAIProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-
 
 	// Create request body
 	reqBody := openAIRequest{
-		Model:    p.model,
-		Messages: messages,
-		Stream:   true,
+		Model:       p.model,
+		Messages:    messages,
+		Stream:      true,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -256,9 +266,8 @@ func (p *OpenAIProvider) StreamMessage(ctx context.Context, req *LLMRequest) (<-
 	return chunkChan, nil
 }
 
-// GetTokenCount estimates the token count for the given text
-// This is a simple approximation: ~4 characters per token for English text
+// GetTokenCount estimates the token count for the given text with the
+// default counter of the OpenAI model family (see tokenizer.ForProvider)
 func (p *OpenAIProvider) GetTokenCount(text string) int {
-	// Simple approximation: 1 token ≈ 4 characters
-	return len(text) / 4
+	return tokenizer.ForProvider("openai").Count(text)
 }
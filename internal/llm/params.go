@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// ErrInvalidParams is returned when per-session parameters are outside the model's bounds
+var ErrInvalidParams = errors.New("invalid LLM parameters")
+
+// Params are sampling parameters chosen by a client for its session. Unset
+// fields keep the model's catalog settings or the provider defaults.
+type Params struct {
+	Temperature *float64
+	MaxTokens   int
+	TopP        *float64
+}
+
+// IsZero reports whether no parameter is set
+func (p Params) IsZero() bool {
+	return p.Temperature == nil && p.MaxTokens == 0 && p.TopP == nil
+}
+
+// paramsKey is the context key for per-request Params
+type paramsKey struct{}
+
+// WithParams returns a context whose SendMessage and StreamMessage calls apply p
+// on top of the model's catalog settings
+func WithParams(ctx context.Context, p Params) context.Context {
+	return context.WithValue(ctx, paramsKey{}, p)
+}
+
+// paramsFromContext returns the Params set with WithParams, if any
+func paramsFromContext(ctx context.Context) Params {
+	p, _ := ctx.Value(paramsKey{}).(Params)
+	return p
+}
+
+// ValidateParams checks p against the bounds of model: max_tokens between 1 and
+// the model's max_tokens_limit, temperature between 0 and its max_temperature,
+// and top_p in (0, 1]
+func ValidateParams(model ModelInfo, p Params) error {
+	maxTokensLimit := model.MaxTokensLimit
+	if maxTokensLimit <= 0 {
+		maxTokensLimit = constants.DefaultMaxTokensLimit
+	}
+	maxTemperature := model.MaxTemperature
+	if maxTemperature <= 0 {
+		maxTemperature = constants.MaxModelTemperature
+	}
+
+	if p.MaxTokens < 0 || p.MaxTokens > maxTokensLimit {
+		return fmt.Errorf("%w: max_tokens must be between 1 and %d", ErrInvalidParams, maxTokensLimit)
+	}
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > maxTemperature) {
+		return fmt.Errorf("%w: temperature must be between 0 and %g", ErrInvalidParams, maxTemperature)
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		return fmt.Errorf("%w: top_p must be greater than 0 and at most 1", ErrInvalidParams)
+	}
+	return nil
+}
+
+// applyParams overrides the request's catalog settings with p. Parameters that
+// no longer fit the model, e.g. after the session switched to a model with lower
+// bounds, are ignored as a whole.
+func applyParams(req *LLMRequest, model ModelInfo, p Params) {
+	if p.IsZero() || ValidateParams(model, p) != nil {
+		return
+	}
+	if p.MaxTokens > 0 {
+		req.MaxTokens = p.MaxTokens
+	}
+	if p.Temperature != nil {
+		req.Temperature = p.Temperature
+	}
+	req.TopP = p.TopP
+}
@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(f float64) *float64 { return &f }
+
+func TestValidateParams(t *testing.T) {
+	defaults := ModelInfo{ID: "default"}
+	assert.NoError(t, ValidateParams(defaults, Params{}))
+	assert.NoError(t, ValidateParams(defaults, Params{Temperature: floatPtr(2), MaxTokens: 4096, TopP: floatPtr(1)}))
+	assert.ErrorIs(t, ValidateParams(defaults, Params{MaxTokens: 4097}), ErrInvalidParams)
+	assert.ErrorIs(t, ValidateParams(defaults, Params{MaxTokens: -1}), ErrInvalidParams)
+	assert.ErrorIs(t, ValidateParams(defaults, Params{Temperature: floatPtr(-0.1)}), ErrInvalidParams)
+	assert.ErrorIs(t, ValidateParams(defaults, Params{TopP: floatPtr(0)}), ErrInvalidParams)
+	assert.ErrorIs(t, ValidateParams(defaults, Params{TopP: floatPtr(1.5)}), ErrInvalidParams)
+
+	bounded := ModelInfo{ID: "bounded", MaxTokensLimit: 512, MaxTemperature: 1}
+	assert.NoError(t, ValidateParams(bounded, Params{Temperature: floatPtr(1), MaxTokens: 512}))
+	assert.ErrorIs(t, ValidateParams(bounded, Params{MaxTokens: 513}), ErrInvalidParams)
+	assert.ErrorIs(t, ValidateParams(bounded, Params{Temperature: floatPtr(1.1)}), ErrInvalidParams)
+}
+
+func TestLLMService_AppliesSessionParams(t *testing.T) {
+	var got openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_ = json.NewEncoder(w).Encode(openAIResponse{
+			Choices: []openAIChoice{{Message: openAIMessage{Role: "assistant", Content: "ok"}}},
+		})
+	}))
+	defer server.Close()
+
+	svc := newTestService(t)
+	svc.models["precise"] = ModelInfo{
+		ID:             "precise",
+		Type:           "openai",
+		Provider:       "openai-main",
+		MaxTokens:      256,
+		Temperature:    floatPtr(0.2),
+		MaxTokensLimit: 1024,
+	}
+	svc.providers["precise"] = NewOpenAIProvider("test-key", server.URL, "gpt-4o", createTestLogger())
+	messages := []ChatMessage{{Role: "user", Content: "Hi"}}
+
+	// Session parameters override the catalog settings they set
+	ctx := WithParams(context.Background(), Params{MaxTokens: 1000, TopP: floatPtr(0.5)})
+	_, err := svc.SendMessage(ctx, "precise", messages)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, got.MaxTokens)
+	assert.Equal(t, 0.2, *got.Temperature)
+	require.NotNil(t, got.TopP)
+	assert.Equal(t, 0.5, *got.TopP)
+
+	// Parameters outside the model's bounds are ignored
+	got = openAIRequest{}
+	ctx = WithParams(context.Background(), Params{MaxTokens: 2000, TopP: floatPtr(0.5)})
+	_, err = svc.SendMessage(ctx, "precise", messages)
+	require.NoError(t, err)
+	assert.Equal(t, 256, got.MaxTokens)
+	assert.Nil(t, got.TopP)
+}
@@ -0,0 +1,31 @@
+package llm
+
+import "context"
+
+// Thread is a conversation a provider keeps server-side, e.g. a Dify
+// conversation. A request that continues a thread only carries the new turn:
+// the provider already holds the earlier context.
+type Thread struct {
+	ModelID string // Model the thread was started with
+	ID      string // The provider's thread (conversation) ID
+}
+
+// threadKey is the context key for the Thread to continue
+type threadKey struct{}
+
+// WithThread returns a context whose SendMessage and StreamMessage calls continue
+// t. Only calls to t.ModelID continue it: a call to any other model, e.g. along a
+// fallback chain, starts a new thread.
+func WithThread(ctx context.Context, t Thread) context.Context {
+	return context.WithValue(ctx, threadKey{}, t)
+}
+
+// threadFromContext returns the ID of the thread set with WithThread for
+// modelID, or "" when the request starts a new thread
+func threadFromContext(ctx context.Context, modelID string) string {
+	t, _ := ctx.Value(threadKey{}).(Thread)
+	if t.ModelID != modelID {
+		return ""
+	}
+	return t.ID
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLLMService_ContinuesThread(t *testing.T) {
+	var got []difyRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req difyRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		got = append(got, req)
+		if req.ConversationID == "gone" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"not_found","message":"Conversation Not Exists."}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(difyResponse{ConversationID: "conv_1", Answer: "ok"})
+	}))
+	defer server.Close()
+
+	svc := newTestService(t)
+	svc.models["support"] = ModelInfo{ID: "support", Type: "dify", Provider: "dify-main", SystemPrompt: "Be brief."}
+	svc.providers["support"] = NewDifyProvider("test-key", server.URL, "support", createTestLogger())
+	messages := []ChatMessage{{Role: "user", Content: "Hi"}}
+
+	// A new thread gets the system prompt and reports the provider's thread
+	resp, err := svc.SendMessage(context.Background(), "support", messages)
+	require.NoError(t, err)
+	assert.Equal(t, "conv_1", resp.ThreadID)
+	require.Len(t, got, 1)
+	assert.Empty(t, got[0].ConversationID)
+	assert.Contains(t, got[0].Query, "Be brief.")
+
+	// Continuing the thread leaves the system prompt out
+	ctx := WithThread(context.Background(), Thread{ModelID: "support", ID: "conv_1"})
+	_, err = svc.SendMessage(ctx, "support", messages)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "conv_1", got[1].ConversationID)
+	assert.NotContains(t, got[1].Query, "Be brief.")
+
+	// A thread of another model is not continued
+	ctx = WithThread(context.Background(), Thread{ModelID: "other", ID: "conv_1"})
+	_, err = svc.SendMessage(ctx, "support", messages)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Empty(t, got[2].ConversationID)
+
+	// A conversation Dify no longer knows is restarted
+	ctx = WithThread(context.Background(), Thread{ModelID: "support", ID: "gone"})
+	resp, err = svc.SendMessage(ctx, "support", messages)
+	require.NoError(t, err)
+	assert.Equal(t, "conv_1", resp.ThreadID)
+	require.Len(t, got, 5)
+	assert.Equal(t, "gone", got[3].ConversationID)
+	assert.Empty(t, got[4].ConversationID)
+}
+
+func TestDifyProvider_StreamMessage_ThreadID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req difyRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "conv_1", req.ConversationID)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"event":"message","conversation_id":"conv_1","answer":"Hello"}` + "\n"))
+		_, _ = w.Write([]byte(`data: {"event":"message_end","conversation_id":"conv_1"}` + "\n"))
+	}))
+	defer server.Close()
+
+	provider := NewDifyProvider("test-key", server.URL, "dify-model", createTestLogger())
+	chunkChan, err := provider.StreamMessage(context.Background(), &LLMRequest{
+		ModelID:  "dify-model",
+		Messages: []ChatMessage{{Role: "user", Content: "Hi"}},
+		Stream:   true,
+		ThreadID: "conv_1",
+	})
+	require.NoError(t, err)
+
+	var chunks []*LLMChunk
+	for chunk := range chunkChan {
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 2)
+	assert.Empty(t, chunks[0].ThreadID)
+	assert.True(t, chunks[1].Done)
+	assert.Equal(t, "conv_1", chunks[1].ThreadID, "the final chunk reports the thread")
+}
@@ -0,0 +1,185 @@
+// Package maintenance holds the scheduled maintenance windows of the service.
+//
+// Windows are configured under [chatbox.maintenance]. During a window new
+// sessions are refused with a MAINTENANCE error while existing sessions keep
+// working; from the notice period before a window until its end, connected
+// clients receive maintenance banners with a countdown, and the readiness
+// endpoint reports the "maintenance" status while a window is active.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// ErrInvalidWindow is returned when a maintenance window is misconfigured
+var ErrInvalidWindow = errors.New("invalid maintenance window")
+
+// Window is a scheduled maintenance period
+type Window struct {
+	Start   time.Time
+	End     time.Time
+	Message string // Shown to users; the schedule's message when not set
+}
+
+// Schedule is the list of maintenance windows. A nil schedule has no windows.
+// It is immutable after creation and safe for concurrent use.
+type Schedule struct {
+	windows []Window      // sorted by start
+	notice  time.Duration // How long before a window banners start
+}
+
+// NewSchedule creates a schedule of windows, announced notice before they
+// start. Windows without a message get message. Returns ErrInvalidWindow for a
+// window that does not end after it starts or overlaps another window.
+func NewSchedule(windows []Window, notice time.Duration, message string) (*Schedule, error) {
+	sorted := make([]Window, len(windows))
+	copy(sorted, windows)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	for i := range sorted {
+		// No else needed: early return pattern (guard clause)
+		if !sorted[i].End.After(sorted[i].Start) {
+			return nil, fmt.Errorf("%w: window starting %s must end after it starts", ErrInvalidWindow, sorted[i].Start.Format(time.RFC3339))
+		}
+		// No else needed: early return pattern (guard clause)
+		if i > 0 && sorted[i].Start.Before(sorted[i-1].End) {
+			return nil, fmt.Errorf("%w: window starting %s overlaps the previous window", ErrInvalidWindow, sorted[i].Start.Format(time.RFC3339))
+		}
+		// No else needed: optional operation (default message)
+		if sorted[i].Message == "" {
+			sorted[i].Message = message
+		}
+	}
+	return &Schedule{windows: sorted, notice: notice}, nil
+}
+
+// ParseConfig converts the raw [chatbox.maintenance] config value, a table
+// with an optional notice duration and message and a windows array of
+// { start, end, message } tables with RFC3339 times, into a schedule
+func ParseConfig(raw interface{}) (*Schedule, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.maintenance is not a table")
+	}
+
+	notice := constants.DefaultMaintenanceNotice
+	// No else needed: optional operation (notice defaults to DefaultMaintenanceNotice)
+	if noticeStr, ok := table["notice"].(string); ok {
+		var err error
+		notice, err = time.ParseDuration(noticeStr)
+		// No else needed: early return pattern (guard clause)
+		if err != nil || notice < 0 {
+			return nil, fmt.Errorf("notice must be a non-negative duration, got %q", noticeStr)
+		}
+	}
+
+	message := constants.DefaultMaintenanceMessage
+	// No else needed: optional operation (message defaults to DefaultMaintenanceMessage)
+	if custom, ok := table["message"].(string); ok && custom != "" {
+		message = custom
+	}
+
+	var items []map[string]interface{}
+	switch list := table["windows"].(type) {
+	case nil:
+	case []map[string]interface{}:
+		items = list
+	case []interface{}:
+		for _, item := range list {
+			window, ok := item.(map[string]interface{})
+			// No else needed: early return pattern (guard clause)
+			if !ok {
+				return nil, fmt.Errorf("windows must be an array of tables")
+			}
+			items = append(items, window)
+		}
+	default:
+		return nil, fmt.Errorf("windows must be an array of tables")
+	}
+
+	windows := make([]Window, 0, len(items))
+	for i, item := range items {
+		start, err := parseTime(item["start"])
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: start %w", i+1, err)
+		}
+		end, err := parseTime(item["end"])
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: end %w", i+1, err)
+		}
+		windowMessage, _ := item["message"].(string)
+		windows = append(windows, Window{Start: start, End: end, Message: windowMessage})
+	}
+	return NewSchedule(windows, notice, message)
+}
+
+// parseTime reads a window time, written as an RFC3339 string or a TOML datetime
+func parseTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("must be an RFC3339 time, got %q", v)
+		}
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("is required")
+	}
+}
+
+// Active returns the window in progress at now, if any
+func (s *Schedule) Active(now time.Time) (Window, bool) {
+	// No else needed: early return pattern (no schedule)
+	if s == nil {
+		return Window{}, false
+	}
+	for _, w := range s.windows {
+		// No else needed: early return pattern (window in progress)
+		if !now.Before(w.Start) && now.Before(w.End) {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+// Announced returns the window to announce at now: the window in progress, or
+// else the next window starting within the notice period
+func (s *Schedule) Announced(now time.Time) (Window, bool) {
+	// No else needed: early return pattern (no schedule)
+	if s == nil {
+		return Window{}, false
+	}
+	for _, w := range s.windows {
+		// No else needed: early return pattern (in progress or starting soon)
+		if now.Before(w.End) && !now.Before(w.Start.Add(-s.notice)) {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+// Next returns the first window that has not ended at now, if any
+func (s *Schedule) Next(now time.Time) (Window, bool) {
+	// No else needed: early return pattern (no schedule)
+	if s == nil {
+		return Window{}, false
+	}
+	for _, w := range s.windows {
+		// No else needed: early return pattern (first window not over)
+		if now.Before(w.End) {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
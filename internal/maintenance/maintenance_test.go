@@ -0,0 +1,88 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	start := time.Date(2026, 11, 1, 2, 0, 0, 0, time.UTC)
+	schedule, err := ParseConfig(map[string]interface{}{
+		"notice":  "15m",
+		"message": "Back soon",
+		"windows": []map[string]interface{}{
+			{"start": "2026-11-08T02:00:00Z", "end": "2026-11-08T03:00:00Z", "message": "Database upgrade"},
+			{"start": start, "end": start.Add(time.Hour)},
+		},
+	})
+	require.NoError(t, err)
+
+	w, ok := schedule.Next(start.Add(-time.Hour))
+	require.True(t, ok)
+	assert.True(t, start.Equal(w.Start), "windows are sorted")
+	assert.Equal(t, "Back soon", w.Message, "windows default to the schedule's message")
+
+	w, ok = schedule.Next(start.Add(time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, "Database upgrade", w.Message)
+
+	schedule, err = ParseConfig(map[string]interface{}{})
+	require.NoError(t, err)
+	_, ok = schedule.Next(start)
+	assert.False(t, ok)
+	assert.Equal(t, constants.DefaultMaintenanceNotice, schedule.notice)
+
+	invalid := []interface{}{
+		"not a table",
+		map[string]interface{}{"notice": "soon"},
+		map[string]interface{}{"windows": "tonight"},
+		map[string]interface{}{"windows": []interface{}{map[string]interface{}{"start": "tonight", "end": "2026-11-01T03:00:00Z"}}},
+		map[string]interface{}{"windows": []interface{}{map[string]interface{}{"start": "2026-11-01T02:00:00Z"}}},
+		map[string]interface{}{"windows": []interface{}{map[string]interface{}{"start": "2026-11-01T03:00:00Z", "end": "2026-11-01T02:00:00Z"}}},
+		map[string]interface{}{"windows": []interface{}{
+			map[string]interface{}{"start": "2026-11-01T02:00:00Z", "end": "2026-11-01T04:00:00Z"},
+			map[string]interface{}{"start": "2026-11-01T03:00:00Z", "end": "2026-11-01T05:00:00Z"},
+		}},
+	}
+	for _, raw := range invalid {
+		_, err := ParseConfig(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+}
+
+func TestSchedule_ActiveAndAnnounced(t *testing.T) {
+	start := time.Date(2026, 11, 1, 2, 0, 0, 0, time.UTC)
+	schedule, err := NewSchedule([]Window{{Start: start, End: start.Add(time.Hour)}}, 30*time.Minute, "Back soon")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		now       time.Time
+		active    bool
+		announced bool
+	}{
+		{"well before", start.Add(-time.Hour), false, false},
+		{"notice period", start.Add(-10 * time.Minute), false, true},
+		{"start", start, true, true},
+		{"in progress", start.Add(30 * time.Minute), true, true},
+		{"end", start.Add(time.Hour), false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, active := schedule.Active(tc.now)
+			assert.Equal(t, tc.active, active)
+			_, announced := schedule.Announced(tc.now)
+			assert.Equal(t, tc.announced, announced)
+		})
+	}
+
+	var none *Schedule
+	_, ok := none.Active(start)
+	assert.False(t, ok, "nil schedules have no windows")
+	_, ok = none.Announced(start)
+	assert.False(t, ok)
+}
@@ -0,0 +1,179 @@
+// Package memory keeps what users tell the assistant about themselves, such as
+// the name they want to be called or how they like answers, across sessions.
+//
+// The router extracts facts from user messages with a few conservative
+// patterns ("my name is ...", "I prefer ...", "remember that ..."), stores them
+// per user in MongoDB, separately from sessions, and renders them into the
+// {{user_memory}} variable of prompt templates. Users can list and delete what
+// is remembered about them through the /chat/memory endpoints.
+package memory
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// Fact kinds
+const (
+	KindName       = "name"       // What the user wants to be called; a user has at most one
+	KindPreference = "preference" // How the user wants to be answered
+	KindNote       = "note"       // Anything else the user asked to be remembered
+)
+
+// ErrFactNotFound is returned when a fact does not exist or belongs to another user
+var ErrFactNotFound = errors.New("memory fact not found")
+
+// Fact is something a user stated about themselves
+type Fact struct {
+	ID        string    `bson:"_id" json:"id"`
+	UserID    string    `bson:"uid" json:"-"`
+	TenantID  string    `bson:"tid,omitempty" json:"-"` // empty for the default tenant
+	Kind      string    `bson:"kind" json:"kind"`
+	Text      string    `bson:"text" json:"text"`
+	SessionID string    `bson:"sid,omitempty" json:"session_id,omitempty"` // Session the fact was stated in
+	CreatedAt time.Time `bson:"ts" json:"created_at"`
+}
+
+// Extracted is a fact found in a message, before it is stored
+type Extracted struct {
+	Kind string
+	Text string
+}
+
+var (
+	// namePattern matches a name of one to three words
+	namePattern = regexp.MustCompile(`(?i)\b(?:my name is|my name's|please call me|you can call me)\s+(\p{L}[\p{L}'-]*(?:\s+\p{L}[\p{L}'-]*){0,2}?)\s*(?:[.,;:!?]|\s+and\b|$)`)
+	// preferencePattern matches a stated preference up to the end of the sentence
+	preferencePattern = regexp.MustCompile(`(?i)\bI(?:\s+would|'d)?\s+prefer\s+([^.!?\n]+)`)
+	// alwaysPattern matches a standing instruction such as "please always answer in German"
+	alwaysPattern = regexp.MustCompile(`(?i)\bplease\s+(always\s+[^.!?\n]+)`)
+	// notePattern matches "remember (that) ..." at the start of a sentence
+	notePattern = regexp.MustCompile(`(?i)(?:^|[.!?\n]\s*)(?:please\s+)?remember(?:\s+that)?\s+([^.!?\n]+)`)
+)
+
+// Extract returns the facts a user message states, in the order of the
+// patterns: name, preferences, notes. Questions, and facts that are too short
+// or longer than constants.MaxMemoryFactLength, are ignored.
+func Extract(text string) []Extracted {
+	var facts []Extracted
+	add := func(kind, value string) {
+		value = strings.Join(strings.Fields(value), " ")
+		// No else needed: early return pattern (nothing worth remembering)
+		if len(value) < 2 || len(value) > constants.MaxMemoryFactLength {
+			return
+		}
+		facts = append(facts, Extracted{Kind: kind, Text: value})
+	}
+
+	// No else needed: optional operation (only the first name counts)
+	if match := namePattern.FindStringSubmatch(text); match != nil {
+		add(KindName, match[1])
+	}
+	for _, match := range preferencePattern.FindAllStringSubmatch(text, -1) {
+		add(KindPreference, match[1])
+	}
+	for _, match := range alwaysPattern.FindAllStringSubmatch(text, -1) {
+		add(KindPreference, match[1])
+	}
+	for _, loc := range notePattern.FindAllStringSubmatchIndex(text, -1) {
+		// No else needed: early return pattern (a question, e.g. "remember what I said?")
+		if loc[1] < len(text) && text[loc[1]] == '?' {
+			continue
+		}
+		add(KindNote, text[loc[2]:loc[3]])
+	}
+	return facts
+}
+
+// Merge decides how extracted facts change a user's stored facts, given oldest
+// first. Facts already known (same kind and text, ignoring case) are skipped, a
+// new name replaces the stored one, and the oldest facts are forgotten beyond
+// max. Returns the facts to add and the IDs of the stored facts to remove.
+func Merge(stored []*Fact, extracted []Extracted, max int) ([]Extracted, []string) {
+	known := make(map[string]bool, len(stored)+len(extracted))
+	for _, f := range stored {
+		known[f.Kind+"\x00"+strings.ToLower(f.Text)] = true
+	}
+
+	var add []Extracted
+	newName := false
+	for _, e := range extracted {
+		key := e.Kind + "\x00" + strings.ToLower(e.Text)
+		// No else needed: early return pattern (already remembered)
+		if known[key] {
+			continue
+		}
+		known[key] = true
+		// No else needed: optional operation (a user has one name)
+		if e.Kind == KindName {
+			// No else needed: early return pattern (keep the first name of the message)
+			if newName {
+				continue
+			}
+			newName = true
+		}
+		add = append(add, e)
+	}
+	// No else needed: optional operation (keep the newest facts)
+	if len(add) > max {
+		add = add[len(add)-max:]
+	}
+
+	var remove []string
+	kept := make([]*Fact, 0, len(stored))
+	for _, f := range stored {
+		// No else needed: optional operation (the new name replaces the old one)
+		if newName && f.Kind == KindName {
+			remove = append(remove, f.ID)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	for excess := len(kept) + len(add) - max; excess > 0 && len(kept) > 0; excess-- {
+		remove = append(remove, kept[0].ID)
+		kept = kept[1:]
+	}
+	return add, remove
+}
+
+// kindLabels are the labels of facts in rendered prompts
+var kindLabels = map[string]string{
+	KindName:       "Name",
+	KindPreference: "Preference",
+	KindNote:       "Note",
+}
+
+// Render formats facts as the value of the {{user_memory}} prompt variable: one
+// labelled line per fact, the name first. Returns the empty string without facts.
+func Render(facts []*Fact) string {
+	var b strings.Builder
+	write := func(f *Fact) {
+		label, ok := kindLabels[f.Kind]
+		// No else needed: optional operation (unknown kinds are notes)
+		if !ok {
+			label = kindLabels[KindNote]
+		}
+		// No else needed: optional operation (one fact per line)
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString("- " + label + ": " + f.Text)
+	}
+	for _, f := range facts {
+		// No else needed: optional operation (name first)
+		if f.Kind == KindName {
+			write(f)
+		}
+	}
+	for _, f := range facts {
+		// No else needed: optional operation (name already written)
+		if f.Kind != KindName {
+			write(f)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Extracted
+	}{
+		{"name", "Hi, my name is Anna.", []Extracted{{KindName, "Anna"}}},
+		{"full name", "My name is Anna Maria Schmidt", []Extracted{{KindName, "Anna Maria Schmidt"}}},
+		{"name then preference", "You can call me Jo and I prefer short answers.", []Extracted{
+			{KindName, "Jo"},
+			{KindPreference, "short answers"},
+		}},
+		{"standing instruction", "Please always answer in German!", []Extracted{{KindPreference, "always answer in German"}}},
+		{"note", "Thanks. Remember that I am vegetarian.", []Extracted{{KindNote, "I am vegetarian"}}},
+		{"question is not a note", "Remember what I told you?", nil},
+		{"mid-sentence remember", "I can't remember the password", nil},
+		{"nothing", "What is the weather like today?", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Extract(tt.text))
+		})
+	}
+}
+
+func TestExtract_TooLong(t *testing.T) {
+	assert.Empty(t, Extract("I prefer "+strings.Repeat("very ", 50)+"long answers"))
+}
+
+func TestMerge(t *testing.T) {
+	stored := []*Fact{
+		{ID: "1", Kind: KindName, Text: "Anna"},
+		{ID: "2", Kind: KindPreference, Text: "short answers"},
+		{ID: "3", Kind: KindNote, Text: "I am vegetarian"},
+	}
+
+	// Known facts are skipped, ignoring case
+	add, remove := Merge(stored, []Extracted{{KindPreference, "Short answers"}}, 10)
+	assert.Empty(t, add)
+	assert.Empty(t, remove)
+
+	// A new name replaces the stored one
+	add, remove = Merge(stored, []Extracted{{KindName, "Jo"}, {KindName, "Joanna"}}, 10)
+	assert.Equal(t, []Extracted{{KindName, "Jo"}}, add)
+	assert.Equal(t, []string{"1"}, remove)
+
+	// The oldest facts are forgotten beyond the limit
+	add, remove = Merge(stored, []Extracted{{KindNote, "I live in Berlin"}, {KindNote, "I have a cat"}}, 3)
+	assert.Len(t, add, 2)
+	assert.Equal(t, []string{"1", "2"}, remove)
+}
+
+func TestRender(t *testing.T) {
+	assert.Equal(t, "", Render(nil))
+
+	now := time.Now()
+	facts := []*Fact{
+		{Kind: KindPreference, Text: "short answers", CreatedAt: now},
+		{Kind: KindName, Text: "Anna", CreatedAt: now},
+		{Kind: KindNote, Text: "I am vegetarian", CreatedAt: now},
+	}
+	assert.Equal(t, "- Name: Anna\n- Preference: short answers\n- Note: I am vegetarian", Render(facts))
+}
@@ -22,6 +22,25 @@ const (
 	TypeModelSelect      MessageType = "model_select"
 	TypeLoading          MessageType = "loading"
 	TypeNotification     MessageType = "notification"
+	TypeAdminMessage     MessageType = "admin_message"
+	TypeQuotaExceeded    MessageType = "quota_exceeded"
+	TypeServerDraining   MessageType = "server_draining"
+	TypeHandback         MessageType = "handback"
+	TypeSessionConfig    MessageType = "session_config"
+	TypeTakeoverDenied   MessageType = "takeover_denied"
+	TypeCancelGeneration MessageType = "cancel_generation"
+	TypeSessionExpiring  MessageType = "session_expiring"
+	TypeMessageFeedback  MessageType = "message_feedback"
+	TypeMessageBegin     MessageType = "message_begin"  // starts a user message sent in chunks
+	TypeMessageAppend    MessageType = "message_append" // adds a chunk to the message
+	TypeMessageCommit    MessageType = "message_commit" // sends the assembled message
+	TypeReadReceipt      MessageType = "read_receipt"   // the user has seen the admin's replies
+	TypeMaintenance      MessageType = "maintenance"    // a scheduled maintenance window is near or in progress
+	TypeQueued           MessageType = "queued"         // the reply waits for a rate limited LLM provider
+	TypeAudioChunk       MessageType = "audio_chunk"    // a chunk of a voice message streamed for transcription
+	TypeTranscript       MessageType = "transcript"     // the transcript so far of a streamed voice message
+	TypeSessionUpdate    MessageType = "session_update" // the session was renamed, ended or deleted elsewhere
+	TypeAIDelta          MessageType = "ai_delta"       // text added to a streamed AI reply, for clients negotiating the delta capability
 )
 
 // SenderType represents who sent the message
@@ -40,27 +59,87 @@ type ModelRef struct {
 	Name string `json:"name"`
 }
 
+// SessionConfig holds the LLM parameters a client sets for its session in a
+// session_config message. Omitted fields keep the model's defaults.
+type SessionConfig struct {
+	Temperature    *float64 `json:"temperature,omitempty"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	TopP           *float64 `json:"top_p,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"` // LLM reply timeout, within the server's bounds
+}
+
+// MessageFeedback is a user's rating of an AI message in a message_feedback
+// message. The message is identified by its index in the session's history.
+type MessageFeedback struct {
+	MessageIndex int    `json:"message_index"`
+	Rating       string `json:"rating"` // "up" or "down"
+	Comment      string `json:"comment,omitempty"`
+}
+
+// AudioChunk is a chunk of a voice message recorded while it is streamed in
+// audio_chunk messages. The recording is identified by the upload_id of the
+// messages; the format is only read from its first chunk.
+type AudioChunk struct {
+	Data   []byte `json:"data,omitempty"`   // audio, base64-encoded in JSON
+	Format string `json:"format,omitempty"` // MIME type, e.g. "audio/webm"
+	Final  bool   `json:"final,omitempty"`  // the recording is complete
+}
+
+// ReconnectPolicy tells clients how to back off before reconnecting: wait
+// about BaseDelayMs * 2^attempt, at most MaxDelayMs, randomized by +/- Jitter
+// of the delay, and give up after MaxAttempts (0 retries forever).
+type ReconnectPolicy struct {
+	BaseDelayMs int     `json:"base_delay_ms"`
+	MaxDelayMs  int     `json:"max_delay_ms"`
+	Jitter      float64 `json:"jitter"` // fraction of the delay, 0 to 1
+	MaxAttempts int     `json:"max_attempts"`
+	Storm       bool    `json:"storm,omitempty"` // delays are raised while the server sees a reconnect storm
+}
+
+// Delta is the compact frame of a streamed AI reply sent to connections that
+// negotiated the delta capability, in place of an ai_response chunk. Seq is the
+// chunk's sequence number for reconnect replay, so deltas are gap-checked and
+// resumed like other messages. The stream ends with an ai_response whose
+// content is the whole reply, marked with the consolidated metadata.
+type Delta struct {
+	Type MessageType `json:"type"` // always TypeAIDelta
+	Seq  uint64      `json:"seq"`
+	Text string      `json:"delta"`
+}
+
 // ErrorInfo contains error details
 type ErrorInfo struct {
 	Code        string `json:"code"`
 	Message     string `json:"message"`
 	Recoverable bool   `json:"recoverable"`
 	RetryAfter  int    `json:"retry_after,omitempty"` // milliseconds
+	Field       string `json:"field,omitempty"`       // field of the client message a validation error is about
+	Rule        string `json:"rule,omitempty"`        // Rule* the field broke, for validation errors
 }
 
 // Message represents a WebSocket message
 type Message struct {
-	Type      MessageType       `json:"type"`
-	SessionID string            `json:"session_id,omitempty"`
-	Content   string            `json:"content,omitempty"`
-	FileID    string            `json:"file_id,omitempty"`
-	FileURL   string            `json:"file_url,omitempty"`
-	ModelID   string            `json:"model_id,omitempty"`
-	Models    []ModelRef        `json:"models,omitempty"`
-	Timestamp time.Time         `json:"timestamp"`
-	Sender    SenderType        `json:"sender"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
-	Error     *ErrorInfo        `json:"error,omitempty"`
+	Type         MessageType       `json:"type"`
+	SessionID    string            `json:"session_id,omitempty"`
+	Content      string            `json:"content,omitempty"`
+	FileID       string            `json:"file_id,omitempty"`
+	FileURL      string            `json:"file_url,omitempty"`
+	ModelID      string            `json:"model_id,omitempty"`
+	Models       []ModelRef        `json:"models,omitempty"`
+	Config       *SessionConfig    `json:"config,omitempty"`          // LLM parameters of a session_config message
+	Feedback     *MessageFeedback  `json:"feedback,omitempty"`        // rating of a message_feedback message
+	UploadID     string            `json:"upload_id,omitempty"`       // chunked message of a message_begin, message_append or message_commit, or recording of an audio_chunk
+	Audio        *AudioChunk       `json:"audio,omitempty"`           // audio of an audio_chunk message
+	ReadAt       *time.Time        `json:"read_at,omitempty"`         // when the user saw the admin's replies, in a read_receipt sent to the admin
+	Timeout      int               `json:"timeout_seconds,omitempty"` // LLM reply timeout of a user_message, overriding the session's
+	Timestamp    time.Time         `json:"timestamp"`
+	Sender       SenderType        `json:"sender"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Error        *ErrorInfo        `json:"error,omitempty"`
+	Seq          uint64            `json:"seq,omitempty"`              // Server-assigned sequence number for reconnect replay
+	Reconnect    *ReconnectPolicy  `json:"reconnect_policy,omitempty"` // backoff guidance in connection_status and server_draining messages
+	Capabilities []string          `json:"capabilities,omitempty"`     // protocol capabilities accepted for the connection, in connection_status messages
+	ReceivedAt   time.Time         `json:"-"`                          // when the server received the message, set by the transport
 }
 
 // MarshalJSON implements custom JSON marshaling for Message
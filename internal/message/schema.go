@@ -0,0 +1,232 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxReportedFieldLength caps field names echoed back in validation errors
+const maxReportedFieldLength = 64
+
+// TypeSpec declares a message type of the protocol: whether clients may send
+// it and the JSON fields it carries besides the envelope fields
+type TypeSpec struct {
+	FromClient bool
+	Fields     []string
+}
+
+// EnvelopeFields are the JSON fields any client message may carry
+var EnvelopeFields = []string{"type", "session_id", "timestamp", "sender", "metadata"}
+
+// registry declares every message type. Types only the server sends list no
+// fields: they are never decoded from client frames.
+var registry = map[MessageType]TypeSpec{
+	TypeUserMessage:      {FromClient: true, Fields: []string{"content", "timeout_seconds"}},
+	TypeHelpRequest:      {FromClient: true, Fields: []string{"content"}},
+	TypeModelSelect:      {FromClient: true, Fields: []string{"model_id"}},
+	TypeFileUpload:       {FromClient: true, Fields: []string{"file_id", "file_url", "content"}},
+	TypeVoiceMessage:     {FromClient: true, Fields: []string{"file_id", "file_url", "content"}},
+	TypeHandback:         {FromClient: true},
+	TypeSessionConfig:    {FromClient: true, Fields: []string{"config"}},
+	TypeCancelGeneration: {FromClient: true},
+	TypeMessageFeedback:  {FromClient: true, Fields: []string{"feedback"}},
+	TypeTypingIndicator:  {FromClient: true},
+	TypeReadReceipt:      {FromClient: true},
+	TypeMessageBegin:     {FromClient: true, Fields: []string{"upload_id", "content"}},
+	TypeMessageAppend:    {FromClient: true, Fields: []string{"upload_id", "content"}},
+	TypeMessageCommit:    {FromClient: true, Fields: []string{"upload_id"}},
+	TypeAudioChunk:       {FromClient: true, Fields: []string{"upload_id", "audio"}},
+
+	TypeAIResponse:       {},
+	TypeError:            {},
+	TypeConnectionStatus: {},
+	TypeAdminJoin:        {},
+	TypeAdminLeave:       {},
+	TypeLoading:          {},
+	TypeNotification:     {},
+	TypeAdminMessage:     {},
+	TypeQuotaExceeded:    {},
+	TypeServerDraining:   {},
+	TypeTakeoverDenied:   {},
+	TypeSessionExpiring:  {},
+	TypeMaintenance:      {},
+	TypeQueued:           {},
+	TypeTranscript:       {},
+	TypeSessionUpdate:    {},
+	TypeAIDelta:          {},
+}
+
+// Spec returns the declaration of message type t
+func Spec(t MessageType) (TypeSpec, bool) {
+	spec, ok := registry[t]
+	return spec, ok
+}
+
+// ClientTypes returns the message types clients may send, sorted
+func ClientTypes() []MessageType {
+	var types []MessageType
+	for t, spec := range registry {
+		// No else needed: optional operation (server-only types skipped)
+		if spec.FromClient {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Decode parses a client frame into a message. Every frame must be a JSON object
+// of a declared type whose fields have the declared JSON types. In strict mode
+// the type must be one clients send, and fields the type does not declare,
+// including unknown fields of config, feedback and audio, are rejected. Errors are
+// *ValidationError naming the offending field; required fields and lengths are
+// checked afterwards by Validate.
+func Decode(data []byte, strict bool) (*Message, error) {
+	var fields map[string]json.RawMessage
+	// No else needed: early return pattern (guard clause)
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return nil, &ValidationError{Rule: RuleInvalid, Message: "message must be a JSON object"}
+	}
+
+	var msgType MessageType
+	raw, ok := fields["type"]
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, &ValidationError{Field: "type", Rule: RuleRequired, Message: "type is required"}
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := json.Unmarshal(raw, &msgType); err != nil {
+		return nil, &ValidationError{Field: "type", Rule: RuleInvalid, Message: "type must be a string"}
+	}
+	spec, declared := registry[msgType]
+	// No else needed: early return pattern (guard clause)
+	if !declared {
+		return nil, &ValidationError{Field: "type", Rule: RuleInvalid, Message: fmt.Sprintf("invalid message type: %s", reportedField(string(msgType)))}
+	}
+
+	// No else needed: optional operation (strict schema)
+	if strict {
+		// No else needed: early return pattern (guard clause)
+		if err := checkStrict(msgType, spec, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	var msg Message
+	// No else needed: early return pattern (guard clause)
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, decodeError(err)
+	}
+	return &msg, nil
+}
+
+// checkStrict rejects a frame of a server-only type or with fields its type
+// does not declare
+func checkStrict(msgType MessageType, spec TypeSpec, fields map[string]json.RawMessage) error {
+	// No else needed: early return pattern (guard clause)
+	if !spec.FromClient {
+		return &ValidationError{Field: "type", Rule: RuleNotAllowed, Message: fmt.Sprintf("%s is only sent by the server", msgType)}
+	}
+
+	allowed := make(map[string]bool, len(EnvelopeFields)+len(spec.Fields))
+	for _, name := range EnvelopeFields {
+		allowed[name] = true
+	}
+	for _, name := range spec.Fields {
+		allowed[name] = true
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Report the same field for the same frame
+	for _, name := range names {
+		// No else needed: early return pattern (guard clause)
+		if !allowed[name] {
+			return &ValidationError{
+				Field:   reportedField(name),
+				Rule:    RuleUnknownField,
+				Message: fmt.Sprintf("unknown field for %s", msgType),
+			}
+		}
+	}
+
+	// Nested objects are checked against their structs
+	nested := map[string]interface{}{"config": &SessionConfig{}, "feedback": &MessageFeedback{}, "audio": &AudioChunk{}}
+	for _, name := range names {
+		target, ok := nested[name]
+		// No else needed: early return pattern (not a nested object)
+		if !ok || bytes.Equal(fields[name], []byte("null")) {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(fields[name]))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(target)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			verr := decodeError(err)
+			verr.Field = name + "." + verr.Field
+			// No else needed: conditional assignment (error about the object itself)
+			if strings.HasSuffix(verr.Field, ".") {
+				verr.Field = name
+			}
+			return verr
+		}
+	}
+	return nil
+}
+
+// decodeError converts a JSON decoding error into a validation error of the
+// field it is about, without echoing the frame's content
+func decodeError(err error) *ValidationError {
+	var typeErr *json.UnmarshalTypeError
+	// No else needed: early return pattern (wrong JSON type)
+	if errors.As(err, &typeErr) {
+		return &ValidationError{
+			Field:   reportedField(typeErr.Field),
+			Rule:    RuleInvalid,
+			Message: fmt.Sprintf("must be a JSON %s", jsonTypeName(typeErr.Type)),
+		}
+	}
+	// No else needed: early return pattern (unknown field of a nested object)
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &ValidationError{Field: reportedField(strings.Trim(name, `"`)), Rule: RuleUnknownField, Message: "unknown field"}
+	}
+	var timeErr *time.ParseError
+	// No else needed: early return pattern (Message.UnmarshalJSON parses the timestamp)
+	if errors.As(err, &timeErr) {
+		return &ValidationError{Field: "timestamp", Rule: RuleInvalid, Message: "timestamp must be an RFC3339 date-time"}
+	}
+	return &ValidationError{Rule: RuleInvalid, Message: "message is not valid JSON"}
+}
+
+// jsonTypeName names the JSON type a Go type decodes from
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Map, reflect.Struct, reflect.Pointer:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "number"
+	}
+}
+
+// reportedField truncates a client-supplied name echoed in an error
+func reportedField(name string) string {
+	// No else needed: early return pattern (short name)
+	if len(name) <= maxReportedFieldLength {
+		return name
+	}
+	return name[:maxReportedFieldLength] + "..."
+}
@@ -0,0 +1,107 @@
+package message
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_DeclaresEveryType(t *testing.T) {
+	types := []MessageType{
+		TypeUserMessage, TypeAIResponse, TypeFileUpload, TypeVoiceMessage,
+		TypeError, TypeConnectionStatus, TypeTypingIndicator, TypeHelpRequest,
+		TypeAdminJoin, TypeAdminLeave, TypeModelSelect, TypeLoading,
+		TypeNotification, TypeAdminMessage, TypeQuotaExceeded, TypeServerDraining,
+		TypeHandback, TypeSessionConfig, TypeTakeoverDenied, TypeCancelGeneration,
+		TypeSessionExpiring, TypeMessageFeedback, TypeMessageBegin, TypeMessageAppend,
+		TypeMessageCommit, TypeReadReceipt, TypeMaintenance, TypeQueued,
+		TypeAudioChunk, TypeTranscript, TypeSessionUpdate,
+	}
+	for _, msgType := range types {
+		_, ok := Spec(msgType)
+		assert.True(t, ok, "%s is not declared", msgType)
+	}
+
+	clientTypes := ClientTypes()
+	assert.Contains(t, clientTypes, TypeUserMessage)
+	assert.NotContains(t, clientTypes, TypeAIResponse)
+}
+
+func TestDecode(t *testing.T) {
+	msg, err := Decode([]byte(`{"type":"user_message","session_id":"s1","content":"hi","timestamp":"2024-01-01T00:00:00Z","extra":1}`), false)
+	require.NoError(t, err)
+	assert.Equal(t, TypeUserMessage, msg.Type)
+	assert.Equal(t, "hi", msg.Content)
+	assert.False(t, msg.Timestamp.IsZero())
+
+	msg, err = Decode([]byte(`{"type":"session_config","session_id":"s1","config":{"temperature":0.5}}`), true)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, *msg.Config.Temperature)
+
+	msg, err = Decode([]byte(`{"type":"user_message","session_id":"s1","content":"hi","timeout_seconds":600}`), true)
+	require.NoError(t, err)
+	assert.Equal(t, 600, msg.Timeout)
+
+	msg, err = Decode([]byte(`{"type":"session_config","session_id":"s1","config":{"timeout_seconds":300}}`), true)
+	require.NoError(t, err)
+	assert.Equal(t, 300, msg.Config.TimeoutSeconds)
+
+	msg, err = Decode([]byte(`{"type":"audio_chunk","session_id":"s1","upload_id":"v1","audio":{"data":"AAE=","format":"audio/ogg","final":true}}`), true)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1}, msg.Audio.Data)
+	assert.Equal(t, "audio/ogg", msg.Audio.Format)
+	assert.True(t, msg.Audio.Final)
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		frame  string
+		strict bool
+		field  string
+		rule   string
+	}{
+		{"not JSON", `hello`, false, "", RuleInvalid},
+		{"not an object", `["user_message"]`, false, "", RuleInvalid},
+		{"null", `null`, false, "", RuleInvalid},
+		{"no type", `{"content":"hi"}`, false, "type", RuleRequired},
+		{"type not a string", `{"type":1}`, false, "type", RuleInvalid},
+		{"unknown type", `{"type":"shout"}`, false, "type", RuleInvalid},
+		{"wrong field type", `{"type":"user_message","content":42}`, false, "content", RuleInvalid},
+		{"wrong nested type", `{"type":"session_config","config":{"max_tokens":"many"}}`, false, "config.max_tokens", RuleInvalid},
+		{"bad timestamp", `{"type":"user_message","timestamp":"yesterday"}`, false, "timestamp", RuleInvalid},
+		{"unknown field", `{"type":"user_message","content":"hi","colour":"red"}`, true, "colour", RuleUnknownField},
+		{"field of another type", `{"type":"user_message","content":"hi","model_id":"gpt"}`, true, "model_id", RuleUnknownField},
+		{"unknown nested field", `{"type":"message_feedback","feedback":{"rating":"up","stars":5}}`, true, "feedback.stars", RuleUnknownField},
+		{"unknown audio field", `{"type":"audio_chunk","audio":{"data":"AAE=","rate":16000}}`, true, "audio.rate", RuleUnknownField},
+		{"server type", `{"type":"ai_response","content":"hi"}`, true, "type", RuleNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.frame), tt.strict)
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr), "got %v", err)
+			assert.Equal(t, tt.field, verr.Field)
+			assert.Equal(t, tt.rule, verr.Rule)
+			assert.NotEmpty(t, verr.Message)
+		})
+	}
+}
+
+func TestDecode_LenientIgnoresUndeclaredFields(t *testing.T) {
+	_, err := Decode([]byte(`{"type":"user_message","content":"hi","colour":"red"}`), false)
+	assert.NoError(t, err)
+	_, err = Decode([]byte(`{"type":"ai_response","content":"hi"}`), false)
+	assert.NoError(t, err, "lenient decoding leaves type checks to the router")
+}
+
+func TestDecode_TruncatesEchoedNames(t *testing.T) {
+	long := strings.Repeat("x", 500)
+	_, err := Decode([]byte(`{"type":"user_message","`+long+`":1}`), true)
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Len(t, verr.Field, maxReportedFieldLength+len("..."))
+}
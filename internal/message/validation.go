@@ -16,9 +16,19 @@ const (
 	MaxSessionIDLength = 128   // Maximum session ID length
 )
 
+// Validation rules a field can break, reported to clients with the field
+const (
+	RuleRequired     = "required"      // A required field is missing or empty
+	RuleInvalid      = "invalid"       // A value of the wrong JSON type or outside the allowed values
+	RuleTooLong      = "too_long"      // A value longer than its maximum length
+	RuleUnknownField = "unknown_field" // A field the message type does not declare (strict mode)
+	RuleNotAllowed   = "not_allowed"   // A type or sender the client may not use
+)
+
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field   string
+	Rule    string // Rule* constant, empty when not classified
 	Message string
 }
 
@@ -28,6 +38,12 @@ func (e *ValidationError) Error() string {
 
 // Validate validates a message according to the protocol specification
 func (m *Message) Validate() error {
+	return m.ValidateWithContentLimit(MaxContentLength)
+}
+
+// ValidateWithContentLimit validates a message like Validate, allowing content of
+// up to maxContentLength bytes. Used for messages assembled from chunks.
+func (m *Message) ValidateWithContentLimit(maxContentLength int) error {
 	// Validate required fields for all messages
 	if err := m.validateRequiredFields(); err != nil {
 		return err
@@ -39,7 +55,7 @@ func (m *Message) Validate() error {
 	}
 
 	// Validate field lengths
-	if err := m.validateFieldLengths(); err != nil {
+	if err := m.validateFieldLengths(maxContentLength); err != nil {
 		return err
 	}
 
@@ -50,32 +66,32 @@ func (m *Message) Validate() error {
 func (m *Message) validateRequiredFields() error {
 	// Type is required
 	if m.Type == "" {
-		return &ValidationError{Field: "type", Message: "type is required"}
+		return &ValidationError{Field: "type", Rule: RuleRequired, Message: "type is required"}
 	}
 
 	// Validate type is a known message type
 	if !isValidMessageType(m.Type) {
-		return &ValidationError{Field: "type", Message: fmt.Sprintf("invalid message type: %s", m.Type)}
+		return &ValidationError{Field: "type", Rule: RuleInvalid, Message: fmt.Sprintf("invalid message type: %s", m.Type)}
 	}
 
 	// Sender is required
 	if m.Sender == "" {
-		return &ValidationError{Field: "sender", Message: "sender is required"}
+		return &ValidationError{Field: "sender", Rule: RuleRequired, Message: "sender is required"}
 	}
 
 	// Validate sender is a known sender type
 	if !isValidSenderType(m.Sender) {
-		return &ValidationError{Field: "sender", Message: fmt.Sprintf("invalid sender type: %s", m.Sender)}
+		return &ValidationError{Field: "sender", Rule: RuleInvalid, Message: fmt.Sprintf("invalid sender type: %s", m.Sender)}
 	}
 
 	// Timestamp is required and must not be zero
 	if m.Timestamp.IsZero() {
-		return &ValidationError{Field: "timestamp", Message: "timestamp is required"}
+		return &ValidationError{Field: "timestamp", Rule: RuleRequired, Message: "timestamp is required"}
 	}
 
 	// Timestamp should not be in the future (with 1 minute tolerance for clock skew)
 	if m.Timestamp.After(time.Now().Add(1 * time.Minute)) {
-		return &ValidationError{Field: "timestamp", Message: "timestamp cannot be in the future"}
+		return &ValidationError{Field: "timestamp", Rule: RuleInvalid, Message: "timestamp cannot be in the future"}
 	}
 
 	return nil
@@ -86,56 +102,102 @@ func (m *Message) validateTypeSpecificFields() error {
 	switch m.Type {
 	case TypeUserMessage:
 		if m.Content == "" {
-			return &ValidationError{Field: "content", Message: "content is required for user_message"}
+			return &ValidationError{Field: "content", Rule: RuleRequired, Message: "content is required for user_message"}
 		}
 
 	case TypeAIResponse:
 		if m.Content == "" {
-			return &ValidationError{Field: "content", Message: "content is required for ai_response"}
+			return &ValidationError{Field: "content", Rule: RuleRequired, Message: "content is required for ai_response"}
 		}
 
 	case TypeFileUpload:
 		if m.FileID == "" {
-			return &ValidationError{Field: "file_id", Message: "file_id is required for file_upload"}
+			return &ValidationError{Field: "file_id", Rule: RuleRequired, Message: "file_id is required for file_upload"}
 		}
 		if m.FileURL == "" {
-			return &ValidationError{Field: "file_url", Message: "file_url is required for file_upload"}
+			return &ValidationError{Field: "file_url", Rule: RuleRequired, Message: "file_url is required for file_upload"}
 		}
 
 	case TypeVoiceMessage:
 		if m.FileID == "" {
-			return &ValidationError{Field: "file_id", Message: "file_id is required for voice_message"}
+			return &ValidationError{Field: "file_id", Rule: RuleRequired, Message: "file_id is required for voice_message"}
 		}
 		if m.FileURL == "" {
-			return &ValidationError{Field: "file_url", Message: "file_url is required for voice_message"}
+			return &ValidationError{Field: "file_url", Rule: RuleRequired, Message: "file_url is required for voice_message"}
 		}
 
 	case TypeError:
 		if m.Error == nil {
-			return &ValidationError{Field: "error", Message: "error is required for error message type"}
+			return &ValidationError{Field: "error", Rule: RuleRequired, Message: "error is required for error message type"}
 		}
 		if m.Error.Code == "" {
-			return &ValidationError{Field: "error.code", Message: "error code is required"}
+			return &ValidationError{Field: "error.code", Rule: RuleRequired, Message: "error code is required"}
 		}
 		if m.Error.Message == "" {
-			return &ValidationError{Field: "error.message", Message: "error message is required"}
+			return &ValidationError{Field: "error.message", Rule: RuleRequired, Message: "error message is required"}
 		}
 
 	case TypeModelSelect:
 		if m.ModelID == "" {
-			return &ValidationError{Field: "model_id", Message: "model_id is required for model_select"}
+			return &ValidationError{Field: "model_id", Rule: RuleRequired, Message: "model_id is required for model_select"}
+		}
+
+	case TypeSessionConfig:
+		if m.SessionID == "" {
+			return &ValidationError{Field: "session_id", Rule: RuleRequired, Message: "session_id is required for session_config"}
+		}
+		if m.Config == nil {
+			return &ValidationError{Field: "config", Rule: RuleRequired, Message: "config is required for session_config"}
+		}
+
+	case TypeCancelGeneration:
+		if m.SessionID == "" {
+			return &ValidationError{Field: "session_id", Rule: RuleRequired, Message: "session_id is required for cancel_generation"}
+		}
+
+	case TypeMessageFeedback:
+		if m.SessionID == "" {
+			return &ValidationError{Field: "session_id", Rule: RuleRequired, Message: "session_id is required for message_feedback"}
+		}
+		if m.Feedback == nil {
+			return &ValidationError{Field: "feedback", Rule: RuleRequired, Message: "feedback is required for message_feedback"}
+		}
+
+	case TypeReadReceipt:
+		if m.SessionID == "" {
+			return &ValidationError{Field: "session_id", Rule: RuleRequired, Message: "session_id is required for read_receipt"}
+		}
+
+	case TypeAudioChunk:
+		if m.SessionID == "" {
+			return &ValidationError{Field: "session_id", Rule: RuleRequired, Message: "session_id is required for audio_chunk"}
+		}
+		if m.UploadID == "" {
+			return &ValidationError{Field: "upload_id", Rule: RuleRequired, Message: "upload_id is required for audio_chunk"}
+		}
+		if m.Audio == nil {
+			return &ValidationError{Field: "audio", Rule: RuleRequired, Message: "audio is required for audio_chunk"}
 		}
 
 	case TypeAdminJoin, TypeAdminLeave:
 		// Admin messages should have admin sender
 		if m.Sender != SenderAdmin {
-			return &ValidationError{Field: "sender", Message: fmt.Sprintf("sender must be 'admin' for %s", m.Type)}
+			return &ValidationError{Field: "sender", Rule: RuleNotAllowed, Message: fmt.Sprintf("sender must be 'admin' for %s", m.Type)}
+		}
+
+	case TypeHandback:
+		// Handback comes from an admin and names the session to return to the AI
+		if m.Sender != SenderAdmin {
+			return &ValidationError{Field: "sender", Rule: RuleNotAllowed, Message: "sender must be 'admin' for handback"}
+		}
+		if m.SessionID == "" {
+			return &ValidationError{Field: "session_id", Rule: RuleRequired, Message: "session_id is required for handback"}
 		}
 
 	case TypeHelpRequest:
 		// Help request should come from user
 		if m.Sender != SenderUser {
-			return &ValidationError{Field: "sender", Message: "sender must be 'user' for help_request"}
+			return &ValidationError{Field: "sender", Rule: RuleNotAllowed, Message: "sender must be 'user' for help_request"}
 		}
 	}
 
@@ -143,24 +205,27 @@ func (m *Message) validateTypeSpecificFields() error {
 }
 
 // validateFieldLengths validates that field values don't exceed maximum lengths
-func (m *Message) validateFieldLengths() error {
+func (m *Message) validateFieldLengths(maxContentLength int) error {
 	if len(m.SessionID) > MaxSessionIDLength {
 		return &ValidationError{
 			Field:   "session_id",
+			Rule:    RuleTooLong,
 			Message: fmt.Sprintf("session_id exceeds maximum length of %d characters", MaxSessionIDLength),
 		}
 	}
 
-	if len(m.Content) > MaxContentLength {
+	if len(m.Content) > maxContentLength {
 		return &ValidationError{
 			Field:   "content",
-			Message: fmt.Sprintf("content exceeds maximum length of %d characters", MaxContentLength),
+			Rule:    RuleTooLong,
+			Message: fmt.Sprintf("content exceeds maximum length of %d characters", maxContentLength),
 		}
 	}
 
 	if len(m.FileID) > MaxFileIDLength {
 		return &ValidationError{
 			Field:   "file_id",
+			Rule:    RuleTooLong,
 			Message: fmt.Sprintf("file_id exceeds maximum length of %d characters", MaxFileIDLength),
 		}
 	}
@@ -168,6 +233,7 @@ func (m *Message) validateFieldLengths() error {
 	if len(m.FileURL) > MaxFileURLLength {
 		return &ValidationError{
 			Field:   "file_url",
+			Rule:    RuleTooLong,
 			Message: fmt.Sprintf("file_url exceeds maximum length of %d characters", MaxFileURLLength),
 		}
 	}
@@ -175,6 +241,7 @@ func (m *Message) validateFieldLengths() error {
 	if len(m.ModelID) > MaxModelIDLength {
 		return &ValidationError{
 			Field:   "model_id",
+			Rule:    RuleTooLong,
 			Message: fmt.Sprintf("model_id exceeds maximum length of %d characters", MaxModelIDLength),
 		}
 	}
@@ -184,6 +251,7 @@ func (m *Message) validateFieldLengths() error {
 		if len(value) > MaxMetadataLength {
 			return &ValidationError{
 				Field:   fmt.Sprintf("metadata.%s", key),
+				Rule:    RuleTooLong,
 				Message: fmt.Sprintf("metadata value exceeds maximum length of %d characters", MaxMetadataLength),
 			}
 		}
@@ -216,6 +284,16 @@ func (m *Message) Sanitize() {
 		m.Metadata = sanitizedMetadata
 	}
 
+	// Sanitize feedback comment if present
+	if m.Feedback != nil {
+		m.Feedback.Comment = sanitizeString(m.Feedback.Comment)
+	}
+
+	// Sanitize audio format if present; the audio itself is binary
+	if m.Audio != nil {
+		m.Audio.Format = sanitizeString(m.Audio.Format)
+	}
+
 	// Sanitize error info if present
 	if m.Error != nil {
 		m.Error.Code = sanitizeString(m.Error.Code)
@@ -237,17 +315,10 @@ func sanitizeString(s string) string {
 	return s
 }
 
-// isValidMessageType checks if the message type is valid
+// isValidMessageType checks if the message type is declared in the registry
 func isValidMessageType(t MessageType) bool {
-	switch t {
-	case TypeUserMessage, TypeAIResponse, TypeFileUpload, TypeVoiceMessage,
-		TypeError, TypeConnectionStatus, TypeTypingIndicator, TypeHelpRequest,
-		TypeAdminJoin, TypeAdminLeave, TypeModelSelect, TypeLoading,
-		TypeNotification:
-		return true
-	default:
-		return false
-	}
+	_, ok := registry[t]
+	return ok
 }
 
 // isValidSenderType checks if the sender type is valid
@@ -356,6 +356,81 @@ func TestValidate_TypeSpecificFields(t *testing.T) {
 			expectedField: "sender",
 			expectedError: "sender must be 'admin' for admin_leave",
 		},
+		{
+			name: "handback with non-admin sender",
+			message: Message{
+				Type:      TypeHandback,
+				SessionID: "session-1",
+				Timestamp: time.Now(),
+				Sender:    SenderUser,
+			},
+			expectedField: "sender",
+			expectedError: "sender must be 'admin' for handback",
+		},
+		{
+			name: "handback without session ID",
+			message: Message{
+				Type:      TypeHandback,
+				Timestamp: time.Now(),
+				Sender:    SenderAdmin,
+			},
+			expectedField: "session_id",
+			expectedError: "session_id is required for handback",
+		},
+		{
+			name: "session config without config",
+			message: Message{
+				Type:      TypeSessionConfig,
+				SessionID: "session-1",
+				Timestamp: time.Now(),
+				Sender:    SenderUser,
+			},
+			expectedField: "config",
+			expectedError: "config is required for session_config",
+		},
+		{
+			name: "cancel generation without session ID",
+			message: Message{
+				Type:      TypeCancelGeneration,
+				Timestamp: time.Now(),
+				Sender:    SenderUser,
+			},
+			expectedField: "session_id",
+			expectedError: "session_id is required for cancel_generation",
+		},
+		{
+			name: "message feedback without feedback",
+			message: Message{
+				Type:      TypeMessageFeedback,
+				SessionID: "session-1",
+				Timestamp: time.Now(),
+				Sender:    SenderUser,
+			},
+			expectedField: "feedback",
+			expectedError: "feedback is required for message_feedback",
+		},
+		{
+			name: "read receipt without session ID",
+			message: Message{
+				Type:      TypeReadReceipt,
+				Timestamp: time.Now(),
+				Sender:    SenderUser,
+			},
+			expectedField: "session_id",
+			expectedError: "session_id is required for read_receipt",
+		},
+		{
+			name: "audio chunk without audio",
+			message: Message{
+				Type:      TypeAudioChunk,
+				SessionID: "session-1",
+				UploadID:  "voice-1",
+				Timestamp: time.Now(),
+				Sender:    SenderUser,
+			},
+			expectedField: "audio",
+			expectedError: "audio is required for audio_chunk",
+		},
 		{
 			name: "help request with non-user sender",
 			message: Message{
@@ -739,7 +814,7 @@ func TestIsValidMessageType(t *testing.T) {
 		TypeUserMessage, TypeAIResponse, TypeFileUpload, TypeVoiceMessage,
 		TypeError, TypeConnectionStatus, TypeTypingIndicator, TypeHelpRequest,
 		TypeAdminJoin, TypeAdminLeave, TypeModelSelect, TypeLoading,
-		TypeNotification,
+		TypeNotification, TypeCancelGeneration, TypeSessionExpiring, TypeMaintenance,
 	}
 
 	for _, msgType := range validTypes {
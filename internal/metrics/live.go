@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// LiveSnapshot holds the real-time counters pushed to admin dashboards. Rates
+// and the latency percentile cover the sampler's window, or the time since the
+// first sample while the sampler is younger than its window.
+type LiveSnapshot struct {
+	Timestamp            time.Time `json:"timestamp"`
+	ActiveSessions       int64     `json:"active_sessions"`
+	Connections          int64     `json:"connections"`
+	MessagesPerMinute    float64   `json:"messages_per_minute"`     // client messages received
+	LLMRequestsPerMinute float64   `json:"llm_requests_per_minute"` // requests to every provider
+	LLMLatencyP95Ms      float64   `json:"llm_latency_p95_ms"`      // 0 when no LLM request finished in the window
+	WindowSeconds        float64   `json:"window_seconds"`
+}
+
+// liveSample is a reading of the cumulative metrics behind a LiveSnapshot
+type liveSample struct {
+	at          time.Time
+	received    float64
+	llmRequests float64
+	llmCount    uint64
+	llmBuckets  []uint64 // cumulative counts per upper bound in llmBounds
+}
+
+// LiveSampler turns the cumulative Prometheus metrics of this process into
+// windowed rates. It is not safe for concurrent use; each dashboard stream
+// owns one.
+type LiveSampler struct {
+	window    time.Duration
+	llmBounds []float64
+	samples   []liveSample
+}
+
+// NewLiveSampler returns a sampler reporting rates over window
+func NewLiveSampler(window time.Duration) *LiveSampler {
+	return &LiveSampler{window: window}
+}
+
+// Sample reads the metrics at now and returns the snapshot for the window ending at now
+func (s *LiveSampler) Sample(now time.Time) LiveSnapshot {
+	current := s.read(now)
+	s.samples = append(s.samples, current)
+
+	// Keep the newest sample at or before the window start as the baseline
+	cutoff := now.Add(-s.window)
+	for len(s.samples) > 2 && !s.samples[1].at.After(cutoff) {
+		s.samples = s.samples[1:]
+	}
+	base := s.samples[0]
+
+	snapshot := LiveSnapshot{
+		Timestamp:      now.UTC(),
+		ActiveSessions: int64(gaugeValue(ActiveSessions)),
+		Connections:    int64(gaugeValue(WebSocketConnections)),
+	}
+	elapsed := current.at.Sub(base.at)
+	// No else needed: early return pattern (first sample, no rate yet)
+	if elapsed <= 0 {
+		return snapshot
+	}
+
+	perMinute := time.Minute.Seconds() / elapsed.Seconds()
+	snapshot.WindowSeconds = elapsed.Seconds()
+	snapshot.MessagesPerMinute = (current.received - base.received) * perMinute
+	snapshot.LLMRequestsPerMinute = (current.llmRequests - base.llmRequests) * perMinute
+	snapshot.LLMLatencyP95Ms = histogramQuantile(0.95, s.llmBounds, diffCounts(current.llmBuckets, base.llmBuckets), current.llmCount-base.llmCount) * 1000
+	return snapshot
+}
+
+// read takes a liveSample of the metrics registry
+func (s *LiveSampler) read(now time.Time) liveSample {
+	sample := liveSample{
+		at:          now,
+		received:    counterValue(MessagesReceived),
+		llmRequests: collectorSum(LLMRequests),
+	}
+
+	// Sum the LLM latency histogram over every provider
+	for _, m := range collect(LLMLatency) {
+		h := m.GetHistogram()
+		// No else needed: optional operation (skip non-histogram metrics)
+		if h == nil {
+			continue
+		}
+		// No else needed: conditional initialization (bounds are fixed per histogram)
+		if s.llmBounds == nil {
+			for _, b := range h.GetBucket() {
+				s.llmBounds = append(s.llmBounds, b.GetUpperBound())
+			}
+		}
+		// No else needed: conditional initialization (first provider of the sample)
+		if sample.llmBuckets == nil {
+			sample.llmBuckets = make([]uint64, len(s.llmBounds))
+		}
+		for i, b := range h.GetBucket() {
+			// No else needed: optional operation (guard against a bucket layout change)
+			if i < len(sample.llmBuckets) {
+				sample.llmBuckets[i] += b.GetCumulativeCount()
+			}
+		}
+		sample.llmCount += h.GetSampleCount()
+	}
+	return sample
+}
+
+// histogramQuantile estimates the q-quantile of observations from cumulative
+// bucket counts, interpolating linearly within the bucket like Prometheus'
+// histogram_quantile. Observations above the last bound report the last bound.
+func histogramQuantile(q float64, bounds []float64, cumulative []uint64, total uint64) float64 {
+	// No else needed: early return pattern (no observations)
+	if total == 0 || len(bounds) == 0 || len(cumulative) != len(bounds) {
+		return 0
+	}
+
+	rank := q * float64(total)
+	lower, below := 0.0, uint64(0)
+	for i, upper := range bounds {
+		// No else needed: optional operation (rank not reached yet)
+		if float64(cumulative[i]) >= rank {
+			inBucket := cumulative[i] - below
+			// No else needed: early return pattern (avoid dividing by an empty bucket)
+			if inBucket == 0 {
+				return upper
+			}
+			return lower + (upper-lower)*(rank-float64(below))/float64(inBucket)
+		}
+		lower, below = upper, cumulative[i]
+	}
+	return bounds[len(bounds)-1]
+}
+
+// diffCounts returns the per-bucket difference of two cumulative readings
+func diffCounts(current, base []uint64) []uint64 {
+	diff := make([]uint64, len(current))
+	for i := range current {
+		diff[i] = current[i]
+		// No else needed: optional operation (base has no reading before the first request)
+		if i < len(base) {
+			diff[i] -= base[i]
+		}
+	}
+	return diff
+}
+
+// collect returns the metrics of a collector
+func collect(c prometheus.Collector) []*dto.Metric {
+	ch := make(chan prometheus.Metric, 16)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	var out []*dto.Metric
+	for m := range ch {
+		var pb dto.Metric
+		// No else needed: optional operation (skip metrics that cannot be read)
+		if err := m.Write(&pb); err == nil {
+			out = append(out, &pb)
+		}
+	}
+	return out
+}
+
+// collectorSum returns the sum of the counters of a collector over every label value
+func collectorSum(c prometheus.Collector) float64 {
+	var sum float64
+	for _, m := range collect(c) {
+		sum += m.GetCounter().GetValue()
+	}
+	return sum
+}
+
+// counterValue returns the value of a counter
+func counterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	// No else needed: early return pattern (unreadable counter)
+	if err := c.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
+
+// gaugeValue returns the value of a gauge, never below 0
+func gaugeValue(g prometheus.Gauge) float64 {
+	var m dto.Metric
+	// No else needed: early return pattern (unreadable gauge)
+	if err := g.Write(&m); err != nil {
+		return 0
+	}
+	return math.Max(m.GetGauge().GetValue(), 0)
+}
+
+// LLMTotals returns the LLM requests and errors of this process so far, summed
+// over every provider
+func LLMTotals() (requests, errors float64) {
+	return collectorSum(LLMRequests), collectorSum(LLMErrors)
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestHistogramQuantile verifies interpolation within and past the buckets
+func TestHistogramQuantile(t *testing.T) {
+	bounds := []float64{0.1, 1, 10}
+
+	tests := []struct {
+		name       string
+		cumulative []uint64
+		total      uint64
+		want       float64
+	}{
+		{"no observations", []uint64{0, 0, 0}, 0, 0},
+		{"all in first bucket", []uint64{10, 10, 10}, 10, 0.095},
+		{"interpolated in second bucket", []uint64{0, 20, 20}, 20, 0.955},
+		{"above the last bound", []uint64{0, 0, 0}, 5, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := histogramQuantile(0.95, bounds, tt.cumulative, tt.total)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Expected %f, got %f", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestLiveSamplerRates verifies that rates and the latency percentile cover the window
+func TestLiveSamplerRates(t *testing.T) {
+	sampler := NewLiveSampler(time.Minute)
+	start := time.Now()
+
+	first := sampler.Sample(start)
+	if first.WindowSeconds != 0 || first.MessagesPerMinute != 0 {
+		t.Errorf("Expected no rates on the first sample, got %+v", first)
+	}
+
+	for i := 0; i < 10; i++ {
+		MessagesReceived.Inc()
+		LLMRequests.WithLabelValues("live-test").Inc()
+		LLMLatency.WithLabelValues("live-test").Observe(0.2)
+	}
+
+	// Ten messages in 30 seconds are 20 per minute
+	snapshot := sampler.Sample(start.Add(30 * time.Second))
+	if snapshot.WindowSeconds != 30 {
+		t.Errorf("Expected a 30 second window, got %f", snapshot.WindowSeconds)
+	}
+	if snapshot.MessagesPerMinute != 20 {
+		t.Errorf("Expected 20 messages per minute, got %f", snapshot.MessagesPerMinute)
+	}
+	if snapshot.LLMRequestsPerMinute != 20 {
+		t.Errorf("Expected 20 LLM requests per minute, got %f", snapshot.LLMRequestsPerMinute)
+	}
+	// 0.2s falls in the (0.1, 0.25] default bucket
+	if snapshot.LLMLatencyP95Ms <= 100 || snapshot.LLMLatencyP95Ms > 250 {
+		t.Errorf("Expected p95 between 100 and 250 ms, got %f", snapshot.LLMLatencyP95Ms)
+	}
+
+	// Once the window has passed, older activity no longer counts
+	sampler.Sample(start.Add(90 * time.Second))
+	idle := sampler.Sample(start.Add(150 * time.Second))
+	if idle.MessagesPerMinute != 0 || idle.LLMLatencyP95Ms != 0 {
+		t.Errorf("Expected an idle window, got %+v", idle)
+	}
+	if idle.WindowSeconds != 60 {
+		t.Errorf("Expected a 60 second window, got %f", idle.WindowSeconds)
+	}
+}
+
+func TestLLMTotals(t *testing.T) {
+	requests, errors := LLMTotals()
+	LLMRequests.WithLabelValues("totals-test").Add(3)
+	LLMErrors.WithLabelValues("totals-test").Inc()
+
+	afterRequests, afterErrors := LLMTotals()
+	if afterRequests-requests != 3 || afterErrors-errors != 1 {
+		t.Errorf("Expected 3 more requests and 1 more error, got %f and %f", afterRequests-requests, afterErrors-errors)
+	}
+}
@@ -44,6 +44,44 @@ var (
 		Help: "Total number of LLM errors by provider",
 	}, []string{"provider"})
 
+	// LLMFallbacks tracks replies served by a fallback model because the session's
+	// model failed (model: the session's model; fallback: the model that served)
+	LLMFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_llm_fallbacks_total",
+		Help: "Total number of LLM requests served by a fallback model",
+	}, []string{"model", "fallback"})
+
+	// LLMRateLimited tracks provider rate limit (429) rejections that sent
+	// requests to the provider's send queue
+	LLMRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_llm_rate_limited_total",
+		Help: "Total number of LLM requests rejected by a provider's rate limits",
+	}, []string{"provider"})
+
+	// LLMQueueDepth tracks the requests waiting in each provider's send queue
+	LLMQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "chatbox_llm_queue_depth",
+		Help: "Number of LLM requests waiting for a rate limited provider",
+	}, []string{"provider"})
+
+	// ClusterMessagesForwarded tracks envelopes sent to the replica holding a connection
+	ClusterMessagesForwarded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_cluster_forwarded_total",
+		Help: "Total number of messages forwarded to another replica",
+	}, []string{"kind"})
+
+	// ClusterMessagesReceived tracks envelopes received from other replicas
+	ClusterMessagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_cluster_received_total",
+		Help: "Total number of messages received from other replicas",
+	}, []string{"kind"})
+
+	// ClusterForwardFailures tracks forwards that failed or found their replica gone
+	ClusterForwardFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chatbox_cluster_forward_failures_total",
+		Help: "Total number of messages that could not be forwarded to another replica",
+	})
+
 	// ActiveSessions tracks the current number of active chat sessions
 	ActiveSessions = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "chatbox_active_sessions_total",
@@ -62,6 +100,12 @@ var (
 		Help: "Total number of chat sessions ended",
 	})
 
+	// SessionsForceEnded tracks sessions ended by an admin, by reason code
+	SessionsForceEnded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_sessions_force_ended_total",
+		Help: "Total number of chat sessions force-ended by an admin, by reason code",
+	}, []string{"reason"})
+
 	// AdminTakeovers tracks the total number of admin takeovers
 	AdminTakeovers = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "chatbox_admin_takeovers_total",
@@ -93,6 +137,12 @@ var (
 		Help: "Total number of requests blocked by rate limiting",
 	}, []string{"limiter"})
 
+	// RateLimitBackendErrors tracks rate limit checks that failed open because the backend was unavailable
+	RateLimitBackendErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_ratelimit_backend_errors_total",
+		Help: "Total number of rate limit checks that failed open due to backend errors",
+	}, []string{"limiter"})
+
 	// WebSocketConnectionDuration tracks the duration of WebSocket connections
 	WebSocketConnectionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "chatbox_websocket_connection_duration_seconds",
@@ -113,4 +163,134 @@ var (
 		Name: "chatbox_admin_messages_dropped_total",
 		Help: "Total number of messages dropped because the admin WebSocket send buffer was full or closing",
 	})
+
+	// WebhookDeliveries tracks webhook delivery outcomes by event type
+	// (result: "delivered", "failed" after all retries, or "dropped" when the queue is full)
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_webhook_deliveries_total",
+		Help: "Total number of webhook deliveries by event type and result",
+	}, []string{"event", "result"})
+
+	// TranscriptEmails tracks transcript email outcomes
+	// (result: "sent", "failed" after all retries, "dropped" when the queue is full,
+	// or "limited" when the recipient was sent too many recently)
+	TranscriptEmails = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_transcript_emails_total",
+		Help: "Total number of transcript emails by result",
+	}, []string{"result"})
+
+	// EventDeliveries tracks session lifecycle event outcomes by event type and sink
+	// (result: "delivered", "failed", or "dropped" with sink "bus" when the queue is full)
+	EventDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_event_deliveries_total",
+		Help: "Total number of session lifecycle events sent to event sinks by event type, sink and result",
+	}, []string{"event", "sink", "result"})
+
+	// ModerationDecisions tracks moderation pipeline outcomes that changed or flagged a message
+	// (direction: "input" for user messages, "output" for AI responses; action: "flag", "redact" or "block")
+	ModerationDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_moderation_decisions_total",
+		Help: "Total number of moderation decisions by message direction and action",
+	}, []string{"direction", "action"})
+
+	// InjectionDetections tracks prompt injections detected before content reached the LLM
+	// (source: "user" for user messages, "document" for retrieved documents; action: "warn", "strip" or "block")
+	InjectionDetections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_injection_detections_total",
+		Help: "Total number of prompt injections detected by content source and action",
+	}, []string{"source", "action"})
+
+	// JobRuns tracks scheduled background job runs on this replica
+	// (result: "succeeded", "failed", or "skipped" when another replica holds the run)
+	JobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_job_runs_total",
+		Help: "Total number of scheduled job runs by job and result",
+	}, []string{"job", "result"})
+
+	// JobDuration tracks how long scheduled background jobs run
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chatbox_job_duration_seconds",
+		Help:    "Duration of scheduled job runs in seconds",
+		Buckets: []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900},
+	}, []string{"job"})
+
+	// WSTickets tracks one-time WebSocket connection tickets
+	// (result: "issued", "redeemed", or "rejected" when unknown, expired or already used)
+	WSTickets = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_ws_tickets_total",
+		Help: "Total number of WebSocket connection tickets by result",
+	}, []string{"result"})
+
+	// SentimentAnalyses tracks sentiment scoring of user messages by analyzer
+	// (result: "scored", "failed", or "dropped" when the queue is full)
+	SentimentAnalyses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_sentiment_analyses_total",
+		Help: "Total number of user messages scored for sentiment by analyzer and result",
+	}, []string{"analyzer", "result"})
+
+	// SentimentAlerts tracks sessions whose rolling sentiment dropped below the alert threshold
+	SentimentAlerts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chatbox_sentiment_alerts_total",
+		Help: "Total number of sessions whose rolling sentiment dropped below the alert threshold",
+	})
+
+	// KeywordAlertHits tracks user messages matching each watchlist keyword
+	// (keyword: the keyword's term, bounded by constants.MaxKeywordsPerTenant)
+	KeywordAlertHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_keyword_alert_hits_total",
+		Help: "Total number of user messages matching a watchlist keyword by tenant and keyword",
+	}, []string{"tenant", "keyword"})
+
+	// MemoryFactsRemembered tracks facts users stated about themselves that were
+	// stored in user memory, by kind
+	MemoryFactsRemembered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_memory_facts_remembered_total",
+		Help: "Total number of facts remembered about users by kind",
+	}, []string{"kind"})
+
+	// Backups tracks session backup jobs by target (result: "completed" or "failed")
+	Backups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_backups_total",
+		Help: "Total number of session backup jobs by target and result",
+	}, []string{"target", "result"})
+
+	// RestoredSessions tracks sessions read from backup archives by restore outcome
+	// ("created", "overwritten", "merged", "skipped" or "failed")
+	RestoredSessions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_restored_sessions_total",
+		Help: "Total number of sessions restored from backup archives by outcome",
+	}, []string{"outcome"})
+
+	// WebSocketPayloadBytes tracks the bytes of outbound WebSocket messages before framing
+	// and compression (compressed: "true" when permessage-deflate was negotiated)
+	WebSocketPayloadBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_websocket_payload_bytes_total",
+		Help: "Total bytes of outbound WebSocket message payloads before compression",
+	}, []string{"compressed"})
+
+	// WebSocketWireBytes tracks the bytes actually written to WebSocket connections,
+	// including framing. Divided by WebSocketPayloadBytes it gives the compression ratio.
+	WebSocketWireBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_websocket_wire_bytes_total",
+		Help: "Total bytes written to WebSocket connections after framing and compression",
+	}, []string{"compressed"})
+
+	// ReconnectStorms tracks how often connection attempts exceeded the storm
+	// limit, raising the reconnect backoff suggested to clients
+	ReconnectStorms = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chatbox_reconnect_storms_total",
+		Help: "Total number of reconnect storms detected",
+	})
+
+	// AlertsFiring is 1 while a usage alert rule fires and 0 otherwise (see internal/alerts)
+	AlertsFiring = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "chatbox_alerts_firing",
+		Help: "Whether a usage alert rule is firing (1) or not (0)",
+	}, []string{"rule"})
+
+	// ExperimentAssignments counts new sessions assigned each A/B experiment variant (see internal/experiment)
+	ExperimentAssignments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbox_experiment_assignments_total",
+		Help: "Total number of new sessions assigned an A/B experiment variant",
+	}, []string{"experiment", "variant"})
 )
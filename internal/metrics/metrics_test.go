@@ -19,12 +19,15 @@ func TestMetricsRegistration(t *testing.T) {
 		{"LLMRequests", LLMRequests},
 		{"LLMLatency", LLMLatency},
 		{"LLMErrors", LLMErrors},
+		{"LLMFallbacks", LLMFallbacks},
 		{"ActiveSessions", ActiveSessions},
 		{"SessionsCreated", SessionsCreated},
 		{"SessionsEnded", SessionsEnded},
 		{"AdminTakeovers", AdminTakeovers},
 		{"MessageErrors", MessageErrors},
 		{"TokensUsed", TokensUsed},
+		{"WebSocketPayloadBytes", WebSocketPayloadBytes},
+		{"WebSocketWireBytes", WebSocketWireBytes},
 	}
 
 	for _, tt := range tests {
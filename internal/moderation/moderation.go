@@ -0,0 +1,172 @@
+// Package moderation screens chat message content before it reaches the LLM and
+// before AI responses are stored.
+//
+// A Pipeline runs an ordered list of filters. Each filter is paired with the action
+// taken when it triggers: flag the message, redact the offending content, or block
+// the message outright. Filters that fail (for example a moderation API outage) are
+// logged and skipped so that moderation problems never take the chat down.
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+)
+
+// Action is what the pipeline does with a message a filter triggered on.
+// Actions are ordered by severity; a message's result carries the most severe one.
+type Action int
+
+const (
+	ActionAllow  Action = iota // Message passes unchanged
+	ActionFlag                 // Message passes unchanged but is marked for review
+	ActionRedact               // Offending content is masked
+	ActionBlock                // Message is rejected
+)
+
+// String returns the action name used in configuration, metadata and metrics
+func (a Action) String() string {
+	switch a {
+	case ActionFlag:
+		return "flag"
+	case ActionRedact:
+		return "redact"
+	case ActionBlock:
+		return "block"
+	default:
+		return "allow"
+	}
+}
+
+// ParseAction parses a configured action name (flag, redact or block)
+func ParseAction(name string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "flag":
+		return ActionFlag, nil
+	case "redact":
+		return ActionRedact, nil
+	case "block":
+		return ActionBlock, nil
+	default:
+		return ActionAllow, fmt.Errorf("unknown moderation action %q (expected flag, redact or block)", name)
+	}
+}
+
+// Direction identifies which side of the conversation is being moderated
+type Direction string
+
+const (
+	DirectionInput  Direction = "input"  // User message, checked before it is stored and sent to the LLM
+	DirectionOutput Direction = "output" // AI response, checked before it is stored
+)
+
+// Verdict is a filter's assessment of a piece of content
+type Verdict struct {
+	Flagged    bool     // Whether the filter triggered
+	Categories []string // Why it triggered, e.g. matched rule names or API categories
+	Redacted   string   // Content with offending spans masked; empty when the filter cannot localize them
+}
+
+// Filter inspects message content. Implementations must be safe for concurrent use.
+type Filter interface {
+	// Name identifies the filter in logs and results
+	Name() string
+	// Check assesses content; an error means the filter could not decide
+	Check(ctx context.Context, content string) (Verdict, error)
+}
+
+// Stage pairs a filter with the action taken when it triggers
+type Stage struct {
+	Filter Filter
+	Action Action
+}
+
+// Result is the outcome of running content through a pipeline
+type Result struct {
+	Action     Action   // Most severe action of the triggered stages
+	Content    string   // Content to use from here on (redacted or replaced when blocked)
+	Categories []string // Categories reported by every triggered filter
+	Filters    []string // Names of the triggered filters
+}
+
+// Pipeline runs content through its stages in order
+type Pipeline struct {
+	stages []Stage
+	logger *golog.Logger
+}
+
+// NewPipeline creates a pipeline that runs the given stages in order
+func NewPipeline(logger *golog.Logger, stages ...Stage) *Pipeline {
+	return &Pipeline{
+		stages: stages,
+		logger: logger,
+	}
+}
+
+// Len returns the number of stages in the pipeline
+func (p *Pipeline) Len() int {
+	return len(p.stages)
+}
+
+// Moderate runs content through every stage. Redactions are applied before the next
+// stage runs, so later filters see the redacted content. A blocking stage stops the
+// pipeline and replaces the content with constants.ModerationRemovedContent.
+// Filters that return an error are logged and skipped (fail open).
+func (p *Pipeline) Moderate(ctx context.Context, direction Direction, content string) *Result {
+	result := &Result{
+		Action:  ActionAllow,
+		Content: content,
+	}
+
+	for _, stage := range p.stages {
+		verdict, err := stage.Filter.Check(ctx, result.Content)
+		// No else needed: early return pattern (skip failed filter)
+		if err != nil {
+			util.LogError(p.logger, "moderation", "check content", err,
+				"filter", stage.Filter.Name(),
+				"direction", string(direction))
+			continue
+		}
+		// No else needed: early return pattern (filter did not trigger)
+		if !verdict.Flagged {
+			continue
+		}
+
+		result.Filters = append(result.Filters, stage.Filter.Name())
+		result.Categories = append(result.Categories, verdict.Categories...)
+		// No else needed: optional operation (keep the most severe action)
+		if stage.Action > result.Action {
+			result.Action = stage.Action
+		}
+
+		// No else needed: optional operation (only redact and block change the content)
+		if stage.Action == ActionRedact {
+			result.Content = verdict.Redacted
+			// No else needed: optional operation (filter could not localize the match)
+			if result.Content == "" {
+				result.Content = constants.ModerationRemovedContent
+			}
+		}
+		// No else needed: early return pattern (blocked content needs no further checks)
+		if stage.Action == ActionBlock {
+			result.Content = constants.ModerationRemovedContent
+			break
+		}
+	}
+
+	// No else needed: optional operation (only record decisions that affected the message)
+	if result.Action != ActionAllow {
+		metrics.ModerationDecisions.WithLabelValues(string(direction), result.Action.String()).Inc()
+		p.logger.Info("Message moderated",
+			"direction", string(direction),
+			"action", result.Action.String(),
+			"filters", result.Filters,
+			"categories", result.Categories)
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestLogger() *golog.Logger {
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            "/tmp/chatbox-moderation-test-logs",
+		Level:          "error",
+		StandardOutput: false,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize test logger: %v", err))
+	}
+	return logger
+}
+
+// stubFilter returns a fixed verdict or error
+type stubFilter struct {
+	name    string
+	verdict Verdict
+	err     error
+	calls   int
+}
+
+func (f *stubFilter) Name() string { return f.name }
+
+func (f *stubFilter) Check(_ context.Context, _ string) (Verdict, error) {
+	f.calls++
+	return f.verdict, f.err
+}
+
+func TestParseAction(t *testing.T) {
+	for name, want := range map[string]Action{"flag": ActionFlag, "Redact": ActionRedact, " block ": ActionBlock} {
+		got, err := ParseAction(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+	_, err := ParseAction("delete")
+	assert.Error(t, err)
+}
+
+func TestDenylistFilter(t *testing.T) {
+	f, err := NewDenylistFilter([]string{"badword", "  ", "two words"})
+	require.NoError(t, err)
+
+	verdict, err := f.Check(context.Background(), "A BadWord and two words, but not badwords")
+	require.NoError(t, err)
+	assert.True(t, verdict.Flagged)
+	assert.Equal(t, []string{"denylist"}, verdict.Categories)
+	assert.Equal(t, "A [redacted] and [redacted], but not badwords", verdict.Redacted)
+
+	verdict, err = f.Check(context.Background(), "all clean")
+	require.NoError(t, err)
+	assert.False(t, verdict.Flagged)
+
+	_, err = NewDenylistFilter([]string{" "})
+	assert.ErrorIs(t, err, ErrNoPatterns)
+}
+
+func TestRegexFilter_InvalidPattern(t *testing.T) {
+	_, err := NewRegexFilter("pii", []string{"("})
+	assert.Error(t, err)
+}
+
+func TestPipeline_Redact(t *testing.T) {
+	f, err := NewRegexFilter("pii", []string{`\b\d{3}-\d{3}-\d{4}\b`})
+	require.NoError(t, err)
+	p := NewPipeline(createTestLogger(), Stage{Filter: f, Action: ActionRedact})
+
+	result := p.Moderate(context.Background(), DirectionInput, "call me at 555-123-4567")
+	assert.Equal(t, ActionRedact, result.Action)
+	assert.Equal(t, "call me at [redacted]", result.Content)
+	assert.Equal(t, []string{"pii"}, result.Filters)
+}
+
+func TestPipeline_RedactWithoutSpansReplacesContent(t *testing.T) {
+	f := &stubFilter{name: "api", verdict: Verdict{Flagged: true, Categories: []string{"violence"}}}
+	p := NewPipeline(createTestLogger(), Stage{Filter: f, Action: ActionRedact})
+
+	result := p.Moderate(context.Background(), DirectionOutput, "something violent")
+	assert.Equal(t, ActionRedact, result.Action)
+	assert.Equal(t, constants.ModerationRemovedContent, result.Content)
+	assert.Equal(t, []string{"violence"}, result.Categories)
+}
+
+func TestPipeline_BlockStopsPipeline(t *testing.T) {
+	blocker := &stubFilter{name: "blocker", verdict: Verdict{Flagged: true}}
+	after := &stubFilter{name: "after"}
+	p := NewPipeline(createTestLogger(),
+		Stage{Filter: blocker, Action: ActionBlock},
+		Stage{Filter: after, Action: ActionFlag})
+
+	result := p.Moderate(context.Background(), DirectionInput, "anything")
+	assert.Equal(t, ActionBlock, result.Action)
+	assert.Equal(t, constants.ModerationRemovedContent, result.Content)
+	assert.Zero(t, after.calls)
+}
+
+func TestPipeline_FlagKeepsContentAndMostSevereActionWins(t *testing.T) {
+	redactor, err := NewDenylistFilter([]string{"secret"})
+	require.NoError(t, err)
+	flagger := &stubFilter{name: "flagger", verdict: Verdict{Flagged: true, Categories: []string{"spam"}}}
+	p := NewPipeline(createTestLogger(),
+		Stage{Filter: redactor, Action: ActionRedact},
+		Stage{Filter: flagger, Action: ActionFlag})
+
+	result := p.Moderate(context.Background(), DirectionInput, "no secret here")
+	assert.Equal(t, ActionRedact, result.Action)
+	assert.Equal(t, "no [redacted] here", result.Content)
+	assert.Equal(t, []string{"denylist", "spam"}, result.Categories)
+
+	result = p.Moderate(context.Background(), DirectionInput, "buy now")
+	assert.Equal(t, ActionFlag, result.Action)
+	assert.Equal(t, "buy now", result.Content)
+}
+
+func TestPipeline_FailsOpen(t *testing.T) {
+	broken := &stubFilter{name: "broken", err: errors.New("service unavailable")}
+	p := NewPipeline(createTestLogger(), Stage{Filter: broken, Action: ActionBlock})
+
+	result := p.Moderate(context.Background(), DirectionInput, "hello")
+	assert.Equal(t, ActionAllow, result.Action)
+	assert.Equal(t, "hello", result.Content)
+}
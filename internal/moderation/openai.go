@@ -0,0 +1,103 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/jsonhttp"
+)
+
+// ErrNoAPIKey is returned when the OpenAI filter is created without an API key
+var ErrNoAPIKey = errors.New("moderation API key is required")
+
+// OpenAIConfig holds OpenAI moderation filter settings
+type OpenAIConfig struct {
+	APIKey   string        // OpenAI API key
+	Endpoint string        // Moderation endpoint (defaults to constants.DefaultOpenAIModerationEndpoint)
+	Model    string        // Moderation model (defaults to constants.DefaultOpenAIModerationModel)
+	Timeout  time.Duration // HTTP timeout (defaults to constants.DefaultModerationTimeout)
+}
+
+// OpenAIFilter classifies content with the OpenAI moderation API. It cannot
+// localize offending spans, so redaction replaces the whole message.
+type OpenAIFilter struct {
+	model  string
+	client *jsonhttp.Client
+}
+
+type openAIModerationRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+type openAIModerationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// NewOpenAIFilter validates the configuration and creates the filter.
+// The endpoint must use https, except internal hosts which may use http.
+func NewOpenAIFilter(cfg OpenAIConfig) (*OpenAIFilter, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.APIKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	endpoint := cfg.Endpoint
+	// No else needed: optional operation (apply default)
+	if endpoint == "" {
+		endpoint = constants.DefaultOpenAIModerationEndpoint
+	}
+	model := cfg.Model
+	// No else needed: optional operation (apply default)
+	if model == "" {
+		model = constants.DefaultOpenAIModerationModel
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultModerationTimeout
+	}
+
+	client, err := jsonhttp.New("moderation", endpoint, cfg.APIKey, timeout)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAIFilter{model: model, client: client}, nil
+}
+
+// Name returns the filter name
+func (f *OpenAIFilter) Name() string {
+	return "openai"
+}
+
+// Check sends content to the moderation API and reports the flagged categories
+func (f *OpenAIFilter) Check(ctx context.Context, content string) (Verdict, error) {
+	var parsed openAIModerationResponse
+	// No else needed: early return pattern (guard clause)
+	if err := f.client.Post(ctx, openAIModerationRequest{Model: f.model, Input: content}, &parsed); err != nil {
+		return Verdict{}, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(parsed.Results) == 0 {
+		return Verdict{}, errors.New("moderation response contained no results")
+	}
+
+	result := parsed.Results[0]
+	verdict := Verdict{Flagged: result.Flagged}
+	for category, flagged := range result.Categories {
+		// No else needed: optional operation (only report triggered categories)
+		if flagged {
+			verdict.Categories = append(verdict.Categories, category)
+		}
+	}
+	sort.Strings(verdict.Categories)
+	return verdict, nil
+}
@@ -0,0 +1,61 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOpenAIFilter_Validation(t *testing.T) {
+	_, err := NewOpenAIFilter(OpenAIConfig{})
+	assert.ErrorIs(t, err, ErrNoAPIKey)
+
+	_, err = NewOpenAIFilter(OpenAIConfig{APIKey: "key", Endpoint: "http://api.example.com/moderations"})
+	assert.Error(t, err, "public endpoints must use https")
+
+	f, err := NewOpenAIFilter(OpenAIConfig{APIKey: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultOpenAIModerationEndpoint, f.client.Endpoint())
+	assert.Equal(t, constants.DefaultOpenAIModerationModel, f.model)
+}
+
+func TestOpenAIFilter_Check(t *testing.T) {
+	var got openAIModerationRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"harassment":true,"sexual":false}}]}`))
+	}))
+	defer server.Close()
+
+	f, err := NewOpenAIFilter(OpenAIConfig{APIKey: "test-key", Endpoint: server.URL})
+	require.NoError(t, err)
+
+	verdict, err := f.Check(context.Background(), "some text")
+	require.NoError(t, err)
+	assert.True(t, verdict.Flagged)
+	assert.Equal(t, []string{"harassment", "violence"}, verdict.Categories)
+	assert.Empty(t, verdict.Redacted)
+	assert.Equal(t, "some text", got.Input)
+	assert.Equal(t, constants.DefaultOpenAIModerationModel, got.Model)
+}
+
+func TestOpenAIFilter_CheckErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	f, err := NewOpenAIFilter(OpenAIConfig{APIKey: "test-key", Endpoint: server.URL})
+	require.NoError(t, err)
+
+	_, err = f.Check(context.Background(), "some text")
+	assert.ErrorContains(t, err, "429")
+}
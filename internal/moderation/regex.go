@@ -0,0 +1,78 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// ErrNoPatterns is returned when a regex or denylist filter is created without any patterns
+var ErrNoPatterns = errors.New("at least one moderation pattern is required")
+
+// RegexFilter triggers on content matching any of its patterns. Matches are
+// replaced with constants.ModerationRedactionMask in the verdict's Redacted content.
+type RegexFilter struct {
+	name     string
+	patterns []*regexp.Regexp
+}
+
+// NewRegexFilter compiles patterns into a filter that reports name as its category
+func NewRegexFilter(name string, patterns []string) (*RegexFilter, error) {
+	// No else needed: early return pattern (guard clause)
+	if len(patterns) == 0 {
+		return nil, ErrNoPatterns
+	}
+
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return &RegexFilter{name: name, patterns: compiled}, nil
+}
+
+// NewDenylistFilter creates a filter that triggers on any of the given words or
+// phrases, matched case-insensitively on word boundaries.
+func NewDenylistFilter(words []string) (*RegexFilter, error) {
+	patterns := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		// No else needed: optional operation (skip blank entries)
+		if word == "" {
+			continue
+		}
+		patterns = append(patterns, `(?i)\b`+regexp.QuoteMeta(word)+`\b`)
+	}
+	return NewRegexFilter("denylist", patterns)
+}
+
+// Name returns the filter name
+func (f *RegexFilter) Name() string {
+	return f.name
+}
+
+// Check reports whether any pattern matches content and masks every match
+func (f *RegexFilter) Check(_ context.Context, content string) (Verdict, error) {
+	verdict := Verdict{Redacted: content}
+	for _, re := range f.patterns {
+		// No else needed: early return pattern (pattern did not match)
+		if !re.MatchString(verdict.Redacted) {
+			continue
+		}
+		verdict.Flagged = true
+		verdict.Redacted = re.ReplaceAllLiteralString(verdict.Redacted, constants.ModerationRedactionMask)
+	}
+	// No else needed: optional operation (report the category once)
+	if verdict.Flagged {
+		verdict.Categories = []string{f.name}
+	}
+	return verdict, nil
+}
@@ -0,0 +1,344 @@
+// Package openapi builds the OpenAPI 3 specification of the REST API from a
+// typed route table.
+//
+// Each Route names its method and path in gin syntax, the auth it requires and
+// the Go types of its request and response bodies; schemas are derived from the
+// types' JSON encoding, so the spec follows the structs the handlers actually
+// encode. Handlers that answer with ad-hoc gin.H maps describe them with Object.
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// Version is the OpenAPI version of the generated documents
+const Version = "3.0.3"
+
+// ErrInvalidRoute is returned when a route table entry cannot be documented
+var ErrInvalidRoute = errors.New("invalid route")
+
+// Auth is the authentication a route requires
+type Auth int
+
+const (
+	AuthNone     Auth = iota // Public endpoint
+	AuthUser                 // User JWT
+	AuthAdmin                // Admin JWT or API key
+	AuthAdminJWT             // Admin JWT only
+)
+
+// Security scheme names in components.securitySchemes
+const (
+	SchemeBearer = "bearerAuth"
+	SchemeAPIKey = "apiKeyAuth"
+)
+
+// Route documents one REST endpoint
+type Route struct {
+	Method      string // http.MethodGet, ...
+	Path        string // gin syntax, relative to the server URL, e.g. /sessions/:sessionID
+	Tag         string
+	Summary     string
+	Description string
+	Auth        Auth
+	Permission  string  // admin permission required, added to the description
+	Query       []Param // query parameters; path parameters are derived from Path
+	Request     interface{}
+	Response    interface{} // JSON response body: a Go value or type sample, or a *Schema
+	Status      int         // success status, http.StatusOK when 0
+	Files       []string    // content types of file responses, e.g. text/csv, besides the JSON Response
+	Errors      []int       // error statuses besides the auth ones and 500
+}
+
+// Param is a query parameter
+type Param struct {
+	Name        string
+	Description string
+	Schema      *Schema
+	Required    bool
+}
+
+// Info is the document's info object
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers,omitempty"`
+	Tags       []Tag                            `json:"tags,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// Server is a base URL of the API
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Components holds the schemas and security schemes operations refer to
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is an authentication method
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Operation is one method of a path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of a request
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is a response of an operation
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body in one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Build documents routes served under serverURL, e.g. the path prefix. The
+// errorBody sample is the JSON body of every error response. Routes are
+// validated: each method and path must be documented once, with a summary.
+func Build(info Info, serverURL string, errorBody interface{}, routes []Route) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]*SecurityScheme{
+				SchemeBearer: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				SchemeAPIKey: {Type: "apiKey", In: "header", Name: constants.HeaderAPIKey, Description: "API key of server-to-server admin calls"},
+			},
+		},
+	}
+	// No else needed: optional operation (server URL known)
+	if serverURL != "" {
+		doc.Servers = []Server{{URL: serverURL}}
+	}
+
+	schemas := newSchemaSet(doc.Components.Schemas)
+	errorSchema := schemas.of(errorBody)
+	tags := make(map[string]bool)
+	for _, route := range routes {
+		op, path, err := operation(route, schemas, errorSchema)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		method := strings.ToLower(route.Method)
+		// No else needed: optional operation (first method of the path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		// No else needed: early return pattern (guard clause)
+		if doc.Paths[path][method] != nil {
+			return nil, fmt.Errorf("%w: %s %s documented twice", ErrInvalidRoute, route.Method, route.Path)
+		}
+		doc.Paths[path][method] = op
+		// No else needed: optional operation (list each tag once)
+		if route.Tag != "" && !tags[route.Tag] {
+			tags[route.Tag] = true
+			doc.Tags = append(doc.Tags, Tag{Name: route.Tag})
+		}
+	}
+	return doc, nil
+}
+
+// operation documents route, returning the OpenAPI path it is served at
+func operation(route Route, schemas *schemaSet, errorSchema *Schema) (*Operation, string, error) {
+	switch route.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported method %q for %s", ErrInvalidRoute, route.Method, route.Path)
+	}
+	// No else needed: early return pattern (guard clause)
+	if !strings.HasPrefix(route.Path, "/") || route.Summary == "" {
+		return nil, "", fmt.Errorf("%w: %s %s needs an absolute path and a summary", ErrInvalidRoute, route.Method, route.Path)
+	}
+
+	path, pathParams := convertPath(route.Path)
+	op := &Operation{
+		OperationID: operationID(route.Method, route.Path),
+		Summary:     route.Summary,
+		Description: route.Description,
+		Responses:   make(map[string]*Response),
+	}
+	// No else needed: optional operation (tagged route)
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	// No else needed: optional operation (permission noted)
+	if route.Permission != "" {
+		op.Description = strings.TrimSpace(op.Description + "\n\nRequires the `" + route.Permission + "` permission.")
+	}
+
+	for _, name := range pathParams {
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, param := range route.Query {
+		schema := param.Schema
+		// No else needed: conditional assignment (string by default)
+		if schema == nil {
+			schema = &Schema{Type: "string"}
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        param.Name,
+			In:          "query",
+			Description: param.Description,
+			Required:    param.Required,
+			Schema:      schema,
+		})
+	}
+
+	// No else needed: optional operation (route takes a body)
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: schemas.of(route.Request)}},
+		}
+	}
+
+	status := route.Status
+	// No else needed: conditional assignment (200 by default)
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	// No else needed: optional operation (JSON response body)
+	if route.Response != nil {
+		success.Content = map[string]*MediaType{"application/json": {Schema: schemas.of(route.Response)}}
+	}
+	for _, contentType := range route.Files {
+		// No else needed: optional operation (first content type)
+		if success.Content == nil {
+			success.Content = make(map[string]*MediaType)
+		}
+		success.Content[contentType] = &MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+	}
+	op.Responses[fmt.Sprint(status)] = success
+
+	errorStatuses := append([]int{}, route.Errors...)
+	switch route.Auth {
+	case AuthUser:
+		op.Security = []map[string][]string{{SchemeBearer: {}}}
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized)
+	case AuthAdmin:
+		op.Security = []map[string][]string{{SchemeBearer: {}}, {SchemeAPIKey: {}}}
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests)
+	case AuthAdminJWT:
+		op.Security = []map[string][]string{{SchemeBearer: {}}}
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	errorStatuses = append(errorStatuses, http.StatusInternalServerError)
+	for _, code := range errorStatuses {
+		op.Responses[fmt.Sprint(code)] = &Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*MediaType{"application/json": {Schema: errorSchema}},
+		}
+	}
+	return op, path, nil
+}
+
+// convertPath converts a gin path to an OpenAPI path, returning the names of its
+// path parameters in order: /sessions/:sessionID becomes /sessions/{sessionID}
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		// No else needed: optional operation (only parameter segments change)
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID derives a stable operation ID from the method and path, e.g.
+// getSessionsBySessionIDSnapshots for GET /sessions/:sessionID/snapshots
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		// No else needed: optional operation (parameters read as "By<Name>")
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			b.WriteString("By")
+			segment = segment[1:]
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// Operations returns the method and OpenAPI path of every documented
+// operation, sorted, e.g. "GET /sessions/{sessionID}"
+func (d *Document) Operations() []string {
+	var ops []string
+	for path, methods := range d.Paths {
+		for method := range methods {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// OperationFor returns the OpenAPI method and path documenting a gin route,
+// in the form returned by Operations
+func OperationFor(method, ginPath string) string {
+	path, _ := convertPath(ginPath)
+	return strings.ToUpper(method) + " " + path
+}
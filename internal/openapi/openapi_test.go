@@ -0,0 +1,155 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testItem struct {
+	testBase
+	Name     string            `json:"name"`
+	Note     string            `json:"note,omitempty"`
+	Count    int64             `json:"count,string"`
+	Created  time.Time         `json:"created_at"`
+	Ended    *time.Time        `json:"ended_at,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Children []*testItem       `json:"children"`
+	Secret   string            `json:"-"`
+	Untagged bool
+	internal string
+}
+
+type testRename struct {
+	Name string `json:"name"`
+}
+
+func TestBuild(t *testing.T) {
+	doc, err := Build(Info{Title: "Test", Version: "1"}, "/api", testError{}, []Route{
+		{Method: http.MethodGet, Path: "/healthz", Tag: "health", Summary: "Liveness"},
+		{
+			Method:   http.MethodGet,
+			Path:     "/items/:itemID",
+			Tag:      "items",
+			Summary:  "Get an item",
+			Auth:     AuthUser,
+			Query:    []Param{{Name: "limit", Schema: Integer()}},
+			Response: &testItem{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method:     http.MethodPatch,
+			Path:       "/items/:itemID",
+			Tag:        "items",
+			Summary:    "Rename an item",
+			Auth:       AuthAdmin,
+			Permission: "manage",
+			Request:    testRename{},
+			Response:   Object(map[string]interface{}{"id": "", "items": ArrayOf(testItem{})}),
+		},
+		{Method: http.MethodGet, Path: "/items/:itemID/export", Summary: "Export", Auth: AuthAdmin, Response: testItem{}, Files: []string{"text/csv"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, []Server{{URL: "/api"}}, doc.Servers)
+	assert.Equal(t, []Tag{{Name: "health"}, {Name: "items"}}, doc.Tags)
+	assert.Equal(t, []string{
+		"GET /healthz",
+		"GET /items/{itemID}",
+		"GET /items/{itemID}/export",
+		"PATCH /items/{itemID}",
+	}, doc.Operations())
+
+	// Path parameters come from the path, query parameters from the route
+	get := doc.Paths["/items/{itemID}"]["get"]
+	assert.Equal(t, "getItemsByItemID", get.OperationID)
+	require.Len(t, get.Parameters, 2)
+	assert.Equal(t, Parameter{Name: "itemID", In: "path", Required: true, Schema: &Schema{Type: "string"}}, *get.Parameters[0])
+	assert.Equal(t, "query", get.Parameters[1].In)
+	assert.Equal(t, []map[string][]string{{SchemeBearer: {}}}, get.Security)
+	assert.Contains(t, get.Responses, "200")
+	assert.Contains(t, get.Responses, "401")
+	assert.Equal(t, "#/components/schemas/testError", get.Responses["404"].Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/testItem", get.Responses["200"].Content["application/json"].Schema.Ref)
+
+	// Admin routes take a JWT or an API key, and note the permission
+	patch := doc.Paths["/items/{itemID}"]["patch"]
+	assert.Len(t, patch.Security, 2)
+	assert.Contains(t, patch.Description, "`manage`")
+	assert.Contains(t, patch.Responses, "403")
+	assert.Equal(t, "#/components/schemas/testRename", patch.RequestBody.Content["application/json"].Schema.Ref)
+	body := patch.Responses["200"].Content["application/json"].Schema
+	assert.Equal(t, &Schema{Type: "string"}, body.Properties["id"])
+	assert.Equal(t, "#/components/schemas/testItem", body.Properties["items"].Items.Ref)
+
+	export := doc.Paths["/items/{itemID}/export"]["get"]
+	assert.Equal(t, "binary", export.Responses["200"].Content["text/csv"].Schema.Format)
+	assert.Contains(t, export.Responses["200"].Content, "application/json")
+
+	// Struct schemas follow the JSON encoding
+	item := doc.Components.Schemas["testItem"]
+	require.NotNil(t, item)
+	assert.ElementsMatch(t, []string{"id", "name", "note", "count", "created_at", "ended_at", "labels", "children", "Untagged"}, keys(item.Properties))
+	assert.Empty(t, item.Required)
+	assert.Equal(t, &Schema{Type: "string"}, item.Properties["count"])
+	assert.Equal(t, DateTime(), item.Properties["ended_at"])
+	assert.Equal(t, &Schema{Type: "string"}, item.Properties["labels"].AdditionalProperties)
+	assert.Equal(t, "#/components/schemas/testItem", item.Properties["children"].Items.Ref, "recursive types refer to themselves")
+
+	// The document encodes as JSON
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), `{"openapi":"3.0.3"`))
+}
+
+func TestBuild_InvalidRoutes(t *testing.T) {
+	tests := map[string][]Route{
+		"duplicate": {
+			{Method: http.MethodGet, Path: "/a", Summary: "A"},
+			{Method: http.MethodGet, Path: "/a", Summary: "A again"},
+		},
+		"method":     {{Method: "TRACE", Path: "/a", Summary: "A"}},
+		"no summary": {{Method: http.MethodGet, Path: "/a"}},
+		"relative":   {{Method: http.MethodGet, Path: "a", Summary: "A"}},
+	}
+	for name, routes := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Build(Info{}, "", testError{}, routes)
+			assert.ErrorIs(t, err, ErrInvalidRoute)
+		})
+	}
+}
+
+func TestOperationFor(t *testing.T) {
+	assert.Equal(t, "DELETE /sessions/{sessionID}/tags/{tag}", OperationFor("delete", "/sessions/:sessionID/tags/:tag"))
+}
+
+func TestUIPage(t *testing.T) {
+	page := string(UIPage("Chat <API>", "/chat/openapi.json"))
+	assert.Contains(t, page, "<title>Chat &lt;API&gt;</title>")
+	assert.Contains(t, page, `url: "/chat/openapi.json"`)
+	assert.Contains(t, page, "swagger-ui-dist@"+SwaggerUIVersion)
+}
+
+func keys(m map[string]*Schema) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
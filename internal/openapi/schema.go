@@ -0,0 +1,249 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON schema of a body, parameter or component
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+
+	sample interface{} // Go sample the schema is derived from when added to a document
+}
+
+// Object describes an ad-hoc JSON object, e.g. a gin.H response. Property
+// values are Go value or type samples, or *Schema.
+func Object(properties map[string]interface{}) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema, len(properties))}
+	for name, sample := range properties {
+		schema.Properties[name] = schemaOrSample(sample)
+	}
+	return schema
+}
+
+// ArrayOf describes a JSON array of items, a Go sample or *Schema
+func ArrayOf(items interface{}) *Schema {
+	return &Schema{Type: "array", Items: schemaOrSample(items)}
+}
+
+// String is a string schema, restricted to values when any are given
+func String(values ...string) *Schema {
+	return &Schema{Type: "string", Enum: values}
+}
+
+// Integer is an integer schema
+func Integer() *Schema {
+	return &Schema{Type: "integer"}
+}
+
+// Boolean is a boolean schema
+func Boolean() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+// DateTime is an RFC3339 date-time string schema
+func DateTime() *Schema {
+	return &Schema{Type: "string", Format: "date-time"}
+}
+
+// schemaOrSample returns sample when it is a schema, and otherwise a schema
+// derived from it when added to a document
+func schemaOrSample(sample interface{}) *Schema {
+	// No else needed: early return pattern (already a schema)
+	if schema, ok := sample.(*Schema); ok {
+		return schema
+	}
+	return &Schema{sample: sample}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaSet derives schemas from Go types, adding named structs to the
+// document's component schemas and referring to them by $ref
+type schemaSet struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaSet(components map[string]*Schema) *schemaSet {
+	return &schemaSet{components: components, names: make(map[reflect.Type]string)}
+}
+
+// of returns the schema of v: a *Schema, whose Go samples are resolved, or a
+// Go value or type sample
+func (s *schemaSet) of(v interface{}) *Schema {
+	switch v := v.(type) {
+	case nil:
+		return &Schema{}
+	case *Schema:
+		s.resolve(v)
+		return v
+	case reflect.Type:
+		return s.forType(v)
+	default:
+		return s.forType(reflect.TypeOf(v))
+	}
+}
+
+// resolve replaces the Go samples inside schema with their schemas
+func (s *schemaSet) resolve(schema *Schema) {
+	// No else needed: early return pattern (sample placeholder)
+	if schema.sample != nil {
+		*schema = *s.of(schema.sample)
+		return
+	}
+	for _, property := range schema.Properties {
+		s.resolve(property)
+	}
+	// No else needed: optional operation (array schema)
+	if schema.Items != nil {
+		s.resolve(schema.Items)
+	}
+	// No else needed: optional operation (map schema)
+	if schema.AdditionalProperties != nil {
+		s.resolve(schema.AdditionalProperties)
+	}
+}
+
+// forType returns the schema of t's JSON encoding
+func (s *schemaSet) forType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return DateTime()
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{} // Custom encoding: any JSON value
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Integer()
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// No else needed: early return pattern ([]byte is base64)
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.forType(t.Elem())}
+	case reflect.Struct:
+		return s.forStruct(t)
+	default:
+		return &Schema{} // interface{}: any JSON value
+	}
+}
+
+// forStruct returns a $ref to the component schema of a named struct, adding
+// it on first use, or the inline schema of an anonymous struct
+func (s *schemaSet) forStruct(t reflect.Type) *Schema {
+	// No else needed: early return pattern (anonymous struct)
+	if t.Name() == "" {
+		return s.structSchema(t)
+	}
+	name, seen := s.names[t]
+	// No else needed: optional operation (first use of the type)
+	if !seen {
+		name = s.componentName(t)
+		s.names[t] = name
+		s.components[name] = &Schema{} // Placeholder for recursive types
+		s.components[name] = s.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName names t's component schema after the type, qualified with its
+// package when another package has a type of the same name
+func (s *schemaSet) componentName(t reflect.Type) string {
+	name := t.Name()
+	// No else needed: early return pattern (unique name)
+	if _, taken := s.components[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	// No else needed: conditional assignment (last path element)
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return pkg + "." + name
+}
+
+// structSchema returns the object schema of a struct's exported fields, with
+// embedded structs flattened as encoding/json does. No field is marked
+// required: request bodies decode missing fields as zero values, and handlers
+// check the ones they need.
+func (s *schemaSet) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		// No else needed: early return pattern (skipped field)
+		if tag == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		// No else needed: optional operation (embedded struct fields are promoted)
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			embedded := s.structSchema(fieldType)
+			for propName, prop := range embedded.Properties {
+				schema.Properties[propName] = prop
+			}
+			continue
+		}
+		// No else needed: early return pattern (unexported embedded non-struct)
+		if field.PkgPath != "" {
+			continue
+		}
+		// No else needed: conditional assignment (untagged fields keep their Go name)
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := s.forType(field.Type)
+		// No else needed: conditional assignment (",string" encodes numbers and bools as strings)
+		if strings.Contains(opts, "string") && prop.Ref == "" {
+			prop = &Schema{Type: "string"}
+		}
+		schema.Properties[name] = prop
+	}
+	return schema
+}
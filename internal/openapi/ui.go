@@ -0,0 +1,35 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+)
+
+// SwaggerUIVersion is the swagger-ui-dist release the UI page loads from the CDN
+const SwaggerUIVersion = "5.17.14"
+
+// UIPage returns a Swagger UI page that renders the spec at specURL. The UI's
+// scripts and styles are loaded from the jsDelivr CDN, so the page needs no
+// bundled assets.
+func UIPage(title, specURL string) []byte {
+	url, _ := json.Marshal(specURL) // JS string literal; encoding/json escapes <, > and &
+	return []byte(fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%[1]s</title>
+<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@%[3]s/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@%[3]s/swagger-ui-bundle.js" crossorigin="anonymous"></script>
+<script>
+window.onload = function () {
+  window.ui = SwaggerUIBundle({url: %[2]s, dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`, html.EscapeString(title), url, SwaggerUIVersion))
+}
@@ -0,0 +1,145 @@
+// Package priority scores sessions waiting in the help queue so admins can
+// triage them.
+//
+// A session's score adds up three components: the time it has waited, how
+// frustrated its user sounds (see sentiment.Classify) and the customer tier
+// claimed by the user's token. Each is weighted in points, e.g. with the
+// default weights a fully negative conversation counts as much as half an hour
+// of waiting.
+package priority
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/sentiment"
+)
+
+// Weights converts the score components into points
+type Weights struct {
+	PerMinute   float64            // points per minute waited
+	Frustration float64            // points for a fully negative conversation
+	Tiers       map[string]float64 // points per customer tier claim
+}
+
+// DefaultWeights returns the weights used when none are configured
+func DefaultWeights() Weights {
+	return Weights{PerMinute: 1, Frustration: 30}
+}
+
+// Input is what a session is scored on
+type Input struct {
+	Wait     time.Duration
+	Messages []string // the user's latest messages
+	Tier     string
+}
+
+// Score is a session's priority and its components, in points
+type Score struct {
+	Total       float64 `json:"total"`
+	Wait        float64 `json:"wait"`
+	Frustration float64 `json:"frustration"`
+	Tier        float64 `json:"tier"`
+	Sentiment   float64 `json:"sentiment"` // classifier output in [-1, 1], not points
+}
+
+// Scorer scores sessions with fixed weights
+type Scorer struct {
+	weights Weights
+}
+
+// NewScorer creates a scorer with weights
+func NewScorer(weights Weights) *Scorer {
+	tiers := make(map[string]float64, len(weights.Tiers))
+	for tier, points := range weights.Tiers {
+		tiers[tier] = points
+	}
+	weights.Tiers = tiers
+	return &Scorer{weights: weights}
+}
+
+// Score returns the priority of a session. Only negative sentiment raises it;
+// unknown tiers add nothing.
+func (s *Scorer) Score(in Input) Score {
+	score := sentiment.Classify(in.Messages...)
+	result := Score{
+		Wait:        math.Max(in.Wait.Minutes(), 0) * s.weights.PerMinute,
+		Frustration: math.Max(-score, 0) * s.weights.Frustration,
+		Tier:        s.weights.Tiers[in.Tier],
+		Sentiment:   score,
+	}
+	result.Total = result.Wait + result.Frustration + result.Tier
+	return result
+}
+
+// ParseWeights converts the raw [chatbox.help_queue] config value into weights:
+// wait_weight (points per minute), frustration_weight and a tiers table of
+// tier = points entries. Omitted weights keep their defaults; all must be
+// non-negative numbers.
+func ParseWeights(raw interface{}) (Weights, error) {
+	weights := DefaultWeights()
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return weights, fmt.Errorf("chatbox.help_queue is not a table")
+	}
+
+	for key, value := range table {
+		var err error
+		switch key {
+		case "wait_weight":
+			weights.PerMinute, err = parsePoints(key, value)
+		case "frustration_weight":
+			weights.Frustration, err = parsePoints(key, value)
+		case "tiers":
+			weights.Tiers, err = parseTiers(value)
+		default:
+			err = fmt.Errorf("unknown key %s", key)
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return weights, err
+		}
+	}
+	return weights, nil
+}
+
+// parseTiers converts the tiers table into points per tier
+func parseTiers(raw interface{}) (map[string]float64, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("tiers is not a table")
+	}
+	tiers := make(map[string]float64, len(table))
+	for tier, value := range table {
+		points, err := parsePoints("tier "+tier, value)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		tiers[tier] = points
+	}
+	return tiers, nil
+}
+
+// parsePoints converts a config number into non-negative points
+func parsePoints(name string, value interface{}) (float64, error) {
+	var points float64
+	switch v := value.(type) {
+	case float64:
+		points = v
+	case int64:
+		points = float64(v)
+	case int:
+		points = float64(v)
+	default:
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	// No else needed: early return pattern (guard clause)
+	if points < 0 || math.IsNaN(points) || math.IsInf(points, 0) {
+		return 0, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return points, nil
+}
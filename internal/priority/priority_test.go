@@ -0,0 +1,59 @@
+package priority
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScorer_Score(t *testing.T) {
+	tiers := map[string]float64{"enterprise": 20}
+	scorer := NewScorer(Weights{PerMinute: 2, Frustration: 30, Tiers: tiers})
+	tiers["enterprise"] = 0 // the scorer keeps its own copy
+
+	calm := scorer.Score(Input{Wait: 5 * time.Minute, Messages: []string{"how do I reset my password"}})
+	assert.InDelta(t, 10.0, calm.Wait, 1e-9)
+	assert.Zero(t, calm.Frustration)
+	assert.Zero(t, calm.Tier)
+	assert.InDelta(t, 10.0, calm.Total, 1e-9)
+
+	angry := scorer.Score(Input{Wait: 5 * time.Minute, Messages: []string{"this is broken, I am furious"}, Tier: "enterprise"})
+	assert.Greater(t, angry.Frustration, 0.0)
+	assert.Less(t, angry.Sentiment, 0.0)
+	assert.Equal(t, 20.0, angry.Tier)
+	assert.InDelta(t, angry.Wait+angry.Frustration+angry.Tier, angry.Total, 1e-9)
+	assert.Greater(t, angry.Total, calm.Total)
+
+	happy := scorer.Score(Input{Messages: []string{"thanks, great help"}, Tier: "unknown"})
+	assert.Zero(t, happy.Frustration, "positive sentiment does not lower the score")
+	assert.Zero(t, happy.Tier)
+	assert.Zero(t, scorer.Score(Input{Wait: -time.Minute}).Wait)
+}
+
+func TestParseWeights(t *testing.T) {
+	weights, err := ParseWeights(map[string]interface{}{
+		"wait_weight": int64(3),
+		"tiers":       map[string]interface{}{"gold": 15.0, "silver": 5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, weights.PerMinute)
+	assert.Equal(t, DefaultWeights().Frustration, weights.Frustration)
+	assert.Equal(t, map[string]float64{"gold": 15, "silver": 5}, weights.Tiers)
+
+	invalid := []interface{}{
+		"not a table",
+		map[string]interface{}{"wait_weight": "3"},
+		map[string]interface{}{"frustration_weight": -1.0},
+		map[string]interface{}{"frustration_weight": math.NaN()},
+		map[string]interface{}{"tiers": "gold"},
+		map[string]interface{}{"tiers": map[string]interface{}{"gold": math.Inf(1)}},
+		map[string]interface{}{"sentiment": 1.0},
+	}
+	for _, raw := range invalid {
+		_, err := ParseWeights(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+}
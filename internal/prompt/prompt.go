@@ -0,0 +1,227 @@
+// Package prompt resolves and renders the system prompt templates that can be
+// selected for a chat session when it is created.
+//
+// Templates come from two places: read-only templates defined in config under
+// [chatbox.prompts.<id>], available to every tenant, and templates managed by
+// admins through the API, stored in MongoDB per tenant. Template content may
+// reference the variables below as {{name}} placeholders, which are filled in
+// for the session's user every time the prompt is sent to the LLM.
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// Template variables
+const (
+	VarUserName = "user_name" // Display name from the user's JWT
+	VarUserID   = "user_id"
+	VarTenant   = "tenant" // Tenant ID; empty for the default tenant
+	// VarUserMemory lists the facts remembered about the user, one per line
+	// (see internal/memory); empty when user memory is disabled
+	VarUserMemory = "user_memory"
+)
+
+var (
+	// ErrTemplateNotFound is returned when no template with the ID is visible to the tenant
+	ErrTemplateNotFound = errors.New("prompt template not found")
+	// ErrInvalidTemplate is returned when a template fails validation
+	ErrInvalidTemplate = errors.New("invalid prompt template")
+)
+
+// placeholderPattern matches {{name}} placeholders, allowing spaces inside the braces
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_]+)\s*\}\}`)
+
+// knownVars is the set of variables templates may reference
+var knownVars = map[string]bool{
+	VarUserName:   true,
+	VarUserID:     true,
+	VarTenant:     true,
+	VarUserMemory: true,
+}
+
+// Template is a system prompt template
+type Template struct {
+	ID        string    `bson:"_id" json:"id"`
+	TenantID  string    `bson:"tid,omitempty" json:"tenant_id,omitempty"` // empty for the default tenant
+	Name      string    `bson:"nm" json:"name"`
+	Content   string    `bson:"content" json:"content"`
+	ReadOnly  bool      `bson:"-" json:"read_only"` // defined in config; cannot be changed through the API
+	CreatedAt time.Time `bson:"ts,omitempty" json:"created_at,omitempty"`
+	UpdatedAt time.Time `bson:"mt,omitempty" json:"updated_at,omitempty"`
+}
+
+// Validate checks that a template has a name and content within the length
+// limits and references only known variables
+func Validate(name, content string) error {
+	// No else needed: early return pattern (guard clause)
+	if name == "" || len(name) > constants.MaxPromptNameLength {
+		return fmt.Errorf("%w: name must be 1-%d bytes", ErrInvalidTemplate, constants.MaxPromptNameLength)
+	}
+	// No else needed: early return pattern (guard clause)
+	if content == "" || len(content) > constants.MaxPromptContentLength {
+		return fmt.Errorf("%w: content must be 1-%d bytes", ErrInvalidTemplate, constants.MaxPromptContentLength)
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		// No else needed: early return pattern (guard clause)
+		if !knownVars[match[1]] {
+			return fmt.Errorf("%w: unknown variable {{%s}}", ErrInvalidTemplate, match[1])
+		}
+	}
+	return nil
+}
+
+// Render replaces the {{name}} placeholders in content with vars.
+// Placeholders without a value are replaced with an empty string.
+func Render(content string, vars map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		return vars[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+// ParseConfigTemplates converts the raw [chatbox.prompts] config value into
+// read-only templates sorted by ID
+func ParseConfigTemplates(raw interface{}) ([]*Template, error) {
+	tables, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.prompts is not a table")
+	}
+
+	templates := make([]*Template, 0, len(tables))
+	for id, value := range tables {
+		table, ok := value.(map[string]interface{})
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("prompt %s: not a table", id)
+		}
+
+		name, _ := table["name"].(string)
+		content, _ := table["content"].(string)
+		// No else needed: optional operation (name defaults to the ID)
+		if name == "" {
+			name = id
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := Validate(name, content); err != nil {
+			return nil, fmt.Errorf("prompt %s: %w", id, err)
+		}
+		templates = append(templates, &Template{ID: id, Name: name, Content: content, ReadOnly: true})
+	}
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates, nil
+}
+
+// StoreFunc looks up an admin-managed template visible to a tenant.
+// It returns ErrTemplateNotFound when there is none.
+type StoreFunc func(tenantID, id string) (*Template, error)
+
+// cacheEntry is a stored template and when it was looked up
+type cacheEntry struct {
+	template *Template
+	loadedAt time.Time
+}
+
+// Service resolves templates by ID: config templates first, then the store.
+// Store lookups are cached for constants.PromptCacheTTL so rendering a prompt
+// for every message does not hit MongoDB; changes made on another instance
+// become visible once the cached entry expires.
+type Service struct {
+	config map[string]*Template
+	store  StoreFunc // nil when only config templates are available
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry // cacheKey(tenantID, id) -> template
+}
+
+// NewService creates a template service. store may be nil.
+func NewService(configTemplates []*Template, store StoreFunc) *Service {
+	config := make(map[string]*Template, len(configTemplates))
+	for _, t := range configTemplates {
+		config[t.ID] = t
+	}
+	return &Service{
+		config: config,
+		store:  store,
+		cache:  make(map[string]cacheEntry),
+	}
+}
+
+// cacheKey identifies a stored template; IDs of stored templates are only
+// looked up within the tenant that owns them
+func cacheKey(tenantID, id string) string {
+	return tenantID + "\x00" + id
+}
+
+// ConfigTemplates returns the read-only config templates sorted by ID
+func (s *Service) ConfigTemplates() []*Template {
+	templates := make([]*Template, 0, len(s.config))
+	for _, t := range s.config {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates
+}
+
+// IsConfigTemplate reports whether id names a read-only config template
+func (s *Service) IsConfigTemplate(id string) bool {
+	_, exists := s.config[id]
+	return exists
+}
+
+// Get returns the template with the ID visible to the tenant
+func (s *Service) Get(tenantID, id string) (*Template, error) {
+	// No else needed: early return pattern (config templates are shared by all tenants)
+	if t, exists := s.config[id]; exists {
+		return t, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.store == nil || id == "" {
+		return nil, ErrTemplateNotFound
+	}
+
+	key := cacheKey(tenantID, id)
+	s.mu.Lock()
+	entry, cached := s.cache[key]
+	s.mu.Unlock()
+	// No else needed: early return pattern (fresh cache hit)
+	if cached && time.Since(entry.loadedAt) < constants.PromptCacheTTL {
+		return entry.template, nil
+	}
+
+	t, err := s.store(tenantID, id)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[key] = cacheEntry{template: t, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return t, nil
+}
+
+// Render renders the template with the ID for the tenant
+func (s *Service) Render(tenantID, id string, vars map[string]string) (string, error) {
+	t, err := s.Get(tenantID, id)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", err
+	}
+	return Render(t.Content, vars), nil
+}
+
+// Invalidate drops a cached stored template after it was changed or deleted
+func (s *Service) Invalidate(tenantID, id string) {
+	s.mu.Lock()
+	delete(s.cache, cacheKey(tenantID, id))
+	s.mu.Unlock()
+}
@@ -0,0 +1,89 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("Support", "Help {{ user_name }} from {{tenant}} ({{user_id}})."))
+	assert.NoError(t, Validate("Plain", "No placeholders at all"))
+
+	assert.ErrorIs(t, Validate("", "content"), ErrInvalidTemplate)
+	assert.ErrorIs(t, Validate("Name", ""), ErrInvalidTemplate)
+	err := Validate("Name", "Hello {{email}}")
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+	assert.Contains(t, err.Error(), "{{email}}")
+}
+
+func TestRender(t *testing.T) {
+	vars := map[string]string{VarUserName: "Alice", VarTenant: "acme"}
+	assert.Equal(t, "Hi Alice of acme, id=.",
+		Render("Hi {{user_name}} of {{ tenant }}, id={{user_id}}.", vars))
+	assert.Equal(t, "Literal {braces} stay", Render("Literal {braces} stay", vars))
+}
+
+func TestParseConfigTemplates(t *testing.T) {
+	templates, err := ParseConfigTemplates(map[string]interface{}{
+		"support": map[string]interface{}{"name": "Support", "content": "Help {{user_name}}"},
+		"brief":   map[string]interface{}{"content": "Be brief"},
+	})
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, "brief", templates[0].ID)
+	assert.Equal(t, "brief", templates[0].Name, "name defaults to the ID")
+	assert.True(t, templates[1].ReadOnly)
+
+	_, err = ParseConfigTemplates(map[string]interface{}{
+		"bad": map[string]interface{}{"content": "{{unknown}}"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+	_, err = ParseConfigTemplates("not a table")
+	assert.Error(t, err)
+}
+
+func TestService(t *testing.T) {
+	lookups := 0
+	stored := map[string]*Template{
+		"acme\x00custom": {ID: "custom", TenantID: "acme", Name: "Custom", Content: "Custom for {{user_name}}"},
+	}
+	store := StoreFunc(func(tenantID, id string) (*Template, error) {
+		lookups++
+		t, exists := stored[tenantID+"\x00"+id]
+		if !exists {
+			return nil, ErrTemplateNotFound
+		}
+		return t, nil
+	})
+	svc := NewService([]*Template{{ID: "support", Name: "Support", Content: "Support {{tenant}}", ReadOnly: true}}, store)
+
+	// Config templates are shared by all tenants and never hit the store
+	rendered, err := svc.Render("acme", "support", map[string]string{VarTenant: "acme"})
+	require.NoError(t, err)
+	assert.Equal(t, "Support acme", rendered)
+	assert.True(t, svc.IsConfigTemplate("support"))
+	assert.Len(t, svc.ConfigTemplates(), 1)
+	assert.Equal(t, 0, lookups)
+
+	// Stored templates are cached per tenant
+	rendered, err = svc.Render("acme", "custom", map[string]string{VarUserName: "Bob"})
+	require.NoError(t, err)
+	assert.Equal(t, "Custom for Bob", rendered)
+	_, err = svc.Get("acme", "custom")
+	require.NoError(t, err)
+	assert.Equal(t, 1, lookups)
+
+	_, err = svc.Get("other", "custom")
+	assert.ErrorIs(t, err, ErrTemplateNotFound, "templates are not visible to other tenants")
+
+	stored["acme\x00custom"] = &Template{ID: "custom", TenantID: "acme", Name: "Custom", Content: "Updated"}
+	svc.Invalidate("acme", "custom")
+	rendered, err = svc.Render("acme", "custom", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Updated", rendered)
+
+	_, err = NewService(nil, nil).Get("", "custom")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
@@ -0,0 +1,278 @@
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+)
+
+// IPBan is a temporary ban of a client IP from opening connections
+type IPBan struct {
+	IP       string    `json:"ip"`
+	Reason   string    `json:"reason,omitempty"`
+	BannedBy string    `json:"banned_by,omitempty"` // admin user ID
+	Until    time.Time `json:"until"`
+}
+
+// IPStats reports the recent connection attempts of a client IP
+type IPStats struct {
+	IP          string     `json:"ip"`
+	Attempts    int        `json:"attempts"` // allowed attempts in the current window
+	Rejected    int        `json:"rejected"` // attempts refused since the IP became active
+	LastSeen    time.Time  `json:"last_seen"`
+	BannedUntil *time.Time `json:"banned_until,omitempty"`
+}
+
+// ipActivity is the tracked connection activity of one client IP
+type ipActivity struct {
+	attempts []time.Time // allowed attempts within the window
+	rejected int
+	lastSeen time.Time
+}
+
+// IPLimiter limits connection attempts per client IP using a sliding window and
+// keeps a list of temporarily banned IPs. It applies before authentication, so
+// anonymous reconnect storms are refused without validating tokens, and is
+// independent of the per-user connection and message limits. State is kept in
+// process memory, so limits and bans apply per replica.
+type IPLimiter struct {
+	clients map[string]*ipActivity
+	bans    map[string]IPBan
+	window  time.Duration
+	limit   int // 0 disables throttling; bans still apply
+	mu      sync.Mutex
+
+	// Cleanup goroutine management
+	cleanupInterval time.Duration
+	stopCleanup     chan struct{}
+	cleanupWg       sync.WaitGroup
+	stopOnce        sync.Once
+}
+
+// NewIPLimiter creates a connection limiter allowing limit attempts per IP in
+// each window. A limit of 0 disables throttling but keeps the ban list.
+func NewIPLimiter(window time.Duration, limit int) *IPLimiter {
+	return &IPLimiter{
+		clients:         make(map[string]*ipActivity),
+		bans:            make(map[string]IPBan),
+		window:          window,
+		limit:           limit,
+		cleanupInterval: constants.DefaultCleanupInterval,
+		stopCleanup:     make(chan struct{}),
+	}
+}
+
+// Allow records a connection attempt from ip and reports whether it may proceed.
+// Attempts from banned IPs and attempts over the limit are refused.
+func (il *IPLimiter) Allow(ip string) bool {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	now := time.Now()
+	activity, exists := il.clients[ip]
+	if !exists {
+		// Reject new IPs when the tracked IP count exceeds the limit
+		if len(il.clients) >= constants.MaxUsersTracked {
+			metrics.RateLimitBlocked.With(prometheus.Labels{"limiter": "ip_connection"}).Inc()
+			return false
+		}
+		activity = &ipActivity{}
+		il.clients[ip] = activity
+	}
+	activity.lastSeen = now
+	activity.attempts = recentAttempts(activity.attempts, now.Add(-il.window))
+
+	if il.bannedLocked(ip, now) {
+		activity.rejected++
+		metrics.RateLimitBlocked.With(prometheus.Labels{"limiter": "ip_ban"}).Inc()
+		return false
+	}
+	if il.limit > 0 && len(activity.attempts) >= il.limit {
+		activity.rejected++
+		metrics.RateLimitBlocked.With(prometheus.Labels{"limiter": "ip_connection"}).Inc()
+		return false
+	}
+
+	activity.attempts = append(activity.attempts, now)
+	if len(activity.attempts) > constants.MaxEventsPerUser {
+		activity.attempts = activity.attempts[len(activity.attempts)-constants.MaxEventsPerUser:]
+	}
+	return true
+}
+
+// recentAttempts returns the attempts after cutoff, reusing the slice
+func recentAttempts(attempts []time.Time, cutoff time.Time) []time.Time {
+	recent := attempts[:0]
+	for _, t := range attempts {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// GetRetryAfter returns the time in milliseconds until ip may connect again:
+// until its ban expires, or until its oldest attempt leaves the window
+func (il *IPLimiter) GetRetryAfter(ip string) int {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	now := time.Now()
+	if ban, ok := il.bans[ip]; ok && ban.Until.After(now) {
+		return int(ban.Until.Sub(now).Milliseconds())
+	}
+
+	activity, ok := il.clients[ip]
+	if !ok || il.limit <= 0 || len(activity.attempts) < il.limit {
+		return 0
+	}
+	retryAfter := activity.attempts[0].Add(il.window).Sub(now)
+	if retryAfter < 0 {
+		return 0
+	}
+	return int(retryAfter.Milliseconds())
+}
+
+// Ban refuses connections from ip for duration and returns the ban.
+// Banning an already banned IP replaces its ban.
+func (il *IPLimiter) Ban(ip string, duration time.Duration, reason, bannedBy string) IPBan {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	ban := IPBan{IP: ip, Reason: reason, BannedBy: bannedBy, Until: time.Now().Add(duration)}
+	il.bans[ip] = ban
+	return ban
+}
+
+// Unban lifts the ban of ip and reports whether it was banned
+func (il *IPLimiter) Unban(ip string) bool {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	ban, ok := il.bans[ip]
+	delete(il.bans, ip)
+	return ok && ban.Until.After(time.Now())
+}
+
+// Banned returns the active ban of ip, if any
+func (il *IPLimiter) Banned(ip string) (IPBan, bool) {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	if !il.bannedLocked(ip, time.Now()) {
+		return IPBan{}, false
+	}
+	return il.bans[ip], true
+}
+
+// bannedLocked reports whether ip has an active ban. Caller must hold il.mu.
+func (il *IPLimiter) bannedLocked(ip string, now time.Time) bool {
+	ban, ok := il.bans[ip]
+	return ok && ban.Until.After(now)
+}
+
+// Bans returns the active bans, soonest expiry first
+func (il *IPLimiter) Bans() []IPBan {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	now := time.Now()
+	bans := make([]IPBan, 0, len(il.bans))
+	for _, ban := range il.bans {
+		if ban.Until.After(now) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Until.Before(bans[j].Until)
+	})
+	return bans
+}
+
+// TopTalkers returns the n IPs with the most connection attempts in the current
+// window, counting refused attempts
+func (il *IPLimiter) TopTalkers(n int) []IPStats {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-il.window)
+	stats := make([]IPStats, 0, len(il.clients))
+	for ip, activity := range il.clients {
+		activity.attempts = recentAttempts(activity.attempts, cutoff)
+		entry := IPStats{
+			IP:       ip,
+			Attempts: len(activity.attempts),
+			Rejected: activity.rejected,
+			LastSeen: activity.lastSeen,
+		}
+		if ban, ok := il.bans[ip]; ok && ban.Until.After(now) {
+			until := ban.Until
+			entry.BannedUntil = &until
+		}
+		stats = append(stats, entry)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		ti, tj := stats[i].Attempts+stats[i].Rejected, stats[j].Attempts+stats[j].Rejected
+		if ti != tj {
+			return ti > tj
+		}
+		return stats[i].IP < stats[j].IP
+	})
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// Cleanup forgets IPs without activity in the last window and expired bans
+func (il *IPLimiter) Cleanup() {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-il.window)
+	for ip, activity := range il.clients {
+		if !activity.lastSeen.After(cutoff) {
+			delete(il.clients, ip)
+		}
+	}
+	for ip, ban := range il.bans {
+		if !ban.Until.After(now) {
+			delete(il.bans, ip)
+		}
+	}
+}
+
+// StartCleanup starts a background goroutine that periodically calls Cleanup
+func (il *IPLimiter) StartCleanup() {
+	il.cleanupWg.Add(1)
+	go func() {
+		defer il.cleanupWg.Done()
+		ticker := time.NewTicker(il.cleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				il.Cleanup()
+			case <-il.stopCleanup:
+				return
+			}
+		}
+	}()
+}
+
+// StopCleanup stops the cleanup goroutine and waits for it to finish.
+// Safe to call concurrently and multiple times.
+func (il *IPLimiter) StopCleanup() {
+	il.stopOnce.Do(func() {
+		close(il.stopCleanup)
+	})
+	il.cleanupWg.Wait()
+}
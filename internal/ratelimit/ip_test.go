@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPLimiter_ThrottlesPerIP(t *testing.T) {
+	limiter := NewIPLimiter(time.Minute, 2)
+
+	assert.True(t, limiter.Allow("10.0.0.1"))
+	assert.True(t, limiter.Allow("10.0.0.1"))
+	assert.False(t, limiter.Allow("10.0.0.1"))
+	assert.Greater(t, limiter.GetRetryAfter("10.0.0.1"), 0)
+
+	// Other IPs have their own window
+	assert.True(t, limiter.Allow("10.0.0.2"))
+	assert.Equal(t, 0, limiter.GetRetryAfter("10.0.0.2"))
+}
+
+func TestIPLimiter_WindowExpires(t *testing.T) {
+	limiter := NewIPLimiter(50*time.Millisecond, 1)
+
+	assert.True(t, limiter.Allow("10.0.0.1"))
+	assert.False(t, limiter.Allow("10.0.0.1"))
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, limiter.Allow("10.0.0.1"))
+}
+
+func TestIPLimiter_ZeroLimitOnlyBans(t *testing.T) {
+	limiter := NewIPLimiter(time.Minute, 0)
+
+	for i := 0; i < 100; i++ {
+		require.True(t, limiter.Allow("10.0.0.1"))
+	}
+
+	limiter.Ban("10.0.0.1", time.Hour, "", "")
+	assert.False(t, limiter.Allow("10.0.0.1"))
+}
+
+func TestIPLimiter_BanAndUnban(t *testing.T) {
+	limiter := NewIPLimiter(time.Minute, 10)
+
+	ban := limiter.Ban("10.0.0.1", time.Hour, "reconnect storm", "admin-1")
+	assert.Equal(t, "reconnect storm", ban.Reason)
+	assert.Equal(t, "admin-1", ban.BannedBy)
+
+	got, banned := limiter.Banned("10.0.0.1")
+	require.True(t, banned)
+	assert.Equal(t, ban, got)
+	assert.False(t, limiter.Allow("10.0.0.1"))
+	assert.Greater(t, limiter.GetRetryAfter("10.0.0.1"), int((59 * time.Minute).Milliseconds()))
+	assert.Equal(t, []IPBan{ban}, limiter.Bans())
+
+	assert.True(t, limiter.Unban("10.0.0.1"))
+	assert.False(t, limiter.Unban("10.0.0.1"))
+	assert.True(t, limiter.Allow("10.0.0.1"))
+	assert.Empty(t, limiter.Bans())
+}
+
+func TestIPLimiter_BanExpires(t *testing.T) {
+	limiter := NewIPLimiter(time.Minute, 10)
+
+	limiter.Ban("10.0.0.1", 20*time.Millisecond, "", "")
+	assert.False(t, limiter.Allow("10.0.0.1"))
+	time.Sleep(30 * time.Millisecond)
+
+	_, banned := limiter.Banned("10.0.0.1")
+	assert.False(t, banned)
+	assert.True(t, limiter.Allow("10.0.0.1"))
+	assert.Empty(t, limiter.Bans())
+}
+
+func TestIPLimiter_TopTalkers(t *testing.T) {
+	limiter := NewIPLimiter(time.Minute, 3)
+
+	for i := 0; i < 5; i++ {
+		limiter.Allow("10.0.0.1")
+	}
+	limiter.Allow("10.0.0.2")
+	limiter.Allow("10.0.0.3")
+	limiter.Allow("10.0.0.3")
+	limiter.Ban("10.0.0.3", time.Hour, "", "")
+
+	top := limiter.TopTalkers(2)
+	require.Len(t, top, 2)
+	assert.Equal(t, "10.0.0.1", top[0].IP)
+	assert.Equal(t, 3, top[0].Attempts)
+	assert.Equal(t, 2, top[0].Rejected)
+	assert.Nil(t, top[0].BannedUntil)
+	assert.Equal(t, "10.0.0.3", top[1].IP)
+	assert.NotNil(t, top[1].BannedUntil)
+
+	assert.Len(t, limiter.TopTalkers(10), 3)
+}
+
+func TestIPLimiter_Cleanup(t *testing.T) {
+	limiter := NewIPLimiter(20*time.Millisecond, 10)
+
+	limiter.Allow("10.0.0.1")
+	limiter.Ban("10.0.0.2", 10*time.Millisecond, "", "")
+	time.Sleep(30 * time.Millisecond)
+	limiter.Cleanup()
+
+	assert.Empty(t, limiter.TopTalkers(10))
+	limiter.mu.Lock()
+	assert.Empty(t, limiter.bans)
+	limiter.mu.Unlock()
+}
+
+func TestIPLimiter_StopCleanupIsIdempotent(t *testing.T) {
+	limiter := NewIPLimiter(time.Minute, 10)
+	limiter.StartCleanup()
+	limiter.StopCleanup()
+	limiter.StopCleanup()
+}
@@ -0,0 +1,185 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// Policy is a named rate limit for a group of admin endpoints, applied per
+// caller on top of the global admin rate limit
+type Policy struct {
+	Name   string
+	Limit  int           // Requests allowed per window
+	Window time.Duration // Sliding window length
+}
+
+// PolicyNames lists the policies endpoints can be assigned to
+var PolicyNames = []string{constants.RatePolicyList, constants.RatePolicyExport, constants.RatePolicyBulk}
+
+// ParsePolicies converts the raw [chatbox.rate_limits] config value, a table of
+// policy name = { limit, window } entries, into policies sorted by name. The
+// window defaults to constants.DefaultRateWindow. Unknown policy names are
+// rejected so a typo cannot silently leave endpoints on the global limit.
+func ParsePolicies(raw interface{}) ([]Policy, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.rate_limits is not a table")
+	}
+
+	policies := make([]Policy, 0, len(table))
+	for name, value := range table {
+		// No else needed: early return pattern (guard clause)
+		if !isPolicyName(name) {
+			return nil, fmt.Errorf("unknown rate limit policy %q: must be one of %s", name, strings.Join(PolicyNames, ", "))
+		}
+		entry, ok := value.(map[string]interface{})
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("rate limit policy %s is not a table", name)
+		}
+
+		var limit int
+		switch v := entry["limit"].(type) {
+		case int64:
+			limit = int(v)
+		case int:
+			limit = v
+		default:
+			return nil, fmt.Errorf("rate limit policy %s: limit must be a positive integer", name)
+		}
+		// No else needed: early return pattern (guard clause)
+		if limit <= 0 {
+			return nil, fmt.Errorf("rate limit policy %s: limit must be a positive integer", name)
+		}
+
+		window := constants.DefaultRateWindow
+		// No else needed: optional operation (window defaults to DefaultRateWindow)
+		if windowStr, ok := entry["window"].(string); ok {
+			var err error
+			window, err = time.ParseDuration(windowStr)
+			// No else needed: early return pattern (guard clause)
+			if err != nil || window <= 0 {
+				return nil, fmt.Errorf("rate limit policy %s: window must be a positive duration, got %q", name, windowStr)
+			}
+		}
+		policies = append(policies, Policy{Name: name, Limit: limit, Window: window})
+	}
+
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	return policies, nil
+}
+
+// isPolicyName reports whether name is one of PolicyNames
+func isPolicyName(name string) bool {
+	for _, known := range PolicyNames {
+		// No else needed: early return pattern (match found)
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyLimiterFunc creates the limiter enforcing a policy
+type PolicyLimiterFunc func(policy Policy) Limiter
+
+// Policies holds the limiter of each configured policy. A nil Policies has no
+// limiters.
+type Policies struct {
+	limiters map[string]Limiter // policy name -> limiter
+}
+
+// NewPolicies creates the limiters of policies. newLimiter may be nil, in which
+// case windows are kept in process memory.
+func NewPolicies(policies []Policy, newLimiter PolicyLimiterFunc) *Policies {
+	// No else needed: optional operation (in-memory windows by default)
+	if newLimiter == nil {
+		newLimiter = func(policy Policy) Limiter {
+			return NewMessageLimiter(policy.Window, policy.Limit)
+		}
+	}
+	limiters := make(map[string]Limiter, len(policies))
+	for _, policy := range policies {
+		limiters[policy.Name] = newLimiter(policy)
+	}
+	return &Policies{limiters: limiters}
+}
+
+// Limiter returns the limiter of the named policy, if it is configured
+func (p *Policies) Limiter(name string) (Limiter, bool) {
+	// No else needed: early return pattern (no policies)
+	if p == nil {
+		return nil, false
+	}
+	limiter, ok := p.limiters[name]
+	return limiter, ok
+}
+
+// StartCleanup starts the background maintenance of every limiter
+func (p *Policies) StartCleanup() {
+	// No else needed: early return pattern (no policies)
+	if p == nil {
+		return
+	}
+	for _, limiter := range p.limiters {
+		limiter.StartCleanup()
+	}
+}
+
+// StopCleanup stops the background maintenance of every limiter. Safe to call
+// multiple times.
+func (p *Policies) StopCleanup() {
+	// No else needed: early return pattern (no policies)
+	if p == nil {
+		return
+	}
+	for _, limiter := range p.limiters {
+		limiter.StopCleanup()
+	}
+}
+
+// CheckReconfigure reports whether Reconfigure would accept policies, without
+// changing anything.
+func (p *Policies) CheckReconfigure(policies []Policy) error {
+	var configured map[string]Limiter
+	// No else needed: conditional assignment (a nil Policies has no limiters)
+	if p != nil {
+		configured = p.limiters
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(policies) != len(configured) {
+		return fmt.Errorf("rate limit policies cannot be added or removed without a restart")
+	}
+	for _, policy := range policies {
+		limiter, ok := configured[policy.Name]
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return fmt.Errorf("rate limit policy %s cannot be added without a restart", policy.Name)
+		}
+		// No else needed: early return pattern (guard clause)
+		if _, ok := limiter.(Reconfigurable); !ok {
+			return fmt.Errorf("rate limit policy %s cannot be reconfigured", policy.Name)
+		}
+	}
+	return nil
+}
+
+// Reconfigure changes the limits and windows of the configured policies, e.g.
+// on a configuration reload. Endpoints are assigned their limiters when routes
+// are registered, so policies cannot be added or removed; nothing changes
+// unless policies names exactly the configured ones, see CheckReconfigure.
+func (p *Policies) Reconfigure(policies []Policy) error {
+	// No else needed: early return pattern (guard clause)
+	if err := p.CheckReconfigure(policies); err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		p.limiters[policy.Name].(Reconfigurable).SetLimit(policy.Window, policy.Limit)
+	}
+	return nil
+}
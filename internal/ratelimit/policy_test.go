@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies(map[string]interface{}{
+		"list":   map[string]interface{}{"limit": int64(60)},
+		"export": map[string]interface{}{"limit": int64(5), "window": "10m"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Policy{
+		{Name: constants.RatePolicyExport, Limit: 5, Window: 10 * time.Minute},
+		{Name: constants.RatePolicyList, Limit: 60, Window: constants.DefaultRateWindow},
+	}, policies)
+
+	invalid := []interface{}{
+		"not a table",
+		map[string]interface{}{"exports": map[string]interface{}{"limit": int64(5)}},
+		map[string]interface{}{"export": int64(5)},
+		map[string]interface{}{"export": map[string]interface{}{}},
+		map[string]interface{}{"export": map[string]interface{}{"limit": int64(0)}},
+		map[string]interface{}{"export": map[string]interface{}{"limit": "5"}},
+		map[string]interface{}{"export": map[string]interface{}{"limit": int64(5), "window": "soon"}},
+		map[string]interface{}{"export": map[string]interface{}{"limit": int64(5), "window": "-1m"}},
+	}
+	for _, raw := range invalid {
+		_, err := ParsePolicies(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+}
+
+func TestPolicies_Limiter(t *testing.T) {
+	policies := NewPolicies([]Policy{{Name: constants.RatePolicyBulk, Limit: 1, Window: time.Minute}}, nil)
+	defer policies.StopCleanup()
+
+	limiter, ok := policies.Limiter(constants.RatePolicyBulk)
+	require.True(t, ok)
+	assert.True(t, limiter.Allow("admin-1"))
+	assert.False(t, limiter.Allow("admin-1"), "the policy limit applies per caller")
+	assert.True(t, limiter.Allow("admin-2"))
+
+	_, ok = policies.Limiter(constants.RatePolicyExport)
+	assert.False(t, ok, "unconfigured policies have no limiter")
+
+	var none *Policies
+	_, ok = none.Limiter(constants.RatePolicyBulk)
+	assert.False(t, ok)
+	none.StartCleanup()
+	none.StopCleanup()
+}
+
+func TestPolicies_Reconfigure(t *testing.T) {
+	policies := NewPolicies([]Policy{{Name: constants.RatePolicyBulk, Limit: 1, Window: time.Minute}}, nil)
+	defer policies.StopCleanup()
+	limiter, _ := policies.Limiter(constants.RatePolicyBulk)
+	assert.True(t, limiter.Allow("admin-1"))
+	assert.False(t, limiter.Allow("admin-1"))
+
+	require.NoError(t, policies.Reconfigure([]Policy{{Name: constants.RatePolicyBulk, Limit: 2, Window: time.Minute}}))
+	assert.True(t, limiter.Allow("admin-1"), "the endpoint's limiter has the new limit")
+	assert.False(t, limiter.Allow("admin-1"))
+
+	// Policies cannot be added or removed
+	assert.NoError(t, policies.CheckReconfigure([]Policy{{Name: constants.RatePolicyBulk, Limit: 3, Window: time.Minute}}))
+	assert.Error(t, policies.CheckReconfigure(nil))
+	assert.False(t, limiter.Allow("admin-1"), "CheckReconfigure changes nothing")
+	assert.Error(t, policies.Reconfigure(nil))
+	assert.Error(t, policies.Reconfigure([]Policy{{Name: constants.RatePolicyExport, Limit: 5, Window: time.Minute}}))
+	assert.Error(t, policies.Reconfigure([]Policy{
+		{Name: constants.RatePolicyBulk, Limit: 5, Window: time.Minute},
+		{Name: constants.RatePolicyExport, Limit: 5, Window: time.Minute},
+	}))
+
+	var none *Policies
+	assert.NoError(t, none.Reconfigure(nil))
+	assert.Error(t, none.Reconfigure([]Policy{{Name: constants.RatePolicyBulk, Limit: 1, Window: time.Minute}}))
+}
@@ -62,6 +62,29 @@ func (cl *ConnectionLimiter) GetCount(userID string) int {
 	return cl.connections[userID]
 }
 
+// Limiter limits the rate of events per key (user ID or client IP).
+// MessageLimiter keeps its window in process memory; RedisLimiter shares it
+// across replicas.
+type Limiter interface {
+	// Allow records an event for key and reports whether it is within the limit
+	Allow(key string) bool
+	// GetRetryAfter returns the time in milliseconds until the next event is allowed
+	GetRetryAfter(key string) int
+	// Reset clears the rate limit history for key
+	Reset(key string)
+	// StartCleanup starts any background maintenance the limiter needs
+	StartCleanup()
+	// StopCleanup stops background maintenance. Safe to call multiple times.
+	StopCleanup()
+}
+
+// Reconfigurable is implemented by limiters whose window and limit can change
+// while they are in use, e.g. on a configuration reload
+type Reconfigurable interface {
+	// SetLimit changes the window and limit; events already recorded are kept
+	SetLimit(window time.Duration, limit int)
+}
+
 // MessageLimiter limits the rate of messages per user using sliding window
 type MessageLimiter struct {
 	events map[string][]time.Time // userID -> timestamps
@@ -175,6 +198,14 @@ func (ml *MessageLimiter) GetRetryAfter(userID string) int {
 	return int(retryAfter.Milliseconds())
 }
 
+// SetLimit changes the window and limit of the limiter
+func (ml *MessageLimiter) SetLimit(window time.Duration, limit int) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	ml.window = window
+	ml.limit = limit
+}
+
 // Reset clears the rate limit history for a user
 func (ml *MessageLimiter) Reset(userID string) {
 	ml.mu.Lock()
@@ -253,3 +284,92 @@ func (ml *MessageLimiter) StopCleanup() {
 	})
 	ml.cleanupWg.Wait()
 }
+
+// TokenBudgetLimiter caps the number of LLM tokens each user may consume per
+// UTC day. Usage is recorded after each LLM response, so a user's final
+// response of the day may overshoot the budget; the next message is rejected.
+// All usage resets at midnight UTC.
+type TokenBudgetLimiter struct {
+	used    map[string]int // userID -> tokens used today
+	budget  int
+	resetAt time.Time // start of the next UTC day
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewTokenBudgetLimiter creates a limiter allowing budget tokens per user per day
+func NewTokenBudgetLimiter(budget int) *TokenBudgetLimiter {
+	tl := &TokenBudgetLimiter{
+		used:   make(map[string]int),
+		budget: budget,
+		now:    time.Now,
+	}
+	tl.resetAt = nextUTCMidnight(tl.now())
+	return tl
+}
+
+// nextUTCMidnight returns the start of the UTC day after t
+func nextUTCMidnight(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// rollover clears usage once the day has ended. Caller must hold mu.
+func (tl *TokenBudgetLimiter) rollover() {
+	now := tl.now()
+	// No else needed: optional operation (only at the day boundary)
+	if !now.Before(tl.resetAt) {
+		tl.used = make(map[string]int)
+		tl.resetAt = nextUTCMidnight(now)
+	}
+}
+
+// Allow reports whether the user has budget left for another LLM request
+func (tl *TokenBudgetLimiter) Allow(userID string) bool {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	tl.rollover()
+	if tl.used[userID] >= tl.budget {
+		metrics.RateLimitBlocked.With(prometheus.Labels{"limiter": "token_budget"}).Inc()
+		return false
+	}
+	return true
+}
+
+// Consume records tokens used by the user
+func (tl *TokenBudgetLimiter) Consume(userID string, tokens int) {
+	// No else needed: early return pattern (guard clause)
+	if tokens <= 0 {
+		return
+	}
+
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	tl.rollover()
+	tl.used[userID] += tokens
+}
+
+// Used returns the tokens the user has consumed today
+func (tl *TokenBudgetLimiter) Used(userID string) int {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	tl.rollover()
+	return tl.used[userID]
+}
+
+// Budget returns the daily token budget per user
+func (tl *TokenBudgetLimiter) Budget() int {
+	return tl.budget
+}
+
+// ResetAt returns when the current day's usage resets
+func (tl *TokenBudgetLimiter) ResetAt() time.Time {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	tl.rollover()
+	return tl.resetAt
+}
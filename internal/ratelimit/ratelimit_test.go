@@ -114,6 +114,18 @@ func TestMessageLimiter_Reset(t *testing.T) {
 	assert.True(t, ml.Allow("user1"))
 }
 
+func TestMessageLimiter_SetLimit(t *testing.T) {
+	ml := NewMessageLimiter(time.Minute, 1)
+	assert.True(t, ml.Allow("user1"))
+	assert.False(t, ml.Allow("user1"))
+
+	// Recorded messages count towards the new limit
+	ml.SetLimit(time.Minute, 3)
+	assert.True(t, ml.Allow("user1"))
+	assert.True(t, ml.Allow("user1"))
+	assert.False(t, ml.Allow("user1"))
+}
+
 func TestMessageLimiter_Cleanup(t *testing.T) {
 	ml := NewMessageLimiter(100*time.Millisecond, 2)
 
@@ -267,3 +279,39 @@ func TestBoundedEvents_MaxUsersTracked(t *testing.T) {
 	allowed := ml.Allow("brand-new-user")
 	assert.False(t, allowed, "new user should be denied when MaxUsersTracked exceeded")
 }
+
+func TestTokenBudgetLimiter_BlocksOnceBudgetSpent(t *testing.T) {
+	tl := NewTokenBudgetLimiter(100)
+
+	assert.True(t, tl.Allow("user-1"))
+	tl.Consume("user-1", 60)
+	assert.True(t, tl.Allow("user-1"), "user under budget should be allowed")
+
+	// The response that crosses the budget is recorded; the next request is rejected
+	tl.Consume("user-1", 60)
+	assert.False(t, tl.Allow("user-1"))
+	assert.Equal(t, 120, tl.Used("user-1"))
+
+	// Other users have their own budget
+	assert.True(t, tl.Allow("user-2"))
+
+	// Non-positive usage is ignored
+	tl.Consume("user-2", -5)
+	assert.Equal(t, 0, tl.Used("user-2"))
+}
+
+func TestTokenBudgetLimiter_ResetsAtUTCMidnight(t *testing.T) {
+	now := time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC)
+	tl := NewTokenBudgetLimiter(10)
+	tl.now = func() time.Time { return now }
+	tl.resetAt = nextUTCMidnight(now)
+
+	tl.Consume("user-1", 10)
+	require.False(t, tl.Allow("user-1"))
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), tl.ResetAt())
+
+	now = now.Add(time.Hour)
+	assert.True(t, tl.Allow("user-1"), "budget should reset on the next UTC day")
+	assert.Equal(t, 0, tl.Used("user-1"))
+	assert.Equal(t, time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC), tl.ResetAt())
+}
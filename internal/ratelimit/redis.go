@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript atomically trims a key's window, counts the remaining events
+// and records a new one when under the limit. Events are members of a sorted set
+// scored by their timestamp in milliseconds.
+//
+// KEYS[1] window key; ARGV: now (ms), window (ms), limit, member.
+// Returns 1 when the event was recorded, 0 when the limit is reached.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+if redis.call('ZCARD', key) >= limit then
+	return 0
+end
+redis.call('ZADD', key, now, ARGV[4])
+redis.call('PEXPIRE', key, window)
+return 1
+`)
+
+// RedisLimiter is a sliding window limiter whose windows live in Redis, so the
+// limit applies across all replicas rather than per process.
+//
+// Timestamps come from the calling replica's clock; replicas are assumed to be
+// NTP-synchronised. If Redis is unavailable, checks fail open: the request is
+// allowed and chatbox_ratelimit_backend_errors_total is incremented.
+type RedisLimiter struct {
+	client   redis.UniversalClient
+	name     string // Distinguishes limiters sharing a Redis instance, e.g. "message" or "admin"
+	window   time.Duration
+	limit    int
+	mu       sync.RWMutex // Protects window and limit
+	instance string       // Random per-limiter prefix keeping event members unique across replicas
+	seq      atomic.Uint64
+}
+
+// NewRedisLimiter creates a Redis-backed sliding window limiter.
+// name: limiter name used in keys and metrics (e.g., "message", "admin")
+// window: time window for rate limiting (e.g., 1 minute)
+// limit: maximum number of events allowed in the window
+func NewRedisLimiter(client redis.UniversalClient, name string, window time.Duration, limit int) *RedisLimiter {
+	instance := make([]byte, 8)
+	// crypto/rand.Read never returns an error on supported platforms
+	_, _ = rand.Read(instance)
+	return &RedisLimiter{
+		client:   client,
+		name:     name,
+		window:   window,
+		limit:    limit,
+		instance: hex.EncodeToString(instance),
+	}
+}
+
+// SetLimit changes the window and limit of the limiter. Windows already in
+// Redis keep their events; their expiry follows the new window on the next event.
+func (rl *RedisLimiter) SetLimit(window time.Duration, limit int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.window = window
+	rl.limit = limit
+}
+
+// limits returns the current window and limit
+func (rl *RedisLimiter) limits() (time.Duration, int) {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.window, rl.limit
+}
+
+// key returns the Redis key holding the window for a user or client IP
+func (rl *RedisLimiter) key(key string) string {
+	return constants.RedisRateLimitPrefix + rl.name + ":" + key
+}
+
+// Allow checks if an event is allowed based on rate limiting
+// Returns true if allowed, false if rate limit exceeded
+func (rl *RedisLimiter) Allow(key string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.RedisOperationTimeout)
+	defer cancel()
+
+	window, limit := rl.limits()
+	now := time.Now().UnixMilli()
+	member := fmt.Sprintf("%d-%s-%d", now, rl.instance, rl.seq.Add(1))
+	allowed, err := slidingWindowScript.Run(ctx, rl.client, []string{rl.key(key)},
+		now, window.Milliseconds(), limit, member).Int()
+	if err != nil {
+		metrics.RateLimitBackendErrors.With(prometheus.Labels{"limiter": rl.name}).Inc()
+		return true
+	}
+
+	if allowed == 0 {
+		metrics.RateLimitBlocked.With(prometheus.Labels{"limiter": rl.name}).Inc()
+		return false
+	}
+	return true
+}
+
+// GetRetryAfter returns the time in milliseconds until the next event is allowed
+func (rl *RedisLimiter) GetRetryAfter(key string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.RedisOperationTimeout)
+	defer cancel()
+
+	window, limit := rl.limits()
+	now := time.Now()
+	cutoff := strconv.FormatInt(now.Add(-window).UnixMilli(), 10)
+
+	count, err := rl.client.ZCount(ctx, rl.key(key), "("+cutoff, "+inf").Result()
+	if err != nil || count < int64(limit) {
+		return 0
+	}
+
+	// Find the oldest event in the window
+	oldest, err := rl.client.ZRangeByScoreWithScores(ctx, rl.key(key), &redis.ZRangeBy{
+		Min:   "(" + cutoff,
+		Max:   "+inf",
+		Count: 1,
+	}).Result()
+	if err != nil || len(oldest) == 0 {
+		return 0
+	}
+
+	// Calculate when the oldest event will expire
+	expiresAt := time.UnixMilli(int64(oldest[0].Score)).Add(window)
+	retryAfter := expiresAt.Sub(now)
+	if retryAfter < 0 {
+		return 0
+	}
+	return int(retryAfter.Milliseconds())
+}
+
+// Reset clears the rate limit history for a user or client IP
+func (rl *RedisLimiter) Reset(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.RedisOperationTimeout)
+	defer cancel()
+
+	if err := rl.client.Del(ctx, rl.key(key)).Err(); err != nil {
+		metrics.RateLimitBackendErrors.With(prometheus.Labels{"limiter": rl.name}).Inc()
+	}
+}
+
+// StartCleanup is a no-op: Redis expires each window key once it goes idle.
+func (rl *RedisLimiter) StartCleanup() {}
+
+// StopCleanup is a no-op. The Redis client is owned by the caller, which closes it.
+func (rl *RedisLimiter) StopCleanup() {}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Both implementations must be usable wherever a Limiter is expected
+var (
+	_ Limiter = (*MessageLimiter)(nil)
+	_ Limiter = (*RedisLimiter)(nil)
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+func TestRedisLimiter_Allow(t *testing.T) {
+	_, client := newTestRedis(t)
+	rl := NewRedisLimiter(client, "message", time.Minute, 3)
+
+	assert.True(t, rl.Allow("user1"))
+	assert.True(t, rl.Allow("user1"))
+	assert.True(t, rl.Allow("user1"))
+	assert.False(t, rl.Allow("user1"))
+
+	// Different user should be allowed
+	assert.True(t, rl.Allow("user2"))
+}
+
+func TestRedisLimiter_SetLimit(t *testing.T) {
+	_, client := newTestRedis(t)
+	rl := NewRedisLimiter(client, "message", time.Minute, 1)
+	assert.True(t, rl.Allow("user1"))
+	assert.False(t, rl.Allow("user1"))
+
+	rl.SetLimit(time.Minute, 2)
+	assert.True(t, rl.Allow("user1"))
+	assert.False(t, rl.Allow("user1"))
+	assert.Greater(t, rl.GetRetryAfter("user1"), 0)
+}
+
+func TestRedisLimiter_SharedAcrossReplicas(t *testing.T) {
+	_, client := newTestRedis(t)
+	replicaA := NewRedisLimiter(client, "message", time.Minute, 2)
+	replicaB := NewRedisLimiter(client, "message", time.Minute, 2)
+
+	assert.True(t, replicaA.Allow("user1"))
+	assert.True(t, replicaB.Allow("user1"))
+	assert.False(t, replicaA.Allow("user1"), "limit applies across replicas")
+	assert.False(t, replicaB.Allow("user1"))
+
+	// Limiters with different names keep separate windows
+	admin := NewRedisLimiter(client, "admin", time.Minute, 2)
+	assert.True(t, admin.Allow("user1"))
+}
+
+func TestRedisLimiter_GetRetryAfter(t *testing.T) {
+	_, client := newTestRedis(t)
+	rl := NewRedisLimiter(client, "message", time.Minute, 2)
+
+	assert.Equal(t, 0, rl.GetRetryAfter("user1"))
+
+	rl.Allow("user1")
+	rl.Allow("user1")
+	retryAfter := rl.GetRetryAfter("user1")
+	assert.Greater(t, retryAfter, 0)
+	assert.LessOrEqual(t, retryAfter, int(time.Minute.Milliseconds()))
+}
+
+func TestRedisLimiter_Reset(t *testing.T) {
+	_, client := newTestRedis(t)
+	rl := NewRedisLimiter(client, "message", time.Minute, 1)
+
+	assert.True(t, rl.Allow("user1"))
+	assert.False(t, rl.Allow("user1"))
+
+	rl.Reset("user1")
+	assert.True(t, rl.Allow("user1"))
+}
+
+func TestRedisLimiter_WindowKeyExpires(t *testing.T) {
+	mr, client := newTestRedis(t)
+	rl := NewRedisLimiter(client, "message", time.Minute, 1)
+
+	require.True(t, rl.Allow("user1"))
+	key := rl.key("user1")
+	assert.True(t, mr.Exists(key))
+	assert.Greater(t, mr.TTL(key), time.Duration(0))
+
+	mr.FastForward(time.Minute)
+	assert.False(t, mr.Exists(key), "idle windows expire without a cleanup goroutine")
+}
+
+func TestRedisLimiter_FailsOpen(t *testing.T) {
+	mr, client := newTestRedis(t)
+	rl := NewRedisLimiter(client, "message", time.Minute, 1)
+	mr.Close()
+
+	assert.True(t, rl.Allow("user1"))
+	assert.True(t, rl.Allow("user1"), "requests are allowed while Redis is unavailable")
+	assert.Equal(t, 0, rl.GetRetryAfter("user1"))
+}
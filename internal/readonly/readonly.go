@@ -0,0 +1,71 @@
+// Package readonly holds the read-only mode of the service, an admin toggle for
+// storage incidents. While it is on, new sessions and messages are refused with
+// a READ_ONLY error while session history stays readable.
+//
+// The mode is toggled with POST /admin/readonly and persisted in MongoDB, so it
+// survives restarts; every replica periodically adopts the stored mode, so a
+// toggle on one replica reaches the others.
+package readonly
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the read-only mode as last set by an admin
+type State struct {
+	Enabled   bool      `json:"enabled"`
+	Message   string    `json:"message,omitempty"`    // Shown to users; constants.DefaultReadOnlyMessage when empty
+	UpdatedBy string    `json:"updated_by,omitempty"` // Admin who set the mode
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Mode is the current read-only mode of a replica. A nil Mode is never
+// read-only. Safe for concurrent use.
+type Mode struct {
+	mu    sync.RWMutex
+	state State
+}
+
+// New creates a mode starting in state
+func New(state State) *Mode {
+	return &Mode{state: state}
+}
+
+// State returns the current state
+func (m *Mode) State() State {
+	// No else needed: early return pattern (no mode)
+	if m == nil {
+		return State{}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Enabled reports whether the service is read-only, and the message to show users
+func (m *Mode) Enabled() (string, bool) {
+	state := m.State()
+	return state.Message, state.Enabled
+}
+
+// Set replaces the current state
+func (m *Mode) Set(state State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+}
+
+// Sync adopts state when it was set after the current state, e.g. stored by an
+// admin on another replica, and reports whether it did. A state set on this
+// replica that could not be stored is kept until a newer one is stored.
+func (m *Mode) Sync(state State) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// No else needed: early return pattern (the current state is as recent)
+	if !state.UpdatedAt.After(m.state.UpdatedAt) {
+		return false
+	}
+	m.state = state
+	return true
+}
@@ -0,0 +1,32 @@
+package readonly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMode(t *testing.T) {
+	var none *Mode
+	_, enabled := none.Enabled()
+	assert.False(t, enabled, "nil modes are never read-only")
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	mode := New(State{})
+	mode.Set(State{Enabled: true, Message: "Storage incident", UpdatedBy: "admin-1", UpdatedAt: now})
+	message, enabled := mode.Enabled()
+	assert.True(t, enabled)
+	assert.Equal(t, "Storage incident", message)
+
+	// Older stored states do not override newer local ones
+	assert.False(t, mode.Sync(State{UpdatedAt: now.Add(-time.Minute)}))
+	assert.False(t, mode.Sync(State{UpdatedAt: now}))
+	_, enabled = mode.Enabled()
+	assert.True(t, enabled)
+
+	assert.True(t, mode.Sync(State{Enabled: false, UpdatedBy: "admin-2", UpdatedAt: now.Add(time.Minute)}))
+	_, enabled = mode.Enabled()
+	assert.False(t, enabled)
+	assert.Equal(t, "admin-2", mode.State().UpdatedBy)
+}
@@ -0,0 +1,217 @@
+// Package remotewrite pushes samples to a Prometheus remote write endpoint
+// (remote write 1.0: a snappy-compressed protobuf WriteRequest), for sites that
+// collect metrics without scraping the service.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/real-rm/chatbox/internal/constants"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrNoEndpoint is returned when the client is created without an endpoint
+var ErrNoEndpoint = errors.New("remote write endpoint is required")
+
+// Sample is the value of one series at push time
+type Sample struct {
+	Name   string            // Metric name
+	Labels map[string]string // Labels besides the metric name
+	Value  float64
+}
+
+// Config holds remote write endpoint settings
+type Config struct {
+	Endpoint    string        // URL the samples are POSTed to
+	BearerToken string        // Sent as a bearer token when set
+	Timeout     time.Duration // HTTP timeout (defaults to constants.DefaultMetricsPushTimeout)
+}
+
+// Client pushes samples to a remote write endpoint
+type Client struct {
+	endpoint    string
+	bearerToken string
+	client      *http.Client
+}
+
+// NewClient creates a remote write client. The endpoint is not validated
+// beyond being set; callers check its scheme.
+func NewClient(cfg Config) (*Client, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.Endpoint == "" {
+		return nil, ErrNoEndpoint
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultMetricsPushTimeout
+	}
+	return &Client{
+		endpoint:    cfg.Endpoint,
+		bearerToken: cfg.BearerToken,
+		client:      &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Push sends samples, all timestamped at, in one request
+func (c *Client) Push(ctx context.Context, samples []Sample, at time.Time) error {
+	body := snappy.Encode(nil, Encode(samples, at))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create remote write request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	// No else needed: optional operation (unauthenticated endpoints)
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	resp, err := c.client.Do(req)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to send remote write request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// No else needed: early return pattern (guard clause)
+	if resp.StatusCode/100 != 2 {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, constants.MaxErrorBodyBytes))
+		return fmt.Errorf("remote write endpoint returned status %d: %s", resp.StatusCode, errBody)
+	}
+	return nil
+}
+
+// Encode returns the uncompressed protobuf WriteRequest of samples, each a
+// series with one sample timestamped at. Labels are sorted by name, as
+// receivers require.
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; } // milliseconds
+func Encode(samples []Sample, at time.Time) []byte {
+	var req []byte
+	for _, s := range samples {
+		names := make([]string, 0, len(s.Labels)+1)
+		values := make(map[string]string, len(s.Labels)+1)
+		for name, value := range s.Labels {
+			names = append(names, name)
+			values[name] = value
+		}
+		names = append(names, "__name__")
+		values["__name__"] = s.Name
+		sort.Strings(names)
+
+		var series []byte
+		for _, name := range names {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, values[name])
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(at.UnixMilli()))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, series)
+	}
+	return req
+}
+
+// CollectFunc returns the samples to push
+type CollectFunc func(ctx context.Context) ([]Sample, error)
+
+// Pusher periodically collects samples and pushes them. Failed pushes are
+// reported to onError and not retried: the next push carries fresh values.
+type Pusher struct {
+	client   *Client
+	interval time.Duration
+	collect  CollectFunc
+	onError  func(error)
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewPusher creates a pusher of the samples returned by collect every
+// interval. onError may be nil.
+func NewPusher(client *Client, interval time.Duration, collect CollectFunc, onError func(error)) *Pusher {
+	// No else needed: optional operation (errors ignored by default)
+	if onError == nil {
+		onError = func(error) {}
+	}
+	return &Pusher{
+		client:   client,
+		interval: interval,
+		collect:  collect,
+		onError:  onError,
+		stop:     make(chan struct{}),
+	}
+}
+
+// PushOnce collects the samples and pushes them
+func (p *Pusher) PushOnce(ctx context.Context) error {
+	samples, err := p.collect(ctx)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to collect metrics: %w", err)
+	}
+	return p.client.Push(ctx, samples, time.Now())
+}
+
+// Start pushes every interval until Stop is called
+func (p *Pusher) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+			// No else needed: optional operation (errors are reported, next push retries)
+			if err := p.PushOnce(ctx); err != nil {
+				p.onError(err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// Stop stops the pusher and waits for a push in progress.
+// Safe to call multiple times and when the pusher was never started.
+func (p *Pusher) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+}
@@ -0,0 +1,167 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodedSeries is a TimeSeries read back from an encoded WriteRequest
+type decodedSeries struct {
+	labels    [][2]string
+	value     float64
+	timestamp int64
+}
+
+// fields splits a protobuf message into its length-delimited and fixed/varint fields
+func fields(t *testing.T, b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, m := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, m, 0)
+			fn(num, typ, v, 0)
+			b = b[m:]
+		case protowire.Fixed64Type:
+			v, m := protowire.ConsumeFixed64(b)
+			require.GreaterOrEqual(t, m, 0)
+			fn(num, typ, nil, v)
+			b = b[m:]
+		case protowire.VarintType:
+			v, m := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, m, 0)
+			fn(num, typ, nil, v)
+			b = b[m:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+}
+
+func decode(t *testing.T, req []byte) []decodedSeries {
+	var out []decodedSeries
+	fields(t, req, func(_ protowire.Number, _ protowire.Type, series []byte, _ uint64) {
+		var s decodedSeries
+		fields(t, series, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+			switch num {
+			case 1:
+				var label [2]string
+				fields(t, v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+					label[num-1] = string(v)
+				})
+				s.labels = append(s.labels, label)
+			case 2:
+				fields(t, v, func(num protowire.Number, _ protowire.Type, _ []byte, x uint64) {
+					if num == 1 {
+						s.value = math.Float64frombits(x)
+					} else {
+						s.timestamp = int64(x)
+					}
+				})
+			}
+		})
+		out = append(out, s)
+	})
+	return out
+}
+
+func TestEncode(t *testing.T) {
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	series := decode(t, Encode([]Sample{
+		{Name: "chatbox_sessions", Labels: map[string]string{"window": "24h", "job": "chatbox"}, Value: 42},
+		{Name: "chatbox_feedback_positive_rate", Value: 0.75},
+	}, at))
+
+	require.Len(t, series, 2)
+	assert.Equal(t, [][2]string{{"__name__", "chatbox_sessions"}, {"job", "chatbox"}, {"window", "24h"}}, series[0].labels, "labels are sorted by name")
+	assert.Equal(t, 42.0, series[0].value)
+	assert.Equal(t, at.UnixMilli(), series[0].timestamp)
+	assert.Equal(t, [][2]string{{"__name__", "chatbox_feedback_positive_rate"}}, series[1].labels)
+	assert.Equal(t, 0.75, series[1].value)
+}
+
+func TestClient_Push(t *testing.T) {
+	var got []decodedSeries
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		got = decode(t, req)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Endpoint: server.URL, BearerToken: "secret"})
+	require.NoError(t, err)
+	require.NoError(t, client.Push(context.Background(), []Sample{{Name: "chatbox_sessions", Value: 3}}, time.Now()))
+	require.Len(t, got, 1)
+	assert.Equal(t, 3.0, got[0].value)
+
+	_, err = NewClient(Config{})
+	assert.ErrorIs(t, err, ErrNoEndpoint)
+}
+
+func TestClient_PushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Endpoint: server.URL})
+	require.NoError(t, err)
+	err = client.Push(context.Background(), []Sample{{Name: "chatbox_sessions", Value: 1}}, time.Now())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+	assert.Contains(t, err.Error(), "out of order sample")
+}
+
+func TestPusher(t *testing.T) {
+	pushed := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed <- struct{}{}
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{Endpoint: server.URL})
+	require.NoError(t, err)
+
+	collected := 0
+	pusher := NewPusher(client, 10*time.Millisecond, func(ctx context.Context) ([]Sample, error) {
+		collected++
+		return []Sample{{Name: "chatbox_sessions", Value: float64(collected)}}, nil
+	}, nil)
+	pusher.Start()
+	select {
+	case <-pushed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no push")
+	}
+	pusher.Stop()
+	pusher.Stop()
+
+	failing := NewPusher(client, time.Hour, func(ctx context.Context) ([]Sample, error) {
+		return nil, errors.New("mongo down")
+	}, nil)
+	err = failing.PushOnce(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mongo down")
+	failing.Stop()
+}
@@ -0,0 +1,93 @@
+// Package requestid assigns every HTTP request and WebSocket connection an ID
+// that correlates its log lines across the chatbox and the services around it.
+//
+// The ID comes from the caller's X-Request-ID header when it is well formed, so
+// a request keeps one ID from the edge proxy through the chatbox; otherwise a
+// new one is generated. The middleware echoes the ID in the response header and
+// stores it in the request context, where log calls and the WebSocket handler
+// pick it up.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// ContextKey is the gin context key the middleware stores the ID under
+const ContextKey = "request_id"
+
+type contextKey struct{}
+
+// New generates a request ID
+func New() string {
+	id := make([]byte, 16)
+	// No else needed: fallback logic for rare error case
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(id)
+}
+
+// Valid reports whether id may be used as a request ID: 1 to
+// constants.MaxRequestIDLength letters, digits, '-', '_', '.' or ':', so a
+// caller's header cannot forge log fields
+func Valid(id string) bool {
+	// No else needed: early return pattern (guard clause)
+	if id == "" || len(id) > constants.MaxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithContext returns a copy of ctx carrying id
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx, or "" when there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromRequest returns the ID of r: the one assigned by Middleware, else the
+// X-Request-ID header when valid, else a new ID
+func FromRequest(r *http.Request) string {
+	// No else needed: early return pattern (assigned by the middleware)
+	if id := FromContext(r.Context()); id != "" {
+		return id
+	}
+	// No else needed: early return pattern (continue the caller's ID)
+	if id := r.Header.Get(constants.HeaderRequestID); Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// Middleware returns a gin middleware that assigns each request its ID, echoes
+// it in the X-Request-ID response header and stores it in the request context
+// and under ContextKey
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := FromRequest(c.Request)
+		c.Request = c.Request.WithContext(WithContext(c.Request.Context(), id))
+		c.Set(ContextKey, id)
+		c.Header(constants.HeaderRequestID, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("3f2a9c1e-7b4d-4e8a-9f00-1c2d3e4f5a6b"))
+	assert.True(t, Valid("edge.proxy:1234_a"))
+	assert.True(t, Valid(New()))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("id with spaces"))
+	assert.False(t, Valid("id\nforged=field"))
+	assert.False(t, Valid(strings.Repeat("a", constants.MaxRequestIDLength+1)))
+}
+
+func TestFromRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/chat/ws", nil)
+	req.Header.Set(constants.HeaderRequestID, "caller-id")
+	assert.Equal(t, "caller-id", FromRequest(req), "the caller's ID is continued")
+
+	req.Header.Set(constants.HeaderRequestID, "bad id")
+	id := FromRequest(req)
+	assert.NotEqual(t, "bad id", id)
+	assert.True(t, Valid(id), "an invalid header is replaced")
+
+	req = req.WithContext(WithContext(context.Background(), "assigned-id"))
+	assert.Equal(t, "assigned-id", FromRequest(req), "the middleware's ID wins")
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var fromContext, fromGin string
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/ping", func(c *gin.Context) {
+		fromContext = FromContext(c.Request.Context())
+		fromGin = c.GetString(ContextKey)
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	id := w.Header().Get(constants.HeaderRequestID)
+	assert.True(t, Valid(id))
+	assert.Equal(t, id, fromContext)
+	assert.Equal(t, id, fromGin)
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(constants.HeaderRequestID, "caller-id")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "caller-id", w.Header().Get(constants.HeaderRequestID))
+	assert.Equal(t, "caller-id", fromContext)
+}
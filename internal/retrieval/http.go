@@ -0,0 +1,91 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/jsonhttp"
+)
+
+// ErrNoEndpoint is returned when the HTTP retriever is created without an endpoint
+var ErrNoEndpoint = errors.New("retrieval endpoint is required")
+
+// HTTPConfig holds HTTP retrieval service settings
+type HTTPConfig struct {
+	Endpoint string        // URL the query is POSTed to
+	APIKey   string        // Sent as a bearer token when set
+	TopK     int           // Maximum documents per query (defaults to constants.DefaultRetrievalTopK)
+	Timeout  time.Duration // HTTP timeout (defaults to constants.DefaultRetrievalTimeout)
+}
+
+// HTTPRetriever queries an external retrieval service. It POSTs
+// {"session_id", "query", "top_k"} as JSON and expects {"documents": [...]}
+// in return, each document with id, content and optionally title, source and
+// score.
+type HTTPRetriever struct {
+	topK   int
+	client *jsonhttp.Client
+}
+
+type httpQueryRequest struct {
+	SessionID string `json:"session_id"`
+	Query     string `json:"query"`
+	TopK      int    `json:"top_k"`
+}
+
+type httpQueryResponse struct {
+	Documents []Document `json:"documents"`
+}
+
+// NewHTTPRetriever validates the configuration and creates the retriever.
+// The endpoint must use https, except internal hosts which may use http.
+func NewHTTPRetriever(cfg HTTPConfig) (*HTTPRetriever, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.Endpoint == "" {
+		return nil, ErrNoEndpoint
+	}
+	topK := cfg.TopK
+	// No else needed: optional operation (apply default)
+	if topK <= 0 {
+		topK = constants.DefaultRetrievalTopK
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultRetrievalTimeout
+	}
+
+	client, err := jsonhttp.New("retrieval", cfg.Endpoint, cfg.APIKey, timeout)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPRetriever{topK: topK, client: client}, nil
+}
+
+// Query asks the retrieval service for the documents relevant to prompt.
+// At most TopK documents are returned, in the order the service ranked them.
+func (r *HTTPRetriever) Query(ctx context.Context, sessionID, prompt string) ([]Document, error) {
+	var parsed httpQueryResponse
+	// No else needed: early return pattern (guard clause)
+	if err := r.client.Post(ctx, httpQueryRequest{SessionID: sessionID, Query: prompt, TopK: r.topK}, &parsed); err != nil {
+		return nil, err
+	}
+
+	docs := make([]Document, 0, len(parsed.Documents))
+	for _, doc := range parsed.Documents {
+		// No else needed: optional operation (skip empty documents)
+		if doc.Content == "" {
+			continue
+		}
+		docs = append(docs, doc)
+		// No else needed: early return pattern (enough documents)
+		if len(docs) == r.topK {
+			break
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,72 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPRetriever_Validation(t *testing.T) {
+	_, err := NewHTTPRetriever(HTTPConfig{})
+	assert.ErrorIs(t, err, ErrNoEndpoint)
+
+	_, err = NewHTTPRetriever(HTTPConfig{Endpoint: "http://search.example.com/query"})
+	assert.Error(t, err, "public endpoints must use https")
+
+	r, err := NewHTTPRetriever(HTTPConfig{Endpoint: "https://search.example.com/query"})
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultRetrievalTopK, r.topK)
+	assert.Equal(t, constants.DefaultRetrievalTimeout, r.client.Timeout())
+}
+
+func TestHTTPRetriever_Query(t *testing.T) {
+	var got httpQueryRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"documents":[
+			{"id":"faq-1","title":"Refunds","content":"Refunds take 5 days.","source":"https://help.example.com/refunds","score":0.9},
+			{"id":"empty","content":""},
+			{"id":"faq-2","content":"Shipping is free."},
+			{"id":"faq-3","content":"Over the limit."}
+		]}`))
+	}))
+	defer server.Close()
+
+	r, err := NewHTTPRetriever(HTTPConfig{Endpoint: server.URL, APIKey: "test-key", TopK: 2})
+	require.NoError(t, err)
+
+	docs, err := r.Query(context.Background(), "session-1", "How long do refunds take?")
+	require.NoError(t, err)
+	assert.Equal(t, httpQueryRequest{SessionID: "session-1", Query: "How long do refunds take?", TopK: 2}, got)
+
+	// Empty documents are skipped and at most TopK are returned
+	require.Len(t, docs, 2)
+	assert.Equal(t, "faq-1", docs[0].ID)
+	assert.Equal(t, "Refunds", docs[0].Title)
+	assert.Equal(t, 0.9, docs[0].Score)
+	assert.Equal(t, "faq-2", docs[1].ID)
+}
+
+func TestHTTPRetriever_QueryErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"), "no API key configured")
+		http.Error(w, "index unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	r, err := NewHTTPRetriever(HTTPConfig{Endpoint: server.URL})
+	require.NoError(t, err)
+
+	_, err = r.Query(context.Background(), "session-1", "hello")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	assert.Contains(t, err.Error(), "index unavailable")
+}
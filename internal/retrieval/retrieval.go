@@ -0,0 +1,86 @@
+// Package retrieval supplies documents relevant to a user message so that LLM
+// replies can be grounded in them (retrieval-augmented generation).
+//
+// A Retriever is queried by the router before each LLM call. The documents it
+// returns are given to the LLM as a system message ahead of the user message,
+// and recorded as short snippets in the AI reply's metadata for audit.
+// HTTPRetriever is the reference implementation, calling an external
+// retrieval service.
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is a piece of knowledge returned by a retriever
+type Document struct {
+	ID      string  `json:"id"`
+	Title   string  `json:"title,omitempty"`
+	Content string  `json:"content"`
+	Source  string  `json:"source,omitempty"` // Where the document came from, e.g. a URL
+	Score   float64 `json:"score,omitempty"`  // Relevance reported by the retriever; higher is more relevant
+}
+
+// Retriever finds the documents relevant to a user prompt
+type Retriever interface {
+	Query(ctx context.Context, sessionID, prompt string) ([]Document, error)
+}
+
+// Snippet is the audit record of a retrieved document stored in message metadata
+type Snippet struct {
+	ID      string  `json:"id"`
+	Source  string  `json:"source,omitempty"`
+	Score   float64 `json:"score,omitempty"`
+	Snippet string  `json:"snippet"`
+}
+
+// Context formats documents as the content of the system message that gives
+// them to the LLM
+func Context(docs []Document) string {
+	var b strings.Builder
+	b.WriteString("Use the following documents to answer the user's next message when they are relevant.\n")
+	for i, doc := range docs {
+		fmt.Fprintf(&b, "\n[%d]", i+1)
+		// No else needed: optional operation (untitled documents)
+		if doc.Title != "" {
+			b.WriteString(" " + doc.Title)
+		}
+		// No else needed: optional operation (documents without a source)
+		if doc.Source != "" {
+			b.WriteString(" (" + doc.Source + ")")
+		}
+		b.WriteString("\n" + strings.TrimSpace(doc.Content) + "\n")
+	}
+	return b.String()
+}
+
+// Snippets returns the audit records of docs, each cut to at most maxBytes of
+// content on a UTF-8 boundary
+func Snippets(docs []Document, maxBytes int) []Snippet {
+	snippets := make([]Snippet, 0, len(docs))
+	for _, doc := range docs {
+		snippets = append(snippets, Snippet{
+			ID:      doc.ID,
+			Source:  doc.Source,
+			Score:   doc.Score,
+			Snippet: truncate(strings.TrimSpace(doc.Content), maxBytes),
+		})
+	}
+	return snippets
+}
+
+// truncate cuts s to at most maxBytes without splitting a multi-byte character
+func truncate(s string, maxBytes int) string {
+	// No else needed: early return pattern (short enough)
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,32 @@
+package retrieval
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	content := Context([]Document{
+		{ID: "a", Title: "Refunds", Content: " Refunds take 5 days. ", Source: "https://help.example.com/refunds"},
+		{ID: "b", Content: "Shipping is free."},
+	})
+
+	assert.True(t, strings.HasPrefix(content, "Use the following documents"))
+	assert.Contains(t, content, "[1] Refunds (https://help.example.com/refunds)\nRefunds take 5 days.\n")
+	assert.Contains(t, content, "[2]\nShipping is free.\n")
+}
+
+func TestSnippets(t *testing.T) {
+	snippets := Snippets([]Document{
+		{ID: "a", Content: "Refunds take five days.", Source: "faq", Score: 0.5},
+		{ID: "b", Content: "日本語の文書"},
+	}, 10)
+
+	assert.Equal(t, []Snippet{
+		{ID: "a", Source: "faq", Score: 0.5, Snippet: "Refunds ta"},
+		// Cut on a character boundary: 3 characters are 9 bytes
+		{ID: "b", Snippet: "日本語"},
+	}, snippets)
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendAdminMessage_DeliversAndStores(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	sent, err := router.SendAdminMessage("admin-1", "Alice", sess.ID, "  How can I help?  ")
+	require.NoError(t, err)
+	assert.Equal(t, message.TypeAdminMessage, sent.Type)
+	assert.Equal(t, "How can I help?", sent.Content)
+
+	select {
+	case data := <-conn.ReceiveForTest():
+		var msg message.Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, message.TypeAdminMessage, msg.Type)
+		assert.Equal(t, message.SenderAdmin, msg.Sender)
+		assert.Equal(t, "admin-1", msg.Metadata["admin_id"])
+	default:
+		t.Fatal("user connection did not receive the admin message")
+	}
+
+	require.Len(t, sess.Messages, 1)
+	assert.Equal(t, string(message.SenderAdmin), sess.Messages[0].Sender)
+	assert.Equal(t, "Alice", sess.Messages[0].Metadata["admin_name"])
+}
+
+func TestSendAdminMessage_RejectsOtherAssistingAdmin(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.HandleAdminTakeover(websocket.NewConnection("admin-1", []string{"admin"}), sess.ID))
+
+	_, err = router.SendAdminMessage("admin-2", "Bob", sess.ID, "hello")
+	require.Error(t, err)
+
+	// The assisting admin may still post
+	_, err = router.SendAdminMessage("admin-1", "Alice", sess.ID, "hello")
+	assert.NoError(t, err)
+}
+
+func TestSendAdminMessage_Validation(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	_, err := router.SendAdminMessage("admin-1", "Alice", "missing-session", "hello")
+	var chatErr *chaterrors.ChatError
+	require.True(t, errors.As(err, &chatErr))
+	assert.Equal(t, chaterrors.ErrCodeSessionNotFound, chatErr.Code)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	_, err = router.SendAdminMessage("admin-1", "Alice", sess.ID, "   ")
+	require.True(t, errors.As(err, &chatErr))
+	assert.Equal(t, chaterrors.ErrCodeMissingField, chatErr.Code)
+}
@@ -0,0 +1,145 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// autoTitlePrompt asks the LLM for a session title
+const autoTitlePrompt = "Write a concise title of at most six words for the conversation below. " +
+	"Reply with the title only, without quotes or trailing punctuation."
+
+// SetAutoTitle enables titling sessions with the LLM once they reach
+// constants.AutoTitleAfterExchanges AI replies. Only sessions still named after
+// their first message (or unnamed) are titled; user renames are kept.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetAutoTitle(enabled bool) {
+	mr.autoTitle = enabled
+}
+
+// scheduleAutoTitle starts title generation in the background when the session
+// has just reached constants.AutoTitleAfterExchanges AI replies
+func (mr *MessageRouter) scheduleAutoTitle(sess *session.Session, modelID string) {
+	// No else needed: early return pattern (auto-titles disabled)
+	if !mr.autoTitle || mr.llmService == nil || mr.humanOnly {
+		return
+	}
+
+	sess.RLock()
+	name := sess.Name
+	replies := 0
+	firstUser := ""
+	foundUser := false
+	var transcript strings.Builder
+	for _, m := range sess.Messages {
+		// No else needed: optional operation (only conversation content is titled)
+		if m.IsSystemEvent() || (m.Sender != constants.SenderUser && m.Sender != constants.SenderAI) {
+			continue
+		}
+		// No else needed: optional operation (count AI replies)
+		if m.Sender == constants.SenderAI {
+			replies++
+		}
+		// No else needed: optional operation (the session was named after the first user message)
+		if m.Sender == constants.SenderUser && !foundUser {
+			firstUser = m.Content
+			foundUser = true
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n\n", m.Sender, truncateRunes(m.Content, constants.AutoTitleMaxContext))
+	}
+	sess.RUnlock()
+
+	// No else needed: early return pattern (titled once, when the threshold is reached)
+	if replies != constants.AutoTitleAfterExchanges {
+		return
+	}
+	// No else needed: early return pattern (renamed by the user)
+	if name != "" && name != session.GenerateSessionName(firstUser, constants.AutoTitleMaxLength) {
+		return
+	}
+
+	sessionID := sess.ID
+	conversation := transcript.String()
+	mr.safeGo("autoTitle", func() {
+		mr.generateTitle(sessionID, modelID, name, conversation)
+	})
+}
+
+// generateTitle asks the LLM for a title and stores it as the session name,
+// unless the session was renamed while the title was generated
+func (mr *MessageRouter) generateTitle(sessionID, modelID, oldName, conversation string) {
+	ctx, cancel := context.WithTimeout(mr.ctx, constants.AutoTitleTimeout)
+	defer cancel()
+
+	// The conversation is sent as one user message so every provider accepts it
+	llmMessages := []llm.ChatMessage{
+		{Role: constants.SenderSystem, Content: autoTitlePrompt},
+		{Role: constants.SenderUser, Content: conversation},
+	}
+
+	var resp *llm.LLMResponse
+	_, release, err := mr.tryModels(ctx, modelID, func(ctx context.Context, modelID string) error {
+		var err error
+		resp, err = mr.llmService.SendMessage(ctx, modelID, llmMessages)
+		return err
+	})
+	// No else needed: early return pattern (the session keeps its name)
+	if err != nil {
+		util.LogError(mr.logger, "router", "generate session title", err, "session_id", sessionID, "model_id", modelID)
+		return
+	}
+	release()
+	title := cleanTitle(resp.Content)
+	// No else needed: early return pattern (nothing usable returned)
+	if title == "" {
+		mr.logger.Warn("LLM returned an empty session title", "session_id", sessionID)
+		return
+	}
+
+	replaced, err := mr.sessionManager.ReplaceSessionName(sessionID, oldName, title)
+	// No else needed: early return pattern (session ended, or renamed meanwhile)
+	if err != nil || !replaced {
+		return
+	}
+	// No else needed: optional operation (only when storage is configured)
+	if mr.storageService != nil {
+		// No else needed: optional operation, storage failure is logged but not fatal
+		if err := mr.storageService.UpdateSessionName(sessionID, title); err != nil {
+			mr.logger.Warn("Failed to persist session title", "session_id", sessionID, "error", err)
+		}
+	}
+	mr.logger.Debug("Session titled", "session_id", sessionID, "tokens", resp.TokensUsed)
+}
+
+// cleanTitle reduces an LLM reply to a single-line title without surrounding
+// quotes, at most constants.AutoTitleMaxLength bytes long
+func cleanTitle(reply string) string {
+	title := strings.TrimSpace(reply)
+	// No else needed: optional operation (keep the first line only)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = title[:i]
+	}
+	title = strings.TrimSpace(strings.Trim(strings.TrimSpace(title), "\"'`*#"))
+	title = strings.TrimRight(title, ".")
+	// No else needed: early return pattern (empty reply)
+	if title == "" {
+		return ""
+	}
+	return session.GenerateSessionName(title, constants.AutoTitleMaxLength)
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	// No else needed: early return pattern (short enough)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,79 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleAutoTitle(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmService := &mockLLMService{}
+	storage := &mockStorageService{}
+	router := NewMessageRouter(sm, llmService, nil, nil, storage, 120*time.Second, logger)
+	defer router.Shutdown()
+	router.SetAutoTitle(true)
+
+	newSession := func(userID string) *session.Session {
+		sess, err := sm.CreateSession(userID)
+		require.NoError(t, err)
+		require.NoError(t, sm.SetSessionNameFromMessage(sess.ID, "How do I reset my password?"))
+		require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "How do I reset my password?", Sender: constants.SenderUser}))
+		require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "Use the login page.", Sender: constants.SenderAI}))
+		return sess
+	}
+	addExchange := func(sess *session.Session) {
+		require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "Where is it?", Sender: constants.SenderUser}))
+		require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "Top right.", Sender: constants.SenderAI}))
+	}
+
+	// Too early: the session keeps the name of its first message
+	sess := newSession("user-1")
+	router.scheduleAutoTitle(sess, "gpt-4")
+	router.wg.Wait()
+	assert.Equal(t, "How do I reset my password?", sess.Name)
+
+	// Titled once the session reaches AutoTitleAfterExchanges replies
+	addExchange(sess)
+	router.scheduleAutoTitle(sess, "gpt-4")
+	router.wg.Wait()
+	assert.Equal(t, "Mock response", sess.Name)
+	assert.Contains(t, llmService.lastMessages[1].Content, "user: How do I reset my password?")
+
+	// Sessions renamed by the user are not titled
+	renamed := newSession("user-2")
+	require.NoError(t, sm.SetSessionName(renamed.ID, "Passwords"))
+	addExchange(renamed)
+	router.scheduleAutoTitle(renamed, "gpt-4")
+	router.wg.Wait()
+	assert.Equal(t, "Passwords", renamed.Name)
+
+	// Disabled
+	router.SetAutoTitle(false)
+	disabled := newSession("user-3")
+	addExchange(disabled)
+	router.scheduleAutoTitle(disabled, "gpt-4")
+	router.wg.Wait()
+	assert.Equal(t, "How do I reset my password?", disabled.Name)
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  string
+	}{
+		{reply: "Password Reset Help", want: "Password Reset Help"},
+		{reply: "  \"Password Reset Help.\"  ", want: "Password Reset Help"},
+		{reply: "**Password Reset**\nThis conversation is about...", want: "Password Reset"},
+		{reply: "A very long title that goes on and on well past the fifty byte limit", want: "A very long title that goes on and on well..."},
+		{reply: "  \"\" ", want: ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, cleanTitle(tt.reply), tt.reply)
+	}
+}
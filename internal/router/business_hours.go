@@ -0,0 +1,59 @@
+package router
+
+import (
+	"time"
+
+	"github.com/real-rm/chatbox/internal/businesshours"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+)
+
+// SessionTagger adds tags to stored sessions (to avoid coupling the router to
+// a concrete storage backend)
+type SessionTagger interface {
+	AddSessionTags(sessionID string, tags []string) ([]string, error)
+}
+
+// SetBusinessHours answers help requests outside hours with an automatic reply
+// saying when administrators are back, and tags their sessions with
+// constants.AfterHoursTag using tagger (nil when the storage has no tags).
+// A nil schedule is always open. LLM chat is not affected.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetBusinessHours(hours *businesshours.Schedule, tagger SessionTagger) {
+	mr.businessHours = hours
+	mr.sessionTagger = tagger
+}
+
+// afterHoursReply returns the automatic reply to a help request for sessionID
+// at now, or nil within business hours. Outside them the session is tagged
+// for next-day triage and the reply is recorded in its transcript.
+func (mr *MessageRouter) afterHoursReply(sessionID string, now time.Time) *message.Message {
+	// No else needed: early return pattern (within business hours)
+	if mr.businessHours.Open(now) {
+		return nil
+	}
+	content, opensAt := mr.businessHours.AutoResponse(now)
+
+	// No else needed: optional operation (only storage with tags)
+	if mr.sessionTagger != nil {
+		// No else needed: optional operation (triage still works from the transcript)
+		if _, err := mr.sessionTagger.AddSessionTags(sessionID, []string{constants.AfterHoursTag}); err != nil {
+			mr.logger.Warn("Failed to tag after-hours help request", "session_id", sessionID, "error", err)
+		}
+	}
+
+	metadata := map[string]string{
+		constants.AfterHoursTag: "true",
+		"opens_at":              opensAt.UTC().Format(time.RFC3339),
+	}
+	mr.RecordSystemEvent(sessionID, constants.SystemEventAfterHours, content, metadata)
+
+	return &message.Message{
+		Type:      message.TypeNotification,
+		SessionID: sessionID,
+		Content:   content,
+		Sender:    message.SenderSystem,
+		Timestamp: now,
+		Metadata:  metadata,
+	}
+}
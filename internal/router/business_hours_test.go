@@ -0,0 +1,135 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/businesshours"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTagger records the tags added to sessions
+type recordingTagger struct {
+	tags map[string][]string
+	err  error
+}
+
+func (r *recordingTagger) AddSessionTags(sessionID string, tags []string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.tags[sessionID] = append(r.tags[sessionID], tags...)
+	return r.tags[sessionID], nil
+}
+
+// hoursOpenOnlyAt returns business hours open for the hour starting at start
+// (UTC, on the hour) and closed the rest of the week
+func hoursOpenOnlyAt(t *testing.T, start time.Time) *businesshours.Schedule {
+	t.Helper()
+	open := start.Hour() * 60
+	hours, err := businesshours.NewSchedule(time.UTC, map[time.Weekday][]businesshours.Period{
+		start.Weekday(): {{Open: open, Close: open + 60}},
+	}, "")
+	require.NoError(t, err)
+	return hours
+}
+
+func TestHandleHelpRequest_AfterHours(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	// Open for an hour starting two hours from now
+	opensAt := time.Now().UTC().Truncate(time.Hour).Add(2 * time.Hour)
+	tagger := &recordingTagger{tags: map[string][]string{}}
+	router.SetBusinessHours(hoursOpenOnlyAt(t, opensAt), tagger)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	require.NoError(t, router.handleHelpRequest(conn, &message.Message{
+		Type:      message.TypeHelpRequest,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+	}))
+
+	var reply message.Message
+	select {
+	case data := <-conn.ReceiveForTest():
+		require.NoError(t, json.Unmarshal(data, &reply))
+	case <-time.After(time.Second):
+		t.Fatal("no after-hours reply")
+	}
+	assert.Equal(t, message.TypeNotification, reply.Type)
+	assert.Equal(t, message.SenderSystem, reply.Sender)
+	assert.Contains(t, reply.Content, constants.DefaultAfterHoursMessage)
+	assert.Equal(t, "true", reply.Metadata[constants.AfterHoursTag])
+	assert.Equal(t, opensAt.Format(time.RFC3339), reply.Metadata["opens_at"])
+
+	assert.Equal(t, []string{constants.AfterHoursTag}, tagger.tags[sess.ID])
+	assert.True(t, sess.HelpRequested, "the session still joins the help queue")
+	last := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, constants.SystemEventAfterHours, last.Event)
+	assert.Equal(t, reply.Content, last.Content)
+}
+
+func TestHandleHelpRequest_WithinBusinessHours(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	tagger := &recordingTagger{tags: map[string][]string{}}
+	router.SetBusinessHours(hoursOpenOnlyAt(t, time.Now().UTC().Truncate(time.Hour)), tagger)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	require.NoError(t, router.handleHelpRequest(conn, &message.Message{
+		Type:      message.TypeHelpRequest,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+	}))
+	assert.Equal(t, []message.MessageType{message.TypeConnectionStatus}, drainTypes(t, conn))
+	assert.Empty(t, tagger.tags)
+}
+
+func TestHumanOnlyMode_AfterHours(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	router.SetHumanOnlyMode(true)
+	defer router.Shutdown()
+
+	// Tagging failures do not block the reply
+	tagger := &recordingTagger{err: errors.New("storage down")}
+	router.SetBusinessHours(hoursOpenOnlyAt(t, time.Now().UTC().Truncate(time.Hour).Add(2*time.Hour)), tagger)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Anyone there?",
+		Sender:    message.SenderUser,
+	}))
+	assert.Equal(t, []message.MessageType{message.TypeNotification}, drainTypes(t, conn))
+	assert.Equal(t, constants.SystemEventAfterHours, sess.Messages[len(sess.Messages)-1].Event)
+}
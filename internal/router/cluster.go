@@ -0,0 +1,191 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/real-rm/chatbox/internal/cluster"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+)
+
+// ClusterBridge relays traffic to connections held by other replicas (to avoid
+// coupling the router to a concrete pub/sub backend)
+type ClusterBridge interface {
+	Claim(key string) error
+	Release(key string)
+	Forward(key string, env cluster.Envelope) (bool, error)
+	Request(key string, env cluster.Envelope) (cluster.Envelope, error)
+}
+
+// SetClusterBridge routes traffic for sessions and admins connected to other
+// replicas through bridge: frames for their connections are forwarded, and
+// admin messages and tenant checks for their sessions run on the replica
+// holding the session. The bridge must hand its envelopes to
+// HandleClusterEnvelope. Must be called before the router handles any messages.
+func (mr *MessageRouter) SetClusterBridge(bridge ClusterBridge) {
+	mr.cluster = bridge
+}
+
+// claimConnection records that this replica holds the connection of key
+func (mr *MessageRouter) claimConnection(key string) {
+	// No else needed: early return pattern (single replica)
+	if mr.cluster == nil {
+		return
+	}
+	// No else needed: optional operation (other replicas cannot reach the connection until the next claim)
+	if err := mr.cluster.Claim(key); err != nil {
+		mr.logger.Warn("Failed to claim connection ownership", "key", key, "error", err)
+	}
+}
+
+// releaseConnection records that this replica no longer holds the connection of key
+func (mr *MessageRouter) releaseConnection(key string) {
+	// No else needed: optional operation (single replica)
+	if mr.cluster != nil {
+		mr.cluster.Release(key)
+	}
+}
+
+// forwardFrame sends env to the replica holding the connection of key.
+// Returns false when no other replica holds it.
+func (mr *MessageRouter) forwardFrame(key string, env cluster.Envelope) bool {
+	// No else needed: early return pattern (single replica)
+	if mr.cluster == nil {
+		return false
+	}
+	forwarded, err := mr.cluster.Forward(key, env)
+	// No else needed: optional operation (reported as not connected)
+	if err != nil {
+		mr.logger.Warn("Failed to forward to another replica", "key", key, "error", err)
+	}
+	return forwarded
+}
+
+// requestOwner runs env on the replica holding sessionID's connection and
+// returns its reply. Returns false when no other replica holds the session.
+func (mr *MessageRouter) requestOwner(sessionID string, env cluster.Envelope) (cluster.Envelope, bool) {
+	// No else needed: early return pattern (single replica)
+	if mr.cluster == nil {
+		return cluster.Envelope{}, false
+	}
+	env.SessionID = sessionID
+	reply, err := mr.cluster.Request(cluster.SessionKey(sessionID), env)
+	// No else needed: early return pattern (not held elsewhere)
+	if errors.Is(err, cluster.ErrNotForwarded) {
+		return cluster.Envelope{}, false
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		mr.logger.Warn("Request to another replica failed", "session_id", sessionID, "kind", env.Kind, "error", err)
+		return cluster.Envelope{Error: &cluster.ReplyError{
+			Code:    string(chaterrors.ErrCodeServiceError),
+			Message: "The server holding the session did not respond",
+		}}, true
+	}
+	return reply, true
+}
+
+// replyError converts the error of a reply back into a ChatError
+func replyError(reply cluster.Envelope) error {
+	// No else needed: early return pattern (request succeeded)
+	if reply.Error == nil {
+		return nil
+	}
+	category := chaterrors.CategoryValidation
+	// No else needed: conditional assignment (service failures keep their category)
+	if reply.Error.Code == string(chaterrors.ErrCodeServiceError) || reply.Error.Code == string(chaterrors.ErrCodeDatabaseError) {
+		category = chaterrors.CategoryService
+	}
+	return &chaterrors.ChatError{
+		Category: category,
+		Code:     chaterrors.ErrorCode(reply.Error.Code),
+		Message:  reply.Error.Message,
+	}
+}
+
+// clusterReply is the reply to a request, carrying payload or err
+func clusterReply(payload interface{}, err error) cluster.Envelope {
+	// No else needed: early return pattern (failed request)
+	if err != nil {
+		var chatErr *chaterrors.ChatError
+		// No else needed: early return pattern (errors without a code)
+		if !errors.As(err, &chatErr) {
+			return cluster.Envelope{Error: &cluster.ReplyError{Code: string(chaterrors.ErrCodeServiceError), Message: err.Error()}}
+		}
+		return cluster.Envelope{Error: &cluster.ReplyError{Code: string(chatErr.Code), Message: chatErr.Message}}
+	}
+	// No else needed: early return pattern (nothing to return)
+	if payload == nil {
+		return cluster.Envelope{}
+	}
+	data, err := json.Marshal(payload)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return cluster.Envelope{Error: &cluster.ReplyError{Code: string(chaterrors.ErrCodeServiceError), Message: err.Error()}}
+	}
+	return cluster.Envelope{Data: data}
+}
+
+// HandleClusterEnvelope handles an envelope another replica sent to this one
+// (see cluster.Handler): frames go to the connections held here, and requests
+// for sessions held here are run and answered.
+func (mr *MessageRouter) HandleClusterEnvelope(env cluster.Envelope) cluster.Envelope {
+	switch env.Kind {
+	case cluster.KindUser:
+		mr.mirrorToWatchers(env.SessionID, env.Data)
+		// No else needed: optional operation (the client may have disconnected since)
+		if err := mr.sendRawLocal(env.SessionID, env.Data); err != nil {
+			mr.logger.Debug("Forwarded message not delivered", "session_id", env.SessionID, "error", err)
+		}
+	case cluster.KindAdmin:
+		// No else needed: optional operation (the admin may have disconnected since)
+		if !mr.sendToAdmin(env.AdminID, env.SessionID, env.Data) {
+			mr.logger.Debug("Forwarded admin message not delivered", "session_id", env.SessionID, "admin_id", env.AdminID)
+		}
+	case cluster.KindTenantCheck:
+		return clusterReply(nil, mr.CheckTenantAccess(env.SessionID, env.TenantID, env.Roles))
+	case cluster.KindAdminMessage:
+		msg, err := mr.SendAdminMessage(env.AdminID, env.AdminName, env.SessionID, env.Content)
+		return clusterReply(msg, err)
+	default:
+		mr.logger.Warn("Unknown cluster envelope", "kind", env.Kind)
+	}
+	return cluster.Envelope{}
+}
+
+// checkTenantAccessRemote runs the tenant check of CheckTenantAccess on the
+// replica holding sessionID. Returns false when no other replica holds it.
+func (mr *MessageRouter) checkTenantAccessRemote(sessionID, tenantID string, roles []string) (bool, error) {
+	reply, forwarded := mr.requestOwner(sessionID, cluster.Envelope{
+		Kind:     cluster.KindTenantCheck,
+		TenantID: tenantID,
+		Roles:    roles,
+	})
+	return forwarded, replyError(reply)
+}
+
+// sendAdminMessageRemote runs SendAdminMessage on the replica holding
+// sessionID. Returns false when no other replica holds it.
+func (mr *MessageRouter) sendAdminMessageRemote(adminID, adminName, sessionID, content string) (*message.Message, bool, error) {
+	reply, forwarded := mr.requestOwner(sessionID, cluster.Envelope{
+		Kind:      cluster.KindAdminMessage,
+		AdminID:   adminID,
+		AdminName: adminName,
+		Content:   content,
+	})
+	// No else needed: early return pattern (not held elsewhere)
+	if !forwarded {
+		return nil, false, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := replyError(reply); err != nil {
+		return nil, true, err
+	}
+	var msg message.Message
+	// No else needed: early return pattern (guard clause)
+	if err := json.Unmarshal(reply.Data, &msg); err != nil {
+		return nil, true, chaterrors.ErrInvalidMessageFormat("invalid reply from another replica", err)
+	}
+	return &msg, true, nil
+}
@@ -0,0 +1,140 @@
+package router
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/cluster"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClusterBridge records ownership changes and what was sent to other replicas
+type fakeClusterBridge struct {
+	mu        sync.Mutex
+	claimed   []string
+	released  []string
+	forwarded []cluster.Envelope
+	requests  []cluster.Envelope
+	remote    bool             // whether another replica holds every key
+	reply     cluster.Envelope // reply to every request
+}
+
+func (f *fakeClusterBridge) Claim(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claimed = append(f.claimed, key)
+	return nil
+}
+
+func (f *fakeClusterBridge) Release(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.released = append(f.released, key)
+}
+
+func (f *fakeClusterBridge) Forward(key string, env cluster.Envelope) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.remote {
+		return false, nil
+	}
+	f.forwarded = append(f.forwarded, env)
+	return true, nil
+}
+
+func (f *fakeClusterBridge) Request(key string, env cluster.Envelope) (cluster.Envelope, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.remote {
+		return cluster.Envelope{}, cluster.ErrNotForwarded
+	}
+	f.requests = append(f.requests, env)
+	return f.reply, nil
+}
+
+func newClusterRouter(t *testing.T) (*MessageRouter, *session.SessionManager, *fakeClusterBridge) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	bridge := &fakeClusterBridge{}
+	router.SetClusterBridge(bridge)
+	t.Cleanup(router.Shutdown)
+	return router, sm, bridge
+}
+
+func TestCluster_ClaimsAndReleasesConnections(t *testing.T) {
+	router, sm, bridge := newClusterRouter(t)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	require.NoError(t, router.RegisterConnection(sess.ID, mockConnection("user-1")))
+	require.NoError(t, router.RegisterAdminConnection("admin-1", sess.ID, mockConnection("admin-1")))
+	assert.Equal(t, []string{cluster.SessionKey(sess.ID), cluster.AdminKey("admin-1", sess.ID)}, bridge.claimed)
+
+	router.UnregisterConnection(sess.ID)
+	router.UnregisterAdminConnection("admin-1", sess.ID)
+	assert.Equal(t, []string{cluster.SessionKey(sess.ID), cluster.AdminKey("admin-1", sess.ID)}, bridge.released)
+}
+
+func TestCluster_ForwardsFramesForConnectionsElsewhere(t *testing.T) {
+	router, _, bridge := newClusterRouter(t)
+	data := []byte(`{"type":"ai_response"}`)
+
+	err := router.sendRawToConnection("remote-session", data)
+	assert.ErrorIs(t, err, ErrConnectionNotFound, "not connected anywhere")
+
+	bridge.remote = true
+	require.NoError(t, router.sendRawToConnection("remote-session", data))
+	require.Len(t, bridge.forwarded, 1)
+	assert.Equal(t, cluster.KindUser, bridge.forwarded[0].Kind)
+	assert.Equal(t, "remote-session", bridge.forwarded[0].SessionID)
+	assert.JSONEq(t, string(data), string(bridge.forwarded[0].Data))
+}
+
+func TestCluster_AdminMessageRunsOnOwner(t *testing.T) {
+	router, _, bridge := newClusterRouter(t)
+	bridge.remote = true
+	sent, err := json.Marshal(&message.Message{Type: message.TypeAdminMessage, SessionID: "remote-session", Content: "Hello", Sender: message.SenderAdmin})
+	require.NoError(t, err)
+	bridge.reply = cluster.Envelope{Data: sent}
+
+	msg, err := router.SendAdminMessage("admin-1", "Alice", "remote-session", "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", msg.Content)
+	require.Len(t, bridge.requests, 1)
+	assert.Equal(t, cluster.KindAdminMessage, bridge.requests[0].Kind)
+	assert.Equal(t, "admin-1", bridge.requests[0].AdminID)
+	assert.Equal(t, "Alice", bridge.requests[0].AdminName)
+
+	bridge.reply = cluster.Envelope{Error: &cluster.ReplyError{Code: string(chaterrors.ErrCodeUnauthorized), Message: "Session belongs to another tenant"}}
+	err = router.CheckTenantAccess("remote-session", "tenant-b", nil)
+	var chatErr *chaterrors.ChatError
+	require.ErrorAs(t, err, &chatErr)
+	assert.Equal(t, chaterrors.ErrCodeUnauthorized, chatErr.Code)
+}
+
+func TestHandleClusterEnvelope(t *testing.T) {
+	router, sm, _ := newClusterRouter(t)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	frame, err := json.Marshal(&message.Message{Type: message.TypeAIResponse, SessionID: sess.ID, Content: "Hi"})
+	require.NoError(t, err)
+	router.HandleClusterEnvelope(cluster.Envelope{Kind: cluster.KindUser, SessionID: sess.ID, Data: frame})
+	assert.Equal(t, []message.MessageType{message.TypeAIResponse}, drainTypes(t, conn))
+
+	reply := router.HandleClusterEnvelope(cluster.Envelope{Kind: cluster.KindTenantCheck, SessionID: sess.ID})
+	assert.Nil(t, reply.Error)
+	reply = router.HandleClusterEnvelope(cluster.Envelope{Kind: cluster.KindTenantCheck, SessionID: "missing"})
+	require.NotNil(t, reply.Error)
+	assert.Equal(t, string(chaterrors.ErrCodeSessionNotFound), reply.Error.Code)
+}
@@ -0,0 +1,97 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/cluster"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// sendStreamChunk sends a chunk of a streamed AI reply like sendToConnection.
+// Connections on this replica that negotiated websocket.CapabilityDelta get the
+// compact form instead: an ai_delta frame with the chunk's sequence number and
+// text, or for the final chunk an ai_response holding the whole reply so far.
+// Watchers, the replay buffer and connections on other replicas get the full chunk.
+func (mr *MessageRouter) sendStreamChunk(sessionID string, msg *message.Message, done bool, reply *strings.Builder) error {
+	data, err := mr.marshalForSession(sessionID, msg)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	mr.mirrorToWatchers(sessionID, data)
+
+	compact, err := compactChunk(msg, done, reply)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delta: %w", err)
+	}
+
+	err = mr.sendStreamLocal(sessionID, data, compact)
+	// No else needed: early return pattern (connected to another replica)
+	if errors.Is(err, ErrConnectionNotFound) && mr.forwardFrame(cluster.SessionKey(sessionID), cluster.Envelope{
+		Kind:      cluster.KindUser,
+		SessionID: sessionID,
+		Data:      data,
+	}) {
+		return nil
+	}
+	return err
+}
+
+// compactChunk marshals the delta form of a reply chunk whose sequence number
+// is already assigned. The final chunk becomes the consolidated ai_response.
+func compactChunk(msg *message.Message, done bool, reply *strings.Builder) ([]byte, error) {
+	// No else needed: early return pattern (text added to the reply)
+	if !done {
+		return util.MarshalJSON(&message.Delta{Type: message.TypeAIDelta, Seq: msg.Seq, Text: msg.Content})
+	}
+
+	consolidated := *msg
+	consolidated.Content = reply.String()
+	consolidated.Metadata = make(map[string]string, len(msg.Metadata)+1)
+	for k, v := range msg.Metadata {
+		consolidated.Metadata[k] = v
+	}
+	consolidated.Metadata[constants.MetadataKeyConsolidated] = "true"
+	return util.MarshalJSON(&consolidated)
+}
+
+// sendStreamLocal sends a reply chunk to every device of a session on this
+// replica, in the form each device negotiated. Like sendRawLocal, only a failed
+// send to the active device is reported.
+func (mr *MessageRouter) sendStreamLocal(sessionID string, data, compact []byte) error {
+	mr.mu.RLock()
+	conn, exists := mr.connections[sessionID]
+	mr.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if !exists {
+		return fmt.Errorf("%w: session %s", ErrConnectionNotFound, sessionID)
+	}
+
+	frame := func(c *websocket.Connection) []byte {
+		// No else needed: early return pattern (compact form negotiated)
+		if c.Supports(websocket.CapabilityDelta) {
+			return compact
+		}
+		return data
+	}
+	for _, device := range mr.otherDevices(sessionID, conn) {
+		// No else needed: optional operation (fire-and-forget)
+		if !device.SafeSend(frame(device)) {
+			mr.logger.Warn("Device send channel full or closing",
+				"session_id", sessionID,
+				"connection_id", device.ConnectionID)
+		}
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if !conn.SafeSend(frame(conn)) {
+		return fmt.Errorf("connection send channel is full or closing for session %s", sessionID)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreaming_DeltaFrames(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	router := NewMessageRouter(sm, &streamingMockLLMService{chunks: []string{"Hello", "", ", ", "world!"}}, nil, nil, nil, 120*time.Second, logger)
+	conn := websocket.NewConnection("user-1", []string{"user"})
+	conn.SessionID = sess.ID
+	conn.SetCapabilitiesForTest(websocket.CapabilityDelta)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	go func() {
+		assert.NoError(t, router.HandleUserMessage(conn, &message.Message{
+			Type:      message.TypeUserMessage,
+			SessionID: sess.ID,
+			Content:   "Hi",
+			Sender:    message.SenderUser,
+			Timestamp: time.Now(),
+		}))
+	}()
+
+	var deltas []message.Delta
+	var final *message.Message
+	timeout := time.After(2 * time.Second)
+	for final == nil {
+		select {
+		case data := <-conn.ReceiveForTest():
+			var envelope struct {
+				Type message.MessageType `json:"type"`
+			}
+			require.NoError(t, json.Unmarshal(data, &envelope))
+			switch envelope.Type {
+			case message.TypeAIDelta:
+				var delta message.Delta
+				require.NoError(t, json.Unmarshal(data, &delta))
+				deltas = append(deltas, delta)
+			case message.TypeAIResponse:
+				final = &message.Message{}
+				require.NoError(t, json.Unmarshal(data, final))
+			}
+		case <-timeout:
+			t.Fatal("stream did not end")
+		}
+	}
+
+	require.Len(t, deltas, 2, "empty chunks are skipped and the last one is consolidated")
+	assert.Equal(t, "Hello", deltas[0].Text)
+	assert.Equal(t, ", ", deltas[1].Text)
+	assert.Equal(t, deltas[0].Seq+1, deltas[1].Seq)
+
+	assert.Equal(t, "Hello, world!", final.Content)
+	assert.Equal(t, deltas[1].Seq+1, final.Seq)
+	assert.Equal(t, "true", final.Metadata["done"])
+	assert.Equal(t, "true", final.Metadata[constants.MetadataKeyConsolidated])
+
+	// A client resuming after a dropped connection gets the full chunks
+	missed := router.getReplayBuffer(sess.ID).since(deltas[0].Seq - 1)
+	require.GreaterOrEqual(t, len(missed), 3)
+	var replayed message.Message
+	require.NoError(t, json.Unmarshal(missed[0], &replayed))
+	assert.Equal(t, message.TypeAIResponse, replayed.Type)
+	assert.Equal(t, "Hello", replayed.Content)
+}
+
+func TestCompactChunk(t *testing.T) {
+	chunk := &message.Message{
+		Type:      message.TypeAIResponse,
+		SessionID: "session-1",
+		Content:   "lo",
+		Sender:    message.SenderAI,
+		Timestamp: time.Now(),
+		Metadata:  map[string]string{"streaming": "true", "done": "false"},
+		Seq:       7,
+	}
+	var reply strings.Builder
+	reply.WriteString("Hello")
+
+	data, err := compactChunk(chunk, false, &reply)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"ai_delta","seq":7,"delta":"lo"}`, string(data))
+
+	data, err = compactChunk(chunk, true, &reply)
+	require.NoError(t, err)
+	var consolidated message.Message
+	require.NoError(t, json.Unmarshal(data, &consolidated))
+	assert.Equal(t, "Hello", consolidated.Content)
+	assert.Equal(t, uint64(7), consolidated.Seq)
+	assert.Equal(t, "true", consolidated.Metadata[constants.MetadataKeyConsolidated])
+	assert.NotContains(t, chunk.Metadata, constants.MetadataKeyConsolidated, "the full chunk is unchanged")
+}
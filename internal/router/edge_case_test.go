@@ -337,7 +337,7 @@ func TestEdgeCase_AdminTakeoverNonExistentSession(t *testing.T) {
 
 	var chatErr *chaterrors.ChatError
 	if assert.ErrorAs(t, err, &chatErr) {
-		assert.Equal(t, chaterrors.ErrCodeNotFound, chatErr.Code)
+		assert.Equal(t, chaterrors.ErrCodeSessionNotFound, chatErr.Code)
 	}
 }
 
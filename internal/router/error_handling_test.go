@@ -54,6 +54,30 @@ func (m *mockStorageServiceForErrorTests) UpdateSessionModelID(sessionID, modelI
 	return nil
 }
 
+func (m *mockStorageServiceForErrorTests) RecordHandback(sessionID string, intervention *session.AdminIntervention) error {
+	return nil
+}
+
+func (m *mockStorageServiceForErrorTests) UpdateSessionLLMParams(sessionID string, params *session.LLMParams) error {
+	return nil
+}
+
+func (m *mockStorageServiceForErrorTests) UpdateSessionProviderThread(sessionID string, thread *session.ProviderThread) error {
+	return nil
+}
+
+func (m *mockStorageServiceForErrorTests) SetMessageFeedback(sessionID string, index int, feedback *session.Feedback) error {
+	return nil
+}
+
+func (m *mockStorageServiceForErrorTests) MarkMessagesRead(sessionID string, indexes []int, at time.Time) error {
+	return nil
+}
+
+func (m *mockStorageServiceForErrorTests) EndSession(sessionID string, endTime time.Time) error {
+	return nil
+}
+
 // TestErrorHandling_NilConnection tests that nil connection is properly handled
 // **Validates: Requirements 6.1**
 func TestErrorHandling_NilConnection(t *testing.T) {
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/events"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// EventPublisher publishes session lifecycle events for integrations (to avoid
+// coupling the router to the event bus sinks)
+type EventPublisher interface {
+	Publish(event events.Event)
+}
+
+// SetEventPublisher publishes session lifecycle events (created sessions, added
+// messages, help requests, takeovers, handbacks and ended sessions) to publisher.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetEventPublisher(publisher EventPublisher) {
+	mr.events = publisher
+}
+
+// publishEvent publishes a lifecycle event when the event bus is configured
+func (mr *MessageRouter) publishEvent(event events.Event) {
+	// No else needed: optional operation (only when the event bus is configured)
+	if mr.events != nil {
+		mr.events.Publish(event)
+	}
+}
+
+// publishMessageAdded publishes a message_added event for a message stored in a
+// session. The event carries the sender, not the content.
+func (mr *MessageRouter) publishMessageAdded(sessionID string, msg *session.Message) {
+	// No else needed: early return pattern (event bus not configured)
+	if mr.events == nil {
+		return
+	}
+	sess, err := mr.sessionManager.GetSession(sessionID)
+	// No else needed: early return pattern (session already gone from memory)
+	if err != nil {
+		return
+	}
+
+	data := map[string]string{"sender": msg.Sender}
+	// No else needed: optional operation (system events name what happened)
+	if msg.Event != "" {
+		data["event"] = msg.Event
+	}
+	mr.events.Publish(events.Event{
+		Type:      events.TypeMessageAdded,
+		SessionID: sessionID,
+		UserID:    sess.UserID,
+		TenantID:  sess.TenantID,
+		Timestamp: msg.Timestamp.UTC(),
+		Data:      data,
+	})
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/real-rm/chatbox/internal/experiment"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// ExperimentAssigner assigns users to A/B experiment variants (to avoid coupling
+// the router to the experiment store)
+type ExperimentAssigner interface {
+	Assign(tenantID, userID string) ([]experiment.Assignment, error)
+}
+
+// SetExperiments enables assigning new sessions to A/B experiments. Must be
+// called before the router handles any messages.
+func (mr *MessageRouter) SetExperiments(assigner ExperimentAssigner) {
+	mr.experiments = assigner
+}
+
+// applyExperiments assigns a new session to the running experiments of its
+// tenant: model variants set the session's model, and prompt variants its
+// system prompt template unless the client selected one, in which case the
+// session is not part of the prompt experiment. Variants that cannot
+// be applied, e.g. a model removed from the catalog, are skipped so the session
+// is not counted for them. Failures are logged; the session continues without
+// the experiment.
+func (mr *MessageRouter) applyExperiments(sess *session.Session, promptTemplateID string) {
+	// No else needed: early return pattern (experiments disabled)
+	if mr.experiments == nil {
+		return
+	}
+
+	assignments, err := mr.experiments.Assign(sess.TenantID, sess.UserID)
+	// No else needed: optional operation (assignments of config experiments are still applied)
+	if err != nil {
+		mr.logger.Warn("Failed to list experiments",
+			"session_id", sess.ID,
+			"tenant_id", sess.TenantID,
+			"error", err)
+	}
+
+	var applied []session.ExperimentVariant
+	for _, a := range assignments {
+		// No else needed: optional operation (the client's template choice wins)
+		if a.Kind == experiment.KindPrompt && promptTemplateID != "" {
+			continue
+		}
+		// No else needed: optional operation (skip variants that cannot be applied)
+		if err := mr.applyVariant(sess, a); err != nil {
+			mr.logger.Warn("Failed to apply experiment variant",
+				"session_id", sess.ID,
+				"experiment_id", a.ExperimentID,
+				"variant", a.Variant,
+				"error", err)
+			continue
+		}
+		applied = append(applied, session.ExperimentVariant{ExperimentID: a.ExperimentID, Variant: a.Variant})
+		metrics.ExperimentAssignments.WithLabelValues(a.ExperimentID, a.Variant).Inc()
+	}
+	// No else needed: early return pattern (no experiment applies)
+	if len(applied) == 0 {
+		return
+	}
+	// No else needed: optional operation (the variants stay applied even when not recorded)
+	if err := mr.sessionManager.SetExperiments(sess.ID, applied); err != nil {
+		mr.logger.Warn("Failed to record experiment variants", "session_id", sess.ID, "error", err)
+	}
+}
+
+// applyVariant sets the session's model or prompt template to the variant's value
+func (mr *MessageRouter) applyVariant(sess *session.Session, a experiment.Assignment) error {
+	switch a.Kind {
+	case experiment.KindModel:
+		// No else needed: early return pattern (guard clause)
+		if err := mr.llmService.ValidateModel(a.Value); err != nil {
+			return err
+		}
+		return mr.sessionManager.SetModelID(sess.ID, a.Value)
+	case experiment.KindPrompt:
+		// No else needed: early return pattern (guard clause)
+		if mr.prompts == nil {
+			return errors.New("prompt templates are not enabled")
+		}
+		// No else needed: early return pattern (guard clause)
+		if _, err := mr.prompts.Render(sess.TenantID, a.Value, nil); err != nil {
+			return err
+		}
+		return mr.sessionManager.SetPromptTemplateID(sess.ID, a.Value)
+	default:
+		return fmt.Errorf("unknown experiment kind %q", a.Kind)
+	}
+}
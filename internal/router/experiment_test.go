@@ -0,0 +1,84 @@
+package router
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/experiment"
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExperimentService() *experiment.Service {
+	return experiment.NewService([]*experiment.Experiment{
+		{ID: "models", Kind: experiment.KindModel, Active: true, Variants: []experiment.Variant{
+			{Name: "a", Value: "model-a", Weight: 1},
+			{Name: "b", Value: "model-b", Weight: 1},
+		}},
+		{ID: "prompts", Kind: experiment.KindPrompt, Active: true, Variants: []experiment.Variant{
+			{Name: "support", Value: "support", Weight: 1},
+			{Name: "brief", Value: "brief", Weight: 1},
+		}},
+	}, nil)
+}
+
+func TestExperiments_AssignedAtCreation(t *testing.T) {
+	router, _, storage := newPromptTestRouter(t)
+	router.SetPromptRenderer(prompt.NewService([]*prompt.Template{
+		{ID: "support", Name: "Support", Content: "Support", ReadOnly: true},
+		{ID: "brief", Name: "Brief", Content: "Be brief", ReadOnly: true},
+	}, nil))
+	experiments := newTestExperimentService()
+	router.SetExperiments(experiments)
+
+	conn := mockConnection("user-1")
+	sess, err := router.createNewSession(conn, "")
+	require.NoError(t, err)
+
+	want, err := experiments.Assign("", "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, want[0].Value, sess.GetModelID())
+	assert.Equal(t, want[1].Value, sess.GetPromptTemplateID())
+	assert.Equal(t, []session.ExperimentVariant{
+		{ExperimentID: "models", Variant: want[0].Variant},
+		{ExperimentID: "prompts", Variant: want[1].Variant},
+	}, sess.GetExperiments())
+	require.Len(t, storage.createdSessions, 1)
+	assert.Len(t, storage.createdSessions[0].Experiments, 2, "variants are persisted with the new session")
+}
+
+func TestExperiments_ClientTemplateWins(t *testing.T) {
+	router, _, _ := newPromptTestRouter(t)
+	router.SetPromptRenderer(prompt.NewService([]*prompt.Template{
+		{ID: "custom", Name: "Custom", Content: "Custom", ReadOnly: true},
+	}, nil))
+	router.SetExperiments(newTestExperimentService())
+
+	sess, err := router.createNewSession(mockConnection("user-1"), "custom")
+	require.NoError(t, err)
+	assert.Equal(t, "custom", sess.GetPromptTemplateID())
+	experiments := sess.GetExperiments()
+	require.Len(t, experiments, 1, "the session is not part of the prompt experiment")
+	assert.Equal(t, "models", experiments[0].ExperimentID)
+}
+
+// failingAssigner fails to list experiments
+type failingAssigner struct{}
+
+func (failingAssigner) Assign(tenantID, userID string) ([]experiment.Assignment, error) {
+	return []experiment.Assignment{{ExperimentID: "prompts", Kind: experiment.KindPrompt, Variant: "a", Value: "missing"}},
+		errors.New("database unavailable")
+}
+
+func TestExperiments_FailuresDoNotBlockSessions(t *testing.T) {
+	router, _, storage := newPromptTestRouter(t)
+	router.SetExperiments(failingAssigner{})
+
+	sess, err := router.createNewSession(mockConnection("user-1"), "")
+	require.NoError(t, err)
+	assert.Empty(t, sess.GetPromptTemplateID(), "variants that cannot be applied are skipped")
+	assert.Empty(t, sess.GetExperiments())
+	assert.Len(t, storage.createdSessions, 1)
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// ApplySessionChange brings the in-memory copy of a session up to date after
+// its stored copy changed, possibly on another instance or through tooling, and
+// pushes what changed to the session's client and watchers on this replica:
+// each message added elsewhere, then a session_update carrying the new name or
+// telling the client the session was ended or deleted. stored is the session
+// after the change, nil when it was deleted. Every replica receives every
+// change, so nothing is forwarded to other replicas.
+func (mr *MessageRouter) ApplySessionChange(sessionID string, stored *session.Session) {
+	change := mr.sessionManager.ApplyExternal(sessionID, stored)
+	// No else needed: early return pattern (not in memory, or changed here)
+	if change == nil {
+		return
+	}
+	// No else needed: optional operation (stop replying to a session that is over)
+	if change.Ended || change.Removed {
+		mr.CancelGeneration(sessionID)
+	}
+
+	for _, m := range change.Messages {
+		mr.pushExternal(sessionID, externalMessage(sessionID, m))
+	}
+
+	// No else needed: early return pattern (only messages were added)
+	if change.Name == "" && !change.Ended && !change.Removed {
+		return
+	}
+	update := &message.Message{
+		Type:      message.TypeSessionUpdate,
+		SessionID: sessionID,
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Metadata:  map[string]string{constants.MetadataKeyExternal: "true"},
+	}
+	// No else needed: optional operation (renamed elsewhere)
+	if change.Name != "" {
+		update.Metadata[constants.MetadataKeySessionName] = change.Name
+	}
+	// No else needed: optional operation (ended elsewhere)
+	if change.Ended {
+		update.Metadata[constants.MetadataKeyEnded] = "true"
+	}
+	// No else needed: optional operation (deleted elsewhere)
+	if change.Removed {
+		update.Metadata[constants.MetadataKeyDeleted] = "true"
+	}
+	mr.pushExternal(sessionID, update)
+}
+
+// pushExternal sends msg to the session's connection and watchers on this
+// replica only
+func (mr *MessageRouter) pushExternal(sessionID string, msg *message.Message) {
+	data, err := mr.marshalForSession(sessionID, msg)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		mr.logger.Warn("Failed to marshal external session change", "session_id", sessionID, "error", err)
+		return
+	}
+	mr.mirrorToWatchers(sessionID, data)
+	// No else needed: optional operation (the client may be connected elsewhere or not at all)
+	if err := mr.sendRawLocal(sessionID, data); err != nil {
+		mr.logger.Debug("External session change not delivered", "session_id", sessionID, "error", err)
+	}
+}
+
+// externalMessage converts a message stored elsewhere to the frame a client
+// would have received for it
+func externalMessage(sessionID string, m *session.Message) *message.Message {
+	msgType := message.TypeNotification
+	switch m.Sender {
+	case string(message.SenderUser):
+		msgType = message.TypeUserMessage
+	case string(message.SenderAI):
+		msgType = message.TypeAIResponse
+	case string(message.SenderAdmin):
+		msgType = message.TypeAdminMessage
+	}
+
+	metadata := make(map[string]string, len(m.Metadata)+2)
+	for k, v := range m.Metadata {
+		metadata[k] = v
+	}
+	metadata[constants.MetadataKeyExternal] = "true"
+	// A reply stored elsewhere is complete, like the final chunk of a stream
+	// No else needed: optional operation (AI replies only)
+	if msgType == message.TypeAIResponse {
+		metadata["done"] = "true"
+	}
+	return &message.Message{
+		Type:      msgType,
+		SessionID: sessionID,
+		Content:   m.Content,
+		FileID:    m.FileID,
+		FileURL:   m.FileURL,
+		Sender:    message.SenderType(m.Sender),
+		Timestamp: m.Timestamp,
+		Metadata:  metadata,
+	}
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySessionChange_PushesExternalMessages(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	receiveAll(t, conn)
+
+	stored := &session.Session{
+		ID:     sess.ID,
+		UserID: "user-1",
+		Name:   "Refund request",
+		Messages: []*session.Message{
+			{Content: "I can help with that refund.", Sender: constants.SenderAdmin, Timestamp: time.Now()},
+		},
+	}
+	router.ApplySessionChange(sess.ID, stored)
+
+	msgs := receiveAll(t, conn)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, message.TypeAdminMessage, msgs[0].Type)
+	assert.Equal(t, "I can help with that refund.", msgs[0].Content)
+	assert.Equal(t, "true", msgs[0].Metadata[constants.MetadataKeyExternal])
+	assert.Equal(t, message.TypeSessionUpdate, msgs[1].Type)
+	assert.Equal(t, "Refund request", msgs[1].Metadata[constants.MetadataKeySessionName])
+	assert.Len(t, sess.Messages, 1)
+
+	// The same stored copy again is already in memory
+	router.ApplySessionChange(sess.ID, stored)
+	assert.Empty(t, receiveAll(t, conn))
+}
+
+func TestApplySessionChange_EndedAndDeleted(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	receiveAll(t, conn)
+
+	ended := time.Now()
+	router.ApplySessionChange(sess.ID, &session.Session{ID: sess.ID, UserID: "user-1", EndTime: &ended})
+	msgs := receiveAll(t, conn)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, message.TypeSessionUpdate, msgs[0].Type)
+	assert.Equal(t, "true", msgs[0].Metadata[constants.MetadataKeyEnded])
+	assert.False(t, sess.IsActive)
+
+	router.ApplySessionChange(sess.ID, nil)
+	msgs = receiveAll(t, conn)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "true", msgs[0].Metadata[constants.MetadataKeyDeleted])
+	_, err = sm.GetSession(sess.ID)
+	assert.Error(t, err)
+}
+
+func TestExternalMessage(t *testing.T) {
+	msg := externalMessage("s1", &session.Message{
+		Content:   "Here is the answer",
+		Sender:    constants.SenderAI,
+		Timestamp: time.Now(),
+		Metadata:  map[string]string{constants.MetadataKeyModel: "gpt-4"},
+	})
+	assert.Equal(t, message.TypeAIResponse, msg.Type)
+	assert.Equal(t, message.SenderAI, msg.Sender)
+	assert.Equal(t, "gpt-4", msg.Metadata[constants.MetadataKeyModel])
+	assert.Equal(t, "true", msg.Metadata["done"])
+
+	msg = externalMessage("s1", &session.Message{Content: "Session resumed", Sender: constants.SenderSystem})
+	assert.Equal(t, message.TypeNotification, msg.Type)
+}
@@ -0,0 +1,187 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/metrics"
+)
+
+// errAttemptTimeout is returned when an LLM attempt exceeds FallbackConfig.AttemptTimeout.
+// Its message contains "timeout" so llm.IsRetryable treats it as transient.
+var errAttemptTimeout = errors.New("LLM attempt timeout")
+
+// FallbackConfig configures how LLM calls recover from a failing model
+type FallbackConfig struct {
+	// Chains maps a model ID to the models tried, in order, when it fails.
+	// Fallback models may use other providers.
+	Chains map[string][]string
+	// AttemptTimeout bounds each attempt at getting a reply or establishing a
+	// stream (0 leaves attempts bounded by the request timeout only)
+	AttemptTimeout time.Duration
+	// Retries is the number of extra attempts on the same model after a
+	// transient error, with jittered exponential backoff. These come on top of
+	// the LLM service's own retries of the provider call.
+	Retries int
+}
+
+// SetFallback configures fallback chains, per-attempt timeouts and retries for
+// LLM calls. Without it every call goes to the session's model only.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetFallback(cfg FallbackConfig) {
+	mr.fallback = cfg
+}
+
+// ParseFallbackChains converts the raw [chatbox.llm_fallback.chains] config value,
+// a table of model = ["fallback", ...] entries, into fallback chains
+func ParseFallbackChains(raw interface{}) (map[string][]string, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.llm_fallback.chains is not a table")
+	}
+
+	chains := make(map[string][]string, len(table))
+	for modelID, value := range table {
+		// No else needed: optional operation (string lists decoded as []string)
+		if names, ok := value.([]string); ok {
+			chains[modelID] = names
+			continue
+		}
+		list, ok := value.([]interface{})
+		// No else needed: early return pattern (guard clause)
+		if !ok {
+			return nil, fmt.Errorf("model %s: fallbacks must be a list of model IDs", modelID)
+		}
+		chain := make([]string, 0, len(list))
+		for _, item := range list {
+			name, ok := item.(string)
+			// No else needed: early return pattern (guard clause)
+			if !ok {
+				return nil, fmt.Errorf("model %s: fallbacks must be a list of model IDs", modelID)
+			}
+			chain = append(chain, name)
+		}
+		chains[modelID] = chain
+	}
+
+	for modelID, chain := range chains {
+		seen := map[string]bool{modelID: true}
+		for _, fallback := range chain {
+			// No else needed: early return pattern (guard clause)
+			if seen[fallback] {
+				return nil, fmt.Errorf("model %s: fallback %q listed twice or falls back to itself", modelID, fallback)
+			}
+			seen[fallback] = true
+		}
+	}
+	return chains, nil
+}
+
+// tryModels runs attempt for modelID and then for each model of its fallback
+// chain until one succeeds. It returns the model that succeeded and the function
+// releasing its attempt context. Transient errors are retried on the same model
+// first; other errors move straight to the next model. With send queues
+// enabled, rate limited attempts wait in their provider's queue instead.
+//
+// Each attempt's context is cancelled if the attempt timeout expires before
+// attempt returns. For the successful attempt it stays valid until release is
+// called, so a stream established by attempt can outlive it.
+func (mr *MessageRouter) tryModels(ctx context.Context, modelID string, attempt func(ctx context.Context, modelID string) error) (string, context.CancelFunc, error) {
+	candidates := append([]string{modelID}, mr.fallback.Chains[modelID]...)
+	var lastErr error
+	for _, candidate := range candidates {
+		for try := 0; try <= mr.fallback.Retries; try++ {
+			// No else needed: optional operation (back off before a retry)
+			if try > 0 {
+				select {
+				case <-ctx.Done():
+					return "", nil, ctx.Err()
+				case <-time.After(retryDelay(try)):
+				}
+			}
+
+			release, err := mr.queueAttempt(ctx, candidate, attempt)
+			// No else needed: early return pattern (attempt succeeded)
+			if err == nil {
+				// No else needed: optional operation (only when a fallback served)
+				if candidate != modelID {
+					mr.logger.Warn("LLM fallback model used", "model_id", modelID, "fallback_model_id", candidate, "error", lastErr)
+					metrics.LLMFallbacks.WithLabelValues(modelID, candidate).Inc()
+				}
+				return candidate, release, nil
+			}
+			lastErr = err
+			mr.logger.Warn("LLM attempt failed", "model_id", candidate, "attempt", try+1, "error", err)
+
+			// No else needed: early return pattern (the request itself timed out or was cancelled)
+			if ctx.Err() != nil {
+				return "", nil, err
+			}
+			// No else needed: optional operation (permanent errors are not retried on the same model)
+			if !llm.IsRetryable(err) {
+				break
+			}
+		}
+	}
+	return "", nil, lastErr
+}
+
+// runAttempt calls attempt with a context cancelled after the attempt timeout.
+// On success it returns the function releasing that context.
+func (mr *MessageRouter) runAttempt(ctx context.Context, modelID string, attempt func(ctx context.Context, modelID string) error) (context.CancelFunc, error) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	timeout := mr.fallback.AttemptTimeout
+	var timer *time.Timer
+	// No else needed: optional operation (attempts are otherwise bounded by ctx)
+	if timeout > 0 {
+		timer = time.AfterFunc(timeout, cancel)
+	}
+
+	err := attempt(attemptCtx, modelID)
+	// No else needed: early return pattern (the timer cancelled the attempt)
+	if timer != nil && !timer.Stop() {
+		cancel()
+		return nil, fmt.Errorf("%w: model %s after %s", errAttemptTimeout, modelID, timeout)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return cancel, nil
+}
+
+// retryDelay returns the backoff before retry n (counting from 1): exponential
+// from constants.FallbackRetryBaseDelay up to constants.FallbackRetryMaxDelay,
+// of which up to half is random so that sessions hit by the same outage do not
+// retry in lockstep
+func retryDelay(n int) time.Duration {
+	delay := constants.FallbackRetryMaxDelay
+	// No else needed: conditional assignment (capped for late retries)
+	if n < 16 && constants.FallbackRetryBaseDelay<<uint(n-1) < delay {
+		delay = constants.FallbackRetryBaseDelay << uint(n-1)
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// servedMetadata returns the message metadata recording the model and provider
+// that served a reply, and the session's model when a fallback served it
+func (mr *MessageRouter) servedMetadata(modelID, servedBy string) map[string]string {
+	metadata := map[string]string{constants.MetadataKeyModel: servedBy}
+	// No else needed: optional operation (models outside the catalog have no provider)
+	if provider := mr.modelInfo(servedBy).Provider; provider != "" {
+		metadata[constants.MetadataKeyProvider] = provider
+	}
+	// No else needed: optional operation (only when a fallback served)
+	if servedBy != modelID {
+		metadata[constants.MetadataKeyFallbackFrom] = modelID
+	}
+	return metadata
+}
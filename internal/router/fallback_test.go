@@ -0,0 +1,235 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fallbackLLMService fails calls to a model with its queued errors, and blocks
+// calls to models marked slow until their context ends
+type fallbackLLMService struct {
+	mu    sync.Mutex
+	errs  map[string][]error
+	slow  map[string]bool
+	calls []string
+}
+
+// next records a call to modelID and returns the error it should fail with
+func (m *fallbackLLMService) next(ctx context.Context, modelID string) error {
+	m.mu.Lock()
+	m.calls = append(m.calls, modelID)
+	slow := m.slow[modelID]
+	var err error
+	if queued := m.errs[modelID]; len(queued) > 0 {
+		err, m.errs[modelID] = queued[0], queued[1:]
+	}
+	m.mu.Unlock()
+
+	if slow {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return err
+}
+
+func (m *fallbackLLMService) SendMessage(ctx context.Context, modelID string, messages []llm.ChatMessage) (*llm.LLMResponse, error) {
+	if err := m.next(ctx, modelID); err != nil {
+		return nil, err
+	}
+	return &llm.LLMResponse{Content: "Reply from " + modelID, TokensUsed: 10}, nil
+}
+
+func (m *fallbackLLMService) StreamMessage(ctx context.Context, modelID string, messages []llm.ChatMessage) (<-chan *llm.LLMChunk, error) {
+	if err := m.next(ctx, modelID); err != nil {
+		return nil, err
+	}
+	ch := make(chan *llm.LLMChunk, 1)
+	ch <- &llm.LLMChunk{Content: "Reply from " + modelID, Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func (m *fallbackLLMService) ValidateModel(modelID string) error { return nil }
+
+func (m *fallbackLLMService) GetAvailableModels() []llm.ModelInfo {
+	return []llm.ModelInfo{
+		{ID: "primary", Provider: "openai-main"},
+		{ID: "backup", Provider: "anthropic-main"},
+	}
+}
+
+func (m *fallbackLLMService) recordedCalls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls...)
+}
+
+func newFallbackRouter(t *testing.T, llmService *fallbackLLMService, cfg FallbackConfig) (*MessageRouter, *session.SessionManager) {
+	t.Helper()
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, llmService, nil, nil, nil, 120*time.Second, logger)
+	t.Cleanup(router.Shutdown)
+	router.SetFallback(cfg)
+	return router, sm
+}
+
+// send calls SendMessage through the router's fallback chain
+func send(router *MessageRouter, modelID string) (string, string, error) {
+	var resp *llm.LLMResponse
+	servedBy, release, err := router.tryModels(context.Background(), modelID, func(ctx context.Context, modelID string) error {
+		var err error
+		resp, err = router.llmService.SendMessage(ctx, modelID, nil)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	release()
+	return servedBy, resp.Content, nil
+}
+
+func TestTryModels_FallsBackOnPermanentError(t *testing.T) {
+	llmService := &fallbackLLMService{errs: map[string][]error{
+		"primary": {errors.New("API error: status 401")},
+	}}
+	router, _ := newFallbackRouter(t, llmService, FallbackConfig{
+		Chains:  map[string][]string{"primary": {"backup"}},
+		Retries: 2,
+	})
+
+	servedBy, content, err := send(router, "primary")
+	require.NoError(t, err)
+	assert.Equal(t, "backup", servedBy)
+	assert.Equal(t, "Reply from backup", content)
+	assert.Equal(t, []string{"primary", "backup"}, llmService.recordedCalls(), "permanent errors are not retried")
+}
+
+func TestTryModels_RetriesTransientErrors(t *testing.T) {
+	llmService := &fallbackLLMService{errs: map[string][]error{
+		"primary": {errors.New("API error: status 503")},
+	}}
+	router, _ := newFallbackRouter(t, llmService, FallbackConfig{
+		Chains:  map[string][]string{"primary": {"backup"}},
+		Retries: 1,
+	})
+
+	servedBy, _, err := send(router, "primary")
+	require.NoError(t, err)
+	assert.Equal(t, "primary", servedBy)
+	assert.Equal(t, []string{"primary", "primary"}, llmService.recordedCalls())
+}
+
+func TestTryModels_AttemptTimeout(t *testing.T) {
+	llmService := &fallbackLLMService{slow: map[string]bool{"primary": true}}
+	router, _ := newFallbackRouter(t, llmService, FallbackConfig{
+		Chains:         map[string][]string{"primary": {"backup"}},
+		AttemptTimeout: 50 * time.Millisecond,
+	})
+
+	servedBy, _, err := send(router, "primary")
+	require.NoError(t, err)
+	assert.Equal(t, "backup", servedBy)
+}
+
+func TestTryModels_ChainExhausted(t *testing.T) {
+	llmService := &fallbackLLMService{errs: map[string][]error{
+		"primary": {errors.New("API error: status 500")},
+		"backup":  {errors.New("API error: status 400")},
+	}}
+	router, _ := newFallbackRouter(t, llmService, FallbackConfig{
+		Chains: map[string][]string{"primary": {"backup"}},
+	})
+
+	_, _, err := send(router, "primary")
+	assert.EqualError(t, err, "API error: status 400", "the last model's error is returned")
+
+	// Without a chain only the model itself is tried
+	llmService.errs = map[string][]error{"backup": {errors.New("API error: status 500")}}
+	_, _, err = send(router, "backup")
+	assert.Error(t, err)
+}
+
+func TestRouteMessage_RecordsServingModel(t *testing.T) {
+	llmService := &fallbackLLMService{errs: map[string][]error{
+		"primary": {errors.New("API error: status 500")},
+	}}
+	router, sm := newFallbackRouter(t, llmService, FallbackConfig{
+		Chains: map[string][]string{"primary": {"backup"}},
+	})
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetModelID(sess.ID, "primary"))
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	}))
+
+	reply := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, "Reply from backup", reply.Content)
+	assert.Equal(t, "backup", reply.Metadata[constants.MetadataKeyModel])
+	assert.Equal(t, "anthropic-main", reply.Metadata[constants.MetadataKeyProvider])
+	assert.Equal(t, "primary", reply.Metadata[constants.MetadataKeyFallbackFrom])
+}
+
+func TestServedMetadata(t *testing.T) {
+	router, _ := newFallbackRouter(t, &fallbackLLMService{}, FallbackConfig{})
+
+	assert.Equal(t, map[string]string{
+		constants.MetadataKeyModel:    "primary",
+		constants.MetadataKeyProvider: "openai-main",
+	}, router.servedMetadata("primary", "primary"))
+	assert.Equal(t, map[string]string{constants.MetadataKeyModel: "unlisted"}, router.servedMetadata("unlisted", "unlisted"))
+}
+
+func TestParseFallbackChains(t *testing.T) {
+	chains, err := ParseFallbackChains(map[string]interface{}{
+		"gpt-4":  []interface{}{"claude-3", "gpt-3.5"},
+		"claude": []string{"gpt-4"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"gpt-4":  {"claude-3", "gpt-3.5"},
+		"claude": {"gpt-4"},
+	}, chains)
+
+	invalid := []interface{}{
+		"not a table",
+		map[string]interface{}{"gpt-4": "claude-3"},
+		map[string]interface{}{"gpt-4": []interface{}{1}},
+		map[string]interface{}{"gpt-4": []interface{}{"gpt-4"}},
+		map[string]interface{}{"gpt-4": []interface{}{"claude-3", "claude-3"}},
+	}
+	for _, raw := range invalid {
+		_, err := ParseFallbackChains(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for n := 1; n <= 20; n++ {
+		full := constants.FallbackRetryMaxDelay
+		if n < 16 && constants.FallbackRetryBaseDelay<<uint(n-1) < full {
+			full = constants.FallbackRetryBaseDelay << uint(n-1)
+		}
+		delay := retryDelay(n)
+		assert.GreaterOrEqual(t, delay, full/2, "retry %d", n)
+		assert.LessOrEqual(t, delay, full, "retry %d", n)
+	}
+}
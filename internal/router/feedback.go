@@ -0,0 +1,87 @@
+package router
+
+import (
+	"errors"
+	"time"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// handleMessageFeedback records a user's thumbs up/down and optional comment on
+// an AI message of their session, replacing any earlier feedback on it. The
+// accepted feedback is echoed back to the client.
+func (mr *MessageRouter) handleMessageFeedback(conn *websocket.Connection, msg *message.Message) error {
+	if conn == nil {
+		return ErrNilConnection
+	}
+	if msg == nil {
+		return ErrNilMessage
+	}
+
+	// Validate session ID
+	if msg.SessionID == "" {
+		return chaterrors.ErrMissingField("session_id")
+	}
+
+	// Validate feedback
+	if msg.Feedback == nil {
+		return chaterrors.ErrMissingField("feedback")
+	}
+
+	// Verify session exists and ownership
+	sess, err := mr.sessionManager.GetSession(msg.SessionID)
+	if err != nil {
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	if !ownsSession(conn, sess) {
+		mr.logger.Warn("Session ownership violation in message feedback",
+			"session_id", msg.SessionID,
+			"session_owner", sess.UserID,
+			"requesting_user", conn.UserID)
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeUnauthorized,
+			"You do not have permission to access this session",
+			nil,
+		)
+	}
+
+	feedback := &session.Feedback{
+		Rating:    msg.Feedback.Rating,
+		Comment:   msg.Feedback.Comment,
+		UpdatedAt: time.Now(),
+	}
+	if err := feedback.Validate(); err != nil {
+		return chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat, err.Error(), err)
+	}
+
+	// Store the feedback in the session (in-memory + persistent)
+	index := msg.Feedback.MessageIndex
+	if err := mr.sessionManager.SetMessageFeedback(sess.ID, index, feedback); err != nil {
+		// No else needed: early return pattern (client addressed a wrong message)
+		if errors.Is(err, session.ErrInvalidMessageIndex) || errors.Is(err, session.ErrNotAIMessage) {
+			return chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat, err.Error(), err)
+		}
+		return chaterrors.ErrDatabaseError(err)
+	}
+	if mr.storageService != nil {
+		if err := mr.storageService.SetMessageFeedback(sess.ID, index, feedback); err != nil {
+			mr.logger.Warn("Failed to persist message feedback", "session_id", sess.ID, "message_index", index, "error", err)
+		}
+	}
+
+	mr.logger.Info("Message feedback", "session_id", sess.ID, "message_index", index, "rating", feedback.Rating)
+
+	// Echo the accepted feedback back to the client
+	response := &message.Message{
+		Type:      message.TypeMessageFeedback,
+		SessionID: sess.ID,
+		Feedback:  msg.Feedback,
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+	}
+
+	return mr.sendToConnection(sess.ID, response)
+}
@@ -0,0 +1,60 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMessageFeedback(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	storage := &mockStorageService{}
+	router := NewMessageRouter(sm, nil, nil, nil, storage, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "Hi", Sender: constants.SenderUser}))
+	require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "Hello!", Sender: constants.SenderAI}))
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	feedback := func(index int, rating string) *message.Message {
+		return &message.Message{
+			Type:      message.TypeMessageFeedback,
+			SessionID: sess.ID,
+			Feedback:  &message.MessageFeedback{MessageIndex: index, Rating: rating, Comment: "Helpful"},
+			Sender:    message.SenderUser,
+		}
+	}
+
+	require.NoError(t, router.handleMessageFeedback(conn, feedback(1, constants.FeedbackUp)))
+	assert.Equal(t, []message.MessageType{message.TypeMessageFeedback}, drainTypes(t, conn))
+	require.NotNil(t, sess.Messages[1].Feedback)
+	assert.Equal(t, constants.FeedbackUp, sess.Messages[1].Feedback.Rating)
+	require.Len(t, storage.feedback, 1, "feedback is persisted")
+	assert.Equal(t, "Helpful", storage.feedback[0].Comment)
+
+	// Only valid ratings of AI messages are accepted
+	assert.Error(t, router.handleMessageFeedback(conn, feedback(1, "meh")))
+	assert.Error(t, router.handleMessageFeedback(conn, feedback(0, constants.FeedbackUp)))
+	assert.Error(t, router.handleMessageFeedback(conn, feedback(5, constants.FeedbackUp)))
+	assert.Len(t, storage.feedback, 1)
+
+	// Only the session owner can rate its messages
+	assert.Error(t, router.handleMessageFeedback(mockConnection("user-2"), feedback(1, constants.FeedbackDown)))
+	assert.Equal(t, constants.FeedbackUp, sess.Messages[1].Feedback.Rating)
+
+	// Missing feedback
+	assert.Error(t, router.handleMessageFeedback(conn, &message.Message{
+		Type:      message.TypeMessageFeedback,
+		SessionID: sess.ID,
+	}))
+}
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"slices"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/webhook"
+)
+
+// ValidEndReason reports whether reason is a reason code an admin can
+// force-end a session with (see constants.EndReasons)
+func ValidEndReason(reason string) bool {
+	return slices.Contains(constants.EndReasons, reason)
+}
+
+// ForceEndSession ends a session on behalf of an admin with a reason code.
+// Any reply being generated is cancelled, the client is sent a session_update
+// marking the session ended with the reason, the end is recorded as a
+// force_ended system event, the in-memory session is ended, and a
+// session_ended webhook is published with the reason. stored is the session
+// as stored; persisting the end and closing the client's connections are left
+// to the caller, after this returns so the notice is queued first.
+func (mr *MessageRouter) ForceEndSession(stored *session.Session, adminID, reason string) {
+	sessionID := stored.ID
+	userID := stored.UserID
+	// No else needed: optional operation (the in-memory copy has the user's own ID, also in anonymized mode)
+	if live, err := mr.sessionManager.GetSession(sessionID); err == nil {
+		userID = live.UserID
+	}
+	mr.CancelGeneration(sessionID)
+
+	update := &message.Message{
+		Type:      message.TypeSessionUpdate,
+		SessionID: sessionID,
+		Content:   "Session ended by an administrator.",
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			constants.MetadataKeyEnded:     "true",
+			constants.MetadataKeyEndReason: reason,
+		},
+	}
+	// No else needed: optional operation (the client may be disconnected)
+	if err := mr.sendToConnection(sessionID, update); err != nil {
+		mr.logger.Debug("Force-end notice not delivered", "session_id", sessionID, "error", err)
+	}
+
+	mr.RecordSystemEvent(sessionID, constants.SystemEventForceEnded, "Session ended by an administrator",
+		map[string]string{"reason": reason, "admin_id": adminID})
+	// Ignore not-found: the session may have expired from memory or live on another replica
+	_ = mr.sessionManager.EndSession(sessionID)
+
+	metrics.SessionsForceEnded.WithLabelValues(reason).Inc()
+	mr.logger.Info("Session force-ended by admin",
+		"session_id", sessionID,
+		"admin_id", adminID,
+		"reason", reason)
+	mr.publishWebhook(webhook.Event{
+		Type:      webhook.EventSessionEnded,
+		SessionID: sessionID,
+		UserID:    userID,
+		TenantID:  stored.TenantID,
+		Data:      map[string]string{"reason": reason, "ended_by": adminID},
+	})
+}
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidEndReason(t *testing.T) {
+	for _, reason := range constants.EndReasons {
+		assert.True(t, ValidEndReason(reason), reason)
+	}
+	assert.False(t, ValidEndReason(""))
+	assert.False(t, ValidEndReason("Abuse"))
+}
+
+func TestForceEndSession_NotifiesAndEnds(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+	webhooks := &recordingPublisher{}
+	router.SetWebhookPublisher(webhooks)
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	router.ForceEndSession(sess, "admin-1", constants.EndReasonAbuse)
+
+	msg := nextMessage(t, conn)
+	assert.Equal(t, message.TypeSessionUpdate, msg.Type)
+	assert.Equal(t, "true", msg.Metadata[constants.MetadataKeyEnded])
+	assert.Equal(t, constants.EndReasonAbuse, msg.Metadata[constants.MetadataKeyEndReason])
+
+	assert.False(t, sess.IsActive)
+	last := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, constants.SystemEventForceEnded, last.Event)
+	assert.Equal(t, "admin-1", last.Metadata["admin_id"])
+
+	assert.Equal(t, []string{"session_ended"}, webhooks.types())
+	assert.Equal(t, constants.EndReasonAbuse, webhooks.events[0].Data["reason"])
+	assert.Equal(t, "admin-1", webhooks.events[0].Data["ended_by"])
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"context"
+	"errors"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// errGenerationCancelled is the cancellation cause of an LLM stream the client cancelled
+var errGenerationCancelled = errors.New("generation cancelled by client")
+
+// generation is an in-flight LLM stream of a session
+type generation struct {
+	cancel context.CancelCauseFunc
+}
+
+// startGeneration registers the LLM stream of a session so a cancel_generation
+// message can abort it. The returned context is cancelled with
+// errGenerationCancelled by CancelGeneration; finish must be called when the
+// stream ends.
+func (mr *MessageRouter) startGeneration(ctx context.Context, sessionID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	gen := &generation{cancel: cancel}
+
+	mr.mu.Lock()
+	mr.generations[sessionID] = gen
+	mr.mu.Unlock()
+
+	return ctx, func() {
+		mr.mu.Lock()
+		// No else needed: optional operation (a newer stream of the session replaced this one)
+		if mr.generations[sessionID] == gen {
+			delete(mr.generations, sessionID)
+		}
+		mr.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// CancelGeneration aborts the in-flight LLM stream of a session, if any, and
+// reports whether there was one. The partial reply is kept, flagged truncated.
+func (mr *MessageRouter) CancelGeneration(sessionID string) bool {
+	mr.mu.Lock()
+	gen, ok := mr.generations[sessionID]
+	delete(mr.generations, sessionID)
+	mr.mu.Unlock()
+
+	// No else needed: early return pattern (nothing is being generated)
+	if !ok {
+		return false
+	}
+	gen.cancel(errGenerationCancelled)
+	return true
+}
+
+// generationCancelled reports whether a stream context was cancelled by the client
+func generationCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errGenerationCancelled)
+}
+
+// handleCancelGeneration handles a cancel_generation message: the session's
+// in-flight LLM stream stops, its partial reply is stored flagged truncated, and
+// the router worker handling it is freed. Cancelling when nothing is being
+// generated is a no-op.
+func (mr *MessageRouter) handleCancelGeneration(conn *websocket.Connection, msg *message.Message) error {
+	if conn == nil {
+		return ErrNilConnection
+	}
+	if msg == nil {
+		return ErrNilMessage
+	}
+
+	// Validate session ID
+	if msg.SessionID == "" {
+		return chaterrors.ErrMissingField("session_id")
+	}
+
+	// Verify session exists and ownership
+	sess, err := mr.sessionManager.GetSession(msg.SessionID)
+	if err != nil {
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	if !ownsSession(conn, sess) {
+		mr.logger.Warn("Session ownership violation in cancel generation",
+			"session_id", msg.SessionID,
+			"session_owner", sess.UserID,
+			"requesting_user", conn.UserID)
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeUnauthorized,
+			"You do not have permission to access this session",
+			nil,
+		)
+	}
+
+	// No else needed: optional operation (log only when a stream was cancelled)
+	if mr.CancelGeneration(sess.ID) {
+		mr.logger.Info("LLM generation cancelled by client",
+			"session_id", sess.ID,
+			"user_id", conn.UserID)
+	}
+	return nil
+}
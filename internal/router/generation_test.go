@@ -0,0 +1,124 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextMessage waits for the next message queued on a connection
+func nextMessage(t *testing.T, conn *websocket.Connection) *message.Message {
+	t.Helper()
+	select {
+	case data := <-conn.ReceiveForTest():
+		var msg message.Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		return &msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a message")
+		return nil
+	}
+}
+
+func TestCancelGeneration_StopsStreamAndKeepsPartialReply(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+
+	// The provider streams one chunk, then stalls without watching the context
+	stalled := make(chan struct{})
+	defer close(stalled)
+	llmMock := &mockLLMServiceWithContext{
+		onStreamMessage: func(ctx context.Context, modelID string, messages []llm.ChatMessage) (<-chan *llm.LLMChunk, error) {
+			ch := make(chan *llm.LLMChunk, 1)
+			ch <- &llm.LLMChunk{Content: "Partial answer"}
+			go func() {
+				<-stalled
+				close(ch)
+			}()
+			return ch, nil
+		},
+	}
+	router := NewMessageRouter(sm, llmMock, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- router.RouteMessage(conn, &message.Message{
+			Type:      message.TypeUserMessage,
+			SessionID: sess.ID,
+			Content:   "Tell me a long story",
+			Sender:    message.SenderUser,
+			Timestamp: time.Now(),
+		})
+	}()
+
+	assert.Equal(t, message.TypeLoading, nextMessage(t, conn).Type)
+	assert.Equal(t, "Partial answer", nextMessage(t, conn).Content)
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeCancelGeneration,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+		Timestamp: time.Now(),
+	}))
+
+	// The worker is freed although the provider never closes its stream
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancelled generation did not free the router worker")
+	}
+
+	final := nextMessage(t, conn)
+	assert.Equal(t, message.TypeAIResponse, final.Type)
+	assert.Equal(t, "true", final.Metadata["done"])
+	assert.Equal(t, "true", final.Metadata[constants.MetadataKeyTruncated])
+
+	reply := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, constants.SenderAI, reply.Sender)
+	assert.Equal(t, "Partial answer", reply.Content)
+	assert.Equal(t, "true", reply.Metadata[constants.MetadataKeyTruncated])
+
+	assert.False(t, router.CancelGeneration(sess.ID), "the finished stream is no longer cancellable")
+}
+
+func TestCancelGeneration_Validation(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	// Nothing is being generated: a no-op
+	assert.NoError(t, router.handleCancelGeneration(mockConnection("user-1"), &message.Message{
+		Type:      message.TypeCancelGeneration,
+		SessionID: sess.ID,
+	}))
+
+	// Other users cannot cancel the session's generations
+	assert.Error(t, router.handleCancelGeneration(mockConnection("user-2"), &message.Message{
+		Type:      message.TypeCancelGeneration,
+		SessionID: sess.ID,
+	}))
+	assert.Error(t, router.handleCancelGeneration(mockConnection("user-1"), &message.Message{
+		Type: message.TypeCancelGeneration,
+	}))
+}
@@ -0,0 +1,114 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleHandback_RoutesBackToLLM(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockLLMService{}
+	storage := &mockStorageService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, storage, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	admin := websocket.NewConnection("admin-1", []string{"admin"})
+	admin.Name = "Alice"
+	require.NoError(t, router.HandleAdminTakeover(admin, sess.ID))
+	drainTypes(t, admin)
+	drainTypes(t, conn)
+
+	userMsg := &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "are you a person?",
+		Sender:    message.SenderUser,
+	}
+
+	// During the takeover the admin answers, not the LLM
+	require.NoError(t, router.HandleUserMessage(conn, userMsg))
+	assert.False(t, llmMock.streamCalled, "LLM must not be called during a takeover")
+	assert.Equal(t, []message.MessageType{message.TypeUserMessage}, drainTypes(t, admin))
+
+	require.NoError(t, router.RouteMessage(admin, &message.Message{
+		Type:      message.TypeHandback,
+		SessionID: sess.ID,
+		Sender:    message.SenderAdmin,
+	}))
+	assert.Equal(t, []message.MessageType{message.TypeHandback}, drainTypes(t, conn))
+
+	require.Len(t, storage.handbacks, 1)
+	assert.Equal(t, "admin-1", storage.handbacks[0].AdminID)
+	assert.Equal(t, "Alice", storage.handbacks[0].AdminName)
+	assert.Equal(t, "admin-1", storage.handbacks[0].HandedBackBy)
+
+	last := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, constants.SystemEventAdminHandback, last.Event)
+
+	// The LLM answers again and the former admin no longer receives messages
+	require.NoError(t, router.HandleUserMessage(conn, userMsg))
+	assert.True(t, llmMock.streamCalled)
+	assert.Empty(t, drainTypes(t, admin))
+}
+
+func TestHandleHandback_Errors(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	admin := websocket.NewConnection("admin-1", []string{"admin"})
+
+	assert.Error(t, router.HandleHandback(nil, sess.ID))
+	assert.Error(t, router.HandleHandback(admin, ""))
+	assert.Error(t, router.HandleHandback(admin, "non-existent-session"))
+	assert.Error(t, router.HandleHandback(admin, sess.ID), "session was never taken over")
+
+	// Users cannot hand back their own session
+	user := mockConnection("user-1")
+	assert.Error(t, router.RouteMessage(user, &message.Message{
+		Type:      message.TypeHandback,
+		SessionID: sess.ID,
+		Sender:    message.SenderAdmin,
+	}))
+}
+
+func TestHandleHandbackMessage_RequiresTakeoverPermission(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	policy, err := authz.NewPolicy(map[string][]authz.Permission{
+		"viewer":  {authz.PermViewSessions},
+		"support": {authz.PermTakeover},
+	})
+	require.NoError(t, err)
+	router.SetPolicy(policy)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.HandleAdminTakeover(websocket.NewConnection("admin-1", []string{"support"}), sess.ID))
+
+	handback := &message.Message{Type: message.TypeHandback, SessionID: sess.ID, Sender: message.SenderAdmin}
+	assert.Error(t, router.RouteMessage(websocket.NewConnection("admin-2", []string{"viewer"}), handback))
+	assert.Error(t, router.RouteMessage(websocket.NewConnection("admin-3", []string{constants.RoleAdmin}), handback),
+		"roles missing from the policy grant nothing")
+	assert.NoError(t, router.RouteMessage(websocket.NewConnection("admin-1", []string{"support"}), handback))
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// drainTypes returns the message types currently queued on a test connection
+func drainTypes(t *testing.T, conn *websocket.Connection) []message.MessageType {
+	var types []message.MessageType
+	for {
+		select {
+		case data := <-conn.ReceiveForTest():
+			var msg message.Message
+			require.NoError(t, json.Unmarshal(data, &msg))
+			types = append(types, msg.Type)
+			continue
+		default:
+		}
+		return types
+	}
+}
+
+func TestHumanOnlyMode_QueuesSessionWithoutLLM(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockLLMService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	router.SetHumanOnlyMode(true)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	msg := &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "I need a person",
+		Sender:    message.SenderUser,
+	}
+	require.NoError(t, router.HandleUserMessage(conn, msg))
+
+	assert.False(t, llmMock.streamCalled, "LLM must not be called in human-only mode")
+	helpRequested, err := sm.IsHelpRequested(sess.ID)
+	require.NoError(t, err)
+	assert.True(t, helpRequested)
+	assert.Equal(t, []message.MessageType{message.TypeNotification}, drainTypes(t, conn))
+
+	// Follow-up messages are stored without re-queueing the session
+	require.NoError(t, router.HandleUserMessage(conn, msg))
+	assert.Empty(t, drainTypes(t, conn))
+	// Two user messages plus the handoff system event
+	assert.Len(t, sess.Messages, 3)
+}
+
+func TestHumanOnlyMode_ForwardsToAssistingAdmin(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	router.SetHumanOnlyMode(true)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	admin := websocket.NewConnection("admin-1", []string{"admin"})
+	require.NoError(t, router.HandleAdminTakeover(admin, sess.ID))
+	drainTypes(t, admin)
+
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "hello admin",
+		Sender:    message.SenderUser,
+	}))
+
+	assert.Equal(t, []message.MessageType{message.TypeUserMessage}, drainTypes(t, admin))
+}
+
+func TestHumanOnlyMode_RejectsModelSelection(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	router.SetHumanOnlyMode(true)
+	defer router.Shutdown()
+
+	assert.Nil(t, router.GetAvailableModelRefs())
+
+	err := router.handleModelSelection(mockConnection("user-1"), &message.Message{
+		Type:      message.TypeModelSelect,
+		SessionID: "session-1",
+		ModelID:   "gpt-4",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/webhook"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// touchSession records a client message as activity on the sender's session,
+// resetting its idle timer. Messages for sessions of other users are ignored.
+func (mr *MessageRouter) touchSession(conn *websocket.Connection, sessionID string) {
+	// No else needed: early return pattern (message not bound to a session yet)
+	if sessionID == "" {
+		return
+	}
+	sess, err := mr.sessionManager.GetSession(sessionID)
+	// No else needed: early return pattern (unknown or foreign session)
+	if err != nil || sess.UserID != conn.UserID || sess.TenantID != conn.TenantID {
+		return
+	}
+	_ = mr.sessionManager.TouchSession(sessionID)
+}
+
+// SessionExpiring implements session.IdleHandler: it sends a session_expiring
+// warning to the session's client; any client message cancels it
+func (mr *MessageRouter) SessionExpiring(sess *session.Session, remaining time.Duration) {
+	seconds := int(remaining.Seconds())
+	warning := &message.Message{
+		Type:      message.TypeSessionExpiring,
+		SessionID: sess.ID,
+		Content:   fmt.Sprintf("Session will end in %d seconds due to inactivity.", seconds),
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"countdown_seconds": strconv.Itoa(seconds),
+			"deadline":          time.Now().Add(remaining).UTC().Format(time.RFC3339),
+		},
+	}
+	// No else needed: optional operation (the client may be disconnected)
+	if err := mr.sendToConnection(sess.ID, warning); err != nil {
+		mr.logger.Debug("Idle warning not delivered", "session_id", sess.ID, "error", err)
+	}
+}
+
+// SessionIdleExpired implements session.IdleHandler: the session, already ended
+// in memory, is recorded and persisted as ended, the client is told, and a
+// session_ended webhook is published. Storage is updated asynchronously so the
+// session manager's cleanup goroutine is not blocked.
+func (mr *MessageRouter) SessionIdleExpired(sess *session.Session) {
+	mr.CancelGeneration(sess.ID)
+
+	ended := &message.Message{
+		Type:      message.TypeSessionExpiring,
+		SessionID: sess.ID,
+		Content:   "Session ended due to inactivity.",
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"countdown_seconds": "0",
+			"ended":             "true",
+		},
+	}
+	// No else needed: optional operation (the client may be disconnected)
+	if err := mr.sendToConnection(sess.ID, ended); err != nil {
+		mr.logger.Debug("Idle end notice not delivered", "session_id", sess.ID, "error", err)
+	}
+
+	mr.safeGo("idleSessionEnd", func() {
+		mr.RecordSystemEvent(sess.ID, constants.SystemEventIdleTimeout, "Session ended after inactivity", nil)
+		// No else needed: optional operation (only when storage is configured)
+		if mr.storageService != nil {
+			// No else needed: optional operation, storage failure is logged but not fatal
+			if err := mr.storageService.EndSession(sess.ID, time.Now()); err != nil {
+				util.LogError(mr.logger, "router", "persist idle session end", err, "session_id", sess.ID)
+			}
+		}
+		mr.publishWebhook(webhook.Event{
+			Type:      webhook.EventSessionEnded,
+			SessionID: sess.ID,
+			UserID:    sess.UserID,
+			TenantID:  sess.TenantID,
+			Data:      map[string]string{"reason": constants.SystemEventIdleTimeout},
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionExpiring_WarnsClient(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	router.SessionExpiring(sess, 45*time.Second)
+
+	msg := nextMessage(t, conn)
+	assert.Equal(t, message.TypeSessionExpiring, msg.Type)
+	assert.Equal(t, sess.ID, msg.SessionID)
+	assert.Equal(t, "45", msg.Metadata["countdown_seconds"])
+	assert.NotEmpty(t, msg.Metadata["deadline"])
+}
+
+func TestSessionIdleExpired_PersistsAndNotifies(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	storage := &mockStorageService{}
+	router := NewMessageRouter(sm, nil, nil, nil, storage, 120*time.Second, logger)
+	webhooks := &recordingPublisher{}
+	router.SetWebhookPublisher(webhooks)
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+	require.NoError(t, sm.EndSession(sess.ID))
+
+	router.SessionIdleExpired(sess)
+
+	msg := nextMessage(t, conn)
+	assert.Equal(t, message.TypeSessionExpiring, msg.Type)
+	assert.Equal(t, "true", msg.Metadata["ended"])
+
+	// Shutdown waits for the asynchronous storage update
+	router.Shutdown()
+	assert.Equal(t, []string{sess.ID}, storage.endedSessions)
+
+	require.Len(t, webhooks.events, 1)
+	assert.Equal(t, []string{"session_ended"}, webhooks.types())
+	assert.Equal(t, constants.SystemEventIdleTimeout, webhooks.events[0].Data["reason"])
+
+	last := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, constants.SystemEventIdleTimeout, last.Event)
+}
+
+func TestRouteMessage_ResetsIdleTimer(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	idleSince := time.Now().Add(-time.Hour)
+	sess.Lock()
+	sess.LastActivity = idleSince
+	sess.Unlock()
+
+	// Messages for another user's session do not count as activity
+	_ = router.RouteMessage(mockConnection("user-2"), &message.Message{
+		Type:      message.TypeCancelGeneration,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+		Timestamp: time.Now(),
+	})
+	sess.RLock()
+	assert.Equal(t, idleSince, sess.LastActivity)
+	sess.RUnlock()
+
+	_ = router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeCancelGeneration,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+		Timestamp: time.Now(),
+	})
+	sess.RLock()
+	assert.True(t, sess.LastActivity.After(idleSince))
+	sess.RUnlock()
+}
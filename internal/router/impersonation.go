@@ -0,0 +1,47 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// withImpersonator returns a copy of metadata marking a message as sent by
+// adminID while impersonating the session's user, so transcripts and audits
+// can tell it apart from the user's own messages
+func withImpersonator(metadata map[string]string, adminID string) map[string]string {
+	annotated := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		annotated[k] = v
+	}
+	annotated[constants.MetadataKeyImpersonatedBy] = adminID
+	return annotated
+}
+
+// ImpersonationAuditor records messages admins send with impersonation tokens in
+// the audit log (to avoid coupling the router to a concrete storage backend)
+type ImpersonationAuditor interface {
+	RecordImpersonatedMessage(adminID, tenantID, userID, sessionID, ip string) error
+}
+
+// SetImpersonationAuditor records every user message sent with an impersonation
+// token in the audit log, under the admin's ID.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetImpersonationAuditor(auditor ImpersonationAuditor) {
+	mr.impersonationLog = auditor
+}
+
+// auditImpersonatedMessage records a message the connection's admin sent as the
+// user. Failures are logged; the message is still delivered.
+func (mr *MessageRouter) auditImpersonatedMessage(conn *websocket.Connection, sessionID string) {
+	// No else needed: early return pattern (no audit log configured)
+	if mr.impersonationLog == nil {
+		return
+	}
+	// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
+	if err := mr.impersonationLog.RecordImpersonatedMessage(conn.ImpersonatorID, conn.TenantID, conn.UserID, sessionID, conn.Info().ClientIP); err != nil {
+		mr.logger.Warn("Failed to audit impersonated message",
+			"session_id", sessionID,
+			"admin_id", conn.ImpersonatorID,
+			"error", err)
+	}
+}
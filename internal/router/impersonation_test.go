@@ -0,0 +1,84 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonation_UserMessagesAreMarked(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	conn.ImpersonatorID = "admin-1"
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	msg := &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "reproducing the issue",
+		Sender:    message.SenderUser,
+		Metadata:  map[string]string{"client": "web"},
+	}
+	require.NoError(t, router.HandleUserMessage(conn, msg))
+
+	var userMsg *session.Message
+	for _, m := range sess.Messages {
+		if m.Sender == constants.SenderUser {
+			userMsg = m
+		}
+	}
+	require.NotNil(t, userMsg)
+	assert.Equal(t, "admin-1", userMsg.Metadata[constants.MetadataKeyImpersonatedBy])
+	assert.Equal(t, "web", userMsg.Metadata["client"])
+	assert.NotContains(t, msg.Metadata, constants.MetadataKeyImpersonatedBy, "the client's metadata is not modified")
+}
+
+// recordingAuditor records the impersonated messages it is given
+type recordingAuditor struct {
+	entries [][5]string
+}
+
+func (a *recordingAuditor) RecordImpersonatedMessage(adminID, tenantID, userID, sessionID, ip string) error {
+	a.entries = append(a.entries, [5]string{adminID, tenantID, userID, sessionID, ip})
+	return nil
+}
+
+func TestImpersonation_UserMessagesAreAudited(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+	auditor := &recordingAuditor{}
+	router.SetImpersonationAuditor(auditor)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	own := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, own))
+	require.NoError(t, router.HandleUserMessage(own, &message.Message{
+		Type: message.TypeUserMessage, SessionID: sess.ID, Content: "my own message", Sender: message.SenderUser,
+	}))
+	assert.Empty(t, auditor.entries, "the user's own messages are not audited")
+
+	conn := mockConnection("user-1")
+	conn.ImpersonatorID = "admin-1"
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type: message.TypeUserMessage, SessionID: sess.ID, Content: "reproducing the issue", Sender: message.SenderUser,
+	}))
+	require.Len(t, auditor.entries, 1)
+	assert.Equal(t, "admin-1", auditor.entries[0][0])
+	assert.Equal(t, "user-1", auditor.entries[0][2])
+	assert.Equal(t, sess.ID, auditor.entries[0][3])
+}
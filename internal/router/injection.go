@@ -0,0 +1,79 @@
+package router
+
+import (
+	"context"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/injection"
+	"github.com/real-rm/chatbox/internal/retrieval"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// InjectionScanner looks for prompt injections in content bound for the LLM
+// (to avoid coupling the router to a concrete scanner)
+type InjectionScanner interface {
+	Scan(ctx context.Context, source injection.Source, content string) *injection.Result
+}
+
+// SetInjectionScanner enables prompt injection detection in user messages,
+// after moderation and before they are stored or sent to the LLM, and in
+// retrieved documents before they are given to the LLM.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetInjectionScanner(scanner InjectionScanner) {
+	mr.injection = scanner
+}
+
+// scanInjection runs content through the injection scanner. Returns nil when no
+// scanner is configured or nothing was detected.
+func (mr *MessageRouter) scanInjection(source injection.Source, content string) *injection.Result {
+	// No else needed: early return pattern (detection not configured)
+	if mr.injection == nil {
+		return nil
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultInjectionTimeout)
+	defer cancel()
+
+	result := mr.injection.Scan(ctx, source, content)
+	// No else needed: early return pattern (nothing detected)
+	if result == nil || result.Action == injection.ActionNone {
+		return nil
+	}
+	return result
+}
+
+// withInjection returns a copy of metadata annotated with an injection result
+func withInjection(metadata map[string]string, result *injection.Result) map[string]string {
+	annotated := make(map[string]string, len(metadata)+2)
+	for k, v := range metadata {
+		annotated[k] = v
+	}
+	annotated[constants.MetadataKeyInjection] = result.Action.String()
+	annotated[constants.MetadataKeyInjectionRules] = strings.Join(result.Rules, ",")
+	return annotated
+}
+
+// screenDocuments scans retrieved documents for injections, stripping or
+// dropping them as configured, and returns the documents to give to the LLM
+func (mr *MessageRouter) screenDocuments(docs []retrieval.Document) []retrieval.Document {
+	// No else needed: early return pattern (detection not configured, or nothing retrieved)
+	if mr.injection == nil || len(docs) == 0 {
+		return docs
+	}
+
+	screened := make([]retrieval.Document, 0, len(docs))
+	for _, doc := range docs {
+		result := mr.scanInjection(injection.SourceDocument, doc.Content)
+		// No else needed: optional operation (only when an injection was detected)
+		if result != nil {
+			// No else needed: early return pattern (drop blocked documents)
+			if result.Action == injection.ActionBlock {
+				continue
+			}
+			doc.Content = result.Content
+		}
+		screened = append(screened, doc)
+	}
+	return screened
+}
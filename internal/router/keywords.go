@@ -0,0 +1,104 @@
+package router
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/watchlist"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// KeywordMatcher matches user messages against a tenant's keyword watchlist
+// (to avoid coupling the router to a concrete store)
+type KeywordMatcher interface {
+	Match(tenantID, content string) ([]*watchlist.Keyword, error)
+}
+
+// DashboardAlerter pushes alerts to the admin dashboard streams that may see
+// a tenant's sessions
+type DashboardAlerter interface {
+	PublishDashboardAlert(tenantID string, data []byte) int
+}
+
+// SetKeywordAlerts enables keyword alerts: user messages matching the tenant's
+// watchlist are pushed to admin dashboards through alerter and their sessions
+// are tagged with the keywords' tags (using the tagger set by
+// SetBusinessHours). Must be called before the router handles any messages.
+func (mr *MessageRouter) SetKeywordAlerts(matcher KeywordMatcher, alerter DashboardAlerter) {
+	mr.keywords = matcher
+	mr.alerts = alerter
+}
+
+// checkKeywords matches a stored user message against the tenant's watchlist
+// in the background. Messages an admin sent with an impersonation token are
+// skipped.
+func (mr *MessageRouter) checkKeywords(conn *websocket.Connection, sessionID, content string) {
+	// No else needed: early return pattern (alerts disabled, or nothing to match)
+	if mr.keywords == nil || content == "" || conn.ImpersonatorID != "" {
+		return
+	}
+	userID, tenantID := conn.UserID, conn.TenantID
+	mr.safeGo("checkKeywords", func() {
+		mr.raiseKeywordAlert(sessionID, userID, tenantID, content)
+	})
+}
+
+// raiseKeywordAlert counts, tags and publishes the watchlist keywords content matches
+func (mr *MessageRouter) raiseKeywordAlert(sessionID, userID, tenantID, content string) {
+	matched, err := mr.keywords.Match(tenantID, content)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(mr.logger, "router", "match keyword watchlist", err, "session_id", sessionID)
+		return
+	}
+	// No else needed: early return pattern (no keyword matched)
+	if len(matched) == 0 {
+		return
+	}
+
+	terms := make([]string, 0, len(matched))
+	var tags []string
+	seen := make(map[string]bool, len(matched))
+	for _, k := range matched {
+		terms = append(terms, k.Term)
+		metrics.KeywordAlertHits.With(prometheus.Labels{"tenant": tenantID, "keyword": k.Term}).Inc()
+		// No else needed: optional operation (skip duplicate tags)
+		if !seen[k.Tag] {
+			seen[k.Tag] = true
+			tags = append(tags, k.Tag)
+		}
+	}
+
+	// No else needed: optional operation (only storage with tags)
+	if mr.sessionTagger != nil {
+		// No else needed: optional operation (the alert is still raised)
+		if _, err := mr.sessionTagger.AddSessionTags(sessionID, tags); err != nil {
+			util.LogError(mr.logger, "router", "tag keyword alert session", err, "session_id", sessionID)
+		}
+	}
+
+	// No else needed: early return pattern (no dashboards to alert)
+	if mr.alerts == nil {
+		return
+	}
+	data, err := json.Marshal(watchlist.Alert{
+		Type:      watchlist.AlertType,
+		SessionID: sessionID,
+		UserID:    userID,
+		TenantID:  tenantID,
+		Keywords:  terms,
+		Tags:      tags,
+		Excerpt:   truncateRunes(content, constants.MaxKeywordAlertExcerpt),
+		Timestamp: time.Now(),
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(mr.logger, "router", "marshal keyword alert", err, "session_id", sessionID)
+		return
+	}
+	mr.alerts.PublishDashboardAlert(tenantID, data)
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/watchlist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAlerter records the dashboard alerts published per tenant
+type recordingAlerter struct {
+	mu     sync.Mutex
+	alerts map[string][]watchlist.Alert
+}
+
+func (r *recordingAlerter) PublishDashboardAlert(tenantID string, data []byte) int {
+	var alert watchlist.Alert
+	if err := json.Unmarshal(data, &alert); err != nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts[tenantID] = append(r.alerts[tenantID], alert)
+	return 1
+}
+
+// newKeywordRouter returns a router alerting on acme's watchlist
+func newKeywordRouter(t *testing.T) (*MessageRouter, *session.SessionManager, *recordingTagger, *recordingAlerter) {
+	t.Helper()
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &streamingMockLLMService{chunks: []string{"Happy to help."}}, nil, nil, nil, 120*time.Second, logger)
+	t.Cleanup(func() { router.Shutdown() })
+
+	watchlists := map[string][]*watchlist.Keyword{
+		"acme": {
+			{ID: "1", Term: "refund", Tag: constants.KeywordAlertTag},
+			{ID: "2", Term: "lawyer|attorney", Regex: true, Tag: "legal"},
+			{ID: "3", Term: "cancel account", Tag: constants.KeywordAlertTag},
+		},
+	}
+	tagger := &recordingTagger{tags: map[string][]string{}}
+	alerter := &recordingAlerter{alerts: map[string][]watchlist.Alert{}}
+	router.SetBusinessHours(nil, tagger)
+	router.SetKeywordAlerts(watchlist.NewService(func(tenantID string) ([]*watchlist.Keyword, error) {
+		return watchlists[tenantID], nil
+	}), alerter)
+	return router, sm, tagger, alerter
+}
+
+func sendUserMessage(t *testing.T, router *MessageRouter, sm *session.SessionManager, tenantID, content string, impersonated bool) *session.Session {
+	t.Helper()
+	sess, err := sm.CreateSessionForTenant("user-1", tenantID)
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	conn.TenantID = tenantID
+	if impersonated {
+		conn.ImpersonatorID = "admin-1"
+	}
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   content,
+		Sender:    message.SenderUser,
+	}))
+	router.wg.Wait()
+	return sess
+}
+
+func TestRouteMessage_KeywordAlert(t *testing.T) {
+	router, sm, tagger, alerter := newKeywordRouter(t)
+	hits := testutil.ToFloat64(metrics.KeywordAlertHits.WithLabelValues("acme", "refund"))
+
+	content := "My lawyer says I should get a Refund " + strings.Repeat("now ", constants.MaxKeywordAlertExcerpt)
+	sess := sendUserMessage(t, router, sm, "acme", content, false)
+
+	assert.Equal(t, []string{constants.KeywordAlertTag, "legal"}, tagger.tags[sess.ID])
+	require.Len(t, alerter.alerts["acme"], 1)
+	alert := alerter.alerts["acme"][0]
+	assert.Equal(t, watchlist.AlertType, alert.Type)
+	assert.Equal(t, sess.ID, alert.SessionID)
+	assert.Equal(t, "user-1", alert.UserID)
+	assert.Equal(t, []string{"refund", "lawyer|attorney"}, alert.Keywords)
+	assert.Len(t, []rune(alert.Excerpt), constants.MaxKeywordAlertExcerpt)
+	assert.Equal(t, hits+1, testutil.ToFloat64(metrics.KeywordAlertHits.WithLabelValues("acme", "refund")))
+}
+
+func TestRouteMessage_KeywordAlertSkipped(t *testing.T) {
+	router, sm, tagger, alerter := newKeywordRouter(t)
+
+	// No match, another tenant's watchlist, and an impersonating admin
+	quiet := sendUserMessage(t, router, sm, "acme", "Please cancel my subscription", false)
+	otherTenant := sendUserMessage(t, router, sm, "globex", "I want a refund", false)
+	impersonated := sendUserMessage(t, router, sm, "acme", "I want a refund", true)
+
+	assert.Empty(t, tagger.tags[quiet.ID])
+	assert.Empty(t, tagger.tags[otherTenant.ID])
+	assert.Empty(t, tagger.tags[impersonated.ID])
+	assert.Empty(t, alerter.alerts)
+}
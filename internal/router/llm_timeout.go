@@ -0,0 +1,100 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// SetLLMTimeoutBounds lets sessions (timeout_seconds in session_config) and
+// single user messages (timeout_seconds on the message) override the LLM stream
+// timeout with one between minTimeout and maxTimeout. A maxTimeout of 0 rejects
+// overrides; a minTimeout of 0 applies constants.DefaultMinLLMTimeout. Replies
+// given a longer timeout than the router's run in long-running mode, sending
+// keepalives while the model is silent. Must be called before the router
+// handles any messages.
+func (mr *MessageRouter) SetLLMTimeoutBounds(minTimeout, maxTimeout time.Duration) {
+	// No else needed: conditional assignment (apply default)
+	if minTimeout <= 0 {
+		minTimeout = constants.DefaultMinLLMTimeout
+	}
+	mr.llmTimeoutMin = minTimeout
+	mr.llmTimeoutMax = maxTimeout
+}
+
+// baseLLMTimeout returns the router's LLM stream timeout
+func (mr *MessageRouter) baseLLMTimeout() time.Duration {
+	// No else needed: early return pattern (apply default)
+	if mr.llmStreamTimeout == 0 {
+		return constants.DefaultLLMStreamTimeout
+	}
+	return mr.llmStreamTimeout
+}
+
+// checkLLMTimeout validates a timeout of seconds asked for by a client against
+// the configured bounds. 0 asks for none and is returned as 0.
+func (mr *MessageRouter) checkLLMTimeout(seconds int) (time.Duration, error) {
+	// No else needed: early return pattern (no override)
+	if seconds == 0 {
+		return 0, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if mr.llmTimeoutMax <= 0 {
+		return 0, chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat, "LLM timeout overrides are not enabled", nil)
+	}
+	timeout := time.Duration(seconds) * time.Second
+	// No else needed: early return pattern (guard clause)
+	if seconds < 0 || timeout < mr.llmTimeoutMin || timeout > mr.llmTimeoutMax {
+		return 0, chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat,
+			fmt.Sprintf("timeout_seconds must be between %d and %d",
+				int(mr.llmTimeoutMin/time.Second), int(mr.llmTimeoutMax/time.Second)), nil)
+	}
+	return timeout, nil
+}
+
+// streamTimeout returns the timeout of the reply to msg: the message's own,
+// else the session's while overrides are enabled (capped at the current
+// maximum), else the router's. longRunning is true when it exceeds the
+// router's timeout.
+func (mr *MessageRouter) streamTimeout(sess *session.Session, msg *message.Message) (timeout time.Duration, longRunning bool, err error) {
+	base := mr.baseLLMTimeout()
+	timeout, err = mr.checkLLMTimeout(msg.Timeout)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, false, err
+	}
+	// No else needed: optional operation (the session's override applies)
+	if params := sess.GetLLMParams(); timeout == 0 && params != nil && mr.llmTimeoutMax > 0 {
+		timeout = min(params.Timeout, mr.llmTimeoutMax)
+	}
+	// No else needed: early return pattern (no override)
+	if timeout <= 0 {
+		return base, false, nil
+	}
+	return timeout, timeout > base, nil
+}
+
+// sendKeepalive sends a loading message for a long-running reply that has
+// been silent for constants.LLMKeepaliveInterval, so proxies see traffic and
+// keep the connection open
+func (mr *MessageRouter) sendKeepalive(sessionID string, started time.Time) {
+	keepalive := &message.Message{
+		Type:      message.TypeLoading,
+		SessionID: sessionID,
+		Sender:    message.SenderAI,
+		Metadata: map[string]string{
+			constants.MetadataKeyKeepalive:      "true",
+			constants.MetadataKeyElapsedSeconds: strconv.Itoa(int(time.Since(started) / time.Second)),
+		},
+		Timestamp: time.Now(),
+	}
+	// No else needed: optional operation (the client may have disconnected)
+	if err := mr.sendToConnection(sessionID, keepalive); err != nil {
+		mr.logger.Debug("Failed to send keepalive", "session_id", sessionID, "error", err)
+	}
+}
@@ -0,0 +1,177 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlineLLMService records the time left before the deadline of each stream
+type deadlineLLMService struct {
+	mockLLMService
+	left time.Duration
+}
+
+func (m *deadlineLLMService) StreamMessage(ctx context.Context, modelID string, messages []llm.ChatMessage) (<-chan *llm.LLMChunk, error) {
+	deadline, _ := ctx.Deadline()
+	m.mu.Lock()
+	m.left = time.Until(deadline)
+	m.mu.Unlock()
+	return m.mockLLMService.StreamMessage(ctx, modelID, messages)
+}
+
+func newTimeoutTestRouter(t *testing.T, llmService LLMService) (*MessageRouter, *session.SessionManager) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, llmService, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	t.Cleanup(router.Shutdown)
+	return router, sm
+}
+
+func TestCheckLLMTimeout(t *testing.T) {
+	router, _ := newTimeoutTestRouter(t, &mockLLMService{})
+
+	timeout, err := router.checkLLMTimeout(0)
+	require.NoError(t, err)
+	assert.Zero(t, timeout)
+	_, err = router.checkLLMTimeout(300)
+	assert.Error(t, err, "overrides are not enabled")
+
+	router.SetLLMTimeoutBounds(0, 10*time.Minute)
+	assert.Equal(t, constants.DefaultMinLLMTimeout, router.llmTimeoutMin)
+	timeout, err = router.checkLLMTimeout(300)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, timeout)
+	for _, seconds := range []int{-1, 5, 601} {
+		_, err := router.checkLLMTimeout(seconds)
+		assert.Error(t, err, "%d seconds", seconds)
+	}
+}
+
+func TestStreamTimeout(t *testing.T) {
+	router, sm := newTimeoutTestRouter(t, &mockLLMService{})
+	router.SetLLMTimeoutBounds(10*time.Second, 10*time.Minute)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	msg := &message.Message{Type: message.TypeUserMessage, SessionID: sess.ID}
+
+	timeout, longRunning, err := router.streamTimeout(sess, msg)
+	require.NoError(t, err)
+	assert.Equal(t, 120*time.Second, timeout)
+	assert.False(t, longRunning)
+
+	require.NoError(t, sm.SetLLMParams(sess.ID, session.LLMParams{Timeout: 5 * time.Minute}))
+	timeout, longRunning, err = router.streamTimeout(sess, msg)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, timeout)
+	assert.True(t, longRunning)
+
+	// The message's own timeout wins over the session's
+	msg.Timeout = 30
+	timeout, longRunning, err = router.streamTimeout(sess, msg)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, timeout)
+	assert.False(t, longRunning)
+
+	msg.Timeout = 3600
+	_, _, err = router.streamTimeout(sess, msg)
+	assert.Error(t, err)
+
+	// Lowering the maximum caps sessions configured before; disabling
+	// overrides ignores them
+	msg.Timeout = 0
+	router.SetLLMTimeoutBounds(10*time.Second, 3*time.Minute)
+	timeout, _, err = router.streamTimeout(sess, msg)
+	require.NoError(t, err)
+	assert.Equal(t, 3*time.Minute, timeout)
+	router.SetLLMTimeoutBounds(0, 0)
+	timeout, _, err = router.streamTimeout(sess, msg)
+	require.NoError(t, err)
+	assert.Equal(t, 120*time.Second, timeout)
+}
+
+func TestHandleUserMessage_LongRunning(t *testing.T) {
+	llmMock := &deadlineLLMService{}
+	router, sm := newTimeoutTestRouter(t, llmMock)
+	router.SetLLMTimeoutBounds(10*time.Second, 10*time.Minute)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Analyse this document",
+		Timeout:   600,
+		Sender:    message.SenderUser,
+	}))
+	llmMock.mu.Lock()
+	assert.Greater(t, llmMock.left, 9*time.Minute)
+	llmMock.mu.Unlock()
+
+	var loading message.Message
+	require.NoError(t, json.Unmarshal(<-conn.ReceiveForTest(), &loading))
+	assert.Equal(t, message.TypeLoading, loading.Type)
+	assert.Equal(t, "600", loading.Metadata[constants.MetadataKeyTimeoutSeconds])
+
+	// Timeouts outside the bounds reject the message before it is stored
+	drainTypes(t, conn)
+	stored := len(sess.Messages)
+	err = router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Too long",
+		Timeout:   3600,
+		Sender:    message.SenderUser,
+	})
+	assert.Error(t, err)
+	assert.Len(t, sess.Messages, stored)
+}
+
+func TestHandleSessionConfig_Timeout(t *testing.T) {
+	router, sm := newTimeoutTestRouter(t, &mockLLMService{})
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	config := &message.Message{
+		Type:      message.TypeSessionConfig,
+		SessionID: sess.ID,
+		Config:    &message.SessionConfig{TimeoutSeconds: 300},
+		Sender:    message.SenderUser,
+	}
+	assert.Error(t, router.handleSessionConfig(conn, config), "overrides are not enabled")
+	assert.Nil(t, sess.GetLLMParams())
+
+	router.SetLLMTimeoutBounds(10*time.Second, 10*time.Minute)
+	require.NoError(t, router.handleSessionConfig(conn, config))
+	require.NotNil(t, sess.GetLLMParams())
+	assert.Equal(t, 5*time.Minute, sess.GetLLMParams().Timeout)
+}
+
+func TestSendKeepalive(t *testing.T) {
+	router, sm := newTimeoutTestRouter(t, &mockLLMService{})
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	router.sendKeepalive(sess.ID, time.Now().Add(-45*time.Second))
+	var keepalive message.Message
+	require.NoError(t, json.Unmarshal(<-conn.ReceiveForTest(), &keepalive))
+	assert.Equal(t, message.TypeLoading, keepalive.Type)
+	assert.Equal(t, "true", keepalive.Metadata[constants.MetadataKeyKeepalive])
+	assert.Equal(t, "45", keepalive.Metadata[constants.MetadataKeyElapsedSeconds])
+}
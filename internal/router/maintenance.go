@@ -0,0 +1,97 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/maintenance"
+	"github.com/real-rm/chatbox/internal/message"
+)
+
+// SetMaintenance sets the scheduled maintenance windows: new sessions are
+// refused during a window, and connected clients get a maintenance banner
+// every constants.MaintenanceAnnounceInterval from the schedule's notice period
+// before a window until it ends. Must be called before the router handles any
+// messages.
+func (mr *MessageRouter) SetMaintenance(schedule *maintenance.Schedule) {
+	mr.maintenance = schedule
+	// No else needed: early return pattern (no windows to announce)
+	if schedule == nil {
+		return
+	}
+
+	mr.safeGo("maintenanceAnnouncer", func() {
+		ticker := time.NewTicker(constants.MaintenanceAnnounceInterval)
+		defer ticker.Stop()
+		for {
+			mr.announceMaintenance(time.Now())
+			select {
+			case <-mr.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// checkMaintenance returns a MAINTENANCE error while a window is in progress,
+// retryable when it ends
+func (mr *MessageRouter) checkMaintenance(now time.Time) error {
+	window, active := mr.maintenance.Active(now)
+	// No else needed: early return pattern (no window in progress)
+	if !active {
+		return nil
+	}
+	return chaterrors.ErrMaintenance(window.Message, int(window.End.Sub(now).Milliseconds()))
+}
+
+// announceMaintenance sends the maintenance banner of the window announced at
+// now, if any, to every connected session. The countdown runs to the start of
+// the window, or to its end once it has started.
+func (mr *MessageRouter) announceMaintenance(now time.Time) {
+	window, announced := mr.maintenance.Announced(now)
+	// No else needed: early return pattern (nothing to announce)
+	if !announced {
+		return
+	}
+
+	started := !now.Before(window.Start)
+	deadline := window.Start
+	content := fmt.Sprintf("Scheduled maintenance starts at %s. %s", window.Start.UTC().Format(time.RFC3339), window.Message)
+	// No else needed: optional operation (count down to the end once started)
+	if started {
+		deadline = window.End
+		content = fmt.Sprintf("Scheduled maintenance until %s. %s", window.End.UTC().Format(time.RFC3339), window.Message)
+	}
+
+	mr.mu.RLock()
+	sessionIDs := make([]string, 0, len(mr.connections))
+	for sessionID := range mr.connections {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	mr.mu.RUnlock()
+
+	for _, sessionID := range sessionIDs {
+		banner := &message.Message{
+			Type:      message.TypeMaintenance,
+			SessionID: sessionID,
+			Content:   content,
+			Sender:    message.SenderSystem,
+			Timestamp: now,
+			Metadata: map[string]string{
+				"countdown_seconds": strconv.Itoa(int(deadline.Sub(now).Seconds())),
+				"deadline":          deadline.UTC().Format(time.RFC3339),
+				"starts_at":         window.Start.UTC().Format(time.RFC3339),
+				"ends_at":           window.End.UTC().Format(time.RFC3339),
+				"started":           strconv.FormatBool(started),
+			},
+		}
+		// No else needed: optional operation (the client may be disconnected)
+		if err := mr.sendToConnection(sessionID, banner); err != nil {
+			mr.logger.Debug("Maintenance banner not delivered", "session_id", sessionID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/maintenance"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance_RefusesNewSessionsAndAnnounces(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	userConn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, userConn))
+	drainTypes(t, userConn)
+
+	now := time.Now()
+	schedule, err := maintenance.NewSchedule([]maintenance.Window{
+		{Start: now.Add(-time.Minute), End: now.Add(time.Hour), Message: "Database upgrade"},
+	}, 30*time.Minute, "Back soon")
+	require.NoError(t, err)
+	router.SetMaintenance(schedule)
+
+	// The announcer sends a banner to connected sessions right away
+	var banner message.Message
+	select {
+	case data := <-userConn.ReceiveForTest():
+		require.NoError(t, json.Unmarshal(data, &banner))
+	case <-time.After(time.Second):
+		t.Fatal("no maintenance banner")
+	}
+	assert.Equal(t, message.TypeMaintenance, banner.Type)
+	assert.Equal(t, sess.ID, banner.SessionID)
+	assert.Equal(t, "true", banner.Metadata["started"])
+	assert.Contains(t, banner.Content, "Database upgrade")
+
+	// New sessions are refused until the window ends
+	_, err = router.createNewSession(mockConnection("user-2"), "")
+	var chatErr *chaterrors.ChatError
+	require.ErrorAs(t, err, &chatErr)
+	assert.Equal(t, chaterrors.ErrCodeMaintenance, chatErr.Code)
+	assert.Equal(t, "Database upgrade", chatErr.Message)
+	assert.Greater(t, chatErr.RetryAfter, 0)
+
+	// Before the notice period nothing is announced and sessions are created
+	router.announceMaintenance(now.Add(-time.Hour))
+	assert.Empty(t, drainTypes(t, userConn))
+	assert.NoError(t, router.checkMaintenance(now.Add(-time.Hour)))
+
+	// During the notice period the countdown runs to the start
+	router.announceMaintenance(now.Add(-11 * time.Minute))
+	select {
+	case data := <-userConn.ReceiveForTest():
+		require.NoError(t, json.Unmarshal(data, &banner))
+	default:
+		t.Fatal("no maintenance banner")
+	}
+	assert.Equal(t, "false", banner.Metadata["started"])
+	assert.Equal(t, "600", banner.Metadata["countdown_seconds"])
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/memory"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// MemoryStore keeps the facts users state about themselves across sessions
+// (implemented by storage.StorageService)
+type MemoryStore interface {
+	// RememberUserFacts stores facts of a user and returns the ones that were new
+	RememberUserFacts(tenantID, userID, sessionID string, facts []memory.Extracted) ([]*memory.Fact, error)
+	// UserMemory returns the facts remembered about a user, oldest first
+	UserMemory(tenantID, userID string) ([]*memory.Fact, error)
+}
+
+// SetMemory enables user memory: facts users state about themselves are
+// stored in store and rendered into the {{user_memory}} variable of prompt
+// templates. Must be called before the router handles any messages.
+func (mr *MessageRouter) SetMemory(store MemoryStore) {
+	mr.memory = store
+}
+
+// rememberFacts stores the facts a user message states in the background.
+// Messages an admin sent with an impersonation token are skipped, as they are
+// not the user's own words.
+func (mr *MessageRouter) rememberFacts(conn *websocket.Connection, sessionID, content string) {
+	// No else needed: early return pattern (memory disabled, or nothing to remember)
+	if mr.memory == nil || content == "" || conn.ImpersonatorID != "" {
+		return
+	}
+	facts := memory.Extract(content)
+	// No else needed: early return pattern (the message states no facts)
+	if len(facts) == 0 {
+		return
+	}
+	userID, tenantID := conn.UserID, conn.TenantID
+	mr.safeGo("rememberFacts", func() {
+		added, err := mr.memory.RememberUserFacts(tenantID, userID, sessionID, facts)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(mr.logger, "router", "remember user facts", err, "session_id", sessionID)
+			return
+		}
+		for _, f := range added {
+			metrics.MemoryFactsRemembered.WithLabelValues(f.Kind).Inc()
+		}
+	})
+}
+
+// userMemory renders the facts remembered about the user for the
+// {{user_memory}} prompt variable. Returns the empty string when memory is
+// disabled or cannot be read.
+func (mr *MessageRouter) userMemory(conn *websocket.Connection, sessionID string) string {
+	// No else needed: early return pattern (memory disabled)
+	if mr.memory == nil {
+		return ""
+	}
+	facts, err := mr.memory.UserMemory(conn.TenantID, conn.UserID)
+	// No else needed: early return pattern (the prompt is sent without memory)
+	if err != nil {
+		util.LogError(mr.logger, "router", "load user memory", err, "session_id", sessionID)
+		return ""
+	}
+	return memory.Render(facts)
+}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/memory"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMemoryStore keeps facts in memory per tenant and user
+type fakeMemoryStore struct {
+	mu    sync.Mutex
+	facts map[string][]*memory.Fact
+}
+
+func (f *fakeMemoryStore) RememberUserFacts(tenantID, userID, sessionID string, facts []memory.Extracted) ([]*memory.Fact, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var added []*memory.Fact
+	for _, e := range facts {
+		fact := &memory.Fact{Kind: e.Kind, Text: e.Text, SessionID: sessionID, CreatedAt: time.Now()}
+		f.facts[tenantID+"/"+userID] = append(f.facts[tenantID+"/"+userID], fact)
+		added = append(added, fact)
+	}
+	return added, nil
+}
+
+func (f *fakeMemoryStore) UserMemory(tenantID, userID string) ([]*memory.Fact, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.facts[tenantID+"/"+userID], nil
+}
+
+func TestUserMemory_RememberedAndRendered(t *testing.T) {
+	router, llmMock, _ := newPromptTestRouter(t)
+	router.SetPromptRenderer(prompt.NewService([]*prompt.Template{
+		{ID: "support", Name: "Support", Content: "Known about the user:\n{{user_memory}}", ReadOnly: true},
+	}, nil))
+	store := &fakeMemoryStore{facts: map[string][]*memory.Fact{}}
+	router.SetMemory(store)
+
+	conn := mockConnection("user-1")
+	conn.TenantID = "acme"
+	require.NoError(t, router.RegisterConnection("new-session", conn))
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: "new-session",
+		Content:   "Hi, my name is Anna. I prefer short answers.",
+		Sender:    message.SenderUser,
+		Metadata:  map[string]string{constants.MetadataKeyPromptTemplate: "support"},
+	}))
+	router.wg.Wait()
+
+	facts, _ := store.UserMemory("acme", "user-1")
+	require.Len(t, facts, 2)
+	assert.Equal(t, memory.KindName, facts[0].Kind)
+	assert.Equal(t, "Anna", facts[0].Text)
+	assert.Equal(t, conn.GetSessionID(), facts[0].SessionID)
+
+	// The next prompt includes what was remembered
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: conn.GetSessionID(),
+		Content:   "What should I cook?",
+		Sender:    message.SenderUser,
+	}))
+	router.wg.Wait()
+	llmMock.mu.Lock()
+	system := llmMock.lastMessages[0]
+	llmMock.mu.Unlock()
+	assert.Equal(t, constants.SenderSystem, system.Role)
+	assert.Equal(t, "Known about the user:\n- Name: Anna\n- Preference: short answers", system.Content)
+}
+
+func TestUserMemory_SkipsImpersonatedMessages(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &streamingMockLLMService{chunks: []string{"Noted."}}, nil, nil, nil, 120*time.Second, logger)
+	t.Cleanup(func() { router.Shutdown() })
+	store := &fakeMemoryStore{facts: map[string][]*memory.Fact{}}
+	router.SetMemory(store)
+
+	sendUserMessage(t, router, sm, "acme", "My name is Mallory.", true)
+	facts, _ := store.UserMemory("acme", "user-1")
+	assert.Empty(t, facts)
+
+	sendUserMessage(t, router, sm, "acme", "My name is Anna.", false)
+	facts, _ = store.UserMemory("acme", "user-1")
+	require.Len(t, facts, 1)
+	assert.Equal(t, "Anna", facts[0].Text)
+}
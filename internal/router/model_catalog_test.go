@@ -0,0 +1,51 @@
+package router
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// catalogLLMService is an LLM service with a fixed model catalog
+type catalogLLMService struct {
+	mockLLMService
+	catalog []string
+}
+
+func (m *catalogLLMService) ValidateModel(modelID string) error {
+	for _, id := range m.catalog {
+		if id == modelID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", llm.ErrProviderNotFound, modelID)
+}
+
+func (m *catalogLLMService) GetAvailableModels() []llm.ModelInfo {
+	models := make([]llm.ModelInfo, 0, len(m.catalog))
+	for _, id := range m.catalog {
+		models = append(models, llm.ModelInfo{ID: id, Name: id})
+	}
+	return models
+}
+
+func TestResolveModel(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+
+	withDefault := NewMessageRouter(sm, &catalogLLMService{catalog: []string{"claude", "gpt-4"}}, nil, nil, nil, 120*time.Second, logger)
+	defer withDefault.Shutdown()
+	assert.Equal(t, "claude", withDefault.resolveModel("s1", "claude"))
+	assert.Equal(t, "gpt-4", withDefault.resolveModel("s1", ""))
+	assert.Equal(t, "gpt-4", withDefault.resolveModel("s1", "removed-model"))
+
+	// Without the default model in the catalog, the first catalog model is used
+	withoutDefault := NewMessageRouter(sm, &catalogLLMService{catalog: []string{"claude", "mistral"}}, nil, nil, nil, 120*time.Second, logger)
+	defer withoutDefault.Shutdown()
+	assert.Equal(t, "claude", withoutDefault.resolveModel("s1", ""))
+	assert.Equal(t, "mistral", withoutDefault.resolveModel("s1", "mistral"))
+}
@@ -0,0 +1,99 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/moderation"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// Moderator screens message content (to avoid coupling the router to a concrete pipeline)
+type Moderator interface {
+	Moderate(ctx context.Context, direction moderation.Direction, content string) *moderation.Result
+}
+
+// SetModerator enables content moderation of user messages before they are stored
+// or sent to the LLM, and of AI responses before they are stored.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetModerator(moderator Moderator) {
+	mr.moderator = moderator
+}
+
+// moderate runs content through the moderator. Returns nil when no moderator is
+// configured or the content passed unchanged and unflagged. Redactions and blocks
+// are recorded as redaction system events in the session transcript.
+func (mr *MessageRouter) moderate(sessionID string, direction moderation.Direction, content string) *moderation.Result {
+	// No else needed: early return pattern (moderation not configured)
+	if mr.moderator == nil {
+		return nil
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultModerationTimeout)
+	defer cancel()
+
+	result := mr.moderator.Moderate(ctx, direction, content)
+	// No else needed: early return pattern (content allowed)
+	if result == nil || result.Action == moderation.ActionAllow {
+		return nil
+	}
+
+	// No else needed: optional operation (flagged content is kept as-is)
+	if result.Action != moderation.ActionFlag {
+		mr.RecordSystemEvent(sessionID, constants.SystemEventRedaction,
+			"Message content removed by moderation",
+			map[string]string{
+				"direction":                               string(direction),
+				constants.MetadataKeyModeration:           result.Action.String(),
+				constants.MetadataKeyModerationCategories: strings.Join(result.Categories, ","),
+			})
+	}
+	return result
+}
+
+// withModeration returns a copy of metadata annotated with a moderation result
+func withModeration(metadata map[string]string, result *moderation.Result) map[string]string {
+	annotated := make(map[string]string, len(metadata)+2)
+	for k, v := range metadata {
+		annotated[k] = v
+	}
+	annotated[constants.MetadataKeyModeration] = result.Action.String()
+	// No else needed: optional operation (filters may not report categories)
+	if len(result.Categories) > 0 {
+		annotated[constants.MetadataKeyModerationCategories] = strings.Join(result.Categories, ",")
+	}
+	return annotated
+}
+
+// moderateAIResponse screens an AI response before it is stored. Returns the content
+// and metadata to store. When streamed content was redacted or blocked, the client is
+// told to replace the response it already displayed.
+func (mr *MessageRouter) moderateAIResponse(sessionID, content string, metadata map[string]string, streamed bool) (string, map[string]string) {
+	result := mr.moderate(sessionID, moderation.DirectionOutput, content)
+	// No else needed: early return pattern (response allowed)
+	if result == nil {
+		return content, metadata
+	}
+
+	// No else needed: optional operation (only already-streamed, altered content needs a correction)
+	if streamed && result.Action != moderation.ActionFlag {
+		notice := &message.Message{
+			Type:      message.TypeNotification,
+			SessionID: sessionID,
+			Content:   result.Content,
+			Sender:    message.SenderSystem,
+			Timestamp: time.Now(),
+			Metadata: map[string]string{
+				constants.MetadataKeyModeration: result.Action.String(),
+			},
+		}
+		// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
+		if err := mr.sendToConnection(sessionID, notice); err != nil {
+			mr.logger.Warn("Failed to send moderation notice", "session_id", sessionID, "error", err)
+		}
+	}
+	return result.Content, withModeration(metadata, result)
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/moderation"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newModerationTestRouter creates a router moderating with a denylist of "forbidden" and the given action
+func newModerationTestRouter(t *testing.T, action moderation.Action, llmMock LLMService) (*MessageRouter, *session.SessionManager) {
+	t.Helper()
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, llmMock, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	filter, err := moderation.NewDenylistFilter([]string{"forbidden"})
+	require.NoError(t, err)
+	router.SetModerator(moderation.NewPipeline(logger, moderation.Stage{Filter: filter, Action: action}))
+	t.Cleanup(router.Shutdown)
+	return router, sm
+}
+
+func TestModeration_BlockedInputNeverReachesLLM(t *testing.T) {
+	llmMock := &mockLLMService{}
+	router, sm := newModerationTestRouter(t, moderation.ActionBlock, llmMock)
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	msg := &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "something forbidden",
+		Sender:    message.SenderUser,
+	}
+	require.NoError(t, router.HandleUserMessage(conn, msg))
+
+	assert.False(t, llmMock.streamCalled, "blocked messages must not reach the LLM")
+	assert.Equal(t, []message.MessageType{message.TypeError}, drainTypes(t, conn))
+	// Only the redaction event is stored, never the blocked content
+	require.Len(t, sess.Messages, 1)
+	assert.Equal(t, constants.SystemEventRedaction, sess.Messages[0].Event)
+}
+
+func TestModeration_RedactedInputIsStoredAndSentRedacted(t *testing.T) {
+	llmMock := &mockLLMService{}
+	router, sm := newModerationTestRouter(t, moderation.ActionRedact, llmMock)
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	msg := &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "something forbidden",
+		Sender:    message.SenderUser,
+	}
+	require.NoError(t, router.HandleUserMessage(conn, msg))
+
+	require.True(t, llmMock.streamCalled)
+	require.Len(t, llmMock.lastMessages, 1)
+	assert.Equal(t, "something [redacted]", llmMock.lastMessages[0].Content)
+
+	var userMsg *session.Message
+	for _, m := range sess.Messages {
+		if m.Sender == constants.SenderUser {
+			userMsg = m
+		}
+	}
+	require.NotNil(t, userMsg)
+	assert.Equal(t, "something [redacted]", userMsg.Content)
+	assert.Equal(t, "redact", userMsg.Metadata[constants.MetadataKeyModeration])
+}
+
+func TestModeration_FlaggedOutputIsAnnotated(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+	filter, err := moderation.NewDenylistFilter([]string{"mock chunk"})
+	require.NoError(t, err)
+	router.SetModerator(moderation.NewPipeline(logger, moderation.Stage{Filter: filter, Action: moderation.ActionFlag}))
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	msg := &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "hello",
+		Sender:    message.SenderUser,
+	}
+	require.NoError(t, router.HandleUserMessage(conn, msg))
+
+	aiMsg := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, constants.SenderAI, aiMsg.Sender)
+	assert.Equal(t, "Mock chunk", aiMsg.Content)
+	assert.Equal(t, "flag", aiMsg.Metadata[constants.MetadataKeyModeration])
+	assert.Equal(t, "denylist", aiMsg.Metadata[constants.MetadataKeyModerationCategories])
+}
@@ -0,0 +1,204 @@
+package router
+
+import (
+	"time"
+
+	"github.com/real-rm/chatbox/internal/cluster"
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// SetMultiDevice enables binding several connections to one session, so a user
+// connected from several devices sees the same conversation on all of them.
+// Outbound session messages fan out to every device; the device a client
+// message last came from is the session's active device (last writer wins).
+// When disabled, a new connection for a session replaces the previous one.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetMultiDevice(enabled bool) {
+	mr.multiDevice = enabled
+}
+
+// UnregisterDevice removes conn from a session, leaving the session's other
+// devices bound. When conn was the active device, one of the remaining devices
+// takes over until the user writes from another. Unregistering a connection
+// that was already replaced does nothing.
+func (mr *MessageRouter) UnregisterDevice(sessionID string, conn *websocket.Connection) {
+	mr.mu.Lock()
+	devices := mr.devices[sessionID]
+	delete(devices, conn)
+	// No else needed: optional operation (drop empty device sets)
+	if len(devices) == 0 {
+		delete(mr.devices, sessionID)
+	}
+
+	// No else needed: early return pattern (another device is active)
+	if mr.connections[sessionID] != conn {
+		mr.mu.Unlock()
+		return
+	}
+	delete(mr.connections, sessionID)
+	for device := range devices {
+		mr.connections[sessionID] = device
+		break
+	}
+	_, connected := mr.connections[sessionID]
+	mr.mu.Unlock()
+
+	// No else needed: optional operation (the session's last device left)
+	if !connected {
+		mr.releaseConnection(cluster.SessionKey(sessionID))
+	}
+}
+
+// bindDevice adds conn to the devices of a session and makes it the active
+// device. Caller must hold mr.mu.
+func (mr *MessageRouter) bindDevice(sessionID string, conn *websocket.Connection) {
+	devices, ok := mr.devices[sessionID]
+	// No else needed: optional operation (first device of the session)
+	if !ok {
+		devices = make(map[*websocket.Connection]struct{})
+		mr.devices[sessionID] = devices
+	}
+	devices[conn] = struct{}{}
+	mr.connections[sessionID] = conn
+}
+
+// rebindConnection moves conn, and the devices bound with it, from the session
+// ID the client sent to the authoritative session ID the server resolved
+func (mr *MessageRouter) rebindConnection(conn *websocket.Connection, from, to string) {
+	mr.mu.Lock()
+	for device := range mr.devices[from] {
+		mr.bindDevice(to, device)
+		device.SetSessionID(to)
+	}
+	delete(mr.devices, from)
+	// No else needed: optional operation (connection may not be registered yet)
+	c, registered := mr.connections[from]
+	if registered {
+		delete(mr.connections, from)
+		mr.connections[to] = c
+	}
+	mr.mu.Unlock()
+
+	conn.SetSessionID(to)
+	// No else needed: optional operation (connection may not be registered yet)
+	if registered {
+		mr.releaseConnection(cluster.SessionKey(from))
+		mr.claimConnection(cluster.SessionKey(to))
+	}
+}
+
+// markActiveDevice makes conn the active device of a multi-device session:
+// typing and compose state follow the device the user last wrote from
+func (mr *MessageRouter) markActiveDevice(conn *websocket.Connection, sessionID string) {
+	// No else needed: early return pattern (single connection per session)
+	if !mr.multiDevice {
+		return
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	// No else needed: optional operation (only devices bound to the session)
+	if _, ok := mr.devices[sessionID][conn]; ok {
+		mr.connections[sessionID] = conn
+	}
+}
+
+// otherDevices returns the devices of a session other than conn
+func (mr *MessageRouter) otherDevices(sessionID string, conn *websocket.Connection) []*websocket.Connection {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	others := make([]*websocket.Connection, 0, len(mr.devices[sessionID]))
+	for device := range mr.devices[sessionID] {
+		// No else needed: optional operation (skip the excluded device)
+		if device != conn {
+			others = append(others, device)
+		}
+	}
+	return others
+}
+
+// sendToDevices sends pre-marshaled bytes to the devices of a session other than
+// conn. Secondary devices are best-effort like admin connections: a full buffer
+// drops the message.
+func (mr *MessageRouter) sendToDevices(sessionID string, conn *websocket.Connection, data []byte) {
+	for _, device := range mr.otherDevices(sessionID, conn) {
+		// No else needed: optional operation (fire-and-forget)
+		if !device.SafeSend(data) {
+			mr.logger.Warn("Device send channel full or closing",
+				"session_id", sessionID,
+				"connection_id", device.ConnectionID)
+		}
+	}
+}
+
+// handleTypingIndicator relays a typing indicator from one of the user's devices
+// to the session's other devices and the assisting admin. The sending device
+// becomes the active device, so the latest indicator always wins.
+func (mr *MessageRouter) handleTypingIndicator(conn *websocket.Connection, msg *message.Message) error {
+	if conn == nil {
+		return ErrNilConnection
+	}
+	if msg == nil {
+		return ErrNilMessage
+	}
+
+	// Validate session ID
+	if msg.SessionID == "" {
+		return chaterrors.ErrMissingField("session_id")
+	}
+
+	// Verify session exists and ownership
+	sess, err := mr.sessionManager.GetSession(msg.SessionID)
+	if err != nil {
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	if !ownsSession(conn, sess) {
+		mr.logger.Warn("Session ownership violation in typing indicator",
+			"session_id", msg.SessionID,
+			"session_owner", sess.UserID,
+			"requesting_user", conn.UserID)
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeUnauthorized,
+			"You do not have permission to access this session",
+			nil,
+		)
+	}
+
+	metadata := make(map[string]string, len(msg.Metadata)+1)
+	for k, v := range msg.Metadata {
+		metadata[k] = v
+	}
+	metadata[constants.MetadataKeyConnectionID] = conn.ConnectionID
+
+	// Typing indicators are transient: they skip the replay buffer and watchers
+	data, err := util.MarshalJSON(&message.Message{
+		Type:      message.TypeTypingIndicator,
+		SessionID: msg.SessionID,
+		Sender:    message.SenderUser,
+		Metadata:  metadata,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return chaterrors.ErrInvalidMessageFormat("failed to marshal typing indicator", err)
+	}
+	mr.sendToDevices(msg.SessionID, conn, data)
+
+	// No else needed: optional operation, only relay if an admin is assisting
+	if assistingAdminID := sess.GetAssistingAdminID(); assistingAdminID != "" {
+		mr.mu.RLock()
+		adminConn, exists := mr.adminConns[assistingAdminID+":"+msg.SessionID]
+		mr.mu.RUnlock()
+		// No else needed: optional operation (fire-and-forget)
+		if exists && !adminConn.SafeSend(data) {
+			metrics.AdminMessagesDropped.Inc()
+		}
+	}
+	return nil
+}
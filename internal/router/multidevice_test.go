@@ -0,0 +1,147 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMultiDeviceRouter returns a multi-device router with a session of user-1
+// bound to a phone and a laptop connection, in that order
+func newMultiDeviceRouter(t *testing.T) (*MessageRouter, *session.Session, *websocket.Connection, *websocket.Connection) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	router.SetMultiDevice(true)
+	t.Cleanup(router.Shutdown)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	phone := mockConnection("user-1")
+	phone.ConnectionID = "phone"
+	laptop := mockConnection("user-1")
+	laptop.ConnectionID = "laptop"
+	require.NoError(t, router.RegisterConnection(sess.ID, phone))
+	require.NoError(t, router.RegisterConnection(sess.ID, laptop))
+	drainTypes(t, phone)
+	drainTypes(t, laptop)
+	return router, sess, phone, laptop
+}
+
+func TestMultiDevice_FansOutToEveryDevice(t *testing.T) {
+	router, sess, phone, laptop := newMultiDeviceRouter(t)
+
+	active, err := router.GetConnection(sess.ID)
+	require.NoError(t, err)
+	assert.Same(t, laptop, active, "the latest device is active")
+
+	require.NoError(t, router.sendToConnection(sess.ID, &message.Message{
+		Type:      message.TypeAIResponse,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderAI,
+	}))
+	assert.Equal(t, []message.MessageType{message.TypeAIResponse}, drainTypes(t, phone))
+	assert.Equal(t, []message.MessageType{message.TypeAIResponse}, drainTypes(t, laptop))
+}
+
+func TestMultiDevice_UserMessageMirroredToOtherDevices(t *testing.T) {
+	router, sess, phone, laptop := newMultiDeviceRouter(t)
+	router.SetHumanOnlyMode(true)
+
+	require.NoError(t, router.RouteMessage(phone, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hi from my phone",
+		Sender:    message.SenderUser,
+	}))
+
+	active, err := router.GetConnection(sess.ID)
+	require.NoError(t, err)
+	assert.Same(t, phone, active, "the last writer becomes the active device")
+	assert.NotContains(t, drainTypes(t, phone), message.TypeUserMessage)
+	assert.Contains(t, drainTypes(t, laptop), message.TypeUserMessage)
+}
+
+func TestMultiDevice_TypingIndicatorRelayedToOtherDevices(t *testing.T) {
+	router, sess, phone, laptop := newMultiDeviceRouter(t)
+
+	require.NoError(t, router.RouteMessage(phone, &message.Message{
+		Type:      message.TypeTypingIndicator,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+		Metadata:  map[string]string{"typing": "true"},
+	}))
+
+	assert.Empty(t, drainTypes(t, phone), "the typing device gets no echo")
+	select {
+	case data := <-laptop.ReceiveForTest():
+		var msg message.Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, message.TypeTypingIndicator, msg.Type)
+		assert.Equal(t, "phone", msg.Metadata[constants.MetadataKeyConnectionID])
+		assert.Equal(t, "true", msg.Metadata["typing"])
+	default:
+		t.Fatal("typing indicator not relayed to the other device")
+	}
+
+	active, err := router.GetConnection(sess.ID)
+	require.NoError(t, err)
+	assert.Same(t, phone, active, "the typing device becomes the active device")
+
+	other := mockConnection("user-2")
+	err = router.RouteMessage(other, &message.Message{
+		Type:      message.TypeTypingIndicator,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+	})
+	assert.Error(t, err, "indicators for sessions of other users are rejected")
+}
+
+func TestMultiDevice_UnregisterDeviceKeepsOtherDevices(t *testing.T) {
+	router, sess, phone, laptop := newMultiDeviceRouter(t)
+
+	router.UnregisterDevice(sess.ID, laptop)
+	active, err := router.GetConnection(sess.ID)
+	require.NoError(t, err)
+	assert.Same(t, phone, active, "a remaining device takes over")
+
+	require.NoError(t, router.sendToConnection(sess.ID, &message.Message{
+		Type:      message.TypeAIResponse,
+		SessionID: sess.ID,
+		Sender:    message.SenderAI,
+	}))
+	assert.Len(t, drainTypes(t, phone), 1)
+	assert.Empty(t, drainTypes(t, laptop))
+
+	router.UnregisterDevice(sess.ID, phone)
+	_, err = router.GetConnection(sess.ID)
+	assert.ErrorIs(t, err, ErrConnectionNotFound)
+}
+
+func TestSingleDevice_UnregisterReplacedConnectionKeepsNewOne(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	oldConn := mockConnection("user-1")
+	newConn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, oldConn))
+	require.NoError(t, router.RegisterConnection(sess.ID, newConn))
+
+	// The replaced connection's read loop ends after the new one registered
+	router.UnregisterDevice(sess.ID, oldConn)
+	active, err := router.GetConnection(sess.ID)
+	require.NoError(t, err)
+	assert.Same(t, newConn, active)
+}
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// PromptRenderer renders system prompt templates (to avoid coupling the router to the template store)
+type PromptRenderer interface {
+	Render(tenantID, templateID string, vars map[string]string) (string, error)
+}
+
+// SetPromptRenderer enables selecting a system prompt template when a session is
+// created. Must be called before the router handles any messages.
+func (mr *MessageRouter) SetPromptRenderer(renderer PromptRenderer) {
+	mr.prompts = renderer
+}
+
+// promptTemplateID returns the template a message selects for the session it creates
+func promptTemplateID(msg *message.Message) string {
+	return msg.Metadata[constants.MetadataKeyPromptTemplate]
+}
+
+// validatePromptTemplate checks that a template selected for a new session exists
+// for the user's tenant
+func (mr *MessageRouter) validatePromptTemplate(conn *websocket.Connection, templateID string) error {
+	// No else needed: early return pattern (no template selected)
+	if templateID == "" {
+		return nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if mr.prompts == nil {
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeInvalidFormat,
+			"Prompt templates are not enabled",
+			nil,
+		)
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := mr.prompts.Render(conn.TenantID, templateID, nil); err != nil {
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeInvalidFormat,
+			"Unknown prompt template: "+templateID,
+			err,
+		)
+	}
+	return nil
+}
+
+// systemPrompt renders the session's prompt template for the user as the system
+// message that precedes the conversation. Returns nil when the session has no
+// template or it can no longer be rendered, e.g. because it was deleted.
+func (mr *MessageRouter) systemPrompt(conn *websocket.Connection, sess *session.Session) *llm.ChatMessage {
+	templateID := sess.GetPromptTemplateID()
+	// No else needed: early return pattern (no template selected)
+	if templateID == "" || mr.prompts == nil {
+		return nil
+	}
+
+	content, err := mr.prompts.Render(sess.TenantID, templateID, map[string]string{
+		prompt.VarUserName:   conn.Name,
+		prompt.VarUserID:     conn.UserID,
+		prompt.VarTenant:     sess.TenantID,
+		prompt.VarUserMemory: mr.userMemory(conn, sess.ID),
+	})
+	// No else needed: early return pattern (session continues without a system prompt)
+	if err != nil {
+		mr.logger.Warn("Failed to render prompt template",
+			"session_id", sess.ID,
+			"template_id", templateID,
+			"error", err)
+		return nil
+	}
+	return &llm.ChatMessage{Role: constants.SenderSystem, Content: content}
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPromptTestRouter(t *testing.T) (*MessageRouter, *mockLLMService, *mockStorageService) {
+	t.Helper()
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockLLMService{}
+	storage := &mockStorageService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, storage, 120*time.Second, logger)
+	t.Cleanup(router.Shutdown)
+	return router, llmMock, storage
+}
+
+func TestPromptTemplate_SelectedAtCreation(t *testing.T) {
+	router, llmMock, storage := newPromptTestRouter(t)
+	router.SetPromptRenderer(prompt.NewService([]*prompt.Template{
+		{ID: "support", Name: "Support", Content: "You help {{user_name}} ({{user_id}}).", ReadOnly: true},
+	}, nil))
+
+	conn := mockConnection("user-1")
+	conn.Name = "Alice"
+	require.NoError(t, router.RegisterConnection("new-session", conn))
+
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: "new-session",
+		Content:   "hello",
+		Sender:    message.SenderUser,
+		Metadata:  map[string]string{constants.MetadataKeyPromptTemplate: "support"},
+	}))
+
+	require.Len(t, storage.createdSessions, 1)
+	assert.Equal(t, "support", storage.createdSessions[0].PromptTemplateID, "template is persisted with the new session")
+
+	llmMock.mu.Lock()
+	messages := llmMock.lastMessages
+	llmMock.mu.Unlock()
+	require.Len(t, messages, 2)
+	assert.Equal(t, llm.ChatMessage{Role: constants.SenderSystem, Content: "You help Alice (user-1)."}, messages[0])
+	assert.Equal(t, constants.SenderUser, messages[1].Role)
+
+	// Later messages keep the template; selecting another one has no effect
+	sessionID := conn.GetSessionID()
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sessionID,
+		Content:   "again",
+		Sender:    message.SenderUser,
+		Metadata:  map[string]string{constants.MetadataKeyPromptTemplate: "unknown"},
+	}))
+	llmMock.mu.Lock()
+	assert.Equal(t, constants.SenderSystem, llmMock.lastMessages[0].Role)
+	llmMock.mu.Unlock()
+}
+
+func TestPromptTemplate_InvalidSelection(t *testing.T) {
+	router, _, storage := newPromptTestRouter(t)
+	conn := mockConnection("user-1")
+
+	// Templates are not enabled
+	_, err := router.createNewSession(conn, "support")
+	assert.Error(t, err)
+
+	router.SetPromptRenderer(prompt.NewService(nil, nil))
+	_, err = router.createNewSession(conn, "support")
+	assert.Error(t, err)
+	assert.Empty(t, storage.createdSessions, "no session is created for an unknown template")
+
+	sess, err := router.createNewSession(conn, "")
+	require.NoError(t, err)
+	assert.Empty(t, sess.GetPromptTemplateID())
+}
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"time"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// SetReadReceipts enables read receipts: when the user's client reports that
+// the conversation was seen, the admin replies not read yet get a read_at
+// timestamp, which is stored and relayed to the assisting admin. Deployments
+// that must not track when users read messages leave it disabled, and receipts
+// are then dropped. Must be called before the router handles any messages.
+func (mr *MessageRouter) SetReadReceipts(enabled bool) {
+	mr.readReceipts = enabled
+}
+
+// handleReadReceipt marks the session's unread admin messages as read by the
+// user and tells the assisting admin
+func (mr *MessageRouter) handleReadReceipt(conn *websocket.Connection, msg *message.Message) error {
+	if conn == nil {
+		return ErrNilConnection
+	}
+	if msg == nil {
+		return ErrNilMessage
+	}
+
+	// Validate session ID
+	if msg.SessionID == "" {
+		return chaterrors.ErrMissingField("session_id")
+	}
+
+	// No else needed: early return pattern (read receipts disabled)
+	if !mr.readReceipts {
+		mr.logger.Debug("Read receipt dropped, read receipts disabled", "session_id", msg.SessionID)
+		return nil
+	}
+
+	// Verify session exists and ownership
+	sess, err := mr.sessionManager.GetSession(msg.SessionID)
+	if err != nil {
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	if !ownsSession(conn, sess) {
+		mr.logger.Warn("Session ownership violation in read receipt",
+			"session_id", msg.SessionID,
+			"session_owner", sess.UserID,
+			"requesting_user", conn.UserID)
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeUnauthorized,
+			"You do not have permission to access this session",
+			nil,
+		)
+	}
+
+	now := time.Now()
+	marked, err := mr.sessionManager.MarkAdminMessagesRead(msg.SessionID, now)
+	if err != nil {
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	// No else needed: early return pattern (every admin message already read)
+	if len(marked) == 0 {
+		return nil
+	}
+
+	// No else needed: optional operation (storage not configured)
+	if mr.storageService != nil {
+		// No else needed: optional operation (the receipt is kept in memory)
+		if err := mr.storageService.MarkMessagesRead(msg.SessionID, marked, now); err != nil {
+			mr.logger.Warn("Failed to persist read receipt",
+				"session_id", msg.SessionID,
+				"messages", len(marked),
+				"error", err)
+		}
+	}
+
+	assistingAdminID := sess.GetAssistingAdminID()
+	// No else needed: early return pattern (no admin to notify)
+	if assistingAdminID == "" {
+		return nil
+	}
+	mr.mu.RLock()
+	adminConn, exists := mr.adminConns[assistingAdminID+":"+msg.SessionID]
+	mr.mu.RUnlock()
+	// No else needed: early return pattern (admin not connected)
+	if !exists {
+		return nil
+	}
+
+	// Read receipts are transient: they skip the replay buffer and watchers
+	data, err := util.MarshalJSON(&message.Message{
+		Type:      message.TypeReadReceipt,
+		SessionID: msg.SessionID,
+		Sender:    message.SenderUser,
+		ReadAt:    &now,
+		Timestamp: now,
+	})
+	if err != nil {
+		return chaterrors.ErrInvalidMessageFormat("failed to marshal read receipt", err)
+	}
+	// No else needed: optional operation (fire-and-forget)
+	if !adminConn.SafeSend(data) {
+		metrics.AdminMessagesDropped.Inc()
+	}
+	return nil
+}
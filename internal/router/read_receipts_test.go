@@ -0,0 +1,61 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleReadReceipt(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	storage := &mockStorageService{}
+	router := NewMessageRouter(sm, nil, nil, nil, storage, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	userConn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, userConn))
+
+	adminConn := mockConnection("admin-1")
+	adminConn.Roles = []string{"admin"}
+	require.NoError(t, router.HandleAdminTakeover(adminConn, sess.ID))
+	require.NoError(t, router.RegisterAdminConnection("admin-1", sess.ID, adminConn))
+	require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "Help", Sender: constants.SenderUser}))
+	require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "On it", Sender: constants.SenderAdmin}))
+	drainTypes(t, userConn)
+	drainTypes(t, adminConn)
+
+	receipt := &message.Message{Type: message.TypeReadReceipt, SessionID: sess.ID, Sender: message.SenderUser}
+
+	// Disabled by default: receipts are dropped
+	require.NoError(t, router.handleReadReceipt(userConn, receipt))
+	assert.Empty(t, drainTypes(t, adminConn))
+	assert.Nil(t, sess.Messages[1].ReadAt)
+
+	router.SetReadReceipts(true)
+	require.NoError(t, router.handleReadReceipt(userConn, receipt))
+	assert.Equal(t, []message.MessageType{message.TypeReadReceipt}, drainTypes(t, adminConn))
+	assert.Nil(t, sess.Messages[0].ReadAt, "user messages are never marked")
+	require.NotNil(t, sess.Messages[1].ReadAt)
+	assert.Equal(t, []int{1}, storage.readIndexes, "read receipts are persisted")
+
+	// Nothing new to mark: the admin is not told again
+	require.NoError(t, router.handleReadReceipt(userConn, receipt))
+	assert.Empty(t, drainTypes(t, adminConn))
+	assert.Equal(t, []int{1}, storage.readIndexes)
+
+	// Only the session owner can send read receipts
+	require.NoError(t, sm.AddMessage(sess.ID, &session.Message{Content: "Anything else?", Sender: constants.SenderAdmin}))
+	assert.Error(t, router.handleReadReceipt(mockConnection("user-2"), receipt))
+	assert.Nil(t, sess.Messages[2].ReadAt)
+
+	// Missing session ID
+	assert.Error(t, router.handleReadReceipt(userConn, &message.Message{Type: message.TypeReadReceipt}))
+}
@@ -0,0 +1,81 @@
+package router
+
+import (
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/readonly"
+)
+
+// SetReadOnly sets the admin read-only mode: while it is on, new sessions and
+// the messages that write to a session are refused with a READ_ONLY error.
+// When load is not nil, the stored mode is reloaded every
+// constants.ReadOnlySyncInterval and adopted when newer, so a toggle on another
+// replica reaches this one. Must be called before the router handles any
+// messages.
+func (mr *MessageRouter) SetReadOnly(mode *readonly.Mode, load func() (readonly.State, error)) {
+	mr.readOnly = mode
+	// No else needed: early return pattern (nothing to sync from)
+	if mode == nil || load == nil {
+		return
+	}
+
+	mr.safeGo("readOnlySync", func() {
+		ticker := time.NewTicker(constants.ReadOnlySyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-mr.ctx.Done():
+				return
+			case <-ticker.C:
+				mr.syncReadOnly(load)
+			}
+		}
+	})
+}
+
+// syncReadOnly adopts the stored read-only mode when it is newer than the
+// current one. A failed load keeps the current mode.
+func (mr *MessageRouter) syncReadOnly(load func() (readonly.State, error)) {
+	state, err := load()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		mr.logger.Warn("Failed to reload read-only mode", "error", err)
+		return
+	}
+	// No else needed: optional operation (log only changes)
+	if mr.readOnly.Sync(state) {
+		mr.logger.Info("Read-only mode changed",
+			"enabled", state.Enabled,
+			"updated_by", state.UpdatedBy)
+	}
+}
+
+// checkReadOnly returns a READ_ONLY error while the read-only mode is on
+func (mr *MessageRouter) checkReadOnly() error {
+	msg, enabled := mr.readOnly.Enabled()
+	// No else needed: early return pattern (not read-only)
+	if !enabled {
+		return nil
+	}
+	// No else needed: conditional assignment (default message)
+	if msg == "" {
+		msg = constants.DefaultReadOnlyMessage
+	}
+	return chaterrors.ErrReadOnly(msg)
+}
+
+// writesSession reports whether a client message of type t adds to a session,
+// and so is refused while read-only. Model selection, session config, typing
+// indicators and read receipts are still handled.
+func writesSession(t message.MessageType) bool {
+	switch t {
+	case message.TypeUserMessage, message.TypeHelpRequest, message.TypeFileUpload,
+		message.TypeVoiceMessage, message.TypeAudioChunk, message.TypeMessageFeedback:
+		return true
+	default:
+		return false
+	}
+}
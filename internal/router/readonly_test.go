@@ -0,0 +1,96 @@
+package router
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/readonly"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnly_RefusesNewSessionsAndMessages(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockLLMService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	mode := readonly.New(readonly.State{})
+	router.SetReadOnly(mode, nil)
+
+	userConn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, userConn))
+
+	mode.Set(readonly.State{Enabled: true, Message: "Storage incident", UpdatedAt: time.Now()})
+
+	// New sessions are refused
+	_, err = router.createNewSession(mockConnection("user-2"), "")
+	var chatErr *chaterrors.ChatError
+	require.ErrorAs(t, err, &chatErr)
+	assert.Equal(t, chaterrors.ErrCodeReadOnly, chatErr.Code)
+	assert.Equal(t, "Storage incident", chatErr.Message)
+
+	// Messages that write are refused, and the client gets the error
+	err = router.RouteMessage(userConn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "hello",
+		Sender:    message.SenderUser,
+	})
+	require.ErrorAs(t, err, &chatErr)
+	assert.Equal(t, chaterrors.ErrCodeReadOnly, chatErr.Code)
+	assert.Contains(t, drainTypes(t, userConn), message.TypeError)
+	assert.Empty(t, sess.Messages, "nothing is added to the session")
+
+	_, err = router.SendAdminMessage("admin-1", "Admin", sess.ID, "hi")
+	require.ErrorAs(t, err, &chatErr)
+	assert.Equal(t, chaterrors.ErrCodeReadOnly, chatErr.Code)
+
+	// Messages that do not write are still handled
+	assert.NoError(t, router.RouteMessage(userConn, &message.Message{
+		Type:      message.TypeTypingIndicator,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+	}))
+
+	// Without a message the default one is shown
+	mode.Set(readonly.State{Enabled: true, UpdatedAt: time.Now()})
+	err = router.checkReadOnly()
+	require.ErrorAs(t, err, &chatErr)
+	assert.Equal(t, constants.DefaultReadOnlyMessage, chatErr.Message)
+
+	// Turned off, everything is accepted again
+	mode.Set(readonly.State{UpdatedAt: time.Now()})
+	assert.NoError(t, router.checkReadOnly())
+	_, err = router.createNewSession(mockConnection("user-2"), "")
+	assert.NoError(t, err)
+}
+
+func TestReadOnly_SyncAdoptsNewerStoredMode(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	now := time.Now()
+	mode := readonly.New(readonly.State{UpdatedAt: now})
+	router.SetReadOnly(mode, nil)
+
+	// A mode stored on another replica after the local one is adopted
+	stored := readonly.State{Enabled: true, UpdatedBy: "admin-1", UpdatedAt: now.Add(time.Second)}
+	router.syncReadOnly(func() (readonly.State, error) { return stored, nil })
+	assert.Equal(t, stored, mode.State())
+
+	// An older stored mode, or a failed load, keeps the current one
+	router.syncReadOnly(func() (readonly.State, error) { return readonly.State{UpdatedAt: now}, nil })
+	router.syncReadOnly(func() (readonly.State, error) { return readonly.State{}, errors.New("mongo down") })
+	assert.Equal(t, stored, mode.State())
+}
@@ -0,0 +1,158 @@
+package router
+
+import (
+	"sync"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// bufferedMessage is a marshaled outbound message tagged with its sequence number
+type bufferedMessage struct {
+	seq  uint64
+	data []byte
+}
+
+// replayBuffer holds the most recent outbound messages for a session so they can
+// be replayed to a client that reconnects within the reconnect timeout.
+// Sequence numbers are per session, start at 1, and increase monotonically.
+type replayBuffer struct {
+	mu       sync.Mutex
+	lastSeq  uint64
+	entries  []bufferedMessage
+	capacity int
+}
+
+// newReplayBuffer creates a replay buffer holding at most capacity messages
+func newReplayBuffer(capacity int) *replayBuffer {
+	return &replayBuffer{
+		entries:  make([]bufferedMessage, 0, capacity),
+		capacity: capacity,
+	}
+}
+
+// record assigns the next sequence number to msg, marshals it, and stores the
+// result. Assignment and marshaling happen under the same lock so that buffered
+// entries are always in sequence order.
+func (b *replayBuffer) record(msg *message.Message) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	msg.Seq = b.lastSeq + 1
+	data, err := util.MarshalJSON(msg)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		msg.Seq = 0
+		return nil, err
+	}
+	b.lastSeq = msg.Seq
+
+	// Drop the oldest entry once the buffer is full
+	// No else needed: optional operation (only evict when at capacity)
+	if len(b.entries) >= b.capacity {
+		copy(b.entries, b.entries[1:])
+		b.entries = b.entries[:len(b.entries)-1]
+	}
+	b.entries = append(b.entries, bufferedMessage{seq: msg.Seq, data: data})
+
+	return data, nil
+}
+
+// since returns the buffered messages with a sequence number greater than seq,
+// oldest first. Messages already evicted from the buffer are not returned.
+func (b *replayBuffer) since(seq uint64) [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make([][]byte, 0)
+	for _, entry := range b.entries {
+		// No else needed: optional operation (only include newer messages)
+		if entry.seq > seq {
+			result = append(result, entry.data)
+		}
+	}
+	return result
+}
+
+// isReplayable reports whether a message type should be buffered for replay.
+// Connection status is regenerated on every registration, so replaying old
+// copies would only confuse the client.
+func isReplayable(msg *message.Message) bool {
+	return msg.Type != message.TypeConnectionStatus
+}
+
+// getReplayBuffer returns the replay buffer for a session, creating it if needed.
+// When a new buffer is created, buffers of sessions that no longer exist in the
+// session manager are dropped so memory stays bounded by live sessions.
+func (mr *MessageRouter) getReplayBuffer(sessionID string) *replayBuffer {
+	mr.mu.RLock()
+	buf, exists := mr.replayBuffers[sessionID]
+	mr.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if exists {
+		return buf
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	// No else needed: early return pattern (created by a concurrent caller)
+	if buf, exists := mr.replayBuffers[sessionID]; exists {
+		return buf
+	}
+
+	// Lock ordering is safe: mr.mu → sessionManager.mu
+	for id := range mr.replayBuffers {
+		// No else needed: optional operation (only drop buffers of expired sessions)
+		if _, err := mr.sessionManager.GetSession(id); err != nil {
+			delete(mr.replayBuffers, id)
+		}
+	}
+
+	buf = newReplayBuffer(constants.ReplayBufferSize)
+	mr.replayBuffers[sessionID] = buf
+	return buf
+}
+
+// marshalForSession marshals an outbound session message, assigning it a
+// sequence number and buffering it for replay when applicable.
+func (mr *MessageRouter) marshalForSession(sessionID string, msg *message.Message) ([]byte, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" || !isReplayable(msg) {
+		return util.MarshalJSON(msg)
+	}
+	return mr.getReplayBuffer(sessionID).record(msg)
+}
+
+// replayMissedMessages sends buffered messages with a sequence number greater
+// than resumeFrom to a connection that reconnected with resume_from.
+func (mr *MessageRouter) replayMissedMessages(sessionID string, conn *websocket.Connection, resumeFrom uint64) {
+	mr.mu.RLock()
+	buf, exists := mr.replayBuffers[sessionID]
+	mr.mu.RUnlock()
+	// No else needed: early return pattern (nothing buffered for this session)
+	if !exists {
+		return
+	}
+
+	missed := buf.since(resumeFrom)
+	replayed := 0
+	for _, data := range missed {
+		// No else needed: early return pattern (stop when the connection cannot accept more)
+		if !conn.SafeSend(data) {
+			mr.logger.Warn("Replay interrupted, connection send channel full or closing",
+				"session_id", sessionID,
+				"replayed", replayed,
+				"missed", len(missed))
+			return
+		}
+		replayed++
+	}
+
+	mr.logger.Info("Replayed missed messages on reconnect",
+		"session_id", sessionID,
+		"resume_from", resumeFrom,
+		"replayed", replayed)
+}
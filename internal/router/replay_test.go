@@ -0,0 +1,117 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayBuffer_AssignsSequenceAndEvicts(t *testing.T) {
+	buf := newReplayBuffer(3)
+
+	for i := 1; i <= 5; i++ {
+		msg := &message.Message{Type: message.TypeAIResponse, Content: "chunk", Timestamp: time.Now()}
+		_, err := buf.record(msg)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i), msg.Seq)
+	}
+
+	all := buf.since(0)
+	require.Len(t, all, 3, "only the newest messages are kept")
+
+	var first message.Message
+	require.NoError(t, json.Unmarshal(all[0], &first))
+	assert.Equal(t, uint64(3), first.Seq)
+
+	assert.Len(t, buf.since(4), 1)
+	assert.Empty(t, buf.since(5))
+}
+
+func TestRegisterConnection_ReplaysMissedMessages(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	first := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, first))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, router.sendToConnection(sess.ID, &message.Message{
+			Type:      message.TypeAIResponse,
+			SessionID: sess.ID,
+			Content:   "hello",
+			Sender:    message.SenderAI,
+			Timestamp: time.Now(),
+		}))
+	}
+
+	// Client saw only the first message before disconnecting
+	router.UnregisterConnection(sess.ID)
+
+	second := mockConnection("user-1")
+	second.SetResumeFrom(1)
+	require.NoError(t, router.RegisterConnection(sess.ID, second))
+
+	var seqs []uint64
+	for {
+		select {
+		case data := <-second.ReceiveForTest():
+			var msg message.Message
+			require.NoError(t, json.Unmarshal(data, &msg))
+			if msg.Type == message.TypeAIResponse {
+				seqs = append(seqs, msg.Seq)
+			}
+			continue
+		default:
+		}
+		break
+	}
+
+	assert.Equal(t, []uint64{2, 3}, seqs)
+
+	_, pending := second.TakeResumeFrom()
+	assert.False(t, pending, "resume position is consumed after replay")
+}
+
+func TestRegisterConnection_NoReplayWithoutResume(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	require.NoError(t, router.RegisterConnection(sess.ID, mockConnection("user-1")))
+	require.NoError(t, router.sendToConnection(sess.ID, &message.Message{
+		Type:      message.TypeAIResponse,
+		SessionID: sess.ID,
+		Sender:    message.SenderAI,
+		Timestamp: time.Now(),
+	}))
+	router.UnregisterConnection(sess.ID)
+
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	for {
+		select {
+		case data := <-conn.ReceiveForTest():
+			var msg message.Message
+			require.NoError(t, json.Unmarshal(data, &msg))
+			assert.NotEqual(t, message.TypeAIResponse, msg.Type)
+			continue
+		default:
+		}
+		break
+	}
+}
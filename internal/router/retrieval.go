@@ -0,0 +1,66 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/retrieval"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// Retriever finds documents relevant to a user message (to avoid coupling the
+// router to a concrete retrieval backend)
+type Retriever interface {
+	Query(ctx context.Context, sessionID, prompt string) ([]retrieval.Document, error)
+}
+
+// SetRetriever enables retrieval-augmented generation: before each LLM call the
+// retriever is queried with the user message, and the documents it returns are
+// given to the LLM and recorded in the reply's metadata.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetRetriever(retriever Retriever) {
+	mr.retriever = retriever
+}
+
+// retrieve queries the retriever for documents relevant to content. Returns nil
+// when retrieval is not configured, finds nothing or fails; failures are logged
+// so that a retrieval outage never stops the chat.
+func (mr *MessageRouter) retrieve(ctx context.Context, sessionID, content string) []retrieval.Document {
+	// No else needed: early return pattern (retrieval not configured)
+	if mr.retriever == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, constants.DefaultRetrievalTimeout)
+	defer cancel()
+
+	docs, err := mr.retriever.Query(ctx, sessionID, content)
+	// No else needed: early return pattern (answer without documents)
+	if err != nil {
+		util.LogError(mr.logger, "router", "retrieve documents", err, "session_id", sessionID)
+		return nil
+	}
+	return docs
+}
+
+// retrievalMessage returns the system message giving docs to the LLM
+func retrievalMessage(docs []retrieval.Document) llm.ChatMessage {
+	return llm.ChatMessage{Role: constants.SenderSystem, Content: retrieval.Context(docs)}
+}
+
+// recordRetrieval adds the audit snippets of docs to an AI reply's metadata
+func (mr *MessageRouter) recordRetrieval(sessionID string, metadata map[string]string, docs []retrieval.Document) {
+	// No else needed: early return pattern (reply not grounded in documents)
+	if len(docs) == 0 {
+		return
+	}
+	data, err := json.Marshal(retrieval.Snippets(docs, constants.MaxRetrievalSnippetBytes))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(mr.logger, "router", "marshal retrieval snippets", err, "session_id", sessionID)
+		return
+	}
+	metadata[constants.MetadataKeyRetrieval] = string(data)
+}
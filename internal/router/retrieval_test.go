@@ -0,0 +1,93 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/retrieval"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRetriever returns fixed documents or an error
+type stubRetriever struct {
+	docs    []retrieval.Document
+	err     error
+	prompts []string
+}
+
+func (r *stubRetriever) Query(_ context.Context, _ string, prompt string) ([]retrieval.Document, error) {
+	r.prompts = append(r.prompts, prompt)
+	return r.docs, r.err
+}
+
+func TestRouteMessage_GroundsReplyInRetrievedDocuments(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockLLMService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+	retriever := &stubRetriever{docs: []retrieval.Document{
+		{ID: "faq-1", Title: "Refunds", Content: "Refunds take 5 days.", Source: "faq", Score: 0.9},
+	}}
+	router.SetRetriever(retriever)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "How long do refunds take?",
+		Sender:    message.SenderUser,
+	}))
+
+	assert.Equal(t, []string{"How long do refunds take?"}, retriever.prompts)
+
+	// The documents precede the user message as a system message
+	require.Len(t, llmMock.lastMessages, 2)
+	assert.Equal(t, constants.SenderSystem, llmMock.lastMessages[0].Role)
+	assert.Contains(t, llmMock.lastMessages[0].Content, "Refunds take 5 days.")
+	assert.Equal(t, "How long do refunds take?", llmMock.lastMessages[1].Content)
+
+	// The reply records what it was grounded in
+	reply := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, constants.SenderAI, reply.Sender)
+	var snippets []retrieval.Snippet
+	require.NoError(t, json.Unmarshal([]byte(reply.Metadata[constants.MetadataKeyRetrieval]), &snippets))
+	assert.Equal(t, []retrieval.Snippet{{ID: "faq-1", Source: "faq", Score: 0.9, Snippet: "Refunds take 5 days."}}, snippets)
+}
+
+func TestRouteMessage_RetrievalFailureDoesNotBlockReply(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockLLMService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+	router.SetRetriever(&stubRetriever{err: errors.New("index unavailable")})
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	}))
+
+	require.Len(t, llmMock.lastMessages, 1)
+	assert.Equal(t, "Hello", llmMock.lastMessages[0].Content)
+	reply := sess.Messages[len(sess.Messages)-1]
+	assert.NotContains(t, reply.Metadata, constants.MetadataKeyRetrieval)
+}
@@ -6,19 +6,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/chatbox/internal/businesshours"
+	"github.com/real-rm/chatbox/internal/cluster"
 	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/cost"
 	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/events"
+	"github.com/real-rm/chatbox/internal/injection"
 	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/maintenance"
 	"github.com/real-rm/chatbox/internal/message"
 	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/moderation"
 	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/readonly"
 	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/tracing"
 	"github.com/real-rm/chatbox/internal/upload"
 	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/webhook"
 	"github.com/real-rm/chatbox/internal/websocket"
 	"github.com/real-rm/golog"
 )
@@ -49,12 +61,23 @@ type NotificationService interface {
 	SendHelpRequestAlert(userID, sessionID string) error
 }
 
+// WebhookPublisher interface for publishing lifecycle events to external systems
+type WebhookPublisher interface {
+	Publish(event webhook.Event)
+}
+
 // StorageService interface for storage operations (to avoid circular dependency and enable testing)
 type StorageService interface {
 	CreateSession(sess *session.Session) error
 	AddMessage(sessionID string, msg *session.Message) error
 	UpdateSessionName(sessionID, name string) error
 	UpdateSessionModelID(sessionID, modelID string) error
+	RecordHandback(sessionID string, intervention *session.AdminIntervention) error
+	UpdateSessionLLMParams(sessionID string, params *session.LLMParams) error
+	UpdateSessionProviderThread(sessionID string, thread *session.ProviderThread) error
+	SetMessageFeedback(sessionID string, index int, feedback *session.Feedback) error
+	MarkMessagesRead(sessionID string, indexes []int, at time.Time) error
+	EndSession(sessionID string, endTime time.Time) error
 }
 
 // MessageRouter routes messages between clients, LLM backends, and admin users
@@ -62,11 +85,54 @@ type MessageRouter struct {
 	llmService          LLMService
 	uploadService       *upload.UploadService
 	notificationService NotificationService
+	webhooks            WebhookPublisher   // nil when no webhook endpoints are configured
+	events              EventPublisher     // nil when the lifecycle event bus is disabled
+	moderator           Moderator          // nil when content moderation is disabled
+	injection           InjectionScanner   // nil when prompt injection detection is disabled
+	sentiment           SentimentScorer    // nil when sentiment analysis is disabled
+	prompts             PromptRenderer     // nil when prompt templates are disabled
+	experiments         ExperimentAssigner // nil when A/B experiments are disabled
+	retriever           Retriever          // nil when retrieval-augmented generation is disabled
+	codeRunner          CodeRunner         // nil when the code execution sandbox is disabled
+	keywords            KeywordMatcher     // nil when keyword alerts are disabled
+	memory              MemoryStore        // nil when user memory is disabled
+	alerts              DashboardAlerter   // Receives keyword alerts (nil tags sessions without alerting)
 	sessionManager      *session.SessionManager
 	storageService      StorageService // NEW: for persisting sessions
-	messageLimiter      *ratelimit.MessageLimiter
-	connections         map[string]*websocket.Connection // sessionID -> Connection
-	adminConns          map[string]*websocket.Connection // adminID -> Connection
+	messageLimiter      ratelimit.Limiter
+	guestLimiter        ratelimit.Limiter                             // nil when guest connections are disabled
+	tokenBudget         *ratelimit.TokenBudgetLimiter                 // nil when no daily token budget is configured
+	impersonationLog    ImpersonationAuditor                          // nil when impersonated messages are not audited
+	tokenCounter        TokenCounter                                  // nil estimates tokens at constants.CharsPerToken
+	usageRecorder       UsageRecorder                                 // nil when LLM usage is not persisted
+	costs               *cost.Calculator                              // Per-model prices of recorded usage (nil records no cost)
+	connections         map[string]*websocket.Connection              // sessionID -> Connection (the active device in multi-device mode)
+	devices             map[string]map[*websocket.Connection]struct{} // sessionID -> every device bound in multi-device mode
+	adminConns          map[string]*websocket.Connection              // adminID -> Connection
+	replayBuffers       map[string]*replayBuffer                      // sessionID -> outbound messages for reconnect replay
+	watchers            map[string]map[*websocket.Connection]struct{} // sessionID -> read-only spectator connections
+	takeoverQueues      map[string][]takeoverWaiter                   // sessionID -> admins waiting to take over, in arrival order
+	takeoverClaims      map[string]takeoverClaim                      // sessionID -> admin the released session is reserved for
+	takeoverMu          sync.Mutex                                    // serializes takeover claims and releases
+	generations         map[string]*generation                        // sessionID -> in-flight LLM stream, cancellable by the client
+	humanOnly           bool                                          // Route user messages to the help queue instead of the LLM
+	autoTitle           bool                                          // Title sessions with the LLM after constants.AutoTitleAfterExchanges replies
+	multiDevice         bool                                          // Bind every connection of a session instead of replacing the previous one
+	readReceipts        bool                                          // Store read receipts and relay them to the assisting admin
+	maintenance         *maintenance.Schedule                         // Scheduled maintenance windows (nil when none are configured)
+	businessHours       *businesshours.Schedule                       // Hours help requests are answered (nil is always open)
+	sessionTagger       SessionTagger                                 // Tags after-hours help requests and keyword matches (nil when storage has no tags)
+	readOnly            *readonly.Mode                                // Admin read-only mode (nil when never read-only)
+	policy              *authz.Policy                                 // Role permissions for admin actions
+	fallback            FallbackConfig                                // Model fallback chains and retries for LLM calls
+	sendQueues          *providerQueues                               // Per-provider queues of rate limited LLM requests (nil fails them)
+	cluster             ClusterBridge                                 // Relays traffic for connections on other replicas (nil on a single replica)
+	transcriber         Transcriber                                   // Transcribes streamed voice messages (nil when disabled)
+	audioStore          AudioStore                                    // Keeps the recordings of streamed voice messages (nil keeps none)
+	voiceStreams        map[string]*voiceStream                       // sessionID:uploadID -> voice message being streamed
+	llmTimeoutMin       time.Duration                                 // Shortest LLM timeout a session or message may ask for
+	llmTimeoutMax       time.Duration                                 // Longest LLM timeout a session or message may ask for (0 allows no overrides)
+	sandboxTenants      map[string]bool                               // Tenants whose AI replies have their code run (key "" is the default tenant)
 	mu                  sync.RWMutex
 	wg                  sync.WaitGroup // tracks all goroutines launched via safeGo
 	logger              *golog.Logger
@@ -92,7 +158,15 @@ func NewMessageRouter(sessionManager *session.SessionManager, llmService LLMServ
 		storageService:      storageService,
 		messageLimiter:      messageLimiter,
 		connections:         make(map[string]*websocket.Connection),
+		devices:             make(map[string]map[*websocket.Connection]struct{}),
 		adminConns:          make(map[string]*websocket.Connection),
+		replayBuffers:       make(map[string]*replayBuffer),
+		watchers:            make(map[string]map[*websocket.Connection]struct{}),
+		takeoverQueues:      make(map[string][]takeoverWaiter),
+		takeoverClaims:      make(map[string]takeoverClaim),
+		generations:         make(map[string]*generation),
+		voiceStreams:        make(map[string]*voiceStream),
+		policy:              authz.Default(),
 		llmStreamTimeout:    llmStreamTimeout,
 		logger:              routerLogger,
 		ctx:                 ctx,
@@ -100,6 +174,75 @@ func NewMessageRouter(sessionManager *session.SessionManager, llmService LLMServ
 	}
 }
 
+// SetHumanOnlyMode enables or disables human-only chat.
+// When enabled, no LLM calls are made: user messages are stored and routed to
+// the admin help queue, and model selection is rejected.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetHumanOnlyMode(enabled bool) {
+	mr.humanOnly = enabled
+}
+
+// SetPolicy replaces the default role permissions (see authz.DefaultRoles)
+// checked for admin actions sent over WebSocket.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetPolicy(policy *authz.Policy) {
+	mr.policy = policy
+}
+
+// SetWebhookPublisher enables webhook events for help requests and admin takeovers.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetWebhookPublisher(publisher WebhookPublisher) {
+	mr.webhooks = publisher
+}
+
+// publishWebhook posts a lifecycle event when webhooks are configured, and
+// publishes it on the event bus
+func (mr *MessageRouter) publishWebhook(event webhook.Event) {
+	// No else needed: optional operation (only when webhooks are configured)
+	if mr.webhooks != nil {
+		mr.webhooks.Publish(event)
+	}
+	mr.publishEvent(event)
+}
+
+// SetMessageLimiter replaces the default in-memory per-user message limiter,
+// e.g. with a Redis-backed limiter shared across replicas.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetMessageLimiter(limiter ratelimit.Limiter) {
+	mr.messageLimiter.StopCleanup()
+	mr.messageLimiter = limiter
+	mr.messageLimiter.StartCleanup()
+}
+
+// SetGuestMessageLimiter enables a separate, usually stricter, message limiter
+// for guest connections (see constants.RoleGuest).
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetGuestMessageLimiter(limiter ratelimit.Limiter) {
+	mr.guestLimiter = limiter
+	mr.guestLimiter.StartCleanup()
+}
+
+// limiterFor returns the message limiter that applies to a connection
+func (mr *MessageRouter) limiterFor(conn *websocket.Connection) ratelimit.Limiter {
+	// No else needed: early return pattern (guests use the guest limiter)
+	if mr.guestLimiter != nil && util.HasRole(conn.Roles, constants.RoleGuest) {
+		return mr.guestLimiter
+	}
+	return mr.messageLimiter
+}
+
+// SetTokenBudget enables per-user daily token budget enforcement.
+// Once a user's budget is spent, their messages are rejected with a quota_exceeded event.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetTokenBudget(limiter *ratelimit.TokenBudgetLimiter) {
+	mr.tokenBudget = limiter
+}
+
+// IsHumanOnly reports whether human-only chat mode is enabled.
+func (mr *MessageRouter) IsHumanOnly() bool {
+	return mr.humanOnly
+}
+
 // safeGo launches a goroutine tracked by the router's WaitGroup so that
 // Shutdown() can wait for all in-flight goroutines to finish.
 // It also adds panic recovery to prevent a misbehaving goroutine from crashing
@@ -112,13 +255,19 @@ func (mr *MessageRouter) safeGo(component string, fn func()) {
 	})
 }
 
-// persistMessage persists a message to storage (fire-and-forget).
+// persistMessage persists a message to storage (fire-and-forget) and publishes a
+// message_added event.
 // In-memory session is the source of truth; storage failure is logged but non-fatal.
-func (mr *MessageRouter) persistMessage(sessionID string, msg *session.Message) {
+// The storage span is a child of any span in ctx.
+func (mr *MessageRouter) persistMessage(ctx context.Context, sessionID string, msg *session.Message) {
+	mr.publishMessageAdded(sessionID, msg)
 	if mr.storageService == nil {
 		return
 	}
-	if err := mr.storageService.AddMessage(sessionID, msg); err != nil {
+	_, span := tracing.StartMongo(ctx, "AddMessage")
+	err := mr.storageService.AddMessage(sessionID, msg)
+	tracing.End(span, err)
+	if err != nil {
 		mr.logger.Warn("Failed to persist message to storage",
 			"session_id", sessionID,
 			"sender", msg.Sender,
@@ -126,6 +275,28 @@ func (mr *MessageRouter) persistMessage(sessionID string, msg *session.Message)
 	}
 }
 
+// RecordSystemEvent appends a system event (takeover, handoff, redaction,
+// reconnect, ...) to the session transcript and persists it, so stored history
+// shows what happened and when. System events are stored with the system sender
+// and are never included in LLM context.
+func (mr *MessageRouter) RecordSystemEvent(sessionID, event, content string, metadata map[string]string) {
+	eventMsg := &session.Message{
+		Content:   content,
+		Timestamp: time.Now(),
+		Sender:    constants.SenderSystem,
+		Event:     event,
+		Metadata:  metadata,
+	}
+	// No else needed: optional operation, in-memory failure is logged but not fatal
+	if err := mr.sessionManager.AddMessage(sessionID, eventMsg); err != nil {
+		mr.logger.Warn("Failed to store system event in session",
+			"session_id", sessionID,
+			"event", event,
+			"error", err)
+	}
+	mr.persistMessage(mr.ctx, sessionID, eventMsg)
+}
+
 // redactURLQuery returns a URL with query parameters removed for safe logging.
 // Pre-signed URLs contain signing keys that should not appear in logs.
 func redactURLQuery(rawURL string) string {
@@ -137,8 +308,11 @@ func redactURLQuery(rawURL string) string {
 
 // RegisterConnection registers a connection for a session.
 // If the session already exists, ownership is verified before registration.
-// If an old connection exists for the session, it is marked as closing.
-// On success, sends an initial connection_status message with available models.
+// If an old connection exists for the session, it is marked as closing, unless
+// multi-device mode is enabled, in which case both stay bound and conn becomes
+// the session's active device.
+// On success, sends an initial connection_status message with available models and
+// replays any buffered messages newer than the connection's resume_from sequence.
 func (mr *MessageRouter) RegisterConnection(sessionID string, conn *websocket.Connection) error {
 	if conn == nil {
 		return ErrNilConnection
@@ -152,8 +326,10 @@ func (mr *MessageRouter) RegisterConnection(sessionID string, conn *websocket.Co
 	// Verify session ownership inside the lock to prevent a TOCTOU race between the
 	// ownership check and the connection registration. Both operations are atomic under mu.
 	// Lock ordering is safe: mr.mu → sessionManager.mu (sessionManager never calls back into router).
-	if sess, err := mr.sessionManager.GetSession(sessionID); err == nil {
-		if sess.UserID != conn.UserID {
+	sess, err := mr.sessionManager.GetSession(sessionID)
+	sessionExists := err == nil
+	if sessionExists {
+		if !ownsSession(conn, sess) {
 			mr.mu.Unlock()
 			mr.logger.Warn("Session ownership violation in RegisterConnection",
 				"session_id", sessionID,
@@ -168,22 +344,42 @@ func (mr *MessageRouter) RegisterConnection(sessionID string, conn *websocket.Co
 	}
 
 	// Close old connection if it exists and is different from the new one
-	if oldConn, exists := mr.connections[sessionID]; exists && oldConn != conn {
+	oldConn, replaced := mr.connections[sessionID]
+	replaced = replaced && oldConn != conn && !mr.multiDevice
+	if replaced {
 		oldConn.SetClosing()
 	}
 
+	// No else needed: optional operation (devices are only tracked in multi-device mode)
+	if mr.multiDevice {
+		mr.bindDevice(sessionID, conn)
+	}
 	mr.connections[sessionID] = conn
 	mr.mu.Unlock()
+	mr.claimConnection(cluster.SessionKey(sessionID))
+
+	resumeFrom, resuming := conn.TakeResumeFrom()
+
+	// A client rejoining a live session (explicit resume or a replaced connection) is a reconnect
+	// No else needed: optional operation (only record reconnects to existing sessions)
+	if sessionExists && (resuming || replaced) {
+		mr.RecordSystemEvent(sessionID, constants.SystemEventReconnect, "User reconnected",
+			map[string]string{"connection_id": conn.ConnectionID})
+	}
 
 	// Send initial connection_status with available models (outside the lock).
 	mr.sendInitialStatus(conn, sessionID)
+	// No else needed: optional operation (only for clients that sent resume_from)
+	if resuming {
+		mr.replayMissedMessages(sessionID, conn, resumeFrom)
+	}
 	return nil
 }
 
 // sendInitialStatus sends a connection_status message with available models to the client.
 func (mr *MessageRouter) sendInitialStatus(conn *websocket.Connection, sessionID string) {
 	var models []message.ModelRef
-	if mr.llmService != nil {
+	if mr.llmService != nil && !mr.humanOnly {
 		available := mr.llmService.GetAvailableModels()
 		models = make([]message.ModelRef, 0, len(available))
 		for _, m := range available {
@@ -197,14 +393,50 @@ func (mr *MessageRouter) sendInitialStatus(conn *websocket.Connection, sessionID
 		Timestamp: time.Now(),
 		Models:    models,
 	}
-	if err := mr.sendToConnection(sessionID, status); err != nil {
+	// Sent to conn only: the session's other devices are already initialized
+	data, err := mr.marshalForSession(sessionID, status)
+	if err != nil {
 		mr.logger.Warn("Failed to send initial connection status", "session_id", sessionID, "error", err)
+		return
+	}
+	mr.mirrorToWatchers(sessionID, data)
+	// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
+	if !conn.SafeSend(data) {
+		mr.logger.Warn("Failed to send initial connection status", "session_id", sessionID,
+			"error", "connection send channel is full or closing")
 	}
 }
 
+// resolveModel returns the model to use for a session. The session's model is used
+// if it is in the model catalog; sessions without one, or whose model was removed
+// from the catalog, fall back to constants.DefaultModel, or to the first catalog
+// model when the default is not in the catalog either.
+func (mr *MessageRouter) resolveModel(sessionID, modelID string) string {
+	// No else needed: early return pattern (session model is valid)
+	if modelID != "" && mr.llmService.ValidateModel(modelID) == nil {
+		return modelID
+	}
+	// No else needed: optional operation (log stale model selections)
+	if modelID != "" {
+		mr.logger.Warn("Session model not in catalog, using default",
+			"session_id", sessionID,
+			"model_id", modelID)
+	}
+	// No else needed: early return pattern (default model is valid)
+	if mr.llmService.ValidateModel(constants.DefaultModel) == nil {
+		return constants.DefaultModel
+	}
+	// No else needed: early return pattern (first catalog model)
+	if available := mr.llmService.GetAvailableModels(); len(available) > 0 {
+		return available[0].ID
+	}
+	return constants.DefaultModel
+}
+
 // GetAvailableModelRefs returns available models as ModelRef values for the client.
+// Returns nil in human-only mode so clients hide the model selector.
 func (mr *MessageRouter) GetAvailableModelRefs() []message.ModelRef {
-	if mr.llmService == nil {
+	if mr.llmService == nil || mr.humanOnly {
 		return nil
 	}
 	available := mr.llmService.GetAvailableModels()
@@ -215,12 +447,14 @@ func (mr *MessageRouter) GetAvailableModelRefs() []message.ModelRef {
 	return refs
 }
 
-// UnregisterConnection removes a connection for a session
+// UnregisterConnection removes every connection bound to a session
 func (mr *MessageRouter) UnregisterConnection(sessionID string) {
 	mr.mu.Lock()
-	defer mr.mu.Unlock()
-
 	delete(mr.connections, sessionID)
+	delete(mr.devices, sessionID)
+	mr.mu.Unlock()
+
+	mr.releaseConnection(cluster.SessionKey(sessionID))
 }
 
 // RouteMessage routes a message to the appropriate handler based on message type
@@ -232,11 +466,19 @@ func (mr *MessageRouter) RouteMessage(conn *websocket.Connection, msg *message.M
 		return ErrNilMessage
 	}
 
+	// Per-message span, joining the trace of the request that opened the connection
+	ctx, span := tracing.Start(conn.TraceContext(), "websocket.message",
+		tracing.AttrMessageType.String(string(msg.Type)),
+		tracing.AttrSessionID.String(msg.SessionID),
+		tracing.AttrRequestID.String(conn.RequestID()))
+	defer span.End()
+
 	// Check message rate limit for user messages
 	// No else needed: only user messages require rate limiting (optional operation)
 	if msg.Type == message.TypeUserMessage {
-		if !mr.messageLimiter.Allow(conn.UserID) {
-			retryAfter := mr.messageLimiter.GetRetryAfter(conn.UserID)
+		limiter := mr.limiterFor(conn)
+		if !limiter.Allow(conn.UserID) {
+			retryAfter := limiter.GetRetryAfter(conn.UserID)
 			mr.logger.Warn("Message rate limit exceeded",
 				"user_id", conn.UserID,
 				"session_id", msg.SessionID,
@@ -246,13 +488,33 @@ func (mr *MessageRouter) RouteMessage(conn *websocket.Connection, msg *message.M
 			mr.HandleError(msg.SessionID, chatErr)
 			return chatErr
 		}
+
+		// No else needed: optional operation (only when a token budget is configured)
+		if mr.tokenBudget != nil && !mr.humanOnly && !mr.tokenBudget.Allow(budgetKey(conn.TenantID, conn.UserID)) {
+			return mr.rejectOverBudget(conn, msg.SessionID)
+		}
 	}
 
+	// No else needed: optional operation (only messages that write are refused while read-only)
+	if writesSession(msg.Type) {
+		// No else needed: early return pattern (guard clause)
+		if err := mr.checkReadOnly(); err != nil {
+			tracing.RecordError(span, err)
+			mr.HandleError(msg.SessionID, err)
+			return err
+		}
+	}
+
+	// Any client message counts as activity for the idle timeout, and makes the
+	// sending device the session's active device (last writer wins)
+	mr.touchSession(conn, msg.SessionID)
+	mr.markActiveDevice(conn, msg.SessionID)
+
 	// Route based on message type
 	var err error
 	switch msg.Type {
 	case message.TypeUserMessage:
-		err = mr.HandleUserMessage(conn, msg)
+		err = mr.handleUserMessage(ctx, conn, msg)
 	case message.TypeHelpRequest:
 		err = mr.handleHelpRequest(conn, msg)
 	case message.TypeModelSelect:
@@ -261,6 +523,20 @@ func (mr *MessageRouter) RouteMessage(conn *websocket.Connection, msg *message.M
 		err = mr.handleFileUpload(conn, msg)
 	case message.TypeVoiceMessage:
 		err = mr.handleVoiceMessage(conn, msg)
+	case message.TypeAudioChunk:
+		err = mr.handleAudioChunk(conn, msg)
+	case message.TypeHandback:
+		err = mr.handleHandbackMessage(conn, msg)
+	case message.TypeSessionConfig:
+		err = mr.handleSessionConfig(conn, msg)
+	case message.TypeCancelGeneration:
+		err = mr.handleCancelGeneration(conn, msg)
+	case message.TypeMessageFeedback:
+		err = mr.handleMessageFeedback(conn, msg)
+	case message.TypeTypingIndicator:
+		err = mr.handleTypingIndicator(conn, msg)
+	case message.TypeReadReceipt:
+		err = mr.handleReadReceipt(conn, msg)
 	default:
 		err = chaterrors.ErrInvalidMessageFormat(
 			fmt.Sprintf("unknown message type %s", msg.Type),
@@ -271,6 +547,7 @@ func (mr *MessageRouter) RouteMessage(conn *websocket.Connection, msg *message.M
 	// Handle any errors that occurred
 	// No else needed: early return pattern (guard clause)
 	if err != nil {
+		tracing.RecordError(span, err)
 		mr.HandleError(msg.SessionID, err)
 		return err // Still return the error for logging/testing
 	}
@@ -278,8 +555,71 @@ func (mr *MessageRouter) RouteMessage(conn *websocket.Connection, msg *message.M
 	return nil
 }
 
+// rejectOverBudget tells a user their daily token budget is spent. The
+// quota_exceeded event is sent directly on the connection so it arrives even
+// before the connection is registered for a session.
+func (mr *MessageRouter) rejectOverBudget(conn *websocket.Connection, sessionID string) error {
+	resetAt := mr.tokenBudget.ResetAt()
+	used := mr.tokenBudget.Used(budgetKey(conn.TenantID, conn.UserID))
+	chatErr := chaterrors.ErrQuotaExceeded(int(time.Until(resetAt).Milliseconds()))
+
+	mr.logger.Warn("Daily token budget exceeded",
+		"user_id", conn.UserID,
+		"session_id", sessionID,
+		"tokens_used", used,
+		"budget", mr.tokenBudget.Budget())
+
+	quotaMsg := &message.Message{
+		Type:      message.TypeQuotaExceeded,
+		SessionID: sessionID,
+		Sender:    message.SenderSystem,
+		Error:     chatErr.ToErrorInfo(),
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"daily_token_budget": strconv.Itoa(mr.tokenBudget.Budget()),
+			"tokens_used":        strconv.Itoa(used),
+			"reset_at":           resetAt.Format(time.RFC3339),
+		},
+	}
+	data, err := util.MarshalJSON(quotaMsg)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return chaterrors.ErrInvalidMessageFormat("failed to marshal message", err)
+	}
+	// No else needed: optional operation (fire-and-forget), the message is rejected either way
+	if !conn.SafeSend(data) {
+		mr.logger.Warn("Failed to send quota_exceeded event", "user_id", conn.UserID)
+	}
+	return chatErr
+}
+
+// consumeTokenBudget charges LLM tokens against a user's daily budget
+func (mr *MessageRouter) consumeTokenBudget(tenantID, userID string, tokens int) {
+	// No else needed: optional operation (only when a token budget is configured)
+	if mr.tokenBudget != nil {
+		mr.tokenBudget.Consume(budgetKey(tenantID, userID), tokens)
+	}
+}
+
+// budgetKey identifies a user's daily token budget. User IDs are only unique
+// within a tenant, so the tenant is part of the key; the default tenant uses
+// the bare user ID.
+func budgetKey(tenantID, userID string) string {
+	// No else needed: early return pattern (default tenant)
+	if tenantID == "" {
+		return userID
+	}
+	return tenantID + "\x00" + userID
+}
+
 // HandleUserMessage processes user messages and forwards them to the LLM
 func (mr *MessageRouter) HandleUserMessage(conn *websocket.Connection, msg *message.Message) error {
+	return mr.handleUserMessage(context.Background(), conn, msg)
+}
+
+// handleUserMessage is HandleUserMessage with ctx carrying the parent span for
+// the storage and LLM spans of the message
+func (mr *MessageRouter) handleUserMessage(ctx context.Context, conn *websocket.Connection, msg *message.Message) error {
 	if conn == nil {
 		return ErrNilConnection
 	}
@@ -291,8 +631,9 @@ func (mr *MessageRouter) HandleUserMessage(conn *websocket.Connection, msg *mess
 	if msg.SessionID == "" {
 		return chaterrors.ErrMissingField("session_id")
 	}
+	timer := newPipelineTimer(msg)
 
-	sess, err := mr.getOrCreateSession(conn, msg.SessionID)
+	sess, err := mr.getOrCreateSession(conn, msg.SessionID, promptTemplateID(msg))
 	if err != nil {
 		return err
 	}
@@ -304,45 +645,105 @@ func (mr *MessageRouter) HandleUserMessage(conn *websocket.Connection, msg *mess
 	// If the session ID differs from what the client sent, re-register the
 	// connection under the correct session ID so sendToConnection can find it.
 	if sessionID != msg.SessionID {
-		mr.mu.Lock()
-		if c, ok := mr.connections[msg.SessionID]; ok {
-			delete(mr.connections, msg.SessionID)
-			mr.connections[sessionID] = c
-		}
-		mr.mu.Unlock()
+		mr.rebindConnection(conn, msg.SessionID, sessionID)
+	}
+
+	// The reply's timeout, which the message or session may override
+	timeout, longRunning, err := mr.streamTimeout(sess, msg)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
 
-		conn.SetSessionID(sessionID)
+	// Screen the message before it is stored or reaches the LLM
+	content := msg.Content
+	metadata := msg.Metadata
+	// No else needed: optional operation (only when moderation triggered)
+	if result := mr.moderate(sessionID, moderation.DirectionInput, content); result != nil {
+		// No else needed: early return pattern (blocked messages are not stored)
+		if result.Action == moderation.ActionBlock {
+			errorMsg := &message.Message{
+				Type:      message.TypeError,
+				SessionID: sessionID,
+				Sender:    message.SenderSystem,
+				Error:     chaterrors.ErrContentBlocked().ToErrorInfo(),
+				Timestamp: time.Now(),
+			}
+			return mr.sendToConnection(sessionID, errorMsg)
+		}
+		content = result.Content
+		metadata = withModeration(metadata, result)
+	}
+	// No else needed: optional operation (only when an injection was detected)
+	if result := mr.scanInjection(injection.SourceUser, content); result != nil {
+		// No else needed: early return pattern (blocked messages are not stored)
+		if result.Action == injection.ActionBlock {
+			errorMsg := &message.Message{
+				Type:      message.TypeError,
+				SessionID: sessionID,
+				Sender:    message.SenderSystem,
+				Error:     chaterrors.ErrInjectionBlocked().ToErrorInfo(),
+				Timestamp: time.Now(),
+			}
+			return mr.sendToConnection(sessionID, errorMsg)
+		}
+		content = result.Content
+		metadata = withInjection(metadata, result)
+	}
+	timer.markScreened()
+	// No else needed: optional operation (only for admin impersonation tokens)
+	if conn.ImpersonatorID != "" {
+		metadata = withImpersonator(metadata, conn.ImpersonatorID)
+		mr.logger.Info("Impersonated user message",
+			"session_id", sessionID,
+			"user_id", conn.UserID,
+			"admin_id", conn.ImpersonatorID)
+		mr.auditImpersonatedMessage(conn, sessionID)
 	}
 
 	sessModelID := sess.GetModelID()
 	mr.logger.Debug("Routing user message to LLM",
 		"session_id", sessionID,
-		"content_length", len(msg.Content),
+		"content_length", len(content),
 		"model_id", sessModelID)
 
 	// Store user message in session and persist to storage
 	userSessionMsg := &session.Message{
-		Content:   msg.Content,
+		Content:   content,
 		Timestamp: time.Now(),
 		Sender:    string(message.SenderUser),
-		Metadata:  msg.Metadata,
+		Metadata:  metadata,
 	}
 	if err := mr.sessionManager.AddMessage(sessionID, userSessionMsg); err != nil {
 		mr.logger.Warn("Failed to store user message in session", "error", err, "session_id", sessionID)
 	}
-	mr.persistMessage(sessionID, userSessionMsg)
+	mr.persistMessage(ctx, sessionID, userSessionMsg)
+	mr.mirrorUserMessage(conn, sessionID, userSessionMsg)
+	mr.scoreSentiment(conn, sessionID, content)
+	mr.checkKeywords(conn, sessionID, content)
+	mr.rememberFacts(conn, sessionID, content)
 
 	// Set session name from first user message and persist to storage.
 	// Check before/after to avoid redundant DB writes on subsequent messages.
 	nameBefore := sess.Name
-	if err := mr.sessionManager.SetSessionNameFromMessage(sessionID, msg.Content); err == nil {
+	if err := mr.sessionManager.SetSessionNameFromMessage(sessionID, content); err == nil {
 		if nameBefore == "" && sess.Name != "" && mr.storageService != nil {
-			if err := mr.storageService.UpdateSessionName(sessionID, sess.Name); err != nil {
+			_, span := tracing.StartMongo(ctx, "UpdateSessionName")
+			err := mr.storageService.UpdateSessionName(sessionID, sess.Name)
+			tracing.End(span, err)
+			if err != nil {
 				mr.logger.Warn("Failed to persist session name", "session_id", sessionID, "error", err)
 			}
 		}
 	}
 
+	// Human-only mode or an admin takeover: the assisting admin (or the help
+	// queue) answers, never the LLM, until the session is handed back
+	// No else needed: early return pattern (guard clause)
+	if mr.humanOnly || sess.GetAssistingAdminID() != "" {
+		return mr.routeToHumanAgent(sess, userSessionMsg)
+	}
+
 	// Send loading indicator to client
 	loadingMsg := &message.Message{
 		Type:      message.TypeLoading,
@@ -350,41 +751,66 @@ func (mr *MessageRouter) HandleUserMessage(conn *websocket.Connection, msg *mess
 		Sender:    message.SenderAI,
 		Timestamp: time.Now(),
 	}
+	// No else needed: optional operation (long-running replies announce their timeout)
+	if longRunning {
+		loadingMsg.Metadata = map[string]string{constants.MetadataKeyTimeoutSeconds: strconv.Itoa(int(timeout / time.Second))}
+	}
 	// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
 	if err := mr.sendToConnection(sessionID, loadingMsg); err != nil {
 		mr.logger.Warn("Failed to send loading indicator", "error", err)
 	}
 
+	// Use the session's model if it is still in the catalog, otherwise the default
+	modelID := mr.resolveModel(sessionID, sessModelID)
+
+	// A provider thread of the model already holds the conversation; a thread
+	// of another model, e.g. after a model switch, is migrated to this one
+	thread := sess.GetProviderThread()
+	continuing := thread != nil && thread.ModelID == modelID
+	migrated := thread != nil && !continuing
+
 	// Prepare messages for LLM (convert from message.Message to llm.ChatMessage)
-	llmMessages := []llm.ChatMessage{
-		{
-			Role:    constants.SenderUser,
-			Content: msg.Content,
-		},
+	llmMessages := make([]llm.ChatMessage, 0, 4)
+	// No else needed: optional operation (only when the session selected a prompt template and no thread holds it)
+	if systemMsg := mr.systemPrompt(conn, sess); systemMsg != nil && !continuing {
+		llmMessages = append(llmMessages, *systemMsg)
+	}
+	// No else needed: optional operation (only when taking over another model's thread)
+	if migrated {
+		// No else needed: optional operation (only when there is a conversation to migrate)
+		if transcript := threadMigrationMessage(sess, userSessionMsg); transcript != nil {
+			llmMessages = append(llmMessages, *transcript)
+		}
 	}
-
-	// Use default model if not set
-	// No else needed: conditional assignment, value already set if condition is false
-	modelID := sessModelID
-	if modelID == "" {
-		modelID = constants.DefaultModel
+	docs := mr.screenDocuments(mr.retrieve(ctx, sessionID, content))
+	// No else needed: optional operation (only when documents were retrieved)
+	if len(docs) > 0 {
+		llmMessages = append(llmMessages, retrievalMessage(docs))
 	}
+	llmMessages = append(llmMessages, llm.ChatMessage{
+		Role:    constants.SenderUser,
+		Content: content,
+	})
 
 	// Forward to LLM service with streaming
-	// Use configured timeout for LLM streaming
-	// No else needed: conditional assignment, value already set if condition is false
-	timeout := mr.llmStreamTimeout
-	if timeout == 0 {
-		timeout = constants.DefaultLLMStreamTimeout
-	}
-
-	ctx, cancel := util.NewTimeoutContext(timeout)
+	ctx, cancel := context.WithTimeout(llmThreadContext(llmParamsContext(ctx, sess), thread), timeout)
 	defer cancel()
+	ctx = withQueueNotice(ctx, mr.queuedNotice(sessionID, modelID))
+
+	// The client can abort the stream with a cancel_generation message
+	ctx, finishGeneration := mr.startGeneration(ctx, sessionID)
+	defer finishGeneration()
 
 	startTime := time.Now()
 
-	// Use streaming for real-time response
-	chunkChan, err := mr.llmService.StreamMessage(ctx, modelID, llmMessages)
+	// Use streaming for real-time response, falling back along the model's
+	// fallback chain if the stream cannot be established
+	var chunkChan <-chan *llm.LLMChunk
+	servedBy, release, err := mr.tryModels(ctx, modelID, func(ctx context.Context, modelID string) error {
+		var err error
+		chunkChan, err = mr.llmService.StreamMessage(ctx, modelID, llmMessages)
+		return err
+	})
 	// No else needed: early return pattern (guard clause)
 	if err != nil {
 		// Check if error is due to timeout
@@ -427,18 +853,61 @@ func (mr *MessageRouter) HandleUserMessage(conn *websocket.Connection, msg *mess
 		}
 		return mr.sendToConnection(sessionID, errorMsg)
 	}
+	defer release()
+	served := mr.servedMetadata(modelID, servedBy)
 
 	// Stream response chunks to client
 	var fullContent strings.Builder
 	var tokenCount int
+	var threadID string
+	truncated := false
+
+	// Long-running replies send keepalives while the model is silent, so
+	// proxies do not cut the connection
+	var keepalive *time.Ticker
+	var keepaliveC <-chan time.Time
+	// No else needed: optional operation (long-running mode only)
+	if longRunning {
+		keepalive = time.NewTicker(constants.LLMKeepaliveInterval)
+		defer keepalive.Stop()
+		keepaliveC = keepalive.C
+	}
+
+stream:
+	for {
+		// Wait for the next chunk, but stop as soon as the stream is cancelled
+		// or times out rather than when the provider notices
+		var chunk *llm.LLMChunk
+		select {
+		case c, ok := <-chunkChan:
+			// No else needed: early return pattern (stream closed)
+			if !ok {
+				break stream
+			}
+			chunk = c
+			// No else needed: optional operation (output restarts the silence)
+			if keepalive != nil {
+				keepalive.Reset(constants.LLMKeepaliveInterval)
+			}
+		case <-keepaliveC:
+			mr.sendKeepalive(sessionID, startTime)
+			continue stream
+		case <-ctx.Done():
+		}
+
+		// The client cancelled: end the stream and keep the partial reply
+		// No else needed: optional operation (cancellation ends the stream)
+		if generationCancelled(ctx) {
+			truncated = true
+			chunk = &llm.LLMChunk{Done: true}
+		}
 
-	for chunk := range chunkChan {
 		// Check if context has timed out during streaming
 		// No else needed: early return pattern (guard clause)
-		if ctx.Err() == context.DeadlineExceeded {
+		if !truncated && ctx.Err() == context.DeadlineExceeded {
 			util.LogError(mr.logger, "router", "process LLM streaming chunk", ctx.Err(),
 				"session_id", sessionID,
-				"model_id", modelID,
+				"model_id", servedBy,
 				"timeout", timeout,
 				"elapsed", time.Since(startTime))
 
@@ -455,8 +924,19 @@ func (mr *MessageRouter) HandleUserMessage(conn *websocket.Connection, msg *mess
 			return ctx.Err()
 		}
 
+		// The router is shutting down or the request was cancelled
+		// No else needed: early return pattern (stream abandoned)
+		if chunk == nil {
+			break
+		}
+
 		if chunk.Content != "" {
 			fullContent.WriteString(chunk.Content)
+			timer.markToken()
+		}
+		// No else needed: optional operation (providers with server-side threads report theirs)
+		if chunk.ThreadID != "" {
+			threadID = chunk.ThreadID
 		}
 
 		// Send chunk to client when there is content, or when the
@@ -467,15 +947,25 @@ func (mr *MessageRouter) HandleUserMessage(conn *websocket.Connection, msg *mess
 				SessionID: sessionID,
 				Content:   chunk.Content,
 				Sender:    message.SenderAI,
-				ModelID:   modelID,
+				ModelID:   servedBy,
 				Timestamp: time.Now(),
 				Metadata: map[string]string{
 					"streaming": "true",
 					"done":      fmt.Sprintf("%t", chunk.Done),
 				},
 			}
+			// No else needed: optional operation (only cancelled streams are truncated)
+			if truncated {
+				chunkMsg.Metadata[constants.MetadataKeyTruncated] = "true"
+			}
+			// No else needed: optional operation (the final chunk says who served the reply)
+			if chunk.Done {
+				for k, v := range served {
+					chunkMsg.Metadata[k] = v
+				}
+			}
 
-			if err := mr.sendToConnection(sessionID, chunkMsg); err != nil {
+			if err := mr.sendStreamChunk(sessionID, chunkMsg, chunk.Done, &fullContent); err != nil {
 				mr.logger.Warn("Failed to send chunk to client",
 					"session_id", sessionID,
 					"error", err)
@@ -493,24 +983,49 @@ func (mr *MessageRouter) HandleUserMessage(conn *websocket.Connection, msg *mess
 	if err := mr.sessionManager.RecordResponseTime(sessionID, responseTime); err != nil {
 		mr.logger.Warn("Failed to record response time", "session_id", sessionID, "error", err)
 	}
+	mr.updateProviderThread(sessionID, thread, servedBy, threadID, migrated)
 
 	// Persist the AI response to session and storage
 	if fullContent.Len() > 0 {
+		// Estimate token usage with the model's tokenizer
+		tokenCount = mr.countTokens(servedBy, fullContent.String())
+
+		metadata := map[string]string{constants.MetadataKeyTokens: strconv.Itoa(tokenCount)}
+		for k, v := range served {
+			metadata[k] = v
+		}
+		// No else needed: optional operation (flag replies cut short by the client)
+		if truncated {
+			metadata[constants.MetadataKeyTruncated] = "true"
+		}
+		mr.recordRetrieval(sessionID, metadata, docs)
+		aiContent, aiMetadata := mr.moderateAIResponse(sessionID, fullContent.String(), metadata, true)
 		aiSessionMsg := &session.Message{
-			Content:   fullContent.String(),
+			Content:   aiContent,
 			Timestamp: time.Now(),
 			Sender:    constants.SenderAI,
+			Metadata:  aiMetadata,
+			Timing:    timer.timing(time.Now()),
 		}
 		if err := mr.sessionManager.AddMessage(sessionID, aiSessionMsg); err != nil {
 			mr.logger.Warn("Failed to store AI response in session", "error", err, "session_id", sessionID)
 		}
-		mr.persistMessage(sessionID, aiSessionMsg)
+		mr.persistMessage(ctx, sessionID, aiSessionMsg)
 
-		// Estimate token usage (rough estimate: ~4 chars per token)
-		tokenCount = fullContent.Len() / constants.CharsPerToken
-		if err := mr.sessionManager.UpdateTokenUsage(sessionID, tokenCount); err != nil {
+		// Usage and the budget are charged for the prompt as well as the reply,
+		// like the totals providers report for non-streaming requests
+		usedTokens := tokenCount + mr.countPromptTokens(servedBy, llmMessages)
+		if err := mr.sessionManager.UpdateTokenUsage(sessionID, usedTokens); err != nil {
 			mr.logger.Warn("Failed to update token usage", "session_id", sessionID, "error", err)
 		}
+		mr.consumeTokenBudget(sess.TenantID, sess.UserID, usedTokens)
+		mr.recordUsage(sessionID, sess.TenantID, servedBy, usedTokens)
+
+		mr.scheduleAutoTitle(sess, modelID)
+		// No else needed: optional operation (only complete replies that were not blocked)
+		if !truncated && aiMetadata[constants.MetadataKeyModeration] != moderation.ActionBlock.String() {
+			mr.scheduleCodeRun(sess, aiContent)
+		}
 	}
 
 	return nil
@@ -533,13 +1048,9 @@ func (mr *MessageRouter) handleHelpRequest(conn *websocket.Connection, msg *mess
 	// Verify session exists and ownership
 	sess, err := mr.sessionManager.GetSession(msg.SessionID)
 	if err != nil {
-		return chaterrors.NewValidationError(
-			chaterrors.ErrCodeNotFound,
-			"Session not found",
-			err,
-		)
+		return chaterrors.ErrSessionNotFound(err)
 	}
-	if sess.UserID != conn.UserID {
+	if !ownsSession(conn, sess) {
 		mr.logger.Warn("Session ownership violation in help request",
 			"session_id", msg.SessionID,
 			"session_owner", sess.UserID,
@@ -551,6 +1062,15 @@ func (mr *MessageRouter) handleHelpRequest(conn *websocket.Connection, msg *mess
 		)
 	}
 
+	// Refresh the tier, which a session restored from storage does not carry
+	// No else needed: optional operation (only when the token claims a tier)
+	if conn.Tier != "" {
+		// No else needed: early return pattern (guard clause)
+		if err := mr.sessionManager.SetCustomerTier(msg.SessionID, conn.Tier); err != nil {
+			return chaterrors.ErrDatabaseError(err)
+		}
+	}
+
 	// Mark session as requiring assistance
 	if err := mr.sessionManager.MarkHelpRequested(msg.SessionID); err != nil {
 		util.LogError(mr.logger, "router", "mark help requested", err, "session_id", msg.SessionID)
@@ -561,23 +1081,15 @@ func (mr *MessageRouter) handleHelpRequest(conn *websocket.Connection, msg *mess
 		"session_id", msg.SessionID,
 		"user_id", sess.UserID)
 
-	// Send notification to admins
-	// No else needed: optional operation (fire-and-forget), only send if service is available
-	if mr.notificationService != nil {
-		userID := sess.UserID
-		sessionID := msg.SessionID
-		routerCtx := mr.ctx
-		mr.safeGo("helpRequestNotification", func() {
-			// Check if router is shutting down before sending notification
-			if routerCtx.Err() != nil {
-				return
-			}
-			if err := mr.notificationService.SendHelpRequestAlert(userID, sessionID); err != nil {
-				util.LogError(mr.logger, "router", "send help request notification", err,
-					"session_id", sessionID,
-					"user_id", userID)
-			}
-		})
+	mr.RecordSystemEvent(msg.SessionID, constants.SystemEventHandoff,
+		"User requested help from an administrator", nil)
+
+	mr.notifyAdmins(sess.UserID, sess.TenantID, msg.SessionID)
+
+	// Outside business hours the user is told when administrators are back
+	// No else needed: early return pattern (after hours)
+	if reply := mr.afterHoursReply(msg.SessionID, time.Now()); reply != nil {
+		return mr.sendToConnection(msg.SessionID, reply)
 	}
 
 	// Send confirmation message back to user
@@ -592,18 +1104,121 @@ func (mr *MessageRouter) handleHelpRequest(conn *websocket.Connection, msg *mess
 	return mr.sendToConnection(msg.SessionID, response)
 }
 
+// notifyAdmins sends a help request alert to admins in the background.
+func (mr *MessageRouter) notifyAdmins(userID, tenantID, sessionID string) {
+	mr.publishWebhook(webhook.Event{
+		Type:      webhook.EventHelpRequested,
+		SessionID: sessionID,
+		UserID:    userID,
+		TenantID:  tenantID,
+	})
+
+	// No else needed: early return pattern (only send if service is available)
+	if mr.notificationService == nil {
+		return
+	}
+
+	routerCtx := mr.ctx
+	mr.safeGo("helpRequestNotification", func() {
+		// Check if router is shutting down before sending notification
+		if routerCtx.Err() != nil {
+			return
+		}
+		if err := mr.notificationService.SendHelpRequestAlert(userID, sessionID); err != nil {
+			util.LogError(mr.logger, "router", "send help request notification", err,
+				"session_id", sessionID,
+				"user_id", userID)
+		}
+	})
+}
+
+// routeToHumanAgent delivers a stored user message in human-only mode.
+// If an admin is assisting the session the message is forwarded to them;
+// otherwise the session joins the help queue on its first message and the
+// user is told an administrator will reply.
+func (mr *MessageRouter) routeToHumanAgent(sess *session.Session, userMsg *session.Message) error {
+	sessionID := sess.ID
+
+	// Forward to the assisting admin when one has taken over the session
+	// No else needed: early return pattern (guard clause)
+	if adminID := sess.GetAssistingAdminID(); adminID != "" {
+		forward := &message.Message{
+			Type:      message.TypeUserMessage,
+			SessionID: sessionID,
+			Content:   userMsg.Content,
+			Sender:    message.SenderUser,
+			Metadata:  userMsg.Metadata,
+			Timestamp: userMsg.Timestamp,
+		}
+		data, err := util.MarshalJSON(forward)
+		if err != nil {
+			return chaterrors.ErrInvalidMessageFormat("failed to marshal message", err)
+		}
+
+		mr.mu.RLock()
+		adminConn, exists := mr.adminConns[adminID+":"+sessionID]
+		mr.mu.RUnlock()
+
+		// Admin connections are best-effort: a full/closing buffer drops the message.
+		if exists && !adminConn.SafeSend(data) {
+			mr.logger.Warn("Admin connection send channel full or closing", "admin_id", adminID)
+			metrics.AdminMessagesDropped.Inc()
+		}
+		return nil
+	}
+
+	alreadyQueued, err := mr.sessionManager.IsHelpRequested(sessionID)
+	if err != nil {
+		return chaterrors.ErrDatabaseError(err)
+	}
+	// No else needed: early return pattern (session already waiting for an admin)
+	if alreadyQueued {
+		return nil
+	}
+
+	if err := mr.sessionManager.MarkHelpRequested(sessionID); err != nil {
+		util.LogError(mr.logger, "router", "mark help requested", err, "session_id", sessionID)
+		return chaterrors.ErrDatabaseError(err)
+	}
+
+	mr.logger.Info("Session queued for human agent",
+		"session_id", sessionID,
+		"user_id", sess.UserID)
+
+	mr.RecordSystemEvent(sessionID, constants.SystemEventHandoff,
+		"Session queued for an administrator (LLM disabled)", nil)
+
+	mr.notifyAdmins(sess.UserID, sess.TenantID, sessionID)
+
+	// No else needed: early return pattern (after hours)
+	if reply := mr.afterHoursReply(sessionID, time.Now()); reply != nil {
+		return mr.sendToConnection(sessionID, reply)
+	}
+
+	queued := &message.Message{
+		Type:      message.TypeNotification,
+		SessionID: sessionID,
+		Content:   "Your message has been received. An administrator will join your session shortly.",
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+	}
+	return mr.sendToConnection(sessionID, queued)
+}
+
 // getOrCreateSession retrieves an existing session or creates a new one if not found.
 // If the client-provided sessionID is not in memory but the user already has an active
 // session (e.g. client used a stale/random ID), the existing active session is returned.
+// promptTemplateID selects the system prompt template of a newly created session;
+// it is ignored for existing sessions.
 // SECURITY: Enforces session ownership - users can only access their own sessions
-func (mr *MessageRouter) getOrCreateSession(conn *websocket.Connection, sessionID string) (*session.Session, error) {
+func (mr *MessageRouter) getOrCreateSession(conn *websocket.Connection, sessionID, promptTemplateID string) (*session.Session, error) {
 	// Try to get existing session
 	sess, err := mr.sessionManager.GetSession(sessionID)
 	// No else needed: early return pattern (guard clause)
 	if err == nil {
 		// CRITICAL FIX C1: Verify session ownership to prevent IDOR
 		// No else needed: early return pattern (guard clause)
-		if sess.UserID != conn.UserID {
+		if !ownsSession(conn, sess) {
 			mr.logger.Warn("Session ownership violation attempt",
 				"session_id", sessionID,
 				"session_owner", sess.UserID,
@@ -619,7 +1234,7 @@ func (mr *MessageRouter) getOrCreateSession(conn *websocket.Connection, sessionI
 
 	// Session not found - create new one or reuse existing active session
 	if errors.Is(err, session.ErrSessionNotFound) {
-		newSess, createErr := mr.createNewSession(conn)
+		newSess, createErr := mr.createNewSession(conn, promptTemplateID)
 		if createErr == nil {
 			return newSess, nil
 		}
@@ -627,7 +1242,7 @@ func (mr *MessageRouter) getOrCreateSession(conn *websocket.Connection, sessionI
 		// If creation failed because user already has an active session,
 		// return that existing session instead of erroring out.
 		if errors.Is(createErr, session.ErrActiveSessionExists) {
-			if activeSess, activeErr := mr.sessionManager.GetActiveSessionForUser(conn.UserID); activeErr == nil {
+			if activeSess, activeErr := mr.sessionManager.GetActiveSessionForTenantUser(conn.TenantID, conn.UserID); activeErr == nil {
 				mr.logger.Info("Reusing existing active session for user",
 					"requested_session_id", sessionID,
 					"active_session_id", activeSess.ID,
@@ -643,13 +1258,58 @@ func (mr *MessageRouter) getOrCreateSession(conn *websocket.Connection, sessionI
 	return nil, err
 }
 
-// createNewSession creates a new session for the user and persists it to the database
-func (mr *MessageRouter) createNewSession(conn *websocket.Connection) (*session.Session, error) {
+// createNewSession creates a new session for the user, with the selected system
+// prompt template if any, assigns it to the running A/B experiments and persists
+// it to the database
+func (mr *MessageRouter) createNewSession(conn *websocket.Connection, promptTemplateID string) (*session.Session, error) {
+	// No else needed: early return pattern (new sessions refused during maintenance)
+	if err := mr.checkMaintenance(time.Now()); err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (new sessions refused while read-only)
+	if err := mr.checkReadOnly(); err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := mr.validatePromptTemplate(conn, promptTemplateID); err != nil {
+		return nil, err
+	}
+
 	// Create session in memory
-	sess, err := mr.sessionManager.CreateSession(conn.UserID)
+	sess, err := mr.sessionManager.CreateSessionForTenant(conn.UserID, conn.TenantID)
 	if err != nil {
 		return nil, chaterrors.ErrDatabaseError(err)
 	}
+	// No else needed: optional operation (only when a template was selected)
+	if promptTemplateID != "" {
+		// No else needed: early return pattern (guard clause)
+		if err := mr.sessionManager.SetPromptTemplateID(sess.ID, promptTemplateID); err != nil {
+			mr.sessionManager.EndSession(sess.ID)
+			return nil, chaterrors.ErrDatabaseError(err)
+		}
+	}
+	mr.applyExperiments(sess, promptTemplateID)
+	// No else needed: optional operation (only when the client sent metadata)
+	if client := conn.ClientMetadata(); !client.IsZero() {
+		// No else needed: early return pattern (guard clause)
+		if err := mr.sessionManager.SetClientInfo(sess.ID, session.ClientInfo{
+			AppVersion: client.AppVersion,
+			Platform:   client.Platform,
+			UserAgent:  client.UserAgent,
+			ScreenSize: client.ScreenSize,
+		}); err != nil {
+			mr.sessionManager.EndSession(sess.ID)
+			return nil, chaterrors.ErrDatabaseError(err)
+		}
+	}
+	// No else needed: optional operation (only when the token claims a tier)
+	if conn.Tier != "" {
+		// No else needed: early return pattern (guard clause)
+		if err := mr.sessionManager.SetCustomerTier(sess.ID, conn.Tier); err != nil {
+			mr.sessionManager.EndSession(sess.ID)
+			return nil, chaterrors.ErrDatabaseError(err)
+		}
+	}
 
 	// Persist to database
 	if mr.storageService != nil {
@@ -660,6 +1320,12 @@ func (mr *MessageRouter) createNewSession(conn *websocket.Connection) (*session.
 		}
 	}
 
+	mr.publishEvent(events.Event{
+		Type:      events.TypeSessionCreated,
+		SessionID: sess.ID,
+		UserID:    sess.UserID,
+		TenantID:  sess.TenantID,
+	})
 	return sess, nil
 }
 
@@ -682,8 +1348,17 @@ func (mr *MessageRouter) handleModelSelection(conn *websocket.Connection, msg *m
 		return chaterrors.ErrMissingField("model_id")
 	}
 
+	// No models to choose from when LLM features are disabled
+	if mr.humanOnly {
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeInvalidFormat,
+			"Model selection is not available in human-only chat",
+			nil,
+		)
+	}
+
 	// Get or create session (user may switch model before sending any message)
-	sess, err := mr.getOrCreateSession(conn, msg.SessionID)
+	sess, err := mr.getOrCreateSession(conn, msg.SessionID, promptTemplateID(msg))
 	if err != nil {
 		return err
 	}
@@ -691,13 +1366,7 @@ func (mr *MessageRouter) handleModelSelection(conn *websocket.Connection, msg *m
 	// Re-register connection under the authoritative session ID if it changed
 	sessionID := sess.ID
 	if sessionID != msg.SessionID {
-		mr.mu.Lock()
-		if c, ok := mr.connections[msg.SessionID]; ok {
-			delete(mr.connections, msg.SessionID)
-			mr.connections[sessionID] = c
-		}
-		mr.mu.Unlock()
-		conn.SetSessionID(sessionID)
+		mr.rebindConnection(conn, msg.SessionID, sessionID)
 	}
 
 	// Validate model ID against configured providers
@@ -764,13 +1433,9 @@ func (mr *MessageRouter) handleFileUpload(conn *websocket.Connection, msg *messa
 	// Verify session exists and ownership
 	sess, err := mr.sessionManager.GetSession(msg.SessionID)
 	if err != nil {
-		return chaterrors.NewValidationError(
-			chaterrors.ErrCodeNotFound,
-			"Session not found",
-			err,
-		)
+		return chaterrors.ErrSessionNotFound(err)
 	}
-	if sess.UserID != conn.UserID {
+	if !ownsSession(conn, sess) {
 		mr.logger.Warn("Session ownership violation in file upload",
 			"session_id", msg.SessionID,
 			"session_owner", sess.UserID,
@@ -803,7 +1468,7 @@ func (mr *MessageRouter) handleFileUpload(conn *websocket.Connection, msg *messa
 		util.LogError(mr.logger, "router", "store file upload message", err, "session_id", msg.SessionID)
 		return chaterrors.ErrDatabaseError(err)
 	}
-	mr.persistMessage(msg.SessionID, sessionMsg)
+	mr.persistMessage(mr.ctx, msg.SessionID, sessionMsg)
 
 	// Broadcast file upload notification to all session participants
 	notification := &message.Message{
@@ -854,13 +1519,9 @@ func (mr *MessageRouter) handleVoiceMessage(conn *websocket.Connection, msg *mes
 	// Verify session exists and ownership
 	sess, err := mr.sessionManager.GetSession(msg.SessionID)
 	if err != nil {
-		return chaterrors.NewValidationError(
-			chaterrors.ErrCodeNotFound,
-			"Session not found",
-			err,
-		)
+		return chaterrors.ErrSessionNotFound(err)
 	}
-	if sess.UserID != conn.UserID {
+	if !ownsSession(conn, sess) {
 		mr.logger.Warn("Session ownership violation in voice message",
 			"session_id", msg.SessionID,
 			"session_owner", sess.UserID,
@@ -878,6 +1539,14 @@ func (mr *MessageRouter) handleVoiceMessage(conn *websocket.Connection, msg *mes
 		"file_url", redactURLQuery(msg.FileURL),
 		"user_id", sess.UserID)
 
+	return mr.deliverVoiceMessage(sess, msg, "")
+}
+
+// deliverVoiceMessage stores a voice message (its content, file and metadata),
+// broadcasts it to all session participants and forwards it to the LLM if the
+// LLM service is available. transcript, when set, is sent to the LLM instead
+// of the audio file reference.
+func (mr *MessageRouter) deliverVoiceMessage(sess *session.Session, msg *message.Message, transcript string) error {
 	// Convert message.Message to session.Message for storage
 	sessionMsg := &session.Message{
 		Content:   msg.Content,
@@ -889,54 +1558,63 @@ func (mr *MessageRouter) handleVoiceMessage(conn *websocket.Connection, msg *mes
 	}
 
 	// Store voice message in session
-	if err := mr.sessionManager.AddMessage(msg.SessionID, sessionMsg); err != nil {
-		util.LogError(mr.logger, "router", "store voice message", err, "session_id", msg.SessionID)
+	if err := mr.sessionManager.AddMessage(sess.ID, sessionMsg); err != nil {
+		util.LogError(mr.logger, "router", "store voice message", err, "session_id", sess.ID)
 		return chaterrors.ErrDatabaseError(err)
 	}
-	mr.persistMessage(msg.SessionID, sessionMsg)
+	mr.persistMessage(mr.ctx, sess.ID, sessionMsg)
 
 	// Broadcast voice message notification to all session participants
 	notification := &message.Message{
 		Type:      message.TypeVoiceMessage,
-		SessionID: msg.SessionID,
+		SessionID: sess.ID,
 		Content:   msg.Content, // Optional transcription
 		FileID:    msg.FileID,
 		FileURL:   msg.FileURL,
+		UploadID:  msg.UploadID, // Recording of a streamed voice message
 		Sender:    message.SenderUser,
 		Metadata:  msg.Metadata,
 		Timestamp: time.Now(),
 	}
 
 	// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
-	if err := mr.BroadcastToSession(msg.SessionID, notification); err != nil {
-		mr.logger.Warn("Failed to broadcast voice message", "error", err, "session_id", msg.SessionID)
+	if err := mr.BroadcastToSession(sess.ID, notification); err != nil {
+		mr.logger.Warn("Failed to broadcast voice message", "error", err, "session_id", sess.ID)
 	}
 
 	// Forward audio file reference to LLM for transcription/processing if LLM service is available
 	// No else needed: optional operation (fire-and-forget), only process if LLM service is available
 	voiceModelID := sess.GetModelID()
-	if mr.llmService != nil && voiceModelID != "" {
-		sessionID := msg.SessionID
+	if mr.llmService != nil && !mr.humanOnly && voiceModelID != "" {
+		sessionID := sess.ID
 		fileURL := msg.FileURL
+		llmCtx := llmParamsContext(mr.ctx, sess)
 		mr.safeGo("voiceMessageLLM", func() {
-			mr.processVoiceMessageWithLLM(sessionID, fileURL, voiceModelID)
+			mr.processVoiceMessageWithLLM(llmCtx, sessionID, fileURL, transcript, voiceModelID)
 		})
 	}
 
 	return nil
 }
 
-// processVoiceMessageWithLLM forwards the voice message to LLM for transcription
-func (mr *MessageRouter) processVoiceMessageWithLLM(sessionID string, audioFileURL string, modelID string) {
-	ctx, cancel := context.WithTimeout(mr.ctx, constants.VoiceProcessTimeout)
+// processVoiceMessageWithLLM forwards the voice message to LLM for transcription,
+// or its transcript when there is one
+func (mr *MessageRouter) processVoiceMessageWithLLM(ctx context.Context, sessionID string, audioFileURL string, transcript string, modelID string) {
+	ctx, cancel := context.WithTimeout(ctx, constants.VoiceProcessTimeout)
 	defer cancel()
+	ctx = withQueueNotice(ctx, mr.queuedNotice(sessionID, modelID))
 
 	// Create a message indicating the audio file for the LLM.
 	// Redact query parameters to avoid leaking pre-signed S3 credentials to external providers.
+	content := fmt.Sprintf("Audio file: %s", redactURLQuery(audioFileURL))
+	// No else needed: conditional assignment (already transcribed)
+	if transcript != "" {
+		content = transcript
+	}
 	llmMessages := []llm.ChatMessage{
 		{
 			Role:    constants.SenderUser,
-			Content: fmt.Sprintf("Audio file: %s", redactURLQuery(audioFileURL)),
+			Content: content,
 		},
 	}
 
@@ -946,36 +1624,44 @@ func (mr *MessageRouter) processVoiceMessageWithLLM(sessionID string, audioFileU
 		"model_id", modelID)
 
 	// Send to LLM for processing
-	resp, err := mr.llmService.SendMessage(ctx, modelID, llmMessages)
+	var resp *llm.LLMResponse
+	servedBy, release, err := mr.tryModels(ctx, modelID, func(ctx context.Context, modelID string) error {
+		var err error
+		resp, err = mr.llmService.SendMessage(ctx, modelID, llmMessages)
+		return err
+	})
 	// No else needed: early return pattern (guard clause)
 	if err != nil {
 		util.LogError(mr.logger, "router", "process voice message with LLM", err,
 			"session_id", sessionID)
 		return
 	}
+	release()
 
 	// If LLM provides a response (transcription or processing result), send it back
 	// No else needed: optional operation, only send if there's content
 	if resp.Content != "" {
+		content, metadata := mr.moderateAIResponse(sessionID, resp.Content, nil, false)
 		aiMessage := &message.Message{
 			Type:      message.TypeAIResponse,
 			SessionID: sessionID,
-			Content:   resp.Content,
+			Content:   content,
 			Sender:    message.SenderAI,
 			Timestamp: time.Now(),
 		}
 
 		// Store AI response
 		sessionMsg := &session.Message{
-			Content:   resp.Content,
+			Content:   content,
 			Timestamp: time.Now(),
 			Sender:    string(message.SenderAI),
+			Metadata:  metadata,
 		}
 		// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
 		if err := mr.sessionManager.AddMessage(sessionID, sessionMsg); err != nil {
 			util.LogError(mr.logger, "router", "store AI response", err, "session_id", sessionID)
 		}
-		mr.persistMessage(sessionID, sessionMsg)
+		mr.persistMessage(mr.ctx, sessionID, sessionMsg)
 
 		// Broadcast AI response
 		// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
@@ -988,6 +1674,11 @@ func (mr *MessageRouter) processVoiceMessageWithLLM(sessionID string, audioFileU
 		if err := mr.sessionManager.UpdateTokenUsage(sessionID, resp.TokensUsed); err != nil {
 			mr.logger.Warn("Failed to update token usage", "error", err, "session_id", sessionID)
 		}
+		// No else needed: optional operation (session may have expired during processing)
+		if sess, err := mr.sessionManager.GetSession(sessionID); err == nil {
+			mr.consumeTokenBudget(sess.TenantID, sess.UserID, resp.TokensUsed)
+			mr.recordUsage(sessionID, sess.TenantID, servedBy, resp.TokensUsed)
+		}
 
 		// Record response time
 		// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
@@ -1041,11 +1732,7 @@ func (mr *MessageRouter) HandleAIGeneratedFile(sessionID string, fileURL string,
 	// Verify session exists
 	_, err := mr.sessionManager.GetSession(sessionID)
 	if err != nil {
-		return chaterrors.NewValidationError(
-			chaterrors.ErrCodeNotFound,
-			"Session not found",
-			err,
-		)
+		return chaterrors.ErrSessionNotFound(err)
 	}
 
 	mr.logger.Info("AI generated file",
@@ -1067,7 +1754,7 @@ func (mr *MessageRouter) HandleAIGeneratedFile(sessionID string, fileURL string,
 		util.LogError(mr.logger, "router", "store AI generated file message", err, "session_id", sessionID)
 		return chaterrors.ErrDatabaseError(err)
 	}
-	mr.persistMessage(sessionID, sessionMsg)
+	mr.persistMessage(mr.ctx, sessionID, sessionMsg)
 
 	// Create AI response message with file
 	aiMessage := &message.Message{
@@ -1105,11 +1792,7 @@ func (mr *MessageRouter) HandleAIVoiceResponse(sessionID string, audioFileURL st
 	// Verify session exists
 	_, err := mr.sessionManager.GetSession(sessionID)
 	if err != nil {
-		return chaterrors.NewValidationError(
-			chaterrors.ErrCodeNotFound,
-			"Session not found",
-			err,
-		)
+		return chaterrors.ErrSessionNotFound(err)
 	}
 
 	mr.logger.Info("AI voice response generated",
@@ -1131,7 +1814,7 @@ func (mr *MessageRouter) HandleAIVoiceResponse(sessionID string, audioFileURL st
 		util.LogError(mr.logger, "router", "store AI voice response", err, "session_id", sessionID)
 		return chaterrors.ErrDatabaseError(err)
 	}
-	mr.persistMessage(sessionID, sessionMsg)
+	mr.persistMessage(mr.ctx, sessionID, sessionMsg)
 
 	// Create AI voice response message
 	aiMessage := &message.Message{
@@ -1155,15 +1838,33 @@ func (mr *MessageRouter) HandleAIVoiceResponse(sessionID string, audioFileURL st
 
 // sendToConnection sends a message to a specific session's connection
 func (mr *MessageRouter) sendToConnection(sessionID string, msg *message.Message) error {
-	data, err := util.MarshalJSON(msg)
+	data, err := mr.marshalForSession(sessionID, msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
+	mr.mirrorToWatchers(sessionID, data)
 	return mr.sendRawToConnection(sessionID, data)
 }
 
-// sendRawToConnection sends pre-marshaled bytes to a specific session's connection
+// sendRawToConnection sends pre-marshaled bytes to a specific session's connection,
+// forwarding them to the replica holding it when the session is connected elsewhere.
 func (mr *MessageRouter) sendRawToConnection(sessionID string, data []byte) error {
+	err := mr.sendRawLocal(sessionID, data)
+	// No else needed: early return pattern (connected to another replica)
+	if errors.Is(err, ErrConnectionNotFound) && mr.forwardFrame(cluster.SessionKey(sessionID), cluster.Envelope{
+		Kind:      cluster.KindUser,
+		SessionID: sessionID,
+		Data:      data,
+	}) {
+		return nil
+	}
+	return err
+}
+
+// sendRawLocal sends pre-marshaled bytes to a session's connection on this replica.
+// In multi-device mode the bytes fan out to every device of the session; only a
+// failed send to the active device is reported.
+func (mr *MessageRouter) sendRawLocal(sessionID string, data []byte) error {
 	mr.mu.RLock()
 	conn, exists := mr.connections[sessionID]
 	mr.mu.RUnlock()
@@ -1172,6 +1873,8 @@ func (mr *MessageRouter) sendRawToConnection(sessionID string, data []byte) erro
 		return fmt.Errorf("%w: session %s", ErrConnectionNotFound, sessionID)
 	}
 
+	mr.sendToDevices(sessionID, conn, data)
+
 	if !conn.SafeSend(data) {
 		return fmt.Errorf("connection send channel is full or closing for session %s", sessionID)
 	}
@@ -1192,18 +1895,15 @@ func (mr *MessageRouter) BroadcastToSession(sessionID string, msg *message.Messa
 	// Verify session exists
 	sess, err := mr.sessionManager.GetSession(sessionID)
 	if err != nil {
-		return chaterrors.NewValidationError(
-			chaterrors.ErrCodeNotFound,
-			"Session not found",
-			err,
-		)
+		return chaterrors.ErrSessionNotFound(err)
 	}
 
 	// Marshal once, reuse for all recipients
-	data, err := util.MarshalJSON(msg)
+	data, err := mr.marshalForSession(sessionID, msg)
 	if err != nil {
 		return chaterrors.ErrInvalidMessageFormat("failed to marshal message", err)
 	}
+	mr.mirrorToWatchers(sessionID, data)
 
 	// Send to user connection
 	// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
@@ -1215,24 +1915,39 @@ func (mr *MessageRouter) BroadcastToSession(sessionID string, msg *message.Messa
 	// No else needed: optional operation, only send if admin is assisting
 	assistingAdminID := sess.GetAssistingAdminID()
 	if assistingAdminID != "" {
-		adminConnKey := assistingAdminID + ":" + sessionID
-		mr.mu.RLock()
-		adminConn, exists := mr.adminConns[adminConnKey]
-		mr.mu.RUnlock()
-
-		// No else needed: optional operation, only send if admin connection exists
-		if exists {
-			// Admin connections are best-effort: a full/closing buffer drops the message.
-			if !adminConn.SafeSend(data) {
-				mr.logger.Warn("Admin connection send channel full or closing", "admin_id", assistingAdminID)
-				metrics.AdminMessagesDropped.Inc()
-			}
+		// No else needed: optional operation (the admin may be connected to another replica)
+		if !mr.sendToAdmin(assistingAdminID, sessionID, data) {
+			mr.forwardFrame(cluster.AdminKey(assistingAdminID, sessionID), cluster.Envelope{
+				Kind:      cluster.KindAdmin,
+				SessionID: sessionID,
+				AdminID:   assistingAdminID,
+				Data:      data,
+			})
 		}
 	}
 
 	return nil
 }
 
+// sendToAdmin sends pre-marshaled bytes to the connection of adminID to a
+// session on this replica. Returns false when there is none.
+func (mr *MessageRouter) sendToAdmin(adminID, sessionID string, data []byte) bool {
+	mr.mu.RLock()
+	adminConn, exists := mr.adminConns[adminID+":"+sessionID]
+	mr.mu.RUnlock()
+
+	// No else needed: early return pattern (not connected here)
+	if !exists {
+		return false
+	}
+	// Admin connections are best-effort: a full/closing buffer drops the message.
+	if !adminConn.SafeSend(data) {
+		mr.logger.Warn("Admin connection send channel full or closing", "admin_id", adminID)
+		metrics.AdminMessagesDropped.Inc()
+	}
+	return true
+}
+
 // GetConnection retrieves a connection by session ID
 func (mr *MessageRouter) GetConnection(sessionID string) (*websocket.Connection, error) {
 	mr.mu.RLock()
@@ -1260,11 +1975,10 @@ func (mr *MessageRouter) HandleAdminTakeover(adminConn *websocket.Connection, se
 	// Verify session exists
 	sess, err := mr.sessionManager.GetSession(sessionID)
 	if err != nil {
-		return chaterrors.NewValidationError(
-			chaterrors.ErrCodeNotFound,
-			"Session not found",
-			err,
-		)
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	if err := mr.verifyTenantAccess(sess, adminConn.TenantID, adminConn.Roles); err != nil {
+		return err
 	}
 
 	// Get admin name from connection (extracted from JWT claims)
@@ -1274,19 +1988,9 @@ func (mr *MessageRouter) HandleAdminTakeover(adminConn *websocket.Connection, se
 		adminName = adminConn.UserID // Fallback to user ID if name not available
 	}
 
-	// Mark session as admin-assisted (atomic check-and-set inside MarkAdminAssisted
-	// prevents TOCTOU race where two admins could both pass a pre-check)
-	if err := mr.sessionManager.MarkAdminAssisted(sessionID, adminConn.UserID, adminName); err != nil {
-		util.LogError(mr.logger, "router", "mark admin assisted", err, "session_id", sessionID)
-		// Check if it's an "already assisted" error via sentinel
-		if errors.Is(err, session.ErrAlreadyAssisted) {
-			return chaterrors.NewValidationError(
-				chaterrors.ErrCodeInvalidFormat,
-				err.Error(),
-				err,
-			)
-		}
-		return chaterrors.ErrDatabaseError(err)
+	// Claim the session for this admin, or join its waiting list if another admin holds it
+	if err := mr.claimTakeover(sess, adminConn, adminName); err != nil {
+		return err
 	}
 
 	// Register admin connection
@@ -1295,10 +1999,27 @@ func (mr *MessageRouter) HandleAdminTakeover(adminConn *websocket.Connection, se
 	mr.mu.Lock()
 	mr.adminConns[adminConnKey] = adminConn
 	mr.mu.Unlock()
+	mr.claimConnection(cluster.AdminKey(adminConn.UserID, sessionID))
 
 	// Increment admin takeover metric
 	metrics.AdminTakeovers.Inc()
 
+	mr.RecordSystemEvent(sessionID, constants.SystemEventAdminTakeover,
+		fmt.Sprintf("Administrator %s took over the session", adminName),
+		map[string]string{
+			"admin_id":   adminConn.UserID,
+			"admin_name": adminName,
+		})
+
+	mr.publishWebhook(webhook.Event{
+		Type:      webhook.EventAdminTakeover,
+		SessionID: sessionID,
+		UserID:    sess.UserID,
+		TenantID:  sess.TenantID,
+		AdminID:   adminConn.UserID,
+		Data:      map[string]string{"admin_name": adminName},
+	})
+
 	mr.logger.Info("Admin takeover initiated",
 		"session_id", sessionID,
 		"admin_id", adminConn.UserID,
@@ -1342,11 +2063,7 @@ func (mr *MessageRouter) HandleAdminLeave(adminID, sessionID string) error {
 	// Verify session exists
 	sess, err := mr.sessionManager.GetSession(sessionID)
 	if err != nil {
-		return chaterrors.NewValidationError(
-			chaterrors.ErrCodeNotFound,
-			"Session not found",
-			err,
-		)
+		return chaterrors.ErrSessionNotFound(err)
 	}
 
 	// Verify this admin is assisting the session
@@ -1359,8 +2076,10 @@ func (mr *MessageRouter) HandleAdminLeave(adminID, sessionID string) error {
 		)
 	}
 
-	// Clear admin assistance
-	if err := mr.sessionManager.ClearAdminAssistance(sessionID); err != nil {
+	// Clear admin assistance; the next waiting admin gets the session reserved
+	if err := mr.releaseTakeover(sessionID, func() error {
+		return mr.sessionManager.ClearAdminAssistance(sessionID)
+	}); err != nil {
 		util.LogError(mr.logger, "router", "clear admin assistance", err, "session_id", sessionID)
 		return chaterrors.ErrDatabaseError(err)
 	}
@@ -1370,6 +2089,7 @@ func (mr *MessageRouter) HandleAdminLeave(adminID, sessionID string) error {
 	mr.mu.Lock()
 	delete(mr.adminConns, adminConnKey)
 	mr.mu.Unlock()
+	mr.releaseConnection(cluster.AdminKey(adminID, sessionID))
 
 	mr.logger.Info("Admin left session",
 		"session_id", sessionID,
@@ -1377,6 +2097,13 @@ func (mr *MessageRouter) HandleAdminLeave(adminID, sessionID string) error {
 		"admin_name", adminName,
 		"user_id", sess.UserID)
 
+	mr.RecordSystemEvent(sessionID, constants.SystemEventAdminLeave,
+		fmt.Sprintf("Administrator %s left the session", adminName),
+		map[string]string{
+			"admin_id":   adminID,
+			"admin_name": adminName,
+		})
+
 	// Send admin leave message to user
 	adminLeaveMsg := &message.Message{
 		Type:      message.TypeAdminLeave,
@@ -1398,6 +2125,208 @@ func (mr *MessageRouter) HandleAdminLeave(adminID, sessionID string) error {
 	return nil
 }
 
+// handleHandbackMessage processes a handback sent by an admin WebSocket connection
+func (mr *MessageRouter) handleHandbackMessage(conn *websocket.Connection, msg *message.Message) error {
+	// No else needed: early return pattern (guard clause)
+	if !mr.policy.Allows(conn.Roles, authz.PermTakeover) {
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeInsufficientPerms,
+			"Only administrators can hand back a session",
+			nil,
+		)
+	}
+	return mr.HandleHandback(conn, msg.SessionID)
+}
+
+// HandleHandback returns a taken-over session to the AI. User messages are routed
+// to the LLM again, the intervention window is recorded in the session document,
+// and the user is notified. Any admin with access to the session may hand it back,
+// so a takeover does not outlive the admin who started it.
+func (mr *MessageRouter) HandleHandback(adminConn *websocket.Connection, sessionID string) error {
+	if adminConn == nil {
+		return ErrNilConnection
+	}
+	if sessionID == "" {
+		return chaterrors.ErrMissingField("session_id")
+	}
+
+	sess, err := mr.sessionManager.GetSession(sessionID)
+	if err != nil {
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	if err := mr.verifyTenantAccess(sess, adminConn.TenantID, adminConn.Roles); err != nil {
+		return err
+	}
+
+	// Hand back; the next waiting admin gets the session reserved
+	var intervention *session.AdminIntervention
+	err = mr.releaseTakeover(sessionID, func() error {
+		var handBackErr error
+		intervention, handBackErr = mr.sessionManager.HandBack(sessionID, adminConn.UserID)
+		return handBackErr
+	})
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, session.ErrNotAssisted) {
+			return chaterrors.NewValidationError(
+				chaterrors.ErrCodeInvalidFormat,
+				err.Error(),
+				err,
+			)
+		}
+		util.LogError(mr.logger, "router", "hand back session", err, "session_id", sessionID)
+		return chaterrors.ErrDatabaseError(err)
+	}
+
+	// Unregister the connection of the admin who took over (keyed by adminID:sessionID)
+	mr.mu.Lock()
+	delete(mr.adminConns, intervention.AdminID+":"+sessionID)
+	mr.mu.Unlock()
+	mr.releaseConnection(cluster.AdminKey(intervention.AdminID, sessionID))
+
+	// In-memory session is the source of truth; storage failure is logged but non-fatal
+	// No else needed: optional operation (storage may not be configured)
+	if mr.storageService != nil {
+		if err := mr.storageService.RecordHandback(sessionID, intervention); err != nil {
+			mr.logger.Warn("Failed to record handback in storage", "session_id", sessionID, "error", err)
+		}
+	}
+
+	mr.RecordSystemEvent(sessionID, constants.SystemEventAdminHandback,
+		fmt.Sprintf("Administrator %s handed the session back to the assistant", intervention.AdminName),
+		map[string]string{
+			"admin_id":       intervention.AdminID,
+			"admin_name":     intervention.AdminName,
+			"handed_back_by": adminConn.UserID,
+		})
+
+	mr.publishWebhook(webhook.Event{
+		Type:      webhook.EventAdminHandback,
+		SessionID: sessionID,
+		UserID:    sess.UserID,
+		TenantID:  sess.TenantID,
+		AdminID:   adminConn.UserID,
+		Data: map[string]string{
+			"assisting_admin_id": intervention.AdminID,
+			"duration_seconds":   strconv.FormatInt(int64(intervention.EndTime.Sub(intervention.StartTime).Seconds()), 10),
+		},
+	})
+
+	mr.logger.Info("Session handed back to AI",
+		"session_id", sessionID,
+		"admin_id", intervention.AdminID,
+		"handed_back_by", adminConn.UserID,
+		"user_id", sess.UserID)
+
+	handbackMsg := &message.Message{
+		Type:      message.TypeHandback,
+		SessionID: sessionID,
+		Content:   "You are now chatting with the AI assistant again",
+		Sender:    message.SenderAdmin,
+		Timestamp: intervention.EndTime,
+		Metadata: map[string]string{
+			"admin_id":   intervention.AdminID,
+			"admin_name": intervention.AdminName,
+		},
+	}
+
+	// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
+	if err := mr.sendToConnection(sessionID, handbackMsg); err != nil {
+		mr.logger.Warn("Failed to send handback message", "error", err, "session_id", sessionID)
+	}
+
+	return nil
+}
+
+// SendAdminMessage injects an admin-authored message into a session without an
+// admin WebSocket. The message is stored in the session, persisted, and
+// broadcast to the user and any assisting admin connection. Sessions assisted
+// by a different admin are rejected so two admins cannot talk over each other.
+func (mr *MessageRouter) SendAdminMessage(adminID, adminName, sessionID, content string) (*message.Message, error) {
+	if adminID == "" {
+		return nil, chaterrors.ErrMissingField("admin_id")
+	}
+	if sessionID == "" {
+		return nil, chaterrors.ErrMissingField("session_id")
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, chaterrors.ErrMissingField("content")
+	}
+	if len(content) > message.MaxContentLength {
+		return nil, chaterrors.ErrInvalidMessageFormat(
+			fmt.Sprintf("content exceeds maximum length of %d characters", message.MaxContentLength), nil)
+	}
+
+	// No else needed: early return pattern (new messages refused while read-only)
+	if err := mr.checkReadOnly(); err != nil {
+		return nil, err
+	}
+
+	sess, err := mr.sessionManager.GetSession(sessionID)
+	if err != nil {
+		// No else needed: early return pattern (session held by another replica)
+		if msg, forwarded, remoteErr := mr.sendAdminMessageRemote(adminID, adminName, sessionID, content); forwarded {
+			return msg, remoteErr
+		}
+		return nil, chaterrors.ErrSessionNotFound(err)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if assistingAdminID := sess.GetAssistingAdminID(); assistingAdminID != "" && assistingAdminID != adminID {
+		return nil, chaterrors.NewValidationError(
+			chaterrors.ErrCodeInvalidFormat,
+			fmt.Sprintf("Session %s is being assisted by another administrator", sessionID),
+			nil,
+		)
+	}
+
+	// No else needed: conditional assignment, value already set if condition is false
+	if adminName == "" {
+		adminName = adminID // Fallback to user ID if name not available
+	}
+
+	now := time.Now()
+	metadata := map[string]string{
+		"admin_id":   adminID,
+		"admin_name": adminName,
+	}
+
+	sessionMsg := &session.Message{
+		Content:   content,
+		Timestamp: now,
+		Sender:    string(message.SenderAdmin),
+		Metadata:  metadata,
+	}
+	// No else needed: optional operation, in-memory failure is logged but not fatal
+	if err := mr.sessionManager.AddMessage(sessionID, sessionMsg); err != nil {
+		mr.logger.Warn("Failed to store admin message in session", "error", err, "session_id", sessionID)
+	}
+	mr.persistMessage(mr.ctx, sessionID, sessionMsg)
+
+	adminMsg := &message.Message{
+		Type:      message.TypeAdminMessage,
+		SessionID: sessionID,
+		Content:   content,
+		Sender:    message.SenderAdmin,
+		Timestamp: now,
+		Metadata:  metadata,
+	}
+	// No else needed: optional operation (fire-and-forget), the message is already stored
+	if err := mr.BroadcastToSession(sessionID, adminMsg); err != nil {
+		mr.logger.Warn("Failed to broadcast admin message", "error", err, "session_id", sessionID)
+	}
+
+	mr.logger.Info("Admin message sent",
+		"session_id", sessionID,
+		"admin_id", adminID,
+		"user_id", sess.UserID,
+		"content_length", len(content))
+
+	return adminMsg, nil
+}
+
 // RegisterAdminConnection registers an admin connection keyed by adminID:sessionID.
 // This matches the key scheme used by HandleAdminTakeover and BroadcastToSession.
 // Key format: adminID + ":" + sessionID. Both IDs are guaranteed to be UUID-hex
@@ -1411,20 +2340,22 @@ func (mr *MessageRouter) RegisterAdminConnection(adminID string, sessionID strin
 	}
 
 	mr.mu.Lock()
-	defer mr.mu.Unlock()
-
 	adminConnKey := adminID + ":" + sessionID
 	mr.adminConns[adminConnKey] = conn
+	mr.mu.Unlock()
+
+	mr.claimConnection(cluster.AdminKey(adminID, sessionID))
 	return nil
 }
 
 // UnregisterAdminConnection removes an admin connection keyed by adminID:sessionID.
 func (mr *MessageRouter) UnregisterAdminConnection(adminID string, sessionID string) {
 	mr.mu.Lock()
-	defer mr.mu.Unlock()
-
 	adminConnKey := adminID + ":" + sessionID
 	delete(mr.adminConns, adminConnKey)
+	mr.mu.Unlock()
+
+	mr.releaseConnection(cluster.AdminKey(adminID, sessionID))
 }
 
 // HandleError handles errors by sending appropriate error messages to the client
@@ -1502,7 +2433,7 @@ func (mr *MessageRouter) handleChatError(sessionID string, chatErr *chaterrors.C
 						"error", err)
 				}
 
-				mr.UnregisterConnection(closeSID)
+				mr.UnregisterDevice(closeSID, conn)
 			})
 		}
 	}
@@ -1541,7 +2472,11 @@ func (mr *MessageRouter) Shutdown() {
 	// Wait for all goroutines launched via safeGo to finish before tearing down
 	// dependencies (MongoDB, LLM service, etc.).
 	mr.wg.Wait()
+	mr.stopVoiceStreams()
 	if mr.messageLimiter != nil {
 		mr.messageLimiter.StopCleanup()
 	}
+	if mr.guestLimiter != nil {
+		mr.guestLimiter.StopCleanup()
+	}
 }
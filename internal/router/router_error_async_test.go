@@ -42,6 +42,30 @@ func (m *mockStorageForAsync) UpdateSessionModelID(sessionID, modelID string) er
 	return nil
 }
 
+func (m *mockStorageForAsync) RecordHandback(sessionID string, intervention *session.AdminIntervention) error {
+	return nil
+}
+
+func (m *mockStorageForAsync) UpdateSessionLLMParams(sessionID string, params *session.LLMParams) error {
+	return nil
+}
+
+func (m *mockStorageForAsync) UpdateSessionProviderThread(sessionID string, thread *session.ProviderThread) error {
+	return nil
+}
+
+func (m *mockStorageForAsync) SetMessageFeedback(sessionID string, index int, feedback *session.Feedback) error {
+	return nil
+}
+
+func (m *mockStorageForAsync) MarkMessagesRead(sessionID string, indexes []int, at time.Time) error {
+	return nil
+}
+
+func (m *mockStorageForAsync) EndSession(sessionID string, endTime time.Time) error {
+	return nil
+}
+
 func TestHandleChatError_FatalDoesNotBlock(t *testing.T) {
 	logger, err := golog.InitLog(golog.LogConfig{
 		Dir:            t.TempDir(),
@@ -32,7 +32,7 @@ func TestIntegration_CompleteMessageFlow(t *testing.T) {
 	userConn := mockConnection("user-123")
 
 	// Create session
-	sess, err := router.getOrCreateSession(userConn, "test-session-1")
+	sess, err := router.getOrCreateSession(userConn, "test-session-1", "")
 	require.NoError(t, err)
 	require.NotNil(t, sess)
 	sessionID := sess.ID
@@ -87,7 +87,7 @@ func TestIntegration_AdminTakeoverFlow(t *testing.T) {
 
 	// Create user connection and session
 	userConn := mockConnection("user-456")
-	sess, err := router.getOrCreateSession(userConn, "test-session-2")
+	sess, err := router.getOrCreateSession(userConn, "test-session-2", "")
 	require.NoError(t, err)
 	sessionID := sess.ID
 
@@ -166,7 +166,7 @@ func TestIntegration_FileUploadFlow(t *testing.T) {
 
 	// Create user connection and session
 	userConn := mockConnection("user-789")
-	sess, err := router.getOrCreateSession(userConn, "test-session-3")
+	sess, err := router.getOrCreateSession(userConn, "test-session-3", "")
 	require.NoError(t, err)
 	sessionID := sess.ID
 
@@ -234,7 +234,7 @@ func TestIntegration_VoiceMessageFlow(t *testing.T) {
 
 	// Create user connection and session
 	userConn := mockConnection("user-999")
-	sess, err := router.getOrCreateSession(userConn, "test-session-4")
+	sess, err := router.getOrCreateSession(userConn, "test-session-4", "")
 	require.NoError(t, err)
 	sessionID := sess.ID
 
@@ -304,7 +304,7 @@ func TestIntegration_MultipleFlowsCombined(t *testing.T) {
 
 	// Create user connection and session
 	userConn := mockConnection("user-combined")
-	sess, err := router.getOrCreateSession(userConn, "test-session-combined")
+	sess, err := router.getOrCreateSession(userConn, "test-session-combined", "")
 	require.NoError(t, err)
 	sessionID := sess.ID
 
@@ -44,6 +44,30 @@ func (m *MockStorageService) UpdateSessionModelID(sessionID, modelID string) err
 	return nil
 }
 
+func (m *MockStorageService) RecordHandback(sessionID string, intervention *session.AdminIntervention) error {
+	return nil
+}
+
+func (m *MockStorageService) UpdateSessionLLMParams(sessionID string, params *session.LLMParams) error {
+	return nil
+}
+
+func (m *MockStorageService) UpdateSessionProviderThread(sessionID string, thread *session.ProviderThread) error {
+	return nil
+}
+
+func (m *MockStorageService) SetMessageFeedback(sessionID string, index int, feedback *session.Feedback) error {
+	return nil
+}
+
+func (m *MockStorageService) MarkMessagesRead(sessionID string, indexes []int, at time.Time) error {
+	return nil
+}
+
+func (m *MockStorageService) EndSession(sessionID string, endTime time.Time) error {
+	return nil
+}
+
 // MockLLMService for testing
 type MockLLMService struct {
 	StreamMessageFunc func(context.Context, string, []llm.ChatMessage) (<-chan *llm.LLMChunk, error)
@@ -116,7 +140,7 @@ func TestProductionIssue02_SessionIDConsistency(t *testing.T) {
 	// Sub-task 2.2.1: Test getOrCreateSession flow
 	t.Log("Testing getOrCreateSession flow with non-existent session...")
 	clientProvidedID := "non-existent-session"
-	sess, err := mr.getOrCreateSession(conn, clientProvidedID)
+	sess, err := mr.getOrCreateSession(conn, clientProvidedID, "")
 	require.NoError(t, err)
 	require.NotNil(t, sess)
 	t.Log("✓ getOrCreateSession successfully created new session")
@@ -214,7 +238,7 @@ func TestProductionIssue02_CreateNewSessionFlow(t *testing.T) {
 
 		// Sub-task 2.3.2: Test createNewSession method
 		t.Log("Sub-task 2.3.2: Testing createNewSession method...")
-		sess, err := mr.createNewSession(conn)
+		sess, err := mr.createNewSession(conn, "")
 		require.NoError(t, err, "createNewSession should succeed")
 		require.NotNil(t, sess, "Session should not be nil")
 		assert.NotEmpty(t, sess.ID, "Session should have an ID")
@@ -275,7 +299,7 @@ func TestProductionIssue02_CreateNewSessionFlow(t *testing.T) {
 
 		// Attempt to create session (should fail)
 		t.Log("Attempting to create session with failing storage...")
-		sess, err := mr.createNewSession(conn)
+		sess, err := mr.createNewSession(conn, "")
 
 		// Verify error is returned
 		assert.Error(t, err, "createNewSession should return error on storage failure")
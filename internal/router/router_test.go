@@ -23,6 +23,12 @@ type mockStorageService struct {
 	createSessionCalled bool
 	createSessionError  error
 	createdSessions     []*session.Session
+	handbacks           []*session.AdminIntervention
+	llmParams           []*session.LLMParams
+	threads             []*session.ProviderThread
+	feedback            []*session.Feedback
+	readIndexes         []int
+	endedSessions       []string
 }
 
 func (m *mockStorageService) CreateSession(sess *session.Session) error {
@@ -46,6 +52,36 @@ func (m *mockStorageService) UpdateSessionModelID(sessionID, modelID string) err
 	return nil
 }
 
+func (m *mockStorageService) RecordHandback(sessionID string, intervention *session.AdminIntervention) error {
+	m.handbacks = append(m.handbacks, intervention)
+	return nil
+}
+
+func (m *mockStorageService) UpdateSessionLLMParams(sessionID string, params *session.LLMParams) error {
+	m.llmParams = append(m.llmParams, params)
+	return nil
+}
+
+func (m *mockStorageService) UpdateSessionProviderThread(sessionID string, thread *session.ProviderThread) error {
+	m.threads = append(m.threads, thread)
+	return nil
+}
+
+func (m *mockStorageService) SetMessageFeedback(sessionID string, index int, feedback *session.Feedback) error {
+	m.feedback = append(m.feedback, feedback)
+	return nil
+}
+
+func (m *mockStorageService) MarkMessagesRead(sessionID string, indexes []int, at time.Time) error {
+	m.readIndexes = append(m.readIndexes, indexes...)
+	return nil
+}
+
+func (m *mockStorageService) EndSession(sessionID string, endTime time.Time) error {
+	m.endedSessions = append(m.endedSessions, sessionID)
+	return nil
+}
+
 func TestNewMessageRouter(t *testing.T) {
 	logger := createTestLogger()
 	sm := session.NewSessionManager(15*time.Minute, logger)
@@ -381,7 +417,7 @@ func TestBroadcastToSession(t *testing.T) {
 				Timestamp: time.Now(),
 			},
 			wantErr: true,
-			errCode: chaterrors.ErrCodeNotFound,
+			errCode: chaterrors.ErrCodeSessionNotFound,
 		},
 	}
 
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/sandbox"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// CodeRunner runs code proposed by the LLM in a sandbox (to avoid coupling the
+// router to a concrete sandbox service)
+type CodeRunner interface {
+	Languages() []string
+	Run(ctx context.Context, sessionID string, snippet sandbox.Snippet) (*sandbox.Result, error)
+}
+
+// SetCodeRunner enables the code execution sandbox for the listed tenants:
+// after an AI reply in one of their sessions, the reply's code blocks in the
+// runner's languages are run and each output is appended to the session as a
+// code_execution system event. constants.SandboxDefaultTenant names the
+// default tenant and constants.SandboxAllTenants enables every tenant.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetCodeRunner(runner CodeRunner, tenants []string) {
+	mr.codeRunner = runner
+	mr.sandboxTenants = make(map[string]bool, len(tenants))
+	for _, tenant := range tenants {
+		// No else needed: conditional assignment (the default tenant has no ID)
+		if tenant == constants.SandboxDefaultTenant {
+			tenant = ""
+		}
+		mr.sandboxTenants[tenant] = true
+	}
+}
+
+// sandboxEnabled reports whether code in AI replies of tenantID's sessions is run
+func (mr *MessageRouter) sandboxEnabled(tenantID string) bool {
+	return mr.codeRunner != nil && (mr.sandboxTenants[constants.SandboxAllTenants] || mr.sandboxTenants[tenantID])
+}
+
+// scheduleCodeRun starts running the code blocks of an AI reply in the
+// background when the sandbox is enabled for the session's tenant
+func (mr *MessageRouter) scheduleCodeRun(sess *session.Session, reply string) {
+	// No else needed: early return pattern (sandbox not enabled for the tenant)
+	if !mr.sandboxEnabled(sess.TenantID) {
+		return
+	}
+	snippets := sandbox.ExtractCode(reply, mr.codeRunner.Languages(), constants.MaxSandboxRunsPerReply)
+	// No else needed: early return pattern (nothing to run)
+	if len(snippets) == 0 {
+		return
+	}
+
+	sessionID := sess.ID
+	mr.safeGo("codeRun", func() {
+		for _, snippet := range snippets {
+			mr.runCode(sessionID, snippet)
+		}
+	})
+}
+
+// runCode runs one snippet and appends its output to the session. Failures are
+// logged so that a sandbox outage never stops the chat.
+func (mr *MessageRouter) runCode(sessionID string, snippet sandbox.Snippet) {
+	ctx, cancel := context.WithTimeout(mr.ctx, constants.DefaultSandboxTimeout)
+	defer cancel()
+
+	result, err := mr.codeRunner.Run(ctx, sessionID, snippet)
+	// No else needed: early return pattern (the reply stands without its output)
+	if err != nil {
+		util.LogError(mr.logger, "router", "run code in sandbox", err,
+			"session_id", sessionID,
+			"language", snippet.Language)
+		return
+	}
+
+	content := sandbox.Output(result, constants.MaxSandboxOutputBytes)
+	metadata := map[string]string{
+		constants.MetadataKeyLanguage: snippet.Language,
+		constants.MetadataKeyExitCode: strconv.Itoa(result.ExitCode),
+	}
+	mr.RecordSystemEvent(sessionID, constants.SystemEventCodeExecution, content, metadata)
+
+	notice := &message.Message{
+		Type:      message.TypeNotification,
+		SessionID: sessionID,
+		Content:   content,
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	}
+	// No else needed: optional operation (the output is in the transcript either way)
+	if err := mr.sendToConnection(sessionID, notice); err != nil {
+		mr.logger.Warn("Failed to send code execution output", "session_id", sessionID, "error", err)
+	}
+}
@@ -0,0 +1,135 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/sandbox"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubCodeRunner records the snippets it runs and returns a fixed result or error
+type stubCodeRunner struct {
+	mu       sync.Mutex
+	result   *sandbox.Result
+	err      error
+	snippets []sandbox.Snippet
+}
+
+func (r *stubCodeRunner) Languages() []string { return []string{"python"} }
+
+func (r *stubCodeRunner) Run(_ context.Context, _ string, snippet sandbox.Snippet) (*sandbox.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snippets = append(r.snippets, snippet)
+	return r.result, r.err
+}
+
+func (r *stubCodeRunner) runs() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.snippets)
+}
+
+// codeReply is an AI reply proposing one Python and one unsupported snippet
+var codeReply = []string{"Try this:\n```python\nprint(2 + 2)\n```\n", "and\n```ruby\nputs 4\n```"}
+
+// codeEvents returns the code_execution system events of a session
+func codeEvents(sess *session.Session) []*session.Message {
+	sess.RLock()
+	defer sess.RUnlock()
+	var events []*session.Message
+	for _, m := range sess.Messages {
+		if m.Event == constants.SystemEventCodeExecution {
+			events = append(events, m)
+		}
+	}
+	return events
+}
+
+func sendCodeQuestion(t *testing.T, router *MessageRouter, sm *session.SessionManager, tenantID string) *session.Session {
+	t.Helper()
+	sess, err := sm.CreateSessionForTenant("user-1", tenantID)
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	conn.TenantID = tenantID
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "What is 2 + 2 in Python?",
+		Sender:    message.SenderUser,
+	}))
+	return sess
+}
+
+func TestRouteMessage_RunsProposedCode(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &streamingMockLLMService{chunks: codeReply}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+	runner := &stubCodeRunner{result: &sandbox.Result{Stdout: "4\n"}}
+	router.SetCodeRunner(runner, []string{constants.SandboxDefaultTenant})
+
+	sess := sendCodeQuestion(t, router, sm, "")
+
+	require.Eventually(t, func() bool { return len(codeEvents(sess)) == 1 }, time.Second, 10*time.Millisecond)
+	event := codeEvents(sess)[0]
+	assert.Equal(t, constants.SenderSystem, event.Sender)
+	assert.Contains(t, event.Content, "Exit code 0")
+	assert.Contains(t, event.Content, "4\n")
+	assert.Equal(t, "python", event.Metadata[constants.MetadataKeyLanguage])
+	assert.Equal(t, "0", event.Metadata[constants.MetadataKeyExitCode])
+
+	// Only the supported language was sent to the sandbox
+	assert.Equal(t, []sandbox.Snippet{{Language: "python", Code: "print(2 + 2)"}}, runner.snippets)
+}
+
+func TestRouteMessage_CodeRunGatedPerTenant(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &streamingMockLLMService{chunks: codeReply}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+	runner := &stubCodeRunner{result: &sandbox.Result{Stdout: "4\n"}}
+	router.SetCodeRunner(runner, []string{"acme"})
+
+	other := sendCodeQuestion(t, router, sm, "globex")
+	allowed := sendCodeQuestion(t, router, sm, "acme")
+
+	require.Eventually(t, func() bool { return len(codeEvents(allowed)) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Empty(t, codeEvents(other))
+	assert.Equal(t, 1, runner.runs())
+
+	assert.True(t, router.sandboxEnabled("acme"))
+	assert.False(t, router.sandboxEnabled(""))
+	router.SetCodeRunner(runner, []string{constants.SandboxAllTenants})
+	assert.True(t, router.sandboxEnabled("globex"))
+	assert.True(t, router.sandboxEnabled(""))
+}
+
+func TestRouteMessage_CodeRunFailureDoesNotBlockReply(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &streamingMockLLMService{chunks: codeReply}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+	runner := &stubCodeRunner{err: errors.New("sandbox unavailable")}
+	router.SetCodeRunner(runner, []string{constants.SandboxAllTenants})
+
+	sess := sendCodeQuestion(t, router, sm, "")
+
+	require.Eventually(t, func() bool { return runner.runs() == 1 }, time.Second, 10*time.Millisecond)
+	router.wg.Wait()
+	assert.Empty(t, codeEvents(sess))
+
+	sess.RLock()
+	defer sess.RUnlock()
+	assert.Equal(t, constants.SenderAI, sess.Messages[len(sess.Messages)-1].Sender)
+}
@@ -0,0 +1,340 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+)
+
+// ErrProviderQueueFull is returned for a request to a rate limited provider
+// whose send queue already holds the maximum number of requests
+var ErrProviderQueueFull = errors.New("LLM provider send queue is full")
+
+// queueNotice is told a queued request's position (1 is next) and the
+// estimated wait before it is sent
+type queueNotice func(position int, eta time.Duration)
+
+type queueNoticeKey struct{}
+
+// withQueueNotice returns ctx telling notice when its LLM request is queued
+func withQueueNotice(ctx context.Context, notice queueNotice) context.Context {
+	return context.WithValue(ctx, queueNoticeKey{}, notice)
+}
+
+// queueNoticeFrom returns the queue notice carried by ctx, or nil
+func queueNoticeFrom(ctx context.Context) queueNotice {
+	notice, _ := ctx.Value(queueNoticeKey{}).(queueNotice)
+	return notice
+}
+
+// queueTicket is a request's place in a send queue. It is not empty so that
+// every ticket has its own address.
+type queueTicket struct {
+	queuedAt time.Time // when the request joined the queue
+}
+
+// sendQueue holds the requests to one provider while it is rate limited
+type sendQueue struct {
+	until   time.Time      // the provider is not sent requests before then
+	backoff time.Duration  // wait after the next 429, doubled after each consecutive one
+	waiting []*queueTicket // queued requests in arrival order; only the head is sent
+	changed chan struct{}  // closed, and replaced, whenever until or waiting change
+}
+
+// broadcast wakes the requests waiting on q. Callers hold providerQueues.mu.
+func (q *sendQueue) broadcast() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// position returns the 1-based position of ticket in q, or 0 if it is not queued
+func (q *sendQueue) position(ticket *queueTicket) int {
+	for i, t := range q.waiting {
+		// No else needed: early return pattern (found)
+		if t == ticket {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// providerQueues holds LLM requests per provider while the provider rejects
+// requests for exceeding its rate limits. Requests to a provider that is not
+// rate limited, and has nothing queued, go straight through. After a 429 the
+// provider is left alone for a backoff, doubled after each consecutive 429,
+// and its queued requests are then sent one at a time in arrival order until
+// the queue is empty.
+type providerQueues struct {
+	mu             sync.Mutex
+	maxDepth       int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	queues         map[string]*sendQueue
+}
+
+// newProviderQueues returns send queues holding up to maxDepth requests per provider
+func newProviderQueues(maxDepth int) *providerQueues {
+	return &providerQueues{
+		maxDepth:       maxDepth,
+		initialBackoff: constants.ProviderQueueInitialBackoff,
+		maxBackoff:     constants.ProviderQueueMaxBackoff,
+		queues:         make(map[string]*sendQueue),
+	}
+}
+
+// queue returns the send queue of provider. Callers hold pq.mu.
+func (pq *providerQueues) queue(provider string) *sendQueue {
+	q, ok := pq.queues[provider]
+	// No else needed: optional operation (created on first use)
+	if !ok {
+		q = &sendQueue{changed: make(chan struct{})}
+		pq.queues[provider] = q
+	}
+	return q
+}
+
+// eta estimates the wait of the request at position in q. Callers hold pq.mu.
+func (pq *providerQueues) eta(q *sendQueue, position int, now time.Time) time.Duration {
+	wait := q.until.Sub(now)
+	// No else needed: conditional assignment (capacity already returned)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait + time.Duration(position-1)*constants.ProviderQueueSendEstimate
+}
+
+// send calls attempt once provider may be sent a request, queueing the request
+// while the provider is rate limited. A rate limited attempt queues the request
+// (or keeps it at the head of the queue) and tries again after the backoff;
+// notice, if not nil, is told each time. Any other result of attempt is returned.
+func (pq *providerQueues) send(ctx context.Context, provider string, notice queueNotice, attempt func() error) error {
+	var ticket *queueTicket
+	defer func() {
+		// No else needed: optional operation (only queued requests leave the queue)
+		if ticket != nil {
+			pq.leave(provider, ticket)
+		}
+	}()
+
+	for {
+		var err error
+		ticket, err = pq.wait(ctx, provider, ticket, notice)
+		// No else needed: early return pattern (queue full or request cancelled)
+		if err != nil {
+			return err
+		}
+
+		err = attempt()
+		// No else needed: early return pattern (sent, or failed for another reason)
+		if !llm.IsRateLimited(err) {
+			// No else needed: optional operation (a success ends the backoff)
+			if err == nil {
+				pq.recovered(provider)
+			}
+			return err
+		}
+		pq.limited(provider, ticket, notice)
+	}
+}
+
+// wait returns once the request holding ticket, or a new request if ticket is
+// nil, may be sent to provider. New requests are queued, and notice told, when
+// the provider is rate limited or other requests are queued. It returns the
+// request's ticket, nil if it was not queued, even on error.
+func (pq *providerQueues) wait(ctx context.Context, provider string, ticket *queueTicket, notice queueNotice) (*queueTicket, error) {
+	pq.mu.Lock()
+	q := pq.queue(provider)
+	now := time.Now()
+
+	// No else needed: optional operation (queued requests keep their place)
+	if ticket == nil {
+		// No else needed: early return pattern (nothing to wait for)
+		if len(q.waiting) == 0 && !now.Before(q.until) {
+			pq.mu.Unlock()
+			return nil, nil
+		}
+		// No else needed: early return pattern (backpressure)
+		if len(q.waiting) >= pq.maxDepth {
+			pq.mu.Unlock()
+			return nil, ErrProviderQueueFull
+		}
+		ticket = &queueTicket{queuedAt: now}
+		q.waiting = append(q.waiting, ticket)
+		metrics.LLMQueueDepth.WithLabelValues(provider).Set(float64(len(q.waiting)))
+		position := len(q.waiting)
+		eta := pq.eta(q, position, now)
+		pq.mu.Unlock()
+		// No else needed: optional operation (background requests have no client)
+		if notice != nil {
+			notice(position, eta)
+		}
+		pq.mu.Lock()
+	}
+
+	for {
+		now = time.Now()
+		// No else needed: early return pattern (the request's turn)
+		if q.waiting[0] == ticket && !now.Before(q.until) {
+			pq.mu.Unlock()
+			return ticket, nil
+		}
+		changed := q.changed
+		delay := q.until.Sub(now)
+		pq.mu.Unlock()
+
+		// Only the wait for the backoff to end needs a timer: everything
+		// else that lets the request go signals changed
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		// No else needed: optional operation (rate limited)
+		if delay > 0 {
+			timer = time.NewTimer(delay)
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-timeout:
+		}
+		// No else needed: optional operation (only when a timer was started)
+		if timer != nil {
+			timer.Stop()
+		}
+		// No else needed: early return pattern (request cancelled or timed out)
+		if ctx.Err() != nil {
+			return ticket, ctx.Err()
+		}
+		pq.mu.Lock()
+	}
+}
+
+// limited records that provider rejected the request holding ticket for
+// exceeding its rate limits, and tells notice of the new wait of the request
+// if it stays at the head of the queue
+func (pq *providerQueues) limited(provider string, ticket *queueTicket, notice queueNotice) {
+	metrics.LLMRateLimited.WithLabelValues(provider).Inc()
+	pq.mu.Lock()
+	q := pq.queue(provider)
+	now := time.Now()
+	// No else needed: conditional assignment (first 429 since the provider recovered)
+	if q.backoff == 0 {
+		q.backoff = pq.initialBackoff
+	}
+	// No else needed: optional operation (concurrent 429s do not extend each other's backoff)
+	if until := now.Add(q.backoff); until.After(q.until) {
+		q.until = until
+		q.backoff *= 2
+		// No else needed: conditional assignment (capped)
+		if q.backoff > pq.maxBackoff {
+			q.backoff = pq.maxBackoff
+		}
+	}
+	q.broadcast()
+	position := q.position(ticket)
+	eta := pq.eta(q, position, now)
+	pq.mu.Unlock()
+
+	// No else needed: optional operation (new requests are told when they are queued)
+	if position > 0 && notice != nil {
+		notice(position, eta)
+	}
+}
+
+// recovered records that provider accepted a request
+func (pq *providerQueues) recovered(provider string) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	pq.queue(provider).backoff = 0
+}
+
+// leave removes ticket from the send queue of provider, letting the next
+// request go
+func (pq *providerQueues) leave(provider string, ticket *queueTicket) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	q := pq.queue(provider)
+	for i, t := range q.waiting {
+		// No else needed: optional operation (remove the ticket)
+		if t == ticket {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			break
+		}
+	}
+	metrics.LLMQueueDepth.WithLabelValues(provider).Set(float64(len(q.waiting)))
+	q.broadcast()
+}
+
+// depth returns the number of requests queued for provider
+func (pq *providerQueues) depth(provider string) int {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	return len(pq.queue(provider).waiting)
+}
+
+// SetProviderQueue makes requests that a provider rejects for exceeding its
+// rate limits wait in a per-provider send queue of up to maxDepth requests,
+// instead of failing. Queued clients receive a queued message with their
+// position and estimated wait. Requests that find the queue full fail.
+// maxDepth 0 disables queueing. Must be called before the router handles any
+// messages.
+func (mr *MessageRouter) SetProviderQueue(maxDepth int) {
+	// No else needed: early return pattern (queueing disabled)
+	if maxDepth <= 0 {
+		mr.sendQueues = nil
+		return
+	}
+	mr.sendQueues = newProviderQueues(maxDepth)
+}
+
+// queueAttempt runs an attempt on modelID through the send queue of the
+// model's provider, when queueing is enabled
+func (mr *MessageRouter) queueAttempt(ctx context.Context, modelID string, attempt func(ctx context.Context, modelID string) error) (context.CancelFunc, error) {
+	// No else needed: early return pattern (queueing disabled)
+	if mr.sendQueues == nil {
+		return mr.runAttempt(ctx, modelID, attempt)
+	}
+
+	// Models outside the catalog have no provider and are queued on their own
+	provider := mr.modelInfo(modelID).Provider
+	// No else needed: conditional assignment (no provider)
+	if provider == "" {
+		provider = modelID
+	}
+	var release context.CancelFunc
+	err := mr.sendQueues.send(ctx, provider, queueNoticeFrom(ctx), func() error {
+		var err error
+		release, err = mr.runAttempt(ctx, modelID, attempt)
+		return err
+	})
+	return release, err
+}
+
+// queuedNotice returns the queue notice sending the client of sessionID a
+// queued message
+func (mr *MessageRouter) queuedNotice(sessionID, modelID string) queueNotice {
+	return func(position int, eta time.Duration) {
+		etaSeconds := int((eta + time.Second - 1) / time.Second)
+		queued := &message.Message{
+			Type:      message.TypeQueued,
+			SessionID: sessionID,
+			Sender:    message.SenderSystem,
+			ModelID:   modelID,
+			Timestamp: time.Now(),
+			Metadata: map[string]string{
+				"position":    strconv.Itoa(position),
+				"eta_seconds": strconv.Itoa(etaSeconds),
+			},
+		}
+		// No else needed: optional operation (the client may be disconnected)
+		if err := mr.sendToConnection(sessionID, queued); err != nil {
+			mr.logger.Debug("Queued notice not delivered", "session_id", sessionID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errRateLimited = errors.New("OpenAI API error (status 429): rate limit reached")
+
+// testQueues returns send queues with short backoffs
+func testQueues(maxDepth int) *providerQueues {
+	pq := newProviderQueues(maxDepth)
+	pq.initialBackoff = 50 * time.Millisecond
+	pq.maxBackoff = 200 * time.Millisecond
+	return pq
+}
+
+func TestProviderQueues_PassThrough(t *testing.T) {
+	pq := testQueues(10)
+	calls := 0
+	err := pq.send(context.Background(), "openai", nil, func() error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	// Other errors are returned without queueing
+	permanent := errors.New("status 401")
+	err = pq.send(context.Background(), "openai", nil, func() error { return permanent })
+	assert.Equal(t, permanent, err)
+	assert.Equal(t, 0, pq.depth("openai"))
+}
+
+func TestProviderQueues_RetriesAfterBackoff(t *testing.T) {
+	pq := testQueues(10)
+	var notices []int
+	attempts := 0
+	start := time.Now()
+	err := pq.send(context.Background(), "openai", func(position int, eta time.Duration) {
+		notices = append(notices, position)
+		assert.LessOrEqual(t, eta, pq.maxBackoff)
+	}, func() error {
+		attempts++
+		if attempts <= 2 {
+			return errRateLimited
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond, "backoff doubles after consecutive 429s")
+	assert.Equal(t, []int{1, 1}, notices, "queued, then told the new wait")
+	assert.Equal(t, 0, pq.depth("openai"))
+}
+
+func TestProviderQueues_InOrder(t *testing.T) {
+	pq := testQueues(10)
+	// A 429 makes the provider rate limited for the backoff
+	pq.limited("openai", nil, nil)
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		queued := make(chan struct{})
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := pq.send(context.Background(), "openai", func(position int, eta time.Duration) {
+				assert.Equal(t, i, position)
+				close(queued)
+			}, func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, i)
+				return nil
+			})
+			assert.NoError(t, err)
+		}(i)
+		<-queued
+	}
+	assert.Equal(t, 3, pq.depth("openai"))
+
+	// Other providers are not held up
+	require.NoError(t, pq.send(context.Background(), "anthropic", nil, func() error { return nil }))
+
+	wg.Wait()
+	assert.Equal(t, []int{1, 2, 3}, order)
+	assert.Equal(t, 0, pq.depth("openai"))
+}
+
+func TestProviderQueues_Backpressure(t *testing.T) {
+	pq := testQueues(1)
+	pq.maxBackoff = time.Hour
+	pq.initialBackoff = time.Hour
+	pq.limited("openai", nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	queued := make(chan struct{})
+	go func() {
+		done <- pq.send(ctx, "openai", func(int, time.Duration) { close(queued) }, func() error { return nil })
+	}()
+	<-queued
+
+	err := pq.send(context.Background(), "openai", nil, func() error { return nil })
+	assert.ErrorIs(t, err, ErrProviderQueueFull)
+
+	// Cancelled requests leave the queue
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, 0, pq.depth("openai"))
+}
+
+func TestRouteMessage_QueuedWhenRateLimited(t *testing.T) {
+	llmService := &fallbackLLMService{errs: map[string][]error{
+		"primary": {errRateLimited},
+	}}
+	router, sm := newFallbackRouter(t, llmService, FallbackConfig{})
+	router.SetProviderQueue(10)
+	router.sendQueues.initialBackoff = 10 * time.Millisecond
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetModelID(sess.ID, "primary"))
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	}))
+	assert.Equal(t, "Reply from primary", sess.Messages[len(sess.Messages)-1].Content)
+	assert.Equal(t, []string{"primary", "primary"}, llmService.recordedCalls())
+
+	var queued *message.Message
+	for queued == nil {
+		select {
+		case data := <-conn.ReceiveForTest():
+			var msg message.Message
+			require.NoError(t, json.Unmarshal(data, &msg))
+			if msg.Type == message.TypeQueued {
+				queued = &msg
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no queued message")
+		}
+	}
+	assert.Equal(t, "1", queued.Metadata["position"])
+	assert.Equal(t, "1", queued.Metadata["eta_seconds"])
+	assert.Equal(t, "primary", queued.ModelID)
+}
+
+func TestSetProviderQueue(t *testing.T) {
+	router, _ := newFallbackRouter(t, &fallbackLLMService{}, FallbackConfig{})
+	router.SetProviderQueue(5)
+	require.NotNil(t, router.sendQueues)
+	assert.Equal(t, 5, router.sendQueues.maxDepth)
+	router.SetProviderQueue(0)
+	assert.Nil(t, router.sendQueues)
+}
@@ -0,0 +1,35 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/sentiment"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// SentimentScorer queues user messages for sentiment analysis (to avoid
+// coupling the router to a concrete pipeline)
+type SentimentScorer interface {
+	Submit(job sentiment.Job)
+}
+
+// SetSentimentScorer enables sentiment analysis of user messages, which keeps
+// a rolling sentiment per session. Must be called before the router handles
+// any messages.
+func (mr *MessageRouter) SetSentimentScorer(scorer SentimentScorer) {
+	mr.sentiment = scorer
+}
+
+// scoreSentiment queues a stored user message for sentiment analysis. Messages
+// an admin sent with an impersonation token do not reflect the user's mood and
+// are skipped.
+func (mr *MessageRouter) scoreSentiment(conn *websocket.Connection, sessionID, content string) {
+	// No else needed: early return pattern (analysis disabled, or nothing to score)
+	if mr.sentiment == nil || content == "" || conn.ImpersonatorID != "" {
+		return
+	}
+	mr.sentiment.Submit(sentiment.Job{
+		SessionID: sessionID,
+		UserID:    conn.UserID,
+		TenantID:  conn.TenantID,
+		Text:      content,
+	})
+}
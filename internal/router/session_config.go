@@ -0,0 +1,130 @@
+package router
+
+import (
+	"context"
+	"time"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// handleSessionConfig sets the LLM parameters (temperature, max_tokens, top_p) of
+// a session. They are validated against the bounds of the session's model in the
+// model catalog and apply to all later LLM calls of the session. timeout_seconds
+// overrides the LLM reply timeout within the bounds set with SetLLMTimeoutBounds.
+// A config with no parameters resets the session to the model's defaults.
+func (mr *MessageRouter) handleSessionConfig(conn *websocket.Connection, msg *message.Message) error {
+	if conn == nil {
+		return ErrNilConnection
+	}
+	if msg == nil {
+		return ErrNilMessage
+	}
+
+	// Validate session ID
+	if msg.SessionID == "" {
+		return chaterrors.ErrMissingField("session_id")
+	}
+
+	// Validate config
+	if msg.Config == nil {
+		return chaterrors.ErrMissingField("config")
+	}
+
+	// There are no LLM calls to configure when LLM features are disabled
+	if mr.humanOnly || mr.llmService == nil {
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeInvalidFormat,
+			"LLM parameters are not available in human-only chat",
+			nil,
+		)
+	}
+
+	// Get or create session (user may configure the session before sending any message)
+	sess, err := mr.getOrCreateSession(conn, msg.SessionID, promptTemplateID(msg))
+	if err != nil {
+		return err
+	}
+
+	// Re-register connection under the authoritative session ID if it changed
+	sessionID := sess.ID
+	if sessionID != msg.SessionID {
+		mr.rebindConnection(conn, msg.SessionID, sessionID)
+	}
+
+	// Validate against the bounds of the model the session currently uses
+	modelID := mr.resolveModel(sessionID, sess.GetModelID())
+	params := llm.Params{
+		Temperature: msg.Config.Temperature,
+		MaxTokens:   msg.Config.MaxTokens,
+		TopP:        msg.Config.TopP,
+	}
+	if err := llm.ValidateParams(mr.modelInfo(modelID), params); err != nil {
+		return chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat, err.Error(), err)
+	}
+	timeout, err := mr.checkLLMTimeout(msg.Config.TimeoutSeconds)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+
+	// Store the parameters in the session (in-memory + persistent)
+	sessParams := session.LLMParams{
+		Temperature: params.Temperature,
+		MaxTokens:   params.MaxTokens,
+		TopP:        params.TopP,
+		Timeout:     timeout,
+	}
+	if err := mr.sessionManager.SetLLMParams(sessionID, sessParams); err != nil {
+		return chaterrors.ErrDatabaseError(err)
+	}
+	if mr.storageService != nil {
+		if err := mr.storageService.UpdateSessionLLMParams(sessionID, &sessParams); err != nil {
+			mr.logger.Warn("Failed to persist LLM parameters", "session_id", sessionID, "error", err)
+		}
+	}
+
+	mr.logger.Info("Session config", "session_id", sessionID, "model_id", modelID)
+
+	// Echo the accepted config back to the client
+	response := &message.Message{
+		Type:      message.TypeSessionConfig,
+		SessionID: sessionID,
+		ModelID:   modelID,
+		Config:    msg.Config,
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+	}
+
+	return mr.sendToConnection(sessionID, response)
+}
+
+// modelInfo returns the catalog entry of a model, or an entry without bounds if
+// the model is not in the catalog
+func (mr *MessageRouter) modelInfo(modelID string) llm.ModelInfo {
+	for _, m := range mr.llmService.GetAvailableModels() {
+		// No else needed: early return pattern (model found)
+		if m.ID == modelID {
+			return m
+		}
+	}
+	return llm.ModelInfo{ID: modelID}
+}
+
+// llmParamsContext returns ctx carrying the session's LLM parameters, if the
+// client set any
+func llmParamsContext(ctx context.Context, sess *session.Session) context.Context {
+	params := sess.GetLLMParams()
+	// No else needed: early return pattern (no parameters set)
+	if params == nil {
+		return ctx
+	}
+	return llm.WithParams(ctx, llm.Params{
+		Temperature: params.Temperature,
+		MaxTokens:   params.MaxTokens,
+		TopP:        params.TopP,
+	})
+}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCatalogLLMService reports a model catalog with per-session parameter bounds
+type mockCatalogLLMService struct {
+	mockLLMService
+	models []llm.ModelInfo
+}
+
+func (m *mockCatalogLLMService) GetAvailableModels() []llm.ModelInfo { return m.models }
+
+func TestHandleSessionConfig(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockCatalogLLMService{models: []llm.ModelInfo{
+		{ID: constants.DefaultModel, MaxTokensLimit: 1000, MaxTemperature: 1},
+	}}
+	storage := &mockStorageService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, storage, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+
+	temperature, topP := 0.5, 0.9
+	require.NoError(t, router.handleSessionConfig(conn, &message.Message{
+		Type:      message.TypeSessionConfig,
+		SessionID: sess.ID,
+		Config:    &message.SessionConfig{Temperature: &temperature, MaxTokens: 800, TopP: &topP},
+		Sender:    message.SenderUser,
+	}))
+	assert.Equal(t, []message.MessageType{message.TypeSessionConfig}, drainTypes(t, conn))
+
+	params := sess.GetLLMParams()
+	require.NotNil(t, params)
+	assert.Equal(t, 800, params.MaxTokens)
+	assert.Equal(t, 0.5, *params.Temperature)
+	assert.Equal(t, 0.9, *params.TopP)
+	require.Len(t, storage.llmParams, 1, "parameters are persisted")
+
+	// Values outside the model's bounds are rejected and keep the previous config
+	tooHot := 1.5
+	for _, cfg := range []*message.SessionConfig{
+		{MaxTokens: 2000},
+		{Temperature: &tooHot},
+		{TopP: new(float64)},
+	} {
+		err := router.handleSessionConfig(conn, &message.Message{
+			Type:      message.TypeSessionConfig,
+			SessionID: sess.ID,
+			Config:    cfg,
+			Sender:    message.SenderUser,
+		})
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 800, sess.GetLLMParams().MaxTokens)
+	assert.Len(t, storage.llmParams, 1)
+
+	// Missing config
+	assert.Error(t, router.handleSessionConfig(conn, &message.Message{
+		Type:      message.TypeSessionConfig,
+		SessionID: sess.ID,
+	}))
+}
+
+func TestHandleSessionConfig_HumanOnly(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	router.SetHumanOnlyMode(true)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	err = router.handleSessionConfig(conn, &message.Message{
+		Type:      message.TypeSessionConfig,
+		SessionID: sess.ID,
+		Config:    &message.SessionConfig{MaxTokens: 100},
+	})
+	assert.Error(t, err)
+	assert.Nil(t, sess.GetLLMParams())
+}
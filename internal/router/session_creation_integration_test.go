@@ -32,7 +32,7 @@ func TestIntegration_SessionCreationFlow(t *testing.T) {
 		conn := mockConnection("user-new-123")
 
 		// First, create a session to get a valid session ID
-		sess, err := router.getOrCreateSession(conn, "any-id")
+		sess, err := router.getOrCreateSession(conn, "any-id", "")
 		require.NoError(t, err)
 		require.NotNil(t, sess)
 		sessionID := sess.ID
@@ -78,7 +78,7 @@ func TestIntegration_SessionCreationFlow(t *testing.T) {
 
 		// Create initial session for user
 		conn1 := mockConnection("user-reconnect-456")
-		sess, err := router.getOrCreateSession(conn1, "any-id")
+		sess, err := router.getOrCreateSession(conn1, "any-id", "")
 		require.NoError(t, err)
 		require.NotNil(t, sess)
 		sessionID := sess.ID
@@ -100,7 +100,7 @@ func TestIntegration_SessionCreationFlow(t *testing.T) {
 
 		// Simulate reconnection with same session ID (getOrCreateSession should return existing)
 		conn2 := mockConnection("user-reconnect-456")
-		restoredSess, err := router.getOrCreateSession(conn2, sessionID)
+		restoredSess, err := router.getOrCreateSession(conn2, sessionID, "")
 		require.NoError(t, err)
 		require.NotNil(t, restoredSess)
 
@@ -136,7 +136,7 @@ func TestIntegration_SessionCreationFlow(t *testing.T) {
 				defer wg.Done()
 				conn := mockConnection(userID)
 				sessionID := fmt.Sprintf("concurrent-session-%d", id)
-				sess, err := router.getOrCreateSession(conn, sessionID)
+				sess, err := router.getOrCreateSession(conn, sessionID, "")
 				if err != nil {
 					errors <- err
 				} else {
@@ -186,7 +186,7 @@ func TestIntegration_SessionCreationFlow(t *testing.T) {
 		conn := mockConnection("user-db-fail-999")
 		sessionID := "db-fail-session"
 
-		sess, err := router.getOrCreateSession(conn, sessionID)
+		sess, err := router.getOrCreateSession(conn, sessionID, "")
 
 		// Should return error
 		require.Error(t, err)
@@ -223,7 +223,7 @@ func TestIntegration_SessionCreationWithMessages(t *testing.T) {
 		conn := mockConnection("user-msg-123")
 
 		// Create session first
-		sess, err := router.getOrCreateSession(conn, "any-id")
+		sess, err := router.getOrCreateSession(conn, "any-id", "")
 		require.NoError(t, err)
 		sessionID := sess.ID
 
@@ -269,7 +269,7 @@ func TestIntegration_SessionCreationWithMessages(t *testing.T) {
 		conn := mockConnection("user-msg-456")
 
 		// Create session
-		sess, err := router.getOrCreateSession(conn, "any-id")
+		sess, err := router.getOrCreateSession(conn, "any-id", "")
 		require.NoError(t, err)
 		sessionID := sess.ID
 
@@ -333,7 +333,7 @@ func TestIntegration_SessionCreationErrorRecovery(t *testing.T) {
 		conn := mockConnection("user-recovery-123")
 
 		// First attempt - should fail
-		sess1, err1 := router.getOrCreateSession(conn, "any-id")
+		sess1, err1 := router.getOrCreateSession(conn, "any-id", "")
 		require.Error(t, err1)
 		assert.Nil(t, sess1)
 
@@ -341,7 +341,7 @@ func TestIntegration_SessionCreationErrorRecovery(t *testing.T) {
 		mockStorage.createSessionError = nil
 
 		// Second attempt - should succeed (user doesn't have active session anymore)
-		sess2, err2 := router.getOrCreateSession(conn, "any-id")
+		sess2, err2 := router.getOrCreateSession(conn, "any-id", "")
 		require.NoError(t, err2)
 		assert.NotNil(t, sess2)
 
@@ -374,7 +374,7 @@ func TestIntegration_SessionCreationWithDifferentUsers(t *testing.T) {
 			conn := mockConnection(userID)
 			sessionID := fmt.Sprintf("session-%d", i)
 
-			sess, err := router.getOrCreateSession(conn, sessionID)
+			sess, err := router.getOrCreateSession(conn, sessionID, "")
 			require.NoError(t, err)
 			require.NotNil(t, sess)
 
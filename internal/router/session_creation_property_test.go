@@ -38,7 +38,7 @@ func TestProperty_AutomaticSessionCreationForNewUsers(t *testing.T) {
 			sessionID := fmt.Sprintf("new-session-%s", userID)
 
 			// Call getOrCreateSession with a non-existent session ID
-			sess, err := router.getOrCreateSession(conn, sessionID)
+			sess, err := router.getOrCreateSession(conn, sessionID, "")
 
 			// Should create a new session without error
 			if err != nil {
@@ -110,7 +110,7 @@ func TestProperty_ExistingSessionReuse(t *testing.T) {
 			conn := mockConnection(userID)
 
 			// Call getOrCreateSession with existing session ID
-			sess, err := router.getOrCreateSession(conn, existingSess.ID)
+			sess, err := router.getOrCreateSession(conn, existingSess.ID, "")
 
 			// Should return the existing session without error
 			if err != nil {
@@ -163,7 +163,7 @@ func TestProperty_SessionCreationWithProvidedID(t *testing.T) {
 			conn := mockConnection(userID)
 
 			// Call getOrCreateSession with a non-existent session ID
-			sess, err := router.getOrCreateSession(conn, sessionID)
+			sess, err := router.getOrCreateSession(conn, sessionID, "")
 
 			// Should create a new session without error
 			if err != nil {
@@ -223,7 +223,7 @@ func TestProperty_DualStorageConsistency(t *testing.T) {
 			conn := mockConnection(userID)
 
 			// Create new session
-			sess, err := router.createNewSession(conn)
+			sess, err := router.createNewSession(conn, "")
 			if err != nil {
 				t.Logf("Failed to create session: %v", err)
 				return false
@@ -298,7 +298,7 @@ func TestProperty_UserAssociationCorrectness(t *testing.T) {
 			conn := mockConnection(userID)
 
 			// Create new session
-			sess, err := router.createNewSession(conn)
+			sess, err := router.createNewSession(conn, "")
 			if err != nil {
 				t.Logf("Failed to create session: %v", err)
 				return false
@@ -358,7 +358,7 @@ func TestProperty_SessionCreationErrorHandling(t *testing.T) {
 			conn := mockConnection(userID)
 
 			// Attempt to create new session
-			sess, err := router.createNewSession(conn)
+			sess, err := router.createNewSession(conn, "")
 
 			// Should return error
 			if err == nil {
@@ -425,7 +425,7 @@ func TestProperty_ConcurrentSessionCreationSafety(t *testing.T) {
 				go func(id int) {
 					defer wg.Done()
 					sessionID := fmt.Sprintf("concurrent-session-%d", id)
-					_, err := router.getOrCreateSession(conn, sessionID)
+					_, err := router.getOrCreateSession(conn, sessionID, "")
 					results <- err
 				}(i)
 			}
@@ -490,7 +490,7 @@ func TestProperty_SessionRestorationFromDatabase(t *testing.T) {
 			conn := mockConnection(userID)
 
 			// Create initial session
-			sess, err := router.createNewSession(conn)
+			sess, err := router.createNewSession(conn, "")
 			if err != nil {
 				t.Logf("Failed to create initial session: %v", err)
 				return false
@@ -518,7 +518,7 @@ func TestProperty_SessionRestorationFromDatabase(t *testing.T) {
 			}
 
 			// Simulate reconnection by getting the session again
-			restoredSess, err := router.getOrCreateSession(conn, sess.ID)
+			restoredSess, err := router.getOrCreateSession(conn, sess.ID, "")
 			if err != nil {
 				t.Logf("Failed to restore session: %v", err)
 				return false
@@ -22,7 +22,7 @@ func TestGetOrCreateSession_AutomaticCreation(t *testing.T) {
 	sessionID := "new-session-id"
 
 	// Call getOrCreateSession with a non-existent session ID
-	sess, err := router.getOrCreateSession(conn, sessionID)
+	sess, err := router.getOrCreateSession(conn, sessionID, "")
 
 	// Should create a new session without error
 	require.NoError(t, err)
@@ -51,7 +51,7 @@ func TestGetOrCreateSession_ExistingSessionReuse(t *testing.T) {
 	conn := mockConnection("user-123")
 
 	// Call getOrCreateSession with existing session ID
-	sess, err := router.getOrCreateSession(conn, existingSess.ID)
+	sess, err := router.getOrCreateSession(conn, existingSess.ID, "")
 
 	// Should return the existing session
 	require.NoError(t, err)
@@ -76,7 +76,7 @@ func TestGetOrCreateSession_SessionCreationWithProvidedID(t *testing.T) {
 	providedSessionID := "provided-session-id"
 
 	// Call getOrCreateSession with a provided session ID that doesn't exist
-	sess, err := router.getOrCreateSession(conn, providedSessionID)
+	sess, err := router.getOrCreateSession(conn, providedSessionID, "")
 
 	// Should create a new session
 	require.NoError(t, err)
@@ -100,7 +100,7 @@ func TestCreateNewSession_DualStorage(t *testing.T) {
 	conn := mockConnection("user-789")
 
 	// Create new session
-	sess, err := router.createNewSession(conn)
+	sess, err := router.createNewSession(conn, "")
 
 	// Should succeed
 	require.NoError(t, err)
@@ -155,7 +155,7 @@ func TestCreateNewSession_UserIDAssociation(t *testing.T) {
 			conn := mockConnection(tt.userID)
 
 			// Create new session
-			sess, err := router.createNewSession(conn)
+			sess, err := router.createNewSession(conn, "")
 
 			// Should succeed
 			require.NoError(t, err)
@@ -187,7 +187,7 @@ func TestCreateNewSession_DatabaseFailure(t *testing.T) {
 	conn := mockConnection("user-999")
 
 	// Attempt to create new session
-	sess, err := router.createNewSession(conn)
+	sess, err := router.createNewSession(conn, "")
 
 	// Should return error
 	require.Error(t, err)
@@ -213,7 +213,7 @@ func TestCreateNewSession_RollbackOnPartialFailure(t *testing.T) {
 	conn := mockConnection("user-rollback")
 
 	// Attempt to create new session (will fail at database step)
-	sess, err := router.createNewSession(conn)
+	sess, err := router.createNewSession(conn, "")
 
 	// Should return error
 	require.Error(t, err)
@@ -240,12 +240,12 @@ func TestGetOrCreateSession_ConcurrentCreation(t *testing.T) {
 	sessionID := "concurrent-session"
 
 	// First call should create the session
-	sess1, err1 := router.getOrCreateSession(conn, sessionID)
+	sess1, err1 := router.getOrCreateSession(conn, sessionID, "")
 	require.NoError(t, err1)
 	assert.NotNil(t, sess1)
 
 	// Second call with same user should return error (user already has active session)
-	sess2, err2 := router.getOrCreateSession(conn, sessionID)
+	sess2, err2 := router.getOrCreateSession(conn, sessionID, "")
 
 	// Should either:
 	// 1. Return the existing session (if session ID matches)
@@ -273,7 +273,7 @@ func TestGetOrCreateSession_EmptyUserID(t *testing.T) {
 	sessionID := "test-session"
 
 	// Attempt to create session with empty user ID
-	sess, err := router.getOrCreateSession(conn, sessionID)
+	sess, err := router.getOrCreateSession(conn, sessionID, "")
 
 	// Should return error
 	require.Error(t, err)
@@ -303,7 +303,7 @@ func TestGetOrCreateSession_NilConnection(t *testing.T) {
 	}()
 
 	// This should panic
-	sess, err := router.getOrCreateSession(nil, sessionID)
+	sess, err := router.getOrCreateSession(nil, sessionID, "")
 
 	// If we get here without panic, the implementation has changed
 	// In that case, we should get an error
@@ -326,7 +326,7 @@ func TestCreateNewSession_SessionMetadata(t *testing.T) {
 	conn := mockConnection("user-metadata")
 
 	// Create new session
-	sess, err := router.createNewSession(conn)
+	sess, err := router.createNewSession(conn, "")
 
 	// Should succeed
 	require.NoError(t, err)
@@ -361,7 +361,7 @@ func TestGetOrCreateSession_MultipleUsers(t *testing.T) {
 		conn := mockConnection(userID)
 		sessionID := "session-" + userID
 
-		sess, err := router.getOrCreateSession(conn, sessionID)
+		sess, err := router.getOrCreateSession(conn, sessionID, "")
 		require.NoError(t, err)
 		assert.NotNil(t, sess)
 		assert.Equal(t, userID, sess.UserID)
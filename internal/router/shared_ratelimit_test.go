@@ -0,0 +1,80 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSetMessageLimiter_SharedAcrossRouters verifies that routers on different
+// replicas sharing a Redis-backed limiter enforce a single per-user limit
+func TestSetMessageLimiter_SharedAcrossRouters(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	logger := createTestLogger()
+	send := func(router *MessageRouter, sm *session.SessionManager) error {
+		sess, err := sm.CreateSession("user-1")
+		require.NoError(t, err)
+		conn := mockConnection("user-1")
+		conn.SessionID = sess.ID
+		require.NoError(t, router.RegisterConnection(sess.ID, conn))
+		return router.RouteMessage(conn, &message.Message{
+			Type:      message.TypeUserMessage,
+			SessionID: sess.ID,
+			Content:   "hello",
+			Sender:    message.SenderUser,
+			Timestamp: time.Now(),
+		})
+	}
+
+	smA := session.NewSessionManager(15*time.Minute, logger)
+	replicaA := NewMessageRouter(smA, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer replicaA.Shutdown()
+	replicaA.SetMessageLimiter(ratelimit.NewRedisLimiter(client, "message", time.Minute, 1))
+
+	smB := session.NewSessionManager(15*time.Minute, logger)
+	replicaB := NewMessageRouter(smB, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer replicaB.Shutdown()
+	replicaB.SetMessageLimiter(ratelimit.NewRedisLimiter(client, "message", time.Minute, 1))
+
+	require.NoError(t, send(replicaA, smA))
+
+	err := send(replicaB, smB)
+	var chatErr *chaterrors.ChatError
+	require.ErrorAs(t, err, &chatErr)
+	assert.Equal(t, chaterrors.ErrCodeTooManyRequests, chatErr.Code)
+	assert.Greater(t, chatErr.RetryAfter, 0)
+}
+
+// TestSetGuestMessageLimiter verifies that guests are limited by the guest
+// limiter while other users keep the regular message limit
+func TestSetGuestMessageLimiter(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	user := mockConnection("user-1")
+	guest := websocket.NewConnection("guest-1", []string{constants.RoleGuest})
+	assert.Same(t, router.messageLimiter, router.limiterFor(guest), "guests use the regular limit until a guest limiter is set")
+
+	router.SetGuestMessageLimiter(ratelimit.NewMessageLimiter(time.Minute, 1))
+	assert.Same(t, router.guestLimiter, router.limiterFor(guest))
+	assert.Same(t, router.messageLimiter, router.limiterFor(user))
+
+	assert.True(t, router.limiterFor(guest).Allow(guest.UserID))
+	assert.False(t, router.limiterFor(guest).Allow(guest.UserID))
+	assert.True(t, router.limiterFor(user).Allow(user.UserID))
+}
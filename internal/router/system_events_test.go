@@ -0,0 +1,122 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// systemEvents returns the event types recorded in a session transcript, in order
+func systemEvents(sess *session.Session) []string {
+	sess.RLock()
+	defer sess.RUnlock()
+
+	var events []string
+	for _, msg := range sess.Messages {
+		// No else needed: optional operation (only collect system events)
+		if msg.IsSystemEvent() {
+			events = append(events, msg.Event)
+		}
+	}
+	return events
+}
+
+func TestSystemEvents_TakeoverAndLeave(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	admin := websocket.NewConnection("admin-1", []string{"admin"})
+	admin.Name = "Alice"
+	require.NoError(t, router.HandleAdminTakeover(admin, sess.ID))
+	require.NoError(t, router.HandleAdminLeave("admin-1", sess.ID))
+
+	assert.Equal(t, []string{constants.SystemEventAdminTakeover, constants.SystemEventAdminLeave}, systemEvents(sess))
+
+	takeover := sess.Messages[0]
+	assert.Equal(t, constants.SenderSystem, takeover.Sender)
+	assert.Equal(t, "admin-1", takeover.Metadata["admin_id"])
+	assert.Equal(t, "Alice", takeover.Metadata["admin_name"])
+}
+
+func TestSystemEvents_HelpRequestRecordsHandoff(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.handleHelpRequest(conn, &message.Message{
+		Type:      message.TypeHelpRequest,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+	}))
+
+	assert.Equal(t, []string{constants.SystemEventHandoff}, systemEvents(sess))
+}
+
+func TestSystemEvents_Reconnect(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	// First connection and repeated registration of the same connection are not reconnects
+	first := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, first))
+	require.NoError(t, router.RegisterConnection(sess.ID, first))
+	assert.Empty(t, systemEvents(sess))
+
+	// A client resuming after a dropped connection is
+	router.UnregisterConnection(sess.ID)
+	second := mockConnection("user-1")
+	second.SetResumeFrom(0)
+	require.NoError(t, router.RegisterConnection(sess.ID, second))
+
+	assert.Equal(t, []string{constants.SystemEventReconnect}, systemEvents(sess))
+}
+
+func TestSystemEvents_PersistedToStorage(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	storage := &recordingStorage{}
+	router := NewMessageRouter(sm, nil, nil, nil, storage, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	router.RecordSystemEvent(sess.ID, constants.SystemEventRedaction, "Message removed by moderation", nil)
+
+	require.Len(t, storage.messages, 1)
+	assert.Equal(t, constants.SystemEventRedaction, storage.messages[0].Event)
+	assert.True(t, storage.messages[0].IsSystemEvent())
+}
+
+// recordingStorage records persisted messages
+type recordingStorage struct {
+	mockStorageService
+	messages []*session.Message
+}
+
+func (r *recordingStorage) AddMessage(sessionID string, msg *session.Message) error {
+	r.messages = append(r.messages, msg)
+	return nil
+}
@@ -0,0 +1,271 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// TakeoverDeniedError describes why HandleAdminTakeover denied a takeover: the
+// session is held by another admin, or reserved for the admin next in its waiting
+// list. It is the cause of the *chaterrors.ChatError returned, so callers can
+// retrieve it with errors.As.
+type TakeoverDeniedError struct {
+	SessionID  string
+	HolderID   string // admin holding or reserved to take over the session
+	HolderName string
+	Reserved   bool // the holder has not taken over yet: the session is reserved for them
+	Position   int  // 1-based position of the denied admin in the waiting list
+}
+
+func (e *TakeoverDeniedError) Error() string {
+	// No else needed: early return pattern (reserved sessions)
+	if e.Reserved {
+		return fmt.Sprintf("session reserved for another admin: %s (%s)", e.HolderName, e.HolderID)
+	}
+	return fmt.Sprintf("%s: %s (%s)", session.ErrAlreadyAssisted, e.HolderName, e.HolderID)
+}
+
+// Unwrap lets callers match the denial with errors.Is(err, session.ErrAlreadyAssisted)
+func (e *TakeoverDeniedError) Unwrap() error {
+	return session.ErrAlreadyAssisted
+}
+
+// takeoverWaiter is an admin waiting to take over a session
+type takeoverWaiter struct {
+	adminID   string
+	adminName string
+	conn      *websocket.Connection
+	since     time.Time
+}
+
+// takeoverClaim reserves a released session for the first waiting admin
+type takeoverClaim struct {
+	waiter takeoverWaiter
+	until  time.Time
+}
+
+// claimTakeover atomically assigns a session to an admin. The assignment is
+// sticky: it holds until the admin leaves or the session is handed back. While
+// another admin holds the session, or it is reserved for the next waiting admin,
+// the admin is added to the session's waiting list, sent a takeover_denied
+// message, and an error wrapping a *TakeoverDeniedError is returned.
+func (mr *MessageRouter) claimTakeover(sess *session.Session, adminConn *websocket.Connection, adminName string) error {
+	sessionID := sess.ID
+	now := time.Now()
+
+	mr.takeoverMu.Lock()
+	mr.pruneTakeoverWaitersLocked(sessionID, now)
+
+	// A released session is reserved for the first waiting admin for a short window
+	if claim, ok := mr.takeoverClaims[sessionID]; ok && claim.waiter.adminID != adminConn.UserID {
+		denied := &TakeoverDeniedError{
+			SessionID:  sessionID,
+			HolderID:   claim.waiter.adminID,
+			HolderName: claim.waiter.adminName,
+			Reserved:   true,
+			Position:   mr.enqueueTakeoverLocked(sessionID, adminConn, adminName, now),
+		}
+		mr.takeoverMu.Unlock()
+		return mr.denyTakeover(adminConn, denied)
+	}
+
+	// Atomic check-and-set inside MarkAdminAssisted prevents two admins from both
+	// taking over; the lock keeps the waiting list consistent with the holder
+	err := mr.sessionManager.MarkAdminAssisted(sessionID, adminConn.UserID, adminName)
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(err, session.ErrAlreadyAssisted) {
+		holderID, holderName := sess.GetAdminAssistance()
+		denied := &TakeoverDeniedError{
+			SessionID:  sessionID,
+			HolderID:   holderID,
+			HolderName: holderName,
+			Position:   mr.enqueueTakeoverLocked(sessionID, adminConn, adminName, now),
+		}
+		mr.takeoverMu.Unlock()
+		return mr.denyTakeover(adminConn, denied)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		mr.takeoverMu.Unlock()
+		util.LogError(mr.logger, "router", "mark admin assisted", err, "session_id", sessionID)
+		return chaterrors.ErrDatabaseError(err)
+	}
+
+	// The admin got the session: drop its reservation and its place in the queue
+	delete(mr.takeoverClaims, sessionID)
+	mr.removeTakeoverWaiterLocked(sessionID, adminConn.UserID)
+	mr.takeoverMu.Unlock()
+	return nil
+}
+
+// releaseTakeover runs release, which ends the session's admin assistance, and
+// then reserves the session for the first admin in its waiting list, who is
+// notified. Both steps happen under the takeover lock so no other admin can take
+// the session over in between.
+func (mr *MessageRouter) releaseTakeover(sessionID string, release func() error) error {
+	mr.takeoverMu.Lock()
+	// No else needed: early return pattern (guard clause)
+	if err := release(); err != nil {
+		mr.takeoverMu.Unlock()
+		return err
+	}
+
+	now := time.Now()
+	mr.pruneTakeoverWaitersLocked(sessionID, now)
+	delete(mr.takeoverClaims, sessionID)
+	waiters := mr.takeoverQueues[sessionID]
+	// No else needed: early return pattern (nobody is waiting)
+	if len(waiters) == 0 {
+		mr.takeoverMu.Unlock()
+		return nil
+	}
+	next := waiters[0]
+	mr.takeoverClaims[sessionID] = takeoverClaim{waiter: next, until: now.Add(constants.TakeoverClaimWindow)}
+	mr.removeTakeoverWaiterLocked(sessionID, next.adminID)
+	mr.takeoverMu.Unlock()
+
+	mr.logger.Info("Session reserved for next waiting admin",
+		"session_id", sessionID,
+		"admin_id", next.adminID,
+		"claim_window", constants.TakeoverClaimWindow)
+	mr.sendDirect(next.conn, &message.Message{
+		Type:      message.TypeNotification,
+		SessionID: sessionID,
+		Content:   fmt.Sprintf("Session %s is available for you to take over for the next %s", sessionID, constants.TakeoverClaimWindow),
+		Sender:    message.SenderSystem,
+		Timestamp: now,
+	})
+	return nil
+}
+
+// denyTakeover logs a denied takeover, sends the admin a takeover_denied message
+// and returns the error for HandleAdminTakeover
+func (mr *MessageRouter) denyTakeover(adminConn *websocket.Connection, denied *TakeoverDeniedError) error {
+	mr.logger.Info("Admin takeover denied",
+		"session_id", denied.SessionID,
+		"admin_id", adminConn.UserID,
+		"holder_id", denied.HolderID,
+		"reserved", denied.Reserved,
+		"queue_position", denied.Position)
+
+	mr.sendDirect(adminConn, &message.Message{
+		Type:      message.TypeTakeoverDenied,
+		SessionID: denied.SessionID,
+		Content:   fmt.Sprintf("Administrator %s is handling this session; you are number %d in line", denied.HolderName, denied.Position),
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"holder_id":      denied.HolderID,
+			"holder_name":    denied.HolderName,
+			"reserved":       strconv.FormatBool(denied.Reserved),
+			"queue_position": strconv.Itoa(denied.Position),
+		},
+	})
+	return chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat, denied.Error(), denied)
+}
+
+// sendDirect sends a message to one connection, best-effort like admin
+// connections: a full buffer drops the message
+func (mr *MessageRouter) sendDirect(conn *websocket.Connection, msg *message.Message) {
+	data, err := json.Marshal(msg)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(mr.logger, "router", "marshal direct message", err, "session_id", msg.SessionID)
+		return
+	}
+	// No else needed: optional operation (fire-and-forget)
+	if !conn.SafeSend(data) {
+		mr.logger.Warn("Direct message dropped",
+			"session_id", msg.SessionID,
+			"user_id", conn.UserID,
+			"type", string(msg.Type))
+	}
+}
+
+// enqueueTakeoverLocked adds an admin to a session's waiting list, or refreshes
+// its entry if it is already waiting, and returns its 1-based position.
+// Must be called with takeoverMu held.
+func (mr *MessageRouter) enqueueTakeoverLocked(sessionID string, conn *websocket.Connection, adminName string, now time.Time) int {
+	waiters := mr.takeoverQueues[sessionID]
+	for i := range waiters {
+		// No else needed: early return pattern (already waiting, keep the place in line)
+		if waiters[i].adminID == conn.UserID {
+			waiters[i].conn = conn
+			waiters[i].since = now
+			return i + 1
+		}
+	}
+	mr.takeoverQueues[sessionID] = append(waiters, takeoverWaiter{
+		adminID:   conn.UserID,
+		adminName: adminName,
+		conn:      conn,
+		since:     now,
+	})
+	return len(mr.takeoverQueues[sessionID])
+}
+
+// removeTakeoverWaiterLocked removes an admin from a session's waiting list.
+// Must be called with takeoverMu held.
+func (mr *MessageRouter) removeTakeoverWaiterLocked(sessionID, adminID string) {
+	waiters := mr.takeoverQueues[sessionID]
+	kept := waiters[:0]
+	for _, w := range waiters {
+		// No else needed: optional operation (filter out the admin)
+		if w.adminID != adminID {
+			kept = append(kept, w)
+		}
+	}
+	// No else needed: conditional assignment (drop empty lists)
+	if len(kept) == 0 {
+		delete(mr.takeoverQueues, sessionID)
+		return
+	}
+	mr.takeoverQueues[sessionID] = kept
+}
+
+// pruneTakeoverWaitersLocked drops waiting admins older than TakeoverWaitTimeout
+// and an expired reservation. Must be called with takeoverMu held.
+func (mr *MessageRouter) pruneTakeoverWaitersLocked(sessionID string, now time.Time) {
+	// No else needed: optional operation (reservation expired unclaimed)
+	if claim, ok := mr.takeoverClaims[sessionID]; ok && now.After(claim.until) {
+		delete(mr.takeoverClaims, sessionID)
+	}
+
+	waiters := mr.takeoverQueues[sessionID]
+	kept := waiters[:0]
+	for _, w := range waiters {
+		// No else needed: optional operation (filter out stale entries)
+		if now.Sub(w.since) <= constants.TakeoverWaitTimeout {
+			kept = append(kept, w)
+		}
+	}
+	// No else needed: conditional assignment (drop empty lists)
+	if len(kept) == 0 {
+		delete(mr.takeoverQueues, sessionID)
+		return
+	}
+	mr.takeoverQueues[sessionID] = kept
+}
+
+// TakeoverQueue returns the IDs of the admins waiting to take over a session, in order
+func (mr *MessageRouter) TakeoverQueue(sessionID string) []string {
+	mr.takeoverMu.Lock()
+	defer mr.takeoverMu.Unlock()
+
+	mr.pruneTakeoverWaitersLocked(sessionID, time.Now())
+	ids := make([]string, 0, len(mr.takeoverQueues[sessionID]))
+	for _, w := range mr.takeoverQueues[sessionID] {
+		ids = append(ids, w.adminID)
+	}
+	return ids
+}
@@ -0,0 +1,133 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func takeoverAdmin(id, name string) *websocket.Connection {
+	conn := websocket.NewConnection(id, []string{"admin"})
+	conn.Name = name
+	return conn
+}
+
+// lastMessage returns the last message queued on a connection
+func lastMessage(t *testing.T, conn *websocket.Connection) *message.Message {
+	t.Helper()
+	var last *message.Message
+	for {
+		select {
+		case data := <-conn.ReceiveForTest():
+			last = &message.Message{}
+			require.NoError(t, json.Unmarshal(data, last))
+			continue
+		default:
+		}
+		return last
+	}
+}
+
+func TestHandleAdminTakeover_ConcurrentClaimsHaveOneWinner(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	const admins = 10
+	errs := make([]error, admins)
+	var wg sync.WaitGroup
+	for i := 0; i < admins; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = router.HandleAdminTakeover(takeoverAdmin(string(rune('a'+i)), "Admin"), sess.ID)
+		}(i)
+	}
+	wg.Wait()
+
+	winners := 0
+	for _, err := range errs {
+		var denied *TakeoverDeniedError
+		switch {
+		case err == nil:
+			winners++
+		case errors.As(err, &denied):
+			assert.Equal(t, sess.AssistingAdminID, denied.HolderID)
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, winners)
+	assert.Len(t, router.TakeoverQueue(sess.ID), admins-1, "every denied admin waits in line")
+}
+
+func TestHandleAdminTakeover_WaitingListAndReservation(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, nil, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	alice := takeoverAdmin("admin-1", "Alice")
+	bob := takeoverAdmin("admin-2", "Bob")
+	carol := takeoverAdmin("admin-3", "Carol")
+	require.NoError(t, router.HandleAdminTakeover(alice, sess.ID))
+
+	// Denied admins learn who holds the session and their place in line
+	err = router.HandleAdminTakeover(bob, sess.ID)
+	var denied *TakeoverDeniedError
+	require.ErrorAs(t, err, &denied)
+	assert.ErrorIs(t, err, session.ErrAlreadyAssisted)
+	assert.Equal(t, "admin-1", denied.HolderID)
+	assert.Equal(t, "Alice", denied.HolderName)
+	assert.Equal(t, 1, denied.Position)
+
+	msg := lastMessage(t, bob)
+	require.NotNil(t, msg)
+	assert.Equal(t, message.TypeTakeoverDenied, msg.Type)
+	assert.Equal(t, "admin-1", msg.Metadata["holder_id"])
+	assert.Equal(t, "1", msg.Metadata["queue_position"])
+
+	require.ErrorAs(t, router.HandleAdminTakeover(carol, sess.ID), &denied)
+	assert.Equal(t, 2, denied.Position)
+	require.ErrorAs(t, router.HandleAdminTakeover(bob, sess.ID), &denied)
+	assert.Equal(t, 1, denied.Position, "retrying keeps the place in line")
+
+	// Handing back reserves the session for the first admin in line
+	drainTypes(t, bob)
+	require.NoError(t, router.HandleHandback(alice, sess.ID))
+	assert.Equal(t, []message.MessageType{message.TypeNotification}, drainTypes(t, bob))
+
+	require.ErrorAs(t, router.HandleAdminTakeover(carol, sess.ID), &denied)
+	assert.True(t, denied.Reserved)
+	assert.Equal(t, "admin-2", denied.HolderID)
+
+	require.NoError(t, router.HandleAdminTakeover(bob, sess.ID))
+	assert.Equal(t, []string{"admin-3"}, router.TakeoverQueue(sess.ID))
+
+	// An unclaimed reservation expires and the session is free again
+	require.NoError(t, router.HandleAdminLeave("admin-2", sess.ID))
+	router.takeoverMu.Lock()
+	claim := router.takeoverClaims[sess.ID]
+	assert.Equal(t, "admin-3", claim.waiter.adminID)
+	claim.until = time.Now().Add(-time.Second)
+	router.takeoverClaims[sess.ID] = claim
+	router.takeoverMu.Unlock()
+
+	require.NoError(t, router.HandleAdminTakeover(alice, sess.ID))
+	assert.Empty(t, router.TakeoverQueue(sess.ID))
+}
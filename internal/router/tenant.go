@@ -0,0 +1,55 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// ownsSession reports whether conn belongs to the user who owns sess.
+// User IDs are only unique within a tenant, so both must match.
+func ownsSession(conn *websocket.Connection, sess *session.Session) bool {
+	return sess.UserID == conn.UserID && sess.TenantID == conn.TenantID
+}
+
+// canAccessTenant reports whether a caller of tenantID with roles may act on sess.
+// Super admins may access sessions of every tenant.
+func canAccessTenant(tenantID string, roles []string, sess *session.Session) bool {
+	return sess.TenantID == tenantID || util.HasRole(roles, constants.RoleSuperAdmin)
+}
+
+// CheckTenantAccess verifies that an admin of tenantID with roles may act on the
+// session. Returns a NOT_FOUND ChatError for unknown sessions and an UNAUTHORIZED
+// ChatError for sessions of another tenant unless the admin is a super admin.
+func (mr *MessageRouter) CheckTenantAccess(sessionID, tenantID string, roles []string) error {
+	sess, err := mr.sessionManager.GetSession(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (session held by another replica)
+		if forwarded, remoteErr := mr.checkTenantAccessRemote(sessionID, tenantID, roles); forwarded {
+			return remoteErr
+		}
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	return mr.verifyTenantAccess(sess, tenantID, roles)
+}
+
+// verifyTenantAccess returns an UNAUTHORIZED ChatError when a caller of tenantID
+// with roles may not act on sess
+func (mr *MessageRouter) verifyTenantAccess(sess *session.Session, tenantID string, roles []string) error {
+	// No else needed: early return pattern (guard clause)
+	if !canAccessTenant(tenantID, roles, sess) {
+		mr.logger.Warn("Cross-tenant session access denied",
+			"session_id", sess.ID,
+			"session_tenant", sess.TenantID,
+			"requesting_tenant", tenantID)
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeUnauthorized,
+			"Session belongs to another tenant",
+			nil,
+		)
+	}
+	return nil
+}
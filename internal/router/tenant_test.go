@@ -0,0 +1,103 @@
+package router
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantConnection creates a connection for a user of the given tenant
+func tenantConnection(userID, tenantID string, roles ...string) *websocket.Connection {
+	conn := websocket.NewConnection(userID, roles)
+	conn.TenantID = tenantID
+	return conn
+}
+
+// requireErrorCode asserts that err is a ChatError with the given code
+func requireErrorCode(t *testing.T, err error, code chaterrors.ErrorCode) {
+	t.Helper()
+	var chatErr *chaterrors.ChatError
+	require.True(t, errors.As(err, &chatErr), "expected ChatError, got %v", err)
+	assert.Equal(t, code, chatErr.Code)
+}
+
+func TestTenantIsolation_SameUserIDInOtherTenant(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSessionForTenant("user-1", "tenant-a")
+	require.NoError(t, err)
+
+	// A user with the same ID in another tenant does not own the session
+	err = router.RegisterConnection(sess.ID, tenantConnection("user-1", "tenant-b"))
+	requireErrorCode(t, err, chaterrors.ErrCodeUnauthorized)
+
+	assert.NoError(t, router.RegisterConnection(sess.ID, tenantConnection("user-1", "tenant-a")))
+}
+
+func TestCreateNewSession_UsesConnectionTenant(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := router.getOrCreateSession(tenantConnection("user-1", "tenant-a"), "new-session", "")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", sess.TenantID)
+}
+
+func TestHandleAdminTakeover_RejectsCrossTenant(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSessionForTenant("user-1", "tenant-a")
+	require.NoError(t, err)
+
+	err = router.HandleAdminTakeover(tenantConnection("admin-b", "tenant-b", "admin"), sess.ID)
+	requireErrorCode(t, err, chaterrors.ErrCodeUnauthorized)
+	assert.Empty(t, sess.GetAssistingAdminID())
+
+	// Super admins may take over sessions of any tenant
+	require.NoError(t, router.HandleAdminTakeover(tenantConnection("root", "tenant-b", "super_admin"), sess.ID))
+	assert.Equal(t, "root", sess.GetAssistingAdminID())
+}
+
+func TestCheckTenantAccess(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSessionForTenant("user-1", "tenant-a")
+	require.NoError(t, err)
+
+	assert.NoError(t, router.CheckTenantAccess(sess.ID, "tenant-a", []string{"admin"}))
+	assert.NoError(t, router.CheckTenantAccess(sess.ID, "", []string{"super_admin"}))
+	requireErrorCode(t, router.CheckTenantAccess(sess.ID, "tenant-b", []string{"admin"}), chaterrors.ErrCodeUnauthorized)
+	requireErrorCode(t, router.CheckTenantAccess(sess.ID, "", []string{"admin"}), chaterrors.ErrCodeUnauthorized)
+	requireErrorCode(t, router.CheckTenantAccess("missing-session", "tenant-a", []string{"admin"}), chaterrors.ErrCodeSessionNotFound)
+}
+
+func TestWatchSession_RejectsCrossTenant(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSessionForTenant("user-1", "tenant-a")
+	require.NoError(t, err)
+
+	err = router.WatchSession(sess.ID, tenantConnection("admin-b", "tenant-b", "admin"))
+	requireErrorCode(t, err, chaterrors.ErrCodeUnauthorized)
+	assert.NoError(t, router.WatchSession(sess.ID, tenantConnection("admin-a", "tenant-a", "admin")))
+}
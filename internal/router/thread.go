@@ -0,0 +1,88 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// threadMigrationHeader introduces the conversation so far to a model that
+// takes over a session from another model's thread
+const threadMigrationHeader = "The conversation so far, continued from another model:"
+
+// llmThreadContext returns ctx continuing the session's LLM provider thread, if
+// it has one. Only requests to the thread's model continue it: other models,
+// e.g. along a fallback chain, start a new thread.
+func llmThreadContext(ctx context.Context, thread *session.ProviderThread) context.Context {
+	// No else needed: early return pattern (no thread)
+	if thread == nil {
+		return ctx
+	}
+	return llm.WithThread(ctx, llm.Thread{ModelID: thread.ModelID, ID: thread.ID})
+}
+
+// threadMigrationMessage returns the last constants.MaxThreadMigrationMessages
+// user and AI messages of the session before current, for a model that takes
+// over from the thread of another model, or nil when there are none. The old
+// thread held the context server-side, so the new model would otherwise start
+// without it.
+func threadMigrationMessage(sess *session.Session, current *session.Message) *llm.ChatMessage {
+	var turns []string
+	sess.RLock()
+	for _, m := range sess.Messages {
+		// No else needed: optional operation (only conversation content is migrated)
+		if m == current || m.IsSystemEvent() || (m.Sender != constants.SenderUser && m.Sender != constants.SenderAI) {
+			continue
+		}
+		turns = append(turns, fmt.Sprintf("%s: %s", m.Sender, m.Content))
+	}
+	sess.RUnlock()
+
+	// No else needed: early return pattern (nothing to migrate)
+	if len(turns) == 0 {
+		return nil
+	}
+	// No else needed: optional operation (keep the most recent turns)
+	if len(turns) > constants.MaxThreadMigrationMessages {
+		turns = turns[len(turns)-constants.MaxThreadMigrationMessages:]
+	}
+	return &llm.ChatMessage{
+		Role:    constants.SenderSystem,
+		Content: threadMigrationHeader + "\n\n" + strings.Join(turns, "\n\n"),
+	}
+}
+
+// updateProviderThread records the provider thread of a reply served by
+// servedBy, threadID being empty when its provider keeps none. A session that
+// migrated away from its thread drops it even then, so the conversation is
+// migrated only once; otherwise a fallback model without threads keeps the
+// session's thread for the next reply.
+func (mr *MessageRouter) updateProviderThread(sessionID string, previous *session.ProviderThread, servedBy, threadID string, migrated bool) {
+	var next *session.ProviderThread
+	// No else needed: optional operation (only providers with server-side threads report one)
+	if threadID != "" {
+		next = &session.ProviderThread{ModelID: servedBy, ID: threadID}
+	}
+	// No else needed: early return pattern (thread unchanged)
+	if (next == nil && !migrated) || (next != nil && previous != nil && *next == *previous) {
+		return
+	}
+
+	// No else needed: early return pattern (session may have expired during the reply)
+	if err := mr.sessionManager.SetProviderThread(sessionID, next); err != nil {
+		mr.logger.Warn("Failed to set provider thread", "session_id", sessionID, "error", err)
+		return
+	}
+	// No else needed: optional operation (persist only when storage is configured)
+	if mr.storageService != nil {
+		// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
+		if err := mr.storageService.UpdateSessionProviderThread(sessionID, next); err != nil {
+			util.LogError(mr.logger, "router", "persist provider thread", err, "session_id", sessionID)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package router
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderThread_ContinuedAndMigrated(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+
+	var mu sync.Mutex
+	var lastMessages []llm.ChatMessage
+	llmMock := &mockLLMServiceWithContext{
+		onStreamMessage: func(ctx context.Context, modelID string, messages []llm.ChatMessage) (<-chan *llm.LLMChunk, error) {
+			mu.Lock()
+			lastMessages = messages
+			mu.Unlock()
+			ch := make(chan *llm.LLMChunk, 2)
+			ch <- &llm.LLMChunk{Content: "Reply from " + modelID}
+			// Only the support model's provider keeps server-side threads
+			threadID := ""
+			if modelID == "support" {
+				threadID = "conv_1"
+			}
+			ch <- &llm.LLMChunk{Done: true, ThreadID: threadID}
+			close(ch)
+			return ch, nil
+		},
+	}
+	storage := &mockStorageService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, storage, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetModelID(sess.ID, "support"))
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	send := func(content string) []llm.ChatMessage {
+		t.Helper()
+		require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+			Type:      message.TypeUserMessage,
+			SessionID: sess.ID,
+			Content:   content,
+			Sender:    message.SenderUser,
+		}))
+		mu.Lock()
+		defer mu.Unlock()
+		return lastMessages
+	}
+
+	// The first reply starts a thread, which is stored with the session
+	send("hello")
+	assert.Equal(t, &session.ProviderThread{ModelID: "support", ID: "conv_1"}, sess.GetProviderThread())
+	require.Len(t, storage.threads, 1)
+	assert.Equal(t, "conv_1", storage.threads[0].ID)
+
+	// Continuing the thread sends only the new message and stores nothing
+	messages := send("and then?")
+	require.Len(t, messages, 1)
+	assert.Equal(t, "and then?", messages[0].Content)
+	assert.Len(t, storage.threads, 1)
+
+	// Another model takes over: the conversation so far is migrated once, and
+	// the old thread is dropped as the new provider keeps none
+	require.NoError(t, sm.SetModelID(sess.ID, "other"))
+	messages = send("still there?")
+	require.Len(t, messages, 2)
+	assert.Equal(t, constants.SenderSystem, messages[0].Role)
+	assert.Contains(t, messages[0].Content, "user: hello")
+	assert.Contains(t, messages[0].Content, "ai: Reply from support")
+	assert.NotContains(t, messages[0].Content, "still there?")
+	assert.Nil(t, sess.GetProviderThread())
+	require.Len(t, storage.threads, 2)
+	assert.Nil(t, storage.threads[1])
+
+	messages = send("thanks")
+	assert.Len(t, messages, 1, "the conversation is migrated only once")
+}
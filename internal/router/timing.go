@@ -0,0 +1,67 @@
+package router
+
+import (
+	"time"
+
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// pipelineTimer records when a user message reached each stage of the message
+// pipeline, so the AI reply can be stored with its stage durations
+type pipelineTimer struct {
+	received   time.Time
+	screened   time.Time
+	firstToken time.Time
+	lastToken  time.Time
+}
+
+// newPipelineTimer starts timing msg from when the transport received it, or
+// from now when the transport did not record it
+func newPipelineTimer(msg *message.Message) *pipelineTimer {
+	received := msg.ReceivedAt
+	// No else needed: conditional assignment (messages not received over a transport)
+	if received.IsZero() {
+		received = time.Now()
+	}
+	return &pipelineTimer{received: received}
+}
+
+// markScreened records that moderation and injection screening finished
+func (t *pipelineTimer) markScreened() {
+	t.screened = time.Now()
+}
+
+// markToken records an LLM token; the first call records the first token
+func (t *pipelineTimer) markToken() {
+	now := time.Now()
+	// No else needed: conditional assignment (only the first token starts streaming)
+	if t.firstToken.IsZero() {
+		t.firstToken = now
+	}
+	t.lastToken = now
+}
+
+// timing returns the stage durations of a reply persisted at persisted. Stages
+// that were not reached, e.g. a reply without tokens, take no time.
+func (t *pipelineTimer) timing(persisted time.Time) *session.MessageTiming {
+	screened := orTime(t.screened, t.received)
+	firstToken := orTime(t.firstToken, screened)
+	lastToken := orTime(t.lastToken, firstToken)
+	return &session.MessageTiming{
+		Moderation: screened.Sub(t.received),
+		FirstToken: firstToken.Sub(screened),
+		Streaming:  lastToken.Sub(firstToken),
+		Persist:    persisted.Sub(lastToken),
+		Total:      persisted.Sub(t.received),
+	}
+}
+
+// orTime returns t, or fallback when t is not set
+func orTime(t, fallback time.Time) time.Time {
+	// No else needed: early return pattern (stage not reached)
+	if t.IsZero() {
+		return fallback
+	}
+	return t
+}
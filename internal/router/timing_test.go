@@ -0,0 +1,63 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineTimer_Stages(t *testing.T) {
+	received := time.Now().Add(-time.Second)
+	timer := &pipelineTimer{
+		received:   received,
+		screened:   received.Add(10 * time.Millisecond),
+		firstToken: received.Add(300 * time.Millisecond),
+		lastToken:  received.Add(800 * time.Millisecond),
+	}
+	assert.Equal(t, &session.MessageTiming{
+		Moderation: 10 * time.Millisecond,
+		FirstToken: 290 * time.Millisecond,
+		Streaming:  500 * time.Millisecond,
+		Persist:    200 * time.Millisecond,
+		Total:      time.Second,
+	}, timer.timing(received.Add(time.Second)))
+
+	// Stages that were not reached take no time
+	timer = &pipelineTimer{received: received}
+	assert.Equal(t, &session.MessageTiming{Persist: time.Second, Total: time.Second}, timer.timing(received.Add(time.Second)))
+}
+
+func TestPipelineTimer_ReceivedAt(t *testing.T) {
+	received := time.Now().Add(-time.Minute)
+	assert.Equal(t, received, newPipelineTimer(&message.Message{ReceivedAt: received}).received)
+	assert.False(t, newPipelineTimer(&message.Message{}).received.IsZero(), "messages without a receive time start now")
+}
+
+func TestHandleUserMessage_RecordsTiming(t *testing.T) {
+	router, _, _ := newPromptTestRouter(t)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection("new-session", conn))
+
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:       message.TypeUserMessage,
+		SessionID:  "new-session",
+		Content:    "hello",
+		Sender:     message.SenderUser,
+		ReceivedAt: time.Now().Add(-50 * time.Millisecond),
+	}))
+
+	sess, err := router.sessionManager.GetSession(conn.GetSessionID())
+	require.NoError(t, err)
+	require.Len(t, sess.Messages, 2)
+	assert.Nil(t, sess.Messages[0].Timing, "user messages are not timed")
+	ai := sess.Messages[1]
+	assert.Equal(t, constants.SenderAI, ai.Sender)
+	require.NotNil(t, ai.Timing)
+	assert.GreaterOrEqual(t, ai.Timing.Moderation, 50*time.Millisecond, "timing starts when the message was received")
+	assert.Equal(t, ai.Timing.Total, ai.Timing.Moderation+ai.Timing.FirstToken+ai.Timing.Streaming+ai.Timing.Persist)
+}
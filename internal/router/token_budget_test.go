@@ -0,0 +1,108 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteMessage_RejectsUserOverTokenBudget(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockLLMService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	budget := ratelimit.NewTokenBudgetLimiter(50)
+	budget.Consume("user-1", 50)
+	router.SetTokenBudget(budget)
+
+	conn := mockConnection("user-1")
+	err := router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: "session-1",
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	})
+
+	var chatErr *chaterrors.ChatError
+	require.True(t, errors.As(err, &chatErr))
+	assert.Equal(t, chaterrors.ErrCodeQuotaExceeded, chatErr.Code)
+	assert.False(t, llmMock.streamCalled, "LLM must not be called once the budget is spent")
+
+	select {
+	case data := <-conn.ReceiveForTest():
+		var msg message.Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, message.TypeQuotaExceeded, msg.Type)
+		require.NotNil(t, msg.Error)
+		assert.Equal(t, string(chaterrors.ErrCodeQuotaExceeded), msg.Error.Code)
+		assert.Equal(t, "50", msg.Metadata["daily_token_budget"])
+		assert.Equal(t, "50", msg.Metadata["tokens_used"])
+		assert.Equal(t, budget.ResetAt().Format(time.RFC3339), msg.Metadata["reset_at"])
+	default:
+		t.Fatal("expected a quota_exceeded event")
+	}
+}
+
+func TestRouteMessage_ConsumesTokenBudget(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	budget := ratelimit.NewTokenBudgetLimiter(1000)
+	router.SetTokenBudget(budget)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	}))
+
+	assert.Positive(t, budget.Used("user-1"))
+	assert.Equal(t, sess.TotalTokens, budget.Used("user-1"))
+}
+
+func TestRouteMessage_TokenBudgetIsPerTenant(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	budget := ratelimit.NewTokenBudgetLimiter(1000)
+	router.SetTokenBudget(budget)
+
+	// The same user ID in another tenant is a different user with its own budget
+	budget.Consume(budgetKey("tenant-a", "user-1"), 1000)
+	sess, err := sm.CreateSessionForTenant("user-1", "tenant-b")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	conn.TenantID = "tenant-b"
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	}))
+
+	assert.Equal(t, sess.TotalTokens, budget.Used(budgetKey("tenant-b", "user-1")))
+	assert.Equal(t, 1000, budget.Used(budgetKey("tenant-a", "user-1")))
+	assert.Zero(t, budget.Used("user-1"), "tenant users are not charged to the default tenant")
+}
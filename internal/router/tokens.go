@@ -0,0 +1,47 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/tokenizer"
+)
+
+// TokenCounter estimates token counts with a model's tokenizer (to avoid coupling
+// the router to the LLM service's tokenizer configuration)
+type TokenCounter interface {
+	GetTokenCount(modelID string, text string) (int, error)
+}
+
+// fallbackCounter estimates tokens when no counter is set or it does not know the model
+var fallbackCounter = tokenizer.Heuristic{CharsPerToken: constants.CharsPerToken}
+
+// SetTokenCounter makes token usage and budget charges use each model's own
+// tokenizer. Without one, tokens are estimated at constants.CharsPerToken.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetTokenCounter(counter TokenCounter) {
+	mr.tokenCounter = counter
+}
+
+// countTokens estimates the tokens text costs with modelID
+func (mr *MessageRouter) countTokens(modelID, text string) int {
+	// No else needed: early return pattern (no counter configured)
+	if mr.tokenCounter == nil {
+		return fallbackCounter.Count(text)
+	}
+	count, err := mr.tokenCounter.GetTokenCount(modelID, text)
+	// No else needed: early return pattern (model unknown to the counter)
+	if err != nil {
+		mr.logger.Debug("Token counter failed, using estimate", "model_id", modelID, "error", err)
+		return fallbackCounter.Count(text)
+	}
+	return count
+}
+
+// countPromptTokens estimates the input tokens of the messages sent to modelID
+func (mr *MessageRouter) countPromptTokens(modelID string, messages []llm.ChatMessage) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += mr.countTokens(modelID, msg.Content)
+	}
+	return tokens
+}
@@ -0,0 +1,70 @@
+package router
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// charCounter counts one token per byte, and fails for unknown models
+type charCounter struct {
+	models []string
+}
+
+func (c *charCounter) GetTokenCount(modelID string, text string) (int, error) {
+	c.models = append(c.models, modelID)
+	if modelID == "unknown" {
+		return 0, errors.New("unknown model")
+	}
+	return len(text), nil
+}
+
+func TestCountTokens(t *testing.T) {
+	router := NewMessageRouter(session.NewSessionManager(15*time.Minute, createTestLogger()), nil, nil, nil, nil, 120*time.Second, createTestLogger())
+	defer router.Shutdown()
+
+	// Without a counter the generic estimate is used
+	assert.Equal(t, 3, router.countTokens("gpt-4", "Hello, world"))
+
+	counter := &charCounter{}
+	router.SetTokenCounter(counter)
+	assert.Equal(t, 12, router.countTokens("gpt-4", "Hello, world"))
+	// Models the counter rejects fall back to the estimate
+	assert.Equal(t, 3, router.countTokens("unknown", "Hello, world"))
+	assert.Equal(t, []string{"gpt-4", "unknown"}, counter.models)
+}
+
+func TestRouteMessage_ChargesTokensWithModelCounter(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+	router.SetTokenCounter(&charCounter{})
+	budget := ratelimit.NewTokenBudgetLimiter(1000)
+	router.SetTokenBudget(budget)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	}))
+
+	// The reply "Mock chunk" is 10 tokens, the prompt "Hello" 5 more
+	last := sess.Messages[len(sess.Messages)-1]
+	assert.Equal(t, "10", last.Metadata[constants.MetadataKeyTokens])
+	assert.Equal(t, 15, sess.TotalTokens)
+	assert.Equal(t, 15, budget.Used("user-1"))
+}
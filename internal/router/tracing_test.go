@@ -0,0 +1,72 @@
+package router
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestRouteMessage_Tracing verifies that a user message produces a message span
+// whose children cover the LLM call and the storage writes
+func TestRouteMessage_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(prevProvider)
+
+	var llmSpan trace.SpanContext
+	llmService := &mockLLMServiceWithContext{
+		onStreamMessage: func(ctx context.Context, modelID string, messages []llm.ChatMessage) (<-chan *llm.LLMChunk, error) {
+			llmSpan = trace.SpanContextFromContext(ctx)
+			ch := make(chan *llm.LLMChunk, 1)
+			ch <- &llm.LLMChunk{Content: "Hi there", Done: true}
+			close(ch)
+			return ch, nil
+		},
+	}
+
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, llmService, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	conn.SessionID = sess.ID
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "hello",
+		Sender:    message.SenderUser,
+		Timestamp: time.Now(),
+	}))
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	root, ok := spans["websocket.message"]
+	require.True(t, ok, "message span recorded")
+	assert.False(t, root.Parent().IsValid(), "connection opened without a trace starts a new one")
+
+	write, ok := spans["mongodb.AddMessage"]
+	require.True(t, ok, "storage span recorded")
+	assert.Equal(t, root.SpanContext().SpanID(), write.Parent().SpanID())
+
+	require.True(t, llmSpan.IsValid(), "LLM call receives the message span")
+	assert.Equal(t, root.SpanContext().TraceID(), llmSpan.TraceID())
+}
@@ -0,0 +1,322 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/transcribe"
+	"github.com/real-rm/chatbox/internal/upload"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// Transcriber starts speech-to-text streams (to avoid coupling the router to a
+// concrete speech-to-text backend)
+type Transcriber interface {
+	Start(ctx context.Context, sessionID, format string) (transcribe.Stream, error)
+}
+
+// AudioStore stores the recordings of streamed voice messages
+type AudioStore interface {
+	UploadFile(ctx context.Context, file io.Reader, filename string, userID string) (*upload.UploadResult, error)
+}
+
+// voiceStream is a voice message being streamed in audio_chunk messages
+type voiceStream struct {
+	mu        sync.Mutex // serializes the chunks of the recording
+	stream    transcribe.Stream
+	sessionID string
+	format    string       // MIME type of the recording
+	audio     bytes.Buffer // the recording so far
+	done      bool         // finished or dropped; protected by mu
+	expiresAt time.Time    // dropped when no chunk arrives before then; protected by the router's mu
+}
+
+// SetTranscriber enables voice messages streamed in audio_chunk messages: the
+// chunks are transcribed by transcriber as they arrive and interim transcripts
+// are sent back to the user. The final transcript is stored as the content of
+// a voice message, with the recording stored in store (nil keeps no recording).
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetTranscriber(transcriber Transcriber, store AudioStore) {
+	mr.transcriber = transcriber
+	mr.audioStore = store
+}
+
+// handleAudioChunk applies a chunk of a streamed voice message. The first
+// chunk of a recording starts its transcription; the final one stores the
+// voice message.
+func (mr *MessageRouter) handleAudioChunk(conn *websocket.Connection, msg *message.Message) error {
+	if conn == nil {
+		return ErrNilConnection
+	}
+	if msg == nil {
+		return ErrNilMessage
+	}
+	// No else needed: early return pattern (guard clause)
+	if mr.transcriber == nil {
+		return chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat, "Streaming transcription is not enabled", nil)
+	}
+	// No else needed: early return pattern (guard clause)
+	if msg.SessionID == "" {
+		return chaterrors.ErrMissingField("session_id")
+	}
+	// No else needed: early return pattern (guard clause)
+	if msg.UploadID == "" || len(msg.UploadID) > constants.MaxUploadIDLength {
+		return chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat,
+			fmt.Sprintf("upload_id is required and at most %d bytes", constants.MaxUploadIDLength), nil)
+	}
+	// No else needed: early return pattern (guard clause)
+	if msg.Audio == nil {
+		return chaterrors.ErrMissingField("audio")
+	}
+
+	// Verify session exists and ownership
+	sess, err := mr.sessionManager.GetSession(msg.SessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return chaterrors.ErrSessionNotFound(err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if !ownsSession(conn, sess) {
+		mr.logger.Warn("Session ownership violation in audio chunk",
+			"session_id", msg.SessionID,
+			"session_owner", sess.UserID,
+			"requesting_user", conn.UserID)
+		return chaterrors.NewValidationError(
+			chaterrors.ErrCodeUnauthorized,
+			"You do not have permission to access this session",
+			nil,
+		)
+	}
+
+	key := sess.ID + ":" + msg.UploadID
+	vs, err := mr.voiceStreamFor(key, sess.ID, msg.Audio.Format)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	// No else needed: early return pattern (expired or finished meanwhile)
+	if vs.done {
+		return chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat,
+			fmt.Sprintf("voice message %s has ended", msg.UploadID), nil)
+	}
+	// No else needed: early return pattern (recording too long)
+	if vs.audio.Len()+len(msg.Audio.Data) > constants.MaxVoiceStreamBytes {
+		mr.dropVoiceStream(key, vs)
+		return chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat,
+			fmt.Sprintf("voice message exceeds the %d byte limit", constants.MaxVoiceStreamBytes), nil)
+	}
+
+	ctx, cancel := context.WithTimeout(mr.ctx, constants.DefaultTranscriptionTimeout)
+	defer cancel()
+
+	// No else needed: optional operation (the final chunk may carry no audio)
+	if len(msg.Audio.Data) > 0 {
+		vs.audio.Write(msg.Audio.Data)
+		interim, err := vs.stream.Send(ctx, msg.Audio.Data)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			mr.dropVoiceStream(key, vs)
+			util.LogError(mr.logger, "router", "transcribe audio chunk", err, "session_id", sess.ID)
+			return chaterrors.NewServiceError(chaterrors.ErrCodeServiceError, "Transcription service is temporarily unavailable", err)
+		}
+		mr.sendTranscript(sess.ID, msg.UploadID, interim, false)
+	}
+
+	// No else needed: early return pattern (more chunks to come)
+	if !msg.Audio.Final {
+		return nil
+	}
+
+	transcript, err := vs.stream.Finish(ctx)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		mr.dropVoiceStream(key, vs)
+		util.LogError(mr.logger, "router", "finish transcription", err, "session_id", sess.ID)
+		return chaterrors.NewServiceError(chaterrors.ErrCodeServiceError, "Transcription service is temporarily unavailable", err)
+	}
+	vs.done = true
+	mr.forgetVoiceStream(key, vs)
+	mr.sendTranscript(sess.ID, msg.UploadID, transcript, true)
+
+	voice := &message.Message{
+		Type:      message.TypeVoiceMessage,
+		SessionID: sess.ID,
+		Content:   transcript,
+		UploadID:  msg.UploadID,
+		Sender:    message.SenderUser,
+		Metadata:  map[string]string{constants.MetadataKeyTranscribed: "true"},
+	}
+	mr.storeRecording(ctx, conn.UserID, voice, vs)
+
+	mr.logger.Info("Streamed voice message transcribed",
+		"session_id", sess.ID,
+		"upload_id", msg.UploadID,
+		"audio_bytes", vs.audio.Len(),
+		"file_id", voice.FileID,
+		"user_id", sess.UserID)
+
+	return mr.deliverVoiceMessage(sess, voice, transcript)
+}
+
+// voiceStreamFor returns the voice stream of key, starting it for sessionID on
+// the recording's first chunk. Expired streams are dropped first.
+func (mr *MessageRouter) voiceStreamFor(key, sessionID, format string) (*voiceStream, error) {
+	now := time.Now()
+	mr.mu.Lock()
+	vs, exists := mr.voiceStreams[key]
+	// No else needed: early return pattern (next chunk of a live recording)
+	if exists && now.Before(vs.expiresAt) {
+		vs.expiresAt = now.Add(constants.VoiceStreamIdleTimeout)
+		mr.mu.Unlock()
+		return vs, nil
+	}
+
+	var expired []*voiceStream
+	streaming := 0
+	for k, other := range mr.voiceStreams {
+		// No else needed: optional operation (only expired streams)
+		if !now.Before(other.expiresAt) {
+			delete(mr.voiceStreams, k)
+			expired = append(expired, other)
+			continue
+		}
+		// No else needed: optional operation (count the session's streams)
+		if other.sessionID == sessionID {
+			streaming++
+		}
+	}
+	mr.mu.Unlock()
+	for _, other := range expired {
+		other.abandon()
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if streaming >= constants.MaxVoiceStreamsPerSession {
+		return nil, chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat,
+			fmt.Sprintf("too many voice messages in progress (max %d)", constants.MaxVoiceStreamsPerSession), nil)
+	}
+	// No else needed: conditional assignment (default format)
+	if format == "" {
+		format = constants.DefaultVoiceStreamFormat
+	}
+	// No else needed: early return pattern (guard clause)
+	if !transcribe.SupportedFormat(format) {
+		return nil, chaterrors.NewValidationError(chaterrors.ErrCodeInvalidFormat,
+			fmt.Sprintf("unsupported audio format %q", format), nil)
+	}
+
+	stream, err := mr.transcriber.Start(mr.ctx, sessionID, format)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(mr.logger, "router", "start transcription", err, "session_id", sessionID)
+		return nil, chaterrors.NewServiceError(chaterrors.ErrCodeServiceError, "Transcription service is temporarily unavailable", err)
+	}
+	vs = &voiceStream{
+		stream:    stream,
+		sessionID: sessionID,
+		format:    format,
+		expiresAt: now.Add(constants.VoiceStreamIdleTimeout),
+	}
+
+	mr.mu.Lock()
+	existing, raced := mr.voiceStreams[key]
+	// No else needed: conditional assignment (first chunk of the recording)
+	if !raced {
+		mr.voiceStreams[key] = vs
+	}
+	mr.mu.Unlock()
+	// No else needed: early return pattern (another chunk started it meanwhile)
+	if raced {
+		stream.Close()
+		return existing, nil
+	}
+	return vs, nil
+}
+
+// forgetVoiceStream removes vs from the streams in progress
+func (mr *MessageRouter) forgetVoiceStream(key string, vs *voiceStream) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	// No else needed: optional operation (replaced by a new recording)
+	if mr.voiceStreams[key] == vs {
+		delete(mr.voiceStreams, key)
+	}
+}
+
+// dropVoiceStream abandons a failed recording, so the client has to start
+// again. The caller must hold vs.mu.
+func (mr *MessageRouter) dropVoiceStream(key string, vs *voiceStream) {
+	mr.forgetVoiceStream(key, vs)
+	vs.done = true
+	vs.stream.Close()
+}
+
+// abandon closes a stream that was dropped without finishing
+func (vs *voiceStream) abandon() {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	// No else needed: optional operation (already finished)
+	if !vs.done {
+		vs.done = true
+		vs.stream.Close()
+	}
+}
+
+// stopVoiceStreams abandons every recording in progress, on shutdown
+func (mr *MessageRouter) stopVoiceStreams() {
+	mr.mu.Lock()
+	streams := mr.voiceStreams
+	mr.voiceStreams = make(map[string]*voiceStream)
+	mr.mu.Unlock()
+	for _, vs := range streams {
+		vs.abandon()
+	}
+}
+
+// storeRecording stores the audio of a finished recording and references it
+// on voice. Without an audio store, or when storing fails, the voice message
+// keeps only its transcript.
+func (mr *MessageRouter) storeRecording(ctx context.Context, userID string, voice *message.Message, vs *voiceStream) {
+	// No else needed: early return pattern (recordings not kept)
+	if mr.audioStore == nil || vs.audio.Len() == 0 {
+		return
+	}
+	filename := "voice" + transcribe.Extension(vs.format)
+	result, err := mr.audioStore.UploadFile(ctx, bytes.NewReader(vs.audio.Bytes()), filename, userID)
+	// No else needed: early return pattern (keep the transcript)
+	if err != nil {
+		util.LogError(mr.logger, "router", "store voice recording", err, "session_id", voice.SessionID)
+		return
+	}
+	voice.FileID = result.FileID
+	voice.FileURL = result.FileURL
+}
+
+// sendTranscript sends the transcript so far of a streamed voice message to
+// the user's devices
+func (mr *MessageRouter) sendTranscript(sessionID, uploadID, transcript string, final bool) {
+	// No else needed: optional operation (the client may have disconnected)
+	if err := mr.sendToConnection(sessionID, &message.Message{
+		Type:      message.TypeTranscript,
+		SessionID: sessionID,
+		UploadID:  uploadID,
+		Content:   transcript,
+		Sender:    message.SenderSystem,
+		Metadata:  map[string]string{constants.MetadataKeyTranscriptFinal: strconv.FormatBool(final)},
+		Timestamp: time.Now(),
+	}); err != nil {
+		mr.logger.Debug("Failed to send transcript", "session_id", sessionID, "error", err)
+	}
+}
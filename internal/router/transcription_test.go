@@ -0,0 +1,204 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/transcribe"
+	"github.com/real-rm/chatbox/internal/upload"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTranscriber transcribes each chunk as its length and records the streams it started
+type fakeTranscriber struct {
+	mu      sync.Mutex
+	streams []*fakeStream
+	sendErr error
+}
+
+type fakeStream struct {
+	format string
+	chunks [][]byte
+	closed bool
+	err    error
+}
+
+func (f *fakeTranscriber) Start(ctx context.Context, sessionID, format string) (transcribe.Stream, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stream := &fakeStream{format: format, err: f.sendErr}
+	f.streams = append(f.streams, stream)
+	return stream, nil
+}
+
+func (s *fakeStream) Send(ctx context.Context, audio []byte) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.chunks = append(s.chunks, audio)
+	return "partial " + strconv.Itoa(len(s.chunks)), nil
+}
+
+func (s *fakeStream) Finish(ctx context.Context) (string, error) {
+	return "Hello there.", nil
+}
+
+func (s *fakeStream) Close() { s.closed = true }
+
+// fakeAudioStore records the recordings it stores
+type fakeAudioStore struct {
+	filename string
+	data     []byte
+}
+
+func (f *fakeAudioStore) UploadFile(ctx context.Context, file io.Reader, filename string, userID string) (*upload.UploadResult, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	f.filename = filename
+	f.data = data
+	return &upload.UploadResult{FileID: "file-1", FileURL: "https://files.example.com/file-1"}, nil
+}
+
+func newTranscriptionRouter(t *testing.T, transcriber *fakeTranscriber, store AudioStore) (*MessageRouter, *session.Session, *websocket.Connection) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	// No transcriber leaves streaming disabled
+	if transcriber != nil {
+		router.SetTranscriber(transcriber, store)
+	}
+	t.Cleanup(router.Shutdown)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+	drainTypes(t, conn)
+	return router, sess, conn
+}
+
+func audioChunk(sessionID string, data []byte, final bool) *message.Message {
+	return &message.Message{
+		Type:      message.TypeAudioChunk,
+		SessionID: sessionID,
+		UploadID:  "voice-1",
+		Audio:     &message.AudioChunk{Data: data, Format: "audio/ogg", Final: final},
+		Sender:    message.SenderUser,
+	}
+}
+
+// receiveAll returns the messages sent to conn so far
+func receiveAll(t *testing.T, conn *websocket.Connection) []message.Message {
+	var msgs []message.Message
+	for {
+		select {
+		case data := <-conn.ReceiveForTest():
+			var msg message.Message
+			require.NoError(t, json.Unmarshal(data, &msg))
+			msgs = append(msgs, msg)
+			continue
+		default:
+		}
+		return msgs
+	}
+}
+
+func TestHandleAudioChunk_StreamsTranscripts(t *testing.T) {
+	transcriber := &fakeTranscriber{}
+	store := &fakeAudioStore{}
+	router, sess, conn := newTranscriptionRouter(t, transcriber, store)
+
+	require.NoError(t, router.handleAudioChunk(conn, audioChunk(sess.ID, []byte{1, 2}, false)))
+	msgs := receiveAll(t, conn)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, message.TypeTranscript, msgs[0].Type)
+	assert.Equal(t, "voice-1", msgs[0].UploadID)
+	assert.Equal(t, "partial 1", msgs[0].Content)
+	assert.Equal(t, "false", msgs[0].Metadata[constants.MetadataKeyTranscriptFinal])
+
+	require.NoError(t, router.handleAudioChunk(conn, audioChunk(sess.ID, []byte{3}, true)))
+	msgs = receiveAll(t, conn)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "partial 2", msgs[0].Content)
+	assert.Equal(t, "Hello there.", msgs[1].Content)
+	assert.Equal(t, "true", msgs[1].Metadata[constants.MetadataKeyTranscriptFinal])
+	assert.Equal(t, message.TypeVoiceMessage, msgs[2].Type)
+	assert.Equal(t, "file-1", msgs[2].FileID)
+
+	require.Len(t, transcriber.streams, 1)
+	assert.Equal(t, "audio/ogg", transcriber.streams[0].format)
+	assert.Equal(t, "voice.ogg", store.filename)
+	assert.Equal(t, []byte{1, 2, 3}, store.data)
+
+	require.Len(t, sess.Messages, 1)
+	assert.Equal(t, "Hello there.", sess.Messages[0].Content)
+	assert.Equal(t, "file-1", sess.Messages[0].FileID)
+	assert.Equal(t, "true", sess.Messages[0].Metadata[constants.MetadataKeyTranscribed])
+
+	err := router.handleAudioChunk(conn, audioChunk(sess.ID, []byte{4}, false))
+	require.NoError(t, err, "the upload ID starts a new recording once finished")
+	assert.Len(t, transcriber.streams, 2)
+}
+
+func TestHandleAudioChunk_Rejected(t *testing.T) {
+	router, sess, conn := newTranscriptionRouter(t, nil, nil)
+	err := router.handleAudioChunk(conn, audioChunk(sess.ID, []byte{1}, false))
+	assert.Error(t, err, "streaming transcription is not enabled")
+
+	transcriber := &fakeTranscriber{}
+	router, sess, conn = newTranscriptionRouter(t, transcriber, nil)
+	chunk := audioChunk(sess.ID, []byte{1}, false)
+	chunk.Audio.Format = "video/mp4"
+	assert.Error(t, router.handleAudioChunk(conn, chunk), "unsupported format")
+
+	other := mockConnection("user-2")
+	assert.Error(t, router.handleAudioChunk(other, audioChunk(sess.ID, []byte{1}, false)), "not the session owner")
+	assert.Empty(t, transcriber.streams)
+}
+
+func TestHandleAudioChunk_ProviderErrorDropsStream(t *testing.T) {
+	transcriber := &fakeTranscriber{sendErr: errors.New("service down")}
+	router, sess, conn := newTranscriptionRouter(t, transcriber, &fakeAudioStore{})
+
+	err := router.handleAudioChunk(conn, audioChunk(sess.ID, []byte{1}, false))
+	var chatErr *chaterrors.ChatError
+	require.ErrorAs(t, err, &chatErr)
+	assert.Equal(t, chaterrors.ErrCodeServiceError, chatErr.Code)
+	require.Len(t, transcriber.streams, 1)
+	assert.True(t, transcriber.streams[0].closed)
+	assert.Empty(t, router.voiceStreams)
+	assert.Empty(t, sess.Messages)
+}
+
+func TestHandleAudioChunk_LimitsStreamsPerSession(t *testing.T) {
+	transcriber := &fakeTranscriber{}
+	router, sess, conn := newTranscriptionRouter(t, transcriber, nil)
+
+	for i := 0; i < constants.MaxVoiceStreamsPerSession; i++ {
+		chunk := audioChunk(sess.ID, []byte{1}, false)
+		chunk.UploadID = "voice-" + strconv.Itoa(i)
+		require.NoError(t, router.handleAudioChunk(conn, chunk))
+	}
+	chunk := audioChunk(sess.ID, []byte{1}, false)
+	chunk.UploadID = "voice-z"
+	assert.Error(t, router.handleAudioChunk(conn, chunk))
+
+	router.stopVoiceStreams()
+	for _, stream := range transcriber.streams {
+		assert.True(t, stream.closed)
+	}
+}
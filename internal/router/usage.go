@@ -0,0 +1,37 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/cost"
+)
+
+// UsageRecorder persists the token usage and cost of LLM calls (to avoid
+// coupling the router to a concrete storage backend)
+type UsageRecorder interface {
+	RecordUsage(sessionID, tenantID, modelID string, tokens int, cost float64) error
+}
+
+// SetUsageRecorder records the tokens of every LLM reply, charged to the model
+// that served it, with recorder. Costs are priced with costs; a nil calculator
+// records tokens at no cost.
+// Must be called before the router handles any messages.
+func (mr *MessageRouter) SetUsageRecorder(recorder UsageRecorder, costs *cost.Calculator) {
+	mr.usageRecorder = recorder
+	mr.costs = costs
+}
+
+// recordUsage persists the tokens used by an LLM reply served by modelID.
+// Failures are logged; they never affect the chat.
+func (mr *MessageRouter) recordUsage(sessionID, tenantID, modelID string, tokens int) {
+	// No else needed: early return pattern (usage not persisted, or nothing used)
+	if mr.usageRecorder == nil || tokens <= 0 {
+		return
+	}
+	// No else needed: optional operation (fire-and-forget), failure is logged but not fatal
+	if err := mr.usageRecorder.RecordUsage(sessionID, tenantID, modelID, tokens, mr.costs.Cost(modelID, tokens)); err != nil {
+		mr.logger.Warn("Failed to record LLM usage",
+			"session_id", sessionID,
+			"model_id", modelID,
+			"tokens", tokens,
+			"error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/cost"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type usageCall struct {
+	sessionID, tenantID, modelID string
+	tokens                       int
+	cost                         float64
+}
+
+// fakeUsageRecorder records the usage it is given
+type fakeUsageRecorder struct {
+	mu    sync.Mutex
+	calls []usageCall
+}
+
+func (f *fakeUsageRecorder) RecordUsage(sessionID, tenantID, modelID string, tokens int, cost float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, usageCall{sessionID, tenantID, modelID, tokens, cost})
+	return nil
+}
+
+func TestRouteMessage_RecordsUsage(t *testing.T) {
+	llmService := &fallbackLLMService{errs: map[string][]error{
+		"primary": {errors.New("API error: status 500")},
+	}}
+	router, sm := newFallbackRouter(t, llmService, FallbackConfig{
+		Chains: map[string][]string{"primary": {"backup"}},
+	})
+	recorder := &fakeUsageRecorder{}
+	router.SetUsageRecorder(recorder, cost.NewCalculator("USD", map[string]float64{"backup": 1_000_000}))
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	sess.TenantID = "tenant-a"
+	require.NoError(t, sm.SetModelID(sess.ID, "primary"))
+	conn := mockConnection("user-1")
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	}))
+
+	require.Len(t, recorder.calls, 1)
+	call := recorder.calls[0]
+	assert.Equal(t, sess.ID, call.sessionID)
+	assert.Equal(t, "tenant-a", call.tenantID)
+	assert.Equal(t, "backup", call.modelID, "usage is charged to the model that served the reply")
+	assert.Equal(t, sess.TotalTokens, call.tokens)
+	assert.InDelta(t, float64(call.tokens), call.cost, 1e-9)
+}
+
+func TestRecordUsage_SkipsWithoutRecorder(t *testing.T) {
+	router, _ := newFallbackRouter(t, &fallbackLLMService{}, FallbackConfig{})
+	router.recordUsage("session-1", "", "primary", 10) // no recorder set
+
+	recorder := &fakeUsageRecorder{}
+	router.SetUsageRecorder(recorder, nil)
+	router.recordUsage("session-1", "", "primary", 0)
+	router.recordUsage("session-1", "", "unpriced", 10)
+
+	require.Len(t, recorder.calls, 1, "calls using no tokens are not recorded")
+	assert.Zero(t, recorder.calls[0].cost, "a nil calculator records no cost")
+}
@@ -0,0 +1,114 @@
+package router
+
+import (
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// WatchSession adds a read-only spectator connection to a session. Watchers receive
+// a copy of every user message and every outbound session message without the
+// session being taken over; nothing a watcher sends is routed. Admins may only
+// watch sessions of their own tenant unless they are super admins.
+func (mr *MessageRouter) WatchSession(sessionID string, conn *websocket.Connection) error {
+	// No else needed: early return pattern (guard clause)
+	if conn == nil {
+		return ErrNilConnection
+	}
+	sess, err := mr.sessionManager.GetSession(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := mr.verifyTenantAccess(sess, conn.TenantID, conn.Roles); err != nil {
+		return err
+	}
+
+	mr.mu.Lock()
+	// No else needed: initialize if needed (lazy initialization)
+	if mr.watchers[sessionID] == nil {
+		mr.watchers[sessionID] = make(map[*websocket.Connection]struct{})
+	}
+	mr.watchers[sessionID][conn] = struct{}{}
+	count := len(mr.watchers[sessionID])
+	mr.mu.Unlock()
+
+	mr.logger.Info("Admin started watching session",
+		"session_id", sessionID,
+		"admin_id", conn.UserID,
+		"watchers", count)
+	return nil
+}
+
+// UnwatchSession removes a spectator connection added by WatchSession
+func (mr *MessageRouter) UnwatchSession(sessionID string, conn *websocket.Connection) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	// No else needed: early return pattern (not watching)
+	if _, exists := mr.watchers[sessionID][conn]; !exists {
+		return
+	}
+	delete(mr.watchers[sessionID], conn)
+	// No else needed: optional operation (drop empty sets)
+	if len(mr.watchers[sessionID]) == 0 {
+		delete(mr.watchers, sessionID)
+	}
+
+	mr.logger.Info("Admin stopped watching session",
+		"session_id", sessionID,
+		"admin_id", conn.UserID)
+}
+
+// mirrorToWatchers sends a marshaled session message to the session's watchers.
+// Watchers are best-effort like admin connections: a full buffer drops the message.
+func (mr *MessageRouter) mirrorToWatchers(sessionID string, data []byte) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	for conn := range mr.watchers[sessionID] {
+		// No else needed: optional operation (fire-and-forget)
+		if !conn.SafeSend(data) {
+			mr.logger.Warn("Watcher send channel full or closing",
+				"session_id", sessionID,
+				"admin_id", conn.UserID)
+			metrics.AdminMessagesDropped.Inc()
+		}
+	}
+}
+
+// mirrorUserMessage sends an inbound user message to the session's watchers and
+// to the user's devices other than conn, the one it was written on
+func (mr *MessageRouter) mirrorUserMessage(conn *websocket.Connection, sessionID string, userMsg *session.Message) {
+	mr.mu.RLock()
+	watching := len(mr.watchers[sessionID]) > 0
+	siblings := len(mr.devices[sessionID])
+	// No else needed: optional operation (the sender does not get its own message back)
+	if _, ok := mr.devices[sessionID][conn]; ok {
+		siblings--
+	}
+	mr.mu.RUnlock()
+	// No else needed: early return pattern (skip marshaling when nobody else sees it)
+	if !watching && siblings == 0 {
+		return
+	}
+
+	data, err := util.MarshalJSON(&message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sessionID,
+		Content:   userMsg.Content,
+		Sender:    message.SenderUser,
+		Metadata:  userMsg.Metadata,
+		Timestamp: userMsg.Timestamp,
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(mr.logger, "router", "marshal message for watchers", err, "session_id", sessionID)
+		return
+	}
+	mr.mirrorToWatchers(sessionID, data)
+	mr.sendToDevices(sessionID, conn, data)
+}
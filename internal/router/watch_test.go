@@ -0,0 +1,63 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchSession_MirrorsSessionTraffic(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	llmMock := &mockLLMService{}
+	router := NewMessageRouter(sm, llmMock, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	watcher := websocket.NewConnection("admin-1", []string{"admin"})
+	require.NoError(t, router.WatchSession(sess.ID, watcher))
+
+	require.NoError(t, router.HandleUserMessage(conn, &message.Message{
+		Type:      message.TypeUserMessage,
+		SessionID: sess.ID,
+		Content:   "Hello",
+		Sender:    message.SenderUser,
+	}))
+
+	types := drainTypes(t, watcher)
+	require.NotEmpty(t, types)
+	assert.Equal(t, message.TypeUserMessage, types[0])
+	assert.Contains(t, types, message.TypeAIResponse)
+
+	// Watching does not take the session over
+	assert.Empty(t, sess.GetAssistingAdminID())
+
+	router.UnwatchSession(sess.ID, watcher)
+	require.NoError(t, router.BroadcastToSession(sess.ID, &message.Message{
+		Type:      message.TypeNotification,
+		SessionID: sess.ID,
+		Content:   "after unwatch",
+		Sender:    message.SenderSystem,
+	}))
+	assert.Empty(t, drainTypes(t, watcher))
+}
+
+func TestWatchSession_UnknownSession(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, nil, 120*time.Second, logger)
+	defer router.Shutdown()
+
+	err := router.WatchSession("missing-session", websocket.NewConnection("admin-1", []string{"admin"}))
+	assert.Error(t, err)
+	assert.ErrorIs(t, router.WatchSession("missing-session", nil), ErrNilConnection)
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/webhook"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher captures published webhook events
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []webhook.Event
+}
+
+func (p *recordingPublisher) Publish(event webhook.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+func (p *recordingPublisher) types() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var types []string
+	for _, e := range p.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestWebhooks_HelpRequestAndTakeover(t *testing.T) {
+	logger := createTestLogger()
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	router := NewMessageRouter(sm, &mockLLMService{}, nil, nil, &mockStorageService{}, 120*time.Second, logger)
+	defer router.Shutdown()
+	publisher := &recordingPublisher{}
+	router.SetWebhookPublisher(publisher)
+
+	conn := mockConnection("user-1")
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, router.RegisterConnection(sess.ID, conn))
+
+	require.NoError(t, router.RouteMessage(conn, &message.Message{
+		Type:      message.TypeHelpRequest,
+		SessionID: sess.ID,
+		Sender:    message.SenderUser,
+	}))
+
+	admin := websocket.NewConnection("admin-1", []string{"admin"})
+	require.NoError(t, router.HandleAdminTakeover(admin, sess.ID))
+
+	assert.Equal(t, []string{webhook.EventHelpRequested, webhook.EventAdminTakeover}, publisher.types())
+	takeover := publisher.events[1]
+	assert.Equal(t, sess.ID, takeover.SessionID)
+	assert.Equal(t, "user-1", takeover.UserID)
+	assert.Equal(t, "admin-1", takeover.AdminID)
+}
@@ -0,0 +1,86 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/jsonhttp"
+)
+
+// ErrNoEndpoint is returned when the HTTP runner is created without an endpoint
+var ErrNoEndpoint = errors.New("sandbox endpoint is required")
+
+// HTTPConfig holds HTTP sandbox service settings
+type HTTPConfig struct {
+	Endpoint  string        // URL the code is POSTed to
+	APIKey    string        // Sent as a bearer token when set
+	Languages []string      // Code fence languages the sandbox runs (defaults to constants.DefaultSandboxLanguages)
+	Timeout   time.Duration // HTTP timeout (defaults to constants.DefaultSandboxTimeout)
+}
+
+// HTTPRunner runs code in an external sandbox service. It POSTs
+// {"session_id", "language", "code"} as JSON and expects
+// {"stdout", "stderr", "exit_code", "timed_out"} in return. The service is
+// trusted to isolate the code and enforce its own resource limits.
+type HTTPRunner struct {
+	languages []string
+	client    *jsonhttp.Client
+}
+
+type httpRunRequest struct {
+	SessionID string `json:"session_id"`
+	Language  string `json:"language"`
+	Code      string `json:"code"`
+}
+
+// NewHTTPRunner validates the configuration and creates the runner.
+// The endpoint must use https, except internal hosts which may use http.
+func NewHTTPRunner(cfg HTTPConfig) (*HTTPRunner, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.Endpoint == "" {
+		return nil, ErrNoEndpoint
+	}
+	var languages []string
+	for _, lang := range cfg.Languages {
+		// No else needed: optional operation (skip blank entries)
+		if lang = strings.ToLower(strings.TrimSpace(lang)); lang != "" {
+			languages = append(languages, lang)
+		}
+	}
+	// No else needed: optional operation (apply default)
+	if len(languages) == 0 {
+		languages = constants.DefaultSandboxLanguages
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultSandboxTimeout
+	}
+
+	client, err := jsonhttp.New("sandbox", cfg.Endpoint, cfg.APIKey, timeout)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPRunner{languages: languages, client: client}, nil
+}
+
+// Languages returns the code fence languages the sandbox runs
+func (r *HTTPRunner) Languages() []string {
+	return r.languages
+}
+
+// Run sends snippet to the sandbox service and returns what it printed and
+// its exit code. A non-zero exit code is a result, not an error.
+func (r *HTTPRunner) Run(ctx context.Context, sessionID string, snippet Snippet) (*Result, error) {
+	var result Result
+	// No else needed: early return pattern (guard clause)
+	if err := r.client.Post(ctx, httpRunRequest{SessionID: sessionID, Language: snippet.Language, Code: snippet.Code}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
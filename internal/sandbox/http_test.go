@@ -0,0 +1,65 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPRunner_Validation(t *testing.T) {
+	_, err := NewHTTPRunner(HTTPConfig{})
+	assert.ErrorIs(t, err, ErrNoEndpoint)
+
+	_, err = NewHTTPRunner(HTTPConfig{Endpoint: "http://sandbox.example.com/run"})
+	assert.Error(t, err, "public endpoints must use https")
+
+	r, err := NewHTTPRunner(HTTPConfig{Endpoint: "https://sandbox.example.com/run"})
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultSandboxLanguages, r.Languages())
+	assert.Equal(t, constants.DefaultSandboxTimeout, r.client.Timeout())
+
+	r, err = NewHTTPRunner(HTTPConfig{Endpoint: "https://sandbox.example.com/run", Languages: []string{" Python", "", "node "}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"python", "node"}, r.Languages())
+}
+
+func TestHTTPRunner_Run(t *testing.T) {
+	var got httpRunRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"stdout":"","stderr":"NameError","exit_code":1}`))
+	}))
+	defer server.Close()
+
+	r, err := NewHTTPRunner(HTTPConfig{Endpoint: server.URL, APIKey: "test-key"})
+	require.NoError(t, err)
+
+	result, err := r.Run(context.Background(), "session-1", Snippet{Language: "python", Code: "print(x)"})
+	require.NoError(t, err)
+	assert.Equal(t, httpRunRequest{SessionID: "session-1", Language: "python", Code: "print(x)"}, got)
+	assert.Equal(t, &Result{Stderr: "NameError", ExitCode: 1}, result)
+}
+
+func TestHTTPRunner_RunErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"), "no API key configured")
+		http.Error(w, "sandbox overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	r, err := NewHTTPRunner(HTTPConfig{Endpoint: server.URL})
+	require.NoError(t, err)
+
+	_, err = r.Run(context.Background(), "session-1", Snippet{Language: "python", Code: "print(1)"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	assert.Contains(t, err.Error(), "sandbox overloaded")
+}
@@ -0,0 +1,109 @@
+// Package sandbox runs code proposed by the LLM in an isolated execution
+// service, so users can see what the code actually does.
+//
+// When an AI reply contains fenced code blocks in a language the sandbox
+// supports, the router sends each block to a Runner and appends the output to
+// the session as a system message. Only tenants allowed in the configuration
+// have their code run. HTTPRunner is the reference implementation, calling an
+// external sandbox service.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Snippet is a block of code proposed in an AI reply
+type Snippet struct {
+	Language string `json:"language"` // Lowercase language of the code fence, e.g. "python"
+	Code     string `json:"code"`
+}
+
+// Result is the outcome of running a snippet
+type Result struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	TimedOut bool   `json:"timed_out,omitempty"` // The sandbox stopped the code at its time limit
+}
+
+// Runner executes code snippets in a sandbox
+type Runner interface {
+	Run(ctx context.Context, sessionID string, snippet Snippet) (*Result, error)
+}
+
+// ExtractCode returns the fenced code blocks of reply whose language is one of
+// languages, in reply order and at most limit of them. Blocks without a language,
+// in other languages, or left unclosed are skipped.
+func ExtractCode(reply string, languages []string, limit int) []Snippet {
+	var snippets []Snippet
+	lines := strings.Split(reply, "\n")
+	for i := 0; i < len(lines) && len(snippets) < limit; i++ {
+		line := strings.TrimSpace(lines[i])
+		// No else needed: optional operation (not an opening fence)
+		if !strings.HasPrefix(line, "```") {
+			continue
+		}
+		info := strings.Fields(strings.TrimPrefix(line, "```"))
+
+		// Find the closing fence
+		end := i + 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "```" {
+			end++
+		}
+		// No else needed: early return pattern (unclosed block)
+		if end == len(lines) {
+			break
+		}
+		code := strings.Join(lines[i+1:end], "\n")
+		i = end
+
+		// No else needed: optional operation (only supported languages are run)
+		if len(info) == 0 || !slices.Contains(languages, strings.ToLower(info[0])) {
+			continue
+		}
+		// No else needed: optional operation (nothing to run)
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+		snippets = append(snippets, Snippet{Language: strings.ToLower(info[0]), Code: code})
+	}
+	return snippets
+}
+
+// Output formats a result as the content of the system message showing it,
+// each stream cut to at most maxBytes
+func Output(result *Result, maxBytes int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Exit code %d", result.ExitCode)
+	// No else needed: optional operation (only code stopped at the time limit)
+	if result.TimedOut {
+		b.WriteString(" (timed out)")
+	}
+	b.WriteString("\n")
+	// No else needed: optional operation (code without output)
+	if result.Stdout != "" {
+		b.WriteString("\nstdout:\n" + truncate(result.Stdout, maxBytes) + "\n")
+	}
+	// No else needed: optional operation (code without errors)
+	if result.Stderr != "" {
+		b.WriteString("\nstderr:\n" + truncate(result.Stderr, maxBytes) + "\n")
+	}
+	return b.String()
+}
+
+// truncate cuts s to at most maxBytes without splitting a multi-byte character
+func truncate(s string, maxBytes int) string {
+	// No else needed: early return pattern (short enough)
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,42 @@
+package sandbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractCode(t *testing.T) {
+	reply := "Here you go:\n" +
+		"```python\nprint('hi')\nprint('there')\n```\n" +
+		"Plain fence:\n```\nnot run\n```\n" +
+		"```Bash  title=demo\necho hi\n```\n" +
+		"```go\nfmt.Println(1)\n```\n" +
+		"```python\n   \n```\n" +
+		"```python\nunclosed()\n"
+
+	snippets := ExtractCode(reply, []string{"python", "bash"}, 5)
+	assert.Equal(t, []Snippet{
+		{Language: "python", Code: "print('hi')\nprint('there')"},
+		{Language: "bash", Code: "echo hi"},
+	}, snippets)
+
+	// The limit keeps the first blocks
+	assert.Len(t, ExtractCode(reply, []string{"python", "bash"}, 1), 1)
+	assert.Empty(t, ExtractCode("No code here.", []string{"python"}, 3))
+}
+
+func TestOutput(t *testing.T) {
+	out := Output(&Result{Stdout: "4\n", ExitCode: 0}, 100)
+	assert.Equal(t, "Exit code 0\n\nstdout:\n4\n\n", out)
+
+	out = Output(&Result{Stderr: "Traceback", ExitCode: 1, TimedOut: true}, 100)
+	assert.Contains(t, out, "Exit code 1 (timed out)")
+	assert.Contains(t, out, "stderr:\nTraceback")
+	assert.NotContains(t, out, "stdout")
+
+	// Long output is cut without splitting characters
+	out = Output(&Result{Stdout: strings.Repeat("é", 10)}, 5)
+	assert.Contains(t, out, "stdout:\néé\n")
+}
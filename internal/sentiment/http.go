@@ -0,0 +1,76 @@
+package sentiment
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/jsonhttp"
+)
+
+// ErrNoEndpoint is returned when the HTTP analyzer is created without an endpoint
+var ErrNoEndpoint = errors.New("sentiment endpoint is required")
+
+// HTTPConfig holds external sentiment service settings
+type HTTPConfig struct {
+	Endpoint string        // URL the text is POSTed to
+	APIKey   string        // Sent as a bearer token when set
+	Timeout  time.Duration // HTTP timeout (defaults to constants.DefaultSentimentTimeout)
+}
+
+// HTTPAnalyzer scores messages with an external sentiment service. It POSTs
+// {"text"} as JSON and expects {"score"} in return, a number in [-1, 1].
+type HTTPAnalyzer struct {
+	client *jsonhttp.Client
+}
+
+type httpAnalyzeRequest struct {
+	Text string `json:"text"`
+}
+
+type httpAnalyzeResponse struct {
+	Score *float64 `json:"score"`
+}
+
+// NewHTTPAnalyzer validates the configuration and creates the analyzer.
+// The endpoint must use https, except internal hosts which may use http.
+func NewHTTPAnalyzer(cfg HTTPConfig) (*HTTPAnalyzer, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.Endpoint == "" {
+		return nil, ErrNoEndpoint
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultSentimentTimeout
+	}
+
+	client, err := jsonhttp.New("sentiment", cfg.Endpoint, cfg.APIKey, timeout)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPAnalyzer{client: client}, nil
+}
+
+// Name returns the analyzer name
+func (a *HTTPAnalyzer) Name() string {
+	return AnalyzerHTTP
+}
+
+// Analyze sends text to the sentiment service and returns its score
+func (a *HTTPAnalyzer) Analyze(ctx context.Context, text string) (float64, error) {
+	var parsed httpAnalyzeResponse
+	// No else needed: early return pattern (guard clause)
+	if err := a.client.Post(ctx, httpAnalyzeRequest{Text: text}, &parsed); err != nil {
+		return 0, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if parsed.Score == nil || *parsed.Score < -1 || *parsed.Score > 1 || math.IsNaN(*parsed.Score) {
+		return 0, errors.New("sentiment response has no score in [-1, 1]")
+	}
+	return *parsed.Score, nil
+}
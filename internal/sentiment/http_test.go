@@ -0,0 +1,69 @@
+package sentiment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPAnalyzer_Validation(t *testing.T) {
+	_, err := NewHTTPAnalyzer(HTTPConfig{})
+	assert.ErrorIs(t, err, ErrNoEndpoint)
+
+	_, err = NewHTTPAnalyzer(HTTPConfig{Endpoint: "http://sentiment.example.com/score"})
+	assert.Error(t, err, "public endpoints must use https")
+
+	a, err := NewHTTPAnalyzer(HTTPConfig{Endpoint: "https://sentiment.example.com/score"})
+	require.NoError(t, err)
+	assert.Equal(t, AnalyzerHTTP, a.Name())
+	assert.Equal(t, constants.DefaultSentimentTimeout, a.client.Timeout())
+}
+
+func TestHTTPAnalyzer_Analyze(t *testing.T) {
+	var got httpAnalyzeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"score":-0.7}`))
+	}))
+	defer server.Close()
+
+	a, err := NewHTTPAnalyzer(HTTPConfig{Endpoint: server.URL, APIKey: "test-key"})
+	require.NoError(t, err)
+
+	score, err := a.Analyze(context.Background(), "this is broken")
+	require.NoError(t, err)
+	assert.Equal(t, "this is broken", got.Text)
+	assert.Equal(t, -0.7, score)
+}
+
+func TestHTTPAnalyzer_AnalyzeErrors(t *testing.T) {
+	responses := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusInternalServerError, `oops`},
+		{http.StatusOK, `not json`},
+		{http.StatusOK, `{}`},
+		{http.StatusOK, `{"score":3}`},
+	}
+	for _, resp := range responses {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Empty(t, r.Header.Get("Authorization"), "no API key configured")
+			w.WriteHeader(resp.status)
+			_, _ = w.Write([]byte(resp.body))
+		}))
+		a, err := NewHTTPAnalyzer(HTTPConfig{Endpoint: server.URL})
+		require.NoError(t, err)
+		_, err = a.Analyze(context.Background(), "hello")
+		assert.Error(t, err, "%d %s", resp.status, resp.body)
+		server.Close()
+	}
+}
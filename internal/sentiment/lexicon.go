@@ -0,0 +1,109 @@
+package sentiment
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// lexicon rates words a frustrated or satisfied user tends to use, from -4
+// (very negative) to 4 (very positive). Deliberately small: it only has to rank
+// waiting sessions, not understand them.
+var lexicon = map[string]float64{
+	"angry": -3, "annoyed": -2, "annoying": -2, "awful": -3, "bad": -2,
+	"broken": -2, "bug": -1, "cancel": -2, "crash": -2, "crashed": -2,
+	"disappointed": -2, "doesn't": -1, "error": -1, "fail": -2, "failed": -2,
+	"failing": -2, "frustrated": -3, "frustrating": -3, "furious": -4,
+	"hate": -3, "horrible": -3, "lost": -2, "refund": -2, "ridiculous": -3,
+	"slow": -1, "stuck": -2, "terrible": -3, "unacceptable": -3, "upset": -2,
+	"useless": -3, "urgent": -2, "waiting": -1, "worst": -4, "wrong": -2,
+	"excellent": 3, "fixed": 2, "glad": 2, "good": 2, "great": 3,
+	"helpful": 2, "love": 3, "nice": 2, "perfect": 3, "resolved": 2,
+	"thank": 2, "thanks": 2, "working": 1,
+}
+
+// negations flip the rating of the word that follows within negationWindow words
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "cannot": true, "can't": true,
+	"don't": true, "isn't": true, "won't": true, "didn't": true, "nothing": true,
+}
+
+const (
+	negationWindow  = 3
+	negationFactor  = -0.75 // "not good" is milder than "bad"
+	shoutBoost      = 0.5   // added to the magnitude of an ALL-CAPS word
+	exclaimBoost    = 0.3   // per "!" in a message, up to maxExclaims
+	maxExclaims     = 3
+	normalizeFactor = 15 // how quickly the sum saturates towards ±1
+)
+
+// Classify rates texts with a small built-in lexicon and returns a score in
+// [-1, 1]: negative for frustrated users, positive for satisfied ones, 0 when
+// nothing is rated. Negations flip nearby words, and ALL-CAPS words and
+// exclamation marks intensify them.
+func Classify(texts ...string) float64 {
+	var sum float64
+	for _, text := range texts {
+		sum += rate(text)
+	}
+	// No else needed: early return pattern (guard clause)
+	if sum == 0 {
+		return 0
+	}
+	return sum / math.Sqrt(sum*sum+normalizeFactor)
+}
+
+// rate sums the ratings of the words in text
+func rate(text string) float64 {
+	var sum float64
+	sinceNegation := negationWindow + 1
+	for _, raw := range strings.Fields(text) {
+		word := strings.TrimFunc(raw, func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '\''
+		})
+		lower := strings.ToLower(word)
+		// No else needed: optional operation (negations only affect later words)
+		if negations[lower] {
+			sinceNegation = 0
+			continue
+		}
+		sinceNegation++
+
+		rating, ok := lexicon[lower]
+		// No else needed: optional operation (unrated words add nothing)
+		if !ok {
+			continue
+		}
+		// No else needed: optional operation (intensify shouted words)
+		if len(word) > 1 && word == strings.ToUpper(word) {
+			rating += math.Copysign(shoutBoost, rating)
+		}
+		// No else needed: optional operation (flip negated words)
+		if sinceNegation <= negationWindow {
+			rating *= negationFactor
+		}
+		sum += rating
+	}
+
+	// No else needed: optional operation (exclamations intensify the message)
+	if sum != 0 {
+		exclaims := math.Min(float64(strings.Count(text, "!")), maxExclaims)
+		sum += math.Copysign(exclaims*exclaimBoost, sum)
+	}
+	return sum
+}
+
+// LexiconAnalyzer scores messages locally with Classify. It needs no network
+// and never fails.
+type LexiconAnalyzer struct{}
+
+// Name returns the analyzer name
+func (LexiconAnalyzer) Name() string {
+	return AnalyzerLexicon
+}
+
+// Analyze scores text with Classify
+func (LexiconAnalyzer) Analyze(_ context.Context, text string) (float64, error) {
+	return Classify(text), nil
+}
@@ -0,0 +1,39 @@
+package sentiment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	assert.Zero(t, Classify())
+	assert.Zero(t, Classify("what are your opening hours?"))
+	assert.Less(t, Classify("this is broken and useless"), 0.0)
+	assert.Greater(t, Classify("great, thanks for the help"), 0.0)
+
+	angry := Classify("this is terrible")
+	assert.Less(t, Classify("this is TERRIBLE!!!"), angry, "caps and exclamations intensify")
+	assert.Greater(t, Classify("this is not terrible"), 0.0, "negation flips the rating")
+	assert.Less(t, Classify("this is terrible", "still broken", "worst support ever"), angry,
+		"messages add up")
+
+	for _, texts := range [][]string{
+		{"HATE HATE HATE HATE HATE HATE HATE HATE!!!!!"},
+		{"PERFECT PERFECT PERFECT PERFECT PERFECT!!!"},
+	} {
+		s := Classify(texts...)
+		assert.True(t, s >= -1 && s <= 1, "%v out of range", s)
+	}
+}
+
+func TestLexiconAnalyzer(t *testing.T) {
+	analyzer := LexiconAnalyzer{}
+	assert.Equal(t, AnalyzerLexicon, analyzer.Name())
+
+	score, err := analyzer.Analyze(context.Background(), "this is broken")
+	require.NoError(t, err)
+	assert.Equal(t, Classify("this is broken"), score)
+}
@@ -0,0 +1,248 @@
+// Package sentiment scores user messages for sentiment in the background and
+// keeps a rolling sentiment per session.
+//
+// A Pipeline queues messages and scores them one at a time with an Analyzer:
+// the built-in lexicon (LexiconAnalyzer) or an external service (HTTPAnalyzer).
+// Each score is folded into the session's rolling sentiment, which is persisted
+// for admin listings. When the rolling sentiment drops below the alert
+// threshold, a sentiment_dropped webhook event is published. Analysis never
+// blocks or fails the chat: full queues drop messages and analyzer errors are
+// logged.
+package sentiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/webhook"
+	"github.com/real-rm/golog"
+)
+
+// Analyzer names used in configuration and metrics
+const (
+	AnalyzerLexicon = "lexicon"
+	AnalyzerHTTP    = "http"
+)
+
+// ErrNoAnalyzer is returned when a pipeline is created without an analyzer
+var ErrNoAnalyzer = errors.New("sentiment analyzer is required")
+
+// Analyzer scores message text. Implementations must be safe for concurrent use.
+type Analyzer interface {
+	// Name identifies the analyzer in logs and metrics
+	Name() string
+	// Analyze returns the sentiment of text in [-1, 1]; an error means it could not decide
+	Analyze(ctx context.Context, text string) (float64, error)
+}
+
+// Recorder keeps the rolling sentiment of sessions (implemented by
+// session.SessionManager)
+type Recorder interface {
+	RecordSentiment(sessionID string, score, smoothing float64) (previous *session.Sentiment, current session.Sentiment, err error)
+}
+
+// Store persists rolling sentiments (implemented by storage.StorageService)
+type Store interface {
+	UpdateSessionSentiment(sessionID string, sentiment session.Sentiment) error
+}
+
+// Publisher posts alerts (implemented by webhook.Dispatcher)
+type Publisher interface {
+	Publish(event webhook.Event)
+}
+
+// Config holds pipeline settings
+type Config struct {
+	Smoothing float64       // Weight of the newest message in the rolling sentiment, in (0, 1] (defaults to constants.DefaultSentimentSmoothing)
+	Threshold float64       // Rolling sentiment below which an alert is published, in [-1, 1]
+	Timeout   time.Duration // Time allowed to score one message (defaults to constants.DefaultSentimentTimeout)
+}
+
+// Job is a user message to score
+type Job struct {
+	SessionID string
+	UserID    string
+	TenantID  string
+	Text      string
+}
+
+// Pipeline scores queued messages and updates the sessions' rolling sentiment
+type Pipeline struct {
+	analyzer  Analyzer
+	smoothing float64
+	threshold float64
+	timeout   time.Duration
+	recorder  Recorder
+	store     Store     // nil when sentiments are not persisted
+	alerts    Publisher // nil when no webhook endpoints are configured
+	logger    *golog.Logger
+
+	queue  chan Job
+	wg     sync.WaitGroup
+	closed bool
+	mu     sync.RWMutex
+}
+
+// NewPipeline validates the configuration and starts the analysis worker.
+// store and alerts may be nil.
+func NewPipeline(analyzer Analyzer, cfg Config, recorder Recorder, store Store, alerts Publisher, logger *golog.Logger) (*Pipeline, error) {
+	// No else needed: early return pattern (guard clause)
+	if analyzer == nil {
+		return nil, ErrNoAnalyzer
+	}
+	smoothing := cfg.Smoothing
+	// No else needed: optional operation (apply default)
+	if smoothing == 0 {
+		smoothing = constants.DefaultSentimentSmoothing
+	}
+	// No else needed: early return pattern (guard clause)
+	if smoothing < 0 || smoothing > 1 || math.IsNaN(smoothing) {
+		return nil, fmt.Errorf("sentiment smoothing must be in (0, 1], got %v", cfg.Smoothing)
+	}
+	// No else needed: early return pattern (guard clause)
+	if cfg.Threshold < -1 || cfg.Threshold > 1 || math.IsNaN(cfg.Threshold) {
+		return nil, fmt.Errorf("sentiment alert threshold must be in [-1, 1], got %v", cfg.Threshold)
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultSentimentTimeout
+	}
+
+	p := &Pipeline{
+		analyzer:  analyzer,
+		smoothing: smoothing,
+		threshold: cfg.Threshold,
+		timeout:   timeout,
+		recorder:  recorder,
+		store:     store,
+		alerts:    alerts,
+		logger:    logger,
+		queue:     make(chan Job, constants.SentimentQueueSize),
+	}
+
+	p.wg.Add(1)
+	util.SafeGo(logger, "sentiment", func() {
+		defer p.wg.Done()
+		p.run()
+	})
+	return p, nil
+}
+
+// Submit queues a message for analysis. It never blocks: when the queue is
+// full or the pipeline has shut down, the message is not scored.
+func (p *Pipeline) Submit(job Job) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if p.closed {
+		return
+	}
+
+	select {
+	case p.queue <- job:
+	default:
+		metrics.SentimentAnalyses.WithLabelValues(p.analyzer.Name(), "dropped").Inc()
+		p.logger.Warn("Sentiment queue full, dropping message", "session_id", job.SessionID)
+	}
+}
+
+// Shutdown stops accepting messages and waits for queued ones to be scored.
+// If ctx expires first, ctx.Err() is returned; the worker finishes in the
+// background.
+func (p *Pipeline) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	// No else needed: optional operation (close once)
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run scores queued messages until the queue is closed
+func (p *Pipeline) run() {
+	for job := range p.queue {
+		p.process(job)
+	}
+}
+
+// process scores one message, records the session's rolling sentiment and
+// publishes an alert when it drops below the threshold
+func (p *Pipeline) process(job Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	score, err := p.analyzer.Analyze(ctx, job.Text)
+	cancel()
+	// No else needed: early return pattern (unscored messages leave the sentiment unchanged)
+	if err != nil {
+		metrics.SentimentAnalyses.WithLabelValues(p.analyzer.Name(), "failed").Inc()
+		util.LogError(p.logger, "sentiment", "analyze message", err,
+			"analyzer", p.analyzer.Name(),
+			"session_id", job.SessionID)
+		return
+	}
+	metrics.SentimentAnalyses.WithLabelValues(p.analyzer.Name(), "scored").Inc()
+	score = math.Max(-1, math.Min(1, score))
+
+	previous, current, err := p.recorder.RecordSentiment(job.SessionID, score, p.smoothing)
+	// No else needed: early return pattern (session ended meanwhile)
+	if err != nil {
+		p.logger.Debug("Sentiment not recorded", "session_id", job.SessionID, "error", err)
+		return
+	}
+	// No else needed: optional operation (only when sentiments are persisted)
+	if p.store != nil {
+		// No else needed: optional operation (failure is logged but not fatal)
+		if err := p.store.UpdateSessionSentiment(job.SessionID, current); err != nil {
+			util.LogError(p.logger, "sentiment", "persist sentiment", err, "session_id", job.SessionID)
+		}
+	}
+
+	// Alert once per drop: only when the sentiment crosses the threshold
+	// No else needed: early return pattern (still above, or already alerted)
+	if current.Score >= p.threshold || (previous != nil && previous.Score < p.threshold) {
+		return
+	}
+	metrics.SentimentAlerts.Inc()
+	p.logger.Info("Session sentiment dropped",
+		"session_id", job.SessionID,
+		"user_id", job.UserID,
+		"sentiment", current.Score,
+		"threshold", p.threshold)
+	// No else needed: optional operation (only when webhooks are configured)
+	if p.alerts != nil {
+		p.alerts.Publish(webhook.Event{
+			Type:      webhook.EventSentimentDropped,
+			SessionID: job.SessionID,
+			UserID:    job.UserID,
+			TenantID:  job.TenantID,
+			Data: map[string]string{
+				"sentiment": strconv.FormatFloat(current.Score, 'f', 3, 64),
+				"threshold": strconv.FormatFloat(p.threshold, 'f', 3, 64),
+				"samples":   strconv.Itoa(current.Samples),
+			},
+		})
+	}
+}
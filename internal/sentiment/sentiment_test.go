@@ -0,0 +1,126 @@
+package sentiment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/webhook"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedAnalyzer returns the score queued for each text
+type fixedAnalyzer map[string]float64
+
+func (a fixedAnalyzer) Name() string { return "fixed" }
+
+func (a fixedAnalyzer) Analyze(_ context.Context, text string) (float64, error) {
+	score, ok := a[text]
+	if !ok {
+		return 0, errors.New("unknown text")
+	}
+	return score, nil
+}
+
+// recordingStore records persisted sentiments
+type recordingStore struct {
+	mu      sync.Mutex
+	updates []session.Sentiment
+}
+
+func (s *recordingStore) UpdateSessionSentiment(_ string, sentiment session.Sentiment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updates = append(s.updates, sentiment)
+	return nil
+}
+
+// recordingPublisher records published alerts
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []webhook.Event
+}
+
+func (p *recordingPublisher) Publish(event webhook.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+func newTestPipeline(t *testing.T, analyzer Analyzer, cfg Config, store Store, alerts Publisher) (*Pipeline, *session.SessionManager, *session.Session) {
+	t.Helper()
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	sm := session.NewSessionManager(15*time.Minute, logger)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	p, err := NewPipeline(analyzer, cfg, sm, store, alerts, logger)
+	require.NoError(t, err)
+	return p, sm, sess
+}
+
+func TestNewPipeline_Validation(t *testing.T) {
+	logger, err := golog.InitLog(golog.LogConfig{Dir: t.TempDir(), Level: "error"})
+	require.NoError(t, err)
+	defer logger.Close()
+	sm := session.NewSessionManager(time.Minute, logger)
+
+	_, err = NewPipeline(nil, Config{}, sm, nil, nil, logger)
+	assert.ErrorIs(t, err, ErrNoAnalyzer)
+	for _, cfg := range []Config{{Smoothing: -0.1}, {Smoothing: 1.5}, {Threshold: -2}, {Threshold: 1.1}} {
+		_, err := NewPipeline(LexiconAnalyzer{}, cfg, sm, nil, nil, logger)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
+
+func TestPipeline_AlertsOncePerDrop(t *testing.T) {
+	analyzer := fixedAnalyzer{"fine": 0.5, "annoyed": -0.6, "furious": -1, "better": 1}
+	store := &recordingStore{}
+	alerts := &recordingPublisher{}
+	p, _, sess := newTestPipeline(t, analyzer, Config{Smoothing: 0.5, Threshold: -0.3}, store, alerts)
+
+	for _, text := range []string{"fine", "annoyed", "furious", "unscorable", "furious", "better", "better", "furious", "furious"} {
+		p.Submit(Job{SessionID: sess.ID, UserID: "user-1", TenantID: "tenant-a", Text: text})
+	}
+	p.Submit(Job{SessionID: "ended", Text: "furious"}) // no longer in memory: skipped
+	require.NoError(t, p.Shutdown(context.Background()))
+	p.Submit(Job{SessionID: sess.ID, Text: "furious"}) // after shutdown: ignored
+
+	// 0.5, -0.05, -0.525 (alert), -0.7625, 0.11875, 0.559375, -0.2203125, -0.61015625 (alert)
+	assert.Len(t, store.updates, 8, "unscorable messages leave the sentiment unchanged")
+	final := sess.GetSentiment()
+	require.NotNil(t, final)
+	assert.Equal(t, 8, final.Samples)
+	assert.InDelta(t, -0.61015625, final.Score, 1e-9)
+
+	require.Len(t, alerts.events, 2)
+	event := alerts.events[0]
+	assert.Equal(t, webhook.EventSentimentDropped, event.Type)
+	assert.Equal(t, sess.ID, event.SessionID)
+	assert.Equal(t, "user-1", event.UserID)
+	assert.Equal(t, "tenant-a", event.TenantID)
+	assert.Equal(t, "-0.525", event.Data["sentiment"])
+	assert.Equal(t, "-0.300", event.Data["threshold"])
+	assert.Equal(t, "3", event.Data["samples"])
+}
+
+func TestPipeline_WithoutStoreOrAlerts(t *testing.T) {
+	p, _, sess := newTestPipeline(t, LexiconAnalyzer{}, Config{Threshold: -0.1}, nil, nil)
+	p.Submit(Job{SessionID: sess.ID, Text: "this is TERRIBLE, I am furious!!"})
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	final := sess.GetSentiment()
+	require.NotNil(t, final)
+	assert.Less(t, final.Score, -0.1)
+}
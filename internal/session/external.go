@@ -0,0 +1,108 @@
+package session
+
+// ExternalChange is what a change made to a session by another instance or by
+// tooling changed in the in-memory session
+type ExternalChange struct {
+	Messages []*Message // Messages added elsewhere, oldest first
+	Name     string     // The new name; empty when unchanged
+	Ended    bool       // The session was ended elsewhere
+	Removed  bool       // The session was deleted and dropped from memory
+}
+
+// messageKey identifies a message across memory and storage. Storage keeps
+// timestamps at millisecond precision.
+type messageKey struct {
+	millis  int64
+	sender  string
+	content string
+}
+
+func keyOf(m *Message) messageKey {
+	return messageKey{millis: m.Timestamp.UnixMilli(), sender: m.Sender, content: m.Content}
+}
+
+// ApplyExternal brings an in-memory session up to date with its stored copy
+// after a change that may have been made elsewhere. stored is nil when the
+// session was deleted; it is then dropped from memory. Messages of stored the
+// session lacks are appended, and a new name or end time is taken over. Changes
+// this instance made are already in memory and change nothing, and messages it
+// has not written yet are kept. Returns nil for sessions not in memory and when
+// nothing changed.
+func (sm *SessionManager) ApplyExternal(sessionID string, stored *Session) *ExternalChange {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	// No else needed: early return pattern (only sessions in memory go stale)
+	if !exists {
+		return nil
+	}
+
+	key := ownerKey(session.TenantID, session.UserID)
+	// No else needed: early return pattern (deleted elsewhere)
+	if stored == nil {
+		// No else needed: optional operation (the user may have started another session)
+		if sm.userSessions[key] == sessionID {
+			delete(sm.userSessions, key)
+		}
+		delete(sm.sessions, sessionID)
+		sm.logger.Info("Session deleted elsewhere", "session_id", sessionID)
+		return &ExternalChange{Removed: true}
+	}
+
+	// Acquire session.mu per lock ordering (sm.mu → session.mu)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	change := &ExternalChange{}
+	known := make(map[messageKey]int, len(session.Messages))
+	for _, m := range session.Messages {
+		known[keyOf(m)]++
+	}
+	for _, m := range stored.Messages {
+		k := keyOf(m)
+		// No else needed: optional operation (skip messages already in memory)
+		if known[k] > 0 {
+			known[k]--
+			continue
+		}
+		change.Messages = append(change.Messages, m.clone())
+	}
+	// No else needed: optional operation (only when messages were added)
+	if len(change.Messages) > 0 {
+		session.Messages = append(session.Messages, change.Messages...)
+	}
+	// No else needed: optional operation (activity elsewhere resets the idle timer)
+	if stored.LastActivity.After(session.LastActivity) {
+		session.LastActivity = stored.LastActivity
+	}
+
+	// No else needed: optional operation (renamed elsewhere)
+	if stored.Name != "" && stored.Name != session.Name {
+		session.Name = stored.Name
+		change.Name = stored.Name
+	}
+
+	// No else needed: optional operation (ended elsewhere)
+	if stored.EndTime != nil && session.EndTime == nil {
+		endTime := *stored.EndTime
+		session.IsActive = false
+		session.EndTime = &endTime
+		// No else needed: optional operation (the user may have started another session)
+		if sm.userSessions[key] == sessionID {
+			delete(sm.userSessions, key)
+		}
+		change.Ended = true
+	}
+
+	// No else needed: early return pattern (nothing changed)
+	if len(change.Messages) == 0 && change.Name == "" && !change.Ended {
+		return nil
+	}
+	sm.logger.Info("Session changed elsewhere",
+		"session_id", sessionID,
+		"messages", len(change.Messages),
+		"renamed", change.Name != "",
+		"ended", change.Ended)
+	return change
+}
@@ -0,0 +1,99 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExternalTestManager(t *testing.T) *SessionManager {
+	t.Helper()
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+	return NewSessionManager(15*time.Minute, logger)
+}
+
+// storedCopy returns a copy of sess as storage would return it
+func storedCopy(sess *Session) *Session {
+	stored := &Session{ID: sess.ID, UserID: sess.UserID, Name: sess.Name, LastActivity: sess.LastActivity}
+	for _, m := range sess.Messages {
+		c := m.clone()
+		c.Timestamp = m.Timestamp.Truncate(time.Millisecond)
+		stored.Messages = append(stored.Messages, c)
+	}
+	return stored
+}
+
+func TestApplyExternal_Messages(t *testing.T) {
+	sm := newExternalTestManager(t)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, sm.AddMessage(sess.ID, &Message{Content: "hi", Sender: "user", Timestamp: time.Now()}))
+
+	// A change this instance made is already in memory
+	stored := storedCopy(sess)
+	assert.Nil(t, sm.ApplyExternal(sess.ID, stored))
+
+	// A message added elsewhere is appended; one not written yet is kept
+	external := &Message{Content: "Hello, I'm Sam from support", Sender: "admin", Timestamp: time.Now().Truncate(time.Millisecond)}
+	stored.Messages = append(stored.Messages, external)
+	stored.LastActivity = time.Now().Add(time.Second)
+	require.NoError(t, sm.AddMessage(sess.ID, &Message{Content: "pending", Sender: "user", Timestamp: time.Now()}))
+
+	change := sm.ApplyExternal(sess.ID, stored)
+	require.NotNil(t, change)
+	require.Len(t, change.Messages, 1)
+	assert.Equal(t, external.Content, change.Messages[0].Content)
+	require.Len(t, sess.Messages, 3)
+	assert.Equal(t, "pending", sess.Messages[1].Content)
+	assert.Equal(t, external.Content, sess.Messages[2].Content)
+	assert.Equal(t, stored.LastActivity, sess.LastActivity)
+
+	assert.Nil(t, sm.ApplyExternal(sess.ID, stored), "applying the same copy again changes nothing")
+}
+
+func TestApplyExternal_RenameAndEnd(t *testing.T) {
+	sm := newExternalTestManager(t)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	stored := storedCopy(sess)
+	stored.Name = "Billing question"
+	ended := time.Now()
+	stored.EndTime = &ended
+
+	change := sm.ApplyExternal(sess.ID, stored)
+	require.NotNil(t, change)
+	assert.Equal(t, "Billing question", change.Name)
+	assert.True(t, change.Ended)
+	assert.Equal(t, "Billing question", sess.Name)
+	assert.False(t, sess.IsActive)
+	require.NotNil(t, sess.EndTime)
+
+	_, err = sm.GetActiveSessionForUser("user-1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestApplyExternal_Deleted(t *testing.T) {
+	sm := newExternalTestManager(t)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	assert.Nil(t, sm.ApplyExternal("unknown", nil), "sessions not in memory are ignored")
+
+	change := sm.ApplyExternal(sess.ID, nil)
+	require.NotNil(t, change)
+	assert.True(t, change.Removed)
+	_, err = sm.GetSession(sess.ID)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = sm.GetActiveSessionForUser("user-1")
+	assert.Error(t, err)
+}
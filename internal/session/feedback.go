@@ -0,0 +1,71 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+var (
+	// ErrInvalidFeedback is returned when a rating is not up or down or the comment is too long
+	ErrInvalidFeedback = errors.New("invalid feedback")
+	// ErrNotAIMessage is returned when feedback targets a message that is not an AI reply
+	ErrNotAIMessage = errors.New("feedback can only be given on AI messages")
+)
+
+// Feedback is a user's rating of an AI message, with an optional comment
+type Feedback struct {
+	Rating    string    `json:"rating"` // constants.FeedbackUp or constants.FeedbackDown
+	Comment   string    `json:"comment,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the rating and the comment length
+func (f *Feedback) Validate() error {
+	// No else needed: early return pattern (guard clause)
+	if f.Rating != constants.FeedbackUp && f.Rating != constants.FeedbackDown {
+		return fmt.Errorf("%w: rating must be %q or %q", ErrInvalidFeedback, constants.FeedbackUp, constants.FeedbackDown)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(f.Comment) > constants.MaxFeedbackCommentLength {
+		return fmt.Errorf("%w: comment must be at most %d bytes", ErrInvalidFeedback, constants.MaxFeedbackCommentLength)
+	}
+	return nil
+}
+
+// SetMessageFeedback records feedback on the AI message at index, replacing any
+// earlier feedback on it. Returns ErrInvalidMessageIndex when the index is out of
+// range and ErrNotAIMessage when the message is not an AI reply.
+func (sm *SessionManager) SetMessageFeedback(sessionID string, index int, feedback *Feedback) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// No else needed: early return pattern (guard clause)
+	if index < 0 || index >= len(session.Messages) {
+		return ErrInvalidMessageIndex
+	}
+	msg := session.Messages[index]
+	// No else needed: early return pattern (guard clause)
+	if msg.Sender != constants.SenderAI {
+		return ErrNotAIMessage
+	}
+
+	// Replace rather than modify: readers may hold a clone sharing the old value
+	stored := *feedback
+	msg.Feedback = &stored
+	return nil
+}
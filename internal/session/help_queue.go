@@ -0,0 +1,78 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// HelpRequest is a snapshot of an active session waiting for an admin
+type HelpRequest struct {
+	SessionID    string
+	UserID       string
+	TenantID     string
+	Name         string
+	ModelID      string
+	RequestedAt  time.Time
+	CustomerTier string
+	UserMessages []string // the user's latest messages, oldest first
+}
+
+// SetCustomerTier records the tier claimed by the session owner's token
+func (sm *SessionManager) SetCustomerTier(sessionID, tier string) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionID]
+	sm.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.CustomerTier = tier
+	return nil
+}
+
+// HelpQueue returns the active sessions of this replica that requested help and
+// that no admin has taken over yet, with up to recentMessages of the user's
+// latest messages each. The order is unspecified.
+func (sm *SessionManager) HelpQueue(recentMessages int) []HelpRequest {
+	sm.mu.RLock()
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for _, session := range sm.sessions {
+		sessions = append(sessions, session)
+	}
+	sm.mu.RUnlock()
+
+	var queue []HelpRequest
+	for _, session := range sessions {
+		session.mu.RLock()
+		// No else needed: optional operation (only waiting sessions)
+		if session.IsActive && session.HelpRequested && !session.HelpRequestedAt.IsZero() && session.AssistingAdminID == "" {
+			request := HelpRequest{
+				SessionID:    session.ID,
+				UserID:       session.UserID,
+				TenantID:     session.TenantID,
+				Name:         session.Name,
+				ModelID:      session.ModelID,
+				RequestedAt:  session.HelpRequestedAt,
+				CustomerTier: session.CustomerTier,
+			}
+			for i := len(session.Messages) - 1; i >= 0 && len(request.UserMessages) < recentMessages; i-- {
+				// No else needed: optional operation (only the user's messages)
+				if msg := session.Messages[i]; msg.Sender == constants.SenderUser && msg.Content != "" {
+					request.UserMessages = append([]string{msg.Content}, request.UserMessages...)
+				}
+			}
+			queue = append(queue, request)
+		}
+		session.mu.RUnlock()
+	}
+	return queue
+}
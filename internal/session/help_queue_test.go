@@ -0,0 +1,67 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHelpQueueTestManager(t *testing.T) *SessionManager {
+	t.Helper()
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+	return NewSessionManager(15*time.Minute, logger)
+}
+
+func TestHelpQueue(t *testing.T) {
+	sm := newHelpQueueTestManager(t)
+
+	waiting, err := sm.CreateSessionForTenant("user-1", "tenant-a")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetCustomerTier(waiting.ID, "enterprise"))
+	for _, msg := range []*Message{
+		{Content: "first", Sender: constants.SenderUser},
+		{Content: "second", Sender: constants.SenderUser},
+		{Content: "an answer", Sender: constants.SenderAI},
+		{Content: "third", Sender: constants.SenderUser},
+	} {
+		require.NoError(t, sm.AddMessage(waiting.ID, msg))
+	}
+	require.NoError(t, sm.MarkHelpRequested(waiting.ID))
+
+	_, err = sm.CreateSession("user-2") // never asks for help
+	require.NoError(t, err)
+
+	queue := sm.HelpQueue(2)
+	require.Len(t, queue, 1)
+	req := queue[0]
+	assert.Equal(t, waiting.ID, req.SessionID)
+	assert.Equal(t, "user-1", req.UserID)
+	assert.Equal(t, "tenant-a", req.TenantID)
+	assert.Equal(t, "enterprise", req.CustomerTier)
+	assert.Equal(t, []string{"second", "third"}, req.UserMessages)
+	assert.False(t, req.RequestedAt.IsZero())
+
+	// A repeated request keeps the session's place in the queue
+	require.NoError(t, sm.MarkHelpRequested(waiting.ID))
+	assert.Equal(t, req.RequestedAt, sm.HelpQueue(2)[0].RequestedAt)
+
+	// Taking the session over removes it from the queue
+	require.NoError(t, sm.MarkAdminAssisted(waiting.ID, "admin-1", "Admin"))
+	assert.Empty(t, sm.HelpQueue(2))
+}
+
+func TestSetCustomerTier_Errors(t *testing.T) {
+	sm := newHelpQueueTestManager(t)
+	assert.ErrorIs(t, sm.SetCustomerTier("", "gold"), ErrInvalidSessionID)
+	assert.ErrorIs(t, sm.SetCustomerTier("missing", "gold"), ErrSessionNotFound)
+}
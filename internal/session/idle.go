@@ -0,0 +1,172 @@
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultIdleCheckInterval is how often idle sessions are checked for.
+// It is shortened for short warning leads so warnings are not skipped.
+const defaultIdleCheckInterval = 15 * time.Second
+
+// IdleHandler is notified by the SessionManager about idle sessions.
+// Calls are made from the cleanup goroutine and must not block for long.
+type IdleHandler interface {
+	// SessionExpiring is called once per idle period, warning before the
+	// session is ended; remaining is the time left without activity.
+	SessionExpiring(sess *Session, remaining time.Duration)
+	// SessionIdleExpired is called after an idle session was ended in memory,
+	// so the handler can persist the end and notify the client.
+	SessionIdleExpired(sess *Session)
+}
+
+// SetIdleTimeout ends active sessions without activity for timeout. warning
+// before that, handler is told the session is expiring; a warning of 0 sends no
+// warning. Any activity (LastActivity update) resets the timer. A timeout of 0
+// disables idle expiry. handler may be nil.
+// Must be called before StartCleanup.
+func (sm *SessionManager) SetIdleTimeout(timeout, warning time.Duration, handler IdleHandler) error {
+	if timeout < 0 || warning < 0 {
+		return fmt.Errorf("idle timeout and warning cannot be negative")
+	}
+	if timeout > 0 && warning >= timeout {
+		return fmt.Errorf("idle warning %v must be shorter than the idle timeout %v", warning, timeout)
+	}
+
+	interval := defaultIdleCheckInterval
+	if warning > 0 && warning/2 < interval {
+		interval = warning / 2
+	}
+	if timeout > 0 && timeout/2 < interval {
+		interval = timeout / 2
+	}
+
+	sm.idleTimeout = timeout
+	sm.idleWarning = warning
+	sm.idleCheckInterval = interval
+	sm.idleHandler = handler
+	return nil
+}
+
+// TouchSession records activity on an active session, resetting its idle timer
+func (sm *SessionManager) TouchSession(sessionID string) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	// No else needed: ended sessions are not revived by activity
+	if session.IsActive {
+		session.LastActivity = time.Now()
+	}
+	session.mu.Unlock()
+	return nil
+}
+
+// idleSession is an active session found idle by checkIdleSessions
+type idleSession struct {
+	session      *Session
+	lastActivity time.Time
+}
+
+// checkIdleSessions warns about sessions nearing the idle timeout and ends
+// those that reached it. This method should only be called by the cleanup
+// goroutine, which owns idleWarned.
+func (sm *SessionManager) checkIdleSessions(now time.Time) {
+	if sm.idleTimeout <= 0 {
+		return
+	}
+
+	sm.mu.RLock()
+	idle := make([]idleSession, 0)
+	for _, sess := range sm.sessions {
+		sess.mu.RLock()
+		isActive := sess.IsActive
+		lastActivity := sess.LastActivity
+		sess.mu.RUnlock()
+
+		if isActive && now.Sub(lastActivity) >= sm.idleTimeout-sm.idleWarning {
+			idle = append(idle, idleSession{session: sess, lastActivity: lastActivity})
+		}
+	}
+	sm.mu.RUnlock()
+
+	// Forget warnings of sessions that became active again or ended
+	stillIdle := make(map[string]bool, len(idle))
+	for _, entry := range idle {
+		stillIdle[entry.session.ID] = true
+	}
+	for sessionID := range sm.idleWarned {
+		if !stillIdle[sessionID] {
+			delete(sm.idleWarned, sessionID)
+		}
+	}
+
+	expired := 0
+	for _, entry := range idle {
+		sess := entry.session
+		idleFor := now.Sub(entry.lastActivity)
+
+		if idleFor >= sm.idleTimeout {
+			delete(sm.idleWarned, sess.ID)
+			if !sm.endIdleSession(sess, entry.lastActivity) {
+				continue
+			}
+			expired++
+			if sm.idleHandler != nil {
+				sm.idleHandler.SessionIdleExpired(sess)
+			}
+			continue
+		}
+
+		// Warn once per idle period; activity changes LastActivity and re-arms the warning
+		if warnedAt, warned := sm.idleWarned[sess.ID]; warned && warnedAt.Equal(entry.lastActivity) {
+			continue
+		}
+		sm.idleWarned[sess.ID] = entry.lastActivity
+		if sm.idleHandler != nil {
+			sm.idleHandler.SessionExpiring(sess, sm.idleTimeout-idleFor)
+		}
+	}
+
+	if expired > 0 {
+		sm.logger.Info("Ended idle sessions", "count", expired, "idle_timeout", sm.idleTimeout)
+	}
+}
+
+// endIdleSession ends sess unless it saw activity since lastActivity or was
+// already ended, and reports whether it was ended
+func (sm *SessionManager) endIdleSession(sess *Session, lastActivity time.Time) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sess.mu.Lock()
+	if !sess.IsActive || !sess.LastActivity.Equal(lastActivity) {
+		sess.mu.Unlock()
+		return false
+	}
+	now := time.Now()
+	sess.IsActive = false
+	sess.EndTime = &now
+	sess.mu.Unlock()
+
+	key := ownerKey(sess.TenantID, sess.UserID)
+	// No else needed: optional operation (the owner may have a newer session)
+	if sm.userSessions[key] == sess.ID {
+		delete(sm.userSessions, key)
+	}
+
+	sm.logger.Info("Session ended after inactivity",
+		"session_id", sess.ID,
+		"user_id", sess.UserID,
+		"idle_for", now.Sub(lastActivity))
+	return true
+}
@@ -0,0 +1,155 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingIdleHandler records idle notifications
+type recordingIdleHandler struct {
+	warned  []time.Duration
+	expired []string
+}
+
+func (h *recordingIdleHandler) SessionExpiring(sess *Session, remaining time.Duration) {
+	h.warned = append(h.warned, remaining)
+}
+
+func (h *recordingIdleHandler) SessionIdleExpired(sess *Session) {
+	h.expired = append(h.expired, sess.ID)
+}
+
+func newIdleTestManager(t *testing.T, handler IdleHandler) *SessionManager {
+	t.Helper()
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	sm := NewSessionManager(15*time.Minute, logger)
+	require.NoError(t, sm.SetIdleTimeout(10*time.Minute, time.Minute, handler))
+	return sm
+}
+
+func TestSetIdleTimeout_Validation(t *testing.T) {
+	sm := newIdleTestManager(t, nil)
+
+	assert.Error(t, sm.SetIdleTimeout(-time.Minute, 0, nil))
+	assert.Error(t, sm.SetIdleTimeout(time.Minute, -time.Second, nil))
+	assert.Error(t, sm.SetIdleTimeout(time.Minute, time.Minute, nil))
+	assert.NoError(t, sm.SetIdleTimeout(0, 0, nil))
+
+	require.NoError(t, sm.SetIdleTimeout(time.Minute, 10*time.Second, nil))
+	assert.Equal(t, 5*time.Second, sm.idleCheckInterval)
+}
+
+func TestCheckIdleSessions_WarnsThenEnds(t *testing.T) {
+	handler := &recordingIdleHandler{}
+	sm := newIdleTestManager(t, handler)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	start := sess.LastActivity
+
+	// Not idle yet
+	sm.checkIdleSessions(start.Add(5 * time.Minute))
+	assert.Empty(t, handler.warned)
+
+	// Inside the warning window: warned once
+	sm.checkIdleSessions(start.Add(9*time.Minute + 30*time.Second))
+	sm.checkIdleSessions(start.Add(9*time.Minute + 45*time.Second))
+	require.Len(t, handler.warned, 1)
+	assert.Equal(t, 30*time.Second, handler.warned[0])
+
+	// Timed out: ended and reported
+	sm.checkIdleSessions(start.Add(10 * time.Minute))
+	assert.Equal(t, []string{sess.ID}, handler.expired)
+	assert.False(t, sess.IsActive)
+	assert.NotNil(t, sess.EndTime)
+	_, err = sm.GetActiveSessionForUser("user-1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// Ended sessions are not reported again
+	sm.checkIdleSessions(start.Add(20 * time.Minute))
+	assert.Len(t, handler.expired, 1)
+	assert.Empty(t, sm.idleWarned)
+}
+
+func TestCheckIdleSessions_ActivityResetsTimer(t *testing.T) {
+	handler := &recordingIdleHandler{}
+	sm := newIdleTestManager(t, handler)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+
+	// Warned while idle
+	sess.mu.Lock()
+	sess.LastActivity = time.Now().Add(-9*time.Minute - 30*time.Second)
+	sess.mu.Unlock()
+	sm.checkIdleSessions(time.Now())
+	require.Len(t, handler.warned, 1)
+
+	// Activity moves the timer, so the session is neither warned nor ended
+	require.NoError(t, sm.TouchSession(sess.ID))
+	sm.checkIdleSessions(time.Now())
+	assert.Len(t, handler.warned, 1)
+	assert.Empty(t, handler.expired)
+	assert.True(t, sess.IsActive)
+
+	// Idle again: warned again
+	sess.mu.Lock()
+	sess.LastActivity = time.Now().Add(-9 * time.Minute)
+	sess.mu.Unlock()
+	sm.checkIdleSessions(time.Now())
+	assert.Len(t, handler.warned, 2)
+}
+
+func TestEndIdleSession_SkipsSessionsWithNewActivity(t *testing.T) {
+	sm := newIdleTestManager(t, nil)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	stale := sess.LastActivity.Add(-time.Hour)
+
+	assert.False(t, sm.endIdleSession(sess, stale))
+	assert.True(t, sess.IsActive)
+	assert.True(t, sm.endIdleSession(sess, sess.LastActivity))
+	assert.False(t, sess.IsActive)
+}
+
+func TestTouchSession(t *testing.T) {
+	sm := newIdleTestManager(t, nil)
+
+	assert.ErrorIs(t, sm.TouchSession(""), ErrInvalidSessionID)
+	assert.ErrorIs(t, sm.TouchSession("missing"), ErrSessionNotFound)
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	require.NoError(t, sm.EndSession(sess.ID))
+	ended := sess.LastActivity
+
+	// Ended sessions keep their last activity
+	require.NoError(t, sm.TouchSession(sess.ID))
+	assert.Equal(t, ended, sess.LastActivity)
+}
+
+func TestCheckIdleSessions_Disabled(t *testing.T) {
+	handler := &recordingIdleHandler{}
+	sm := newIdleTestManager(t, handler)
+	require.NoError(t, sm.SetIdleTimeout(0, 0, handler))
+
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	sm.checkIdleSessions(time.Now().Add(24 * time.Hour))
+
+	assert.True(t, sess.IsActive)
+	assert.Empty(t, handler.warned)
+	assert.Empty(t, handler.expired)
+}
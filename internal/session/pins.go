@@ -0,0 +1,57 @@
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// Pin marks a message of the session as important. Messages are identified by
+// their index in the session's history.
+type Pin struct {
+	MessageIndex int       `json:"message_index"`
+	PinnedBy     string    `json:"pinned_by"` // user or admin ID
+	PinnedAt     time.Time `json:"pinned_at"`
+}
+
+// PinnedMessage is a pinned message with its pin
+type PinnedMessage struct {
+	Pin
+	Message *Message `json:"message"`
+}
+
+// PinnedMessages returns the session's pinned messages in pin order. Pins of
+// messages the session does not have are skipped.
+func (s *Session) PinnedMessages() []PinnedMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pinned := make([]PinnedMessage, 0, len(s.Pins))
+	for _, pin := range s.Pins {
+		// No else needed: optional operation (skip pins of missing messages)
+		if pin.MessageIndex >= 0 && pin.MessageIndex < len(s.Messages) {
+			pinned = append(pinned, PinnedMessage{Pin: pin, Message: s.Messages[pin.MessageIndex]})
+		}
+	}
+	return pinned
+}
+
+// SetPins replaces the pins of an in-memory session, keeping it in sync with
+// storage after a pin or unpin
+func (sm *SessionManager) SetPins(sessionID string, pins []Pin) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionID]
+	sm.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	// Replace rather than modify: readers may hold the old slice
+	session.Pins = append([]Pin(nil), pins...)
+	return nil
+}
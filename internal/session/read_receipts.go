@@ -0,0 +1,40 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// MarkAdminMessagesRead records that the user saw every admin message of the
+// session not read yet, at time at. Returns the indexes of the messages marked,
+// oldest first; none when every admin message was already read.
+func (sm *SessionManager) MarkAdminMessagesRead(sessionID string, at time.Time) ([]int, error) {
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionID]
+	sm.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	var marked []int
+	for i, msg := range session.Messages {
+		// No else needed: optional operation (only unread admin messages)
+		if msg.Sender != constants.SenderAdmin || msg.ReadAt != nil {
+			continue
+		}
+		// Replace rather than modify: readers may hold a clone sharing the old value
+		readAt := at
+		msg.ReadAt = &readAt
+		marked = append(marked, i)
+	}
+	return marked, nil
+}
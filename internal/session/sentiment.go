@@ -0,0 +1,57 @@
+package session
+
+import (
+	"fmt"
+)
+
+// Sentiment is the rolling sentiment of a session's user messages
+type Sentiment struct {
+	Score   float64 // exponential moving average of the message scores, in [-1, 1]
+	Samples int     // messages scored so far
+}
+
+// RecordSentiment folds the score of a new user message into the session's
+// rolling sentiment, weighting it by smoothing (in (0, 1]); the first score is
+// taken as is. Returns the rolling sentiment before and after; previous is nil
+// when no message had been scored.
+func (sm *SessionManager) RecordSentiment(sessionID string, score, smoothing float64) (previous *Sentiment, current Sentiment, err error) {
+	if sessionID == "" {
+		return nil, Sentiment{}, ErrInvalidSessionID
+	}
+
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionID]
+	sm.mu.RUnlock()
+	if !exists {
+		return nil, Sentiment{}, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	current = Sentiment{Score: score, Samples: 1}
+	// No else needed: optional operation (the first score starts the average)
+	if session.Sentiment != nil {
+		prev := *session.Sentiment
+		previous = &prev
+		current = Sentiment{
+			Score:   smoothing*score + (1-smoothing)*prev.Score,
+			Samples: prev.Samples + 1,
+		}
+	}
+	session.Sentiment = &current
+	return previous, current, nil
+}
+
+// GetSentiment returns a copy of the session's rolling sentiment in a
+// thread-safe manner, or nil when no message was scored.
+func (s *Session) GetSentiment() *Sentiment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// No else needed: early return pattern (nothing scored)
+	if s.Sentiment == nil {
+		return nil
+	}
+	sentiment := *s.Sentiment
+	return &sentiment
+}
@@ -0,0 +1,32 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordSentiment(t *testing.T) {
+	sm := newHelpQueueTestManager(t)
+	sess, err := sm.CreateSession("user-1")
+	require.NoError(t, err)
+	assert.Nil(t, sess.GetSentiment())
+
+	previous, current, err := sm.RecordSentiment(sess.ID, -0.8, 0.5)
+	require.NoError(t, err)
+	assert.Nil(t, previous)
+	assert.Equal(t, Sentiment{Score: -0.8, Samples: 1}, current, "the first score starts the average")
+
+	previous, current, err = sm.RecordSentiment(sess.ID, 0.4, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, &Sentiment{Score: -0.8, Samples: 1}, previous)
+	assert.InDelta(t, -0.2, current.Score, 1e-9)
+	assert.Equal(t, 2, current.Samples)
+	assert.Equal(t, &current, sess.GetSentiment())
+
+	_, _, err = sm.RecordSentiment("", 0, 0.5)
+	assert.ErrorIs(t, err, ErrInvalidSessionID)
+	_, _, err = sm.RecordSentiment("missing", 0, 0.5)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
@@ -39,16 +39,96 @@ var (
 	ErrNegativeDuration = errors.New("duration cannot be negative")
 	// ErrAlreadyAssisted is returned when a different admin is already assisting
 	ErrAlreadyAssisted = errors.New("session already assisted by another admin")
+	// ErrNotAssisted is returned when handing back a session no admin has taken over
+	ErrNotAssisted = errors.New("session is not assisted by an admin")
+	// ErrInvalidMessageIndex is returned when a fork point is outside the session's messages
+	ErrInvalidMessageIndex = errors.New("message index out of range")
 )
 
 // Message represents a chat message
 type Message struct {
 	Content   string            `json:"content"`
 	Timestamp time.Time         `json:"timestamp"`
-	Sender    string            `json:"sender"` // "user", "ai", "admin"
+	Sender    string            `json:"sender"`          // "user", "ai", "admin", "system"
+	Event     string            `json:"event,omitempty"` // system event type, set only for system messages
 	FileID    string            `json:"file_id,omitempty"`
 	FileURL   string            `json:"file_url,omitempty"`
 	Metadata  map[string]string `json:"metadata,omitempty"`
+	Feedback  *Feedback         `json:"feedback,omitempty"` // user rating of an AI message
+	ReadAt    *time.Time        `json:"read_at,omitempty"`  // when the user saw an admin message (read receipts only)
+	Timing    *MessageTiming    `json:"timing,omitempty"`   // pipeline stage durations of an AI reply
+}
+
+// MessageTiming is the time an AI reply spent in each stage of the message
+// pipeline, from receiving the user's message to handing the reply to storage
+type MessageTiming struct {
+	Moderation time.Duration `json:"moderation"`  // received until the input was screened
+	FirstToken time.Duration `json:"first_token"` // screened until the first LLM token
+	Streaming  time.Duration `json:"streaming"`   // first until the last LLM token
+	Persist    time.Duration `json:"persist"`     // last token until the reply was handed to storage
+	Total      time.Duration `json:"total"`       // received until the reply was handed to storage
+}
+
+// AdminIntervention is the window during which an admin had taken over a session
+type AdminIntervention struct {
+	AdminID      string    `json:"admin_id"`
+	AdminName    string    `json:"admin_name"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	HandedBackBy string    `json:"handed_back_by"` // admin who returned the session to the AI
+}
+
+// clone returns a copy of the message that shares no mutable state with it
+func (m *Message) clone() *Message {
+	c := *m
+	// No else needed: optional operation (copy metadata only when present)
+	if m.Metadata != nil {
+		c.Metadata = make(map[string]string, len(m.Metadata))
+		for k, v := range m.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return &c
+}
+
+// IsSystemEvent reports whether the message records a session event (takeover,
+// handoff, reconnect, ...) rather than conversation content.
+// System events belong in the transcript but must be excluded from LLM context.
+func (m *Message) IsSystemEvent() bool {
+	return m.Event != ""
+}
+
+// LLMParams are the LLM sampling parameters a client chose for its session.
+// Unset fields keep the model's defaults.
+type LLMParams struct {
+	Temperature *float64
+	MaxTokens   int
+	TopP        *float64
+	Timeout     time.Duration // LLM reply timeout; 0 uses the server's
+}
+
+// ProviderThread is the conversation an LLM provider keeps server-side for the
+// session, e.g. a Dify conversation. Replies from ModelID continue it instead of
+// resending the context.
+type ProviderThread struct {
+	ModelID string // Model whose provider holds the thread
+	ID      string // The provider's thread ID
+}
+
+// ExperimentVariant records the variant of an A/B experiment a session was
+// assigned at creation (see internal/experiment)
+type ExperimentVariant struct {
+	ExperimentID string
+	Variant      string
+}
+
+// ClientInfo describes the client SDK that created a session, as sent when its
+// connection opened. Empty fields were not sent.
+type ClientInfo struct {
+	AppVersion string
+	Platform   string
+	UserAgent  string
+	ScreenSize string
 }
 
 // Session represents an active user session.
@@ -56,15 +136,22 @@ type Message struct {
 // All other fields require mu.RLock() for reads and mu.Lock() for writes.
 type Session struct {
 	// Identity (immutable after construction -- no lock required for reads)
-	ID     string
-	UserID string
-	Name   string
+	ID       string
+	UserID   string
+	TenantID string // Empty for the default tenant
+	Name     string
 
 	// Configuration
-	ModelID string
+	ModelID          string
+	PromptTemplateID string              // System prompt template selected at creation; empty for none
+	LLMParams        *LLMParams          // Client-chosen LLM parameters; nil uses the model defaults
+	Client           *ClientInfo         // Client SDK that created the session; nil when it sent no metadata
+	ProviderThread   *ProviderThread     // Server-side LLM thread; nil when the provider keeps none
+	Experiments      []ExperimentVariant // A/B experiment variants assigned at creation
 
 	// Content
 	Messages []*Message
+	Pins     []Pin // Messages pinned by the user or admins, in pin order
 
 	// Timing
 	StartTime    time.Time
@@ -72,13 +159,17 @@ type Session struct {
 	EndTime      *time.Time
 
 	// State
-	IsActive      bool
-	HelpRequested bool
+	IsActive        bool
+	HelpRequested   bool
+	HelpRequestedAt time.Time  // when the session joined the help queue; zero once an admin takes it over
+	CustomerTier    string     // the owner's tier claim, weighting its place in the help queue
+	Sentiment       *Sentiment // rolling sentiment of the user's messages; nil until one is scored
 
 	// Admin Assistance
 	AdminAssisted      bool
 	AssistingAdminID   string
 	AssistingAdminName string
+	AssistStartTime    time.Time // when the current takeover started
 
 	// Metrics
 	TotalTokens   int
@@ -90,13 +181,15 @@ type Session struct {
 
 // SessionManager manages active sessions in memory.
 // NOTE: In-memory sessions are NOT automatically synchronized across pods.
-// On startup, call RehydrateFromStorage() to load active sessions from MongoDB.
+// On startup, call RehydrateFromStorage() to load active sessions from MongoDB;
+// with chatbox.change_stream enabled, ApplyExternal() then picks up changes
+// made elsewhere.
 // For horizontal scaling, configure K8s sticky sessions (sessionAffinity: ClientIP
 // and ingress cookie affinity) to pin WebSocket connections to a single pod.
 // True multi-pod session sharing requires a Redis-backed session store.
 type SessionManager struct {
 	sessions         map[string]*Session // sessionID -> Session
-	userSessions     map[string]string   // userID -> active sessionID
+	userSessions     map[string]string   // ownerKey(tenantID, userID) -> active sessionID
 	mu               sync.RWMutex
 	reconnectTimeout time.Duration
 	logger           *golog.Logger
@@ -107,6 +200,13 @@ type SessionManager struct {
 	stopCleanup     chan struct{}
 	cleanupWg       sync.WaitGroup
 	stopOnce        sync.Once
+
+	// Idle timeout, checked by the cleanup goroutine (disabled when idleTimeout is 0)
+	idleTimeout       time.Duration
+	idleWarning       time.Duration
+	idleCheckInterval time.Duration
+	idleHandler       IdleHandler
+	idleWarned        map[string]time.Time // sessionID -> LastActivity when the expiry warning was sent
 }
 
 // StorageLoader provides the ability to load active sessions from persistent storage.
@@ -127,7 +227,17 @@ func NewSessionManager(reconnectTimeout time.Duration, logger *golog.Logger) *Se
 		cleanupInterval:  5 * time.Minute,  // Default: cleanup every 5 minutes
 		sessionTTL:       15 * time.Minute, // Default: remove sessions 15 minutes after EndTime
 		stopCleanup:      make(chan struct{}),
+		idleWarned:       make(map[string]time.Time),
+	}
+}
+
+// ownerKey identifies a session owner. User IDs are only unique within a tenant,
+// so the tenant is part of the key; the default tenant uses the bare user ID.
+func ownerKey(tenantID, userID string) string {
+	if tenantID == "" {
+		return userID
 	}
+	return tenantID + "\x00" + userID
 }
 
 // RehydrateFromStorage loads active sessions from persistent storage into the
@@ -148,7 +258,7 @@ func (sm *SessionManager) RehydrateFromStorage(loader StorageLoader) error {
 			continue
 		}
 		sm.sessions[sess.ID] = sess
-		sm.userSessions[sess.UserID] = sess.ID
+		sm.userSessions[ownerKey(sess.TenantID, sess.UserID)] = sess.ID
 		loaded++
 	}
 
@@ -161,6 +271,12 @@ func (sm *SessionManager) RehydrateFromStorage(loader StorageLoader) error {
 // NOTE: The returned *Session pointer is shared. Callers must use Session.mu
 // for any field mutations to avoid data races with concurrent goroutines.
 func (sm *SessionManager) CreateSession(userID string) (*Session, error) {
+	return sm.CreateSessionForTenant(userID, "")
+}
+
+// CreateSessionForTenant creates a new session for a user of the given tenant.
+// User IDs are only unique within a tenant, so the active-session check is per tenant.
+func (sm *SessionManager) CreateSessionForTenant(userID, tenantID string) (*Session, error) {
 	if userID == "" {
 		return nil, ErrInvalidUserID
 	}
@@ -169,7 +285,8 @@ func (sm *SessionManager) CreateSession(userID string) (*Session, error) {
 	defer sm.mu.Unlock()
 
 	// Check if user already has an active session
-	if existingSessionID, exists := sm.userSessions[userID]; exists {
+	key := ownerKey(tenantID, userID)
+	if existingSessionID, exists := sm.userSessions[key]; exists {
 		if session, ok := sm.sessions[existingSessionID]; ok && session.IsActive {
 			return nil, fmt.Errorf("%w: session %s", ErrActiveSessionExists, existingSessionID)
 		}
@@ -187,6 +304,7 @@ func (sm *SessionManager) CreateSession(userID string) (*Session, error) {
 	session := &Session{
 		ID:                 sessionID,
 		UserID:             userID,
+		TenantID:           tenantID,
 		Name:               "",
 		ModelID:            "",
 		Messages:           []*Message{},
@@ -204,15 +322,65 @@ func (sm *SessionManager) CreateSession(userID string) (*Session, error) {
 
 	// Store session and mapping
 	sm.sessions[session.ID] = session
-	sm.userSessions[userID] = session.ID
+	sm.userSessions[key] = session.ID
 
-	sm.logger.Info("Session created", "session_id", session.ID, "user_id", userID)
+	sm.logger.Info("Session created", "session_id", session.ID, "user_id", userID, "tenant_id", tenantID)
 	return session, nil
 }
 
+// ForkSession creates a new active session for the owner of source holding copies of
+// its first messageCount messages, so the conversation can continue down another path
+// while source is left untouched. The name and model are carried over.
+// Like CreateSessionForTenant, fails with ErrActiveSessionExists if the owner already
+// has an active session; end it first.
+func (sm *SessionManager) ForkSession(source *Session, messageCount int) (*Session, error) {
+	if source == nil {
+		return nil, ErrInvalidSessionID
+	}
+
+	source.mu.RLock()
+	total := len(source.Messages)
+	if messageCount < 0 || messageCount > total {
+		source.mu.RUnlock()
+		return nil, fmt.Errorf("%w: %d (session has %d messages)", ErrInvalidMessageIndex, messageCount, total)
+	}
+	messages := make([]*Message, messageCount)
+	for i, msg := range source.Messages[:messageCount] {
+		messages[i] = msg.clone()
+		messages[i].Feedback = nil // ratings stay with the original session
+		messages[i].ReadAt = nil
+	}
+	name, modelID := source.Name, source.ModelID
+	source.mu.RUnlock()
+
+	fork, err := sm.CreateSessionForTenant(source.UserID, source.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	fork.mu.Lock()
+	fork.Name = name
+	fork.ModelID = modelID
+	fork.Messages = messages
+	fork.mu.Unlock()
+
+	sm.logger.Info("Session forked",
+		"session_id", fork.ID,
+		"source_session_id", source.ID,
+		"user_id", source.UserID,
+		"messages", messageCount)
+	return fork, nil
+}
+
 // GetActiveSessionForUser returns the user's active session, if any.
 // Returns ErrSessionNotFound if the user has no active session.
 func (sm *SessionManager) GetActiveSessionForUser(userID string) (*Session, error) {
+	return sm.GetActiveSessionForTenantUser("", userID)
+}
+
+// GetActiveSessionForTenantUser returns the active session of a user of the given tenant.
+// Returns ErrSessionNotFound if the user has no active session.
+func (sm *SessionManager) GetActiveSessionForTenantUser(tenantID, userID string) (*Session, error) {
 	if userID == "" {
 		return nil, ErrInvalidUserID
 	}
@@ -220,7 +388,7 @@ func (sm *SessionManager) GetActiveSessionForUser(userID string) (*Session, erro
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
-	sessionID, exists := sm.userSessions[userID]
+	sessionID, exists := sm.userSessions[ownerKey(tenantID, userID)]
 	if !exists {
 		return nil, fmt.Errorf("%w: no active session for user %s", ErrSessionNotFound, userID)
 	}
@@ -295,7 +463,7 @@ func (sm *SessionManager) RestoreSession(userID, sessionID string) (*Session, er
 	session.mu.Unlock()
 
 	// Restore user mapping
-	sm.userSessions[userID] = sessionID
+	sm.userSessions[ownerKey(session.TenantID, userID)] = sessionID
 
 	sm.logger.Info("Session restored", "session_id", sessionID, "user_id", userID)
 	return session, nil
@@ -323,14 +491,39 @@ func (sm *SessionManager) EndSession(sessionID string) error {
 	session.mu.Unlock()
 
 	// Remove user mapping
-	delete(sm.userSessions, session.UserID)
+	delete(sm.userSessions, ownerKey(session.TenantID, session.UserID))
 
 	sm.logger.Info("Session ended", "session_id", sessionID, "user_id", session.UserID, "duration", time.Since(session.StartTime))
 	return nil
 }
 
+// RemoveSessions drops sessions from memory immediately, whether active or not,
+// e.g. after their data was erased from storage. Unknown IDs are ignored.
+// Returns the number of sessions removed.
+func (sm *SessionManager) RemoveSessions(sessionIDs []string) int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	removed := 0
+	for _, sessionID := range sessionIDs {
+		session, exists := sm.sessions[sessionID]
+		if !exists {
+			continue
+		}
+
+		key := ownerKey(session.TenantID, session.UserID)
+		if sm.userSessions[key] == sessionID {
+			delete(sm.userSessions, key)
+		}
+		delete(sm.sessions, sessionID)
+		removed++
+	}
+	return removed
+}
+
 // StartCleanup starts the background cleanup goroutine
 // This should be called after creating the SessionManager
+// When an idle timeout is set, the goroutine also ends idle sessions.
 func (sm *SessionManager) StartCleanup() {
 	sm.cleanupWg.Add(1)
 	go func() {
@@ -338,10 +531,20 @@ func (sm *SessionManager) StartCleanup() {
 		ticker := time.NewTicker(sm.cleanupInterval)
 		defer ticker.Stop()
 
+		// A nil channel never fires, so idle checks only run when enabled
+		var idleTick <-chan time.Time
+		if sm.idleTimeout > 0 {
+			idleTicker := time.NewTicker(sm.idleCheckInterval)
+			defer idleTicker.Stop()
+			idleTick = idleTicker.C
+		}
+
 		for {
 			select {
 			case <-ticker.C:
 				sm.cleanupExpiredSessions()
+			case now := <-idleTick:
+				sm.checkIdleSessions(now)
 			case <-sm.stopCleanup:
 				return
 			}
@@ -431,6 +634,53 @@ func (sm *SessionManager) SetSessionNameFromMessage(sessionID, message string) e
 	return nil
 }
 
+// SetSessionName replaces the session name, e.g. when the user renames the session
+func (sm *SessionManager) SetSessionName(sessionID, name string) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	session.Name = name
+	session.mu.Unlock()
+
+	return nil
+}
+
+// ReplaceSessionName sets the session name to name only if it is still old, so
+// a generated title never overwrites a rename made while it was being generated.
+// It reports whether the name was replaced.
+func (sm *SessionManager) ReplaceSessionName(sessionID, old, name string) (bool, error) {
+	if sessionID == "" {
+		return false, ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return false, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	// No else needed: early return pattern (renamed in the meantime)
+	if session.Name != old {
+		return false, nil
+	}
+	session.Name = name
+	return true, nil
+}
+
 // GenerateSessionName generates a descriptive session name from the first message
 // It extracts the first sentence or line, truncates to maxLength, and returns a default
 // name if the message is empty or whitespace-only.
@@ -722,6 +972,117 @@ func (sm *SessionManager) SetModelID(sessionID, modelID string) error {
 	return nil
 }
 
+// SetPromptTemplateID selects the system prompt template for the session
+func (sm *SessionManager) SetPromptTemplateID(sessionID, templateID string) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.PromptTemplateID = templateID
+
+	return nil
+}
+
+// SetClientInfo records the client SDK that created the session
+func (sm *SessionManager) SetClientInfo(sessionID string, info ClientInfo) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.Client = &info
+
+	return nil
+}
+
+// SetExperiments records the A/B experiment variants the session was assigned
+func (sm *SessionManager) SetExperiments(sessionID string, variants []ExperimentVariant) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.Experiments = append([]ExperimentVariant(nil), variants...)
+
+	return nil
+}
+
+// SetLLMParams sets the client-chosen LLM parameters for the session
+func (sm *SessionManager) SetLLMParams(sessionID string, params LLMParams) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.LLMParams = &params
+
+	return nil
+}
+
+// SetProviderThread records the LLM provider thread the session continues; nil
+// clears it
+func (sm *SessionManager) SetProviderThread(sessionID string, thread *ProviderThread) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.ProviderThread = thread
+
+	return nil
+}
+
 // GetModelID returns the model ID for the session
 // Returns empty string if no model is set
 // Returns error if session not found
@@ -762,8 +1123,13 @@ func (sm *SessionManager) MarkHelpRequested(sessionID string) error {
 	session.mu.Lock()
 	defer session.mu.Unlock()
 
+	now := time.Now()
+	// No else needed: optional operation (a repeated request keeps its place in the queue)
+	if session.HelpRequestedAt.IsZero() && session.AssistingAdminID == "" {
+		session.HelpRequestedAt = now
+	}
 	session.HelpRequested = true
-	session.LastActivity = time.Now()
+	session.LastActivity = now
 
 	sm.logger.Info("Help requested for session", "session_id", sessionID, "user_id", session.UserID)
 	return nil
@@ -819,10 +1185,16 @@ func (sm *SessionManager) MarkAdminAssisted(sessionID, adminID, adminName string
 		return fmt.Errorf("%w: %s (%s)", ErrAlreadyAssisted, session.AssistingAdminName, session.AssistingAdminID)
 	}
 
+	now := time.Now()
+	// No else needed: optional operation (a repeated takeover by the same admin keeps its start time)
+	if session.AssistingAdminID == "" {
+		session.AssistStartTime = now
+	}
 	session.AdminAssisted = true
 	session.AssistingAdminID = adminID
 	session.AssistingAdminName = adminName
-	session.LastActivity = time.Now()
+	session.HelpRequestedAt = time.Time{} // Leaves the help queue
+	session.LastActivity = now
 
 	sm.logger.Info("Admin joined session",
 		"session_id", sessionID,
@@ -852,6 +1224,7 @@ func (sm *SessionManager) ClearAdminAssistance(sessionID string) error {
 	adminID := session.AssistingAdminID
 	session.AssistingAdminID = ""
 	session.AssistingAdminName = ""
+	session.AssistStartTime = time.Time{}
 	session.LastActivity = time.Now()
 
 	sm.logger.Info("Admin left session",
@@ -860,6 +1233,50 @@ func (sm *SessionManager) ClearAdminAssistance(sessionID string) error {
 	return nil
 }
 
+// HandBack ends the admin takeover of a session so user messages are routed to
+// the LLM again, and returns the intervention window. The AdminAssisted flag is
+// kept as a historical record. Returns ErrNotAssisted when no admin is assisting.
+func (sm *SessionManager) HandBack(sessionID, handedBackBy string) (*AdminIntervention, error) {
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.AssistingAdminID == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotAssisted, sessionID)
+	}
+
+	now := time.Now()
+	intervention := &AdminIntervention{
+		AdminID:      session.AssistingAdminID,
+		AdminName:    session.AssistingAdminName,
+		StartTime:    session.AssistStartTime,
+		EndTime:      now,
+		HandedBackBy: handedBackBy,
+	}
+	session.AssistingAdminID = ""
+	session.AssistingAdminName = ""
+	session.AssistStartTime = time.Time{}
+	session.LastActivity = now
+
+	sm.logger.Info("Session handed back to AI",
+		"session_id", sessionID,
+		"admin_id", intervention.AdminID,
+		"handed_back_by", handedBackBy,
+		"duration", now.Sub(intervention.StartTime))
+	return intervention, nil
+}
+
 // GetAssistingAdmin returns the admin ID and name assisting a session
 // Returns empty strings if no admin is assisting
 // Returns error if session not found
@@ -889,6 +1306,47 @@ func (s *Session) GetModelID() string {
 	return s.ModelID
 }
 
+// GetPromptTemplateID returns the session's prompt template ID in a thread-safe manner.
+func (s *Session) GetPromptTemplateID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.PromptTemplateID
+}
+
+// GetExperiments returns a copy of the session's A/B experiment variants in a
+// thread-safe manner
+func (s *Session) GetExperiments() []ExperimentVariant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]ExperimentVariant(nil), s.Experiments...)
+}
+
+// GetLLMParams returns a copy of the session's LLM parameters in a thread-safe
+// manner, or nil when the client has not set any.
+func (s *Session) GetLLMParams() *LLMParams {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// No else needed: early return pattern (no parameters set)
+	if s.LLMParams == nil {
+		return nil
+	}
+	params := *s.LLMParams
+	return &params
+}
+
+// GetProviderThread returns a copy of the session's LLM provider thread in a
+// thread-safe manner, or nil when there is none.
+func (s *Session) GetProviderThread() *ProviderThread {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// No else needed: early return pattern (no thread)
+	if s.ProviderThread == nil {
+		return nil
+	}
+	thread := *s.ProviderThread
+	return &thread
+}
+
 // GetAssistingAdminID returns the assisting admin's ID in a thread-safe manner.
 func (s *Session) GetAssistingAdminID() string {
 	s.mu.RLock()
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -250,6 +251,25 @@ func TestEndSession_EmptySessionID(t *testing.T) {
 	assert.Contains(t, err.Error(), "session ID")
 }
 
+func TestRemoveSessions(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	active, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	other, err := sm.CreateSession("user-456")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, sm.RemoveSessions([]string{active.ID, "unknown"}))
+
+	_, err = sm.GetSession(active.ID)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = sm.GetActiveSessionForUser("user-123")
+	assert.Error(t, err, "user mapping is removed")
+	_, err = sm.GetSession(other.ID)
+	assert.NoError(t, err)
+}
+
 func TestSessionTimeout_DefaultValue(t *testing.T) {
 	logger := getTestLogger()
 	sm := NewSessionManager(15*time.Minute, logger)
@@ -578,6 +598,50 @@ func TestSetSessionNameFromMessage_OnlyFirstMessage(t *testing.T) {
 	assert.Equal(t, "First message", retrieved.Name)
 }
 
+func TestSetSessionName(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetSessionNameFromMessage(session.ID, "First message"))
+
+	// Unlike SetSessionNameFromMessage, an existing name is replaced
+	require.NoError(t, sm.SetSessionName(session.ID, "Renamed"))
+	retrieved, err := sm.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", retrieved.Name)
+
+	assert.ErrorIs(t, sm.SetSessionName("non-existent-id", "Name"), ErrSessionNotFound)
+	assert.ErrorIs(t, sm.SetSessionName("", "Name"), ErrInvalidSessionID)
+}
+
+func TestReplaceSessionName(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	require.NoError(t, sm.SetSessionNameFromMessage(session.ID, "First message"))
+
+	replaced, err := sm.ReplaceSessionName(session.ID, "First message", "Generated title")
+	require.NoError(t, err)
+	assert.True(t, replaced)
+
+	// A name that changed in the meantime is kept
+	replaced, err = sm.ReplaceSessionName(session.ID, "First message", "Other title")
+	require.NoError(t, err)
+	assert.False(t, replaced)
+	retrieved, err := sm.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Generated title", retrieved.Name)
+
+	_, err = sm.ReplaceSessionName("non-existent-id", "", "Name")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = sm.ReplaceSessionName("", "", "Name")
+	assert.ErrorIs(t, err, ErrInvalidSessionID)
+}
+
 func TestSetSessionNameFromMessage_NonExistentSession(t *testing.T) {
 	logger := getTestLogger()
 	sm := NewSessionManager(15*time.Minute, logger)
@@ -976,6 +1040,85 @@ func TestSetModelID(t *testing.T) {
 	assert.Equal(t, "gpt-4", modelID)
 }
 
+func TestSetPromptTemplateID(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	assert.Empty(t, session.GetPromptTemplateID())
+
+	require.NoError(t, sm.SetPromptTemplateID(session.ID, "support"))
+	assert.Equal(t, "support", session.GetPromptTemplateID())
+
+	assert.ErrorIs(t, sm.SetPromptTemplateID("", "support"), ErrInvalidSessionID)
+	assert.ErrorIs(t, sm.SetPromptTemplateID("non-existent-session", "support"), ErrSessionNotFound)
+}
+
+func TestSetClientInfo(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	assert.Nil(t, session.Client)
+
+	require.NoError(t, sm.SetClientInfo(session.ID, ClientInfo{AppVersion: "2.4.1", Platform: "ios"}))
+	session.RLock()
+	assert.Equal(t, &ClientInfo{AppVersion: "2.4.1", Platform: "ios"}, session.Client)
+	session.RUnlock()
+
+	assert.ErrorIs(t, sm.SetClientInfo("", ClientInfo{}), ErrInvalidSessionID)
+	assert.ErrorIs(t, sm.SetClientInfo("non-existent-session", ClientInfo{}), ErrSessionNotFound)
+}
+
+func TestSetLLMParams(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	assert.Nil(t, session.GetLLMParams())
+
+	temperature := 0.3
+	require.NoError(t, sm.SetLLMParams(session.ID, LLMParams{Temperature: &temperature, MaxTokens: 500}))
+	params := session.GetLLMParams()
+	require.NotNil(t, params)
+	assert.Equal(t, 0.3, *params.Temperature)
+	assert.Equal(t, 500, params.MaxTokens)
+	assert.Nil(t, params.TopP)
+
+	params.MaxTokens = 1
+	assert.Equal(t, 500, session.GetLLMParams().MaxTokens, "callers get a copy")
+
+	assert.ErrorIs(t, sm.SetLLMParams("", LLMParams{}), ErrInvalidSessionID)
+	assert.ErrorIs(t, sm.SetLLMParams("non-existent-session", LLMParams{}), ErrSessionNotFound)
+}
+
+func TestSetProviderThread(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	assert.Nil(t, session.GetProviderThread())
+
+	require.NoError(t, sm.SetProviderThread(session.ID, &ProviderThread{ModelID: "support", ID: "conv_1"}))
+	thread := session.GetProviderThread()
+	require.NotNil(t, thread)
+	assert.Equal(t, "support", thread.ModelID)
+	assert.Equal(t, "conv_1", thread.ID)
+
+	thread.ID = "changed"
+	assert.Equal(t, "conv_1", session.GetProviderThread().ID, "callers get a copy")
+
+	require.NoError(t, sm.SetProviderThread(session.ID, nil))
+	assert.Nil(t, session.GetProviderThread())
+
+	assert.ErrorIs(t, sm.SetProviderThread("", nil), ErrInvalidSessionID)
+	assert.ErrorIs(t, sm.SetProviderThread("non-existent-session", nil), ErrSessionNotFound)
+}
+
 func TestSetModelID_EmptySessionID(t *testing.T) {
 	logger := getTestLogger()
 	sm := NewSessionManager(15*time.Minute, logger)
@@ -1108,3 +1251,209 @@ func TestModelSelection_Persistence(t *testing.T) {
 	// Model ID should still be set
 	assert.Equal(t, "gpt-4", restored.ModelID)
 }
+
+func TestCreateSessionForTenant_IsolatesUsersByTenant(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	// The same user ID in two tenants is two different users
+	sessA, err := sm.CreateSessionForTenant("user-123", "tenant-a")
+	require.NoError(t, err)
+	sessB, err := sm.CreateSessionForTenant("user-123", "tenant-b")
+	require.NoError(t, err)
+	assert.NotEqual(t, sessA.ID, sessB.ID)
+	assert.Equal(t, "tenant-a", sessA.TenantID)
+	assert.Equal(t, "tenant-b", sessB.TenantID)
+
+	_, err = sm.CreateSessionForTenant("user-123", "tenant-a")
+	assert.ErrorIs(t, err, ErrActiveSessionExists)
+
+	active, err := sm.GetActiveSessionForTenantUser("tenant-b", "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, sessB.ID, active.ID)
+
+	// The default tenant is separate from both
+	_, err = sm.GetActiveSessionForUser("user-123")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	require.NoError(t, sm.EndSession(sessA.ID))
+	_, err = sm.GetActiveSessionForTenantUser("tenant-a", "user-123")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = sm.GetActiveSessionForTenantUser("tenant-b", "user-123")
+	assert.NoError(t, err)
+}
+
+func TestForkSession(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	source, err := sm.CreateSessionForTenant("user-123", "tenant-a")
+	require.NoError(t, err)
+	source.Name = "Trip planning"
+	source.ModelID = "gpt-4"
+	for _, content := range []string{"first", "second", "third"} {
+		require.NoError(t, sm.AddMessage(source.ID, &Message{
+			Content:  content,
+			Sender:   "user",
+			Metadata: map[string]string{"k": content},
+		}))
+	}
+
+	// The owner must end the active session before forking
+	_, err = sm.ForkSession(source, 2)
+	assert.ErrorIs(t, err, ErrActiveSessionExists)
+	require.NoError(t, sm.EndSession(source.ID))
+
+	_, err = sm.ForkSession(source, 4)
+	assert.ErrorIs(t, err, ErrInvalidMessageIndex)
+
+	fork, err := sm.ForkSession(source, 2)
+	require.NoError(t, err)
+	assert.NotEqual(t, source.ID, fork.ID)
+	assert.Equal(t, "user-123", fork.UserID)
+	assert.Equal(t, "tenant-a", fork.TenantID)
+	assert.Equal(t, "Trip planning", fork.Name)
+	assert.Equal(t, "gpt-4", fork.ModelID)
+	assert.True(t, fork.IsActive)
+	require.Len(t, fork.Messages, 2)
+	assert.Equal(t, "second", fork.Messages[1].Content)
+
+	// The fork shares no state with the original
+	fork.Messages[0].Metadata["k"] = "changed"
+	assert.Equal(t, "first", source.Messages[0].Metadata["k"])
+	assert.Len(t, source.Messages, 3)
+
+	active, err := sm.GetActiveSessionForTenantUser("tenant-a", "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, fork.ID, active.ID)
+}
+
+func TestHandBack(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+
+	_, err = sm.HandBack(session.ID, "admin-1")
+	assert.ErrorIs(t, err, ErrNotAssisted)
+
+	before := time.Now()
+	require.NoError(t, sm.MarkAdminAssisted(session.ID, "admin-1", "Alice"))
+
+	intervention, err := sm.HandBack(session.ID, "admin-2")
+	require.NoError(t, err)
+	assert.Equal(t, "admin-1", intervention.AdminID)
+	assert.Equal(t, "Alice", intervention.AdminName)
+	assert.Equal(t, "admin-2", intervention.HandedBackBy)
+	assert.False(t, intervention.StartTime.Before(before))
+	assert.False(t, intervention.EndTime.Before(intervention.StartTime))
+
+	// The takeover is over but remains on record
+	retrieved, err := sm.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Empty(t, retrieved.GetAssistingAdminID())
+	assert.True(t, retrieved.AdminAssisted)
+
+	_, err = sm.HandBack(session.ID, "admin-1")
+	assert.ErrorIs(t, err, ErrNotAssisted)
+	_, err = sm.HandBack("non-existent-id", "admin-1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSetMessageFeedback(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	require.NoError(t, sm.AddMessage(session.ID, &Message{Content: "Hi", Sender: "user"}))
+	require.NoError(t, sm.AddMessage(session.ID, &Message{Content: "Hello!", Sender: "ai"}))
+
+	feedback := &Feedback{Rating: "up", Comment: "Spot on", UpdatedAt: time.Now()}
+	require.NoError(t, sm.SetMessageFeedback(session.ID, 1, feedback))
+	assert.ErrorIs(t, sm.SetMessageFeedback(session.ID, 0, feedback), ErrNotAIMessage)
+	assert.ErrorIs(t, sm.SetMessageFeedback(session.ID, 2, feedback), ErrInvalidMessageIndex)
+	assert.ErrorIs(t, sm.SetMessageFeedback("missing", 1, feedback), ErrSessionNotFound)
+
+	// The session keeps its own copy
+	feedback.Rating = "down"
+	got, err := sm.GetSession(session.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.Messages[1].Feedback)
+	assert.Equal(t, "up", got.Messages[1].Feedback.Rating)
+	assert.Equal(t, "Spot on", got.Messages[1].Feedback.Comment)
+
+	// Forks start without ratings
+	require.NoError(t, sm.EndSession(session.ID))
+	fork, err := sm.ForkSession(got, 2)
+	require.NoError(t, err)
+	assert.Nil(t, fork.Messages[1].Feedback)
+}
+
+func TestSetPins(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	require.NoError(t, sm.AddMessage(session.ID, &Message{Content: "Hi", Sender: "user"}))
+	require.NoError(t, sm.AddMessage(session.ID, &Message{Content: "Hello!", Sender: "ai"}))
+
+	pins := []Pin{
+		{MessageIndex: 1, PinnedBy: "user-123", PinnedAt: time.Now()},
+		{MessageIndex: 5, PinnedBy: "admin-1", PinnedAt: time.Now()},
+	}
+	require.NoError(t, sm.SetPins(session.ID, pins))
+	assert.ErrorIs(t, sm.SetPins("missing", pins), ErrSessionNotFound)
+	assert.ErrorIs(t, sm.SetPins("", pins), ErrInvalidSessionID)
+
+	// The session keeps its own copy
+	pins[0].MessageIndex = 0
+	pinned := session.PinnedMessages()
+	require.Len(t, pinned, 1, "pins of missing messages are skipped")
+	assert.Equal(t, 1, pinned[0].MessageIndex)
+	assert.Equal(t, "Hello!", pinned[0].Message.Content)
+}
+
+func TestMarkAdminMessagesRead(t *testing.T) {
+	logger := getTestLogger()
+	sm := NewSessionManager(15*time.Minute, logger)
+
+	session, err := sm.CreateSession("user-123")
+	require.NoError(t, err)
+	require.NoError(t, sm.AddMessage(session.ID, &Message{Content: "Help", Sender: "user"}))
+	require.NoError(t, sm.AddMessage(session.ID, &Message{Content: "On it", Sender: "admin"}))
+	require.NoError(t, sm.AddMessage(session.ID, &Message{Content: "Done", Sender: "admin"}))
+
+	readAt := time.Now()
+	marked, err := sm.MarkAdminMessagesRead(session.ID, readAt)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, marked)
+
+	// Messages already read keep their first read time
+	require.NoError(t, sm.AddMessage(session.ID, &Message{Content: "Anything else?", Sender: "admin"}))
+	marked, err = sm.MarkAdminMessagesRead(session.ID, readAt.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, marked)
+
+	got, err := sm.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.Messages[0].ReadAt, "only admin messages get read receipts")
+	require.NotNil(t, got.Messages[1].ReadAt)
+	assert.True(t, readAt.Equal(*got.Messages[1].ReadAt))
+
+	marked, err = sm.MarkAdminMessagesRead(session.ID, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, marked)
+
+	_, err = sm.MarkAdminMessagesRead("missing", time.Now())
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestFeedbackValidate(t *testing.T) {
+	assert.NoError(t, (&Feedback{Rating: "up"}).Validate())
+	assert.NoError(t, (&Feedback{Rating: "down", Comment: "Wrong answer"}).Validate())
+	assert.ErrorIs(t, (&Feedback{Rating: "meh"}).Validate(), ErrInvalidFeedback)
+	assert.ErrorIs(t, (&Feedback{Rating: "up", Comment: strings.Repeat("x", 1001)}).Validate(), ErrInvalidFeedback)
+}
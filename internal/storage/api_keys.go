@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/apikey"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// APIKeyDocument is an API key for server-to-server admin calls. Documents in
+// the chat_api_keys collection override the configured key of the same name.
+type APIKeyDocument struct {
+	Name     string `bson:"_id"`
+	Hash     string `bson:"hash"` // hex SHA-256 of the key
+	Scope    string `bson:"scope"`
+	TenantID string `bson:"tid,omitempty"`
+}
+
+// ListAPIKeys returns the API keys stored in MongoDB. Invalid keys are rejected
+// with apikey.ErrInvalidKey so a typo does not silently lock a service out or
+// grant it the wrong scope.
+func (s *StorageService) ListAPIKeys() ([]*apikey.Key, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_api_keys"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	cursor, err := s.apiKeys.Find(ctx, bson.M{}, gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldID, Value: 1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var keys []*apikey.Key
+	for cursor.Next(ctx) {
+		var doc APIKeyDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode api key: %w", err)
+		}
+		key := &apikey.Key{Name: doc.Name, Hash: doc.Hash, Scope: apikey.Scope(doc.Scope), TenantID: doc.TenantID}
+		// No else needed: early return pattern (guard clause)
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("api key %s: %w", doc.Name, err)
+		}
+		keys = append(keys, key)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return keys, nil
+}
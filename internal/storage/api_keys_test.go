@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/apikey"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAPIKeys(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.apiKeys = c })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keys, err := service.ListAPIKeys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = service.apiKeys.InsertOne(ctx, APIKeyDocument{Name: "reporting", Hash: apikey.Hash("secret"), Scope: "read", TenantID: "acme"})
+	require.NoError(t, err)
+
+	keys, err = service.ListAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, &apikey.Key{Name: "reporting", Hash: apikey.Hash("secret"), Scope: apikey.ScopeRead, TenantID: "acme"}, keys[0])
+
+	// Invalid keys are rejected rather than ignored
+	_, err = service.apiKeys.InsertOne(ctx, APIKeyDocument{Name: "billing", Hash: "secret", Scope: "admin"})
+	require.NoError(t, err)
+	_, err = service.ListAPIKeys()
+	assert.ErrorIs(t, err, apikey.ErrInvalidKey)
+}
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidAuditEntry is returned when an audit entry has no actor or action
+var ErrInvalidAuditEntry = errors.New("audit entry requires an actor and an action")
+
+// AuditEntry records one admin action in the audit_log collection of the
+// datastore of its tenant (see SetResidency). Entries are append-only and are
+// not affected by the session retention policy.
+type AuditEntry struct {
+	ID        string    `bson:"_id" json:"id"`
+	Action    string    `bson:"act" json:"action"` // constants.AuditAction*
+	ActorID   string    `bson:"actor" json:"actor_id"`
+	ActorName string    `bson:"actorNm,omitempty" json:"actor_name,omitempty"`
+	TenantID  string    `bson:"tid,omitempty" json:"tenant_id,omitempty"` // actor's tenant; empty for the default tenant
+	SessionID string    `bson:"sid,omitempty" json:"session_id,omitempty"`
+	UserID    string    `bson:"uid,omitempty" json:"user_id,omitempty"` // data subject of user data requests
+	IP        string    `bson:"ip" json:"ip"`
+	Method    string    `bson:"method" json:"method"`
+	Path      string    `bson:"path" json:"path"`
+	Status    int       `bson:"status" json:"status"` // HTTP status returned to the admin
+	Timestamp time.Time `bson:"ts" json:"timestamp"`
+}
+
+// AuditListOptions defines filtering and pagination options for listing audit entries
+type AuditListOptions struct {
+	// Pagination
+	Limit  int // Maximum number of results to return (default: 100, max: 1000)
+	Offset int // Number of results to skip for pagination
+
+	// Filtering
+	ActorID   string     // Filter by admin user ID
+	SessionID string     // Filter by target session
+	Action    string     // Filter by action (constants.AuditAction*)
+	From      *time.Time // Filter entries at or after this time
+	To        *time.Time // Filter entries at or before this time
+}
+
+// ensureAuditIndexes creates the indexes for the audit_log collection
+func (s *StorageService) ensureAuditIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+			Options: options.Index().SetName(constants.IndexAuditTime),
+		},
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldActorID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexAuditActor),
+		},
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldSessionID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexAuditSession).SetSparse(true),
+		},
+	}
+
+	_, err := s.auditLog.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log indexes: %w", err)
+	}
+	return nil
+}
+
+// RecordAudit appends an entry to the admin audit log of the datastore of
+// entry.TenantID. The ID and timestamp are filled in when not set.
+func (s *StorageService) RecordAudit(entry *AuditEntry) error {
+	// No else needed: early return pattern (guard clause)
+	if entry == nil || entry.ActorID == "" || entry.Action == "" {
+		return ErrInvalidAuditEntry
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "record_audit"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	// No else needed: optional operation (caller may supply its own ID)
+	if entry.ID == "" {
+		entry.ID = primitive.NewObjectID().Hex()
+	}
+	// No else needed: optional operation (caller may supply its own timestamp)
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	// Entries hold user IDs and IPs, so they stay in the tenant's datastore
+	auditLog := s.DatastoreFor(entry.TenantID).auditLog
+	err := s.retryOperation(ctx, "RecordAudit", func() error {
+		_, err := auditLog.InsertOne(ctx, entry)
+		return err
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// RecordImpersonatedMessage audits a chat message adminID sent as userID of
+// tenantID with an impersonation token. Messages arrive over a WebSocket or SSE
+// connection, so the entry has no HTTP method, path or status.
+func (s *StorageService) RecordImpersonatedMessage(adminID, tenantID, userID, sessionID, ip string) error {
+	return s.RecordAudit(&AuditEntry{
+		Action:    constants.AuditActionImpersonatedMessage,
+		ActorID:   adminID,
+		TenantID:  tenantID,
+		SessionID: sessionID,
+		UserID:    userID,
+		IP:        ip,
+	})
+}
+
+// ListAuditEntries lists audit entries, most recent first. On a tenant view only
+// actions of that tenant's admins are returned; unscoped services include the
+// datastores of pinned tenants.
+func (s *StorageService) ListAuditEntries(opts *AuditListOptions) ([]*AuditEntry, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_audit_entries"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	// Set defaults
+	if opts == nil {
+		opts = &AuditListOptions{}
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = constants.DefaultSessionLimit
+	}
+	if opts.Limit > constants.MaxSessionLimit {
+		opts.Limit = constants.MaxSessionLimit // Cap at max for performance
+	}
+
+	// No else needed: early return pattern (single datastore)
+	if s.tenantScoped || len(s.datastores) == 0 {
+		return s.findAuditEntries(ctx, opts, opts.Limit, opts.Offset)
+	}
+
+	// Each datastore returns its first Offset+Limit entries, which are merged and paged
+	entries, err := s.findAuditEntries(ctx, opts, opts.Offset+opts.Limit, 0)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	for _, view := range s.datastores {
+		viewEntries, err := view.findAuditEntries(ctx, opts, opts.Offset+opts.Limit, 0)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("datastore %s: %w", view.datastore, err)
+		}
+		entries = append(entries, viewEntries...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+	// No else needed: early return pattern (page past the last entry)
+	if opts.Offset >= len(entries) {
+		return []*AuditEntry{}, nil
+	}
+	entries = entries[opts.Offset:]
+	// No else needed: optional operation (trim to the page)
+	if len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+	}
+	return entries, nil
+}
+
+// findAuditEntries runs an audit log query on this service's datastore
+func (s *StorageService) findAuditEntries(ctx context.Context, opts *AuditListOptions, limit, skip int) ([]*AuditEntry, error) {
+	cursor, err := s.auditLog.Find(ctx, s.auditFilter(opts), gomongo.QueryOptions{
+		Sort:  bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+		Limit: int64(limit),
+		Skip:  int64(skip),
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]*AuditEntry, 0)
+	for cursor.Next(ctx) {
+		var entry AuditEntry
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return entries, nil
+}
+
+// auditFilter builds the query filter for ListAuditEntries
+func (s *StorageService) auditFilter(opts *AuditListOptions) bson.M {
+	filter := s.tenantFilter(bson.M{})
+
+	// No else needed: optional operation (only add filter if specified)
+	if opts.ActorID != "" {
+		filter[constants.MongoFieldActorID] = opts.ActorID
+	}
+	// No else needed: optional operation (only add filter if specified)
+	if opts.SessionID != "" {
+		filter[constants.MongoFieldSessionID] = opts.SessionID
+	}
+	// No else needed: optional operation (only add filter if specified)
+	if opts.Action != "" {
+		filter[constants.MongoFieldAction] = opts.Action
+	}
+
+	timeRange := bson.M{}
+	// No else needed: optional operation (only add filter if specified)
+	if opts.From != nil {
+		timeRange["$gte"] = *opts.From
+	}
+	// No else needed: optional operation (only add filter if specified)
+	if opts.To != nil {
+		timeRange["$lte"] = *opts.To
+	}
+	// No else needed: optional operation (only add filter if specified)
+	if len(timeRange) > 0 {
+		filter[constants.MongoFieldTimestamp] = timeRange
+	}
+	return filter
+}
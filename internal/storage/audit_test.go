@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAuditFilter(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	svc := &StorageService{}
+
+	filter := svc.auditFilter(&AuditListOptions{
+		ActorID:   "admin-1",
+		SessionID: "session-1",
+		Action:    constants.AuditActionExport,
+		From:      &from,
+		To:        &to,
+	})
+	assert.Equal(t, bson.M{
+		constants.MongoFieldActorID:   "admin-1",
+		constants.MongoFieldSessionID: "session-1",
+		constants.MongoFieldAction:    constants.AuditActionExport,
+		constants.MongoFieldTimestamp: bson.M{"$gte": from, "$lte": to},
+	}, filter)
+
+	// Tenant views only see their own tenant's entries
+	tenantFilter := svc.ForTenant("tenant-a").auditFilter(&AuditListOptions{})
+	assert.Equal(t, bson.M{constants.MongoFieldTenantID: "tenant-a"}, tenantFilter)
+}
+
+func TestRecordAudit_Invalid(t *testing.T) {
+	svc := &StorageService{}
+	assert.ErrorIs(t, svc.RecordAudit(nil), ErrInvalidAuditEntry)
+	assert.ErrorIs(t, svc.RecordAudit(&AuditEntry{Action: constants.AuditActionDrain}), ErrInvalidAuditEntry)
+	assert.ErrorIs(t, svc.RecordAudit(&AuditEntry{ActorID: "admin-1"}), ErrInvalidAuditEntry)
+}
+
+func TestRecordAudit_ListAuditEntries(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.auditLog = c })
+
+	base := time.Now().UTC().Truncate(time.Millisecond)
+	entries := []*AuditEntry{
+		{Action: constants.AuditActionTakeover, ActorID: "admin-1", SessionID: "session-1", IP: "10.0.0.1", Status: 200, Timestamp: base.Add(-2 * time.Minute)},
+		{Action: constants.AuditActionExport, ActorID: "admin-1", SessionID: "session-2", IP: "10.0.0.1", Status: 200, Timestamp: base.Add(-time.Minute)},
+		{Action: constants.AuditActionExport, ActorID: "admin-2", TenantID: "tenant-a", SessionID: "session-3", IP: "10.0.0.2", Status: 404, Timestamp: base},
+	}
+	for _, entry := range entries {
+		require.NoError(t, service.RecordAudit(entry))
+		assert.NotEmpty(t, entry.ID)
+	}
+
+	all, err := service.ListAuditEntries(nil)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "session-3", all[0].SessionID, "most recent first")
+	assert.Equal(t, "10.0.0.2", all[0].IP)
+	assert.Equal(t, 404, all[0].Status)
+
+	exports, err := service.ListAuditEntries(&AuditListOptions{Action: constants.AuditActionExport, ActorID: "admin-1"})
+	require.NoError(t, err)
+	require.Len(t, exports, 1)
+	assert.Equal(t, "session-2", exports[0].SessionID)
+
+	since := base.Add(-90 * time.Second)
+	recent, err := service.ListAuditEntries(&AuditListOptions{From: &since})
+	require.NoError(t, err)
+	assert.Len(t, recent, 2)
+
+	tenantA, err := service.ForTenant("tenant-a").ListAuditEntries(nil)
+	require.NoError(t, err)
+	require.Len(t, tenantA, 1)
+	assert.Equal(t, "admin-2", tenantA[0].ActorID)
+
+	defaultTenant, err := service.ForTenant("").ListAuditEntries(nil)
+	require.NoError(t, err)
+	assert.Len(t, defaultTenant, 2)
+}
@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ConflictStrategy decides what ImportSession does with a session that is
+// already stored
+type ConflictStrategy string
+
+// Conflict strategies of ImportSession
+const (
+	ConflictSkip      ConflictStrategy = "skip"      // keep the stored session
+	ConflictOverwrite ConflictStrategy = "overwrite" // replace the stored session with the backup
+	ConflictMerge     ConflictStrategy = "merge"     // add the backup's missing messages to the stored session
+)
+
+// RestoreOutcome describes what ImportSession did with a session
+type RestoreOutcome string
+
+// Outcomes of ImportSession
+const (
+	RestoreCreated     RestoreOutcome = "created"
+	RestoreSkipped     RestoreOutcome = "skipped"
+	RestoreOverwritten RestoreOutcome = "overwritten"
+	RestoreMerged      RestoreOutcome = "merged"
+)
+
+// ErrInvalidConflictStrategy is returned for an unknown conflict strategy
+var ErrInvalidConflictStrategy = errors.New("conflict strategy must be skip, overwrite or merge")
+
+// ParseConflictStrategy validates a conflict strategy name
+func ParseConflictStrategy(name string) (ConflictStrategy, error) {
+	strategy := ConflictStrategy(name)
+	switch strategy {
+	case ConflictSkip, ConflictOverwrite, ConflictMerge:
+		return strategy, nil
+	default:
+		return "", ErrInvalidConflictStrategy
+	}
+}
+
+// ExportSessions calls fn with every stored session, soft-deleted ones
+// included, in ID order. Message content is decrypted: ciphertexts are bound to
+// the encryption key and the message position, so backups carry plaintext and
+// are protected by their own key. Search hashes are dropped as they are
+// derived from the encryption key; ImportSession recomputes them.
+//
+// Fails when a message does not pass its integrity check, so tampered content
+// is never restored as genuine.
+func (s *StorageService) ExportSessions(ctx context.Context, fn func(doc *SessionDocument) error) error {
+	// No else needed: optional operation (write buffered messages first)
+	if s.batcher != nil {
+		s.flushMessages()
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "export_sessions"}).Observe(time.Since(start).Seconds())
+	}()
+
+	cursor, err := s.collection.Find(ctx, s.tenantFilter(bson.M{}), gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldID, Value: 1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to find sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc SessionDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode session document: %w", err)
+		}
+		msgs, err := s.openMessages(&doc)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return err
+		}
+		doc.Messages = msgs
+		doc.SearchTerms = nil
+		// No else needed: early return pattern (guard clause)
+		if err := fn(&doc); err != nil {
+			return err
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+	return nil
+}
+
+// ImportSession stores a session exported by ExportSessions, encrypting its
+// messages with this service's key. When the session already exists, strategy
+// decides what happens: skip keeps it, overwrite replaces it, and merge adds the
+// backup's messages it does not have, interleaved by timestamp; a merge that
+// adds nothing is reported as skipped.
+//
+// Returns ErrAnonymizedMode for sessions with messages in anonymized mode and
+// ErrMergeConflict when the stored session received messages during a merge.
+func (s *StorageService) ImportSession(doc *SessionDocument, strategy ConflictStrategy) (RestoreOutcome, error) {
+	// No else needed: early return pattern (guard clause)
+	if doc == nil {
+		return "", ErrInvalidSession
+	}
+	// No else needed: early return pattern (guard clause)
+	if doc.ID == "" {
+		return "", ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := ParseConflictStrategy(string(strategy)); err != nil {
+		return "", err
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.IsAnonymizedTenant(doc.TenantID) && len(doc.Messages) > 0 {
+		return "", ErrAnonymizedMode
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "import_session"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	sealed, err := s.sealDocument(doc, doc.Messages)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", err
+	}
+
+	// No else needed: early return pattern (replace or create)
+	if strategy == ConflictOverwrite {
+		var matched int64
+		err := s.retryOperation(ctx, "ImportSession.replace", func() error {
+			result, opErr := s.collection.ReplaceOne(ctx, bson.M{constants.MongoFieldID: doc.ID}, sealed, options.Replace().SetUpsert(true))
+			// No else needed: optional operation (count only on success)
+			if opErr == nil {
+				matched = result.MatchedCount
+			}
+			return opErr
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return "", fmt.Errorf("failed to restore session: %w", err)
+		}
+		// No else needed: early return pattern (guard clause)
+		if matched > 0 {
+			return RestoreOverwritten, nil
+		}
+		return RestoreCreated, nil
+	}
+
+	err = s.retryOperation(ctx, "ImportSession.insert", func() error {
+		_, opErr := s.collection.InsertOne(ctx, sealed)
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err == nil {
+		return RestoreCreated, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if !mongo.IsDuplicateKeyError(err) {
+		return "", fmt.Errorf("failed to restore session: %w", err)
+	}
+	// No else needed: early return pattern (keep the stored session)
+	if strategy == ConflictSkip {
+		return RestoreSkipped, nil
+	}
+	return s.mergeRestoredMessages(ctx, doc)
+}
+
+// mergeRestoredMessages adds the messages of doc missing from the stored
+// session of the same ID. Messages are the same when their timestamp, sender
+// and content match.
+func (s *StorageService) mergeRestoredMessages(ctx context.Context, doc *SessionDocument) (RestoreOutcome, error) {
+	var stored SessionDocument
+	err := s.retryOperation(ctx, "ImportSession.find", func() error {
+		return s.collection.FindOne(ctx, bson.M{constants.MongoFieldID: doc.ID}).Decode(&stored)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", fmt.Errorf("failed to get session: %w", err)
+	}
+	storedMsgs, err := s.openMessages(&stored)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", err
+	}
+
+	seen := make(map[string]bool, len(storedMsgs))
+	for _, msg := range storedMsgs {
+		seen[messageKey(msg)] = true
+	}
+	merged := append([]MessageDocument(nil), storedMsgs...)
+	for _, msg := range doc.Messages {
+		// No else needed: optional operation (only missing messages are added)
+		if !seen[messageKey(msg)] {
+			seen[messageKey(msg)] = true
+			merged = append(merged, msg)
+		}
+	}
+	// No else needed: early return pattern (nothing to add)
+	if len(merged) == len(storedMsgs) {
+		return RestoreSkipped, nil
+	}
+	// The stored pins follow their messages
+	merged, pins := sortMessagesByTime(merged, stored.Pins)
+
+	sealed, err := s.sealDocument(&stored, merged)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", err
+	}
+	set := bson.M{constants.MongoFieldMessages: sealed.Messages, constants.MongoFieldPins: pins}
+	// No else needed: optional operation (searchable hashes only when enabled)
+	if s.usesSearchHashIndex() {
+		set[constants.MongoFieldSearchTerms] = sealed.SearchTerms
+	}
+
+	// The stored session must not have changed since it was read
+	var matched int64
+	err = s.retryOperation(ctx, "ImportSession.merge", func() error {
+		result, opErr := s.collection.UpdateOne(ctx, withMessageCount(bson.M{constants.MongoFieldID: doc.ID}, len(storedMsgs)), bson.M{"$set": set})
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge session: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if matched == 0 {
+		return "", ErrMergeConflict
+	}
+	return RestoreMerged, nil
+}
+
+// sealDocument returns a copy of doc holding msgs, with their content encrypted
+// for doc's ID and the search hashes recomputed
+func (s *StorageService) sealDocument(doc *SessionDocument, msgs []MessageDocument) (*SessionDocument, error) {
+	sealed := *doc
+	sealed.Messages = make([]MessageDocument, len(msgs))
+	sealed.SearchTerms = nil
+	// No else needed: optional operation (searchable hashes only when enabled)
+	if s.usesSearchHashIndex() {
+		var terms []string
+		for _, msg := range msgs {
+			terms = append(terms, searchTerms(msg.Content)...)
+		}
+		sealed.SearchTerms = s.hashTerms(terms)
+	}
+	for i, msg := range msgs {
+		// No else needed: optional operation (only encrypt if key is available)
+		if len(s.encryptionKey) > 0 {
+			content, err := s.sealMessage(doc.ID, i, msg.Content)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt message content: %w", err)
+			}
+			msg.Content = content
+		}
+		sealed.Messages[i] = msg
+	}
+	return &sealed, nil
+}
+
+// messageKey identifies a message when merging a restored session
+func messageKey(msg MessageDocument) string {
+	return strconv.FormatInt(msg.Timestamp.UnixNano(), 10) + "\x00" + msg.Sender + "\x00" + msg.Content
+}
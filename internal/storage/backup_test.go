@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAndImportSessions(t *testing.T) {
+	service, cleanup := setupTestStorage(t, []byte("12345678901234567890123456789012"))
+	defer cleanup()
+
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:        "backed-up",
+		UserID:    "user-1",
+		StartTime: now.Add(-time.Hour),
+		Messages: []*session.Message{
+			{Content: "hello", Timestamp: now.Add(-time.Hour), Sender: "user"},
+			{Content: "hi there", Timestamp: now.Add(-59 * time.Minute), Sender: "ai"},
+		},
+	}))
+
+	var exported []*SessionDocument
+	require.NoError(t, service.ExportSessions(context.Background(), func(doc *SessionDocument) error {
+		exported = append(exported, doc)
+		return nil
+	}))
+	require.Len(t, exported, 1)
+	backup := exported[0]
+	require.Len(t, backup.Messages, 2)
+	assert.Equal(t, "hello", backup.Messages[0].Content, "exports carry decrypted content")
+
+	_, err := service.ImportSession(backup, "replace")
+	assert.ErrorIs(t, err, ErrInvalidConflictStrategy)
+
+	// Existing sessions are kept by skip
+	outcome, err := service.ImportSession(backup, ConflictSkip)
+	require.NoError(t, err)
+	assert.Equal(t, RestoreSkipped, outcome)
+
+	// Merge adds the backup's messages the stored session lacks
+	require.NoError(t, service.AddMessage("backed-up", &session.Message{Content: "newer", Timestamp: now, Sender: "user"}))
+	backup.Messages = append(backup.Messages, MessageDocument{Content: "only in backup", Timestamp: now.Add(-30 * time.Minute), Sender: "user"})
+	outcome, err = service.ImportSession(backup, ConflictMerge)
+	require.NoError(t, err)
+	assert.Equal(t, RestoreMerged, outcome)
+	merged, err := service.GetSession("backed-up")
+	require.NoError(t, err)
+	var contents []string
+	for _, msg := range merged.Messages {
+		contents = append(contents, msg.Content)
+	}
+	assert.Equal(t, []string{"hello", "hi there", "only in backup", "newer"}, contents)
+
+	outcome, err = service.ImportSession(backup, ConflictMerge)
+	require.NoError(t, err)
+	assert.Equal(t, RestoreSkipped, outcome, "merging again adds nothing")
+
+	// Overwrite replaces the stored session; missing sessions are created
+	outcome, err = service.ImportSession(backup, ConflictOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, RestoreOverwritten, outcome)
+	restored, err := service.GetSession("backed-up")
+	require.NoError(t, err)
+	assert.Len(t, restored.Messages, 3)
+
+	backup.ID = "restored-copy"
+	outcome, err = service.ImportSession(backup, ConflictSkip)
+	require.NoError(t, err)
+	assert.Equal(t, RestoreCreated, outcome)
+	copied, err := service.GetSession("restored-copy")
+	require.NoError(t, err)
+	require.Len(t, copied.Messages, 3)
+	assert.Equal(t, "hello", copied.Messages[0].Content, "content is re-encrypted for the new session")
+}
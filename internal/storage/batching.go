@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// messageBatcher buffers message appends per session, so a streamed
+// conversation costs one MongoDB update per batch instead of one per message
+type messageBatcher struct {
+	size    int                          // Messages of a session that trigger a write
+	pending map[string][]MessageDocument // sessionID -> messages not written yet, oldest first
+	stopped bool                         // Set by StopMessageBatching; messages are then written directly
+	mu      sync.Mutex                   // guards pending and stopped
+
+	// Held while writing so the messages of a session are written in order
+	// even when a batch is retried after a later one was buffered
+	flushMu sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// add buffers msgDoc for sessionID. buffered is false once batching was
+// stopped; full is true when the session's batch reached its size.
+func (b *messageBatcher) add(sessionID string, msgDoc MessageDocument) (buffered, full bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// No else needed: early return pattern (batching stopped)
+	if b.stopped {
+		return false, false
+	}
+	b.pending[sessionID] = append(b.pending[sessionID], msgDoc)
+	return true, len(b.pending[sessionID]) >= b.size
+}
+
+// take removes and returns the buffered messages of sessionID
+func (b *messageBatcher) take(sessionID string) []MessageDocument {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msgDocs := b.pending[sessionID]
+	delete(b.pending, sessionID)
+	return msgDocs
+}
+
+// requeue puts back msgDocs of a failed write ahead of the session's messages
+// buffered since
+func (b *messageBatcher) requeue(sessionID string, msgDocs []MessageDocument) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[sessionID] = append(msgDocs, b.pending[sessionID]...)
+}
+
+// sessions returns the IDs of the sessions with buffered messages
+func (b *messageBatcher) sessions() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]string, 0, len(b.pending))
+	for id := range b.pending {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// StartMessageBatching starts buffering the messages passed to AddMessage and
+// writing each session's messages in one update, every interval or as soon as
+// size messages of a session are buffered. Reads and updates of a session
+// (GetSession, EndSession, feedback and read receipts) write its buffered
+// messages first; session lists and searches may miss messages buffered for up
+// to interval. Call StopMessageBatching on shutdown to write the remaining
+// messages. Must be called on the base service before it is used; the
+// datastores of pinned tenants (see SetResidency) batch their own messages.
+func (s *StorageService) StartMessageBatching(interval time.Duration, size int) {
+	for _, view := range s.datastores {
+		view.StartMessageBatching(interval, size)
+	}
+	b := &messageBatcher{
+		size:    size,
+		pending: make(map[string][]MessageDocument),
+		stop:    make(chan struct{}),
+	}
+	s.batcher = b
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.flushMessages()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopMessageBatching stops the batching goroutine and writes every buffered
+// message; messages added afterwards are written directly. Safe to call
+// multiple times and when batching was never started.
+func (s *StorageService) StopMessageBatching() {
+	for _, view := range s.datastores {
+		view.StopMessageBatching()
+	}
+	b := s.batcher
+	// No else needed: early return pattern (batching not started)
+	if b == nil {
+		return
+	}
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+	b.wg.Wait()
+
+	b.mu.Lock()
+	b.stopped = true
+	b.mu.Unlock()
+	s.flushMessages()
+}
+
+// flushMessages writes the buffered messages of every session. Failed writes
+// are logged and kept for the next flush, except for sessions that no longer
+// exist.
+func (s *StorageService) flushMessages() {
+	for _, sessionID := range s.batcher.sessions() {
+		// No else needed: optional operation (errors are logged, next flush retries)
+		if err := s.flushSession(sessionID); err != nil {
+			util.LogError(s.logger, "storage", "flush buffered messages", err, "session_id", sessionID)
+		}
+	}
+}
+
+// flushSession writes the buffered messages of sessionID, if any
+func (s *StorageService) flushSession(sessionID string) error {
+	b := s.batcher
+	// No else needed: early return pattern (batching not started)
+	if b == nil {
+		return nil
+	}
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	msgDocs := b.take(sessionID)
+	// No else needed: early return pattern (nothing buffered)
+	if len(msgDocs) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	err := s.appendMessages(sessionID, msgDocs)
+	metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "flush_messages"}).Observe(time.Since(start).Seconds())
+	// No else needed: optional operation (keep the batch for a retry unless the session is gone)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		b.requeue(sessionID, msgDocs)
+	}
+	return err
+}
+
+// flushPending writes the buffered messages of sessionID before the session is
+// read or updated. Failures are logged and the operation goes on without them.
+func (s *StorageService) flushPending(sessionID string) {
+	// No else needed: optional operation (errors are logged, next flush retries)
+	if err := s.flushSession(sessionID); err != nil {
+		util.LogError(s.logger, "storage", "flush buffered messages", err, "session_id", sessionID)
+	}
+}
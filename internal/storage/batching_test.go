@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMessageBatcher_AddAndRequeue(t *testing.T) {
+	b := &messageBatcher{size: 2, pending: make(map[string][]MessageDocument)}
+
+	buffered, full := b.add("session-1", MessageDocument{Content: "one"})
+	assert.True(t, buffered)
+	assert.False(t, full)
+	buffered, full = b.add("session-1", MessageDocument{Content: "two"})
+	assert.True(t, buffered)
+	assert.True(t, full, "the batch is full at its size")
+	assert.Equal(t, []string{"session-1"}, b.sessions())
+
+	taken := b.take("session-1")
+	require.Len(t, taken, 2)
+	assert.Empty(t, b.sessions())
+
+	// A failed batch goes back ahead of the messages buffered since
+	b.add("session-1", MessageDocument{Content: "three"})
+	b.requeue("session-1", taken)
+	var contents []string
+	for _, msgDoc := range b.take("session-1") {
+		contents = append(contents, msgDoc.Content)
+	}
+	assert.Equal(t, []string{"one", "two", "three"}, contents)
+
+	b.stopped = true
+	buffered, _ = b.add("session-1", MessageDocument{Content: "four"})
+	assert.False(t, buffered, "stopped batchers leave messages to direct writes")
+}
+
+func TestMessageBatching(t *testing.T) {
+	service, cleanup := setupTestStorage(t, []byte("12345678901234567890123456789012"))
+	defer cleanup()
+
+	now := time.Now()
+	createSessionWithActivity(t, service, "session-1", now)
+	createSessionWithActivity(t, service, "session-2", now)
+	service.StartMessageBatching(time.Hour, 3)
+
+	add := func(sessionID, content string) {
+		require.NoError(t, service.AddMessage(sessionID, &session.Message{Content: content, Timestamp: now, Sender: constants.SenderUser}))
+	}
+	stored := func(sessionID string) int {
+		count, err := service.countMessages(context.Background(), bson.M{constants.MongoFieldID: sessionID})
+		require.NoError(t, err)
+		return count
+	}
+
+	add("session-1", "one")
+	add("session-1", "two")
+	assert.Equal(t, 0, stored("session-1"), "messages are buffered")
+
+	// Reads write the session's buffered messages first
+	sess, err := service.GetSession("session-1")
+	require.NoError(t, err)
+	require.Len(t, sess.Messages, 2)
+	assert.Equal(t, "two", sess.Messages[1].Content)
+
+	// A full batch is written right away
+	add("session-1", "three")
+	add("session-1", "four")
+	add("session-1", "five")
+	assert.Equal(t, 5, stored("session-1"))
+
+	// Stopping writes every buffered message, then messages are written directly
+	add("session-2", "hello")
+	add("session-1", "six")
+	service.StopMessageBatching()
+	assert.Equal(t, 1, stored("session-2"))
+	assert.Equal(t, 6, stored("session-1"))
+	add("session-2", "again")
+	assert.Equal(t, 2, stored("session-2"))
+
+	sess, err = service.GetSession("session-1")
+	require.NoError(t, err)
+	require.Len(t, sess.Messages, 6)
+	for i, content := range []string{"one", "two", "three", "four", "five", "six"} {
+		assert.Equal(t, content, sess.Messages[i].Content, "sealed for their position")
+		assert.Empty(t, sess.Messages[i].Metadata[constants.MetadataKeyIntegrity])
+	}
+}
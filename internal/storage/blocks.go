@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrUserBlockNotFound is returned when a user is not blocked, or their block expired
+	ErrUserBlockNotFound = errors.New("user block not found")
+	// ErrInvalidUserBlock is returned when a block has no user or its reason exceeds the limit
+	ErrInvalidUserBlock = errors.New("invalid user block")
+)
+
+// UserBlock bars a user from opening WebSocket and SSE connections until it
+// expires or is lifted. Blocks are per tenant: the same user ID in another
+// tenant is a different user. They are kept in the datastore of their tenant
+// (see SetResidency).
+type UserBlock struct {
+	ID        string     `bson:"_id" json:"-"`
+	UserID    string     `bson:"uid" json:"user_id"` // hashed in anonymized mode, like stored sessions
+	TenantID  string     `bson:"tid,omitempty" json:"tenant_id,omitempty"`
+	Reason    string     `bson:"reason,omitempty" json:"reason,omitempty"`
+	BlockedBy string     `bson:"by" json:"blocked_by"`
+	CreatedAt time.Time  `bson:"ts" json:"created_at"`
+	ExpiresAt *time.Time `bson:"exp,omitempty" json:"expires_at,omitempty"` // nil: until lifted
+}
+
+// Active reports whether the block is in force at now
+func (b *UserBlock) Active(now time.Time) bool {
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
+
+// userBlockID returns the document ID of the block of a user of a tenant
+func userBlockID(tenantID, userID string) string {
+	// No else needed: early return pattern (default tenant)
+	if tenantID == "" {
+		return userID
+	}
+	return tenantID + "\x00" + userID
+}
+
+// ensureBlockIndexes creates the indexes for the user_blocks collection.
+// Expired blocks are removed by MongoDB's TTL monitor; reads ignore them until then.
+func (s *StorageService) ensureBlockIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldTenantID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexBlockTenant),
+		},
+		{
+			Keys:    bson.D{{Key: "exp", Value: 1}},
+			Options: options.Index().SetName(constants.IndexBlockExpiry).SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := s.blocks.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create user block indexes: %w", err)
+	}
+	return nil
+}
+
+// BlockUser blocks block.UserID of block.TenantID, replacing an earlier block
+// of the user. The document ID and creation time are set; in anonymized mode
+// the user ID is stored hashed.
+func (s *StorageService) BlockUser(block *UserBlock) error {
+	// No else needed: early return pattern (guard clause)
+	if block == nil || block.UserID == "" {
+		return fmt.Errorf("%w: user ID is required", ErrInvalidUserBlock)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(block.Reason) > constants.MaxBanReasonLength {
+		return fmt.Errorf("%w: reason exceeds maximum length of %d bytes", ErrInvalidUserBlock, constants.MaxBanReasonLength)
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "block_user"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	block.UserID = s.StoredUserIDFor(block.TenantID, block.UserID)
+	block.ID = userBlockID(block.TenantID, block.UserID)
+	block.CreatedAt = time.Now().UTC()
+	blocks := s.DatastoreFor(block.TenantID).blocks
+	err := s.retryOperation(ctx, "BlockUser", func() error {
+		_, opErr := blocks.ReplaceOne(ctx, bson.M{constants.MongoFieldID: block.ID}, block, options.Replace().SetUpsert(true))
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+	return nil
+}
+
+// UnblockUser lifts the block of userID of tenantID. Returns
+// ErrUserBlockNotFound when the user is not blocked.
+func (s *StorageService) UnblockUser(tenantID, userID string) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "unblock_user"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	id := userBlockID(tenantID, s.StoredUserIDFor(tenantID, userID))
+	blocks := s.DatastoreFor(tenantID).blocks
+	var deleted int64
+	err := s.retryOperation(ctx, "UnblockUser", func() error {
+		result, opErr := blocks.DeleteOne(ctx, bson.M{constants.MongoFieldID: id})
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			deleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if deleted == 0 {
+		return ErrUserBlockNotFound
+	}
+	return nil
+}
+
+// GetUserBlock returns the active block of userID of tenantID, or
+// ErrUserBlockNotFound when the user is not blocked or the block expired
+func (s *StorageService) GetUserBlock(tenantID, userID string) (*UserBlock, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_user_block"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var block UserBlock
+	blocks := s.DatastoreFor(tenantID).blocks
+	err := s.retryOperation(ctx, "GetUserBlock", func() error {
+		return blocks.FindOne(ctx, bson.M{constants.MongoFieldID: userBlockID(tenantID, s.StoredUserIDFor(tenantID, userID))}).Decode(&block)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUserBlockNotFound
+		}
+		return nil, fmt.Errorf("failed to get user block: %w", err)
+	}
+	// The TTL monitor removes expired blocks only about once a minute
+	// No else needed: early return pattern (guard clause)
+	if !block.Active(time.Now()) {
+		return nil, ErrUserBlockNotFound
+	}
+	return &block, nil
+}
+
+// UserBlocked reports whether userID of tenantID is blocked from connecting.
+// It satisfies websocket.UserBlockChecker.
+func (s *StorageService) UserBlocked(tenantID, userID string) (bool, error) {
+	_, err := s.GetUserBlock(tenantID, userID)
+	// No else needed: early return pattern (not blocked)
+	if errors.Is(err, ErrUserBlockNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ListUserBlocks lists the active blocks visible through this service, newest
+// first. Unscoped services include the datastores of pinned tenants.
+func (s *StorageService) ListUserBlocks() ([]*UserBlock, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_user_blocks"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	blocks, err := s.findUserBlocks(ctx)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (tenant views already read the tenant's datastore)
+	if s.tenantScoped || len(s.datastores) == 0 {
+		return blocks, nil
+	}
+	for _, view := range s.datastores {
+		viewBlocks, err := view.findUserBlocks(ctx)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("datastore %s: %w", view.datastore, err)
+		}
+		blocks = append(blocks, viewBlocks...)
+	}
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].CreatedAt.After(blocks[j].CreatedAt)
+	})
+	return blocks, nil
+}
+
+// findUserBlocks lists the active blocks in this service's datastore, newest first
+func (s *StorageService) findUserBlocks(ctx context.Context) ([]*UserBlock, error) {
+	filter := s.tenantFilter(bson.M{
+		"$or": bson.A{
+			bson.M{"exp": bson.M{"$exists": false}},
+			bson.M{"exp": bson.M{"$gt": time.Now()}},
+		},
+	})
+	cursor, err := s.blocks.Find(ctx, filter, gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user blocks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	blocks := make([]*UserBlock, 0)
+	for cursor.Next(ctx) {
+		var block UserBlock
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&block); err != nil {
+			return nil, fmt.Errorf("failed to decode user block: %w", err)
+		}
+		blocks = append(blocks, &block)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return blocks, nil
+}
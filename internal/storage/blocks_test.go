@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserBlock_Active(t *testing.T) {
+	now := time.Now()
+	assert.True(t, (&UserBlock{}).Active(now), "blocks without expiry last until lifted")
+
+	expiresAt := now.Add(time.Minute)
+	block := &UserBlock{ExpiresAt: &expiresAt}
+	assert.True(t, block.Active(now))
+	assert.False(t, block.Active(expiresAt))
+
+	assert.Equal(t, "user-1", userBlockID("", "user-1"))
+	assert.NotEqual(t, userBlockID("acme", "user-1"), userBlockID("", "user-1"))
+
+	svc := &StorageService{}
+	assert.ErrorIs(t, svc.BlockUser(nil), ErrInvalidUserBlock)
+	assert.ErrorIs(t, svc.BlockUser(&UserBlock{}), ErrInvalidUserBlock)
+}
+
+func TestUserBlocks(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.blocks = c })
+
+	require.NoError(t, service.BlockUser(&UserBlock{UserID: "user-1", Reason: "spam", BlockedBy: "admin-1"}))
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, service.BlockUser(&UserBlock{UserID: "user-2", BlockedBy: "admin-1", ExpiresAt: &expired}))
+	require.NoError(t, service.BlockUser(&UserBlock{UserID: "user-1", TenantID: "acme", BlockedBy: "admin-2"}))
+
+	block, err := service.GetUserBlock("", "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "spam", block.Reason)
+	assert.Equal(t, "admin-1", block.BlockedBy)
+	assert.Nil(t, block.ExpiresAt)
+
+	blocked, err := service.UserBlocked("", "user-1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	blocked, err = service.UserBlocked("", "user-2")
+	require.NoError(t, err)
+	assert.False(t, blocked, "expired blocks are ignored before the TTL monitor removes them")
+	blocked, err = service.UserBlocked("other", "user-1")
+	require.NoError(t, err)
+	assert.False(t, blocked, "blocks are per tenant")
+
+	// Tenant views list only their tenant's active blocks
+	blocks, err := service.ForTenant("").ListUserBlocks()
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "user-1", blocks[0].UserID)
+	blocks, err = service.ListUserBlocks()
+	require.NoError(t, err)
+	assert.Len(t, blocks, 2)
+
+	// Blocking again replaces the block
+	require.NoError(t, service.BlockUser(&UserBlock{UserID: "user-1", Reason: "abuse", BlockedBy: "admin-3"}))
+	block, err = service.GetUserBlock("", "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "abuse", block.Reason)
+
+	require.NoError(t, service.UnblockUser("", "user-1"))
+	assert.ErrorIs(t, service.UnblockUser("", "user-1"), ErrUserBlockNotFound)
+	_, err = service.GetUserBlock("", "user-1")
+	assert.ErrorIs(t, err, ErrUserBlockNotFound)
+	blocked, err = service.UserBlocked("acme", "user-1")
+	require.NoError(t, err)
+	assert.True(t, blocked, "lifting a block leaves other tenants' blocks")
+}
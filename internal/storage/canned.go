@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrCannedNotFound is returned when a canned response does not exist or belongs to another tenant
+	ErrCannedNotFound = errors.New("canned response not found")
+	// ErrInvalidCanned is returned when a canned response has no title or steps, or exceeds a limit
+	ErrInvalidCanned = errors.New("invalid canned response")
+)
+
+// CannedResponse is an admin-managed reply that admins insert into sessions
+// they took over. A single step is a plain canned response; several steps form
+// a flow whose messages are sent in order, or one at a time.
+type CannedResponse struct {
+	ID         string     `bson:"_id" json:"id"`
+	TenantID   string     `bson:"tid,omitempty" json:"tenant_id,omitempty"`
+	Title      string     `bson:"title" json:"title"`
+	Steps      []string   `bson:"steps" json:"steps"`
+	UsageCount int64      `bson:"uses" json:"usage_count"`                          // times inserted into a session
+	LastUsedAt *time.Time `bson:"lastUsed,omitempty" json:"last_used_at,omitempty"` // last insertion
+	CreatedBy  string     `bson:"by" json:"created_by"`
+	CreatedAt  time.Time  `bson:"ts" json:"created_at"`
+	UpdatedAt  time.Time  `bson:"mt" json:"updated_at"`
+}
+
+// ValidateCanned checks a canned response's title and steps against the limits
+func ValidateCanned(title string, steps []string) error {
+	// No else needed: early return pattern (guard clause)
+	if title == "" || len(title) > constants.MaxCannedTitleLength {
+		return fmt.Errorf("%w: title is required and at most %d bytes", ErrInvalidCanned, constants.MaxCannedTitleLength)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(steps) == 0 || len(steps) > constants.MaxCannedSteps {
+		return fmt.Errorf("%w: between 1 and %d steps are required", ErrInvalidCanned, constants.MaxCannedSteps)
+	}
+	for i, step := range steps {
+		// No else needed: early return pattern (guard clause)
+		if strings.TrimSpace(step) == "" || len(step) > constants.MaxCannedStepLength {
+			return fmt.Errorf("%w: step %d must be non-empty and at most %d bytes", ErrInvalidCanned, i, constants.MaxCannedStepLength)
+		}
+	}
+	return nil
+}
+
+// ensureCannedIndexes creates the indexes for the canned_responses collection
+func (s *StorageService) ensureCannedIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldTenantID, Value: 1},
+				{Key: "uses", Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexCannedTenant),
+		},
+	}
+
+	_, err := s.canned.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create canned response indexes: %w", err)
+	}
+	return nil
+}
+
+// GetCanned returns a canned response. On a tenant view only canned responses
+// of that tenant are visible; ErrCannedNotFound is returned otherwise.
+func (s *StorageService) GetCanned(id string) (*CannedResponse, error) {
+	// No else needed: early return pattern (guard clause)
+	if id == "" {
+		return nil, ErrCannedNotFound
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_canned"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var canned CannedResponse
+	err := s.retryOperation(ctx, "GetCanned", func() error {
+		return s.canned.FindOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id})).Decode(&canned)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCannedNotFound
+		}
+		return nil, fmt.Errorf("failed to get canned response: %w", err)
+	}
+	return &canned, nil
+}
+
+// ListCanned lists the canned responses visible through this service, most
+// used first
+func (s *StorageService) ListCanned() ([]*CannedResponse, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_canned"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	cursor, err := s.canned.Find(ctx, s.tenantFilter(bson.M{}), gomongo.QueryOptions{
+		Sort: bson.D{{Key: "uses", Value: -1}, {Key: "title", Value: 1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list canned responses: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	list := make([]*CannedResponse, 0)
+	for cursor.Next(ctx) {
+		var canned CannedResponse
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&canned); err != nil {
+			return nil, fmt.Errorf("failed to decode canned response: %w", err)
+		}
+		list = append(list, &canned)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return list, nil
+}
+
+// CreateCanned stores a new canned response. The ID, timestamps and usage
+// counter are generated, and on a tenant view it is owned by that tenant.
+func (s *StorageService) CreateCanned(canned *CannedResponse) error {
+	// No else needed: early return pattern (guard clause)
+	if canned == nil {
+		return ErrInvalidCanned
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := ValidateCanned(canned.Title, canned.Steps); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "create_canned"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	canned.ID = primitive.NewObjectID().Hex()
+	canned.CreatedAt = now
+	canned.UpdatedAt = now
+	canned.UsageCount = 0
+	canned.LastUsedAt = nil
+	// No else needed: optional operation (unscoped callers choose the tenant)
+	if s.tenantScoped {
+		canned.TenantID = s.tenantID
+	}
+
+	err := s.retryOperation(ctx, "CreateCanned", func() error {
+		_, err := s.canned.InsertOne(ctx, canned)
+		return err
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create canned response: %w", err)
+	}
+	return nil
+}
+
+// UpdateCanned replaces the title and steps of a canned response and returns
+// the updated canned response. Its usage counter is kept.
+func (s *StorageService) UpdateCanned(id, title string, steps []string) (*CannedResponse, error) {
+	// No else needed: early return pattern (guard clause)
+	if err := ValidateCanned(title, steps); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "update_canned"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"title": title, "steps": steps, "mt": time.Now().UTC()}}
+	findOpts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var canned CannedResponse
+	err := s.retryOperation(ctx, "UpdateCanned", func() error {
+		return s.canned.FindOneAndUpdate(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id}), update, findOpts).Decode(&canned)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCannedNotFound
+		}
+		return nil, fmt.Errorf("failed to update canned response: %w", err)
+	}
+	return &canned, nil
+}
+
+// DeleteCanned removes a canned response
+func (s *StorageService) DeleteCanned(id string) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "delete_canned"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var deleted int64
+	err := s.retryOperation(ctx, "DeleteCanned", func() error {
+		result, opErr := s.canned.DeleteOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id}))
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			deleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to delete canned response: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if deleted == 0 {
+		return ErrCannedNotFound
+	}
+	return nil
+}
+
+// RecordCannedUse counts an insertion of a canned response into a session
+func (s *StorageService) RecordCannedUse(id string, at time.Time) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "record_canned_use"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	update := bson.M{
+		"$inc": bson.M{"uses": 1},
+		"$set": bson.M{"lastUsed": at.UTC()},
+	}
+	var matched int64
+	err := s.retryOperation(ctx, "RecordCannedUse", func() error {
+		result, opErr := s.canned.UpdateOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id}), update)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to record canned response use: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if matched == 0 {
+		return ErrCannedNotFound
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCanned(t *testing.T) {
+	assert.NoError(t, ValidateCanned("Greeting", []string{"Hello!"}))
+	assert.ErrorIs(t, ValidateCanned("", []string{"Hello!"}), ErrInvalidCanned)
+	assert.ErrorIs(t, ValidateCanned(strings.Repeat("t", constants.MaxCannedTitleLength+1), []string{"Hello!"}), ErrInvalidCanned)
+	assert.ErrorIs(t, ValidateCanned("Greeting", nil), ErrInvalidCanned)
+	assert.ErrorIs(t, ValidateCanned("Greeting", make([]string, constants.MaxCannedSteps+1)), ErrInvalidCanned)
+	assert.ErrorIs(t, ValidateCanned("Greeting", []string{"Hello!", "  "}), ErrInvalidCanned)
+
+	svc := &StorageService{}
+	assert.ErrorIs(t, svc.CreateCanned(nil), ErrInvalidCanned)
+	_, err := svc.UpdateCanned("id", "Greeting", nil)
+	assert.ErrorIs(t, err, ErrInvalidCanned)
+}
+
+func TestCanned_CRUDAndUsage(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.canned = c })
+
+	acme := service.ForTenant("acme")
+	flow := &CannedResponse{Title: "Refund", Steps: []string{"Sorry to hear that.", "I have started the refund."}, CreatedBy: "admin-1", TenantID: "other"}
+	require.NoError(t, acme.CreateCanned(flow))
+	assert.NotEmpty(t, flow.ID)
+	assert.Equal(t, "acme", flow.TenantID, "tenant views own the canned responses they create")
+	greeting := &CannedResponse{Title: "Greeting", Steps: []string{"Hello!"}}
+	require.NoError(t, acme.CreateCanned(greeting))
+
+	// Other tenants cannot see, change, use or delete it
+	other := service.ForTenant("")
+	_, err := other.GetCanned(flow.ID)
+	assert.ErrorIs(t, err, ErrCannedNotFound)
+	_, err = other.UpdateCanned(flow.ID, "Taken", []string{"Taken"})
+	assert.ErrorIs(t, err, ErrCannedNotFound)
+	assert.ErrorIs(t, other.RecordCannedUse(flow.ID, time.Now()), ErrCannedNotFound)
+	assert.ErrorIs(t, other.DeleteCanned(flow.ID), ErrCannedNotFound)
+
+	usedAt := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, acme.RecordCannedUse(flow.ID, usedAt))
+	require.NoError(t, acme.RecordCannedUse(flow.ID, usedAt))
+
+	updated, err := acme.UpdateCanned(flow.ID, "Refund flow", []string{"Refund started."})
+	require.NoError(t, err)
+	assert.Equal(t, "Refund flow", updated.Title)
+	assert.Equal(t, []string{"Refund started."}, updated.Steps)
+	assert.Equal(t, int64(2), updated.UsageCount, "updates keep the usage counter")
+	require.NotNil(t, updated.LastUsedAt)
+	assert.True(t, usedAt.Equal(*updated.LastUsedAt))
+
+	listed, err := acme.ListCanned()
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, flow.ID, listed[0].ID, "most used first")
+	assert.Zero(t, listed[1].UsageCount)
+
+	require.NoError(t, acme.DeleteCanned(greeting.ID))
+	_, err = acme.GetCanned(greeting.ID)
+	assert.ErrorIs(t, err, ErrCannedNotFound)
+}
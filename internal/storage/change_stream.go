@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Server error codes of a change stream that cannot be resumed from its token
+const (
+	errCodeChangeStreamFatal       = 280
+	errCodeChangeStreamHistoryLost = 286
+)
+
+// sessionChangePipeline selects the change events of existing session documents.
+// Inserted sessions are new to every other instance, so there is nothing to refresh.
+var sessionChangePipeline = mongo.Pipeline{
+	{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"update", "replace", "delete"}}}}},
+}
+
+// SessionChange is a change to a stored session, made by any instance or by
+// tooling writing to the collection directly
+type SessionChange struct {
+	SessionID string
+	Session   *session.Session // The session after the change; nil when it was deleted or soft-deleted
+}
+
+// sessionChangeEvent is the part of a change stream event the watcher reads
+type sessionChangeEvent struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID string `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *SessionDocument `bson:"fullDocument"`
+}
+
+// StartSessionWatch follows the session collection's change stream in a
+// background goroutine and calls handle with every change to a session, in
+// order. The stream requires a replica set or sharded cluster; when it fails it
+// is reopened after a backoff, resuming after the last change handled, so no
+// change is missed unless the oplog no longer holds it. Call StopSessionWatch
+// to stop it. The datastores of pinned tenants (see SetResidency) are followed
+// too, each with its own change stream; handle is then called from one
+// goroutine per datastore.
+func (s *StorageService) StartSessionWatch(handle func(SessionChange)) {
+	for _, view := range s.datastores {
+		view.StartSessionWatch(handle)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.watchCancel = cancel
+	s.watchWg.Add(1)
+	go func() {
+		defer s.watchWg.Done()
+		s.watchSessions(ctx, handle)
+	}()
+}
+
+// StopSessionWatch stops the change stream goroutine.
+// Safe to call multiple times and when the watch was never started.
+func (s *StorageService) StopSessionWatch() {
+	for _, view := range s.datastores {
+		view.StopSessionWatch()
+	}
+	// No else needed: early return pattern (watch not started)
+	if s.watchCancel == nil {
+		return
+	}
+	s.watchCancel()
+	s.watchWg.Wait()
+}
+
+// watchSessions reopens the change stream until ctx is done
+func (s *StorageService) watchSessions(ctx context.Context, handle func(SessionChange)) {
+	var resumeToken bson.Raw
+	delay := constants.ChangeStreamRetryDelay
+	for {
+		token, handled, err := s.followSessionChanges(ctx, resumeToken, handle)
+		// No else needed: optional operation (keep the previous token when nothing was handled)
+		if token != nil {
+			resumeToken = token
+		}
+		// No else needed: early return pattern (watch stopped)
+		if ctx.Err() != nil {
+			return
+		}
+		// No else needed: optional operation (a stream that worked starts a new backoff)
+		if handled > 0 {
+			delay = constants.ChangeStreamRetryDelay
+		}
+		// No else needed: optional operation (changes since the token are gone; start from now)
+		if changeStreamLost(err) {
+			s.logger.Warn("Session change stream history lost, changes may have been missed", "error", err)
+			resumeToken = nil
+		} else {
+			util.LogError(s.logger, "storage", "watch session changes", err, "retry_in", delay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, constants.ChangeStreamMaxRetryDelay)
+	}
+}
+
+// followSessionChanges opens the change stream after resumeToken (nil for now)
+// and handles its events until it fails or ctx is done. Returns the resume
+// token of the last event handled and the number of events handled.
+func (s *StorageService) followSessionChanges(ctx context.Context, resumeToken bson.Raw, handle func(SessionChange)) (bson.Raw, int, error) {
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	// No else needed: optional operation (first stream starts from now)
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+	stream, err := s.collection.Watch(ctx, sessionChangePipeline, opts)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open session change stream: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	var token bson.Raw
+	handled := 0
+	for stream.Next(ctx) {
+		token = stream.ResumeToken()
+		var event sessionChangeEvent
+		// No else needed: optional operation (undecodable events are skipped)
+		if err := stream.Decode(&event); err != nil {
+			util.LogError(s.logger, "storage", "decode session change", err)
+			continue
+		}
+		handle(s.sessionChange(&event))
+		handled++
+	}
+	return token, handled, stream.Err()
+}
+
+// sessionChange converts a change event to the session after the change
+func (s *StorageService) sessionChange(event *sessionChangeEvent) SessionChange {
+	change := SessionChange{SessionID: event.DocumentKey.ID}
+	// The full document is missing for deletes, and for updates when the
+	// session was deleted before it was looked up
+	// No else needed: early return pattern (deleted or soft-deleted)
+	if event.OperationType == "delete" || event.FullDocument == nil || event.FullDocument.DeletedAt != nil {
+		return change
+	}
+	change.Session = s.documentToSession(event.FullDocument)
+	return change
+}
+
+// changeStreamLost reports whether err means the stream cannot be resumed
+// from its token
+func changeStreamLost(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) &&
+		(serverErr.HasErrorCode(errCodeChangeStreamHistoryLost) || serverErr.HasErrorCode(errCodeChangeStreamFatal))
+}
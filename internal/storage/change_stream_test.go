@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSessionChange(t *testing.T) {
+	svc := &StorageService{}
+	now := time.Now()
+
+	event := &sessionChangeEvent{
+		OperationType: "update",
+		FullDocument: &SessionDocument{
+			ID:       "s1",
+			UserID:   "user-1",
+			Name:     "Renamed",
+			Messages: []MessageDocument{{Content: "hello", Sender: "admin", Timestamp: now}},
+		},
+	}
+	event.DocumentKey.ID = "s1"
+	change := svc.sessionChange(event)
+	assert.Equal(t, "s1", change.SessionID)
+	require.NotNil(t, change.Session)
+	assert.Equal(t, "Renamed", change.Session.Name)
+	require.Len(t, change.Session.Messages, 1)
+	assert.Equal(t, "hello", change.Session.Messages[0].Content)
+
+	event.FullDocument.DeletedAt = &now
+	assert.Nil(t, svc.sessionChange(event).Session, "soft-deleted sessions are reported deleted")
+
+	deleted := &sessionChangeEvent{OperationType: "delete"}
+	deleted.DocumentKey.ID = "s1"
+	change = svc.sessionChange(deleted)
+	assert.Equal(t, "s1", change.SessionID)
+	assert.Nil(t, change.Session)
+}
+
+func TestChangeStreamLost(t *testing.T) {
+	assert.True(t, changeStreamLost(mongo.CommandError{Code: errCodeChangeStreamHistoryLost}))
+	assert.True(t, changeStreamLost(fmt.Errorf("wrapped: %w", mongo.CommandError{Code: errCodeChangeStreamFatal})))
+	assert.False(t, changeStreamLost(mongo.CommandError{Code: 11600}))
+	assert.False(t, changeStreamLost(fmt.Errorf("connection reset")))
+	assert.False(t, changeStreamLost(nil))
+}
+
+func TestStopSessionWatch_NotStarted(t *testing.T) {
+	svc := &StorageService{}
+	svc.StopSessionWatch()
+	svc.StopSessionWatch()
+}
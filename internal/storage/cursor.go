@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+	// ErrCursorSort is returned when cursor pagination is requested with a sort
+	// other than start time
+	ErrCursorSort = errors.New("cursor pagination requires sorting by start time")
+)
+
+// sessionCursor is the position of a session in a start-time ordered listing.
+// The session ID breaks ties between sessions started at the same time.
+type sessionCursor struct {
+	StartTime time.Time `json:"ts"`
+	ID        string    `json:"id"`
+}
+
+// SessionCursor returns the opaque cursor that resumes a start-time ordered
+// listing after the given session
+func SessionCursor(meta *SessionMetadata) string {
+	data, _ := json.Marshal(sessionCursor{StartTime: meta.StartTime.UTC(), ID: meta.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSessionCursor parses a cursor returned by SessionCursor
+func decodeSessionCursor(token string) (*sessionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor sessionCursor
+	// No else needed: early return pattern (guard clause)
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" || cursor.StartTime.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// afterCursor returns the filter condition selecting the sessions that follow
+// the cursor position in a listing sorted by start time and ID with sortOrder
+// (1 ascending, -1 descending)
+func afterCursor(cursor *sessionCursor, sortOrder int) bson.A {
+	op := "$lt"
+	// No else needed: optional operation (only change if ascending)
+	if sortOrder == 1 {
+		op = "$gt"
+	}
+	return bson.A{
+		bson.M{constants.MongoFieldTimestamp: bson.M{op: cursor.StartTime}},
+		bson.M{
+			constants.MongoFieldTimestamp: cursor.StartTime,
+			constants.MongoFieldID:        bson.M{op: cursor.ID},
+		},
+	}
+}
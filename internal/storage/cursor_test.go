@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAllSessionsWithOptions_CursorPagination(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	// Sessions 0 and 1 share a start time, so the ID has to break the tie
+	base := time.Now().Truncate(time.Millisecond)
+	for i := 0; i < 7; i++ {
+		startTime := base.Add(time.Duration(-i) * time.Hour)
+		if i == 1 {
+			startTime = base
+		}
+		require.NoError(t, service.CreateSession(&session.Session{
+			ID:        fmt.Sprintf("session-%d", i),
+			UserID:    "user-1",
+			Messages:  []*session.Message{},
+			StartTime: startTime,
+		}))
+	}
+
+	for _, order := range []string{constants.SortOrderDesc, constants.SortOrderAsc} {
+		var seen []string
+		opts := &SessionListOptions{Limit: 3, SortOrder: order}
+		for page := 0; page < 5; page++ {
+			sessions, err := service.ListAllSessionsWithOptions(opts)
+			require.NoError(t, err)
+			for _, s := range sessions {
+				seen = append(seen, s.ID)
+			}
+			if len(sessions) < opts.Limit {
+				break
+			}
+			opts = &SessionListOptions{Limit: 3, SortOrder: order, After: SessionCursor(sessions[len(sessions)-1])}
+		}
+		assert.Len(t, seen, 7, "every session is listed exactly once (%s)", order)
+		assert.ElementsMatch(t, []string{"session-0", "session-1", "session-2", "session-3", "session-4", "session-5", "session-6"}, seen)
+	}
+}
+
+func TestListAllSessionsWithOptions_CursorErrors(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	_, err := service.ListAllSessionsWithOptions(&SessionListOptions{After: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	cursor := SessionCursor(&SessionMetadata{ID: "session-1", StartTime: time.Now()})
+	_, err = service.ListAllSessionsWithOptions(&SessionListOptions{After: cursor, SortBy: constants.SortByTotalTokens})
+	assert.ErrorIs(t, err, ErrCursorSort)
+}
+
+func TestDecodeSessionCursor(t *testing.T) {
+	startTime := time.Date(2026, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	cursor, err := decodeSessionCursor(SessionCursor(&SessionMetadata{ID: "session-1", StartTime: startTime}))
+	require.NoError(t, err)
+	assert.Equal(t, "session-1", cursor.ID)
+	assert.True(t, startTime.Equal(cursor.StartTime))
+
+	_, err = decodeSessionCursor("e30") // {}
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/experiment"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExperimentDocument stores the variant of an A/B experiment a session was assigned
+type ExperimentDocument struct {
+	ID      string `bson:"id" json:"id"`
+	Variant string `bson:"variant" json:"variant"`
+}
+
+// ExperimentMetrics are the metrics of the sessions assigned one variant of an experiment
+type ExperimentMetrics struct {
+	Variant string   `json:"variant"`
+	Metrics *Metrics `json:"metrics"`
+}
+
+// experimentsToDocuments converts a session's experiment variants for storage
+func experimentsToDocuments(variants []session.ExperimentVariant) []ExperimentDocument {
+	// No else needed: early return pattern (no experiments)
+	if len(variants) == 0 {
+		return nil
+	}
+	docs := make([]ExperimentDocument, len(variants))
+	for i, v := range variants {
+		docs[i] = ExperimentDocument{ID: v.ExperimentID, Variant: v.Variant}
+	}
+	return docs
+}
+
+// experimentsFromDocuments converts stored experiment variants back
+func experimentsFromDocuments(docs []ExperimentDocument) []session.ExperimentVariant {
+	// No else needed: early return pattern (no experiments)
+	if len(docs) == 0 {
+		return nil
+	}
+	variants := make([]session.ExperimentVariant, len(docs))
+	for i, doc := range docs {
+		variants[i] = session.ExperimentVariant{ExperimentID: doc.ID, Variant: doc.Variant}
+	}
+	return variants
+}
+
+// ensureExperimentIndexes creates the indexes for the chat_experiments collection
+func (s *StorageService) ensureExperimentIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: constants.MongoFieldTenantID, Value: 1}},
+			Options: options.Index().SetName(constants.IndexExperimentTenant),
+		},
+	}
+
+	_, err := s.experiments.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create experiment indexes: %w", err)
+	}
+	return nil
+}
+
+// TenantExperiments returns the stored experiments of tenantID
+func (s *StorageService) TenantExperiments(tenantID string) ([]*experiment.Experiment, error) {
+	return s.ForTenant(tenantID).ListExperiments()
+}
+
+// ListExperiments lists the stored experiments visible through this service,
+// most recently updated first
+func (s *StorageService) ListExperiments() ([]*experiment.Experiment, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_experiments"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	cursor, err := s.experiments.Find(ctx, s.tenantFilter(bson.M{}), gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldModified, Value: -1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiments: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	experiments := make([]*experiment.Experiment, 0)
+	for cursor.Next(ctx) {
+		var e experiment.Experiment
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&e); err != nil {
+			return nil, fmt.Errorf("failed to decode experiment: %w", err)
+		}
+		experiments = append(experiments, &e)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return experiments, nil
+}
+
+// GetExperiment returns a stored experiment. On a tenant view only experiments
+// of that tenant are visible; experiment.ErrExperimentNotFound is returned otherwise.
+func (s *StorageService) GetExperiment(id string) (*experiment.Experiment, error) {
+	// No else needed: early return pattern (guard clause)
+	if id == "" {
+		return nil, experiment.ErrExperimentNotFound
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_experiment"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var e experiment.Experiment
+	err := s.retryOperation(ctx, "GetExperiment", func() error {
+		return s.experiments.FindOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id})).Decode(&e)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, experiment.ErrExperimentNotFound
+		}
+		return nil, fmt.Errorf("failed to get experiment: %w", err)
+	}
+	return &e, nil
+}
+
+// CreateExperiment stores a new experiment. The ID and timestamps are
+// generated, and on a tenant view the experiment is owned by that tenant.
+func (s *StorageService) CreateExperiment(e *experiment.Experiment) error {
+	// No else needed: early return pattern (guard clause)
+	if e == nil {
+		return experiment.ErrInvalidExperiment
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "create_experiment"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	e.ID = primitive.NewObjectID().Hex()
+	e.CreatedAt = now
+	e.UpdatedAt = now
+	e.ReadOnly = false
+	// No else needed: optional operation (unscoped callers choose the tenant)
+	if s.tenantScoped {
+		e.TenantID = s.tenantID
+	}
+
+	err := s.retryOperation(ctx, "CreateExperiment", func() error {
+		_, err := s.experiments.InsertOne(ctx, e)
+		return err
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create experiment: %w", err)
+	}
+	return nil
+}
+
+// DeleteExperiment removes a stored experiment. Sessions already assigned one
+// of its variants keep it, so its metrics remain available.
+func (s *StorageService) DeleteExperiment(id string) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "delete_experiment"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var deleted int64
+	err := s.retryOperation(ctx, "DeleteExperiment", func() error {
+		result, opErr := s.experiments.DeleteOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id}))
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			deleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to delete experiment: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if deleted == 0 {
+		return experiment.ErrExperimentNotFound
+	}
+	return nil
+}
+
+// GetExperimentMetrics returns the metrics of the sessions visible to this
+// service that were assigned a variant of the experiment and started in
+// [startTime, endTime), per variant sorted by name. Variants without sessions
+// are not listed.
+func (s *StorageService) GetExperimentMetrics(experimentID string, startTime, endTime time.Time) ([]*ExperimentMetrics, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_experiment_metrics"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	totals, err := s.aggregateVariantTotals(ctx, experimentID, startTime, endTime)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ExperimentMetrics, 0, len(totals))
+	for variant, t := range totals {
+		result = append(result, &ExperimentMetrics{Variant: variant, Metrics: t.metrics()})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Variant < result[j].Variant })
+	return result, nil
+}
+
+// aggregateVariantTotals aggregates the metrics of the experiment's sessions per
+// variant. Unscoped services include the datastores of pinned tenants.
+func (s *StorageService) aggregateVariantTotals(ctx context.Context, experimentID string, startTime, endTime time.Time) (map[string]*metricsTotals, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: s.scope(bson.M{
+			constants.MongoFieldExperimentID: experimentID,
+			constants.MongoFieldTimestamp:    bson.M{"$gte": startTime, "$lt": endTime},
+		})}},
+		{{Key: "$unwind", Value: "$" + constants.MongoFieldExperiments}},
+		{{Key: "$match", Value: bson.M{constants.MongoFieldExperimentID: experimentID}}},
+		{{Key: "$group", Value: totalsGroup("$" + constants.MongoFieldExperiments + ".variant")}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate experiment metrics: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	totals := make(map[string]*metricsTotals)
+	for cursor.Next(ctx) {
+		var row struct {
+			Variant string        `bson:"_id"`
+			Totals  metricsTotals `bson:",inline"`
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode experiment metrics: %w", err)
+		}
+		t := row.Totals
+		totals[row.Variant] = &t
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	// No else needed: early return pattern (tenant views already read the tenant's datastore)
+	if s.tenantScoped {
+		return totals, nil
+	}
+	for _, view := range s.datastores {
+		viewTotals, err := view.aggregateVariantTotals(ctx, experimentID, startTime, endTime)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("datastore %s: %w", view.datastore, err)
+		}
+		for variant, t := range viewTotals {
+			// No else needed: conditional assignment (first datastore with the variant)
+			if totals[variant] == nil {
+				totals[variant] = &metricsTotals{}
+			}
+			totals[variant].add(t)
+		}
+	}
+	return totals, nil
+}
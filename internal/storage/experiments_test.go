@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/experiment"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExperiment() *experiment.Experiment {
+	return &experiment.Experiment{Name: "Models", Kind: experiment.KindModel, Active: true, Variants: []experiment.Variant{
+		{Name: "control", Value: "gpt-4", Weight: 1},
+		{Name: "claude", Value: "claude-3", Weight: 1},
+	}}
+}
+
+func TestExperimentDocuments(t *testing.T) {
+	variants := []session.ExperimentVariant{{ExperimentID: "models", Variant: "claude"}}
+	docs := experimentsToDocuments(variants)
+	assert.Equal(t, []ExperimentDocument{{ID: "models", Variant: "claude"}}, docs)
+	assert.Equal(t, variants, experimentsFromDocuments(docs))
+	assert.Nil(t, experimentsToDocuments(nil))
+	assert.Nil(t, experimentsFromDocuments(nil))
+
+	meta := buildSessionMetadata(&SessionDocument{Experiments: docs}, time.Now())
+	assert.Equal(t, map[string]string{"models": "claude"}, meta.Experiments)
+}
+
+func TestCreateExperiment_Invalid(t *testing.T) {
+	svc := &StorageService{}
+	assert.ErrorIs(t, svc.CreateExperiment(nil), experiment.ErrInvalidExperiment)
+	e := newTestExperiment()
+	e.Variants = e.Variants[:1]
+	assert.ErrorIs(t, svc.CreateExperiment(e), experiment.ErrInvalidExperiment)
+}
+
+func TestExperiments_CRUD(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.experiments = c })
+
+	acme := service.ForTenant("acme")
+	e := newTestExperiment()
+	e.TenantID = "other"
+	require.NoError(t, acme.CreateExperiment(e))
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, "acme", e.TenantID, "tenant views own the experiments they create")
+
+	got, err := acme.GetExperiment(e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, e.Variants, got.Variants)
+
+	// Other tenants cannot see or delete the experiment
+	other := service.ForTenant("")
+	_, err = other.GetExperiment(e.ID)
+	assert.ErrorIs(t, err, experiment.ErrExperimentNotFound)
+	assert.ErrorIs(t, other.DeleteExperiment(e.ID), experiment.ErrExperimentNotFound)
+	listed, err := service.TenantExperiments("")
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	listed, err = service.TenantExperiments("acme")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.True(t, listed[0].Active)
+
+	require.NoError(t, acme.DeleteExperiment(e.ID))
+	_, err = acme.GetExperiment(e.ID)
+	assert.ErrorIs(t, err, experiment.ErrExperimentNotFound)
+}
+
+func TestGetExperimentMetrics(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	create := func(id, tenantID, variant string, tokens int) {
+		sess := &session.Session{
+			ID:            id,
+			UserID:        "user-" + id,
+			TenantID:      tenantID,
+			Messages:      []*session.Message{},
+			StartTime:     now.Add(-time.Hour),
+			LastActivity:  now,
+			TotalTokens:   tokens,
+			ResponseTimes: []time.Duration{time.Second},
+		}
+		// No variant: the session is not part of the experiment
+		if variant != "" {
+			sess.Experiments = []session.ExperimentVariant{
+				{ExperimentID: "other", Variant: "x"},
+				{ExperimentID: "models", Variant: variant},
+			}
+		}
+		require.NoError(t, service.CreateSession(sess))
+	}
+	create("s1", "", "control", 100)
+	create("s2", "acme", "control", 50)
+	create("s3", "acme", "claude", 30)
+	create("s4", "acme", "", 999)
+
+	result, err := service.GetExperimentMetrics("models", now.Add(-2*time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "claude", result[0].Variant)
+	assert.Equal(t, 1, result[0].Metrics.TotalSessions)
+	assert.Equal(t, 30, result[0].Metrics.TotalTokens)
+	assert.Equal(t, "control", result[1].Variant)
+	assert.Equal(t, 2, result[1].Metrics.TotalSessions)
+	assert.Equal(t, 150, result[1].Metrics.TotalTokens)
+	assert.Equal(t, int64(1000), result[1].Metrics.AvgResponseTime)
+
+	// Tenant views only count their tenant's sessions
+	result, err = service.ForTenant("acme").GetExperimentMetrics("models", now.Add(-2*time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, 1, result[1].Metrics.TotalSessions)
+
+	result, err = service.GetExperimentMetrics("models", now.Add(-10*time.Minute), now)
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrMessageNotFound is returned when feedback targets a message that does not
+// exist or is not an AI reply, or a session that does not exist or belongs to someone else
+var ErrMessageNotFound = errors.New("AI message not found")
+
+// FeedbackDocument is a user's rating of an AI message, stored on the message
+type FeedbackDocument struct {
+	Rating    string    `bson:"rating" json:"rating"`
+	Comment   string    `bson:"comment,omitempty" json:"comment,omitempty"` // encrypted like message content
+	UpdatedAt time.Time `bson:"ts" json:"ts"`
+}
+
+// feedbackFromDocument converts a stored rating, decrypting its comment
+func (s *StorageService) feedbackFromDocument(doc *FeedbackDocument) *session.Feedback {
+	// No else needed: early return pattern (message without feedback)
+	if doc == nil {
+		return nil
+	}
+	comment := doc.Comment
+	// No else needed: optional operation (only decrypt if key is available)
+	if len(s.encryptionKey) > 0 && comment != "" {
+		// No else needed: optional operation (fallback to original on error)
+		if decrypted, err := s.decrypt(comment); err == nil {
+			comment = decrypted
+		}
+	}
+	return &session.Feedback{Rating: doc.Rating, Comment: comment, UpdatedAt: doc.UpdatedAt}
+}
+
+// SetMessageFeedback records feedback on the AI message at index of a session,
+// replacing any earlier feedback on it. The caller is responsible for checking
+// session ownership; use SetUserMessageFeedback to enforce it.
+func (s *StorageService) SetMessageFeedback(sessionID string, index int, feedback *session.Feedback) error {
+	return s.setMessageFeedback(s.scope(bson.M{constants.MongoFieldID: sessionID}), sessionID, index, feedback)
+}
+
+// SetUserMessageFeedback records feedback on the AI message at index of a session
+// owned by userID or shared with them for writing. Returns ErrMessageNotFound when the session does not exist,
+// belongs to someone else or has no AI message at index, and ErrAnonymizedMode
+// when messages are not stored.
+func (s *StorageService) SetUserMessageFeedback(sessionID, userID string, index int, feedback *session.Feedback) error {
+	return s.setMessageFeedback(s.accessibleBy(sessionID, userID, constants.GrantAccessWrite), sessionID, index, feedback)
+}
+
+// setMessageFeedback stores feedback on the AI message at index of the session matching filter
+func (s *StorageService) setMessageFeedback(filter bson.M, sessionID string, index int, feedback *session.Feedback) error {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return ErrAnonymizedMode
+	}
+	// No else needed: early return pattern (guard clause)
+	if index < 0 {
+		return ErrMessageNotFound
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := feedback.Validate(); err != nil {
+		return err
+	}
+	s.flushPending(sessionID)
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "set_message_feedback"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	doc := FeedbackDocument{Rating: feedback.Rating, Comment: feedback.Comment, UpdatedAt: feedback.UpdatedAt}
+	// No else needed: optional operation (only encrypt if key is available)
+	if len(s.encryptionKey) > 0 && doc.Comment != "" {
+		encrypted, err := s.encrypt(doc.Comment)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt feedback comment: %w", err)
+		}
+		doc.Comment = encrypted
+	}
+
+	field := fmt.Sprintf("%s.%d", constants.MongoFieldMessages, index)
+	filter[field+".sender"] = constants.SenderAI
+	update := bson.M{"$set": bson.M{field + ".feedback": doc}}
+
+	var matched int64
+	err := s.retryOperation(ctx, "SetMessageFeedback", func() error {
+		result, opErr := s.collection.UpdateOne(ctx, filter, update)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to set message feedback: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if matched == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUserMessageFeedback(t *testing.T) {
+	service, cleanup := setupTestStorage(t, []byte("12345678901234567890123456789012"))
+	defer cleanup()
+
+	now := time.Now()
+	createSessionWithActivity(t, service, "session-1", now)
+	require.NoError(t, service.AddMessage("session-1", &session.Message{Content: "Hi", Timestamp: now, Sender: constants.SenderUser}))
+	require.NoError(t, service.AddMessage("session-1", &session.Message{Content: "Hello!", Timestamp: now, Sender: constants.SenderAI}))
+
+	feedback := &session.Feedback{Rating: constants.FeedbackDown, Comment: "Too short", UpdatedAt: now}
+	require.NoError(t, service.SetUserMessageFeedback("session-1", "user-1", 1, feedback))
+
+	sess, err := service.GetSession("session-1")
+	require.NoError(t, err)
+	require.NotNil(t, sess.Messages[1].Feedback)
+	assert.Equal(t, constants.FeedbackDown, sess.Messages[1].Feedback.Rating)
+	assert.Equal(t, "Too short", sess.Messages[1].Feedback.Comment, "comments are decrypted")
+	assert.Nil(t, sess.Messages[0].Feedback)
+
+	// Later feedback replaces earlier feedback
+	require.NoError(t, service.SetUserMessageFeedback("session-1", "user-1", 1, &session.Feedback{Rating: constants.FeedbackUp, UpdatedAt: now}))
+	sess, err = service.GetSession("session-1")
+	require.NoError(t, err)
+	assert.Equal(t, constants.FeedbackUp, sess.Messages[1].Feedback.Rating)
+	assert.Empty(t, sess.Messages[1].Feedback.Comment)
+
+	// Only AI messages of the owner's sessions can be rated
+	assert.ErrorIs(t, service.SetUserMessageFeedback("session-1", "user-1", 0, feedback), ErrMessageNotFound)
+	assert.ErrorIs(t, service.SetUserMessageFeedback("session-1", "user-1", 2, feedback), ErrMessageNotFound)
+	assert.ErrorIs(t, service.SetUserMessageFeedback("session-1", "user-1", -1, feedback), ErrMessageNotFound)
+	assert.ErrorIs(t, service.SetUserMessageFeedback("session-1", "user-2", 1, feedback), ErrMessageNotFound)
+	assert.ErrorIs(t, service.ForTenant("tenant-b").SetUserMessageFeedback("session-1", "user-1", 1, feedback), ErrMessageNotFound)
+	assert.ErrorIs(t, service.SetUserMessageFeedback("", "user-1", 1, feedback), ErrInvalidSessionID)
+	assert.ErrorIs(t, service.SetUserMessageFeedback("session-1", "user-1", 1, &session.Feedback{Rating: "meh"}), session.ErrInvalidFeedback)
+
+	service.anonymized = true
+	assert.ErrorIs(t, service.SetMessageFeedback("session-1", 1, feedback), ErrAnonymizedMode)
+}
+
+func TestGetSessionMetrics_Feedback(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	for _, id := range []string{"session-1", "session-2"} {
+		createSessionWithActivity(t, service, id, now)
+		for i := 0; i < 2; i++ {
+			require.NoError(t, service.AddMessage(id, &session.Message{Content: "Answer", Timestamp: now, Sender: constants.SenderAI}))
+		}
+	}
+	require.NoError(t, service.SetMessageFeedback("session-1", 0, &session.Feedback{Rating: constants.FeedbackUp, UpdatedAt: now}))
+	require.NoError(t, service.SetMessageFeedback("session-1", 1, &session.Feedback{Rating: constants.FeedbackUp, UpdatedAt: now}))
+	require.NoError(t, service.SetMessageFeedback("session-2", 1, &session.Feedback{Rating: constants.FeedbackDown, UpdatedAt: now}))
+
+	m, err := service.GetSessionMetrics(now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, m.FeedbackUp)
+	assert.Equal(t, 1, m.FeedbackDown)
+	assert.InDelta(t, 2.0/3.0, m.FeedbackPositiveRate, 0.0001)
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FinetuneOptions selects the sessions of a fine-tuning dataset
+type FinetuneOptions struct {
+	Tags             []string   // Sessions having all of these tags
+	StartTimeFrom    *time.Time // Sessions starting at or after this time
+	StartTimeTo      *time.Time // Sessions starting before this time
+	MinFeedbackScore *int       // Sessions whose AI messages' up minus down ratings reach this; nil for all
+	Limit            int        // Maximum sessions (default and cap: constants.MaxFinetuneSessions)
+}
+
+// FinetuneSessions returns the stored sessions selected for a fine-tuning
+// dataset with their messages, oldest first. Soft-deleted sessions are never
+// included. Returns ErrAnonymizedMode when messages are not stored.
+func (s *StorageService) FinetuneSessions(opts FinetuneOptions) ([]*session.Session, error) {
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+	limit := opts.Limit
+	// No else needed: optional operation (apply default and cap)
+	if limit <= 0 || limit > constants.MaxFinetuneSessions {
+		limit = constants.MaxFinetuneSessions
+	}
+	// No else needed: optional operation (write buffered messages first)
+	if s.batcher != nil {
+		s.flushMessages()
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "finetune_sessions"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+	defer cancel()
+
+	// Sessions of anonymized tenants have no messages to learn from
+	filter := s.withoutAnonymizedTenants(bson.M{})
+	// No else needed: optional operation (filter by tags)
+	if len(opts.Tags) > 0 {
+		filter[constants.MongoFieldTags] = bson.M{"$all": opts.Tags}
+	}
+	startRange := bson.M{}
+	// No else needed: optional operation (lower bound)
+	if opts.StartTimeFrom != nil {
+		startRange["$gte"] = *opts.StartTimeFrom
+	}
+	// No else needed: optional operation (upper bound)
+	if opts.StartTimeTo != nil {
+		startRange["$lt"] = *opts.StartTimeTo
+	}
+	// No else needed: optional operation (filter by start time)
+	if len(startRange) > 0 {
+		filter[constants.MongoFieldTimestamp] = startRange
+	}
+
+	queryOpts := gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldTimestamp, Value: 1}},
+	}
+	// The feedback score is computed from the decoded messages, so the limit
+	// applies after it rather than in the query
+	// No else needed: optional operation (limit in the query when every match is kept)
+	if opts.MinFeedbackScore == nil {
+		queryOpts.Limit = int64(limit)
+	}
+	cursor, err := s.collection.Find(ctx, s.scope(filter), queryOpts)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions for fine-tuning: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := make([]*session.Session, 0)
+	for len(sessions) < limit && cursor.Next(ctx) {
+		var doc SessionDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode session document: %w", err)
+		}
+		sess := s.documentToSession(&doc)
+		// No else needed: optional operation (skip sessions rated below the minimum)
+		if opts.MinFeedbackScore != nil && FeedbackScore(sess) < *opts.MinFeedbackScore {
+			continue
+		}
+		sessions = append(sessions, sess)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return sessions, nil
+}
+
+// FeedbackScore returns the number of AI messages of the session rated up
+// minus the number rated down
+func FeedbackScore(sess *session.Session) int {
+	sess.RLock()
+	defer sess.RUnlock()
+
+	score := 0
+	for _, msg := range sess.Messages {
+		// No else needed: optional operation (unrated messages do not count)
+		if msg.Feedback == nil {
+			continue
+		}
+		switch msg.Feedback.Rating {
+		case constants.FeedbackUp:
+			score++
+		case constants.FeedbackDown:
+			score--
+		}
+	}
+	return score
+}
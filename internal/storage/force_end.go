@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RecordEndReason records why an admin force-ended a session and who did, for
+// the session list and the end reason counts of the session metrics. The
+// session is ended separately with EndSession. On a tenant view only the
+// tenant's sessions can be updated.
+func (s *StorageService) RecordEndReason(sessionID, reason, adminID string) error {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "record_end_reason"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var matched int64
+	err := s.retryOperation(ctx, "RecordEndReason", func() error {
+		result, opErr := s.collection.UpdateOne(ctx,
+			s.scope(bson.M{constants.MongoFieldID: sessionID}),
+			bson.M{"$set": bson.M{
+				constants.MongoFieldEndReason: reason,
+				constants.MongoFieldEndedBy:   adminID,
+			}})
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to record session end reason: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if matched == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordEndReason(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	createSessionWithActivity(t, service, "session-1", time.Now())
+	require.NoError(t, service.EndSession("session-1", time.Now()))
+	require.NoError(t, service.RecordEndReason("session-1", constants.EndReasonStale, "admin-1"))
+
+	sessions, err := service.ListUserSessions("user-1", 10)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, constants.EndReasonStale, sessions[0].EndReason)
+	assert.Equal(t, "admin-1", sessions[0].EndedBy)
+	assert.False(t, sessions[0].IsActive)
+
+	// Other tenants' sessions look the same as missing ones
+	assert.ErrorIs(t, service.ForTenant("tenant-b").RecordEndReason("session-1", constants.EndReasonAbuse, "admin-2"), ErrSessionNotFound)
+	assert.ErrorIs(t, service.RecordEndReason("missing", constants.EndReasonAbuse, "admin-1"), ErrSessionNotFound)
+	assert.ErrorIs(t, service.RecordEndReason("", constants.EndReasonAbuse, "admin-1"), ErrInvalidSessionID)
+}
@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrInvalidGrant is returned for grants to no user or to the owner, and
+	// for access levels other than constants.GrantAccessRead and GrantAccessWrite
+	ErrInvalidGrant = errors.New("invalid grant")
+	// ErrTooManyGrants is returned when sharing would exceed constants.MaxSessionGrants
+	ErrTooManyGrants = errors.New("too many grants")
+)
+
+// SessionGrant gives a user of the owner's tenant access to one of the owner's
+// sessions. Grants are stored only in MongoDB, so session updates from memory
+// never overwrite them.
+type SessionGrant struct {
+	UserID    string    `bson:"uid" json:"user_id"`
+	Access    string    `bson:"acc" json:"access"` // constants.GrantAccessRead or constants.GrantAccessWrite
+	GrantedAt time.Time `bson:"ts" json:"granted_at"`
+}
+
+// SharedSessionMetadata is a session shared with the caller, with the access
+// they were granted
+type SharedSessionMetadata struct {
+	*SessionMetadata
+	Access string `json:"access"`
+}
+
+// accessibleBy restricts a filter to a visible session owned by userID or
+// shared with them with at least access. Write grants include read access.
+func (s *StorageService) accessibleBy(sessionID, userID, access string) bson.M {
+	storedUserID := s.StoredUserID(userID)
+	grant := bson.M{"uid": storedUserID}
+	// No else needed: optional operation (read access is granted by any grant)
+	if access == constants.GrantAccessWrite {
+		grant["acc"] = constants.GrantAccessWrite
+	}
+	return s.scope(bson.M{
+		constants.MongoFieldID: sessionID,
+		"$or": bson.A{
+			bson.M{constants.MongoFieldUserID: storedUserID},
+			bson.M{constants.MongoFieldGrants: bson.M{"$elemMatch": grant}},
+		},
+	})
+}
+
+// GetUserSession retrieves a session owned by userID or shared with them.
+// Returns ErrSessionNotFound when the session does not exist or the user has
+// no access to it.
+func (s *StorageService) GetUserSession(sessionID, userID string) (*session.Session, error) {
+	return s.getSession(sessionID, s.accessibleBy(sessionID, userID, constants.GrantAccessRead))
+}
+
+// ListSessionGrants returns the users a session owned by ownerID is shared
+// with, in grant order
+func (s *StorageService) ListSessionGrants(sessionID, ownerID string) ([]SessionGrant, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var doc SessionDocument
+	err := s.retryOperation(ctx, "ListSessionGrants", func() error {
+		return s.collection.FindOne(ctx, s.ownedBy(sessionID, ownerID), options.FindOne().SetProjection(bson.M{
+			constants.MongoFieldGrants: 1,
+		})).Decode(&doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session grants: %w", err)
+	}
+	return grantsOrEmpty(doc.Grants), nil
+}
+
+// GrantUserSessionAccess shares a session owned by ownerID with granteeID,
+// replacing the access of an earlier grant to them, and returns the session's
+// grants. Returns ErrSessionNotFound when the session does not exist or belongs
+// to someone else, and ErrAnonymizedMode when messages are not stored.
+func (s *StorageService) GrantUserSessionAccess(sessionID, ownerID, granteeID, access string) ([]SessionGrant, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+	// Nothing can be read from sessions without stored messages
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+	// No else needed: early return pattern (guard clause)
+	if access != constants.GrantAccessRead && access != constants.GrantAccessWrite {
+		return nil, fmt.Errorf("%w: access must be %s or %s", ErrInvalidGrant, constants.GrantAccessRead, constants.GrantAccessWrite)
+	}
+	// No else needed: early return pattern (guard clause)
+	if granteeID == "" || len(granteeID) > constants.MaxGrantUserIDLength {
+		return nil, fmt.Errorf("%w: user ID must be 1-%d bytes", ErrInvalidGrant, constants.MaxGrantUserIDLength)
+	}
+	// No else needed: early return pattern (guard clause)
+	if granteeID == ownerID {
+		return nil, fmt.Errorf("%w: sessions cannot be shared with their owner", ErrInvalidGrant)
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "grant_session_access"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	owned := s.ownedBy(sessionID, ownerID)
+	grantee := s.StoredUserID(granteeID)
+	now := time.Now().UTC().Truncate(time.Millisecond) // MongoDB stores milliseconds
+
+	// Change the access of an existing grant
+	granted := bson.M{constants.MongoFieldGrantUserID: grantee}
+	for k, v := range owned {
+		granted[k] = v
+	}
+	update := bson.M{"$set": bson.M{
+		constants.MongoFieldGrants + ".$.acc": access,
+		constants.MongoFieldGrants + ".$.ts":  now,
+	}}
+	grants, err := s.updateGrants(ctx, "GrantSessionAccess", granted, update)
+	// No else needed: early return pattern (guard clause)
+	if !errors.Is(err, ErrSessionNotFound) {
+		return grants, err
+	}
+
+	// Add a grant. The duplicate and limit checks are part of the filter so
+	// concurrent grants cannot add a user twice or exceed the limit.
+	lastGrant := fmt.Sprintf("%s.%d", constants.MongoFieldGrants, constants.MaxSessionGrants-1)
+	grantable := bson.M{
+		constants.MongoFieldGrantUserID: bson.M{"$ne": grantee},
+		lastGrant:                       bson.M{"$exists": false},
+	}
+	for k, v := range owned {
+		grantable[k] = v
+	}
+	grant := SessionGrant{UserID: grantee, Access: access, GrantedAt: now}
+	update = bson.M{"$push": bson.M{constants.MongoFieldGrants: grant}}
+	grants, err = s.updateGrants(ctx, "GrantSessionAccess", grantable, update)
+	// No else needed: early return pattern (guard clause)
+	if !errors.Is(err, ErrSessionNotFound) {
+		return grants, err
+	}
+
+	// Nothing matched: tell a missing session from one with too many grants
+	grants, err = s.ListSessionGrants(sessionID, ownerID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range grants {
+		// No else needed: early return pattern (granted concurrently)
+		if existing.UserID == grantee {
+			return grants, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: at most %d per session", ErrTooManyGrants, constants.MaxSessionGrants)
+}
+
+// RevokeUserSessionAccess removes the grant of granteeID to a session owned by
+// ownerID and returns the session's remaining grants. Revoking access that was
+// never granted changes nothing.
+func (s *StorageService) RevokeUserSessionAccess(sessionID, ownerID, granteeID string) ([]SessionGrant, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "revoke_session_access"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	update := bson.M{"$pull": bson.M{constants.MongoFieldGrants: bson.M{"uid": s.StoredUserID(granteeID)}}}
+	return s.updateGrants(ctx, "RevokeSessionAccess", s.ownedBy(sessionID, ownerID), update)
+}
+
+// updateGrants applies a grant update to the session matching filter and
+// returns the session's grants after the update
+func (s *StorageService) updateGrants(ctx context.Context, operation string, filter, update bson.M) ([]SessionGrant, error) {
+	findOpts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{constants.MongoFieldGrants: 1})
+
+	var doc SessionDocument
+	err := s.retryOperation(ctx, operation, func() error {
+		return s.collection.FindOneAndUpdate(ctx, filter, update, findOpts).Decode(&doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session grants: %w", err)
+	}
+	return grantsOrEmpty(doc.Grants), nil
+}
+
+// grantsOrEmpty returns grants, or an empty list for sessions shared with no one
+func grantsOrEmpty(grants []SessionGrant) []SessionGrant {
+	// No else needed: early return pattern (sessions without grants)
+	if grants == nil {
+		return []SessionGrant{}
+	}
+	return grants
+}
+
+// ListSharedSessions returns the sessions other users of the storage view's
+// tenant shared with userID, most recent first. Share links and the other
+// grants of a session are the owner's and are not included.
+func (s *StorageService) ListSharedSessions(userID string, limit int) ([]*SharedSessionMetadata, error) {
+	// No else needed: early return pattern (guard clause)
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+	// No else needed: conditional assignment (default to a safe limit)
+	if limit <= 0 {
+		limit = constants.DefaultSessionLimit
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	grantee := s.StoredUserID(userID)
+	filter := s.scope(bson.M{constants.MongoFieldGrantUserID: grantee})
+	cursor, err := s.collection.Find(ctx, filter, gomongo.QueryOptions{
+		Sort:  bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+		Limit: int64(limit),
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := make([]*SharedSessionMetadata, 0)
+	for cursor.Next(ctx) {
+		var doc SessionDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode session document: %w", err)
+		}
+
+		lastMessageTime := doc.StartTime
+		// No else needed: conditional assignment (sessions with messages)
+		if len(doc.Messages) > 0 {
+			lastMessageTime = doc.Messages[len(doc.Messages)-1].Timestamp
+		}
+		shared := &SharedSessionMetadata{SessionMetadata: buildSessionMetadata(&doc, lastMessageTime)}
+		shared.ShareToken = ""
+		shared.ShareExpiresAt = nil
+		shared.Grants = nil
+		for _, grant := range doc.Grants {
+			// No else needed: optional operation (the caller's grant)
+			if grant.UserID == grantee {
+				shared.Access = grant.Access
+			}
+		}
+		sessions = append(sessions, shared)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionGrants(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:        "session-1",
+		UserID:    "owner",
+		StartTime: now,
+		IsActive:  true,
+		Messages:  []*session.Message{{Content: "hello", Timestamp: now, Sender: "user"}},
+	}))
+
+	// Before sharing, only the owner can read and write the session
+	_, err := service.GetUserSession("session-1", "colleague")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.ErrorIs(t, service.RenameUserSession("session-1", "colleague", "Mine"), ErrSessionNotFound)
+
+	grants, err := service.GrantUserSessionAccess("session-1", "owner", "colleague", constants.GrantAccessRead)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	assert.Equal(t, "colleague", grants[0].UserID)
+	assert.Equal(t, constants.GrantAccessRead, grants[0].Access)
+
+	// Read grants allow reading only
+	sess, err := service.GetUserSession("session-1", "colleague")
+	require.NoError(t, err)
+	assert.Equal(t, "owner", sess.UserID)
+	assert.ErrorIs(t, service.RenameUserSession("session-1", "colleague", "Mine"), ErrSessionNotFound)
+	_, err = service.AddUserSessionTags("session-1", "colleague", []string{"billing"})
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// Granting again changes the access of the grant
+	grants, err = service.GrantUserSessionAccess("session-1", "owner", "colleague", constants.GrantAccessWrite)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	assert.Equal(t, constants.GrantAccessWrite, grants[0].Access)
+	require.NoError(t, service.RenameUserSession("session-1", "colleague", "Shared"))
+	_, err = service.AddUserSessionTags("session-1", "colleague", []string{"billing"})
+	require.NoError(t, err)
+
+	// Updating the session from memory keeps the grants
+	sess.Name = "Shared"
+	require.NoError(t, service.UpdateSession(sess))
+	grants, err = service.ListSessionGrants("session-1", "owner")
+	require.NoError(t, err)
+	assert.Len(t, grants, 1)
+
+	// Only the owner manages grants
+	_, err = service.GrantUserSessionAccess("session-1", "colleague", "someone", constants.GrantAccessRead)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.ListSessionGrants("session-1", "colleague")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.GrantUserSessionAccess("session-1", "owner", "owner", constants.GrantAccessRead)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+	_, err = service.GrantUserSessionAccess("session-1", "owner", "someone", "admin")
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	// Shared sessions are listed for the grantee without the owner's share settings
+	shared, err := service.ListSharedSessions("colleague", 0)
+	require.NoError(t, err)
+	require.Len(t, shared, 1)
+	assert.Equal(t, "session-1", shared[0].ID)
+	assert.Equal(t, constants.GrantAccessWrite, shared[0].Access)
+	assert.Nil(t, shared[0].Grants)
+	owned, err := service.ListUserSessions("owner", 0)
+	require.NoError(t, err)
+	require.Len(t, owned, 1)
+	assert.Len(t, owned[0].Grants, 1, "owners see who their sessions are shared with")
+
+	grants, err = service.RevokeUserSessionAccess("session-1", "owner", "colleague")
+	require.NoError(t, err)
+	assert.Empty(t, grants)
+	_, err = service.GetUserSession("session-1", "colleague")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	shared, err = service.ListSharedSessions("colleague", 0)
+	require.NoError(t, err)
+	assert.Empty(t, shared)
+}
+
+func TestSessionGrants_Limit(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	require.NoError(t, service.CreateSession(&session.Session{ID: "session-1", UserID: "owner", StartTime: time.Now(), IsActive: true}))
+	for i := 0; i < constants.MaxSessionGrants; i++ {
+		_, err := service.GrantUserSessionAccess("session-1", "owner", fmt.Sprintf("user-%d", i), constants.GrantAccessRead)
+		require.NoError(t, err)
+	}
+	_, err := service.GrantUserSessionAccess("session-1", "owner", "one-too-many", constants.GrantAccessRead)
+	assert.ErrorIs(t, err, ErrTooManyGrants)
+
+	// Existing grants can still be changed at the limit
+	grants, err := service.GrantUserSessionAccess("session-1", "owner", "user-0", constants.GrantAccessWrite)
+	require.NoError(t, err)
+	assert.Len(t, grants, constants.MaxSessionGrants)
+
+	_, err = service.GrantUserSessionAccess("missing", "owner", "user-0", constants.GrantAccessRead)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// messageCipherV1 is the version byte of message ciphertexts bound to their
+// position: base64(version || nonce || AES-GCM(content)) with the session ID and
+// message index as additional authenticated data. Legacy ciphertexts,
+// base64(nonce || AES-GCM(content)) without AAD, remain readable but can be
+// swapped between messages and sessions unnoticed.
+const messageCipherV1 byte = 0x01
+
+// errMessageConflict is returned when a sealed message could not be appended
+// because other messages kept being added to the session concurrently
+var errMessageConflict = errors.New("concurrent message appends")
+
+// messageAAD returns the additional authenticated data binding a message
+// ciphertext to the message at index of sessionID
+func messageAAD(sessionID string, index int) []byte {
+	return []byte(sessionID + "\x00" + strconv.Itoa(index))
+}
+
+// sealMessage encrypts the content of the message at index of sessionID.
+// Returns content unchanged when encryption is disabled.
+func (s *StorageService) sealMessage(sessionID string, index int, content string) (string, error) {
+	gcm, err := s.getGCM()
+	if err != nil {
+		return "", err
+	}
+	if gcm == nil {
+		return content, nil
+	}
+
+	// Version byte, then nonce, then the sealed content
+	header := make([]byte, 1+gcm.NonceSize(), 1+gcm.NonceSize()+len(content)+gcm.Overhead())
+	header[0] = messageCipherV1
+	nonce := header[1:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	ciphertext := gcm.Seal(header, nonce, []byte(content), messageAAD(sessionID, index))
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// openMessage decrypts the content of the message at index of sessionID.
+// Legacy ciphertexts are decrypted without binding, and content that is not a
+// ciphertext (stored before encryption was enabled) is returned as is.
+// tampered is true when content is a versioned ciphertext that does not
+// authenticate at this position: it was modified, or moved from another
+// message or session.
+func (s *StorageService) openMessage(sessionID string, index int, content string) (plaintext string, tampered bool) {
+	gcm, err := s.getGCM()
+	// No else needed: early return pattern (encryption disabled)
+	if err != nil || gcm == nil {
+		return content, false
+	}
+
+	data, err := base64.StdEncoding.DecodeString(content)
+	// No else needed: early return pattern (not a ciphertext)
+	if err != nil {
+		return content, false
+	}
+
+	nonceSize := gcm.NonceSize()
+	versioned := len(data) > nonceSize && data[0] == messageCipherV1
+	// No else needed: optional operation (only versioned ciphertexts are bound)
+	if versioned {
+		nonce := data[1 : 1+nonceSize]
+		opened, err := gcm.Open(nil, nonce, data[1+nonceSize:], messageAAD(sessionID, index))
+		// No else needed: early return pattern (authenticated at this position)
+		if err == nil {
+			return string(opened), false
+		}
+	}
+
+	// A legacy ciphertext whose nonce happens to start with the version byte
+	decrypted, err := s.decrypt(content)
+	// No else needed: early return pattern (legacy ciphertext)
+	if err == nil {
+		return decrypted, false
+	}
+	// No else needed: early return pattern (fails every format)
+	if versioned {
+		return "", true
+	}
+	return content, false
+}
+
+// tamperedMessages returns the indexes of the messages of sess flagged by
+// documentToSession as failing the integrity check
+func tamperedMessages(sess *session.Session) []int {
+	var indexes []int
+	for i, msg := range sess.Messages {
+		// No else needed: optional operation (only flagged messages)
+		if msg.Metadata[constants.MetadataKeyIntegrity] == constants.MessageIntegrityTampered {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// appendSealed seals content for the next position of sessionID and appends it.
+// count returns the session's current number of messages and push appends the
+// sealed content only if the session still has index messages, returning
+// ErrSessionNotFound otherwise. Concurrent appends are retried at the new
+// position, up to constants.MessageSealAttempts times.
+func (s *StorageService) appendSealed(sessionID, content string, count func() (int, error), push func(index int, sealed string) error) error {
+	return s.appendSealedAll(sessionID, []string{content}, count,
+		func(index int, sealed []string) error { return push(index, sealed[0]) })
+}
+
+// appendSealedAll is appendSealed for several messages appended at once:
+// contents[i] is sealed for position index+i.
+func (s *StorageService) appendSealedAll(sessionID string, contents []string, count func() (int, error), push func(index int, sealed []string) error) error {
+	for attempt := 0; attempt < constants.MessageSealAttempts; attempt++ {
+		index, err := count()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return err
+		}
+		sealed := make([]string, len(contents))
+		for i, content := range contents {
+			sealed[i], err = s.sealMessage(sessionID, index+i, content)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt message content: %w", err)
+			}
+		}
+		err = push(index, sealed)
+		// No else needed: early return pattern (appended, or failed for another reason)
+		if !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to add message: %w", errMessageConflict)
+}
+
+// countMessages returns the number of messages stored for the session matching filter
+func (s *StorageService) countMessages(ctx context.Context, filter bson.M) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$project", Value: bson.M{"n": bson.M{"$size": bson.M{"$ifNull": bson.A{"$" + constants.MongoFieldMessages, bson.A{}}}}}}},
+	}
+
+	var result struct {
+		N int `bson:"n"`
+	}
+	found := false
+	err := s.retryOperation(ctx, "AddMessage.count", func() error {
+		cursor, err := s.collection.Aggregate(ctx, pipeline)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		found = cursor.Next(ctx)
+		// No else needed: optional operation (no result means no session)
+		if found {
+			// No else needed: early return pattern (guard clause)
+			if err := cursor.Decode(&result); err != nil {
+				return err
+			}
+		}
+		return cursor.Err()
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if !found {
+		return 0, ErrSessionNotFound
+	}
+	return result.N, nil
+}
+
+// withMessageCount returns a copy of filter that only matches while the
+// session has n messages
+func withMessageCount(filter bson.M, n int) bson.M {
+	guarded := bson.M{}
+	for k, v := range filter {
+		guarded[k] = v
+	}
+	// No else needed: conditional assignment (new sessions may have no messages field)
+	if n == 0 {
+		guarded["$or"] = bson.A{
+			bson.M{constants.MongoFieldMessages: bson.M{"$size": 0}},
+			bson.M{constants.MongoFieldMessages: nil},
+		}
+		return guarded
+	}
+	guarded[constants.MongoFieldMessages] = bson.M{"$size": n}
+	return guarded
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newIntegrityService() *StorageService {
+	return &StorageService{encryptionKey: []byte("12345678901234567890123456789012")}
+}
+
+func TestSealMessage_RoundTrip(t *testing.T) {
+	service := newIntegrityService()
+
+	sealed, err := service.sealMessage("session-1", 3, "Hello")
+	require.NoError(t, err)
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, messageCipherV1, data[0], "ciphertexts start with the version byte")
+
+	content, tampered := service.openMessage("session-1", 3, sealed)
+	assert.False(t, tampered)
+	assert.Equal(t, "Hello", content)
+
+	_, tampered = service.openMessage("session-1", 4, sealed)
+	assert.True(t, tampered, "moved to another index")
+	_, tampered = service.openMessage("session-2", 3, sealed)
+	assert.True(t, tampered, "moved to another session")
+
+	data[len(data)-1] ^= 0xff
+	_, tampered = service.openMessage("session-1", 3, base64.StdEncoding.EncodeToString(data))
+	assert.True(t, tampered, "modified ciphertext")
+}
+
+func TestOpenMessage_LegacyAndPlaintext(t *testing.T) {
+	service := newIntegrityService()
+
+	legacy, err := service.encrypt("Legacy message")
+	require.NoError(t, err)
+	content, tampered := service.openMessage("session-1", 0, legacy)
+	assert.False(t, tampered)
+	assert.Equal(t, "Legacy message", content, "ciphertexts written before binding stay readable")
+
+	content, tampered = service.openMessage("session-1", 0, "stored before encryption was enabled")
+	assert.False(t, tampered)
+	assert.Equal(t, "stored before encryption was enabled", content)
+
+	// Without a key content is never opened
+	content, tampered = (&StorageService{}).openMessage("session-1", 0, legacy)
+	assert.False(t, tampered)
+	assert.Equal(t, legacy, content)
+}
+
+func TestDocumentToSession_FlagsSwappedMessages(t *testing.T) {
+	service := newIntegrityService()
+
+	// Sealed for index 1, the first message is stored at index 1 and the
+	// second, swapped, at index 0
+	first, err := service.sealMessage("session-1", 1, "First")
+	require.NoError(t, err)
+	second, err := service.sealMessage("session-1", 1, "Second")
+	require.NoError(t, err)
+	other, err := service.sealMessage("session-2", 0, "Other session")
+	require.NoError(t, err)
+
+	now := time.Now()
+	doc := &SessionDocument{
+		ID:        "session-1",
+		UserID:    "user-1",
+		StartTime: now,
+		Messages: []MessageDocument{
+			{Content: second, Timestamp: now, Sender: "user", Metadata: map[string]string{"k": "v"}},
+			{Content: first, Timestamp: now, Sender: "ai"},
+			{Content: other, Timestamp: now, Sender: "user"},
+		},
+	}
+
+	sess := service.documentToSession(doc)
+	require.Len(t, sess.Messages, 3)
+
+	assert.Empty(t, sess.Messages[0].Content)
+	assert.Equal(t, constants.MessageIntegrityTampered, sess.Messages[0].Metadata[constants.MetadataKeyIntegrity])
+	assert.Equal(t, "v", sess.Messages[0].Metadata["k"], "existing metadata is kept")
+	assert.NotContains(t, doc.Messages[0].Metadata, constants.MetadataKeyIntegrity, "the document is not modified")
+
+	assert.Equal(t, "First", sess.Messages[1].Content)
+	assert.NotContains(t, sess.Messages[1].Metadata, constants.MetadataKeyIntegrity)
+
+	assert.Equal(t, []int{0, 2}, tamperedMessages(sess))
+}
+
+func TestAppendSealed_RetriesConcurrentAppends(t *testing.T) {
+	service := newIntegrityService()
+
+	count := 2
+	var pushed []int
+	err := service.appendSealed("session-1", "Hello",
+		func() (int, error) { return count, nil },
+		func(index int, sealed string) error {
+			pushed = append(pushed, index)
+			// The first push loses the race with another append
+			if len(pushed) == 1 {
+				count++
+				return ErrSessionNotFound
+			}
+			content, tampered := service.openMessage("session-1", index, sealed)
+			assert.False(t, tampered)
+			assert.Equal(t, "Hello", content)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, pushed)
+
+	err = service.appendSealed("session-1", "Hello",
+		func() (int, error) { return 0, nil },
+		func(int, string) error { return ErrSessionNotFound })
+	assert.ErrorIs(t, err, errMessageConflict)
+
+	err = service.appendSealed("missing", "Hello",
+		func() (int, error) { return 0, ErrSessionNotFound },
+		func(int, string) error { return errors.New("not reached") })
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestWithMessageCount(t *testing.T) {
+	filter := bson.M{constants.MongoFieldID: "session-1"}
+
+	guarded := withMessageCount(filter, 2)
+	assert.Equal(t, bson.M{"$size": 2}, guarded[constants.MongoFieldMessages])
+	assert.NotContains(t, filter, constants.MongoFieldMessages, "the filter is copied")
+
+	guarded = withMessageCount(filter, 0)
+	assert.Contains(t, guarded, "$or", "sessions without a messages field match too")
+}
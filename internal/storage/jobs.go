@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/jobs"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ensureJobIndexes creates the indexes for the job_runs collection.
+// Runs older than constants.JobRunRetention are removed by MongoDB's TTL monitor.
+func (s *StorageService) ensureJobIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "job", Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexJobRuns),
+		},
+		{
+			Keys:    bson.D{{Key: constants.MongoFieldTimestamp, Value: 1}},
+			Options: options.Index().SetName(constants.IndexJobRunExpiry).SetExpireAfterSeconds(int32(constants.JobRunRetention.Seconds())),
+		},
+	}
+
+	_, err := s.jobRuns.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create job run indexes: %w", err)
+	}
+	return nil
+}
+
+// AcquireJobLock claims the run of job for slot on behalf of owner, until the
+// given time at the latest. It fails, returning false, when any replica already
+// claimed this slot or a later one, or the previous run still holds the lock.
+// Replicas computing the same slots from the same schedule thus run each slot
+// once, even with slightly skewed clocks. The lock of a job is the job_locks
+// document {_id: job, owner, slot, until}.
+func (s *StorageService) AcquireJobLock(job, owner string, slot, until time.Time) (bool, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "acquire_job_lock"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		constants.MongoFieldID: job,
+		"slot":                 bson.M{"$lt": slot},
+		"until":                bson.M{"$lt": time.Now()},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "slot": slot, "until": until}}
+	acquired := false
+	err := s.retryOperation(ctx, "AcquireJobLock", func() error {
+		// Without a matching lock the upsert inserts one; when the job's lock
+		// exists but does not match, the insert fails on its _id
+		_, opErr := s.jobLocks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		// No else needed: early return pattern (lock held elsewhere)
+		if mongo.IsDuplicateKeyError(opErr) {
+			return nil
+		}
+		acquired = opErr == nil
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+	return acquired, nil
+}
+
+// ReleaseJobLock frees the lock owner holds on job for slot, so that the next
+// slot can run at once. The slot stays recorded so it does not run again.
+func (s *StorageService) ReleaseJobLock(job, owner string, slot time.Time) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "release_job_lock"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := bson.M{constants.MongoFieldID: job, "owner": owner, "slot": slot}
+	err := s.retryOperation(ctx, "ReleaseJobLock", func() error {
+		_, opErr := s.jobLocks.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"until": time.Now()}})
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to release job lock: %w", err)
+	}
+	return nil
+}
+
+// RecordJobRun adds run to the job run history (see internal/jobs)
+func (s *StorageService) RecordJobRun(run *jobs.Run) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "record_job_run"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	err := s.retryOperation(ctx, "RecordJobRun", func() error {
+		_, opErr := s.jobRuns.InsertOne(ctx, run)
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to record job run: %w", err)
+	}
+	return nil
+}
+
+// ListJobRuns returns the most recent runs of job on any replica, newest first
+func (s *StorageService) ListJobRuns(job string, limit int) ([]*jobs.Run, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_job_runs"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	cursor, err := s.jobRuns.Find(ctx, bson.M{"job": job}, gomongo.QueryOptions{
+		Sort:  bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+		Limit: int64(limit),
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job runs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	runs := make([]*jobs.Run, 0)
+	for cursor.Next(ctx) {
+		var run jobs.Run
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&run); err != nil {
+			return nil, fmt.Errorf("failed to decode job run: %w", err)
+		}
+		runs = append(runs, &run)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/jobs"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobLocks(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.jobLocks = c })
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.jobRuns = c })
+
+	slot := time.Now().UTC().Truncate(time.Hour)
+	until := time.Now().Add(time.Minute)
+
+	acquired, err := service.AcquireJobLock("purge", "replica-a", slot, until)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = service.AcquireJobLock("purge", "replica-b", slot, until)
+	require.NoError(t, err)
+	assert.False(t, acquired, "slot already claimed")
+
+	acquired, err = service.AcquireJobLock("purge", "replica-b", slot.Add(time.Hour), until)
+	require.NoError(t, err)
+	assert.False(t, acquired, "previous run still holds the lock")
+
+	acquired, err = service.AcquireJobLock("rollup", "replica-b", slot, until)
+	require.NoError(t, err)
+	assert.True(t, acquired, "locks are per job")
+
+	require.NoError(t, service.ReleaseJobLock("purge", "replica-a", slot))
+	acquired, err = service.AcquireJobLock("purge", "replica-b", slot, until)
+	require.NoError(t, err)
+	assert.False(t, acquired, "released slots do not run again")
+
+	acquired, err = service.AcquireJobLock("purge", "replica-b", slot.Add(time.Hour), until)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestJobRuns(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.jobLocks = c })
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.jobRuns = c })
+
+	base := time.Now().UTC().Truncate(time.Millisecond)
+	for i := 0; i < 3; i++ {
+		started := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, service.RecordJobRun(&jobs.Run{
+			Job:        "purge",
+			Owner:      "replica-a",
+			Slot:       started,
+			StartedAt:  started,
+			FinishedAt: started.Add(time.Second),
+			DurationMs: 1000,
+			Status:     jobs.RunSucceeded,
+		}))
+	}
+	require.NoError(t, service.RecordJobRun(&jobs.Run{Job: "rollup", StartedAt: base, Status: jobs.RunFailed, Error: "timeout"}))
+
+	runs, err := service.ListJobRuns("purge", 2)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, base.Add(2*time.Minute), runs[0].StartedAt.UTC(), "newest first")
+	assert.Equal(t, base.Add(time.Minute), runs[1].StartedAt.UTC())
+
+	runs, err = service.ListJobRuns("rollup", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "timeout", runs[0].Error)
+
+	runs, err = service.ListJobRuns("unknown", 10)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/watchlist"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ValidateKeyword checks a watchlist keyword's term and returns its normalized
+// tag, constants.KeywordAlertTag when tag is empty
+func ValidateKeyword(term string, regex bool, tag string) (string, error) {
+	// No else needed: early return pattern (guard clause)
+	if err := watchlist.Validate(term, regex); err != nil {
+		return "", err
+	}
+	// No else needed: early return pattern (default tag)
+	if tag == "" {
+		return constants.KeywordAlertTag, nil
+	}
+	tags, err := NormalizeTags([]string{tag})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", watchlist.ErrInvalidKeyword, err)
+	}
+	return tags[0], nil
+}
+
+// ensureKeywordIndexes creates the indexes for the keyword_watchlist collection
+func (s *StorageService) ensureKeywordIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldTenantID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: 1},
+			},
+			Options: options.Index().SetName(constants.IndexKeywordTenant),
+		},
+	}
+
+	_, err := s.keywords.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create keyword indexes: %w", err)
+	}
+	return nil
+}
+
+// GetKeyword returns a watchlist keyword. On a tenant view only keywords of
+// that tenant are visible; watchlist.ErrKeywordNotFound is returned otherwise.
+func (s *StorageService) GetKeyword(id string) (*watchlist.Keyword, error) {
+	// No else needed: early return pattern (guard clause)
+	if id == "" {
+		return nil, watchlist.ErrKeywordNotFound
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_keyword"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var k watchlist.Keyword
+	err := s.retryOperation(ctx, "GetKeyword", func() error {
+		return s.keywords.FindOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id})).Decode(&k)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, watchlist.ErrKeywordNotFound
+		}
+		return nil, fmt.Errorf("failed to get keyword: %w", err)
+	}
+	return &k, nil
+}
+
+// ListKeywords lists the watchlist keywords visible through this service,
+// oldest first. On a tenant view this is the tenant's watchlist.
+func (s *StorageService) ListKeywords() ([]*watchlist.Keyword, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_keywords"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	cursor, err := s.keywords.Find(ctx, s.tenantFilter(bson.M{}), gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldTimestamp, Value: 1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keywords: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	keywords := make([]*watchlist.Keyword, 0)
+	for cursor.Next(ctx) {
+		var k watchlist.Keyword
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&k); err != nil {
+			return nil, fmt.Errorf("failed to decode keyword: %w", err)
+		}
+		keywords = append(keywords, &k)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return keywords, nil
+}
+
+// CreateKeyword adds a keyword to a watchlist. The ID, timestamps and
+// normalized tag are set, and on a tenant view it is owned by that tenant.
+// A tenant's watchlist holds at most constants.MaxKeywordsPerTenant keywords.
+func (s *StorageService) CreateKeyword(k *watchlist.Keyword) error {
+	// No else needed: early return pattern (guard clause)
+	if k == nil {
+		return watchlist.ErrInvalidKeyword
+	}
+	tag, err := ValidateKeyword(k.Term, k.Regex, k.Tag)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: optional operation (unscoped callers choose the tenant)
+	if s.tenantScoped {
+		k.TenantID = s.tenantID
+	}
+
+	existing, err := s.ForTenant(k.TenantID).ListKeywords()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(existing) >= constants.MaxKeywordsPerTenant {
+		return fmt.Errorf("%w: a watchlist holds at most %d keywords", watchlist.ErrInvalidKeyword, constants.MaxKeywordsPerTenant)
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "create_keyword"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	k.ID = primitive.NewObjectID().Hex()
+	k.Tag = tag
+	k.CreatedAt = now
+	k.UpdatedAt = now
+
+	err = s.retryOperation(ctx, "CreateKeyword", func() error {
+		_, opErr := s.keywords.InsertOne(ctx, k)
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create keyword: %w", err)
+	}
+	return nil
+}
+
+// UpdateKeyword replaces the term, kind and tag of a watchlist keyword and
+// returns the updated keyword
+func (s *StorageService) UpdateKeyword(id, term string, regex bool, tag string) (*watchlist.Keyword, error) {
+	tag, err := ValidateKeyword(term, regex, tag)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "update_keyword"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"term": term, "regex": regex, "tag": tag, "mt": time.Now().UTC()}}
+	findOpts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var k watchlist.Keyword
+	err = s.retryOperation(ctx, "UpdateKeyword", func() error {
+		return s.keywords.FindOneAndUpdate(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id}), update, findOpts).Decode(&k)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, watchlist.ErrKeywordNotFound
+		}
+		return nil, fmt.Errorf("failed to update keyword: %w", err)
+	}
+	return &k, nil
+}
+
+// DeleteKeyword removes a keyword from its watchlist
+func (s *StorageService) DeleteKeyword(id string) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "delete_keyword"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var deleted int64
+	err := s.retryOperation(ctx, "DeleteKeyword", func() error {
+		result, opErr := s.keywords.DeleteOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id}))
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			deleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to delete keyword: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if deleted == 0 {
+		return watchlist.ErrKeywordNotFound
+	}
+	return nil
+}
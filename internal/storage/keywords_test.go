@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/watchlist"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKeyword(t *testing.T) {
+	tag, err := ValidateKeyword("refund", false, "")
+	require.NoError(t, err)
+	assert.Equal(t, constants.KeywordAlertTag, tag)
+
+	tag, err = ValidateKeyword(`cancel\s+account`, true, " Churn-Risk ")
+	require.NoError(t, err)
+	assert.Equal(t, "churn-risk", tag)
+
+	_, err = ValidateKeyword("  ", false, "")
+	assert.ErrorIs(t, err, watchlist.ErrInvalidKeyword)
+	_, err = ValidateKeyword(strings.Repeat("k", constants.MaxKeywordTermLength+1), false, "")
+	assert.ErrorIs(t, err, watchlist.ErrInvalidKeyword)
+	_, err = ValidateKeyword("(unclosed", true, "")
+	assert.ErrorIs(t, err, watchlist.ErrInvalidKeyword)
+	_, err = ValidateKeyword("refund", false, "not a tag!")
+	assert.ErrorIs(t, err, watchlist.ErrInvalidKeyword)
+
+	svc := &StorageService{}
+	assert.ErrorIs(t, svc.CreateKeyword(nil), watchlist.ErrInvalidKeyword)
+	_, err = svc.UpdateKeyword("id", "", false, "")
+	assert.ErrorIs(t, err, watchlist.ErrInvalidKeyword)
+}
+
+func TestKeywords_CRUD(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.keywords = c })
+
+	acme := service.ForTenant("acme")
+	refund := &watchlist.Keyword{Term: "refund", CreatedBy: "admin-1", TenantID: "other"}
+	require.NoError(t, acme.CreateKeyword(refund))
+	assert.NotEmpty(t, refund.ID)
+	assert.Equal(t, "acme", refund.TenantID, "tenant views own the keywords they create")
+	assert.Equal(t, constants.KeywordAlertTag, refund.Tag)
+	lawyer := &watchlist.Keyword{Term: "lawyer|attorney", Regex: true, Tag: "legal"}
+	require.NoError(t, acme.CreateKeyword(lawyer))
+
+	// Other tenants cannot see, change or delete it
+	other := service.ForTenant("")
+	_, err := other.GetKeyword(refund.ID)
+	assert.ErrorIs(t, err, watchlist.ErrKeywordNotFound)
+	_, err = other.UpdateKeyword(refund.ID, "taken", false, "")
+	assert.ErrorIs(t, err, watchlist.ErrKeywordNotFound)
+	assert.ErrorIs(t, other.DeleteKeyword(refund.ID), watchlist.ErrKeywordNotFound)
+	otherList, err := other.ListKeywords()
+	require.NoError(t, err)
+	assert.Empty(t, otherList)
+
+	updated, err := acme.UpdateKeyword(refund.ID, "money back", false, "billing")
+	require.NoError(t, err)
+	assert.Equal(t, "money back", updated.Term)
+	assert.Equal(t, "billing", updated.Tag)
+	assert.Equal(t, "admin-1", updated.CreatedBy)
+
+	listed, err := acme.ListKeywords()
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, refund.ID, listed[0].ID, "oldest first")
+	assert.True(t, listed[1].Regex)
+
+	require.NoError(t, acme.DeleteKeyword(lawyer.ID))
+	_, err = acme.GetKeyword(lawyer.ID)
+	assert.ErrorIs(t, err, watchlist.ErrKeywordNotFound)
+	assert.ErrorIs(t, acme.DeleteKeyword(lawyer.ID), watchlist.ErrKeywordNotFound)
+}
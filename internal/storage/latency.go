@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Pipeline stages of an AI reply, as reported by GetLatencyBreakdown
+const (
+	StageModeration = "moderation"  // received until the input was screened
+	StageFirstToken = "first_token" // screened until the first LLM token
+	StageStreaming  = "streaming"   // first until the last LLM token
+	StagePersist    = "persist"     // last token until the reply was handed to storage
+	StageTotal      = "total"       // received until the reply was handed to storage
+)
+
+// LatencyStages lists the pipeline stages in pipeline order
+var LatencyStages = []string{StageModeration, StageFirstToken, StageStreaming, StagePersist, StageTotal}
+
+// TimingDocument stores the pipeline stage durations of an AI reply, in milliseconds
+type TimingDocument struct {
+	Moderation int64 `bson:"mod" json:"mod"`
+	FirstToken int64 `bson:"first" json:"first"`
+	Streaming  int64 `bson:"stream" json:"stream"`
+	Persist    int64 `bson:"persist" json:"persist"`
+	Total      int64 `bson:"total" json:"total"`
+}
+
+// StageLatency holds the latency percentiles of one pipeline stage
+type StageLatency struct {
+	P50 int64 `json:"p50_ms"`
+	P95 int64 `json:"p95_ms"`
+}
+
+// LatencyBreakdown is the latency of each pipeline stage of the AI replies in a
+// time range, keyed by stage (see LatencyStages)
+type LatencyBreakdown struct {
+	Samples int                     `json:"samples"` // AI replies the percentiles are computed from
+	Stages  map[string]StageLatency `json:"stages"`
+}
+
+// timingToDocument converts the stage durations of a message for storage
+func timingToDocument(t *session.MessageTiming) *TimingDocument {
+	// No else needed: early return pattern (messages without timing)
+	if t == nil {
+		return nil
+	}
+	return &TimingDocument{
+		Moderation: t.Moderation.Milliseconds(),
+		FirstToken: t.FirstToken.Milliseconds(),
+		Streaming:  t.Streaming.Milliseconds(),
+		Persist:    t.Persist.Milliseconds(),
+		Total:      t.Total.Milliseconds(),
+	}
+}
+
+// timingFromDocument converts stored stage durations back
+func timingFromDocument(doc *TimingDocument) *session.MessageTiming {
+	// No else needed: early return pattern (messages without timing)
+	if doc == nil {
+		return nil
+	}
+	return &session.MessageTiming{
+		Moderation: time.Duration(doc.Moderation) * time.Millisecond,
+		FirstToken: time.Duration(doc.FirstToken) * time.Millisecond,
+		Streaming:  time.Duration(doc.Streaming) * time.Millisecond,
+		Persist:    time.Duration(doc.Persist) * time.Millisecond,
+		Total:      time.Duration(doc.Total) * time.Millisecond,
+	}
+}
+
+// stages returns the duration of each stage, keyed like LatencyStages
+func (d *TimingDocument) stages() map[string]int64 {
+	return map[string]int64{
+		StageModeration: d.Moderation,
+		StageFirstToken: d.FirstToken,
+		StageStreaming:  d.Streaming,
+		StagePersist:    d.Persist,
+		StageTotal:      d.Total,
+	}
+}
+
+// GetLatencyBreakdown returns the p50 and p95 latency of each pipeline stage of
+// the AI replies visible to this service that were sent in [startTime, endTime).
+// At most constants.MaxLatencySamples of the most recent replies are sampled
+// per datastore.
+func (s *StorageService) GetLatencyBreakdown(startTime, endTime time.Time) (*LatencyBreakdown, error) {
+	// No else needed: early return pattern (guard clause)
+	if endTime.Before(startTime) {
+		return nil, errors.New("end time must be after start time")
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_latency_breakdown"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.MetricsTimeout)
+	defer cancel()
+
+	samples, err := s.latencySamples(ctx, startTime, endTime)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	return latencyBreakdown(samples), nil
+}
+
+// latencySamples returns the timings of the most recent AI replies sent in the
+// time range. Unscoped services include the datastores of pinned tenants.
+func (s *StorageService) latencySamples(ctx context.Context, startTime, endTime time.Time) ([]TimingDocument, error) {
+	window := bson.M{"$gte": startTime, "$lt": endTime}
+	pipeline := mongo.Pipeline{
+		// Sessions started before the range end hold replies in it when they
+		// were still active at its start; sessions stored whole may not
+		// record their last activity
+		{{Key: "$match", Value: s.scope(bson.M{
+			constants.MongoFieldTimestamp: bson.M{"$lt": endTime},
+			constants.MongoFieldMsgTiming: bson.M{"$exists": true},
+			"$or": bson.A{
+				bson.M{constants.MongoFieldLastActivity: bson.M{"$gte": startTime}},
+				bson.M{constants.MongoFieldLastActivity: bson.M{"$exists": false}},
+			},
+		})}},
+		{{Key: "$unwind", Value: "$" + constants.MongoFieldMessages}},
+		{{Key: "$match", Value: bson.M{
+			constants.MongoFieldMsgTiming:    bson.M{"$exists": true},
+			constants.MongoFieldMsgTimestamp: window,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: constants.MongoFieldMsgTimestamp, Value: -1}}}},
+		{{Key: "$limit", Value: constants.MaxLatencySamples}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$" + constants.MongoFieldMsgTiming}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate message timings: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var samples []TimingDocument
+	for cursor.Next(ctx) {
+		var doc TimingDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode message timing: %w", err)
+		}
+		samples = append(samples, doc)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	// No else needed: early return pattern (tenant views already read the tenant's datastore)
+	if s.tenantScoped {
+		return samples, nil
+	}
+	for _, view := range s.datastores {
+		viewSamples, err := view.latencySamples(ctx, startTime, endTime)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("datastore %s: %w", view.datastore, err)
+		}
+		samples = append(samples, viewSamples...)
+	}
+	return samples, nil
+}
+
+// latencyBreakdown computes the percentiles of each stage of samples
+func latencyBreakdown(samples []TimingDocument) *LatencyBreakdown {
+	durations := make(map[string][]int64, len(LatencyStages))
+	for i := range samples {
+		for stage, ms := range samples[i].stages() {
+			durations[stage] = append(durations[stage], ms)
+		}
+	}
+
+	result := &LatencyBreakdown{Samples: len(samples), Stages: make(map[string]StageLatency, len(LatencyStages))}
+	for _, stage := range LatencyStages {
+		values := durations[stage]
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		result.Stages[stage] = StageLatency{P50: percentile(values, 0.50), P95: percentile(values, 0.95)}
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile p (0-1) of sorted, 0 when empty
+func percentile(sorted []int64, p float64) int64 {
+	// No else needed: early return pattern (no samples)
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	// No else needed: conditional assignment (the smallest rank is the first sample)
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimingDocument(t *testing.T) {
+	timing := &session.MessageTiming{
+		Moderation: 5 * time.Millisecond,
+		FirstToken: 400 * time.Millisecond,
+		Streaming:  2 * time.Second,
+		Persist:    3 * time.Millisecond,
+		Total:      2408 * time.Millisecond,
+	}
+	doc := timingToDocument(timing)
+	assert.Equal(t, &TimingDocument{Moderation: 5, FirstToken: 400, Streaming: 2000, Persist: 3, Total: 2408}, doc)
+	assert.Equal(t, timing, timingFromDocument(doc))
+	assert.Nil(t, timingToDocument(nil))
+	assert.Nil(t, timingFromDocument(nil))
+}
+
+func TestPercentile(t *testing.T) {
+	values := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assert.Equal(t, int64(5), percentile(values, 0.50))
+	assert.Equal(t, int64(10), percentile(values, 0.95))
+	assert.Equal(t, int64(1), percentile(values, 0))
+	assert.Equal(t, int64(0), percentile(nil, 0.95))
+}
+
+func TestLatencyBreakdown(t *testing.T) {
+	var samples []TimingDocument
+	for i := int64(1); i <= 20; i++ {
+		samples = append(samples, TimingDocument{Moderation: 1, FirstToken: i * 100, Streaming: 50, Total: i*100 + 51})
+	}
+	result := latencyBreakdown(samples)
+	assert.Equal(t, 20, result.Samples)
+	assert.Len(t, result.Stages, len(LatencyStages))
+	assert.Equal(t, StageLatency{P50: 1000, P95: 1900}, result.Stages[StageFirstToken])
+	assert.Equal(t, StageLatency{P50: 50, P95: 50}, result.Stages[StageStreaming])
+	assert.Equal(t, StageLatency{}, result.Stages[StagePersist])
+
+	empty := latencyBreakdown(nil)
+	assert.Equal(t, 0, empty.Samples)
+	assert.Equal(t, StageLatency{}, empty.Stages[StageTotal])
+}
+
+func TestGetLatencyBreakdown(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	reply := func(at time.Time, firstToken time.Duration) *session.Message {
+		return &session.Message{Content: "reply", Sender: "ai", Timestamp: at, Timing: &session.MessageTiming{FirstToken: firstToken, Total: firstToken}}
+	}
+	create := func(id, tenantID string, messages ...*session.Message) {
+		require.NoError(t, service.CreateSession(&session.Session{
+			ID:           id,
+			UserID:       "user-" + id,
+			TenantID:     tenantID,
+			Messages:     messages,
+			StartTime:    now.Add(-2 * time.Hour),
+			LastActivity: now,
+		}))
+	}
+	create("s1", "",
+		&session.Message{Content: "hi", Sender: "user", Timestamp: now.Add(-time.Minute)},
+		reply(now.Add(-time.Minute), 100*time.Millisecond),
+		reply(now.Add(-90*time.Minute), 9*time.Second))
+	create("s2", "acme", reply(now.Add(-time.Minute), 300*time.Millisecond))
+
+	result, err := service.GetLatencyBreakdown(now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Samples, "only timed replies in the range are sampled")
+	assert.Equal(t, StageLatency{P50: 100, P95: 300}, result.Stages[StageFirstToken])
+
+	// Tenant views only sample their tenant's replies
+	result, err = service.ForTenant("acme").GetLatencyBreakdown(now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Samples)
+	assert.Equal(t, int64(300), result.Stages[StageTotal].P50)
+
+	_, err = service.GetLatencyBreakdown(now, now.Add(-time.Hour))
+	assert.Error(t, err)
+}
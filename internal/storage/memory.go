@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/memory"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ensureMemoryIndexes creates the indexes for the user_memory collection
+func (s *StorageService) ensureMemoryIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldTenantID, Value: 1},
+				{Key: constants.MongoFieldUserID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: 1},
+			},
+			Options: options.Index().SetName(constants.IndexMemoryUser),
+		},
+	}
+
+	_, err := s.memory.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create memory indexes: %w", err)
+	}
+	return nil
+}
+
+// UserMemory returns the facts remembered about a user of tenantID, oldest
+// first. Nothing is remembered about users of anonymized tenants.
+func (s *StorageService) UserMemory(tenantID, userID string) ([]*memory.Fact, error) {
+	// No else needed: early return pattern (no per-user data in anonymized mode)
+	if s.IsAnonymizedTenant(tenantID) {
+		return nil, nil
+	}
+	return s.ForTenant(tenantID).ListUserMemory(userID)
+}
+
+// ListUserMemory returns the facts remembered about a user, oldest first. On a
+// tenant view only the tenant's facts are listed.
+func (s *StorageService) ListUserMemory(userID string) ([]*memory.Fact, error) {
+	// No else needed: early return pattern (guard clause)
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	return s.findMemory(s.tenantFilter(bson.M{constants.MongoFieldUserID: userID}))
+}
+
+// ExportUserMemory returns every fact remembered about a user for a data
+// export. On an unscoped service this covers all tenants.
+func (s *StorageService) ExportUserMemory(userID string) ([]*memory.Fact, error) {
+	// No else needed: early return pattern (guard clause)
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	return s.findMemory(s.userDataFilter(userID))
+}
+
+// findMemory returns the facts matching filter with their text decrypted, oldest first
+func (s *StorageService) findMemory(filter bson.M) ([]*memory.Fact, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_user_memory"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	cursor, err := s.memory.Find(ctx, filter, gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldTimestamp, Value: 1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user memory: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	facts := make([]*memory.Fact, 0)
+	for cursor.Next(ctx) {
+		var f memory.Fact
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&f); err != nil {
+			return nil, fmt.Errorf("failed to decode memory fact: %w", err)
+		}
+		f.Text, err = s.decrypt(f.Text)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt memory fact: %w", err)
+		}
+		facts = append(facts, &f)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return facts, nil
+}
+
+// RememberUserFacts stores facts a user of tenantID stated in a session, as
+// decided by memory.Merge: known facts are skipped, a new name replaces the
+// old one, and beyond constants.MaxMemoryFacts the oldest facts are forgotten.
+// Returns the facts that were added. Concurrent calls for the same user may
+// both add a fact; duplicates are harmless and age out. Nothing is remembered
+// about users of anonymized tenants.
+func (s *StorageService) RememberUserFacts(tenantID, userID, sessionID string, facts []memory.Extracted) ([]*memory.Fact, error) {
+	// No else needed: early return pattern (no per-user data in anonymized mode)
+	if s.IsAnonymizedTenant(tenantID) {
+		return nil, nil
+	}
+	view := s.ForTenant(tenantID)
+	stored, err := view.ListUserMemory(userID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	add, remove := memory.Merge(stored, facts, constants.MaxMemoryFacts)
+	// No else needed: early return pattern (nothing new)
+	if len(add) == 0 {
+		return nil, nil
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "remember_user_facts"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	// No else needed: optional operation (forget replaced and excess facts)
+	if len(remove) > 0 {
+		err = s.retryOperation(ctx, "RememberUserFacts.forget", func() error {
+			_, opErr := view.memory.DeleteMany(ctx, view.tenantFilter(bson.M{
+				constants.MongoFieldID:     bson.M{"$in": remove},
+				constants.MongoFieldUserID: userID,
+			}))
+			return opErr
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to forget memory facts: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	added := make([]*memory.Fact, 0, len(add))
+	for i, e := range add {
+		text, err := s.encrypt(e.Text)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt memory fact: %w", err)
+		}
+		fact := memory.Fact{
+			ID:        primitive.NewObjectID().Hex(),
+			UserID:    userID,
+			TenantID:  tenantID,
+			Kind:      e.Kind,
+			Text:      text,
+			SessionID: sessionID,
+			// Facts of one message keep their order
+			CreatedAt: now.Add(time.Duration(i) * time.Millisecond),
+		}
+		err = s.retryOperation(ctx, "RememberUserFacts", func() error {
+			_, opErr := view.memory.InsertOne(ctx, &fact)
+			return opErr
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remember user fact: %w", err)
+		}
+		fact.Text = e.Text
+		added = append(added, &fact)
+	}
+	return added, nil
+}
+
+// DeleteUserMemoryFact forgets one fact remembered about a user. On a tenant
+// view only the tenant's facts can be deleted; memory.ErrFactNotFound is
+// returned when the fact does not exist or belongs to someone else.
+func (s *StorageService) DeleteUserMemoryFact(userID, factID string) error {
+	// No else needed: early return pattern (guard clause)
+	if userID == "" || factID == "" {
+		return memory.ErrFactNotFound
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "delete_user_memory_fact"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var deleted int64
+	err := s.retryOperation(ctx, "DeleteUserMemoryFact", func() error {
+		result, opErr := s.memory.DeleteOne(ctx, s.tenantFilter(bson.M{
+			constants.MongoFieldID:     factID,
+			constants.MongoFieldUserID: userID,
+		}))
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			deleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to delete memory fact: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if deleted == 0 {
+		return memory.ErrFactNotFound
+	}
+	return nil
+}
+
+// ClearUserMemory forgets everything remembered about a user and returns the
+// number of facts deleted. On a tenant view only the tenant's facts are deleted.
+func (s *StorageService) ClearUserMemory(userID string) (int64, error) {
+	// No else needed: early return pattern (guard clause)
+	if userID == "" {
+		return 0, ErrInvalidUserID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "clear_user_memory"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var deleted int64
+	err := s.retryOperation(ctx, "ClearUserMemory", func() error {
+		result, opErr := s.memory.DeleteMany(ctx, s.tenantFilter(bson.M{constants.MongoFieldUserID: userID}))
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			deleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear user memory: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/memory"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUserMemory(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.memory = c })
+
+	added, err := service.RememberUserFacts("acme", "user-1", "sess-1", []memory.Extracted{
+		{Kind: memory.KindName, Text: "Anna"},
+		{Kind: memory.KindPreference, Text: "short answers"},
+	})
+	require.NoError(t, err)
+	require.Len(t, added, 2)
+	assert.Equal(t, "Anna", added[0].Text)
+
+	// Known facts are not stored twice, and a new name replaces the old one
+	added, err = service.RememberUserFacts("acme", "user-1", "sess-2", []memory.Extracted{
+		{Kind: memory.KindPreference, Text: "Short answers"},
+		{Kind: memory.KindName, Text: "Jo"},
+	})
+	require.NoError(t, err)
+	require.Len(t, added, 1)
+
+	facts, err := service.UserMemory("acme", "user-1")
+	require.NoError(t, err)
+	require.Len(t, facts, 2)
+	assert.Equal(t, "short answers", facts[0].Text, "oldest first")
+	assert.Equal(t, "Jo", facts[1].Text)
+	assert.Equal(t, "sess-2", facts[1].SessionID)
+
+	// Facts are per tenant and user
+	otherTenant, err := service.UserMemory("", "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, otherTenant)
+	assert.ErrorIs(t, service.ForTenant("").DeleteUserMemoryFact("user-1", facts[0].ID), memory.ErrFactNotFound)
+	assert.ErrorIs(t, service.ForTenant("acme").DeleteUserMemoryFact("user-2", facts[0].ID), memory.ErrFactNotFound)
+
+	acme := service.ForTenant("acme")
+	require.NoError(t, acme.DeleteUserMemoryFact("user-1", facts[0].ID))
+	assert.ErrorIs(t, acme.DeleteUserMemoryFact("user-1", facts[0].ID), memory.ErrFactNotFound)
+
+	exported, err := service.ExportUserMemory("user-1")
+	require.NoError(t, err)
+	require.Len(t, exported, 1)
+	assert.Equal(t, "Jo", exported[0].Text)
+
+	deleted, err := acme.ClearUserMemory("user-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	facts, err = acme.ListUserMemory("user-1")
+	require.NoError(t, err)
+	assert.Empty(t, facts)
+}
+
+func TestUserMemory_Encrypted(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	service, cleanup := setupTestStorage(t, key)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.memory = c })
+
+	_, err := service.RememberUserFacts("", "user-1", "sess-1", []memory.Extracted{{Kind: memory.KindNote, Text: "I am vegetarian"}})
+	require.NoError(t, err)
+
+	// The stored text is encrypted, the listed text is not
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var raw memory.Fact
+	require.NoError(t, service.memory.FindOne(ctx, bson.M{constants.MongoFieldUserID: "user-1"}).Decode(&raw))
+	assert.NotEqual(t, "I am vegetarian", raw.Text)
+
+	facts, err := service.UserMemory("", "user-1")
+	require.NoError(t, err)
+	require.Len(t, facts, 1)
+	assert.Equal(t, "I am vegetarian", facts[0].Text)
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrSessionActive is returned when a session must be ended for the operation
+	ErrSessionActive = errors.New("session is still active")
+	// ErrMergeConflict is returned when a session changed while it was being merged
+	ErrMergeConflict = errors.New("session changed during merge")
+)
+
+// MergeDocument records a session merged into another one
+type MergeDocument struct {
+	SessionID    string    `bson:"sid" json:"session_id"`
+	MergedAt     time.Time `bson:"ts" json:"merged_at"`
+	MessageCount int       `bson:"msgCount" json:"message_count"`
+}
+
+// MergeResult describes a completed merge
+type MergeResult struct {
+	SessionID    string `json:"session_id"`
+	MergedFrom   string `json:"merged_from"`
+	MessageCount int    `json:"message_count"`
+	TotalTokens  int    `json:"total_tokens"`
+	SourceEnded  bool   `json:"-"` // the source was still active and was ended by the merge
+}
+
+// MergeUserSessions merges session sourceID into targetID, both owned by
+// userID. The messages of both sessions are interleaved by timestamp (target
+// first on ties), tokens and cost are added up, the target records the merge in
+// its provenance, and the source is ended if still active and soft-deleted with
+// a reference to the target, so admins can restore it.
+//
+// Returns ErrSessionNotFound when either session does not exist or belongs to
+// someone else, ErrSessionActive when the target is still active (its messages
+// live in memory), ErrMergeConflict when the target received messages during the
+// merge, and ErrAnonymizedMode when message content is not stored.
+func (s *StorageService) MergeUserSessions(targetID, sourceID, userID string, now time.Time) (*MergeResult, error) {
+	// No else needed: early return pattern (guard clause)
+	if targetID == "" || sourceID == "" || targetID == sourceID {
+		return nil, ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+	s.flushPending(targetID)
+	s.flushPending(sourceID)
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "merge_user_sessions"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+	defer cancel()
+
+	docs := make(map[string]*SessionDocument, 2)
+	for _, sessionID := range []string{targetID, sourceID} {
+		var doc SessionDocument
+		err := s.retryOperation(ctx, "MergeUserSessions.find", func() error {
+			return s.collection.FindOne(ctx, s.ownedBy(sessionID, userID)).Decode(&doc)
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			// No else needed: early return pattern (guard clause)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, ErrSessionNotFound
+			}
+			return nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		docs[sessionID] = &doc
+	}
+	target, source := docs[targetID], docs[sourceID]
+	// No else needed: early return pattern (guard clause)
+	if target.EndTime == nil {
+		return nil, ErrSessionActive
+	}
+
+	// Decrypt both histories: ciphertexts are bound to their session and index
+	targetMsgs, err := s.openMessages(target)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	sourceMsgs, err := s.openMessages(source)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]MessageDocument, 0, len(targetMsgs)+len(sourceMsgs))
+	merged = append(merged, targetMsgs...)
+	merged = append(merged, sourceMsgs...)
+	// Pins follow their messages; the source's come after the target's
+	pins := append([]PinDocument(nil), target.Pins...)
+	for _, pin := range source.Pins {
+		pin.Index += len(targetMsgs)
+		pins = append(pins, pin)
+	}
+	merged, pins = sortMessagesByTime(merged, pins)
+
+	set := bson.M{
+		constants.MongoFieldTotalTokens: target.TotalTokens + source.TotalTokens,
+		constants.MongoFieldCost:        target.Cost + source.Cost,
+	}
+	// No else needed: optional operation (keep the latest activity)
+	if source.LastActivity.After(target.LastActivity) {
+		set[constants.MongoFieldLastActivity] = source.LastActivity
+	}
+	// No else needed: optional operation (the merged history starts earlier)
+	if source.StartTime.Before(target.StartTime) {
+		set[constants.MongoFieldTimestamp] = source.StartTime
+	}
+	// No else needed: optional operation (searchable hashes only when enabled)
+	if s.usesSearchHashIndex() {
+		var terms []string
+		for _, msg := range merged {
+			terms = append(terms, searchTerms(msg.Content)...)
+		}
+		set[constants.MongoFieldSearchTerms] = s.hashTerms(terms)
+	}
+	// No else needed: optional operation (only encrypt if key is available)
+	if len(s.encryptionKey) > 0 {
+		for i := range merged {
+			sealed, err := s.sealMessage(targetID, i, merged[i].Content)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt message content: %w", err)
+			}
+			merged[i].Content = sealed
+		}
+	}
+	set[constants.MongoFieldMessages] = merged
+	set[constants.MongoFieldPins] = pins
+
+	// The target must not have changed since it was read
+	provenance := MergeDocument{SessionID: sourceID, MergedAt: now, MessageCount: len(sourceMsgs)}
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{constants.MongoFieldMergedFrom: provenance},
+	}
+	var matched int64
+	err = s.retryOperation(ctx, "MergeUserSessions.target", func() error {
+		result, opErr := s.collection.UpdateOne(ctx, withMessageCount(s.ownedBy(targetID, userID), len(targetMsgs)), update)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge into session: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if matched == 0 {
+		return nil, ErrMergeConflict
+	}
+
+	// No else needed: optional operation (already-ended sessions keep their end time)
+	if source.EndTime == nil {
+		// No else needed: early return pattern (guard clause)
+		if err := s.EndSession(sourceID, now); err != nil {
+			return nil, fmt.Errorf("failed to end merged session: %w", err)
+		}
+	}
+	err = s.retryOperation(ctx, "MergeUserSessions.source", func() error {
+		_, opErr := s.collection.UpdateOne(ctx, bson.M{constants.MongoFieldID: sourceID}, bson.M{"$set": bson.M{
+			constants.MongoFieldDeletedAt:  now,
+			constants.MongoFieldMergedInto: targetID,
+		}})
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete merged session: %w", err)
+	}
+
+	s.logger.Info("Sessions merged",
+		"session_id", targetID,
+		"source_session_id", sourceID,
+		"message_count", len(merged))
+	return &MergeResult{
+		SessionID:    targetID,
+		MergedFrom:   sourceID,
+		MessageCount: len(merged),
+		TotalTokens:  target.TotalTokens + source.TotalTokens,
+		SourceEnded:  source.EndTime == nil,
+	}, nil
+}
+
+// openMessages returns the messages of doc with decrypted content. Fails when a
+// message does not pass its integrity check, so tampered content is never
+// re-encrypted as genuine.
+func (s *StorageService) openMessages(doc *SessionDocument) ([]MessageDocument, error) {
+	msgs := make([]MessageDocument, len(doc.Messages))
+	for i, msg := range doc.Messages {
+		// No else needed: optional operation (only decrypt if key is available)
+		if len(s.encryptionKey) > 0 {
+			content, tampered := s.openMessage(doc.ID, i, msg.Content)
+			// No else needed: early return pattern (guard clause)
+			if tampered {
+				return nil, fmt.Errorf("message %d of session %s failed its integrity check", i, doc.ID)
+			}
+			msg.Content = content
+		}
+		msgs[i] = msg
+	}
+	return msgs, nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeUserSessions(t *testing.T) {
+	service, cleanup := setupTestStorage(t, []byte("12345678901234567890123456789012"))
+	defer cleanup()
+
+	now := time.Now().Truncate(time.Millisecond)
+	ended := now.Add(-time.Hour)
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:          "target",
+		UserID:      "user-1",
+		StartTime:   now.Add(-2 * time.Hour),
+		EndTime:     &ended,
+		TotalTokens: 100,
+		Messages: []*session.Message{
+			{Content: "first", Timestamp: now.Add(-2 * time.Hour), Sender: "user"},
+			{Content: "third", Timestamp: now.Add(-90 * time.Minute), Sender: "ai"},
+		},
+	}))
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:          "source",
+		UserID:      "user-1",
+		StartTime:   now.Add(-100 * time.Minute),
+		IsActive:    true,
+		TotalTokens: 50,
+		Messages: []*session.Message{
+			{Content: "second", Timestamp: now.Add(-100 * time.Minute), Sender: "user"},
+			{Content: "fourth", Timestamp: now.Add(-time.Minute), Sender: "user"},
+		},
+	}))
+
+	_, err := service.MergeUserSessions("target", "source", "user-2", now)
+	assert.ErrorIs(t, err, ErrSessionNotFound, "only the owner can merge sessions")
+	_, err = service.MergeUserSessions("source", "target", "user-1", now)
+	assert.ErrorIs(t, err, ErrSessionActive, "active sessions cannot be merged into")
+	_, err = service.MergeUserSessions("target", "target", "user-1", now)
+	assert.ErrorIs(t, err, ErrInvalidSessionID)
+
+	result, err := service.MergeUserSessions("target", "source", "user-1", now)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.MessageCount)
+	assert.Equal(t, 150, result.TotalTokens)
+	assert.True(t, result.SourceEnded)
+
+	// Messages are interleaved and re-encrypted for their new positions
+	merged, err := service.GetSession("target")
+	require.NoError(t, err)
+	var contents []string
+	for _, msg := range merged.Messages {
+		contents = append(contents, msg.Content)
+	}
+	assert.Equal(t, []string{"first", "second", "third", "fourth"}, contents)
+	assert.Empty(t, tamperedMessages(merged))
+	assert.Equal(t, 150, merged.TotalTokens)
+
+	// The source is soft-deleted, pointing at the target
+	_, err = service.GetSession("source")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	require.NoError(t, service.RestoreSession("source"))
+	source, err := service.GetSession("source")
+	require.NoError(t, err)
+	assert.NotNil(t, source.EndTime)
+}
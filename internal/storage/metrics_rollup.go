@@ -0,0 +1,679 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rollupStatusID is the _id of the metrics_rollup document recording how far
+// rollups have been computed
+const rollupStatusID = "status"
+
+// day is the size of daily rollup buckets (UTC days)
+const day = 24 * time.Hour
+
+// metricsTotals holds additive session metrics, so totals of separate time
+// ranges and tenants can be summed. Rollup documents store them as computed.
+type metricsTotals struct {
+	Sessions          int            `bson:"sessions"`
+	ActiveSessions    int            `bson:"active"`
+	AdminAssisted     int            `bson:"adminAssisted"`
+	TotalTokens       int            `bson:"totalTokens"`
+	MaxResponseTime   int64          `bson:"maxRespTime"`   // milliseconds
+	ResponseTimeSum   float64        `bson:"respTimeSum"`   // sum of per-session average response times
+	ResponseTimeCount int            `bson:"respTimeCount"` // sessions with an average response time
+	FeedbackUp        int            `bson:"fbUp"`          // AI messages rated up
+	FeedbackDown      int            `bson:"fbDown"`        // AI messages rated down
+	Tags              map[string]int `bson:"tags,omitempty"`
+	EndReasons        map[string]int `bson:"endReasons,omitempty"` // force-ended sessions per reason code
+}
+
+// add adds other to t
+func (t *metricsTotals) add(other *metricsTotals) {
+	t.Sessions += other.Sessions
+	t.ActiveSessions += other.ActiveSessions
+	t.AdminAssisted += other.AdminAssisted
+	t.TotalTokens += other.TotalTokens
+	t.ResponseTimeSum += other.ResponseTimeSum
+	t.ResponseTimeCount += other.ResponseTimeCount
+	t.FeedbackUp += other.FeedbackUp
+	t.FeedbackDown += other.FeedbackDown
+	// No else needed: optional operation (keep the larger maximum)
+	if other.MaxResponseTime > t.MaxResponseTime {
+		t.MaxResponseTime = other.MaxResponseTime
+	}
+	for tag, count := range other.Tags {
+		// No else needed: conditional assignment (lazy map creation)
+		if t.Tags == nil {
+			t.Tags = make(map[string]int)
+		}
+		t.Tags[tag] += count
+	}
+	for reason, count := range other.EndReasons {
+		// No else needed: conditional assignment (lazy map creation)
+		if t.EndReasons == nil {
+			t.EndReasons = make(map[string]int)
+		}
+		t.EndReasons[reason] += count
+	}
+}
+
+// metrics converts the totals to the Metrics reported by the admin API
+func (t *metricsTotals) metrics() *Metrics {
+	result := &Metrics{
+		TotalSessions:      t.Sessions,
+		ActiveSessions:     t.ActiveSessions,
+		TotalTokens:        t.TotalTokens,
+		MaxResponseTime:    t.MaxResponseTime,
+		AdminAssistedCount: t.AdminAssisted,
+		TagCounts:          topTags(t.Tags, constants.MaxTagCounts),
+		EndReasonCounts:    t.EndReasons,
+		FeedbackUp:         t.FeedbackUp,
+		FeedbackDown:       t.FeedbackDown,
+	}
+	// No else needed: optional operation (no response times recorded)
+	if t.ResponseTimeCount > 0 {
+		result.AvgResponseTime = int64(t.ResponseTimeSum / float64(t.ResponseTimeCount))
+	}
+	// No else needed: optional operation (no rated messages)
+	if rated := t.FeedbackUp + t.FeedbackDown; rated > 0 {
+		result.FeedbackPositiveRate = float64(t.FeedbackUp) / float64(rated)
+	}
+	return result
+}
+
+// topTags returns the limit most used tags of counts, ties broken by name
+func topTags(counts map[string]int, limit int) map[string]int {
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		// No else needed: early return pattern (order by count first)
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	// No else needed: optional operation (truncate to limit)
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	top := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		top[tag] = counts[tag]
+	}
+	return top
+}
+
+// rollupKey identifies the totals of one tenant in one bucket
+type rollupKey struct {
+	TenantID string
+	Start    time.Time
+}
+
+// rollupDocument is one tenant's session metrics for an hour or a day in the
+// metrics_rollup collection. Sessions are counted in the bucket they started in.
+type rollupDocument struct {
+	ID          string        `bson:"_id"`
+	Granularity string        `bson:"gran"`          // constants.RollupHourly or constants.RollupDaily
+	TenantID    string        `bson:"tid,omitempty"` // empty for the default tenant
+	Start       time.Time     `bson:"ts"`
+	Totals      metricsTotals `bson:"m"`
+	ComputedAt  time.Time     `bson:"computedAt"`
+}
+
+// rollupRange is a time range [from, to) served from rollups of one granularity
+type rollupRange struct {
+	granularity string
+	from, to    time.Time
+}
+
+// rollupStatusDocument records that every complete hour and day before Through
+// has been rolled up
+type rollupStatusDocument struct {
+	ID      string    `bson:"_id"`
+	Through time.Time `bson:"through"`
+}
+
+// ensureRollupIndexes creates the indexes for the metrics_rollup collection
+func (s *StorageService) ensureRollupIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldGranularity, Value: 1},
+				{Key: constants.MongoFieldTenantID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: 1},
+			},
+			Options: options.Index().SetName(constants.IndexRollupBucket),
+		},
+	}
+
+	_, err := s.rollups.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics rollup indexes: %w", err)
+	}
+	return nil
+}
+
+// StartMetricsRollup starts a background goroutine computing the metrics rollups
+// served by GetRollupMetrics. It runs once immediately, then every
+// constants.MetricsRollupInterval. Call StopMetricsRollup to stop it.
+func (s *StorageService) StartMetricsRollup() {
+	s.rollupStop = make(chan struct{})
+	s.rollupWg.Add(1)
+	go func() {
+		defer s.rollupWg.Done()
+		ticker := time.NewTicker(constants.MetricsRollupInterval)
+		defer ticker.Stop()
+
+		for {
+			// No else needed: optional operation (errors are logged, next run retries)
+			if err := s.RollupMetrics(time.Now()); err != nil {
+				util.LogError(s.logger, "storage", "roll up session metrics", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.rollupStop:
+				return
+			}
+		}
+	}()
+}
+
+// StopMetricsRollup stops the metrics rollup goroutine.
+// Safe to call multiple times and when the aggregator was never started.
+func (s *StorageService) StopMetricsRollup() {
+	// No else needed: early return pattern (aggregator not started)
+	if s.rollupStop == nil {
+		return
+	}
+	s.rollupOnce.Do(func() {
+		close(s.rollupStop)
+	})
+	s.rollupWg.Wait()
+}
+
+// RollupMetrics computes the hourly rollups of every complete hour and the daily
+// rollups of every complete UTC day as of now. The first run covers all stored
+// sessions; later runs recompute the buckets of the last
+// constants.MetricsRollupLookback before the previous run, because sessions keep
+// changing after they start (tokens, end time, tags). Later changes to older
+// sessions, including their deletion, are not reflected in their rollups. The
+// datastores of pinned tenants (see SetResidency) are rolled up into their own
+// rollups collection.
+func (s *StorageService) RollupMetrics(now time.Time) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "rollup_metrics"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+	defer cancel()
+
+	previous, err := s.rollupWatermark(ctx)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+
+	// Day-aligned so the daily buckets of the window are recomputed whole
+	var from time.Time
+	// No else needed: optional operation (first run rolls up all sessions)
+	if !previous.IsZero() {
+		from = previous.Add(-constants.MetricsRollupLookback).Truncate(day)
+	}
+	through := now.Truncate(time.Hour)
+
+	// MongoDB stores milliseconds; truncating keeps this run's documents out of the stale cleanup
+	computedAt := start.Truncate(time.Millisecond)
+	// No else needed: early return pattern (guard clause)
+	if err := s.writeRollups(ctx, constants.RollupHourly, time.Hour, from, through, computedAt); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.writeRollups(ctx, constants.RollupDaily, day, from, through.Truncate(day), computedAt); err != nil {
+		return err
+	}
+
+	err = s.retryOperation(ctx, "RollupMetrics.status", func() error {
+		_, opErr := s.rollups.ReplaceOne(ctx,
+			bson.M{constants.MongoFieldID: rollupStatusID},
+			rollupStatusDocument{ID: rollupStatusID, Through: through},
+			options.Replace().SetUpsert(true))
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to update metrics rollup status: %w", err)
+	}
+
+	s.logger.Debug("Session metrics rolled up",
+		"from", from,
+		"through", through,
+		"duration", time.Since(start))
+
+	// Each datastore keeps the rollups of its own sessions
+	for _, view := range s.datastores {
+		// No else needed: early return pattern (guard clause)
+		if err := view.RollupMetrics(now); err != nil {
+			return fmt.Errorf("datastore %s: %w", view.datastore, err)
+		}
+	}
+	return nil
+}
+
+// writeRollups replaces the rollups of granularity in [from, to) with totals
+// aggregated from the sessions. A zero from rolls up everything before to.
+func (s *StorageService) writeRollups(ctx context.Context, granularity string, bucket time.Duration, from, to, computedAt time.Time) error {
+	// No else needed: early return pattern (nothing complete in the window)
+	if !from.IsZero() && !from.Before(to) {
+		return nil
+	}
+
+	window := bson.M{"$lt": to}
+	// No else needed: optional operation (bounded window after the first run)
+	if !from.IsZero() {
+		window["$gte"] = from
+	}
+
+	totals, err := s.aggregateTotals(ctx, window, bucket)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+
+	for key, t := range totals {
+		doc := rollupDocument{
+			ID:          fmt.Sprintf("%s:%s:%d", granularity, key.TenantID, key.Start.Unix()),
+			Granularity: granularity,
+			TenantID:    key.TenantID,
+			Start:       key.Start,
+			Totals:      *t,
+			ComputedAt:  computedAt,
+		}
+		err := s.retryOperation(ctx, "RollupMetrics.write", func() error {
+			_, opErr := s.rollups.ReplaceOne(ctx, bson.M{constants.MongoFieldID: doc.ID}, doc, options.Replace().SetUpsert(true))
+			return opErr
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return fmt.Errorf("failed to write %s metrics rollup: %w", granularity, err)
+		}
+	}
+
+	// Buckets of the window that no longer have sessions
+	stale := bson.M{
+		constants.MongoFieldGranularity: granularity,
+		constants.MongoFieldTimestamp:   window,
+		constants.MongoFieldComputedAt:  bson.M{"$lt": computedAt},
+	}
+	err = s.retryOperation(ctx, "RollupMetrics.cleanup", func() error {
+		_, opErr := s.rollups.DeleteMany(ctx, stale)
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to remove stale %s metrics rollups: %w", granularity, err)
+	}
+	return nil
+}
+
+// rollupWatermark returns the time before which all complete hours and days are
+// rolled up, or the zero time when rollups were never computed
+func (s *StorageService) rollupWatermark(ctx context.Context) (time.Time, error) {
+	var status rollupStatusDocument
+	err := s.rollups.FindOne(ctx, bson.M{constants.MongoFieldID: rollupStatusID}).Decode(&status)
+	// No else needed: early return pattern (no rollups yet)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read metrics rollup status: %w", err)
+	}
+	return status.Through, nil
+}
+
+// GetRollupMetrics returns the metrics of GetSessionMetrics, served from the
+// metrics rollups where they cover the time range: complete days and hours up to
+// the last rollup run come from rollups, the partial hours at the edges of the
+// range and the time since the last run are aggregated from the sessions. Without
+// rollups, or when reading them fails, the whole range is aggregated on demand.
+func (s *StorageService) GetRollupMetrics(startTime, endTime time.Time) (*Metrics, error) {
+	// No else needed: early return pattern (guard clause)
+	if endTime.Before(startTime) {
+		return nil, errors.New("end time must be after start time")
+	}
+
+	opStart := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_rollup_metrics"}).Observe(time.Since(opStart).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.MetricsTimeout)
+	defer cancel()
+
+	through, err := s.rollupWatermark(ctx)
+	// No else needed: early return pattern (fall back to on-demand aggregation)
+	if err != nil {
+		s.logger.Warn("Metrics rollups unavailable, aggregating on demand", "error", err)
+		return s.GetSessionMetrics(startTime, endTime)
+	}
+
+	// Rolled-up part of the range: [from, to)
+	from := ceilTime(startTime, time.Hour)
+	to := endTime.Truncate(time.Hour)
+	// No else needed: conditional assignment (rollups end at the last run)
+	if through.Before(to) {
+		to = through
+	}
+	// No else needed: early return pattern (no complete rolled-up hour in the range)
+	if !from.Before(to) {
+		return s.GetSessionMetrics(startTime, endTime)
+	}
+
+	total := &metricsTotals{}
+	// No else needed: optional operation (partial hour at the start of the range)
+	if startTime.Before(from) {
+		if err := s.addSessionTotals(ctx, total, bson.M{"$gte": startTime, "$lt": from}); err != nil {
+			return nil, err
+		}
+	}
+	// Partial hour at the end of the range and the time since the last run
+	// No else needed: early return pattern (guard clause)
+	if err := s.addSessionTotals(ctx, total, bson.M{"$gte": to, "$lte": endTime}); err != nil {
+		return nil, err
+	}
+
+	// Complete days from daily rollups, the hours around them from hourly rollups
+	dayFrom, dayTo := ceilTime(from, day), to.Truncate(day)
+	ranges := []rollupRange{{constants.RollupHourly, from, to}}
+	// No else needed: optional operation (range spans a complete day)
+	if dayFrom.Before(dayTo) {
+		ranges = []rollupRange{
+			{constants.RollupHourly, from, dayFrom},
+			{constants.RollupDaily, dayFrom, dayTo},
+			{constants.RollupHourly, dayTo, to},
+		}
+	}
+	for _, r := range ranges {
+		// No else needed: early return pattern (guard clause)
+		if err := s.addRollupTotals(ctx, total, r.granularity, r.from, r.to); err != nil {
+			return nil, err
+		}
+	}
+
+	return total.metrics(), nil
+}
+
+// addSessionTotals adds the totals of the sessions started in window to total
+func (s *StorageService) addSessionTotals(ctx context.Context, total *metricsTotals, window bson.M) error {
+	totals, err := s.aggregateTotals(ctx, window, 0)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	for _, t := range totals {
+		total.add(t)
+	}
+	return nil
+}
+
+// addRollupTotals adds the rollups of granularity in [from, to) visible to this
+// storage view to total
+func (s *StorageService) addRollupTotals(ctx context.Context, total *metricsTotals, granularity string, from, to time.Time) error {
+	// No else needed: early return pattern (empty range)
+	if !from.Before(to) {
+		return nil
+	}
+
+	filter := s.tenantFilter(bson.M{
+		constants.MongoFieldGranularity: granularity,
+		constants.MongoFieldTimestamp:   bson.M{"$gte": from, "$lt": to},
+	})
+	cursor, err := s.rollups.Find(ctx, filter, gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldTimestamp, Value: 1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to read %s metrics rollups: %w", granularity, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc rollupDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode metrics rollup: %w", err)
+		}
+		total.add(&doc.Totals)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+	return nil
+}
+
+// aggregateTotals aggregates the metrics of the sessions visible to this storage
+// view that started in window (a condition on the start time), per tenant and per
+// bucket of the given size. A zero bucket size aggregates per tenant only.
+func (s *StorageService) aggregateTotals(ctx context.Context, window bson.M, bucket time.Duration) (map[rollupKey]*metricsTotals, error) {
+	groupID := bson.M{"tid": "$" + constants.MongoFieldTenantID}
+	// No else needed: optional operation (bucketed aggregation for rollups)
+	if bucket > 0 {
+		// Bucket start: the start time rounded down to a multiple of the bucket size since the epoch
+		groupID["bucket"] = bson.M{"$subtract": bson.A{
+			"$" + constants.MongoFieldTimestamp,
+			bson.M{"$mod": bson.A{bson.M{"$toLong": "$" + constants.MongoFieldTimestamp}, bucket.Milliseconds()}},
+		}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: s.scope(bson.M{constants.MongoFieldTimestamp: window})}},
+		{{Key: "$group", Value: totalsGroup(groupID)}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate session metrics: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	totals := make(map[rollupKey]*metricsTotals)
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				TenantID string    `bson:"tid"`
+				Bucket   time.Time `bson:"bucket"`
+			} `bson:"_id"`
+			Totals metricsTotals `bson:",inline"`
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode metrics: %w", err)
+		}
+		t := row.Totals
+		totals[rollupKey{TenantID: row.ID.TenantID, Start: row.ID.Bucket.UTC()}] = &t
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := s.aggregateTagTotals(ctx, window, groupID, totals); err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.aggregateEndReasonTotals(ctx, window, groupID, totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// totalsGroup is a $group stage body computing the additive metricsTotals
+// (except tags and end reasons) of the sessions grouped by groupID
+func totalsGroup(groupID interface{}) bson.M {
+	return bson.M{
+		"_id":           groupID,
+		"sessions":      bson.M{"$sum": 1},
+		"active":        bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$type": "$" + constants.MongoFieldEndTime}, "missing"}}, 1, 0}}},
+		"adminAssisted": bson.M{"$sum": bson.M{"$cond": bson.A{"$" + constants.MongoFieldAdminAssisted, 1, 0}}},
+		"totalTokens":   bson.M{"$sum": "$" + constants.MongoFieldTotalTokens},
+		"maxRespTime":   bson.M{"$max": "$maxRespTime"},
+		"respTimeSum":   bson.M{"$sum": "$avgRespTime"},
+		"respTimeCount": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$in": bson.A{bson.M{"$type": "$avgRespTime"}, bson.A{"missing", "null"}}}, 0, 1}}},
+		"fbUp":          bson.M{"$sum": countFeedback(constants.FeedbackUp)},
+		"fbDown":        bson.M{"$sum": countFeedback(constants.FeedbackDown)},
+	}
+}
+
+// countFeedback is an aggregation expression counting the messages of a session
+// rated with rating
+func countFeedback(rating string) bson.M {
+	return bson.M{"$size": bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$" + constants.MongoFieldMessages, bson.A{}}},
+		"cond":  bson.M{"$eq": bson.A{"$$this.feedback.rating", rating}},
+	}}}
+}
+
+// aggregateTagTotals adds the number of sessions per tag to the totals of
+// aggregateTotals, grouped the same way
+func (s *StorageService) aggregateTagTotals(ctx context.Context, window, groupID bson.M, totals map[rollupKey]*metricsTotals) error {
+	tagGroupID := bson.M{"tag": "$" + constants.MongoFieldTags}
+	for k, v := range groupID {
+		tagGroupID[k] = v
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: s.scope(bson.M{
+			constants.MongoFieldTimestamp: window,
+			constants.MongoFieldTags:      bson.M{"$exists": true},
+		})}},
+		{{Key: "$unwind", Value: "$" + constants.MongoFieldTags}},
+		{{Key: "$group", Value: bson.M{"_id": tagGroupID, "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to count session tags: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				TenantID string    `bson:"tid"`
+				Bucket   time.Time `bson:"bucket"`
+				Tag      string    `bson:"tag"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&row); err != nil {
+			return fmt.Errorf("failed to decode tag count: %w", err)
+		}
+		key := rollupKey{TenantID: row.ID.TenantID, Start: row.ID.Bucket.UTC()}
+		t, ok := totals[key]
+		// No else needed: conditional assignment (tagged sessions are always counted, defensive)
+		if !ok {
+			t = &metricsTotals{}
+			totals[key] = t
+		}
+		// No else needed: conditional assignment (lazy map creation)
+		if t.Tags == nil {
+			t.Tags = make(map[string]int)
+		}
+		t.Tags[row.ID.Tag] = row.Count
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+	return nil
+}
+
+// aggregateEndReasonTotals adds the number of force-ended sessions per reason
+// code to the totals of aggregateTotals, grouped the same way
+func (s *StorageService) aggregateEndReasonTotals(ctx context.Context, window, groupID bson.M, totals map[rollupKey]*metricsTotals) error {
+	reasonGroupID := bson.M{"reason": "$" + constants.MongoFieldEndReason}
+	for k, v := range groupID {
+		reasonGroupID[k] = v
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: s.scope(bson.M{
+			constants.MongoFieldTimestamp: window,
+			constants.MongoFieldEndReason: bson.M{"$exists": true},
+		})}},
+		{{Key: "$group", Value: bson.M{"_id": reasonGroupID, "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to count session end reasons: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				TenantID string    `bson:"tid"`
+				Bucket   time.Time `bson:"bucket"`
+				Reason   string    `bson:"reason"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&row); err != nil {
+			return fmt.Errorf("failed to decode end reason count: %w", err)
+		}
+		key := rollupKey{TenantID: row.ID.TenantID, Start: row.ID.Bucket.UTC()}
+		t, ok := totals[key]
+		// No else needed: conditional assignment (force-ended sessions are always counted, defensive)
+		if !ok {
+			t = &metricsTotals{}
+			totals[key] = t
+		}
+		// No else needed: conditional assignment (lazy map creation)
+		if t.EndReasons == nil {
+			t.EndReasons = make(map[string]int)
+		}
+		t.EndReasons[row.ID.Reason] = row.Count
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+	return nil
+}
+
+// ceilTime rounds t up to a multiple of d
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	truncated := t.Truncate(d)
+	// No else needed: conditional assignment (t is not aligned)
+	if truncated.Before(t) {
+		truncated = truncated.Add(d)
+	}
+	return truncated
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRollupTestStorage creates a test storage service with its own
+// metrics_rollup collection, so rollup status does not leak between tests
+func setupRollupTestStorage(t *testing.T) (*StorageService, func()) {
+	service, cleanup := setupTestStorage(t, nil)
+	if service == nil {
+		return nil, cleanup
+	}
+
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.rollups = c })
+	return service, cleanup
+}
+
+// createSessionAt stores an ended session of tenantID started at start
+func createSessionAt(t *testing.T, service *StorageService, id, tenantID string, start time.Time, tokens int) {
+	t.Helper()
+	end := start.Add(10 * time.Minute)
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:           id,
+		UserID:       "user-1",
+		TenantID:     tenantID,
+		Messages:     []*session.Message{},
+		StartTime:    start,
+		LastActivity: end,
+		EndTime:      &end,
+		TotalTokens:  tokens,
+	}))
+}
+
+func TestCeilTime(t *testing.T) {
+	base := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, base, ceilTime(base, time.Hour))
+	assert.Equal(t, base.Add(time.Hour), ceilTime(base.Add(time.Second), time.Hour))
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), ceilTime(base, day))
+}
+
+func TestMetricsTotals(t *testing.T) {
+	total := &metricsTotals{}
+	total.add(&metricsTotals{Sessions: 2, TotalTokens: 30, MaxResponseTime: 900, ResponseTimeSum: 1000, ResponseTimeCount: 2, FeedbackUp: 2, FeedbackDown: 1, Tags: map[string]int{"billing": 2}, EndReasons: map[string]int{"abuse": 1}})
+	total.add(&metricsTotals{Sessions: 1, ActiveSessions: 1, TotalTokens: 5, MaxResponseTime: 400, ResponseTimeSum: 200, ResponseTimeCount: 1, FeedbackUp: 1, Tags: map[string]int{"billing": 1, "urgent": 1}, EndReasons: map[string]int{"abuse": 1, "stale": 1}})
+
+	m := total.metrics()
+	assert.Equal(t, 3, m.TotalSessions)
+	assert.Equal(t, 1, m.ActiveSessions)
+	assert.Equal(t, 35, m.TotalTokens)
+	assert.Equal(t, int64(900), m.MaxResponseTime)
+	assert.Equal(t, int64(400), m.AvgResponseTime)
+	assert.Equal(t, map[string]int{"billing": 3, "urgent": 1}, m.TagCounts)
+	assert.Equal(t, map[string]int{"abuse": 2, "stale": 1}, m.EndReasonCounts)
+	assert.Equal(t, 3, m.FeedbackUp)
+	assert.Equal(t, 1, m.FeedbackDown)
+	assert.Equal(t, 0.75, m.FeedbackPositiveRate)
+
+	assert.Equal(t, map[string]int{}, (&metricsTotals{}).metrics().TagCounts)
+	assert.Zero(t, (&metricsTotals{}).metrics().FeedbackPositiveRate)
+}
+
+func TestTopTags(t *testing.T) {
+	counts := map[string]int{"a": 1, "b": 3, "c": 3, "d": 2}
+	assert.Equal(t, map[string]int{"b": 3, "c": 3}, topTags(counts, 2))
+	assert.Equal(t, counts, topTags(counts, 10))
+}
+
+func TestGetRollupMetrics_MatchesOnDemand(t *testing.T) {
+	service, cleanup := setupRollupTestStorage(t)
+	defer cleanup()
+
+	// Rollups cover everything before 12:00; the 12:10 session is aggregated on demand
+	now := time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC)
+	createSessionAt(t, service, "two-days-ago", "tenant-a", now.Add(-50*time.Hour), 100)
+	createSessionAt(t, service, "this-morning", "", now.Add(-3*time.Hour-30*time.Minute), 50)
+	createSessionAt(t, service, "range-start", "", now.Add(-72*time.Hour+20*time.Minute), 7)
+	_, err := service.AddSessionTags("this-morning", []string{"billing"})
+	require.NoError(t, err)
+	require.NoError(t, service.RecordEndReason("two-days-ago", constants.EndReasonAbuse, "admin-1"))
+
+	require.NoError(t, service.RollupMetrics(now))
+	createSessionAt(t, service, "after-rollup", "", now.Add(-20*time.Minute), 10)
+
+	start, end := now.Add(-72*time.Hour+15*time.Minute), now
+	want, err := service.GetSessionMetrics(start, end)
+	require.NoError(t, err)
+	got, err := service.GetRollupMetrics(start, end)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 4, got.TotalSessions)
+	assert.Equal(t, 167, got.TotalTokens)
+	assert.Equal(t, map[string]int{"billing": 1}, got.TagCounts)
+	assert.Equal(t, map[string]int{constants.EndReasonAbuse: 1}, got.EndReasonCounts)
+
+	// Tenant views only see their tenant's rollups
+	tenant, err := service.ForTenant("tenant-a").GetRollupMetrics(start, end)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tenant.TotalSessions)
+	assert.Equal(t, 100, tenant.TotalTokens)
+
+	// A second run recomputes the lookback window without duplicating buckets
+	require.NoError(t, service.RollupMetrics(now.Add(time.Hour)))
+	got, err = service.GetRollupMetrics(start, end)
+	require.NoError(t, err)
+	assert.Equal(t, 4, got.TotalSessions)
+}
+
+func TestGetRollupMetrics_FallsBackWithoutRollups(t *testing.T) {
+	service, cleanup := setupRollupTestStorage(t)
+	defer cleanup()
+
+	now := time.Now()
+	createSessionAt(t, service, "session-1", "", now.Add(-5*time.Hour), 42)
+
+	got, err := service.GetRollupMetrics(now.Add(-24*time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.TotalSessions)
+	assert.Equal(t, 42, got.TotalTokens)
+
+	_, err = service.GetRollupMetrics(now, now.Add(-time.Hour))
+	assert.Error(t, err)
+}
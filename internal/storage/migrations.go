@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionSchemaVersion is the schema version of the session documents this
+// build writes: the version of the last entry of sessionMigrations
+const SessionSchemaVersion = 3
+
+// sessionMigration upgrades session documents below version. Migrations run in
+// version order and must be idempotent: a document is migrated again when the
+// runner stops before recording its new version.
+type sessionMigration struct {
+	version     int
+	description string
+	// migrate returns the fields to set on doc, or nil when doc needs no change
+	migrate func(doc bson.M) bson.M
+}
+
+// sessionMigrations lists every schema change of session documents, oldest
+// first. Add new migrations at the end and bump SessionSchemaVersion.
+var sessionMigrations = []sessionMigration{
+	{
+		version:     1,
+		description: "Backfill lastActivity from the end or start time",
+		migrate: func(doc bson.M) bson.M {
+			// No else needed: early return pattern (already set)
+			if doc[constants.MongoFieldLastActivity] != nil {
+				return nil
+			}
+			// No else needed: early return pattern (ended sessions were last active when they ended)
+			if end := doc[constants.MongoFieldEndTime]; end != nil {
+				return bson.M{constants.MongoFieldLastActivity: end}
+			}
+			// No else needed: early return pattern (no start time to copy)
+			if start := doc[constants.MongoFieldTimestamp]; start != nil {
+				return bson.M{constants.MongoFieldLastActivity: start}
+			}
+			return nil
+		},
+	},
+	{
+		version:     2,
+		description: "Store missing message lists as empty arrays",
+		migrate: func(doc bson.M) bson.M {
+			// No else needed: early return pattern (already a list)
+			if doc[constants.MongoFieldMessages] != nil {
+				return nil
+			}
+			return bson.M{constants.MongoFieldMessages: bson.A{}}
+		},
+	},
+	{
+		version:     3,
+		description: "Backfill share link expiry from the start time",
+		migrate: func(doc bson.M) bson.M {
+			// No else needed: early return pattern (not shared, or the expiry is set)
+			if doc[constants.MongoFieldShareToken] == nil || doc[constants.MongoFieldShareExpires] != nil {
+				return nil
+			}
+			start, ok := doc[constants.MongoFieldTimestamp].(primitive.DateTime)
+			// No else needed: early return pattern (no start time to count from)
+			if !ok {
+				return nil
+			}
+			return bson.M{constants.MongoFieldShareExpires: start.Time().Add(constants.DefaultShareLinkTTL).UTC()}
+		},
+	},
+}
+
+// MigrationResult reports one migration of a MigrationReport
+type MigrationResult struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	Pending     int64  `json:"pending"` // documents below this version when the migration ran
+	Changed     int64  `json:"changed"` // documents whose fields were (or in a dry run would be) changed
+}
+
+// MigrationReport reports a run of the session schema migrations
+type MigrationReport struct {
+	SchemaVersion int               `json:"schema_version"`
+	DryRun        bool              `json:"dry_run"`
+	Migrations    []MigrationResult `json:"migrations"`
+}
+
+// PendingDocuments returns the number of documents below the current schema version
+func (r *MigrationReport) PendingDocuments() int64 {
+	var pending int64
+	for _, m := range r.Migrations {
+		// No else needed: optional operation (every document below version 1 is below the rest)
+		if m.Pending > pending {
+			pending = m.Pending
+		}
+	}
+	return pending
+}
+
+// belowVersion matches session documents whose schema version is below version,
+// including documents written before schemaVersion existed
+func belowVersion(version int) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{constants.MongoFieldSchemaVersion: bson.M{"$lt": version}},
+		bson.M{constants.MongoFieldSchemaVersion: bson.M{"$exists": false}},
+	}}
+}
+
+// MigrateSessions upgrades every session document, soft-deleted ones included,
+// to SessionSchemaVersion by running the pending migrations in order. Each
+// migrated document records the version it reached in schemaVersion. With
+// dryRun, nothing is written and the report tells which documents each
+// migration would change; later migrations then see the documents unmigrated.
+// The report counts the documents of every datastore (see SetResidency).
+func (s *StorageService) MigrateSessions(ctx context.Context, dryRun bool) (*MigrationReport, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "migrate_sessions"}).Observe(time.Since(start).Seconds())
+	}()
+
+	report := &MigrationReport{SchemaVersion: SessionSchemaVersion, DryRun: dryRun}
+	for _, migration := range sessionMigrations {
+		result, err := s.runMigration(ctx, migration, dryRun)
+		// No else needed: early return pattern (later migrations depend on this one)
+		if err != nil {
+			return report, fmt.Errorf("migration %d (%s): %w", migration.version, migration.description, err)
+		}
+		report.Migrations = append(report.Migrations, *result)
+
+		// No else needed: optional operation (log only when documents were migrated)
+		if !dryRun && result.Pending > 0 {
+			s.logger.Info("Session schema migration applied",
+				"version", migration.version,
+				"description", migration.description,
+				"migrated", result.Pending,
+				"changed", result.Changed)
+		}
+	}
+
+	for _, view := range s.datastores {
+		viewReport, err := view.MigrateSessions(ctx, dryRun)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return report, fmt.Errorf("datastore %s: %w", view.datastore, err)
+		}
+		report.add(viewReport)
+	}
+	return report, nil
+}
+
+// add adds the document counts of other, a report of the same migrations in
+// another datastore, to r
+func (r *MigrationReport) add(other *MigrationReport) {
+	for i := range r.Migrations {
+		// No else needed: early return pattern (guard clause)
+		if i >= len(other.Migrations) {
+			return
+		}
+		r.Migrations[i].Pending += other.Migrations[i].Pending
+		r.Migrations[i].Changed += other.Migrations[i].Changed
+	}
+}
+
+// runMigration applies migration to the documents below its version
+func (s *StorageService) runMigration(ctx context.Context, migration sessionMigration, dryRun bool) (*MigrationResult, error) {
+	result := &MigrationResult{Version: migration.version, Description: migration.description}
+	filter := belowVersion(migration.version)
+
+	cursor, err := s.collection.Find(ctx, filter, gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldID, Value: 1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find documents: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode session document: %w", err)
+		}
+		result.Pending++
+
+		set := migration.migrate(doc)
+		// No else needed: optional operation (count documents the migration changes)
+		if set != nil {
+			result.Changed++
+		}
+		// No else needed: optional operation (dry runs only report)
+		if dryRun {
+			continue
+		}
+
+		// No else needed: conditional initialization (only the version changes)
+		if set == nil {
+			set = bson.M{}
+		}
+		set[constants.MongoFieldSchemaVersion] = migration.version
+		// The version guard skips documents another replica migrated meanwhile
+		docFilter := belowVersion(migration.version)
+		docFilter[constants.MongoFieldID] = doc[constants.MongoFieldID]
+		err := s.retryOperation(ctx, "MigrateSessions", func() error {
+			_, opErr := s.collection.UpdateOne(ctx, docFilter, bson.M{"$set": set})
+			return opErr
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate session %v: %w", doc[constants.MongoFieldID], err)
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSessionMigrations_Sequential(t *testing.T) {
+	require.NotEmpty(t, sessionMigrations)
+	for i, migration := range sessionMigrations {
+		assert.Equal(t, i+1, migration.version, "migration versions must be 1, 2, 3, ...")
+		assert.NotEmpty(t, migration.description)
+	}
+	assert.Equal(t, SessionSchemaVersion, sessionMigrations[len(sessionMigrations)-1].version)
+}
+
+func TestMigrateSessions_UpgradesLegacyDocuments(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// A shared document written before lastActivity, msgs, shareExpTs and schemaVersion were stored
+	start := time.Now().Add(-time.Hour).UTC().Truncate(time.Millisecond)
+	_, err := service.collection.InsertOne(ctx, bson.M{
+		constants.MongoFieldID:         "legacy-session",
+		constants.MongoFieldUserID:     "user-1",
+		constants.MongoFieldTimestamp:  start,
+		constants.MongoFieldShareToken: "legacy-token",
+	})
+	require.NoError(t, err)
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID: "current-session", UserID: "user-1", StartTime: start, LastActivity: start, IsActive: true,
+	}))
+
+	report, err := service.MigrateSessions(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(1), report.PendingDocuments(), "new sessions are written at the current version")
+	require.Len(t, report.Migrations, SessionSchemaVersion)
+	for _, result := range report.Migrations {
+		assert.Equal(t, int64(1), result.Changed, "migration %d", result.Version)
+	}
+
+	report, err = service.MigrateSessions(ctx, false)
+	require.NoError(t, err)
+	assert.False(t, report.DryRun)
+
+	var doc bson.M
+	require.NoError(t, service.collection.FindOne(ctx, bson.M{constants.MongoFieldID: "legacy-session"}).Decode(&doc))
+	assert.EqualValues(t, SessionSchemaVersion, doc[constants.MongoFieldSchemaVersion])
+	assert.Equal(t, doc[constants.MongoFieldTimestamp], doc[constants.MongoFieldLastActivity])
+	assert.Equal(t, bson.A{}, doc[constants.MongoFieldMessages])
+	shareExpires, ok := doc[constants.MongoFieldShareExpires].(primitive.DateTime)
+	require.True(t, ok, "share link expiry backfilled")
+	assert.True(t, start.Add(constants.DefaultShareLinkTTL).Equal(shareExpires.Time()))
+
+	report, err = service.MigrateSessions(ctx, true)
+	require.NoError(t, err)
+	assert.Zero(t, report.PendingDocuments(), "migrations run once")
+}
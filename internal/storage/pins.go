@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrPinnedMessageNotFound is returned when a pin targets a message index the session does not have
+	ErrPinnedMessageNotFound = errors.New("message not found")
+	// ErrTooManyPins is returned when pinning would exceed constants.MaxPinnedMessages
+	ErrTooManyPins = errors.New("too many pinned messages")
+)
+
+// PinDocument marks the message at Index of the session as pinned
+type PinDocument struct {
+	Index    int       `bson:"idx" json:"idx"`
+	PinnedBy string    `bson:"by" json:"by"`
+	PinnedAt time.Time `bson:"ts" json:"ts"`
+}
+
+// pinsFromDocuments converts stored pins
+func pinsFromDocuments(docs []PinDocument) []session.Pin {
+	// No else needed: early return pattern (no pins)
+	if len(docs) == 0 {
+		return nil
+	}
+	pins := make([]session.Pin, len(docs))
+	for i, doc := range docs {
+		pins[i] = session.Pin{MessageIndex: doc.Index, PinnedBy: doc.PinnedBy, PinnedAt: doc.PinnedAt}
+	}
+	return pins
+}
+
+// PinUserMessage pins the message at index of a session owned by userID, or
+// shared with them for writing, and returns the session's pins. Pinning a
+// pinned message keeps its pin. Returns ErrSessionNotFound when the session
+// does not exist or the user cannot write to it.
+func (s *StorageService) PinUserMessage(sessionID, userID string, index int) ([]session.Pin, error) {
+	return s.pinMessage(sessionID, s.accessibleBy(sessionID, userID, constants.GrantAccessWrite), index, userID)
+}
+
+// PinMessage pins the message at index of any session visible to the storage
+// view on behalf of adminID and returns the session's pins
+func (s *StorageService) PinMessage(sessionID string, index int, adminID string) ([]session.Pin, error) {
+	return s.pinMessage(sessionID, s.scope(bson.M{constants.MongoFieldID: sessionID}), index, adminID)
+}
+
+// UnpinUserMessage removes the pin of the message at index of a session owned
+// by userID, or shared with them for writing, and returns the session's
+// remaining pins
+func (s *StorageService) UnpinUserMessage(sessionID, userID string, index int) ([]session.Pin, error) {
+	return s.unpinMessage(sessionID, s.accessibleBy(sessionID, userID, constants.GrantAccessWrite), index)
+}
+
+// UnpinMessage removes the pin of the message at index of any session visible
+// to the storage view and returns the session's remaining pins
+func (s *StorageService) UnpinMessage(sessionID string, index int) ([]session.Pin, error) {
+	return s.unpinMessage(sessionID, s.scope(bson.M{constants.MongoFieldID: sessionID}), index)
+}
+
+// pinMessage pins the message at index of the session matching filter. The
+// message and limit checks are part of the filter so concurrent pins cannot
+// pin a message twice or exceed the limit.
+func (s *StorageService) pinMessage(sessionID string, filter bson.M, index int, pinnedBy string) ([]session.Pin, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+	// No else needed: early return pattern (guard clause)
+	if index < 0 {
+		return nil, ErrPinnedMessageNotFound
+	}
+	s.flushPending(sessionID)
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "pin_message"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	message := fmt.Sprintf("%s.%d", constants.MongoFieldMessages, index)
+	lastPin := fmt.Sprintf("%s.%d", constants.MongoFieldPins, constants.MaxPinnedMessages-1)
+	pinnable := bson.M{
+		message:                           bson.M{"$exists": true},
+		constants.MongoFieldPins + ".idx": bson.M{"$ne": index},
+		lastPin:                           bson.M{"$exists": false},
+	}
+	for k, v := range filter {
+		pinnable[k] = v
+	}
+	pin := PinDocument{Index: index, PinnedBy: pinnedBy, PinnedAt: time.Now()}
+	update := bson.M{"$push": bson.M{constants.MongoFieldPins: pin}}
+
+	pins, err := s.updatePins(ctx, "PinMessage", pinnable, update)
+	// No else needed: early return pattern (guard clause)
+	if !errors.Is(err, ErrSessionNotFound) {
+		return pins, err
+	}
+
+	// Nothing matched: tell a missing session or message from one already
+	// pinned or with too many pins
+	var doc SessionDocument
+	findErr := s.collection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{
+		constants.MongoFieldPins:     1,
+		constants.MongoFieldMessages: bson.M{"$slice": bson.A{index, 1}},
+	})).Decode(&doc)
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(findErr, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	// No else needed: early return pattern (guard clause)
+	if findErr != nil {
+		return nil, fmt.Errorf("failed to pin message: %w", findErr)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(doc.Messages) == 0 {
+		return nil, ErrPinnedMessageNotFound
+	}
+	for _, existing := range doc.Pins {
+		// No else needed: early return pattern (already pinned)
+		if existing.Index == index {
+			return pinsFromDocuments(doc.Pins), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: at most %d per session", ErrTooManyPins, constants.MaxPinnedMessages)
+}
+
+// unpinMessage removes the pin of the message at index of the session matching filter
+func (s *StorageService) unpinMessage(sessionID string, filter bson.M, index int) ([]session.Pin, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "unpin_message"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	update := bson.M{"$pull": bson.M{constants.MongoFieldPins: bson.M{"idx": index}}}
+	return s.updatePins(ctx, "UnpinMessage", filter, update)
+}
+
+// updatePins applies a pin update to the session matching filter and returns
+// the session's pins after the update
+func (s *StorageService) updatePins(ctx context.Context, operation string, filter, update bson.M) ([]session.Pin, error) {
+	findOpts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{constants.MongoFieldPins: 1})
+
+	var doc SessionDocument
+	err := s.retryOperation(ctx, operation, func() error {
+		return s.collection.FindOneAndUpdate(ctx, filter, update, findOpts).Decode(&doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update pinned messages: %w", err)
+	}
+	pins := pinsFromDocuments(doc.Pins)
+	// No else needed: conditional assignment (sessions without pins)
+	if pins == nil {
+		pins = []session.Pin{}
+	}
+	return pins, nil
+}
+
+// sortMessagesByTime sorts msgs by timestamp, keeping the order of messages
+// with equal timestamps, and returns pins moved along with their messages.
+// Pins of messages msgs does not have, and second pins of a message, are
+// dropped, as are pins over constants.MaxPinnedMessages.
+func sortMessagesByTime(msgs []MessageDocument, pins []PinDocument) ([]MessageDocument, []PinDocument) {
+	order := make([]int, len(msgs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return msgs[order[i]].Timestamp.Before(msgs[order[j]].Timestamp) })
+
+	sorted := make([]MessageDocument, len(msgs))
+	moved := make([]int, len(msgs))
+	for newIndex, oldIndex := range order {
+		sorted[newIndex] = msgs[oldIndex]
+		moved[oldIndex] = newIndex
+	}
+
+	// Never nil: $push fails on a null field
+	kept := make([]PinDocument, 0, len(pins))
+	seen := make(map[int]bool, len(pins))
+	for _, pin := range pins {
+		// No else needed: optional operation (skip pins of missing messages)
+		if pin.Index < 0 || pin.Index >= len(msgs) || seen[moved[pin.Index]] || len(kept) == constants.MaxPinnedMessages {
+			continue
+		}
+		pin.Index = moved[pin.Index]
+		seen[pin.Index] = true
+		kept = append(kept, pin)
+	}
+	return sorted, kept
+}
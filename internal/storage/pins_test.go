@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortMessagesByTime(t *testing.T) {
+	now := time.Now()
+	msgs := []MessageDocument{
+		{Content: "b", Timestamp: now.Add(2 * time.Minute)},
+		{Content: "d", Timestamp: now.Add(4 * time.Minute)},
+		{Content: "a", Timestamp: now.Add(time.Minute)},
+		{Content: "c", Timestamp: now.Add(2 * time.Minute)},
+	}
+	pins := []PinDocument{
+		{Index: 1, PinnedBy: "user-1"},
+		{Index: 7, PinnedBy: "user-1"},
+		{Index: 2, PinnedBy: "admin-1"},
+		{Index: 1, PinnedBy: "admin-1"},
+	}
+
+	sorted, moved := sortMessagesByTime(msgs, pins)
+	var contents []string
+	for _, msg := range sorted {
+		contents = append(contents, msg.Content)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, contents)
+	assert.Equal(t, []PinDocument{
+		{Index: 3, PinnedBy: "user-1"},
+		{Index: 0, PinnedBy: "admin-1"},
+	}, moved, "pins follow their messages; missing and duplicate ones are dropped")
+
+	_, moved = sortMessagesByTime(nil, nil)
+	assert.NotNil(t, moved)
+}
+
+func TestPinMessages(t *testing.T) {
+	service, cleanup := setupTestStorage(t, []byte("12345678901234567890123456789012"))
+	defer cleanup()
+
+	now := time.Now()
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:        "session-1",
+		UserID:    "user-1",
+		StartTime: now,
+		IsActive:  true,
+		Messages: []*session.Message{
+			{Content: "hello", Timestamp: now, Sender: "user"},
+			{Content: "the answer", Timestamp: now.Add(time.Second), Sender: "ai"},
+		},
+	}))
+
+	pins, err := service.PinUserMessage("session-1", "user-1", 1)
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	assert.Equal(t, 1, pins[0].MessageIndex)
+	assert.Equal(t, "user-1", pins[0].PinnedBy)
+
+	// Pinning a pinned message keeps the first pin
+	pins, err = service.PinMessage("session-1", 1, "admin-1")
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	assert.Equal(t, "user-1", pins[0].PinnedBy)
+
+	pins, err = service.PinMessage("session-1", 0, "admin-1")
+	require.NoError(t, err)
+	assert.Len(t, pins, 2)
+
+	// Pins are returned with the session, in pin order
+	sess, err := service.GetSession("session-1")
+	require.NoError(t, err)
+	pinned := sess.PinnedMessages()
+	require.Len(t, pinned, 2)
+	assert.Equal(t, "the answer", pinned[0].Message.Content)
+	assert.Equal(t, "hello", pinned[1].Message.Content)
+
+	_, err = service.PinUserMessage("session-1", "user-1", 2)
+	assert.ErrorIs(t, err, ErrPinnedMessageNotFound)
+	_, err = service.PinUserMessage("session-1", "user-2", 0)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.ForTenant("tenant-b").PinMessage("session-1", 0, "admin-1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	pins, err = service.UnpinUserMessage("session-1", "user-1", 1)
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	assert.Equal(t, 0, pins[0].MessageIndex)
+	pins, err = service.UnpinMessage("session-1", 0)
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+	assert.NotNil(t, pins)
+}
+
+func TestPinMessages_Limit(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	messages := make([]*session.Message, constants.MaxPinnedMessages+1)
+	for i := range messages {
+		messages[i] = &session.Message{Content: "message", Timestamp: now, Sender: "user"}
+	}
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:        "session-1",
+		UserID:    "user-1",
+		StartTime: now,
+		IsActive:  true,
+		Messages:  messages,
+	}))
+
+	for i := 0; i < constants.MaxPinnedMessages; i++ {
+		_, err := service.PinUserMessage("session-1", "user-1", i)
+		require.NoError(t, err)
+	}
+	_, err := service.PinUserMessage("session-1", "user-1", constants.MaxPinnedMessages)
+	assert.ErrorIs(t, err, ErrTooManyPins)
+}
+
+func TestMergeUserSessions_MovesPins(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now().Truncate(time.Millisecond)
+	ended := now.Add(-time.Hour)
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:        "target",
+		UserID:    "user-1",
+		StartTime: now.Add(-2 * time.Hour),
+		EndTime:   &ended,
+		Messages: []*session.Message{
+			{Content: "first", Timestamp: now.Add(-2 * time.Hour), Sender: "user"},
+			{Content: "third", Timestamp: now.Add(-90 * time.Minute), Sender: "ai"},
+		},
+	}))
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:        "source",
+		UserID:    "user-1",
+		StartTime: now.Add(-100 * time.Minute),
+		IsActive:  true,
+		Messages: []*session.Message{
+			{Content: "second", Timestamp: now.Add(-100 * time.Minute), Sender: "user"},
+		},
+	}))
+	_, err := service.PinUserMessage("target", "user-1", 1)
+	require.NoError(t, err)
+	_, err = service.PinUserMessage("source", "user-1", 0)
+	require.NoError(t, err)
+
+	_, err = service.MergeUserSessions("target", "source", "user-1", now)
+	require.NoError(t, err)
+
+	merged, err := service.GetSession("target")
+	require.NoError(t, err)
+	var contents []string
+	for _, pinned := range merged.PinnedMessages() {
+		contents = append(contents, pinned.Message.Content)
+	}
+	assert.Equal(t, []string{"third", "second"}, contents)
+}
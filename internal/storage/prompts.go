@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ensurePromptIndexes creates the indexes for the prompt_templates collection
+func (s *StorageService) ensurePromptIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: constants.MongoFieldTenantID, Value: 1}},
+			Options: options.Index().SetName(constants.IndexPromptTenant),
+		},
+	}
+
+	_, err := s.prompts.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create prompt template indexes: %w", err)
+	}
+	return nil
+}
+
+// GetPromptTemplate returns a stored prompt template. On a tenant view only
+// templates of that tenant are visible; prompt.ErrTemplateNotFound is returned otherwise.
+func (s *StorageService) GetPromptTemplate(id string) (*prompt.Template, error) {
+	// No else needed: early return pattern (guard clause)
+	if id == "" {
+		return nil, prompt.ErrTemplateNotFound
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_prompt_template"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var t prompt.Template
+	err := s.retryOperation(ctx, "GetPromptTemplate", func() error {
+		return s.prompts.FindOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id})).Decode(&t)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, prompt.ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+	return &t, nil
+}
+
+// ListPromptTemplates lists the stored prompt templates visible through this
+// service, most recently updated first
+func (s *StorageService) ListPromptTemplates() ([]*prompt.Template, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_prompt_templates"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	cursor, err := s.prompts.Find(ctx, s.tenantFilter(bson.M{}), gomongo.QueryOptions{
+		Sort: bson.D{{Key: "mt", Value: -1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	templates := make([]*prompt.Template, 0)
+	for cursor.Next(ctx) {
+		var t prompt.Template
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&t); err != nil {
+			return nil, fmt.Errorf("failed to decode prompt template: %w", err)
+		}
+		templates = append(templates, &t)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return templates, nil
+}
+
+// CreatePromptTemplate stores a new prompt template. The ID and timestamps are
+// generated, and on a tenant view the template is owned by that tenant.
+func (s *StorageService) CreatePromptTemplate(t *prompt.Template) error {
+	// No else needed: early return pattern (guard clause)
+	if t == nil {
+		return prompt.ErrInvalidTemplate
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := prompt.Validate(t.Name, t.Content); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "create_prompt_template"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	t.ID = primitive.NewObjectID().Hex()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	t.ReadOnly = false
+	// No else needed: optional operation (unscoped callers choose the tenant)
+	if s.tenantScoped {
+		t.TenantID = s.tenantID
+	}
+
+	err := s.retryOperation(ctx, "CreatePromptTemplate", func() error {
+		_, err := s.prompts.InsertOne(ctx, t)
+		return err
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create prompt template: %w", err)
+	}
+	return nil
+}
+
+// UpdatePromptTemplate replaces the name and content of a stored prompt template
+// and returns the updated template
+func (s *StorageService) UpdatePromptTemplate(id, name, content string) (*prompt.Template, error) {
+	// No else needed: early return pattern (guard clause)
+	if err := prompt.Validate(name, content); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "update_prompt_template"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"nm": name, "content": content, "mt": time.Now().UTC()}}
+	findOpts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var t prompt.Template
+	err := s.retryOperation(ctx, "UpdatePromptTemplate", func() error {
+		return s.prompts.FindOneAndUpdate(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id}), update, findOpts).Decode(&t)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, prompt.ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to update prompt template: %w", err)
+	}
+	return &t, nil
+}
+
+// DeletePromptTemplate removes a stored prompt template. Sessions that selected
+// it continue without a system prompt.
+func (s *StorageService) DeletePromptTemplate(id string) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "delete_prompt_template"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var deleted int64
+	err := s.retryOperation(ctx, "DeletePromptTemplate", func() error {
+		result, opErr := s.prompts.DeleteOne(ctx, s.tenantFilter(bson.M{constants.MongoFieldID: id}))
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			deleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to delete prompt template: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if deleted == 0 {
+		return prompt.ErrTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePromptTemplate_Invalid(t *testing.T) {
+	svc := &StorageService{}
+	assert.ErrorIs(t, svc.CreatePromptTemplate(nil), prompt.ErrInvalidTemplate)
+	assert.ErrorIs(t, svc.CreatePromptTemplate(&prompt.Template{Name: "Bad", Content: "{{email}}"}), prompt.ErrInvalidTemplate)
+	_, err := svc.UpdatePromptTemplate("id", "", "content")
+	assert.ErrorIs(t, err, prompt.ErrInvalidTemplate)
+}
+
+func TestPromptTemplates_CRUD(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.prompts = c })
+
+	acme := service.ForTenant("acme")
+	tmpl := &prompt.Template{Name: "Support", Content: "Help {{user_name}}", TenantID: "other"}
+	require.NoError(t, acme.CreatePromptTemplate(tmpl))
+	assert.NotEmpty(t, tmpl.ID)
+	assert.Equal(t, "acme", tmpl.TenantID, "tenant views own the templates they create")
+	require.NoError(t, service.ForTenant("").CreatePromptTemplate(&prompt.Template{Name: "Default", Content: "Hi"}))
+
+	got, err := acme.GetPromptTemplate(tmpl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Help {{user_name}}", got.Content)
+
+	// Other tenants cannot see, change or delete the template
+	other := service.ForTenant("")
+	_, err = other.GetPromptTemplate(tmpl.ID)
+	assert.ErrorIs(t, err, prompt.ErrTemplateNotFound)
+	_, err = other.UpdatePromptTemplate(tmpl.ID, "Taken", "Taken")
+	assert.ErrorIs(t, err, prompt.ErrTemplateNotFound)
+	assert.ErrorIs(t, other.DeletePromptTemplate(tmpl.ID), prompt.ErrTemplateNotFound)
+
+	listed, err := acme.ListPromptTemplates()
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	all, err := service.ListPromptTemplates()
+	require.NoError(t, err)
+	assert.Len(t, all, 2, "the unscoped service sees every tenant")
+
+	updated, err := acme.UpdatePromptTemplate(tmpl.ID, "Support v2", "Assist {{user_name}} at {{tenant}}")
+	require.NoError(t, err)
+	assert.Equal(t, "Support v2", updated.Name)
+	assert.True(t, !updated.UpdatedAt.Before(updated.CreatedAt))
+
+	require.NoError(t, acme.DeletePromptTemplate(tmpl.ID))
+	_, err = acme.GetPromptTemplate(tmpl.ID)
+	assert.ErrorIs(t, err, prompt.ErrTemplateNotFound)
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MarkMessagesRead stores the read receipt of the admin messages at indexes of
+// a session. Returns ErrMessageNotFound when the session does not exist or any
+// of the messages is not an admin message. In anonymized mode, where messages
+// are not stored, it does nothing.
+func (s *StorageService) MarkMessagesRead(sessionID string, indexes []int, at time.Time) error {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (nothing to mark)
+	if len(indexes) == 0 || s.anonymized {
+		return nil
+	}
+	s.flushPending(sessionID)
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "mark_messages_read"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	// Matching the sender also keeps $set from padding the array past its end
+	filter := s.scope(bson.M{constants.MongoFieldID: sessionID})
+	set := bson.M{}
+	for _, index := range indexes {
+		// No else needed: early return pattern (guard clause)
+		if index < 0 {
+			return ErrMessageNotFound
+		}
+		field := fmt.Sprintf("%s.%d", constants.MongoFieldMessages, index)
+		filter[field+".sender"] = constants.SenderAdmin
+		set[field+".readAt"] = at
+	}
+
+	var matched int64
+	err := s.retryOperation(ctx, "MarkMessagesRead", func() error {
+		result, opErr := s.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to mark messages read: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if matched == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkMessagesRead(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	createSessionWithActivity(t, service, "session-1", now)
+	require.NoError(t, service.AddMessage("session-1", &session.Message{Content: "Help", Timestamp: now, Sender: constants.SenderUser}))
+	require.NoError(t, service.AddMessage("session-1", &session.Message{Content: "On it", Timestamp: now, Sender: constants.SenderAdmin}))
+	require.NoError(t, service.AddMessage("session-1", &session.Message{Content: "Done", Timestamp: now, Sender: constants.SenderAdmin}))
+
+	readAt := now.Add(time.Minute).UTC().Truncate(time.Millisecond)
+	require.NoError(t, service.MarkMessagesRead("session-1", []int{1, 2}, readAt))
+
+	sess, err := service.GetSession("session-1")
+	require.NoError(t, err)
+	assert.Nil(t, sess.Messages[0].ReadAt)
+	for _, i := range []int{1, 2} {
+		require.NotNil(t, sess.Messages[i].ReadAt, "message %d", i)
+		assert.True(t, readAt.Equal(*sess.Messages[i].ReadAt))
+	}
+
+	// Only existing admin messages can be marked read
+	assert.ErrorIs(t, service.MarkMessagesRead("session-1", []int{0}, readAt), ErrMessageNotFound)
+	assert.ErrorIs(t, service.MarkMessagesRead("session-1", []int{1, 3}, readAt), ErrMessageNotFound)
+	assert.ErrorIs(t, service.MarkMessagesRead("session-1", []int{-1}, readAt), ErrMessageNotFound)
+	assert.ErrorIs(t, service.MarkMessagesRead("missing", []int{1}, readAt), ErrMessageNotFound)
+	assert.ErrorIs(t, service.MarkMessagesRead("", []int{1}, readAt), ErrInvalidSessionID)
+	assert.NoError(t, service.MarkMessagesRead("session-1", nil, readAt))
+
+	sess, err = service.GetSession("session-1")
+	require.NoError(t, err)
+	assert.Len(t, sess.Messages, 3, "marking never pads the message list")
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrReportedMessageNotFound is returned when a report targets a message that
+	// does not exist or is not an AI or admin message
+	ErrReportedMessageNotFound = errors.New("AI or admin message not found")
+	// ErrInvalidReport is returned when a report has an unknown category or its reason exceeds the limit
+	ErrInvalidReport = errors.New("invalid report")
+	// ErrAlreadyReported is returned when the message was reported before
+	ErrAlreadyReported = errors.New("message already reported")
+)
+
+// ReportCategories are the kinds of abuse a message can be reported for
+var ReportCategories = []string{"harassment", "hate", "sexual", "violence", "self_harm", "misinformation", "spam", "other"}
+
+// AbuseReport is a user's report of an abusive AI or admin message in one of
+// their sessions. The message is copied into the report so it can be reviewed
+// even after the session is deleted.
+type AbuseReport struct {
+	ID           string    `bson:"_id" json:"id"`
+	TenantID     string    `bson:"tid,omitempty" json:"tenant_id,omitempty"`
+	SessionID    string    `bson:"sid" json:"session_id"`
+	MessageIndex int       `bson:"idx" json:"message_index"`
+	Sender       string    `bson:"sender" json:"sender"`                     // "ai" or "admin"
+	Author       string    `bson:"author,omitempty" json:"author,omitempty"` // admin ID of an admin message, model of an AI reply
+	Content      string    `bson:"content" json:"content"`                   // encrypted like message content
+	MessageTime  time.Time `bson:"msgTs" json:"message_time"`
+	ReporterID   string    `bson:"uid" json:"reporter_id"`
+	Category     string    `bson:"cat" json:"category"`
+	Reason       string    `bson:"reason,omitempty" json:"reason,omitempty"` // encrypted like message content
+	CreatedAt    time.Time `bson:"ts" json:"created_at"`
+}
+
+// ValidateReport checks a report's category and reason
+func ValidateReport(category, reason string) error {
+	// No else needed: early return pattern (guard clause)
+	if !slices.Contains(ReportCategories, category) {
+		return fmt.Errorf("%w: category must be one of %v", ErrInvalidReport, ReportCategories)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(reason) > constants.MaxReportReasonLength {
+		return fmt.Errorf("%w: reason exceeds maximum length of %d bytes", ErrInvalidReport, constants.MaxReportReasonLength)
+	}
+	return nil
+}
+
+// reportID returns the document ID of the report of the message at index of a
+// session. Only the session's owner can report its messages, so each message
+// is reported at most once.
+func reportID(sessionID string, index int) string {
+	return sessionID + ":" + strconv.Itoa(index)
+}
+
+// ensureReportIndexes creates the indexes for the abuse_reports collection
+func (s *StorageService) ensureReportIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldTenantID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexReportTenant),
+		},
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldSessionID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexReportSession),
+		},
+	}
+
+	_, err := s.reports.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create abuse report indexes: %w", err)
+	}
+	return nil
+}
+
+// ReportMessage records userID's report of the AI or admin message at index of
+// a session they own and returns the report. Returns ErrSessionNotFound when
+// the session does not exist or belongs to someone else,
+// ErrReportedMessageNotFound when it has no AI or admin message at index,
+// ErrAlreadyReported when the message was reported before, and
+// ErrAnonymizedMode when messages are not stored.
+func (s *StorageService) ReportMessage(sessionID, userID string, index int, category, reason string) (*AbuseReport, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+	// No else needed: early return pattern (guard clause)
+	if index < 0 {
+		return nil, ErrReportedMessageNotFound
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := ValidateReport(category, reason); err != nil {
+		return nil, err
+	}
+	s.flushPending(sessionID)
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "report_message"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var doc SessionDocument
+	err := s.retryOperation(ctx, "ReportMessage.find", func() error {
+		return s.collection.FindOne(ctx, s.ownedBy(sessionID, userID), options.FindOne().SetProjection(bson.M{
+			constants.MongoFieldTenantID: 1,
+			constants.MongoFieldMessages: bson.M{"$slice": bson.A{index, 1}},
+		})).Decode(&doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get reported message: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(doc.Messages) == 0 || (doc.Messages[0].Sender != constants.SenderAI && doc.Messages[0].Sender != constants.SenderAdmin) {
+		return nil, ErrReportedMessageNotFound
+	}
+	msg := doc.Messages[0]
+
+	report := &AbuseReport{
+		ID:           reportID(sessionID, index),
+		TenantID:     doc.TenantID,
+		SessionID:    sessionID,
+		MessageIndex: index,
+		Sender:       msg.Sender,
+		MessageTime:  msg.Timestamp,
+		ReporterID:   userID,
+		Category:     category,
+		CreatedAt:    time.Now().UTC(),
+	}
+	// No else needed: conditional assignment (who wrote the message)
+	if msg.Sender == constants.SenderAdmin {
+		report.Author = msg.Metadata["admin_id"]
+	} else {
+		report.Author = msg.Metadata[constants.MetadataKeyModel]
+	}
+
+	// The report keeps the message and reason encrypted at rest like the session
+	content, _ := s.openMessage(sessionID, index, msg.Content)
+	stored := *report
+	stored.Content = content
+	stored.Reason = reason
+	// No else needed: optional operation (only encrypt if key is available)
+	if len(s.encryptionKey) > 0 {
+		// No else needed: early return pattern (guard clause)
+		if stored.Content, err = s.encrypt(content); err != nil {
+			return nil, fmt.Errorf("failed to encrypt reported message: %w", err)
+		}
+		// No else needed: optional operation (the reason is optional)
+		if reason != "" {
+			// No else needed: early return pattern (guard clause)
+			if stored.Reason, err = s.encrypt(reason); err != nil {
+				return nil, fmt.Errorf("failed to encrypt report reason: %w", err)
+			}
+		}
+	}
+
+	err = s.retryOperation(ctx, "ReportMessage.insert", func() error {
+		_, opErr := s.reports.InsertOne(ctx, stored)
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrAlreadyReported
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store abuse report: %w", err)
+	}
+	report.Content = content
+	report.Reason = reason
+	return report, nil
+}
+
+// ListAbuseReports lists the abuse reports visible through this service,
+// newest first. sessionID restricts the list to one session when not empty;
+// limit defaults to and is capped by the report list limits.
+func (s *StorageService) ListAbuseReports(sessionID string, limit int) ([]*AbuseReport, error) {
+	// No else needed: optional operation (apply default limit)
+	if limit <= 0 {
+		limit = constants.DefaultReportListLimit
+	}
+	// No else needed: optional operation (cap limit)
+	if limit > constants.MaxReportListLimit {
+		limit = constants.MaxReportListLimit
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_abuse_reports"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := bson.M{}
+	// No else needed: optional operation (filter by session)
+	if sessionID != "" {
+		filter[constants.MongoFieldSessionID] = sessionID
+	}
+	cursor, err := s.reports.Find(ctx, s.tenantFilter(filter), gomongo.QueryOptions{
+		Sort:  bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+		Limit: int64(limit),
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list abuse reports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reports := make([]*AbuseReport, 0)
+	for cursor.Next(ctx) {
+		var report AbuseReport
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&report); err != nil {
+			return nil, fmt.Errorf("failed to decode abuse report: %w", err)
+		}
+		// No else needed: optional operation (only decrypt if key is available)
+		if len(s.encryptionKey) > 0 {
+			// No else needed: optional operation (fallback to original on error)
+			if decrypted, err := s.decrypt(report.Content); err == nil {
+				report.Content = decrypted
+			}
+			// No else needed: optional operation (the reason is optional)
+			if report.Reason != "" {
+				// No else needed: optional operation (fallback to original on error)
+				if decrypted, err := s.decrypt(report.Reason); err == nil {
+					report.Reason = decrypted
+				}
+			}
+		}
+		reports = append(reports, &report)
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return reports, nil
+}
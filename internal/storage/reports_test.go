@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReport(t *testing.T) {
+	assert.NoError(t, ValidateReport("harassment", ""))
+	assert.NoError(t, ValidateReport("other", "It insulted me"))
+	assert.ErrorIs(t, ValidateReport("", ""), ErrInvalidReport)
+	assert.ErrorIs(t, ValidateReport("rude", ""), ErrInvalidReport)
+	assert.ErrorIs(t, ValidateReport("spam", strings.Repeat("r", constants.MaxReportReasonLength+1)), ErrInvalidReport)
+
+	svc := &StorageService{}
+	_, err := svc.ReportMessage("", "user-1", 0, "spam", "")
+	assert.ErrorIs(t, err, ErrInvalidSessionID)
+	_, err = svc.ReportMessage("session-1", "user-1", -1, "spam", "")
+	assert.ErrorIs(t, err, ErrReportedMessageNotFound)
+	_, err = svc.ReportMessage("session-1", "user-1", 0, "rude", "")
+	assert.ErrorIs(t, err, ErrInvalidReport)
+
+	anonymized := &StorageService{anonymized: true}
+	_, err = anonymized.ReportMessage("session-1", "user-1", 0, "spam", "")
+	assert.ErrorIs(t, err, ErrAnonymizedMode)
+}
+
+func TestReportMessage(t *testing.T) {
+	service, cleanup := setupTestStorage(t, []byte("12345678901234567890123456789012"))
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.reports = c })
+
+	now := time.Now()
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:        "session-1",
+		UserID:    "user-1",
+		TenantID:  "acme",
+		StartTime: now,
+		IsActive:  true,
+		Messages: []*session.Message{
+			{Content: "hello", Timestamp: now, Sender: "user"},
+			{Content: "a rude answer", Timestamp: now.Add(time.Second), Sender: "ai", Metadata: map[string]string{constants.MetadataKeyModel: "gpt-4"}},
+			{Content: "an admin reply", Timestamp: now.Add(2 * time.Second), Sender: "admin", Metadata: map[string]string{"admin_id": "admin-1"}},
+		},
+	}))
+	acme := service.ForTenant("acme")
+
+	report, err := acme.ReportMessage("session-1", "user-1", 1, "harassment", "It insulted me")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", report.TenantID)
+	assert.Equal(t, "ai", report.Sender)
+	assert.Equal(t, "gpt-4", report.Author)
+	assert.Equal(t, "a rude answer", report.Content)
+
+	_, err = acme.ReportMessage("session-1", "user-1", 1, "spam", "")
+	assert.ErrorIs(t, err, ErrAlreadyReported)
+	report, err = acme.ReportMessage("session-1", "user-1", 2, "other", "")
+	require.NoError(t, err)
+	assert.Equal(t, "admin-1", report.Author)
+
+	// Only AI and admin messages of the user's own sessions can be reported
+	_, err = acme.ReportMessage("session-1", "user-1", 0, "spam", "")
+	assert.ErrorIs(t, err, ErrReportedMessageNotFound)
+	_, err = acme.ReportMessage("session-1", "user-1", 9, "spam", "")
+	assert.ErrorIs(t, err, ErrReportedMessageNotFound)
+	_, err = acme.ReportMessage("session-1", "user-2", 1, "spam", "")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.ForTenant("other").ReportMessage("session-1", "user-1", 1, "spam", "")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// Reports are listed decrypted, newest first, to admins of the tenant
+	reports, err := acme.ListAbuseReports("", 0)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Equal(t, 2, reports[0].MessageIndex)
+	assert.Equal(t, "a rude answer", reports[1].Content)
+	assert.Equal(t, "It insulted me", reports[1].Reason)
+
+	reports, err = service.ForTenant("").ListAbuseReports("", 0)
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+	reports, err = service.ListAbuseReports("session-2", 0)
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+}
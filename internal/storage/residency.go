@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// ParseResidency converts the raw [chatbox.residency.tenants] config value, a
+// table of tenant = "datastore" entries, into a map of tenant IDs to the names
+// of the [dbs.<name>] datastores their data is kept in
+func ParseResidency(raw interface{}) (map[string]string, error) {
+	table, ok := raw.(map[string]interface{})
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("chatbox.residency.tenants is not a table")
+	}
+
+	tenants := make(map[string]string, len(table))
+	for tenantID, value := range table {
+		datastore, ok := value.(string)
+		// No else needed: early return pattern (guard clause)
+		if !ok || datastore == "" {
+			return nil, fmt.Errorf("tenant %s: datastore must be the name of a [dbs.<name>] entry", tenantID)
+		}
+		// No else needed: early return pattern (guard clause)
+		if tenantID == "" {
+			return nil, fmt.Errorf("the default tenant cannot be pinned to a datastore")
+		}
+		tenants[tenantID] = datastore
+	}
+	return tenants, nil
+}
+
+// SetResidency keeps the data of the tenants in the map in their datastore, the
+// named gomongo database, instead of the service's own. A datastore holds the
+// sessions, snapshots, abuse reports, metrics rollups, user memory, audit log,
+// usage records and user blocks of its tenants: everything that names a user,
+// an IP or a session. Only configuration without user data stays in the
+// service's database: prompts, canned responses, roles, API keys, settings,
+// watchlist keywords, experiments and job locks and runs (enforced by
+// TestDatastoreView_SharedCollections). Tenants mapped to the service's own
+// database are ignored.
+//
+// ForTenant and DatastoreFor return views of a tenant's datastore, and index
+// creation, migrations, the retention purge, metrics rollups, message batching
+// and the session change stream of the base service cover every datastore.
+// Must be called on the base service after its other settings, before it is
+// used.
+func (s *StorageService) SetResidency(dbName string, tenants map[string]string) {
+	views := make(map[string]*StorageService)
+	s.residency = make(map[string]*StorageService, len(tenants))
+	s.datastores = nil
+	for tenantID, datastore := range tenants {
+		// No else needed: early return pattern (tenant stays in the base datastore)
+		if datastore == dbName {
+			continue
+		}
+		view, ok := views[datastore]
+		// No else needed: optional operation (one view per datastore)
+		if !ok {
+			view = s.datastoreView(datastore)
+			views[datastore] = view
+			s.datastores = append(s.datastores, view)
+		}
+		s.residency[tenantID] = view
+	}
+	sort.Slice(s.datastores, func(i, j int) bool {
+		return s.datastores[i].datastore < s.datastores[j].datastore
+	})
+}
+
+// datastoreView returns a copy of the service whose tenant data collections are
+// in the database dbName. The shared configuration collections listed in
+// SetResidency stay in the service's database.
+func (s *StorageService) datastoreView(dbName string) *StorageService {
+	return &StorageService{
+		mongo:           s.mongo,
+		collName:        s.collName,
+		datastore:       dbName,
+		collection:      s.mongo.Coll(dbName, s.collName),
+		auditLog:        s.mongo.Coll(dbName, constants.AuditCollection),
+		prompts:         s.prompts,
+		canned:          s.canned,
+		rollups:         s.mongo.Coll(dbName, constants.RollupCollection),
+		snapshots:       s.mongo.Coll(dbName, constants.SnapshotCollection),
+		roles:           s.roles,
+		apiKeys:         s.apiKeys,
+		usage:           s.mongo.Coll(dbName, constants.UsageCollection),
+		settings:        s.settings,
+		blocks:          s.mongo.Coll(dbName, constants.BlockCollection),
+		reports:         s.mongo.Coll(dbName, constants.ReportCollection),
+		keywords:        s.keywords,
+		jobLocks:        s.jobLocks,
+		jobRuns:         s.jobRuns,
+		memory:          s.mongo.Coll(dbName, constants.MemoryCollection),
+		experiments:     s.experiments,
+		logger:          s.logger,
+		encryptionKey:   s.encryptionKey,
+		gcm:             s.gcm,
+		anonymized:      s.anonymized,
+		searchHashIndex: s.searchHashIndex,
+		restoreGrace:    s.restoreGrace,
+
+		hashSalt:          s.hashSalt,
+		anonymizedTenants: s.anonymizedTenants,
+	}
+}
+
+// DatastoreFor returns the service holding the data of tenantID: a view of the
+// datastore the tenant is pinned to, or s. Unlike ForTenant, the view is not
+// restricted to the tenant's sessions.
+func (s *StorageService) DatastoreFor(tenantID string) *StorageService {
+	// No else needed: optional operation (tenant pinned to another datastore)
+	if view, ok := s.residency[tenantID]; ok {
+		return view
+	}
+	return s
+}
+
+// ResidentStore is the SessionStore of a StorageService with tenants pinned to
+// other datastores (see SetResidency). New sessions are written to their
+// tenant's datastore, and later calls go to the datastore of the session's
+// tenant, found with the resolver. Sessions the resolver does not know are
+// looked up in every datastore.
+type ResidentStore struct {
+	*StorageService
+	resolve func(sessionID string) (tenantID string, ok bool)
+}
+
+// NewResidentStore creates the session store of base. resolve returns the
+// tenant of a session known to the caller, typically from the session manager.
+func NewResidentStore(base *StorageService, resolve func(sessionID string) (string, bool)) *ResidentStore {
+	return &ResidentStore{StorageService: base, resolve: resolve}
+}
+
+// storeOf returns the datastore holding sessionID
+func (r *ResidentStore) storeOf(sessionID string) (*StorageService, error) {
+	// No else needed: early return pattern (tenant known in memory)
+	if tenantID, ok := r.resolve(sessionID); ok {
+		return r.DatastoreFor(tenantID), nil
+	}
+	for _, store := range r.all() {
+		_, err := store.GetSession(sessionID)
+		// No else needed: optional operation (keep looking in the next datastore)
+		if errors.Is(err, ErrSessionNotFound) {
+			continue
+		}
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	return nil, ErrSessionNotFound
+}
+
+// all returns the base service followed by its regional datastores
+func (r *ResidentStore) all() []*StorageService {
+	return append([]*StorageService{r.StorageService}, r.datastores...)
+}
+
+// CreateSession writes sess to its tenant's datastore
+func (r *ResidentStore) CreateSession(sess *session.Session) error {
+	// No else needed: early return pattern (guard clause)
+	if sess == nil {
+		return ErrInvalidSession
+	}
+	return r.DatastoreFor(sess.TenantID).CreateSession(sess)
+}
+
+// GetSession reads a session from the datastore holding it
+func (r *ResidentStore) GetSession(sessionID string) (*session.Session, error) {
+	// No else needed: early return pattern (tenant known in memory)
+	if tenantID, ok := r.resolve(sessionID); ok {
+		return r.DatastoreFor(tenantID).GetSession(sessionID)
+	}
+	for _, store := range r.all() {
+		sess, err := store.GetSession(sessionID)
+		// No else needed: optional operation (keep looking in the next datastore)
+		if errors.Is(err, ErrSessionNotFound) {
+			continue
+		}
+		return sess, err
+	}
+	return nil, ErrSessionNotFound
+}
+
+// AddMessage adds msg to the session in its datastore
+func (r *ResidentStore) AddMessage(sessionID string, msg *session.Message) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.AddMessage(sessionID, msg)
+}
+
+// UpdateSessionName renames the session in its datastore
+func (r *ResidentStore) UpdateSessionName(sessionID, name string) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.UpdateSessionName(sessionID, name)
+}
+
+// UpdateSessionModelID updates the session's model in its datastore
+func (r *ResidentStore) UpdateSessionModelID(sessionID, modelID string) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.UpdateSessionModelID(sessionID, modelID)
+}
+
+// UpdateSessionLLMParams updates the session's LLM parameters in its datastore
+func (r *ResidentStore) UpdateSessionLLMParams(sessionID string, params *session.LLMParams) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.UpdateSessionLLMParams(sessionID, params)
+}
+
+// UpdateSessionProviderThread updates the session's provider thread in its datastore
+func (r *ResidentStore) UpdateSessionProviderThread(sessionID string, thread *session.ProviderThread) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.UpdateSessionProviderThread(sessionID, thread)
+}
+
+// RecordHandback records an admin intervention in the session's datastore
+func (r *ResidentStore) RecordHandback(sessionID string, intervention *session.AdminIntervention) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.RecordHandback(sessionID, intervention)
+}
+
+// SetMessageFeedback sets message feedback in the session's datastore
+func (r *ResidentStore) SetMessageFeedback(sessionID string, index int, feedback *session.Feedback) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.SetMessageFeedback(sessionID, index, feedback)
+}
+
+// MarkMessagesRead records read receipts in the session's datastore
+func (r *ResidentStore) MarkMessagesRead(sessionID string, indexes []int, at time.Time) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.MarkMessagesRead(sessionID, indexes, at)
+}
+
+// EndSession ends the session in its datastore
+func (r *ResidentStore) EndSession(sessionID string, endTime time.Time) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.EndSession(sessionID, endTime)
+}
+
+// UpdateSessionSentiment updates the session's rolling sentiment in its datastore
+func (r *ResidentStore) UpdateSessionSentiment(sessionID string, sentiment session.Sentiment) error {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	return store.UpdateSessionSentiment(sessionID, sentiment)
+}
+
+// AddSessionTags tags the session in its datastore
+func (r *ResidentStore) AddSessionTags(sessionID string, tags []string) ([]string, error) {
+	store, err := r.storeOf(sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	return store.AddSessionTags(sessionID, tags)
+}
+
+// LoadActiveSessions returns the active sessions of every datastore
+func (r *ResidentStore) LoadActiveSessions() ([]*session.Session, error) {
+	var sessions []*session.Session
+	for _, store := range r.all() {
+		active, err := store.LoadActiveSessions()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("datastore %s: %w", store.datastoreName(), err)
+		}
+		sessions = append(sessions, active...)
+	}
+	return sessions, nil
+}
+
+// datastoreName names the datastore of s in errors and logs
+func (s *StorageService) datastoreName() string {
+	// No else needed: early return pattern (base datastore)
+	if s.datastore == "" {
+		return "default"
+	}
+	return s.datastore
+}
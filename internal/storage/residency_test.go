@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestParseResidency(t *testing.T) {
+	tenants, err := ParseResidency(map[string]interface{}{
+		"acme-eu": "chat_eu",
+		"globex":  "chat",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"acme-eu": "chat_eu", "globex": "chat"}, tenants)
+
+	_, err = ParseResidency("chat_eu")
+	assert.Error(t, err)
+	_, err = ParseResidency(map[string]interface{}{"acme-eu": []string{"chat_eu"}})
+	assert.Error(t, err)
+	_, err = ParseResidency(map[string]interface{}{"acme-eu": ""})
+	assert.Error(t, err)
+	_, err = ParseResidency(map[string]interface{}{"": "chat_eu"})
+	assert.Error(t, err)
+}
+
+func TestDatastoreFor(t *testing.T) {
+	eu := &StorageService{datastore: "chat_eu"}
+	base := &StorageService{
+		residency:  map[string]*StorageService{"acme-eu": eu},
+		datastores: []*StorageService{eu},
+	}
+
+	assert.Same(t, eu, base.DatastoreFor("acme-eu"))
+	assert.Same(t, base, base.DatastoreFor("globex"))
+	assert.Same(t, base, base.DatastoreFor(""))
+
+	// Tenant views of pinned tenants use their datastore
+	assert.Equal(t, "chat_eu", base.ForTenant("acme-eu").datastore)
+	assert.Equal(t, "", base.ForTenant("globex").datastore)
+}
+
+func TestMigrationReport_Add(t *testing.T) {
+	report := &MigrationReport{Migrations: []MigrationResult{{Version: 1, Pending: 2, Changed: 1}, {Version: 2, Pending: 1}}}
+	report.add(&MigrationReport{Migrations: []MigrationResult{{Version: 1, Pending: 3, Changed: 3}, {Version: 2, Pending: 4, Changed: 2}}})
+	assert.Equal(t, []MigrationResult{{Version: 1, Pending: 5, Changed: 4}, {Version: 2, Pending: 5, Changed: 2}}, report.Migrations)
+}
+
+func TestResidentStore(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	// Stand in for another database with another session collection
+	euCollection := getUniqueCollectionName(t) + "_eu"
+	eu := service.datastoreView("chatbox")
+	eu.datastore = "chatbox_eu"
+	eu.collection = service.mongo.Coll("chatbox", euCollection)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		db, _ := service.mongo.Database("chatbox")
+		if db != nil {
+			db.Coll(euCollection).Drop(ctx)
+		}
+	}()
+	service.residency = map[string]*StorageService{"acme-eu": eu}
+	service.datastores = []*StorageService{eu}
+
+	known := map[string]string{}
+	store := NewResidentStore(service, func(sessionID string) (string, bool) {
+		tenantID, ok := known[sessionID]
+		return tenantID, ok
+	})
+
+	now := time.Now()
+	require.NoError(t, store.CreateSession(&session.Session{ID: "eu-session", UserID: "user-1", TenantID: "acme-eu", StartTime: now, IsActive: true}))
+	require.NoError(t, store.CreateSession(&session.Session{ID: "us-session", UserID: "user-2", TenantID: "globex", StartTime: now, IsActive: true}))
+
+	// The EU session is only in its datastore
+	_, err := service.GetSession("eu-session")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = eu.GetSession("eu-session")
+	require.NoError(t, err)
+	_, err = eu.GetSession("us-session")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// Sessions unknown to the resolver are looked up in every datastore
+	msg := &session.Message{Content: "Hallo", Timestamp: now, Sender: "user"}
+	require.NoError(t, store.AddMessage("eu-session", msg))
+	known["us-session"] = "globex"
+	require.NoError(t, store.AddMessage("us-session", msg))
+
+	sess, err := store.GetSession("eu-session")
+	require.NoError(t, err)
+	require.Len(t, sess.Messages, 1)
+	assert.Equal(t, "Hallo", sess.Messages[0].Content)
+	assert.ErrorIs(t, store.AddMessage("missing", msg), ErrSessionNotFound)
+
+	active, err := store.LoadActiveSessions()
+	require.NoError(t, err)
+	ids := []string{}
+	for _, s := range active {
+		ids = append(ids, s.ID)
+	}
+	assert.ElementsMatch(t, []string{"eu-session", "us-session"}, ids)
+}
+
+func TestDatastoreView_SharedCollections(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	// Only configuration without user data may stay in the base database when a
+	// tenant is pinned to another datastore; keep SetResidency's list in sync
+	shared := map[string]bool{
+		"prompts":     true,
+		"canned":      true,
+		"roles":       true,
+		"apiKeys":     true,
+		"settings":    true,
+		"keywords":    true,
+		"jobLocks":    true,
+		"jobRuns":     true,
+		"experiments": true,
+	}
+
+	view := service.datastoreView("chatbox_eu")
+	base := reflect.ValueOf(service).Elem()
+	routed := reflect.ValueOf(view).Elem()
+	collectionType := reflect.TypeOf((*gomongo.MongoCollection)(nil))
+	for i := 0; i < routed.NumField(); i++ {
+		field := routed.Type().Field(i)
+		if field.Type != collectionType {
+			continue
+		}
+		same := routed.Field(i).Pointer() == base.Field(i).Pointer()
+		assert.Equal(t, shared[field.Name], same, "collection %s: shared collections must be listed, all others routed", field.Name)
+	}
+}
+
+func TestResidency_RoutesAuditUsageAndBlocks(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	// Stand in for another database with other collections
+	eu := service.datastoreView("chatbox")
+	eu.datastore = "chatbox_eu"
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.auditLog = c })
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.usage = c })
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.blocks = c })
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { eu.auditLog = c })
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { eu.usage = c })
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { eu.blocks = c })
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { eu.collection = c })
+	service.residency = map[string]*StorageService{"acme-eu": eu}
+	service.datastores = []*StorageService{eu}
+	ctx := context.Background()
+
+	// Audit entries of the pinned tenant only reach its datastore
+	now := time.Now().UTC()
+	require.NoError(t, service.RecordAudit(&AuditEntry{Action: "view", ActorID: "admin-eu", TenantID: "acme-eu", IP: "192.0.2.1", Timestamp: now}))
+	require.NoError(t, service.RecordAudit(&AuditEntry{Action: "view", ActorID: "admin-us", Timestamp: now.Add(time.Second)}))
+	baseEntries, err := service.findAuditEntries(ctx, &AuditListOptions{}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, baseEntries, 1)
+	assert.Equal(t, "admin-us", baseEntries[0].ActorID)
+	entries, err := service.ListAuditEntries(nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "super admins see every datastore")
+	assert.Equal(t, "admin-us", entries[0].ActorID)
+	entries, err = service.ListAuditEntries(&AuditListOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "admin-eu", entries[0].ActorID)
+	entries, err = service.ForTenant("acme-eu").ListAuditEntries(nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "admin-eu", entries[0].ActorID)
+
+	// Usage records and blocks follow their tenant too
+	require.NoError(t, eu.CreateSession(&session.Session{ID: "eu-session", UserID: "user-1", TenantID: "acme-eu", StartTime: now, IsActive: true}))
+	require.NoError(t, service.RecordUsage("eu-session", "acme-eu", "gpt-4", 100, 0.5))
+	costs, err := service.aggregateCosts(ctx, mongo.Pipeline{})
+	require.NoError(t, err)
+	assert.Empty(t, costs)
+	groups, err := service.GetCosts(now.Add(-time.Hour), now.Add(time.Hour), constants.CostGroupByTenant)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "acme-eu", groups[0].Key)
+	assert.Equal(t, int64(100), groups[0].Tokens)
+
+	require.NoError(t, service.BlockUser(&UserBlock{UserID: "user-1", TenantID: "acme-eu", BlockedBy: "admin-eu"}))
+	baseBlocks, err := service.findUserBlocks(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, baseBlocks)
+	blocked, err := service.UserBlocked("acme-eu", "user-1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	blocks, err := service.ListUserBlocks()
+	require.NoError(t, err)
+	assert.Len(t, blocks, 1)
+	require.NoError(t, service.UnblockUser("acme-eu", "user-1"))
+}
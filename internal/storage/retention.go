@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrSessionNotRestorable is returned when a session is not soft-deleted or its
+// restore grace window has passed
+var ErrSessionNotRestorable = errors.New("session is not soft-deleted or its restore window has passed")
+
+// StartRetentionPurger starts a background goroutine enforcing the session retention policy.
+// Sessions with no activity for longer than retention are soft-deleted: they disappear from
+// all queries but can be restored with RestoreSession for the grace period, after which
+// they are permanently deleted. The purger runs once immediately, then every
+// constants.RetentionPurgeInterval. Call StopRetentionPurger to stop it.
+func (s *StorageService) StartRetentionPurger(retention, grace time.Duration) {
+	s.restoreGrace = grace
+	s.purgeStop = make(chan struct{})
+	s.purgeWg.Add(1)
+	go func() {
+		defer s.purgeWg.Done()
+		ticker := time.NewTicker(constants.RetentionPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			// No else needed: optional operation (errors are logged, next run retries)
+			if _, _, err := s.PurgeExpiredSessions(time.Now(), retention, grace); err != nil {
+				util.LogError(s.logger, "storage", "purge expired sessions", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.purgeStop:
+				return
+			}
+		}
+	}()
+}
+
+// SetRestoreGrace sets how long soft-deleted sessions can be restored, for
+// retention purges run by a scheduler instead of StartRetentionPurger.
+// Must be called before the service handles any requests.
+func (s *StorageService) SetRestoreGrace(grace time.Duration) {
+	s.restoreGrace = grace
+}
+
+// StopRetentionPurger stops the retention purger goroutine.
+// Safe to call multiple times and when the purger was never started.
+func (s *StorageService) StopRetentionPurger() {
+	// No else needed: early return pattern (purger not started)
+	if s.purgeStop == nil {
+		return
+	}
+	s.purgeOnce.Do(func() {
+		close(s.purgeStop)
+	})
+	s.purgeWg.Wait()
+}
+
+// PurgeExpiredSessions applies the retention policy once as of now. It soft-deletes
+// sessions whose last activity is older than retention, then hard-deletes sessions
+// soft-deleted more than grace ago, in every datastore (see SetResidency). Returns
+// the number of sessions in each step.
+func (s *StorageService) PurgeExpiredSessions(now time.Time, retention, grace time.Duration) (softDeleted, hardDeleted int64, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "purge_expired_sessions"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+	defer cancel()
+
+	// Sessions written before lastActivity existed fall back to their start time
+	cutoff := now.Add(-retention)
+	softFilter := notDeleted(bson.M{
+		"$or": bson.A{
+			bson.M{constants.MongoFieldLastActivity: bson.M{"$lt": cutoff}},
+			bson.M{
+				constants.MongoFieldLastActivity: bson.M{"$exists": false},
+				constants.MongoFieldTimestamp:    bson.M{"$lt": cutoff},
+			},
+		},
+	})
+	softUpdate := bson.M{"$set": bson.M{constants.MongoFieldDeletedAt: now}}
+
+	err = s.retryOperation(ctx, "PurgeExpiredSessions.softDelete", func() error {
+		result, opErr := s.collection.UpdateMany(ctx, softFilter, softUpdate)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			softDeleted = result.ModifiedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to soft-delete expired sessions: %w", err)
+	}
+
+	hardFilter := bson.M{constants.MongoFieldDeletedAt: bson.M{"$lt": now.Add(-grace)}}
+	err = s.retryOperation(ctx, "PurgeExpiredSessions.hardDelete", func() error {
+		result, opErr := s.collection.DeleteMany(ctx, hardFilter)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			hardDeleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return softDeleted, 0, fmt.Errorf("failed to delete soft-deleted sessions: %w", err)
+	}
+
+	for _, view := range s.datastores {
+		viewSoft, viewHard, viewErr := view.PurgeExpiredSessions(now, retention, grace)
+		softDeleted += viewSoft
+		hardDeleted += viewHard
+		// No else needed: early return pattern (guard clause)
+		if viewErr != nil {
+			return softDeleted, hardDeleted, fmt.Errorf("datastore %s: %w", view.datastore, viewErr)
+		}
+	}
+
+	// No else needed: optional operation (log only when something changed)
+	if softDeleted > 0 || hardDeleted > 0 {
+		s.logger.Info("Session retention purge completed",
+			"soft_deleted", softDeleted,
+			"hard_deleted", hardDeleted)
+	}
+	return softDeleted, hardDeleted, nil
+}
+
+// RestoreSession undoes a soft delete. While the retention purger is running, only
+// sessions soft-deleted within its grace window can be restored; returns
+// ErrSessionNotRestorable otherwise. The restored session counts as active now so
+// the next purge does not delete it again.
+func (s *StorageService) RestoreSession(sessionID string) error {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	filter := s.tenantFilter(bson.M{
+		constants.MongoFieldID:        sessionID,
+		constants.MongoFieldDeletedAt: bson.M{"$exists": true},
+	})
+	// No else needed: optional operation (grace window only applies while purging)
+	if s.restoreGrace > 0 {
+		filter[constants.MongoFieldDeletedAt] = bson.M{"$gte": now.Add(-s.restoreGrace)}
+	}
+	update := bson.M{
+		"$unset": bson.M{constants.MongoFieldDeletedAt: ""},
+		"$set":   bson.M{constants.MongoFieldLastActivity: now},
+	}
+
+	var matched int64
+	err := s.retryOperation(ctx, "RestoreSession", func() error {
+		result, opErr := s.collection.UpdateOne(ctx, filter, update)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to restore session: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if matched == 0 {
+		return ErrSessionNotRestorable
+	}
+
+	s.logger.Info("Session restored from soft delete", "session_id", sessionID)
+	return nil
+}
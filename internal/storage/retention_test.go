@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createSessionWithActivity stores a session whose last activity was at the given time
+func createSessionWithActivity(t *testing.T, service *StorageService, id string, lastActivity time.Time) {
+	t.Helper()
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:           id,
+		UserID:       "user-1",
+		Messages:     []*session.Message{},
+		StartTime:    lastActivity,
+		LastActivity: lastActivity,
+		IsActive:     true,
+	}))
+}
+
+func TestPurgeExpiredSessions_SoftDeleteAndRestore(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	retention := 30 * 24 * time.Hour
+	grace := 7 * 24 * time.Hour
+	createSessionWithActivity(t, service, "old-session", now.Add(-40*24*time.Hour))
+	createSessionWithActivity(t, service, "recent-session", now.Add(-time.Hour))
+
+	soft, hard, err := service.PurgeExpiredSessions(now, retention, grace)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), soft)
+	assert.Equal(t, int64(0), hard)
+
+	// Soft-deleted sessions are hidden from reads and listings
+	_, err = service.GetSession("old-session")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	sessions, err := service.ListAllSessions(0)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "recent-session", sessions[0].ID)
+
+	// Restoring brings the session back and resets its activity
+	require.NoError(t, service.RestoreSession("old-session"))
+	_, err = service.GetSession("old-session")
+	assert.NoError(t, err)
+
+	soft, _, err = service.PurgeExpiredSessions(now, retention, grace)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), soft, "restored session should not be purged again immediately")
+
+	// Sessions that are not soft-deleted cannot be restored
+	assert.ErrorIs(t, service.RestoreSession("recent-session"), ErrSessionNotRestorable)
+}
+
+func TestPurgeExpiredSessions_HardDeletesAfterGrace(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	retention := 30 * 24 * time.Hour
+	grace := 7 * 24 * time.Hour
+	service.restoreGrace = grace
+	createSessionWithActivity(t, service, "old-session", now.Add(-40*24*time.Hour))
+
+	// Soft-delete as of 10 days ago, so the grace window has already passed
+	soft, _, err := service.PurgeExpiredSessions(now.Add(-10*24*time.Hour), retention, grace)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), soft)
+	assert.ErrorIs(t, service.RestoreSession("old-session"), ErrSessionNotRestorable)
+
+	_, hard, err := service.PurgeExpiredSessions(now, retention, grace)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), hard)
+}
+
+func TestStopRetentionPurger_NotStarted(t *testing.T) {
+	svc := &StorageService{}
+	assert.NotPanics(t, svc.StopRetentionPurger)
+}
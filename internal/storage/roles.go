@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RoleDocument grants a role its admin permissions. Documents in the chat_roles
+// collection override the permissions of the same role from config.
+type RoleDocument struct {
+	Role        string   `bson:"_id"`
+	Permissions []string `bson:"perms"`
+}
+
+// ListRolePermissions returns the role permissions stored in MongoDB. Unknown
+// permissions are rejected with authz.ErrUnknownPermission so a typo does not
+// silently revoke access.
+func (s *StorageService) ListRolePermissions() (map[string][]authz.Permission, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_role_permissions"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	cursor, err := s.roles.Find(ctx, bson.M{}, gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldID, Value: 1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role permissions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	roles := make(map[string][]authz.Permission)
+	for cursor.Next(ctx) {
+		var doc RoleDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode role permissions: %w", err)
+		}
+		perms := make([]authz.Permission, 0, len(doc.Permissions))
+		for _, name := range doc.Permissions {
+			perms = append(perms, authz.Permission(name))
+		}
+		roles[doc.Role] = perms
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := authz.NewPolicy(roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
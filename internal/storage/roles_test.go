@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListRolePermissions(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.roles = c })
+
+	roles, err := service.ListRolePermissions()
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = service.roles.InsertOne(ctx, RoleDocument{Role: "support", Permissions: []string{"view_sessions", "takeover"}})
+	require.NoError(t, err)
+
+	roles, err = service.ListRolePermissions()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]authz.Permission{"support": {authz.PermViewSessions, authz.PermTakeover}}, roles)
+
+	// Unknown permissions are rejected rather than ignored
+	_, err = service.roles.InsertOne(ctx, RoleDocument{Role: "auditor", Permissions: []string{"read_minds"}})
+	require.NoError(t, err)
+	_, err = service.ListRolePermissions()
+	assert.ErrorIs(t, err, authz.ErrUnknownPermission)
+}
@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrEmptySearchQuery is returned when a search query contains no searchable terms
+var ErrEmptySearchQuery = errors.New("search query contains no searchable terms")
+
+// SearchResult is a session with messages matching a search query
+type SearchResult struct {
+	Session  *SessionMetadata `json:"session"`
+	Snippets []SearchSnippet  `json:"snippets"`
+}
+
+// SearchSnippet is an excerpt of a matching message
+type SearchSnippet struct {
+	MessageIndex int       `json:"message_index"`
+	Sender       string    `json:"sender"`
+	Timestamp    time.Time `json:"timestamp"`
+	Text         string    `json:"text"`
+}
+
+// SetSearchHashIndex enables the searchable-hash index for encrypted deployments.
+// Each stored message adds keyed hashes of its words to the session, so searches
+// only decrypt candidate sessions instead of scanning the most recent ones. The
+// hashes reveal which sessions share a word, but not the word itself. Only messages
+// stored after enabling are indexed. Has no effect without an encryption key.
+// Must be called before the service handles any requests.
+func (s *StorageService) SetSearchHashIndex(enabled bool) {
+	s.searchHashIndex = enabled
+}
+
+// usesSearchHashIndex reports whether message terms are stored as searchable hashes
+func (s *StorageService) usesSearchHashIndex() bool {
+	return s.searchHashIndex && len(s.encryptionKey) > 0
+}
+
+// searchIndexes returns the indexes backing SearchSessions: a text index over message
+// content when content is stored in plaintext, or a multikey index over the term
+// hashes when the searchable-hash index is enabled
+func (s *StorageService) searchIndexes() []mongo.IndexModel {
+	// No else needed: early return pattern (plaintext content)
+	if len(s.encryptionKey) == 0 {
+		return []mongo.IndexModel{{
+			Keys:    bson.D{{Key: constants.MongoFieldMsgContent, Value: "text"}},
+			Options: options.Index().SetName(constants.IndexMessageText),
+		}}
+	}
+	// No else needed: early return pattern (encrypted content, no hash index)
+	if !s.usesSearchHashIndex() {
+		return nil
+	}
+	return []mongo.IndexModel{{
+		Keys:    bson.D{{Key: constants.MongoFieldSearchTerms, Value: 1}},
+		Options: options.Index().SetName(constants.IndexSearchTerms).SetSparse(true),
+	}}
+}
+
+// searchTerms splits text into lowercase words, without duplicates
+func searchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	seen := make(map[string]bool, len(words))
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		// No else needed: optional operation (skip duplicates)
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// hashTerms returns the searchable hashes of terms, keyed with the encryption key
+func (s *StorageService) hashTerms(terms []string) []string {
+	hashes := make([]string, len(terms))
+	for i, term := range terms {
+		mac := hmac.New(sha256.New, s.encryptionKey)
+		mac.Write([]byte("search:" + term))
+		hashes[i] = hex.EncodeToString(mac.Sum(nil))[:constants.SearchTermHashLength]
+	}
+	return hashes
+}
+
+// SearchSessions finds sessions whose messages contain every term of query, most
+// recent first, with snippets of the matching messages. Plaintext deployments use
+// the MongoDB text index; encrypted deployments decrypt candidate sessions, found
+// through the searchable-hash index when enabled, otherwise among the
+// constants.MaxSearchScanSessions most recent sessions.
+func (s *StorageService) SearchSessions(query string, limit int) ([]*SearchResult, error) {
+	// No else needed: early return pattern (no message content is stored)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+
+	terms := searchTerms(query)
+	// No else needed: early return pattern (guard clause)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+
+	// No else needed: optional operation (apply default and cap)
+	if limit <= 0 || limit > constants.MaxSearchLimit {
+		limit = constants.DefaultSearchLimit
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "search_sessions"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+	defer cancel()
+
+	// Build the candidate query; every candidate is verified against the decrypted content.
+	// Sessions of anonymized tenants have no messages to match.
+	filter := s.withoutAnonymizedTenants(s.scope(bson.M{}))
+	queryOpts := gomongo.QueryOptions{
+		Sort:  bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+		Limit: int64(limit),
+	}
+	switch {
+	case len(s.encryptionKey) == 0:
+		// Quoted terms make $text require all of them
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = strconv.Quote(term)
+		}
+		filter["$text"] = bson.M{"$search": strings.Join(quoted, " ")}
+	case s.usesSearchHashIndex():
+		filter[constants.MongoFieldSearchTerms] = bson.M{"$all": s.hashTerms(terms)}
+	default:
+		queryOpts.Limit = constants.MaxSearchScanSessions
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, queryOpts)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	results := make([]*SearchResult, 0)
+	for len(results) < limit && cursor.Next(ctx) {
+		var doc SessionDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode session document: %w", err)
+		}
+
+		// No else needed: optional operation (include only verified matches)
+		if result := s.matchSession(&doc, terms); result != nil {
+			results = append(results, result)
+		}
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return results, nil
+}
+
+// matchSession returns a search result for doc when its decrypted messages contain
+// every term, or nil otherwise
+func (s *StorageService) matchSession(doc *SessionDocument, terms []string) *SearchResult {
+	sess := s.documentToSession(doc)
+
+	found := make(map[string]bool, len(terms))
+	var snippets []SearchSnippet
+	for i, msg := range sess.Messages {
+		content := strings.ToLower(msg.Content)
+		matchedAt := -1
+		for _, term := range terms {
+			// No else needed: optional operation (record the first matching term)
+			if idx := strings.Index(content, term); idx >= 0 {
+				found[term] = true
+				// No else needed: optional operation (snippet around the earliest match)
+				if matchedAt < 0 || idx < matchedAt {
+					matchedAt = idx
+				}
+			}
+		}
+		// No else needed: optional operation (snippet only for matching messages)
+		if matchedAt >= 0 && len(snippets) < constants.MaxSearchSnippets {
+			snippets = append(snippets, SearchSnippet{
+				MessageIndex: i,
+				Sender:       msg.Sender,
+				Timestamp:    msg.Timestamp,
+				Text:         snippetAround(msg.Content, matchedAt),
+			})
+		}
+	}
+
+	// No else needed: early return pattern (not every term matched)
+	if len(found) < len(terms) {
+		return nil
+	}
+
+	lastMessageTime := doc.StartTime
+	// No else needed: optional operation (only update if messages exist)
+	if len(doc.Messages) > 0 {
+		lastMessageTime = doc.Messages[len(doc.Messages)-1].Timestamp
+	}
+	return &SearchResult{
+		Session:  buildSessionMetadata(doc, lastMessageTime),
+		Snippets: snippets,
+	}
+}
+
+// snippetAround returns the text around byte offset idx, keeping
+// constants.SearchSnippetRadius characters on each side. Truncated ends are
+// marked with an ellipsis.
+func snippetAround(text string, idx int) string {
+	runes := []rune(text)
+	// Convert the byte offset in the lowercased text to a rune offset; lowercasing
+	// may change byte lengths, so clamp to the original text
+	pos := len([]rune(strings.ToLower(text)[:idx]))
+	// No else needed: optional operation (clamp to text length)
+	if pos > len(runes) {
+		pos = len(runes)
+	}
+
+	from := pos - constants.SearchSnippetRadius
+	to := pos + constants.SearchSnippetRadius
+	prefix, suffix := "…", "…"
+	// No else needed: optional operation (snippet starts at the text start)
+	if from <= 0 {
+		from, prefix = 0, ""
+	}
+	// No else needed: optional operation (snippet ends at the text end)
+	if to >= len(runes) {
+		to, suffix = len(runes), ""
+	}
+	return prefix + strings.TrimSpace(string(runes[from:to])) + suffix
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSearchKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"refund", "order", "42"}, searchTerms("Refund, order #42 — REFUND!"))
+	assert.Equal(t, []string{"größe", "café"}, searchTerms("Größe café"))
+	assert.Empty(t, searchTerms("  ?!… "))
+}
+
+func TestSnippetAround(t *testing.T) {
+	assert.Equal(t, "short text", snippetAround("short text", 6))
+
+	long := strings.Repeat("a", 100) + "needle" + strings.Repeat("b", 100)
+	snippet := snippetAround(long, 100)
+	assert.True(t, strings.HasPrefix(snippet, "…"))
+	assert.True(t, strings.HasSuffix(snippet, "…"))
+	assert.Contains(t, snippet, "needle")
+	assert.Equal(t, 2*constants.SearchSnippetRadius+2, len([]rune(snippet)))
+
+	// Multi-byte characters before the match must not split runes
+	multi := strings.Repeat("é", 80) + " needle"
+	idx := strings.Index(strings.ToLower(multi), "needle")
+	assert.Contains(t, snippetAround(multi, idx), "needle")
+}
+
+func TestHashTerms(t *testing.T) {
+	svc := &StorageService{encryptionKey: testSearchKey}
+	hashes := svc.hashTerms([]string{"refund", "order"})
+	require.Len(t, hashes, 2)
+	assert.Len(t, hashes[0], constants.SearchTermHashLength)
+	assert.NotEqual(t, hashes[0], hashes[1])
+	assert.Equal(t, hashes, svc.hashTerms([]string{"refund", "order"}), "hashes are deterministic")
+
+	other := &StorageService{encryptionKey: []byte("fedcba9876543210fedcba9876543210")}
+	assert.NotEqual(t, hashes[0], other.hashTerms([]string{"refund"})[0], "hashes depend on the key")
+}
+
+func TestSearchIndexes(t *testing.T) {
+	plain := &StorageService{}
+	require.Len(t, plain.searchIndexes(), 1)
+	assert.Equal(t, constants.IndexMessageText, *plain.searchIndexes()[0].Options.Name)
+
+	encrypted := &StorageService{encryptionKey: testSearchKey}
+	assert.Empty(t, encrypted.searchIndexes())
+
+	encrypted.SetSearchHashIndex(true)
+	require.Len(t, encrypted.searchIndexes(), 1)
+	assert.Equal(t, constants.IndexSearchTerms, *encrypted.searchIndexes()[0].Options.Name)
+
+	// The hash index needs an encryption key
+	plain.SetSearchHashIndex(true)
+	assert.False(t, plain.usesSearchHashIndex())
+}
+
+func TestMatchSession(t *testing.T) {
+	svc := &StorageService{encryptionKey: testSearchKey}
+	now := time.Now()
+	doc := svc.sessionToDocument(&session.Session{
+		ID:        "search-1",
+		UserID:    "user-1",
+		StartTime: now,
+		Messages: []*session.Message{
+			{Content: "I need a refund", Timestamp: now, Sender: constants.SenderUser},
+			{Content: "Which order is it?", Timestamp: now, Sender: constants.SenderAI},
+			{Content: "Order 42", Timestamp: now, Sender: constants.SenderUser},
+		},
+	})
+	for i := range doc.Messages {
+		encrypted, err := svc.encrypt(doc.Messages[i].Content)
+		require.NoError(t, err)
+		doc.Messages[i].Content = encrypted
+	}
+
+	result := svc.matchSession(doc, []string{"refund", "order"})
+	require.NotNil(t, result)
+	assert.Equal(t, "search-1", result.Session.ID)
+	require.Len(t, result.Snippets, 3)
+	assert.Equal(t, 0, result.Snippets[0].MessageIndex)
+	assert.Equal(t, "I need a refund", result.Snippets[0].Text)
+	assert.Equal(t, constants.SenderAI, result.Snippets[1].Sender)
+
+	assert.Nil(t, svc.matchSession(doc, []string{"refund", "shipping"}), "every term must match")
+}
+
+func TestSearchSessions_Guards(t *testing.T) {
+	svc := &StorageService{encryptionKey: testAnonymizationKey}
+	_, err := svc.SearchSessions("?!", 10)
+	assert.ErrorIs(t, err, ErrEmptySearchQuery)
+
+	require.NoError(t, svc.SetAnonymizedMode(true))
+	_, err = svc.SearchSessions("refund", 10)
+	assert.ErrorIs(t, err, ErrAnonymizedMode)
+}
+
+// createSearchSessions stores sessions whose first message is each of contents
+func createSearchSessions(t *testing.T, service *StorageService, contents ...string) {
+	t.Helper()
+	base := time.Now().Add(-time.Hour)
+	for i, content := range contents {
+		require.NoError(t, service.CreateSession(&session.Session{
+			ID:        "search-" + string(rune('a'+i)),
+			UserID:    "user-1",
+			StartTime: base.Add(time.Duration(i) * time.Minute),
+			Messages: []*session.Message{
+				{Content: content, Timestamp: base, Sender: constants.SenderUser},
+			},
+		}))
+	}
+}
+
+func TestSearchSessions(t *testing.T) {
+	for name, configure := range map[string]func(t *testing.T) *StorageService{
+		"plaintext": func(t *testing.T) *StorageService {
+			service, cleanup := setupTestStorage(t, nil)
+			t.Cleanup(cleanup)
+			return service
+		},
+		"encrypted scan": func(t *testing.T) *StorageService {
+			service, cleanup := setupTestStorage(t, testSearchKey)
+			t.Cleanup(cleanup)
+			return service
+		},
+		"encrypted hash index": func(t *testing.T) *StorageService {
+			service, cleanup := setupTestStorage(t, testSearchKey)
+			t.Cleanup(cleanup)
+			service.SetSearchHashIndex(true)
+			return service
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			service := configure(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			require.NoError(t, service.EnsureIndexes(ctx))
+
+			createSearchSessions(t, service,
+				"My refund for order 42 is late",
+				"Where is my order?",
+				"Refund please",
+			)
+			// Messages added later are searchable too
+			require.NoError(t, service.AddMessage("search-b", &session.Message{
+				Content: "Still waiting for a refund", Timestamp: time.Now(), Sender: constants.SenderUser,
+			}))
+
+			results, err := service.SearchSessions("refund", 10)
+			require.NoError(t, err)
+			require.Len(t, results, 3)
+			assert.Equal(t, "search-c", results[0].Session.ID, "most recent first")
+
+			results, err = service.SearchSessions("REFUND order", 10)
+			require.NoError(t, err)
+			require.Len(t, results, 2)
+			assert.Equal(t, "search-b", results[0].Session.ID)
+			assert.Equal(t, "search-a", results[1].Session.ID)
+			assert.Len(t, results[0].Snippets, 2)
+
+			results, err = service.SearchSessions("refund", 1)
+			require.NoError(t, err)
+			assert.Len(t, results, 1)
+
+			results, err = service.SearchSessions("shipping", 10)
+			require.NoError(t, err)
+			assert.Empty(t, results)
+
+			// Tenant views only see their own tenant's sessions
+			results, err = service.ForTenant("tenant-a").SearchSessions("refund", 10)
+			require.NoError(t, err)
+			assert.Empty(t, results)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SentimentDocument stores the rolling sentiment of a session's user messages
+// (see internal/sentiment)
+type SentimentDocument struct {
+	Score   float64 `bson:"score" json:"score"`
+	Samples int     `bson:"n" json:"n"`
+}
+
+// sentimentToDocument converts a session's rolling sentiment for storage; nil stays nil
+func sentimentToDocument(sentiment *session.Sentiment) *SentimentDocument {
+	// No else needed: early return pattern (nothing scored)
+	if sentiment == nil {
+		return nil
+	}
+	return &SentimentDocument{Score: sentiment.Score, Samples: sentiment.Samples}
+}
+
+// sentimentFromDocument converts a stored rolling sentiment back; nil stays nil
+func sentimentFromDocument(doc *SentimentDocument) *session.Sentiment {
+	// No else needed: early return pattern (nothing scored)
+	if doc == nil {
+		return nil
+	}
+	return &session.Sentiment{Score: doc.Score, Samples: doc.Samples}
+}
+
+// UpdateSessionSentiment persists the rolling sentiment of a session's user
+// messages, shown in admin session listings
+func (s *StorageService) UpdateSessionSentiment(sessionID string, sentiment session.Sentiment) error {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "update_sentiment"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := bson.M{constants.MongoFieldID: sessionID}
+	update := bson.M{"$set": bson.M{constants.MongoFieldSentiment: sentimentToDocument(&sentiment)}}
+
+	var result *mongo.UpdateResult
+	err := s.retryOperation(ctx, "UpdateSessionSentiment", func() error {
+		var opErr error
+		result, opErr = s.collection.UpdateOne(ctx, filter, update)
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to update session sentiment: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/readonly"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReadOnlyDocument is the read-only mode setting, stored in the chat_settings
+// collection so it survives restarts and is shared by all replicas
+type ReadOnlyDocument struct {
+	ID        string    `bson:"_id"`
+	Enabled   bool      `bson:"on"`
+	Message   string    `bson:"msg,omitempty"`
+	UpdatedBy string    `bson:"by,omitempty"`
+	UpdatedAt time.Time `bson:"ts"`
+}
+
+// GetReadOnlyMode returns the stored read-only mode. The zero State (disabled)
+// is returned when it was never set.
+func (s *StorageService) GetReadOnlyMode() (readonly.State, error) {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_read_only_mode"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var doc ReadOnlyDocument
+	err := s.retryOperation(ctx, "GetReadOnlyMode", func() error {
+		return s.settings.FindOne(ctx, bson.M{constants.MongoFieldID: constants.ReadOnlySettingID}).Decode(&doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return readonly.State{}, nil
+		}
+		return readonly.State{}, fmt.Errorf("failed to get read-only mode: %w", err)
+	}
+	return readonly.State{
+		Enabled:   doc.Enabled,
+		Message:   doc.Message,
+		UpdatedBy: doc.UpdatedBy,
+		UpdatedAt: doc.UpdatedAt,
+	}, nil
+}
+
+// SetReadOnlyMode stores the read-only mode, replacing the previous one
+func (s *StorageService) SetReadOnlyMode(state readonly.State) error {
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "set_read_only_mode"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	doc := ReadOnlyDocument{
+		ID:        constants.ReadOnlySettingID,
+		Enabled:   state.Enabled,
+		Message:   state.Message,
+		UpdatedBy: state.UpdatedBy,
+		UpdatedAt: state.UpdatedAt,
+	}
+	err := s.retryOperation(ctx, "SetReadOnlyMode", func() error {
+		_, opErr := s.settings.ReplaceOne(ctx, bson.M{constants.MongoFieldID: doc.ID}, doc, options.Replace().SetUpsert(true))
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to set read-only mode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/readonly"
+	"github.com/real-rm/gomongo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyMode(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	setupTestCollection(t, service, func(c *gomongo.MongoCollection) { service.settings = c })
+
+	// Never set: disabled
+	state, err := service.GetReadOnlyMode()
+	require.NoError(t, err)
+	assert.Equal(t, readonly.State{}, state)
+
+	enabled := readonly.State{
+		Enabled:   true,
+		Message:   "Storage maintenance",
+		UpdatedBy: "admin-1",
+		UpdatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	require.NoError(t, service.SetReadOnlyMode(enabled))
+	state, err = service.GetReadOnlyMode()
+	require.NoError(t, err)
+	assert.Equal(t, enabled, state)
+
+	// Setting it again replaces the stored mode
+	disabled := readonly.State{UpdatedBy: "admin-2", UpdatedAt: enabled.UpdatedAt.Add(time.Minute)}
+	require.NoError(t, service.SetReadOnlyMode(disabled))
+	state, err = service.GetReadOnlyMode()
+	require.NoError(t, err)
+	assert.Equal(t, disabled, state)
+}
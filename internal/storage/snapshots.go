@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrSnapshotNotFound is returned when a snapshot does not exist or belongs to someone else
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrTooManySnapshots is returned when a session already has constants.MaxSnapshotsPerSession snapshots
+	ErrTooManySnapshots = errors.New("too many snapshots")
+)
+
+// SnapshotDocument is a copy of a session's stored state at a point in time,
+// kept in the session_snapshots collection. Message content stays encrypted as
+// it was in the session document.
+type SnapshotDocument struct {
+	ID        string          `bson:"_id"`
+	SessionID string          `bson:"sid"`
+	UserID    string          `bson:"uid"`
+	TenantID  string          `bson:"tid,omitempty"`
+	Label     string          `bson:"label,omitempty"`
+	CreatedAt time.Time       `bson:"ts"`
+	Session   SessionDocument `bson:"sess"`
+}
+
+// Snapshot describes a stored session snapshot
+type Snapshot struct {
+	ID           string    `json:"id"`
+	SessionID    string    `json:"session_id"`
+	Label        string    `json:"label,omitempty"`
+	MessageCount int       `json:"message_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// snapshotFromDocument returns the description of a snapshot document
+func snapshotFromDocument(doc *SnapshotDocument) *Snapshot {
+	return &Snapshot{
+		ID:           doc.ID,
+		SessionID:    doc.SessionID,
+		Label:        doc.Label,
+		MessageCount: len(doc.Session.Messages),
+		CreatedAt:    doc.CreatedAt,
+	}
+}
+
+// ensureSnapshotIndexes creates the indexes for the session_snapshots collection
+func (s *StorageService) ensureSnapshotIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldSessionID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexSnapshots),
+		},
+	}
+
+	_, err := s.snapshots.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot indexes: %w", err)
+	}
+	return nil
+}
+
+// CreateUserSnapshot copies the stored state of a session owned by userID into
+// a new snapshot. Returns ErrSessionNotFound when the session does not exist or
+// belongs to someone else, ErrTooManySnapshots when the session has reached
+// constants.MaxSnapshotsPerSession, and ErrAnonymizedMode when message content
+// is not stored.
+func (s *StorageService) CreateUserSnapshot(sessionID, userID, label string) (*Snapshot, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "create_snapshot"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var sessDoc SessionDocument
+	err := s.retryOperation(ctx, "CreateUserSnapshot.find", func() error {
+		return s.collection.FindOne(ctx, s.ownedBy(sessionID, userID)).Decode(&sessDoc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	count, err := s.countSnapshots(ctx, sessionID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if count >= constants.MaxSnapshotsPerSession {
+		return nil, fmt.Errorf("%w: at most %d per session", ErrTooManySnapshots, constants.MaxSnapshotsPerSession)
+	}
+
+	// The share link belongs to the session, not to its snapshots
+	sessDoc.ShareToken = ""
+	sessDoc.ShareExpiresAt = nil
+	doc := &SnapshotDocument{
+		ID:        primitive.NewObjectID().Hex(),
+		SessionID: sessDoc.ID,
+		UserID:    sessDoc.UserID,
+		TenantID:  sessDoc.TenantID,
+		Label:     label,
+		CreatedAt: time.Now().UTC(),
+		Session:   sessDoc,
+	}
+	err = s.retryOperation(ctx, "CreateUserSnapshot.insert", func() error {
+		_, err := s.snapshots.InsertOne(ctx, doc)
+		return err
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	return snapshotFromDocument(doc), nil
+}
+
+// countSnapshots returns the number of snapshots of a session
+func (s *StorageService) countSnapshots(ctx context.Context, sessionID string) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{constants.MongoFieldSessionID: sessionID}}},
+		{{Key: "$count", Value: "n"}},
+	}
+	cursor, err := s.snapshots.Aggregate(ctx, pipeline)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count snapshots: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		N int `bson:"n"`
+	}
+	// No else needed: optional operation (no result means no snapshots)
+	if cursor.Next(ctx) {
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&result); err != nil {
+			return 0, fmt.Errorf("failed to decode snapshot count: %w", err)
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return 0, fmt.Errorf("cursor error: %w", err)
+	}
+	return result.N, nil
+}
+
+// ListUserSnapshots lists the snapshots of a session owned by userID, newest first
+func (s *StorageService) ListUserSnapshots(sessionID, userID string) ([]*Snapshot, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "list_snapshots"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := s.tenantFilter(bson.M{
+		constants.MongoFieldSessionID: sessionID,
+		constants.MongoFieldUserID:    s.StoredUserID(userID),
+	})
+	cursor, err := s.snapshots.Find(ctx, filter, gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := make([]*Snapshot, 0)
+	for cursor.Next(ctx) {
+		var doc SnapshotDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshotFromDocument(&doc))
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return snapshots, nil
+}
+
+// GetUserSnapshot returns a snapshot owned by userID together with the session
+// state it holds, message content decrypted. Returns ErrSnapshotNotFound when
+// the snapshot does not exist or belongs to someone else.
+func (s *StorageService) GetUserSnapshot(snapshotID, userID string) (*Snapshot, *session.Session, error) {
+	// No else needed: early return pattern (guard clause)
+	if snapshotID == "" {
+		return nil, nil, ErrSnapshotNotFound
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_snapshot"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := s.tenantFilter(bson.M{
+		constants.MongoFieldID:     snapshotID,
+		constants.MongoFieldUserID: s.StoredUserID(userID),
+	})
+	var doc SnapshotDocument
+	err := s.retryOperation(ctx, "GetUserSnapshot", func() error {
+		return s.snapshots.FindOne(ctx, filter).Decode(&doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil, ErrSnapshotNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return snapshotFromDocument(&doc), s.documentToSession(&doc.Session), nil
+}
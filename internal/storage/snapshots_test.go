@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSnapshots_CreateListGet(t *testing.T) {
+	service, cleanup := setupTestStorage(t, []byte("12345678901234567890123456789012"))
+	defer cleanup()
+
+	// Snapshots share one collection across tests, so the session ID is unique
+	sessionID := fmt.Sprintf("snapshot-session-%d", time.Now().UnixNano())
+	now := time.Now()
+	createSessionWithActivity(t, service, sessionID, now)
+	require.NoError(t, service.AddMessage(sessionID, &session.Message{
+		Content:   "first question",
+		Timestamp: now,
+		Sender:    "user",
+	}))
+
+	first, err := service.CreateUserSnapshot(sessionID, "user-1", "before follow-up")
+	require.NoError(t, err)
+	assert.Equal(t, sessionID, first.SessionID)
+	assert.Equal(t, "before follow-up", first.Label)
+	assert.Equal(t, 1, first.MessageCount)
+
+	require.NoError(t, service.AddMessage(sessionID, &session.Message{
+		Content:   "follow-up",
+		Timestamp: now.Add(time.Second),
+		Sender:    "user",
+	}))
+	time.Sleep(10 * time.Millisecond)
+	second, err := service.CreateUserSnapshot(sessionID, "user-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.MessageCount)
+
+	snapshots, err := service.ListUserSnapshots(sessionID, "user-1")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, second.ID, snapshots[0].ID, "newest first")
+	assert.Equal(t, first.ID, snapshots[1].ID)
+
+	// The snapshot keeps the state it was taken at, content decrypted
+	snap, sess, err := service.GetUserSnapshot(first.ID, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, snap.ID)
+	require.Len(t, sess.Messages, 1)
+	assert.Equal(t, "first question", sess.Messages[0].Content)
+
+	// Other users can neither snapshot nor read the session's snapshots
+	_, err = service.CreateUserSnapshot(sessionID, "user-2", "")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, _, err = service.GetUserSnapshot(first.ID, "user-2")
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+	snapshots, err = service.ListUserSnapshots(sessionID, "user-2")
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestCreateUserSnapshot_AnonymizedMode(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+	service.anonymized = true
+
+	_, err := service.CreateUserSnapshot("session-1", "user-1", "")
+	assert.ErrorIs(t, err, ErrAnonymizedMode)
+}
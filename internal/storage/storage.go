@@ -4,13 +4,17 @@ import (
 	"context"
 	"crypto/aes"
 	cipherPkg "crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,6 +36,12 @@ var (
 	ErrInvalidSessionID = errors.New("session ID cannot be empty")
 	// ErrSessionNotFound is returned when session is not found in database
 	ErrSessionNotFound = errors.New("session not found in database")
+	// ErrAnonymizedMode is returned by operations that need per-user history
+	// or message content while anonymized analytics mode is enabled
+	ErrAnonymizedMode = errors.New("operation not available in anonymized analytics mode")
+	// ErrAnonymizationKeyRequired is returned when anonymized analytics mode is
+	// enabled without an anonymization salt or encryption key to key the user ID hashes
+	ErrAnonymizationKeyRequired = errors.New("anonymized analytics mode requires an anonymization salt or encryption key")
 )
 
 // retryConfig holds configuration for MongoDB retry logic
@@ -53,62 +63,187 @@ var defaultRetryConfig = retryConfig{
 // StorageService manages conversation persistence in MongoDB using gomongo
 type StorageService struct {
 	mongo         *gomongo.Mongo
+	collName      string // Name of the session collection
 	collection    *gomongo.MongoCollection
+	auditLog      *gomongo.MongoCollection // Admin action audit trail (see audit.go)
+	prompts       *gomongo.MongoCollection // Admin-managed system prompt templates (see prompts.go)
+	canned        *gomongo.MongoCollection // Admin canned responses and flows (see canned.go)
+	rollups       *gomongo.MongoCollection // Hourly and daily session metrics (see metrics_rollup.go)
+	snapshots     *gomongo.MongoCollection // Session snapshots and restore points (see snapshots.go)
+	roles         *gomongo.MongoCollection // Role permissions for admin authorization (see roles.go)
+	apiKeys       *gomongo.MongoCollection // Hashed API keys for server-to-server admin calls (see api_keys.go)
+	usage         *gomongo.MongoCollection // LLM token usage and cost per call (see usage.go)
+	settings      *gomongo.MongoCollection // Runtime settings such as read-only mode (see settings.go)
+	blocks        *gomongo.MongoCollection // Users blocked from connecting (see blocks.go)
+	reports       *gomongo.MongoCollection // Abuse reports of AI and admin messages (see reports.go)
+	keywords      *gomongo.MongoCollection // Keyword watchlists admins are alerted about (see keywords.go)
+	jobLocks      *gomongo.MongoCollection // Which replica runs each scheduled job (see jobs.go)
+	jobRuns       *gomongo.MongoCollection // Scheduled job run history (see jobs.go)
+	memory        *gomongo.MongoCollection // Facts users stated about themselves (see memory.go)
+	experiments   *gomongo.MongoCollection // Admin-managed A/B experiments (see experiments.go)
 	logger        *golog.Logger
 	encryptionKey []byte         // Key for encrypting sensitive fields
 	gcm           cipherPkg.AEAD // Pre-computed AES-GCM cipher (nil if encryption disabled)
+	anonymized    bool           // Store only hashed user IDs and aggregate counters
+	hashSalt      []byte         // Key of the user ID hashes of anonymized mode; the encryption key when empty
+
+	// Tenants whose data is anonymized like in anonymized mode (see
+	// SetAnonymizedTenants); set on the base service and copied to its views
+	anonymizedTenants map[string]bool
+
+	searchHashIndex bool // Store keyed hashes of message words for search (see search.go)
+
+	batcher *messageBatcher // Write-behind buffer of message appends (see batching.go); nil writes each message directly
+
+	// Tenant scoping (see tenant.go); set only on views returned by ForTenant
+	tenantScoped bool
+	tenantID     string
+
+	// Data residency (see residency.go). datastore names the database of a
+	// view returned by DatastoreFor; residency and datastores are set on the
+	// base service only.
+	datastore  string
+	residency  map[string]*StorageService // Tenant ID -> view of its datastore
+	datastores []*StorageService          // One view per datastore, sorted by name
+
+	// Retention purger (see retention.go)
+	restoreGrace time.Duration // How long soft-deleted sessions can be restored
+	purgeStop    chan struct{}
+	purgeOnce    sync.Once
+	purgeWg      sync.WaitGroup
+
+	// Metrics rollup aggregator (see metrics_rollup.go)
+	rollupStop chan struct{}
+	rollupOnce sync.Once
+	rollupWg   sync.WaitGroup
+
+	// Session change stream (see change_stream.go)
+	watchCancel context.CancelFunc
+	watchWg     sync.WaitGroup
 }
 
 // SessionDocument represents a session stored in MongoDB
 type SessionDocument struct {
-	ID                 string            `bson:"_id"`
-	UserID             string            `bson:"uid"`
-	Name               string            `bson:"nm"`
-	ModelID            string            `bson:"modelId"`
-	Messages           []MessageDocument `bson:"msgs"`
-	StartTime          time.Time         `bson:"ts"`
-	EndTime            *time.Time        `bson:"endTs,omitempty"`
-	Duration           int64             `bson:"dur"` // seconds
-	AdminAssisted      bool              `bson:"adminAssisted"`
-	AssistingAdminID   string            `bson:"assistingAdminId,omitempty"`
-	AssistingAdminName string            `bson:"assistingAdminName,omitempty"`
-	HelpRequested      bool              `bson:"helpRequested"`
-	TotalTokens        int               `bson:"totalTokens"`
-	LastActivity       time.Time         `bson:"lastActivity,omitempty"`
-	MaxResponseTime    int64             `bson:"maxRespTime"` // milliseconds
-	AvgResponseTime    int64             `bson:"avgRespTime"` // milliseconds
-	ShareToken         string            `bson:"shareToken,omitempty"`
-	CreatedAt          time.Time         `bson:"_ts,omitempty"` // gomongo automatic timestamp
-	ModifiedAt         time.Time         `bson:"_mt,omitempty"` // gomongo automatic timestamp
-}
-
-// MessageDocument represents a message stored in MongoDB
+	ID                 string                 `bson:"_id"`
+	UserID             string                 `bson:"uid"`
+	TenantID           string                 `bson:"tid,omitempty"` // empty for the default tenant
+	Name               string                 `bson:"nm"`
+	ModelID            string                 `bson:"modelId"`
+	PromptTemplateID   string                 `bson:"promptId,omitempty"`       // system prompt template selected at creation
+	LLMParams          *LLMParamsDocument     `bson:"llmParams,omitempty"`      // client-chosen LLM parameters
+	Client             *ClientDocument        `bson:"client,omitempty"`         // client SDK that created the session
+	ProviderThread     *ThreadDocument        `bson:"providerThread,omitempty"` // server-side LLM thread the session continues
+	Experiments        []ExperimentDocument   `bson:"experiments,omitempty"`    // A/B experiment variants assigned at creation (see experiments.go)
+	Messages           []MessageDocument      `bson:"msgs"`
+	MessageCount       int                    `bson:"msgCount,omitempty"` // message counter used in anonymized mode
+	StartTime          time.Time              `bson:"ts"`
+	EndTime            *time.Time             `bson:"endTs,omitempty"`
+	Duration           int64                  `bson:"dur"`                 // seconds
+	EndReason          string                 `bson:"endReason,omitempty"` // reason code of a session an admin force-ended (see force_end.go)
+	EndedBy            string                 `bson:"endedBy,omitempty"`   // admin who force-ended the session
+	AdminAssisted      bool                   `bson:"adminAssisted"`
+	AssistingAdminID   string                 `bson:"assistingAdminId,omitempty"`
+	AssistingAdminName string                 `bson:"assistingAdminName,omitempty"`
+	Interventions      []InterventionDocument `bson:"interventions,omitempty"` // completed admin takeovers, appended on handback
+	HelpRequested      bool                   `bson:"helpRequested"`
+	TotalTokens        int                    `bson:"totalTokens"`
+	Cost               float64                `bson:"cost,omitempty"`      // LLM cost in the configured currency (see usage.go)
+	Sentiment          *SentimentDocument     `bson:"sentiment,omitempty"` // rolling sentiment of the user's messages (see sentiment.go)
+	LastActivity       time.Time              `bson:"lastActivity,omitempty"`
+	MaxResponseTime    int64                  `bson:"maxRespTime"` // milliseconds
+	AvgResponseTime    int64                  `bson:"avgRespTime"` // milliseconds
+	ShareToken         string                 `bson:"shareToken,omitempty"`
+	ShareExpiresAt     *time.Time             `bson:"shareExpTs,omitempty"`    // when the share link stops working (see shareLinkOf)
+	Tags               []string               `bson:"tags,omitempty"`          // labels set by the user or admins (see tags.go)
+	Pins               []PinDocument          `bson:"pins,omitempty"`          // pinned messages in pin order (see pins.go)
+	Grants             []SessionGrant         `bson:"grants,omitempty"`        // users the owner shared the session with (see grants.go)
+	SearchTerms        []string               `bson:"srch,omitempty"`          // keyed word hashes for encrypted search (see search.go)
+	DeletedAt          *time.Time             `bson:"delTs,omitempty"`         // soft-delete time set by the retention purger
+	MergedFrom         []MergeDocument        `bson:"mergedFrom,omitempty"`    // sessions merged into this one (see merge.go)
+	MergedInto         string                 `bson:"mergedInto,omitempty"`    // session this one was merged into before its soft-delete
+	CreatedAt          time.Time              `bson:"_ts,omitempty"`           // gomongo automatic timestamp
+	ModifiedAt         time.Time              `bson:"_mt,omitempty"`           // gomongo automatic timestamp
+	SchemaVersion      int                    `bson:"schemaVersion,omitempty"` // document schema version (see migrations.go); set on insert only
+}
+
+// MessageDocument represents a message stored in MongoDB. The JSON names match
+// the BSON names.
 type MessageDocument struct {
-	Content   string            `bson:"content"`
-	Timestamp time.Time         `bson:"ts"`
-	Sender    string            `bson:"sender"` // "user", "ai", "admin"
-	FileID    string            `bson:"fileId,omitempty"`
-	FileURL   string            `bson:"fileUrl,omitempty"`
-	Metadata  map[string]string `bson:"meta,omitempty"`
+	Content   string            `bson:"content" json:"content"`
+	Timestamp time.Time         `bson:"ts" json:"ts"`
+	Sender    string            `bson:"sender" json:"sender"`                   // "user", "ai", "admin", "system"
+	Event     string            `bson:"event,omitempty" json:"event,omitempty"` // system event type
+	FileID    string            `bson:"fileId,omitempty" json:"fileId,omitempty"`
+	FileURL   string            `bson:"fileUrl,omitempty" json:"fileUrl,omitempty"`
+	Metadata  map[string]string `bson:"meta,omitempty" json:"meta,omitempty"`
+	Feedback  *FeedbackDocument `bson:"feedback,omitempty" json:"feedback,omitempty"` // user rating of an AI message
+	ReadAt    *time.Time        `bson:"readAt,omitempty" json:"readAt,omitempty"`     // when the user saw an admin message (read receipts only)
+	Timing    *TimingDocument   `bson:"timing,omitempty" json:"timing,omitempty"`     // pipeline stage durations of an AI reply
+}
+
+// InterventionDocument records an admin takeover that was handed back to the AI
+type InterventionDocument struct {
+	AdminID      string    `bson:"adminId" json:"adminId"`
+	AdminName    string    `bson:"adminName" json:"adminName"`
+	StartTime    time.Time `bson:"ts" json:"ts"`
+	EndTime      time.Time `bson:"endTs" json:"endTs"`
+	HandedBackBy string    `bson:"by" json:"by"`
+}
+
+// LLMParamsDocument stores the LLM parameters a client chose for its session
+type LLMParamsDocument struct {
+	Temperature *float64 `bson:"temp,omitempty" json:"temp,omitempty"`
+	MaxTokens   int      `bson:"maxTokens,omitempty" json:"maxTokens,omitempty"`
+	TopP        *float64 `bson:"topP,omitempty" json:"topP,omitempty"`
+	TimeoutSec  int      `bson:"timeoutSec,omitempty" json:"timeoutSec,omitempty"` // LLM reply timeout in seconds
+}
+
+// ThreadDocument stores the LLM provider thread a session continues
+type ThreadDocument struct {
+	ModelID string `bson:"modelId" json:"modelId"`
+	ID      string `bson:"id" json:"id"`
+}
+
+// ClientDocument stores the client SDK metadata sent when a session's first
+// connection opened
+type ClientDocument struct {
+	AppVersion string `bson:"appVer,omitempty" json:"appVer,omitempty"`
+	Platform   string `bson:"platform,omitempty" json:"platform,omitempty"`
+	UserAgent  string `bson:"ua,omitempty" json:"ua,omitempty"`
+	ScreenSize string `bson:"screen,omitempty" json:"screen,omitempty"`
 }
 
 // SessionMetadata represents summary information about a session
 type SessionMetadata struct {
-	ID                 string     `json:"id"`
-	UserID             string     `json:"user_id"`
-	Name               string     `json:"name"`
-	LastMessageTime    time.Time  `json:"last_activity"`
-	MessageCount       int        `json:"message_count"`
-	AdminAssisted      bool       `json:"admin_assisted"`
-	StartTime          time.Time  `json:"start_time"`
-	EndTime            *time.Time `json:"end_time,omitempty"`
-	IsActive           bool       `json:"is_active"`
-	Duration           int64      `json:"duration"` // seconds
-	TotalTokens        int        `json:"total_tokens"`
-	MaxResponseTime    int64      `json:"max_response_time"` // milliseconds
-	AvgResponseTime    int64      `json:"avg_response_time"` // milliseconds
-	AssistingAdminName string     `json:"assisting_admin_name,omitempty"`
-	ShareToken         string     `json:"share_token,omitempty"`
+	ID                 string         `json:"id"`
+	UserID             string         `json:"user_id"`
+	TenantID           string         `json:"tenant_id,omitempty"`
+	Name               string         `json:"name"`
+	LastMessageTime    time.Time      `json:"last_activity"`
+	MessageCount       int            `json:"message_count"`
+	AdminAssisted      bool           `json:"admin_assisted"`
+	StartTime          time.Time      `json:"start_time"`
+	EndTime            *time.Time     `json:"end_time,omitempty"`
+	IsActive           bool           `json:"is_active"`
+	Duration           int64          `json:"duration"`             // seconds
+	EndReason          string         `json:"end_reason,omitempty"` // reason code of a session an admin force-ended
+	EndedBy            string         `json:"ended_by,omitempty"`   // admin who force-ended the session
+	TotalTokens        int            `json:"total_tokens"`
+	Cost               float64        `json:"cost,omitempty"`      // LLM cost in the configured currency
+	Sentiment          *float64       `json:"sentiment,omitempty"` // rolling sentiment of the user's messages, in [-1, 1]; absent until scored
+	MaxResponseTime    int64          `json:"max_response_time"`   // milliseconds
+	AvgResponseTime    int64          `json:"avg_response_time"`   // milliseconds
+	AssistingAdminName string         `json:"assisting_admin_name,omitempty"`
+	ShareToken         string         `json:"share_token,omitempty"`
+	ShareExpiresAt     *time.Time     `json:"share_expires_at,omitempty"`
+	Tags               []string       `json:"tags,omitempty"`
+	Grants             []SessionGrant `json:"grants,omitempty"`      // users the owner shared the session with
+	AppVersion         string         `json:"app_version,omitempty"` // client SDK that created the session
+	Platform           string         `json:"platform,omitempty"`
+	UserAgent          string         `json:"user_agent,omitempty"`
+	ScreenSize         string         `json:"screen_size,omitempty"`
+	// Experiments maps the A/B experiments the session takes part in to its variant
+	Experiments map[string]string `json:"experiments,omitempty"`
 }
 
 // buildSessionMetadata constructs a SessionMetadata from a SessionDocument,
@@ -122,30 +257,67 @@ func buildSessionMetadata(doc *SessionDocument, lastMessageTime time.Time) *Sess
 		duration = int64(time.Since(doc.StartTime).Seconds())
 	}
 
-	return &SessionMetadata{
+	meta := &SessionMetadata{
 		ID:                 doc.ID,
 		UserID:             doc.UserID,
+		TenantID:           doc.TenantID,
 		Name:               doc.Name,
 		LastMessageTime:    lastMessageTime,
-		MessageCount:       len(doc.Messages),
+		MessageCount:       messageCountFromDoc(doc),
 		AdminAssisted:      doc.AdminAssisted,
 		StartTime:          doc.StartTime,
 		EndTime:            doc.EndTime,
 		IsActive:           isActive,
 		Duration:           duration,
+		EndReason:          doc.EndReason,
+		EndedBy:            doc.EndedBy,
 		TotalTokens:        doc.TotalTokens,
+		Cost:               doc.Cost,
 		MaxResponseTime:    doc.MaxResponseTime,
 		AvgResponseTime:    doc.AvgResponseTime,
 		AssistingAdminName: doc.AssistingAdminName,
 		ShareToken:         doc.ShareToken,
+		Tags:               doc.Tags,
+		Grants:             doc.Grants,
+	}
+	// No else needed: optional operation (only shared sessions)
+	if doc.ShareToken != "" {
+		meta.ShareExpiresAt = shareLinkOf(doc).ExpiresAt
+	}
+	// No else needed: optional operation (only sessions with scored messages)
+	if doc.Sentiment != nil {
+		score := doc.Sentiment.Score
+		meta.Sentiment = &score
+	}
+	// No else needed: optional operation (only sessions created with client metadata)
+	if doc.Client != nil {
+		meta.AppVersion = doc.Client.AppVersion
+		meta.Platform = doc.Client.Platform
+		meta.UserAgent = doc.Client.UserAgent
+		meta.ScreenSize = doc.Client.ScreenSize
+	}
+	// No else needed: optional operation (only sessions assigned to experiments)
+	if len(doc.Experiments) > 0 {
+		meta.Experiments = make(map[string]string, len(doc.Experiments))
+		for _, e := range doc.Experiments {
+			meta.Experiments[e.ID] = e.Variant
+		}
 	}
+	return meta
+}
+
+// messageCountFromDoc returns the number of messages recorded for a session.
+// Anonymized sessions keep no message array, only the msgCount counter.
+func messageCountFromDoc(doc *SessionDocument) int {
+	return len(doc.Messages) + doc.MessageCount
 }
 
 // SessionListOptions defines filtering, sorting, and pagination options for listing sessions
 type SessionListOptions struct {
 	// Pagination
-	Limit  int // Maximum number of results to return (default: 100, max: 1000)
-	Offset int // Number of results to skip for pagination
+	Limit  int    // Maximum number of results to return (default: 100, max: 1000)
+	Offset int    // Number of results to skip for pagination (ignored when After is set)
+	After  string // Cursor from SessionCursor; resumes after that session (start time sort only)
 
 	// Filtering
 	UserID        string     // Filter by specific user ID
@@ -153,6 +325,9 @@ type SessionListOptions struct {
 	StartTimeTo   *time.Time // Filter sessions starting before this time
 	AdminAssisted *bool      // Filter by admin assistance status (nil = all, true = assisted only, false = not assisted)
 	Active        *bool      // Filter by active status (nil = all, true = active only, false = ended only)
+	Tags          []string   // Filter by tags (sessions having all of them)
+	AppVersion    string     // Filter by client SDK app version
+	Platform      string     // Filter by client platform
 
 	// Sorting
 	SortBy    string // Field to sort by: "ts", "endTs", "message_count", "totalTokens", "uid"
@@ -169,6 +344,12 @@ type Metrics struct {
 	AvgResponseTime    int64 // milliseconds
 	MaxResponseTime    int64 // milliseconds
 	AdminAssistedCount int
+	TagCounts          map[string]int // sessions per tag, for the constants.MaxTagCounts most used tags
+	EndReasonCounts    map[string]int // sessions force-ended by an admin, per reason code
+	FeedbackUp         int            // AI messages rated up
+	FeedbackDown       int            // AI messages rated down
+	// FeedbackPositiveRate is the share of rated AI messages rated up, 0 when none are rated
+	FeedbackPositiveRate float64
 }
 
 // NewStorageService creates a new storage service using gomongo
@@ -182,27 +363,187 @@ func NewStorageService(mongo *gomongo.Mongo, dbName, collName string, logger *go
 
 	svc := &StorageService{
 		mongo:         mongo,
+		collName:      collName,
 		collection:    collection,
+		auditLog:      mongo.Coll(dbName, constants.AuditCollection),
+		prompts:       mongo.Coll(dbName, constants.PromptCollection),
+		canned:        mongo.Coll(dbName, constants.CannedCollection),
+		rollups:       mongo.Coll(dbName, constants.RollupCollection),
+		snapshots:     mongo.Coll(dbName, constants.SnapshotCollection),
+		roles:         mongo.Coll(dbName, constants.RoleCollection),
+		apiKeys:       mongo.Coll(dbName, constants.APIKeyCollection),
+		usage:         mongo.Coll(dbName, constants.UsageCollection),
+		settings:      mongo.Coll(dbName, constants.SettingsCollection),
+		blocks:        mongo.Coll(dbName, constants.BlockCollection),
+		reports:       mongo.Coll(dbName, constants.ReportCollection),
+		keywords:      mongo.Coll(dbName, constants.KeywordCollection),
+		jobLocks:      mongo.Coll(dbName, constants.JobLockCollection),
+		jobRuns:       mongo.Coll(dbName, constants.JobRunCollection),
+		memory:        mongo.Coll(dbName, constants.MemoryCollection),
+		experiments:   mongo.Coll(dbName, constants.ExperimentCollection),
 		logger:        logger,
 		encryptionKey: encryptionKey,
+		gcm:           newGCM(encryptionKey, logger),
 	}
 
-	// Pre-compute AES-GCM cipher to avoid per-call key schedule overhead
-	if len(encryptionKey) > 0 {
-		block, err := aes.NewCipher(encryptionKey)
-		if err != nil {
-			logger.Error("AES-GCM cipher initialization failed, encryption disabled", "error", err)
-		} else {
-			gcm, err := cipherPkg.NewGCM(block)
-			if err != nil {
-				logger.Error("AES-GCM initialization failed, encryption disabled", "error", err)
-			} else {
-				svc.gcm = gcm
-			}
+	return svc
+}
+
+// newGCM pre-computes the AES-GCM cipher for encryptionKey to avoid per-call key
+// schedule overhead. Returns nil when the key is empty or invalid.
+func newGCM(encryptionKey []byte, logger *golog.Logger) cipherPkg.AEAD {
+	if len(encryptionKey) == 0 {
+		return nil
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		logger.Error("AES-GCM cipher initialization failed, encryption disabled", "error", err)
+		return nil
+	}
+	gcm, err := cipherPkg.NewGCM(block)
+	if err != nil {
+		logger.Error("AES-GCM initialization failed, encryption disabled", "error", err)
+		return nil
+	}
+	return gcm
+}
+
+// SetAnonymizedMode enables or disables anonymized analytics mode for every tenant.
+// When enabled, user IDs are stored as keyed hashes and message content,
+// session names, and per-user history are never persisted. Only aggregate
+// counters (message count, tokens, response times) are kept. The hashes are
+// keyed with the anonymization salt or the encryption key, without which they
+// could be reversed by hashing every known user ID, so enabling the mode
+// without either returns ErrAnonymizationKeyRequired.
+// Must be called before the service handles any requests.
+func (s *StorageService) SetAnonymizedMode(enabled bool) error {
+	// No else needed: early return pattern (guard clause)
+	if enabled && len(s.userIDHashKey()) == 0 {
+		return ErrAnonymizationKeyRequired
+	}
+	s.anonymized = enabled
+	return nil
+}
+
+// SetAnonymizedTenants enables anonymized analytics mode for the listed tenants
+// only; see SetAnonymizedMode. Tenant views returned by ForTenant are anonymized
+// for these tenants, and sessions the base service writes are anonymized by
+// their tenant. The default tenant can only be anonymized with SetAnonymizedMode.
+// Must be called on the base service before it is used.
+func (s *StorageService) SetAnonymizedTenants(tenantIDs []string) error {
+	// No else needed: early return pattern (guard clause)
+	if len(tenantIDs) > 0 && len(s.userIDHashKey()) == 0 {
+		return ErrAnonymizationKeyRequired
+	}
+	tenants := make(map[string]bool, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		// No else needed: early return pattern (guard clause)
+		if tenantID == "" {
+			return fmt.Errorf("the default tenant cannot be anonymized on its own")
 		}
+		tenants[tenantID] = true
+	}
+	s.anonymizedTenants = tenants
+	for _, view := range s.datastores {
+		view.anonymizedTenants = tenants
 	}
+	return nil
+}
 
-	return svc
+// SetAnonymizationSalt sets the secret keying the user ID hashes of anonymized
+// mode instead of the encryption key. Changing it changes every stored hash.
+// Must be called before SetAnonymizedMode and SetAnonymizedTenants.
+func (s *StorageService) SetAnonymizationSalt(salt []byte) {
+	s.hashSalt = salt
+}
+
+// userIDHashKey returns the key of the user ID hashes of anonymized mode
+func (s *StorageService) userIDHashKey() []byte {
+	// No else needed: early return pattern (dedicated salt configured)
+	if len(s.hashSalt) > 0 {
+		return s.hashSalt
+	}
+	return s.encryptionKey
+}
+
+// IsAnonymized reports whether anonymized analytics mode is enabled for every
+// tenant, or on a tenant view, for the view's tenant.
+func (s *StorageService) IsAnonymized() bool {
+	return s.anonymized
+}
+
+// IsAnonymizedTenant reports whether the data of tenantID is anonymized
+func (s *StorageService) IsAnonymizedTenant(tenantID string) bool {
+	return s.anonymized || s.anonymizedTenants[tenantID]
+}
+
+// StoredUserID returns the identifier under which a user's sessions are stored.
+// In anonymized mode this is an HMAC-SHA256 of the user ID keyed with the
+// anonymization salt or encryption key; otherwise it is the user ID itself. On the base service,
+// users of tenants anonymized with SetAnonymizedTenants are only hashed by
+// StoredUserIDFor.
+// Callers comparing ownership or filtering by user must go through this method.
+func (s *StorageService) StoredUserID(userID string) string {
+	// No else needed: early return pattern (guard clause)
+	if !s.anonymized || userID == "" {
+		return userID
+	}
+	return s.hashUserID(userID)
+}
+
+// StoredUserIDFor returns the identifier under which the sessions of a user of
+// tenantID are stored; see StoredUserID
+func (s *StorageService) StoredUserIDFor(tenantID, userID string) string {
+	// No else needed: early return pattern (guard clause)
+	if !s.IsAnonymizedTenant(tenantID) || userID == "" {
+		return userID
+	}
+	return s.hashUserID(userID)
+}
+
+// hashUserID returns the keyed hash of a user ID stored in anonymized mode
+func (s *StorageService) hashUserID(userID string) string {
+	mac := hmac.New(sha256.New, s.userIDHashKey())
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// userIDFilter returns the filter value matching the stored sessions of userID.
+// An unscoped service with anonymized tenants matches both the plain and the
+// hashed user ID.
+func (s *StorageService) userIDFilter(userID string) interface{} {
+	// No else needed: early return pattern (one stored form of the user ID)
+	if s.anonymized || s.tenantScoped || len(s.anonymizedTenants) == 0 || userID == "" {
+		return s.StoredUserID(userID)
+	}
+	return bson.M{"$in": bson.A{userID, s.hashUserID(userID)}}
+}
+
+// anonymizedTenantList returns the tenants anonymized with SetAnonymizedTenants
+func (s *StorageService) anonymizedTenantList() bson.A {
+	tenants := make(bson.A, 0, len(s.anonymizedTenants))
+	for tenantID := range s.anonymizedTenants {
+		tenants = append(tenants, tenantID)
+	}
+	return tenants
+}
+
+// hasAnonymizedTenants reports whether calls on this service that are not bound
+// to a tenant must tell the sessions of anonymized tenants apart. Tenant views
+// and services anonymizing every tenant need not.
+func (s *StorageService) hasAnonymizedTenants() bool {
+	return !s.anonymized && !s.tenantScoped && len(s.anonymizedTenants) > 0
+}
+
+// withoutAnonymizedTenants excludes the sessions of anonymized tenants from a
+// filter of a call that is not bound to a tenant
+func (s *StorageService) withoutAnonymizedTenants(filter bson.M) bson.M {
+	// No else needed: early return pattern (no tenant to exclude)
+	if !s.hasAnonymizedTenants() {
+		return filter
+	}
+	filter[constants.MongoFieldTenantID] = bson.M{"$nin": s.anonymizedTenantList()}
+	return filter
 }
 
 // isRetryableError checks if an error is retryable (transient)
@@ -286,14 +627,57 @@ func (s *StorageService) EnsureIndexes(ctx context.Context) error {
 		Options: options.Index().SetName(constants.IndexShareToken).SetUnique(true).SetSparse(true),
 	}
 
-	// Create all indexes
+	// Create sparse index for delTs - used by the retention purger to find sessions to hard-delete
+	deletedAtIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: constants.MongoFieldDeletedAt, Value: 1}},
+		Options: options.Index().SetName(constants.IndexDeletedAt).SetSparse(true),
+	}
+
+	// Create compound index for tenant-scoped listings (tenant + start_time)
+	tenantIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: constants.MongoFieldTenantID, Value: 1},
+			{Key: constants.MongoFieldTimestamp, Value: -1},
+		},
+		Options: options.Index().SetName(constants.IndexTenantStart).
+			SetPartialFilterExpression(bson.M{constants.MongoFieldTenantID: bson.M{"$exists": true}}),
+	}
+
+	// Create sparse multikey index for tags - used for tag filtering in admin listings
+	tagsIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: constants.MongoFieldTags, Value: 1}},
+		Options: options.Index().SetName(constants.IndexTags).SetSparse(true),
+	}
+
+	// Create sparse multikey index for grants - used to list the sessions shared with a user
+	grantsIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: constants.MongoFieldGrantUserID, Value: 1}},
+		Options: options.Index().SetName(constants.IndexGrants).SetSparse(true),
+	}
+
+	// Create sparse multikey index for experiment variants - used for per-variant metrics
+	experimentsIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: constants.MongoFieldExperimentID, Value: 1},
+			{Key: constants.MongoFieldTimestamp, Value: 1},
+		},
+		Options: options.Index().SetName(constants.IndexExperiments).SetSparse(true),
+	}
+
+	// Create all indexes, plus the message search index for this deployment's storage mode
 	indexes := []mongo.IndexModel{
 		userIDIndex,
 		startTimeIndex,
 		adminAssistedIndex,
 		compoundIndex,
 		shareTokenIndex,
+		deletedAtIndex,
+		tenantIndex,
+		tagsIndex,
+		grantsIndex,
+		experimentsIndex,
 	}
+	indexes = append(indexes, s.searchIndexes()...)
 
 	_, err := s.collection.CreateIndexes(ctx, indexes)
 	// No else needed: early return pattern (guard clause)
@@ -301,8 +685,66 @@ func (s *StorageService) EnsureIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureAuditIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensurePromptIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureRollupIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureSnapshotIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureUsageIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureCannedIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureBlockIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureReportIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureKeywordIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureJobIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureMemoryIndexes(ctx); err != nil {
+		return err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := s.ensureExperimentIndexes(ctx); err != nil {
+		return err
+	}
+	for _, view := range s.datastores {
+		// No else needed: early return pattern (guard clause)
+		if err := view.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("datastore %s: %w", view.datastore, err)
+		}
+	}
+
 	s.logger.Info("MongoDB indexes created successfully",
-		"indexes", []string{constants.IndexUserID, constants.IndexStartTime, constants.IndexAdminAssisted, constants.IndexUserStartTime, constants.IndexShareToken},
+		"indexes", []string{constants.IndexUserID, constants.IndexStartTime, constants.IndexAdminAssisted, constants.IndexUserStartTime, constants.IndexShareToken, constants.IndexDeletedAt, constants.IndexTenantStart, constants.IndexTags, constants.IndexExperiments,
+			constants.IndexAuditTime, constants.IndexAuditActor, constants.IndexAuditSession, constants.IndexPromptTenant, constants.IndexRollupBucket, constants.IndexSnapshots, constants.IndexUsageTime, constants.IndexUsageTenant, constants.IndexCannedTenant,
+			constants.IndexBlockTenant, constants.IndexBlockExpiry, constants.IndexReportTenant, constants.IndexReportSession, constants.IndexKeywordTenant,
+			constants.IndexJobRuns, constants.IndexJobRunExpiry, constants.IndexMemoryUser, constants.IndexExperimentTenant},
 	)
 
 	return nil
@@ -330,6 +772,29 @@ func (s *StorageService) CreateSession(sess *session.Session) error {
 
 	// Convert session to document
 	doc := s.sessionToDocument(sess)
+	doc.SchemaVersion = SessionSchemaVersion
+
+	// Encrypt the content of sessions created with messages (e.g. forks)
+	// No else needed: optional operation (only encrypt if key is available)
+	if len(s.encryptionKey) > 0 {
+		// No else needed: optional operation (searchable hashes only when enabled)
+		if s.usesSearchHashIndex() {
+			var text strings.Builder
+			for _, msg := range doc.Messages {
+				text.WriteString(msg.Content)
+				text.WriteString(" ")
+			}
+			doc.SearchTerms = s.hashTerms(searchTerms(text.String()))
+		}
+		for i := range doc.Messages {
+			encrypted, err := s.sealMessage(doc.ID, i, doc.Messages[i].Content)
+			// No else needed: early return pattern (guard clause)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt message content: %w", err)
+			}
+			doc.Messages[i].Content = encrypted
+		}
+	}
 
 	// Insert document with retry logic for transient errors
 	err := s.retryOperation(ctx, "CreateSession", func() error {
@@ -417,10 +882,16 @@ func (s *StorageService) UpdateSessionName(sessionID, name string) error {
 		return ErrInvalidSessionID
 	}
 
+	// Session names are derived from message content and are not stored in anonymized mode
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil
+	}
+
 	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
 	defer cancel()
 
-	filter := bson.M{constants.MongoFieldID: sessionID}
+	filter := s.withoutAnonymizedTenants(bson.M{constants.MongoFieldID: sessionID})
 	update := bson.M{"$set": bson.M{"nm": name}}
 
 	var result *mongo.UpdateResult
@@ -433,11 +904,30 @@ func (s *StorageService) UpdateSessionName(sessionID, name string) error {
 		return fmt.Errorf("failed to update session name: %w", err)
 	}
 	if result.MatchedCount == 0 {
+		// Names of anonymized tenants' sessions are dropped like in anonymized mode
+		// No else needed: early return pattern (session of an anonymized tenant)
+		if s.isAnonymizedTenantSession(ctx, sessionID) {
+			return nil
+		}
 		return ErrSessionNotFound
 	}
 	return nil
 }
 
+// isAnonymizedTenantSession reports whether sessionID belongs to a tenant
+// anonymized with SetAnonymizedTenants
+func (s *StorageService) isAnonymizedTenantSession(ctx context.Context, sessionID string) bool {
+	// No else needed: early return pattern (no anonymized tenants)
+	if !s.hasAnonymizedTenants() {
+		return false
+	}
+	count, err := s.collection.CountDocuments(ctx, bson.M{
+		constants.MongoFieldID:       sessionID,
+		constants.MongoFieldTenantID: bson.M{"$in": s.anonymizedTenantList()},
+	})
+	return err == nil && count > 0
+}
+
 // UpdateSessionModelID persists the selected model ID for a session.
 func (s *StorageService) UpdateSessionModelID(sessionID, modelID string) error {
 	if sessionID == "" {
@@ -465,17 +955,218 @@ func (s *StorageService) UpdateSessionModelID(sessionID, modelID string) error {
 	return nil
 }
 
-// SetShareToken sets the share token for a session in MongoDB.
-func (s *StorageService) SetShareToken(sessionID, token string) error {
+// UpdateSessionLLMParams persists the client-chosen LLM parameters for a session.
+func (s *StorageService) UpdateSessionLLMParams(sessionID string, params *session.LLMParams) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := bson.M{constants.MongoFieldID: sessionID}
+	update := bson.M{"$set": bson.M{constants.MongoFieldLLMParams: llmParamsToDocument(params)}}
+
+	var result *mongo.UpdateResult
+	err := s.retryOperation(ctx, "UpdateSessionLLMParams", func() error {
+		var err error
+		result, err = s.collection.UpdateOne(ctx, filter, update)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update session LLM parameters: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// UpdateSessionProviderThread persists the LLM provider thread a session
+// continues; nil clears it.
+func (s *StorageService) UpdateSessionProviderThread(sessionID string, thread *session.ProviderThread) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := bson.M{constants.MongoFieldID: sessionID}
+	update := bson.M{"$unset": bson.M{constants.MongoFieldThread: ""}}
+	// No else needed: optional operation (nil clears the thread)
+	if thread != nil {
+		update = bson.M{"$set": bson.M{constants.MongoFieldThread: threadToDocument(thread)}}
+	}
+
+	var result *mongo.UpdateResult
+	err := s.retryOperation(ctx, "UpdateSessionProviderThread", func() error {
+		var err error
+		result, err = s.collection.UpdateOne(ctx, filter, update)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update session provider thread: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// llmParamsToDocument converts session LLM parameters for storage; nil stays nil
+func llmParamsToDocument(params *session.LLMParams) *LLMParamsDocument {
+	if params == nil {
+		return nil
+	}
+	return &LLMParamsDocument{
+		Temperature: params.Temperature,
+		MaxTokens:   params.MaxTokens,
+		TopP:        params.TopP,
+		TimeoutSec:  int(params.Timeout / time.Second),
+	}
+}
+
+// llmParamsFromDocument converts stored LLM parameters back; nil stays nil
+func llmParamsFromDocument(doc *LLMParamsDocument) *session.LLMParams {
+	if doc == nil {
+		return nil
+	}
+	return &session.LLMParams{
+		Temperature: doc.Temperature,
+		MaxTokens:   doc.MaxTokens,
+		TopP:        doc.TopP,
+		Timeout:     time.Duration(doc.TimeoutSec) * time.Second,
+	}
+}
+
+// threadToDocument converts a session's provider thread for storage; nil stays nil
+func threadToDocument(thread *session.ProviderThread) *ThreadDocument {
+	if thread == nil {
+		return nil
+	}
+	return &ThreadDocument{ModelID: thread.ModelID, ID: thread.ID}
+}
+
+// threadFromDocument converts a stored provider thread back; nil stays nil
+func threadFromDocument(doc *ThreadDocument) *session.ProviderThread {
+	if doc == nil {
+		return nil
+	}
+	return &session.ProviderThread{ModelID: doc.ModelID, ID: doc.ID}
+}
+
+// clientToDocument converts client SDK metadata to its stored form
+func clientToDocument(info *session.ClientInfo) *ClientDocument {
+	// No else needed: early return pattern (no metadata sent)
+	if info == nil {
+		return nil
+	}
+	return &ClientDocument{
+		AppVersion: info.AppVersion,
+		Platform:   info.Platform,
+		UserAgent:  info.UserAgent,
+		ScreenSize: info.ScreenSize,
+	}
+}
+
+// clientFromDocument converts stored client SDK metadata back
+func clientFromDocument(doc *ClientDocument) *session.ClientInfo {
+	// No else needed: early return pattern (no metadata stored)
+	if doc == nil {
+		return nil
+	}
+	return &session.ClientInfo{
+		AppVersion: doc.AppVersion,
+		Platform:   doc.Platform,
+		UserAgent:  doc.UserAgent,
+		ScreenSize: doc.ScreenSize,
+	}
+}
+
+// RecordHandback appends a completed admin intervention to the session document
+// and clears the assisting admin, so the session is routed to the LLM again
+// after a restart.
+func (s *StorageService) RecordHandback(sessionID string, intervention *session.AdminIntervention) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+	if intervention == nil {
+		return errors.New("intervention cannot be nil")
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := bson.M{constants.MongoFieldID: sessionID}
+	update := bson.M{
+		"$push": bson.M{constants.MongoFieldInterventions: InterventionDocument{
+			AdminID:      intervention.AdminID,
+			AdminName:    intervention.AdminName,
+			StartTime:    intervention.StartTime,
+			EndTime:      intervention.EndTime,
+			HandedBackBy: intervention.HandedBackBy,
+		}},
+		"$unset": bson.M{"assistingAdminId": "", "assistingAdminName": ""},
+	}
+
+	var result *mongo.UpdateResult
+	err := s.retryOperation(ctx, "RecordHandback", func() error {
+		var err error
+		result, err = s.collection.UpdateOne(ctx, filter, update)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record handback: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// ShareLink is the public read-only link of a shared session
+type ShareLink struct {
+	Token     string     `json:"share_token"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// shareLinkOf returns the share link of doc. Links created before share links
+// expired have no expiry stored until migration 3 backfills it, so they expire
+// constants.DefaultShareLinkTTL after the session started.
+func shareLinkOf(doc *SessionDocument) *ShareLink {
+	link := &ShareLink{Token: doc.ShareToken, ExpiresAt: doc.ShareExpiresAt}
+	// No else needed: optional operation (legacy links get the default lifetime)
+	if link.ExpiresAt == nil {
+		expiresAt := doc.StartTime.Add(constants.DefaultShareLinkTTL)
+		link.ExpiresAt = &expiresAt
+	}
+	return link
+}
+
+// Expired reports whether the link stopped working at now
+func (l *ShareLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// SetShareToken sets the share token of a session and when it expires in
+// MongoDB, replacing any previous link
+func (s *StorageService) SetShareToken(sessionID, token string, expiresAt time.Time) error {
 	if sessionID == "" {
 		return ErrInvalidSessionID
 	}
+	if s.anonymized {
+		return ErrAnonymizedMode
+	}
 
 	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
 	defer cancel()
 
 	filter := bson.M{constants.MongoFieldID: sessionID}
-	update := bson.M{"$set": bson.M{constants.MongoFieldShareToken: token}}
+	update := bson.M{"$set": bson.M{
+		constants.MongoFieldShareToken:   token,
+		constants.MongoFieldShareExpires: expiresAt.UTC(),
+	}}
 
 	var result *mongo.UpdateResult
 	err := s.retryOperation(ctx, "SetShareToken", func() error {
@@ -492,16 +1183,48 @@ func (s *StorageService) SetShareToken(sessionID, token string) error {
 	return nil
 }
 
+// RevokeShareToken removes the share link of a session, so its token stops
+// working. Returns ErrSessionNotFound when the session does not exist.
+func (s *StorageService) RevokeShareToken(sessionID string) error {
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := bson.M{constants.MongoFieldID: sessionID}
+	update := bson.M{"$unset": bson.M{
+		constants.MongoFieldShareToken:   "",
+		constants.MongoFieldShareExpires: "",
+	}}
+
+	var result *mongo.UpdateResult
+	err := s.retryOperation(ctx, "RevokeShareToken", func() error {
+		var err error
+		result, err = s.collection.UpdateOne(ctx, filter, update)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke share token: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
 // GetSessionByShareToken retrieves a session from MongoDB by its share token.
-func (s *StorageService) GetSessionByShareToken(token string) (*session.Session, error) {
+// Expired links are not found.
+func (s *StorageService) GetSessionByShareToken(token string) (*session.Session, *ShareLink, error) {
 	if token == "" {
-		return nil, errors.New("share token cannot be empty")
+		return nil, nil, errors.New("share token cannot be empty")
 	}
 
 	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
 	defer cancel()
 
-	filter := bson.M{constants.MongoFieldShareToken: token}
+	filter := notDeleted(bson.M{constants.MongoFieldShareToken: token})
 	var doc SessionDocument
 
 	err := s.retryOperation(ctx, "GetSessionByShareToken", func() error {
@@ -511,55 +1234,76 @@ func (s *StorageService) GetSessionByShareToken(token string) (*session.Session,
 
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, ErrSessionNotFound
+			// Links carry no tenant, so the datastores of pinned tenants are searched too
+			for _, view := range s.datastores {
+				// No else needed: optional operation (keep looking in the next datastore)
+				if sess, link, viewErr := view.GetSessionByShareToken(token); !errors.Is(viewErr, ErrSessionNotFound) {
+					return sess, link, viewErr
+				}
+			}
+			return nil, nil, ErrSessionNotFound
 		}
-		return nil, fmt.Errorf("failed to get session by share token: %w", err)
+		return nil, nil, fmt.Errorf("failed to get session by share token: %w", err)
 	}
 
+	link := shareLinkOf(&doc)
+	// No else needed: early return pattern (expired links are gone)
+	if link.Expired(time.Now()) {
+		return nil, nil, ErrSessionNotFound
+	}
 	sess := s.documentToSession(&doc)
-	return sess, nil
+	return sess, link, nil
 }
 
-// GetShareToken retrieves the share token for a session from MongoDB.
-// Returns empty string if session has no share token.
-func (s *StorageService) GetShareToken(sessionID string) (string, error) {
+// GetShareLink retrieves the share link of a session from MongoDB.
+// Returns nil if the session has no share link.
+func (s *StorageService) GetShareLink(sessionID string) (*ShareLink, error) {
 	if sessionID == "" {
-		return "", ErrInvalidSessionID
+		return nil, ErrInvalidSessionID
 	}
 
 	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
 	defer cancel()
 
-	filter := bson.M{constants.MongoFieldID: sessionID}
+	filter := s.scope(bson.M{constants.MongoFieldID: sessionID})
 
 	var doc SessionDocument
-	err := s.retryOperation(ctx, "GetShareToken", func() error {
+	err := s.retryOperation(ctx, "GetShareLink", func() error {
 		result := s.collection.FindOne(ctx, filter)
 		return result.Decode(&doc)
 	})
 
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return "", ErrSessionNotFound
+			return nil, ErrSessionNotFound
 		}
-		return "", fmt.Errorf("failed to get share token: %w", err)
+		return nil, fmt.Errorf("failed to get share link: %w", err)
 	}
 
-	return doc.ShareToken, nil
+	// No else needed: early return pattern (not shared)
+	if doc.ShareToken == "" {
+		return nil, nil
+	}
+	return shareLinkOf(&doc), nil
 }
 
 // GetSession retrieves a session from MongoDB by ID
 func (s *StorageService) GetSession(sessionID string) (*session.Session, error) {
+	return s.getSession(sessionID, s.scope(bson.M{constants.MongoFieldID: sessionID}))
+}
+
+// getSession retrieves the session sessionID if it matches filter
+func (s *StorageService) getSession(sessionID string, filter bson.M) (*session.Session, error) {
 	// No else needed: early return pattern (guard clause)
 	if sessionID == "" {
 		return nil, ErrInvalidSessionID
 	}
+	s.flushPending(sessionID)
 
 	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
 	defer cancel()
 
 	// Find document with retry logic for transient errors
-	filter := bson.M{constants.MongoFieldID: sessionID}
 	var doc SessionDocument
 
 	err := s.retryOperation(ctx, "GetSession", func() error {
@@ -579,9 +1323,22 @@ func (s *StorageService) GetSession(sessionID string) (*session.Session, error)
 	// Convert document to session
 	sess := s.documentToSession(&doc)
 
+	// No else needed: optional operation (only report failed integrity checks)
+	if tampered := tamperedMessages(sess); len(tampered) > 0 {
+		s.logger.Error("Message integrity check failed",
+			"session_id", sessionID,
+			"message_indexes", tampered)
+	}
+
 	return sess, nil
 }
 
+// notDeleted adds a condition excluding soft-deleted sessions to a query filter
+func notDeleted(filter bson.M) bson.M {
+	filter[constants.MongoFieldDeletedAt] = bson.M{"$exists": false}
+	return filter
+}
+
 // sessionToDocument converts a Session to a SessionDocument
 // This method acquires a read lock on the session to ensure thread-safe access
 func (s *StorageService) sessionToDocument(sess *session.Session) *SessionDocument {
@@ -596,9 +1353,12 @@ func (s *StorageService) sessionToDocument(sess *session.Session) *SessionDocume
 			Content:   msg.Content,
 			Timestamp: msg.Timestamp,
 			Sender:    msg.Sender,
+			Event:     msg.Event,
 			FileID:    msg.FileID,
 			FileURL:   msg.FileURL,
 			Metadata:  msg.Metadata,
+			ReadAt:    msg.ReadAt,
+			Timing:    timingToDocument(msg.Timing),
 		}
 	}
 
@@ -629,11 +1389,18 @@ func (s *StorageService) sessionToDocument(sess *session.Session) *SessionDocume
 		avgResponseTime = (total / time.Duration(len(sess.ResponseTimes))).Milliseconds()
 	}
 
-	return &SessionDocument{
+	doc := &SessionDocument{
 		ID:                 sess.ID,
 		UserID:             sess.UserID,
+		TenantID:           sess.TenantID,
 		Name:               sess.Name,
 		ModelID:            sess.ModelID,
+		PromptTemplateID:   sess.PromptTemplateID,
+		LLMParams:          llmParamsToDocument(sess.LLMParams),
+		Client:             clientToDocument(sess.Client),
+		ProviderThread:     threadToDocument(sess.ProviderThread),
+		Experiments:        experimentsToDocuments(sess.Experiments),
+		Sentiment:          sentimentToDocument(sess.Sentiment),
 		Messages:           messages,
 		StartTime:          sess.StartTime,
 		EndTime:            sess.EndTime,
@@ -646,6 +1413,23 @@ func (s *StorageService) sessionToDocument(sess *session.Session) *SessionDocume
 		MaxResponseTime:    maxResponseTime,
 		AvgResponseTime:    avgResponseTime,
 	}
+
+	// Strip identifying data and keep only counters in anonymized mode
+	// No else needed: optional operation (only anonymize if enabled)
+	if s.IsAnonymizedTenant(sess.TenantID) {
+		doc.UserID = s.StoredUserIDFor(sess.TenantID, sess.UserID)
+		doc.Name = ""
+		doc.Messages = []MessageDocument{}
+		doc.MessageCount = len(messages)
+		// The user agent and screen size help fingerprint users; the SDK version does not
+		// No else needed: optional operation (only sessions created with client metadata)
+		if doc.Client != nil {
+			doc.Client.UserAgent = ""
+			doc.Client.ScreenSize = ""
+		}
+	}
+
+	return doc
 }
 
 // documentToSession converts a SessionDocument to a Session
@@ -654,24 +1438,35 @@ func (s *StorageService) documentToSession(doc *SessionDocument) *session.Sessio
 	messages := make([]*session.Message, len(doc.Messages))
 	for i, msg := range doc.Messages {
 		content := msg.Content
+		metadata := msg.Metadata
 		// Decrypt content if encryption key is provided
 		// No else needed: optional operation (only decrypt if key is available)
 		if len(s.encryptionKey) > 0 {
-			decrypted, err := s.decrypt(msg.Content)
-			// No else needed: optional operation (fallback to original on error)
-			if err == nil {
-				content = decrypted
+			// Content that is not a ciphertext is kept as is (might be unencrypted)
+			var tampered bool
+			content, tampered = s.openMessage(doc.ID, i, msg.Content)
+			// Flag messages whose ciphertext was modified or moved from elsewhere
+			// No else needed: optional operation (only flag failed integrity checks)
+			if tampered {
+				metadata = make(map[string]string, len(msg.Metadata)+1)
+				for k, v := range msg.Metadata {
+					metadata[k] = v
+				}
+				metadata[constants.MetadataKeyIntegrity] = constants.MessageIntegrityTampered
 			}
-			// If decryption fails, use original content (might be unencrypted)
 		}
 
 		messages[i] = &session.Message{
 			Content:   content,
 			Timestamp: msg.Timestamp,
 			Sender:    msg.Sender,
+			Event:     msg.Event,
 			FileID:    msg.FileID,
 			FileURL:   msg.FileURL,
-			Metadata:  msg.Metadata,
+			Metadata:  metadata,
+			Feedback:  s.feedbackFromDocument(msg.Feedback),
+			ReadAt:    msg.ReadAt,
+			Timing:    timingFromDocument(msg.Timing),
 		}
 	}
 
@@ -693,9 +1488,17 @@ func (s *StorageService) documentToSession(doc *SessionDocument) *session.Sessio
 	return &session.Session{
 		ID:                 doc.ID,
 		UserID:             doc.UserID,
+		TenantID:           doc.TenantID,
 		Name:               doc.Name,
 		ModelID:            doc.ModelID,
+		PromptTemplateID:   doc.PromptTemplateID,
+		LLMParams:          llmParamsFromDocument(doc.LLMParams),
+		Client:             clientFromDocument(doc.Client),
+		ProviderThread:     threadFromDocument(doc.ProviderThread),
+		Experiments:        experimentsFromDocuments(doc.Experiments),
+		Sentiment:          sentimentFromDocument(doc.Sentiment),
 		Messages:           messages,
+		Pins:               pinsFromDocuments(doc.Pins),
 		StartTime:          doc.StartTime,
 		LastActivity:       lastActivityFromDoc(doc),
 		EndTime:            doc.EndTime,
@@ -718,7 +1521,10 @@ func lastActivityFromDoc(doc *SessionDocument) time.Time {
 	return doc.StartTime
 }
 
-// AddMessage adds a message to an existing session and persists it immediately
+// AddMessage adds a message to an existing session and persists it immediately.
+// With message batching started (see batching.go), the message is buffered and
+// written with the session's next batch instead; write errors are then logged
+// by the flush rather than returned.
 func (s *StorageService) AddMessage(sessionID string, msg *session.Message) error {
 	// No else needed: early return pattern (guard clause)
 	if sessionID == "" {
@@ -730,37 +1536,109 @@ func (s *StorageService) AddMessage(sessionID string, msg *session.Message) erro
 		return errors.New("message cannot be nil")
 	}
 
-	ctx, cancel := util.NewTimeoutContext(constants.MessageAddTimeout)
-	defer cancel()
-
 	// Convert message to document
 	msgDoc := MessageDocument{
 		Content:   msg.Content,
 		Timestamp: msg.Timestamp,
 		Sender:    msg.Sender,
+		Event:     msg.Event,
 		FileID:    msg.FileID,
 		FileURL:   msg.FileURL,
 		Metadata:  msg.Metadata,
+		Timing:    timingToDocument(msg.Timing),
 	}
 
-	// Encrypt sensitive content if encryption key is provided
-	// No else needed: optional operation (only encrypt if key is available)
-	if len(s.encryptionKey) > 0 {
-		encrypted, err := s.encrypt(msgDoc.Content)
-		// No else needed: early return pattern (guard clause)
-		if err != nil {
-			return fmt.Errorf("failed to encrypt message content: %w", err)
+	// No else needed: early return pattern (buffered for the session's next batch)
+	if s.batcher != nil {
+		buffered, full := s.batcher.add(sessionID, msgDoc)
+		// No else needed: early return pattern (batch size reached, write it now)
+		if full {
+			return s.flushSession(sessionID)
+		}
+		// No else needed: early return pattern (batching stopped, write directly)
+		if buffered {
+			return nil
 		}
-		msgDoc.Content = encrypted
 	}
 
-	// Push message to messages array using gomongo (automatically updates _mt)
+	return s.appendMessages(sessionID, []MessageDocument{msgDoc})
+}
+
+// appendMessages appends messages to a session in one update, in order
+func (s *StorageService) appendMessages(sessionID string, msgDocs []MessageDocument) error {
+	ctx, cancel := util.NewTimeoutContext(constants.MessageAddTimeout)
+	defer cancel()
+
 	filter := bson.M{constants.MongoFieldID: sessionID}
+
+	// In anonymized mode only the message counter is advanced; content is never stored
+	counted := bson.M{
+		"$inc": bson.M{constants.MongoFieldMessageCount: len(msgDocs)},
+		"$set": bson.M{constants.MongoFieldLastActivity: time.Now()},
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return s.updateMessageDocument(ctx, filter, counted)
+	}
+	// No else needed: optional operation (sessions of anonymized tenants are only counted)
+	if s.hasAnonymizedTenants() {
+		anonymizedFilter := bson.M{
+			constants.MongoFieldID:       sessionID,
+			constants.MongoFieldTenantID: bson.M{"$in": s.anonymizedTenantList()},
+		}
+		err := s.updateMessageDocument(ctx, anonymizedFilter, counted)
+		// No else needed: early return pattern (counted, or failed)
+		if !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+		filter = s.withoutAnonymizedTenants(filter)
+	}
+
+	// Push messages to messages array using gomongo (automatically updates _mt)
 	update := bson.M{
-		"$push": bson.M{constants.MongoFieldMessages: msgDoc},
+		"$push": bson.M{constants.MongoFieldMessages: bson.M{"$each": msgDocs}},
 		"$set":  bson.M{constants.MongoFieldLastActivity: time.Now()},
 	}
 
+	// No else needed: optional operation (searchable hashes only when enabled)
+	if s.usesSearchHashIndex() {
+		var terms []string
+		for _, msgDoc := range msgDocs {
+			terms = append(terms, searchTerms(msgDoc.Content)...)
+		}
+		// No else needed: optional operation (messages without words add no terms)
+		if len(terms) > 0 {
+			update["$addToSet"] = bson.M{constants.MongoFieldSearchTerms: bson.M{"$each": s.hashTerms(terms)}}
+		}
+	}
+
+	// Encrypt sensitive content for each message's index if encryption key is provided.
+	// The push is conditional on the message count so the indexes stay accurate.
+	// No else needed: early return pattern (only encrypt if key is available)
+	if len(s.encryptionKey) > 0 {
+		contents := make([]string, len(msgDocs))
+		for i, msgDoc := range msgDocs {
+			contents[i] = msgDoc.Content
+		}
+		return s.appendSealedAll(sessionID, contents,
+			func() (int, error) { return s.countMessages(ctx, filter) },
+			func(index int, sealed []string) error {
+				sealedDocs := make([]MessageDocument, len(msgDocs))
+				for i, msgDoc := range msgDocs {
+					msgDoc.Content = sealed[i]
+					sealedDocs[i] = msgDoc
+				}
+				update["$push"] = bson.M{constants.MongoFieldMessages: bson.M{"$each": sealedDocs}}
+				return s.updateMessageDocument(ctx, withMessageCount(filter, index), update)
+			})
+	}
+
+	return s.updateMessageDocument(ctx, filter, update)
+}
+
+// updateMessageDocument applies a message update to a session document with retry
+// and maps an unmatched filter to ErrSessionNotFound.
+func (s *StorageService) updateMessageDocument(ctx context.Context, filter, update bson.M) error {
 	var result *mongo.UpdateResult
 	err := s.retryOperation(ctx, "AddMessage", func() error {
 		var opErr error
@@ -785,6 +1663,7 @@ func (s *StorageService) EndSession(sessionID string, endTime time.Time) error {
 	if sessionID == "" {
 		return ErrInvalidSessionID
 	}
+	s.flushPending(sessionID)
 
 	start := time.Now()
 	defer func() {
@@ -921,6 +1800,12 @@ func (s *StorageService) ListUserSessions(userID string, limit int) ([]*SessionM
 		return nil, errors.New("user ID cannot be empty")
 	}
 
+	// No per-user history is exposed in anonymized mode
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+
 	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
 	defer cancel()
 
@@ -930,7 +1815,7 @@ func (s *StorageService) ListUserSessions(userID string, limit int) ([]*SessionM
 	}
 
 	// Build query filter
-	filter := bson.M{constants.MongoFieldUserID: userID}
+	filter := s.scope(bson.M{constants.MongoFieldUserID: userID})
 
 	// Build find options with sorting by ts (descending)
 	queryOpts := gomongo.QueryOptions{
@@ -989,8 +1874,8 @@ func (s *StorageService) ListAllSessions(limit int) ([]*SessionMetadata, error)
 	}
 	queryOpts.Limit = int64(limit)
 
-	// Execute query using gomongo (no filter = all documents)
-	cursor, err := s.collection.Find(ctx, bson.M{}, queryOpts)
+	// Execute query using gomongo (all documents except soft-deleted ones)
+	cursor, err := s.collection.Find(ctx, s.scope(bson.M{}), queryOpts)
 	// No else needed: early return pattern (guard clause)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list all sessions: %w", err)
@@ -1018,7 +1903,7 @@ func (s *StorageService) ListAllSessions(limit int) ([]*SessionMetadata, error)
 			UserID:          doc.UserID, // Include user ID for admin view
 			Name:            doc.Name,
 			LastMessageTime: lastMessageTime,
-			MessageCount:    len(doc.Messages),
+			MessageCount:    messageCountFromDoc(&doc),
 			AdminAssisted:   doc.AdminAssisted,
 			StartTime:       doc.StartTime,
 			EndTime:         doc.EndTime,
@@ -1039,7 +1924,9 @@ func (s *StorageService) ListAllSessions(limit int) ([]*SessionMetadata, error)
 }
 
 // ListAllSessionsWithOptions lists all sessions with filtering, sorting, and pagination
-// This method is designed for admin dashboards to efficiently query large session datasets
+// This method is designed for admin dashboards to efficiently query large session datasets.
+// Deep pages are cheaper with opts.After than with opts.Offset; an After cursor
+// returns ErrInvalidCursor when malformed and ErrCursorSort unless sorting by start time.
 func (s *StorageService) ListAllSessionsWithOptions(opts *SessionListOptions) ([]*SessionMetadata, error) {
 	start := time.Now()
 	defer func() {
@@ -1067,11 +1954,11 @@ func (s *StorageService) ListAllSessionsWithOptions(opts *SessionListOptions) ([
 	}
 
 	// Build filter
-	filter := bson.M{}
+	filter := s.scope(bson.M{})
 
 	// No else needed: optional operation (only add filter if specified)
 	if opts.UserID != "" {
-		filter[constants.MongoFieldUserID] = opts.UserID
+		filter[constants.MongoFieldUserID] = s.userIDFilter(opts.UserID)
 	}
 
 	// No else needed: optional operation (only add filter if specified)
@@ -1106,6 +1993,21 @@ func (s *StorageService) ListAllSessionsWithOptions(opts *SessionListOptions) ([
 		}
 	}
 
+	// No else needed: optional operation (only add filter if specified)
+	if len(opts.Tags) > 0 {
+		filter[constants.MongoFieldTags] = bson.M{"$all": opts.Tags}
+	}
+
+	// No else needed: optional operation (only add filter if specified)
+	if opts.AppVersion != "" {
+		filter[constants.MongoFieldClientAppVersion] = opts.AppVersion
+	}
+
+	// No else needed: optional operation (only add filter if specified)
+	if opts.Platform != "" {
+		filter[constants.MongoFieldClientPlatform] = opts.Platform
+	}
+
 	// Build sort
 	sortOrder := -1 // descending
 	// No else needed: optional operation (only change if ascending)
@@ -1113,6 +2015,21 @@ func (s *StorageService) ListAllSessionsWithOptions(opts *SessionListOptions) ([
 		sortOrder = 1
 	}
 
+	// No else needed: optional operation (cursor pagination replaces the offset)
+	if opts.After != "" {
+		// No else needed: early return pattern (guard clause)
+		if opts.SortBy != constants.SortByTimestamp {
+			return nil, ErrCursorSort
+		}
+		cursor, err := decodeSessionCursor(opts.After)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		filter["$or"] = afterCursor(cursor, sortOrder)
+		opts.Offset = 0
+	}
+
 	sortField := constants.MongoFieldTimestamp
 	useAggregation := false
 	switch opts.SortBy {
@@ -1137,7 +2054,10 @@ func (s *StorageService) ListAllSessionsWithOptions(opts *SessionListOptions) ([
 		pipeline := mongo.Pipeline{
 			{{Key: "$match", Value: filter}},
 			{{Key: "$addFields", Value: bson.M{
-				"_messageCount": bson.M{"$size": bson.M{"$ifNull": bson.A{"$msgs", bson.A{}}}},
+				"_messageCount": bson.M{"$add": bson.A{
+					bson.M{"$size": bson.M{"$ifNull": bson.A{"$msgs", bson.A{}}}},
+					bson.M{"$ifNull": bson.A{"$" + constants.MongoFieldMessageCount, 0}},
+				}},
 			}}},
 			{{Key: "$sort", Value: bson.D{{Key: "_messageCount", Value: sortOrder}}}},
 			{{Key: "$skip", Value: int64(opts.Offset)}},
@@ -1145,8 +2065,13 @@ func (s *StorageService) ListAllSessionsWithOptions(opts *SessionListOptions) ([
 		}
 		cursor, err = s.collection.Aggregate(ctx, pipeline)
 	} else {
+		sortKeys := bson.D{{Key: sortField, Value: sortOrder}}
+		// No else needed: optional operation (ID breaks start time ties so cursors are stable)
+		if sortField == constants.MongoFieldTimestamp {
+			sortKeys = append(sortKeys, bson.E{Key: constants.MongoFieldID, Value: sortOrder})
+		}
 		queryOpts := gomongo.QueryOptions{
-			Sort:  bson.D{{Key: sortField, Value: sortOrder}},
+			Sort:  sortKeys,
 			Limit: int64(opts.Limit),
 			Skip:  int64(opts.Offset),
 		}
@@ -1202,6 +2127,7 @@ func sortByMessageCount(sessions []*SessionMetadata, ascending bool) {
 
 // GetSessionMetrics calculates aggregated metrics for all sessions within a time period
 // using a MongoDB aggregation pipeline instead of loading all docs into memory.
+// The admin metrics endpoint serves precomputed rollups instead (see GetRollupMetrics).
 // Returns metrics including total sessions, active sessions, token usage, and response times.
 func (s *StorageService) GetSessionMetrics(startTime, endTime time.Time) (*Metrics, error) {
 	if endTime.Before(startTime) {
@@ -1216,61 +2142,13 @@ func (s *StorageService) GetSessionMetrics(startTime, endTime time.Time) (*Metri
 	ctx, cancel := util.NewTimeoutContext(constants.MetricsTimeout)
 	defer cancel()
 
-	// Use aggregation pipeline to compute metrics in the database
-	pipeline := mongo.Pipeline{
-		// Match sessions in time range
-		{{Key: "$match", Value: bson.M{
-			constants.MongoFieldTimestamp: bson.M{
-				"$gte": startTime,
-				"$lte": endTime,
-			},
-		}}},
-		// No $limit needed: $group reduces to a single summary document
-		// Group and aggregate
-		{{Key: "$group", Value: bson.M{
-			"_id":             nil,
-			"totalSessions":   bson.M{"$sum": 1},
-			"activeSessions":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$type": "$" + constants.MongoFieldEndTime}, "missing"}}, 1, 0}}},
-			"adminAssisted":   bson.M{"$sum": bson.M{"$cond": bson.A{"$" + constants.MongoFieldAdminAssisted, 1, 0}}},
-			"totalTokens":     bson.M{"$sum": "$" + constants.MongoFieldTotalTokens},
-			"maxResponseTime": bson.M{"$max": "$maxRespTime"},
-			"avgResponseTime": bson.M{"$avg": "$avgRespTime"},
-		}}},
-	}
-
-	cursor, err := s.collection.Aggregate(ctx, pipeline)
-	if err != nil {
+	// Totals are computed per tenant in the database, then summed
+	total := &metricsTotals{}
+	// No else needed: early return pattern (guard clause)
+	if err := s.addSessionTotals(ctx, total, bson.M{"$gte": startTime, "$lte": endTime}); err != nil {
 		return nil, fmt.Errorf("failed to get session metrics: %w", err)
 	}
-	defer cursor.Close(ctx)
-
-	result := &Metrics{}
-
-	if cursor.Next(ctx) {
-		var aggResult struct {
-			TotalSessions   int     `bson:"totalSessions"`
-			ActiveSessions  int     `bson:"activeSessions"`
-			AdminAssisted   int     `bson:"adminAssisted"`
-			TotalTokens     int     `bson:"totalTokens"`
-			MaxResponseTime int64   `bson:"maxResponseTime"`
-			AvgResponseTime float64 `bson:"avgResponseTime"`
-		}
-		if err := cursor.Decode(&aggResult); err != nil {
-			return nil, fmt.Errorf("failed to decode metrics: %w", err)
-		}
-		result.TotalSessions = aggResult.TotalSessions
-		result.ActiveSessions = aggResult.ActiveSessions
-		result.AdminAssistedCount = aggResult.AdminAssisted
-		result.TotalTokens = aggResult.TotalTokens
-		result.MaxResponseTime = aggResult.MaxResponseTime
-		result.AvgResponseTime = int64(aggResult.AvgResponseTime)
-	}
-
-	if err := cursor.Err(); err != nil {
-		return nil, fmt.Errorf("cursor error: %w", err)
-	}
-
-	return result, nil
+	return total.metrics(), nil
 }
 
 // GetTokenUsage calculates the total token usage across all sessions within a time period.
@@ -1286,12 +2164,12 @@ func (s *StorageService) GetTokenUsage(startTime, endTime time.Time) (int, error
 
 	// Use MongoDB aggregation pipeline to sum token usage
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{
+		{{Key: "$match", Value: s.scope(bson.M{
 			constants.MongoFieldTimestamp: bson.M{
 				"$gte": startTime,
 				"$lte": endTime,
 			},
-		}}},
+		})}},
 		{{Key: "$group", Value: bson.M{
 			"_id":         nil,
 			"totalTokens": bson.M{"$sum": "$totalTokens"},
@@ -1329,12 +2207,19 @@ func (s *StorageService) GetTokenUsage(startTime, endTime time.Time) (int, error
 // LoadActiveSessions returns all sessions that have no end time (still active).
 // Used by SessionManager.RehydrateFromStorage to restore sessions on startup.
 func (s *StorageService) LoadActiveSessions() ([]*session.Session, error) {
+	// Hashed user IDs cannot be mapped back to connecting users, so anonymized
+	// sessions are not rehydrated; users start a new session on reconnect.
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, nil
+	}
+
 	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
 	defer cancel()
 
-	filter := bson.M{
+	filter := s.withoutAnonymizedTenants(notDeleted(bson.M{
 		constants.MongoFieldEndTime: bson.M{"$exists": false},
-	}
+	}))
 
 	queryOpts := gomongo.QueryOptions{
 		Limit: int64(constants.MaxSessionLimit),
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var testAnonymizationKey = []byte("01234567890123456789012345678901")
+
+func TestStoredUserID_Anonymized(t *testing.T) {
+	plain := &StorageService{}
+	assert.Equal(t, "user-1", plain.StoredUserID("user-1"), "user ID must be unchanged when mode is off")
+
+	anon := &StorageService{encryptionKey: []byte("01234567890123456789012345678901")}
+	require.NoError(t, anon.SetAnonymizedMode(true))
+	require.True(t, anon.IsAnonymized())
+
+	hashed := anon.StoredUserID("user-1")
+	assert.NotEqual(t, "user-1", hashed)
+	assert.Len(t, hashed, 64, "expected hex-encoded SHA-256")
+	assert.Equal(t, hashed, anon.StoredUserID("user-1"), "hash must be deterministic")
+	assert.NotEqual(t, hashed, anon.StoredUserID("user-2"))
+	assert.Empty(t, anon.StoredUserID(""))
+
+	otherKey := &StorageService{encryptionKey: []byte("abcdefghijabcdefghijabcdefghij12")}
+	require.NoError(t, otherKey.SetAnonymizedMode(true))
+	assert.NotEqual(t, hashed, otherKey.StoredUserID("user-1"), "hash must be keyed")
+}
+
+func TestSessionToDocument_Anonymized(t *testing.T) {
+	svc := &StorageService{encryptionKey: testAnonymizationKey}
+	require.NoError(t, svc.SetAnonymizedMode(true))
+
+	now := time.Now()
+	sess := &session.Session{
+		ID:        "sess-1",
+		UserID:    "user-1",
+		Name:      "My private question",
+		ModelID:   "gpt-4",
+		StartTime: now,
+		Messages: []*session.Message{
+			{Content: "secret", Timestamp: now, Sender: "user"},
+			{Content: "answer", Timestamp: now, Sender: "ai"},
+		},
+		TotalTokens: 42,
+		IsActive:    true,
+	}
+
+	doc := svc.sessionToDocument(sess)
+
+	assert.Equal(t, svc.StoredUserID("user-1"), doc.UserID)
+	assert.Empty(t, doc.Name)
+	assert.Empty(t, doc.Messages)
+	assert.Equal(t, 2, doc.MessageCount)
+	assert.Equal(t, 42, doc.TotalTokens)
+	assert.Equal(t, 2, buildSessionMetadata(doc, now).MessageCount)
+}
+
+func TestAnonymizedMode_DisablesHistory(t *testing.T) {
+	svc := &StorageService{encryptionKey: testAnonymizationKey}
+	require.NoError(t, svc.SetAnonymizedMode(true))
+
+	_, err := svc.ListUserSessions("user-1", 10)
+	assert.ErrorIs(t, err, ErrAnonymizedMode)
+
+	assert.ErrorIs(t, svc.SetShareToken("sess-1", "token", time.Now().Add(time.Hour)), ErrAnonymizedMode)
+	assert.NoError(t, svc.UpdateSessionName("sess-1", "name"), "name updates are dropped silently")
+
+	sessions, err := svc.LoadActiveSessions()
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestSetAnonymizedMode_RequiresKey(t *testing.T) {
+	svc := &StorageService{}
+	assert.ErrorIs(t, svc.SetAnonymizedMode(true), ErrAnonymizationKeyRequired, "unkeyed hashes of user IDs can be reversed")
+	assert.False(t, svc.IsAnonymized())
+	assert.NoError(t, svc.SetAnonymizedMode(false))
+	assert.ErrorIs(t, svc.SetAnonymizedTenants([]string{"acme"}), ErrAnonymizationKeyRequired)
+	assert.NoError(t, svc.SetAnonymizedTenants(nil))
+
+	// A dedicated salt keys the hashes instead of the encryption key
+	svc.SetAnonymizationSalt([]byte("test-anonymization-salt"))
+	require.NoError(t, svc.SetAnonymizedMode(true))
+	keyed := &StorageService{encryptionKey: testAnonymizationKey}
+	require.NoError(t, keyed.SetAnonymizedMode(true))
+	assert.NotEqual(t, keyed.StoredUserID("user-1"), svc.StoredUserID("user-1"))
+}
+
+func TestAnonymizedTenants(t *testing.T) {
+	svc := &StorageService{encryptionKey: testAnonymizationKey}
+	require.NoError(t, svc.SetAnonymizedTenants([]string{"acme"}))
+	assert.Error(t, svc.SetAnonymizedTenants([]string{""}), "the default tenant is anonymized with SetAnonymizedMode")
+	require.NoError(t, svc.SetAnonymizedTenants([]string{"acme"}))
+
+	assert.False(t, svc.IsAnonymized())
+	assert.True(t, svc.IsAnonymizedTenant("acme"))
+	assert.False(t, svc.IsAnonymizedTenant("globex"))
+	assert.True(t, svc.ForTenant("acme").IsAnonymized())
+	assert.False(t, svc.ForTenant("globex").IsAnonymized())
+	assert.False(t, svc.ForTenant("").IsAnonymized())
+
+	hashed := svc.StoredUserIDFor("acme", "user-1")
+	assert.Len(t, hashed, 64)
+	assert.Equal(t, hashed, svc.ForTenant("acme").StoredUserID("user-1"))
+	assert.Equal(t, "user-1", svc.StoredUserIDFor("globex", "user-1"))
+	assert.Equal(t, "user-1", svc.StoredUserID("user-1"), "the base service is not bound to a tenant")
+
+	now := time.Now()
+	sess := &session.Session{
+		ID:        "sess-1",
+		UserID:    "user-1",
+		TenantID:  "acme",
+		Name:      "My private question",
+		StartTime: now,
+		Messages:  []*session.Message{{Content: "secret", Timestamp: now, Sender: "user"}},
+	}
+	doc := svc.sessionToDocument(sess)
+	assert.Equal(t, hashed, doc.UserID)
+	assert.Empty(t, doc.Name)
+	assert.Empty(t, doc.Messages)
+	assert.Equal(t, 1, doc.MessageCount)
+
+	sess.TenantID = "globex"
+	doc = svc.sessionToDocument(sess)
+	assert.Equal(t, "user-1", doc.UserID)
+	assert.Equal(t, "My private question", doc.Name)
+	assert.Len(t, doc.Messages, 1)
+
+	// Calls that are not bound to a tenant skip the anonymized tenants' sessions
+	filter := svc.withoutAnonymizedTenants(bson.M{})
+	assert.Equal(t, bson.M{"$nin": bson.A{"acme"}}, filter["tid"])
+	assert.Empty(t, svc.ForTenant("acme").withoutAnonymizedTenants(bson.M{}))
+
+	// Nothing is remembered about users of anonymized tenants
+	facts, err := svc.UserMemory("acme", "user-1")
+	assert.NoError(t, err)
+	assert.Empty(t, facts)
+}
@@ -481,6 +481,170 @@ func TestMongoDBFieldNaming_UpdateSession(t *testing.T) {
 	assert.Equal(t, "Admin Smith", rawDoc["assistingAdminName"])
 }
 
+// TestMongoDBFieldNaming_RecordHandback tests the intervention window appended on handback
+func TestMongoDBFieldNaming_RecordHandback(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	sess := &session.Session{
+		ID:                 "handback-test-1",
+		UserID:             "user-123",
+		Messages:           []*session.Message{},
+		StartTime:          now,
+		AdminAssisted:      true,
+		AssistingAdminID:   "admin-789",
+		AssistingAdminName: "Admin Smith",
+	}
+	require.NoError(t, service.CreateSession(sess))
+
+	err := service.RecordHandback(sess.ID, &session.AdminIntervention{
+		AdminID:      "admin-789",
+		AdminName:    "Admin Smith",
+		StartTime:    now,
+		EndTime:      now.Add(10 * time.Minute),
+		HandedBackBy: "admin-789",
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.RecordHandback("missing", &session.AdminIntervention{}), ErrSessionNotFound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rawDoc bson.M
+	err = service.collection.FindOne(ctx, bson.M{"_id": sess.ID}).Decode(&rawDoc)
+	require.NoError(t, err)
+
+	assert.Equal(t, true, rawDoc["adminAssisted"], "takeover stays on record")
+	assert.NotContains(t, rawDoc, "assistingAdminId")
+	interventions, ok := rawDoc["interventions"].(bson.A)
+	require.True(t, ok)
+	require.Len(t, interventions, 1)
+	intervention := interventions[0].(bson.M)
+	assert.Equal(t, "admin-789", intervention["adminId"])
+	assert.Equal(t, "admin-789", intervention["by"])
+	assert.Contains(t, intervention, "ts")
+	assert.Contains(t, intervention, "endTs")
+}
+
+func TestMongoDBFieldNaming_UpdateSessionLLMParams(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	sess := &session.Session{
+		ID:        "llm-params-test-1",
+		UserID:    "user-123",
+		Messages:  []*session.Message{},
+		StartTime: time.Now(),
+	}
+	require.NoError(t, service.CreateSession(sess))
+
+	temperature := 0.4
+	err := service.UpdateSessionLLMParams(sess.ID, &session.LLMParams{Temperature: &temperature, MaxTokens: 300, Timeout: 5 * time.Minute})
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.UpdateSessionLLMParams("missing", &session.LLMParams{}), ErrSessionNotFound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rawDoc bson.M
+	err = service.collection.FindOne(ctx, bson.M{"_id": sess.ID}).Decode(&rawDoc)
+	require.NoError(t, err)
+	params, ok := rawDoc["llmParams"].(bson.M)
+	require.True(t, ok)
+	assert.Equal(t, 0.4, params["temp"])
+	assert.EqualValues(t, 300, params["maxTokens"])
+	assert.EqualValues(t, 300, params["timeoutSec"])
+	assert.NotContains(t, params, "topP")
+
+	// Parameters survive a reload
+	loaded, err := service.GetSession(sess.ID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.LLMParams)
+	assert.Equal(t, 300, loaded.LLMParams.MaxTokens)
+	assert.Equal(t, 5*time.Minute, loaded.LLMParams.Timeout)
+}
+
+func TestMongoDBFieldNaming_UpdateSessionProviderThread(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	sess := &session.Session{
+		ID:        "provider-thread-test-1",
+		UserID:    "user-123",
+		Messages:  []*session.Message{},
+		StartTime: time.Now(),
+	}
+	require.NoError(t, service.CreateSession(sess))
+
+	err := service.UpdateSessionProviderThread(sess.ID, &session.ProviderThread{ModelID: "support", ID: "conv_1"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.UpdateSessionProviderThread("missing", nil), ErrSessionNotFound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rawDoc bson.M
+	err = service.collection.FindOne(ctx, bson.M{"_id": sess.ID}).Decode(&rawDoc)
+	require.NoError(t, err)
+	thread, ok := rawDoc["providerThread"].(bson.M)
+	require.True(t, ok)
+	assert.Equal(t, "support", thread["modelId"])
+	assert.Equal(t, "conv_1", thread["id"])
+
+	// The thread survives a reload
+	loaded, err := service.GetSession(sess.ID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.ProviderThread)
+	assert.Equal(t, "conv_1", loaded.ProviderThread.ID)
+
+	// nil clears it
+	require.NoError(t, service.UpdateSessionProviderThread(sess.ID, nil))
+	loaded, err = service.GetSession(sess.ID)
+	require.NoError(t, err)
+	assert.Nil(t, loaded.ProviderThread)
+}
+
+func TestMongoDBFieldNaming_UpdateSessionSentiment(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	sess := &session.Session{
+		ID:        "sentiment-test-1",
+		UserID:    "user-123",
+		Messages:  []*session.Message{},
+		StartTime: time.Now(),
+	}
+	require.NoError(t, service.CreateSession(sess))
+
+	err := service.UpdateSessionSentiment(sess.ID, session.Sentiment{Score: -0.25, Samples: 3})
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.UpdateSessionSentiment("missing", session.Sentiment{}), ErrSessionNotFound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rawDoc bson.M
+	err = service.collection.FindOne(ctx, bson.M{"_id": sess.ID}).Decode(&rawDoc)
+	require.NoError(t, err)
+	stored, ok := rawDoc["sentiment"].(bson.M)
+	require.True(t, ok)
+	assert.Equal(t, -0.25, stored["score"])
+	assert.EqualValues(t, 3, stored["n"])
+
+	// The sentiment survives a reload and is listed
+	loaded, err := service.GetSession(sess.ID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.Sentiment)
+	assert.Equal(t, 3, loaded.Sentiment.Samples)
+
+	var doc SessionDocument
+	require.NoError(t, service.collection.FindOne(ctx, bson.M{"_id": sess.ID}).Decode(&doc))
+	meta := buildSessionMetadata(&doc, doc.StartTime)
+	require.NotNil(t, meta.Sentiment)
+	assert.Equal(t, -0.25, *meta.Sentiment)
+}
+
 // TestMongoDBFieldNaming_AddMessage tests adding messages with new field names
 func TestMongoDBFieldNaming_AddMessage(t *testing.T) {
 	service, cleanup := setupTestStorage(t, nil)
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionDocument_PreservesSystemEvents(t *testing.T) {
+	svc := &StorageService{}
+	now := time.Now()
+	sess := &session.Session{
+		ID:        "sess-1",
+		UserID:    "user-1",
+		StartTime: now,
+		Messages: []*session.Message{
+			{Content: "hello", Timestamp: now, Sender: constants.SenderUser},
+			{
+				Content:   "Administrator Alice took over the session",
+				Timestamp: now,
+				Sender:    constants.SenderSystem,
+				Event:     constants.SystemEventAdminTakeover,
+				Metadata:  map[string]string{"admin_id": "admin-1"},
+			},
+		},
+	}
+
+	doc := svc.sessionToDocument(sess)
+	require.Len(t, doc.Messages, 2)
+	assert.Empty(t, doc.Messages[0].Event)
+	assert.Equal(t, constants.SystemEventAdminTakeover, doc.Messages[1].Event)
+
+	restored := svc.documentToSession(doc)
+	require.Len(t, restored.Messages, 2)
+	assert.False(t, restored.Messages[0].IsSystemEvent())
+	assert.True(t, restored.Messages[1].IsSystemEvent())
+	assert.Equal(t, "admin-1", restored.Messages[1].Metadata["admin_id"])
+}
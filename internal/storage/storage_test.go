@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/real-rm/chatbox/internal/constants"
 	"github.com/real-rm/chatbox/internal/session"
 	"github.com/real-rm/golog"
 	"github.com/real-rm/gomongo"
@@ -39,6 +41,22 @@ func getUniqueCollectionName(t *testing.T) string {
 	return fmt.Sprintf("test_%s_%d", t.Name(), time.Now().UnixNano())
 }
 
+// testCollectionSeq tells apart collections a test creates in the same nanosecond
+var testCollectionSeq atomic.Int64
+
+// setupTestCollection gives the test its own collection, dropped when the test
+// ends, and hands it to assign to replace one of the service's collections
+func setupTestCollection(t *testing.T, service *StorageService, assign func(*gomongo.MongoCollection)) {
+	t.Helper()
+	collection := service.mongo.Coll("chatbox", fmt.Sprintf("%s_%d", getUniqueCollectionName(t), testCollectionSeq.Add(1)))
+	assign(collection)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = collection.Drop(ctx)
+	})
+}
+
 // setupTestStorage creates a test storage service with a unique collection name
 // This prevents duplicate key errors when tests run concurrently or sequentially
 func setupTestStorage(t *testing.T, encryptionKey []byte) (*StorageService, func()) {
@@ -2698,3 +2716,78 @@ func TestGetSession_WithResponseTimes(t *testing.T) {
 	// We store max and avg, so we can only reconstruct an approximation
 	assert.NotNil(t, retrievedSess.ResponseTimes)
 }
+
+func TestShareLink_ExpiryAndRevocation(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	sessionID := fmt.Sprintf("share-session-%d", time.Now().UnixNano())
+	createSessionWithActivity(t, service, sessionID, time.Now())
+
+	link, err := service.GetShareLink(sessionID)
+	require.NoError(t, err)
+	assert.Nil(t, link, "not shared yet")
+
+	token := sessionID + "-token"
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+	require.NoError(t, service.SetShareToken(sessionID, token, expiresAt))
+
+	sess, link, err := service.GetSessionByShareToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, sessionID, sess.ID)
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*link.ExpiresAt))
+
+	// An expired link is no longer served, but is still reported to the owner
+	require.NoError(t, service.SetShareToken(sessionID, token, time.Now().Add(-time.Minute)))
+	_, _, err = service.GetSessionByShareToken(token)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	link, err = service.GetShareLink(sessionID)
+	require.NoError(t, err)
+	assert.True(t, link.Expired(time.Now()))
+
+	// Revoking removes the link
+	require.NoError(t, service.SetShareToken(sessionID, token, expiresAt))
+	require.NoError(t, service.RevokeShareToken(sessionID))
+	_, _, err = service.GetSessionByShareToken(token)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	link, err = service.GetShareLink(sessionID)
+	require.NoError(t, err)
+	assert.Nil(t, link)
+
+	assert.ErrorIs(t, service.RevokeShareToken("missing-session"), ErrSessionNotFound)
+}
+
+func TestShareLink_LegacyLinksExpireAfterDefaultTTL(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Links shared before expiry was stored have no shareExpTs
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for id, start := range map[string]time.Time{
+		"legacy-recent": now.Add(-time.Hour),
+		"legacy-old":    now.Add(-constants.DefaultShareLinkTTL - time.Hour),
+	} {
+		_, err := service.collection.InsertOne(ctx, bson.M{
+			constants.MongoFieldID:         id,
+			constants.MongoFieldUserID:     "user-1",
+			constants.MongoFieldTimestamp:  start,
+			constants.MongoFieldShareToken: id + "-token",
+		})
+		require.NoError(t, err)
+	}
+
+	_, link, err := service.GetSessionByShareToken("legacy-recent-token")
+	require.NoError(t, err)
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, now.Add(-time.Hour).Add(constants.DefaultShareLinkTTL).Equal(*link.ExpiresAt))
+
+	_, _, err = service.GetSessionByShareToken("legacy-old-token")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	link, err = service.GetShareLink("legacy-old")
+	require.NoError(t, err)
+	assert.True(t, link.Expired(time.Now()))
+}
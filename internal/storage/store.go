@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+)
+
+// SessionStore persists the sessions of the real-time chat path: sessions the
+// message router creates and updates, and the active sessions rehydrated on
+// startup. StorageService implements it on MongoDB, and ResidentStore routes
+// each session to its tenant's datastore.
+type SessionStore interface {
+	CreateSession(sess *session.Session) error
+	GetSession(sessionID string) (*session.Session, error)
+	AddMessage(sessionID string, msg *session.Message) error
+	UpdateSessionName(sessionID, name string) error
+	UpdateSessionModelID(sessionID, modelID string) error
+	UpdateSessionLLMParams(sessionID string, params *session.LLMParams) error
+	UpdateSessionProviderThread(sessionID string, thread *session.ProviderThread) error
+	RecordHandback(sessionID string, intervention *session.AdminIntervention) error
+	SetMessageFeedback(sessionID string, index int, feedback *session.Feedback) error
+	MarkMessagesRead(sessionID string, indexes []int, at time.Time) error
+	EndSession(sessionID string, endTime time.Time) error
+	LoadActiveSessions() ([]*session.Session, error)
+}
+
+var (
+	_ SessionStore = (*StorageService)(nil)
+	_ SessionStore = (*ResidentStore)(nil)
+)
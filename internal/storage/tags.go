@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrInvalidTag is returned for tags that are empty, too long or contain
+	// characters other than letters, digits, '-' and '_'
+	ErrInvalidTag = errors.New("invalid tag")
+	// ErrTooManyTags is returned when adding tags would exceed constants.MaxSessionTags
+	ErrTooManyTags = errors.New("too many tags")
+)
+
+// NormalizeTags trims and lower-cases tags and removes duplicates, keeping the
+// first occurrence order. Tags are 1 to constants.MaxTagLength bytes of
+// letters, digits, '-' and '_'.
+func NormalizeTags(tags []string) ([]string, error) {
+	// No else needed: early return pattern (guard clause)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("%w: at least one tag is required", ErrInvalidTag)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(tags) > constants.MaxSessionTags {
+		return nil, fmt.Errorf("%w: at most %d tags per session", ErrTooManyTags, constants.MaxSessionTags)
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		// No else needed: early return pattern (guard clause)
+		if !validTag(tag) {
+			return nil, fmt.Errorf("%w: %q must be 1-%d letters, digits, '-' or '_'", ErrInvalidTag, tag, constants.MaxTagLength)
+		}
+		// No else needed: optional operation (skip duplicates)
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// validTag reports whether a normalized tag is well-formed
+func validTag(tag string) bool {
+	// No else needed: early return pattern (guard clause)
+	if tag == "" || len(tag) > constants.MaxTagLength {
+		return false
+	}
+	for _, r := range tag {
+		// No else needed: early return pattern (invalid character)
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// AddUserSessionTags adds tags to a session owned by userID, or shared with
+// them for writing, and returns the session's tags. Returns ErrSessionNotFound
+// when the session does not exist or the user cannot write to it.
+func (s *StorageService) AddUserSessionTags(sessionID, userID string, tags []string) ([]string, error) {
+	return s.addTags(sessionID, s.accessibleBy(sessionID, userID, constants.GrantAccessWrite), tags)
+}
+
+// AddSessionTags adds tags to any session visible to the storage view (all
+// sessions of the tenant for a tenant view) and returns the session's tags.
+func (s *StorageService) AddSessionTags(sessionID string, tags []string) ([]string, error) {
+	return s.addTags(sessionID, s.scope(bson.M{constants.MongoFieldID: sessionID}), tags)
+}
+
+// RemoveUserSessionTag removes a tag from a session owned by userID, or shared
+// with them for writing, and returns the session's remaining tags
+func (s *StorageService) RemoveUserSessionTag(sessionID, userID, tag string) ([]string, error) {
+	return s.removeTag(sessionID, s.accessibleBy(sessionID, userID, constants.GrantAccessWrite), tag)
+}
+
+// RemoveSessionTag removes a tag from any session visible to the storage view
+// and returns the session's remaining tags
+func (s *StorageService) RemoveSessionTag(sessionID, tag string) ([]string, error) {
+	return s.removeTag(sessionID, s.scope(bson.M{constants.MongoFieldID: sessionID}), tag)
+}
+
+// addTags adds normalized tags to the session matching filter. The size check is
+// part of the filter so concurrent additions cannot exceed the limit.
+func (s *StorageService) addTags(sessionID string, filter bson.M, tags []string) ([]string, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+	// Tags are free text that may identify the user
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+	tags, err := NormalizeTags(tags)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "add_session_tags"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	limited := bson.M{"$expr": bson.M{"$lte": bson.A{
+		bson.M{"$size": bson.M{"$setUnion": bson.A{
+			bson.M{"$ifNull": bson.A{"$" + constants.MongoFieldTags, bson.A{}}},
+			tags,
+		}}},
+		constants.MaxSessionTags,
+	}}}
+	for k, v := range filter {
+		limited[k] = v
+	}
+	update := bson.M{"$addToSet": bson.M{constants.MongoFieldTags: bson.M{"$each": tags}}}
+
+	result, err := s.updateTags(ctx, "AddSessionTags", limited, update)
+	// No else needed: early return pattern (guard clause)
+	if !errors.Is(err, ErrSessionNotFound) {
+		return result, err
+	}
+
+	// Nothing matched: tell a missing session from one that has too many tags
+	var doc SessionDocument
+	findErr := s.collection.FindOne(ctx, filter).Decode(&doc)
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(findErr, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	// No else needed: early return pattern (guard clause)
+	if findErr != nil {
+		return nil, fmt.Errorf("failed to add session tags: %w", findErr)
+	}
+	return nil, fmt.Errorf("%w: at most %d tags per session", ErrTooManyTags, constants.MaxSessionTags)
+}
+
+// removeTag removes a normalized tag from the session matching filter
+func (s *StorageService) removeTag(sessionID string, filter bson.M, tag string) ([]string, error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return nil, ErrAnonymizedMode
+	}
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	// No else needed: early return pattern (guard clause)
+	if !validTag(tag) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "remove_session_tag"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	update := bson.M{"$pull": bson.M{constants.MongoFieldTags: tag}}
+	return s.updateTags(ctx, "RemoveSessionTag", filter, update)
+}
+
+// updateTags applies a tag update to the session matching filter and returns
+// the session's tags after the update
+func (s *StorageService) updateTags(ctx context.Context, operation string, filter, update bson.M) ([]string, error) {
+	findOpts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{constants.MongoFieldTags: 1})
+
+	var doc SessionDocument
+	err := s.retryOperation(ctx, operation, func() error {
+		return s.collection.FindOneAndUpdate(ctx, filter, update, findOpts).Decode(&doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session tags: %w", err)
+	}
+	// No else needed: conditional assignment (sessions without tags)
+	if doc.Tags == nil {
+		doc.Tags = []string{}
+	}
+	return doc.Tags, nil
+}
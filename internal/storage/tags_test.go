@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Billing ", "billing", "follow_up", "VIP-2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"billing", "follow_up", "vip-2"}, tags)
+
+	tooMany := make([]string, constants.MaxSessionTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag-%d", i)
+	}
+
+	for name, input := range map[string][]string{
+		"empty list":  {},
+		"blank tag":   {"  "},
+		"space":       {"follow up"},
+		"punctuation": {"a,b"},
+		"too long":    {strings.Repeat("a", constants.MaxTagLength+1)},
+		"too many":    tooMany,
+	} {
+		_, err := NormalizeTags(input)
+		assert.Error(t, err, name)
+	}
+	_, err = NormalizeTags(tooMany)
+	assert.ErrorIs(t, err, ErrTooManyTags)
+	_, err = NormalizeTags([]string{"a b"})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestSessionTags(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	createSessionWithActivity(t, service, "session-1", time.Now())
+
+	tags, err := service.AddUserSessionTags("session-1", "user-1", []string{"Billing", "urgent"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"billing", "urgent"}, tags)
+
+	// Adding an existing tag is a no-op
+	tags, err = service.AddSessionTags("session-1", []string{"billing", "escalated"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"billing", "urgent", "escalated"}, tags)
+
+	tags, err = service.RemoveUserSessionTag("session-1", "user-1", "URGENT")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"billing", "escalated"}, tags)
+
+	// Other users' sessions and other tenants look the same as missing ones
+	_, err = service.AddUserSessionTags("session-1", "user-2", []string{"mine"})
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.RemoveUserSessionTag("session-1", "user-2", "billing")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.ForTenant("tenant-b").AddSessionTags("session-1", []string{"other"})
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.AddSessionTags("missing", []string{"other"})
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.RemoveSessionTag("session-1", "not a tag")
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestSessionTags_Limit(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	createSessionWithActivity(t, service, "session-1", time.Now())
+
+	tags := make([]string, constants.MaxSessionTags)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag-%d", i)
+	}
+	_, err := service.AddSessionTags("session-1", tags)
+	require.NoError(t, err)
+
+	// Re-adding existing tags does not count against the limit
+	_, err = service.AddSessionTags("session-1", []string{"tag-0"})
+	assert.NoError(t, err)
+
+	_, err = service.AddSessionTags("session-1", []string{"one-too-many"})
+	assert.ErrorIs(t, err, ErrTooManyTags)
+}
+
+func TestSessionTags_FilterAndCounts(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	createSessionWithActivity(t, service, "session-1", now)
+	createSessionWithActivity(t, service, "session-2", now)
+	createSessionWithActivity(t, service, "session-3", now)
+
+	_, err := service.AddSessionTags("session-1", []string{"billing", "urgent"})
+	require.NoError(t, err)
+	_, err = service.AddSessionTags("session-2", []string{"billing"})
+	require.NoError(t, err)
+
+	sessions, err := service.ListAllSessionsWithOptions(&SessionListOptions{Tags: []string{"billing"}})
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	sessions, err = service.ListAllSessionsWithOptions(&SessionListOptions{Tags: []string{"billing", "urgent"}})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "session-1", sessions[0].ID)
+	assert.ElementsMatch(t, []string{"billing", "urgent"}, sessions[0].Tags)
+
+	metrics, err := service.GetSessionMetrics(now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"billing": 2, "urgent": 1}, metrics.TagCounts)
+}
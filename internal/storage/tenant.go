@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"github.com/real-rm/chatbox/internal/constants"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ForTenant returns a view of the service whose reads only see sessions of tenantID.
+// The empty tenant ID selects the default tenant: sessions stored without a tenant.
+// The view shares the underlying collection and settings; it does not own the
+// retention purger or the metrics rollup aggregator, so StartRetentionPurger and
+// StartMetricsRollup should only be called on the base service. The view of a
+// tenant pinned to another datastore (see SetResidency) uses that datastore.
+func (s *StorageService) ForTenant(tenantID string) *StorageService {
+	s = s.DatastoreFor(tenantID)
+	return &StorageService{
+		mongo:           s.mongo,
+		collName:        s.collName,
+		datastore:       s.datastore,
+		collection:      s.collection,
+		auditLog:        s.auditLog,
+		prompts:         s.prompts,
+		canned:          s.canned,
+		rollups:         s.rollups,
+		snapshots:       s.snapshots,
+		roles:           s.roles,
+		apiKeys:         s.apiKeys,
+		usage:           s.usage,
+		blocks:          s.blocks,
+		reports:         s.reports,
+		keywords:        s.keywords,
+		memory:          s.memory,
+		experiments:     s.experiments,
+		logger:          s.logger,
+		encryptionKey:   s.encryptionKey,
+		gcm:             s.gcm,
+		anonymized:      s.IsAnonymizedTenant(tenantID),
+		searchHashIndex: s.searchHashIndex,
+		batcher:         s.batcher,
+		restoreGrace:    s.restoreGrace,
+		tenantScoped:    true,
+		tenantID:        tenantID,
+
+		hashSalt:          s.hashSalt,
+		anonymizedTenants: s.anonymizedTenants,
+	}
+}
+
+// scope restricts a read filter to sessions visible through this service:
+// sessions that are not soft-deleted and, on a tenant view, belong to its tenant.
+func (s *StorageService) scope(filter bson.M) bson.M {
+	return s.tenantFilter(notDeleted(filter))
+}
+
+// tenantFilter adds the tenant condition of a tenant view to a query filter.
+// Filters are returned unchanged on an unscoped service.
+func (s *StorageService) tenantFilter(filter bson.M) bson.M {
+	// No else needed: early return pattern (unscoped service sees all tenants)
+	if !s.tenantScoped {
+		return filter
+	}
+	// No else needed: early return pattern (default tenant)
+	if s.tenantID == "" {
+		filter[constants.MongoFieldTenantID] = bson.M{"$exists": false}
+		return filter
+	}
+	filter[constants.MongoFieldTenantID] = s.tenantID
+	return filter
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTenantSession stores an active session owned by userID in tenantID
+func createTenantSession(t *testing.T, service *StorageService, id, userID, tenantID string) {
+	t.Helper()
+	now := time.Now()
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:           id,
+		UserID:       userID,
+		TenantID:     tenantID,
+		Messages:     []*session.Message{},
+		StartTime:    now,
+		LastActivity: now,
+		IsActive:     true,
+	}))
+}
+
+func TestForTenant_ScopesReads(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	createTenantSession(t, service, "session-a", "user-1", "tenant-a")
+	createTenantSession(t, service, "session-b", "user-1", "tenant-b")
+	createTenantSession(t, service, "session-default", "user-1", "")
+
+	tenantA := service.ForTenant("tenant-a")
+	sess, err := tenantA.GetSession("session-a")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", sess.TenantID)
+
+	_, err = tenantA.GetSession("session-b")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = tenantA.GetSession("session-default")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	sessions, err := tenantA.ListUserSessions("user-1", 0)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "session-a", sessions[0].ID)
+	assert.Equal(t, "tenant-a", sessions[0].TenantID)
+
+	// The empty tenant only sees sessions stored without a tenant
+	sessions, err = service.ForTenant("").ListAllSessions(0)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "session-default", sessions[0].ID)
+
+	// The unscoped service sees every tenant
+	sessions, err = service.ListAllSessions(0)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 3)
+}
+
+func TestForTenant_RestoreSession(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	createTenantSession(t, service, "session-a", "user-1", "tenant-a")
+	_, _, err := service.PurgeExpiredSessions(now.Add(time.Hour), time.Minute, 24*time.Hour)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.ForTenant("tenant-b").RestoreSession("session-a"), ErrSessionNotRestorable)
+	assert.NoError(t, service.ForTenant("tenant-a").RestoreSession("session-a"))
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrTranscriptAlreadySent is returned when the transcript of a session was already emailed
+var ErrTranscriptAlreadySent = errors.New("session transcript was already emailed")
+
+// MarkTranscriptSent records that the transcript of a session is being
+// emailed. Only the first call for a session succeeds, so each transcript is
+// sent at most once; later calls return ErrTranscriptAlreadySent. On a tenant
+// view only the tenant's sessions can be updated.
+func (s *StorageService) MarkTranscriptSent(sessionID string, sentAt time.Time) error {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "mark_transcript_sent"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	var matched int64
+	err := s.retryOperation(ctx, "MarkTranscriptSent", func() error {
+		result, opErr := s.collection.UpdateOne(ctx,
+			s.scope(bson.M{
+				constants.MongoFieldID:           sessionID,
+				constants.MongoFieldTranscriptTs: bson.M{"$exists": false},
+			}),
+			bson.M{"$set": bson.M{constants.MongoFieldTranscriptTs: sentAt}})
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to mark transcript sent: %w", err)
+	}
+	// No else needed: early return pattern (session gone or transcript already sent)
+	if matched == 0 {
+		return ErrTranscriptAlreadySent
+	}
+	return nil
+}
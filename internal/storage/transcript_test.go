@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkTranscriptSent(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	createSessionWithActivity(t, service, "session-1", time.Now())
+	require.NoError(t, service.MarkTranscriptSent("session-1", time.Now()))
+
+	// Each transcript is sent at most once
+	assert.ErrorIs(t, service.MarkTranscriptSent("session-1", time.Now()), ErrTranscriptAlreadySent)
+
+	// Other tenants cannot mark the session
+	createSessionWithActivity(t, service, "session-2", time.Now())
+	assert.ErrorIs(t, service.ForTenant("tenant-b").MarkTranscriptSent("session-2", time.Now()), ErrTranscriptAlreadySent)
+	require.NoError(t, service.MarkTranscriptSent("session-2", time.Now()))
+
+	assert.ErrorIs(t, service.MarkTranscriptSent("", time.Now()), ErrInvalidSessionID)
+}
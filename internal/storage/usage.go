@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidCostGrouping is returned for a cost report grouping other than the
+// constants.CostGroupBy* values
+var ErrInvalidCostGrouping = errors.New("invalid cost grouping")
+
+// UsageRecord records the tokens and cost of one LLM call in the llm_usage
+// collection of the datastore of its tenant (see SetResidency). Records carry
+// no user data and are kept when their session is deleted, so cost reports stay
+// complete for finance.
+type UsageRecord struct {
+	ID        string    `bson:"_id"`
+	SessionID string    `bson:"sid"`
+	TenantID  string    `bson:"tid,omitempty"` // session's tenant; empty for the default tenant
+	ModelID   string    `bson:"model"`         // model that served the call
+	Tokens    int       `bson:"tokens"`
+	Cost      float64   `bson:"cost"` // in the configured cost currency
+	Timestamp time.Time `bson:"ts"`
+}
+
+// CostGroup is the LLM usage of one group of a cost report
+type CostGroup struct {
+	Key      string  `bson:"_id" json:"key"` // tenant ID, model ID or UTC day (YYYY-MM-DD)
+	Requests int     `bson:"requests" json:"requests"`
+	Tokens   int64   `bson:"tokens" json:"tokens"`
+	Cost     float64 `bson:"cost" json:"cost"`
+}
+
+// ensureUsageIndexes creates the indexes for the llm_usage collection
+func (s *StorageService) ensureUsageIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: constants.MongoFieldTimestamp, Value: -1}},
+			Options: options.Index().SetName(constants.IndexUsageTime),
+		},
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldTenantID, Value: 1},
+				{Key: constants.MongoFieldTimestamp, Value: -1},
+			},
+			Options: options.Index().SetName(constants.IndexUsageTenant),
+		},
+	}
+
+	_, err := s.usage.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create usage indexes: %w", err)
+	}
+	return nil
+}
+
+// RecordUsage records the tokens and cost of an LLM call served by modelID for
+// a session in the datastore of tenantID, and adds them to the session's totals
+func (s *StorageService) RecordUsage(sessionID, tenantID, modelID string, tokens int, cost float64) error {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "record_usage"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	record := &UsageRecord{
+		ID:        primitive.NewObjectID().Hex(),
+		SessionID: sessionID,
+		TenantID:  tenantID,
+		ModelID:   modelID,
+		Tokens:    tokens,
+		Cost:      cost,
+		Timestamp: time.Now().UTC(),
+	}
+	store := s.DatastoreFor(tenantID)
+	err := s.retryOperation(ctx, "RecordUsage", func() error {
+		_, err := store.usage.InsertOne(ctx, record)
+		return err
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+
+	var result *mongo.UpdateResult
+	update := bson.M{"$inc": bson.M{
+		constants.MongoFieldTotalTokens: tokens,
+		constants.MongoFieldCost:        cost,
+	}}
+	err = s.retryOperation(ctx, "RecordUsage.session", func() error {
+		var opErr error
+		result, opErr = store.collection.UpdateOne(ctx, bson.M{constants.MongoFieldID: sessionID}, update)
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to update session usage: %w", err)
+	}
+	// No else needed: early return pattern (usage is recorded without its session)
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// GetCosts reports the LLM usage recorded from startTime (inclusive) to endTime
+// (exclusive), grouped by constants.CostGroupByTenant, CostGroupByModel or
+// CostGroupByDay and sorted by group key. On a tenant view only that tenant's
+// usage is reported; unscoped services include the datastores of pinned
+// tenants. Returns ErrInvalidCostGrouping for other groupings.
+func (s *StorageService) GetCosts(startTime, endTime time.Time, groupBy string) ([]*CostGroup, error) {
+	var key interface{}
+	switch groupBy {
+	case constants.CostGroupByTenant:
+		key = bson.M{"$ifNull": bson.A{"$" + constants.MongoFieldTenantID, ""}}
+	case constants.CostGroupByModel:
+		key = "$" + constants.MongoFieldModel
+	case constants.CostGroupByDay:
+		key = bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$" + constants.MongoFieldTimestamp}}
+	default:
+		return nil, ErrInvalidCostGrouping
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "get_costs"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.MetricsTimeout)
+	defer cancel()
+
+	window := bson.M{"$gte": startTime, "$lt": endTime}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: s.tenantFilter(bson.M{constants.MongoFieldTimestamp: window})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      key,
+			"requests": bson.M{"$sum": 1},
+			"tokens":   bson.M{"$sum": "$tokens"},
+			"cost":     bson.M{"$sum": "$" + constants.MongoFieldCost},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	groups, err := s.aggregateCosts(ctx, pipeline)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (tenant views already read the tenant's datastore)
+	if s.tenantScoped || len(s.datastores) == 0 {
+		return groups, nil
+	}
+
+	merged := make(map[string]*CostGroup, len(groups))
+	for _, group := range groups {
+		merged[group.Key] = group
+	}
+	for _, view := range s.datastores {
+		viewGroups, err := view.aggregateCosts(ctx, pipeline)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("datastore %s: %w", view.datastore, err)
+		}
+		for _, group := range viewGroups {
+			total, ok := merged[group.Key]
+			// No else needed: early return pattern (first datastore with the group)
+			if !ok {
+				merged[group.Key] = group
+				groups = append(groups, group)
+				continue
+			}
+			total.Requests += group.Requests
+			total.Tokens += group.Tokens
+			total.Cost += group.Cost
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Key < groups[j].Key
+	})
+	return groups, nil
+}
+
+// aggregateCosts runs a cost report pipeline on this service's datastore
+func (s *StorageService) aggregateCosts(ctx context.Context, pipeline mongo.Pipeline) ([]*CostGroup, error) {
+	cursor, err := s.usage.Aggregate(ctx, pipeline)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate costs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	groups := make([]*CostGroup, 0)
+	for cursor.Next(ctx) {
+		var group CostGroup
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&group); err != nil {
+			return nil, fmt.Errorf("failed to decode cost group: %w", err)
+		}
+		groups = append(groups, &group)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return groups, nil
+}
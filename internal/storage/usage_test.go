@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCosts_InvalidGrouping(t *testing.T) {
+	service := &StorageService{}
+	_, err := service.GetCosts(time.Now().Add(-time.Hour), time.Now(), "user")
+	assert.ErrorIs(t, err, ErrInvalidCostGrouping)
+}
+
+func TestRecordUsage_RequiresSessionID(t *testing.T) {
+	service := &StorageService{}
+	assert.ErrorIs(t, service.RecordUsage("", "", "gpt-4", 10, 0.1), ErrInvalidSessionID)
+}
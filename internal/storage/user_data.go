@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrInvalidUserID is returned when a user data request has no user ID
+var ErrInvalidUserID = errors.New("user ID cannot be empty")
+
+// userDataFilter matches every stored session of a user, including soft-deleted
+// sessions that have not been purged yet: data subject requests cover all data
+// still held, not only what the user can see.
+func (s *StorageService) userDataFilter(userID string) bson.M {
+	return s.tenantFilter(bson.M{constants.MongoFieldUserID: s.userIDFilter(userID)})
+}
+
+// ExportUserData returns all stored sessions of a user with their messages,
+// oldest first, for a data subject access request.
+func (s *StorageService) ExportUserData(userID string) ([]*session.Session, error) {
+	// No else needed: early return pattern (guard clause)
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "export_user_data"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+	defer cancel()
+
+	queryOpts := gomongo.QueryOptions{
+		Sort: bson.D{{Key: constants.MongoFieldTimestamp, Value: 1}},
+	}
+	cursor, err := s.collection.Find(ctx, s.userDataFilter(userID), queryOpts)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := make([]*session.Session, 0)
+	for cursor.Next(ctx) {
+		var doc SessionDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode session document: %w", err)
+		}
+		sessions = append(sessions, s.documentToSession(&doc))
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteUserData permanently deletes all stored sessions of a user, including
+// soft-deleted ones, their snapshots and the facts remembered about the user,
+// and revokes the user's access to sessions shared with them, for a data
+// subject erasure request.
+// There is no grace period and no way to restore them. Returns the IDs of the
+// deleted sessions so callers can drop them from memory too.
+//
+// Message content is encrypted with a service-wide key, so the data cannot be
+// crypto-shredded per user; it is hard-deleted instead.
+func (s *StorageService) DeleteUserData(userID string) ([]string, error) {
+	// No else needed: early return pattern (guard clause)
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "delete_user_data"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+	defer cancel()
+
+	filter := s.userDataFilter(userID)
+	cursor, err := s.collection.Find(ctx, filter, gomongo.QueryOptions{})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessionIDs := make([]string, 0)
+	for cursor.Next(ctx) {
+		var doc SessionDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode session document: %w", err)
+		}
+		sessionIDs = append(sessionIDs, doc.ID)
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	// Delete by user rather than by the IDs found, so sessions created meanwhile go too
+	var deleted int64
+	err = s.retryOperation(ctx, "DeleteUserData", func() error {
+		result, opErr := s.collection.DeleteMany(ctx, filter)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			deleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+
+	// Snapshots hold copies of the sessions, so they are erased too
+	var snapshotsDeleted int64
+	err = s.retryOperation(ctx, "DeleteUserData.snapshots", func() error {
+		result, opErr := s.snapshots.DeleteMany(ctx, filter)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			snapshotsDeleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user snapshots: %w", err)
+	}
+
+	// Facts remembered about the user are personal data as well
+	var factsDeleted int64
+	err = s.retryOperation(ctx, "DeleteUserData.memory", func() error {
+		result, opErr := s.memory.DeleteMany(ctx, filter)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			factsDeleted = result.DeletedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user memory: %w", err)
+	}
+
+	// Other users' sessions shared with the user no longer name them
+	storedUserID := s.StoredUserID(userID)
+	err = s.retryOperation(ctx, "DeleteUserData.grants", func() error {
+		_, opErr := s.collection.UpdateMany(ctx,
+			s.tenantFilter(bson.M{constants.MongoFieldGrantUserID: storedUserID}),
+			bson.M{"$pull": bson.M{constants.MongoFieldGrants: bson.M{"uid": storedUserID}}})
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke user session grants: %w", err)
+	}
+
+	s.logger.Info("User data erased", "sessions_deleted", deleted, "snapshots_deleted", snapshotsDeleted, "memory_facts_deleted", factsDeleted)
+	return sessionIDs, nil
+}
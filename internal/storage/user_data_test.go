@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAndDeleteUserData(t *testing.T) {
+	service, cleanup := setupTestStorage(t, []byte("12345678901234567890123456789012"))
+	defer cleanup()
+
+	now := time.Now()
+	createSessionWithActivity(t, service, "session-old", now.Add(-2*time.Hour))
+	createSessionWithActivity(t, service, "session-new", now.Add(-time.Hour))
+	require.NoError(t, service.AddMessage("session-new", &session.Message{
+		Content:   "my address is 1 Main St",
+		Timestamp: now,
+		Sender:    "user",
+	}))
+	require.NoError(t, service.CreateSession(&session.Session{
+		ID:        "other-user",
+		UserID:    "user-2",
+		StartTime: now,
+		IsActive:  true,
+	}))
+	require.NoError(t, service.ForTenant("tenant-b").CreateSession(&session.Session{
+		ID:        "other-tenant",
+		UserID:    "user-1",
+		StartTime: now,
+		IsActive:  true,
+	}))
+	// Soft-deleted sessions are still held, so they are exported and erased too
+	_, err := service.DeleteUserSession("session-old", "user-1", now)
+	require.NoError(t, err)
+
+	sessions, err := service.ForTenant("").ExportUserData("user-1")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "session-old", sessions[0].ID, "oldest first")
+	require.Len(t, sessions[1].Messages, 1)
+	assert.Equal(t, "my address is 1 Main St", sessions[1].Messages[0].Content, "content is decrypted")
+
+	deleted, err := service.ForTenant("").DeleteUserData("user-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"session-old", "session-new"}, deleted)
+
+	sessions, err = service.ForTenant("").ExportUserData("user-1")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	assert.ErrorIs(t, service.RestoreSession("session-old"), ErrSessionNotRestorable)
+
+	// Other users and tenants are untouched
+	_, err = service.GetSession("other-user")
+	assert.NoError(t, err)
+	_, err = service.GetSession("other-tenant")
+	assert.NoError(t, err)
+
+	_, err = service.ExportUserData("")
+	assert.ErrorIs(t, err, ErrInvalidUserID)
+	_, err = service.DeleteUserData("")
+	assert.ErrorIs(t, err, ErrInvalidUserID)
+}
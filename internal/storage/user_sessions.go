@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ownedBy restricts a filter to a visible session owned by userID.
+// SECURITY: This is the ownership check of the user session endpoints. Sessions
+// of other users, other tenants and soft-deleted sessions never match, so
+// callers cannot tell them apart from sessions that do not exist.
+func (s *StorageService) ownedBy(sessionID, userID string) bson.M {
+	return s.scope(bson.M{
+		constants.MongoFieldID:     sessionID,
+		constants.MongoFieldUserID: s.StoredUserID(userID),
+	})
+}
+
+// RenameUserSession sets the name of a session owned by userID or shared with
+// them for writing. Returns ErrSessionNotFound when the session does not exist
+// or the user cannot write to it, and ErrAnonymizedMode when session names are
+// not stored.
+func (s *StorageService) RenameUserSession(sessionID, userID, name string) error {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return ErrInvalidSessionID
+	}
+	// No else needed: early return pattern (guard clause)
+	if s.anonymized {
+		return ErrAnonymizedMode
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "rename_user_session"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	filter := s.accessibleBy(sessionID, userID, constants.GrantAccessWrite)
+	update := bson.M{"$set": bson.M{"nm": name}}
+
+	var matched int64
+	err := s.retryOperation(ctx, "RenameUserSession", func() error {
+		result, opErr := s.collection.UpdateOne(ctx, filter, update)
+		// No else needed: optional operation (count only on success)
+		if opErr == nil {
+			matched = result.MatchedCount
+		}
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to rename session: %w", err)
+	}
+	// No else needed: early return pattern (guard clause)
+	if matched == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteUserSession ends a session owned by userID, if it is still active, and
+// soft-deletes it. The session disappears from all queries and is purged after
+// the retention grace period unless an admin restores it. Returns whether the
+// session was still active, and ErrSessionNotFound when the session does not
+// exist, belongs to someone else or is already deleted.
+func (s *StorageService) DeleteUserSession(sessionID, userID string, now time.Time) (wasActive bool, err error) {
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" {
+		return false, ErrInvalidSessionID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "delete_user_session"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.DefaultContextTimeout)
+	defer cancel()
+
+	// Soft-delete and read the previous end time in one round-trip
+	filter := s.ownedBy(sessionID, userID)
+	update := bson.M{"$set": bson.M{constants.MongoFieldDeletedAt: now}}
+	findOpts := options.FindOneAndUpdate().
+		SetReturnDocument(options.Before).
+		SetProjection(bson.M{constants.MongoFieldEndTime: 1})
+
+	var doc SessionDocument
+	err = s.retryOperation(ctx, "DeleteUserSession", func() error {
+		return s.collection.FindOneAndUpdate(ctx, filter, update, findOpts).Decode(&doc)
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		// No else needed: early return pattern (guard clause)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, ErrSessionNotFound
+		}
+		return false, fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	// No else needed: optional operation (already-ended sessions keep their end time)
+	if doc.EndTime == nil {
+		// No else needed: early return pattern (guard clause)
+		if err := s.EndSession(sessionID, now); err != nil {
+			return true, fmt.Errorf("failed to end deleted session: %w", err)
+		}
+	}
+
+	s.logger.Info("Session deleted by owner", "session_id", sessionID)
+	return doc.EndTime == nil, nil
+}
+
+// ClaimGuestSessions transfers the sessions of guest guestID to userID, who
+// logged in after chatting as a guest, so they appear in the user's history.
+// Guest sessions belong to the default tenant; they move to the tenant of this
+// view. Open guest sessions are ended, since the guest's connection keeps the
+// guest identity. Returns the IDs of the claimed sessions.
+func (s *StorageService) ClaimGuestSessions(guestID, userID string, now time.Time) ([]string, error) {
+	// No else needed: early return pattern (guard clause)
+	if guestID == "" || userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.MongoDBOperationDuration.With(prometheus.Labels{"operation": "claim_guest_sessions"}).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+	defer cancel()
+
+	filter := notDeleted(bson.M{
+		constants.MongoFieldUserID:   s.StoredUserID(guestID),
+		constants.MongoFieldTenantID: bson.M{"$exists": false},
+	})
+	cursor, err := s.collection.Find(ctx, filter, gomongo.QueryOptions{
+		Limit: int64(constants.MaxSessionLimit),
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find guest sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var ids, open []string
+	for cursor.Next(ctx) {
+		var doc SessionDocument
+		// No else needed: early return pattern (guard clause)
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode guest session: %w", err)
+		}
+		ids = append(ids, doc.ID)
+		// No else needed: optional operation (only open sessions are ended)
+		if doc.EndTime == nil {
+			open = append(open, doc.ID)
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read guest sessions: %w", err)
+	}
+	// No else needed: early return pattern (nothing to claim)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	for _, sessionID := range open {
+		// No else needed: early return pattern (guard clause)
+		if err := s.EndSession(sessionID, now); err != nil {
+			return nil, fmt.Errorf("failed to end guest session: %w", err)
+		}
+	}
+
+	set := bson.M{constants.MongoFieldUserID: s.StoredUserID(userID)}
+	// No else needed: optional operation (the default tenant has no tenant field)
+	if s.tenantID != "" {
+		set[constants.MongoFieldTenantID] = s.tenantID
+	}
+	err = s.retryOperation(ctx, "ClaimGuestSessions", func() error {
+		_, opErr := s.collection.UpdateMany(ctx, bson.M{constants.MongoFieldID: bson.M{"$in": ids}}, bson.M{"$set": set})
+		return opErr
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim guest sessions: %w", err)
+	}
+
+	s.logger.Info("Guest sessions claimed", "count", len(ids), "ended", len(open))
+	return ids, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameUserSession(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	createSessionWithActivity(t, service, "session-1", time.Now())
+
+	require.NoError(t, service.RenameUserSession("session-1", "user-1", "Trip planning"))
+	sess, err := service.GetSession("session-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Trip planning", sess.Name)
+
+	// Other users' sessions look the same as missing ones
+	assert.ErrorIs(t, service.RenameUserSession("session-1", "user-2", "Mine now"), ErrSessionNotFound)
+	assert.ErrorIs(t, service.RenameUserSession("missing", "user-1", "Name"), ErrSessionNotFound)
+	assert.ErrorIs(t, service.ForTenant("tenant-b").RenameUserSession("session-1", "user-1", "Name"), ErrSessionNotFound)
+	assert.ErrorIs(t, service.RenameUserSession("", "user-1", "Name"), ErrInvalidSessionID)
+
+	service.anonymized = true
+	assert.ErrorIs(t, service.RenameUserSession("session-1", "user-1", "Name"), ErrAnonymizedMode)
+}
+
+func TestDeleteUserSession(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	createSessionWithActivity(t, service, "session-1", now.Add(-time.Hour))
+
+	_, err := service.DeleteUserSession("session-1", "user-2", now)
+	assert.ErrorIs(t, err, ErrSessionNotFound, "only the owner can delete a session")
+
+	wasActive, err := service.DeleteUserSession("session-1", "user-1", now)
+	require.NoError(t, err)
+	assert.True(t, wasActive)
+
+	// Deleted sessions are hidden, but ended and restorable
+	_, err = service.GetSession("session-1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.DeleteUserSession("session-1", "user-1", now)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	require.NoError(t, service.RestoreSession("session-1"))
+	sess, err := service.GetSession("session-1")
+	require.NoError(t, err)
+	require.NotNil(t, sess.EndTime)
+	assert.WithinDuration(t, now, *sess.EndTime, time.Second)
+	assert.False(t, sess.IsActive)
+}
+
+func TestDeleteUserSession_AlreadyEnded(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	endTime := now.Add(-time.Minute)
+	createSessionWithActivity(t, service, "session-1", now.Add(-time.Hour))
+	require.NoError(t, service.EndSession("session-1", endTime))
+
+	wasActive, err := service.DeleteUserSession("session-1", "user-1", now)
+	require.NoError(t, err)
+	assert.False(t, wasActive)
+
+	require.NoError(t, service.RestoreSession("session-1"))
+	sess, err := service.GetSession("session-1")
+	require.NoError(t, err)
+	require.NotNil(t, sess.EndTime)
+	assert.WithinDuration(t, endTime, *sess.EndTime, time.Second, "end time is kept")
+}
+
+func TestClaimGuestSessions(t *testing.T) {
+	service, cleanup := setupTestStorage(t, nil)
+	defer cleanup()
+
+	now := time.Now()
+	createTenantSession(t, service, "guest-open", "guest-1", "")
+	createTenantSession(t, service, "guest-ended", "guest-1", "")
+	require.NoError(t, service.EndSession("guest-ended", now.Add(-time.Hour)))
+	createTenantSession(t, service, "other-guest", "guest-2", "")
+	createTenantSession(t, service, "tenant-guest", "guest-1", "tenant-b")
+
+	ids, err := service.ForTenant("tenant-a").ClaimGuestSessions("guest-1", "user-1", now)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"guest-open", "guest-ended"}, ids)
+
+	claimed, err := service.ForTenant("tenant-a").GetSession("guest-open")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claimed.UserID)
+	assert.Equal(t, "tenant-a", claimed.TenantID)
+	require.NotNil(t, claimed.EndTime, "open guest sessions are ended")
+	assert.WithinDuration(t, now, *claimed.EndTime, time.Second)
+
+	ended, err := service.GetSession("guest-ended")
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(-time.Hour), *ended.EndTime, time.Second, "end time is kept")
+
+	// Other guests and sessions outside the default tenant are untouched
+	other, err := service.GetSession("other-guest")
+	require.NoError(t, err)
+	assert.Equal(t, "guest-2", other.UserID)
+	tenant, err := service.GetSession("tenant-guest")
+	require.NoError(t, err)
+	assert.Equal(t, "guest-1", tenant.UserID)
+
+	// Claiming again finds nothing
+	ids, err = service.ClaimGuestSessions("guest-1", "user-1", now)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	_, err = service.ClaimGuestSessions("", "user-1", now)
+	assert.ErrorIs(t, err, ErrInvalidUserID)
+}
@@ -0,0 +1,152 @@
+// Package tokenizer estimates how many tokens a text costs with a given model.
+//
+// Token counts drive per-session usage and daily token budgets, and each model
+// family splits text differently. A Counter is chosen per model: OpenAI models use a tiktoken-compatible
+// counter, other families a characters-per-token heuristic. Models can
+// override the choice with the tokenizer and chars_per_token settings of their
+// [chatbox.models.<id>] table.
+package tokenizer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// Tokenizer names accepted in model configuration
+const (
+	NameTiktoken  = "tiktoken"
+	NameHeuristic = "heuristic"
+)
+
+// anthropicCharsPerToken is the average characters per token of Claude models,
+// which split English text finer than OpenAI encodings
+const anthropicCharsPerToken = 3.5
+
+// Counter estimates the number of tokens in a text
+type Counter interface {
+	// Count returns the estimated token count of text; empty text costs 0
+	Count(text string) int
+	// Name returns the tokenizer name used in configuration
+	Name() string
+}
+
+// Heuristic estimates tokens from the text length at a fixed number of
+// characters per token. Characters are counted as runes so that non-Latin
+// scripts are not overcounted by their UTF-8 length.
+type Heuristic struct {
+	CharsPerToken float64
+}
+
+// Count returns the rune count divided by CharsPerToken, rounded up
+func (h Heuristic) Count(text string) int {
+	runes := utf8.RuneCountInString(text)
+	// No else needed: early return pattern (nothing to count)
+	if runes == 0 {
+		return 0
+	}
+	perToken := h.CharsPerToken
+	// No else needed: conditional assignment (zero value uses the default)
+	if perToken <= 0 {
+		perToken = constants.CharsPerToken
+	}
+	return int(math.Ceil(float64(runes) / perToken))
+}
+
+// Name returns NameHeuristic
+func (h Heuristic) Name() string {
+	return NameHeuristic
+}
+
+// pretokenPattern splits text the way tiktoken's cl100k_base and o200k_base
+// encodings do before applying byte-pair merges: contractions, words with
+// their leading space, digit groups of up to three, punctuation runs and
+// whitespace. The trailing-whitespace lookahead of the original pattern is not
+// supported by RE2 and is dropped, which only moves a space between pieces.
+var pretokenPattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// Tiktoken estimates tokens for OpenAI models. It pre-tokenizes text exactly
+// like tiktoken and estimates the merges within each piece instead of loading
+// the encoding's rank table: short words and digit groups are one token, longer
+// words and non-Latin text are split by length. This stays close to tiktoken's
+// counts for English prose and code without bundling its vocabulary.
+type Tiktoken struct{}
+
+// Count returns the estimated tiktoken token count of text
+func (Tiktoken) Count(text string) int {
+	tokens := 0
+	for _, piece := range pretokenPattern.FindAllString(text, -1) {
+		tokens += pieceTokens(piece)
+	}
+	return tokens
+}
+
+// Name returns NameTiktoken
+func (Tiktoken) Name() string {
+	return NameTiktoken
+}
+
+// pieceTokens estimates the byte-pair merges of one pre-tokenized piece.
+// ASCII pieces of up to six bytes (a leading space plus a short word, a digit
+// group, a contraction) are almost always a single token in cl100k_base;
+// longer ASCII pieces split about every four bytes. Multi-byte text merges far
+// less, at roughly one token per character.
+func pieceTokens(piece string) int {
+	runes := utf8.RuneCountInString(piece)
+	// No else needed: early return pattern (multi-byte text)
+	if runes != len(piece) {
+		return runes
+	}
+	// No else needed: early return pattern (short pieces are one token)
+	if len(piece) <= 6 {
+		return 1
+	}
+	return (len(piece) + 3) / 4
+}
+
+// ForProvider returns the default counter for a provider type: tiktoken for
+// OpenAI, a 3.5 characters-per-token heuristic for Anthropic and the generic
+// heuristic (constants.CharsPerToken) for everything else.
+func ForProvider(providerType string) Counter {
+	switch providerType {
+	case "openai":
+		return Tiktoken{}
+	case "anthropic":
+		return Heuristic{CharsPerToken: anthropicCharsPerToken}
+	default:
+		return Heuristic{CharsPerToken: constants.CharsPerToken}
+	}
+}
+
+// New returns the counter configured for a model. An empty name uses the
+// provider type's default; charsPerToken only applies to the heuristic and 0
+// keeps its default.
+func New(name, providerType string, charsPerToken float64) (Counter, error) {
+	// No else needed: early return pattern (guard clause)
+	if charsPerToken < 0 {
+		return nil, fmt.Errorf("chars_per_token must not be negative")
+	}
+
+	switch name {
+	case "":
+		counter := ForProvider(providerType)
+		// No else needed: optional operation (only heuristic counters take a ratio)
+		if _, ok := counter.(Heuristic); ok && charsPerToken > 0 {
+			return Heuristic{CharsPerToken: charsPerToken}, nil
+		}
+		return counter, nil
+	case NameTiktoken:
+		return Tiktoken{}, nil
+	case NameHeuristic:
+		// No else needed: conditional assignment (zero uses the default ratio)
+		if charsPerToken == 0 {
+			charsPerToken = constants.CharsPerToken
+		}
+		return Heuristic{CharsPerToken: charsPerToken}, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q (expected %q or %q)", name, NameTiktoken, NameHeuristic)
+	}
+}
@@ -0,0 +1,76 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeuristic_Count(t *testing.T) {
+	h := Heuristic{CharsPerToken: 4}
+	assert.Equal(t, 0, h.Count(""))
+	assert.Equal(t, 1, h.Count("abc"))
+	assert.Equal(t, 2, h.Count("abcdefgh"))
+	assert.Equal(t, 3, h.Count("abcdefghi"))
+
+	// Runes, not bytes: four CJK characters are twelve UTF-8 bytes
+	assert.Equal(t, 1, h.Count("你好世界"))
+
+	// The zero value falls back to the default ratio
+	assert.Equal(t, 2, Heuristic{}.Count("abcdefgh"))
+}
+
+func TestTiktoken_Count(t *testing.T) {
+	tk := Tiktoken{}
+	assert.Equal(t, 0, tk.Count(""))
+
+	// Short words with their leading space are one token each
+	assert.Equal(t, 9, tk.Count("The quick brown fox jumps over the lazy dog"))
+	// Contractions and punctuation are separate pieces
+	assert.Equal(t, 4, tk.Count("I'm here."))
+	// Digits are grouped by three
+	assert.Equal(t, 3, tk.Count("1234567"))
+	// Long words split by length
+	assert.Equal(t, 5, tk.Count("internationalization"))
+	// Non-Latin text costs about one token per character
+	assert.Equal(t, 4, tk.Count("你好世界"))
+
+	// Prose stays near the usual four characters per token
+	prose := strings.Repeat("Tokens are estimated per model family. ", 10)
+	assert.InDelta(t, len(prose)/4, tk.Count(prose), float64(len(prose))/8)
+}
+
+func TestForProvider(t *testing.T) {
+	assert.Equal(t, NameTiktoken, ForProvider("openai").Name())
+	assert.Equal(t, Heuristic{CharsPerToken: anthropicCharsPerToken}, ForProvider("anthropic"))
+	assert.Equal(t, Heuristic{CharsPerToken: 4}, ForProvider("dify"))
+}
+
+func TestNew(t *testing.T) {
+	counter, err := New("", "openai", 0)
+	require.NoError(t, err)
+	assert.Equal(t, NameTiktoken, counter.Name())
+
+	counter, err = New("", "dify", 3)
+	require.NoError(t, err)
+	assert.Equal(t, Heuristic{CharsPerToken: 3}, counter, "a ratio overrides the family heuristic")
+
+	counter, err = New("", "openai", 3)
+	require.NoError(t, err)
+	assert.Equal(t, NameTiktoken, counter.Name(), "a ratio does not replace tiktoken")
+
+	counter, err = New(NameHeuristic, "openai", 0)
+	require.NoError(t, err)
+	assert.Equal(t, Heuristic{CharsPerToken: 4}, counter)
+
+	counter, err = New(NameTiktoken, "anthropic", 0)
+	require.NoError(t, err)
+	assert.Equal(t, NameTiktoken, counter.Name())
+
+	_, err = New("sentencepiece", "openai", 0)
+	assert.Error(t, err)
+	_, err = New(NameHeuristic, "openai", -1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+// Package tracing sets up OpenTelemetry tracing for the chatbox service.
+//
+// Spans cover incoming HTTP requests (including WebSocket upgrades), WebSocket
+// message handling, LLM calls and MongoDB operations on the message path, so slow
+// end-to-end message latency can be broken down per stage. Trace context is
+// propagated from incoming W3C traceparent headers and exported over OTLP/HTTP.
+//
+// Until Init is called the global OpenTelemetry provider is a no-op, so the
+// helpers in this package are free to call when tracing is disabled.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attribute keys specific to the chatbox
+const (
+	AttrSessionID   = attribute.Key("chatbox.session_id")
+	AttrMessageType = attribute.Key("chatbox.message_type")
+	AttrModelID     = attribute.Key("chatbox.model_id")
+	AttrProvider    = attribute.Key("chatbox.llm_provider")
+	AttrRequestID   = attribute.Key("chatbox.request_id")
+)
+
+// Config holds the tracing exporter settings
+type Config struct {
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://otel-collector:4318.
+	// When empty the standard OTEL_EXPORTER_OTLP_* environment variables apply.
+	Endpoint string
+
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+
+	// SampleRatio is the fraction of new traces sampled (0-1). Traces started
+	// upstream follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// Init installs an OTLP-exporting tracer provider and the W3C trace context
+// propagator as the OpenTelemetry globals. The caller must call Shutdown on the
+// returned provider to flush buffered spans.
+func Init(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("tracing sample ratio must be between 0 and 1, got %v", cfg.SampleRatio)
+	}
+
+	var opts []otlptracehttp.Option
+	// No else needed: optional operation (default to OTEL_EXPORTER_OTLP_* environment)
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	serviceName := cfg.ServiceName
+	// No else needed: optional operation (apply default)
+	if serviceName == "" {
+		serviceName = constants.DefaultTracingServiceName
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return provider, nil
+}
+
+// Tracer returns the chatbox tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(constants.TracerName)
+}
+
+// Start starts an internal span as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed when err is not nil
+func End(span trace.Span, err error) {
+	RecordError(span, err)
+	span.End()
+}
+
+// RecordError marks span failed with err. A nil err is ignored.
+func RecordError(span trace.Span, err error) {
+	// No else needed: optional operation (only record failures)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// StartMongo starts a span for a MongoDB operation issued by the storage layer
+func StartMongo(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "mongodb."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMongoDB, semconv.DBOperationName(operation)))
+}
+
+// Middleware returns a gin middleware that continues the trace from incoming
+// traceparent headers and wraps each request in a server span. Handlers find
+// the span in c.Request.Context().
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		// No else needed: optional operation (unmatched routes keep a bounded span name)
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		// No else needed: optional operation (only server errors fail the span)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
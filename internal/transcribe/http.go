@@ -0,0 +1,126 @@
+package transcribe
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/jsonhttp"
+)
+
+// ErrNoEndpoint is returned when the HTTP provider is created without an endpoint
+var ErrNoEndpoint = errors.New("transcription endpoint is required")
+
+// HTTPConfig holds HTTP speech-to-text service settings
+type HTTPConfig struct {
+	Endpoint string        // URL the audio chunks are POSTed to
+	APIKey   string        // Sent as a bearer token when set
+	Timeout  time.Duration // HTTP timeout per chunk (defaults to constants.DefaultTranscriptionTimeout)
+}
+
+// HTTPProvider streams audio to an external speech-to-text service. Each chunk
+// is POSTed as JSON {"stream_id", "session_id", "seq", "format", "audio",
+// "final"}, with the audio base64-encoded, and the service answers
+// {"transcript"} with the transcript of the stream so far. The last request
+// has "final": true and no audio, and is answered with the final transcript.
+// The service keeps the state of a stream by its ID; abandoned streams are
+// never finished, so it should expire them.
+type HTTPProvider struct {
+	client *jsonhttp.Client
+}
+
+type httpChunkRequest struct {
+	StreamID  string `json:"stream_id"`
+	SessionID string `json:"session_id"`
+	Seq       int    `json:"seq"`
+	Format    string `json:"format"`
+	Audio     []byte `json:"audio,omitempty"`
+	Final     bool   `json:"final"`
+}
+
+type httpChunkResponse struct {
+	Transcript string `json:"transcript"`
+}
+
+// httpStream is a recording being streamed to the service
+type httpStream struct {
+	provider  *HTTPProvider
+	id        string
+	sessionID string
+	format    string
+	seq       int
+}
+
+// NewHTTPProvider validates the configuration and creates the provider.
+// The endpoint must use https, except internal hosts which may use http.
+func NewHTTPProvider(cfg HTTPConfig) (*HTTPProvider, error) {
+	// No else needed: early return pattern (guard clause)
+	if cfg.Endpoint == "" {
+		return nil, ErrNoEndpoint
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultTranscriptionTimeout
+	}
+
+	client, err := jsonhttp.New("transcription", cfg.Endpoint, cfg.APIKey, timeout)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPProvider{client: client}, nil
+}
+
+// Start begins a stream. Nothing is sent until the first chunk.
+func (p *HTTPProvider) Start(_ context.Context, sessionID, format string) (Stream, error) {
+	id := make([]byte, 16)
+	// No else needed: early return pattern (guard clause)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate stream ID: %w", err)
+	}
+	return &httpStream{
+		provider:  p,
+		id:        hex.EncodeToString(id),
+		sessionID: sessionID,
+		format:    format,
+	}, nil
+}
+
+// Send posts a chunk of audio and returns the interim transcript
+func (s *httpStream) Send(ctx context.Context, audio []byte) (string, error) {
+	return s.post(ctx, audio, false)
+}
+
+// Finish tells the service the recording is complete and returns the final transcript
+func (s *httpStream) Finish(ctx context.Context) (string, error) {
+	return s.post(ctx, nil, true)
+}
+
+// Close does nothing: the service expires streams that are never finished
+func (s *httpStream) Close() {}
+
+// post sends the next request of the stream
+func (s *httpStream) post(ctx context.Context, audio []byte, final bool) (string, error) {
+	req := httpChunkRequest{
+		StreamID:  s.id,
+		SessionID: s.sessionID,
+		Seq:       s.seq,
+		Format:    s.format,
+		Audio:     audio,
+		Final:     final,
+	}
+	s.seq++
+
+	var parsed httpChunkResponse
+	// No else needed: early return pattern (guard clause)
+	if err := s.provider.client.Post(ctx, req, &parsed); err != nil {
+		return "", err
+	}
+	return parsed.Transcript, nil
+}
@@ -0,0 +1,88 @@
+package transcribe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPProvider_Validation(t *testing.T) {
+	_, err := NewHTTPProvider(HTTPConfig{})
+	assert.ErrorIs(t, err, ErrNoEndpoint)
+
+	_, err = NewHTTPProvider(HTTPConfig{Endpoint: "http://stt.example.com/stream"})
+	assert.Error(t, err, "public endpoints must use https")
+
+	p, err := NewHTTPProvider(HTTPConfig{Endpoint: "https://stt.example.com/stream"})
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultTranscriptionTimeout, p.client.Timeout())
+}
+
+func TestHTTPProvider_Stream(t *testing.T) {
+	var got []httpChunkRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var req httpChunkRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		got = append(got, req)
+		transcript := "hello"
+		if req.Final {
+			transcript = "Hello there."
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(httpChunkResponse{Transcript: transcript})
+	}))
+	defer server.Close()
+
+	p, err := NewHTTPProvider(HTTPConfig{Endpoint: server.URL, APIKey: "test-key"})
+	require.NoError(t, err)
+	stream, err := p.Start(context.Background(), "session-1", "audio/webm")
+	require.NoError(t, err)
+	defer stream.Close()
+
+	transcript, err := stream.Send(context.Background(), []byte{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", transcript)
+	_, err = stream.Send(context.Background(), []byte{4})
+	require.NoError(t, err)
+	transcript, err = stream.Finish(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there.", transcript)
+
+	require.Len(t, got, 3)
+	assert.NotEmpty(t, got[0].StreamID)
+	for i, req := range got {
+		assert.Equal(t, got[0].StreamID, req.StreamID)
+		assert.Equal(t, "session-1", req.SessionID)
+		assert.Equal(t, "audio/webm", req.Format)
+		assert.Equal(t, i, req.Seq)
+	}
+	assert.Equal(t, []byte{1, 2, 3}, got[0].Audio)
+	assert.False(t, got[1].Final)
+	assert.True(t, got[2].Final)
+	assert.Empty(t, got[2].Audio)
+}
+
+func TestHTTPProvider_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"), "no API key configured")
+		http.Error(w, "unsupported codec", http.StatusUnprocessableEntity)
+	}))
+	defer server.Close()
+
+	p, err := NewHTTPProvider(HTTPConfig{Endpoint: server.URL})
+	require.NoError(t, err)
+	stream, err := p.Start(context.Background(), "session-1", "audio/ogg")
+	require.NoError(t, err)
+
+	_, err = stream.Send(context.Background(), []byte{1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "422")
+	assert.Contains(t, err.Error(), "unsupported codec")
+}
@@ -0,0 +1,59 @@
+// Package transcribe turns voice messages streamed by clients into text.
+//
+// A client records a voice message and sends it in audio_chunk frames while
+// the user is still speaking. The router starts a Stream for the recording
+// with the configured Provider, feeds it each chunk and relays the interim
+// transcript back to the client, then finishes the stream on the last chunk
+// and stores the final transcript on the voice message. HTTPProvider is the
+// reference implementation, calling an external speech-to-text service.
+package transcribe
+
+import (
+	"context"
+	"strings"
+)
+
+// Formats maps the audio formats (MIME types) clients may stream to the file
+// extension the recording is stored with
+var Formats = map[string]string{
+	"audio/webm": ".webm",
+	"audio/ogg":  ".ogg",
+	"audio/wav":  ".wav",
+	"audio/mpeg": ".mp3",
+	"audio/aac":  ".aac",
+	"audio/m4a":  ".m4a",
+}
+
+// Provider starts speech-to-text streams
+type Provider interface {
+	// Start begins transcribing a recording of the given format for sessionID
+	Start(ctx context.Context, sessionID, format string) (Stream, error)
+}
+
+// Stream transcribes one recording as it arrives. Its methods are not called
+// concurrently.
+type Stream interface {
+	// Send feeds the next chunk of audio and returns the transcript so far
+	Send(ctx context.Context, audio []byte) (string, error)
+	// Finish ends the recording and returns the final transcript
+	Finish(ctx context.Context) (string, error)
+	// Close abandons a stream that is not finished
+	Close()
+}
+
+// SupportedFormat reports whether format (a MIME type, parameters ignored)
+// can be streamed
+func SupportedFormat(format string) bool {
+	_, ok := Formats[baseFormat(format)]
+	return ok
+}
+
+// Extension returns the file extension recordings of format are stored with
+func Extension(format string) string {
+	return Formats[baseFormat(format)]
+}
+
+// baseFormat strips the parameters of a MIME type, e.g. "audio/webm;codecs=opus"
+func baseFormat(format string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(format, ";")[0]))
+}
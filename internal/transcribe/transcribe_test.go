@@ -0,0 +1,19 @@
+package transcribe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupportedFormat(t *testing.T) {
+	assert.True(t, SupportedFormat("audio/webm"))
+	assert.True(t, SupportedFormat("audio/webm;codecs=opus"))
+	assert.True(t, SupportedFormat(" Audio/OGG "))
+	assert.False(t, SupportedFormat("video/mp4"))
+	assert.False(t, SupportedFormat(""))
+
+	assert.Equal(t, ".webm", Extension("audio/webm; codecs=opus"))
+	assert.Equal(t, ".mp3", Extension("audio/mpeg"))
+	assert.Empty(t, Extension("text/plain"))
+}
@@ -0,0 +1,260 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+	"github.com/real-rm/goupload"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BlobDocument is the file_stats record of a deduplicated upload: one stored
+// copy per distinct content, keyed by the SHA-256 of the content and shared by
+// every upload of it. Refs counts the uploads still referencing the copy; the
+// garbage collector deletes copies left unreferenced for a grace period.
+type BlobDocument struct {
+	ID       string    `bson:"_id"` // constants.BlobIDPrefix + hex SHA-256
+	Path     string    `bson:"path"`
+	URL      string    `bson:"url"`
+	Size     int64     `bson:"size"`
+	MimeType string    `bson:"mime"`
+	Refs     int       `bson:"refs"`
+	Created  time.Time `bson:"ts"`
+	Modified time.Time `bson:"mt"` // Last reference change
+}
+
+// EnsureIndexes creates the file_stats indexes used to release references and
+// find unreferenced copies
+func (u *UploadService) EnsureIndexes(ctx context.Context) error {
+	blobsOnly := bson.M{constants.MongoFieldBlobRefs: bson.M{"$exists": true}}
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: constants.MongoFieldBlobPath, Value: 1}},
+			Options: options.Index().SetName(constants.IndexBlobPath).SetPartialFilterExpression(blobsOnly),
+		},
+		{
+			Keys: bson.D{
+				{Key: constants.MongoFieldBlobRefs, Value: 1},
+				{Key: constants.MongoFieldModified, Value: 1},
+			},
+			Options: options.Index().SetName(constants.IndexBlobRefs).SetPartialFilterExpression(blobsOnly),
+		},
+	}
+
+	_, err := u.blobs.CreateIndexes(ctx, indexes)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return fmt.Errorf("failed to create file_stats indexes: %w", err)
+	}
+	return nil
+}
+
+// contentHash returns the hex SHA-256 of content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// uploadDeduplicated stores content once per distinct SHA-256: when a copy
+// already exists it gains a reference and is returned instead of uploading
+// the content again
+func (u *UploadService) uploadDeduplicated(ctx context.Context, content []byte, filename, userID string) (*UploadResult, error) {
+	hash := contentHash(content)
+	blobID := constants.BlobIDPrefix + hash
+
+	result, err := u.addReference(ctx, blobID)
+	// No else needed: early return pattern (existing copy referenced)
+	if err == nil {
+		return result, nil
+	}
+	// No else needed: early return pattern (guard clause)
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to look up file: %w", err)
+	}
+
+	stored, err := u.store(ctx, content, filename, userID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	stored.Hash = hash
+
+	now := time.Now().UTC()
+	_, err = u.blobs.InsertOne(ctx, BlobDocument{
+		ID:       blobID,
+		Path:     stored.FileID,
+		URL:      stored.FileURL,
+		Size:     stored.Size,
+		MimeType: stored.MimeType,
+		Refs:     1,
+		Created:  now,
+		Modified: now,
+	})
+	// No else needed: early return pattern (first copy recorded)
+	if err == nil {
+		return stored, nil
+	}
+
+	// Either way our copy is not recorded: remove it so it is not left behind
+	// unreferenced. A concurrent upload of the same content won the insert, so
+	// its copy is referenced instead.
+	_, _ = goupload.Delete(ctx, u.statsUpdater, u.site, u.entryName, stored.FileID)
+	// No else needed: early return pattern (guard clause)
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("failed to record file: %w", err)
+	}
+	result, err = u.addReference(ctx, blobID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reference file: %w", err)
+	}
+	return result, nil
+}
+
+// addReference adds a reference to the stored copy blobID and returns it.
+// Returns mongo.ErrNoDocuments when there is no such copy.
+func (u *UploadService) addReference(ctx context.Context, blobID string) (*UploadResult, error) {
+	var doc BlobDocument
+	err := u.blobs.FindOneAndUpdate(ctx,
+		bson.M{constants.MongoFieldID: blobID},
+		bson.M{
+			"$inc": bson.M{constants.MongoFieldBlobRefs: 1},
+			"$set": bson.M{constants.MongoFieldModified: time.Now().UTC()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&doc)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{
+		FileID:       doc.Path,
+		FileURL:      doc.URL,
+		Size:         doc.Size,
+		MimeType:     doc.MimeType,
+		Hash:         doc.ID[len(constants.BlobIDPrefix):],
+		Deduplicated: true,
+	}, nil
+}
+
+// releaseReference removes a reference to the stored copy at fileID. Returns
+// false when fileID is not a deduplicated copy.
+func (u *UploadService) releaseReference(ctx context.Context, fileID string) (bool, error) {
+	result, err := u.blobs.UpdateOne(ctx,
+		bson.M{constants.MongoFieldBlobPath: fileID, constants.MongoFieldBlobRefs: bson.M{"$exists": true}},
+		bson.M{
+			"$inc": bson.M{constants.MongoFieldBlobRefs: -1},
+			"$set": bson.M{constants.MongoFieldModified: time.Now().UTC()},
+		},
+	)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return false, fmt.Errorf("failed to release file: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// CollectGarbage deletes the stored copies that have had no references since
+// before cutoff and returns how many were deleted. A copy referenced again
+// while it is being collected is kept.
+func (u *UploadService) CollectGarbage(ctx context.Context, cutoff time.Time) (int, error) {
+	// No else needed: early return pattern (no deduplicated uploads)
+	if u.blobs == nil {
+		return 0, nil
+	}
+
+	cursor, err := u.blobs.Find(ctx, bson.M{
+		constants.MongoFieldBlobRefs: bson.M{"$lte": 0},
+		constants.MongoFieldModified: bson.M{"$lt": cutoff},
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find unreferenced files: %w", err)
+	}
+	var docs []BlobDocument
+	// No else needed: early return pattern (guard clause)
+	if err := cursor.All(ctx, &docs); err != nil {
+		return 0, fmt.Errorf("failed to decode unreferenced files: %w", err)
+	}
+
+	deleted := 0
+	for _, doc := range docs {
+		// Remove the record first, so a concurrent upload of the same content
+		// either references the copy before (and keeps it) or stores a new one
+		result, err := u.blobs.DeleteOne(ctx, bson.M{
+			constants.MongoFieldID:       doc.ID,
+			constants.MongoFieldBlobRefs: bson.M{"$lte": 0},
+		})
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete file record %s: %w", doc.ID, err)
+		}
+		// No else needed: optional operation (referenced again meanwhile)
+		if result.DeletedCount == 0 {
+			continue
+		}
+		// No else needed: early return pattern (guard clause)
+		if _, err := goupload.Delete(ctx, u.statsUpdater, u.site, u.entryName, doc.Path); err != nil {
+			// Put the record back so the next run retries the copy
+			_, _ = u.blobs.InsertOne(ctx, doc)
+			return deleted, fmt.Errorf("failed to delete file %s: %w", doc.Path, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// StartGarbageCollector periodically deletes stored copies left unreferenced
+// for longer than grace. Stop it with StopGarbageCollector.
+func (u *UploadService) StartGarbageCollector(interval, grace time.Duration, logger *golog.Logger) {
+	u.gcStop = make(chan struct{})
+	u.gcWg.Add(1)
+	go func() {
+		defer u.gcWg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-u.gcStop:
+				return
+			}
+
+			ctx, cancel := util.NewTimeoutContext(constants.LongContextTimeout)
+			deleted, err := u.CollectGarbage(ctx, time.Now().Add(-grace))
+			cancel()
+			// No else needed: optional operation (errors are logged, next run retries)
+			if err != nil {
+				util.LogError(logger, "upload", "collect unreferenced uploads", err, "deleted", deleted)
+				continue
+			}
+			// No else needed: optional operation (log only when something was deleted)
+			if deleted > 0 {
+				logger.Info("Collected unreferenced uploads", "deleted", deleted)
+			}
+		}
+	}()
+}
+
+// StopGarbageCollector stops the garbage collector goroutine.
+// Safe to call multiple times and when the collector was never started.
+func (u *UploadService) StopGarbageCollector() {
+	// No else needed: early return pattern (collector not started)
+	if u.gcStop == nil {
+		return
+	}
+	u.gcOnce.Do(func() {
+		close(u.gcStop)
+	})
+	u.gcWg.Wait()
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/real-rm/gomongo"
@@ -93,20 +94,29 @@ var MaliciousPatterns = [][]byte{
 	[]byte("onload="),
 }
 
-// UploadService manages file storage using goupload
+// UploadService manages file storage using goupload. Uploads are
+// deduplicated by content (see dedup.go).
 type UploadService struct {
 	statsUpdater goupload.StatsUpdater
+	blobs        *gomongo.MongoCollection // file_stats: reference counts of deduplicated uploads
 	site         string
 	entryName    string
 	maxFileSize  int64 // Maximum file size in bytes
+
+	// Unreferenced upload garbage collector (see dedup.go)
+	gcStop chan struct{}
+	gcOnce sync.Once
+	gcWg   sync.WaitGroup
 }
 
 // UploadResult contains information about an uploaded file
 type UploadResult struct {
-	FileID   string
-	FileURL  string
-	Size     int64
-	MimeType string
+	FileID       string
+	FileURL      string
+	Size         int64
+	MimeType     string
+	Hash         string // Hex SHA-256 of the content
+	Deduplicated bool   // An identical file was already stored and is shared
 }
 
 // NewUploadService creates a new upload service using goupload
@@ -131,6 +141,7 @@ func NewUploadService(site, entryName string, statsColl *gomongo.MongoCollection
 
 	return &UploadService{
 		statsUpdater: statsUpdater,
+		blobs:        statsColl,
 		site:         site,
 		entryName:    entryName,
 		maxFileSize:  100 * 1024 * 1024, // Default 100MB
@@ -262,7 +273,9 @@ func (u *UploadService) scanMaliciousContent(content []byte, filename string) er
 	return nil
 }
 
-// UploadFile uploads a file using goupload and returns file information
+// UploadFile uploads a file using goupload and returns file information.
+// Content that is already stored is not uploaded again: the result refers to
+// the existing copy, which gains a reference.
 func (u *UploadService) UploadFile(ctx context.Context, file io.Reader, filename string, userID string) (*UploadResult, error) {
 	if file == nil {
 		return nil, ErrInvalidFile
@@ -282,8 +295,21 @@ func (u *UploadService) UploadFile(ctx context.Context, file io.Reader, filename
 		return nil, err
 	}
 
+	if u.blobs != nil {
+		return u.uploadDeduplicated(ctx, validatedContent, filename, userID)
+	}
+	result, err := u.store(ctx, validatedContent, filename, userID)
+	if err != nil {
+		return nil, err
+	}
+	result.Hash = contentHash(validatedContent)
+	return result, nil
+}
+
+// store uploads validated content using goupload
+func (u *UploadService) store(ctx context.Context, content []byte, filename string, userID string) (*UploadResult, error) {
 	// Create a new reader from validated content
-	validatedReader := bytes.NewReader(validatedContent)
+	validatedReader := bytes.NewReader(content)
 
 	// Upload file using goupload
 	result, err := goupload.Upload(
@@ -337,12 +363,25 @@ func (u *UploadService) DownloadFile(ctx context.Context, filePath string) ([]by
 	return info.Content, info.Filename, nil
 }
 
-// DeleteFile deletes a file using goupload
+// DeleteFile deletes a file using goupload. A deduplicated file only loses a
+// reference; the stored copy is deleted by the garbage collector once no
+// upload references it.
 func (u *UploadService) DeleteFile(ctx context.Context, fileID string) error {
 	if fileID == "" {
 		return ErrInvalidFileID
 	}
 
+	// Files stored before deduplication have no reference count and are deleted directly
+	if u.blobs != nil {
+		released, err := u.releaseReference(ctx, fileID)
+		if err != nil {
+			return err
+		}
+		if released {
+			return nil
+		}
+	}
+
 	// Delete file using goupload
 	result, err := goupload.Delete(ctx, u.statsUpdater, u.site, u.entryName, fileID)
 	if err != nil {
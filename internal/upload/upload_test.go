@@ -760,3 +760,29 @@ func TestValidateFileType_BothMimeTypesDisallowed(t *testing.T) {
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, ErrInvalidFileType)
 }
+
+// TestContentHash covers the SHA-256 key of deduplicated uploads
+func TestContentHash(t *testing.T) {
+	// SHA-256 of "hello"
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", contentHash([]byte("hello")))
+	assert.Equal(t, contentHash([]byte("same pdf")), contentHash([]byte("same pdf")))
+	assert.NotEqual(t, contentHash([]byte("a.pdf")), contentHash([]byte("b.pdf")))
+}
+
+// TestUploadService_GarbageCollectorWithoutStats covers the garbage collector
+// of a service without a stats collection: nothing to collect, stop is safe
+func TestUploadService_GarbageCollectorWithoutStats(t *testing.T) {
+	service := &UploadService{
+		site:      "CHAT",
+		entryName: "uploads",
+	}
+
+	deleted, err := service.CollectGarbage(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	service.StopGarbageCollector()
+	service.StartGarbageCollector(time.Hour, time.Hour, nil)
+	service.StopGarbageCollector()
+	service.StopGarbageCollector()
+}
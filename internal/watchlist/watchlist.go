@@ -0,0 +1,193 @@
+// Package watchlist matches user messages against keyword watchlists managed
+// by admins, so conversations mentioning e.g. refunds, lawyers or account
+// cancellation are noticed while they happen.
+//
+// Each tenant has its own watchlist, stored in MongoDB. A keyword is either a
+// plain term, matched case-insensitively as whole words, or a regular
+// expression. When a user message matches, the router tags the session with
+// the keyword's tag and pushes an Alert to the admin dashboard streams.
+package watchlist
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// AlertType is the type of Alert frames on admin dashboard streams
+const AlertType = "keyword_alert"
+
+var (
+	// ErrKeywordNotFound is returned when a keyword does not exist or belongs to another tenant
+	ErrKeywordNotFound = errors.New("keyword not found")
+	// ErrInvalidKeyword is returned when a keyword fails validation
+	ErrInvalidKeyword = errors.New("invalid keyword")
+)
+
+// Keyword is an entry of a tenant's watchlist
+type Keyword struct {
+	ID        string    `bson:"_id" json:"id"`
+	TenantID  string    `bson:"tid,omitempty" json:"tenant_id,omitempty"` // empty for the default tenant
+	Term      string    `bson:"term" json:"term"`
+	Regex     bool      `bson:"regex" json:"regex"` // Term is a regular expression rather than plain words
+	Tag       string    `bson:"tag" json:"tag"`     // Tag of sessions the keyword matches in
+	CreatedBy string    `bson:"by" json:"created_by"`
+	CreatedAt time.Time `bson:"ts" json:"created_at"`
+	UpdatedAt time.Time `bson:"mt" json:"updated_at"`
+}
+
+// Validate checks that a term is within the length limit and, for regular
+// expressions, compiles
+func Validate(term string, regex bool) error {
+	// No else needed: early return pattern (guard clause)
+	if strings.TrimSpace(term) == "" || len(term) > constants.MaxKeywordTermLength {
+		return fmt.Errorf("%w: term must be 1-%d bytes", ErrInvalidKeyword, constants.MaxKeywordTermLength)
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, err := compile(term, regex); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidKeyword, err)
+	}
+	return nil
+}
+
+// wordChar matches a character that a word boundary can follow or precede
+var wordChar = regexp.MustCompile(`^\w$`)
+
+// compile returns the case-insensitive pattern of a term. Plain terms match
+// as whole words, with any whitespace between them.
+func compile(term string, regex bool) (*regexp.Regexp, error) {
+	// No else needed: early return pattern (admin-written pattern)
+	if regex {
+		return regexp.Compile("(?i)" + term)
+	}
+	words := strings.Fields(term)
+	// No else needed: early return pattern (guard clause)
+	if len(words) == 0 {
+		return nil, errors.New("term has no words")
+	}
+	// Go's \b is ASCII-only, so the first and last bytes decide whether it fits
+	trimmed := strings.TrimSpace(term)
+	first, last := trimmed[:1], trimmed[len(trimmed)-1:]
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	pattern := strings.Join(words, `\s+`)
+	// No else needed: optional operation (a boundary only fits next to a word character)
+	if wordChar.MatchString(first) {
+		pattern = `\b` + pattern
+	}
+	// No else needed: optional operation (a boundary only fits next to a word character)
+	if wordChar.MatchString(last) {
+		pattern += `\b`
+	}
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// Alert is pushed to the admin dashboard streams when a user message matches
+// keywords of the tenant's watchlist
+type Alert struct {
+	Type      string    `json:"type"` // AlertType
+	SessionID string    `json:"session_id"`
+	UserID    string    `json:"user_id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Keywords  []string  `json:"keywords"` // Terms that matched
+	Tags      []string  `json:"tags"`     // Tags added to the session
+	Excerpt   string    `json:"excerpt"`  // Start of the message
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StoreFunc lists the keywords of a tenant's watchlist
+type StoreFunc func(tenantID string) ([]*Keyword, error)
+
+// matcher is a compiled keyword
+type matcher struct {
+	keyword *Keyword
+	re      *regexp.Regexp
+}
+
+// cacheEntry is a tenant's compiled watchlist and when it was looked up
+type cacheEntry struct {
+	matchers []matcher
+	loadedAt time.Time
+}
+
+// Service matches messages against the watchlists in the store. Watchlists
+// are cached per tenant for constants.KeywordCacheTTL so matching every user
+// message does not hit MongoDB; changes made on another instance become
+// visible once the cached watchlist expires.
+type Service struct {
+	store StoreFunc
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry // tenantID -> compiled watchlist
+}
+
+// NewService creates a watchlist service reading watchlists from store
+func NewService(store StoreFunc) *Service {
+	return &Service{
+		store: store,
+		cache: make(map[string]cacheEntry),
+	}
+}
+
+// Match returns the keywords of tenantID's watchlist that content matches,
+// in watchlist order
+func (s *Service) Match(tenantID, content string) ([]*Keyword, error) {
+	matchers, err := s.watchlist(tenantID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*Keyword
+	for _, m := range matchers {
+		// No else needed: optional operation (collect matching keywords)
+		if m.re.MatchString(content) {
+			matched = append(matched, m.keyword)
+		}
+	}
+	return matched, nil
+}
+
+// watchlist returns the compiled watchlist of tenantID, from the cache while it is fresh
+func (s *Service) watchlist(tenantID string) ([]matcher, error) {
+	s.mu.Lock()
+	entry, cached := s.cache[tenantID]
+	s.mu.Unlock()
+	// No else needed: early return pattern (fresh cache hit)
+	if cached && time.Since(entry.loadedAt) < constants.KeywordCacheTTL {
+		return entry.matchers, nil
+	}
+
+	keywords, err := s.store(tenantID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	matchers := make([]matcher, 0, len(keywords))
+	for _, k := range keywords {
+		re, err := compile(k.Term, k.Regex)
+		// No else needed: optional operation (skip keywords stored before validation tightened)
+		if err != nil {
+			continue
+		}
+		matchers = append(matchers, matcher{keyword: k, re: re})
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = cacheEntry{matchers: matchers, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return matchers, nil
+}
+
+// Invalidate drops the cached watchlist of tenantID after it was changed
+func (s *Service) Invalidate(tenantID string) {
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+}
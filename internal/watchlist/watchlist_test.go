@@ -0,0 +1,89 @@
+package watchlist
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("refund", false))
+	assert.NoError(t, Validate("cancel account", false))
+	assert.NoError(t, Validate(`law(yer|suit)s?`, true))
+	assert.ErrorIs(t, Validate("", false), ErrInvalidKeyword)
+	assert.ErrorIs(t, Validate(" \t", false), ErrInvalidKeyword)
+	assert.ErrorIs(t, Validate(strings.Repeat("k", constants.MaxKeywordTermLength+1), false), ErrInvalidKeyword)
+	assert.ErrorIs(t, Validate("(unclosed", true), ErrInvalidKeyword)
+}
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		term    string
+		regex   bool
+		content string
+		match   bool
+	}{
+		{"refund", false, "I want a REFUND now", true},
+		{"refund", false, "refunds are slow", false},
+		{"cancel account", false, "please cancel   my account", false},
+		{"cancel account", false, "please Cancel\nAccount", true},
+		{"a.b", false, "axb", false},
+		{"c++", false, "I write C++ daily", true},
+		{"lawyer|attorney", true, "my Attorney says", true},
+		{"lawyer|attorney", false, "my attorney says", false},
+	}
+	for _, tc := range cases {
+		re, err := compile(tc.term, tc.regex)
+		require.NoError(t, err, tc.term)
+		assert.Equal(t, tc.match, re.MatchString(tc.content), "%q in %q", tc.term, tc.content)
+	}
+}
+
+func TestService_MatchAndCache(t *testing.T) {
+	loads := 0
+	watchlists := map[string][]*Keyword{
+		"acme": {
+			{ID: "1", Term: "refund"},
+			{ID: "2", Term: "(broken", Regex: true},
+			{ID: "3", Term: "lawyer|attorney", Regex: true},
+		},
+	}
+	svc := NewService(func(tenantID string) ([]*Keyword, error) {
+		loads++
+		return watchlists[tenantID], nil
+	})
+
+	matched, err := svc.Match("acme", "My lawyer wants a refund")
+	require.NoError(t, err)
+	require.Len(t, matched, 2)
+	assert.Equal(t, "1", matched[0].ID)
+	assert.Equal(t, "3", matched[1].ID)
+
+	matched, err = svc.Match("acme", "Thanks!")
+	require.NoError(t, err)
+	assert.Empty(t, matched)
+	assert.Equal(t, 1, loads, "watchlists are cached")
+
+	// Other tenants have their own watchlist
+	matched, err = svc.Match("", "refund")
+	require.NoError(t, err)
+	assert.Empty(t, matched)
+	assert.Equal(t, 2, loads)
+
+	watchlists["acme"] = []*Keyword{{ID: "4", Term: "thanks"}}
+	svc.Invalidate("acme")
+	matched, err = svc.Match("acme", "Thanks!")
+	require.NoError(t, err)
+	require.Len(t, matched, 1)
+	assert.Equal(t, 3, loads)
+}
+
+func TestService_StoreError(t *testing.T) {
+	svc := NewService(func(string) ([]*Keyword, error) { return nil, errors.New("mongo down") })
+	_, err := svc.Match("acme", "refund")
+	assert.Error(t, err)
+}
@@ -0,0 +1,304 @@
+// Package webhook delivers chat lifecycle events (help requests, admin takeovers,
+// ended sessions) to external HTTP endpoints.
+//
+// Events are queued and delivered in the background by a single worker so callers
+// on the message path never block on network I/O. Each delivery is signed with
+// HMAC-SHA256 when a secret is configured and retried with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/golog"
+)
+
+// Event types posted to webhook endpoints
+const (
+	EventHelpRequested = "help_requested"
+	EventAdminTakeover = "admin_takeover"
+	EventAdminHandback = "admin_handback"
+	EventSessionEnded  = "session_ended"
+
+	// EventSentimentDropped is sent when a session's rolling sentiment drops
+	// below the alert threshold (see internal/sentiment)
+	EventSentimentDropped = "sentiment_dropped"
+
+	// EventAlertFiring and EventAlertResolved are sent when a usage alert rule
+	// starts or stops firing (see internal/alerts). They carry no session.
+	EventAlertFiring   = "alert_firing"
+	EventAlertResolved = "alert_resolved"
+)
+
+// ErrNoURLs is returned when a dispatcher is created without any endpoints
+var ErrNoURLs = errors.New("at least one webhook URL is required")
+
+// Event is the JSON body posted to webhook endpoints
+type Event struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	SessionID string            `json:"session_id"`
+	UserID    string            `json:"user_id,omitempty"`
+	TenantID  string            `json:"tenant_id,omitempty"`
+	AdminID   string            `json:"admin_id,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// Config holds dispatcher settings
+type Config struct {
+	URLs       []string      // Endpoints every event is posted to
+	Secret     string        // HMAC signing secret; deliveries are unsigned when empty
+	MaxRetries int           // Attempts per endpoint (defaults to constants.DefaultWebhookMaxRetries)
+	Timeout    time.Duration // Per-attempt HTTP timeout (defaults to constants.DefaultWebhookTimeout)
+}
+
+// Dispatcher queues events and posts them to the configured endpoints
+type Dispatcher struct {
+	urls       []string
+	secret     []byte
+	maxRetries int
+	baseDelay  time.Duration
+	client     *http.Client
+	logger     *golog.Logger
+
+	queue  chan Event
+	ctx    context.Context // cancelled when shutdown gives up on pending deliveries
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	closed bool
+	mu     sync.RWMutex
+}
+
+// NewDispatcher validates the configuration and starts the delivery worker.
+// Endpoints must use https, except internal hosts which may use http.
+func NewDispatcher(cfg Config, logger *golog.Logger) (*Dispatcher, error) {
+	// No else needed: early return pattern (guard clause)
+	if len(cfg.URLs) == 0 {
+		return nil, ErrNoURLs
+	}
+	for _, u := range cfg.URLs {
+		// No else needed: early return pattern (guard clause)
+		if err := llm.ValidateEndpoint(u); err != nil {
+			return nil, fmt.Errorf("invalid webhook URL %q: %w", u, err)
+		}
+	}
+
+	maxRetries := cfg.MaxRetries
+	// No else needed: optional operation (apply default)
+	if maxRetries <= 0 {
+		maxRetries = constants.DefaultWebhookMaxRetries
+	}
+	timeout := cfg.Timeout
+	// No else needed: optional operation (apply default)
+	if timeout <= 0 {
+		timeout = constants.DefaultWebhookTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		urls:       cfg.URLs,
+		secret:     []byte(cfg.Secret),
+		maxRetries: maxRetries,
+		baseDelay:  constants.WebhookInitialRetryDelay,
+		client:     &http.Client{Timeout: timeout},
+		logger:     logger,
+		queue:      make(chan Event, constants.WebhookQueueSize),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	d.wg.Add(1)
+	util.SafeGo(logger, "webhook", func() {
+		defer d.wg.Done()
+		d.run()
+	})
+	return d, nil
+}
+
+// Publish queues an event for delivery. It never blocks: when the queue is full
+// or the dispatcher has shut down, the event is dropped and logged.
+// ID and Timestamp are filled in when empty.
+func (d *Dispatcher) Publish(event Event) {
+	// No else needed: optional operation (fill defaults)
+	if event.ID == "" {
+		event.ID = NewEventID()
+	}
+	// No else needed: optional operation (fill defaults)
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if d.closed {
+		d.logger.Warn("Webhook dispatcher stopped, dropping event", "event", event.Type, "session_id", event.SessionID)
+		return
+	}
+
+	select {
+	case d.queue <- event:
+	default:
+		metrics.WebhookDeliveries.WithLabelValues(event.Type, "dropped").Inc()
+		d.logger.Warn("Webhook queue full, dropping event", "event", event.Type, "session_id", event.SessionID)
+	}
+}
+
+// Shutdown stops accepting events and waits for queued events to be delivered.
+// If ctx expires first, pending deliveries are abandoned and ctx.Err() is returned.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	// No else needed: optional operation (close once)
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		return ctx.Err()
+	}
+}
+
+// run delivers queued events until the queue is closed
+func (d *Dispatcher) run() {
+	for event := range d.queue {
+		body, err := util.MarshalJSON(event)
+		// No else needed: early return pattern (skip unencodable event)
+		if err != nil {
+			util.LogError(d.logger, "webhook", "marshal event", err, "event", event.Type)
+			continue
+		}
+		for _, u := range d.urls {
+			// No else needed: optional operation (failures are logged and counted)
+			if err := d.deliver(u, event, body); err != nil {
+				metrics.WebhookDeliveries.WithLabelValues(event.Type, "failed").Inc()
+				util.LogError(d.logger, "webhook", "deliver event", err,
+					"event", event.Type,
+					"event_id", event.ID,
+					"session_id", event.SessionID,
+					"url", u)
+				continue
+			}
+			metrics.WebhookDeliveries.WithLabelValues(event.Type, "delivered").Inc()
+		}
+	}
+}
+
+// deliver posts an event to one endpoint with retry logic and exponential backoff.
+// Client errors (4xx other than 408 and 429) are not retried.
+func (d *Dispatcher) deliver(url string, event Event, body []byte) error {
+	var lastErr error
+	for attempt := 0; attempt < d.maxRetries; attempt++ {
+		if attempt > 0 {
+			// Calculate exponential backoff delay
+			delay := d.baseDelay * time.Duration(1<<uint(attempt-1))
+			if delay > constants.WebhookMaxRetryDelay {
+				delay = constants.WebhookMaxRetryDelay
+			}
+
+			select {
+			case <-d.ctx.Done():
+				return fmt.Errorf("delivery abandoned: %w", lastErr)
+			case <-time.After(delay):
+			}
+		}
+
+		retryable, err := d.post(url, event, body)
+		// No else needed: early return pattern (success)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		// No else needed: early return pattern (permanent failure)
+		if !retryable {
+			return err
+		}
+	}
+	return fmt.Errorf("failed after %d attempts: %w", d.maxRetries, lastErr)
+}
+
+// post makes a single delivery attempt and reports whether a failure is retryable
+func (d *Dispatcher) post(url string, event Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, url, bytes.NewReader(body))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.HeaderWebhookEvent, event.Type)
+	req.Header.Set(constants.HeaderWebhookDelivery, event.ID)
+	req.Header.Set(constants.HeaderWebhookTimestamp, timestamp)
+	// No else needed: optional operation (signing only when a secret is configured)
+	if len(d.secret) > 0 {
+		req.Header.Set(constants.HeaderWebhookSignature, Sign(d.secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	// No else needed: early return pattern (network errors are retryable)
+	if err != nil {
+		return true, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drain so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, constants.MaxLLMErrorBodySize))
+
+	// No else needed: early return pattern (success)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+}
+
+// Sign returns the signature header value for a delivery: "sha256=" followed by
+// the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers should recompute it with
+// the shared secret and reject stale timestamps to prevent replays.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewEventID returns a random 16-byte hex event ID
+func NewEventID() string {
+	b := make([]byte, 16)
+	// No else needed: optional operation (fall back to a timestamp ID)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestLogger() *golog.Logger {
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            "/tmp/chatbox-webhook-test-logs",
+		Level:          "error",
+		StandardOutput: false,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize test logger: %v", err))
+	}
+	return logger
+}
+
+// newTestDispatcher creates a dispatcher with a short retry delay for tests
+func newTestDispatcher(t *testing.T, cfg Config) *Dispatcher {
+	t.Helper()
+	d, err := NewDispatcher(cfg, createTestLogger())
+	require.NoError(t, err)
+	d.baseDelay = time.Millisecond
+	return d
+}
+
+func TestDispatcher_DeliversSignedEvent(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan delivery, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{header: r.Header.Clone(), body: body}
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(t, Config{URLs: []string{server.URL}, Secret: "shared-secret"})
+	d.Publish(Event{Type: EventHelpRequested, SessionID: "session-1", UserID: "user-1"})
+	require.NoError(t, d.Shutdown(context.Background()))
+
+	got := <-received
+	var event Event
+	require.NoError(t, json.Unmarshal(got.body, &event))
+	assert.Equal(t, EventHelpRequested, event.Type)
+	assert.Equal(t, "session-1", event.SessionID)
+	assert.Equal(t, "user-1", event.UserID)
+	assert.NotEmpty(t, event.ID)
+	assert.False(t, event.Timestamp.IsZero())
+
+	assert.Equal(t, EventHelpRequested, got.header.Get(constants.HeaderWebhookEvent))
+	assert.Equal(t, event.ID, got.header.Get(constants.HeaderWebhookDelivery))
+	timestamp := got.header.Get(constants.HeaderWebhookTimestamp)
+	assert.Equal(t, Sign([]byte("shared-secret"), timestamp, got.body), got.header.Get(constants.HeaderWebhookSignature))
+}
+
+func TestDispatcher_UnsignedWithoutSecret(t *testing.T) {
+	var signature atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature.Store(r.Header.Get(constants.HeaderWebhookSignature))
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(t, Config{URLs: []string{server.URL}})
+	d.Publish(Event{Type: EventSessionEnded, SessionID: "session-1"})
+	require.NoError(t, d.Shutdown(context.Background()))
+
+	assert.Equal(t, "", signature.Load())
+}
+
+func TestDispatcher_RetriesServerErrors(t *testing.T) {
+	var attempts atomic.Int32
+	var mu sync.Mutex
+	var deliveryIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		deliveryIDs = append(deliveryIDs, r.Header.Get(constants.HeaderWebhookDelivery))
+		mu.Unlock()
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(t, Config{URLs: []string{server.URL}, MaxRetries: 3})
+	d.Publish(Event{Type: EventAdminTakeover, SessionID: "session-1"})
+	require.NoError(t, d.Shutdown(context.Background()))
+
+	assert.Equal(t, int32(3), attempts.Load())
+	// The delivery ID is stable across retries so receivers can deduplicate
+	require.Len(t, deliveryIDs, 3)
+	assert.Equal(t, deliveryIDs[0], deliveryIDs[2])
+}
+
+func TestDispatcher_DoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(t, Config{URLs: []string{server.URL}, MaxRetries: 3})
+	d.Publish(Event{Type: EventAdminTakeover, SessionID: "session-1"})
+	require.NoError(t, d.Shutdown(context.Background()))
+
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestDispatcher_PublishAfterShutdownIsDropped(t *testing.T) {
+	d := newTestDispatcher(t, Config{URLs: []string{"https://hooks.example.com/chat"}})
+	require.NoError(t, d.Shutdown(context.Background()))
+
+	assert.NotPanics(t, func() {
+		d.Publish(Event{Type: EventSessionEnded, SessionID: "session-1"})
+	})
+	assert.NoError(t, d.Shutdown(context.Background()), "shutdown should be idempotent")
+}
+
+func TestNewDispatcher_ValidatesURLs(t *testing.T) {
+	logger := createTestLogger()
+
+	_, err := NewDispatcher(Config{}, logger)
+	assert.ErrorIs(t, err, ErrNoURLs)
+
+	_, err = NewDispatcher(Config{URLs: []string{"http://hooks.example.com/chat"}}, logger)
+	assert.Error(t, err, "plain http to a public host should be rejected")
+
+	_, err = NewDispatcher(Config{URLs: []string{"ftp://10.0.0.1/hook"}}, logger)
+	assert.Error(t, err)
+}
@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// frameLog accumulates the frames a connection received since its last access
+// log line. Frames are logged in batches of constants.AccessLogFrameBatchSize,
+// or once the batch is constants.AccessLogFrameBatchInterval old, rather than
+// one line each.
+type frameLog struct {
+	mu        sync.Mutex
+	started   time.Time     // arrival of the batch's first frame
+	frames    int           // frames received
+	bytes     int           // payload bytes received
+	rejected  int           // frames refused before routing (invalid, overloaded, draining)
+	routed    int           // frames the router finished handling
+	failed    int           // routed frames the router returned an error for
+	routeTime time.Duration // total handling time of the routed frames
+	bytesSent uint64        // the connection's bytesSent at the previous line
+}
+
+// frameBatch is a logged batch of frames
+type frameBatch struct {
+	frames    int
+	bytes     int
+	rejected  int
+	routed    int
+	failed    int
+	routeTime time.Duration
+	bytesOut  uint64
+	duration  time.Duration
+}
+
+// add records a received frame of size bytes and reports whether the batch is
+// due to be logged
+func (f *frameLog) add(size int, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// No else needed: optional operation (first frame of a batch)
+	if f.frames == 0 {
+		f.started = now
+	}
+	f.frames++
+	f.bytes += size
+	return f.frames >= constants.AccessLogFrameBatchSize || now.Sub(f.started) >= constants.AccessLogFrameBatchInterval
+}
+
+// reject records a frame refused before routing
+func (f *frameLog) reject() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rejected++
+}
+
+// route records a frame the router finished handling in elapsed
+func (f *frameLog) route(elapsed time.Duration, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.routed++
+	f.routeTime += elapsed
+	// No else needed: optional operation (count failures)
+	if err != nil {
+		f.failed++
+	}
+}
+
+// take returns the batch so far and starts the next one. bytesSent is the
+// connection's outbound byte count; ok is false when nothing happened since
+// the previous batch.
+func (f *frameLog) take(bytesSent uint64, now time.Time) (batch frameBatch, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// No else needed: early return pattern (nothing to log)
+	if f.frames == 0 && f.routed == 0 && f.rejected == 0 {
+		return frameBatch{}, false
+	}
+	batch = frameBatch{
+		frames:    f.frames,
+		bytes:     f.bytes,
+		rejected:  f.rejected,
+		routed:    f.routed,
+		failed:    f.failed,
+		routeTime: f.routeTime,
+		bytesOut:  bytesSent - f.bytesSent,
+	}
+	// No else needed: optional operation (batches of late routing results only)
+	if f.frames > 0 {
+		batch.duration = now.Sub(f.started)
+	}
+	f.frames, f.bytes, f.rejected, f.routed, f.failed, f.routeTime = 0, 0, 0, 0, 0, 0
+	f.bytesSent = bytesSent
+	return batch, true
+}
+
+// logFrames writes the access log line of the connection's frames since the
+// previous line, if any
+func (h *Handler) logFrames(c *Connection) {
+	batch, ok := c.frames.take(c.bytesSent.Load(), time.Now())
+	// No else needed: early return pattern (nothing to log)
+	if !ok {
+		return
+	}
+	var avgLatencyMs float64
+	// No else needed: optional operation (only when frames were routed)
+	if batch.routed > 0 {
+		avgLatencyMs = float64(batch.routeTime) / float64(batch.routed) / float64(time.Millisecond)
+	}
+	transport := TransportWebSocket
+	// No else needed: conditional assignment (WebSocket by default)
+	if c.sse {
+		transport = TransportSSE
+	}
+	h.logger.Info("WebSocket access",
+		"request_id", c.requestID,
+		"transport", transport,
+		"user_id", c.UserID,
+		"session_id", c.GetSessionID(),
+		"connection_id", c.ConnectionID,
+		"frames", batch.frames,
+		"bytes_in", batch.bytes,
+		"bytes_out", batch.bytesOut,
+		"rejected", batch.rejected,
+		"routed", batch.routed,
+		"failed", batch.failed,
+		"avg_latency_ms", avgLatencyMs,
+		"duration_ms", batch.duration.Milliseconds(),
+		"component", "access")
+}
@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameLog_Batches(t *testing.T) {
+	var f frameLog
+	now := time.Now()
+
+	_, ok := f.take(0, now)
+	assert.False(t, ok, "nothing to log")
+
+	for i := 1; i < constants.AccessLogFrameBatchSize; i++ {
+		require.False(t, f.add(10, now), "frame %d", i)
+	}
+	assert.True(t, f.add(10, now), "the batch is full")
+
+	f.reject()
+	f.route(20*time.Millisecond, nil)
+	f.route(40*time.Millisecond, errors.New("boom"))
+	batch, ok := f.take(500, now.Add(time.Second))
+	require.True(t, ok)
+	assert.Equal(t, constants.AccessLogFrameBatchSize, batch.frames)
+	assert.Equal(t, constants.AccessLogFrameBatchSize*10, batch.bytes)
+	assert.Equal(t, 1, batch.rejected)
+	assert.Equal(t, 2, batch.routed)
+	assert.Equal(t, 1, batch.failed)
+	assert.Equal(t, 60*time.Millisecond, batch.routeTime)
+	assert.Equal(t, uint64(500), batch.bytesOut)
+	assert.Equal(t, time.Second, batch.duration)
+
+	// The next batch counts outbound bytes from the previous line and is
+	// logged once it is old enough
+	assert.False(t, f.add(5, now))
+	assert.True(t, f.add(5, now.Add(constants.AccessLogFrameBatchInterval)))
+	batch, ok = f.take(800, now.Add(constants.AccessLogFrameBatchInterval))
+	require.True(t, ok)
+	assert.Equal(t, 2, batch.frames)
+	assert.Equal(t, uint64(300), batch.bytesOut)
+}
+
+func TestConnection_RequestIDInInfo(t *testing.T) {
+	conn := NewConnection("user-1", []string{"user"})
+	conn.requestID = "req-1"
+	assert.Equal(t, "req-1", conn.RequestID())
+	assert.Equal(t, "req-1", conn.Info().RequestID)
+}
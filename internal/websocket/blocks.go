@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"net/http"
+
+	"github.com/real-rm/chatbox/internal/apierror"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// UserBlockChecker looks up whether an admin blocked a user from connecting
+type UserBlockChecker interface {
+	UserBlocked(tenantID, userID string) (bool, error)
+}
+
+// SetUserBlocks refuses WebSocket and SSE connections of users blocked by an
+// admin. A nil checker (the default) admits every user.
+func (h *Handler) SetUserBlocks(checker UserBlockChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.userBlocks = checker
+}
+
+// allowUser refuses a blocked user's connection with 403 USER_BLOCKED. Users
+// are admitted when the lookup fails, so an outage of the block store does not
+// lock every user out.
+func (h *Handler) allowUser(w http.ResponseWriter, claims *auth.Claims) bool {
+	h.mu.RLock()
+	checker := h.userBlocks
+	h.mu.RUnlock()
+
+	// No else needed: early return pattern (blocks not enforced)
+	if checker == nil {
+		return true
+	}
+
+	blocked, err := checker.UserBlocked(claims.TenantID, claims.UserID)
+	// No else needed: early return pattern (fail open)
+	if err != nil {
+		util.LogError(h.logger, "websocket", "check user block", err, "user_id", claims.UserID)
+		return true
+	}
+	// No else needed: early return pattern (not blocked)
+	if !blocked {
+		return true
+	}
+
+	h.logger.Warn("Connection of blocked user refused",
+		"user_id", claims.UserID,
+		"tenant_id", claims.TenantID,
+		"component", "websocket")
+	apierror.Write(w, apierror.CodeUserBlocked, constants.ErrMsgUserBlocked)
+	return false
+}
@@ -0,0 +1,69 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBlocks blocks the listed user IDs, or fails every lookup with err
+type fakeBlocks struct {
+	blocked map[string]bool
+	err     error
+}
+
+func (f *fakeBlocks) UserBlocked(tenantID, userID string) (bool, error) {
+	return f.blocked[tenantID+"/"+userID], f.err
+}
+
+func TestHandleWebSocket_BlockedUser(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	handler := NewHandler(auth.NewJWTValidator(secret), nil, testLogger(), 1048576)
+	handler.SetUserBlocks(&fakeBlocks{blocked: map[string]bool{"/user-blocked": true}})
+
+	// Requests come with a canceled context so an admitted SSE stream ends at once
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dial := func(handle http.HandlerFunc, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil).WithContext(ctx)
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, secret, userID, []string{"user"}))
+		w := httptest.NewRecorder()
+		handle(w, req)
+		return w
+	}
+
+	for name, handle := range map[string]http.HandlerFunc{"ws": handler.HandleWebSocket, "sse": handler.HandleSSE} {
+		t.Run(name, func(t *testing.T) {
+			w := dial(handle, "user-blocked")
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), "USER_BLOCKED")
+
+			// The upgrade of a plain request fails, but not because of a block
+			w = dial(handle, "user-ok")
+			assert.NotEqual(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+func TestAllowUser(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret"), nil, testLogger(), 1048576)
+	claims := &auth.Claims{UserID: "user-1", TenantID: "acme"}
+
+	// No checker: everyone is admitted
+	assert.True(t, handler.allowUser(httptest.NewRecorder(), claims))
+
+	// Blocks are per tenant
+	handler.SetUserBlocks(&fakeBlocks{blocked: map[string]bool{"/user-1": true}})
+	assert.True(t, handler.allowUser(httptest.NewRecorder(), claims))
+	handler.SetUserBlocks(&fakeBlocks{blocked: map[string]bool{"acme/user-1": true}})
+	assert.False(t, handler.allowUser(httptest.NewRecorder(), claims))
+
+	// A failed lookup admits the user
+	handler.SetUserBlocks(&fakeBlocks{err: errors.New("mongo down")})
+	assert.True(t, handler.allowUser(httptest.NewRecorder(), claims))
+}
@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Protocol capabilities a client can request with the capabilities query
+// parameter at connect, e.g. ?capabilities=delta
+const (
+	// CapabilityDelta streams AI replies as compact ai_delta frames holding the
+	// sequence number and the new text, ending with a consolidated ai_response
+	// carrying the whole reply (see message.Delta)
+	CapabilityDelta = "delta"
+)
+
+// supportedCapabilities are the capabilities the server accepts
+var supportedCapabilities = map[string]bool{
+	CapabilityDelta: true,
+}
+
+// negotiateCapabilities returns the supported capabilities of the comma-separated
+// capabilities query parameter of the connect request r, in request order.
+// Unknown capabilities are ignored, so clients can ask newer servers for more.
+func negotiateCapabilities(r *http.Request) []string {
+	var accepted []string
+	for _, name := range strings.Split(r.URL.Query().Get("capabilities"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		// No else needed: optional operation (only supported capabilities, once)
+		if supportedCapabilities[name] && !slices.Contains(accepted, name) {
+			accepted = append(accepted, name)
+		}
+	}
+	return accepted
+}
+
+// Capabilities returns the protocol capabilities negotiated at connect.
+// Immutable after creation, so no mutex is needed.
+func (c *Connection) Capabilities() []string {
+	return c.capabilities
+}
+
+// Supports reports whether the connection negotiated capability
+func (c *Connection) Supports(capability string) bool {
+	return slices.Contains(c.capabilities, capability)
+}
+
+// SetCapabilitiesForTest sets the negotiated capabilities for testing purposes
+// This should only be used in tests
+func (c *Connection) SetCapabilitiesForTest(capabilities ...string) {
+	c.capabilities = capabilities
+}
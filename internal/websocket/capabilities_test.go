@@ -0,0 +1,47 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateCapabilities(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"?capabilities=delta", []string{CapabilityDelta}},
+		{"?capabilities=%20Delta%20,delta", []string{CapabilityDelta}},
+		{"?capabilities=future,delta", []string{CapabilityDelta}},
+		{"?capabilities=future", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, negotiateCapabilities(httptest.NewRequest("GET", "/ws"+tt.query, nil)))
+		})
+	}
+
+	conn := NewConnection("user-1", []string{"user"})
+	assert.False(t, conn.Supports(CapabilityDelta))
+	conn.SetCapabilitiesForTest(CapabilityDelta)
+	assert.True(t, conn.Supports(CapabilityDelta))
+}
+
+func TestCapabilities_SentInConnectionStatus(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret"), nil, testLogger(), 1048576)
+
+	conn := NewConnection("user-1", []string{"user"})
+	conn.SetCapabilitiesForTest(CapabilityDelta)
+	handler.sendInitialStatus(conn)
+	var status message.Message
+	require.NoError(t, json.Unmarshal(<-conn.send, &status))
+	assert.Equal(t, message.TypeConnectionStatus, status.Type)
+	assert.Equal(t, []string{CapabilityDelta}, status.Capabilities)
+}
@@ -0,0 +1,216 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+)
+
+// chunkedUpload is a user message being assembled from chunks: message_begin
+// names the session and metadata, each message_append adds content and
+// message_commit sends the assembled message as a user_message
+type chunkedUpload struct {
+	sessionID string
+	metadata  map[string]string
+	content   strings.Builder
+	expiresAt time.Time
+}
+
+// SetChunkedMessageLimit sets the maximum assembled size in bytes of a message
+// sent in chunks (message_begin, message_append, message_commit), so messages
+// larger than one WebSocket frame can be sent. Each frame is still limited by
+// the max message size. A limit of 0 disables chunked messages.
+// Defaults to constants.DefaultMaxChunkedMessageSize.
+func (h *Handler) SetChunkedMessageLimit(limit int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxChunkedSize = limit
+}
+
+// chunkedMessageLimit returns the maximum assembled size of a chunked message
+func (h *Handler) chunkedMessageLimit() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.maxChunkedSize
+}
+
+// isChunkFrame reports whether t is a frame of a chunked message
+func isChunkFrame(t message.MessageType) bool {
+	return t == message.TypeMessageBegin || t == message.TypeMessageAppend || t == message.TypeMessageCommit
+}
+
+// handleChunk applies a frame of a chunked message. On commit, the assembled
+// message is sanitized, validated against the chunked message limit and
+// dispatched like a user_message sent in one frame.
+func (h *Handler) handleChunk(c *Connection, msg *message.Message, routeSem chan struct{}) {
+	limit := h.chunkedMessageLimit()
+	// No else needed: early return pattern (chunked messages disabled)
+	if limit <= 0 {
+		h.rejectChunk(c, msg, "Chunked messages are disabled")
+		return
+	}
+	// No else needed: early return pattern (guard clause)
+	if msg.UploadID == "" || len(msg.UploadID) > constants.MaxUploadIDLength {
+		h.rejectChunk(c, msg, fmt.Sprintf("upload_id is required and at most %d bytes", constants.MaxUploadIDLength))
+		return
+	}
+
+	switch msg.Type {
+	case message.TypeMessageBegin:
+		// No else needed: early return pattern (guard clause)
+		if msg.SessionID == "" {
+			h.rejectChunk(c, msg, "session_id is required for message_begin")
+			return
+		}
+		// No else needed: early return pattern (guard clause)
+		if err := c.beginUpload(msg, int(limit)); err != nil {
+			h.rejectChunk(c, msg, err.Error())
+			return
+		}
+
+	case message.TypeMessageAppend:
+		// No else needed: early return pattern (guard clause)
+		if err := c.appendUpload(msg.UploadID, msg.Content, int(limit)); err != nil {
+			h.rejectChunk(c, msg, err.Error())
+			return
+		}
+
+	case message.TypeMessageCommit:
+		upload, err := c.takeUpload(msg.UploadID)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			h.rejectChunk(c, msg, err.Error())
+			return
+		}
+
+		assembled := message.Message{
+			Type:      message.TypeUserMessage,
+			SessionID: upload.sessionID,
+			Content:   upload.content.String(),
+			Metadata:  upload.metadata,
+			Sender:    message.SenderUser,
+			Timestamp: time.Now(),
+		}
+		assembled.Sanitize()
+		// No else needed: early return pattern (guard clause)
+		if err := assembled.ValidateWithContentLimit(int(limit)); err != nil {
+			h.rejectInvalid(c, "Chunked message validation failed", err)
+			return
+		}
+
+		h.logger.Debug("Chunked message assembled",
+			"user_id", c.UserID,
+			"connection_id", c.ConnectionID,
+			"upload_id", msg.UploadID,
+			"content_length", len(assembled.Content),
+			"component", "websocket")
+		h.dispatchMessage(c, assembled, routeSem)
+	}
+}
+
+// rejectChunk logs a rejected chunked message frame and tells the client
+func (h *Handler) rejectChunk(c *Connection, msg *message.Message, reason string) {
+	h.logger.Warn("Chunked message frame rejected",
+		"user_id", c.UserID,
+		"connection_id", c.ConnectionID,
+		"message_type", msg.Type,
+		"reason", reason,
+		"component", "websocket")
+	metrics.MessageErrors.Inc()
+	c.sendErrorResponse(chaterrors.ErrCodeInvalidFormat, reason)
+}
+
+// beginUpload starts assembling the chunked message of a message_begin, whose
+// content (if any) is the first chunk. Expired uploads are dropped first.
+func (c *Connection) beginUpload(msg *message.Message, limit int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for id, upload := range c.uploads {
+		// No else needed: optional operation (only expired uploads)
+		if now.After(upload.expiresAt) {
+			delete(c.uploads, id)
+		}
+	}
+	// No else needed: early return pattern (guard clause)
+	if _, exists := c.uploads[msg.UploadID]; exists {
+		return fmt.Errorf("upload %s already started", msg.UploadID)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(c.uploads) >= constants.MaxPendingChunkedMessages {
+		return fmt.Errorf("too many chunked messages in progress (max %d)", constants.MaxPendingChunkedMessages)
+	}
+	// No else needed: early return pattern (guard clause)
+	if len(msg.Content) > limit {
+		return fmt.Errorf("message exceeds the %d byte limit", limit)
+	}
+
+	// No else needed: conditional initialization (first upload of the connection)
+	if c.uploads == nil {
+		c.uploads = make(map[string]*chunkedUpload)
+	}
+	upload := &chunkedUpload{
+		sessionID: msg.SessionID,
+		metadata:  msg.Metadata,
+		expiresAt: now.Add(constants.ChunkedMessageTimeout),
+	}
+	upload.content.WriteString(msg.Content)
+	c.uploads[msg.UploadID] = upload
+	return nil
+}
+
+// appendUpload adds a chunk to an upload. An upload growing past limit is
+// dropped, so the client has to begin again.
+func (c *Connection) appendUpload(uploadID, chunk string, limit int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	upload, err := c.liveUploadLocked(uploadID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return err
+	}
+	// No else needed: early return pattern (aggregate limit exceeded)
+	if upload.content.Len()+len(chunk) > limit {
+		delete(c.uploads, uploadID)
+		return fmt.Errorf("message exceeds the %d byte limit", limit)
+	}
+	upload.content.WriteString(chunk)
+	return nil
+}
+
+// takeUpload removes an upload for commit
+func (c *Connection) takeUpload(uploadID string) (*chunkedUpload, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	upload, err := c.liveUploadLocked(uploadID)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	delete(c.uploads, uploadID)
+	return upload, nil
+}
+
+// liveUploadLocked returns an upload that has not expired. Expired uploads are
+// dropped. The caller must hold c.mu.
+func (c *Connection) liveUploadLocked(uploadID string) (*chunkedUpload, error) {
+	upload, ok := c.uploads[uploadID]
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil, fmt.Errorf("unknown upload %s", uploadID)
+	}
+	// No else needed: early return pattern (commit deadline passed)
+	if time.Now().After(upload.expiresAt) {
+		delete(c.uploads, uploadID)
+		return nil, fmt.Errorf("upload %s expired", uploadID)
+	}
+	return upload, nil
+}
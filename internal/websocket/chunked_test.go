@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendFrame passes a client frame to the handler like readPump does
+func sendFrame(t *testing.T, handler *Handler, conn *Connection, routeSem chan struct{}, frame map[string]interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(frame)
+	require.NoError(t, err)
+	handler.handleIncoming(conn, raw, routeSem)
+}
+
+// drainErrors returns the error messages sent to conn
+func drainErrors(conn *Connection) []string {
+	var errs []string
+	for {
+		select {
+		case raw := <-conn.send:
+			var msg message.Message
+			// No else needed: optional operation (only error messages)
+			if json.Unmarshal(raw, &msg) == nil && msg.Type == message.TypeError {
+				errs = append(errs, msg.Error.Message)
+			}
+		default:
+			return errs
+		}
+	}
+}
+
+func TestChunkedMessage_AssembledAndRoutedAsUserMessage(t *testing.T) {
+	mockRouter := newMockRouter()
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), mockRouter, testLogger(), 1048576)
+	conn := NewConnection("user-1", []string{"user"})
+	routeSem := make(chan struct{}, 1)
+
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{
+		"type": "message_begin", "upload_id": "u1", "session_id": "session-1",
+		"content": "line 1\n", "metadata": map[string]string{"source": "paste"},
+	})
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_append", "upload_id": "u1", "content": "line 2\n"})
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_append", "upload_id": "u1", "content": strings.Repeat("x", 12000)})
+	assert.Empty(t, mockRouter.RoutedMessages(), "nothing is routed before commit")
+
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_commit", "upload_id": "u1"})
+	require.Eventually(t, func() bool { return len(mockRouter.RoutedMessages()) == 1 }, time.Second, 10*time.Millisecond)
+
+	routed := mockRouter.RoutedMessages()[0]
+	assert.Equal(t, message.TypeUserMessage, routed.Type)
+	assert.Equal(t, "session-1", routed.SessionID)
+	assert.Equal(t, "line 1\nline 2\n"+strings.Repeat("x", 12000), routed.Content, "chunk boundaries keep their whitespace")
+	assert.Equal(t, "paste", routed.Metadata["source"])
+	assert.Equal(t, "session-1", conn.GetSessionID())
+	assert.Empty(t, drainErrors(conn))
+}
+
+func TestChunkedMessage_AggregateLimitDropsUpload(t *testing.T) {
+	mockRouter := newMockRouter()
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), mockRouter, testLogger(), 1048576)
+	handler.SetChunkedMessageLimit(10)
+	conn := NewConnection("user-1", []string{"user"})
+	routeSem := make(chan struct{}, 1)
+
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_begin", "upload_id": "u1", "session_id": "session-1", "content": "12345"})
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_append", "upload_id": "u1", "content": "678901"})
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_commit", "upload_id": "u1"})
+
+	errs := drainErrors(conn)
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0], "10 byte limit")
+	assert.Contains(t, errs[1], "unknown upload", "the oversized upload was dropped")
+	assert.Empty(t, mockRouter.RoutedMessages())
+	assert.False(t, conn.closing.Load(), "the connection stays open")
+}
+
+func TestChunkedMessage_Rejections(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+	conn := NewConnection("user-1", []string{"user"})
+	routeSem := make(chan struct{}, 1)
+
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_begin", "session_id": "session-1"})
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_begin", "upload_id": "u1"})
+	for _, id := range []string{"u1", "u2", "u3"} {
+		sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_begin", "upload_id": id, "session_id": "session-1"})
+	}
+
+	errs := drainErrors(conn)
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0], "upload_id is required")
+	assert.Contains(t, errs[1], "session_id is required")
+	assert.Contains(t, errs[2], "too many chunked messages")
+
+	handler.SetChunkedMessageLimit(0)
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "message_append", "upload_id": "u1", "content": "x"})
+	assert.Equal(t, []string{"Chunked messages are disabled"}, drainErrors(conn))
+}
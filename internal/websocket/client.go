@@ -0,0 +1,59 @@
+package websocket
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/real-rm/chatbox/internal/constants"
+)
+
+// ClientMetadata describes the client SDK that opened a connection, as sent at
+// connect: the app_version, platform and screen_size query parameters and the
+// User-Agent header. Every field is optional and untrusted.
+type ClientMetadata struct {
+	AppVersion string `json:"app_version,omitempty"`
+	Platform   string `json:"platform,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	ScreenSize string `json:"screen_size,omitempty"`
+}
+
+// IsZero reports whether the client sent no metadata
+func (m ClientMetadata) IsZero() bool {
+	return m == ClientMetadata{}
+}
+
+// clientMetadata reads the client metadata of the connect request r
+func clientMetadata(r *http.Request) ClientMetadata {
+	query := r.URL.Query()
+	return ClientMetadata{
+		AppVersion: cleanClientValue(query.Get("app_version")),
+		Platform:   cleanClientValue(query.Get("platform")),
+		UserAgent:  cleanClientValue(r.UserAgent()),
+		ScreenSize: cleanClientValue(query.Get("screen_size")),
+	}
+}
+
+// cleanClientValue drops control characters from a client-supplied value and
+// truncates it to constants.MaxClientMetadataLength characters, so it is safe
+// to store and log
+func cleanClientValue(value string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		// No else needed: early return pattern (drop control characters)
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value))
+	// No else needed: optional operation (truncate long values)
+	if runes := []rune(cleaned); len(runes) > constants.MaxClientMetadataLength {
+		cleaned = string(runes[:constants.MaxClientMetadataLength])
+	}
+	return cleaned
+}
+
+// ClientMetadata returns the metadata the client sent at connect.
+// Immutable after creation, so no mutex is needed.
+func (c *Connection) ClientMetadata() ClientMetadata {
+	return c.client
+}
@@ -0,0 +1,31 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientMetadata(t *testing.T) {
+	r := httptest.NewRequest("GET", "/ws?app_version=2.4.1&platform=ios&screen_size=390x844", nil)
+	r.Header.Set("User-Agent", "ChatSDK/2.4.1 (iPhone)")
+	assert.Equal(t, ClientMetadata{
+		AppVersion: "2.4.1",
+		Platform:   "ios",
+		UserAgent:  "ChatSDK/2.4.1 (iPhone)",
+		ScreenSize: "390x844",
+	}, clientMetadata(r))
+
+	r = httptest.NewRequest("GET", "/ws?platform=web%0A%1Bevil&app_version="+strings.Repeat("9", 1000), nil)
+	r.Header.Del("User-Agent")
+	m := clientMetadata(r)
+	assert.Equal(t, "webevil", m.Platform, "control characters are dropped")
+	assert.Len(t, m.AppVersion, constants.MaxClientMetadataLength)
+	assert.Empty(t, m.UserAgent)
+
+	assert.True(t, ClientMetadata{}.IsZero())
+	assert.False(t, m.IsZero())
+}
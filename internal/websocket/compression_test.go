@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffersDeflate(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   bool
+	}{
+		{"no header", nil, false},
+		{"plain", []string{"permessage-deflate"}, true},
+		{"with parameters", []string{"permessage-deflate; client_max_window_bits"}, true},
+		{"among others", []string{"x-webkit-deflate-frame, Permessage-Deflate"}, true},
+		{"separate headers", []string{"foo", "permessage-deflate"}, true},
+		{"other extension", []string{"x-webkit-deflate-frame"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			for _, h := range tt.header {
+				r.Header.Add("Sec-WebSocket-Extensions", h)
+			}
+			assert.Equal(t, tt.want, offersDeflate(r))
+		})
+	}
+}
+
+func TestUpgrade_NegotiatesCompressionWhenEnabled(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+		handler.SetCompression(enabled)
+
+		written := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer close(written)
+			conn, payload, err := handler.upgrade(w, r)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			msg := []byte(strings.Repeat("compressible ", 100))
+			payload.Add(float64(len(msg)))
+			_ = conn.WriteMessage(websocket.TextMessage, msg)
+		}))
+
+		label := "false"
+		if enabled {
+			label = "true"
+		}
+		wireBefore := testutil.ToFloat64(metrics.WebSocketWireBytes.WithLabelValues(label))
+		payloadBefore := testutil.ToFloat64(metrics.WebSocketPayloadBytes.WithLabelValues(label))
+
+		dialer := websocket.Dialer{EnableCompression: true}
+		conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		require.NoError(t, err)
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		<-written
+		conn.Close()
+		server.Close()
+
+		assert.Len(t, data, 1300)
+		negotiated := strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+		assert.Equal(t, enabled, negotiated)
+
+		wire := testutil.ToFloat64(metrics.WebSocketWireBytes.WithLabelValues(label)) - wireBefore
+		payload := testutil.ToFloat64(metrics.WebSocketPayloadBytes.WithLabelValues(label)) - payloadBefore
+		assert.Equal(t, float64(1300), payload)
+		if enabled {
+			assert.Less(t, wire, payload, "compressed frames are smaller than the payload")
+		} else {
+			assert.Greater(t, wire, payload, "uncompressed frames add the handshake and framing")
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package websocket
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transports reported in ConnectionInfo
+const (
+	TransportWebSocket = "websocket"
+	TransportSSE       = "sse"
+)
+
+// ConnectionInfo describes an active connection for admin diagnostics
+type ConnectionInfo struct {
+	ConnectionID  string          `json:"connection_id"`
+	UserID        string          `json:"user_id"`
+	SessionID     string          `json:"session_id,omitempty"`
+	TenantID      string          `json:"tenant_id,omitempty"`
+	Impersonator  string          `json:"impersonator_id,omitempty"` // admin using an impersonation token
+	Transport     string          `json:"transport"`                 // "websocket" or "sse"
+	ClientIP      string          `json:"client_ip,omitempty"`
+	RequestID     string          `json:"request_id,omitempty"`   // ID of the request that opened the connection
+	Client        *ClientMetadata `json:"client,omitempty"`       // SDK metadata sent at connect
+	Capabilities  []string        `json:"capabilities,omitempty"` // protocol capabilities negotiated at connect
+	ConnectedAt   time.Time       `json:"connected_at"`
+	LastPongAt    *time.Time      `json:"last_pong_at,omitempty"`     // nil until the first heartbeat pong (never for SSE)
+	LastPingRTTMs *float64        `json:"last_ping_rtt_ms,omitempty"` // round trip of the last heartbeat ping
+	BytesSent     uint64          `json:"bytes_sent"`                 // outbound message payload bytes
+	BytesReceived uint64          `json:"bytes_received"`             // inbound message payload bytes
+}
+
+// clientIPKey is the context key for the client IP set with WithClientIP
+type clientIPKey struct{}
+
+// WithClientIP returns a context recording ip as the client address of the
+// request, for servers that resolve it from trusted proxy headers. Without it
+// the connection reports the request's remote address.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// clientIP returns the client address of r set with WithClientIP, or the host
+// of its remote address
+func clientIP(r *http.Request) string {
+	// No else needed: early return pattern (resolved by the server)
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	// No else needed: early return pattern (remote address without a port)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// recordPing notes when a heartbeat ping was written
+func (c *Connection) recordPing(now time.Time) {
+	c.pingSentAt.Store(now.UnixNano())
+}
+
+// recordPong notes a heartbeat pong and the round trip since the last ping
+func (c *Connection) recordPong(now time.Time) {
+	c.lastPongAt.Store(now.UnixNano())
+	// No else needed: optional operation (pongs can arrive unsolicited)
+	if sent := c.pingSentAt.Load(); sent != 0 {
+		c.lastPingRTT.Store(now.UnixNano() - sent)
+	}
+}
+
+// Info returns the connection's diagnostics
+func (c *Connection) Info() ConnectionInfo {
+	info := ConnectionInfo{
+		ConnectionID:  c.ConnectionID,
+		UserID:        c.UserID,
+		SessionID:     c.GetSessionID(),
+		TenantID:      c.TenantID,
+		Impersonator:  c.ImpersonatorID,
+		Transport:     TransportWebSocket,
+		ClientIP:      c.clientIP,
+		RequestID:     c.requestID,
+		Capabilities:  c.capabilities,
+		ConnectedAt:   c.connectedAt,
+		BytesSent:     c.bytesSent.Load(),
+		BytesReceived: c.bytesReceived.Load(),
+	}
+	// No else needed: optional operation (only when the client sent metadata)
+	if !c.client.IsZero() {
+		client := c.client
+		info.Client = &client
+	}
+	// No else needed: conditional assignment (WebSocket by default)
+	if c.sse {
+		info.Transport = TransportSSE
+	}
+	// No else needed: optional operation (only after a heartbeat pong)
+	if pong := c.lastPongAt.Load(); pong != 0 {
+		at := time.Unix(0, pong)
+		rtt := float64(c.lastPingRTT.Load()) / float64(time.Millisecond)
+		info.LastPongAt = &at
+		info.LastPingRTTMs = &rtt
+	}
+	return info
+}
+
+// ListConnections returns the diagnostics of every active WebSocket and SSE
+// connection of this replica, oldest first
+func (h *Handler) ListConnections() []ConnectionInfo {
+	h.mu.RLock()
+	conns := make([]*Connection, 0, len(h.connections))
+	for _, userConns := range h.connections {
+		for _, conn := range userConns {
+			conns = append(conns, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	infos := make([]ConnectionInfo, 0, len(conns))
+	for _, conn := range conns {
+		infos = append(infos, conn.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		// No else needed: early return pattern (tie broken by connection ID)
+		if !infos[i].ConnectedAt.Equal(infos[j].ConnectedAt) {
+			return infos[i].ConnectedAt.Before(infos[j].ConnectedAt)
+		}
+		return infos[i].ConnectionID < infos[j].ConnectionID
+	})
+	return infos
+}
+
+// CloseConnection force-closes an active connection of this replica and
+// returns its diagnostics. WebSocket clients receive a close frame and SSE
+// streams end; the connection leaves its session as on a client disconnect.
+// Returns false if no connection has the ID.
+func (h *Handler) CloseConnection(connectionID string) (ConnectionInfo, bool) {
+	var target *Connection
+	h.mu.RLock()
+	for _, userConns := range h.connections {
+		// No else needed: optional operation (stop at the matching connection)
+		if conn, ok := userConns[connectionID]; ok {
+			target = conn
+			break
+		}
+	}
+	h.mu.RUnlock()
+
+	// No else needed: early return pattern (guard clause)
+	if target == nil {
+		return ConnectionInfo{}, false
+	}
+
+	info := target.Info()
+	// Closing the send channel makes the write pump send a close frame (or the
+	// SSE writer end the stream); the read side then cleans up the session binding
+	h.unregisterConnection(target)
+	h.logger.Info("Connection closed by admin",
+		"user_id", target.UserID,
+		"connection_id", connectionID,
+		"component", "websocket")
+	return info, true
+}
+
+// CloseSessionEnded is the WebSocket close code of connections whose session
+// was ended by an admin; the close reason carries the reason code
+const CloseSessionEnded = 4000
+
+// CloseSessionConnections closes the connections of this replica bound to
+// sessionID. WebSocket clients receive a close frame with code and reason
+// after any queued messages, and SSE streams end. Returns the number of
+// connections closed.
+func (h *Handler) CloseSessionConnections(sessionID string, code int, reason string) int {
+	var targets []*Connection
+	h.mu.RLock()
+	for _, userConns := range h.connections {
+		for _, conn := range userConns {
+			// No else needed: optional operation (only connections bound to the session)
+			if conn.GetSessionID() == sessionID {
+				targets = append(targets, conn)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	frame := websocket.FormatCloseMessage(code, reason)
+	for _, conn := range targets {
+		// The frame is set before unregistering, whose own close is then a no-op
+		conn.closeSendWith(frame)
+		h.unregisterConnection(conn)
+		h.logger.Info("Connection closed with its session",
+			"user_id", conn.UserID,
+			"connection_id", conn.ConnectionID,
+			"session_id", sessionID,
+			"close_code", code,
+			"reason", reason,
+			"component", "websocket")
+	}
+	return len(targets)
+}
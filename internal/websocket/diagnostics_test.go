@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDiagnosticsConnection(id, userID string, connectedAt time.Time) *Connection {
+	conn := NewConnection(userID, []string{"user"})
+	conn.ConnectionID = id
+	conn.connectedAt = connectedAt
+	conn.clientIP = "203.0.113.7"
+	return conn
+}
+
+func TestListConnections(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+	now := time.Now()
+
+	newer := newDiagnosticsConnection("conn-2", "user-2", now)
+	newer.sse = true
+	older := newDiagnosticsConnection("conn-1", "user-1", now.Add(-time.Minute))
+	older.SetSessionID("session-1")
+	handler.RegisterConnectionForTest(newer)
+	handler.RegisterConnectionForTest(older)
+
+	handler.handleIncoming(older, []byte("not json"), make(chan struct{}, 1))
+	older.recordPing(now)
+	older.recordPong(now.Add(40 * time.Millisecond))
+
+	conns := handler.ListConnections()
+	require.Len(t, conns, 2)
+
+	first := conns[0]
+	assert.Equal(t, "conn-1", first.ConnectionID, "oldest first")
+	assert.Equal(t, "user-1", first.UserID)
+	assert.Equal(t, "session-1", first.SessionID)
+	assert.Equal(t, TransportWebSocket, first.Transport)
+	assert.Equal(t, "203.0.113.7", first.ClientIP)
+	assert.Equal(t, uint64(len("not json")), first.BytesReceived)
+	require.NotNil(t, first.LastPingRTTMs)
+	assert.InDelta(t, 40.0, *first.LastPingRTTMs, 0.001)
+	require.NotNil(t, first.LastPongAt)
+
+	second := conns[1]
+	assert.Equal(t, TransportSSE, second.Transport)
+	assert.Nil(t, second.LastPingRTTMs, "no heartbeat pong yet")
+}
+
+func TestCloseConnection(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+	conn := newDiagnosticsConnection("conn-1", "user-1", time.Now())
+	handler.RegisterConnectionForTest(conn)
+
+	_, ok := handler.CloseConnection("conn-unknown")
+	assert.False(t, ok)
+
+	info, ok := handler.CloseConnection("conn-1")
+	require.True(t, ok)
+	assert.Equal(t, "user-1", info.UserID)
+	assert.Empty(t, handler.ListConnections())
+
+	_, open := <-conn.ReceiveForTest()
+	assert.False(t, open, "the send channel is closed so the write pump sends a close frame")
+	assert.False(t, conn.SafeSend([]byte("late")))
+}
+
+func TestCloseSessionConnections(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+	bound := newDiagnosticsConnection("conn-1", "user-1", time.Now())
+	bound.SetSessionID("session-1")
+	other := newDiagnosticsConnection("conn-2", "user-2", time.Now())
+	other.SetSessionID("session-2")
+	handler.RegisterConnectionForTest(bound)
+	handler.RegisterConnectionForTest(other)
+
+	require.True(t, bound.SafeSend([]byte("notice")))
+	assert.Equal(t, 0, handler.CloseSessionConnections("session-3", CloseSessionEnded, "abuse"))
+	assert.Equal(t, 1, handler.CloseSessionConnections("session-1", CloseSessionEnded, "abuse"))
+
+	conns := handler.ListConnections()
+	require.Len(t, conns, 1)
+	assert.Equal(t, "conn-2", conns[0].ConnectionID)
+
+	// Queued messages are delivered before the close frame
+	data, open := <-bound.ReceiveForTest()
+	require.True(t, open)
+	assert.Equal(t, "notice", string(data))
+	_, open = <-bound.ReceiveForTest()
+	assert.False(t, open)
+	assert.Equal(t, websocket.FormatCloseMessage(CloseSessionEnded, "abuse"), bound.closeFrame)
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.RemoteAddr = "198.51.100.4:51234"
+	assert.Equal(t, "198.51.100.4", clientIP(req))
+
+	req = req.WithContext(WithClientIP(req.Context(), "203.0.113.9"))
+	assert.Equal(t, "203.0.113.9", clientIP(req), "the server-resolved IP wins")
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// StartDrain puts the handler into drain mode ahead of a shutdown. New WebSocket,
+// SSE and watch connections are refused, new messages from connected clients are
+// rejected, and every connected client receives a server_draining event telling it
+// to reconnect elsewhere within countdown, with the reconnect policy when one is
+// set. Messages already being routed, including active LLM streams, keep
+// running; use WaitForInflight to wait for them.
+// Calling StartDrain again re-notifies clients with the new countdown.
+// Returns the number of connections notified.
+func (h *Handler) StartDrain(countdown time.Duration) int {
+	h.drainMu.Lock()
+	alreadyDraining := h.draining
+	h.draining = true
+	h.drainMu.Unlock()
+
+	deadline := time.Now().Add(countdown)
+	seconds := int(countdown.Seconds())
+	drainMsg := &message.Message{
+		Type:      message.TypeServerDraining,
+		Content:   fmt.Sprintf("Server is shutting down in %d seconds. Please reconnect.", seconds),
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"countdown_seconds": strconv.Itoa(seconds),
+			"deadline":          deadline.UTC().Format(time.RFC3339),
+		},
+		Reconnect: h.reconnectPolicy(),
+	}
+	data, err := json.Marshal(drainMsg)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(h.logger, "websocket", "marshal drain notification", err)
+		return 0
+	}
+
+	// Take a snapshot of the connections under the lock to avoid holding it during channel sends
+	h.mu.RLock()
+	connections := make([]*Connection, 0)
+	for _, userConns := range h.connections {
+		for _, conn := range userConns {
+			connections = append(connections, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	notified := 0
+	for _, conn := range connections {
+		// No else needed: optional operation (closing connections are skipped)
+		if conn.SafeSend(data) {
+			notified++
+		}
+	}
+
+	h.logger.Info("Drain mode started",
+		"countdown", countdown,
+		"connections", len(connections),
+		"notified", notified,
+		"already_draining", alreadyDraining)
+	return notified
+}
+
+// IsDraining reports whether StartDrain has been called
+func (h *Handler) IsDraining() bool {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+	return h.draining
+}
+
+// beginRoute registers a message about to be routed. Returns false in drain mode,
+// in which case the message must not be routed.
+func (h *Handler) beginRoute() bool {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+	// No else needed: early return pattern (guard clause)
+	if h.draining {
+		return false
+	}
+	h.routeWg.Add(1)
+	return true
+}
+
+// WaitForInflight waits until every message that was being routed when drain mode
+// started, including active LLM streams, has finished. Only meaningful after
+// StartDrain. Returns ctx.Err() if ctx expires first.
+func (h *Handler) WaitForInflight(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.routeWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		h.logger.Info("All in-flight messages finished")
+		return nil
+	case <-ctx.Done():
+		h.logger.Warn("Drain deadline exceeded with messages still in flight")
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartDrain_NotifiesConnections(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+	conn := NewConnection("user-1", []string{"user"})
+	conn.ConnectionID = "conn-1"
+	handler.RegisterConnectionForTest(conn)
+
+	assert.False(t, handler.IsDraining())
+	notified := handler.StartDrain(30 * time.Second)
+	assert.Equal(t, 1, notified)
+	assert.True(t, handler.IsDraining())
+
+	select {
+	case data := <-conn.send:
+		var msg message.Message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, message.TypeServerDraining, msg.Type)
+		assert.Equal(t, "30", msg.Metadata["countdown_seconds"])
+		assert.NotEmpty(t, msg.Metadata["deadline"])
+	default:
+		t.Fatal("expected a server_draining event")
+	}
+}
+
+func TestStartDrain_RefusesNewConnections(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	handler := NewHandler(auth.NewJWTValidator(secret), newMockRouter(), testLogger(), 1048576)
+	handler.StartDrain(0)
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, secret, "user-1", []string{"user"}))
+	w := httptest.NewRecorder()
+	handler.HandleWebSocket(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestWaitForInflight(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+
+	// A message routed before the drain is in flight until it finishes
+	require.True(t, handler.beginRoute())
+	handler.StartDrain(time.Second)
+	assert.False(t, handler.beginRoute(), "new messages are rejected while draining")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, handler.WaitForInflight(ctx), context.DeadlineExceeded)
+
+	handler.routeWg.Done()
+	assert.NoError(t, handler.WaitForInflight(context.Background()))
+}
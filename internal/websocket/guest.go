@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/apierror"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// SetGuestMode admits WebSocket clients that connect without a token as
+// anonymous guests. Each gets a guest token valid for ttl, sent in a
+// connection_status message; reconnecting with it keeps the guest identity.
+// A ttl of 0 disables guest mode (the default).
+func (h *Handler) SetGuestMode(ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.guestTokenTTL = ttl
+}
+
+// authenticateOrGuest authenticates a connection request like authenticate. In
+// guest mode, requests without any token are admitted as a new guest instead,
+// and the issued guest token is returned so it can be sent to the client.
+func (h *Handler) authenticateOrGuest(w http.ResponseWriter, r *http.Request) (*auth.Claims, string, bool) {
+	h.mu.RLock()
+	ttl := h.guestTokenTTL
+	h.mu.RUnlock()
+
+	// No else needed: early return pattern (not a guest connection)
+	if ttl <= 0 || hasToken(r) {
+		claims, ok := h.authenticate(w, r)
+		return claims, "", ok
+	}
+
+	token, claims, err := h.validator.IssueGuestToken(ttl)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(h.logger, "websocket", "issue guest token", err)
+		apierror.Write(w, apierror.CodeInternalError, "Failed to create guest session")
+		return nil, "", false
+	}
+
+	h.logger.Info("Guest token issued",
+		"user_id", claims.UserID,
+		"component", "websocket")
+	return claims, token, true
+}
+
+// hasToken reports whether a request carries a token in the Authorization
+// header, the ?token= query parameter or a ?ticket=, valid or not
+func hasToken(r *http.Request) bool {
+	q := r.URL.Query()
+	return r.Header.Get("Authorization") != "" || q.Get("token") != "" || q.Get("ticket") != ""
+}
+
+// sendGuestToken sends a newly issued guest token to the client in a
+// connection_status message. Clients reconnect with it to stay the same guest
+// and pass it to POST /sessions/claim after logging in.
+func (h *Handler) sendGuestToken(c *Connection, token string) {
+	// No else needed: early return pattern (not a new guest)
+	if token == "" {
+		return
+	}
+
+	h.mu.RLock()
+	ttl := h.guestTokenTTL
+	h.mu.RUnlock()
+
+	status := &message.Message{
+		Type:      message.TypeConnectionStatus,
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"guest_token":      token,
+			"guest_user_id":    c.UserID,
+			"guest_expires_at": time.Now().Add(ttl).UTC().Format(time.RFC3339),
+		},
+	}
+	data, err := json.Marshal(status)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(h.logger, "websocket", "marshal guest token", err)
+		return
+	}
+	c.SafeSend(data)
+}
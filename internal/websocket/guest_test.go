@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleWebSocket_GuestMode(t *testing.T) {
+	validator := auth.NewJWTValidator("test-secret")
+	handler := NewHandler(validator, newMockRouter(), testLogger(), 1048576)
+	handler.SetGuestMode(time.Hour)
+
+	server := httptest.NewServer(http.HandlerFunc(handler.HandleWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// Connecting without a token issues a guest token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var status message.Message
+	require.NoError(t, conn.ReadJSON(&status))
+	assert.Equal(t, message.TypeConnectionStatus, status.Type)
+	token := status.Metadata["guest_token"]
+	require.NotEmpty(t, token)
+	assert.NotEmpty(t, status.Metadata["guest_expires_at"])
+
+	claims, err := validator.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{constants.RoleGuest}, claims.Roles)
+	assert.Equal(t, claims.UserID, status.Metadata["guest_user_id"])
+
+	// Reconnecting with the guest token keeps the identity without a new token
+	headers := http.Header{}
+	headers.Add("Authorization", "Bearer "+token)
+	again, _, err := websocket.DefaultDialer.Dial(wsURL, headers)
+	require.NoError(t, err)
+	defer again.Close()
+
+	assert.Eventually(t, func() bool {
+		handler.mu.RLock()
+		defer handler.mu.RUnlock()
+		return len(handler.connections[claims.UserID]) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// An invalid token is still rejected rather than replaced by a guest token
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?token=bogus", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHandleWebSocket_GuestModeDisabled(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret"), nil, testLogger(), 1048576)
+
+	req := httptest.NewRequest("GET", "/ws", nil)
+	w := httptest.NewRecorder()
+	handler.HandleWebSocket(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -10,19 +10,25 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/real-rm/chatbox/internal/apierror"
 	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
 	"github.com/real-rm/chatbox/internal/constants"
 	chaterrors "github.com/real-rm/chatbox/internal/errors"
 	"github.com/real-rm/chatbox/internal/message"
 	"github.com/real-rm/chatbox/internal/metrics"
 	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/requestid"
 	"github.com/real-rm/chatbox/internal/util"
 	"github.com/real-rm/golog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -68,9 +74,68 @@ type Connection struct {
 	// Roles are the user's roles from JWT
 	Roles []string
 
+	// TenantID is the user's tenant from JWT (empty for the default tenant)
+	TenantID string
+
+	// ImpersonatorID is the admin using the chat as the user with an impersonation
+	// token (empty otherwise)
+	ImpersonatorID string
+
+	// Tier is the user's customer tier from JWT (empty when not claimed)
+	Tier string
+
 	// connectedAt tracks when the connection was established for duration metrics
 	connectedAt time.Time
 
+	// resumeFrom is the last sequence number the client received before reconnecting.
+	// Only meaningful when resumePending is true; consumed once by the router.
+	resumeFrom    uint64
+	resumePending bool
+
+	// sse is true when the connection is served over Server-Sent Events
+	// instead of a WebSocket (conn is nil). Immutable after creation.
+	sse bool
+
+	// dashboard is true for admin metrics stream connections, which also
+	// receive dashboard alerts (see metrics_stream.go). Immutable after creation.
+	dashboard bool
+
+	// traceParent is the span of the HTTP request that opened the connection,
+	// so per-message spans join the client's trace. Immutable after creation.
+	traceParent trace.SpanContext
+
+	// payloadBytes counts outbound payload bytes for compression metrics (see
+	// compression.go); nil when not served by the WebSocket handler. Immutable after creation.
+	payloadBytes prometheus.Counter
+
+	// clientIP is the address the connection was opened from. Immutable after creation.
+	clientIP string
+
+	// requestID is the ID of the request that opened the connection (see
+	// internal/requestid). Immutable after creation.
+	requestID string
+
+	// frames accumulates received frames for the access log (see accesslog.go)
+	frames frameLog
+
+	// client is the SDK metadata sent at connect (see client.go). Immutable after creation.
+	client ClientMetadata
+
+	// capabilities are the protocol capabilities negotiated at connect (see
+	// capabilities.go). Immutable after creation.
+	capabilities []string
+
+	// uploads holds the chunked messages being assembled, by upload ID (see
+	// chunked.go). Protected by mu.
+	uploads map[string]*chunkedUpload
+
+	// Traffic and heartbeat counters reported by ListConnections (see diagnostics.go)
+	bytesSent     atomic.Uint64
+	bytesReceived atomic.Uint64
+	pingSentAt    atomic.Int64 // UnixNano of the last ping written
+	lastPongAt    atomic.Int64 // UnixNano of the last pong received
+	lastPingRTT   atomic.Int64 // Nanoseconds between the last ping and its pong
+
 	// send is a buffered channel for outbound messages
 	send chan []byte
 
@@ -82,6 +147,10 @@ type Connection struct {
 	// preventing panics from concurrent teardown paths (readPump, writePump, ShutdownWithContext).
 	sendOnce sync.Once
 
+	// closeFrame is the payload of the close frame written once the send
+	// channel is closed; empty for a plain close. Set by the first closeSendWith.
+	closeFrame []byte
+
 	// mu protects concurrent access to the connection
 	mu sync.RWMutex
 }
@@ -117,6 +186,38 @@ func (c *Connection) SetSessionID(id string) {
 	c.SessionID = id
 }
 
+// SetResumeFrom records the last sequence number the client received, so the
+// router replays newer buffered messages when the connection joins its session.
+func (c *Connection) SetResumeFrom(seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resumeFrom = seq
+	c.resumePending = true
+}
+
+// TakeResumeFrom returns the pending resume sequence number and clears it,
+// so missed messages are replayed at most once per connection.
+func (c *Connection) TakeResumeFrom() (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seq, pending := c.resumeFrom, c.resumePending
+	c.resumeFrom = 0
+	c.resumePending = false
+	return seq, pending
+}
+
+// TraceContext returns a context carrying the span of the request that opened
+// the connection. Message handling spans started from it join the client's trace.
+func (c *Connection) TraceContext() context.Context {
+	return trace.ContextWithSpanContext(context.Background(), c.traceParent)
+}
+
+// RequestID returns the ID of the request that opened the connection, which
+// correlates the logs of its messages
+func (c *Connection) RequestID() string {
+	return c.requestID
+}
+
 // GetRoles returns the roles for this connection.
 // Roles is immutable after construction (set in NewConnection), so no mutex is needed.
 func (c *Connection) GetRoles() []string {
@@ -132,16 +233,56 @@ type Handler struct {
 	allowedOrigins map[string]bool // Allowed origins for CORS
 	maxMessageSize int64           // Maximum message size in bytes
 
+	// maxChunkedSize is the maximum assembled size of a message sent in chunks;
+	// 0 disables chunked messages. Set via SetChunkedMessageLimit() (see chunked.go).
+	maxChunkedSize int64
+
 	// deprecateJWTQueryParam rejects tokens provided via ?token= query parameter when true.
 	// Set via SetDeprecateJWTQueryParam(). Default false preserves backwards compatibility.
 	deprecateJWTQueryParam bool
 
+	// compression enables permessage-deflate negotiation. Set via SetCompression().
+	compression bool
+
+	// strictSchema rejects client messages with fields their type does not declare
+	// and server-only types. Set via SetStrictSchema() (see schema.go).
+	strictSchema bool
+
+	// policy holds the role permissions checked for admin requests such as
+	// watching a session. Set via SetPolicy(); defaults to authz.Default().
+	policy *authz.Policy
+
+	// guestTokenTTL admits WebSocket clients without a token as guests when
+	// positive (see guest.go). Set via SetGuestMode().
+	guestTokenTTL time.Duration
+
+	// userBlocks refuses connections of users blocked by an admin when set
+	// (see blocks.go). Set via SetUserBlocks().
+	userBlocks UserBlockChecker
+
+	// tickets redeems one-time connection tickets passed as ?ticket= when set
+	// (see ticket.go). Set via SetTickets().
+	tickets TicketRedeemer
+
+	// reconnect suggests reconnect backoff to clients and detects reconnect
+	// storms (see reconnect.go). Set via SetReconnectPolicy(); nil sends no guidance.
+	reconnect *reconnectGuard
+
 	// connections tracks active connections by user ID and connection ID
 	connections map[string]map[string]*Connection
 	mu          sync.RWMutex
 
+	// sseStreams tracks open SSE streams by connection ID (see sse.go)
+	sseStreams map[string]*sseStream
+
 	// pumpWg tracks active readPump/writePump goroutines for graceful shutdown
 	pumpWg sync.WaitGroup
+
+	// Drain mode (see drain.go). routeWg tracks RouteMessage goroutines so a drain
+	// can wait for active LLM streams; drainMu orders routeWg.Add before Wait.
+	draining bool
+	drainMu  sync.Mutex
+	routeWg  sync.WaitGroup
 }
 
 // MessageRouter interface for routing messages
@@ -152,6 +293,22 @@ type MessageRouter interface {
 	GetAvailableModelRefs() []message.ModelRef
 }
 
+// DeviceRouter is implemented by routers that bind several connections (one per
+// device) to a session. Closing one device then leaves the others bound.
+type DeviceRouter interface {
+	UnregisterDevice(sessionID string, conn *Connection)
+}
+
+// unbindSession removes c from the router's connections for sessionID
+func (h *Handler) unbindSession(sessionID string, c *Connection) {
+	// No else needed: early return pattern (router tracks each device)
+	if devices, ok := h.router.(DeviceRouter); ok {
+		devices.UnregisterDevice(sessionID, c)
+		return
+	}
+	h.router.UnregisterConnection(sessionID)
+}
+
 // NewHandler creates a new WebSocket handler
 func NewHandler(validator *auth.JWTValidator, router MessageRouter, logger *golog.Logger, maxMessageSize int64) *Handler {
 	wsLogger := logger.WithGroup("websocket")
@@ -162,10 +319,20 @@ func NewHandler(validator *auth.JWTValidator, router MessageRouter, logger *golo
 		connLimiter:    ratelimit.NewConnectionLimiter(10), // Max 10 connections per user
 		allowedOrigins: make(map[string]bool),
 		maxMessageSize: maxMessageSize,
+		maxChunkedSize: constants.DefaultMaxChunkedMessageSize,
 		connections:    make(map[string]map[string]*Connection),
+		sseStreams:     make(map[string]*sseStream),
+		policy:         authz.Default(),
 	}
 }
 
+// SetPolicy replaces the default role permissions checked for admin requests
+func (h *Handler) SetPolicy(policy *authz.Policy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.policy = policy
+}
+
 // SetAllowedOrigins configures the allowed origins for WebSocket connections
 // If no origins are set, all origins are allowed (development mode)
 func (h *Handler) SetAllowedOrigins(origins []string) {
@@ -231,10 +398,98 @@ func (h *Handler) checkOrigin(r *http.Request) bool {
 // HandleWebSocket handles HTTP to WebSocket upgrade requests
 // It performs the following steps:
 // 1. Extract JWT token from query parameter or header
-// 2. Validate the JWT token
+// 2. Validate the JWT token, or issue a guest token in guest mode, and refuse blocked users
 // 3. Upgrade the HTTP connection to WebSocket
 // 4. Create a Connection struct with user context
+// 5. Resume the session given by session_id, replaying messages after resume_from
 func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	claims, guestToken, ok := h.authenticateOrGuest(w, r)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if !h.allowUser(w, claims) {
+		return
+	}
+
+	resume, ok := parseResumeParams(w, r)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if !h.allowConnection(w, claims.UserID) {
+		return
+	}
+
+	// Upgrade HTTP connection to WebSocket
+	conn, payloadBytes, err := h.upgrade(w, r)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(h.logger, "websocket", "upgrade connection", err)
+		return
+	}
+
+	// Set read limit to prevent memory exhaustion from oversized messages
+	conn.SetReadLimit(h.maxMessageSize)
+
+	// Create connection with user context
+	connection := h.createConnection(conn, claims)
+	connection.traceParent = trace.SpanContextFromContext(r.Context())
+	connection.payloadBytes = payloadBytes
+	connection.clientIP = clientIP(r)
+	connection.client = clientMetadata(r)
+	connection.capabilities = negotiateCapabilities(r)
+	connection.requestID = requestid.FromRequest(r)
+	h.prepareResume(connection, resume)
+
+	// Register the connection
+	h.registerConnection(connection)
+
+	h.logger.Info("WebSocket connection established",
+		"user_id", claims.UserID,
+		"connection_id", connection.ConnectionID,
+		"request_id", connection.requestID,
+		"component", "websocket")
+	// No else needed: optional operation (impersonated connections are logged for audit)
+	if claims.ImpersonatorID != "" {
+		h.logger.Warn("Admin impersonating user over WebSocket",
+			"admin_id", claims.ImpersonatorID,
+			"user_id", claims.UserID,
+			"connection_id", connection.ConnectionID,
+			"component", "websocket")
+	}
+
+	// Start read and write pumps in goroutines with panic recovery.
+	// Track with pumpWg so ShutdownWithContext can wait for them.
+	h.pumpWg.Add(2)
+	util.SafeGo(h.logger, "readPump", func() {
+		defer h.pumpWg.Done()
+		connection.readPump(h)
+	})
+	util.SafeGo(h.logger, "writePump", func() {
+		defer h.pumpWg.Done()
+		connection.writePump()
+	})
+
+	h.sendGuestToken(connection, guestToken)
+	h.sendInitialStatus(connection)
+	h.resumeSession(connection, resume)
+}
+
+// authenticate extracts and validates the JWT for a connection request.
+// The Authorization header is preferred; the ?token= query parameter is accepted
+// unless deprecated, and a one-time ?ticket= when tickets are enabled.
+// Writes a 401 response and returns false on failure.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	// No else needed: early return pattern (ticket instead of a JWT, see ticket.go)
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" && r.Header.Get("Authorization") == "" {
+		return h.authenticateTicket(w, r, ticket)
+	}
+
 	// Extract token: prefer Authorization header, fall back to query parameter
 	var token string
 	authHeader := r.Header.Get("Authorization")
@@ -250,8 +505,8 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			if deprecated {
 				h.logger.Warn("JWT query parameter rejected (deprecated transport)",
 					"component", "websocket")
-				http.Error(w, "JWT via query parameter is disabled. Use the Authorization header instead.", http.StatusUnauthorized)
-				return
+				apierror.Write(w, apierror.CodeUnauthorized, "JWT via query parameter is disabled. Use the Authorization header instead.")
+				return nil, false
 			}
 			h.logger.Warn("JWT provided via query parameter (deprecated, use Authorization header)",
 				"component", "websocket")
@@ -261,8 +516,8 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	// No else needed: early return pattern (guard clause)
 	if token == "" {
-		http.Error(w, "Missing authentication token", http.StatusUnauthorized)
-		return
+		apierror.Write(w, apierror.CodeUnauthorized, "Missing authentication token")
+		return nil, false
 	}
 
 	// Validate JWT token
@@ -272,75 +527,151 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		h.logger.Warn("JWT validation failed",
 			"error", err,
 			"component", "websocket")
-		http.Error(w, "Authentication failed", http.StatusUnauthorized)
-		return
+		apierror.Write(w, apierror.CodeInvalidToken, "Authentication failed")
+		return nil, false
 	}
 
-	// Check connection rate limit
 	// No else needed: early return pattern (guard clause)
-	if !h.connLimiter.Allow(claims.UserID) {
-		h.logger.Warn("Connection limit exceeded",
-			"user_id", claims.UserID,
-			"component", "websocket")
+	if !h.allowOrigin(w, r, claims) {
+		return nil, false
+	}
 
-		// Notify existing connections about the limit
-		h.notifyConnectionLimit(claims.UserID)
+	return claims, true
+}
 
-		chatErr := chaterrors.ErrConnectionLimitExceeded(5000)
-		http.Error(w, chatErr.Message, http.StatusTooManyRequests)
-		return
+// allowOrigin refuses requests from another origin than the partner site an
+// embed token was minted for, writing a 401 response
+func (h *Handler) allowOrigin(w http.ResponseWriter, r *http.Request, claims *auth.Claims) bool {
+	// No else needed: early return pattern (origin allowed)
+	if claims.AllowsOrigin(r.Header.Get("Origin")) {
+		return true
 	}
+	h.logger.Warn("Token used from another origin",
+		"user_id", claims.UserID,
+		"origin", r.Header.Get("Origin"),
+		"component", "websocket")
+	apierror.Write(w, apierror.CodeInvalidToken, "Authentication failed")
+	return false
+}
 
-	// Upgrade HTTP connection to WebSocket
-	localUpgrader := upgrader
-	localUpgrader.CheckOrigin = h.checkOrigin
+// allowConnection applies the per-user connection limit.
+// Writes a 429 response and notifies the user's open connections when exceeded.
+// Every attempt counts towards reconnect storm detection (see reconnect.go).
+func (h *Handler) allowConnection(w http.ResponseWriter, userID string) bool {
+	h.recordConnectionAttempt()
 
-	conn, err := localUpgrader.Upgrade(w, r, nil)
 	// No else needed: early return pattern (guard clause)
-	if err != nil {
-		util.LogError(h.logger, "websocket", "upgrade connection", err)
-		return
+	if h.IsDraining() {
+		h.setRetryAfter(w)
+		apierror.Write(w, apierror.CodeServiceUnavailable, "Server is draining, please reconnect")
+		return false
 	}
 
-	// Set read limit to prevent memory exhaustion from oversized messages
-	conn.SetReadLimit(h.maxMessageSize)
+	// No else needed: early return pattern (guard clause)
+	if h.connLimiter.Allow(userID) {
+		return true
+	}
 
-	// Create connection with user context
-	connection := h.createConnection(conn, claims)
+	h.logger.Warn("Connection limit exceeded",
+		"user_id", userID,
+		"component", "websocket")
 
-	// Register the connection
-	h.registerConnection(connection)
+	// Notify existing connections about the limit
+	h.notifyConnectionLimit(userID)
 
-	h.logger.Info("WebSocket connection established",
-		"user_id", claims.UserID,
-		"component", "websocket")
+	chatErr := chaterrors.ErrConnectionLimitExceeded(5000)
+	h.setRetryAfter(w)
+	apierror.Write(w, chatErr.Code, chatErr.Message)
+	return false
+}
 
-	// Start read and write pumps in goroutines with panic recovery.
-	// Track with pumpWg so ShutdownWithContext can wait for them.
-	h.pumpWg.Add(2)
-	util.SafeGo(h.logger, "readPump", func() {
-		defer h.pumpWg.Done()
-		connection.readPump(h)
-	})
-	util.SafeGo(h.logger, "writePump", func() {
-		defer h.pumpWg.Done()
-		connection.writePump()
-	})
+// resumeParams holds the optional session resume request of a reconnecting client
+type resumeParams struct {
+	sessionID  string
+	resumeFrom uint64
+	hasResume  bool
+}
 
-	// Send initial connection status with available models immediately after connect.
-	// This lets the frontend show the model selector before the user sends a message.
-	if h.router != nil {
-		if models := h.router.GetAvailableModelRefs(); len(models) > 0 {
-			status := &message.Message{
-				Type:      message.TypeConnectionStatus,
-				Sender:    message.SenderSystem,
-				Timestamp: time.Now(),
-				Models:    models,
-			}
-			if data, err := json.Marshal(status); err == nil {
-				connection.SafeSend(data)
-			}
+// parseResumeParams reads the optional session_id and resume_from query parameters.
+// Writes a 400 response and returns false when either is malformed.
+func parseResumeParams(w http.ResponseWriter, r *http.Request) (resumeParams, bool) {
+	params := resumeParams{sessionID: r.URL.Query().Get("session_id")}
+	// No else needed: early return pattern (guard clause)
+	if len(params.sessionID) > message.MaxSessionIDLength {
+		apierror.Write(w, apierror.CodeInvalidRequest, "Invalid session_id parameter")
+		return params, false
+	}
+
+	// No else needed: optional operation (only parse if provided)
+	if resumeStr := r.URL.Query().Get("resume_from"); resumeStr != "" {
+		parsed, err := strconv.ParseUint(resumeStr, 10, 64)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			apierror.Write(w, apierror.CodeInvalidRequest, "Invalid resume_from parameter")
+			return params, false
 		}
+		params.resumeFrom = parsed
+		params.hasResume = true
+	}
+
+	return params, true
+}
+
+// prepareResume records the resume position and claims the session before the
+// connection starts reading, so the message path does not register it a second time.
+func (h *Handler) prepareResume(c *Connection, resume resumeParams) {
+	// No else needed: optional operation (only for reconnecting clients)
+	if resume.hasResume {
+		c.SetResumeFrom(resume.resumeFrom)
+	}
+	// No else needed: optional operation (only for reconnecting clients)
+	if resume.sessionID != "" && h.router != nil {
+		c.SetSessionID(resume.sessionID)
+	}
+}
+
+// sendInitialStatus sends the connection status with available models immediately after connect.
+// This lets the frontend show the model selector before the user sends a message.
+// The status also carries the reconnect policy when one is set and the
+// negotiated protocol capabilities.
+func (h *Handler) sendInitialStatus(c *Connection) {
+	var models []message.ModelRef
+	if h.router != nil {
+		models = h.router.GetAvailableModelRefs()
+	}
+	policy := h.reconnectPolicy()
+	// No else needed: early return pattern (nothing to tell the client)
+	if len(models) == 0 && policy == nil && len(c.capabilities) == 0 {
+		return
+	}
+	status := &message.Message{
+		Type:         message.TypeConnectionStatus,
+		Sender:       message.SenderSystem,
+		Timestamp:    time.Now(),
+		Models:       models,
+		Reconnect:    policy,
+		Capabilities: c.capabilities,
+	}
+	if data, err := json.Marshal(status); err == nil {
+		c.SafeSend(data)
+	}
+}
+
+// resumeSession rejoins the previous session right away so missed messages are
+// replayed without waiting for the client to send its next message.
+func (h *Handler) resumeSession(c *Connection, resume resumeParams) {
+	// No else needed: early return pattern (only for reconnecting clients)
+	if resume.sessionID == "" || h.router == nil {
+		return
+	}
+
+	if err := h.router.RegisterConnection(resume.sessionID, c); err != nil {
+		util.LogError(h.logger, "websocket", "resume session", err,
+			"user_id", c.UserID,
+			"session_id", resume.sessionID,
+			"connection_id", c.ConnectionID)
+		c.SetSessionID("")
+		c.sendErrorResponse(chaterrors.ErrCodeServiceError, "Failed to resume session")
 	}
 }
 
@@ -356,26 +687,32 @@ func (h *Handler) createConnection(conn *websocket.Conn, claims *auth.Claims) *C
 		util.LogError(h.logger, "websocket", "generate random bytes for connection ID", err)
 		connectionID := fmt.Sprintf("%s-%d", claims.UserID, time.Now().UnixNano())
 		return &Connection{
-			conn:         conn,
-			ConnectionID: connectionID,
-			UserID:       claims.UserID,
-			Name:         claims.Name,
-			Roles:        claims.Roles,
-			connectedAt:  time.Now(),
-			send:         make(chan []byte, 256),
+			conn:           conn,
+			ConnectionID:   connectionID,
+			UserID:         claims.UserID,
+			Name:           claims.Name,
+			Roles:          claims.Roles,
+			TenantID:       claims.TenantID,
+			ImpersonatorID: claims.ImpersonatorID,
+			Tier:           claims.Tier,
+			connectedAt:    time.Now(),
+			send:           make(chan []byte, 256),
 		}
 	}
 
 	connectionID := fmt.Sprintf("%s-%d-%s", claims.UserID, time.Now().UnixNano(), hex.EncodeToString(randomBytes))
 
 	return &Connection{
-		conn:         conn,
-		ConnectionID: connectionID,
-		UserID:       claims.UserID,
-		Name:         claims.Name,
-		Roles:        claims.Roles,
-		connectedAt:  time.Now(),
-		send:         make(chan []byte, 256),
+		conn:           conn,
+		ConnectionID:   connectionID,
+		UserID:         claims.UserID,
+		Name:           claims.Name,
+		Roles:          claims.Roles,
+		TenantID:       claims.TenantID,
+		ImpersonatorID: claims.ImpersonatorID,
+		Tier:           claims.Tier,
+		connectedAt:    time.Now(),
+		send:           make(chan []byte, 256),
 	}
 }
 
@@ -416,8 +753,7 @@ func (h *Handler) unregisterConnection(conn *Connection) {
 	if userConns, ok := h.connections[conn.UserID]; ok {
 		if _, exists := userConns[conn.ConnectionID]; exists {
 			delete(userConns, conn.ConnectionID)
-			conn.closing.Store(true)
-			conn.sendOnce.Do(func() { close(conn.send) })
+			conn.closeSend()
 
 			// Release connection from rate limiter for each connection
 			h.connLimiter.Release(conn.UserID)
@@ -534,6 +870,12 @@ func (h *Handler) ShutdownWithContext(ctx context.Context) error {
 			}
 			c.mu.Unlock()
 
+			// SSE streams have no socket; ending the send channel stops the stream
+			// No else needed: optional operation (only for SSE connections)
+			if c.sse {
+				c.closeSend()
+			}
+
 			// Close the connection
 			if err := c.Close(); err != nil {
 				errChan <- err
@@ -578,6 +920,21 @@ func (c *Connection) SetClosing() {
 	c.closing.Store(true)
 }
 
+// closeSend marks the connection as closing and closes the send channel exactly once
+func (c *Connection) closeSend() {
+	c.closeSendWith(nil)
+}
+
+// closeSendWith is closeSend with the payload of the close frame the write
+// pump sends after the queued messages. Only the first close sets the frame.
+func (c *Connection) closeSendWith(frame []byte) {
+	c.closing.Store(true)
+	c.sendOnce.Do(func() {
+		c.closeFrame = frame
+		close(c.send)
+	})
+}
+
 // SafeSend attempts to send data to the connection's send channel.
 // Returns false if the connection is closing or the channel is full.
 // This is the preferred method for sending data to avoid panics on closed channels.
@@ -644,9 +1001,10 @@ func (c *Connection) readPump(h *Handler) {
 
 		// Unregister from router if we have a session ID
 		if sid != "" && h.router != nil {
-			h.router.UnregisterConnection(sid)
+			h.unbindSession(sid, c)
 		}
 
+		h.logFrames(c)
 		h.unregisterConnection(c)
 		c.Close()
 	}()
@@ -657,6 +1015,7 @@ func (c *Connection) readPump(h *Handler) {
 	// Configure pong handler to reset read deadline
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.recordPong(time.Now())
 		h.logger.Debug("Heartbeat pong received",
 			"user_id", c.UserID,
 			"session_id", c.GetSessionID(),
@@ -697,153 +1056,181 @@ func (c *Connection) readPump(h *Handler) {
 			break
 		}
 
-		// Parse incoming message
-		var msg message.Message
-		// No else needed: error handling with continue (skips to next iteration)
-		if err := json.Unmarshal(rawMessage, &msg); err != nil {
-			h.logger.Warn("Failed to parse message",
-				"user_id", c.UserID,
-				"connection_id", c.ConnectionID,
-				"error", err)
+		h.handleIncoming(c, rawMessage, routeSem)
+	}
+}
 
-			// Increment message errors metric
-			metrics.MessageErrors.Inc()
+// handleIncoming parses, sanitizes, and validates a raw client message, binds the
+// connection to its session on first use, and dispatches it to the router.
+// Shared by the WebSocket readPump and the SSE message endpoint.
+// routeSem caps concurrent RouteMessage goroutines for the connection.
+func (h *Handler) handleIncoming(c *Connection, rawMessage []byte, routeSem chan struct{}) {
+	c.bytesReceived.Add(uint64(len(rawMessage)))
+	// No else needed: optional operation (log the batch once it is full or old)
+	if c.frames.add(len(rawMessage), time.Now()) {
+		defer h.logFrames(c)
+	}
 
-			// Send error response to client
-			c.sendErrorResponse(chaterrors.ErrCodeInvalidFormat, "Invalid message format")
-			continue
-		}
+	// Parse incoming message against the declared message types
+	msg, err := message.Decode(rawMessage, h.strictSchemaEnabled())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		h.rejectInvalid(c, "Failed to parse message", err)
+		return
+	}
 
-		// CRITICAL FIX C2: Sanitize incoming message to prevent XSS
-		msg.Sanitize()
+	// Chunks are assembled before sanitizing, so whitespace at chunk boundaries
+	// is kept; the assembled message is sanitized and validated on commit
+	// No else needed: early return pattern (chunked message frame)
+	if isChunkFrame(msg.Type) {
+		h.handleChunk(c, msg, routeSem)
+		return
+	}
 
-		// Set defaults before validation (clients may omit these optional fields)
-		if msg.Timestamp.IsZero() {
-			msg.Timestamp = time.Now()
-		}
-		if msg.Sender == "" {
-			msg.Sender = message.SenderUser
-		}
+	// CRITICAL FIX C2: Sanitize incoming message to prevent XSS
+	msg.Sanitize()
 
-		// Validate message fields (type, required fields, length constraints)
-		if err := msg.Validate(); err != nil {
-			h.logger.Warn("Message validation failed",
-				"user_id", c.UserID,
-				"connection_id", c.ConnectionID,
-				"error", err)
+	// Set defaults before validation (clients may omit these optional fields)
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	if msg.Sender == "" {
+		msg.Sender = message.SenderUser
+	}
 
-			metrics.MessageErrors.Inc()
+	// Validate message fields (type, required fields, length constraints)
+	if err := msg.Validate(); err != nil {
+		h.rejectInvalid(c, "Message validation failed", err)
+		return
+	}
 
-			c.sendErrorResponse(chaterrors.ErrCodeInvalidFormat, "Message validation failed")
-			continue
-		}
+	h.dispatchMessage(c, *msg, routeSem)
+}
 
-		h.logger.Debug("Message received",
-			"user_id", c.UserID,
-			"session_id", c.GetSessionID(),
-			"connection_id", c.ConnectionID,
-			"message_type", msg.Type,
-			"component", "websocket")
+// dispatchMessage binds the connection to the session of a validated message on
+// first use and dispatches the message to the router
+func (h *Handler) dispatchMessage(c *Connection, msg message.Message, routeSem chan struct{}) {
+	// The router times the reply's pipeline stages from here
+	msg.ReceivedAt = time.Now()
+	h.logger.Debug("Message received",
+		"user_id", c.UserID,
+		"session_id", c.GetSessionID(),
+		"connection_id", c.ConnectionID,
+		"message_type", msg.Type,
+		"component", "websocket")
 
-		// Increment messages received metric
-		metrics.MessagesReceived.Inc()
-
-		// Route message to message router
-		// No else needed: router is required for message processing
-		if h.router != nil {
-			// If message has a session ID and connection doesn't have one yet, set it.
-			// Both check and assign are under the same lock to avoid a data race.
-			if msg.SessionID != "" {
-				needsRegister := false
-				c.mu.Lock()
-				if c.SessionID == "" {
-					c.SessionID = msg.SessionID
-					needsRegister = true
-				}
-				c.mu.Unlock()
+	// Increment messages received metric
+	metrics.MessagesReceived.Inc()
 
-				if needsRegister {
-					if err := h.router.RegisterConnection(msg.SessionID, c); err != nil {
-						util.LogError(h.logger, "websocket", "register connection with router", err,
-							"user_id", c.UserID,
-							"session_id", msg.SessionID,
-							"connection_id", c.ConnectionID)
-
-						// Send error response to client for registration failure
-						errorMsg := &message.Message{
-							Type:      message.TypeError,
-							SessionID: msg.SessionID,
-							Sender:    message.SenderAI,
-							Error: &message.ErrorInfo{
-								Code:        string(chaterrors.ErrCodeServiceError),
-								Message:     "Failed to establish session connection",
-								Recoverable: true,
-							},
-							Timestamp: time.Now(),
-						}
-						if errorBytes, err := json.Marshal(errorMsg); err == nil {
-							c.SafeSend(errorBytes)
-						}
-						continue
-					}
+	// Route message to message router
+	// No else needed: router is required for message processing
+	if h.router != nil {
+		// If message has a session ID and connection doesn't have one yet, set it.
+		// Both check and assign are under the same lock to avoid a data race.
+		// A handback names another user's session, so it never binds the connection.
+		if msg.SessionID != "" && msg.Type != message.TypeHandback {
+			needsRegister := false
+			c.mu.Lock()
+			if c.SessionID == "" {
+				c.SessionID = msg.SessionID
+				needsRegister = true
+			}
+			c.mu.Unlock()
 
-					h.logger.Info("Connection registered with router",
+			if needsRegister {
+				if err := h.router.RegisterConnection(msg.SessionID, c); err != nil {
+					util.LogError(h.logger, "websocket", "register connection with router", err,
 						"user_id", c.UserID,
 						"session_id", msg.SessionID,
 						"connection_id", c.ConnectionID)
-				}
-			}
 
-			// Dispatch RouteMessage into a goroutine so readPump stays free to
-			// handle pong frames. Without this, long LLM streams (>60s) block
-			// pong handling and the pongWait deadline kills the connection.
-			// Copy msg so the loop variable is not reused across iterations.
-			// SafeGo adds panic recovery: a RouteMessage panic must not crash readPump.
-			// routeSem limits concurrent goroutines to MaxConcurrentMessagesPerConn.
-			routeMsg := msg
-			select {
-			case routeSem <- struct{}{}:
-				util.SafeGo(h.logger, "routeMessage", func() {
-					defer func() { <-routeSem }()
-					if err := h.router.RouteMessage(c, &routeMsg); err != nil {
-						util.LogError(h.logger, "websocket", "route message", err,
-							"user_id", c.UserID,
-							"session_id", c.GetSessionID(),
-							"connection_id", c.ConnectionID,
-							"message_type", routeMsg.Type)
-						metrics.MessageErrors.Inc()
+					// Send error response to client for registration failure
+					errorMsg := &message.Message{
+						Type:      message.TypeError,
+						SessionID: msg.SessionID,
+						Sender:    message.SenderAI,
+						Error: &message.ErrorInfo{
+							Code:        string(chaterrors.ErrCodeServiceError),
+							Message:     "Failed to establish session connection",
+							Recoverable: true,
+						},
+						Timestamp: time.Now(),
+					}
+					if errorBytes, err := json.Marshal(errorMsg); err == nil {
+						c.SafeSend(errorBytes)
 					}
-				})
-			default:
-				// All goroutine slots are full; reject this message to avoid unbounded growth.
-				h.logger.Warn("Connection overloaded: dropping message (too many in-flight)",
+					return
+				}
+
+				h.logger.Info("Connection registered with router",
 					"user_id", c.UserID,
-					"session_id", c.GetSessionID(),
-					"connection_id", c.ConnectionID,
-					"limit", constants.MaxConcurrentMessagesPerConn)
-				metrics.MessageErrors.Inc()
-				c.sendErrorResponse(chaterrors.ErrCodeServiceError, "Server busy — please retry")
+					"session_id", msg.SessionID,
+					"connection_id", c.ConnectionID)
 			}
-		} else {
-			h.logger.Warn("No router configured, message not processed",
-				"user_id", c.UserID,
-				"connection_id", c.ConnectionID)
+		}
 
-			// Send error response to client when router is not configured
-			errorMsg := &message.Message{
-				Type:      message.TypeError,
-				SessionID: msg.SessionID,
-				Sender:    message.SenderAI,
-				Error: &message.ErrorInfo{
-					Code:        string(chaterrors.ErrCodeServiceError),
-					Message:     "Service temporarily unavailable",
-					Recoverable: true,
-				},
-				Timestamp: time.Now(),
-			}
-			if errorBytes, err := json.Marshal(errorMsg); err == nil {
-				c.SafeSend(errorBytes)
+		// Dispatch RouteMessage into a goroutine so readPump stays free to
+		// handle pong frames. Without this, long LLM streams (>60s) block
+		// pong handling and the pongWait deadline kills the connection.
+		// Copy msg so the goroutine owns its value.
+		// SafeGo adds panic recovery: a RouteMessage panic must not crash readPump.
+		// routeSem limits concurrent goroutines to MaxConcurrentMessagesPerConn.
+		routeMsg := msg
+		select {
+		case routeSem <- struct{}{}:
+			// No else needed: early return pattern (drain mode rejects new messages)
+			if !h.beginRoute() {
+				<-routeSem
+				c.frames.reject()
+				c.sendErrorResponse(chaterrors.ErrCodeServiceError, "Server is draining, please reconnect")
+				return
 			}
+			util.SafeGo(h.logger, "routeMessage", func() {
+				defer h.routeWg.Done()
+				defer func() { <-routeSem }()
+				start := time.Now()
+				err := h.router.RouteMessage(c, &routeMsg)
+				c.frames.route(time.Since(start), err)
+				if err != nil {
+					util.LogError(h.logger, "websocket", "route message", err,
+						"user_id", c.UserID,
+						"session_id", c.GetSessionID(),
+						"connection_id", c.ConnectionID,
+						"request_id", c.requestID,
+						"message_type", routeMsg.Type)
+					metrics.MessageErrors.Inc()
+				}
+			})
+		default:
+			// All goroutine slots are full; reject this message to avoid unbounded growth.
+			h.logger.Warn("Connection overloaded: dropping message (too many in-flight)",
+				"user_id", c.UserID,
+				"session_id", c.GetSessionID(),
+				"connection_id", c.ConnectionID,
+				"limit", constants.MaxConcurrentMessagesPerConn)
+			metrics.MessageErrors.Inc()
+			c.frames.reject()
+			c.sendErrorResponse(chaterrors.ErrCodeServiceError, "Server busy — please retry")
+		}
+	} else {
+		h.logger.Warn("No router configured, message not processed",
+			"user_id", c.UserID,
+			"connection_id", c.ConnectionID)
+
+		// Send error response to client when router is not configured
+		errorMsg := &message.Message{
+			Type:      message.TypeError,
+			SessionID: msg.SessionID,
+			Sender:    message.SenderAI,
+			Error: &message.ErrorInfo{
+				Code:        string(chaterrors.ErrCodeServiceError),
+				Message:     "Service temporarily unavailable",
+				Recoverable: true,
+			},
+			Timestamp: time.Now(),
+		}
+		if errorBytes, err := json.Marshal(errorMsg); err == nil {
+			c.SafeSend(errorBytes)
 		}
 	}
 }
@@ -871,7 +1258,12 @@ func (c *Connection) writePump() {
 
 			if !ok {
 				// Channel closed, send close message
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				frame := c.closeFrame
+				// No else needed: optional operation (plain close unless a reason was given)
+				if frame == nil {
+					frame = []byte{}
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, frame)
 				c.mu.Unlock()
 				return
 			}
@@ -886,6 +1278,11 @@ func (c *Connection) writePump() {
 
 			// Increment messages sent metric
 			metrics.MessagesSent.Inc()
+			c.bytesSent.Add(uint64(len(message)))
+			// No else needed: optional operation (connections created outside the handler)
+			if c.payloadBytes != nil {
+				c.payloadBytes.Add(float64(len(message)))
+			}
 
 		case <-ticker.C:
 			// Acquire mutex to prevent concurrent writes with ShutdownWithContext.
@@ -896,6 +1293,7 @@ func (c *Connection) writePump() {
 				return
 			}
 			c.mu.Unlock()
+			c.recordPing(time.Now())
 		}
 	}
 }
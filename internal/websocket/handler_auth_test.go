@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/real-rm/chatbox/internal/auth"
 	"github.com/real-rm/golog"
@@ -59,3 +60,36 @@ func TestHandleWebSocket_AuthPriority(t *testing.T) {
 		assert.Contains(t, w.Body.String(), "Missing authentication token")
 	})
 }
+
+func TestAuthenticate_EmbedTokenOrigin(t *testing.T) {
+	validator := auth.NewJWTValidator("a]S(2jz~t>^L%3qN)_wR#8fVx@5Yb&Ae")
+	logger, err := golog.InitLog(golog.LogConfig{
+		Dir:            t.TempDir(),
+		Level:          "error",
+		StandardOutput: false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	handler := NewHandler(validator, nil, logger, 1048576)
+
+	token, _, err := validator.IssueEmbedToken("https://partner.example.com", "", "", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to issue embed token: %v", err)
+	}
+
+	authenticate := func(origin string) int {
+		req := httptest.NewRequest("GET", "/ws", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		// No response is written on success
+		if _, ok := handler.authenticate(w, req); ok {
+			return http.StatusOK
+		}
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, authenticate("https://partner.example.com"))
+	assert.Equal(t, http.StatusUnauthorized, authenticate("https://evil.example.com"))
+}
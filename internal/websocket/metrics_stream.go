@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/apierror"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// HandleMetricsStream upgrades an admin request to a WebSocket that pushes a
+// metrics.LiveSnapshot of this replica every constants.LiveMetricsInterval, so
+// dashboards do not poll the MongoDB-backed metrics endpoint. Requires the
+// view_sessions permission. Dashboard alerts are pushed on the same stream
+// (see PublishDashboardAlert). Messages sent by the client are discarded.
+// Returns the admin's claims once the stream has started (so callers can audit
+// it), or nil when the request was rejected.
+func (h *Handler) HandleMetricsStream(w http.ResponseWriter, r *http.Request) *auth.Claims {
+	claims, ok := h.authenticate(w, r)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil
+	}
+
+	h.mu.RLock()
+	policy := h.policy
+	h.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if !policy.Allows(claims.Roles, authz.PermViewSessions) {
+		h.logger.Warn("Insufficient permissions to stream metrics",
+			"user_id", claims.UserID,
+			"component", "websocket")
+		apierror.Write(w, apierror.CodeForbidden, "Permission view_sessions required")
+		return nil
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if !h.allowConnection(w, claims.UserID) {
+		return nil
+	}
+
+	conn, payloadBytes, err := h.upgrade(w, r)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		h.connLimiter.Release(claims.UserID)
+		util.LogError(h.logger, "websocket", "upgrade metrics stream connection", err)
+		return nil
+	}
+	conn.SetReadLimit(h.maxMessageSize)
+	connection := h.createConnection(conn, claims)
+	connection.payloadBytes = payloadBytes
+	connection.clientIP = clientIP(r)
+	connection.dashboard = true
+
+	h.registerConnection(connection)
+
+	h.logger.Info("Metrics stream connection established",
+		"admin_id", claims.UserID,
+		"component", "websocket")
+
+	done := make(chan struct{})
+	h.pumpWg.Add(3)
+	util.SafeGo(h.logger, "metricsReadPump", func() {
+		defer h.pumpWg.Done()
+		defer close(done)
+		connection.metricsReadPump(h)
+	})
+	util.SafeGo(h.logger, "writePump", func() {
+		defer h.pumpWg.Done()
+		connection.writePump()
+	})
+	util.SafeGo(h.logger, "pushLiveMetrics", func() {
+		defer h.pumpWg.Done()
+		h.pushLiveMetrics(connection, done)
+	})
+	return claims
+}
+
+// pushLiveMetrics sends a snapshot right away and then every
+// constants.LiveMetricsInterval until done is closed. A snapshot is skipped
+// when the connection's send buffer is full.
+func (h *Handler) pushLiveMetrics(c *Connection, done <-chan struct{}) {
+	sampler := metrics.NewLiveSampler(constants.LiveMetricsWindow)
+	ticker := time.NewTicker(constants.LiveMetricsInterval)
+	defer ticker.Stop()
+
+	push := func(now time.Time) {
+		data, err := json.Marshal(sampler.Sample(now))
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			util.LogError(h.logger, "websocket", "marshal live metrics", err)
+			return
+		}
+		c.SafeSend(data)
+	}
+
+	push(time.Now())
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			push(now)
+		}
+	}
+}
+
+// PublishDashboardAlert sends data to the admin metrics streams of this replica
+// that may see tenantID's alerts: streams of admins of that tenant and of super
+// admins. Alerts are dropped for streams whose send buffer is full. Returns the
+// number of streams the alert was queued on.
+func (h *Handler) PublishDashboardAlert(tenantID string, data []byte) int {
+	var targets []*Connection
+	h.mu.RLock()
+	for _, userConns := range h.connections {
+		for _, conn := range userConns {
+			// No else needed: optional operation (only dashboards allowed to see the tenant)
+			if conn.dashboard && (conn.TenantID == tenantID || util.HasRole(conn.Roles, constants.RoleSuperAdmin)) {
+				targets = append(targets, conn)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	sent := 0
+	for _, conn := range targets {
+		// No else needed: optional operation (count queued alerts)
+		if conn.SafeSend(data) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// metricsReadPump keeps a metrics stream connection alive by processing control
+// frames. Data frames are discarded.
+func (c *Connection) metricsReadPump(h *Handler) {
+	defer func() {
+		h.logger.Info("Metrics stream connection closed",
+			"admin_id", c.UserID,
+			"component", "websocket")
+
+		// No else needed: optional operation (record duration if known)
+		if !c.connectedAt.IsZero() {
+			metrics.WebSocketConnectionDuration.Observe(time.Since(c.connectedAt).Seconds())
+		}
+
+		h.unregisterConnection(c)
+		c.Close()
+	}()
+
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		// No else needed: early return pattern (connection closed)
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMetricsStream_RequiresViewPermission(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	handler := NewHandler(auth.NewJWTValidator(secret), newMockRouter(), testLogger(), 1048576)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/metrics/stream", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, secret, "user-1", []string{"user"}))
+	w := httptest.NewRecorder()
+
+	assert.Nil(t, handler.HandleMetricsStream(w, req))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	assert.Nil(t, handler.HandleMetricsStream(w, httptest.NewRequest(http.MethodGet, "/admin/metrics/stream", nil)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandleMetricsStream_PushesSnapshots(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	handler := NewHandler(auth.NewJWTValidator(secret), newMockRouter(), testLogger(), 1048576)
+
+	started := make(chan *auth.Claims, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- handler.HandleMetricsStream(w, r)
+	}))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	headers := http.Header{}
+	headers.Add("Authorization", "Bearer "+generateTestToken(t, secret, "admin-1", []string{"admin"}))
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, headers)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	claims := <-started
+	require.NotNil(t, claims, "started streams return the admin's claims")
+	assert.Equal(t, "admin-1", claims.UserID)
+
+	// The first snapshot is sent right away
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var snapshot metrics.LiveSnapshot
+	require.NoError(t, conn.ReadJSON(&snapshot))
+	assert.False(t, snapshot.Timestamp.IsZero())
+	assert.GreaterOrEqual(t, snapshot.Connections, int64(1), "the stream itself is connected")
+
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		handler.mu.RLock()
+		defer handler.mu.RUnlock()
+		return len(handler.connections["admin-1"]) == 0
+	}, 2*time.Second, 10*time.Millisecond, "closed streams are unregistered")
+}
+
+func TestPublishDashboardAlert(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+
+	newDashboard := func(id, userID, tenantID string, roles []string) *Connection {
+		conn := NewConnection(userID, roles)
+		conn.ConnectionID = id
+		conn.TenantID = tenantID
+		conn.dashboard = true
+		handler.RegisterConnectionForTest(conn)
+		return conn
+	}
+	acmeAdmin := newDashboard("conn-1", "admin-1", "acme", []string{"admin"})
+	otherAdmin := newDashboard("conn-2", "admin-2", "globex", []string{"admin"})
+	superAdmin := newDashboard("conn-3", "admin-3", "", []string{constants.RoleSuperAdmin})
+	chatUser := NewConnection("user-1", []string{"user"})
+	chatUser.ConnectionID = "conn-4"
+	chatUser.TenantID = "acme"
+	handler.RegisterConnectionForTest(chatUser)
+
+	assert.Equal(t, 2, handler.PublishDashboardAlert("acme", []byte(`{"type":"keyword_alert"}`)))
+	assert.Len(t, acmeAdmin.send, 1)
+	assert.Len(t, superAdmin.send, 1)
+	assert.Empty(t, otherAdmin.send, "admins of other tenants do not see the alert")
+	assert.Empty(t, chatUser.send, "chat connections never receive dashboard alerts")
+}
@@ -0,0 +1,185 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// ReconnectConfig is the reconnect backoff suggested to clients
+type ReconnectConfig struct {
+	BaseDelay   time.Duration // First delay; doubled on every further attempt
+	MaxDelay    time.Duration // Largest delay
+	Jitter      float64       // Randomization, as a fraction of the delay (0 to 1)
+	MaxAttempts int           // Attempts before giving up (0 retries forever)
+	StormLimit  int           // Connection attempts per constants.ReconnectStormWindow that start a storm (0 never does)
+}
+
+// SetReconnectPolicy sends cfg as a reconnect_policy to clients in the
+// connection_status message after connecting and in server_draining messages,
+// and as a Retry-After header on refused connections. When connection attempts
+// exceed cfg.StormLimit within constants.ReconnectStormWindow, the suggested
+// delays are raised by constants.ReconnectStormMultiplier until the attempts
+// stay below the limit for constants.ReconnectStormCooldown, and connected
+// clients are sent the raised policy so they do not join the herd.
+// Without a policy (the default) clients get no guidance.
+func (h *Handler) SetReconnectPolicy(cfg ReconnectConfig) error {
+	// No else needed: early return pattern (guard clause)
+	if cfg.BaseDelay <= 0 || cfg.MaxDelay < cfg.BaseDelay {
+		return errors.New("reconnect delays must be positive, with the max delay at least the base delay")
+	}
+	// No else needed: early return pattern (guard clause)
+	if cfg.Jitter < 0 || cfg.Jitter > 1 {
+		return errors.New("reconnect jitter must be between 0 and 1")
+	}
+	// No else needed: early return pattern (guard clause)
+	if cfg.MaxAttempts < 0 || cfg.StormLimit < 0 {
+		return errors.New("reconnect max attempts and storm limit must not be negative")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reconnect = &reconnectGuard{cfg: cfg}
+	return nil
+}
+
+// reconnectPolicy returns the reconnect policy to suggest now, or nil when none is set
+func (h *Handler) reconnectPolicy() *message.ReconnectPolicy {
+	h.mu.RLock()
+	guard := h.reconnect
+	h.mu.RUnlock()
+	// No else needed: early return pattern (no guidance configured)
+	if guard == nil {
+		return nil
+	}
+	return guard.policy(time.Now())
+}
+
+// recordConnectionAttempt counts a connection attempt towards storm detection
+// and, when it starts a storm, sends the raised policy to connected clients
+func (h *Handler) recordConnectionAttempt() {
+	h.mu.RLock()
+	guard := h.reconnect
+	h.mu.RUnlock()
+	// No else needed: early return pattern (no guidance configured)
+	if guard == nil {
+		return
+	}
+
+	now := time.Now()
+	// No else needed: early return pattern (no storm started)
+	if !guard.record(now) {
+		return
+	}
+	policy := guard.policy(now)
+	metrics.ReconnectStorms.Inc()
+	h.logger.Warn("Reconnect storm detected, raising suggested backoff",
+		"limit", guard.cfg.StormLimit,
+		"window", constants.ReconnectStormWindow,
+		"base_delay_ms", policy.BaseDelayMs,
+		"component", "websocket")
+	h.broadcastReconnectPolicy(policy)
+}
+
+// broadcastReconnectPolicy sends policy in a connection_status message to every
+// connection of this replica
+func (h *Handler) broadcastReconnectPolicy(policy *message.ReconnectPolicy) {
+	data, err := json.Marshal(&message.Message{
+		Type:      message.TypeConnectionStatus,
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+		Reconnect: policy,
+	})
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(h.logger, "websocket", "marshal reconnect policy", err)
+		return
+	}
+
+	// Take a snapshot of the connections under the lock to avoid holding it during channel sends
+	h.mu.RLock()
+	connections := make([]*Connection, 0)
+	for _, userConns := range h.connections {
+		for _, conn := range userConns {
+			connections = append(connections, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, conn := range connections {
+		conn.SafeSend(data)
+	}
+}
+
+// setRetryAfter sets the Retry-After header of a refused connection to the
+// suggested base delay, rounded up to whole seconds
+func (h *Handler) setRetryAfter(w http.ResponseWriter) {
+	policy := h.reconnectPolicy()
+	// No else needed: early return pattern (no guidance configured)
+	if policy == nil {
+		return
+	}
+	seconds := int(math.Ceil(float64(policy.BaseDelayMs) / 1000))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// reconnectGuard counts connection attempts in fixed windows and raises the
+// suggested backoff while they exceed the storm limit
+type reconnectGuard struct {
+	cfg ReconnectConfig
+
+	mu          sync.Mutex
+	windowStart time.Time
+	attempts    int
+	stormUntil  time.Time // Delays are raised before this time
+}
+
+// record counts an attempt at now and reports whether it started a storm
+func (g *reconnectGuard) record(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// No else needed: optional operation (start a new window)
+	if now.Sub(g.windowStart) >= constants.ReconnectStormWindow {
+		g.windowStart = now
+		g.attempts = 0
+	}
+	g.attempts++
+	// No else needed: early return pattern (storm detection disabled, or below the limit)
+	if g.cfg.StormLimit == 0 || g.attempts <= g.cfg.StormLimit {
+		return false
+	}
+	started := !now.Before(g.stormUntil)
+	g.stormUntil = g.windowStart.Add(constants.ReconnectStormWindow + constants.ReconnectStormCooldown)
+	return started
+}
+
+// policy returns the backoff to suggest at now
+func (g *reconnectGuard) policy(now time.Time) *message.ReconnectPolicy {
+	g.mu.Lock()
+	storm := now.Before(g.stormUntil)
+	g.mu.Unlock()
+
+	base, maxDelay := g.cfg.BaseDelay, g.cfg.MaxDelay
+	// No else needed: optional operation (raise delays during a storm)
+	if storm {
+		base *= constants.ReconnectStormMultiplier
+		maxDelay *= constants.ReconnectStormMultiplier
+	}
+	return &message.ReconnectPolicy{
+		BaseDelayMs: int(base.Milliseconds()),
+		MaxDelayMs:  int(maxDelay.Milliseconds()),
+		Jitter:      g.cfg.Jitter,
+		MaxAttempts: g.cfg.MaxAttempts,
+		Storm:       storm,
+	}
+}
@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testReconnectConfig = ReconnectConfig{
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.5,
+	MaxAttempts: 10,
+	StormLimit:  3,
+}
+
+func TestSetReconnectPolicy_Validation(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+	assert.Nil(t, handler.reconnectPolicy(), "no guidance by default")
+
+	assert.Error(t, handler.SetReconnectPolicy(ReconnectConfig{}))
+	assert.Error(t, handler.SetReconnectPolicy(ReconnectConfig{BaseDelay: time.Minute, MaxDelay: time.Second}))
+	assert.Error(t, handler.SetReconnectPolicy(ReconnectConfig{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 1.5}))
+	assert.Error(t, handler.SetReconnectPolicy(ReconnectConfig{BaseDelay: time.Second, MaxDelay: time.Minute, MaxAttempts: -1}))
+	assert.Nil(t, handler.reconnectPolicy())
+
+	require.NoError(t, handler.SetReconnectPolicy(testReconnectConfig))
+	assert.Equal(t, &message.ReconnectPolicy{BaseDelayMs: 1000, MaxDelayMs: 30000, Jitter: 0.5, MaxAttempts: 10}, handler.reconnectPolicy())
+}
+
+func TestReconnectGuard_Storm(t *testing.T) {
+	guard := &reconnectGuard{cfg: testReconnectConfig}
+	start := time.Now()
+
+	for i := 0; i < testReconnectConfig.StormLimit; i++ {
+		assert.False(t, guard.record(start))
+	}
+	assert.False(t, guard.policy(start).Storm)
+
+	assert.True(t, guard.record(start.Add(time.Second)), "the attempt over the limit starts a storm")
+	assert.False(t, guard.record(start.Add(2*time.Second)), "a storm starts once")
+	policy := guard.policy(start.Add(2 * time.Second))
+	assert.True(t, policy.Storm)
+	assert.Equal(t, 1000*constants.ReconnectStormMultiplier, policy.BaseDelayMs)
+	assert.Equal(t, 30000*constants.ReconnectStormMultiplier, policy.MaxDelayMs)
+
+	// A quiet window does not end the storm before the cooldown
+	assert.False(t, guard.record(start.Add(constants.ReconnectStormWindow)))
+	assert.True(t, guard.policy(start.Add(constants.ReconnectStormWindow)).Storm)
+	calm := start.Add(constants.ReconnectStormWindow + constants.ReconnectStormCooldown)
+	assert.False(t, guard.policy(calm).Storm)
+	assert.Equal(t, 1000, guard.policy(calm).BaseDelayMs)
+
+	// Storm detection can be disabled
+	off := &reconnectGuard{cfg: ReconnectConfig{BaseDelay: time.Second, MaxDelay: time.Second}}
+	for i := 0; i < 100; i++ {
+		assert.False(t, off.record(start))
+	}
+}
+
+func TestReconnectPolicy_SentToClients(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	handler := NewHandler(auth.NewJWTValidator(secret), newMockRouter(), testLogger(), 1048576)
+	require.NoError(t, handler.SetReconnectPolicy(testReconnectConfig))
+
+	// The connection status carries the policy even without models
+	conn := NewConnection("user-1", []string{"user"})
+	conn.ConnectionID = "conn-1"
+	handler.RegisterConnectionForTest(conn)
+	handler.sendInitialStatus(conn)
+	var status message.Message
+	require.NoError(t, json.Unmarshal(<-conn.send, &status))
+	assert.Equal(t, message.TypeConnectionStatus, status.Type)
+	require.NotNil(t, status.Reconnect)
+	assert.Equal(t, 1000, status.Reconnect.BaseDelayMs)
+
+	// Going over the storm limit pushes the raised policy to connected clients
+	for i := 0; i <= testReconnectConfig.StormLimit; i++ {
+		handler.recordConnectionAttempt()
+	}
+	var raised message.Message
+	require.NoError(t, json.Unmarshal(<-conn.send, &raised))
+	require.NotNil(t, raised.Reconnect)
+	assert.True(t, raised.Reconnect.Storm)
+	assert.Equal(t, 1000*constants.ReconnectStormMultiplier, raised.Reconnect.BaseDelayMs)
+
+	// Drain notifications carry it, and refused connections get a Retry-After
+	handler.StartDrain(30 * time.Second)
+	var drain message.Message
+	require.NoError(t, json.Unmarshal(<-conn.send, &drain))
+	assert.Equal(t, message.TypeServerDraining, drain.Type)
+	require.NotNil(t, drain.Reconnect)
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, secret, "user-2", []string{"user"}))
+	w := httptest.NewRecorder()
+	handler.HandleWebSocket(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "8", w.Header().Get("Retry-After"))
+}
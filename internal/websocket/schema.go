@@ -0,0 +1,69 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+)
+
+// SetStrictSchema makes client messages validate strictly against the declared
+// message types (see message.Decode): fields a type does not declare and types
+// only the server sends are rejected instead of ignored. Disabled by default, so
+// clients that send extra fields keep working.
+func (h *Handler) SetStrictSchema(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.strictSchema = enabled
+}
+
+// strictSchemaEnabled reports whether client messages are validated strictly
+func (h *Handler) strictSchemaEnabled() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.strictSchema
+}
+
+// rejectInvalid logs a client message that failed parsing or validation and
+// sends the client an INVALID_FORMAT error naming the field and the rule it
+// broke, so clients can fix the frame
+func (h *Handler) rejectInvalid(c *Connection, reason string, err error) {
+	h.logger.Warn(reason,
+		"user_id", c.UserID,
+		"connection_id", c.ConnectionID,
+		"error", err,
+		"component", "websocket")
+	metrics.MessageErrors.Inc()
+	c.frames.reject()
+	c.sendValidationError(err)
+}
+
+// sendValidationError sends an INVALID_FORMAT error for err, with the field and
+// rule of a *message.ValidationError
+func (c *Connection) sendValidationError(err error) {
+	info := &message.ErrorInfo{
+		Code:        string(chaterrors.ErrCodeInvalidFormat),
+		Message:     "Invalid message format",
+		Recoverable: true,
+	}
+	var verr *message.ValidationError
+	// No else needed: optional operation (structured details of schema errors)
+	if errors.As(err, &verr) {
+		info.Message = verr.Message
+		info.Field = verr.Field
+		info.Rule = verr.Rule
+	}
+	errorMsg := &message.Message{
+		Type:      message.TypeError,
+		Sender:    message.SenderAI,
+		Error:     info,
+		Timestamp: time.Now(),
+	}
+	// No else needed: optional operation (marshal failure leaves nothing to send)
+	if errorBytes, err := json.Marshal(errorMsg); err == nil {
+		c.SafeSend(errorBytes)
+	}
+}
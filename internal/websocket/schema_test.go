@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextError returns the next message sent to conn, which must be an error
+func nextError(t *testing.T, conn *Connection) *message.ErrorInfo {
+	t.Helper()
+	select {
+	case raw := <-conn.send:
+		var msg message.Message
+		require.NoError(t, json.Unmarshal(raw, &msg))
+		require.Equal(t, message.TypeError, msg.Type)
+		return msg.Error
+	default:
+		t.Fatal("no error was sent")
+		return nil
+	}
+}
+
+func TestHandleIncoming_StructuredValidationErrors(t *testing.T) {
+	mockRouter := newMockRouter()
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), mockRouter, testLogger(), 1048576)
+	conn := NewConnection("user-1", []string{"user"})
+	routeSem := make(chan struct{}, 1)
+
+	handler.handleIncoming(conn, []byte("not json"), routeSem)
+	info := nextError(t, conn)
+	assert.Equal(t, string(chaterrors.ErrCodeInvalidFormat), info.Code)
+	assert.Equal(t, message.RuleInvalid, info.Rule)
+	assert.True(t, info.Recoverable)
+
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "user_message", "session_id": "session-1", "content": 42})
+	info = nextError(t, conn)
+	assert.Equal(t, "content", info.Field)
+	assert.Equal(t, message.RuleInvalid, info.Rule)
+
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "user_message", "session_id": "session-1"})
+	info = nextError(t, conn)
+	assert.Equal(t, "content", info.Field)
+	assert.Equal(t, message.RuleRequired, info.Rule)
+	assert.Equal(t, "content is required for user_message", info.Message)
+
+	assert.Empty(t, mockRouter.RoutedMessages())
+}
+
+func TestHandleIncoming_StrictSchema(t *testing.T) {
+	mockRouter := newMockRouter()
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), mockRouter, testLogger(), 1048576)
+	conn := NewConnection("user-1", []string{"user"})
+	routeSem := make(chan struct{}, 1)
+	withExtra := map[string]interface{}{"type": "user_message", "session_id": "session-1", "content": "hi", "colour": "red"}
+
+	// Lenient by default: the undeclared field is ignored
+	sendFrame(t, handler, conn, routeSem, withExtra)
+	require.Eventually(t, func() bool { return len(mockRouter.RoutedMessages()) == 1 }, time.Second, 10*time.Millisecond)
+
+	handler.SetStrictSchema(true)
+	sendFrame(t, handler, conn, routeSem, withExtra)
+	info := nextError(t, conn)
+	assert.Equal(t, "colour", info.Field)
+	assert.Equal(t, message.RuleUnknownField, info.Rule)
+
+	sendFrame(t, handler, conn, routeSem, map[string]interface{}{"type": "ai_response", "session_id": "session-1", "content": "hi"})
+	info = nextError(t, conn)
+	assert.Equal(t, "type", info.Field)
+	assert.Equal(t, message.RuleNotAllowed, info.Rule)
+
+	assert.Len(t, mockRouter.RoutedMessages(), 1)
+}
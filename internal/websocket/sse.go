@@ -0,0 +1,218 @@
+package websocket
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/apierror"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/requestid"
+	"github.com/real-rm/chatbox/internal/util"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sseStream is an open Server-Sent Events stream. The stream's Connection has no
+// underlying WebSocket; outbound messages are written as SSE events and inbound
+// messages arrive through HandleSSEMessage.
+type sseStream struct {
+	conn *Connection
+
+	// routeSem caps concurrent RouteMessage goroutines, like readPump's semaphore
+	routeSem chan struct{}
+}
+
+// HandleSSE opens a Server-Sent Events stream as an alternative to the WebSocket
+// transport for networks whose proxies block WebSockets. Authentication, user
+// blocks, the per-user connection limit, and session resume parameters work as for /ws.
+// The first event ("ready") carries the connection ID that clients pass to the
+// message endpoint; every later event is a JSON chat message.
+func (h *Handler) HandleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		apierror.Write(w, apierror.CodeInternalError, "Streaming not supported")
+		return
+	}
+
+	claims, ok := h.authenticate(w, r)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if !h.allowUser(w, claims) {
+		return
+	}
+
+	resume, ok := parseResumeParams(w, r)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if !h.allowConnection(w, claims.UserID) {
+		return
+	}
+
+	connection := h.createConnection(nil, claims)
+	connection.sse = true
+	connection.traceParent = trace.SpanContextFromContext(r.Context())
+	connection.clientIP = clientIP(r)
+	connection.client = clientMetadata(r)
+	connection.capabilities = negotiateCapabilities(r)
+	connection.requestID = requestid.FromRequest(r)
+	h.prepareResume(connection, resume)
+
+	stream := &sseStream{
+		conn:     connection,
+		routeSem: make(chan struct{}, constants.MaxConcurrentMessagesPerConn),
+	}
+
+	h.registerConnection(connection)
+	h.mu.Lock()
+	// No else needed: initialize if needed (lazy initialization)
+	if h.sseStreams == nil {
+		h.sseStreams = make(map[string]*sseStream)
+	}
+	h.sseStreams[connection.ConnectionID] = stream
+	h.mu.Unlock()
+
+	h.pumpWg.Add(1)
+	defer h.pumpWg.Done()
+	defer h.closeSSEStream(connection)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+
+	// No else needed: early return pattern (guard clause)
+	if _, err := fmt.Fprintf(w, "event: ready\ndata: {\"connection_id\":%q}\n\n", connection.ConnectionID); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	h.logger.Info("SSE stream established",
+		"user_id", claims.UserID,
+		"connection_id", connection.ConnectionID,
+		"request_id", connection.requestID,
+		"component", "websocket")
+
+	h.sendInitialStatus(connection)
+	h.resumeSession(connection, resume)
+
+	writeSSE(w, flusher, connection, r)
+}
+
+// writeSSE forwards messages from the connection's send channel to the client
+// until the client disconnects or the stream is closed. Comment lines are sent
+// every pingPeriod so proxies do not drop the idle connection.
+func writeSSE(w io.Writer, flusher http.Flusher, c *Connection, r *http.Request) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			// No else needed: early return pattern (stream closed)
+			if !ok {
+				return
+			}
+			// No else needed: early return pattern (client gone)
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+			metrics.MessagesSent.Inc()
+			c.bytesSent.Add(uint64(len(data)))
+
+		case <-ticker.C:
+			// No else needed: early return pattern (client gone)
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// closeSSEStream removes an SSE stream and releases its connection
+func (h *Handler) closeSSEStream(c *Connection) {
+	sid := c.GetSessionID()
+	h.logger.Info("SSE stream closed",
+		"user_id", c.UserID,
+		"session_id", sid,
+		"component", "websocket")
+
+	// No else needed: optional operation (record duration if known)
+	if !c.connectedAt.IsZero() {
+		metrics.WebSocketConnectionDuration.Observe(time.Since(c.connectedAt).Seconds())
+	}
+
+	// No else needed: optional operation (only unregister bound sessions)
+	if sid != "" && h.router != nil {
+		h.unbindSession(sid, c)
+	}
+
+	h.mu.Lock()
+	delete(h.sseStreams, c.ConnectionID)
+	h.mu.Unlock()
+
+	h.logFrames(c)
+	h.unregisterConnection(c)
+}
+
+// HandleSSEMessage accepts a client message for an open SSE stream.
+// The stream is identified by the connection_id query parameter and must belong
+// to the authenticated user. The body is the same JSON message a WebSocket client
+// sends; it goes through the same validation and routing, and any responses or
+// errors are delivered on the SSE stream. Responds 202 once the message is queued.
+func (h *Handler) HandleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.authenticate(w, r)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return
+	}
+
+	connectionID := r.URL.Query().Get("connection_id")
+	h.mu.RLock()
+	stream, exists := h.sseStreams[connectionID]
+	h.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if !exists || stream.conn.UserID != claims.UserID || stream.conn.TenantID != claims.TenantID {
+		apierror.Write(w, apierror.CodeNotFound, "Stream not found")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxMessageSize))
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		h.logger.Warn("SSE message body rejected",
+			"user_id", claims.UserID,
+			"connection_id", connectionID,
+			"limit", h.maxMessageSize,
+			"error", err,
+			"component", "websocket")
+		apierror.Write(w, apierror.CodePayloadTooLarge, "Message too large")
+		return
+	}
+
+	h.handleIncoming(stream.conn, body, stream.routeSem)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	// No else needed: optional operation (response body is informational)
+	if _, err := io.WriteString(w, `{"status":"accepted"}`); err != nil {
+		util.LogError(h.logger, "websocket", "write SSE message response", err,
+			"connection_id", connectionID)
+	}
+}
@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startSSEServer serves the SSE stream and message endpoints for a handler
+func startSSEServer(h *Handler) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", h.HandleSSE)
+	mux.HandleFunc("/sse/messages", h.HandleSSEMessage)
+	return httptest.NewServer(mux)
+}
+
+// readSSEData returns the data payload of the next SSE event, skipping comments
+func readSSEData(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+		}
+	}
+}
+
+func TestSSE_StreamAndPostMessage(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	router := newMockRouter()
+	handler := NewHandler(auth.NewJWTValidator(secret), router, testLogger(), 1048576)
+	server := startSSEServer(handler)
+	defer server.Close()
+
+	token := generateTestToken(t, secret, "sse-user", []string{"user"})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/sse", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	var ready struct {
+		ConnectionID string `json:"connection_id"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(readSSEData(t, reader)), &ready))
+	require.NotEmpty(t, ready.ConnectionID)
+
+	body := `{"type":"user_message","session_id":"sse-session","content":"hello"}`
+	post, err := http.NewRequest(http.MethodPost, server.URL+"/sse/messages?connection_id="+ready.ConnectionID, strings.NewReader(body))
+	require.NoError(t, err)
+	post.Header.Set("Authorization", "Bearer "+token)
+	postResp, err := http.DefaultClient.Do(post)
+	require.NoError(t, err)
+	postResp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, postResp.StatusCode)
+
+	require.Eventually(t, func() bool {
+		return len(router.RoutedMessages()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, router.RegisteredSessions(), "sse-session")
+
+	// Outbound messages are delivered as SSE events
+	conn := router.RegisteredSessions()["sse-session"]
+	out, err := json.Marshal(&message.Message{Type: message.TypeAIResponse, Content: "hi", Sender: message.SenderAI})
+	require.NoError(t, err)
+	require.True(t, conn.SafeSend(out))
+
+	// Skip connection status and any other events queued before our message
+	var received message.Message
+	for received.Type != message.TypeAIResponse {
+		require.NoError(t, json.Unmarshal([]byte(readSSEData(t, reader)), &received))
+	}
+	assert.Equal(t, "hi", received.Content)
+}
+
+func TestSSE_PostRejectsOtherUsersStream(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	router := newMockRouter()
+	handler := NewHandler(auth.NewJWTValidator(secret), router, testLogger(), 1048576)
+	server := startSSEServer(handler)
+	defer server.Close()
+
+	owner := generateTestToken(t, secret, "owner", []string{"user"})
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/sse", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+owner)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var ready struct {
+		ConnectionID string `json:"connection_id"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(readSSEData(t, bufio.NewReader(resp.Body))), &ready))
+
+	intruder := generateTestToken(t, secret, "intruder", []string{"user"})
+	post, err := http.NewRequest(http.MethodPost, server.URL+"/sse/messages?connection_id="+ready.ConnectionID,
+		strings.NewReader(`{"type":"user_message","session_id":"s","content":"x"}`))
+	require.NoError(t, err)
+	post.Header.Set("Authorization", "Bearer "+intruder)
+	postResp, err := http.DefaultClient.Do(post)
+	require.NoError(t, err)
+	postResp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, postResp.StatusCode)
+	assert.Empty(t, router.RoutedMessages())
+}
+
+func TestSSE_RequiresAuthentication(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+
+	w := httptest.NewRecorder()
+	handler.HandleSSE(w, httptest.NewRequest(http.MethodGet, "/sse", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
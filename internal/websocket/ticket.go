@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/real-rm/chatbox/internal/apierror"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/util"
+	"github.com/real-rm/chatbox/internal/wsticket"
+)
+
+// TicketRedeemer exchanges a one-time connection ticket for the claims it was
+// issued for (implemented by wsticket.MemoryStore and wsticket.RedisStore)
+type TicketRedeemer interface {
+	Redeem(ctx context.Context, ticket string) (*auth.Claims, error)
+}
+
+// SetTickets accepts one-time tickets, passed as ?ticket=, in place of a JWT
+// on WebSocket and SSE connections. A nil redeemer (the default) refuses them.
+func (h *Handler) SetTickets(tickets TicketRedeemer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tickets = tickets
+}
+
+// authenticateTicket redeems a connection ticket. Writes a 401 response and
+// returns false when the ticket is unknown, expired or already used.
+func (h *Handler) authenticateTicket(w http.ResponseWriter, r *http.Request, ticket string) (*auth.Claims, bool) {
+	h.mu.RLock()
+	tickets := h.tickets
+	h.mu.RUnlock()
+
+	// No else needed: early return pattern (tickets not enabled)
+	if tickets == nil {
+		apierror.Write(w, apierror.CodeUnauthorized, "Missing authentication token")
+		return nil, false
+	}
+
+	claims, err := tickets.Redeem(r.Context(), ticket)
+	// No else needed: early return pattern (guard clause)
+	if errors.Is(err, wsticket.ErrInvalidTicket) {
+		h.logger.Warn("Connection ticket rejected",
+			"component", "websocket")
+		apierror.Write(w, apierror.CodeInvalidToken, "Authentication failed")
+		return nil, false
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		util.LogError(h.logger, "websocket", "redeem ticket", err)
+		apierror.Write(w, apierror.CodeServiceUnavailable, "Failed to check connection ticket")
+		return nil, false
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if !h.allowOrigin(w, r, claims) {
+		return nil, false
+	}
+	return claims, true
+}
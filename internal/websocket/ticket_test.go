@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/wsticket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRedeemer stands in for an unreachable ticket store
+type failingRedeemer struct{}
+
+func (failingRedeemer) Redeem(context.Context, string) (*auth.Claims, error) {
+	return nil, errors.New("connection refused")
+}
+
+// authenticateStatus runs authenticate on req and returns 200 on success
+func authenticateStatus(handler *Handler, req *http.Request) (*auth.Claims, int) {
+	w := httptest.NewRecorder()
+	// No response is written on success
+	if claims, ok := handler.authenticate(w, req); ok {
+		return claims, http.StatusOK
+	}
+	return nil, w.Code
+}
+
+func TestAuthenticate_Ticket(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret"), nil, testLogger(), 1048576)
+	store := wsticket.NewMemoryStore(time.Minute)
+	handler.SetTickets(store)
+
+	issued := &auth.Claims{UserID: "user-1", Roles: []string{"user"}}
+	ticket, _, err := store.Issue(context.Background(), issued)
+	require.NoError(t, err)
+
+	claims, status := authenticateStatus(handler, httptest.NewRequest("GET", "/ws?ticket="+ticket, nil))
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, issued, claims)
+
+	// Replaying the ticket fails
+	_, status = authenticateStatus(handler, httptest.NewRequest("GET", "/ws?ticket="+ticket, nil))
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAuthenticate_TicketKeepsOriginRestriction(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret"), nil, testLogger(), 1048576)
+	store := wsticket.NewMemoryStore(time.Minute)
+	handler.SetTickets(store)
+
+	ticket, _, err := store.Issue(context.Background(), &auth.Claims{UserID: "embed-1", Origin: "https://partner.example.com"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/ws?ticket="+ticket, nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	_, status := authenticateStatus(handler, req)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAuthenticate_TicketsDisabled(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret"), nil, testLogger(), 1048576)
+
+	_, status := authenticateStatus(handler, httptest.NewRequest("GET", "/ws?ticket=anything", nil))
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAuthenticate_TicketStoreUnavailable(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret"), nil, testLogger(), 1048576)
+	handler.SetTickets(failingRedeemer{})
+
+	_, status := authenticateStatus(handler, httptest.NewRequest("GET", "/ws?ticket=anything", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
@@ -0,0 +1,23 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestConnection_TraceContext(t *testing.T) {
+	conn := NewConnection("user-1", []string{"user"})
+	assert.False(t, trace.SpanContextFromContext(conn.TraceContext()).IsValid(),
+		"connections opened without a trace carry no parent span")
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	conn.traceParent = parent
+	assert.Equal(t, parent, trace.SpanContextFromContext(conn.TraceContext()))
+}
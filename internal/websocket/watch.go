@@ -0,0 +1,156 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/apierror"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
+	chaterrors "github.com/real-rm/chatbox/internal/errors"
+	"github.com/real-rm/chatbox/internal/message"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/real-rm/chatbox/internal/util"
+)
+
+// SessionWatcher is implemented by routers that support read-only session spectating
+type SessionWatcher interface {
+	WatchSession(sessionID string, conn *Connection) error
+	UnwatchSession(sessionID string, conn *Connection)
+}
+
+// HandleWatch upgrades an admin request to a read-only WebSocket that streams the
+// messages of sessionID in real time without taking the session over. Requires the
+// view_sessions permission. Messages sent by the watcher are discarded.
+// Returns the watching admin's claims once the watch has started (so callers can
+// audit it), or nil when the request was rejected.
+func (h *Handler) HandleWatch(w http.ResponseWriter, r *http.Request, sessionID string) *auth.Claims {
+	claims, ok := h.authenticate(w, r)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		return nil
+	}
+
+	h.mu.RLock()
+	policy := h.policy
+	h.mu.RUnlock()
+	// No else needed: early return pattern (guard clause)
+	if !policy.Allows(claims.Roles, authz.PermViewSessions) {
+		h.logger.Warn("Insufficient permissions to watch session",
+			"user_id", claims.UserID,
+			"session_id", sessionID,
+			"component", "websocket")
+		apierror.Write(w, apierror.CodeForbidden, "Permission view_sessions required")
+		return nil
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if sessionID == "" || len(sessionID) > message.MaxSessionIDLength {
+		apierror.Write(w, apierror.CodeInvalidRequest, "Invalid session ID")
+		return nil
+	}
+
+	watcher, ok := h.router.(SessionWatcher)
+	// No else needed: early return pattern (guard clause)
+	if !ok {
+		apierror.Write(w, apierror.CodeNotImplemented, "Session watching is not supported")
+		return nil
+	}
+
+	// No else needed: early return pattern (guard clause)
+	if !h.allowConnection(w, claims.UserID) {
+		return nil
+	}
+
+	// Join the session before upgrading so an unknown session gets a plain 404
+	connection := h.createConnection(nil, claims)
+	// No else needed: early return pattern (guard clause)
+	if err := watcher.WatchSession(sessionID, connection); err != nil {
+		h.connLimiter.Release(claims.UserID)
+		var chatErr *chaterrors.ChatError
+		// No else needed: early return pattern (cross-tenant access)
+		if errors.As(err, &chatErr) && chatErr.Code == chaterrors.ErrCodeUnauthorized {
+			apierror.Write(w, apierror.CodeForbidden, "Session belongs to another tenant")
+			return nil
+		}
+		apierror.Write(w, apierror.CodeSessionNotFound, "Session not found")
+		return nil
+	}
+
+	conn, payloadBytes, err := h.upgrade(w, r)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		watcher.UnwatchSession(sessionID, connection)
+		h.connLimiter.Release(claims.UserID)
+		util.LogError(h.logger, "websocket", "upgrade watch connection", err)
+		return nil
+	}
+	conn.SetReadLimit(h.maxMessageSize)
+	connection.conn = conn
+	connection.payloadBytes = payloadBytes
+
+	h.registerConnection(connection)
+
+	h.logger.Info("Session watch connection established",
+		"admin_id", claims.UserID,
+		"session_id", sessionID,
+		"component", "websocket")
+
+	h.pumpWg.Add(2)
+	util.SafeGo(h.logger, "watchReadPump", func() {
+		defer h.pumpWg.Done()
+		connection.watchReadPump(h, watcher, sessionID)
+	})
+	util.SafeGo(h.logger, "writePump", func() {
+		defer h.pumpWg.Done()
+		connection.writePump()
+	})
+
+	status := &message.Message{
+		Type:      message.TypeConnectionStatus,
+		SessionID: sessionID,
+		Content:   "Watching session (read-only)",
+		Sender:    message.SenderSystem,
+		Timestamp: time.Now(),
+	}
+	// No else needed: optional operation (status is informational)
+	if data, err := json.Marshal(status); err == nil {
+		connection.SafeSend(data)
+	}
+	return claims
+}
+
+// watchReadPump keeps a watch connection alive by processing control frames.
+// Data frames are discarded; the watcher is removed when the connection closes.
+func (c *Connection) watchReadPump(h *Handler, watcher SessionWatcher, sessionID string) {
+	defer func() {
+		h.logger.Info("Session watch connection closed",
+			"admin_id", c.UserID,
+			"session_id", sessionID,
+			"component", "websocket")
+
+		// No else needed: optional operation (record duration if known)
+		if !c.connectedAt.IsZero() {
+			metrics.WebSocketConnectionDuration.Observe(time.Since(c.connectedAt).Seconds())
+		}
+
+		watcher.UnwatchSession(sessionID, c)
+		h.unregisterConnection(c)
+		c.Close()
+	}()
+
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		// No else needed: early return pattern (connection closed)
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleWatch_RequiresAdminRole(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	handler := NewHandler(auth.NewJWTValidator(secret), newMockRouter(), testLogger(), 1048576)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/sessions/s1/watch", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, secret, "user-1", []string{"user"}))
+	w := httptest.NewRecorder()
+	claims := handler.HandleWatch(w, req, "s1")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Nil(t, claims, "rejected watches return no claims")
+}
+
+func TestHandleWatch_RequiresViewPermission(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	handler := NewHandler(auth.NewJWTValidator(secret), newMockRouter(), testLogger(), 1048576)
+	policy, err := authz.NewPolicy(map[string][]authz.Permission{
+		"support": {authz.PermTakeover},
+		"auditor": {authz.PermViewSessions},
+	})
+	require.NoError(t, err)
+	handler.SetPolicy(policy)
+
+	watch := func(role string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/sessions/s1/watch", nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, secret, "admin-1", []string{role}))
+		w := httptest.NewRecorder()
+		handler.HandleWatch(w, req, "s1")
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, watch("support"))
+	assert.Equal(t, http.StatusNotImplemented, watch("auditor"), "passes the permission check")
+}
+
+func TestHandleWatch_RequiresAuthentication(t *testing.T) {
+	handler := NewHandler(auth.NewJWTValidator("test-secret-32-bytes-padding-ok!"), newMockRouter(), testLogger(), 1048576)
+
+	w := httptest.NewRecorder()
+	handler.HandleWatch(w, httptest.NewRequest(http.MethodGet, "/admin/sessions/s1/watch", nil), "s1")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandleWatch_RouterWithoutWatchSupport(t *testing.T) {
+	secret := "test-secret-32-bytes-padding-ok!"
+	handler := NewHandler(auth.NewJWTValidator(secret), newMockRouter(), testLogger(), 1048576)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/sessions/s1/watch", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, secret, "admin-1", []string{"admin"}))
+	w := httptest.NewRecorder()
+	handler.HandleWatch(w, req, "s1")
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,23 @@
+/*
+ * Chatbox widget loader. Embed with:
+ *   <script src="https://chat.example.com/chat/widget/loader.js" data-token-url="/chat-token" async></script>
+ * It loads the current widget release from the same server; the data-*
+ * attributes of this tag are passed on to the widget.
+ */
+(function () {
+  "use strict";
+  var loader = document.currentScript;
+  if (!loader || window.__chatboxWidgetLoader) {
+    return;
+  }
+  window.__chatboxWidgetLoader = true;
+
+  var widget = document.createElement("script");
+  // Replaced by the server with the path of the current versioned widget
+  widget.src = new URL("__CHATBOX_WIDGET_PATH__", loader.src).href;
+  widget.async = true;
+  for (var key in loader.dataset) {
+    widget.dataset[key] = loader.dataset[key];
+  }
+  (document.head || document.body).appendChild(widget);
+})();
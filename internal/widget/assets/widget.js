@@ -0,0 +1,245 @@
+/*
+ * Chatbox embeddable widget. Loaded by loader.js from a versioned path, e.g.
+ * /chat/widget/<version>/widget.js. Options are data-* attributes of the
+ * loader tag:
+ *   data-token-url  URL on the embedding site returning {"token": "..."}, e.g.
+ *                   a backend route that calls POST /chat/embed/token (required)
+ *   data-title      Title of the chat panel (default "Chat")
+ *   data-position   "right" (default) or "left"
+ * The page can open and close the panel with ChatboxWidget.open() and
+ * ChatboxWidget.close().
+ */
+(function () {
+  "use strict";
+  var script = document.currentScript;
+  if (!script || window.ChatboxWidget) {
+    return;
+  }
+
+  var options = script.dataset;
+  // The widget lives at <base>/widget/<version>/widget.js
+  var base = new URL("../../", script.src);
+  var wsBase = base.href.replace(/^http/, "ws");
+  var sessionEndedCode = 4000;
+  var maxReconnectDelay = 30000;
+
+  var styles =
+    ":host{all:initial;font-family:system-ui,sans-serif;font-size:14px;color:#1f2328}" +
+    ".launcher{position:fixed;bottom:20px;width:56px;height:56px;border:0;border-radius:50%;background:#0969da;color:#fff;font-size:24px;cursor:pointer;box-shadow:0 4px 12px rgba(0,0,0,.2);z-index:2147483646}" +
+    ".panel{position:fixed;bottom:88px;width:360px;max-width:calc(100vw - 40px);height:520px;max-height:calc(100vh - 120px);display:none;flex-direction:column;background:#fff;border-radius:12px;box-shadow:0 8px 24px rgba(0,0,0,.2);overflow:hidden;z-index:2147483647}" +
+    ".panel.open{display:flex}" +
+    ".header{padding:12px 16px;background:#0969da;color:#fff;font-weight:600;display:flex;justify-content:space-between;align-items:center}" +
+    ".header button{background:none;border:0;color:#fff;font-size:18px;cursor:pointer}" +
+    ".messages{flex:1;overflow-y:auto;padding:12px;display:flex;flex-direction:column;gap:8px}" +
+    ".msg{max-width:80%;padding:8px 12px;border-radius:12px;white-space:pre-wrap;word-wrap:break-word;background:#f6f8fa;align-self:flex-start}" +
+    ".msg.user{background:#ddf4ff;align-self:flex-end}" +
+    ".msg.admin{background:#fff8c5}" +
+    ".msg.system{background:none;color:#59636e;font-style:italic;align-self:center}" +
+    ".typing{color:#59636e;font-style:italic;padding:0 12px 8px;display:none}" +
+    ".typing.on{display:block}" +
+    "form{display:flex;border-top:1px solid #d0d7de}" +
+    "input{flex:1;border:0;padding:12px;font:inherit;outline:none}" +
+    "form button{border:0;background:none;color:#0969da;font-weight:600;padding:0 16px;cursor:pointer}";
+
+  var host = document.createElement("div");
+  var root = host.attachShadow({ mode: "open" });
+  var side = options.position === "left" ? "left:20px" : "right:20px";
+  root.innerHTML =
+    "<style>" + styles + ".launcher,.panel{" + side + "}</style>" +
+    '<button class="launcher" aria-label="Open chat">&#128172;</button>' +
+    '<div class="panel" role="dialog">' +
+    '<div class="header"><span class="title"></span><button class="close" aria-label="Close chat">&times;</button></div>' +
+    '<div class="messages" aria-live="polite"></div>' +
+    '<div class="typing">Typing&hellip;</div>' +
+    '<form><input type="text" placeholder="Type a message" aria-label="Message"><button type="submit">Send</button></form>' +
+    "</div>";
+
+  var panel = root.querySelector(".panel");
+  var messages = root.querySelector(".messages");
+  var typing = root.querySelector(".typing");
+  var input = root.querySelector("input");
+  root.querySelector(".title").textContent = options.title || "Chat";
+
+  var ws = null;
+  var connecting = false;
+  var sessionID = null;
+  var streaming = null;
+  var reconnectDelay = 1000;
+
+  function append(sender, text) {
+    var div = document.createElement("div");
+    div.className = "msg " + sender;
+    div.textContent = text;
+    messages.appendChild(div);
+    messages.scrollTop = messages.scrollHeight;
+    return div;
+  }
+
+  function appendStreaming(text) {
+    if (!text) {
+      return;
+    }
+    typing.classList.remove("on");
+    if (!streaming) {
+      streaming = append("ai", "");
+    }
+    streaming.textContent += text;
+    messages.scrollTop = messages.scrollHeight;
+  }
+
+  function onMessage(event) {
+    var msg;
+    try {
+      msg = JSON.parse(event.data);
+    } catch (e) {
+      return;
+    }
+    if (msg.session_id) {
+      sessionID = msg.session_id;
+    }
+    var meta = msg.metadata || {};
+    switch (msg.type) {
+      case "ai_response":
+        if (meta.streaming === "true") {
+          if (meta.consolidated === "true" && streaming) {
+            streaming.textContent = "";
+          }
+          appendStreaming(msg.content);
+          if (meta.done === "true") {
+            streaming = null;
+            typing.classList.remove("on");
+          }
+        } else {
+          typing.classList.remove("on");
+          append("ai", msg.content);
+        }
+        break;
+      case "ai_delta":
+        appendStreaming(msg.delta);
+        break;
+      case "admin_message":
+        append("admin", msg.content);
+        break;
+      case "loading":
+        typing.classList.add("on");
+        break;
+      case "error":
+        typing.classList.remove("on");
+        append("system", (msg.error && msg.error.message) || "Something went wrong.");
+        break;
+      case "session_update":
+        if (meta.ended === "true") {
+          append("system", msg.content || "This conversation has ended.");
+          sessionID = null;
+        }
+        break;
+    }
+  }
+
+  function fetchToken() {
+    if (!options.tokenUrl) {
+      return Promise.reject(new Error("chatbox widget: data-token-url is required"));
+    }
+    return fetch(options.tokenUrl, { credentials: "same-origin" })
+      .then(function (resp) {
+        if (!resp.ok) {
+          throw new Error("chatbox widget: token request failed with " + resp.status);
+        }
+        return resp.json();
+      })
+      .then(function (body) {
+        return body.token;
+      });
+  }
+
+  function connect() {
+    if (ws || connecting) {
+      return;
+    }
+    connecting = true;
+    fetchToken()
+      .then(function (token) {
+        connecting = false;
+        var url = wsBase + "ws?token=" + encodeURIComponent(token);
+        if (sessionID) {
+          url += "&session_id=" + encodeURIComponent(sessionID);
+        }
+        ws = new WebSocket(url);
+        ws.onopen = function () {
+          reconnectDelay = 1000;
+        };
+        ws.onmessage = onMessage;
+        ws.onclose = function (event) {
+          ws = null;
+          streaming = null;
+          typing.classList.remove("on");
+          // An admin ended the session; reconnect only when the user writes again
+          if (event.code === sessionEndedCode) {
+            sessionID = null;
+            return;
+          }
+          scheduleReconnect();
+        };
+      })
+      .catch(function (err) {
+        connecting = false;
+        console.warn(err.message);
+        scheduleReconnect();
+      });
+  }
+
+  function scheduleReconnect() {
+    setTimeout(connect, reconnectDelay);
+    reconnectDelay = Math.min(reconnectDelay * 2, maxReconnectDelay);
+  }
+
+  function send(content) {
+    if (!ws || ws.readyState !== WebSocket.OPEN) {
+      connect();
+      append("system", "Connecting, please try again in a moment.");
+      return;
+    }
+    streaming = null;
+    var msg = {
+      type: "user_message",
+      content: content,
+      sender: "user",
+      timestamp: new Date().toISOString(),
+    };
+    if (sessionID) {
+      msg.session_id = sessionID;
+    }
+    ws.send(JSON.stringify(msg));
+    append("user", content);
+  }
+
+  function open() {
+    panel.classList.add("open");
+    connect();
+    input.focus();
+  }
+
+  function close() {
+    panel.classList.remove("open");
+  }
+
+  root.querySelector(".launcher").addEventListener("click", function () {
+    if (panel.classList.contains("open")) {
+      close();
+    } else {
+      open();
+    }
+  });
+  root.querySelector(".close").addEventListener("click", close);
+  root.querySelector("form").addEventListener("submit", function (event) {
+    event.preventDefault();
+    var content = input.value.trim();
+    if (content) {
+      send(content);
+      input.value = "";
+    }
+  });
+
+  window.ChatboxWidget = { open: open, close: close };
+  (document.body || document.documentElement).appendChild(host);
+})();
@@ -0,0 +1,94 @@
+// Package widget holds the embeddable chat widget, built into the binary so
+// customers can load it from the chatbox server instead of a separate CDN.
+//
+// The widget script is served under a path containing its version, a hash of
+// its content, so browsers and proxies may cache it forever. The small loader
+// script is served at a fixed path with a short cache lifetime and loads the
+// current versioned widget, so pages embedding the loader pick up new releases.
+package widget
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+)
+
+// Files of the widget
+const (
+	FileWidget = "widget.js" // the widget, served under its version
+	FileLoader = "loader.js" // the loader snippet, served at a fixed path
+
+	// ContentType is the content type of both files
+	ContentType = "text/javascript; charset=utf-8"
+)
+
+// loaderPlaceholder is replaced in loader.js with the path of the versioned
+// widget, relative to the loader
+const loaderPlaceholder = "__CHATBOX_WIDGET_PATH__"
+
+// versionLength is the number of hex digits of the content hash used as version
+const versionLength = 12
+
+// ErrNotFound is returned for files and versions the bundle does not serve
+var ErrNotFound = errors.New("widget asset not found")
+
+//go:embed assets/widget.js assets/loader.js
+var assets embed.FS
+
+// Bundle is the widget release built into the binary
+type Bundle struct {
+	version string
+	widget  []byte
+	loader  []byte
+}
+
+// Load reads the widget built into the binary. Its version is derived from
+// the content of the widget, so every change gets a new cache-busting path.
+func Load() (*Bundle, error) {
+	widget, err := assets.ReadFile("assets/" + FileWidget)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FileWidget, err)
+	}
+	loader, err := assets.ReadFile("assets/" + FileLoader)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FileLoader, err)
+	}
+	sum := sha256.Sum256(widget)
+	version := hex.EncodeToString(sum[:])[:versionLength]
+
+	// The loader sits at widget/loader.js, next to the version directories
+	loader = bytes.Replace(loader, []byte(loaderPlaceholder), []byte(path.Join(version, FileWidget)), 1)
+	return &Bundle{version: version, widget: widget, loader: loader}, nil
+}
+
+// Version returns the version of the widget
+func (b *Bundle) Version() string {
+	return b.version
+}
+
+// Asset returns a versioned file of the widget. Only the bundle's own version
+// is served; older versions return ErrNotFound so clients reload the loader.
+func (b *Bundle) Asset(version, name string) ([]byte, error) {
+	// No else needed: early return pattern (guard clause)
+	if version != b.version || name != FileWidget {
+		return nil, ErrNotFound
+	}
+	return b.widget, nil
+}
+
+// Loader returns the loader script, which loads the current versioned widget
+// from the server it was loaded from
+func (b *Bundle) Loader() []byte {
+	return b.loader
+}
+
+// ETag returns the entity tag of the loader or of the versioned widget
+func (b *Bundle) ETag(name string) string {
+	return `"` + b.version + "-" + name + `"`
+}
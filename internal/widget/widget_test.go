@@ -0,0 +1,40 @@
+package widget
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	bundle, err := Load()
+	require.NoError(t, err)
+	assert.Len(t, bundle.Version(), versionLength)
+
+	again, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, bundle.Version(), again.Version(), "the version depends on the content only")
+
+	loader := string(bundle.Loader())
+	assert.NotContains(t, loader, loaderPlaceholder)
+	assert.Contains(t, loader, `"`+bundle.Version()+"/widget.js"+`"`)
+}
+
+func TestAsset(t *testing.T) {
+	bundle, err := Load()
+	require.NoError(t, err)
+
+	content, err := bundle.Asset(bundle.Version(), FileWidget)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "ChatboxWidget")
+
+	_, err = bundle.Asset("0123456789ab", FileWidget)
+	assert.ErrorIs(t, err, ErrNotFound, "older versions are not served")
+	_, err = bundle.Asset(bundle.Version(), FileLoader)
+	assert.ErrorIs(t, err, ErrNotFound, "the loader is not versioned")
+	_, err = bundle.Asset(bundle.Version(), "../widget.go")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NotEqual(t, bundle.ETag(FileWidget), bundle.ETag(FileLoader))
+}
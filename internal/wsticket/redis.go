@@ -0,0 +1,68 @@
+package wsticket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps tickets in Redis, so a ticket issued by one replica can be
+// redeemed on any other. Tickets expire with their keys, and GETDEL makes
+// redeeming atomic: of two connections racing with the same ticket, one wins.
+type RedisStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+// NewRedisStore creates a Redis-backed store issuing tickets valid for ttl
+func NewRedisStore(client redis.UniversalClient, ttl time.Duration) *RedisStore {
+	return &RedisStore{client: client, ttl: ttl}
+}
+
+// Issue creates a ticket for claims
+func (s *RedisStore) Issue(ctx context.Context, claims *auth.Claims) (string, time.Time, error) {
+	data, err := json.Marshal(claims)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal ticket claims: %w", err)
+	}
+
+	ticket := newTicket()
+	expiresAt := time.Now().Add(s.ttl)
+	// No else needed: early return pattern (guard clause)
+	if err := s.client.Set(ctx, constants.WSTicketKeyPrefix+ticket, data, s.ttl).Err(); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to store ticket: %w", err)
+	}
+
+	metrics.WSTickets.WithLabelValues("issued").Inc()
+	return ticket, expiresAt, nil
+}
+
+// Redeem returns the claims of ticket and deletes it
+func (s *RedisStore) Redeem(ctx context.Context, ticket string) (*auth.Claims, error) {
+	data, err := s.client.GetDel(ctx, constants.WSTicketKeyPrefix+ticket).Bytes()
+	// No else needed: early return pattern (unknown, expired or used ticket)
+	if errors.Is(err, redis.Nil) {
+		metrics.WSTickets.WithLabelValues("rejected").Inc()
+		return nil, ErrInvalidTicket
+	}
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem ticket: %w", err)
+	}
+
+	var claims auth.Claims
+	// No else needed: early return pattern (guard clause)
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ticket claims: %w", err)
+	}
+	metrics.WSTickets.WithLabelValues("redeemed").Inc()
+	return &claims, nil
+}
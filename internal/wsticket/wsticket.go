@@ -0,0 +1,108 @@
+// Package wsticket issues one-time tickets that authenticate WebSocket and SSE
+// connections. Browsers cannot set headers on those requests, so without
+// tickets clients pass their JWT in the URL, where access logs and proxies
+// record it.
+//
+// A client exchanges its JWT for a ticket over an authenticated HTTP request
+// and connects with ?ticket=. The ticket carries the JWT's claims, expires
+// after a short TTL, and is deleted when redeemed, so a ticket seen in a log
+// cannot be replayed.
+package wsticket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/metrics"
+)
+
+// ErrInvalidTicket is returned when redeeming an unknown, expired or already used ticket
+var ErrInvalidTicket = errors.New("invalid, expired or already used ticket")
+
+// Store issues and redeems tickets. Implementations must be safe for concurrent use.
+type Store interface {
+	// Issue creates a ticket for claims and returns it with its expiry
+	Issue(ctx context.Context, claims *auth.Claims) (string, time.Time, error)
+	// Redeem returns the claims of ticket and invalidates it
+	Redeem(ctx context.Context, ticket string) (*auth.Claims, error)
+}
+
+// newTicket returns a random URL-safe ticket
+func newTicket() string {
+	b := make([]byte, constants.WSTicketBytes)
+	// crypto/rand.Read never returns an error on supported platforms
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// memoryTicket is a ticket held by MemoryStore
+type memoryTicket struct {
+	claims    auth.Claims
+	expiresAt time.Time
+}
+
+// MemoryStore keeps tickets in process memory. Tickets can only be redeemed
+// on the replica that issued them; use RedisStore behind a load balancer
+// without session affinity.
+type MemoryStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	tickets   map[string]memoryTicket
+	lastSweep time.Time
+	now       func() time.Time // Replaced in tests
+}
+
+// NewMemoryStore creates an in-memory store issuing tickets valid for ttl
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:     ttl,
+		tickets: make(map[string]memoryTicket),
+		now:     time.Now,
+	}
+}
+
+// Issue creates a ticket for claims
+func (s *MemoryStore) Issue(_ context.Context, claims *auth.Claims) (string, time.Time, error) {
+	ticket := newTicket()
+	now := s.now()
+	expiresAt := now.Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// No else needed: optional operation (drop unredeemed tickets once per TTL)
+	if now.Sub(s.lastSweep) >= s.ttl {
+		for t, held := range s.tickets {
+			// No else needed: optional operation (only expired tickets)
+			if !now.Before(held.expiresAt) {
+				delete(s.tickets, t)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.tickets[ticket] = memoryTicket{claims: *claims, expiresAt: expiresAt}
+
+	metrics.WSTickets.WithLabelValues("issued").Inc()
+	return ticket, expiresAt, nil
+}
+
+// Redeem returns the claims of ticket and deletes it
+func (s *MemoryStore) Redeem(_ context.Context, ticket string) (*auth.Claims, error) {
+	s.mu.Lock()
+	held, ok := s.tickets[ticket]
+	delete(s.tickets, ticket)
+	s.mu.Unlock()
+
+	// No else needed: early return pattern (unknown or expired ticket)
+	if !ok || !s.now().Before(held.expiresAt) {
+		metrics.WSTickets.WithLabelValues("rejected").Inc()
+		return nil, ErrInvalidTicket
+	}
+	metrics.WSTickets.WithLabelValues("redeemed").Inc()
+	return &held.claims, nil
+}
@@ -0,0 +1,122 @@
+package wsticket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/real-rm/chatbox/internal/auth"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testClaims() *auth.Claims {
+	return &auth.Claims{UserID: "user-1", Name: "Jane", Roles: []string{"user"}, TenantID: "acme", Origin: "https://partner.example"}
+}
+
+func newTestRedisStore(t *testing.T, ttl time.Duration) (*miniredis.Miniredis, *RedisStore) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, NewRedisStore(client, ttl)
+}
+
+func TestStores_RedeemOnce(t *testing.T) {
+	_, redisStore := newTestRedisStore(t, time.Minute)
+	stores := map[string]Store{
+		"memory": NewMemoryStore(time.Minute),
+		"redis":  redisStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			ticket, expiresAt, err := store.Issue(ctx, testClaims())
+			require.NoError(t, err)
+			assert.Len(t, ticket, 43, "32 random bytes, base64url")
+			assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, time.Second)
+
+			claims, err := store.Redeem(ctx, ticket)
+			require.NoError(t, err)
+			assert.Equal(t, testClaims(), claims)
+
+			// A ticket opens a single connection
+			_, err = store.Redeem(ctx, ticket)
+			assert.ErrorIs(t, err, ErrInvalidTicket)
+			_, err = store.Redeem(ctx, "unknown")
+			assert.ErrorIs(t, err, ErrInvalidTicket)
+		})
+	}
+}
+
+func TestStores_ConcurrentRedeem(t *testing.T) {
+	_, redisStore := newTestRedisStore(t, time.Minute)
+	for name, store := range map[string]Store{"memory": NewMemoryStore(time.Minute), "redis": redisStore} {
+		t.Run(name, func(t *testing.T) {
+			ticket, _, err := store.Issue(context.Background(), testClaims())
+			require.NoError(t, err)
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			redeemed := 0
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := store.Redeem(context.Background(), ticket); err == nil {
+						mu.Lock()
+						redeemed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, 1, redeemed)
+		})
+	}
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	store := NewMemoryStore(30 * time.Second)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	expired, _, err := store.Issue(context.Background(), testClaims())
+	require.NoError(t, err)
+	unused, _, err := store.Issue(context.Background(), testClaims())
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Second)
+	_, err = store.Redeem(context.Background(), expired)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+
+	// Issuing sweeps tickets that expired unredeemed
+	_, _, err = store.Issue(context.Background(), testClaims())
+	require.NoError(t, err)
+	assert.NotContains(t, store.tickets, unused)
+	assert.Len(t, store.tickets, 1)
+}
+
+func TestRedisStore_Expiry(t *testing.T) {
+	mr, store := newTestRedisStore(t, 30*time.Second)
+	ticket, _, err := store.Issue(context.Background(), testClaims())
+	require.NoError(t, err)
+
+	mr.FastForward(30 * time.Second)
+	_, err = store.Redeem(context.Background(), ticket)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+}
+
+func TestRedisStore_Unavailable(t *testing.T) {
+	mr, store := newTestRedisStore(t, time.Minute)
+	mr.Close()
+
+	_, _, err := store.Issue(context.Background(), testClaims())
+	assert.Error(t, err)
+	_, err = store.Redeem(context.Background(), "ticket")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidTicket, "outages are not reported as bad tickets")
+}
@@ -0,0 +1,757 @@
+package chatbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/alerts"
+	"github.com/real-rm/chatbox/internal/apierror"
+	"github.com/real-rm/chatbox/internal/authz"
+	"github.com/real-rm/chatbox/internal/backup"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/experiment"
+	"github.com/real-rm/chatbox/internal/health"
+	"github.com/real-rm/chatbox/internal/jobs"
+	"github.com/real-rm/chatbox/internal/memory"
+	"github.com/real-rm/chatbox/internal/openapi"
+	"github.com/real-rm/chatbox/internal/prompt"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/readonly"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/storage"
+	"github.com/real-rm/chatbox/internal/websocket"
+)
+
+// OpenAPI tags of the route table
+const (
+	tagStreaming = "streaming"
+	tagSessions  = "sessions"
+	tagPublic    = "public"
+	tagAdmin     = "admin"
+	tagUsers     = "users"
+	tagHealth    = "health"
+)
+
+// Error statuses shared by many routes
+var (
+	errNotFound     = []int{http.StatusBadRequest, http.StatusNotFound}
+	errUserWrite    = []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusServiceUnavailable}
+	errPublicLimits = []int{http.StatusTooManyRequests}
+)
+
+// query returns a query parameter of the given schema
+func query(name string, schema *openapi.Schema, description string) openapi.Param {
+	return openapi.Param{Name: name, Schema: schema, Description: description}
+}
+
+// timeRangeSchema is the time_range object of the metrics and costs reports
+func timeRangeSchema() *openapi.Schema {
+	return openapi.Object(map[string]interface{}{"start": openapi.DateTime(), "end": openapi.DateTime()})
+}
+
+// apiRoutes is the route table of the REST API, relative to the path prefix.
+// Every route registered in Register is documented here;
+// TestOpenAPI_DocumentsRegisteredRoutes keeps the two in step.
+func apiRoutes() []openapi.Route {
+	status := func(values ...string) *openapi.Schema { return openapi.String(values...) }
+	return []openapi.Route{
+		// WebSocket and Server-Sent Events transports
+		{
+			Method: http.MethodGet, Path: "/ws", Tag: tagStreaming, Auth: openapi.AuthUser, Status: http.StatusSwitchingProtocols,
+			Summary:     "Open the chat WebSocket",
+			Description: "Upgrades to the WebSocket chat protocol. Browsers that cannot set headers pass a one-time ticket from `POST /ws/ticket` as `?ticket=`, or the JWT as `?token=`.",
+			Query:       []openapi.Param{query("ticket", nil, "One-time ticket from POST /ws/ticket"), query("token", nil, "JWT, when the Authorization header cannot be set"), query("capabilities", nil, "Comma-separated protocol capabilities, e.g. delta")},
+			Errors:      []int{http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodGet, Path: "/sse", Tag: tagStreaming, Auth: openapi.AuthUser, Files: []string{"text/event-stream"},
+			Summary:     "Open the chat event stream",
+			Description: "Server-Sent Events transport for networks that block WebSockets; client messages are sent with `POST /sse/messages`.",
+			Query:       []openapi.Param{query("ticket", nil, "One-time ticket from POST /ws/ticket"), query("token", nil, "JWT, when the Authorization header cannot be set"), query("capabilities", nil, "Comma-separated protocol capabilities, e.g. delta")},
+			Errors:      []int{http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodPost, Path: "/ws/ticket", Tag: tagStreaming, Auth: openapi.AuthUser,
+			Summary:     "Get a one-time connection ticket",
+			Description: "Exchanges the JWT for a ticket that opens one WebSocket or SSE connection as `?ticket=` before it expires, keeping the JWT out of URLs.",
+			Response:    wsTicketResponse{},
+		},
+		{
+			Method: http.MethodPost, Path: "/sse/messages", Tag: tagStreaming, Auth: openapi.AuthUser, Status: http.StatusAccepted,
+			Summary:     "Send a message on an event stream",
+			Description: "Takes a chat protocol message, as sent over the WebSocket; replies and errors are delivered on the stream.",
+			Query:       []openapi.Param{{Name: "connection_id", Required: true, Description: "Connection ID announced on the open stream"}},
+			Request:     openapi.Object(map[string]interface{}{"type": "", "session_id": "", "content": ""}),
+			Response:    openapi.Object(map[string]interface{}{"status": openapi.String("accepted")}),
+			Errors:      []int{http.StatusNotFound, http.StatusRequestEntityTooLarge},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/sessions/:sessionID/watch", Tag: tagStreaming, Auth: openapi.AuthAdminJWT, Status: http.StatusSwitchingProtocols,
+			Summary:     "Watch a live session",
+			Description: "WebSocket that streams a live session's messages read-only, without taking it over.",
+			Permission:  string(authz.PermViewSessions),
+			Query:       []openapi.Param{query("token", nil, "Admin JWT, when the Authorization header cannot be set")},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/metrics/stream", Tag: tagStreaming, Auth: openapi.AuthAdminJWT, Status: http.StatusSwitchingProtocols,
+			Summary:     "Stream live metrics",
+			Description: "WebSocket that pushes the replica's live counters every 5 seconds.",
+			Permission:  string(authz.PermViewSessions),
+			Query:       []openapi.Param{query("token", nil, "Admin JWT, when the Authorization header cannot be set")},
+		},
+
+		// User session endpoints
+		{
+			Method: http.MethodGet, Path: "/models", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "List the available models",
+			Response: openapi.Object(map[string]interface{}{"models": []modelCatalogEntry{}}),
+		},
+		{
+			Method: http.MethodGet, Path: "/sessions", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary: "List the caller's sessions",
+			Response: openapi.Object(map[string]interface{}{
+				"sessions": []*storage.SessionMetadata{}, "user_id": "", "count": 0, "limit": 0, "truncated": false, "anonymized": false,
+			}),
+		},
+		{
+			Method: http.MethodPost, Path: "/sessions/claim", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Claim the sessions of a guest token",
+			Request:  claimGuestSessionsRequest{},
+			Response: openapi.Object(map[string]interface{}{"claimed": 0, "session_ids": []string{}}),
+			Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodGet, Path: "/sessions/shared", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "List the sessions other users shared with the caller",
+			Response: openapi.Object(map[string]interface{}{"sessions": []*storage.SharedSessionMetadata{}, "count": 0, "limit": 0, "truncated": false}),
+			Errors:   []int{http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodGet, Path: "/sessions/:sessionID", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:     "Get a session's messages",
+			Description: "Works for the caller's sessions and sessions other users shared with them.",
+			Response:    openapi.Object(map[string]interface{}{"session_id": "", "name": "", "model_id": "", "pinned": []session.PinnedMessage{}, "messages": []*session.Message{}}),
+			Errors:      []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPatch, Path: "/sessions/:sessionID", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Rename a session",
+			Request:  renameSessionRequest{},
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "name": ""}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodDelete, Path: "/sessions/:sessionID", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "End and delete a session",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "status": status("deleted")}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodPost, Path: "/sessions/:sessionID/end", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "End a session, optionally emailing its transcript",
+			Request:  endSessionRequest{},
+			Response: openapi.Object(map[string]interface{}{"status": status("ended"), "transcript_email": status("queued", "already_sent", "unavailable")}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodPost, Path: "/sessions/:sessionID/share", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Create or extend a read-only share link",
+			Request:  shareSessionRequest{},
+			Response: storage.ShareLink{},
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodDelete, Path: "/sessions/:sessionID/share", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Revoke a session's share link",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "status": status("revoked")}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodGet, Path: "/sessions/:sessionID/grants", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "List the users a session is shared with",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "grants": []storage.SessionGrant{}}),
+			Errors:   []int{http.StatusNotFound, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodPut, Path: "/sessions/:sessionID/grants/:userID", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary: "Share a session with another user",
+			Description: "Grants a user of the caller's tenant `read` access to the session's messages, or `write` access to also rename, " +
+				"tag and pin it and rate its messages. Granting again replaces the user's access. Only the owner manages grants.",
+			Request:  grantSessionAccessRequest{},
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "grants": []storage.SessionGrant{}}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodDelete, Path: "/sessions/:sessionID/grants/:userID", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Revoke a user's access to a session",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "grants": []storage.SessionGrant{}}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodPost, Path: "/sessions/:sessionID/fork", Tag: tagSessions, Auth: openapi.AuthUser, Status: http.StatusCreated,
+			Summary:  "Fork a session",
+			Request:  forkSessionRequest{},
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "forked_from": "", "message_count": 0}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodPost, Path: "/sessions/:sessionID/merge/:sourceID", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Merge a duplicate session into an ended one",
+			Response: storage.MergeResult{},
+			Errors:   append([]int{http.StatusConflict}, errUserWrite...),
+		},
+		{
+			Method: http.MethodPost, Path: "/sessions/:sessionID/snapshot", Tag: tagSessions, Auth: openapi.AuthUser, Status: http.StatusCreated,
+			Summary:  "Create a snapshot of a session",
+			Request:  snapshotRequest{},
+			Response: storage.Snapshot{},
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodGet, Path: "/sessions/:sessionID/snapshots", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "List a session's snapshots",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "snapshots": []*storage.Snapshot{}}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodPost, Path: "/snapshots/:snapshotID/restore", Tag: tagSessions, Auth: openapi.AuthUser, Status: http.StatusCreated,
+			Summary:  "Restore a snapshot as a new session",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "restored_from": "", "message_count": 0}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodPost, Path: "/sessions/:sessionID/tags", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Tag a session",
+			Request:  sessionTagsRequest{},
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "tags": []string{}}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodDelete, Path: "/sessions/:sessionID/tags/:tag", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Remove a session tag",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "tags": []string{}}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodPut, Path: "/sessions/:sessionID/messages/:index/feedback", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Rate an AI message",
+			Request:  messageFeedbackRequest{},
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "message_index": 0, "feedback": session.Feedback{}}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodPost, Path: "/sessions/:sessionID/messages/:index/pin", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Pin a message",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "pins": []session.Pin{}}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodDelete, Path: "/sessions/:sessionID/messages/:index/pin", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Unpin a message",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "pins": []session.Pin{}}),
+			Errors:   errUserWrite,
+		},
+		{
+			Method: http.MethodGet, Path: "/memory", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:     "List what is remembered about the caller",
+			Description: "Facts the caller stated about themselves in earlier sessions, oldest first, offered to prompt templates as {{user_memory}}.",
+			Response:    openapi.Object(map[string]interface{}{"facts": []*memory.Fact{}, "count": 0}),
+		},
+		{
+			Method: http.MethodDelete, Path: "/memory", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Forget everything remembered about the caller",
+			Response: openapi.Object(map[string]interface{}{"status": "", "facts_deleted": 0}),
+			Errors:   []int{http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodDelete, Path: "/memory/:factID", Tag: tagSessions, Auth: openapi.AuthUser,
+			Summary:  "Forget one fact remembered about the caller",
+			Response: openapi.Object(map[string]interface{}{"id": "", "status": ""}),
+			Errors:   []int{http.StatusNotFound, http.StatusServiceUnavailable},
+		},
+
+		// Public endpoints
+		{
+			Method: http.MethodPost, Path: "/embed/token", Tag: tagPublic,
+			Summary:     "Mint an embed token for a partner site",
+			Description: "Authenticated with the partner's API key. Only served when embedding is configured.",
+			Request:     embedTokenRequest{},
+			Response:    openapi.Object(map[string]interface{}{"token": "", "user_id": "", "expires_at": openapi.DateTime()}),
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests},
+		},
+		{
+			Method: http.MethodGet, Path: "/shared/:shareToken", Tag: tagPublic,
+			Summary:  "Get a shared session (an HTML transcript when text/html is accepted)",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "name": "", "messages": []*session.Message{}, "expires_at": openapi.DateTime()}),
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests},
+		},
+
+		// Admin endpoints
+		{
+			Method: http.MethodGet, Path: "/admin/permissions", Tag: tagAdmin, Auth: openapi.AuthAdmin,
+			Summary:  "Get the caller's roles and permissions",
+			Response: openapi.Object(map[string]interface{}{"roles": []string{}, "permissions": []authz.Permission{}}),
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/takeover/:sessionID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermTakeover),
+			Summary:     "Take over a session",
+			Description: "Returns 409 with the current holder and the caller's queue position while another admin holds the session.",
+			Response:    openapi.Object(map[string]interface{}{"message": "", "session_id": "", "admin_id": ""}),
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/handback/:sessionID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermTakeover),
+			Summary:  "Hand a session back to the AI",
+			Response: openapi.Object(map[string]interface{}{"message": "", "session_id": "", "admin_id": ""}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/sessions/:sessionID/messages", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermBroadcast), Status: http.StatusCreated,
+			Summary:  "Send an admin message into a session",
+			Request:  adminMessageRequest{},
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "admin_id": "", "content": "", "seq": uint64(0), "timestamp": openapi.DateTime()}),
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/drain", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermBroadcast),
+			Summary: "Drain the replica before shutdown",
+			Query:   []openapi.Param{query("countdown", openapi.Integer(), "Seconds until clients should reconnect")},
+			Response: openapi.Object(map[string]interface{}{
+				"status": status("draining"), "countdown_seconds": 0, "deadline": openapi.DateTime(), "connections_notified": 0,
+			}),
+			Errors: []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/ip-stats", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "List the IPs with the most connection attempts",
+			Query:    []openapi.Param{query("limit", openapi.Integer(), "Number of IPs, 20 by default")},
+			Response: openapi.Object(map[string]interface{}{"ips": []ratelimit.IPStats{}, "count": 0}),
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/ip-bans", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "List the active IP bans",
+			Response: openapi.Object(map[string]interface{}{"bans": []ratelimit.IPBan{}, "count": 0}),
+		},
+		{
+			Method: http.MethodPut, Path: "/admin/ip-bans/:ip", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Ban a client IP",
+			Request:  banIPRequest{},
+			Response: ratelimit.IPBan{},
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/ip-bans/:ip", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Lift an IP ban",
+			Response: openapi.Object(map[string]interface{}{"ip": "", "status": status("unbanned")}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/connections", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "List the replica's connections",
+			Response: openapi.Object(map[string]interface{}{"connections": []websocket.ConnectionInfo{}, "count": 0}),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/help-queue", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "List the replica's sessions waiting for an admin, highest priority first",
+			Response: openapi.Object(map[string]interface{}{"queue": []helpQueueEntry{}, "count": 0}),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/jobs", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:     "List the background jobs with their recent runs",
+			Description: "Requires the super_admin role. `running` covers this replica only; `recent_runs` lists the latest runs on any replica, newest first.",
+			Response:    openapi.Object(map[string]interface{}{"jobs": []jobs.Status{}}),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/alerts", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:     "List the usage alert rules with their state",
+			Description: "Requires the super_admin role. Rules come from `[chatbox.alerts]` and are evaluated by each replica; the list is this replica's view. `firing` counts the rules in state `firing`.",
+			Response:    openapi.Object(map[string]interface{}{"alerts": []alerts.Alert{}, "firing": 0}),
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/connections/:connectionID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Force-close a connection",
+			Response: openapi.Object(map[string]interface{}{"connection": websocket.ConnectionInfo{}, "status": status("closed")}),
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/impersonate/:userID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermImpersonate),
+			Summary:  "Mint a token to act as a user",
+			Request:  impersonateRequest{},
+			Response: openapi.Object(map[string]interface{}{"token": "", "user_id": "", "impersonator_id": "", "expires_at": openapi.DateTime()}),
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/readonly", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "Get the read-only mode",
+			Response: readonly.State{},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/readonly", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:     "Turn read-only mode on or off",
+			Description: "`persisted` is false when the mode could not be stored and only applies to this replica.",
+			Request:     setReadOnlyRequest{},
+			Response: openapi.Object(map[string]interface{}{
+				"enabled": false, "message": "", "updated_by": "", "updated_at": openapi.DateTime(), "persisted": false,
+			}),
+			Errors: []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/reload", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary: "Reload the configuration",
+			Description: "Re-reads the configuration and applies the WebSocket allowed origins, the admin and guest rate limits, the limits of the " +
+				"`[chatbox.rate_limits]` policies and the model catalog to this replica without dropping connections. Every section is " +
+				"validated first, so an invalid configuration changes nothing. Other settings, including adding or removing rate limit " +
+				"policies and LLM providers, still require a restart. Sending the server SIGHUP does the same.",
+			Response: openapi.Object(map[string]interface{}{"reloaded": []string{}}),
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/sessions", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary: "List sessions",
+			Query: []openapi.Param{
+				query("user_id", nil, ""),
+				query("status", openapi.String("active", "ended"), ""),
+				query("admin_assisted", openapi.Boolean(), ""),
+				query("tags", nil, "Comma-separated tags every session must carry"),
+				query("app_version", nil, "Client SDK app version"),
+				query("platform", nil, "Client platform"),
+				query("start_time_from", openapi.DateTime(), ""),
+				query("start_time_to", openapi.DateTime(), ""),
+				query("sort_by", openapi.String("start_time", "end_time", "message_count", "total_tokens", "user_id"), ""),
+				query("sort_order", openapi.String("asc", "desc"), ""),
+				query("limit", openapi.Integer(), ""),
+				query("offset", openapi.Integer(), ""),
+				query("after", nil, "next_cursor of the previous page"),
+				query("tenant_id", nil, "Super admins: only this tenant's sessions, read from its datastore"),
+			},
+			Response: openapi.Object(map[string]interface{}{
+				"sessions": []*storage.SessionMetadata{}, "count": 0, "limit": 0, "offset": 0, "next_cursor": "",
+			}),
+			Errors: []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/sessions/search", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "Search message content",
+			Query:    []openapi.Param{{Name: "q", Required: true, Description: "Words every session must contain"}, query("limit", openapi.Integer(), ""), query("tenant_id", nil, "Super admins: only this tenant's sessions, read from its datastore")},
+			Response: openapi.Object(map[string]interface{}{"query": "", "results": []*storage.SearchResult{}, "count": 0}),
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/metrics", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary: "Get session metrics",
+			Query: []openapi.Param{
+				query("start_time", openapi.DateTime(), ""),
+				query("end_time", openapi.DateTime(), ""),
+				query("format", openapi.String("json", "csv"), ""),
+				query("tenant_id", nil, "Super admins: only this tenant's sessions, read from its datastore"),
+			},
+			Response: openapi.Object(map[string]interface{}{"metrics": storage.Metrics{}, "latency": storage.LatencyBreakdown{}, "time_range": timeRangeSchema()}),
+			Files:    []string{"text/csv"},
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/costs", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary: "Report LLM usage and cost",
+			Query: []openapi.Param{
+				query("group_by", openapi.String(constants.CostGroupByTenant, constants.CostGroupByModel, constants.CostGroupByDay), ""),
+				query("start_time", openapi.DateTime(), ""),
+				query("end_time", openapi.DateTime(), ""),
+			},
+			Response: openapi.Object(map[string]interface{}{
+				"group_by": "", "currency": "", "groups": []*storage.CostGroup{}, "total_tokens": int64(0), "total_cost": 0.0, "time_range": timeRangeSchema(),
+			}),
+			Errors: []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/sessions/:sessionID/export", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermExport),
+			Summary: "Download a session transcript",
+			Query:   []openapi.Param{query("format", openapi.String("json", "csv", "md"), "")},
+			Files:   []string{"application/json", "text/csv", "text/markdown"},
+			Errors:  errNotFound,
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/sessions/:sessionID/restore", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermPurge),
+			Summary:  "Restore a soft-deleted session",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "status": status("restored")}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/sessions/:sessionID/tags", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Tag any session",
+			Request:  sessionTagsRequest{},
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "tags": []string{}}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/sessions/:sessionID/tags/:tag", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Remove a tag of any session",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "tags": []string{}}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/sessions/:sessionID/end", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:     "Force-end a session",
+			Description: "Ends an active session with a reason code (`abuse`, `stale` or `duplicate`). The client receives a `session_update` with `ended` and `end_reason`, then its WebSocket is closed with code 4000 and the reason code as close reason. The reason is kept on the session and counted in the session metrics.",
+			Request:     forceEndSessionRequest{},
+			Response:    openapi.Object(map[string]interface{}{"status": "", "session_id": "", "reason": "", "closed_connections": 0}),
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/sessions/:sessionID/messages/:index/pin", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Pin a message of any session",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "pins": []session.Pin{}}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/sessions/:sessionID/messages/:index/pin", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Unpin a message of any session",
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "pins": []session.Pin{}}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/migrations", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:     "Dry run the session schema migrations",
+			Description: "Requires the super_admin role.",
+			Response:    storage.MigrationReport{},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/migrations", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:     "Apply the session schema migrations",
+			Description: "Requires the super_admin role.",
+			Response:    storage.MigrationReport{},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/backups", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermExport),
+			Summary:     "List the replica's latest session backups, newest first",
+			Description: "Requires the super_admin role. Only registered when chatbox.backup is enabled.",
+			Response:    openapi.Object(map[string]interface{}{"backups": []backup.Job{}, "count": 0}),
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/backups", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermExport), Status: http.StatusAccepted,
+			Summary:     "Start an encrypted backup of every session",
+			Description: "Requires the super_admin role. The backup runs in the background; poll GET /admin/backups for its outcome. Only registered when chatbox.backup is enabled.",
+			Response:    backup.Job{},
+			Errors:      []int{http.StatusConflict},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/audit", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary: "List the admin audit log",
+			Query: []openapi.Param{
+				query("actor_id", nil, ""),
+				query("session_id", nil, ""),
+				query("action", nil, ""),
+				query("from", openapi.DateTime(), ""),
+				query("to", openapi.DateTime(), ""),
+				query("limit", openapi.Integer(), ""),
+				query("offset", openapi.Integer(), ""),
+			},
+			Response: openapi.Object(map[string]interface{}{"entries": []*storage.AuditEntry{}, "count": 0, "limit": 0, "offset": 0}),
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/prompts", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "List system prompt templates",
+			Response: openapi.Object(map[string]interface{}{"templates": []*prompt.Template{}, "count": 0}),
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/prompts", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage), Status: http.StatusCreated,
+			Summary:  "Create a system prompt template",
+			Request:  promptTemplateRequest{},
+			Response: prompt.Template{},
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/prompts/:templateID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "Get a system prompt template",
+			Response: prompt.Template{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPut, Path: "/admin/prompts/:templateID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Replace a system prompt template",
+			Request:  promptTemplateRequest{},
+			Response: prompt.Template{},
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/prompts/:templateID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Delete a system prompt template",
+			Response: openapi.Object(map[string]interface{}{"id": "", "status": status("deleted")}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/experiments", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "List A/B experiments",
+			Response: openapi.Object(map[string]interface{}{"experiments": []*experiment.Experiment{}, "count": 0}),
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/experiments", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage), Status: http.StatusCreated,
+			Summary:     "Create an A/B experiment",
+			Description: "Splits new sessions between model or prompt template variants by weight. Users are assigned a variant by a hash of their user ID, so they keep it across sessions.",
+			Request:     experimentRequest{},
+			Response:    experiment.Experiment{},
+			Errors:      []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/experiments/:experimentID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Delete an A/B experiment",
+			Response: openapi.Object(map[string]interface{}{"id": "", "status": status("deleted")}),
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/experiments/:experimentID/metrics", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:     "Get per-variant metrics of an A/B experiment",
+			Description: "Session count, tokens, response times and feedback of the sessions assigned each variant. Defaults to the last 30 days.",
+			Query: []openapi.Param{
+				query("start_time", openapi.DateTime(), ""),
+				query("end_time", openapi.DateTime(), ""),
+				query("tenant_id", nil, "Super admins: only this tenant's sessions, read from its datastore"),
+			},
+			Response: openapi.Object(map[string]interface{}{"experiment": experiment.Experiment{}, "variants": []*storage.ExperimentMetrics{}, "time_range": timeRangeSchema()}),
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/canned", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "List canned responses",
+			Response: openapi.Object(map[string]interface{}{"canned": []*storage.CannedResponse{}, "count": 0}),
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/canned", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage), Status: http.StatusCreated,
+			Summary:  "Create a canned response",
+			Request:  cannedRequest{},
+			Response: storage.CannedResponse{},
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/canned/:cannedID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermViewSessions),
+			Summary:  "Get a canned response",
+			Response: storage.CannedResponse{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPut, Path: "/admin/canned/:cannedID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Replace a canned response",
+			Request:  cannedRequest{},
+			Response: storage.CannedResponse{},
+			Errors:   errNotFound,
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/canned/:cannedID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermManage),
+			Summary:  "Delete a canned response",
+			Response: openapi.Object(map[string]interface{}{"id": "", "status": status("deleted")}),
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/sessions/:sessionID/canned/:cannedID", Tag: tagAdmin, Auth: openapi.AuthAdmin, Permission: string(authz.PermBroadcast), Status: http.StatusCreated,
+			Summary:  "Send a canned response into a taken-over session",
+			Request:  sendCannedRequest{},
+			Response: openapi.Object(map[string]interface{}{"session_id": "", "canned_id": "", "admin_id": "", "sent": 0, "seqs": []uint64{}}),
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusServiceUnavailable},
+		},
+
+		// Data subject requests
+		{
+			Method: http.MethodGet, Path: "/users/:userID/export", Tag: tagUsers, Auth: openapi.AuthAdminJWT, Permission: string(authz.PermExport),
+			Summary: "Export a user's data",
+			Files:   []string{"application/zip"},
+			Errors:  []int{http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+		{
+			Method: http.MethodDelete, Path: "/users/:userID/data", Tag: tagUsers, Auth: openapi.AuthAdminJWT, Permission: string(authz.PermPurge),
+			Summary:  "Erase a user's data",
+			Response: openapi.Object(map[string]interface{}{"user_id": "", "status": status("erased"), "sessions_deleted": 0}),
+			Errors:   []int{http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+
+		// Health and service documents
+		{
+			Method: http.MethodGet, Path: "/healthz", Tag: tagHealth,
+			Summary:  "Liveness probe",
+			Response: openapi.Object(map[string]interface{}{"status": status("healthy"), "timestamp": openapi.DateTime()}),
+			Errors:   errPublicLimits,
+		},
+		{
+			Method: http.MethodGet, Path: "/readyz", Tag: tagHealth,
+			Summary:     "Readiness probe",
+			Description: "Answers 503 when a critical dependency fails, with the same body.",
+			Response:    openapi.Object(map[string]interface{}{"status": health.StatusReady, "timestamp": openapi.DateTime(), "checks": map[string]health.Result{}}),
+			Errors:      append([]int{http.StatusServiceUnavailable}, errPublicLimits...),
+		},
+		{
+			Method: http.MethodGet, Path: "/metrics/prometheus", Tag: tagHealth,
+			Summary:     "Prometheus metrics",
+			Description: "Restricted to `chatbox.metrics_allowed_networks`.",
+			Files:       []string{"text/plain"},
+			Errors:      []int{http.StatusForbidden, http.StatusTooManyRequests},
+		},
+		{
+			Method: http.MethodGet, Path: "/openapi.json", Tag: tagHealth,
+			Summary: "This OpenAPI document",
+			Files:   []string{"application/json"},
+			Errors:  errPublicLimits,
+		},
+		{
+			Method: http.MethodGet, Path: "/docs", Tag: tagHealth,
+			Summary:     "Swagger UI",
+			Description: "Only served when `chatbox.openapi_ui` is enabled.",
+			Files:       []string{"text/html"},
+			Errors:      errPublicLimits,
+		},
+		{
+			Method: http.MethodGet, Path: "/widget/loader.js", Tag: tagPublic,
+			Summary:     "Loader script of the embeddable chat widget",
+			Description: "Loads the current widget version. Cached for a few minutes. Only served when `chatbox.widget.enabled` is set.",
+			Files:       []string{"text/javascript"},
+			Errors:      errPublicLimits,
+		},
+		{
+			Method: http.MethodGet, Path: "/widget/version", Tag: tagPublic,
+			Summary:     "Current version of the embeddable chat widget",
+			Description: "Only served when `chatbox.widget.enabled` is set.",
+			Response:    openapi.Object(map[string]interface{}{"version": "", "widget_url": "", "loader_url": ""}),
+			Errors:      errPublicLimits,
+		},
+		{
+			Method: http.MethodGet, Path: "/widget/:version/:file", Tag: tagPublic,
+			Summary:     "Versioned file of the embeddable chat widget",
+			Description: "Cached as immutable. Only the current version is served; other versions return 404. Only served when `chatbox.widget.enabled` is set.",
+			Files:       []string{"text/javascript"},
+			Errors:      append([]int{http.StatusNotFound}, errPublicLimits...),
+		},
+	}
+}
+
+// buildOpenAPISpec returns the encoded OpenAPI document of the REST API served
+// under pathPrefix
+func buildOpenAPISpec(pathPrefix string) ([]byte, error) {
+	doc, err := openapi.Build(openapi.Info{
+		Title:       constants.OpenAPITitle,
+		Version:     constants.OpenAPIVersion,
+		Description: "REST API of the chat service. The chat itself runs over the WebSocket protocol at /ws.",
+	}, pathPrefix, apierror.Envelope{}, apiRoutes())
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI spec: %w", err)
+	}
+	return json.Marshal(doc)
+}
+
+// handleOpenAPISpec returns a handler that serves the encoded OpenAPI document
+func handleOpenAPISpec(spec []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(constants.StatusOK, "application/json; charset=utf-8", spec)
+	}
+}
+
+// handleOpenAPIUI returns a handler that serves a Swagger UI page for the
+// OpenAPI document served under pathPrefix
+func handleOpenAPIUI(pathPrefix string) gin.HandlerFunc {
+	page := openapi.UIPage(constants.OpenAPITitle, path.Join(pathPrefix, "openapi.json"))
+	return func(c *gin.Context) {
+		c.Data(constants.StatusOK, "text/html; charset=utf-8", page)
+	}
+}
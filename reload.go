@@ -0,0 +1,266 @@
+package chatbox
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/embed"
+	"github.com/real-rm/chatbox/internal/httperrors"
+	"github.com/real-rm/chatbox/internal/llm"
+	"github.com/real-rm/chatbox/internal/ratelimit"
+	"github.com/real-rm/chatbox/internal/websocket"
+	"github.com/real-rm/goconfig"
+	"github.com/real-rm/golog"
+)
+
+// Configuration sections applied by a reload
+const (
+	reloadSectionOrigins    = "allowed_origins"
+	reloadSectionAdminLimit = "admin_rate_limit"
+	reloadSectionGuestLimit = "guest_rate_limit"
+	reloadSectionPolicies   = "rate_limits"
+	reloadSectionModels     = "models"
+	reloadSectionCORS       = "cors_allowed_origins"
+)
+
+// configReloader applies the safe-to-change sections of a reloaded
+// configuration to the running components without dropping connections: the
+// WebSocket allowed origins, the admin and guest rate limits, the limits of the
+// [chatbox.rate_limits] policies, the model catalog and the CORS allowed
+// origins. Everything else, e.g. LLM providers or storage, still requires a
+// restart.
+type configReloader struct {
+	load          func() (*goconfig.ConfigAccessor, error)
+	wsHandler     *websocket.Handler
+	cors          *reloadableCORS
+	embedRegistry *embed.Registry // nil unless embedding is configured
+	adminLimiter  ratelimit.Limiter
+	guestLimiter  ratelimit.Limiter // nil unless guest mode is enabled
+	ratePolicies  *ratelimit.Policies
+	llmService    *llm.LLMService // nil in human-only mode
+	logger        *golog.Logger
+	mu            sync.Mutex // Serializes reloads
+}
+
+// reloadedConfig holds the validated safe-to-change sections of a configuration
+type reloadedConfig struct {
+	origins         []string // WebSocket origins, including those of embedding sites
+	adminRateWindow time.Duration
+	adminRateLimit  int
+	guestRateLimit  int
+	ratePolicies    []ratelimit.Policy
+	catalog         *llm.ModelCatalog // nil in human-only mode
+	corsOrigins     []string
+	corsHandler     gin.HandlerFunc // nil when CORS is not reloaded
+}
+
+// loadConfiguration re-reads the configuration file through goconfig, the
+// accessor Register received; environment overrides are read again as well
+func loadConfiguration() (*goconfig.ConfigAccessor, error) {
+	goconfig.ResetConfig()
+	// No else needed: early return pattern (guard clause)
+	if err := goconfig.LoadConfig(); err != nil {
+		return nil, err
+	}
+	return goconfig.Default()
+}
+
+// reloadableCORS is the CORS middleware; a reload swaps in a handler built for
+// the new allowed origins
+type reloadableCORS struct {
+	handler gin.HandlerFunc
+	mu      sync.RWMutex
+}
+
+// newCORSHandler builds the CORS middleware for origins. Without origins CORS
+// is disabled and requests pass through.
+func newCORSHandler(origins []string) (gin.HandlerFunc, error) {
+	// No else needed: early return pattern (CORS disabled)
+	if len(origins) == 0 {
+		return func(c *gin.Context) { c.Next() }, nil
+	}
+	corsConfig := cors.Config{
+		AllowOrigins:     origins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", constants.HeaderRequestID},
+		ExposeHeaders:    []string{"Content-Length", constants.HeaderRequestID},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}
+	// No else needed: early return pattern (cors.New panics on an invalid configuration)
+	if err := corsConfig.Validate(); err != nil {
+		return nil, err
+	}
+	return cors.New(corsConfig), nil
+}
+
+// set replaces the CORS handler
+func (m *reloadableCORS) set(handler gin.HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+}
+
+// handle runs the current CORS handler
+func (m *reloadableCORS) handle(c *gin.Context) {
+	m.mu.RLock()
+	handler := m.handler
+	m.mu.RUnlock()
+	handler(c)
+}
+
+// Reload re-reads the configuration and applies it. Every section is validated
+// before any is applied, so an invalid configuration changes nothing. Returns
+// the sections applied.
+func (r *configReloader) Reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, err := r.load()
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	next, err := r.parse(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every section was built and validated by parse, so they are applied
+	// together. Policies were checked there as well; they go first so nothing
+	// is applied should they be rejected anyway.
+	// No else needed: early return pattern (guard clause)
+	if err := r.ratePolicies.Reconfigure(next.ratePolicies); err != nil {
+		return nil, err
+	}
+	var sections []string
+	// No else needed: optional operation (policies only when configured)
+	if len(next.ratePolicies) > 0 {
+		sections = append(sections, reloadSectionPolicies)
+	}
+
+	// No else needed: optional operation (warn about open origins)
+	if len(next.origins) == 0 {
+		r.logger.Warn("No allowed origins configured, allowing all origins (development mode)")
+	}
+	r.wsHandler.SetAllowedOrigins(next.origins)
+	sections = append(sections, reloadSectionOrigins)
+
+	// No else needed: optional operation (built-in limiters are reconfigurable)
+	if limiter, ok := r.adminLimiter.(ratelimit.Reconfigurable); ok {
+		limiter.SetLimit(next.adminRateWindow, next.adminRateLimit)
+		sections = append(sections, reloadSectionAdminLimit)
+	}
+	// No else needed: optional operation (guests only when enabled)
+	if limiter, ok := r.guestLimiter.(ratelimit.Reconfigurable); ok {
+		limiter.SetLimit(constants.DefaultRateWindow, next.guestRateLimit)
+		sections = append(sections, reloadSectionGuestLimit)
+	}
+	// No else needed: optional operation (LLM only when enabled)
+	if next.catalog != nil {
+		r.llmService.SetCatalog(next.catalog)
+		sections = append(sections, reloadSectionModels)
+	}
+	// No else needed: optional operation (CORS only when the middleware is installed)
+	if next.corsHandler != nil {
+		r.cors.set(next.corsHandler)
+		r.logger.Info("CORS allowed origins reloaded", "allowed_origins", next.corsOrigins)
+		sections = append(sections, reloadSectionCORS)
+	}
+
+	r.logger.Info("Configuration reloaded", "sections", sections)
+	return sections, nil
+}
+
+// parse reads, validates and builds the safe-to-change sections of config
+// without applying any
+func (r *configReloader) parse(config *goconfig.ConfigAccessor) (*reloadedConfig, error) {
+	var next reloadedConfig
+	var err error
+	next.origins, err = loadAllowedOrigins(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: optional operation (only when embedding is configured)
+	if len(next.origins) > 0 && r.embedRegistry != nil {
+		next.origins = append(next.origins, r.embedRegistry.Origins()...)
+	}
+	next.adminRateWindow, next.adminRateLimit, err = loadAdminRateLimit(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: optional operation (guests only when enabled)
+	if r.guestLimiter != nil {
+		next.guestRateLimit, err = loadGuestRateLimit(config)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+	}
+	next.ratePolicies, err = parseRatePolicies(config)
+	// No else needed: early return pattern (guard clause)
+	if err != nil {
+		return nil, err
+	}
+	// No else needed: early return pattern (guard clause)
+	if err := r.ratePolicies.CheckReconfigure(next.ratePolicies); err != nil {
+		return nil, err
+	}
+	// No else needed: optional operation (LLM only when enabled)
+	if r.llmService != nil {
+		next.catalog, err = r.llmService.ParseCatalog(config)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// No else needed: optional operation (CORS only when the middleware is installed)
+	if r.cors != nil {
+		next.corsOrigins, err = loadCORSOrigins(config)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, err
+		}
+		next.corsHandler, err = newCORSHandler(next.corsOrigins)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chatbox.cors_allowed_origins: %w", err)
+		}
+	}
+	return &next, nil
+}
+
+// Reload re-reads the configuration and applies its safe-to-change sections to
+// the registered service without dropping connections, e.g. on SIGHUP. An
+// invalid configuration changes nothing. Returns the sections applied.
+func Reload() ([]string, error) {
+	shutdownMu.Lock()
+	reloader := globalReloader
+	shutdownMu.Unlock()
+	// No else needed: early return pattern (guard clause)
+	if reloader == nil {
+		return nil, fmt.Errorf("chatbox service is not registered")
+	}
+	return reloader.Reload()
+}
+
+// handleReloadConfig reloads the configuration, see configReloader
+func handleReloadConfig(reloader *configReloader, logger *golog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sections, err := reloader.Reload()
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			logger.Warn("Configuration reload rejected", "error", err)
+			httperrors.RespondBadRequest(c, fmt.Sprintf("Configuration not reloaded: %v", err))
+			return
+		}
+		c.JSON(constants.StatusOK, gin.H{"reloaded": sections})
+	}
+}
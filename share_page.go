@@ -0,0 +1,99 @@
+package chatbox
+
+import (
+	"bytes"
+	"html/template"
+	"time"
+
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/session"
+	"github.com/real-rm/chatbox/internal/storage"
+)
+
+// sharePageTemplate renders the transcript of a shared session for browsers.
+// html/template escapes message content, so it is shown as plain text.
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+.msg { margin: 1rem 0; padding: .75rem 1rem; border-radius: .5rem; background: #f6f8fa; white-space: pre-wrap; }
+.msg.user { background: #ddf4ff; }
+.msg.system { background: none; color: #59636e; font-style: italic; }
+.meta { font-size: .8rem; color: #59636e; margin-bottom: .25rem; }
+footer { margin-top: 2rem; font-size: .8rem; color: #59636e; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Messages}}<div class="msg {{.Sender}}"><div class="meta">{{.Label}} · {{.Time}}</div>{{.Content}}</div>
+{{end}}<footer>Read-only shared conversation{{if .ExpiresAt}}. This link expires {{.ExpiresAt}}{{end}}.</footer>
+</body>
+</html>
+`))
+
+// sharePage is the data of sharePageTemplate
+type sharePage struct {
+	Title     string
+	Messages  []sharePageMessage
+	ExpiresAt string
+}
+
+// sharePageMessage is a message of a shared transcript
+type sharePageMessage struct {
+	Sender  string // CSS class: user, ai, admin or system
+	Label   string
+	Time    string
+	Content string
+}
+
+// shareSenderLabels names the senders of a shared transcript
+var shareSenderLabels = map[string]string{
+	constants.SenderUser:   "User",
+	constants.SenderAI:     "Assistant",
+	constants.SenderAdmin:  "Support",
+	constants.SenderSystem: "System",
+}
+
+// renderSharePage renders the transcript of a shared session as an HTML page.
+// Messages without content, such as file-only messages, are left out.
+func renderSharePage(sess *session.Session, link *storage.ShareLink) ([]byte, error) {
+	page := sharePage{Title: sess.Name}
+	// No else needed: optional operation (unnamed sessions)
+	if page.Title == "" {
+		page.Title = "Shared conversation"
+	}
+	// No else needed: optional operation (only expiring links)
+	if link.ExpiresAt != nil {
+		page.ExpiresAt = link.ExpiresAt.UTC().Format(time.RFC1123)
+	}
+	for _, msg := range sess.Messages {
+		// No else needed: optional operation (skip messages without text)
+		if msg.Content == "" {
+			continue
+		}
+		sender := msg.Sender
+		label, ok := shareSenderLabels[sender]
+		// No else needed: optional operation (unknown senders shown as system)
+		if !ok {
+			sender, label = constants.SenderSystem, shareSenderLabels[constants.SenderSystem]
+		}
+		page.Messages = append(page.Messages, sharePageMessage{
+			Sender:  sender,
+			Label:   label,
+			Time:    msg.Timestamp.UTC().Format(time.RFC1123),
+			Content: msg.Content,
+		})
+	}
+
+	var buf bytes.Buffer
+	// No else needed: early return pattern (guard clause)
+	if err := sharePageTemplate.Execute(&buf, page); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
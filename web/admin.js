@@ -202,9 +202,11 @@ function updateSessionsDisplay(sessions) {
             </td>
             <td>
                 ${
-                  session.is_active
-                    ? `<button class="btn-small" data-takeover-session="${safeSessionId}">Takeover</button>`
-                    : "-"
+                  !session.is_active
+                    ? "-"
+                    : session.assisting_admin_name
+                      ? `<button class="btn-small" data-handback-session="${safeSessionId}">Hand back</button>`
+                      : `<button class="btn-small" data-takeover-session="${safeSessionId}">Takeover</button>`
                 }
             </td>
         </tr>`;
@@ -220,6 +222,11 @@ function updateSessionsDisplay(sessions) {
       takeoverSession(el.dataset.takeoverSession),
     );
   });
+  tbody.querySelectorAll("[data-handback-session]").forEach((el) => {
+    el.addEventListener("click", () =>
+      handbackSession(el.dataset.handbackSession),
+    );
+  });
 }
 
 // Show User Sessions Modal
@@ -331,6 +338,36 @@ async function takeoverSession(sessionID) {
   }
 }
 
+// Hand Back Session to the AI assistant
+async function handbackSession(sessionID) {
+  if (!confirm("Hand this session back to the AI assistant?")) {
+    return;
+  }
+
+  try {
+    const response = await fetch(
+      `${API_BASE_URL}${PATH_PREFIX}/admin/handback/${sessionID}`,
+      {
+        method: "POST",
+        headers: {
+          Authorization: `Bearer ${getJWTToken()}`,
+          "Content-Type": "application/json",
+        },
+      },
+    );
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.message || "Failed to hand back session");
+    }
+
+    loadData();
+  } catch (error) {
+    console.error("Error handing back session:", error);
+    showError(error.message || "Failed to hand back session");
+  }
+}
+
 // Apply Filters
 function applyFilters() {
   currentFilters.userID = document
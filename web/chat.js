@@ -210,7 +210,8 @@ class ChatClient {
   openWebSocket(token) {
     // Construct WebSocket URL
     const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
-    let wsUrl = `${protocol}//${API_HOST}${PATH_PREFIX}/ws?token=${token}`;
+    // Ask for compact ai_delta frames while replies stream
+    let wsUrl = `${protocol}//${API_HOST}${PATH_PREFIX}/ws?token=${token}&capabilities=delta`;
 
     // Include session ID if reconnecting or loading existing session
     if (this.sessionID) {
@@ -254,6 +255,9 @@ class ChatClient {
         case "ai_response":
           this.handleAIResponse(message);
           break;
+        case "ai_delta":
+          this.appendStreamingText(message.delta, new Date().toISOString());
+          break;
         case "admin_join":
           this.handleAdminJoin(message);
           break;
@@ -345,34 +349,13 @@ class ChatClient {
     const isDone = message.metadata && message.metadata.done === "true";
 
     if (isStreaming) {
-      // Only create the bubble when there is actual content to display.
-      // Empty chunks (e.g. done=true with no content) should not spawn
-      // a visible bubble or hide the typing indicator prematurely.
-      if (message.content) {
-        if (!this._streamingDiv) {
-          this.hideLoading();
-          this._streamingDiv = document.createElement("div");
-          this._streamingDiv.className = "message ai";
-
-          const contentDiv = document.createElement("div");
-          contentDiv.className = "message-content";
-          this._streamingDiv.appendChild(contentDiv);
-
-          const timestamp = document.createElement("div");
-          timestamp.className = "message-timestamp";
-          this._streamingDiv.appendChild(timestamp);
-
-          this.messagesContainer.appendChild(this._streamingDiv);
-        }
-
-        const contentEl = this._streamingDiv.querySelector(".message-content");
-        contentEl.textContent += message.content;
-
-        const tsEl = this._streamingDiv.querySelector(".message-timestamp");
-        tsEl.textContent = this.formatTimestamp(message.timestamp);
-
-        this.scrollToBottom();
+      // The consolidated final frame of a delta stream holds the whole reply
+      const consolidated =
+        message.metadata && message.metadata.consolidated === "true";
+      if (consolidated && this._streamingDiv) {
+        this._streamingDiv.querySelector(".message-content").textContent = "";
       }
+      this.appendStreamingText(message.content, message.timestamp);
 
       // When the stream is done, release the reference so the next
       // response starts a fresh bubble.
@@ -386,6 +369,37 @@ class ChatClient {
     }
   }
 
+  appendStreamingText(text, timestamp) {
+    // Only create the bubble when there is actual content to display.
+    // Empty chunks (e.g. done=true with no content) should not spawn
+    // a visible bubble or hide the typing indicator prematurely.
+    if (!text) return;
+
+    if (!this._streamingDiv) {
+      this.hideLoading();
+      this._streamingDiv = document.createElement("div");
+      this._streamingDiv.className = "message ai";
+
+      const contentDiv = document.createElement("div");
+      contentDiv.className = "message-content";
+      this._streamingDiv.appendChild(contentDiv);
+
+      const timestampDiv = document.createElement("div");
+      timestampDiv.className = "message-timestamp";
+      this._streamingDiv.appendChild(timestampDiv);
+
+      this.messagesContainer.appendChild(this._streamingDiv);
+    }
+
+    const contentEl = this._streamingDiv.querySelector(".message-content");
+    contentEl.textContent += text;
+
+    const tsEl = this._streamingDiv.querySelector(".message-timestamp");
+    tsEl.textContent = this.formatTimestamp(timestamp);
+
+    this.scrollToBottom();
+  }
+
   handleAdminJoin(message) {
     const adminName = message.metadata?.admin_name || "Admin";
     this.showAdminName(adminName);
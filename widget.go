@@ -0,0 +1,67 @@
+package chatbox
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/real-rm/chatbox/internal/constants"
+	"github.com/real-rm/chatbox/internal/httperrors"
+	"github.com/real-rm/chatbox/internal/widget"
+)
+
+// Cache-Control values of the widget files. The loader is revalidated often so
+// embedding pages pick up new releases; versioned files never change.
+var (
+	widgetLoaderCacheControl = fmt.Sprintf("public, max-age=%d", int(constants.WidgetLoaderMaxAge.Seconds()))
+	widgetAssetCacheControl  = fmt.Sprintf("public, max-age=%d, immutable", int(constants.WidgetAssetMaxAge.Seconds()))
+)
+
+// serveWidgetFile writes a widget file, or 304 when the client already has it
+func serveWidgetFile(c *gin.Context, etag, cacheControl string, content []byte) {
+	c.Header("Cache-Control", cacheControl)
+	c.Header("ETag", etag)
+	// No else needed: optional operation (conditional request)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(constants.StatusNotModified)
+		return
+	}
+	c.Data(constants.StatusOK, widget.ContentType, content)
+}
+
+// handleWidgetLoader returns a handler that serves the loader snippet, which
+// loads the current widget version
+func handleWidgetLoader(bundle *widget.Bundle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serveWidgetFile(c, bundle.ETag(widget.FileLoader), widgetLoaderCacheControl, bundle.Loader())
+	}
+}
+
+// handleWidgetVersion returns a handler that reports the current widget
+// version and the paths of its files under pathPrefix
+func handleWidgetVersion(bundle *widget.Bundle, pathPrefix string) gin.HandlerFunc {
+	body := gin.H{
+		"version":    bundle.Version(),
+		"widget_url": path.Join(pathPrefix, "widget", bundle.Version(), widget.FileWidget),
+		"loader_url": path.Join(pathPrefix, "widget", widget.FileLoader),
+	}
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+		c.JSON(constants.StatusOK, body)
+	}
+}
+
+// handleWidgetAsset returns a handler that serves a file of the current widget
+// version. Other versions are not found, so stale pages reload the loader.
+func handleWidgetAsset(bundle *widget.Bundle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("file")
+		content, err := bundle.Asset(c.Param("version"), name)
+		// No else needed: early return pattern (guard clause)
+		if err != nil {
+			httperrors.RespondNotFound(c, "widget version not found")
+			return
+		}
+		serveWidgetFile(c, bundle.ETag(name), widgetAssetCacheControl, content)
+	}
+}